@@ -3,11 +3,12 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
-	"time"
 
 	"github.com/d60-lab/im-system/internal/app"
 )
@@ -15,6 +16,34 @@ import (
 func main() {
 	// 加载配置
 	config := app.DefaultConfig()
+
+	// 数据库迁移子命令: gateway migrate [up|down|status] [flags]
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		action := app.MigrateUp
+		if len(os.Args) > 2 && !strings.HasPrefix(os.Args[2], "-") {
+			action = os.Args[2]
+			os.Args = append(os.Args[:1], os.Args[3:]...)
+		} else {
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+		}
+		config.ParseFlags()
+		if flag.NArg() > 0 {
+			log.Fatalf("Unexpected arguments: %v", flag.Args())
+		}
+		if err := app.RunMigration(config, action); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// 录制回放子命令: gateway replay [flags] <录制文件...>
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := app.RunReplay(config, os.Args[2:]); err != nil {
+			log.Fatalf("Replay failed: %v", err)
+		}
+		return
+	}
+
 	config.ParseFlags()
 
 	log.Printf("Starting IM Gateway (NodeID: %s)...", config.NodeID)
@@ -44,7 +73,7 @@ func main() {
 	<-quit
 
 	// 优雅关闭
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
 	defer shutdownCancel()
 
 	if err := server.Shutdown(shutdownCtx); err != nil {
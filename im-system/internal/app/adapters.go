@@ -3,9 +3,12 @@ package app
 
 import (
 	"context"
+	"log"
+	"time"
 
 	"github.com/d60-lab/im-system/internal/gateway"
 	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
 	"github.com/d60-lab/im-system/internal/service"
 )
 
@@ -32,12 +35,191 @@ func (a *messageDispatcherAdapter) DispatchToUsers(ctx context.Context, userIDs
 	return a.dispatcher.DispatchToUsers(ctx, userIDs, msg)
 }
 
+// DispatchToConversation 分发消息到会话
+func (a *messageDispatcherAdapter) DispatchToConversation(ctx context.Context, conversationID string, msg *model.Message, excludeUserID string) error {
+	return a.dispatcher.DispatchToConversation(ctx, conversationID, msg, excludeUserID)
+}
+
+// BroadcastToAllNodes 广播消息给所有节点的所有用户
+func (a *messageDispatcherAdapter) BroadcastToAllNodes(ctx context.Context, msg *model.Message) error {
+	return a.dispatcher.BroadcastToAllNodes(ctx, msg)
+}
+
 // messageSaverAdapter 消息保存适配器
 type messageSaverAdapter struct {
 	messageService service.MessageService
 }
 
-// SaveMessage 保存消息
+// SaveMessage 保存消息，会话记录在分发前检查通过后更新
 func (a *messageSaverAdapter) SaveMessage(ctx context.Context, msg *model.Message) error {
-	return a.messageService.SaveMessage(ctx, msg)
+	return a.messageService.SavePendingMessage(ctx, msg)
+}
+
+// RecordConversation 更新会话记录
+func (a *messageSaverAdapter) RecordConversation(ctx context.Context, msg *model.Message) error {
+	return a.messageService.RecordConversation(ctx, msg)
+}
+
+// nodeGatewayAdapter 节点网关适配器
+type nodeGatewayAdapter struct {
+	registry   *gateway.ConnectionRegistry
+	dispatcher gateway.MessageDispatcher
+	latency    *gateway.LatencyTracker
+}
+
+// CountConnections 统计各节点连接数
+func (a *nodeGatewayAdapter) CountConnections(ctx context.Context) ([]*service.NodeConnectionCount, error) {
+	counts, err := a.registry.CountConnections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*service.NodeConnectionCount, len(counts))
+	for i, c := range counts {
+		result[i] = &service.NodeConnectionCount{NodeID: c.NodeID, Connections: c.Connections}
+	}
+	return result, nil
+}
+
+// ListConnections 分页列出节点上的连接
+func (a *nodeGatewayAdapter) ListConnections(ctx context.Context, nodeID string, cursor uint64, count int64) ([]*service.NodeConnection, uint64, error) {
+	entries, next, err := a.registry.ListConnections(ctx, nodeID, cursor, count)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make([]*service.NodeConnection, len(entries))
+	for i, e := range entries {
+		result[i] = &service.NodeConnection{
+			UserID:      e.UserID,
+			ConnID:      e.ConnID,
+			NodeID:      e.NodeID,
+			DeviceID:    e.DeviceID,
+			Platform:    e.Platform,
+			ClientIP:    e.ClientIP,
+			ConnectedAt: e.ConnectedAt,
+
+			AppVersion:   e.AppVersion,
+			OS:           e.OS,
+			NetworkType:  e.NetworkType,
+			Capabilities: e.Capabilities,
+		}
+	}
+	return result, next, nil
+}
+
+// BroadcastToNode 广播消息给节点上的用户
+func (a *nodeGatewayAdapter) BroadcastToNode(ctx context.Context, nodeID string, msg *model.Message, platforms []string) error {
+	return a.dispatcher.BroadcastToNode(ctx, nodeID, msg, platforms)
+}
+
+// BroadcastToAll 广播消息给所有节点上的用户
+func (a *nodeGatewayAdapter) BroadcastToAll(ctx context.Context, msg *model.Message, platforms []string) error {
+	return a.dispatcher.BroadcastToPlatforms(ctx, msg, platforms)
+}
+
+// KickUser 向各节点发送断开用户连接的控制指令（用户可能在多个节点上有连接）
+func (a *nodeGatewayAdapter) KickUser(ctx context.Context, userID string) error {
+	counts, err := a.registry.CountConnections(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range counts {
+		if err := a.dispatcher.SendNodeControl(ctx, c.NodeID, gateway.NodeControlKickUserPrefix+userID); err != nil {
+			log.Printf("send kick control to node %s error: %v", c.NodeID, err)
+		}
+	}
+	return nil
+}
+
+// ClientStats 统计集群客户端分布
+func (a *nodeGatewayAdapter) ClientStats(ctx context.Context) (*service.ClientStats, error) {
+	stats, err := a.registry.ClientStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &service.ClientStats{
+		Total:    stats.Total,
+		Versions: make([]*service.ClientVersionCount, len(stats.Versions)),
+		OS:       stats.OS,
+		Networks: stats.Networks,
+	}
+	for i, v := range stats.Versions {
+		result.Versions[i] = &service.ClientVersionCount{Platform: v.Platform, AppVersion: v.AppVersion, Connections: v.Connections}
+	}
+	return result, nil
+}
+
+// DrainNode 排空节点连接
+func (a *nodeGatewayAdapter) DrainNode(ctx context.Context, nodeID string) error {
+	return a.dispatcher.SendNodeControl(ctx, nodeID, gateway.NodeControlDrain)
+}
+
+// ClusterLatency 获取集群各节点的消息处理阶段耗时
+func (a *nodeGatewayAdapter) ClusterLatency(ctx context.Context) ([]*service.NodeLatency, error) {
+	nodes, err := a.latency.ClusterLatency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*service.NodeLatency, len(nodes))
+	for i, n := range nodes {
+		node := &service.NodeLatency{NodeID: n.NodeID, UpdatedAt: n.UpdatedAt}
+		for _, stage := range gateway.LatencyStages {
+			q, ok := n.Stages[stage]
+			if !ok {
+				continue
+			}
+			node.Stages = append(node.Stages, &service.StageLatency{
+				Stage:   stage,
+				Samples: q.Samples,
+				P50:     q.P50,
+				P95:     q.P95,
+				P99:     q.P99,
+			})
+		}
+		result[i] = node
+	}
+	return result, nil
+}
+
+// latencySampleSaverAdapter 消息耗时抽样保存适配器
+type latencySampleSaverAdapter struct {
+	repo repository.MessageLatencyRepository
+}
+
+// SaveLatencySample 保存耗时抽样（阶段耗时换算为毫秒）
+func (a *latencySampleSaverAdapter) SaveLatencySample(ctx context.Context, sample *gateway.LatencySample) error {
+	stages := make(map[string]float64, len(sample.Stages))
+	for stage, d := range sample.Stages {
+		stages[stage] = float64(d) / float64(time.Millisecond)
+	}
+	return a.repo.Save(ctx, &repository.MessageLatencySample{
+		MessageID:      sample.MessageID,
+		ConversationID: sample.ConversationID,
+		Type:           int(sample.Type),
+		NodeID:         sample.NodeID,
+		StagesMs:       stages,
+		TotalMs:        float64(sample.Total) / float64(time.Millisecond),
+		ReceivedAt:     sample.ReceivedAt,
+	})
+}
+
+// ephemeralTarget 按临时消息所在会话构造等价的聊天消息，用于复用发送前的投递对象检查；
+// 发送者不是单聊一方时返回 nil（由分发时的成员检查拒绝）
+func ephemeralTarget(msg *model.Message) *model.Message {
+	convID, err := model.ParseConversationID(msg.ConversationID)
+	if err != nil {
+		return nil
+	}
+	switch convID.Type {
+	case model.ConversationTypeGroup:
+		return &model.Message{Type: model.MsgGroupChat, From: msg.From, To: convID.GroupID, GroupID: convID.GroupID}
+	case model.ConversationTypeSingle:
+		peer := convID.UserIDs[0]
+		if peer == msg.From {
+			peer = convID.UserIDs[1]
+		} else if convID.UserIDs[1] != msg.From {
+			return nil
+		}
+		return &model.Message{Type: model.MsgSingleChat, From: msg.From, To: peer}
+	}
+	return nil
 }
@@ -4,6 +4,8 @@ package app
 import (
 	"flag"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,6 +16,25 @@ type Config struct {
 	Port   int
 	NodeID string
 
+	// 运行环境: development, production
+	Env string
+
+	// 启动时自动执行数据库迁移，关闭时仅检查结构版本，未迁移则拒绝启动
+	AutoMigrate bool
+
+	// 管理员用户ID（拥有全部管理权限，其余用户按分配的角色访问 /api/admin 接口）
+	AdminUserIDs []string
+	RBACCacheTTL time.Duration // 用户管理权限的Redis缓存时间
+
+	// Web安全配置
+	AllowOrigins  []string // 允许的跨域来源（REST和WebSocket），为空时仅允许同源
+	CookieSession bool     // 启用Web端Cookie会话及CSRF校验
+	CookieSecure  bool     // Cookie仅通过HTTPS发送
+	CookieDomain  string   // Cookie域
+
+	// 可信反向代理（IP或CIDR），仅采信其转发的 X-Forwarded-For / X-Real-IP，为空时客户端IP取连接地址
+	TrustedProxies []string
+
 	// MySQL配置
 	MySQLHost     string
 	MySQLPort     int
@@ -27,10 +48,31 @@ type Config struct {
 	RedisPassword string
 	RedisDB       int
 
+	// 跨节点消息通道配置
+	MessageBroker          string        // 跨节点消息通道: redis（Pub/Sub，默认）, kafka
+	KafkaBrokers           []string      // Kafka 地址列表
+	KafkaTopicPrefix       string        // 节点主题前缀
+	KafkaReplicationFactor int           // 节点主题副本数
+	KafkaMaxReplayAge      time.Duration // 节点断开恢复后补投的消息最大时长
+
 	// MongoDB配置
 	MongoURI      string
 	MongoDatabase string
 
+	// 通过MongoDB变更流维护消息热缓存和会话状态（需要副本集部署）
+	MongoChangeStream bool
+
+	// 消息全文检索配置
+	SearchBackend         string // 检索后端: mongo（文本索引，默认）, elasticsearch
+	ElasticsearchURL      string // Elasticsearch 地址
+	ElasticsearchIndex    string // 消息索引名
+	ElasticsearchUsername string
+	ElasticsearchPassword string
+	ElasticsearchAnalyzer string // 消息文本分词器
+
+	// 路由与OpenAPI接口描述不一致时拒绝启动（用于CI）
+	OpenAPIStrict bool
+
 	// MinIO配置
 	MinioEndpoint  string
 	MinioAccessKey string
@@ -38,25 +80,285 @@ type Config struct {
 	MinioBucket    string
 	MinioUseSSL    bool
 
+	// 对象存储后端：minio（默认）、aws、aliyun、local；访问密钥与存储桶沿用 MinIO 配置
+	StorageProvider string
+	StorageEndpoint string // 为空时 minio 使用 MinioEndpoint，aws / aliyun 按 StorageRegion 使用公网地址
+	StorageRegion   string
+	StorageLocalDir string // 本地磁盘存储的根目录
+
+	// 数据驻留配置（未设置 RegionMongoURIs 时为单区域部署）：MongoDB、MinIO 配置为默认区域的存储
+	RegionDefault      string // 默认区域（未标记区域的用户及启用前的存量数据）
+	RegionTenants      string // 租户所属区域 tenant=region 逗号分隔
+	RegionCrossRules   string // 跨区域会话规则 区域A+区域B=存储区域 逗号分隔，未配置的区域组合之间不能通信
+	RegionMongoURIs    string // 其他区域的MongoDB地址 region=uri 分号分隔
+	RegionMinioBuckets string // 其他区域的对象存储 region=endpoint/bucket 逗号分隔（与默认区域使用相同的访问密钥）
+
+	// 用户搜索配置
+	UserSearchMode      string // exact: 仅精确匹配用户名/手机号, fuzzy: 模糊匹配
+	UserSearchRateLimit int    // 每用户每分钟最大搜索次数（0表示不限制）
+
+	// 访客配置（客服和群组均未配置时不开放访客会话）
+	GuestTTL       time.Duration // 访客账号有效期
+	GuestAgentIDs  []string      // 访客可以单聊的客服用户ID
+	GuestGroupIDs  []string      // 访客可以加入并发言的群组ID
+	GuestRateLimit int           // 每个IP每小时可创建的访客会话数（0表示不限制）
+
+	// 客服配置
+	CSAgentMaxConcurrent int // 坐席默认同时接待的会话上限
+	CSMaxQueue           int // 排队会话上限（0表示不限制）
+
+	// 命名策略配置
+	ReservedNames  []string      // 额外保留名称，以*结尾表示前缀匹配
+	UniqueNickname bool          // 同一租户内昵称唯一
+	RenameCooldown time.Duration // 改名冷却时间
+
+	// 用户批量导入配置
+	UserImportMaxRows int // 单次导入最大行数
+
+	// 消息提醒配置
+	ReminderMaxPerUser int // 每个用户最多待提醒数
+
+	// 群活动每日摘要配置
+	GroupDigestDefaultTimezone string // 订阅未指定时区时使用的时区（IANA）
+
+	// 推送配置
+	PushEnabled bool // 是否启用设备推送（离线消息、提醒、群摘要推送到已注册设备）
+
+	// Web Push 配置（VAPID 密钥为空时 web 平台设备不推送）
+	WebPushPublicKey  string // VAPID 公钥（base64url）
+	WebPushPrivateKey string // VAPID 私钥（base64url）
+	WebPushSubject    string // VAPID 联系方式（mailto: 或 https: 地址）
+
+	// 连接会话计量配置
+	UsageMetering      bool // 是否记录连接会话并汇总月度用量
+	UsageRetentionDays int  // 会话明细保留天数（0表示不清理，月度汇总不清理）
+
+	// 自动回复配置
+	AutoReplyWindow     time.Duration // 同一发送者在窗口内只自动回复一次
+	AutoReplyMaxPerHour int           // 每个用户每小时最多自动回复次数
+
+	// 会话导出配置
+	ExportMaxMessages int      // 单次导出最多消息数
+	ExportPDFCommand  []string // HTML转PDF命令（从标准输入读HTML、向标准输出写PDF），为空时不支持PDF
+
+	// 会话摘要配置
+	SummaryEndpoint      string        // 外部摘要服务地址，为空时不启用摘要
+	SummaryAPIKey        string        // 外部摘要服务API Key
+	SummaryMaxMessages   int           // 单次摘要最多取最近的消息数
+	SummaryMaxPerHour    int           // 每个用户每小时最多摘要次数
+	SummaryTimeout       time.Duration // 调用外部摘要服务超时
+	SummaryRedactPattern string        // 发送前脱敏的正则，为空时使用默认规则
+
+	// 回复建议配置
+	SuggestionEndpoint string        // 外部回复建议服务地址，为空时不启用
+	SuggestionAPIKey   string        // 外部回复建议服务API Key
+	SuggestionTimeout  time.Duration // 单次请求超时
+
+	// 内容审核配置（敏感词与外部审核服务均未配置时不启用）
+	ModerationKeywords      []string      // 敏感词（忽略大小写）
+	ModerationKeywordFile   string        // 敏感词文件：每行一个，re: 开头为正则，# 开头为注释
+	ModerationKeywordAction string        // 命中敏感词的处置方式：reject / mask / flag
+	ModerationHTTPURL       string        // 外部审核服务地址
+	ModerationHTTPAPIKey    string        // 外部审核服务API Key
+	ModerationHTTPAction    string        // 外部审核服务命中但未给出处置方式时的处置
+	ModerationTimeout       time.Duration // 单个审核器超时
+	ModerationFailClosed    bool          // 审核器出错时拒绝内容（默认放行）
+
+	// 群事件通知降级配置
+	GroupEventBatchThreshold int           // 成员数达到该值时合并成员变动通知
+	GroupEventBatchWindow    time.Duration // 合并窗口
+	GroupEventLargeThreshold int           // 成员数达到该值时只通知相关成员和管理员
+	GroupEventLegacyExtra    bool          // 群事件是否同时下发旧版 extra 字段（弃用过渡期）
+
+	GroupDismissGracePeriod time.Duration // 群主账号禁用/注销且无可继任成员时，解散前的宽限期
+	GroupExpiryWarnBefore   time.Duration // 临时群到期前多久发送解散提醒
+
+	// 文件访问控制配置
+	FileProxyDownload    bool     // 通过网关代理下载文件
+	FileURLBindIP        bool     // 代理下载URL绑定客户端IP
+	FileRefererWhitelist []string // 代理下载Referer白名单
+	FileURLSecret        string   // 代理下载URL签名密钥（为空使用JWT密钥）
+
+	// 聊天文件保存期限配置（0表示长期保存）
+	FileRetentionSingleDays int // 单聊文件保存天数
+	FileRetentionGroupDays  int // 群聊文件保存天数
+
+	// CDN配置
+	CDNDomain       string        // CDN域名（如 https://cdn.example.com），为空时直接访问对象存储
+	CDNSignProvider string        // CDN URL签名方式: aliyun, cloudfront, hmac，为空表示不签名
+	CDNSignKey      string        // CDN签名密钥（CloudFront为PEM私钥或私钥文件路径）
+	CDNSignKeyID    string        // CloudFront Key Pair ID
+	CDNSignExpiry   time.Duration // CDN签名URL有效期
+
 	// JWT配置
 	JWTSecret     string
 	JWTExpire     time.Duration
 	JWTRefreshExp time.Duration
 
+	// JWT密钥轮换：旧密钥签发的Token在宽限期截止前仍可验证
+	JWTPreviousSecrets      []string
+	JWTPreviousSecretsUntil string // RFC3339 时间，为空时旧密钥一直有效
+
+	// 认证方式: jwt（默认）, introspection（OAuth2 Token Introspection）, apikey（静态API Key）
+	AuthProvider                  string
+	AuthIntrospectionURL          string
+	AuthIntrospectionClientID     string
+	AuthIntrospectionClientSecret string
+	AuthIntrospectionUserClaim    string        // 作为用户ID的字段
+	AuthIntrospectionCacheTTL     time.Duration // 校验结果缓存时间
+	AuthAPIKeys                   string        // key:user_id 逗号分隔
+
 	// WebSocket配置
 	PingInterval time.Duration
 	PongTimeout  time.Duration
 
+	// 心跳协商：客户端可在范围内请求心跳间隔，连续丢失超过 HeartbeatMaxMissed 个心跳时断开
+	HeartbeatMin       time.Duration
+	HeartbeatMax       time.Duration
+	HeartbeatMaxMissed int
+
+	// 允许的客户端时钟偏差，消息 client_timestamp 超出时拒绝（0表示不校验）
+	MaxClientSkew time.Duration
+
+	// 限流配置（0表示不限制）：登录、注册按客户端IP，上传按用户，均为每分钟次数，Redis 中多节点共享计数；
+	// WebSocket 消息按连接限制每秒条数及突发条数
+	LoginRateLimit    int
+	RegisterRateLimit int
+	UploadRateLimit   int
+	MessageRateLimit  float64
+	MessageRateBurst  int
+
+	// 消息存储时间校验：超前或落后服务器时间超出范围的消息（导入、桥接等路径）转入隔离集合待审核，
+	// MessageClockQuarantine 为 false 时直接拒绝（0表示不校验该方向）
+	MessageMaxFuture       time.Duration
+	MessageMaxPast         time.Duration
+	MessageClockQuarantine bool
+
+	// 各平台最低客户端版本（platform:version 逗号分隔，* 为默认），低于该版本的连接以 4426 关闭
+	MinClientVersions string
+
+	// 临时消息（正在输入、实时光标等）限制：内容最大字节数、每连接每秒条数及突发条数
+	EphemeralMaxBytes int
+	EphemeralRate     int
+	EphemeralBurst    int
+
+	// 正在输入节流：同一用户在同一会话内转发正在输入的最小间隔
+	TypingInterval time.Duration
+
+	// 广播、群事件扇出限速：本节点每秒投递到连接的条数和字节数（0表示不限制）
+	FanoutMessagesPerSecond int64
+	FanoutBytesPerSecond    int64
+
+	// SMTP发件配置（未设置 SMTPHost 时不发送邮件）
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// 新设备首次同步：离线消息数达到阈值时下发会话摘要快照（0表示始终逐条同步），每个会话保留最近若干条逐条拉取
+	OfflineBootstrapThreshold  int
+	OfflineBootstrapKeepRecent int
+
+	// 未读消息邮件摘要：离线超过该时长（小时）且有未读消息时发送，深链前缀及正文模板文件（为空使用内置模板）
+	EmailDigestOfflineHours int
+	EmailDigestLinkBase     string
+	EmailDigestTemplate     string
+
+	// 外部平台桥接（未设置 Token 的平台不启用）：Slack 机器人 Token 及请求签名密钥，
+	// Matrix 应用服务的 homeserver 地址、as_token、hs_token 及机器人用户ID，未映射外部用户代发消息的IM用户
+	BridgeSlackBotToken      string
+	BridgeSlackSigningSecret string
+	BridgeMatrixHomeserver   string
+	BridgeMatrixASToken      string
+	BridgeMatrixHSToken      string
+	BridgeMatrixBotUserID    string
+	BridgeBotUserID          string
+
+	// WebSocket发送合并：等待窗口（毫秒，0表示不合并）及每帧最多合并的条数、字节数
+	WSBatchWindowMs    int64
+	WSBatchMaxMessages int
+	WSBatchMaxBytes    int
+
+	// QoS1 投递确认：等待客户端ACK的超时（毫秒，0表示不跟踪）、最多重发次数、每个连接最多等待确认的消息数
+	WSAckTimeoutMs   int64
+	WSAckMaxRetries  int
+	WSAckMaxInFlight int
+
+	// 连接建立后自动补发离线消息：每批条数（0表示不补发，客户端须通过离线消息接口拉取）
+	WSOfflineReplayBatch int
+
+	// WebSocket连接数限制（0表示不限制）
+	WSMaxConnections        int      // 单节点最大连接数
+	WSMaxConnectionsPerUser int      // 单用户最大并发连接数
+	WSMaxConnectionsPerIP   int      // 单IP最大连接数
+	WSLimitExemptUsers      []string // 不受限制的用户ID（管理员等）
+	WSLimitExemptIPs        []string // 不受限制的IP（内网负载均衡、压测机等）
+
+	// 过载保护：CPU或发送队列占用超过阈值时按比例拒绝新连接（百分比，0表示不启用）
+	LoadShedCPUPercent   int
+	LoadShedQueuePercent int
+	LoadShedPercent      int
+	LoadShedRetryAfter   time.Duration // 建议重试间隔上限
+
+	// 入站帧录制（调试用，录制内容含消息明文，默认关闭）
+	RecordDir           string   // 录制文件目录，为空时不录制
+	RecordSamplePercent int      // 按连接抽样录制的百分比
+	RecordUserIDs       []string // 始终录制的用户ID
+	RecordMaxFileMB     int64    // 单个录制文件大小上限（MB）
+
+	// 消息处理耗时：计算分位数的最近样本数、按消息抽样写入诊断集合的千分比（0表示不写入）
+	LatencyWindow         int
+	LatencySamplePermille int
+
+	// 链路追踪（OpenTelemetry），OTLP 导出地址为空时不启用
+	TracingEndpoint    string  // OTLP 接收地址（host:port 或 http(s):// URL）
+	TracingProtocol    string  // grpc / http/protobuf
+	TracingInsecure    bool    // 不使用 TLS
+	TracingServiceName string  // 服务名
+	TracingSampleRatio float64 // 根 Span 采样比例（0~1）
+
 	// 指标端口
 	MetricsPort int
+
+	// 服务间内部 gRPC 接口：监听端口（0表示不启用）、调用方令牌
+	GRPCPort      int
+	GRPCAuthToken string
+
+	// 优雅关闭：整体超时、单个后台任务或组件的停止超时
+	ShutdownTimeout     time.Duration
+	ShutdownStepTimeout time.Duration
+
+	// ID生成配置
+	IDStrategy      string // legacy, snowflake, ulid, ksuid
+	SnowflakeNodeID int64  // 雪花算法节点ID (0-1023)
 }
 
+// 运行环境
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
 // DefaultConfig 默认配置
 func DefaultConfig() *Config {
+	env := getEnv("APP_ENV", EnvDevelopment)
+
+	// 开发环境默认允许任意来源，生产环境默认仅允许同源
+	defaultOrigins := "*"
+	defaultCookieSecure := "false"
+	defaultAutoMigrate := "true"
+	if env == EnvProduction {
+		defaultOrigins = ""
+		defaultCookieSecure = "true"
+		defaultAutoMigrate = "false"
+	}
+
 	return &Config{
 		Host:           "0.0.0.0",
 		Port:           8080,
 		NodeID:         getEnv("NODE_ID", "node1"),
+		Env:            env,
 		MySQLHost:      getEnv("MYSQL_HOST", "localhost"),
 		MySQLPort:      3306,
 		MySQLUser:      getEnv("MYSQL_USER", "root"),
@@ -73,12 +375,233 @@ func DefaultConfig() *Config {
 		MinioSecretKey: getEnv("MINIO_SECRET_KEY", "minioadmin123"),
 		MinioBucket:    getEnv("MINIO_BUCKET", "im-files"),
 		MinioUseSSL:    getEnv("MINIO_USE_SSL", "false") == "true",
-		JWTSecret:      getEnv("JWT_SECRET", "im-system-jwt-secret-key"),
-		JWTExpire:      7 * 24 * time.Hour,
-		JWTRefreshExp:  30 * 24 * time.Hour,
-		PingInterval:   30 * time.Second,
-		PongTimeout:    60 * time.Second,
-		MetricsPort:    9090,
+
+		StorageProvider: getEnv("STORAGE_PROVIDER", "minio"),
+		StorageEndpoint: getEnv("STORAGE_ENDPOINT", ""),
+		StorageRegion:   getEnv("STORAGE_REGION", ""),
+		StorageLocalDir: getEnv("STORAGE_LOCAL_DIR", "./data/files"),
+
+		AutoMigrate: getEnv("AUTO_MIGRATE", defaultAutoMigrate) == "true",
+
+		MongoChangeStream: getEnv("MONGO_CHANGE_STREAM", "false") == "true",
+
+		SearchBackend:         getEnv("SEARCH_BACKEND", "mongo"),
+		ElasticsearchURL:      getEnv("ELASTICSEARCH_URL", ""),
+		ElasticsearchIndex:    getEnv("ELASTICSEARCH_INDEX", "im_messages"),
+		ElasticsearchUsername: getEnv("ELASTICSEARCH_USERNAME", ""),
+		ElasticsearchPassword: getEnv("ELASTICSEARCH_PASSWORD", ""),
+		ElasticsearchAnalyzer: getEnv("ELASTICSEARCH_ANALYZER", "standard"),
+
+		MessageBroker:          getEnv("MESSAGE_BROKER", "redis"),
+		KafkaBrokers:           splitEnvList(getEnv("KAFKA_BROKERS", "")),
+		KafkaTopicPrefix:       getEnv("KAFKA_TOPIC_PREFIX", "im.node."),
+		KafkaReplicationFactor: int(getEnvInt64("KAFKA_REPLICATION_FACTOR", 1)),
+		KafkaMaxReplayAge:      time.Duration(getEnvInt64("KAFKA_MAX_REPLAY_SECONDS", 300)) * time.Second,
+
+		OpenAPIStrict: getEnv("OPENAPI_STRICT", "false") == "true",
+
+		AdminUserIDs: splitEnvList(getEnv("ADMIN_USER_IDS", "")),
+		RBACCacheTTL: time.Duration(getEnvInt64("RBAC_CACHE_SECONDS", 300)) * time.Second,
+
+		AllowOrigins:  splitEnvList(getEnv("ALLOW_ORIGINS", defaultOrigins)),
+		CookieSession: getEnv("COOKIE_SESSION", "false") == "true",
+		CookieSecure:  getEnv("COOKIE_SECURE", defaultCookieSecure) == "true",
+		CookieDomain:  getEnv("COOKIE_DOMAIN", ""),
+
+		TrustedProxies: splitEnvList(getEnv("TRUSTED_PROXIES", "")),
+
+		RegionDefault:      getEnv("REGION_DEFAULT", "default"),
+		RegionTenants:      getEnv("REGION_TENANTS", ""),
+		RegionCrossRules:   getEnv("REGION_CROSS_RULES", ""),
+		RegionMongoURIs:    getEnv("REGION_MONGO_URIS", ""),
+		RegionMinioBuckets: getEnv("REGION_MINIO_BUCKETS", ""),
+
+		UserSearchMode:      getEnv("USER_SEARCH_MODE", "exact"),
+		UserSearchRateLimit: int(getEnvInt64("USER_SEARCH_RATE_LIMIT", 30)),
+
+		GuestTTL:       time.Duration(getEnvInt64("GUEST_TTL_HOURS", 24)) * time.Hour,
+		GuestAgentIDs:  splitEnvList(getEnv("GUEST_AGENT_IDS", "")),
+		GuestGroupIDs:  splitEnvList(getEnv("GUEST_GROUP_IDS", "")),
+		GuestRateLimit: int(getEnvInt64("GUEST_RATE_LIMIT", 10)),
+
+		CSAgentMaxConcurrent: int(getEnvInt64("CS_AGENT_MAX_CONCURRENT", 5)),
+		CSMaxQueue:           int(getEnvInt64("CS_MAX_QUEUE", 1000)),
+
+		ReservedNames:  splitEnvList(getEnv("RESERVED_NAMES", "")),
+		UniqueNickname: getEnv("UNIQUE_NICKNAME", "false") == "true",
+		RenameCooldown: time.Duration(getEnvInt64("RENAME_COOLDOWN_HOURS", 24)) * time.Hour,
+
+		UserImportMaxRows: int(getEnvInt64("USER_IMPORT_MAX_ROWS", 1000)),
+
+		ReminderMaxPerUser: int(getEnvInt64("REMINDER_MAX_PER_USER", 100)),
+
+		GroupDigestDefaultTimezone: getEnv("GROUP_DIGEST_DEFAULT_TIMEZONE", "UTC"),
+
+		PushEnabled: getEnv("PUSH_ENABLED", "false") == "true",
+
+		WebPushPublicKey:  getEnv("WEBPUSH_VAPID_PUBLIC_KEY", ""),
+		WebPushPrivateKey: getEnv("WEBPUSH_VAPID_PRIVATE_KEY", ""),
+		WebPushSubject:    getEnv("WEBPUSH_SUBJECT", ""),
+
+		UsageMetering:      getEnv("USAGE_METERING", "true") == "true",
+		UsageRetentionDays: int(getEnvInt64("USAGE_RETENTION_DAYS", 90)),
+
+		AutoReplyWindow:     time.Duration(getEnvInt64("AUTO_REPLY_WINDOW_MINUTES", 1440)) * time.Minute,
+		AutoReplyMaxPerHour: int(getEnvInt64("AUTO_REPLY_MAX_PER_HOUR", 100)),
+
+		ExportMaxMessages: int(getEnvInt64("EXPORT_MAX_MESSAGES", 10000)),
+		ExportPDFCommand:  strings.Fields(getEnv("EXPORT_PDF_COMMAND", "")),
+
+		SummaryEndpoint:      getEnv("SUMMARY_ENDPOINT", ""),
+		SummaryAPIKey:        getEnv("SUMMARY_API_KEY", ""),
+		SummaryMaxMessages:   int(getEnvInt64("SUMMARY_MAX_MESSAGES", 100)),
+		SummaryMaxPerHour:    int(getEnvInt64("SUMMARY_MAX_PER_HOUR", 10)),
+		SummaryTimeout:       time.Duration(getEnvInt64("SUMMARY_TIMEOUT_SECONDS", 30)) * time.Second,
+		SummaryRedactPattern: getEnv("SUMMARY_REDACT_PATTERN", ""),
+
+		SuggestionEndpoint: getEnv("SUGGESTION_ENDPOINT", ""),
+		SuggestionAPIKey:   getEnv("SUGGESTION_API_KEY", ""),
+		SuggestionTimeout:  time.Duration(getEnvInt64("SUGGESTION_TIMEOUT_MS", 2000)) * time.Millisecond,
+
+		ModerationKeywords:      splitEnvList(getEnv("MODERATION_KEYWORDS", "")),
+		ModerationKeywordFile:   getEnv("MODERATION_KEYWORD_FILE", ""),
+		ModerationKeywordAction: getEnv("MODERATION_KEYWORD_ACTION", "mask"),
+		ModerationHTTPURL:       getEnv("MODERATION_HTTP_URL", ""),
+		ModerationHTTPAPIKey:    getEnv("MODERATION_HTTP_API_KEY", ""),
+		ModerationHTTPAction:    getEnv("MODERATION_HTTP_ACTION", "reject"),
+		ModerationTimeout:       time.Duration(getEnvInt64("MODERATION_TIMEOUT_MS", 2000)) * time.Millisecond,
+		ModerationFailClosed:    getEnv("MODERATION_FAIL_CLOSED", "false") == "true",
+
+		GroupEventBatchThreshold: int(getEnvInt64("GROUP_EVENT_BATCH_THRESHOLD", 100)),
+		GroupEventBatchWindow:    time.Duration(getEnvInt64("GROUP_EVENT_BATCH_WINDOW_SECONDS", 5)) * time.Second,
+		GroupEventLargeThreshold: int(getEnvInt64("GROUP_EVENT_LARGE_THRESHOLD", 1000)),
+		GroupEventLegacyExtra:    getEnv("GROUP_EVENT_LEGACY_EXTRA", "true") == "true",
+
+		GroupDismissGracePeriod: time.Duration(getEnvInt64("GROUP_DISMISS_GRACE_HOURS", 168)) * time.Hour,
+		GroupExpiryWarnBefore:   time.Duration(getEnvInt64("GROUP_EXPIRY_WARN_MINUTES", 60)) * time.Minute,
+
+		FileProxyDownload:    getEnv("FILE_PROXY_DOWNLOAD", "false") == "true",
+		FileURLBindIP:        getEnv("FILE_URL_BIND_IP", "false") == "true",
+		FileRefererWhitelist: splitEnvList(getEnv("FILE_REFERER_WHITELIST", "")),
+		FileURLSecret:        getEnv("FILE_URL_SECRET", ""),
+
+		FileRetentionSingleDays: int(getEnvInt64("FILE_RETENTION_SINGLE_DAYS", 0)),
+		FileRetentionGroupDays:  int(getEnvInt64("FILE_RETENTION_GROUP_DAYS", 0)),
+
+		CDNDomain:       getEnv("CDN_DOMAIN", ""),
+		CDNSignProvider: getEnv("CDN_SIGN_PROVIDER", ""),
+		CDNSignKey:      getEnv("CDN_SIGN_KEY", ""),
+		CDNSignKeyID:    getEnv("CDN_SIGN_KEY_ID", ""),
+		CDNSignExpiry:   time.Duration(getEnvInt64("CDN_SIGN_EXPIRY_SECONDS", 3600)) * time.Second,
+
+		JWTSecret:     getEnv("JWT_SECRET", "im-system-jwt-secret-key"),
+		JWTExpire:     7 * 24 * time.Hour,
+		JWTRefreshExp: 30 * 24 * time.Hour,
+
+		JWTPreviousSecrets:      splitEnvList(getEnv("JWT_PREVIOUS_SECRETS", "")),
+		JWTPreviousSecretsUntil: getEnv("JWT_PREVIOUS_SECRETS_UNTIL", ""),
+
+		PingInterval:  30 * time.Second,
+		PongTimeout:   60 * time.Second,
+		MetricsPort:   9090,
+		GRPCPort:      int(getEnvInt64("GRPC_PORT", 0)),
+		GRPCAuthToken: getEnv("GRPC_AUTH_TOKEN", ""),
+
+		AuthProvider:                  getEnv("AUTH_PROVIDER", "jwt"),
+		AuthIntrospectionURL:          getEnv("AUTH_INTROSPECTION_URL", ""),
+		AuthIntrospectionClientID:     getEnv("AUTH_INTROSPECTION_CLIENT_ID", ""),
+		AuthIntrospectionClientSecret: getEnv("AUTH_INTROSPECTION_CLIENT_SECRET", ""),
+		AuthIntrospectionUserClaim:    getEnv("AUTH_INTROSPECTION_USER_CLAIM", "sub"),
+		AuthIntrospectionCacheTTL:     time.Duration(getEnvInt64("AUTH_INTROSPECTION_CACHE_SECONDS", 60)) * time.Second,
+		AuthAPIKeys:                   getEnv("AUTH_API_KEYS", ""),
+
+		HeartbeatMin:       time.Duration(getEnvInt64("HEARTBEAT_MIN_SECONDS", 10)) * time.Second,
+		HeartbeatMax:       time.Duration(getEnvInt64("HEARTBEAT_MAX_SECONDS", 120)) * time.Second,
+		HeartbeatMaxMissed: int(getEnvInt64("HEARTBEAT_MAX_MISSED", 2)),
+
+		MaxClientSkew: time.Duration(getEnvInt64("MAX_CLIENT_SKEW_SECONDS", 300)) * time.Second,
+
+		LoginRateLimit:    int(getEnvInt64("LOGIN_RATE_LIMIT", 10)),
+		RegisterRateLimit: int(getEnvInt64("REGISTER_RATE_LIMIT", 5)),
+		UploadRateLimit:   int(getEnvInt64("UPLOAD_RATE_LIMIT", 60)),
+		MessageRateLimit:  float64(getEnvInt64("MESSAGE_RATE_LIMIT", 20)),
+		MessageRateBurst:  int(getEnvInt64("MESSAGE_RATE_BURST", 40)),
+
+		MessageMaxFuture:       time.Duration(getEnvInt64("MESSAGE_MAX_FUTURE_SECONDS", 300)) * time.Second,
+		MessageMaxPast:         time.Duration(getEnvInt64("MESSAGE_MAX_PAST_DAYS", 365)) * 24 * time.Hour,
+		MessageClockQuarantine: getEnv("MESSAGE_CLOCK_QUARANTINE", "true") == "true",
+
+		MinClientVersions: getEnv("MIN_CLIENT_VERSIONS", ""),
+
+		EphemeralMaxBytes: int(getEnvInt64("EPHEMERAL_MAX_BYTES", 4096)),
+		EphemeralRate:     int(getEnvInt64("EPHEMERAL_RATE", 10)),
+		EphemeralBurst:    int(getEnvInt64("EPHEMERAL_BURST", 20)),
+		TypingInterval:    time.Duration(getEnvInt64("TYPING_INTERVAL_MS", 3000)) * time.Millisecond,
+
+		FanoutMessagesPerSecond: getEnvInt64("FANOUT_MESSAGES_PER_SECOND", 20000),
+		FanoutBytesPerSecond:    getEnvInt64("FANOUT_BYTES_PER_SECOND", 20<<20),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     int(getEnvInt64("SMTP_PORT", 587)),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", ""),
+
+		OfflineBootstrapThreshold:  int(getEnvInt64("OFFLINE_BOOTSTRAP_THRESHOLD", 500)),
+		OfflineBootstrapKeepRecent: int(getEnvInt64("OFFLINE_BOOTSTRAP_KEEP_RECENT", 20)),
+
+		EmailDigestOfflineHours: int(getEnvInt64("EMAIL_DIGEST_OFFLINE_HOURS", 72)),
+		EmailDigestLinkBase:     getEnv("EMAIL_DIGEST_LINK_BASE", "imapp://"),
+		EmailDigestTemplate:     getEnv("EMAIL_DIGEST_TEMPLATE", ""),
+
+		BridgeSlackBotToken:      getEnv("BRIDGE_SLACK_BOT_TOKEN", ""),
+		BridgeSlackSigningSecret: getEnv("BRIDGE_SLACK_SIGNING_SECRET", ""),
+		BridgeMatrixHomeserver:   getEnv("BRIDGE_MATRIX_HOMESERVER", ""),
+		BridgeMatrixASToken:      getEnv("BRIDGE_MATRIX_AS_TOKEN", ""),
+		BridgeMatrixHSToken:      getEnv("BRIDGE_MATRIX_HS_TOKEN", ""),
+		BridgeMatrixBotUserID:    getEnv("BRIDGE_MATRIX_BOT_USER_ID", ""),
+		BridgeBotUserID:          getEnv("BRIDGE_BOT_USER_ID", "bridge"),
+
+		WSBatchWindowMs:    getEnvInt64("WS_BATCH_WINDOW_MS", 5),
+		WSBatchMaxMessages: int(getEnvInt64("WS_BATCH_MAX_MESSAGES", 64)),
+		WSBatchMaxBytes:    int(getEnvInt64("WS_BATCH_MAX_BYTES", 64<<10)),
+
+		WSAckTimeoutMs:   getEnvInt64("WS_ACK_TIMEOUT_MS", 10000),
+		WSAckMaxRetries:  int(getEnvInt64("WS_ACK_MAX_RETRIES", 3)),
+		WSAckMaxInFlight: int(getEnvInt64("WS_ACK_MAX_INFLIGHT", 256)),
+
+		WSOfflineReplayBatch: int(getEnvInt64("WS_OFFLINE_REPLAY_BATCH", 100)),
+
+		WSMaxConnections:        int(getEnvInt64("WS_MAX_CONNECTIONS", 100000)),
+		WSMaxConnectionsPerUser: int(getEnvInt64("WS_MAX_CONNECTIONS_PER_USER", 5)),
+		WSMaxConnectionsPerIP:   int(getEnvInt64("WS_MAX_CONNECTIONS_PER_IP", 200)),
+		WSLimitExemptUsers:      splitEnvList(getEnv("WS_LIMIT_EXEMPT_USERS", "")),
+		WSLimitExemptIPs:        splitEnvList(getEnv("WS_LIMIT_EXEMPT_IPS", "")),
+
+		LoadShedCPUPercent:   int(getEnvInt64("LOAD_SHED_CPU_PERCENT", 85)),
+		LoadShedQueuePercent: int(getEnvInt64("LOAD_SHED_QUEUE_PERCENT", 50)),
+		LoadShedPercent:      int(getEnvInt64("LOAD_SHED_PERCENT", 50)),
+		LoadShedRetryAfter:   time.Duration(getEnvInt64("LOAD_SHED_RETRY_AFTER_MAX_SECONDS", 30)) * time.Second,
+
+		RecordDir:           getEnv("RECORD_DIR", ""),
+		RecordSamplePercent: int(getEnvInt64("RECORD_SAMPLE_PERCENT", 1)),
+		RecordUserIDs:       splitEnvList(getEnv("RECORD_USER_IDS", "")),
+		RecordMaxFileMB:     getEnvInt64("RECORD_MAX_FILE_MB", 100),
+
+		LatencyWindow:         int(getEnvInt64("LATENCY_WINDOW", 2048)),
+		LatencySamplePermille: int(getEnvInt64("LATENCY_SAMPLE_PERMILLE", 0)),
+
+		TracingEndpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		TracingProtocol:    getEnv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc"),
+		TracingInsecure:    getEnv("OTEL_EXPORTER_OTLP_INSECURE", "false") == "true",
+		TracingServiceName: getEnv("OTEL_SERVICE_NAME", "im-gateway"),
+		TracingSampleRatio: getEnvFloat64("OTEL_TRACES_SAMPLER_ARG", 1),
+
+		ShutdownTimeout:     time.Duration(getEnvInt64("SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second,
+		ShutdownStepTimeout: time.Duration(getEnvInt64("SHUTDOWN_STEP_TIMEOUT_SECONDS", 10)) * time.Second,
+
+		IDStrategy:      getEnv("ID_STRATEGY", "ulid"),
+		SnowflakeNodeID: getEnvInt64("SNOWFLAKE_NODE_ID", 1),
 	}
 }
 
@@ -87,6 +610,8 @@ func (c *Config) ParseFlags() {
 	flag.StringVar(&c.Host, "host", c.Host, "Server host")
 	flag.IntVar(&c.Port, "port", c.Port, "Server port")
 	flag.StringVar(&c.NodeID, "node-id", c.NodeID, "Node ID")
+	flag.StringVar(&c.Env, "env", c.Env, "Environment (development, production)")
+	flag.BoolVar(&c.AutoMigrate, "auto-migrate", c.AutoMigrate, "Run database migrations on startup")
 	flag.StringVar(&c.MySQLHost, "mysql-host", c.MySQLHost, "MySQL host")
 	flag.IntVar(&c.MySQLPort, "mysql-port", c.MySQLPort, "MySQL port")
 	flag.StringVar(&c.MySQLUser, "mysql-user", c.MySQLUser, "MySQL user")
@@ -96,12 +621,24 @@ func (c *Config) ParseFlags() {
 	flag.IntVar(&c.RedisPort, "redis-port", c.RedisPort, "Redis port")
 	flag.StringVar(&c.RedisPassword, "redis-password", c.RedisPassword, "Redis password")
 	flag.StringVar(&c.MongoURI, "mongo-uri", c.MongoURI, "MongoDB URI")
+	flag.BoolVar(&c.MongoChangeStream, "mongo-change-stream", c.MongoChangeStream, "Drive message caches from MongoDB change streams (requires replica set)")
 	flag.StringVar(&c.MongoDatabase, "mongo-database", c.MongoDatabase, "MongoDB database")
 	flag.StringVar(&c.MinioEndpoint, "minio-endpoint", c.MinioEndpoint, "MinIO endpoint")
 	flag.StringVar(&c.MinioAccessKey, "minio-access-key", c.MinioAccessKey, "MinIO access key")
 	flag.StringVar(&c.MinioSecretKey, "minio-secret-key", c.MinioSecretKey, "MinIO secret key")
 	flag.StringVar(&c.MinioBucket, "minio-bucket", c.MinioBucket, "MinIO bucket")
+	flag.StringVar(&c.CDNDomain, "cdn-domain", c.CDNDomain, "CDN domain for file URLs")
+	flag.StringVar(&c.CDNSignProvider, "cdn-sign-provider", c.CDNSignProvider, "CDN URL signing provider (aliyun, cloudfront, hmac)")
+	flag.StringVar(&c.AuthProvider, "auth-provider", c.AuthProvider, "Authentication provider (jwt, introspection, apikey)")
+	flag.StringVar(&c.UserSearchMode, "user-search-mode", c.UserSearchMode, "User search mode (exact, fuzzy)")
 	flag.IntVar(&c.MetricsPort, "metrics-port", c.MetricsPort, "Metrics port")
+	flag.IntVar(&c.GRPCPort, "grpc-port", c.GRPCPort, "Internal gRPC API port (0 = disabled)")
+	flag.IntVar(&c.WSMaxConnections, "ws-max-connections", c.WSMaxConnections, "Max WebSocket connections per node (0 = unlimited)")
+	flag.IntVar(&c.WSMaxConnectionsPerUser, "ws-max-connections-per-user", c.WSMaxConnectionsPerUser, "Max WebSocket connections per user (0 = unlimited)")
+	flag.IntVar(&c.WSMaxConnectionsPerIP, "ws-max-connections-per-ip", c.WSMaxConnectionsPerIP, "Max WebSocket connections per IP (0 = unlimited)")
+	flag.IntVar(&c.LoadShedPercent, "load-shed-percent", c.LoadShedPercent, "Percentage of new WebSocket upgrades rejected when overloaded (0 = disabled)")
+	flag.StringVar(&c.IDStrategy, "id-strategy", c.IDStrategy, "ID strategy (legacy, snowflake, ulid, ksuid)")
+	flag.Int64Var(&c.SnowflakeNodeID, "snowflake-node-id", c.SnowflakeNodeID, "Snowflake node ID")
 	flag.Parse()
 }
 
@@ -112,3 +649,34 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// splitEnvList 解析逗号分隔的环境变量列表
+func splitEnvList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// getEnvInt64 获取整数环境变量，如果不存在或无法解析返回默认值
+func getEnvInt64(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+// getEnvFloat64 获取浮点数环境变量，如果不存在或无法解析返回默认值
+func getEnvFloat64(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
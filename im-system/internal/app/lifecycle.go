@@ -0,0 +1,141 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// defaultStopTimeout 单个组件停止的默认超时
+const defaultStopTimeout = 10 * time.Second
+
+// lifecycleComponent 生命周期中的组件：后台循环（run）或停止回调（stop）
+type lifecycleComponent struct {
+	name string
+	run  func(ctx context.Context)
+	stop func(ctx context.Context) error
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Lifecycle 后台组件生命周期：按注册顺序启动，关闭时按相反顺序逐个停止。
+// 后注册的组件通常依赖先注册的组件（如汇总任务依赖存储），相反顺序停止保证
+// 依赖方先写出缓冲数据，被依赖的分发器、录制、存储连接最后关闭
+type Lifecycle struct {
+	stopTimeout time.Duration
+
+	mu         sync.Mutex
+	components []*lifecycleComponent
+	ctx        context.Context // Start 传入的上下文，启动后注册的后台循环以此为父上下文
+	stopped    bool
+}
+
+// NewLifecycle 创建生命周期管理，stopTimeout 为单个组件停止的超时（0使用默认值）
+func NewLifecycle(stopTimeout time.Duration) *Lifecycle {
+	if stopTimeout <= 0 {
+		stopTimeout = defaultStopTimeout
+	}
+	return &Lifecycle{stopTimeout: stopTimeout}
+}
+
+// Go 注册后台循环：启动时在独立协程中运行 run(ctx)，run 须在 ctx 取消后写出缓冲并返回；
+// 停止时取消 ctx 并等待 run 返回
+func (l *Lifecycle) Go(name string, run func(ctx context.Context)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stopped {
+		log.Printf("Lifecycle: %s registered after stop, ignored", name)
+		return
+	}
+	c := &lifecycleComponent{name: name, run: run}
+	l.components = append(l.components, c)
+	if l.ctx != nil {
+		l.startLocked(c)
+	}
+}
+
+// OnStop 注册停止回调（关闭组件、写出缓冲），与后台循环一起按注册的相反顺序调用
+func (l *Lifecycle) OnStop(name string, stop func(ctx context.Context) error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.components = append(l.components, &lifecycleComponent{name: name, stop: stop})
+}
+
+// Start 按注册顺序启动后台循环
+func (l *Lifecycle) Start(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.ctx != nil || l.stopped {
+		return
+	}
+	l.ctx = ctx
+	for _, c := range l.components {
+		if c.run != nil {
+			l.startLocked(c)
+		}
+	}
+}
+
+// startLocked 启动后台循环（调用方持有锁）
+func (l *Lifecycle) startLocked(c *lifecycleComponent) {
+	ctx, cancel := context.WithCancel(l.ctx)
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		c.run(ctx)
+	}()
+}
+
+// Stop 按注册的相反顺序停止组件。单个组件超过超时或 ctx 结束时记录后继续停止下一个，
+// 保证存储连接等最先注册的组件总能关闭；返回各组件的错误
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	if l.stopped {
+		l.mu.Unlock()
+		return nil
+	}
+	l.stopped = true
+	components := l.components
+	l.mu.Unlock()
+
+	var errs []error
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		startedAt := time.Now()
+		if err := l.stopComponent(ctx, c); err != nil {
+			log.Printf("Lifecycle: stop %s error: %v", c.name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+			continue
+		}
+		log.Printf("Lifecycle: %s stopped in %s", c.name, time.Since(startedAt).Round(time.Millisecond))
+	}
+	return errors.Join(errs...)
+}
+
+// stopComponent 停止单个组件
+func (l *Lifecycle) stopComponent(ctx context.Context, c *lifecycleComponent) error {
+	stopCtx, cancel := context.WithTimeout(ctx, l.stopTimeout)
+	defer cancel()
+
+	if c.stop != nil {
+		return c.stop(stopCtx)
+	}
+	if c.done == nil {
+		return nil
+	}
+	c.cancel()
+	select {
+	case <-c.done:
+		return nil
+	case <-stopCtx.Done():
+		return fmt.Errorf("not stopped in time: %w", stopCtx.Err())
+	}
+}
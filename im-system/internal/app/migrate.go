@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/migration"
+	"github.com/d60-lab/im-system/pkg/database"
+)
+
+// 迁移子命令动作
+const (
+	MigrateUp     = "up"
+	MigrateDown   = "down"
+	MigrateStatus = "status"
+)
+
+// RunMigration 执行数据库迁移子命令（up: 执行全部迁移, down: 回滚一次MySQL迁移, status: 查看迁移状态）
+func RunMigration(config *Config, action string) error {
+	db, err := database.NewMySQL(&database.MySQLConfig{
+		Host:     config.MySQLHost,
+		Port:     config.MySQLPort,
+		User:     config.MySQLUser,
+		Password: config.MySQLPassword,
+		Database: config.MySQLDatabase,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to MySQL: %w", err)
+	}
+
+	mongoClient, err := database.NewMongoDB(&database.MongoConfig{
+		URI:      config.MongoURI,
+		Database: config.MongoDatabase,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	defer mongoClient.Close(ctx)
+
+	migrator, err := migration.NewMigrator(db, mongoClient)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case MigrateUp:
+		return migrator.Up(ctx)
+	case MigrateDown:
+		return migrator.Down(ctx)
+	case MigrateStatus:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%-8s %-6d %-8s %s\n", s.Store, s.Version, state, s.Description)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate action %q (up, down, status)", action)
+	}
+}
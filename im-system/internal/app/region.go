@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/d60-lab/im-system/internal/migration"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/internal/service"
+	"github.com/d60-lab/im-system/pkg/database"
+	"github.com/d60-lab/im-system/pkg/objectstore"
+)
+
+// connectRegionMongo 连接其他区域的 MongoDB 并执行（或检查）迁移，未配置时返回空
+func connectRegionMongo(ctx context.Context, config *Config, db *gorm.DB) (map[string]*database.MongoClient, error) {
+	uris, err := service.ParseRegionMap(config.RegionMongoURIs, ";")
+	if err != nil {
+		return nil, fmt.Errorf("invalid REGION_MONGO_URIS: %w", err)
+	}
+
+	clients := make(map[string]*database.MongoClient, len(uris))
+	for region, uri := range uris {
+		if region == config.RegionDefault {
+			return nil, fmt.Errorf("invalid REGION_MONGO_URIS: default region %s uses MONGO_URI", region)
+		}
+		client, err := database.NewMongoDB(&database.MongoConfig{URI: uri, Database: config.MongoDatabase})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to MongoDB of region %s: %w", region, err)
+		}
+		clients[region] = client
+
+		migrator, err := migration.NewMigrator(db, client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create migrator: %w", err)
+		}
+		if config.AutoMigrate {
+			err = migrator.Up(ctx)
+		} else {
+			err = migrator.Check(ctx)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("region %s: %w", region, err)
+		}
+		log.Printf("Connected to MongoDB of region %s", region)
+	}
+	return clients, nil
+}
+
+// setupDataRegions 初始化数据驻留：消息仓库按会话区域路由到各区域的 MongoDB，未配置其他区域时不启用
+func (s *Server) setupDataRegions(groupService service.GroupService) error {
+	if len(s.regionMongo) == 0 {
+		return nil
+	}
+
+	regionConfig := service.DefaultRegionConfig()
+	regionConfig.DefaultRegion = s.config.RegionDefault
+	regionConfig.Regions = []string{s.config.RegionDefault}
+	for region := range s.regionMongo {
+		regionConfig.Regions = append(regionConfig.Regions, region)
+	}
+	sort.Strings(regionConfig.Regions)
+
+	tenants, err := service.ParseRegionMap(s.config.RegionTenants, ",")
+	if err != nil {
+		return fmt.Errorf("invalid REGION_TENANTS: %w", err)
+	}
+	rules, err := service.ParseCrossRegionRules(s.config.RegionCrossRules)
+	if err != nil {
+		return fmt.Errorf("invalid REGION_CROSS_RULES: %w", err)
+	}
+	regionConfig.TenantRegions = tenants
+	regionConfig.CrossRegion = rules
+
+	s.dataRegions = service.NewDataRegionService(regionConfig, repository.NewUserRepository(s.db), repository.NewConversationRepository(s.db), groupService, s.redis)
+
+	repos := map[string]repository.MessageRepository{s.config.RegionDefault: s.messageRepo}
+	for region, client := range s.regionMongo {
+		repos[region] = repository.NewMessageRepository(client)
+	}
+	s.messageRepo = repository.NewRegionalMessageRepository(s.config.RegionDefault, repos, s.dataRegions.ConversationRegion)
+	log.Printf("Data residency enabled, regions: %s (default %s)", strings.Join(regionConfig.Regions, ", "), s.config.RegionDefault)
+	return nil
+}
+
+// regionalFileService 各区域使用独立的对象存储，上传按用户区域选择存储桶；未启用数据驻留时返回默认存储
+func (s *Server) regionalFileService(base *service.StorageConfig, defaultService service.FileStorageService) (service.FileStorageService, error) {
+	if s.dataRegions == nil {
+		return defaultService, nil
+	}
+
+	if strings.EqualFold(base.Provider, objectstore.ProviderLocal) {
+		return nil, fmt.Errorf("data residency requires an object storage provider, got STORAGE_PROVIDER=%s", base.Provider)
+	}
+	buckets, err := service.ParseRegionMap(s.config.RegionMinioBuckets, ",")
+	if err != nil {
+		return nil, fmt.Errorf("invalid REGION_MINIO_BUCKETS: %w", err)
+	}
+
+	services := map[string]service.FileStorageService{s.config.RegionDefault: defaultService}
+	for region := range s.regionMongo {
+		endpoint, bucket, ok := strings.Cut(buckets[region], "/")
+		if !ok || endpoint == "" || bucket == "" {
+			return nil, fmt.Errorf("invalid REGION_MINIO_BUCKETS: region %s requires endpoint/bucket", region)
+		}
+		config := *base
+		config.Endpoint = endpoint
+		config.Bucket = bucket
+		config.DataRegion = region
+		svc, err := service.NewObjectStorageService(&config, s.db, s.redis)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize file storage of region %s: %w", region, err)
+		}
+		services[region] = svc
+	}
+	return service.NewRegionalStorageService(s.config.RegionDefault, services, s.dataRegions, s.db), nil
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/d60-lab/im-system/internal/gateway"
+	"github.com/d60-lab/im-system/pkg/auth"
+)
+
+// RunReplay 执行录制回放子命令：gateway replay [flags] <录制文件...>
+// 使用目标环境的 JWT 密钥为录制的用户签发 Token，目标网关须使用 jwt 认证
+func RunReplay(config *Config, args []string) error {
+	replayConfig := gateway.DefaultReplayConfig()
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	target := fs.String("target", "ws://localhost:8080/ws", "Target gateway WebSocket URL, or node=url pairs separated by commas (* for default)")
+	secret := fs.String("jwt-secret", config.JWTSecret, "JWT secret of the target environment")
+	fs.Float64Var(&replayConfig.Speed, "speed", replayConfig.Speed, "Time scale (2 = twice as fast, 0 = no delay)")
+	fs.BoolVar(&replayConfig.ShiftClientTimestamps, "shift-client-timestamps", replayConfig.ShiftClientTimestamps, "Shift client_timestamp to replay time")
+	fs.DurationVar(&replayConfig.Linger, "linger", replayConfig.Linger, "Keep connections open after the last frame")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: gateway replay [flags] <recording.jsonl...>")
+	}
+
+	targets, err := gateway.ParseReplayTargets(*target)
+	if err != nil {
+		return err
+	}
+	replayConfig.Targets = targets
+
+	jwtManager := auth.NewJWTManager(&auth.JWTConfig{
+		Secret:        *secret,
+		Issuer:        "im-system",
+		Expire:        config.JWTExpire,
+		RefreshExpire: config.JWTRefreshExp,
+	})
+	replayConfig.Token = func(frame *gateway.RecordedFrame) (string, error) {
+		return jwtManager.GenerateTokenWithOptions(frame.UserID, frame.UserID, frame.Platform, frame.DeviceID)
+	}
+
+	frames, err := gateway.LoadRecordings(fs.Args()...)
+	if err != nil {
+		return err
+	}
+	log.Printf("Replaying %d recorded events (speed: %g)", len(frames), replayConfig.Speed)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	stats, err := gateway.NewReplayer(replayConfig).Run(ctx, frames)
+	if stats != nil {
+		out, _ := json.MarshalIndent(stats, "", "  ")
+		fmt.Println(string(out))
+	}
+	return err
+}
@@ -3,11 +3,15 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"html/template"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,12 +23,24 @@ import (
 
 	_ "github.com/d60-lab/im-system/docs" // swagger docs
 	"github.com/d60-lab/im-system/internal/gateway"
+	"github.com/d60-lab/im-system/internal/grpcserver"
 	"github.com/d60-lab/im-system/internal/handler"
+	"github.com/d60-lab/im-system/internal/migration"
 	"github.com/d60-lab/im-system/internal/model"
 	"github.com/d60-lab/im-system/internal/repository"
 	"github.com/d60-lab/im-system/internal/service"
 	"github.com/d60-lab/im-system/pkg/auth"
+	"github.com/d60-lab/im-system/pkg/bridge"
+	"github.com/d60-lab/im-system/pkg/cdn"
 	"github.com/d60-lab/im-system/pkg/database"
+	"github.com/d60-lab/im-system/pkg/errcode"
+	"github.com/d60-lab/im-system/pkg/i18n"
+	"github.com/d60-lab/im-system/pkg/mailer"
+	"github.com/d60-lab/im-system/pkg/objectstore"
+	"github.com/d60-lab/im-system/pkg/ratelimit"
+	"github.com/d60-lab/im-system/pkg/tracing"
+	"github.com/d60-lab/im-system/pkg/util"
+	"github.com/d60-lab/im-system/pkg/webpush"
 )
 
 // Server 应用服务器
@@ -33,11 +49,57 @@ type Server struct {
 	db          *gorm.DB
 	redis       *redis.Client
 	mongo       *database.MongoClient
+	regionMongo map[string]*database.MongoClient // 其他区域的MongoDB（数据驻留）
+	lifecycle   *Lifecycle                       // 后台任务及组件的启动、停止顺序
 	engine      *gin.Engine
 	httpServer  *http.Server
 	connManager *gateway.ConnectionManager
 	dispatcher  gateway.MessageDispatcher
 	messageRepo repository.MessageRepository
+
+	fileService        service.FileStorageService
+	fileRetention      service.FileRetentionService
+	filePolicy         service.FileTypePolicyService
+	moderation         service.ContentModerationService
+	messageTypePolicy  service.MessageTypePolicyService
+	fileMessageService service.FileMessageService
+	maintenanceService service.MaintenanceService
+	changeListener     service.MessageChangeListener
+	reminderService    service.ReminderService
+	pollService        service.PollService
+	mentionService     service.MentionService
+
+	groupDigestService service.GroupDigestService
+	searchService      service.SearchService
+	usageService       service.UsageService
+	autoReplyService   service.AutoReplyService
+	integrationService service.IntegrationAppService
+	encryptionService  service.ConversationEncryptionService
+	groupSuccession    service.GroupSuccessionService
+	groupExpiry        service.GroupExpiryService
+	emailDigest        service.EmailDigestService
+	featureFlags       service.FeatureFlagService
+	pushExperiments    service.PushExperimentService
+	pushService        service.PushService
+	notifySettings     service.NotificationSettingsService
+	bridgeService      service.BridgeService
+	slackBridge        *bridge.SlackConnector
+	matrixBridge       *bridge.MatrixConnector
+	analytics          service.ConversationAnalyticsService
+	counters           service.ConversationCounterService
+	dataRegions        service.DataRegionService
+	messageQuarantine  service.MessageQuarantineService
+	connRegistry       *gateway.ConnectionRegistry
+	loadShedder        *gateway.LoadShedder
+	latencyTracker     *gateway.LatencyTracker
+	friendService      service.FriendService
+	e2eeService        service.E2EEService
+	presenceService    service.PresenceService
+	accountService     service.AccountService
+	guestService       service.GuestService
+	customerService    service.CustomerService
+	grpcServer         *grpcserver.Server
+	sessionService     service.SessionService
 }
 
 // NewServer 创建服务器
@@ -56,21 +118,6 @@ func NewServer(config *Config) (*Server, error) {
 	}
 	log.Println("Connected to MySQL")
 
-	// 自动迁移表结构
-	// 注意: Message 存储在 MongoDB，不在 MySQL 中创建表
-	if err := database.AutoMigrate(db,
-		&model.User{},
-		&model.Group{},
-		&model.GroupMember{},
-		&model.OfflineMessage{},
-		&model.Conversation{},
-		&model.UserConversation{},
-		&model.Device{},
-		&model.File{},
-	); err != nil {
-		return nil, fmt.Errorf("failed to auto migrate: %w", err)
-	}
-
 	// 初始化Redis
 	redisConfig := &database.RedisConfig{
 		Host:     config.RedisHost,
@@ -95,36 +142,115 @@ func NewServer(config *Config) (*Server, error) {
 	}
 	log.Println("Connected to MongoDB")
 
+	// 数据库迁移：开发环境自动迁移，生产环境要求预先执行 migrate 子命令
+	migrator, err := migration.NewMigrator(db, mongoClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrator: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	if config.AutoMigrate {
+		if err := migrator.Up(ctx); err != nil {
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	} else if err := migrator.Check(ctx); err != nil {
+		return nil, err
+	}
+
+	// 数据驻留：连接其他区域的MongoDB
+	regionMongo, err := connectRegionMongo(ctx, config, db)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建消息仓库
 	messageRepo := repository.NewMessageRepository(mongoClient)
 
-	// 确保MongoDB索引
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	if err := messageRepo.EnsureIndexes(ctx); err != nil {
-		log.Printf("Warning: Failed to ensure MongoDB indexes: %v", err)
-	}
+	// 存储连接最先注册，所有组件停止后最后关闭
+	lifecycle := NewLifecycle(config.ShutdownStepTimeout)
+	lifecycle.OnStop("storage", func(ctx context.Context) error {
+		var errs []error
+		if err := mongoClient.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("mongo: %w", err))
+		}
+		for region, client := range regionMongo {
+			if err := client.Close(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("mongo of region %s: %w", region, err))
+			}
+		}
+		if err := redisClient.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("redis: %w", err))
+		}
+		if sqlDB, err := db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("mysql: %w", err))
+			}
+		}
+		return errors.Join(errs...)
+	})
 
 	return &Server{
 		config:      config,
 		db:          db,
 		redis:       redisClient,
 		mongo:       mongoClient,
+		regionMongo: regionMongo,
+		lifecycle:   lifecycle,
 		messageRepo: messageRepo,
 	}, nil
 }
 
 // Setup 初始化服务器组件
 func (s *Server) Setup() error {
-	// 初始化JWT管理器
+	// 初始化ID生成器
+	snowflakeConfig := util.DefaultSnowflakeConfig
+	snowflakeConfig.NodeID = s.config.SnowflakeNodeID
+	util.RegisterIDGenerator(util.IDStrategySnowflake, func() (util.IDGenerator, error) {
+		return util.NewSnowflakeIDGenerator(snowflakeConfig)
+	})
+	if err := util.InitIDGenerator(s.config.IDStrategy); err != nil {
+		return fmt.Errorf("failed to init id generator: %w", err)
+	}
+
+	// 链路追踪：早于其他组件注册停止回调，关闭时最后写出缓冲的 Span
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Endpoint:    s.config.TracingEndpoint,
+		Protocol:    s.config.TracingProtocol,
+		Insecure:    s.config.TracingInsecure,
+		ServiceName: s.config.TracingServiceName,
+		NodeID:      s.config.NodeID,
+		SampleRatio: s.config.TracingSampleRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to init tracing: %w", err)
+	}
+	s.lifecycle.OnStop("tracing", shutdownTracing)
+
+	// 初始化JWT管理器：签发与验证（REST、WebSocket握手）均使用该实例
 	jwtConfig := &auth.JWTConfig{
-		Secret:        s.config.JWTSecret,
-		Issuer:        "im-system",
-		Expire:        s.config.JWTExpire,
-		RefreshExpire: s.config.JWTRefreshExp,
+		Secret:          s.config.JWTSecret,
+		Issuer:          "im-system",
+		Expire:          s.config.JWTExpire,
+		RefreshExpire:   s.config.JWTRefreshExp,
+		PreviousSecrets: s.config.JWTPreviousSecrets,
+	}
+	if s.config.JWTPreviousSecretsUntil != "" {
+		until, err := time.Parse(time.RFC3339, s.config.JWTPreviousSecretsUntil)
+		if err != nil {
+			return fmt.Errorf("invalid JWT_PREVIOUS_SECRETS_UNTIL: %w", err)
+		}
+		jwtConfig.PreviousSecretsUntil = until
 	}
 	jwtManager := auth.NewJWTManager(jwtConfig)
-	auth.InitDefaultManager(jwtConfig)
+
+	// 认证提供者：REST鉴权中间件与WebSocket握手共用
+	authenticator, err := s.newAuthenticator(jwtManager)
+	if err != nil {
+		return fmt.Errorf("failed to init authenticator: %w", err)
+	}
+	// 登录会话：强制下线（含账号禁用、注销）后，此前签发的Token在REST、WebSocket握手及刷新时均被拒绝
+	s.sessionService = service.NewSessionService(repository.NewUserRepository(s.db), s.redis, s.config.JWTRefreshExp)
+	authenticator = auth.WithRevocation(authenticator, s.sessionService)
 
 	// 初始化连接管理器
 	connConfig := &gateway.ConnectionConfig{
@@ -133,16 +259,68 @@ func (s *Server) Setup() error {
 	}
 	s.connManager = gateway.NewConnectionManager(s.config.NodeID, connConfig)
 
+	// 节点连接登记，心跳检查（每分钟）时校正，节点宕机后随TTL过期
+	s.connRegistry = gateway.NewConnectionRegistry(s.redis, s.config.NodeID, 3*time.Minute)
+	s.connManager.SetRegistry(s.connRegistry)
+
 	// 初始化服务
-	offlineService := service.NewOfflineService(s.db, s.redis, nil)
+	offlineConfig := service.DefaultOfflineServiceConfig()
+	offlineConfig.BootstrapThreshold = s.config.OfflineBootstrapThreshold
+	offlineConfig.BootstrapKeepRecent = s.config.OfflineBootstrapKeepRecent
+	offlineService := service.NewOfflineService(repository.NewOfflineMessageRepository(s.db), s.redis, offlineConfig)
 	offlineHandler := service.NewOfflineMessageHandler(offlineService)
 
+	// 初始化推送服务：保存离线消息后推送到用户设备（APNs/FCM 客户端未接入时只记录日志）
+	if s.config.PushEnabled {
+		s.pushService = service.NewPushService(
+			service.DefaultPushConfig(),
+			repository.NewDeviceRepository(s.db),
+			s.redis,
+			&service.MockAPNsClient{},
+			&service.MockFCMClient{},
+			offlineService,
+		)
+		s.pushService.SetConversationRepository(repository.NewConversationRepository(s.db))
+		s.pushService.SetUserRepository(repository.NewUserRepository(s.db))
+		// 浏览器推送：配置 VAPID 密钥后 web 平台设备经 Web Push 推送
+		if s.config.WebPushPrivateKey != "" {
+			webPush, err := webpush.NewClient(webpush.Config{
+				PublicKey:  s.config.WebPushPublicKey,
+				PrivateKey: s.config.WebPushPrivateKey,
+				Subject:    s.config.WebPushSubject,
+			}, nil)
+			if err != nil {
+				return fmt.Errorf("failed to init web push: %w", err)
+			}
+			s.pushService.SetWebPushClient(service.NewWebPushClient(webPush))
+		}
+		offlineService.SetPushNotifier(s.pushService)
+		// 用户上线后新消息经长连接送达，丢弃合并窗口中的推送
+		s.connManager.SetOnConnect(func(conn *gateway.Connection) {
+			s.pushService.UserOnline(conn.UserID)
+		})
+		log.Println("Warning: PUSH_ENABLED is set without APNs/FCM clients, push notifications are logged only")
+	}
+
 	// 初始化消息分发器
 	dispatcherConfig := &gateway.DispatcherConfig{
 		NodeID:               s.config.NodeID,
 		OnlineKeyExpire:      s.config.PongTimeout * 2,
 		PublishChannelPrefix: "im:node:",
 	}
+	if s.config.FanoutMessagesPerSecond > 0 || s.config.FanoutBytesPerSecond > 0 {
+		fanoutPace := gateway.DefaultFanoutPaceConfig()
+		fanoutPace.MessagesPerSecond = float64(s.config.FanoutMessagesPerSecond)
+		fanoutPace.BytesPerSecond = float64(s.config.FanoutBytesPerSecond)
+		dispatcherConfig.FanoutPace = fanoutPace
+	}
+	if s.config.WSAckTimeoutMs > 0 {
+		dispatcherConfig.Delivery = &gateway.DeliveryConfig{
+			AckTimeout:  time.Duration(s.config.WSAckTimeoutMs) * time.Millisecond,
+			MaxRetries:  s.config.WSAckMaxRetries,
+			MaxInFlight: s.config.WSAckMaxInFlight,
+		}
+	}
 
 	groupMemberGetter := &groupMemberGetterAdapter{}
 	s.dispatcher = gateway.NewMessageDispatcher(
@@ -151,48 +329,699 @@ func (s *Server) Setup() error {
 		groupMemberGetter,
 		offlineHandler,
 	)
+	// 跨节点消息通道：默认 Redis Pub/Sub，Kafka 下节点短暂断开期间的消息恢复后补投
+	switch s.config.MessageBroker {
+	case "", gateway.BrokerRedis:
+	case gateway.BrokerKafka:
+		kafkaConfig := gateway.DefaultKafkaBrokerConfig()
+		kafkaConfig.Brokers = s.config.KafkaBrokers
+		kafkaConfig.TopicPrefix = s.config.KafkaTopicPrefix
+		kafkaConfig.ReplicationFactor = s.config.KafkaReplicationFactor
+		kafkaConfig.MaxReplayAge = s.config.KafkaMaxReplayAge
+		broker, err := gateway.NewKafkaBroker(kafkaConfig)
+		if err != nil {
+			return fmt.Errorf("failed to init kafka broker: %w", err)
+		}
+		s.dispatcher.SetBroker(broker)
+		log.Printf("Cross-node routing via kafka: %v", s.config.KafkaBrokers)
+	default:
+		return fmt.Errorf("invalid MESSAGE_BROKER: %s", s.config.MessageBroker)
+	}
+	s.lifecycle.OnStop("dispatcher", func(ctx context.Context) error {
+		return s.dispatcher.Close()
+	})
+	s.dispatcher.SetOnNodeControl(func(action string) {
+		if action == gateway.NodeControlDrain {
+			log.Printf("Draining node %s, closed %d connections", s.config.NodeID, s.connManager.Drain())
+		}
+		if userID, ok := strings.CutPrefix(action, gateway.NodeControlKickUserPrefix); ok && s.connManager.KickUser(userID) {
+			log.Printf("Kicked connection of %s on node %s", userID, s.config.NodeID)
+		}
+	})
+
+	// 会话分析：根据分发事件增量汇总会话统计，管理后台分析查询只读汇总表
+	s.analytics = service.NewConversationAnalyticsService(repository.NewConversationStatsRepository(s.db), nil)
+	// 会话派生计数：未读、@我、文件数在分发时增量维护
+	s.counters = service.NewConversationCounterService(s.redis)
+	// 通知设置：免打扰会话的聊天消息在长连接上以低优先级投递
+	s.notifySettings = service.NewNotificationSettingsService(repository.NewUserRepository(s.db), repository.NewConversationRepository(s.db))
+	s.dispatcher.SetMuteChecker(s.notifySettings)
+	s.dispatcher.SetOnDispatch(func(ctx context.Context, msg *model.Message) {
+		s.analytics.Record(ctx, msg)
+		s.counters.Record(ctx, msg)
+		if s.fileRetention != nil {
+			s.fileRetention.Record(ctx, msg)
+		}
+		// 外部平台桥接：异步转发，不阻塞分发
+		if s.bridgeService != nil {
+			go func() {
+				bridgeCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				if err := s.bridgeService.HandleMessage(bridgeCtx, msg); err != nil {
+					log.Printf("bridge message %s error: %v", msg.MessageID, err)
+				}
+			}()
+		}
+	})
 
 	// 初始化群组服务
-	groupService := service.NewGroupService(s.db, s.redis, &messageDispatcherAdapter{dispatcher: s.dispatcher})
+	groupEventPolicy := service.DefaultGroupEventPolicy()
+	groupEventPolicy.BatchThreshold = s.config.GroupEventBatchThreshold
+	groupEventPolicy.BatchWindow = s.config.GroupEventBatchWindow
+	groupEventPolicy.LargeGroupThreshold = s.config.GroupEventLargeThreshold
+	groupEventPolicy.LegacyExtra = s.config.GroupEventLegacyExtra
+	groupService := service.NewGroupService(repository.NewGroupRepository(s.db), s.redis, &messageDispatcherAdapter{dispatcher: s.dispatcher}, groupEventPolicy)
 	groupMemberGetter.groupService = groupService
 
+	// 初始化群主继任服务（群主账号禁用/注销时移交群主）
+	successionConfig := service.DefaultGroupSuccessionConfig()
+	successionConfig.DismissGracePeriod = s.config.GroupDismissGracePeriod
+	successionConfig.LegacyExtra = s.config.GroupEventLegacyExtra
+	s.groupSuccession = service.NewGroupSuccessionService(
+		repository.NewGroupRepository(s.db),
+		repository.NewUserRepository(s.db),
+		s.redis,
+		&messageDispatcherAdapter{dispatcher: s.dispatcher},
+		successionConfig,
+	)
+
+	// 初始化临时群到期服务（到期前提醒，到期自动解散）
+	expiryConfig := service.DefaultGroupExpiryConfig()
+	expiryConfig.WarnBefore = s.config.GroupExpiryWarnBefore
+	expiryConfig.LegacyExtra = s.config.GroupEventLegacyExtra
+	s.groupExpiry = service.NewGroupExpiryService(
+		repository.NewGroupRepository(s.db),
+		s.redis,
+		&messageDispatcherAdapter{dispatcher: s.dispatcher},
+		expiryConfig,
+	)
+
+	// 长期离线用户的未读消息邮件摘要
+	if s.config.SMTPHost != "" {
+		digestConfig := service.DefaultEmailDigestConfig()
+		digestConfig.OfflineAfter = time.Duration(s.config.EmailDigestOfflineHours) * time.Hour
+		digestConfig.LinkBase = s.config.EmailDigestLinkBase
+		if s.config.EmailDigestTemplate != "" {
+			tmpl, err := template.ParseFiles(s.config.EmailDigestTemplate)
+			if err != nil {
+				return fmt.Errorf("invalid EMAIL_DIGEST_TEMPLATE: %w", err)
+			}
+			digestConfig.Template = tmpl
+		}
+		sender := mailer.NewSMTPSender(mailer.SMTPConfig{
+			Host:     s.config.SMTPHost,
+			Port:     s.config.SMTPPort,
+			Username: s.config.SMTPUsername,
+			Password: s.config.SMTPPassword,
+			From:     s.config.SMTPFrom,
+		})
+		emailDigest, err := service.NewEmailDigestService(
+			repository.NewOfflineMessageRepository(s.db),
+			offlineService,
+			repository.NewUserRepository(s.db),
+			groupService,
+			s.dispatcher,
+			s.redis,
+			sender,
+			digestConfig,
+		)
+		if err != nil {
+			return err
+		}
+		s.emailDigest = emailDigest
+	}
+
+	// 数据驻留：消息按会话区域存储到对应区域的MongoDB
+	if err := s.setupDataRegions(groupService); err != nil {
+		return err
+	}
+
+	// 消息时间校验：超出范围的消息转入隔离集合（审核放行时绕过校验写入）
+	quarantineRepo := repository.NewMessageQuarantineRepository(s.mongo)
+	s.messageQuarantine = service.NewMessageQuarantineService(quarantineRepo, s.messageRepo)
+	s.messageRepo = service.NewClockGuardMessageRepository(s.messageRepo, quarantineRepo, &service.MessageClockGuardConfig{
+		MaxFuture:  s.config.MessageMaxFuture,
+		MaxPast:    s.config.MessageMaxPast,
+		Quarantine: s.config.MessageClockQuarantine,
+	})
+
 	// 初始化消息服务（使用MongoDB）
-	messageService := service.NewMessageService(s.messageRepo, groupService)
+	messageService := service.NewMessageService(s.messageRepo, groupService, s.redis)
+	patchNotifier := service.NewMessagePatchNotifier(groupService, &messageDispatcherAdapter{dispatcher: s.dispatcher})
+	messageService.SetPatchNotifier(patchNotifier)
+	messageService.SetCounters(s.counters)
+	messageService.SetPendingQueues(offlineService, s.pushService)
+	if s.dataRegions != nil {
+		messageService.SetDataRegions(s.dataRegions)
+	}
+	// 会话状态：保存消息时更新会话最后一条消息、创建参与者的用户会话并累加未读数（WebSocket 消息在分发前检查通过后更新）
+	conversationState := service.NewConversationStateUpdater(repository.NewConversationRepository(s.db), groupService)
+	messageService.SetConversationState(conversationState)
 	messageSaver := &messageSaverAdapter{messageService: messageService}
 
+	// 消息变更流：统一驱动热缓存和会话更新通知
+	if s.config.MongoChangeStream {
+		listenerConfig := service.DefaultMessageChangeListenerConfig()
+		listenerConfig.NodeID = s.config.NodeID
+		s.changeListener = service.NewMessageChangeListener(listenerConfig, s.messageRepo, s.redis)
+		messageService.UseChangeStream(s.changeListener)
+		s.changeListener.Subscribe(service.NewConversationUpdateNotifier(groupService, &messageDispatcherAdapter{dispatcher: s.dispatcher}).HandleChange)
+	}
+
+	// 消息全文检索：默认使用 MongoDB 文本索引，Elasticsearch 由变更流增量写入索引
+	switch s.config.SearchBackend {
+	case "", "mongo":
+		s.searchService = service.NewSearchService(s.messageRepo, groupService)
+	case "elasticsearch":
+		if s.changeListener == nil {
+			return fmt.Errorf("SEARCH_BACKEND=elasticsearch requires MONGO_CHANGE_STREAM=true")
+		}
+		searchIndex, err := repository.NewElasticsearchMessageIndex(&repository.ElasticsearchConfig{
+			URL:      s.config.ElasticsearchURL,
+			Index:    s.config.ElasticsearchIndex,
+			Username: s.config.ElasticsearchUsername,
+			Password: s.config.ElasticsearchPassword,
+			Analyzer: s.config.ElasticsearchAnalyzer,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to init elasticsearch: %w", err)
+		}
+		ensureCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = searchIndex.EnsureIndex(ensureCtx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to init elasticsearch index: %w", err)
+		}
+		s.changeListener.Subscribe(searchIndex.HandleChange)
+		s.searchService = service.NewSearchService(searchIndex, groupService)
+		log.Printf("Message search via elasticsearch: %s", s.config.ElasticsearchURL)
+	default:
+		return fmt.Errorf("invalid SEARCH_BACKEND: %s", s.config.SearchBackend)
+	}
+
 	// 初始化文件存储服务
 	storageConfig := &service.StorageConfig{
-		Provider:  "minio",
-		Endpoint:  s.config.MinioEndpoint,
+		Provider:  s.config.StorageProvider,
+		Endpoint:  s.config.StorageEndpoint,
 		AccessKey: s.config.MinioAccessKey,
 		SecretKey: s.config.MinioSecretKey,
 		Bucket:    s.config.MinioBucket,
+		Region:    s.config.StorageRegion,
 		UseSSL:    s.config.MinioUseSSL,
+		LocalDir:  s.config.StorageLocalDir,
+
+		AccessControl:    s.fileAccessControl(),
+		URLSigningSecret: s.config.FileURLSecret,
+		ProxyURLPrefix:   "/api/file/proxy",
+
+		CDNDomain: s.config.CDNDomain,
+		CDNSign: &cdn.Config{
+			Provider: s.config.CDNSignProvider,
+			Key:      s.config.CDNSignKey,
+			KeyID:    s.config.CDNSignKeyID,
+			Expiry:   s.config.CDNSignExpiry,
+		},
+	}
+	if storageConfig.Endpoint == "" && strings.EqualFold(storageConfig.Provider, objectstore.ProviderMinio) {
+		storageConfig.Endpoint = s.config.MinioEndpoint
+	}
+	if s.config.CDNDomain != "" && s.config.CDNSignProvider == "" {
+		log.Println("Warning: CDN_DOMAIN is set without CDN_SIGN_PROVIDER, file URLs on the CDN are not signed")
 	}
-	fileService, err := service.NewMinioStorageService(storageConfig, s.db, s.redis)
+	if storageConfig.URLSigningSecret == "" {
+		storageConfig.URLSigningSecret = s.config.JWTSecret
+	}
+	fileService, err := service.NewObjectStorageService(storageConfig, s.db, s.redis)
 	if err != nil {
 		log.Printf("Warning: Failed to initialize file storage service: %v", err)
 		fileService = nil
 	} else {
-		log.Println("File storage service initialized")
+		log.Printf("File storage service initialized (provider: %s)", storageConfig.Provider)
+		if fileService, err = s.regionalFileService(storageConfig, fileService); err != nil {
+			return err
+		}
+	}
+
+	// 会话加密：维护加密标记和密钥版本，加密会话中拒绝明文消息
+	s.encryptionService = service.NewConversationEncryptionService(repository.NewConversationRepository(s.db), groupService, &messageDispatcherAdapter{dispatcher: s.dispatcher})
+
+	// 消息类型策略：按会话类别和发送者角色限制允许发送的聊天消息类型（全局、租户、群组三级）
+	s.messageTypePolicy = service.NewMessageTypePolicyService(s.redis, groupService)
+	tenantUsers := repository.NewUserRepository(s.db)
+	s.messageTypePolicy.SetTenantResolver(func(ctx context.Context, userID string) (string, error) {
+		user, err := tenantUsers.FindByID(ctx, userID)
+		if err != nil || user == nil {
+			return "", err
+		}
+		return user.TenantID, nil
+	})
+
+	// 内容审核：单聊、群聊消息及上传文件在保存前审核，命中记录写入违规记录表
+	moderation, err := s.newContentModeration()
+	if err != nil {
+		return fmt.Errorf("failed to init content moderation: %w", err)
+	}
+	s.moderation = moderation
+
+	// 初始化文件消息服务
+	var fileMessageService service.FileMessageService
+	if fileService != nil {
+		s.fileService = fileService
+		s.filePolicy = service.NewFileTypePolicyService(s.redis, groupService)
+		fileService.SetFileTypePolicy(s.filePolicy)
+		if s.moderation != nil {
+			fileService.SetContentModeration(s.moderation)
+		}
+		retentionConfig := service.DefaultFileRetentionConfig()
+		retentionConfig.SingleChat = time.Duration(s.config.FileRetentionSingleDays) * 24 * time.Hour
+		retentionConfig.GroupChat = time.Duration(s.config.FileRetentionGroupDays) * 24 * time.Hour
+		s.fileRetention = service.NewFileRetentionService(s.db, fileService, retentionConfig)
+		fileMessageService = service.NewFileMessageService(
+			fileService,
+			messageService,
+			groupService,
+			&messageDispatcherAdapter{dispatcher: s.dispatcher},
+			s.redis,
+		)
+		fileMessageService.SetEncryptionService(s.encryptionService)
+		fileMessageService.SetMessageTypePolicy(s.messageTypePolicy)
+		fileMessageService.SetUserRepository(repository.NewUserRepository(s.db))
+		s.fileMessageService = fileMessageService
+	}
+
+	// 初始化消息提醒服务
+	reminderConfig := service.DefaultReminderConfig()
+	reminderConfig.MaxPendingPerUser = s.config.ReminderMaxPerUser
+	s.reminderService = service.NewReminderService(
+		repository.NewReminderRepository(s.db),
+		messageService,
+		groupService,
+		&messageDispatcherAdapter{dispatcher: s.dispatcher},
+		reminderConfig,
+	)
+
+	// 初始化提及服务：群消息的 @群角色、@提及组在保存时按当前群成员解析
+	s.mentionService = service.NewMentionService(
+		repository.NewMentionGroupRepository(s.db),
+		repository.NewGroupRepository(s.db),
+		groupService,
+		nil,
+	)
+	messageService.SetMentionResolver(s.mentionService)
+
+	// 初始化群活动每日摘要服务
+	groupDigestConfig := service.DefaultGroupDigestConfig()
+	groupDigestConfig.DefaultTimezone = s.config.GroupDigestDefaultTimezone
+	s.groupDigestService = service.NewGroupDigestService(
+		repository.NewGroupDigestRepository(s.db),
+		s.messageRepo,
+		repository.NewMessagePinRepository(s.db),
+		groupService,
+		&messageDispatcherAdapter{dispatcher: s.dispatcher},
+		groupDigestConfig,
+	)
+
+	// 初始化群投票服务
+	s.pollService = service.NewPollService(
+		repository.NewPollRepository(s.db),
+		messageService,
+		groupService,
+		&messageDispatcherAdapter{dispatcher: s.dispatcher},
+		patchNotifier,
+		nil,
+	)
+
+	// 初始化自动回复服务
+	autoReplyConfig := service.DefaultAutoReplyConfig()
+	autoReplyConfig.Window = s.config.AutoReplyWindow
+	autoReplyConfig.MaxPerHour = s.config.AutoReplyMaxPerHour
+	s.autoReplyService = service.NewAutoReplyService(
+		repository.NewAutoReplyRepository(s.db),
+		messageService,
+		&messageDispatcherAdapter{dispatcher: s.dispatcher},
+		s.redis,
+		autoReplyConfig,
+	)
+
+	// 外部平台桥接：群组与 Slack 频道 / Matrix 房间双向同步消息
+	if s.config.BridgeSlackBotToken != "" || s.config.BridgeMatrixASToken != "" {
+		bridgeConfig := service.DefaultBridgeConfig()
+		bridgeConfig.BotUserID = s.config.BridgeBotUserID
+		s.bridgeService = service.NewBridgeService(
+			repository.NewBridgeRepository(s.db),
+			repository.NewUserRepository(s.db),
+			messageService,
+			groupService,
+			&messageDispatcherAdapter{dispatcher: s.dispatcher},
+			s.redis,
+			bridgeConfig,
+		)
+		if s.config.BridgeSlackBotToken != "" {
+			s.slackBridge = bridge.NewSlackConnector(&bridge.SlackConfig{
+				BotToken:      s.config.BridgeSlackBotToken,
+				SigningSecret: s.config.BridgeSlackSigningSecret,
+			})
+			s.bridgeService.SetConnector(s.slackBridge)
+		}
+		if s.config.BridgeMatrixASToken != "" {
+			s.matrixBridge = bridge.NewMatrixConnector(&bridge.MatrixConfig{
+				Homeserver: s.config.BridgeMatrixHomeserver,
+				ASToken:    s.config.BridgeMatrixASToken,
+				HSToken:    s.config.BridgeMatrixHSToken,
+				BotUserID:  s.config.BridgeMatrixBotUserID,
+			})
+			s.bridgeService.SetConnector(s.matrixBridge)
+		}
 	}
 
 	// 初始化WebSocket处理器
+	minClientVersions, err := gateway.ParseMinClientVersions(s.config.MinClientVersions)
+	if err != nil {
+		return fmt.Errorf("invalid MIN_CLIENT_VERSIONS: %w", err)
+	}
 	handlerConfig := &gateway.HandlerConfig{
 		NodeID:       s.config.NodeID,
 		PingInterval: s.config.PingInterval,
 		PongTimeout:  s.config.PongTimeout,
+		AllowOrigins: s.config.AllowOrigins,
+		Heartbeat:    s.heartbeatConfig(),
+
+		MaxClientSkew:     s.config.MaxClientSkew,
+		MinClientVersions: minClientVersions,
+		MessageRate:       s.config.MessageRateLimit,
+		MessageBurst:      s.config.MessageRateBurst,
+		Ephemeral: &gateway.EphemeralConfig{
+			MaxSize: s.config.EphemeralMaxBytes,
+			Rate:    float64(s.config.EphemeralRate),
+			Burst:   s.config.EphemeralBurst,
+
+			TypingInterval: s.config.TypingInterval,
+		},
+	}
+	if s.config.WSBatchWindowMs > 0 {
+		handlerConfig.WriteBatch = &gateway.WriteBatchConfig{
+			Window:      time.Duration(s.config.WSBatchWindowMs) * time.Millisecond,
+			MaxMessages: s.config.WSBatchMaxMessages,
+			MaxBytes:    s.config.WSBatchMaxBytes,
+		}
+	}
+	if s.config.WSOfflineReplayBatch > 0 {
+		handlerConfig.OfflineReplay = &gateway.OfflineReplayConfig{BatchSize: s.config.WSOfflineReplayBatch}
+	}
+	if s.config.CookieSession {
+		handlerConfig.SessionCookie = handler.SessionCookieName
+	}
+	wsHandler := gateway.NewWebSocketHandler(handlerConfig, s.connManager, s.dispatcher, authenticator, messageSaver)
+	// 维护模式：拒绝发送新消息，读取不受影响
+	s.maintenanceService = service.NewMaintenanceService(s.redis, &messageDispatcherAdapter{dispatcher: s.dispatcher})
+	// 好友与屏蔽：被接收者屏蔽的单聊消息在发送前拒绝
+	s.friendService = service.NewFriendService(repository.NewFriendRepository(s.db), repository.NewUserRepository(s.db), s.redis, &messageDispatcherAdapter{dispatcher: s.dispatcher}, nil)
+
+	// 初始化端到端加密密钥服务（只保存和分发设备公钥）
+	s.e2eeService = service.NewE2EEService(repository.NewE2EEKeyRepository(s.db), repository.NewUserRepository(s.db), s.friendService, nil)
+	// 在线状态：按用户隐私设置返回在线状态及最近在线时间
+	s.presenceService = service.NewPresenceService(s.dispatcher, repository.NewUserRepository(s.db), s.friendService)
+	// 账号状态管理：注销时移交群主
+	s.accountService = service.NewAccountService(repository.NewUserRepository(s.db))
+	s.accountService.AddListener(s.groupSuccession)
+	s.accountService.AddListener(s.sessionService)
+	// 访客：只能向配置的客服和群组发送消息，过期后账号注销、消息删除
+	guestConfig := service.DefaultGuestConfig()
+	guestConfig.TTL = s.config.GuestTTL
+	guestConfig.AgentIDs = s.config.GuestAgentIDs
+	guestConfig.GroupIDs = s.config.GuestGroupIDs
+	guestConfig.RateLimit = s.config.GuestRateLimit
+	s.guestService = service.NewGuestService(repository.NewUserRepository(s.db), s.messageRepo, groupService, s.accountService, s.redis, guestConfig)
+	// 客服：访客会话排队并分配给在线坐席，访客可以与分配的坐席单聊
+	csConfig := service.DefaultCustomerServiceConfig()
+	csConfig.DefaultMaxConcurrent = s.config.CSAgentMaxConcurrent
+	csConfig.MaxQueue = s.config.CSMaxQueue
+	s.customerService = service.NewCustomerService(repository.NewCustomerServiceRepository(s.db), repository.NewUserRepository(s.db), &messageDispatcherAdapter{dispatcher: s.dispatcher}, csConfig)
+	s.guestService.SetCustomerService(s.customerService)
+	s.messageTypePolicy.SetGuestService(s.guestService)
+	wsHandler.SetSendGuard(func(ctx context.Context, conn *gateway.Connection, msg *model.Message) error {
+		if err := s.maintenanceService.CheckSend(ctx); err != nil {
+			return errors.New(i18n.T(conn.Locale, "error.maintenance"))
+		}
+		// 聊天消息内容须符合该类型的最新结构
+		if msg.Type.IsChat() {
+			if err := messageService.ValidateContent(msg); err != nil {
+				return fmt.Errorf("%s: %v", i18n.T(conn.Locale, "error.message_content_invalid"), err)
+			}
+		}
+		if err := s.encryptionService.CheckMessage(ctx, msg); err != nil {
+			if code, ok := errcode.Lookup(err); ok {
+				return errors.New(code.Message(conn.Locale))
+			}
+			return err
+		}
+		if err := s.friendService.CheckMessage(ctx, msg); err != nil {
+			if code, ok := errcode.Lookup(err); ok {
+				return errors.New(code.Message(conn.Locale))
+			}
+			return err
+		}
+		if err := s.guestService.CheckMessage(ctx, msg); err != nil {
+			if code, ok := errcode.Lookup(err); ok {
+				return errors.New(code.Message(conn.Locale))
+			}
+			return err
+		}
+		// 消息类型策略：拒绝原因（类型、会话类别、发送者角色、策略级别）附在错误信息后
+		if err := s.messageTypePolicy.CheckMessage(ctx, msg); err != nil {
+			if code, ok := errcode.Lookup(err); ok {
+				return fmt.Errorf("%s: %v", code.Message(conn.Locale), err)
+			}
+			return err
+		}
+		// 数据驻留：发送者与会话所在区域须满足跨区域规则
+		if s.dataRegions != nil {
+			if err := s.dataRegions.CheckMessage(ctx, msg); err != nil {
+				if code, ok := errcode.Lookup(err); ok {
+					return errors.New(code.Message(conn.Locale))
+				}
+				return err
+			}
+		}
+		return nil
+	})
+	// 分发前检查（已回ACK）：群消息发送者须为群成员，被拒绝的消息标记失败并通知发送者
+	wsHandler.SetDispatchGuard(func(ctx context.Context, msg *model.Message) error {
+		if msg.Type != model.MsgGroupChat && msg.GroupID == "" {
+			return nil
+		}
+		isMember, err := groupService.IsMember(ctx, msg.To, msg.From)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			return service.ErrNotGroupMember
+		}
+		return nil
+	})
+	// 临时消息（正在输入等）不经过发送前检查，投递前同样校验访客发送范围和对方屏蔽
+	wsHandler.SetEphemeralGuard(func(ctx context.Context, conn *gateway.Connection, msg *model.Message) error {
+		target := ephemeralTarget(msg)
+		if target == nil {
+			return nil
+		}
+		if err := s.friendService.CheckMessage(ctx, target); err != nil {
+			return err
+		}
+		return s.guestService.CheckMessage(ctx, target)
+	})
+	// HTTP 文件消息与 WebSocket 消息一样校验对方屏蔽和访客发送范围
+	if s.fileMessageService != nil {
+		s.fileMessageService.SetSendGuard(func(ctx context.Context, msg *model.Message) error {
+			if err := s.friendService.CheckMessage(ctx, msg); err != nil {
+				return err
+			}
+			return s.guestService.CheckMessage(ctx, msg)
+		})
+	}
+	wsHandler.SetSendFailureRecorder(messageService)
+	// 服务间内部 gRPC 接口：复用消息服务保存、分发器投递，发送前检查维护模式、内容结构、加密会话和数据驻留
+	if s.config.GRPCPort > 0 {
+		s.grpcServer = grpcserver.NewServer(&grpcserver.Config{
+			Addr:      fmt.Sprintf(":%d", s.config.GRPCPort),
+			AuthToken: s.config.GRPCAuthToken,
+		}, s.dispatcher, messageService, groupService)
+		s.grpcServer.SetSendGuard(func(ctx context.Context, msg *model.Message) error {
+			if err := s.maintenanceService.CheckSend(ctx); err != nil {
+				return err
+			}
+			if err := messageService.ValidateContent(msg); err != nil {
+				return err
+			}
+			if err := s.encryptionService.CheckMessage(ctx, msg); err != nil {
+				return err
+			}
+			if s.dataRegions != nil {
+				return s.dataRegions.CheckMessage(ctx, msg)
+			}
+			return nil
+		})
+	}
+	// 群禁言：被禁言成员（含全员禁言下的普通成员）发送的群消息在保存前拒绝
+	wsHandler.SetGroupMuteChecker(groupService.CheckMute)
+	if s.moderation != nil {
+		wsHandler.SetContentChecker(s.moderation.CheckMessage)
+	}
+	wsHandler.SetAfterSend(s.autoReplyService.HandleMessage)
+	// 灰度发布：按用户分组启用新协议行为并统计分组指标
+	s.featureFlags = service.NewFeatureFlagService(s.redis)
+	wsHandler.SetRolloutTracker(s.featureFlags)
+	// 推送文案A/B实验：按灰度分组选择推送文案
+	s.pushExperiments = service.NewPushExperimentService(s.redis, s.featureFlags)
+	if s.pushService != nil {
+		s.pushService.SetExperiments(s.pushExperiments)
+		s.reminderService.SetPushService(s.pushService)
+		s.groupDigestService.SetPushService(s.pushService)
+	}
+	// 回复建议：收到单聊文本消息后异步请求外部建议服务，功能开关 assist.smart_reply 控制启用范围（关闭即停止）
+	if s.config.SuggestionEndpoint != "" {
+		suggestionConfig := gateway.DefaultSuggestionConfig()
+		suggestionConfig.Timeout = s.config.SuggestionTimeout
+		suggestionConfig.Enabled = func(ctx context.Context, userID string) bool {
+			return s.featureFlags.IsEnabled(ctx, model.FlagSmartReply, userID)
+		}
+		wsHandler.SetSuggestionProvider(&gateway.HTTPSuggestionProvider{
+			Endpoint: s.config.SuggestionEndpoint,
+			APIKey:   s.config.SuggestionAPIKey,
+			Client:   &http.Client{Timeout: s.config.SuggestionTimeout},
+		}, suggestionConfig)
+	}
+	// 已读回执：清理已读的离线消息并扣减未读计数
+	wsHandler.SetReadHook(func(ctx context.Context, userID string, receipt *model.ReadReceiptContent) error {
+		s.counters.MarkRead(ctx, userID, receipt.ConversationID)
+		_, err := offlineService.ReconcileRead(ctx, userID, receipt.ConversationID, receipt.LastReadSeq, receipt.MessageIDs)
+		return err
+	})
+	// 离线消息补发：声明 offline_replay 的连接建立后通过 WebSocket 分批下发离线消息，客户端确认后删除
+	wsHandler.SetOfflineSource(offlineService)
+	// 送达确认：记录确认收到的接收者，消息状态更新为已送达
+	wsHandler.SetDeliveredHook(func(ctx context.Context, userID, messageID string) error {
+		_, err := s.messageRepo.MarkDelivered(ctx, messageID, userID)
+		return err
+	})
+	// 自定义消息：校验集成应用签名，会话要求时拒绝未签名消息
+	s.integrationService = service.NewIntegrationAppService(repository.NewIntegrationAppRepository(s.db), groupService, nil)
+	wsHandler.SetCustomVerifier(func(ctx context.Context, conn *gateway.Connection, msg *model.Message) error {
+		if err := s.integrationService.VerifyCustomMessage(ctx, msg); err != nil {
+			if code, ok := errcode.Lookup(err); ok {
+				return errors.New(code.Message(conn.Locale))
+			}
+			return err
+		}
+		return nil
+	})
+	wsHandler.SetConnectionLimiter(gateway.NewConnectionLimiter(&gateway.ConnectionLimitConfig{
+		MaxConnections: s.config.WSMaxConnections,
+		MaxPerUser:     s.config.WSMaxConnectionsPerUser,
+		MaxPerIP:       s.config.WSMaxConnectionsPerIP,
+		ExemptUserIDs:  s.config.WSLimitExemptUsers,
+		ExemptIPs:      s.config.WSLimitExemptIPs,
+	}))
+	if s.config.LoadShedPercent > 0 {
+		shedConfig := gateway.DefaultLoadShedConfig()
+		shedConfig.CPUThreshold = float64(s.config.LoadShedCPUPercent) / 100
+		shedConfig.QueueThreshold = float64(s.config.LoadShedQueuePercent) / 100
+		shedConfig.ShedRatio = float64(s.config.LoadShedPercent) / 100
+		shedConfig.RetryAfterMax = s.config.LoadShedRetryAfter
+		s.loadShedder = gateway.NewLoadShedder(shedConfig, s.connManager.SendQueueUsage)
+		wsHandler.SetLoadShedder(s.loadShedder)
+		// 过载时同时缩减广播、群事件的扇出预算
+		s.dispatcher.SetFanoutLoadSampler(s.loadShedder.Overload)
+	}
+	// 入站帧录制：抽样连接的原始帧写入本地文件，用 gateway replay 在测试环境回放
+	if s.config.RecordDir != "" {
+		recordConfig := gateway.DefaultFrameRecorderConfig()
+		recordConfig.Dir = s.config.RecordDir
+		recordConfig.SampleRate = float64(s.config.RecordSamplePercent) / 100
+		recordConfig.MaxFileBytes = s.config.RecordMaxFileMB << 20
+		for _, userID := range s.config.RecordUserIDs {
+			recordConfig.UserIDs[userID] = true
+		}
+		recorder, err := gateway.NewFrameRecorder(s.config.NodeID, recordConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create frame recorder: %w", err)
+		}
+		wsHandler.SetFrameRecorder(recorder)
+		// 停止时写出剩余的录制
+		s.lifecycle.OnStop("frame recorder", func(ctx context.Context) error {
+			return recorder.Close()
+		})
+		log.Printf("Inbound frame recording enabled (sample: %d%%, users: %d)", s.config.RecordSamplePercent, len(s.config.RecordUserIDs))
+	}
+	// 消息处理耗时：各阶段耗时写入指标并按节点汇总分位数，抽样消息写入诊断集合
+	latencyConfig := gateway.DefaultLatencyConfig()
+	latencyConfig.Window = s.config.LatencyWindow
+	latencyConfig.SampleRate = float64(s.config.LatencySamplePermille) / 1000
+	s.latencyTracker = gateway.NewLatencyTracker(s.redis, s.config.NodeID, latencyConfig)
+	if latencyConfig.SampleRate > 0 {
+		s.latencyTracker.SetSampleSaver(&latencySampleSaverAdapter{repo: repository.NewMessageLatencyRepository(s.mongo)})
+	}
+	wsHandler.SetLatencyTracker(s.latencyTracker)
+
+	// 连接会话计量：连接断开时记录会话时长及近似流量，批量写入并汇总月度用量
+	if s.config.UsageMetering {
+		usageConfig := service.DefaultUsageConfig()
+		usageConfig.Retention = time.Duration(s.config.UsageRetentionDays) * 24 * time.Hour
+		s.usageService = service.NewUsageService(repository.NewUsageRepository(s.db), repository.NewUserRepository(s.db), usageConfig)
+		wsHandler.SetSessionRecorder(func(conn *gateway.Connection, usage gateway.ConnectionUsage, endedAt time.Time) {
+			s.usageService.RecordSession(&model.ConnectionSession{
+				ConnectionID:    conn.ID,
+				UserID:          conn.UserID,
+				NodeID:          conn.NodeID,
+				Platform:        conn.Platform,
+				DeviceID:        conn.DeviceID,
+				ClientIP:        conn.ClientIP,
+				AppVersion:      conn.ClientInfo.AppVersion,
+				ConnectedAt:     conn.CreatedAt,
+				DisconnectedAt:  endedAt,
+				DurationSeconds: int64(endedAt.Sub(conn.CreatedAt) / time.Second),
+				BytesIn:         usage.BytesIn,
+				BytesOut:        usage.BytesOut,
+				FramesIn:        usage.FramesIn,
+				FramesOut:       usage.FramesOut,
+			})
+		})
 	}
-	wsHandler := gateway.NewWebSocketHandler(handlerConfig, s.connManager, s.dispatcher, jwtManager, messageSaver)
 
 	// 创建Gin引擎
 	gin.SetMode(gin.ReleaseMode)
 	s.engine = gin.New()
+	// 客户端IP只采信可信代理转发的请求头，避免伪造 X-Forwarded-For 绕过按IP的限流
+	if err := s.engine.SetTrustedProxies(s.config.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
 	s.engine.Use(gin.Recovery())
 	s.engine.Use(gin.Logger())
 
+	// 跨域来源检查及Cookie会话
+	if s.config.Env == EnvProduction && util.StringSliceContains(s.config.AllowOrigins, "*") {
+		log.Println("Warning: ALLOW_ORIGINS contains \"*\" in production")
+	}
+	handler.SetSecurityConfig(&handler.SecurityConfig{
+		AllowOrigins:  s.config.AllowOrigins,
+		CookieSession: s.config.CookieSession,
+		CookieSecure:  s.config.CookieSecure,
+		CookieDomain:  s.config.CookieDomain,
+		CSRFSecret:    s.config.JWTSecret,
+	})
+	s.engine.Use(handler.OriginMiddleware())
+
+	// 登录、注册、上传限流（Redis 令牌桶，多节点共享计数）
+	handler.SetRateLimiter(ratelimit.NewLimiter(s.redis), map[string]ratelimit.Rule{
+		handler.RateLimitLogin:    {Limit: s.config.LoginRateLimit, Period: time.Minute},
+		handler.RateLimitRegister: {Limit: s.config.RegisterRateLimit, Period: time.Minute},
+		handler.RateLimitUpload:   {Limit: s.config.UploadRateLimit, Period: time.Minute},
+	})
+
 	// 注册路由
-	s.registerRoutes(wsHandler, groupService, offlineService, messageService, fileService, jwtManager)
+	if err := s.registerRoutes(wsHandler, groupService, offlineService, messageService, fileService, fileMessageService, jwtManager, authenticator); err != nil {
+		return err
+	}
 
 	// 创建HTTP服务器
 	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
@@ -206,6 +1035,93 @@ func (s *Server) Setup() error {
 	return nil
 }
 
+// fileAccessControl 根据配置构建文件访问控制策略
+func (s *Server) fileAccessControl() *model.FileAccessControl {
+	accessControl := model.DefaultFileAccessControl()
+	accessControl.ProxyDownload = s.config.FileProxyDownload
+	accessControl.BindClientIP = s.config.FileURLBindIP
+	accessControl.RefererWhitelist = s.config.FileRefererWhitelist
+	return accessControl
+}
+
+// heartbeatConfig 根据配置构建心跳协商配置
+func (s *Server) heartbeatConfig() *gateway.HeartbeatConfig {
+	heartbeat := gateway.DefaultHeartbeatConfig()
+	heartbeat.DefaultInterval = s.config.PingInterval
+	heartbeat.MinInterval = s.config.HeartbeatMin
+	heartbeat.MaxInterval = s.config.HeartbeatMax
+	heartbeat.MaxMissed = s.config.HeartbeatMaxMissed
+	return heartbeat
+}
+
+// newAuthenticator 根据配置创建认证提供者
+func (s *Server) newAuthenticator(jwtManager *auth.JWTManager) (auth.Authenticator, error) {
+	apiKeys, err := auth.ParseAPIKeys(s.config.AuthAPIKeys)
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewAuthenticator(&auth.AuthenticatorConfig{
+		Provider:                  s.config.AuthProvider,
+		IntrospectionURL:          s.config.AuthIntrospectionURL,
+		IntrospectionClientID:     s.config.AuthIntrospectionClientID,
+		IntrospectionClientSecret: s.config.AuthIntrospectionClientSecret,
+		IntrospectionUserClaim:    s.config.AuthIntrospectionUserClaim,
+		IntrospectionCacheTTL:     s.config.AuthIntrospectionCacheTTL,
+		APIKeys:                   apiKeys,
+	}, jwtManager)
+}
+
+// newContentModeration 根据配置创建内容审核服务，敏感词与外部审核服务均未配置时返回 nil
+func (s *Server) newContentModeration() (service.ContentModerationService, error) {
+	var moderators []service.ContentModerator
+
+	keywords := s.config.ModerationKeywords
+	var patterns []string
+	if s.config.ModerationKeywordFile != "" {
+		data, err := os.ReadFile(s.config.ModerationKeywordFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			switch {
+			case line == "" || strings.HasPrefix(line, "#"):
+			case strings.HasPrefix(line, "re:"):
+				patterns = append(patterns, strings.TrimPrefix(line, "re:"))
+			default:
+				keywords = append(keywords, line)
+			}
+		}
+	}
+	if len(keywords) > 0 || len(patterns) > 0 {
+		keywordModerator, err := service.NewKeywordModerator(keywords, patterns, s.config.ModerationKeywordAction)
+		if err != nil {
+			return nil, err
+		}
+		moderators = append(moderators, keywordModerator)
+	}
+
+	if s.config.ModerationHTTPURL != "" {
+		if !service.ValidModerationAction(s.config.ModerationHTTPAction) {
+			return nil, fmt.Errorf("invalid MODERATION_HTTP_ACTION %q", s.config.ModerationHTTPAction)
+		}
+		moderators = append(moderators, &service.HTTPModerator{
+			Endpoint: s.config.ModerationHTTPURL,
+			APIKey:   s.config.ModerationHTTPAPIKey,
+			Action:   s.config.ModerationHTTPAction,
+			Client:   &http.Client{Timeout: s.config.ModerationTimeout},
+		})
+	}
+
+	if len(moderators) == 0 {
+		return nil, nil
+	}
+	moderationConfig := service.DefaultContentModerationConfig()
+	moderationConfig.Timeout = s.config.ModerationTimeout
+	moderationConfig.FailClosed = s.config.ModerationFailClosed
+	return service.NewContentModerationService(repository.NewModerationRepository(s.db), moderationConfig, moderators...), nil
+}
+
 // registerRoutes 注册所有路由
 func (s *Server) registerRoutes(
 	wsHandler *gateway.WebSocketHandler,
@@ -213,38 +1129,229 @@ func (s *Server) registerRoutes(
 	offlineService service.OfflineService,
 	messageService service.MessageService,
 	fileService service.FileStorageService,
+	fileMessageService service.FileMessageService,
 	jwtManager *auth.JWTManager,
-) {
+	authenticator auth.Authenticator,
+) error {
+	// 管理接口权限：按角色校验各管理接口所需的权限并记录审计日志
+	handler.SetAdminUserIDs(s.config.AdminUserIDs)
+	rbacConfig := service.DefaultRBACConfig()
+	rbacConfig.CacheTTL = s.config.RBACCacheTTL
+	rbacService := service.NewRBACService(repository.NewRBACRepository(s.db), s.redis, rbacConfig)
+	// REST 接口的认证中间件与 WebSocket 握手使用同一认证提供者
+	routeAuth := &handler.RouteAuth{Authenticator: authenticator, RBAC: rbacService}
+
 	// WebSocket路由
 	wsHandler.RegisterRoutes(s.engine)
 
 	// 群组API
 	groupHandler := handler.NewGroupHandler(groupService)
-	groupHandler.RegisterRoutes(s.engine)
+	groupHandler.RegisterRoutes(s.engine, routeAuth)
+
+	// 会话API
+	userRepo := repository.NewUserRepository(s.db)
+	conversationService := service.NewConversationService(repository.NewConversationRepository(s.db), s.messageRepo, groupService)
+	conversationService.SetCounters(s.counters)
+	conversationHandler := handler.NewConversationHandler(conversationService)
+	conversationHandler.SetEncryptionService(s.encryptionService)
+	conversationHandler.SetPinService(service.NewMessagePinService(repository.NewMessagePinRepository(s.db), s.messageRepo, groupService, s.counters))
+	if fileService != nil {
+		exportConfig := service.DefaultConversationExportConfig()
+		exportConfig.MaxMessages = s.config.ExportMaxMessages
+		var pdfRenderer service.PDFRenderer
+		if len(s.config.ExportPDFCommand) > 0 {
+			pdfRenderer = &service.CommandPDFRenderer{Command: s.config.ExportPDFCommand[0], Args: s.config.ExportPDFCommand[1:]}
+		}
+		exportService := service.NewConversationExportService(
+			conversationService, s.messageRepo, userRepo, fileService, s.redis, pdfRenderer, exportConfig,
+		)
+		conversationHandler.SetExportService(exportService)
+		s.groupExpiry.SetExportService(exportService)
+	}
+	if s.config.SummaryEndpoint != "" {
+		summaryConfig := service.DefaultSummaryConfig()
+		summaryConfig.MaxMessages = s.config.SummaryMaxMessages
+		summaryConfig.MaxPerHour = s.config.SummaryMaxPerHour
+		if s.config.SummaryRedactPattern != "" {
+			pattern, err := regexp.Compile(s.config.SummaryRedactPattern)
+			if err != nil {
+				return fmt.Errorf("invalid SUMMARY_REDACT_PATTERN: %w", err)
+			}
+			summaryConfig.RedactPattern = pattern
+		}
+		summarizer := &service.HTTPSummarizer{
+			Endpoint: s.config.SummaryEndpoint,
+			APIKey:   s.config.SummaryAPIKey,
+			Client:   &http.Client{Timeout: s.config.SummaryTimeout},
+		}
+		conversationHandler.SetSummaryService(service.NewConversationSummaryService(
+			repository.NewConversationSummaryRepository(s.db), repository.NewConversationRepository(s.db), s.messageRepo, userRepo,
+			groupService, &messageDispatcherAdapter{dispatcher: s.dispatcher}, summarizer, s.redis, summaryConfig,
+		))
+	}
+	conversationHandler.RegisterRoutes(s.engine, routeAuth)
 
 	// 离线消息API
 	offlineAPIHandler := handler.NewOfflineHandler(offlineService)
-	offlineAPIHandler.RegisterRoutes(s.engine)
+	offlineAPIHandler.RegisterRoutes(s.engine, routeAuth)
 
 	// 用户API
 	userHandler := handler.NewUserHandler(s.db, jwtManager)
-	userHandler.RegisterRoutes(s.engine)
+	searchConfig := model.DefaultUserSearchConfig()
+	searchConfig.Mode = s.config.UserSearchMode
+	searchConfig.RateLimit = s.config.UserSearchRateLimit
+	userHandler.SetSearchConfig(searchConfig, s.redis)
+	namingConfig := service.DefaultNamingConfig()
+	namingConfig.ReservedNames = append(namingConfig.ReservedNames, s.config.ReservedNames...)
+	namingConfig.UniqueNickname = s.config.UniqueNickname
+	namingConfig.RenameCooldown = s.config.RenameCooldown
+	namingService := service.NewNamingService(userRepo, namingConfig)
+	userHandler.SetNamingService(namingService)
+	userHandler.SetAutoReplyService(s.autoReplyService)
+	userHandler.SetSessionService(s.sessionService)
+	userHandler.SetNotificationSettingsService(s.notifySettings)
+	userHandler.RegisterRoutes(s.engine, routeAuth)
+
+	// 访客API
+	s.guestService.SetNamingService(namingService)
+	handler.NewGuestHandler(s.guestService, jwtManager).RegisterRoutes(s.engine, routeAuth)
+
+	// 好友API
+	handler.NewFriendHandler(s.friendService).RegisterRoutes(s.engine, routeAuth)
+
+	// 端到端加密密钥API
+	handler.NewE2EEHandler(s.e2eeService).RegisterRoutes(s.engine, routeAuth)
+	handler.NewPresenceHandler(s.presenceService).RegisterRoutes(s.engine, routeAuth)
+
+	// 群投票API
+	handler.NewPollHandler(s.pollService).RegisterRoutes(s.engine, routeAuth)
+
+	// 提及组API
+	handler.NewMentionHandler(s.mentionService).RegisterRoutes(s.engine, routeAuth)
+
+	// 群活动摘要API
+	handler.NewGroupDigestHandler(s.groupDigestService).RegisterRoutes(s.engine, routeAuth)
+
+	// 用量计量API
+	if s.usageService != nil {
+		handler.NewUsageHandler(s.usageService).RegisterRoutes(s.engine, routeAuth)
+	}
+
+	// 客服API
+	handler.NewCSHandler(s.customerService).RegisterRoutes(s.engine, routeAuth)
+
+	// 管理API
+	handler.NewRBACHandler(rbacService).RegisterRoutes(s.engine, routeAuth)
+	adminHandler := handler.NewAdminHandler(s.maintenanceService)
+	nodeService := service.NewNodeService(&nodeGatewayAdapter{registry: s.connRegistry, dispatcher: s.dispatcher, latency: s.latencyTracker})
+	adminHandler.SetNodeService(nodeService)
+	// 强制下线时通知各节点断开用户连接
+	s.sessionService.SetKicker(nodeService)
+	adminHandler.SetSessionService(s.sessionService)
+	// 平台群组管理：查询群组、强制解散违规群
+	adminHandler.SetGroupAdminService(service.NewGroupAdminService(
+		repository.NewGroupRepository(s.db), s.redis, &messageDispatcherAdapter{dispatcher: s.dispatcher}, s.config.GroupEventLegacyExtra,
+	))
+	userImportConfig := service.DefaultUserImportConfig()
+	userImportConfig.MaxRows = s.config.UserImportMaxRows
+	adminHandler.SetUserImportService(service.NewUserImportService(userRepo, namingService, groupService, userImportConfig))
+	adminHandler.SetAccountService(s.accountService)
+	adminHandler.SetGroupSuccessionService(s.groupSuccession)
+	if s.dataRegions != nil {
+		adminHandler.SetDataRegionService(s.dataRegions)
+	}
+	adminHandler.SetMessageQuarantineService(s.messageQuarantine)
+	adminHandler.SetMessageService(messageService)
+	adminHandler.RegisterRoutes(s.engine, routeAuth)
+
+	// 会话分析API
+	handler.NewAnalyticsHandler(s.analytics).RegisterRoutes(s.engine, routeAuth)
+
+	// 功能开关/灰度发布API
+	handler.NewFeatureHandler(s.featureFlags).RegisterRoutes(s.engine, routeAuth)
+
+	// 推送回调/推送分析API
+	pushHandler := handler.NewPushHandler(s.pushExperiments)
+	if s.pushService != nil {
+		pushHandler.SetPushService(s.pushService)
+		// 推送设备注册API
+		deviceHandler := handler.NewRegisterDeviceHandler(s.pushService)
+		if s.config.WebPushPrivateKey != "" {
+			deviceHandler.SetWebPushPublicKey(s.config.WebPushPublicKey)
+		}
+		deviceHandler.RegisterRoutes(s.engine, routeAuth)
+	}
+	pushHandler.RegisterRoutes(s.engine, routeAuth)
+
+	// 集成应用API
+	handler.NewIntegrationHandler(s.integrationService).RegisterRoutes(s.engine, routeAuth)
+
+	// 外部平台桥接API
+	if s.bridgeService != nil {
+		bridgeHandler := handler.NewBridgeHandler(s.bridgeService)
+		if s.slackBridge != nil {
+			bridgeHandler.SetSlack(s.slackBridge)
+		}
+		if s.matrixBridge != nil {
+			bridgeHandler.SetMatrix(s.matrixBridge)
+		}
+		bridgeHandler.RegisterRoutes(s.engine, routeAuth)
+	}
+
+	// 组织架构/通讯录API
+	orgService := service.NewOrgService(repository.NewOrgRepository(s.db), userRepo)
+	handler.NewOrgHandler(orgService, rbacService).RegisterRoutes(s.engine, routeAuth)
+
+	// 多语言文案API
+	handler.NewI18nHandler().RegisterRoutes(s.engine)
+
+	// 服务器时间API
+	handler.NewTimeHandler(s.config.MaxClientSkew).RegisterRoutes(s.engine)
 
 	// 消息历史API
-	messageHandler := handler.NewMessageHandler(messageService)
-	messageHandler.RegisterRoutes(s.engine.Group("/api", handler.AuthMiddleware()))
+	messageHandler := handler.NewMessageHandler(messageService, fileMessageService)
+	messageHandler.SetMaintenanceService(s.maintenanceService)
+	messageHandler.SetReminderService(s.reminderService)
+	messageHandler.SetOfflineService(offlineService)
+	messageHandler.SetCounterService(s.counters)
+	messageHandler.SetSearchService(s.searchService)
+	messageHandler.RegisterRoutes(s.engine.Group("/api", handler.NewAuthMiddleware(authenticator)))
 
 	// 文件上传API
 	if fileService != nil {
 		fileHandler := handler.NewFileHandler(fileService)
-		fileHandler.RegisterRoutes(s.engine)
+		fileHandler.SetRetentionService(s.fileRetention)
+		fileHandler.SetUploadTracker(fileMessageService)
+		fileHandler.RegisterRoutes(s.engine, routeAuth)
+		handler.NewFilePolicyHandler(s.filePolicy).RegisterRoutes(s.engine, routeAuth)
+	}
+	// 内容审核违规记录（启用内容审核时）
+	if s.moderation != nil {
+		handler.NewModerationHandler(s.moderation).RegisterRoutes(s.engine, routeAuth)
 	}
 
+	handler.NewMessageTypePolicyHandler(s.messageTypePolicy).RegisterRoutes(s.engine, routeAuth)
+
 	// Swagger文档
 	s.engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// OpenAPI 3 文档（按已注册的路由生成）
+	openAPIHandler := handler.NewOpenAPIHandler(s.engine)
+	openAPIHandler.RegisterRoutes(s.engine)
+
 	// 静态文件服务
 	s.setupStaticFiles()
+
+	// 校验路由与接口描述是否一致
+	if problems := openAPIHandler.Check(); len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("OpenAPI: %s", problem)
+		}
+		if s.config.OpenAPIStrict {
+			return fmt.Errorf("routes do not match OpenAPI descriptions: %d problems", len(problems))
+		}
+	}
+	return nil
 }
 
 // setupStaticFiles 设置静态文件服务
@@ -339,16 +1446,113 @@ func (s *Server) Run(ctx context.Context) error {
 
 // startBackgroundTasks 启动后台任务
 func (s *Server) startBackgroundTasks(ctx context.Context) {
-	// 启动指标服务
-	go s.startMetricsServer()
-
 	// 启动消息订阅
 	if err := s.dispatcher.SubscribeNodeMessages(ctx); err != nil {
 		log.Printf("Warning: Failed to subscribe node messages: %v", err)
 	}
 
-	// 启动心跳检查
-	go s.connManager.StartHeartbeatChecker(ctx, time.Minute, s.config.PongTimeout*2)
+	// 后台任务按注册顺序启动，关闭时按相反顺序停止（指标服务最后停止）
+	s.lifecycle.Go("metrics server", s.runMetricsServer)
+
+	// 服务间内部 gRPC 接口
+	if s.grpcServer != nil {
+		s.lifecycle.Go("grpc server", s.grpcServer.Start)
+	}
+
+	// 过载保护负载采样
+	if s.loadShedder != nil {
+		s.lifecycle.Go("load shedder", s.loadShedder.Start)
+	}
+
+	// 消息处理耗时分位数发布
+	s.lifecycle.Go("latency tracker", s.latencyTracker.Start)
+
+	// 心跳检查
+	s.lifecycle.Go("heartbeat checker", func(ctx context.Context) {
+		s.connManager.StartHeartbeatChecker(ctx, time.Minute, s.heartbeatConfig().MaxTimeout()*2)
+	})
+
+	// 孤儿文件清理任务
+	if s.fileMessageService != nil {
+		s.lifecycle.Go("orphan file reaper", func(ctx context.Context) {
+			s.fileMessageService.StartOrphanReaper(ctx, 10*time.Minute, time.Hour)
+		})
+	}
+
+	// 推送Worker（停止时立即发出合并窗口中的推送）
+	if s.pushService != nil {
+		s.lifecycle.Go("push worker", func(ctx context.Context) {
+			if err := s.pushService.StartPushWorker(ctx); err != nil {
+				log.Printf("Start push worker error: %v", err)
+				return
+			}
+			<-ctx.Done()
+			s.pushService.StopPushWorker()
+		})
+	}
+
+	// 消息提醒扫描
+	if s.reminderService != nil {
+		s.lifecycle.Go("reminder scheduler", s.reminderService.Start)
+	}
+
+	// 群活动每日摘要扫描
+	if s.groupDigestService != nil {
+		s.lifecycle.Go("group digest scheduler", s.groupDigestService.Start)
+	}
+
+	// 到期投票扫描
+	if s.pollService != nil {
+		s.lifecycle.Go("poll scheduler", s.pollService.Start)
+	}
+
+	// 灰度分组指标写入
+	if s.featureFlags != nil {
+		s.lifecycle.Go("feature flag metrics", s.featureFlags.Start)
+	}
+
+	// 会话统计增量写入（停止时写出剩余增量）
+	if s.analytics != nil {
+		s.lifecycle.Go("conversation analytics", s.analytics.Start)
+	}
+
+	// 连接会话批量写入（停止时写出剩余会话）
+	if s.usageService != nil {
+		s.lifecycle.Go("usage metering", s.usageService.Start)
+	}
+
+	// 群主继任到期解散扫描
+	if s.groupSuccession != nil {
+		s.lifecycle.Go("group succession", s.groupSuccession.Start)
+	}
+
+	// 临时群到期扫描
+	if s.groupExpiry != nil {
+		s.lifecycle.Go("group expiry", s.groupExpiry.Start)
+	}
+
+	// 消息变更流监听
+	if s.changeListener != nil {
+		s.lifecycle.Go("message change listener", s.changeListener.Start)
+	}
+
+	// 过期上传会话清理任务
+	if s.fileService != nil {
+		s.lifecycle.Go("upload session cleanup", func(ctx context.Context) {
+			s.fileService.StartUploadSessionCleanup(ctx, 30*time.Minute)
+		})
+	}
+	if s.fileRetention != nil {
+		s.lifecycle.Go("file retention", s.fileRetention.Start)
+	}
+	if s.emailDigest != nil {
+		s.lifecycle.Go("email digest", s.emailDigest.Start)
+	}
+	if s.guestService != nil {
+		s.lifecycle.Go("guest cleanup", s.guestService.Start)
+	}
+
+	s.lifecycle.Start(ctx)
 
 	// 注册节点
 	if err := database.RegisterNode(ctx, s.redis, s.config.NodeID); err != nil {
@@ -356,20 +1560,30 @@ func (s *Server) startBackgroundTasks(ctx context.Context) {
 	}
 }
 
-// startMetricsServer 启动指标服务器
-func (s *Server) startMetricsServer() {
+// runMetricsServer 运行指标服务器，直到 ctx 取消
+func (s *Server) runMetricsServer(ctx context.Context) {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
 
-	addr := fmt.Sprintf(":%d", s.config.MetricsPort)
-	log.Printf("Metrics server listening on %s", addr)
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", s.config.MetricsPort),
+		Handler: mux,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
 
-	if err := http.ListenAndServe(addr, mux); err != nil {
+	log.Printf("Metrics server listening on %s", server.Addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Printf("Metrics server error: %v", err)
 	}
 }
 
-// Shutdown 优雅关闭服务器
+// Shutdown 优雅关闭服务器：先停止接收新请求和连接，再按相反顺序停止后台任务，
+// 待缓冲数据写出后关闭分发器、录制和存储连接
 func (s *Server) Shutdown(ctx context.Context) error {
 	log.Println("Shutting down server...")
 
@@ -378,26 +1592,22 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		log.Printf("Warning: Failed to unregister node: %v", err)
 	}
 
-	// 关闭所有连接
-	s.connManager.CloseAll()
-
-	// 关闭分发器
-	s.dispatcher.Close()
-
-	// 关闭HTTP服务器
+	// 关闭HTTP服务器（不再接受新连接，等待处理中的请求完成）
 	if err := s.httpServer.Shutdown(ctx); err != nil {
-		return fmt.Errorf("failed to shutdown http server: %w", err)
+		log.Printf("Warning: Failed to shutdown http server: %v", err)
 	}
 
-	// 关闭MongoDB连接
-	if s.mongo != nil {
-		if err := s.mongo.Close(ctx); err != nil {
-			log.Printf("Warning: Failed to close MongoDB connection: %v", err)
-		}
+	// 关闭所有连接
+	s.connManager.CloseAll()
+	if err := s.connRegistry.Clear(ctx); err != nil {
+		log.Printf("Warning: Failed to clear connection registry: %v", err)
 	}
 
+	// 停止后台任务并关闭组件
+	err := s.lifecycle.Stop(ctx)
+
 	log.Println("Server exited")
-	return nil
+	return err
 }
 
 // Config 获取配置
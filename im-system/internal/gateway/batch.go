@@ -0,0 +1,163 @@
+package gateway
+
+import (
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 批量帧格式（握手时通过 batch 参数或 X-Frame-Batch 头协商）
+const (
+	BatchModeJSON           = "json" // 文本帧，JSON数组
+	BatchModeLengthPrefixed = "lp"   // 二进制帧，每条消息前加4字节大端长度
+)
+
+// 批量帧写出原因
+const (
+	batchFlushFull   = "full"   // 达到条数或字节上限
+	batchFlushWindow = "window" // 等待窗口到期
+)
+
+// WriteBatchConfig 发送合并配置：客户端协商后，窗口内排队的多条消息合并为一帧写出
+type WriteBatchConfig struct {
+	Window      time.Duration // 首条消息最多等待的时间（延迟上限）
+	MaxMessages int           // 每帧最多合并的消息数
+	MaxBytes    int           // 每帧合并的消息总字节数上限（最后一条可超出）
+}
+
+// DefaultWriteBatchConfig 默认发送合并配置
+func DefaultWriteBatchConfig() *WriteBatchConfig {
+	return &WriteBatchConfig{
+		Window:      5 * time.Millisecond,
+		MaxMessages: writeBatchSize,
+		MaxBytes:    64 << 10,
+	}
+}
+
+// negotiateBatchMode 协商批量帧格式，服务端未启用或客户端未请求时返回空（逐条写出）
+func negotiateBatchMode(config *WriteBatchConfig, requested string) string {
+	if config == nil || config.Window <= 0 {
+		return ""
+	}
+	switch mode := strings.ToLower(requested); mode {
+	case BatchModeJSON, BatchModeLengthPrefixed:
+		return mode
+	}
+	return ""
+}
+
+// frameBatch 待合并写出的消息
+type frameBatch struct {
+	mode     string
+	messages [][]byte
+	size     int
+	started  time.Time
+}
+
+// add 添加消息
+func (b *frameBatch) add(data []byte) {
+	if len(b.messages) == 0 {
+		b.started = time.Now()
+	}
+	b.messages = append(b.messages, data)
+	b.size += len(data)
+}
+
+// full 是否达到合并上限
+func (b *frameBatch) full(config *WriteBatchConfig) bool {
+	return (config.MaxMessages > 0 && len(b.messages) >= config.MaxMessages) ||
+		(config.MaxBytes > 0 && b.size >= config.MaxBytes)
+}
+
+// encode 编码为一帧：只有一条消息时按普通文本帧写出，保持与逐条写出兼容
+func (b *frameBatch) encode() (int, []byte) {
+	if len(b.messages) == 1 {
+		return websocket.TextMessage, b.messages[0]
+	}
+
+	if b.mode == BatchModeLengthPrefixed {
+		frame := make([]byte, 0, b.size+4*len(b.messages))
+		for _, data := range b.messages {
+			frame = binary.BigEndian.AppendUint32(frame, uint32(len(data)))
+			frame = append(frame, data...)
+		}
+		return websocket.BinaryMessage, frame
+	}
+
+	frame := make([]byte, 0, b.size+len(b.messages)+1)
+	frame = append(frame, '[')
+	for i, data := range b.messages {
+		if i > 0 {
+			frame = append(frame, ',')
+		}
+		frame = append(frame, data...)
+	}
+	frame = append(frame, ']')
+	return websocket.TextMessage, frame
+}
+
+// writeBatched 合并写出排队的消息：窗口内持续收集，达到上限或窗口到期时写出一帧
+func (h *WebSocketHandler) writeBatched(conn *Connection) error {
+	config := h.config.WriteBatch
+	batch := &frameBatch{mode: conn.batchMode}
+	reason := batchFlushWindow
+
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+collect:
+	for {
+		for !batch.full(config) {
+			data, ok := conn.queue.pop()
+			if !ok {
+				break
+			}
+			batch.add(data)
+		}
+		if len(batch.messages) == 0 {
+			return nil
+		}
+		if batch.full(config) {
+			reason = batchFlushFull
+			break
+		}
+
+		wait := config.Window - time.Since(batch.started)
+		if wait <= 0 {
+			break
+		}
+		if timer == nil {
+			timer = time.NewTimer(wait)
+		}
+		select {
+		case <-conn.queue.ready():
+		case <-timer.C:
+			timer = nil
+			break collect
+		case <-conn.Done():
+			return nil
+		}
+	}
+
+	messageType, frame := batch.encode()
+	conn.Conn.SetWriteDeadline(time.Now().Add(h.config.WriteTimeout))
+	if err := conn.Conn.WriteMessage(messageType, frame); err != nil {
+		return err
+	}
+	conn.countOutbound(len(frame))
+
+	writeBatchesTotal.WithLabelValues(batch.mode, reason).Inc()
+	writeBatchMessages.Observe(float64(len(batch.messages)))
+	writeBatchDelaySeconds.Observe(time.Since(batch.started).Seconds())
+
+	if conn.queue.pending() {
+		conn.queue.signal()
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 跨节点消息通道类型
+const (
+	BrokerRedis = "redis" // Redis Pub/Sub（默认，节点断开期间的消息丢失）
+	BrokerKafka = "kafka" // Kafka（节点短暂断开后从已提交位置继续消费）
+)
+
+// MessageBroker 跨节点消息通道：路由消息发布到目标节点，各节点订阅发往自己的消息
+type MessageBroker interface {
+	// Publish 发布路由消息到指定节点
+	Publish(ctx context.Context, nodeID string, data []byte) error
+
+	// Subscribe 订阅发往指定节点的消息，返回的通道在 Close 或 ctx 取消后关闭
+	Subscribe(ctx context.Context, nodeID string) (<-chan []byte, error)
+
+	// Close 关闭通道
+	Close() error
+}
+
+// redisBroker 基于 Redis Pub/Sub 的消息通道，每个节点一个频道
+type redisBroker struct {
+	redis           *redis.Client
+	publishPrefix   string
+	subscribePrefix string
+
+	mu     sync.Mutex
+	pubsub *redis.PubSub
+}
+
+// NewRedisBroker 创建 Redis Pub/Sub 消息通道，频道为前缀+节点ID（订阅前缀为空时与发布前缀相同）
+func NewRedisBroker(redisClient *redis.Client, publishPrefix, subscribePrefix string) MessageBroker {
+	if subscribePrefix == "" {
+		subscribePrefix = publishPrefix
+	}
+	return &redisBroker{redis: redisClient, publishPrefix: publishPrefix, subscribePrefix: subscribePrefix}
+}
+
+// Publish 发布路由消息到节点频道
+func (b *redisBroker) Publish(ctx context.Context, nodeID string, data []byte) error {
+	return b.redis.Publish(ctx, b.publishPrefix+nodeID, data).Err()
+}
+
+// Subscribe 订阅本节点频道
+func (b *redisBroker) Subscribe(ctx context.Context, nodeID string) (<-chan []byte, error) {
+	channel := b.subscribePrefix + nodeID
+	pubsub := b.redis.Subscribe(ctx, channel)
+
+	// 等待订阅确认
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("subscribe error: %w", err)
+	}
+
+	b.mu.Lock()
+	b.pubsub = pubsub
+	b.mu.Unlock()
+	log.Printf("Subscribed to channel: %s", channel)
+
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		ch := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				select {
+				case out <- []byte(msg.Payload):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Close 关闭订阅
+func (b *redisBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pubsub == nil {
+		return nil
+	}
+	return b.pubsub.Close()
+}
@@ -0,0 +1,172 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaBrokerConfig Kafka 消息通道配置
+type KafkaBrokerConfig struct {
+	Brokers           []string      // Kafka 地址列表
+	TopicPrefix       string        // 节点主题前缀，每个节点一个主题（prefix+节点ID）
+	GroupPrefix       string        // 消费组前缀，每个节点一个消费组，重启或断开后从已提交位置继续消费
+	ReplicationFactor int           // 自动创建节点主题时的副本数
+	MaxReplayAge      time.Duration // 断开恢复后补投的消息最大时长，超过的丢弃（接收者已按离线处理），0表示不限制
+	BatchTimeout      time.Duration // 发布批量等待时间，越小延迟越低
+}
+
+// DefaultKafkaBrokerConfig 默认 Kafka 消息通道配置
+func DefaultKafkaBrokerConfig() *KafkaBrokerConfig {
+	return &KafkaBrokerConfig{
+		TopicPrefix:       "im.node.",
+		GroupPrefix:       "im-gateway-",
+		ReplicationFactor: 1,
+		MaxReplayAge:      5 * time.Minute,
+		BatchTimeout:      5 * time.Millisecond,
+	}
+}
+
+// kafkaBroker 基于 Kafka 的消息通道
+// 每个节点一个单分区主题，由该节点的消费组消费：节点短暂断开或重启期间发布的消息保留在主题中，
+// 恢复后从已提交的位置继续投递（至少一次，客户端按 message_id 去重）。
+type kafkaBroker struct {
+	config *KafkaBrokerConfig
+	writer *kafka.Writer
+
+	mu     sync.Mutex
+	reader *kafka.Reader
+}
+
+// NewKafkaBroker 创建 Kafka 消息通道
+func NewKafkaBroker(config *KafkaBrokerConfig) (MessageBroker, error) {
+	if config == nil || len(config.Brokers) == 0 {
+		return nil, errors.New("kafka brokers not configured")
+	}
+	return &kafkaBroker{
+		config: config,
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(config.Brokers...),
+			Balancer:               &kafka.LeastBytes{},
+			BatchTimeout:           config.BatchTimeout,
+			RequiredAcks:           kafka.RequireOne,
+			AllowAutoTopicCreation: true,
+		},
+	}, nil
+}
+
+// Publish 发布路由消息到节点主题
+func (b *kafkaBroker) Publish(ctx context.Context, nodeID string, data []byte) error {
+	return b.writer.WriteMessages(ctx, kafka.Message{
+		Topic: b.config.TopicPrefix + nodeID,
+		Value: data,
+		Time:  time.Now(),
+	})
+}
+
+// Subscribe 以本节点的消费组订阅节点主题
+func (b *kafkaBroker) Subscribe(ctx context.Context, nodeID string) (<-chan []byte, error) {
+	topic := b.config.TopicPrefix + nodeID
+	if err := b.ensureTopic(topic); err != nil {
+		return nil, fmt.Errorf("create topic %s error: %w", topic, err)
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        b.config.Brokers,
+		GroupID:        b.config.GroupPrefix + nodeID,
+		Topic:          topic,
+		StartOffset:    kafka.FirstOffset,
+		MinBytes:       1,
+		MaxBytes:       10 << 20,
+		MaxWait:        500 * time.Millisecond,
+		CommitInterval: time.Second,
+	})
+	b.mu.Lock()
+	b.reader = reader
+	b.mu.Unlock()
+	log.Printf("Subscribed to kafka topic: %s", topic)
+
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		for {
+			msg, err := reader.ReadMessage(ctx)
+			if err != nil {
+				if ctx.Err() != nil || errors.Is(err, io.EOF) {
+					return
+				}
+				log.Printf("read kafka topic %s error: %v", topic, err)
+				select {
+				case <-time.After(time.Second):
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			// 断开时间过长时，积压的消息接收者早已按离线处理，不再补投
+			if b.config.MaxReplayAge > 0 && time.Since(msg.Time) > b.config.MaxReplayAge {
+				routeExpiredTotal.Inc()
+				continue
+			}
+
+			select {
+			case out <- msg.Value:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// ensureTopic 通过控制器创建节点主题（已存在时忽略）
+func (b *kafkaBroker) ensureTopic(topic string) error {
+	conn, err := kafka.Dial("tcp", b.config.Brokers[0])
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	controller, err := conn.Controller()
+	if err != nil {
+		return err
+	}
+	controllerConn, err := kafka.Dial("tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		return err
+	}
+	defer controllerConn.Close()
+
+	err = controllerConn.CreateTopics(kafka.TopicConfig{
+		Topic:             topic,
+		NumPartitions:     1,
+		ReplicationFactor: b.config.ReplicationFactor,
+	})
+	if errors.Is(err, kafka.TopicAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+// Close 关闭消费者（提交已消费位置）和生产者
+func (b *kafkaBroker) Close() error {
+	b.mu.Lock()
+	reader := b.reader
+	b.mu.Unlock()
+
+	var errs []error
+	if reader != nil {
+		errs = append(errs, reader.Close())
+	}
+	errs = append(errs, b.writer.Close())
+	return errors.Join(errs...)
+}
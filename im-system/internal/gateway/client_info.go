@@ -0,0 +1,177 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// CloseUpgradeRequired 客户端版本过低的关闭码（应用自定义范围，对应HTTP 426）
+const CloseUpgradeRequired = 4426
+
+// maxCapabilities 单个连接最多登记的能力项
+const maxCapabilities = 32
+
+// ClientInfo 客户端握手时上报的元数据
+type ClientInfo struct {
+	AppVersion   string   // 应用版本，如 2.3.1
+	OS           string   // 操作系统及版本，如 iOS 17.2
+	NetworkType  string   // 网络类型：wifi、4g、5g 等
+	Capabilities []string // 客户端支持的能力，如 msg_patch、ephemeral
+}
+
+// parseClientInfo 从握手请求读取客户端元数据（查询参数优先，其次请求头）
+func parseClientInfo(c *gin.Context) ClientInfo {
+	info := ClientInfo{
+		AppVersion:  handshakeField(c, "app_version", "X-App-Version", 32),
+		OS:          handshakeField(c, "os", "X-Client-OS", 64),
+		NetworkType: strings.ToLower(handshakeField(c, "network_type", "X-Network-Type", 16)),
+	}
+
+	seen := make(map[string]bool)
+	for _, capability := range strings.Split(handshakeField(c, "capabilities", "X-Client-Capabilities", 512), ",") {
+		capability = strings.ToLower(strings.TrimSpace(capability))
+		if capability == "" || seen[capability] || len(info.Capabilities) >= maxCapabilities {
+			continue
+		}
+		seen[capability] = true
+		info.Capabilities = append(info.Capabilities, capability)
+	}
+	return info
+}
+
+// handshakeField 读取握手字段并截断到最大长度，避免登记信息被超长值撑大
+func handshakeField(c *gin.Context, query, header string, maxLen int) string {
+	value := c.Query(query)
+	if value == "" {
+		value = c.GetHeader(header)
+	}
+	value = strings.TrimSpace(value)
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+	return value
+}
+
+// ParseMinClientVersions 解析 platform:version 逗号分隔的最低客户端版本，platform 为 * 表示默认值
+func ParseMinClientVersions(value string) (map[string]string, error) {
+	versions := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		platform, version, ok := strings.Cut(item, ":")
+		platform = strings.ToLower(strings.TrimSpace(platform))
+		version = strings.TrimSpace(version)
+		if !ok || platform == "" || version == "" {
+			return nil, fmt.Errorf("invalid min client version %q, expected platform:version", item)
+		}
+		if _, err := parseVersion(version); err != nil {
+			return nil, fmt.Errorf("invalid min client version %q: %w", item, err)
+		}
+		versions[platform] = version
+	}
+	return versions, nil
+}
+
+// minClientVersion 获取平台要求的最低版本，未配置时返回空
+func (h *WebSocketHandler) minClientVersion(platform string) string {
+	if version, ok := h.config.MinClientVersions[strings.ToLower(platform)]; ok {
+		return version
+	}
+	return h.config.MinClientVersions["*"]
+}
+
+// clientOutdated 客户端版本是否低于要求；未上报版本或版本无法解析时放行（兼容上报版本之前的客户端）
+func (h *WebSocketHandler) clientOutdated(platform, appVersion string) (string, bool) {
+	minVersion := h.minClientVersion(platform)
+	if minVersion == "" || appVersion == "" {
+		return "", false
+	}
+	cmp, err := CompareVersions(appVersion, minVersion)
+	if err != nil {
+		return "", false
+	}
+	return minVersion, cmp < 0
+}
+
+// UpgradeHint 版本过低时的关闭原因（JSON，写入关闭帧）
+type UpgradeHint struct {
+	Reason     string `json:"reason"`
+	MinVersion string `json:"min_version"`
+}
+
+// rejectOutdated 拒绝版本过低的客户端：完成升级后以 4426 关闭帧告知最低版本，升级失败时返回426
+func (h *WebSocketHandler) rejectOutdated(c *gin.Context, platform, minVersion string) {
+	connectionsRejectedTotal.WithLabelValues("upgrade_required").Inc()
+
+	reason, _ := json.Marshal(&UpgradeHint{Reason: "upgrade_required", MinVersion: minVersion})
+	if wsConn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil); err == nil {
+		wsConn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(CloseUpgradeRequired, string(reason)),
+			time.Now().Add(time.Second))
+		wsConn.Close()
+		return
+	}
+	if !c.Writer.Written() {
+		c.JSON(http.StatusUpgradeRequired, gin.H{"error": "upgrade required", "platform": platform, "min_version": minVersion})
+	}
+}
+
+// CompareVersions 比较点分数字版本号（忽略 v 前缀及 -、+ 之后的预发布/构建信息），返回 -1、0、1
+func CompareVersions(a, b string) (int, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// parseVersion 解析版本号各段
+func parseVersion(version string) ([]int, error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	if version == "" {
+		return nil, fmt.Errorf("empty version")
+	}
+
+	parts := strings.Split(version, ".")
+	segments := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", version)
+		}
+		segments[i] = n
+	}
+	return segments, nil
+}
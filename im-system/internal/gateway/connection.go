@@ -4,12 +4,15 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 
 	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/pkg/i18n"
 )
 
 // ConnectionState 连接状态
@@ -24,20 +27,41 @@ const (
 
 // Connection WebSocket连接封装
 type Connection struct {
-	ID         string          // 连接ID
-	UserID     string          // 用户ID
-	Conn       *websocket.Conn // WebSocket连接
-	Send       chan []byte     // 发送消息通道
-	NodeID     string          // 所在节点ID
-	Platform   string          // 平台: web, ios, android
-	DeviceID   string          // 设备ID
-	State      ConnectionState // 连接状态
-	LastActive time.Time       // 最后活跃时间
-	CreatedAt  time.Time       // 创建时间
-
-	mu       sync.RWMutex
-	closed   bool
-	closedCh chan struct{}
+	ID         string            // 连接ID
+	UserID     string            // 用户ID
+	Conn       *websocket.Conn   // WebSocket连接
+	NodeID     string            // 所在节点ID
+	Platform   string            // 平台: web, ios, android
+	DeviceID   string            // 设备ID
+	ClientIP   string            // 客户端IP
+	Locale     string            // 客户端语言
+	ClientInfo ClientInfo        // 客户端版本、系统、网络类型及能力
+	Cohorts    map[string]string // 灰度分组（开关Key -> 分组），连接建立后不再修改
+	State      ConnectionState   // 连接状态
+	LastActive time.Time         // 最后活跃时间
+	CreatedAt  time.Time         // 创建时间
+
+	mu         sync.RWMutex
+	closed     bool
+	closedCh   chan struct{}
+	closeFrame []byte // 关闭时发送的关闭帧（含重连建议）
+	heartbeat  *heartbeatState
+	queue      *laneQueue[[]byte] // 按优先级分道的发送队列
+	ephemeral  *tokenBucket       // 临时消息限速（只在读协程中使用）
+	batchMode  string             // 协商的批量帧格式，为空时逐条写出
+	recorded   bool               // 是否录制入站帧（连接建立时抽样确定）
+
+	typingSent map[string]time.Time // 各会话最近一次转发正在输入的时间（只在读协程中使用）
+
+	offlineReplay *offlineReplay // 离线消息补发进度，未补发或补发完成时为空（只在读协程中使用）
+
+	messages *tokenBucket // 发送消息限速（只在读协程中使用）
+
+	// 流量计量（只统计消息帧负载，不含协议头和心跳）
+	bytesIn   atomic.Int64
+	bytesOut  atomic.Int64
+	framesIn  atomic.Int64
+	framesOut atomic.Int64
 }
 
 // ConnectionConfig 连接配置
@@ -49,8 +73,12 @@ type ConnectionConfig struct {
 	PongTimeout      time.Duration
 	WriteTimeout     time.Duration
 	ReadTimeout      time.Duration
-	SendChannelSize  int
+	SendChannelSize  int // 聊天消息发送队列容量
 	HandshakeTimeout time.Duration
+
+	ControlChannelSize int // 控制消息（ACK、回执、输入状态等）发送队列容量
+	BulkChannelSize    int // 批量消息（广播、通知）发送队列容量
+	StarvationLimit    int // 低优先级有积压时，高优先级最多连续发送的条数
 }
 
 // DefaultConnectionConfig 默认连接配置
@@ -64,6 +92,10 @@ var DefaultConnectionConfig = ConnectionConfig{
 	ReadTimeout:      60 * time.Second,
 	SendChannelSize:  256,
 	HandshakeTimeout: 10 * time.Second,
+
+	ControlChannelSize: 64,
+	BulkChannelSize:    128,
+	StarvationLimit:    defaultStarvationLimit,
 }
 
 // NewConnection 创建新连接
@@ -76,7 +108,7 @@ func NewConnection(id, userID, nodeID string, conn *websocket.Conn, config *Conn
 		ID:         id,
 		UserID:     userID,
 		Conn:       conn,
-		Send:       make(chan []byte, config.SendChannelSize),
+		queue:      newLaneQueue[[]byte]("connection", config.laneSizes(), config.StarvationLimit),
 		NodeID:     nodeID,
 		State:      StateConnected,
 		LastActive: time.Now(),
@@ -85,6 +117,22 @@ func NewConnection(id, userID, nodeID string, conn *websocket.Conn, config *Conn
 	}
 }
 
+// laneSizes 各优先级发送队列容量，未配置时使用默认值
+func (c *ConnectionConfig) laneSizes() [numPriorities]int {
+	sizes := [numPriorities]int{c.ControlChannelSize, c.SendChannelSize, c.BulkChannelSize}
+	defaults := [numPriorities]int{
+		DefaultConnectionConfig.ControlChannelSize,
+		DefaultConnectionConfig.SendChannelSize,
+		DefaultConnectionConfig.BulkChannelSize,
+	}
+	for i := range sizes {
+		if sizes[i] <= 0 {
+			sizes[i] = defaults[i]
+		}
+	}
+	return sizes
+}
+
 // Close 关闭连接
 func (c *Connection) Close() error {
 	c.mu.Lock()
@@ -95,12 +143,23 @@ func (c *Connection) Close() error {
 	c.closed = true
 	c.State = StateClosed
 	close(c.closedCh)
-	close(c.Send)
+	closeFrame := c.closeFrame
 	c.mu.Unlock()
 
+	if closeFrame != nil {
+		c.Conn.WriteControl(websocket.CloseMessage, closeFrame, time.Now().Add(time.Second))
+	}
 	return c.Conn.Close()
 }
 
+// CloseWithHint 发送带重连建议的关闭帧后关闭连接
+func (c *Connection) CloseWithHint(code int, reason string, retryAfter time.Duration) error {
+	c.mu.Lock()
+	c.closeFrame = websocket.FormatCloseMessage(code, closeReason(reason, retryAfter))
+	c.mu.Unlock()
+	return c.Close()
+}
+
 // IsClosed 检查连接是否已关闭
 func (c *Connection) IsClosed() bool {
 	c.mu.RLock()
@@ -108,8 +167,13 @@ func (c *Connection) IsClosed() bool {
 	return c.closed
 }
 
-// SendMessage 发送消息
+// SendMessage 发送消息（聊天优先级）
 func (c *Connection) SendMessage(data []byte) error {
+	return c.SendPriority(data, PriorityChat)
+}
+
+// SendPriority 按优先级发送消息，对应队列已满时返回 ErrSendBufferFull
+func (c *Connection) SendPriority(data []byte, priority Priority) error {
 	c.mu.RLock()
 	if c.closed {
 		c.mu.RUnlock()
@@ -117,21 +181,23 @@ func (c *Connection) SendMessage(data []byte) error {
 	}
 	c.mu.RUnlock()
 
-	select {
-	case c.Send <- data:
-		return nil
-	default:
+	if !c.queue.push(priority, data) {
 		return ErrSendBufferFull
 	}
+	return nil
 }
 
-// SendJSON 发送JSON消息
+// SendJSON 发送JSON消息，model.Message 按消息类型确定优先级
 func (c *Connection) SendJSON(v interface{}) error {
 	data, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
-	return c.SendMessage(data)
+	priority := PriorityChat
+	if msg, ok := v.(*model.Message); ok {
+		priority = MessagePriority(msg)
+	}
+	return c.SendPriority(data, priority)
 }
 
 // UpdateLastActive 更新最后活跃时间
@@ -162,11 +228,52 @@ func (c *Connection) SetDeviceID(deviceID string) {
 	c.mu.Unlock()
 }
 
+// SetClientInfo 设置客户端元数据
+func (c *Connection) SetClientInfo(info ClientInfo) {
+	c.mu.Lock()
+	c.ClientInfo = info
+	c.mu.Unlock()
+}
+
+// HasCapability 客户端是否声明支持指定能力
+func (c *Connection) HasCapability(capability string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, item := range c.ClientInfo.Capabilities {
+		if item == capability {
+			return true
+		}
+	}
+	return false
+}
+
 // Done 返回关闭信号通道
 func (c *Connection) Done() <-chan struct{} {
 	return c.closedCh
 }
 
+// SendData 发送数据（实现Conn接口）
+func (c *Connection) SendData(data []byte) error {
+	return c.SendMessage(data)
+}
+
+// CloseConn 关闭连接（实现Conn接口）
+func (c *Connection) CloseConn() error {
+	return c.Close()
+}
+
+// GetUserID 获取用户ID（实现Conn接口）
+func (c *Connection) GetUserID() string {
+	return c.UserID
+}
+
+// GetPlatform 获取平台（实现Conn接口）
+func (c *Connection) GetPlatform() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Platform
+}
+
 // ConnectionManager 连接管理器
 type ConnectionManager struct {
 	connections sync.Map // map[userID]*Connection
@@ -179,6 +286,12 @@ type ConnectionManager struct {
 	totalConnections int64
 	activeUsers      int64
 
+	// 节点连接登记（为空时不登记）
+	registry *ConnectionRegistry
+
+	// 排空中：拒绝新连接
+	draining int32
+
 	// 回调函数
 	onConnect    func(*Connection)
 	onDisconnect func(*Connection)
@@ -205,8 +318,9 @@ func (m *ConnectionManager) Register(conn *Connection) {
 		kickMsg := &model.Message{
 			Type: model.MsgKickout,
 			Content: &model.KickoutContent{
-				Reason:   "您的账号在其他设备登录",
-				DeviceID: conn.DeviceID,
+				Reason:     i18n.T(oldConn.Locale, i18n.KeyKickoutOtherDevice),
+				ReasonCode: i18n.KeyKickoutOtherDevice,
+				DeviceID:   conn.DeviceID,
 			},
 			Timestamp: time.Now().UnixMilli(),
 		}
@@ -219,6 +333,12 @@ func (m *ConnectionManager) Register(conn *Connection) {
 	m.connections.Store(conn.UserID, conn)
 	m.connByID.Store(conn.ID, conn)
 
+	if m.registry != nil {
+		if err := m.registry.Add(context.Background(), conn); err != nil {
+			log.Printf("registry add connection %s error: %v", conn.ID, err)
+		}
+	}
+
 	// 更新统计
 	m.mu.Lock()
 	m.totalConnections++
@@ -241,6 +361,12 @@ func (m *ConnectionManager) Unregister(conn *Connection) {
 	}
 	m.connByID.Delete(conn.ID)
 
+	if m.registry != nil {
+		if err := m.registry.Remove(context.Background(), conn); err != nil {
+			log.Printf("registry remove connection %s error: %v", conn.ID, err)
+		}
+	}
+
 	// 更新统计
 	m.mu.Lock()
 	m.activeUsers = m.countActiveUsers()
@@ -305,11 +431,11 @@ func (m *ConnectionManager) IsOnline(userID string) bool {
 	return ok
 }
 
-// Broadcast 广播消息给所有连接
+// Broadcast 广播消息给所有连接（批量优先级）
 func (m *ConnectionManager) Broadcast(data []byte) {
 	m.connections.Range(func(key, value interface{}) bool {
 		conn := value.(*Connection)
-		conn.SendMessage(data)
+		conn.SendPriority(data, PriorityBulk)
 		return true
 	})
 }
@@ -318,7 +444,7 @@ func (m *ConnectionManager) Broadcast(data []byte) {
 func (m *ConnectionManager) BroadcastToUsers(userIDs []string, data []byte) {
 	for _, userID := range userIDs {
 		if conn, ok := m.GetConnection(userID); ok {
-			conn.SendMessage(data)
+			conn.SendPriority(data, PriorityBulk)
 		}
 	}
 }
@@ -417,17 +543,85 @@ func (m *ConnectionManager) StartHeartbeatChecker(ctx context.Context, checkInte
 			return
 		case <-ticker.C:
 			m.CleanIdleConnections(idleTimeout)
+			if m.registry != nil {
+				if err := m.registry.Reconcile(ctx, m.GetAllConnections()); err != nil {
+					log.Printf("reconcile connection registry error: %v", err)
+				}
+			}
 		}
 	}
 }
 
-// CloseAll 关闭所有连接
+// SetRegistry 设置节点连接登记表
+func (m *ConnectionManager) SetRegistry(registry *ConnectionRegistry) {
+	m.registry = registry
+}
+
+// Drain 排空节点：拒绝新连接，通知并断开现有连接，客户端重连到其他节点
+func (m *ConnectionManager) Drain() int {
+	atomic.StoreInt32(&m.draining, 1)
+
+	drained := 0
+	for _, conn := range m.GetAllConnections() {
+		conn.SendJSON(&model.Message{
+			Type: model.MsgKickout,
+			Content: &model.KickoutContent{
+				Reason:     i18n.T(conn.Locale, i18n.KeyKickoutNodeDrain),
+				ReasonCode: i18n.KeyKickoutNodeDrain,
+			},
+			Timestamp: time.Now().UnixMilli(),
+		})
+		conn.CloseWithHint(websocket.CloseServiceRestart, "node_drain", spreadRetryAfter(drainRetryAfterMax))
+		drained++
+	}
+	return drained
+}
+
+// KickUser 通知并断开用户在本节点上的连接（强制下线），返回是否存在连接
+func (m *ConnectionManager) KickUser(userID string) bool {
+	conn, ok := m.GetConnection(userID)
+	if !ok {
+		return false
+	}
+	conn.SendJSON(&model.Message{
+		Type: model.MsgKickout,
+		Content: &model.KickoutContent{
+			Reason:     i18n.T(conn.Locale, i18n.KeyKickoutForceLogout),
+			ReasonCode: i18n.KeyKickoutForceLogout,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+	conn.Close()
+	return true
+}
+
+// IsDraining 节点是否正在排空
+func (m *ConnectionManager) IsDraining() bool {
+	return atomic.LoadInt32(&m.draining) == 1
+}
+
+// CloseAll 关闭所有连接（节点停机），关闭帧中给出分散的重连建议
 func (m *ConnectionManager) CloseAll() {
 	m.connections.Range(func(key, value interface{}) bool {
 		conn := value.(*Connection)
-		conn.Close()
+		conn.CloseWithHint(websocket.CloseGoingAway, "shutdown", spreadRetryAfter(drainRetryAfterMax))
+		return true
+	})
+}
+
+// SendQueueUsage 发送队列总体占用率（0-1）
+func (m *ConnectionManager) SendQueueUsage() float64 {
+	var queued, capacity int
+	m.connections.Range(func(key, value interface{}) bool {
+		q, c := value.(*Connection).queue.usage()
+		queued += q
+		capacity += c
 		return true
 	})
+	if capacity == 0 {
+		return 0
+	}
+	return float64(queued) / float64(capacity)
 }
 
 // 错误定义
@@ -0,0 +1,257 @@
+package gateway
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// CapabilityAck 客户端能力：收到 qos=1 的消息后回复 type 30 ACK，服务端据此跟踪投递并超时重发
+const CapabilityAck = "ack"
+
+// 转存离线消息的原因
+const (
+	escalateRetries    = "retries"    // 多次重发仍未确认
+	escalateDisconnect = "disconnect" // 连接断开时仍未确认
+	escalateOverflow   = "overflow"   // 等待确认的消息超出上限
+)
+
+// DeliveryConfig 投递确认配置
+type DeliveryConfig struct {
+	AckTimeout    time.Duration // 等待客户端ACK的超时时间，超时后重发
+	MaxRetries    int           // 最多重发次数，仍未确认时转存离线消息
+	MaxInFlight   int           // 每个连接最多等待确认的消息数，超出时最早的消息转存离线消息
+	CheckInterval time.Duration // 超时检查间隔
+}
+
+// DefaultDeliveryConfig 默认投递确认配置
+func DefaultDeliveryConfig() *DeliveryConfig {
+	return &DeliveryConfig{
+		AckTimeout:    10 * time.Second,
+		MaxRetries:    3,
+		MaxInFlight:   256,
+		CheckInterval: time.Second,
+	}
+}
+
+// ackCapable 可查询客户端能力的连接
+type ackCapable interface {
+	HasCapability(capability string) bool
+}
+
+// inflightMessage 等待客户端确认的消息
+type inflightMessage struct {
+	msg      *model.Message
+	data     []byte
+	priority Priority
+	sentAt   time.Time
+	deadline time.Time
+	retries  int
+}
+
+// connInflight 一个连接上等待确认的消息
+type connInflight struct {
+	userID   string
+	messages map[string]*inflightMessage // messageID -> 消息
+}
+
+// deliveryTracker 投递确认跟踪：按连接记录已推送、等待ACK的 QoS1 消息，超时只在推送时的连接上重发，
+// 多次重发仍未确认或连接断开、被同一用户的新连接替换时转存离线消息，不会在新连接上重发旧连接的消息
+type deliveryTracker struct {
+	config       *DeliveryConfig
+	offlineSaver OfflineMessageSaver
+
+	mu       sync.Mutex
+	inflight map[Conn]*connInflight
+	count    int
+}
+
+// newDeliveryTracker 创建投递确认跟踪
+func newDeliveryTracker(config *DeliveryConfig, offlineSaver OfflineMessageSaver) *deliveryTracker {
+	defaults := DefaultDeliveryConfig()
+	if config.MaxInFlight <= 0 {
+		config.MaxInFlight = defaults.MaxInFlight
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = defaults.CheckInterval
+	}
+	return &deliveryTracker{
+		config:       config,
+		offlineSaver: offlineSaver,
+		inflight:     make(map[Conn]*connInflight),
+	}
+}
+
+// track 记录推送到连接的消息；只跟踪 qos=1 且客户端声明支持ACK的连接
+func (t *deliveryTracker) track(conn Conn, msg *model.Message, data []byte, priority Priority) {
+	if msg == nil || msg.QoS != model.QoSAtLeastOnce || msg.MessageID == "" {
+		return
+	}
+	if c, ok := conn.(ackCapable); !ok || !c.HasCapability(CapabilityAck) {
+		return
+	}
+
+	now := time.Now()
+	var evicted *inflightMessage
+	t.mu.Lock()
+	entry, ok := t.inflight[conn]
+	if !ok {
+		entry = &connInflight{userID: conn.GetUserID(), messages: make(map[string]*inflightMessage)}
+		t.inflight[conn] = entry
+	}
+	if _, exists := entry.messages[msg.MessageID]; !exists {
+		if len(entry.messages) >= t.config.MaxInFlight {
+			evicted = t.removeOldestLocked(entry.messages)
+		}
+		entry.messages[msg.MessageID] = &inflightMessage{
+			msg:      msg,
+			data:     data,
+			priority: priority,
+			sentAt:   now,
+			deadline: now.Add(t.config.AckTimeout),
+		}
+		t.count++
+	}
+	deliveryInFlight.Set(float64(t.count))
+	t.mu.Unlock()
+
+	if evicted != nil {
+		t.escalate(entry.userID, []*inflightMessage{evicted}, escalateOverflow)
+	}
+}
+
+// removeOldestLocked 移除最早推送的消息（调用方持有锁）
+func (t *deliveryTracker) removeOldestLocked(messages map[string]*inflightMessage) *inflightMessage {
+	var oldest *inflightMessage
+	for _, m := range messages {
+		if oldest == nil || m.sentAt.Before(oldest.sentAt) {
+			oldest = m
+		}
+	}
+	if oldest != nil {
+		delete(messages, oldest.msg.MessageID)
+		t.count--
+	}
+	return oldest
+}
+
+// ack 客户端在连接上确认收到消息，返回首次推送到确认的耗时及是否为该连接等待确认的消息
+func (t *deliveryTracker) ack(conn Conn, messageID string) (time.Duration, bool) {
+	var m *inflightMessage
+	t.mu.Lock()
+	entry, ok := t.inflight[conn]
+	if ok {
+		if m, ok = entry.messages[messageID]; ok {
+			delete(entry.messages, messageID)
+			if len(entry.messages) == 0 {
+				delete(t.inflight, conn)
+			}
+			t.count--
+			deliveryInFlight.Set(float64(t.count))
+		}
+	}
+	t.mu.Unlock()
+
+	if !ok {
+		return 0, false
+	}
+	latency := time.Since(m.sentAt)
+	deliveryAckedTotal.Inc()
+	deliveryAckLatency.Observe(latency.Seconds())
+	return latency, true
+}
+
+// release 连接断开或被替换：该连接等待确认的消息全部转存离线消息
+func (t *deliveryTracker) release(conn Conn) {
+	t.mu.Lock()
+	entry, ok := t.inflight[conn]
+	if ok {
+		delete(t.inflight, conn)
+		t.count -= len(entry.messages)
+		deliveryInFlight.Set(float64(t.count))
+	}
+	t.mu.Unlock()
+
+	if !ok || len(entry.messages) == 0 {
+		return
+	}
+	pending := make([]*inflightMessage, 0, len(entry.messages))
+	for _, m := range entry.messages {
+		pending = append(pending, m)
+	}
+	t.escalate(entry.userID, pending, escalateDisconnect)
+}
+
+// run 定时检查超时未确认的消息，直到 stop 关闭
+func (t *deliveryTracker) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(t.config.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			t.checkTimeouts(now)
+		}
+	}
+}
+
+// checkTimeouts 在推送时的连接上重发超时未确认的消息，超过重发次数的转存离线消息
+func (t *deliveryTracker) checkTimeouts(now time.Time) {
+	resend := make(map[Conn][]*inflightMessage)
+	expired := make(map[string][]*inflightMessage)
+
+	t.mu.Lock()
+	for conn, entry := range t.inflight {
+		for messageID, m := range entry.messages {
+			if now.Before(m.deadline) {
+				continue
+			}
+			if m.retries >= t.config.MaxRetries {
+				delete(entry.messages, messageID)
+				t.count--
+				expired[entry.userID] = append(expired[entry.userID], m)
+				continue
+			}
+			m.retries++
+			m.deadline = now.Add(t.config.AckTimeout)
+			resend[conn] = append(resend[conn], m)
+		}
+		if len(entry.messages) == 0 {
+			delete(t.inflight, conn)
+		}
+	}
+	deliveryInFlight.Set(float64(t.count))
+	t.mu.Unlock()
+
+	for conn, pending := range resend {
+		for _, m := range pending {
+			if err := conn.SendPriority(m.data, m.priority); err != nil {
+				log.Printf("retransmit message %s to user %s error: %v", m.msg.MessageID, conn.GetUserID(), err)
+				continue
+			}
+			deliveryRetransmitTotal.Inc()
+		}
+	}
+	for userID, pending := range expired {
+		t.escalate(userID, pending, escalateRetries)
+	}
+}
+
+// escalate 未确认的消息转存离线消息，客户端上线或拉取离线消息时再次获取（按 message_id 去重）
+func (t *deliveryTracker) escalate(userID string, pending []*inflightMessage, reason string) {
+	deliveryEscalatedTotal.WithLabelValues(reason).Add(float64(len(pending)))
+	if t.offlineSaver == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, m := range pending {
+		if err := t.offlineSaver.SaveOfflineMessage(ctx, userID, m.msg); err != nil {
+			log.Printf("save unacknowledged message %s for user %s error: %v", m.msg.MessageID, userID, err)
+		}
+	}
+}
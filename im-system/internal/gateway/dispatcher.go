@@ -10,7 +10,9 @@ import (
 	"time"
 
 	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/pkg/tracing"
 	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // MessageDispatcher 消息分发器接口
@@ -21,6 +23,9 @@ type MessageDispatcher interface {
 	// DispatchToConversation 分发消息到会话（根据会话类型分发）
 	DispatchToConversation(ctx context.Context, conversationID string, msg *model.Message, excludeUserID string) error
 
+	// DispatchEphemeral 分发临时消息：只投递给在线的会话成员，不保存离线消息，发送者必须是会话成员
+	DispatchEphemeral(ctx context.Context, conversationID string, msg *model.Message, senderID string) error
+
 	// SubscribeNodeMessages 订阅本节点的消息
 	SubscribeNodeMessages(ctx context.Context) error
 
@@ -33,21 +38,61 @@ type MessageDispatcher interface {
 	// IsUserOnline 检查用户是否在线
 	IsUserOnline(ctx context.Context, userID string) (bool, error)
 
+	// LastSeen 获取用户最近一次下线的时间，没有记录时返回零值
+	LastSeen(ctx context.Context, userID string) (time.Time, error)
+
 	// GetUserNode 获取用户所在节点
 	GetUserNode(ctx context.Context, userID string) (string, error)
 
+	// BroadcastToAllNodes 广播消息给所有节点的所有用户
+	BroadcastToAllNodes(ctx context.Context, msg *model.Message) error
+
+	// BroadcastToPlatforms 广播消息给所有节点上指定平台的用户（platforms为空表示不限平台）
+	BroadcastToPlatforms(ctx context.Context, msg *model.Message, platforms []string) error
+
+	// BroadcastToNode 广播消息给指定节点上的用户（platforms为空表示不限平台）
+	BroadcastToNode(ctx context.Context, nodeID string, msg *model.Message, platforms []string) error
+
+	// SendNodeControl 发送节点控制指令（如排空）
+	SendNodeControl(ctx context.Context, nodeID, action string) error
+
+	// SetOnNodeControl 设置本节点收到控制指令时的回调
+	SetOnNodeControl(fn func(action string))
+
+	// SetOnDispatch 设置消息分发事件回调（每次发起分发时调用一次）
+	SetOnDispatch(fn DispatchObserver)
+
+	// SetFanoutLoadSampler 设置节点过载程度采样函数（0-1），过载时缩减广播、群事件的扇出预算
+	SetFanoutLoadSampler(fn func() float64)
+
+	// SetMuteChecker 设置会话免打扰查询，设置后免打扰会话的聊天消息以批量优先级投递给对应用户
+	SetMuteChecker(checker MuteChecker)
+
+	// SetBroker 设置跨节点消息通道（默认 Redis Pub/Sub），须在 SubscribeNodeMessages 之前调用
+	SetBroker(broker MessageBroker)
+
+	// AckDelivery 客户端在连接上确认收到 QoS1 消息，返回首次推送到确认的耗时及是否为该连接等待确认的消息
+	AckDelivery(conn Conn, messageID string) (time.Duration, bool)
+
 	// Close 关闭分发器
 	Close() error
 }
 
+// DispatchObserver 消息分发事件回调，在分发调用方的协程中同步执行，须快速返回
+type DispatchObserver func(ctx context.Context, msg *model.Message)
+
 // Conn 连接接口（用于消息分发）
 type Conn interface {
 	// SendData 发送消息
 	SendData(data []byte) error
+	// SendPriority 按优先级发送消息
+	SendPriority(data []byte, priority Priority) error
 	// CloseConn 关闭连接
 	CloseConn() error
 	// GetUserID 获取用户ID
 	GetUserID() string
+	// GetPlatform 获取连接平台
+	GetPlatform() string
 }
 
 // GroupMemberGetter 群成员获取接口
@@ -56,18 +101,37 @@ type GroupMemberGetter interface {
 	GetGroupMemberIDs(ctx context.Context, groupID string) ([]string, error)
 }
 
+// MuteChecker 会话免打扰查询接口
+type MuteChecker interface {
+	// MutedUsers 返回 userIDs 中对会话设置了免打扰的用户
+	MutedUsers(ctx context.Context, conversationID string, userIDs []string) (map[string]bool, error)
+}
+
+// muteCheckTimeout 分发时查询会话免打扰的超时时间，超时按未免打扰投递
+const muteCheckTimeout = 500 * time.Millisecond
+
 // OfflineMessageSaver 离线消息保存接口
 type OfflineMessageSaver interface {
 	// SaveOfflineMessage 保存离线消息
 	SaveOfflineMessage(ctx context.Context, userID string, msg *model.Message) error
 }
 
+// lastSeenTTL 用户下线时间记录的保留时间
+const lastSeenTTL = 90 * 24 * time.Hour
+
 // DispatcherConfig 分发器配置
 type DispatcherConfig struct {
 	NodeID                 string        // 节点ID
 	OnlineKeyExpire        time.Duration // 在线状态过期时间
-	PublishChannelPrefix   string        // 发布频道前缀
-	SubscribeChannelPrefix string        // 订阅频道前缀
+	PublishChannelPrefix   string        // 发布频道前缀（Redis Pub/Sub 通道）
+	SubscribeChannelPrefix string        // 订阅频道前缀，为空时与发布频道前缀相同
+	RouteQueueSize         int           // 跨节点路由消息每个优先级的队列容量
+	StarvationLimit        int           // 低优先级有积压时，高优先级最多连续处理的条数
+
+	// FanoutPace 广播、群事件扇出限速，为空时不限速
+	FanoutPace *FanoutPaceConfig
+	// Delivery QoS1 消息投递确认（超时重发、转存离线），为空时不跟踪
+	Delivery *DeliveryConfig
 }
 
 // DefaultDispatcherConfig 默认配置
@@ -77,6 +141,9 @@ func DefaultDispatcherConfig() *DispatcherConfig {
 		OnlineKeyExpire:        time.Hour,
 		PublishChannelPrefix:   "im:node:",
 		SubscribeChannelPrefix: "im:node:",
+		RouteQueueSize:         1024,
+		StarvationLimit:        defaultStarvationLimit,
+		FanoutPace:             DefaultFanoutPaceConfig(),
 	}
 }
 
@@ -88,9 +155,18 @@ type messageDispatcherImpl struct {
 	connMutex         sync.RWMutex
 	groupMemberGetter GroupMemberGetter
 	offlineSaver      OfflineMessageSaver
-	pubsub            *redis.PubSub
+	broker            MessageBroker             // 跨节点消息通道
+	routeQueue        *laneQueue[*RouteMessage] // 订阅收到的路由消息，按优先级处理
 	stopChan          chan struct{}
 	wg                sync.WaitGroup
+	onNodeControl     func(action string)
+	onDispatch        DispatchObserver
+	pacer             *fanoutPacer     // 广播、群事件扇出限速，为空时直接投递
+	delivery          *deliveryTracker // QoS1 投递确认跟踪，为空时不跟踪
+
+	muteChecker MuteChecker // 会话免打扰查询，为空时不区分免打扰
+
+	members *conversationMemberCache // 会话路由消息按成员摘要缓存的会话成员
 }
 
 // NewMessageDispatcher 创建消息分发器
@@ -103,23 +179,53 @@ func NewMessageDispatcher(
 	if config == nil {
 		config = DefaultDispatcherConfig()
 	}
+	queueSize := config.RouteQueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultDispatcherConfig().RouteQueueSize
+	}
 
-	return &messageDispatcherImpl{
+	d := &messageDispatcherImpl{
 		config:            config,
 		redis:             redisClient,
 		localConns:        make(map[string]Conn),
 		groupMemberGetter: groupMemberGetter,
 		offlineSaver:      offlineSaver,
+		broker:            NewRedisBroker(redisClient, config.PublishChannelPrefix, config.SubscribeChannelPrefix),
+		routeQueue:        newLaneQueue[*RouteMessage]("dispatcher", [numPriorities]int{queueSize, queueSize, queueSize}, config.StarvationLimit),
 		stopChan:          make(chan struct{}),
+		members:           newConversationMemberCache(),
 	}
+	if pace := config.FanoutPace; pace != nil && (pace.MessagesPerSecond > 0 || pace.BytesPerSecond > 0) {
+		d.pacer = newFanoutPacer(pace)
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.pacer.run(d.stopChan)
+		}()
+	}
+	if config.Delivery != nil && config.Delivery.AckTimeout > 0 {
+		d.delivery = newDeliveryTracker(config.Delivery, offlineSaver)
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.delivery.run(d.stopChan)
+		}()
+	}
+	return d
 }
 
 // RegisterConnection 注册用户连接
 func (d *messageDispatcherImpl) RegisterConnection(userID string, conn Conn) error {
 	d.connMutex.Lock()
+	prev, replaced := d.localConns[userID]
 	d.localConns[userID] = conn
 	d.connMutex.Unlock()
 
+	// 被替换的旧连接不再接收分发，其等待确认的消息转存离线消息，不在新连接上重发
+	if replaced && prev != conn && d.delivery != nil {
+		d.delivery.release(prev)
+	}
+
 	// 在Redis中记录用户在线状态
 	ctx := context.Background()
 	onlineKey := fmt.Sprintf("online:%s", userID)
@@ -131,14 +237,36 @@ func (d *messageDispatcherImpl) RegisterConnection(userID string, conn Conn) err
 // UnregisterConnection 注销用户连接
 func (d *messageDispatcherImpl) UnregisterConnection(userID string) error {
 	d.connMutex.Lock()
+	conn, ok := d.localConns[userID]
 	delete(d.localConns, userID)
 	d.connMutex.Unlock()
 
-	// 从Redis中删除用户在线状态
+	// 等待确认的消息转存离线消息
+	if ok && d.delivery != nil {
+		d.delivery.release(conn)
+	}
+
+	// 从Redis中删除用户在线状态，并记录下线时间
 	ctx := context.Background()
 	onlineKey := fmt.Sprintf("online:%s", userID)
 
-	return d.redis.Del(ctx, onlineKey).Err()
+	pipe := d.redis.TxPipeline()
+	pipe.Del(ctx, onlineKey)
+	pipe.Set(ctx, fmt.Sprintf("last_seen:%s", userID), time.Now().Unix(), lastSeenTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// LastSeen 获取用户最近一次下线的时间
+func (d *messageDispatcherImpl) LastSeen(ctx context.Context, userID string) (time.Time, error) {
+	seconds, err := d.redis.Get(ctx, fmt.Sprintf("last_seen:%s", userID)).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, 0), nil
 }
 
 // IsUserOnline 检查用户是否在线
@@ -170,6 +298,25 @@ func (d *messageDispatcherImpl) GetUserNode(ctx context.Context, userID string)
 
 // DispatchToUsers 分发消息给指定用户
 func (d *messageDispatcherImpl) DispatchToUsers(ctx context.Context, userIDs []string, msg *model.Message) error {
+	ctx, span := startDispatchSpan(ctx, "dispatcher.dispatch_to_users", msg)
+	span.SetAttributes(tracing.AttrRecipients.Int(len(userIDs)))
+
+	d.notifyDispatch(ctx, msg)
+	err := d.dispatchToUsers(ctx, userIDs, msg, true)
+	tracing.End(span, err)
+	return err
+}
+
+// startDispatchSpan 创建分发消息的 Span
+func startDispatchSpan(ctx context.Context, name string, msg *model.Message) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name, trace.WithAttributes(
+		tracing.AttrMessageID.String(msg.MessageID),
+		tracing.AttrConversationID.String(msg.ConversationID),
+	))
+}
+
+// dispatchToUsers 逐个用户分发消息：本地推送、跨节点发布或保存离线消息（saveOffline 为 false 时丢弃）
+func (d *messageDispatcherImpl) dispatchToUsers(ctx context.Context, userIDs []string, msg *model.Message, saveOffline bool) error {
 	if len(userIDs) == 0 {
 		return nil
 	}
@@ -178,20 +325,20 @@ func (d *messageDispatcherImpl) DispatchToUsers(ctx context.Context, userIDs []s
 	if err != nil {
 		return fmt.Errorf("marshal message error: %w", err)
 	}
+	// 先投递本地用户，其余用户查询所在节点
+	remaining := d.deliverLocal(userIDs, msg, data, MessagePriority(msg), fanoutKind(msg))
+	if len(remaining) == 0 {
+		return nil
+	}
 
 	var wg sync.WaitGroup
-	errChan := make(chan error, len(userIDs))
+	errChan := make(chan error, len(remaining))
 
-	for _, userID := range userIDs {
+	for _, userID := range remaining {
 		wg.Add(1)
 		go func(uid string) {
 			defer wg.Done()
 
-			// 尝试本地推送
-			if d.pushToLocalUser(uid, data) {
-				return
-			}
-
 			// 检查用户是否在其他节点
 			nodeID, err := d.GetUserNode(ctx, uid)
 			if err != nil {
@@ -206,7 +353,7 @@ func (d *messageDispatcherImpl) DispatchToUsers(ctx context.Context, userIDs []s
 				}
 			} else {
 				// 用户不在线，保存离线消息
-				if d.offlineSaver != nil {
+				if saveOffline && d.offlineSaver != nil {
 					if err := d.offlineSaver.SaveOfflineMessage(ctx, uid, msg); err != nil {
 						errChan <- fmt.Errorf("save offline message error: %w", err)
 					}
@@ -232,90 +379,298 @@ func (d *messageDispatcherImpl) DispatchToUsers(ctx context.Context, userIDs []s
 }
 
 // DispatchToConversation 分发消息到会话
-func (d *messageDispatcherImpl) DispatchToConversation(ctx context.Context, conversationID string, msg *model.Message, excludeUserID string) error {
-	var targetUserIDs []string
-
-	// 根据会话类型获取目标用户
-	// 支持两种格式: "group:group_xxx" 或 "group_xxx"
-	var groupID string
-	if len(conversationID) > 6 && conversationID[:6] == "group:" {
-		// 格式: group:group_xxx
-		groupID = conversationID[6:]
-	} else if len(conversationID) > 6 && conversationID[:6] == "group_" {
-		// 格式: group_xxx
-		groupID = conversationID
-	}
-
-	if groupID != "" {
-		// 群聊会话
-		if d.groupMemberGetter != nil {
-			memberIDs, err := d.groupMemberGetter.GetGroupMemberIDs(ctx, groupID)
-			if err != nil {
-				return fmt.Errorf("get group members error: %w", err)
-			}
-			targetUserIDs = memberIDs
+func (d *messageDispatcherImpl) DispatchToConversation(ctx context.Context, conversationID string, msg *model.Message, excludeUserID string) (err error) {
+	ctx, span := startDispatchSpan(ctx, "dispatcher.dispatch_to_conversation", msg)
+	defer func() { tracing.End(span, err) }()
+
+	d.notifyDispatch(ctx, msg)
+
+	targetUserIDs, isGroup, err := d.resolveConversationMembers(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+
+	// 排除发送者
+	digest := memberDigest(targetUserIDs)
+	targetUserIDs = excludeUser(targetUserIDs, excludeUserID)
+	span.SetAttributes(tracing.AttrRecipients.Int(len(targetUserIDs)))
+
+	// 群聊会话按节点发布一次会话路由消息，由接收节点解析本地成员
+	if isGroup {
+		return d.dispatchToGroup(ctx, conversationID, digest, targetUserIDs, msg, excludeUserID, true)
+	}
+
+	return d.dispatchToUsers(ctx, targetUserIDs, msg, true)
+}
+
+// DispatchEphemeral 分发临时消息（不触发分发回调，不计入会话统计）
+func (d *messageDispatcherImpl) DispatchEphemeral(ctx context.Context, conversationID string, msg *model.Message, senderID string) error {
+	members, isGroup, err := d.resolveConversationMembers(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	if !containsUser(members, senderID) {
+		return ErrNotConversationMember
+	}
+
+	targetUserIDs := excludeUser(members, senderID)
+	if isGroup {
+		return d.dispatchToGroup(ctx, conversationID, memberDigest(members), targetUserIDs, msg, senderID, false)
+	}
+	return d.dispatchToUsers(ctx, targetUserIDs, msg, false)
+}
+
+// resolveConversationMembers 解析会话成员，返回成员列表及是否为群聊
+func (d *messageDispatcherImpl) resolveConversationMembers(ctx context.Context, conversationID string) ([]string, bool, error) {
+	convID, err := model.ParseConversationID(conversationID)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	if !convID.IsGroup() {
+		return convID.Participants(), false, nil
+	}
+
+	if d.groupMemberGetter != nil {
+		memberIDs, err := d.groupMemberGetter.GetGroupMemberIDs(ctx, convID.GroupID)
+		if err != nil {
+			return nil, true, fmt.Errorf("get group members error: %w", err)
+		}
+		return memberIDs, true, nil
+	}
+
+	// 从Redis获取群成员
+	groupKey := fmt.Sprintf("group:members:%s", convID.GroupID)
+	members, err := d.redis.SMembers(ctx, groupKey).Result()
+	if err != nil {
+		return nil, true, fmt.Errorf("get group members from redis error: %w", err)
+	}
+	return members, true, nil
+}
+
+// containsUser 用户列表是否包含指定用户
+func containsUser(userIDs []string, userID string) bool {
+	for _, uid := range userIDs {
+		if uid == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeUser 从用户列表中排除指定用户
+func excludeUser(userIDs []string, excludeUserID string) []string {
+	if excludeUserID == "" {
+		return userIDs
+	}
+	filtered := make([]string, 0, len(userIDs))
+	for _, uid := range userIDs {
+		if uid != excludeUserID {
+			filtered = append(filtered, uid)
+		}
+	}
+	return filtered
+}
+
+// dispatchToGroup 分发群消息：本地直接推送，远端每个节点只发布一次（附带会话成员摘要 digest），
+// 离线用户保存离线消息（saveOffline 为 false 时丢弃）
+func (d *messageDispatcherImpl) dispatchToGroup(ctx context.Context, conversationID string, digest uint64, userIDs []string, msg *model.Message, excludeUserID string, saveOffline bool) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal message error: %w", err)
+	}
+
+	// 先推送本地用户，剩余用户批量查询所在节点
+	remaining := d.deliverLocal(userIDs, msg, data, MessagePriority(msg), fanoutKind(msg))
+
+	userNodes, err := d.getUserNodes(ctx, remaining)
+	if err != nil {
+		return err
+	}
+
+	nodeUsers := make(map[string][]string)
+	var offlineUsers []string
+	for _, uid := range remaining {
+		nodeID := userNodes[uid]
+		if nodeID != "" && nodeID != d.config.NodeID {
+			nodeUsers[nodeID] = append(nodeUsers[nodeID], uid)
 		} else {
-			// 从Redis获取群成员
-			groupKey := fmt.Sprintf("group:members:%s", groupID)
-			members, err := d.redis.SMembers(ctx, groupKey).Result()
-			if err != nil {
-				return fmt.Errorf("get group members from redis error: %w", err)
-			}
-			targetUserIDs = members
+			offlineUsers = append(offlineUsers, uid)
+		}
+	}
+
+	var errs []error
+	for nodeID, uids := range nodeUsers {
+		if err := d.publishConversationToNode(ctx, nodeID, conversationID, digest, excludeUserID, uids, msg); err != nil {
+			errs = append(errs, fmt.Errorf("publish to node error: %w", err))
 		}
 	}
 
-	if len(conversationID) > 7 && conversationID[:7] == "single_" {
-		// 单聊会话，提取两个用户ID
-		parts := conversationID[7:] // 去掉 "single_" 前缀
-		// 格式: userID1_userID2
-		for i := len(parts) - 1; i >= 0; i-- {
-			if parts[i] == '_' {
-				targetUserIDs = []string{parts[:i], parts[i+1:]}
-				break
+	if saveOffline && d.offlineSaver != nil {
+		for _, uid := range offlineUsers {
+			if err := d.offlineSaver.SaveOfflineMessage(ctx, uid, msg); err != nil {
+				errs = append(errs, fmt.Errorf("save offline message error: %w", err))
 			}
 		}
 	}
 
-	// 排除发送者
-	if excludeUserID != "" {
-		filtered := make([]string, 0, len(targetUserIDs))
-		for _, uid := range targetUserIDs {
-			if uid != excludeUserID {
-				filtered = append(filtered, uid)
+	if len(errs) > 0 {
+		return fmt.Errorf("dispatch errors: %v", errs)
+	}
+
+	return nil
+}
+
+// getUserNodes 批量获取用户所在节点（未在线的用户不在结果中）
+func (d *messageDispatcherImpl) getUserNodes(ctx context.Context, userIDs []string) (map[string]string, error) {
+	result := make(map[string]string, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	pipe := d.redis.Pipeline()
+	cmds := make([]*redis.StringCmd, len(userIDs))
+	for i, uid := range userIDs {
+		cmds[i] = pipe.Get(ctx, fmt.Sprintf("online:%s", uid))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("get user nodes error: %w", err)
+	}
+
+	for i, cmd := range cmds {
+		val, err := cmd.Result()
+		if err != nil {
+			continue
+		}
+		var nodeID string
+		fmt.Sscanf(val, "%[^:]", &nodeID)
+		result[userIDs[i]] = nodeID
+	}
+
+	return result, nil
+}
+
+// deliverLocal 投递给本节点的用户，返回不在本节点（或发送失败）的用户；
+// 群事件等批量扇出交给限速器排队投递，避免挤占直接消息
+func (d *messageDispatcherImpl) deliverLocal(userIDs []string, msg *model.Message, data []byte, priority Priority, kind string) []string {
+	muted := d.mutedRecipients(userIDs, msg, priority)
+	if len(muted) == 0 {
+		return d.deliverLocalPriority(userIDs, msg, data, priority, kind)
+	}
+
+	// 免打扰会话的聊天消息降为批量优先级，不占用用户其他会话消息的发送配额
+	normal := make([]string, 0, len(userIDs)-len(muted))
+	quiet := make([]string, 0, len(muted))
+	for _, uid := range userIDs {
+		if muted[uid] {
+			quiet = append(quiet, uid)
+		} else {
+			normal = append(normal, uid)
+		}
+	}
+	remaining := d.deliverLocalPriority(normal, msg, data, priority, kind)
+	return append(remaining, d.deliverLocalPriority(quiet, msg, data, PriorityBulk, kind)...)
+}
+
+// mutedRecipients 查询接收者中对消息所在会话设置了免打扰的用户，只检查聊天优先级的会话消息
+func (d *messageDispatcherImpl) mutedRecipients(userIDs []string, msg *model.Message, priority Priority) map[string]bool {
+	if d.muteChecker == nil || priority != PriorityChat || msg.ConversationID == "" || len(userIDs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), muteCheckTimeout)
+	defer cancel()
+	muted, err := d.muteChecker.MutedUsers(ctx, msg.ConversationID, userIDs)
+	if err != nil {
+		log.Printf("check muted users of conversation %s error: %v", msg.ConversationID, err)
+		return nil
+	}
+	return muted
+}
+
+// deliverLocalPriority 按指定优先级投递给本节点的用户，返回不在本节点（或发送失败）的用户
+func (d *messageDispatcherImpl) deliverLocalPriority(userIDs []string, msg *model.Message, data []byte, priority Priority, kind string) []string {
+	if kind == "" || d.pacer == nil {
+		remaining := make([]string, 0, len(userIDs))
+		for _, uid := range userIDs {
+			if !d.pushToLocalUser(uid, msg, data, priority) {
+				remaining = append(remaining, uid)
 			}
 		}
-		targetUserIDs = filtered
+		return remaining
 	}
 
-	return d.DispatchToUsers(ctx, targetUserIDs, msg)
+	conns := make([]Conn, 0, len(userIDs))
+	remaining := make([]string, 0, len(userIDs))
+	d.connMutex.RLock()
+	for _, uid := range userIDs {
+		if conn, ok := d.localConns[uid]; ok {
+			conns = append(conns, conn)
+		} else {
+			remaining = append(remaining, uid)
+		}
+	}
+	d.connMutex.RUnlock()
+
+	if len(conns) > 0 {
+		d.pacer.enqueue(&fanoutJob{kind: kind, data: data, priority: priority, conns: conns})
+		// 限速排队或丢弃的扇出同样等待确认，超时后重发
+		if d.delivery != nil {
+			for _, conn := range conns {
+				d.delivery.track(conn, msg, data, priority)
+			}
+		}
+	}
+	return remaining
 }
 
-// pushToLocalUser 推送消息给本地用户
-func (d *messageDispatcherImpl) pushToLocalUser(userID string, data []byte) bool {
+// localConn 获取用户在本节点的连接
+func (d *messageDispatcherImpl) localConn(userID string) (Conn, bool) {
 	d.connMutex.RLock()
+	defer d.connMutex.RUnlock()
 	conn, ok := d.localConns[userID]
-	d.connMutex.RUnlock()
+	return conn, ok
+}
 
+// pushToLocalUser 按优先级推送消息给本地用户，QoS1 消息记录等待客户端确认
+func (d *messageDispatcherImpl) pushToLocalUser(userID string, msg *model.Message, data []byte, priority Priority) bool {
+	conn, ok := d.localConn(userID)
 	if !ok {
 		return false
 	}
 
-	if err := conn.SendData(data); err != nil {
+	if err := conn.SendPriority(data, priority); err != nil {
 		log.Printf("send to user %s error: %v", userID, err)
 		return false
 	}
 
+	if d.delivery != nil {
+		d.delivery.track(conn, msg, data, priority)
+	}
 	return true
 }
 
+// AckDelivery 客户端在连接上确认收到 QoS1 消息
+func (d *messageDispatcherImpl) AckDelivery(conn Conn, messageID string) (time.Duration, bool) {
+	if d.delivery == nil {
+		return 0, false
+	}
+	return d.delivery.ack(conn, messageID)
+}
+
 // publishToNode 发布消息到指定节点
-func (d *messageDispatcherImpl) publishToNode(ctx context.Context, nodeID, targetUserID string, msg *model.Message) error {
-	channel := fmt.Sprintf("%s%s", d.config.PublishChannelPrefix, nodeID)
+func (d *messageDispatcherImpl) publishToNode(ctx context.Context, nodeID, targetUserID string, msg *model.Message) (err error) {
+	ctx, span := startPublishSpan(ctx, nodeID, msg)
+	defer func() { tracing.End(span, err) }()
 
 	routeMsg := &RouteMessage{
-		TargetUsers: []string{targetUserID},
-		Message:     msg,
+		TargetUsers:  []string{targetUserID},
+		Message:      msg,
+		TraceContext: tracing.Inject(ctx),
 	}
 
 	data, err := json.Marshal(routeMsg)
@@ -323,82 +678,202 @@ func (d *messageDispatcherImpl) publishToNode(ctx context.Context, nodeID, targe
 		return err
 	}
 
-	return d.redis.Publish(ctx, channel, data).Err()
+	if err := d.broker.Publish(ctx, nodeID, data); err != nil {
+		return err
+	}
+	recordRoutePublish(routeModeUser, len(data))
+	return nil
 }
 
-// SubscribeNodeMessages 订阅本节点的消息
-func (d *messageDispatcherImpl) SubscribeNodeMessages(ctx context.Context) error {
-	channel := fmt.Sprintf("%s%s", d.config.SubscribeChannelPrefix, d.config.NodeID)
+// startPublishSpan 创建向其他节点发布消息的 Span，链路上下文随路由消息传递给接收节点
+func startPublishSpan(ctx context.Context, nodeID string, msg *model.Message) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "dispatcher.publish", trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(
+		tracing.AttrNodeID.String(nodeID),
+		tracing.AttrMessageID.String(msg.MessageID),
+	))
+}
 
-	d.pubsub = d.redis.Subscribe(ctx, channel)
+// publishConversationToNode 以会话路由模式发布消息到指定节点，
+// 消息体只发送一次，接收节点根据会话ID解析本地成员
+func (d *messageDispatcherImpl) publishConversationToNode(ctx context.Context, nodeID, conversationID string, digest uint64, excludeUserID string, userIDs []string, msg *model.Message) (err error) {
+	ctx, span := startPublishSpan(ctx, nodeID, msg)
+	defer func() { tracing.End(span, err) }()
+	span.SetAttributes(tracing.AttrRecipients.Int(len(userIDs)))
 
-	// 等待订阅确认
-	_, err := d.pubsub.Receive(ctx)
+	routeMsg := &RouteMessage{
+		ConversationID: conversationID,
+		MemberDigest:   digest,
+		ExcludeUser:    excludeUserID,
+		Message:        msg,
+		TraceContext:   tracing.Inject(ctx),
+	}
+
+	data, err := json.Marshal(routeMsg)
 	if err != nil {
-		return fmt.Errorf("subscribe error: %w", err)
+		return err
+	}
+
+	if err := d.broker.Publish(ctx, nodeID, data); err != nil {
+		return err
+	}
+	recordRoutePublish(routeModeConversation, len(data))
+
+	// 估算按用户路由所需字节数，统计节省的带宽
+	perUser, err := json.Marshal(&RouteMessage{TargetUsers: []string{userIDs[0]}, Message: msg})
+	if err == nil {
+		if saved := len(perUser)*len(userIDs) - len(data); saved > 0 {
+			routeBytesSavedTotal.Add(float64(saved))
+		}
 	}
+	return nil
+}
 
-	log.Printf("Subscribed to channel: %s", channel)
+// SubscribeNodeMessages 订阅本节点的消息
+func (d *messageDispatcherImpl) SubscribeNodeMessages(ctx context.Context) error {
+	messages, err := d.broker.Subscribe(ctx, d.config.NodeID)
+	if err != nil {
+		return err
+	}
 
-	// 启动消息处理协程
-	d.wg.Add(1)
-	go d.handleSubscribedMessages(ctx)
+	// 启动消息接收与按优先级处理协程
+	d.wg.Add(2)
+	go d.handleSubscribedMessages(ctx, messages)
+	go d.processRouteQueue(ctx)
 
 	return nil
 }
 
-// handleSubscribedMessages 处理订阅的消息
-func (d *messageDispatcherImpl) handleSubscribedMessages(ctx context.Context) {
+// handleSubscribedMessages 接收订阅的消息，按优先级放入路由队列
+func (d *messageDispatcherImpl) handleSubscribedMessages(ctx context.Context, messages <-chan []byte) {
 	defer d.wg.Done()
 
-	ch := d.pubsub.Channel()
-
 	for {
 		select {
 		case <-d.stopChan:
 			return
 		case <-ctx.Done():
 			return
-		case redisMsg, ok := <-ch:
+		case payload, ok := <-messages:
 			if !ok {
 				return
 			}
 
 			var routeMsg RouteMessage
-			if err := json.Unmarshal([]byte(redisMsg.Payload), &routeMsg); err != nil {
+			if err := json.Unmarshal(payload, &routeMsg); err != nil {
 				log.Printf("unmarshal route message error: %v", err)
 				continue
 			}
 
-			// 处理路由消息
-			d.handleRouteMessage(&routeMsg)
+			if !d.routeQueue.push(routeMsg.Priority(), &routeMsg) {
+				log.Printf("route queue full, drop %s message", routeMsg.Priority())
+			}
+		}
+	}
+}
+
+// processRouteQueue 按优先级处理路由消息，控制消息不会被大量聊天或广播消息阻塞
+func (d *messageDispatcherImpl) processRouteQueue(ctx context.Context) {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-d.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-d.routeQueue.ready():
+			for {
+				routeMsg, ok := d.routeQueue.pop()
+				if !ok {
+					break
+				}
+				d.handleRouteMessage(routeMsg)
+			}
 		}
 	}
 }
 
 // handleRouteMessage 处理路由消息
 func (d *messageDispatcherImpl) handleRouteMessage(routeMsg *RouteMessage) {
+	if routeMsg.Control != "" {
+		d.handleNodeControl(routeMsg.Control)
+		return
+	}
+
 	data, err := json.Marshal(routeMsg.Message)
 	if err != nil {
 		log.Printf("marshal message error: %v", err)
 		return
 	}
 
-	for _, userID := range routeMsg.TargetUsers {
-		if !d.pushToLocalUser(userID, data) {
-			log.Printf("user %s not found on this node", userID)
+	priority := routeMsg.Priority()
+
+	// 广播消息：投递给本节点所有（符合平台过滤条件的）连接
+	if routeMsg.IsBroadcast() {
+		d.broadcastToLocal(data, routeMsg.Platforms)
+		return
+	}
+
+	// 延续发送节点的链路
+	ctx, span := tracing.Start(tracing.Extract(context.Background(), routeMsg.TraceContext), "dispatcher.route_message",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(tracing.AttrMessageID.String(routeMsg.Message.MessageID)),
+	)
+	defer span.End()
+
+	kind := fanoutKind(routeMsg.Message)
+
+	// 会话路由消息：本节点在线连接与会话成员求交集后投递
+	if routeMsg.IsConversationRoute() {
+		d.pushToLocalMembers(ctx, routeMsg, data, priority, kind)
+		return
+	}
+
+	for _, userID := range d.deliverLocal(routeMsg.TargetUsers, routeMsg.Message, data, priority, kind) {
+		log.Printf("user %s not found on this node", userID)
+	}
+}
+
+// pushToLocalMembers 推送消息给本节点在线的会话成员：成员摘要与缓存一致时不再查询会话成员
+func (d *messageDispatcherImpl) pushToLocalMembers(ctx context.Context, routeMsg *RouteMessage, data []byte, priority Priority, kind string) {
+	conversationID := routeMsg.ConversationID
+	members := d.members.get(conversationID, routeMsg.MemberDigest)
+	if members == nil {
+		memberIDs, _, err := d.resolveConversationMembers(ctx, conversationID)
+		if err != nil {
+			log.Printf("resolve conversation %s members error: %v", conversationID, err)
+			return
+		}
+		members = d.members.put(conversationID, memberIDs)
+	}
+
+	// 遍历本地连接与会话成员中较小的一方
+	var localMembers []string
+	d.connMutex.RLock()
+	if len(members) < len(d.localConns) {
+		for uid := range members {
+			if _, ok := d.localConns[uid]; ok && uid != routeMsg.ExcludeUser {
+				localMembers = append(localMembers, uid)
+			}
+		}
+	} else {
+		for uid := range d.localConns {
+			if members[uid] && uid != routeMsg.ExcludeUser {
+				localMembers = append(localMembers, uid)
+			}
 		}
 	}
+	d.connMutex.RUnlock()
+
+	d.deliverLocal(localMembers, routeMsg.Message, data, priority, kind)
 }
 
 // Close 关闭分发器
 func (d *messageDispatcherImpl) Close() error {
 	close(d.stopChan)
 
-	if d.pubsub != nil {
-		if err := d.pubsub.Close(); err != nil {
-			return err
-		}
+	if err := d.broker.Close(); err != nil {
+		return err
 	}
 
 	d.wg.Wait()
@@ -414,10 +889,62 @@ func (d *messageDispatcherImpl) Close() error {
 	return nil
 }
 
+// BroadcastTarget 路由消息中表示广播的特殊目标
+const BroadcastTarget = "*"
+
 // RouteMessage 路由消息
+// 按用户路由时设置TargetUsers；按会话路由时设置ConversationID，由接收节点解析本地成员
 type RouteMessage struct {
-	TargetUsers []string       `json:"target_users"`
-	Message     *model.Message `json:"message"`
+	TargetUsers    []string       `json:"target_users,omitempty"`
+	ConversationID string         `json:"conversation_id,omitempty"` // 会话路由目标
+	ExcludeUser    string         `json:"exclude_user,omitempty"`    // 会话路由时排除的用户（通常为发送者）
+	MemberDigest   uint64         `json:"member_digest,omitempty"`   // 会话路由时发送节点的会话成员摘要，接收节点据此复用缓存的成员
+	Platforms      []string       `json:"platforms,omitempty"`       // 限定投递的平台，为空表示不限
+	Control        string         `json:"control,omitempty"`         // 节点控制指令，设置时忽略其他字段
+	Message        *model.Message `json:"message"`
+
+	TraceContext map[string]string `json:"trace_context,omitempty"` // 链路上下文（W3C Trace Context），接收节点延续发送节点的链路
+}
+
+// 节点控制指令
+const (
+	NodeControlDrain = "drain" // 排空节点连接
+
+	NodeControlKickUserPrefix = "kick_user:" // 断开用户连接（强制下线），后接用户ID
+)
+
+// IsConversationRoute 判断是否为会话路由消息
+func (r *RouteMessage) IsConversationRoute() bool {
+	return r.ConversationID != "" && len(r.TargetUsers) == 0
+}
+
+// IsBroadcast 判断是否为广播消息
+func (r *RouteMessage) IsBroadcast() bool {
+	return len(r.TargetUsers) == 1 && r.TargetUsers[0] == BroadcastTarget
+}
+
+// Priority 路由消息的处理优先级：节点控制指令最高，广播最低，其余按消息类型
+func (r *RouteMessage) Priority() Priority {
+	if r.Control != "" {
+		return PriorityControl
+	}
+	if r.IsBroadcast() {
+		return PriorityBulk
+	}
+	return MessagePriority(r.Message)
+}
+
+// matchPlatform 判断平台是否在过滤列表中
+func matchPlatform(platforms []string, platform string) bool {
+	if len(platforms) == 0 {
+		return true
+	}
+	for _, p := range platforms {
+		if p == platform {
+			return true
+		}
+	}
+	return false
 }
 
 // RefreshOnlineStatus 刷新用户在线状态
@@ -452,27 +979,48 @@ func (d *messageDispatcherImpl) BroadcastToAll(msg *model.Message) error {
 		return err
 	}
 
-	d.connMutex.RLock()
-	defer d.connMutex.RUnlock()
+	d.broadcastToLocal(data, nil)
+	return nil
+}
 
-	for userID, conn := range d.localConns {
-		if err := conn.SendData(data); err != nil {
-			log.Printf("broadcast to user %s error: %v", userID, err)
+// broadcastToLocal 以批量优先级投递数据给本节点符合平台过滤条件的所有连接（启用扇出限速时排队匀速投递）
+func (d *messageDispatcherImpl) broadcastToLocal(data []byte, platforms []string) {
+	d.connMutex.RLock()
+	conns := make([]Conn, 0, len(d.localConns))
+	for _, conn := range d.localConns {
+		if matchPlatform(platforms, conn.GetPlatform()) {
+			conns = append(conns, conn)
 		}
 	}
+	d.connMutex.RUnlock()
 
-	return nil
+	if d.pacer != nil {
+		d.pacer.enqueue(&fanoutJob{kind: fanoutKindBroadcast, data: data, priority: PriorityBulk, conns: conns})
+		return
+	}
+	for _, conn := range conns {
+		if err := conn.SendPriority(data, PriorityBulk); err != nil {
+			log.Printf("broadcast to user %s error: %v", conn.GetUserID(), err)
+		}
+	}
 }
 
 // BroadcastToAllNodes 广播消息给所有节点的所有用户
 func (d *messageDispatcherImpl) BroadcastToAllNodes(ctx context.Context, msg *model.Message) error {
-	// 首先广播给本地用户
-	if err := d.BroadcastToAll(msg); err != nil {
-		log.Printf("broadcast to local users error: %v", err)
+	return d.BroadcastToPlatforms(ctx, msg, nil)
+}
+
+// BroadcastToPlatforms 广播消息给所有节点上指定平台的用户
+func (d *messageDispatcherImpl) BroadcastToPlatforms(ctx context.Context, msg *model.Message, platforms []string) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
 	}
 
+	// 首先广播给本地用户
+	d.broadcastToLocal(data, platforms)
+
 	// 获取所有节点并发布广播消息
-	// 这里假设节点列表存储在Redis的Set中
 	nodesKey := "im:nodes"
 	nodes, err := d.redis.SMembers(ctx, nodesKey).Result()
 	if err != nil {
@@ -480,11 +1028,12 @@ func (d *messageDispatcherImpl) BroadcastToAllNodes(ctx context.Context, msg *mo
 	}
 
 	routeMsg := &RouteMessage{
-		TargetUsers: []string{"*"}, // 特殊标记，表示广播
+		TargetUsers: []string{BroadcastTarget},
+		Platforms:   platforms,
 		Message:     msg,
 	}
 
-	data, err := json.Marshal(routeMsg)
+	routeData, err := json.Marshal(routeMsg)
 	if err != nil {
 		return err
 	}
@@ -494,15 +1043,103 @@ func (d *messageDispatcherImpl) BroadcastToAllNodes(ctx context.Context, msg *mo
 			continue // 跳过本节点
 		}
 
-		channel := fmt.Sprintf("%s%s", d.config.PublishChannelPrefix, nodeID)
-		if err := d.redis.Publish(ctx, channel, data).Err(); err != nil {
+		if err := d.broker.Publish(ctx, nodeID, routeData); err != nil {
 			log.Printf("publish to node %s error: %v", nodeID, err)
+			continue
+		}
+		recordRoutePublish(routeModeBroadcast, len(routeData))
+	}
+
+	return nil
+}
+
+// BroadcastToNode 广播消息给指定节点上的用户
+func (d *messageDispatcherImpl) BroadcastToNode(ctx context.Context, nodeID string, msg *model.Message, platforms []string) error {
+	if nodeID == d.config.NodeID {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return err
 		}
+		d.broadcastToLocal(data, platforms)
+		return nil
+	}
+
+	routeData, err := json.Marshal(&RouteMessage{
+		TargetUsers: []string{BroadcastTarget},
+		Platforms:   platforms,
+		Message:     msg,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := d.broker.Publish(ctx, nodeID, routeData); err != nil {
+		return fmt.Errorf("publish to node %s error: %w", nodeID, err)
+	}
+	recordRoutePublish(routeModeBroadcast, len(routeData))
+	return nil
+}
+
+// SendNodeControl 发送节点控制指令
+func (d *messageDispatcherImpl) SendNodeControl(ctx context.Context, nodeID, action string) error {
+	if nodeID == d.config.NodeID {
+		d.handleNodeControl(action)
+		return nil
+	}
+
+	data, err := json.Marshal(&RouteMessage{Control: action})
+	if err != nil {
+		return err
 	}
 
+	if err := d.broker.Publish(ctx, nodeID, data); err != nil {
+		return fmt.Errorf("publish control to node %s error: %w", nodeID, err)
+	}
 	return nil
 }
 
+// SetBroker 设置跨节点消息通道
+func (d *messageDispatcherImpl) SetBroker(broker MessageBroker) {
+	d.broker = broker
+}
+
+// SetOnNodeControl 设置控制指令回调
+func (d *messageDispatcherImpl) SetOnNodeControl(fn func(action string)) {
+	d.onNodeControl = fn
+}
+
+// SetOnDispatch 设置消息分发事件回调
+func (d *messageDispatcherImpl) SetOnDispatch(fn DispatchObserver) {
+	d.onDispatch = fn
+}
+
+// SetFanoutLoadSampler 设置节点过载程度采样函数
+func (d *messageDispatcherImpl) SetFanoutLoadSampler(fn func() float64) {
+	if d.pacer != nil {
+		d.pacer.setLoadSampler(fn)
+	}
+}
+
+// SetMuteChecker 设置会话免打扰查询
+func (d *messageDispatcherImpl) SetMuteChecker(checker MuteChecker) {
+	d.muteChecker = checker
+}
+
+// notifyDispatch 通知消息分发事件
+func (d *messageDispatcherImpl) notifyDispatch(ctx context.Context, msg *model.Message) {
+	if d.onDispatch != nil {
+		d.onDispatch(ctx, msg)
+	}
+}
+
+// handleNodeControl 处理本节点的控制指令
+func (d *messageDispatcherImpl) handleNodeControl(action string) {
+	log.Printf("Received node control: %s", action)
+	if d.onNodeControl != nil {
+		d.onNodeControl(action)
+	}
+}
+
 // RegisterNode 注册节点
 func (d *messageDispatcherImpl) RegisterNode(ctx context.Context) error {
 	nodesKey := "im:nodes"
@@ -522,7 +1159,7 @@ func (d *messageDispatcherImpl) SendDirectMessage(userID string, msg *model.Mess
 		return err
 	}
 
-	if !d.pushToLocalUser(userID, msgData) {
+	if !d.pushToLocalUser(userID, msg, msgData, MessagePriority(msg)) {
 		return fmt.Errorf("user %s not connected to this node", userID)
 	}
 
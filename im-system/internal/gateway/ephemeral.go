@@ -0,0 +1,192 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// 临时消息错误定义
+var (
+	ErrNotConversationMember = errors.New("not a conversation member")
+)
+
+// EphemeralConfig 临时消息配置
+type EphemeralConfig struct {
+	MaxSize int     // 消息内容最大字节数
+	Rate    float64 // 每个连接每秒允许的临时消息数
+	Burst   int     // 每个连接允许的突发条数
+
+	TypingInterval time.Duration // 同一会话内转发正在输入的最小间隔（0表示不限制）
+}
+
+// EphemeralGuard 临时消息投递前检查（msg.ConversationID 已规范化），返回错误时静默丢弃该消息
+type EphemeralGuard func(ctx context.Context, conn *Connection, msg *model.Message) error
+
+// DefaultEphemeralConfig 默认配置
+func DefaultEphemeralConfig() *EphemeralConfig {
+	return &EphemeralConfig{
+		MaxSize: 4096,
+		Rate:    10,
+		Burst:   20,
+
+		TypingInterval: 3 * time.Second,
+	}
+}
+
+// tokenBucket 令牌桶（非并发安全）
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket 创建令牌桶，初始为满
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow 取一个令牌，没有令牌时返回 false
+func (b *tokenBucket) Allow() bool {
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Wait 距下一个令牌可用的时间
+func (b *tokenBucket) Wait() time.Duration {
+	if b.tokens >= 1 || b.rate <= 0 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// SetEphemeralGuard 设置临时消息投递前检查
+func (h *WebSocketHandler) SetEphemeralGuard(guard EphemeralGuard) {
+	h.ephemeralGuard = guard
+}
+
+// ephemeralConfig 获取临时消息配置
+func (h *WebSocketHandler) ephemeralConfig() *EphemeralConfig {
+	if h.config.Ephemeral != nil {
+		return h.config.Ephemeral
+	}
+	return DefaultEphemeralConfig()
+}
+
+// handleEphemeral 处理临时消息（正在输入、实时光标、标注等）：
+// 超限直接丢弃，只投递给在线的会话成员，不保存、不存离线、不回ACK
+func (h *WebSocketHandler) handleEphemeral(ctx context.Context, conn *Connection, msg *model.Message) error {
+	config := h.ephemeralConfig()
+
+	// 限速：超出速率静默丢弃，避免错误回包放大流量
+	if conn.ephemeral == nil {
+		conn.ephemeral = newTokenBucket(config.Rate, config.Burst)
+	}
+	if !conn.ephemeral.Allow() {
+		ephemeralMessagesTotal.WithLabelValues("rate_limited").Inc()
+		return nil
+	}
+
+	if config.MaxSize > 0 {
+		data, err := json.Marshal(msg.Content)
+		if err != nil || len(data) > config.MaxSize {
+			ephemeralMessagesTotal.WithLabelValues("too_large").Inc()
+			h.sendError(conn, "ephemeral_too_large", "Ephemeral message too large")
+			return nil
+		}
+	}
+
+	conversationID := ephemeralConversationID(conn.UserID, msg)
+	if conversationID == "" {
+		ephemeralMessagesTotal.WithLabelValues("rejected").Inc()
+		return nil
+	}
+	msg.ConversationID = conversationID
+	msg.QoS = model.QoSAtMostOnce
+
+	// 正在输入按会话节流：间隔内重复的输入状态静默丢弃，群聊中避免每次按键都扇出到全体在线成员
+	if msg.Type == model.MsgTyping && !conn.allowTyping(conversationID, config.TypingInterval) {
+		ephemeralMessagesTotal.WithLabelValues("throttled").Inc()
+		return nil
+	}
+
+	// 投递对象检查（访客发送范围、被对方屏蔽等），不回错误避免放大流量
+	if h.ephemeralGuard != nil {
+		if err := h.ephemeralGuard(ctx, conn, msg); err != nil {
+			ephemeralMessagesTotal.WithLabelValues("rejected").Inc()
+			return nil
+		}
+	}
+
+	if err := h.dispatcher.DispatchEphemeral(ctx, conversationID, msg, conn.UserID); err != nil {
+		ephemeralMessagesTotal.WithLabelValues("rejected").Inc()
+		if errors.Is(err, ErrNotConversationMember) {
+			return nil
+		}
+		return err
+	}
+	ephemeralMessagesTotal.WithLabelValues("delivered").Inc()
+	return nil
+}
+
+// ephemeralConversationID 确定临时消息所在会话：群ID > 会话ID > 接收者
+func ephemeralConversationID(userID string, msg *model.Message) string {
+	if msg.GroupID != "" {
+		return model.GetGroupChatConversationID(msg.GroupID)
+	}
+	if msg.ConversationID != "" {
+		if convID, err := model.ParseConversationID(msg.ConversationID); err == nil {
+			return convID.String()
+		}
+		return ""
+	}
+	if msg.To != "" && msg.To != userID {
+		return model.GetSingleChatConversationID(userID, msg.To)
+	}
+	return ""
+}
+
+// maxTypingEntries 每个连接记录的会话数超过该值时清理过期记录
+const maxTypingEntries = 64
+
+// allowTyping 是否转发该会话的正在输入（只在读协程中调用）
+func (c *Connection) allowTyping(conversationID string, interval time.Duration) bool {
+	if interval <= 0 {
+		return true
+	}
+	now := time.Now()
+	if c.typingSent == nil {
+		c.typingSent = make(map[string]time.Time)
+	}
+	if last, ok := c.typingSent[conversationID]; ok && now.Sub(last) < interval {
+		return false
+	}
+	if len(c.typingSent) >= maxTypingEntries {
+		for id, last := range c.typingSent {
+			if now.Sub(last) >= interval {
+				delete(c.typingSent, id)
+			}
+		}
+	}
+	c.typingSent[conversationID] = now
+	return true
+}
@@ -4,39 +4,90 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/d60-lab/im-system/internal/model"
 	"github.com/d60-lab/im-system/pkg/auth"
+	"github.com/d60-lab/im-system/pkg/errcode"
+	"github.com/d60-lab/im-system/pkg/i18n"
+	"github.com/d60-lab/im-system/pkg/tracing"
 	"github.com/d60-lab/im-system/pkg/util"
 )
 
-// MessageSaver 消息保存接口
+// MessageSaver 消息保存接口：SaveMessage 只写入消息，分发前检查通过后再调用 RecordConversation 更新会话记录，
+// 被拒绝的消息不计入未读数和会话列表
 type MessageSaver interface {
 	SaveMessage(ctx context.Context, msg *model.Message) error
+	RecordConversation(ctx context.Context, msg *model.Message) error
 }
 
 // WebSocketHandler WebSocket处理器
 type WebSocketHandler struct {
-	config       *HandlerConfig
-	upgrader     websocket.Upgrader
-	connMgr      *ConnectionManager
-	dispatcher   MessageDispatcher
-	jwtManager   *auth.JWTManager
-	deduper      *MessageDeduper
-	messageSaver MessageSaver
+	config        *HandlerConfig
+	upgrader      websocket.Upgrader
+	connMgr       *ConnectionManager
+	dispatcher    MessageDispatcher
+	authenticator auth.Authenticator
+	deduper       *MessageDeduper
+	messageSaver  MessageSaver
+	limiter       *ConnectionLimiter
+	shedder       *LoadShedder
+	sendGuard     SendGuard
+	afterSend     AfterSendHook
+	verifyCustom  CustomVerifier
+	onRead        ReadHook
+	onDelivered   DeliveredHook
+	rollout       RolloutTracker
+	heartbeat     *HeartbeatConfig
+	suggester     *suggester
+	recorder      *FrameRecorder
+	latency       *LatencyTracker
+
+	dispatchGuard   DispatchGuard
+	failureRecorder SendFailureRecorder
+	muteChecker     GroupMuteChecker
+	sessionRecorder SessionRecorder
+	offlineSource   OfflineSource
+
+	contentChecker ContentChecker
+
+	ephemeralGuard EphemeralGuard
 
 	// 消息处理回调
 	onMessage func(ctx context.Context, conn *Connection, msg *model.Message) error
 }
 
+// SendGuard 发送前检查，返回错误时拒绝该消息（错误信息会返回给客户端）
+type SendGuard func(ctx context.Context, conn *Connection, msg *model.Message) error
+
+// AfterSendHook 消息保存并分发后的回调（如自动回复），错误只记录日志
+type AfterSendHook func(ctx context.Context, msg *model.Message) error
+
+// CustomVerifier 自定义消息分发前校验（如集成应用签名），可改写消息内容，返回错误时拒绝该消息
+type CustomVerifier func(ctx context.Context, conn *Connection, msg *model.Message) error
+
+// GroupMuteChecker 群消息发送前的禁言检查，发送者被禁言时返回禁言状态及错误（按错误码返回给发送者）
+type GroupMuteChecker func(ctx context.Context, groupID, userID string) (*model.GroupMuteStatus, error)
+
+// ContentChecker 单聊、群聊消息保存前的内容审核，可改写消息内容（如替换敏感词），返回错误时拒绝该消息（按错误码返回给发送者）
+type ContentChecker func(ctx context.Context, msg *model.Message) error
+
+// ReadHook 收到已读回执后的回调（如清理已读的离线消息），错误只记录日志
+type ReadHook func(ctx context.Context, userID string, receipt *model.ReadReceiptContent) error
+
+// DeliveredHook 客户端确认收到本节点推送的 QoS1 消息后的回调（如标记消息已送达），错误只记录日志
+type DeliveredHook func(ctx context.Context, userID, messageID string) error
+
 // HandlerConfig 处理器配置
 type HandlerConfig struct {
 	NodeID           string
@@ -46,7 +97,25 @@ type HandlerConfig struct {
 	WriteTimeout     time.Duration
 	ReadTimeout      time.Duration
 	HandshakeTimeout time.Duration
-	AllowOrigins     []string
+	AllowOrigins     []string // 允许的来源，为空时仅允许同源
+	SessionCookie    string   // Web端会话Cookie名称，为空时不从Cookie读取Token
+
+	// Heartbeat 心跳协商配置，为空时按 PingInterval/PongTimeout 使用固定心跳
+	Heartbeat *HeartbeatConfig
+	// MaxClientSkew 允许的客户端时钟偏差，client_timestamp 超出时拒绝消息，0表示不校验
+	MaxClientSkew time.Duration
+	// MinClientVersions 各平台要求的最低客户端版本（key 为平台，* 为默认），低于该版本的连接以 4426 关闭
+	MinClientVersions map[string]string
+	// Ephemeral 临时消息（正在输入、实时光标等）的大小和速率限制，为空时使用默认配置
+	Ephemeral *EphemeralConfig
+	// WriteBatch 发送合并配置，客户端握手时协商批量帧格式后生效，为空时不合并
+	WriteBatch *WriteBatchConfig
+	// OfflineReplay 连接建立后自动补发离线消息的配置（须同时设置离线消息来源，客户端声明 offline_replay 后生效），为空时不补发
+	OfflineReplay *OfflineReplayConfig
+	// MessageRate 每个连接每秒允许发送的消息数（心跳、ACK、回执、临时消息除外），0表示不限制
+	MessageRate float64
+	// MessageBurst 每个连接允许的突发消息数，小于1时按 MessageRate 取整
+	MessageBurst int
 }
 
 // DefaultHandlerConfig 默认配置
@@ -68,7 +137,7 @@ func NewWebSocketHandler(
 	config *HandlerConfig,
 	connMgr *ConnectionManager,
 	dispatcher MessageDispatcher,
-	jwtManager *auth.JWTManager,
+	authenticator auth.Authenticator,
 	messageSaver MessageSaver,
 ) *WebSocketHandler {
 	if config == nil {
@@ -76,12 +145,16 @@ func NewWebSocketHandler(
 	}
 
 	h := &WebSocketHandler{
-		config:       config,
-		connMgr:      connMgr,
-		dispatcher:   dispatcher,
-		jwtManager:   jwtManager,
-		deduper:      NewMessageDeduper(10000),
-		messageSaver: messageSaver,
+		config:        config,
+		connMgr:       connMgr,
+		dispatcher:    dispatcher,
+		authenticator: authenticator,
+		deduper:       NewMessageDeduper(10000),
+		messageSaver:  messageSaver,
+		heartbeat:     config.Heartbeat,
+	}
+	if h.heartbeat == nil {
+		h.heartbeat = fixedHeartbeatConfig(config.PingInterval, config.PongTimeout)
 	}
 
 	h.upgrader = websocket.Upgrader{
@@ -96,22 +169,72 @@ func NewWebSocketHandler(
 
 // checkOrigin 检查请求来源
 func (h *WebSocketHandler) checkOrigin(r *http.Request) bool {
-	if len(h.config.AllowOrigins) == 0 {
-		return true
-	}
-
 	origin := r.Header.Get("Origin")
 	if origin == "" {
+		// 非浏览器客户端不携带Origin
 		return true
 	}
 
-	for _, allowed := range h.config.AllowOrigins {
-		if allowed == "*" || allowed == origin {
-			return true
-		}
+	if !auth.OriginAllowed(origin, r.Host, h.config.AllowOrigins) {
+		log.Printf("Reject WebSocket origin %s", origin)
+		return false
 	}
+	return true
+}
 
-	return false
+// SetConnectionLimiter 设置连接数限制器
+func (h *WebSocketHandler) SetConnectionLimiter(limiter *ConnectionLimiter) {
+	h.limiter = limiter
+}
+
+// SetLoadShedder 设置过载保护器
+func (h *WebSocketHandler) SetLoadShedder(shedder *LoadShedder) {
+	h.shedder = shedder
+}
+
+// SetSendGuard 设置发送前检查（如维护模式）
+func (h *WebSocketHandler) SetSendGuard(guard SendGuard) {
+	h.sendGuard = guard
+}
+
+// SetAfterSend 设置消息发送后回调
+func (h *WebSocketHandler) SetAfterSend(hook AfterSendHook) {
+	h.afterSend = hook
+}
+
+// SetCustomVerifier 设置自定义消息校验
+func (h *WebSocketHandler) SetCustomVerifier(verifier CustomVerifier) {
+	h.verifyCustom = verifier
+}
+
+// SetGroupMuteChecker 设置群消息禁言检查
+func (h *WebSocketHandler) SetGroupMuteChecker(checker GroupMuteChecker) {
+	h.muteChecker = checker
+}
+
+// SetContentChecker 设置消息内容审核
+func (h *WebSocketHandler) SetContentChecker(checker ContentChecker) {
+	h.contentChecker = checker
+}
+
+// SetReadHook 设置已读回执回调
+func (h *WebSocketHandler) SetReadHook(hook ReadHook) {
+	h.onRead = hook
+}
+
+// SetDeliveredHook 设置送达确认回调
+func (h *WebSocketHandler) SetDeliveredHook(hook DeliveredHook) {
+	h.onDelivered = hook
+}
+
+// SetLatencyTracker 设置消息处理耗时跟踪，为空时不记录阶段耗时
+func (h *WebSocketHandler) SetLatencyTracker(tracker *LatencyTracker) {
+	h.latency = tracker
+}
+
+// SetSessionRecorder 设置连接会话记录回调，为空时不记录
+func (h *WebSocketHandler) SetSessionRecorder(recorder SessionRecorder) {
+	h.sessionRecorder = recorder
 }
 
 // SetOnMessage 设置消息处理回调
@@ -128,6 +251,14 @@ func (h *WebSocketHandler) RegisterRoutes(r *gin.Engine) {
 
 // HandleWebSocket 处理WebSocket连接
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
+	// 过载保护：按比例拒绝新连接并给出重试建议
+	if h.shedder != nil {
+		if ok, retryAfter := h.shedder.Admit(); !ok {
+			h.rejectOverloaded(c, retryAfter)
+			return
+		}
+	}
+
 	// 从查询参数或Header获取token
 	token := c.Query("token")
 	if token == "" {
@@ -136,23 +267,88 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 			token = token[7:]
 		}
 	}
+	if token == "" && h.config.SessionCookie != "" {
+		// 浏览器无法为WebSocket设置请求头，Cookie会话依赖Origin检查防止跨站劫持
+		if cookie, err := c.Cookie(h.config.SessionCookie); err == nil {
+			token = cookie
+		}
+	}
 
 	// 验证token
-	claims, err := h.jwtManager.ParseToken(token)
+	identity, err := h.authenticator.Authenticate(c.Request.Context(), token)
 	if err != nil {
 		log.Printf("Invalid token: %v", err)
+		if errors.Is(err, auth.ErrAuthUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "authentication service unavailable"})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 		return
 	}
 
-	userID := claims.UserID
+	// 须修改初始密码时不允许建立长连接
+	if identity.PasswordReset {
+		c.JSON(http.StatusForbidden, gin.H{"error": "password reset required", "must_reset_password": true})
+		return
+	}
+
+	// 节点排空中，提示客户端重连其他节点
+	if h.connMgr.IsDraining() {
+		retryAfter := spreadRetryAfter(drainRetryAfterMax)
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter/time.Second)))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "node draining", "retry_after": int(retryAfter / time.Second)})
+		return
+	}
+
+	userID := identity.UserID
 	platform := c.Query("platform")
 	deviceID := c.Query("device_id")
+	clientIP := c.ClientIP()
+	clientInfo := parseClientInfo(c)
+
+	// 客户端版本过低时要求升级
+	if minVersion, outdated := h.clientOutdated(platform, clientInfo.AppVersion); outdated {
+		log.Printf("Reject outdated client of user %s (platform: %s, version: %s, min: %s)", userID, platform, clientInfo.AppVersion, minVersion)
+		h.rejectOutdated(c, platform, minVersion)
+		return
+	}
+
+	// 升级前检查连接数限制
+	if h.limiter != nil {
+		if err := h.limiter.Acquire(userID, clientIP); err != nil {
+			log.Printf("Reject connection of user %s from %s: %v", userID, clientIP, err)
+			c.Header("Retry-After", "5")
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 协商心跳间隔，与服务器时间、允许的时钟偏差一起通过响应头及首条心跳消息告知客户端
+	heartbeat := newHeartbeatState(h.heartbeat, h.heartbeat.Negotiate(c.Query("heartbeat")))
+	responseHeader := http.Header{}
+	responseHeader.Set("X-Heartbeat-Interval", strconv.Itoa(int(heartbeat.Interval()/time.Second)))
+	responseHeader.Set("X-Server-Time", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	responseHeader.Set("X-Max-Client-Skew", strconv.FormatInt(h.config.MaxClientSkew.Milliseconds(), 10))
+
+	// 协商发送合并：服务端启用且客户端声明支持批量帧时，通过响应头回显采用的格式
+	batchMode := negotiateBatchMode(h.config.WriteBatch, handshakeField(c, "batch", "X-Frame-Batch", 16))
+	if batchMode != "" {
+		responseHeader.Set("X-Frame-Batch", batchMode)
+	}
+
+	// 灰度分组：通过响应头告知客户端进入实验组的开关（如新的帧格式）
+	cohorts := h.resolveCohorts(c.Request.Context(), userID)
+	if flags := treatmentFlags(cohorts); flags != "" {
+		responseHeader.Set("X-Feature-Flags", flags)
+	}
 
 	// 升级为WebSocket连接
-	wsConn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	wsConn, err := h.upgrader.Upgrade(c.Writer, c.Request, responseHeader)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
+		if h.limiter != nil {
+			h.limiter.Release(userID, clientIP)
+		}
 		return
 	}
 
@@ -161,50 +357,102 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 	conn := NewConnection(connID, userID, h.config.NodeID, wsConn, nil)
 	conn.SetPlatform(platform)
 	conn.SetDeviceID(deviceID)
+	conn.SetClientInfo(clientInfo)
+	conn.Cohorts = cohorts
+	conn.ClientIP = clientIP
+	conn.Locale = i18n.Resolve(c.GetHeader("Accept-Language"), c.Query("locale"))
+	conn.heartbeat = heartbeat
+	conn.batchMode = batchMode
+	conn.recorded = h.recorder != nil && h.recorder.Sampled(connID, userID)
 
 	// 注册连接
 	h.connMgr.Register(conn)
+	if err := h.dispatcher.RegisterConnection(userID, conn); err != nil {
+		log.Printf("Register connection to dispatcher error: %v", err)
+	}
 
-	log.Printf("User %s connected (connID: %s, platform: %s)", userID, connID, platform)
+	log.Printf("User %s connected (connID: %s, platform: %s, version: %s, heartbeat: %s)", userID, connID, platform, clientInfo.AppVersion, heartbeat.Interval())
+	h.recordCohort(conn, model.CohortEventConnect, 1)
+	h.record(RecordEventConnect, conn, nil)
+	h.sendHeartbeat(conn)
 
 	// 启动读写协程
 	go h.writePump(conn)
 	go h.readPump(conn)
 }
 
+// rejectOverloaded 拒绝过载时的新连接
+// 浏览器无法读取握手失败的响应，带 Origin 的请求先完成升级再以 1013 关闭帧返回重试建议，其他客户端直接返回503
+func (h *WebSocketHandler) rejectOverloaded(c *gin.Context, retryAfter time.Duration) {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	if c.GetHeader("Origin") != "" {
+		if wsConn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil); err == nil {
+			wsConn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseTryAgainLater, closeReason("overloaded", retryAfter)),
+				time.Now().Add(time.Second))
+			wsConn.Close()
+			return
+		}
+	}
+
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": "server overloaded", "retry_after": seconds})
+}
+
 // readPump 读取消息协程
 func (h *WebSocketHandler) readPump(conn *Connection) {
 	defer func() {
 		h.connMgr.Unregister(conn)
+		// 同一用户已有新连接时不注销分发器中的连接
+		if !h.connMgr.IsOnline(conn.UserID) {
+			if err := h.dispatcher.UnregisterConnection(conn.UserID); err != nil {
+				log.Printf("Unregister connection from dispatcher error: %v", err)
+			}
+		}
 		conn.Close()
+		if h.limiter != nil {
+			h.limiter.Release(conn.UserID, conn.ClientIP)
+		}
+		h.recordCohort(conn, model.CohortEventDisconnect, 1)
+		h.recordCohort(conn, model.CohortEventSessionSeconds, int64(time.Since(conn.CreatedAt)/time.Second))
+		h.record(RecordEventDisconnect, conn, nil)
+		if h.sessionRecorder != nil {
+			h.sessionRecorder(conn, conn.Usage(), time.Now())
+		}
 		log.Printf("User %s disconnected (connID: %s)", conn.UserID, conn.ID)
 	}()
 
 	// 设置读取限制
 	conn.Conn.SetReadLimit(h.config.MaxMessageSize)
-	conn.Conn.SetReadDeadline(time.Now().Add(h.config.PongTimeout))
+	conn.Conn.SetReadDeadline(time.Now().Add(conn.heartbeat.Timeout()))
 
 	// 设置Pong处理器
 	conn.Conn.SetPongHandler(func(string) error {
-		conn.Conn.SetReadDeadline(time.Now().Add(h.config.PongTimeout))
-		conn.UpdateLastActive()
+		h.heartbeatAlive(conn)
 		return nil
 	})
 
 	ctx := context.Background()
 
+	// 在读协程中补发离线消息，与客户端的批次确认串行处理
+	h.startOfflineReplay(ctx, conn)
+
 	for {
 		_, data, err := conn.Conn.ReadMessage()
+		receivedAt := time.Now()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket read error: %v", err)
 			}
 			break
 		}
+		conn.countInbound(len(data))
 
 		// 重置读取超时（每收到消息都重置，不仅仅是 Pong）
-		conn.Conn.SetReadDeadline(time.Now().Add(h.config.PongTimeout))
-		conn.UpdateLastActive()
+		h.heartbeatAlive(conn)
+
+		// 录制原始帧（含无法解析的帧），用于回放复现
+		h.record(RecordEventFrame, conn, data)
 
 		// 解析消息
 		var msg model.Message
@@ -214,17 +462,44 @@ func (h *WebSocketHandler) readPump(conn *Connection) {
 			continue
 		}
 
+		// 用户消息记录各处理阶段耗时（控制消息、临时消息除外）
+		msgCtx := ctx
+		var timing *messageTiming
+		if h.latency != nil && isSendMessage(msg.Type) && !msg.Type.IsEphemeral() {
+			msgCtx, timing = h.latency.withTiming(ctx, receivedAt)
+			markStage(msgCtx, StageReceived)
+		}
+
+		// 用户消息创建链路的根 Span，保存、分发、离线存储和推送的 Span 均挂在其下
+		var span trace.Span
+		if isSendMessage(msg.Type) && !msg.Type.IsEphemeral() {
+			msgCtx, span = startMessageSpan(msgCtx, conn, &msg, receivedAt)
+		}
+
 		// 处理消息
-		if err := h.handleMessage(ctx, conn, &msg); err != nil {
+		startedAt := time.Now()
+		err = h.handleMessage(msgCtx, conn, &msg)
+		if err != nil {
 			log.Printf("Handle message error: %v", err)
 			h.sendError(conn, "handle_error", err.Error())
 		}
+		h.recordCohortMessage(conn, startedAt, err != nil)
+		if timing != nil {
+			timing.finish(&msg)
+		}
+		if span != nil {
+			finishMessageSpan(span, &msg, err)
+		}
 	}
 }
 
+// writeBatchSize 发送协程每轮最多连续写出的消息数
+const writeBatchSize = 64
+
 // writePump 发送消息协程
 func (h *WebSocketHandler) writePump(conn *Connection) {
-	ticker := time.NewTicker(h.config.PingInterval)
+	interval := conn.heartbeat.Interval()
+	ticker := time.NewTicker(interval)
 	defer func() {
 		ticker.Stop()
 		conn.Close()
@@ -232,21 +507,26 @@ func (h *WebSocketHandler) writePump(conn *Connection) {
 
 	for {
 		select {
-		case data, ok := <-conn.Send:
-			if !ok {
-				// 通道已关闭
-				conn.Conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
+		case <-conn.queue.ready():
+			write := h.writeQueued
+			if conn.batchMode != "" {
+				write = h.writeBatched
 			}
-
-			conn.Conn.SetWriteDeadline(time.Now().Add(h.config.WriteTimeout))
-
-			if err := conn.Conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			if err := write(conn); err != nil {
 				log.Printf("WebSocket write error: %v", err)
 				return
 			}
 
 		case <-ticker.C:
+			if conn.heartbeat.Tick() {
+				heartbeatMissedTotal.Inc()
+			}
+			// 心跳间隔自适应调整后重置定时器
+			if current := conn.heartbeat.Interval(); current != interval {
+				interval = current
+				ticker.Reset(interval)
+			}
+
 			conn.Conn.SetWriteDeadline(time.Now().Add(h.config.WriteTimeout))
 			if err := conn.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
@@ -258,6 +538,27 @@ func (h *WebSocketHandler) writePump(conn *Connection) {
 	}
 }
 
+// writeQueued 按优先级逐条写出，每轮最多 writeBatchSize 条，剩余数据重新触发信号，保证心跳能及时发送
+func (h *WebSocketHandler) writeQueued(conn *Connection) error {
+	for i := 0; i < writeBatchSize; i++ {
+		data, ok := conn.queue.pop()
+		if !ok {
+			break
+		}
+
+		conn.Conn.SetWriteDeadline(time.Now().Add(h.config.WriteTimeout))
+
+		if err := conn.Conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			return err
+		}
+		conn.countOutbound(len(data))
+	}
+	if conn.queue.pending() {
+		conn.queue.signal()
+	}
+	return nil
+}
+
 // handleMessage 处理接收到的消息
 func (h *WebSocketHandler) handleMessage(ctx context.Context, conn *Connection, msg *model.Message) error {
 	// 设置消息来源
@@ -269,12 +570,36 @@ func (h *WebSocketHandler) handleMessage(ctx context.Context, conn *Connection,
 		msg.MessageID = util.GenerateMessageID()
 	}
 
+	// 按连接限速：超限时回复限流错误，客户端等待后可用同一消息ID重发
+	if isSendMessage(msg.Type) && !h.allowMessage(conn, msg) {
+		return nil
+	}
+
+	// 客户端时钟偏差过大时拒绝，而不是静默改写时间（先于去重，校准后可用同一消息ID重发）
+	if isSendMessage(msg.Type) && !h.checkClientClock(conn, msg) {
+		return nil
+	}
+
+	// 临时消息不去重、不做发送检查（访客、屏蔽等投递对象检查除外），按连接限速后直接投递给在线成员
+	if msg.Type.IsEphemeral() {
+		return h.handleEphemeral(ctx, conn, msg)
+	}
+
 	// 消息去重
 	if h.deduper.IsDuplicate(msg.MessageID) {
 		log.Printf("Duplicate message: %s", msg.MessageID)
 		return nil
 	}
 
+	// 发送前检查
+	if h.sendGuard != nil && isSendMessage(msg.Type) {
+		if err := h.sendGuard(ctx, conn, msg); err != nil {
+			h.sendError(conn, "send_rejected", err.Error())
+			return nil
+		}
+	}
+	markStage(ctx, StageValidated)
+
 	// 根据消息类型处理
 	switch msg.Type {
 	case model.MsgHeartbeat:
@@ -286,14 +611,20 @@ func (h *WebSocketHandler) handleMessage(ctx context.Context, conn *Connection,
 	case model.MsgGroupChat:
 		return h.handleGroupChat(ctx, conn, msg)
 
+	case model.MsgCustom:
+		return h.handleCustom(ctx, conn, msg)
+
+	case model.MsgEncrypted:
+		return h.handleEncrypted(ctx, conn, msg)
+
 	case model.MsgAck:
 		return h.handleAck(ctx, conn, msg)
 
 	case model.MsgReadReceipt:
 		return h.handleReadReceipt(ctx, conn, msg)
 
-	case model.MsgTyping:
-		return h.handleTyping(ctx, conn, msg)
+	case model.MsgOfflineAck:
+		return h.handleOfflineAck(ctx, conn, msg)
 
 	default:
 		// 自定义消息处理
@@ -304,58 +635,342 @@ func (h *WebSocketHandler) handleMessage(ctx context.Context, conn *Connection,
 	}
 }
 
+// isSendMessage 是否为需要经过发送检查的用户消息（心跳、ACK、回执、输入状态等控制消息除外）
+func isSendMessage(msgType model.MessageType) bool {
+	switch msgType {
+	case model.MsgHeartbeat, model.MsgAck, model.MsgReadReceipt, model.MsgTyping, model.MsgEphemeral, model.MsgOfflineAck:
+		return false
+	}
+	return true
+}
+
+// startMessageSpan 为用户消息创建网关处理的 Span（从读到帧开始计时）
+func startMessageSpan(ctx context.Context, conn *Connection, msg *model.Message, receivedAt time.Time) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "gateway.handle_message",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithTimestamp(receivedAt),
+		trace.WithAttributes(
+			tracing.AttrUserID.String(conn.UserID),
+			tracing.AttrMessageType.Int(int(msg.Type)),
+			tracing.AttrPlatform.String(conn.GetPlatform()),
+		),
+	)
+}
+
+// finishMessageSpan 补充处理后确定的消息ID、会话ID并结束 Span
+func finishMessageSpan(span trace.Span, msg *model.Message, err error) {
+	span.SetAttributes(
+		tracing.AttrMessageID.String(msg.MessageID),
+		tracing.AttrConversationID.String(msg.ConversationID),
+	)
+	tracing.End(span, err)
+}
+
 // handleHeartbeat 处理心跳消息
 func (h *WebSocketHandler) handleHeartbeat(ctx context.Context, conn *Connection, msg *model.Message) error {
 	// 返回心跳响应
+	return h.sendHeartbeat(conn)
+}
+
+// allowMessage 按连接发送速率限流，超限时返回限流错误（含建议的重试等待时间）
+func (h *WebSocketHandler) allowMessage(conn *Connection, msg *model.Message) bool {
+	if h.config.MessageRate <= 0 {
+		return true
+	}
+	if conn.messages == nil {
+		burst := h.config.MessageBurst
+		if burst < 1 {
+			burst = max(1, int(h.config.MessageRate))
+		}
+		conn.messages = newTokenBucket(h.config.MessageRate, burst)
+	}
+	if conn.messages.Allow() {
+		return true
+	}
+
+	messagesRateLimitedTotal.Inc()
+	conn.SendJSON(&model.Message{
+		Type: model.MsgSystem,
+		Content: map[string]interface{}{
+			"error":       "rate_limited",
+			"message":     i18n.T(conn.Locale, "error.rate_limited"),
+			"message_id":  msg.MessageID,
+			"retry_after": conn.messages.Wait().Milliseconds(),
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+	return false
+}
+
+// checkClientClock 校验消息的客户端时间，偏差超出阈值时返回时钟偏差错误（含服务器时间便于客户端校准）
+func (h *WebSocketHandler) checkClientClock(conn *Connection, msg *model.Message) bool {
+	if h.config.MaxClientSkew <= 0 || msg.ClientTimestamp == 0 {
+		return true
+	}
+
+	skew := time.Duration(msg.Timestamp-msg.ClientTimestamp) * time.Millisecond
+	if skew.Abs() <= h.config.MaxClientSkew {
+		return true
+	}
+
+	clockSkewRejectedTotal.Inc()
+	conn.SendJSON(&model.Message{
+		Type: model.MsgSystem,
+		Content: map[string]interface{}{
+			"error":           "clock_skew",
+			"message":         i18n.T(conn.Locale, "error.clock_skew"),
+			"message_id":      msg.MessageID,
+			"server_time":     msg.Timestamp,
+			"skew":            skew.Milliseconds(),
+			"max_client_skew": h.config.MaxClientSkew.Milliseconds(),
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+	return false
+}
+
+// sendHeartbeat 发送携带当前心跳间隔及超时的心跳消息
+func (h *WebSocketHandler) sendHeartbeat(conn *Connection) error {
 	response := model.NewHeartbeatMessage()
+	content := response.Content.(*model.HeartbeatContent)
+	content.Interval = int(conn.heartbeat.Interval() / time.Second)
+	content.Timeout = int(conn.heartbeat.Timeout() / time.Second)
+	content.MaxClientSkew = h.config.MaxClientSkew.Milliseconds()
 	return conn.SendJSON(response)
 }
 
+// heartbeatAlive 收到客户端数据：重置读取超时，心跳间隔调整时通知客户端
+func (h *WebSocketHandler) heartbeatAlive(conn *Connection) {
+	if conn.heartbeat.Alive() {
+		log.Printf("Heartbeat interval of connection %s adjusted to %s", conn.ID, conn.heartbeat.Interval())
+		h.sendHeartbeat(conn)
+	}
+	conn.Conn.SetReadDeadline(time.Now().Add(conn.heartbeat.Timeout()))
+	conn.UpdateLastActive()
+}
+
 // handleSingleChat 处理单聊消息
 func (h *WebSocketHandler) handleSingleChat(ctx context.Context, conn *Connection, msg *model.Message) error {
 	// 设置会话ID
 	msg.ConversationID = model.GetSingleChatConversationID(msg.From, msg.To)
 
+	// 内容审核在保存前进行，被拒绝时不回ACK
+	if !h.checkContent(ctx, conn, msg) {
+		return nil
+	}
+
 	// 保存消息到数据库
+	saved := false
 	if h.messageSaver != nil {
 		if err := h.messageSaver.SaveMessage(ctx, msg); err != nil {
 			log.Printf("Save message error: %v", err)
+		} else {
+			saved = true
 		}
 	}
+	markStage(ctx, StagePersisted)
 
 	// 发送ACK给发送者
 	ack := model.NewAckMessage(msg.MessageID, 0)
 	conn.SendJSON(ack)
 
+	if !h.checkDispatch(ctx, conn, msg) {
+		return nil
+	}
+	if saved {
+		h.recordConversation(ctx, msg)
+	}
+
 	// 分发消息给接收者
-	return h.dispatcher.DispatchToUsers(ctx, []string{msg.To}, msg)
+	if err := h.dispatcher.DispatchToUsers(ctx, []string{msg.To}, msg); err != nil {
+		return err
+	}
+	markStage(ctx, StageDispatched)
+
+	h.requestSuggestions(msg)
+	h.runAfterSend(ctx, msg)
+	return nil
+}
+
+// recordConversation 分发前检查通过后更新会话记录，失败只影响会话列表，不影响投递
+func (h *WebSocketHandler) recordConversation(ctx context.Context, msg *model.Message) {
+	if err := h.messageSaver.RecordConversation(ctx, msg); err != nil {
+		log.Printf("Record conversation of message %s error: %v", msg.MessageID, err)
+	}
+}
+
+// runAfterSend 执行发送后回调
+func (h *WebSocketHandler) runAfterSend(ctx context.Context, msg *model.Message) {
+	if h.afterSend == nil {
+		return
+	}
+	if err := h.afterSend(ctx, msg); err != nil {
+		log.Printf("After send hook error for message %s: %v", msg.MessageID, err)
+	}
 }
 
 // handleGroupChat 处理群聊消息
 func (h *WebSocketHandler) handleGroupChat(ctx context.Context, conn *Connection, msg *model.Message) error {
+	// 被禁言的发送者在保存前拒绝，不回ACK
+	if !h.checkGroupMute(ctx, conn, msg) {
+		return nil
+	}
+
 	// 设置会话ID
 	msg.ConversationID = model.GetGroupChatConversationID(msg.To)
 
+	if !h.checkContent(ctx, conn, msg) {
+		return nil
+	}
+
 	// 保存消息到数据库
+	saved := false
 	if h.messageSaver != nil {
 		if err := h.messageSaver.SaveMessage(ctx, msg); err != nil {
 			log.Printf("Save group message error: %v", err)
+		} else {
+			saved = true
 		}
 	}
+	markStage(ctx, StagePersisted)
 
 	// 发送ACK给发送者
 	ack := model.NewAckMessage(msg.MessageID, 0)
 	conn.SendJSON(ack)
 
+	if !h.checkDispatch(ctx, conn, msg) {
+		return nil
+	}
+	if saved {
+		h.recordConversation(ctx, msg)
+	}
+
 	// 分发消息给群成员（排除发送者）
-	return h.dispatcher.DispatchToConversation(ctx, msg.ConversationID, msg, msg.From)
+	if err := h.dispatcher.DispatchToConversation(ctx, msg.ConversationID, msg, msg.From); err != nil {
+		return err
+	}
+	markStage(ctx, StageDispatched)
+	return nil
+}
+
+// checkGroupMute 检查群消息发送者是否被禁言，被禁言时返回 group_muted 错误（含错误码及禁言状态）并返回 false
+// 查询禁言状态出错时放行，避免缓存或数据库故障导致群聊不可用
+func (h *WebSocketHandler) checkGroupMute(ctx context.Context, conn *Connection, msg *model.Message) bool {
+	if h.muteChecker == nil {
+		return true
+	}
+	status, err := h.muteChecker(ctx, msg.To, msg.From)
+	if err == nil {
+		return true
+	}
+	code, ok := errcode.Lookup(err)
+	if !ok || status == nil {
+		log.Printf("Check group mute of %s in %s error: %v", msg.From, msg.To, err)
+		return true
+	}
+
+	groupMuteRejectedTotal.Inc()
+	conn.SendJSON(&model.Message{
+		Type: model.MsgSystem,
+		Content: map[string]interface{}{
+			"error":      "group_muted",
+			"code":       code.Code,
+			"message":    code.Message(conn.Locale),
+			"message_id": msg.MessageID,
+			"group_id":   msg.To,
+			"mute_all":   status.MuteAll,
+			"mute_until": status.MuteUntil,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+	return false
+}
+
+// checkContent 审核消息内容，被拒绝时返回 content_rejected 错误（含错误码）并返回 false
+// 审核出错但未返回业务错误码时放行
+func (h *WebSocketHandler) checkContent(ctx context.Context, conn *Connection, msg *model.Message) bool {
+	if h.contentChecker == nil {
+		return true
+	}
+	err := h.contentChecker(ctx, msg)
+	if err == nil {
+		return true
+	}
+	code, ok := errcode.Lookup(err)
+	if !ok {
+		log.Printf("Check content of message %s error: %v", msg.MessageID, err)
+		return true
+	}
+
+	contentRejectedTotal.Inc()
+	conn.SendJSON(&model.Message{
+		Type: model.MsgSystem,
+		Content: map[string]interface{}{
+			"error":      "content_rejected",
+			"code":       code.Code,
+			"message":    code.Message(conn.Locale),
+			"message_id": msg.MessageID,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	})
+	return false
+}
+
+// handleCustom 处理自定义消息：校验通过后按单聊/群聊分发
+func (h *WebSocketHandler) handleCustom(ctx context.Context, conn *Connection, msg *model.Message) error {
+	if h.verifyCustom != nil {
+		if err := h.verifyCustom(ctx, conn, msg); err != nil {
+			h.sendError(conn, "custom_rejected", err.Error())
+			return nil
+		}
+	} else if content, ok := msg.Content.(map[string]interface{}); ok {
+		// 未配置校验时一律视为未校验
+		content["verified"] = false
+	}
+
+	if msg.GroupID != "" {
+		msg.To = msg.GroupID
+		return h.handleGroupChat(ctx, conn, msg)
+	}
+	return h.handleSingleChat(ctx, conn, msg)
 }
 
-// handleAck 处理消息确认
+// handleEncrypted 处理端到端加密消息：内容原样保存和转发，按是否携带 group_id 走群聊或单聊
+func (h *WebSocketHandler) handleEncrypted(ctx context.Context, conn *Connection, msg *model.Message) error {
+	if msg.GroupID != "" {
+		msg.To = msg.GroupID
+		return h.handleGroupChat(ctx, conn, msg)
+	}
+	return h.handleSingleChat(ctx, conn, msg)
+}
+
+// handleAck 处理消息确认：停止重发，本节点推送过的消息标记已送达
 func (h *WebSocketHandler) handleAck(ctx context.Context, conn *Connection, msg *model.Message) error {
-	// 这里可以实现消息确认逻辑
-	// 例如：更新消息状态、停止重发等
-	log.Printf("ACK received from %s for message %v", conn.UserID, msg.Content)
+	var messageID string
+	switch content := msg.Content.(type) {
+	case *model.AckContent:
+		messageID = content.MessageID
+	case map[string]interface{}:
+		messageID = getString(content, "message_id")
+	}
+	if messageID == "" {
+		return nil
+	}
+
+	// 只有等待该连接确认的消息才标记送达，避免确认不属于自己的消息
+	latency, ok := h.dispatcher.AckDelivery(conn, messageID)
+	if !ok {
+		return nil
+	}
+	if h.latency != nil {
+		h.latency.Observe(StageDelivered, latency)
+	}
+	if h.onDelivered == nil {
+		return nil
+	}
+	if err := h.onDelivered(ctx, conn.UserID, messageID); err != nil {
+		log.Printf("mark message %s delivered to %s error: %v", messageID, conn.UserID, err)
+	}
 	return nil
 }
 
@@ -368,6 +983,7 @@ func (h *WebSocketHandler) handleReadReceipt(ctx context.Context, conn *Connecti
 		if contentMap, ok := msg.Content.(map[string]interface{}); ok {
 			content = &model.ReadReceiptContent{
 				ConversationID: getString(contentMap, "conversation_id"),
+				MessageIDs:     getStrings(contentMap, "message_ids"),
 				LastReadSeq:    getInt64(contentMap, "last_read_seq"),
 			}
 		} else {
@@ -375,30 +991,17 @@ func (h *WebSocketHandler) handleReadReceipt(ctx context.Context, conn *Connecti
 		}
 	}
 
-	// 如果是单聊，发送给对方
-	if strings.HasPrefix(content.ConversationID, "single_") {
-		parts := content.ConversationID[7:]
-		for i := len(parts) - 1; i >= 0; i-- {
-			if parts[i] == '_' {
-				user1, user2 := parts[:i], parts[i+1:]
-				targetUser := user1
-				if user1 == conn.UserID {
-					targetUser = user2
-				}
-				return h.dispatcher.DispatchToUsers(ctx, []string{targetUser}, msg)
-			}
+	if h.onRead != nil {
+		if err := h.onRead(ctx, conn.UserID, content); err != nil {
+			log.Printf("Read hook error for user %s: %v", conn.UserID, err)
 		}
 	}
 
-	return nil
-}
-
-// handleTyping 处理正在输入
-func (h *WebSocketHandler) handleTyping(ctx context.Context, conn *Connection, msg *model.Message) error {
-	// 转发输入状态给对方
-	if msg.To != "" {
-		return h.dispatcher.DispatchToUsers(ctx, []string{msg.To}, msg)
+	// 如果是单聊，发送给对方
+	if convID, err := model.ParseConversationID(content.ConversationID); err == nil && convID.HasParticipant(conn.UserID) {
+		return h.dispatcher.DispatchToUsers(ctx, []string{convID.Peer(conn.UserID)}, msg)
 	}
+
 	return nil
 }
 
@@ -417,6 +1020,16 @@ func (h *WebSocketHandler) sendError(conn *Connection, code, message string) {
 
 // HandleHealth 健康检查接口
 func (h *WebSocketHandler) HandleHealth(c *gin.Context) {
+	// 排空中返回503，负载均衡摘除本节点
+	if h.connMgr.IsDraining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "draining",
+			"node_id": h.config.NodeID,
+			"time":    time.Now().Format(time.RFC3339),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "ok",
 		"node_id": h.config.NodeID,
@@ -514,6 +1127,21 @@ func getString(m map[string]interface{}, key string) string {
 	return ""
 }
 
+// 辅助函数：从map中获取字符串列表
+func getStrings(m map[string]interface{}, key string) []string {
+	items, ok := m[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
 // 辅助函数：从map中获取int64
 func getInt64(m map[string]interface{}, key string) int64 {
 	if v, ok := m[key]; ok {
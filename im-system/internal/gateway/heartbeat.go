@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HeartbeatConfig 心跳协商配置
+// 客户端握手时通过 heartbeat 参数（秒）请求心跳间隔，服务端限制在 [MinInterval, MaxInterval] 内；
+// 连续丢失超过 MaxMissed 个心跳（即 interval*(MaxMissed+1) 内未收到任何数据）时断开连接。
+// 连接丢失心跳后又恢复（抖动）时心跳间隔减半，连续 RecoverAfter 个周期正常后逐步恢复到协商值。
+type HeartbeatConfig struct {
+	DefaultInterval time.Duration // 客户端未指定时的心跳间隔
+	MinInterval     time.Duration // 最小心跳间隔
+	MaxInterval     time.Duration // 最大心跳间隔
+	MaxMissed       int           // 允许连续丢失的心跳数
+	RecoverAfter    int           // 恢复间隔前需要连续正常的心跳数
+}
+
+// DefaultHeartbeatConfig 默认心跳配置（默认30秒心跳、90秒超时）
+func DefaultHeartbeatConfig() *HeartbeatConfig {
+	return &HeartbeatConfig{
+		DefaultInterval: 30 * time.Second,
+		MinInterval:     10 * time.Second,
+		MaxInterval:     120 * time.Second,
+		MaxMissed:       2,
+		RecoverAfter:    10,
+	}
+}
+
+// fixedHeartbeatConfig 固定心跳配置（不协商、不自适应），兼容只配置了 PingInterval/PongTimeout 的场景
+func fixedHeartbeatConfig(pingInterval, pongTimeout time.Duration) *HeartbeatConfig {
+	maxMissed := int(pongTimeout/pingInterval) - 1
+	if maxMissed < 1 {
+		maxMissed = 1
+	}
+	return &HeartbeatConfig{
+		DefaultInterval: pingInterval,
+		MinInterval:     pingInterval,
+		MaxInterval:     pingInterval,
+		MaxMissed:       maxMissed,
+		RecoverAfter:    1,
+	}
+}
+
+// Negotiate 根据客户端请求的心跳间隔（秒，可为空）计算协商后的间隔
+func (c *HeartbeatConfig) Negotiate(requested string) time.Duration {
+	interval := c.DefaultInterval
+	if seconds, err := strconv.Atoi(requested); err == nil && seconds > 0 {
+		interval = time.Duration(seconds) * time.Second
+	}
+	return c.clamp(interval)
+}
+
+// MaxTimeout 所有连接中最长的心跳超时，用于空闲连接清理
+func (c *HeartbeatConfig) MaxTimeout() time.Duration {
+	return c.MaxInterval * time.Duration(c.MaxMissed+1)
+}
+
+// clamp 将间隔限制在配置范围内
+func (c *HeartbeatConfig) clamp(interval time.Duration) time.Duration {
+	if interval < c.MinInterval {
+		return c.MinInterval
+	}
+	if interval > c.MaxInterval {
+		return c.MaxInterval
+	}
+	return interval
+}
+
+// heartbeatState 连接的心跳状态
+type heartbeatState struct {
+	mu         sync.Mutex
+	config     *HeartbeatConfig
+	negotiated time.Duration // 握手时协商的间隔
+	interval   time.Duration // 当前间隔（抖动时缩短）
+	awaiting   bool          // 已发送Ping，尚未收到任何数据
+	missed     int           // 连续丢失的心跳数
+	healthy    int           // 连续正常的心跳数
+}
+
+// newHeartbeatState 创建心跳状态
+func newHeartbeatState(config *HeartbeatConfig, interval time.Duration) *heartbeatState {
+	return &heartbeatState{
+		config:     config,
+		negotiated: interval,
+		interval:   interval,
+	}
+}
+
+// Interval 当前心跳间隔
+func (s *heartbeatState) Interval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.interval
+}
+
+// Timeout 当前心跳超时（超过后读取失败并断开连接）
+func (s *heartbeatState) Timeout() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.timeout()
+}
+
+func (s *heartbeatState) timeout() time.Duration {
+	return s.interval * time.Duration(s.config.MaxMissed+1)
+}
+
+// Tick 到达心跳周期，返回本周期是否丢失了心跳
+func (s *heartbeatState) Tick() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	missed := s.awaiting
+	if missed {
+		s.missed++
+		s.healthy = 0
+	}
+	s.awaiting = true
+	return missed
+}
+
+// Alive 收到Pong或任意消息，返回心跳间隔是否发生变化
+func (s *heartbeatState) Alive() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.awaiting && s.missed == 0 {
+		// 同一周期内的多条消息只计一次
+		return false
+	}
+	s.awaiting = false
+
+	if s.missed > 0 {
+		// 丢失心跳后恢复：网络抖动，缩短间隔以更快发现断连并保持NAT映射
+		s.missed = 0
+		s.healthy = 0
+		shortened := s.config.clamp(s.interval / 2)
+		if shortened == s.interval {
+			return false
+		}
+		s.interval = shortened
+		heartbeatAdaptedTotal.WithLabelValues("shorten").Inc()
+		return true
+	}
+
+	s.healthy++
+	if s.interval < s.negotiated && s.healthy >= s.config.RecoverAfter {
+		s.healthy = 0
+		s.interval *= 2
+		if s.interval > s.negotiated {
+			s.interval = s.negotiated
+		}
+		heartbeatAdaptedTotal.WithLabelValues("restore").Inc()
+		return true
+	}
+	return false
+}
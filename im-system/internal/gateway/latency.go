@@ -0,0 +1,301 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// 消息处理阶段（每个阶段记录与上一阶段的间隔）
+const (
+	StageReceived   = "received"   // 读到帧到解析完成
+	StageValidated  = "validated"  // 时钟、去重及发送检查完成
+	StagePersisted  = "persisted"  // 消息保存完成
+	StageDispatched = "dispatched" // 分发检查及分发完成
+	StageDelivered  = "delivered"  // 推送到接收者确认（QoS1 投递确认，在接收者所在节点记录）
+)
+
+// LatencyStages 全部消息处理阶段（按处理顺序）
+var LatencyStages = []string{StageReceived, StageValidated, StagePersisted, StageDispatched, StageDelivered}
+
+// latencyNodesKey 各节点阶段耗时分位数（HASH，field为节点ID，value为 NodeLatency JSON）
+const latencyNodesKey = "im:latency:nodes"
+
+// LatencyConfig 消息处理耗时配置
+type LatencyConfig struct {
+	Window          int           // 每个阶段用于计算分位数的最近样本数
+	PublishInterval time.Duration // 本节点分位数发布到Redis的间隔，超过3个间隔未更新的节点不再展示
+	SampleRate      float64       // 写入诊断集合的消息比例（0-1，按消息ID抽样），0表示不写入
+}
+
+// DefaultLatencyConfig 默认消息处理耗时配置
+func DefaultLatencyConfig() *LatencyConfig {
+	return &LatencyConfig{
+		Window:          2048,
+		PublishInterval: 15 * time.Second,
+	}
+}
+
+// StageLatency 阶段耗时分位数（毫秒）
+type StageLatency struct {
+	Samples int     `json:"samples"`
+	P50     float64 `json:"p50_ms"`
+	P95     float64 `json:"p95_ms"`
+	P99     float64 `json:"p99_ms"`
+}
+
+// NodeLatency 节点各阶段耗时分位数
+type NodeLatency struct {
+	NodeID    string                   `json:"node_id"`
+	UpdatedAt int64                    `json:"updated_at"`
+	Stages    map[string]*StageLatency `json:"stages"`
+}
+
+// LatencySample 抽样消息的各阶段耗时
+type LatencySample struct {
+	MessageID      string
+	ConversationID string
+	Type           model.MessageType
+	NodeID         string
+	Stages         map[string]time.Duration
+	Total          time.Duration
+	ReceivedAt     time.Time
+}
+
+// LatencySampleSaver 耗时抽样保存接口
+type LatencySampleSaver interface {
+	SaveLatencySample(ctx context.Context, sample *LatencySample) error
+}
+
+// latencyWindow 最近样本环形缓冲
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+// add 记录样本，缓冲满时覆盖最早的样本
+func (w *latencyWindow) add(d time.Duration) {
+	w.samples[w.next] = d
+	w.next++
+	if w.next == len(w.samples) {
+		w.next = 0
+		w.full = true
+	}
+}
+
+// quantiles 计算分位数
+func (w *latencyWindow) quantiles() *StageLatency {
+	n := w.next
+	if w.full {
+		n = len(w.samples)
+	}
+	if n == 0 {
+		return &StageLatency{}
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, w.samples[:n])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	at := func(q float64) float64 {
+		return float64(sorted[int(q*float64(n-1))]) / float64(time.Millisecond)
+	}
+	return &StageLatency{Samples: n, P50: at(0.50), P95: at(0.95), P99: at(0.99)}
+}
+
+// LatencyTracker 消息处理耗时跟踪：按阶段记录直方图指标和最近样本的分位数，
+// 定期发布到Redis供管理后台按节点查看，并按比例抽样写入诊断集合
+type LatencyTracker struct {
+	redis  *redis.Client
+	nodeID string
+	config *LatencyConfig
+	saver  LatencySampleSaver
+
+	mu      sync.Mutex
+	windows map[string]*latencyWindow
+}
+
+// NewLatencyTracker 创建消息处理耗时跟踪
+func NewLatencyTracker(redisClient *redis.Client, nodeID string, config *LatencyConfig) *LatencyTracker {
+	if config == nil {
+		config = DefaultLatencyConfig()
+	}
+	defaults := DefaultLatencyConfig()
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.PublishInterval <= 0 {
+		config.PublishInterval = defaults.PublishInterval
+	}
+
+	windows := make(map[string]*latencyWindow, len(LatencyStages))
+	for _, stage := range LatencyStages {
+		windows[stage] = &latencyWindow{samples: make([]time.Duration, config.Window)}
+	}
+	return &LatencyTracker{
+		redis:   redisClient,
+		nodeID:  nodeID,
+		config:  config,
+		windows: windows,
+	}
+}
+
+// SetSampleSaver 设置耗时抽样保存，为空时不写入诊断集合
+func (t *LatencyTracker) SetSampleSaver(saver LatencySampleSaver) {
+	t.saver = saver
+}
+
+// Observe 记录阶段耗时
+func (t *LatencyTracker) Observe(stage string, d time.Duration) {
+	messageStageSeconds.WithLabelValues(stage).Observe(d.Seconds())
+
+	t.mu.Lock()
+	if w, ok := t.windows[stage]; ok {
+		w.add(d)
+	}
+	t.mu.Unlock()
+}
+
+// Snapshot 本节点各阶段耗时分位数
+func (t *LatencyTracker) Snapshot() *NodeLatency {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stages := make(map[string]*StageLatency, len(t.windows))
+	for stage, w := range t.windows {
+		stages[stage] = w.quantiles()
+	}
+	return &NodeLatency{
+		NodeID:    t.nodeID,
+		UpdatedAt: time.Now().Unix(),
+		Stages:    stages,
+	}
+}
+
+// Start 定期发布本节点分位数，直到 ctx 取消
+func (t *LatencyTracker) Start(ctx context.Context) {
+	ticker := time.NewTicker(t.config.PublishInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.publish(ctx); err != nil {
+				log.Printf("publish latency snapshot error: %v", err)
+			}
+		}
+	}
+}
+
+// publish 发布本节点分位数
+func (t *LatencyTracker) publish(ctx context.Context) error {
+	data, err := json.Marshal(t.Snapshot())
+	if err != nil {
+		return err
+	}
+	return t.redis.HSet(ctx, latencyNodesKey, t.nodeID, data).Err()
+}
+
+// ClusterLatency 获取集群各节点的阶段耗时分位数（按节点ID排序），本节点使用实时数据
+func (t *LatencyTracker) ClusterLatency(ctx context.Context) ([]*NodeLatency, error) {
+	values, err := t.redis.HGetAll(ctx, latencyNodesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	staleBefore := time.Now().Add(-3 * t.config.PublishInterval).Unix()
+	nodes := []*NodeLatency{t.Snapshot()}
+	for nodeID, value := range values {
+		if nodeID == t.nodeID {
+			continue
+		}
+		var node NodeLatency
+		if err := json.Unmarshal([]byte(value), &node); err != nil || node.UpdatedAt < staleBefore {
+			continue
+		}
+		nodes = append(nodes, &node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].NodeID < nodes[j].NodeID })
+	return nodes, nil
+}
+
+// sampled 消息是否抽样写入诊断集合（按消息ID哈希，同一消息在各节点结果一致）
+func (t *LatencyTracker) sampled(messageID string) bool {
+	if t.saver == nil || t.config.SampleRate <= 0 {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(messageID))
+	return float64(h.Sum32()%10000) < t.config.SampleRate*10000
+}
+
+// messageTiming 单条消息的处理阶段记录
+type messageTiming struct {
+	tracker    *LatencyTracker
+	receivedAt time.Time
+	last       time.Time
+	stages     map[string]time.Duration
+}
+
+// timingKey 消息处理阶段记录在 context 中的Key
+type timingKey struct{}
+
+// withTiming 开始记录消息处理阶段，receivedAt 为读到帧的时间
+func (t *LatencyTracker) withTiming(ctx context.Context, receivedAt time.Time) (context.Context, *messageTiming) {
+	timing := &messageTiming{
+		tracker:    t,
+		receivedAt: receivedAt,
+		last:       receivedAt,
+		stages:     make(map[string]time.Duration, len(LatencyStages)),
+	}
+	return context.WithValue(ctx, timingKey{}, timing), timing
+}
+
+// markStage 记录消息到达处理阶段（未开始记录时忽略），同时作为链路 Span 的事件
+func markStage(ctx context.Context, stage string) {
+	trace.SpanFromContext(ctx).AddEvent(stage)
+
+	timing, ok := ctx.Value(timingKey{}).(*messageTiming)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	d := now.Sub(timing.last)
+	timing.last = now
+	timing.stages[stage] = d
+	timing.tracker.Observe(stage, d)
+}
+
+// finish 消息处理结束：分发完成且被抽样时异步写入诊断集合
+func (m *messageTiming) finish(msg *model.Message) {
+	if _, ok := m.stages[StageDispatched]; !ok || !m.tracker.sampled(msg.MessageID) {
+		return
+	}
+	sample := &LatencySample{
+		MessageID:      msg.MessageID,
+		ConversationID: msg.ConversationID,
+		Type:           msg.Type,
+		NodeID:         m.tracker.nodeID,
+		Stages:         m.stages,
+		Total:          m.last.Sub(m.receivedAt),
+		ReceivedAt:     m.receivedAt,
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := m.tracker.saver.SaveLatencySample(ctx, sample); err != nil {
+			log.Printf("save latency sample of message %s error: %v", sample.MessageID, err)
+		}
+	}()
+}
@@ -0,0 +1,138 @@
+package gateway
+
+import (
+	"sync"
+)
+
+// 连接数限制错误
+var (
+	ErrNodeConnectionLimit = &ConnectionError{Code: 1005, Message: "too many connections on this node"}
+	ErrUserConnectionLimit = &ConnectionError{Code: 1006, Message: "too many connections for this user"}
+	ErrIPConnectionLimit   = &ConnectionError{Code: 1007, Message: "too many connections from this ip"}
+)
+
+// ConnectionLimitConfig 连接数限制配置（0表示不限制）
+type ConnectionLimitConfig struct {
+	MaxConnections int      // 本节点最大连接数
+	MaxPerUser     int      // 单用户最大并发连接数（含升级中的连接）
+	MaxPerIP       int      // 单IP最大连接数
+	ExemptUserIDs  []string // 不受限制的用户
+	ExemptIPs      []string // 不受限制的IP
+}
+
+// ConnectionLimiter 连接数限制器
+type ConnectionLimiter struct {
+	config *ConnectionLimitConfig
+
+	mu          sync.Mutex
+	total       int
+	perUser     map[string]int
+	perIP       map[string]int
+	exemptUsers map[string]bool
+	exemptIPs   map[string]bool
+}
+
+// NewConnectionLimiter 创建连接数限制器
+func NewConnectionLimiter(config *ConnectionLimitConfig) *ConnectionLimiter {
+	if config == nil {
+		config = &ConnectionLimitConfig{}
+	}
+
+	l := &ConnectionLimiter{
+		config:      config,
+		perUser:     make(map[string]int),
+		perIP:       make(map[string]int),
+		exemptUsers: make(map[string]bool),
+		exemptIPs:   make(map[string]bool),
+	}
+	for _, userID := range config.ExemptUserIDs {
+		l.exemptUsers[userID] = true
+	}
+	for _, ip := range config.ExemptIPs {
+		l.exemptIPs[ip] = true
+	}
+	return l
+}
+
+// Acquire 占用一个连接名额，超限时返回对应错误
+func (l *ConnectionLimiter) Acquire(userID, ip string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.exemptUsers[userID] && !l.exemptIPs[ip] {
+		if l.config.MaxConnections > 0 && l.total >= l.config.MaxConnections {
+			connectionsRejectedTotal.WithLabelValues("node").Inc()
+			return ErrNodeConnectionLimit
+		}
+		if l.config.MaxPerUser > 0 && l.perUser[userID] >= l.config.MaxPerUser {
+			connectionsRejectedTotal.WithLabelValues("user").Inc()
+			return ErrUserConnectionLimit
+		}
+		if l.config.MaxPerIP > 0 && l.perIP[ip] >= l.config.MaxPerIP {
+			connectionsRejectedTotal.WithLabelValues("ip").Inc()
+			return ErrIPConnectionLimit
+		}
+	}
+
+	l.total++
+	l.perUser[userID]++
+	l.perIP[ip]++
+	activeConnections.Set(float64(l.total))
+	return nil
+}
+
+// Release 释放连接名额
+func (l *ConnectionLimiter) Release(userID, ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.total > 0 {
+		l.total--
+	}
+	if l.perUser[userID] <= 1 {
+		delete(l.perUser, userID)
+	} else {
+		l.perUser[userID]--
+	}
+	if l.perIP[ip] <= 1 {
+		delete(l.perIP, ip)
+	} else {
+		l.perIP[ip]--
+	}
+	activeConnections.Set(float64(l.total))
+}
+
+// SetUserExempt 设置用户是否不受连接数限制
+func (l *ConnectionLimiter) SetUserExempt(userID string, exempt bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if exempt {
+		l.exemptUsers[userID] = true
+	} else {
+		delete(l.exemptUsers, userID)
+	}
+}
+
+// SetIPExempt 设置IP是否不受连接数限制
+func (l *ConnectionLimiter) SetIPExempt(ip string, exempt bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if exempt {
+		l.exemptIPs[ip] = true
+	} else {
+		delete(l.exemptIPs, ip)
+	}
+}
+
+// Stats 获取限制器统计
+func (l *ConnectionLimiter) Stats() map[string]interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return map[string]interface{}{
+		"total":           l.total,
+		"unique_ips":      len(l.perIP),
+		"max_connections": l.config.MaxConnections,
+		"max_per_user":    l.config.MaxPerUser,
+		"max_per_ip":      l.config.MaxPerIP,
+	}
+}
@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// maxCachedConversations 接收节点最多缓存的会话成员集合数，超出时整体清空
+const maxCachedConversations = 4096
+
+// memberDigest 会话成员摘要（与顺序无关），随会话路由消息发送，接收节点据此判断缓存的成员是否过期；
+// 0 表示没有摘要
+func memberDigest(members []string) uint64 {
+	var digest uint64
+	for _, uid := range members {
+		h := fnv.New64a()
+		h.Write([]byte(uid))
+		digest += h.Sum64()
+	}
+	return digest
+}
+
+// memberSet 缓存的会话成员
+type memberSet struct {
+	digest  uint64
+	members map[string]bool
+}
+
+// conversationMemberCache 接收节点缓存的会话成员：路由消息的成员摘要与缓存一致时直接使用，
+// 成员变更（摘要不一致）后才重新查询，避免每条会话路由消息在每个节点都查询一次成员
+type conversationMemberCache struct {
+	mu      sync.Mutex
+	entries map[string]*memberSet
+}
+
+// newConversationMemberCache 创建会话成员缓存
+func newConversationMemberCache() *conversationMemberCache {
+	return &conversationMemberCache{entries: make(map[string]*memberSet)}
+}
+
+// get 获取摘要一致的缓存成员，未缓存、摘要为0或不一致时返回 nil
+func (c *conversationMemberCache) get(conversationID string, digest uint64) map[string]bool {
+	if digest == 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if set, ok := c.entries[conversationID]; ok && set.digest == digest {
+		return set.members
+	}
+	return nil
+}
+
+// put 缓存查询到的会话成员，返回成员集合
+func (c *conversationMemberCache) put(conversationID string, members []string) map[string]bool {
+	set := &memberSet{digest: memberDigest(members), members: make(map[string]bool, len(members))}
+	for _, uid := range members {
+		set.members[uid] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCachedConversations {
+		c.entries = make(map[string]*memberSet)
+	}
+	c.entries[conversationID] = set
+	return set.members
+}
@@ -0,0 +1,348 @@
+package gateway
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// 路由模式标签
+const (
+	routeModeUser         = "user"         // 按用户路由
+	routeModeConversation = "conversation" // 按会话路由
+	routeModeBroadcast    = "broadcast"    // 广播
+)
+
+var (
+	// routePublishedTotal 跨节点发布的路由消息数
+	routePublishedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "dispatcher",
+		Name:      "route_messages_published_total",
+		Help:      "跨节点发布的路由消息数",
+	}, []string{"mode"})
+
+	// routeBytesPublishedTotal 跨节点发布的路由消息字节数
+	routeBytesPublishedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "dispatcher",
+		Name:      "route_bytes_published_total",
+		Help:      "跨节点发布的路由消息字节数",
+	}, []string{"mode"})
+
+	// routeBytesSavedTotal 会话路由相对按用户路由节省的字节数（估算）
+	routeBytesSavedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "dispatcher",
+		Name:      "route_bytes_saved_total",
+		Help:      "会话路由相对按用户路由节省的字节数（估算）",
+	})
+
+	// routeExpiredTotal 节点断开恢复后超过补投时长而丢弃的路由消息数
+	routeExpiredTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "dispatcher",
+		Name:      "route_messages_expired_total",
+		Help:      "节点断开恢复后超过补投时长而丢弃的路由消息数",
+	})
+
+	// fanoutDeliveredTotal 经限速器投递到连接的扇出消息数
+	fanoutDeliveredTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "dispatcher",
+		Name:      "fanout_delivered_total",
+		Help:      "经限速器投递到连接的扇出消息数（kind: broadcast/group_event）",
+	}, []string{"kind"})
+
+	// fanoutPacedTotal 因超出预算等待的扇出批次数
+	fanoutPacedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "dispatcher",
+		Name:      "fanout_paced_total",
+		Help:      "因超出扇出预算而等待的投递批次数",
+	}, []string{"kind"})
+
+	// fanoutDroppedTotal 因扇出队列已满被丢弃的任务数
+	fanoutDroppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "dispatcher",
+		Name:      "fanout_dropped_total",
+		Help:      "因扇出队列已满被丢弃的任务数",
+	}, []string{"kind"})
+
+	// fanoutDelaySeconds 扇出任务从入队到全部投递完成的时间
+	fanoutDelaySeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "im",
+		Subsystem: "dispatcher",
+		Name:      "fanout_delay_seconds",
+		Help:      "扇出任务从入队到全部投递完成的时间（含限速等待）",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"kind"})
+
+	// fanoutQueueDepth 待投递的扇出任务数
+	fanoutQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "im",
+		Subsystem: "dispatcher",
+		Name:      "fanout_queue_depth",
+		Help:      "待投递的扇出任务数",
+	})
+
+	// activeConnections 本节点受限制器统计的连接数
+	activeConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "active_connections",
+		Help:      "本节点当前连接数",
+	})
+
+	// connectionsRejectedTotal 因超出连接数限制被拒绝的连接数
+	connectionsRejectedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "connections_rejected_total",
+		Help:      "因超出连接数限制被拒绝的连接数",
+	}, []string{"reason"})
+
+	// connectionsShedTotal 过载保护拒绝的连接数
+	connectionsShedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "connections_shed_total",
+		Help:      "过载保护拒绝的新连接数",
+	}, []string{"reason"})
+
+	// heartbeatMissedTotal 丢失的心跳数
+	heartbeatMissedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "heartbeat_missed_total",
+		Help:      "连接丢失的心跳数",
+	})
+
+	// heartbeatAdaptedTotal 心跳间隔自适应调整次数
+	heartbeatAdaptedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "heartbeat_adapted_total",
+		Help:      "心跳间隔自适应调整次数（shorten: 抖动缩短, restore: 恢复）",
+	}, []string{"direction"})
+
+	// clockSkewRejectedTotal 因客户端时钟偏差过大被拒绝的消息数
+	clockSkewRejectedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "clock_skew_rejected_total",
+		Help:      "因客户端时钟偏差过大被拒绝的消息数",
+	})
+
+	// messagesRateLimitedTotal 因超出连接发送速率被拒绝的消息数
+	messagesRateLimitedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "messages_rate_limited_total",
+		Help:      "因超出连接发送速率被拒绝的消息数",
+	})
+
+	// groupMuteRejectedTotal 因发送者被禁言被拒绝的群消息数
+	groupMuteRejectedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "group_mute_rejected_total",
+		Help:      "因发送者被禁言被拒绝的群消息数",
+	})
+
+	// contentRejectedTotal 未通过内容审核被拒绝的消息数
+	contentRejectedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "content_rejected_total",
+		Help:      "未通过内容审核被拒绝的消息数",
+	})
+
+	// ephemeralMessagesTotal 临时消息处理结果数
+	ephemeralMessagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "ephemeral_messages_total",
+		Help:      "临时消息处理结果数（result: delivered/rate_limited/throttled/too_large/rejected）",
+	}, []string{"result"})
+
+	// offlineReplayMessagesTotal 连接建立后补发的离线消息数
+	offlineReplayMessagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "offline_replay_messages_total",
+		Help:      "连接建立后通过 WebSocket 补发的离线消息数（event: sent/acked）",
+	}, []string{"event"})
+
+	// laneEnqueuedTotal 按优先级入队的消息数
+	laneEnqueuedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "lane_enqueued_total",
+		Help:      "按优先级入队的消息数（queue: connection/dispatcher, lane: control/chat/bulk）",
+	}, []string{"queue", "lane"})
+
+	// laneDroppedTotal 因队列已满被丢弃的消息数
+	laneDroppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "lane_dropped_total",
+		Help:      "因优先级队列已满被丢弃的消息数",
+	}, []string{"queue", "lane"})
+
+	// laneStarvationPromotedTotal 为防止饿死被提前出队的低优先级消息数
+	laneStarvationPromotedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "lane_starvation_promoted_total",
+		Help:      "为防止饿死被提前出队的低优先级消息数",
+	}, []string{"queue", "lane"})
+
+	// laneWaitSeconds 消息在优先级队列中的等待时间
+	laneWaitSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "lane_wait_seconds",
+		Help:      "消息在优先级队列中的等待时间",
+		Buckets:   []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	}, []string{"queue", "lane"})
+
+	// writeBatchesTotal 合并写出的帧数
+	writeBatchesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "write_batches_total",
+		Help:      "发送合并写出的帧数（mode: json/lp, reason: full/window）",
+	}, []string{"mode", "reason"})
+
+	// writeBatchMessages 每帧合并的消息数
+	writeBatchMessages = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "write_batch_messages",
+		Help:      "发送合并每帧包含的消息数",
+		Buckets:   []float64{1, 2, 4, 8, 16, 32, 64, 128},
+	})
+
+	// writeBatchDelaySeconds 合并帧中首条消息等待写出的时间
+	writeBatchDelaySeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "write_batch_delay_seconds",
+		Help:      "发送合并帧中首条消息从出队到写出的时间",
+		Buckets:   []float64{0.0005, 0.001, 0.002, 0.005, 0.01, 0.025, 0.05, 0.1},
+	})
+
+	// sendFailedTotal 回ACK后被拒绝的消息数
+	sendFailedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "send_failed_total",
+		Help:      "回ACK后被拒绝的消息数（code: 错误码）",
+	}, []string{"code"})
+
+	// suggestionRequestsTotal 回复建议请求结果数
+	suggestionRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "suggestion_requests_total",
+		Help:      "回复建议请求结果数（result: delivered/empty/disabled/timeout/error/dropped）",
+	}, []string{"result"})
+
+	// suggestionLatency 回复建议服务响应时间
+	suggestionLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "suggestion_latency_seconds",
+		Help:      "外部回复建议服务的响应时间",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5},
+	})
+
+	// recordedFramesTotal 入站帧录制数
+	recordedFramesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "recorded_frames_total",
+		Help:      "入站帧录制数（result: recorded/dropped）",
+	}, []string{"result"})
+
+	// cpuUsage 进程CPU使用率（0-1）
+	cpuUsage = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "cpu_usage_ratio",
+		Help:      "进程CPU使用率（0-1）",
+	})
+
+	// sendQueueUsage 连接发送队列占用率（0-1）
+	sendQueueUsage = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "send_queue_usage_ratio",
+		Help:      "连接发送队列占用率（0-1）",
+	})
+
+	// overloadLevelGauge 过载程度（0表示未过载）
+	overloadLevelGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "overload_level",
+		Help:      "过载程度（0表示未过载，1表示满负荷）",
+	})
+
+	// deliveryInFlight 等待客户端确认的 QoS1 消息数
+	deliveryInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "delivery_inflight",
+		Help:      "等待客户端确认的 QoS1 消息数",
+	})
+
+	// deliveryAckedTotal 客户端确认的 QoS1 消息数
+	deliveryAckedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "delivery_acked_total",
+		Help:      "客户端确认的 QoS1 消息数",
+	})
+
+	// deliveryAckLatency 消息首次推送到客户端确认的时间
+	deliveryAckLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "delivery_ack_latency_seconds",
+		Help:      "QoS1 消息首次推送到客户端确认的时间（含重发）",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	})
+
+	// deliveryRetransmitTotal 超时未确认的重发次数
+	deliveryRetransmitTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "delivery_retransmit_total",
+		Help:      "QoS1 消息超时未确认的重发次数",
+	})
+
+	// deliveryEscalatedTotal 未确认而转存离线消息的消息数
+	deliveryEscalatedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "delivery_escalated_total",
+		Help:      "未确认而转存离线消息的 QoS1 消息数（reason: retries/disconnect/overflow）",
+	}, []string{"reason"})
+
+	// messageStageSeconds 消息各处理阶段耗时
+	messageStageSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "im",
+		Subsystem: "gateway",
+		Name:      "message_stage_seconds",
+		Help:      "消息各处理阶段与上一阶段的间隔（stage: received/validated/persisted/dispatched/delivered）",
+		Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"stage"})
+)
+
+// recordRoutePublish 记录一次路由消息发布
+func recordRoutePublish(mode string, size int) {
+	routePublishedTotal.WithLabelValues(mode).Inc()
+	routeBytesPublishedTotal.WithLabelValues(mode).Add(float64(size))
+}
@@ -0,0 +1,147 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// CapabilityOfflineReplay 客户端能力：连接建立后由网关通过 WebSocket 分批补发离线消息（type 112），
+// 客户端处理完每批后回复 type 37 确认，不再需要连接后单独调用离线消息接口
+const CapabilityOfflineReplay = "offline_replay"
+
+// maxOfflineReplayBatch 每批补发的离线消息数上限（与离线消息接口单次拉取上限一致）
+const maxOfflineReplayBatch = 500
+
+// OfflineReplayConfig 连接建立后自动补发离线消息的配置
+type OfflineReplayConfig struct {
+	BatchSize int // 每批补发的离线消息数，客户端确认后发送下一批
+}
+
+// DefaultOfflineReplayConfig 默认离线消息补发配置
+func DefaultOfflineReplayConfig() *OfflineReplayConfig {
+	return &OfflineReplayConfig{
+		BatchSize: 100,
+	}
+}
+
+// OfflineSource 补发的离线消息来源
+type OfflineSource interface {
+	// PullOfflineMessages 按序号升序拉取 lastSeq 之后的离线消息
+	PullOfflineMessages(ctx context.Context, userID string, lastSeq int64, limit int) ([]*model.OfflineMessage, error)
+	// DeleteOfflineMessages 删除客户端已确认的离线消息
+	DeleteOfflineMessages(ctx context.Context, userID string, messageIDs []string) error
+}
+
+// offlineReplay 连接的离线消息补发进度（只在读协程中使用）
+type offlineReplay struct {
+	lastSeq    int64    // 已发送批次最后一条离线消息的序号
+	messageIDs []string // 已发送、等待确认的消息ID
+	hasMore    bool     // 确认后是否继续拉取下一批
+}
+
+// SetOfflineSource 设置离线消息来源，配置了 OfflineReplay 时对声明 offline_replay 的连接自动补发
+func (h *WebSocketHandler) SetOfflineSource(source OfflineSource) {
+	h.offlineSource = source
+}
+
+// startOfflineReplay 连接建立后发送第一批离线消息
+func (h *WebSocketHandler) startOfflineReplay(ctx context.Context, conn *Connection) {
+	if h.offlineSource == nil || h.config.OfflineReplay == nil || !conn.HasCapability(CapabilityOfflineReplay) {
+		return
+	}
+	conn.offlineReplay = &offlineReplay{}
+	h.sendOfflineBatch(ctx, conn)
+}
+
+// sendOfflineBatch 拉取并发送下一批离线消息；没有更多消息时发送空批次（has_more 为 false）表示补发完成
+func (h *WebSocketHandler) sendOfflineBatch(ctx context.Context, conn *Connection) {
+	replay := conn.offlineReplay
+	batchSize := h.config.OfflineReplay.BatchSize
+	if batchSize <= 0 || batchSize > maxOfflineReplayBatch {
+		batchSize = DefaultOfflineReplayConfig().BatchSize
+	}
+
+	messages, err := h.offlineSource.PullOfflineMessages(ctx, conn.UserID, replay.lastSeq, batchSize)
+	if err != nil {
+		// 补发中止，客户端改用离线消息接口拉取
+		log.Printf("Pull offline messages for replay to user %s error: %v", conn.UserID, err)
+		conn.offlineReplay = nil
+		h.sendError(conn, "offline_replay_failed", "Offline replay failed, pull offline messages instead")
+		return
+	}
+
+	content := &model.OfflineBatchContent{
+		Messages: make([]*model.OfflineBatchItem, 0, len(messages)),
+		HasMore:  len(messages) >= batchSize,
+	}
+	replay.messageIDs = replay.messageIDs[:0]
+	for _, offline := range messages {
+		replay.lastSeq = int64(offline.ID)
+		var msg model.Message
+		if err := json.Unmarshal([]byte(offline.Content), &msg); err != nil {
+			continue
+		}
+		content.Messages = append(content.Messages, &model.OfflineBatchItem{
+			ID:             offline.ID,
+			MessageID:      offline.MessageID,
+			ConversationID: offline.ConversationID,
+			Message:        &msg,
+			CreatedAt:      offline.CreatedAt,
+		})
+		replay.messageIDs = append(replay.messageIDs, offline.MessageID)
+	}
+	content.LastSeq = replay.lastSeq
+	replay.hasMore = content.HasMore
+
+	if err := conn.SendJSON(&model.Message{
+		Type:      model.MsgOfflineBatch,
+		To:        conn.UserID,
+		Content:   content,
+		Timestamp: time.Now().UnixMilli(),
+	}); err != nil {
+		log.Printf("Send offline batch to user %s error: %v", conn.UserID, err)
+		conn.offlineReplay = nil
+		return
+	}
+	offlineReplayMessagesTotal.WithLabelValues("sent").Add(float64(len(content.Messages)))
+	if !content.HasMore && len(content.Messages) == 0 {
+		conn.offlineReplay = nil
+	}
+}
+
+// handleOfflineAck 处理离线消息批次确认：删除该批消息并发送下一批
+func (h *WebSocketHandler) handleOfflineAck(ctx context.Context, conn *Connection, msg *model.Message) error {
+	replay := conn.offlineReplay
+	if replay == nil {
+		return nil
+	}
+
+	var lastSeq int64
+	switch content := msg.Content.(type) {
+	case *model.OfflineAckContent:
+		lastSeq = content.LastSeq
+	case map[string]interface{}:
+		lastSeq = getInt64(content, "last_seq")
+	}
+	// 只接受当前批次的确认，重复或过期的确认忽略
+	if lastSeq != replay.lastSeq {
+		return nil
+	}
+
+	if err := h.offlineSource.DeleteOfflineMessages(ctx, conn.UserID, replay.messageIDs); err != nil {
+		return err
+	}
+	offlineReplayMessagesTotal.WithLabelValues("acked").Add(float64(len(replay.messageIDs)))
+	replay.messageIDs = nil
+
+	if !replay.hasMore {
+		conn.offlineReplay = nil
+		return nil
+	}
+	h.sendOfflineBatch(ctx, conn)
+	return nil
+}
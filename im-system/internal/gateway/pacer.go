@@ -0,0 +1,210 @@
+package gateway
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// 扇出类型
+const (
+	fanoutKindBroadcast  = "broadcast"   // 广播
+	fanoutKindGroupEvent = "group_event" // 群事件
+)
+
+// fanoutChunkSize 每次取令牌投递的最大连接数
+const fanoutChunkSize = 64
+
+// FanoutPaceConfig 广播、群事件扇出限速配置（按本节点投递到连接的条数和字节数计，0表示不限制）
+// 超出预算的扇出在独立协程中排队匀速投递，不影响单聊、群聊等直接消息
+type FanoutPaceConfig struct {
+	MessagesPerSecond float64 // 每秒投递条数
+	BytesPerSecond    float64 // 每秒投递字节数
+	QueueSize         int     // 待投递扇出任务数上限，满时丢弃新任务
+	OverloadMinRatio  float64 // 节点过载（CPU、发送队列）时预算按过载程度缩减，满负荷时降至该比例（0-1）
+}
+
+// DefaultFanoutPaceConfig 默认扇出限速配置
+func DefaultFanoutPaceConfig() *FanoutPaceConfig {
+	return &FanoutPaceConfig{
+		MessagesPerSecond: 20000,
+		BytesPerSecond:    20 << 20,
+		QueueSize:         1024,
+		OverloadMinRatio:  0.25,
+	}
+}
+
+// fanoutKind 判断消息是否为需要限速的批量扇出，返回扇出类型（直接消息返回空）
+func fanoutKind(msg *model.Message) string {
+	if msg == nil {
+		return ""
+	}
+	switch msg.Type {
+	case model.MsgGroupCreated, model.MsgGroupMemberJoin, model.MsgGroupMemberLeave, model.MsgGroupMemberKicked,
+		model.MsgGroupDismissed, model.MsgGroupInfoUpdate, model.MsgGroupAdminChange, model.MsgGroupMute, model.MsgGroupTransfer:
+		return fanoutKindGroupEvent
+	}
+	return ""
+}
+
+// fanoutJob 扇出任务
+type fanoutJob struct {
+	kind     string
+	data     []byte
+	priority Priority
+	conns    []Conn
+	queuedAt time.Time
+}
+
+// fanoutPacer 扇出限速器：按条数和字节数双令牌桶匀速投递排队的扇出任务
+type fanoutPacer struct {
+	config *FanoutPaceConfig
+	jobs   chan *fanoutJob
+
+	mu       sync.Mutex
+	messages float64 // 剩余条数令牌
+	bytes    float64 // 剩余字节令牌（单条超过桶容量时允许透支）
+	last     time.Time
+	load     func() float64 // 节点过载程度（0-1）
+}
+
+// newFanoutPacer 创建扇出限速器
+func newFanoutPacer(config *FanoutPaceConfig) *fanoutPacer {
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultFanoutPaceConfig().QueueSize
+	}
+	return &fanoutPacer{
+		config:   config,
+		jobs:     make(chan *fanoutJob, queueSize),
+		messages: config.MessagesPerSecond,
+		bytes:    config.BytesPerSecond,
+		last:     time.Now(),
+	}
+}
+
+// setLoadSampler 设置节点过载程度采样函数
+func (p *fanoutPacer) setLoadSampler(fn func() float64) {
+	p.mu.Lock()
+	p.load = fn
+	p.mu.Unlock()
+}
+
+// enqueue 提交扇出任务，队列满时丢弃
+func (p *fanoutPacer) enqueue(job *fanoutJob) bool {
+	job.queuedAt = time.Now()
+	select {
+	case p.jobs <- job:
+		fanoutQueueDepth.Set(float64(len(p.jobs)))
+		return true
+	default:
+		fanoutDroppedTotal.WithLabelValues(job.kind).Inc()
+		log.Printf("fanout queue full, drop %s to %d connections", job.kind, len(job.conns))
+		return false
+	}
+}
+
+// run 按预算投递扇出任务，直到 stop 关闭
+func (p *fanoutPacer) run(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case job := <-p.jobs:
+			fanoutQueueDepth.Set(float64(len(p.jobs)))
+			if !p.deliver(job, stop) {
+				return
+			}
+			fanoutDelaySeconds.WithLabelValues(job.kind).Observe(time.Since(job.queuedAt).Seconds())
+		}
+	}
+}
+
+// deliver 分批取令牌投递任务，stop 关闭时返回 false
+func (p *fanoutPacer) deliver(job *fanoutJob, stop <-chan struct{}) bool {
+	for start := 0; start < len(job.conns); {
+		n := p.chunkSize(len(job.conns)-start, len(job.data))
+		if wait := p.reserve(n, n*len(job.data)); wait > 0 {
+			fanoutPacedTotal.WithLabelValues(job.kind).Inc()
+			timer := time.NewTimer(wait)
+			select {
+			case <-stop:
+				timer.Stop()
+				return false
+			case <-timer.C:
+			}
+		}
+
+		for _, conn := range job.conns[start : start+n] {
+			if err := conn.SendPriority(job.data, job.priority); err != nil {
+				log.Printf("fanout %s to user %s error: %v", job.kind, conn.GetUserID(), err)
+			}
+		}
+		fanoutDeliveredTotal.WithLabelValues(job.kind).Add(float64(n))
+		start += n
+	}
+	return true
+}
+
+// chunkSize 本批投递的连接数：不超过 fanoutChunkSize，且字节数不超过一秒的字节预算（至少1条）
+func (p *fanoutPacer) chunkSize(remaining, size int) int {
+	n := remaining
+	if n > fanoutChunkSize {
+		n = fanoutChunkSize
+	}
+	if p.config.BytesPerSecond > 0 && size > 0 {
+		if limit := int(p.config.BytesPerSecond) / size; limit < n {
+			n = limit
+		}
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// reserve 预占令牌，返回需要等待的时间（令牌不足时透支，等待补足后再投递）
+func (p *fanoutPacer) reserve(messages, bytes int) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ratio := 1.0
+	if p.load != nil {
+		if overload := p.load(); overload > 0 {
+			ratio = 1 - overload*(1-p.config.OverloadMinRatio)
+		}
+	}
+	msgRate := p.config.MessagesPerSecond * ratio
+	byteRate := p.config.BytesPerSecond * ratio
+
+	now := time.Now()
+	elapsed := now.Sub(p.last).Seconds()
+	p.last = now
+
+	var wait time.Duration
+	if msgRate > 0 {
+		p.messages += elapsed * msgRate
+		if p.messages > msgRate {
+			p.messages = msgRate
+		}
+		p.messages -= float64(messages)
+		if p.messages < 0 {
+			wait = time.Duration(-p.messages / msgRate * float64(time.Second))
+		}
+	}
+	if byteRate > 0 {
+		p.bytes += elapsed * byteRate
+		if p.bytes > byteRate {
+			p.bytes = byteRate
+		}
+		p.bytes -= float64(bytes)
+		if p.bytes < 0 {
+			if d := time.Duration(-p.bytes / byteRate * float64(time.Second)); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
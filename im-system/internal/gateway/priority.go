@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// Priority 投递优先级，数值越小越优先
+type Priority int
+
+const (
+	PriorityControl Priority = iota // 控制消息：ACK、已读回执、输入状态、心跳、踢下线
+	PriorityChat                    // 聊天消息
+	PriorityBulk                    // 批量消息：广播、服务器通知、会话更新
+	numPriorities
+)
+
+// defaultStarvationLimit 低优先级有积压时，高优先级最多连续出队的次数
+const defaultStarvationLimit = 16
+
+// String 返回优先级名称（用作指标标签）
+func (p Priority) String() string {
+	switch p {
+	case PriorityControl:
+		return "control"
+	case PriorityChat:
+		return "chat"
+	case PriorityBulk:
+		return "bulk"
+	default:
+		return "unknown"
+	}
+}
+
+// MessagePriority 按消息类型确定投递优先级
+func MessagePriority(msg *model.Message) Priority {
+	if msg == nil {
+		return PriorityChat
+	}
+	switch msg.Type {
+	case model.MsgAck, model.MsgReadReceipt, model.MsgTyping, model.MsgEphemeral, model.MsgPatch, model.MsgSendFailed, model.MsgOfflineAck, model.MsgHeartbeat, model.MsgKickout, model.MsgSystem:
+		return PriorityControl
+	case model.MsgServerNotice, model.MsgConvUpdated:
+		return PriorityBulk
+	default:
+		return PriorityChat
+	}
+}
+
+// laneItem 队列元素
+type laneItem[T any] struct {
+	value    T
+	queuedAt time.Time
+}
+
+// laneMetrics 单个队列的指标
+type laneMetrics struct {
+	enqueued prometheus.Counter
+	dropped  prometheus.Counter
+	promoted prometheus.Counter
+	wait     prometheus.Observer
+}
+
+// laneQueue 按优先级分道的有界队列
+// 出队时优先取高优先级数据；低优先级队列有积压且已被连续跳过 starvationLimit 次时先取一条低优先级数据，
+// 避免批量消息被饿死。入队可并发调用，出队只允许单个消费者。
+type laneQueue[T any] struct {
+	lanes           [numPriorities]chan laneItem[T]
+	notify          chan struct{}
+	starvationLimit int
+	skipped         [numPriorities]int // 各队列有积压时被跳过的次数（仅消费者访问）
+	metrics         [numPriorities]laneMetrics
+}
+
+// newLaneQueue 创建分道队列，name 为指标中的 queue 标签
+func newLaneQueue[T any](name string, sizes [numPriorities]int, starvationLimit int) *laneQueue[T] {
+	if starvationLimit <= 0 {
+		starvationLimit = defaultStarvationLimit
+	}
+	q := &laneQueue[T]{
+		notify:          make(chan struct{}, 1),
+		starvationLimit: starvationLimit,
+	}
+	for p := PriorityControl; p < numPriorities; p++ {
+		q.lanes[p] = make(chan laneItem[T], sizes[p])
+		q.metrics[p] = laneMetrics{
+			enqueued: laneEnqueuedTotal.WithLabelValues(name, p.String()),
+			dropped:  laneDroppedTotal.WithLabelValues(name, p.String()),
+			promoted: laneStarvationPromotedTotal.WithLabelValues(name, p.String()),
+			wait:     laneWaitSeconds.WithLabelValues(name, p.String()),
+		}
+	}
+	return q
+}
+
+// push 入队，对应队列已满时返回 false
+func (q *laneQueue[T]) push(p Priority, value T) bool {
+	if p < PriorityControl || p >= numPriorities {
+		p = PriorityChat
+	}
+	select {
+	case q.lanes[p] <- laneItem[T]{value: value, queuedAt: time.Now()}:
+	default:
+		q.metrics[p].dropped.Inc()
+		return false
+	}
+	q.metrics[p].enqueued.Inc()
+	q.signal()
+	return true
+}
+
+// pop 取出下一条数据，所有队列为空时返回 false
+func (q *laneQueue[T]) pop() (T, bool) {
+	// 低优先级等待过久时先让其出队一条
+	for p := numPriorities - 1; p > PriorityControl; p-- {
+		if q.skipped[p] < q.starvationLimit {
+			continue
+		}
+		if value, ok := q.take(p); ok {
+			q.metrics[p].promoted.Inc()
+			return value, true
+		}
+		q.skipped[p] = 0
+	}
+
+	for p := PriorityControl; p < numPriorities; p++ {
+		value, ok := q.take(p)
+		if !ok {
+			continue
+		}
+		for lower := p + 1; lower < numPriorities; lower++ {
+			if len(q.lanes[lower]) > 0 {
+				q.skipped[lower]++
+			}
+		}
+		return value, true
+	}
+
+	var zero T
+	return zero, false
+}
+
+// take 从指定队列取出一条数据
+func (q *laneQueue[T]) take(p Priority) (T, bool) {
+	select {
+	case item := <-q.lanes[p]:
+		q.skipped[p] = 0
+		q.metrics[p].wait.Observe(time.Since(item.queuedAt).Seconds())
+		return item.value, true
+	default:
+		var zero T
+		return zero, false
+	}
+}
+
+// signal 通知消费者有数据待处理
+func (q *laneQueue[T]) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// ready 有新数据入队时收到信号
+func (q *laneQueue[T]) ready() <-chan struct{} {
+	return q.notify
+}
+
+// pending 是否还有未出队的数据
+func (q *laneQueue[T]) pending() bool {
+	for p := PriorityControl; p < numPriorities; p++ {
+		if len(q.lanes[p]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// usage 返回排队数与总容量
+func (q *laneQueue[T]) usage() (queued, capacity int) {
+	for p := PriorityControl; p < numPriorities; p++ {
+		queued += len(q.lanes[p])
+		capacity += cap(q.lanes[p])
+	}
+	return queued, capacity
+}
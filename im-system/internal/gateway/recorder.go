@@ -0,0 +1,228 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 录制事件类型
+const (
+	RecordEventConnect    = "connect"    // 连接建立
+	RecordEventFrame      = "frame"      // 收到入站帧
+	RecordEventDisconnect = "disconnect" // 连接断开
+)
+
+// RecordedFrame 录制的入站帧及其上下文（JSON Lines 格式，每行一条）
+type RecordedFrame struct {
+	Seq        int64  `json:"seq"`     // 本节点录制序号（单调递增）
+	Event      string `json:"event"`   // connect/frame/disconnect
+	NodeID     string `json:"node_id"` // 收到帧的节点
+	ConnID     string `json:"conn_id"` // 连接ID
+	UserID     string `json:"user_id"` // 用户ID
+	Platform   string `json:"platform,omitempty"`
+	DeviceID   string `json:"device_id,omitempty"`
+	Locale     string `json:"locale,omitempty"`
+	AppVersion string `json:"app_version,omitempty"`
+	ReceivedAt int64  `json:"received_at"`    // 收到时间（Unix微秒）
+	Data       string `json:"data,omitempty"` // 原始帧内容（只有 frame 事件）
+}
+
+// FrameRecorderConfig 入站帧录制配置
+type FrameRecorderConfig struct {
+	Dir          string          // 录制文件目录
+	SampleRate   float64         // 按连接抽样的比例（0-1），抽中的连接录制全部入站帧
+	UserIDs      map[string]bool // 始终录制的用户（不受抽样比例影响）
+	MaxFileBytes int64           // 单个录制文件大小上限，超出后切换到新文件
+	BufferSize   int             // 写入队列容量，写入跟不上时丢弃录制（不阻塞读协程）
+}
+
+// DefaultFrameRecorderConfig 默认录制配置
+func DefaultFrameRecorderConfig() *FrameRecorderConfig {
+	return &FrameRecorderConfig{
+		Dir:          "recordings",
+		SampleRate:   0.01,
+		UserIDs:      map[string]bool{},
+		MaxFileBytes: 100 << 20,
+		BufferSize:   4096,
+	}
+}
+
+// FrameRecorder 入站帧录制：抽样连接的入站帧连同连接上下文写入本地文件，用于在测试环境回放复现问题
+type FrameRecorder struct {
+	nodeID string
+	config *FrameRecorderConfig
+	frames chan *RecordedFrame
+	done   chan struct{}
+
+	mu     sync.Mutex
+	seq    int64
+	closed bool
+
+	file    *os.File
+	writer  *bufio.Writer
+	written int64
+}
+
+// NewFrameRecorder 创建入站帧录制器
+func NewFrameRecorder(nodeID string, config *FrameRecorderConfig) (*FrameRecorder, error) {
+	if config == nil {
+		config = DefaultFrameRecorderConfig()
+	}
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create recording dir error: %w", err)
+	}
+
+	r := &FrameRecorder{
+		nodeID: nodeID,
+		config: config,
+		frames: make(chan *RecordedFrame, config.BufferSize),
+		done:   make(chan struct{}),
+	}
+	if err := r.rotate(); err != nil {
+		return nil, err
+	}
+	go r.run()
+	return r, nil
+}
+
+// Sampled 连接是否录制：指定用户始终录制，其余按连接ID哈希抽样（同一连接的帧要么全部录制要么都不录制）
+func (r *FrameRecorder) Sampled(connID, userID string) bool {
+	if r.config.UserIDs[userID] {
+		return true
+	}
+	if r.config.SampleRate <= 0 {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(connID))
+	return float64(h.Sum32()%10000) < r.config.SampleRate*10000
+}
+
+// Record 记录连接事件，data 为空表示连接建立或断开
+func (r *FrameRecorder) Record(event string, conn *Connection, data []byte) {
+	frame := &RecordedFrame{
+		Event:      event,
+		NodeID:     r.nodeID,
+		ConnID:     conn.ID,
+		UserID:     conn.UserID,
+		Platform:   conn.Platform,
+		DeviceID:   conn.DeviceID,
+		Locale:     conn.Locale,
+		AppVersion: conn.ClientInfo.AppVersion,
+		ReceivedAt: time.Now().UnixMicro(),
+		Data:       string(data),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	r.seq++
+	frame.Seq = r.seq
+	select {
+	case r.frames <- frame:
+		recordedFramesTotal.WithLabelValues("recorded").Inc()
+	default:
+		recordedFramesTotal.WithLabelValues("dropped").Inc()
+	}
+}
+
+// Close 停止录制并写出剩余数据
+func (r *FrameRecorder) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.frames)
+	r.mu.Unlock()
+
+	<-r.done
+	return r.closeFile()
+}
+
+// run 写入协程：队列空闲时刷新缓冲，保证进程异常退出时丢失的录制尽量少
+func (r *FrameRecorder) run() {
+	defer close(r.done)
+	for {
+		frame, ok := <-r.frames
+		if !ok {
+			return
+		}
+		r.write(frame)
+		if len(r.frames) == 0 {
+			if err := r.writer.Flush(); err != nil {
+				log.Printf("Flush recording error: %v", err)
+			}
+		}
+	}
+}
+
+// write 写入一条录制，超出文件大小上限时切换文件
+func (r *FrameRecorder) write(frame *RecordedFrame) {
+	line, err := json.Marshal(frame)
+	if err != nil {
+		log.Printf("Marshal recorded frame error: %v", err)
+		return
+	}
+	if r.config.MaxFileBytes > 0 && r.written+int64(len(line))+1 > r.config.MaxFileBytes && r.written > 0 {
+		if err := r.rotate(); err != nil {
+			log.Printf("Rotate recording file error: %v", err)
+			return
+		}
+	}
+	r.writer.Write(line)
+	r.writer.WriteByte('\n')
+	r.written += int64(len(line)) + 1
+}
+
+// rotate 切换到新的录制文件（文件名含节点ID和创建时间）
+func (r *FrameRecorder) rotate() error {
+	if err := r.closeFile(); err != nil {
+		log.Printf("Close recording file error: %v", err)
+	}
+	name := fmt.Sprintf("frames-%s-%s.jsonl", r.nodeID, time.Now().UTC().Format("20060102T150405.000000"))
+	file, err := os.OpenFile(filepath.Join(r.config.Dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open recording file error: %w", err)
+	}
+	r.file = file
+	r.writer = bufio.NewWriter(file)
+	r.written = 0
+	log.Printf("Recording inbound frames to %s", file.Name())
+	return nil
+}
+
+// closeFile 关闭当前录制文件
+func (r *FrameRecorder) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.writer.Flush()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.file = nil
+	return err
+}
+
+// SetFrameRecorder 设置入站帧录制器（为空时不录制）
+func (h *WebSocketHandler) SetFrameRecorder(recorder *FrameRecorder) {
+	h.recorder = recorder
+}
+
+// record 录制抽中连接的事件
+func (h *WebSocketHandler) record(event string, conn *Connection, data []byte) {
+	if h.recorder != nil && conn.recorded {
+		h.recorder.Record(event, conn, data)
+	}
+}
@@ -0,0 +1,250 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// nodeConnsKey 节点连接登记Key（HASH，field为用户ID，value为连接信息）
+func nodeConnsKey(nodeID string) string {
+	return fmt.Sprintf("im:node:conns:%s", nodeID)
+}
+
+// removeNodeConnScript 仅当登记的仍是该连接时删除，避免同一用户新连接的登记被旧连接注销覆盖
+var removeNodeConnScript = redis.NewScript(`
+local val = redis.call("HGET", KEYS[1], ARGV[1])
+if val and cjson.decode(val)["conn_id"] == ARGV[2] then
+	return redis.call("HDEL", KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// ConnectionEntry 节点连接登记信息
+type ConnectionEntry struct {
+	UserID      string `json:"user_id"`
+	ConnID      string `json:"conn_id"`
+	NodeID      string `json:"node_id"`
+	DeviceID    string `json:"device_id,omitempty"`
+	Platform    string `json:"platform,omitempty"`
+	ClientIP    string `json:"client_ip,omitempty"`
+	ConnectedAt int64  `json:"connected_at"`
+
+	AppVersion   string   `json:"app_version,omitempty"`
+	OS           string   `json:"os,omitempty"`
+	NetworkType  string   `json:"network_type,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// ClientVersionCount 按平台、版本统计的连接数
+type ClientVersionCount struct {
+	Platform    string `json:"platform"`
+	AppVersion  string `json:"app_version"`
+	Connections int64  `json:"connections"`
+}
+
+// ClientStats 集群客户端分布
+type ClientStats struct {
+	Total    int64                 `json:"total"`
+	Versions []*ClientVersionCount `json:"versions"` // 按连接数降序
+	OS       map[string]int64      `json:"os"`
+	Networks map[string]int64      `json:"networks"`
+}
+
+// NodeConnectionCount 节点连接数
+type NodeConnectionCount struct {
+	NodeID      string `json:"node_id"`
+	Connections int64  `json:"connections"`
+}
+
+// ConnectionRegistry 节点连接登记表
+// 在Redis中按节点维护已连接的用户/设备，用于按节点定向操作和集群连接数统计；
+// 注册/注销时增量更新，心跳检查时用本地连接全量校正，节点宕机后登记随TTL过期
+type ConnectionRegistry struct {
+	redis  *redis.Client
+	nodeID string
+	ttl    time.Duration
+}
+
+// NewConnectionRegistry 创建节点连接登记表
+// ttl 应大于心跳检查间隔，节点停止校正后登记自动过期
+func NewConnectionRegistry(redisClient *redis.Client, nodeID string, ttl time.Duration) *ConnectionRegistry {
+	return &ConnectionRegistry{
+		redis:  redisClient,
+		nodeID: nodeID,
+		ttl:    ttl,
+	}
+}
+
+// newConnectionEntry 根据连接生成登记信息
+func newConnectionEntry(conn *Connection) *ConnectionEntry {
+	return &ConnectionEntry{
+		UserID:      conn.UserID,
+		ConnID:      conn.ID,
+		NodeID:      conn.NodeID,
+		DeviceID:    conn.DeviceID,
+		Platform:    conn.Platform,
+		ClientIP:    conn.ClientIP,
+		ConnectedAt: conn.CreatedAt.Unix(),
+
+		AppVersion:   conn.ClientInfo.AppVersion,
+		OS:           conn.ClientInfo.OS,
+		NetworkType:  conn.ClientInfo.NetworkType,
+		Capabilities: conn.ClientInfo.Capabilities,
+	}
+}
+
+// Add 登记连接
+func (r *ConnectionRegistry) Add(ctx context.Context, conn *Connection) error {
+	data, err := json.Marshal(newConnectionEntry(conn))
+	if err != nil {
+		return err
+	}
+
+	key := nodeConnsKey(r.nodeID)
+	pipe := r.redis.Pipeline()
+	pipe.HSet(ctx, key, conn.UserID, data)
+	pipe.Expire(ctx, key, r.ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Remove 注销连接登记
+func (r *ConnectionRegistry) Remove(ctx context.Context, conn *Connection) error {
+	return removeNodeConnScript.Run(ctx, r.redis, []string{nodeConnsKey(r.nodeID)}, conn.UserID, conn.ID).Err()
+}
+
+// Reconcile 用本地连接全量校正本节点登记
+func (r *ConnectionRegistry) Reconcile(ctx context.Context, conns []*Connection) error {
+	key := nodeConnsKey(r.nodeID)
+
+	values := make([]interface{}, 0, len(conns)*2)
+	for _, conn := range conns {
+		data, err := json.Marshal(newConnectionEntry(conn))
+		if err != nil {
+			continue
+		}
+		values = append(values, conn.UserID, data)
+	}
+
+	pipe := r.redis.TxPipeline()
+	pipe.Del(ctx, key)
+	if len(values) > 0 {
+		pipe.HSet(ctx, key, values...)
+		pipe.Expire(ctx, key, r.ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Clear 清除本节点登记（节点下线时调用）
+func (r *ConnectionRegistry) Clear(ctx context.Context) error {
+	return r.redis.Del(ctx, nodeConnsKey(r.nodeID)).Err()
+}
+
+// ListConnections 分页列出节点上的连接，返回下一页游标（为0表示结束）
+func (r *ConnectionRegistry) ListConnections(ctx context.Context, nodeID string, cursor uint64, count int64) ([]*ConnectionEntry, uint64, error) {
+	kvs, next, err := r.redis.HScan(ctx, nodeConnsKey(nodeID), cursor, "", count).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("scan node connections error: %w", err)
+	}
+
+	entries := make([]*ConnectionEntry, 0, len(kvs)/2)
+	for i := 1; i < len(kvs); i += 2 {
+		var entry ConnectionEntry
+		if err := json.Unmarshal([]byte(kvs[i]), &entry); err != nil {
+			log.Printf("unmarshal connection entry of %s error: %v", kvs[i-1], err)
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, next, nil
+}
+
+// CountConnections 统计各节点连接数（按节点ID排序）
+func (r *ConnectionRegistry) CountConnections(ctx context.Context) ([]*NodeConnectionCount, error) {
+	nodes, err := r.redis.SMembers(ctx, "im:nodes").Result()
+	if err != nil {
+		return nil, fmt.Errorf("get nodes error: %w", err)
+	}
+	sort.Strings(nodes)
+
+	pipe := r.redis.Pipeline()
+	cmds := make([]*redis.IntCmd, len(nodes))
+	for i, nodeID := range nodes {
+		cmds[i] = pipe.HLen(ctx, nodeConnsKey(nodeID))
+	}
+	if len(nodes) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("count node connections error: %w", err)
+		}
+	}
+
+	counts := make([]*NodeConnectionCount, len(nodes))
+	for i, nodeID := range nodes {
+		counts[i] = &NodeConnectionCount{NodeID: nodeID, Connections: cmds[i].Val()}
+	}
+	return counts, nil
+}
+
+// clientStatsScanCount 统计客户端分布时每次扫描的条数
+const clientStatsScanCount = 1000
+
+// ClientStats 扫描各节点登记，统计客户端版本、系统及网络类型分布（未上报的归为 unknown）
+func (r *ConnectionRegistry) ClientStats(ctx context.Context) (*ClientStats, error) {
+	nodes, err := r.redis.SMembers(ctx, "im:nodes").Result()
+	if err != nil {
+		return nil, fmt.Errorf("get nodes error: %w", err)
+	}
+
+	stats := &ClientStats{OS: make(map[string]int64), Networks: make(map[string]int64)}
+	versions := make(map[[2]string]int64)
+	for _, nodeID := range nodes {
+		var cursor uint64
+		for {
+			entries, next, err := r.ListConnections(ctx, nodeID, cursor, clientStatsScanCount)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				stats.Total++
+				versions[[2]string{orUnknown(entry.Platform), orUnknown(entry.AppVersion)}]++
+				stats.OS[orUnknown(entry.OS)]++
+				stats.Networks[orUnknown(entry.NetworkType)]++
+			}
+			if next == 0 {
+				break
+			}
+			cursor = next
+		}
+	}
+
+	stats.Versions = make([]*ClientVersionCount, 0, len(versions))
+	for key, count := range versions {
+		stats.Versions = append(stats.Versions, &ClientVersionCount{Platform: key[0], AppVersion: key[1], Connections: count})
+	}
+	sort.Slice(stats.Versions, func(i, j int) bool {
+		a, b := stats.Versions[i], stats.Versions[j]
+		if a.Connections != b.Connections {
+			return a.Connections > b.Connections
+		}
+		if a.Platform != b.Platform {
+			return a.Platform < b.Platform
+		}
+		return a.AppVersion < b.AppVersion
+	})
+	return stats, nil
+}
+
+// orUnknown 空值统计为 unknown
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
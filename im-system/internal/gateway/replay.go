@@ -0,0 +1,318 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ReplayConfig 回放配置
+type ReplayConfig struct {
+	Targets map[string]string // 录制节点ID -> 目标网关地址（如 ws://staging:8080/ws），* 为默认目标
+	Speed   float64           // 时间缩放倍数（2表示两倍速），0表示不等待、按录制顺序尽快发送
+	// Token 为录制的连接签发目标环境的访问 Token
+	Token func(frame *RecordedFrame) (string, error)
+	// ShiftClientTimestamps 按回放时间平移帧中的 client_timestamp，避免被目标网关的时钟偏差检查拒绝
+	ShiftClientTimestamps bool
+	DialTimeout           time.Duration // 建立连接超时
+	WriteTimeout          time.Duration // 单帧写入超时
+	Linger                time.Duration // 最后一帧发送后保持连接的时间（等待服务端响应）
+}
+
+// DefaultReplayConfig 默认回放配置
+func DefaultReplayConfig() *ReplayConfig {
+	return &ReplayConfig{
+		Targets:               map[string]string{},
+		Speed:                 1,
+		ShiftClientTimestamps: true,
+		DialTimeout:           10 * time.Second,
+		WriteTimeout:          10 * time.Second,
+		Linger:                2 * time.Second,
+	}
+}
+
+// ReplayStats 回放结果
+type ReplayStats struct {
+	Connections    int           `json:"connections"`     // 建立的连接数
+	FramesSent     int           `json:"frames_sent"`     // 发送的帧数
+	FramesSkipped  int           `json:"frames_skipped"`  // 连接建立失败而跳过的帧数
+	FramesReceived int64         `json:"frames_received"` // 收到的服务端帧数
+	Errors         int           `json:"errors"`          // 建立连接或写入失败次数
+	Duration       time.Duration `json:"duration"`        // 回放耗时
+}
+
+// ParseReplayTargets 解析回放目标：单个地址表示所有节点回放到同一网关，或 node=url 逗号分隔（* 为默认目标）
+func ParseReplayTargets(value string) (map[string]string, error) {
+	targets := make(map[string]string)
+	if value = strings.TrimSpace(value); strings.HasPrefix(value, "ws://") || strings.HasPrefix(value, "wss://") {
+		targets["*"] = value
+		return targets, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		node, target, ok := strings.Cut(item, "=")
+		node, target = strings.TrimSpace(node), strings.TrimSpace(target)
+		if !ok || node == "" || target == "" {
+			return nil, fmt.Errorf("invalid replay target %q", item)
+		}
+		targets[node] = target
+	}
+	return targets, nil
+}
+
+// LoadRecordings 读取录制文件（可来自多个节点），按收到时间合并排序；时间相同时按节点ID和录制序号排序，保证每次回放顺序一致
+func LoadRecordings(paths ...string) ([]*RecordedFrame, error) {
+	var frames []*RecordedFrame
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 16<<20)
+		line := 0
+		for scanner.Scan() {
+			line++
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var frame RecordedFrame
+			if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			frames = append(frames, &frame)
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %s error: %w", path, err)
+		}
+	}
+
+	sort.SliceStable(frames, func(i, j int) bool {
+		a, b := frames[i], frames[j]
+		if a.ReceivedAt != b.ReceivedAt {
+			return a.ReceivedAt < b.ReceivedAt
+		}
+		if a.NodeID != b.NodeID {
+			return a.NodeID < b.NodeID
+		}
+		return a.Seq < b.Seq
+	})
+	return frames, nil
+}
+
+// Replayer 录制回放：按录制的相对时间（可缩放）向目标网关重建连接并发送入站帧，
+// 用于在测试环境复现多节点并发下的乱序、去重和分发问题
+type Replayer struct {
+	config *ReplayConfig
+	dialer *websocket.Dialer
+
+	received atomic.Int64
+	readers  sync.WaitGroup
+}
+
+// replayConn 回放中的连接（conn 为空表示建立失败，之后该连接的帧全部跳过）
+type replayConn struct {
+	conn *websocket.Conn
+}
+
+// NewReplayer 创建回放器
+func NewReplayer(config *ReplayConfig) *Replayer {
+	if config == nil {
+		config = DefaultReplayConfig()
+	}
+	return &Replayer{
+		config: config,
+		dialer: &websocket.Dialer{HandshakeTimeout: config.DialTimeout},
+	}
+}
+
+// Run 回放录制的帧，所有帧发送完毕（或 ctx 取消）并等待 Linger 后关闭连接
+func (r *Replayer) Run(ctx context.Context, frames []*RecordedFrame) (*ReplayStats, error) {
+	if r.config.Token == nil {
+		return nil, errors.New("replay token issuer is required")
+	}
+
+	stats := &ReplayStats{}
+	conns := make(map[string]*replayConn)
+	defer func() {
+		for _, rc := range conns {
+			if rc.conn != nil {
+				rc.conn.Close()
+			}
+		}
+		r.readers.Wait()
+		stats.FramesReceived = r.received.Load()
+	}()
+
+	startedAt := time.Now()
+	for _, frame := range frames {
+		if err := r.wait(ctx, startedAt, frame.ReceivedAt-frames[0].ReceivedAt); err != nil {
+			stats.Duration = time.Since(startedAt)
+			return stats, err
+		}
+
+		key := frame.NodeID + "/" + frame.ConnID
+		rc, ok := conns[key]
+		switch frame.Event {
+		case RecordEventConnect:
+			if ok && rc.conn != nil {
+				continue
+			}
+			conns[key] = r.connect(ctx, frame, stats)
+
+		case RecordEventDisconnect:
+			if ok && rc.conn != nil {
+				rc.conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+				rc.conn.Close()
+			}
+			delete(conns, key)
+
+		case RecordEventFrame:
+			// 录制从连接中途开始（文件切换或录制丢弃）时补建连接
+			if !ok {
+				rc = r.connect(ctx, frame, stats)
+				conns[key] = rc
+			}
+			if rc.conn == nil {
+				stats.FramesSkipped++
+				continue
+			}
+			rc.conn.SetWriteDeadline(time.Now().Add(r.config.WriteTimeout))
+			if err := rc.conn.WriteMessage(websocket.TextMessage, r.payload(frame)); err != nil {
+				log.Printf("Replay frame %s#%d error: %v", frame.NodeID, frame.Seq, err)
+				stats.Errors++
+				rc.conn.Close()
+				rc.conn = nil
+				continue
+			}
+			stats.FramesSent++
+		}
+	}
+
+	// 等待服务端处理并返回响应
+	select {
+	case <-ctx.Done():
+	case <-time.After(r.config.Linger):
+	}
+	stats.Duration = time.Since(startedAt)
+	return stats, nil
+}
+
+// wait 等待到帧的回放时间（录制相对时间按 Speed 缩放）
+func (r *Replayer) wait(ctx context.Context, startedAt time.Time, offsetMicros int64) error {
+	if r.config.Speed <= 0 {
+		return ctx.Err()
+	}
+	delay := time.Until(startedAt.Add(time.Duration(float64(offsetMicros)/r.config.Speed) * time.Microsecond))
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// connect 以录制的用户和设备信息连接目标网关
+func (r *Replayer) connect(ctx context.Context, frame *RecordedFrame, stats *ReplayStats) *replayConn {
+	target := r.config.Targets[frame.NodeID]
+	if target == "" {
+		target = r.config.Targets["*"]
+	}
+	if target == "" {
+		log.Printf("Replay: no target for node %s", frame.NodeID)
+		stats.Errors++
+		return &replayConn{}
+	}
+
+	token, err := r.config.Token(frame)
+	if err != nil {
+		log.Printf("Replay: issue token for user %s error: %v", frame.UserID, err)
+		stats.Errors++
+		return &replayConn{}
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		log.Printf("Replay: invalid target %s: %v", target, err)
+		stats.Errors++
+		return &replayConn{}
+	}
+	query := u.Query()
+	query.Set("platform", frame.Platform)
+	query.Set("device_id", frame.DeviceID)
+	query.Set("locale", frame.Locale)
+	query.Set("app_version", frame.AppVersion)
+	u.RawQuery = query.Encode()
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	conn, _, err := r.dialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		log.Printf("Replay: connect user %s (conn %s) error: %v", frame.UserID, frame.ConnID, err)
+		stats.Errors++
+		return &replayConn{}
+	}
+	stats.Connections++
+
+	// 持续读取服务端帧，避免目标网关发送队列积压
+	r.readers.Add(1)
+	go func() {
+		defer r.readers.Done()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+			r.received.Add(1)
+		}
+	}()
+	return &replayConn{conn: conn}
+}
+
+// payload 回放的帧内容，按需平移 client_timestamp（无法解析的帧原样发送）
+func (r *Replayer) payload(frame *RecordedFrame) []byte {
+	data := []byte(frame.Data)
+	if !r.config.ShiftClientTimestamps {
+		return data
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return data
+	}
+	var clientTimestamp int64
+	if raw, ok := fields["client_timestamp"]; !ok || json.Unmarshal(raw, &clientTimestamp) != nil || clientTimestamp == 0 {
+		return data
+	}
+
+	shift := time.Now().UnixMilli() - frame.ReceivedAt/1000
+	fields["client_timestamp"], _ = json.Marshal(clientTimestamp + shift)
+	shifted, err := json.Marshal(fields)
+	if err != nil {
+		return data
+	}
+	return shifted
+}
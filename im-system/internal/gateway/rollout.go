@@ -0,0 +1,68 @@
+package gateway
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// RolloutTracker 灰度分组（由功能开关服务实现）
+type RolloutTracker interface {
+	// Cohorts 获取用户在各已开启开关中的分组（开关Key -> 分组）
+	Cohorts(ctx context.Context, userID string) map[string]string
+	// Record 记录分组指标
+	Record(cohorts map[string]string, event string, value int64)
+}
+
+// SetRolloutTracker 设置灰度分组，连接建立时确定用户分组并按分组统计指标
+func (h *WebSocketHandler) SetRolloutTracker(tracker RolloutTracker) {
+	h.rollout = tracker
+}
+
+// resolveCohorts 连接建立时确定用户分组（连接期间保持不变，调整比例后对新连接生效）
+func (h *WebSocketHandler) resolveCohorts(ctx context.Context, userID string) map[string]string {
+	if h.rollout == nil {
+		return nil
+	}
+	return h.rollout.Cohorts(ctx, userID)
+}
+
+// recordCohort 按连接所在分组记录指标
+func (h *WebSocketHandler) recordCohort(conn *Connection, event string, value int64) {
+	if h.rollout == nil || len(conn.Cohorts) == 0 {
+		return
+	}
+	h.rollout.Record(conn.Cohorts, event, value)
+}
+
+// recordCohortMessage 记录上行消息的处理结果及耗时
+func (h *WebSocketHandler) recordCohortMessage(conn *Connection, startedAt time.Time, failed bool) {
+	if h.rollout == nil || len(conn.Cohorts) == 0 {
+		return
+	}
+	h.rollout.Record(conn.Cohorts, model.CohortEventMessage, 1)
+	h.rollout.Record(conn.Cohorts, model.CohortEventHandleMillis, time.Since(startedAt).Milliseconds())
+	if failed {
+		h.rollout.Record(conn.Cohorts, model.CohortEventError, 1)
+	}
+}
+
+// treatmentFlags 用户进入实验组的开关（逗号分隔，用于握手响应头）
+func treatmentFlags(cohorts map[string]string) string {
+	flags := make([]string, 0, len(cohorts))
+	for key, cohort := range cohorts {
+		if cohort == model.CohortTreatment {
+			flags = append(flags, key)
+		}
+	}
+	sort.Strings(flags)
+	return strings.Join(flags, ",")
+}
+
+// InTreatment 连接用户是否进入指定开关的实验组
+func (c *Connection) InTreatment(flag string) bool {
+	return c.Cohorts[flag] == model.CohortTreatment
+}
@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/pkg/errcode"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// DispatchGuard 分发前检查（已回ACK之后，如内容审核、禁言、成员资格），返回错误时不再分发并通知发送者发送失败
+type DispatchGuard func(ctx context.Context, msg *model.Message) error
+
+// SendFailureRecorder 记录发送失败（由消息服务实现：标记消息文档、撤回已生成的离线消息）
+type SendFailureRecorder interface {
+	FailMessage(ctx context.Context, messageID string, code int, reason string) error
+}
+
+// SetDispatchGuard 设置分发前检查
+func (h *WebSocketHandler) SetDispatchGuard(guard DispatchGuard) {
+	h.dispatchGuard = guard
+}
+
+// SetSendFailureRecorder 设置发送失败记录
+func (h *WebSocketHandler) SetSendFailureRecorder(recorder SendFailureRecorder) {
+	h.failureRecorder = recorder
+}
+
+// checkDispatch 执行分发前检查，被拒绝时通知发送者并返回 false
+func (h *WebSocketHandler) checkDispatch(ctx context.Context, conn *Connection, msg *model.Message) bool {
+	if h.dispatchGuard == nil {
+		return true
+	}
+	err := h.dispatchGuard(ctx, msg)
+	if err == nil {
+		return true
+	}
+
+	code, reason := errcode.Internal.Code, err.Error()
+	if c, ok := errcode.Lookup(err); ok {
+		code, reason = c.Code, c.Message(conn.Locale)
+	}
+	h.rejectAfterAck(ctx, msg, code, reason)
+	return false
+}
+
+// rejectAfterAck 拒绝已回ACK的消息：记录失败状态，向发送者的所有设备下发 type 36 发送失败通知
+func (h *WebSocketHandler) rejectAfterAck(ctx context.Context, msg *model.Message, code int, reason string) {
+	sendFailedTotal.WithLabelValues(strconv.Itoa(code)).Inc()
+
+	if h.failureRecorder != nil {
+		if err := h.failureRecorder.FailMessage(ctx, msg.MessageID, code, reason); err != nil {
+			log.Printf("Record send failure of message %s error: %v", msg.MessageID, err)
+		}
+	}
+
+	notice := model.NewSendFailedMessage(msg, code, reason)
+	notice.MessageID = util.GenerateMessageID()
+	notice.QoS = model.QoSAtLeastOnce
+	if err := h.dispatcher.DispatchToUsers(ctx, []string{msg.From}, notice); err != nil {
+		log.Printf("Dispatch send failure of message %s error: %v", msg.MessageID, err)
+	}
+}
@@ -0,0 +1,252 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// 过载原因
+const (
+	overloadReasonCPU   = "cpu"
+	overloadReasonQueue = "queue"
+)
+
+// drainRetryAfterMax 排空、停机时客户端重连间隔上限，连接按随机间隔分散重连到其他节点
+const drainRetryAfterMax = 10 * time.Second
+
+// cpuMetricName 进程累计CPU时间（runtime估算，含GC和用户代码）
+const cpuMetricName = "/cpu/classes/total:cpu-seconds"
+
+// LoadShedConfig 过载保护配置
+type LoadShedConfig struct {
+	CPUThreshold   float64       // CPU使用率阈值（0-1，0表示不检查）
+	QueueThreshold float64       // 发送队列占用率阈值（0-1，0表示不检查）
+	ShedRatio      float64       // 过载时拒绝新连接的比例（0-1，0表示不启用）
+	RetryAfterMin  time.Duration // 建议重试间隔下限（轻度过载）
+	RetryAfterMax  time.Duration // 建议重试间隔上限（重度过载）
+	SampleInterval time.Duration // 负载采样间隔
+}
+
+// DefaultLoadShedConfig 默认过载保护配置
+func DefaultLoadShedConfig() *LoadShedConfig {
+	return &LoadShedConfig{
+		CPUThreshold:   0.85,
+		QueueThreshold: 0.5,
+		ShedRatio:      0.5,
+		RetryAfterMin:  2 * time.Second,
+		RetryAfterMax:  30 * time.Second,
+		SampleInterval: time.Second,
+	}
+}
+
+// LoadShedder 过载保护器
+// 定期采样CPU和发送队列占用，超过阈值时按比例拒绝新的WebSocket升级，并给出随过载程度增长、带抖动的重试建议，
+// 避免所有客户端同时重连加剧故障
+type LoadShedder struct {
+	config       *LoadShedConfig
+	queueSampler func() float64
+
+	mu       sync.RWMutex
+	cpu      float64
+	queue    float64
+	overload float64 // 过载程度（0表示未过载，1表示达到满负荷）
+	reason   string
+
+	lastCPU  float64
+	lastWall time.Time
+	rnd      *rand.Rand
+	rndMu    sync.Mutex
+}
+
+// NewLoadShedder 创建过载保护器
+// queueSampler 返回发送队列占用率（0-1），为空时不检查队列
+func NewLoadShedder(config *LoadShedConfig, queueSampler func() float64) *LoadShedder {
+	if config == nil {
+		config = DefaultLoadShedConfig()
+	}
+	if config.SampleInterval <= 0 {
+		config.SampleInterval = time.Second
+	}
+	if config.RetryAfterMax < config.RetryAfterMin {
+		config.RetryAfterMax = config.RetryAfterMin
+	}
+	return &LoadShedder{
+		config:       config,
+		queueSampler: queueSampler,
+		rnd:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Start 启动负载采样，阻塞直到 ctx 取消
+func (s *LoadShedder) Start(ctx context.Context) {
+	s.lastCPU = readCPUSeconds()
+	s.lastWall = time.Now()
+
+	ticker := time.NewTicker(s.config.SampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sample()
+		}
+	}
+}
+
+// sample 采样一次负载
+func (s *LoadShedder) sample() {
+	now := time.Now()
+	cpuSeconds := readCPUSeconds()
+	wall := now.Sub(s.lastWall).Seconds()
+
+	var cpu float64
+	if wall > 0 {
+		cpu = (cpuSeconds - s.lastCPU) / (wall * float64(runtime.GOMAXPROCS(0)))
+	}
+	s.lastCPU = cpuSeconds
+	s.lastWall = now
+
+	var queue float64
+	if s.queueSampler != nil {
+		queue = s.queueSampler()
+	}
+
+	overload, reason := 0.0, ""
+	if level := overloadLevel(cpu, s.config.CPUThreshold); level > overload {
+		overload, reason = level, overloadReasonCPU
+	}
+	if level := overloadLevel(queue, s.config.QueueThreshold); level > overload {
+		overload, reason = level, overloadReasonQueue
+	}
+
+	s.mu.Lock()
+	s.cpu, s.queue, s.overload, s.reason = cpu, queue, overload, reason
+	s.mu.Unlock()
+
+	cpuUsage.Set(cpu)
+	sendQueueUsage.Set(queue)
+	overloadLevelGauge.Set(overload)
+}
+
+// overloadLevel 计算超出阈值的程度：刚超过阈值时接近0，达到100%时为1
+func overloadLevel(value, threshold float64) float64 {
+	if threshold <= 0 || value < threshold {
+		return 0
+	}
+	if threshold >= 1 {
+		return 1
+	}
+	level := (value - threshold) / (1 - threshold)
+	if level < 0.01 {
+		level = 0.01 // 超过阈值即视为过载
+	}
+	if level > 1 {
+		level = 1
+	}
+	return level
+}
+
+// Admit 判断是否接受新连接，拒绝时返回建议的重试间隔
+func (s *LoadShedder) Admit() (bool, time.Duration) {
+	s.mu.RLock()
+	overload, reason := s.overload, s.reason
+	s.mu.RUnlock()
+
+	if overload == 0 || s.config.ShedRatio <= 0 {
+		return true, 0
+	}
+	if s.random() >= s.config.ShedRatio {
+		return true, 0
+	}
+
+	connectionsShedTotal.WithLabelValues(reason).Inc()
+	return false, s.retryAfter(overload)
+}
+
+// RetryAfter 当前负载下建议客户端等待的重连间隔（用于关闭帧）
+func (s *LoadShedder) RetryAfter() time.Duration {
+	s.mu.RLock()
+	overload := s.overload
+	s.mu.RUnlock()
+	return s.retryAfter(overload)
+}
+
+// Overload 当前过载程度（0表示未过载，1表示达到满负荷）
+func (s *LoadShedder) Overload() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.overload
+}
+
+// retryAfter 按过载程度在上下限之间插值，并加入 ±20% 抖动分散重连
+func (s *LoadShedder) retryAfter(overload float64) time.Duration {
+	span := float64(s.config.RetryAfterMax - s.config.RetryAfterMin)
+	base := float64(s.config.RetryAfterMin) + span*overload
+	jitter := 0.8 + 0.4*s.random()
+	d := time.Duration(base * jitter)
+	if d < time.Second {
+		d = time.Second
+	}
+	return d
+}
+
+// random 返回 [0,1) 随机数
+func (s *LoadShedder) random() float64 {
+	s.rndMu.Lock()
+	defer s.rndMu.Unlock()
+	return s.rnd.Float64()
+}
+
+// Stats 获取过载保护状态
+func (s *LoadShedder) Stats() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return map[string]interface{}{
+		"cpu":        s.cpu,
+		"queue":      s.queue,
+		"overload":   s.overload,
+		"reason":     s.reason,
+		"shed_ratio": s.config.ShedRatio,
+	}
+}
+
+// readCPUSeconds 读取进程累计CPU时间
+func readCPUSeconds() float64 {
+	sample := []metrics.Sample{{Name: cpuMetricName}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindFloat64 {
+		return 0
+	}
+	return sample[0].Value.Float64()
+}
+
+// spreadRetryAfter 返回 [1s, max] 内均匀分布的重连间隔
+func spreadRetryAfter(max time.Duration) time.Duration {
+	if max <= time.Second {
+		return time.Second
+	}
+	return time.Second + time.Duration(rand.Int63n(int64(max-time.Second)))
+}
+
+// RetryHint 关闭帧中的重连建议（JSON格式，客户端据此退避重连）
+type RetryHint struct {
+	Reason     string `json:"reason"`
+	RetryAfter int    `json:"retry_after"` // 秒
+}
+
+// closeReason 生成关闭帧原因（WebSocket 限制原因不超过123字节）
+func closeReason(reason string, retryAfter time.Duration) string {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	data, err := json.Marshal(&RetryHint{Reason: reason, RetryAfter: seconds})
+	if err != nil || len(data) > 123 {
+		return ""
+	}
+	return string(data)
+}
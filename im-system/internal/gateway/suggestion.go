@@ -0,0 +1,256 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// EphemeralKindReplySuggestions 回复建议临时消息的 kind
+const EphemeralKindReplySuggestions = "reply_suggestions"
+
+// SuggestionRequest 回复建议请求（发送给外部建议服务）
+type SuggestionRequest struct {
+	MessageID      string `json:"message_id"`
+	ConversationID string `json:"conversation_id"`
+	From           string `json:"from"`
+	To             string `json:"to"`
+	Text           string `json:"text"`
+}
+
+// SuggestionProvider 回复建议服务（外部智能回复的接入点）
+type SuggestionProvider interface {
+	// Suggest 为收到的消息生成简短的回复建议
+	Suggest(ctx context.Context, req *SuggestionRequest) ([]string, error)
+}
+
+// SuggestionContent 回复建议内容（EphemeralContent.Data）
+type SuggestionContent struct {
+	MessageID   string   `json:"message_id"` // 建议针对的消息
+	Suggestions []string `json:"suggestions"`
+}
+
+// SuggestionConfig 回复建议配置
+type SuggestionConfig struct {
+	Timeout        time.Duration                                 // 单次请求超时，超时的建议直接丢弃
+	MaxConcurrent  int                                           // 本节点同时进行的请求数，超出时丢弃
+	MaxSuggestions int                                           // 最多下发的建议条数
+	MaxLength      int                                           // 单条建议最大字符数，超出的建议丢弃
+	MaxInputLength int                                           // 超过该字符数的消息不请求建议
+	Enabled        func(ctx context.Context, userID string) bool // 开关（如功能开关），为空时始终开启；请求前和下发前各检查一次
+}
+
+// DefaultSuggestionConfig 默认回复建议配置
+func DefaultSuggestionConfig() *SuggestionConfig {
+	return &SuggestionConfig{
+		Timeout:        2 * time.Second,
+		MaxConcurrent:  64,
+		MaxSuggestions: 3,
+		MaxLength:      50,
+		MaxInputLength: 1000,
+	}
+}
+
+// suggester 回复建议
+type suggester struct {
+	provider SuggestionProvider
+	config   *SuggestionConfig
+	slots    chan struct{}
+}
+
+// SetSuggestionProvider 设置回复建议服务：收到单聊文本消息后异步请求建议，以临时消息下发给接收者的在线设备
+func (h *WebSocketHandler) SetSuggestionProvider(provider SuggestionProvider, config *SuggestionConfig) {
+	if provider == nil {
+		h.suggester = nil
+		return
+	}
+	if config == nil {
+		config = DefaultSuggestionConfig()
+	}
+	h.suggester = &suggester{
+		provider: provider,
+		config:   config,
+		slots:    make(chan struct{}, config.MaxConcurrent),
+	}
+}
+
+// requestSuggestions 异步请求回复建议（只处理单聊明文文本，密文和其他类型跳过）
+func (h *WebSocketHandler) requestSuggestions(msg *model.Message) {
+	s := h.suggester
+	if s == nil || msg.To == "" || msg.To == msg.From {
+		return
+	}
+	switch msg.Type {
+	case model.MsgText, model.MsgSingleChat:
+	default:
+		return
+	}
+	text := messageText(msg)
+	if text == "" || (s.config.MaxInputLength > 0 && len([]rune(text)) > s.config.MaxInputLength) {
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		suggestionRequestsTotal.WithLabelValues("dropped").Inc()
+		return
+	}
+
+	go func() {
+		defer func() { <-s.slots }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+		defer cancel()
+		result := h.suggest(ctx, s, msg, text)
+		suggestionRequestsTotal.WithLabelValues(result).Inc()
+	}()
+}
+
+// suggest 请求建议并下发，返回处理结果（用于指标）
+func (h *WebSocketHandler) suggest(ctx context.Context, s *suggester, msg *model.Message, text string) string {
+	if s.config.Enabled != nil && !s.config.Enabled(ctx, msg.To) {
+		return "disabled"
+	}
+
+	start := time.Now()
+	suggestions, err := s.provider.Suggest(ctx, &SuggestionRequest{
+		MessageID:      msg.MessageID,
+		ConversationID: msg.ConversationID,
+		From:           msg.From,
+		To:             msg.To,
+		Text:           text,
+	})
+	suggestionLatency.Observe(time.Since(start).Seconds())
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			return "timeout"
+		}
+		log.Printf("Suggest replies for message %s error: %v", msg.MessageID, err)
+		return "error"
+	}
+
+	suggestions = s.filter(suggestions)
+	if len(suggestions) == 0 {
+		return "empty"
+	}
+	// 请求期间开关被关闭时不再下发
+	if s.config.Enabled != nil && !s.config.Enabled(ctx, msg.To) {
+		return "disabled"
+	}
+
+	frame := &model.Message{
+		MessageID:      util.GenerateMessageID(),
+		Type:           model.MsgEphemeral,
+		From:           "system",
+		To:             msg.To,
+		ConversationID: msg.ConversationID,
+		Content: &model.EphemeralContent{
+			Kind: EphemeralKindReplySuggestions,
+			Data: &SuggestionContent{MessageID: msg.MessageID, Suggestions: suggestions},
+		},
+		Timestamp: time.Now().UnixMilli(),
+		QoS:       model.QoSAtMostOnce,
+	}
+	// 单聊临时消息投递给发送者以外的会话成员，即接收者的在线设备，不保存离线消息
+	if err := h.dispatcher.DispatchEphemeral(ctx, msg.ConversationID, frame, msg.From); err != nil {
+		log.Printf("Dispatch reply suggestions for message %s error: %v", msg.MessageID, err)
+		return "error"
+	}
+	return "delivered"
+}
+
+// filter 去除空白、重复和过长的建议，并限制条数
+func (s *suggester) filter(suggestions []string) []string {
+	seen := make(map[string]bool, len(suggestions))
+	result := make([]string, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		suggestion = strings.TrimSpace(suggestion)
+		if suggestion == "" || seen[suggestion] {
+			continue
+		}
+		if s.config.MaxLength > 0 && len([]rune(suggestion)) > s.config.MaxLength {
+			continue
+		}
+		seen[suggestion] = true
+		result = append(result, suggestion)
+		if s.config.MaxSuggestions > 0 && len(result) >= s.config.MaxSuggestions {
+			break
+		}
+	}
+	return result
+}
+
+// messageText 获取明文文本消息的内容，密文消息返回空
+func messageText(msg *model.Message) string {
+	switch content := msg.Content.(type) {
+	case map[string]interface{}:
+		if ciphertext, _ := content["ciphertext"].(string); ciphertext != "" {
+			return ""
+		}
+		text, _ := content["text"].(string)
+		return strings.TrimSpace(text)
+	case *model.TextContent:
+		return strings.TrimSpace(content.Text)
+	}
+	return ""
+}
+
+// HTTPSuggestionProvider 通过HTTP调用外部建议服务：POST JSON（SuggestionRequest），响应 {"suggestions": ["..."]}
+type HTTPSuggestionProvider struct {
+	Endpoint string
+	APIKey   string // 不为空时以 Bearer 方式携带
+	Client   *http.Client
+}
+
+// Suggest 调用外部建议服务
+func (p *HTTPSuggestionProvider) Suggest(ctx context.Context, req *SuggestionRequest) ([]string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if p.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("suggestion endpoint status %d: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Suggestions []string `json:"suggestions"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("decode suggestion response error: %w", err)
+	}
+	return result.Suggestions, nil
+}
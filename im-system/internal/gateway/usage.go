@@ -0,0 +1,36 @@
+package gateway
+
+import "time"
+
+// ConnectionUsage 连接流量统计（按 WebSocket 消息帧负载近似计算）
+type ConnectionUsage struct {
+	BytesIn   int64 // 收到的字节数
+	BytesOut  int64 // 发出的字节数
+	FramesIn  int64 // 收到的消息帧数
+	FramesOut int64 // 发出的消息帧数（批量帧计为一帧）
+}
+
+// SessionRecorder 连接断开后的会话记录回调（如写入计量表），在读协程退出时同步调用，实现方不应阻塞
+type SessionRecorder func(conn *Connection, usage ConnectionUsage, endedAt time.Time)
+
+// Usage 获取连接当前的流量统计
+func (c *Connection) Usage() ConnectionUsage {
+	return ConnectionUsage{
+		BytesIn:   c.bytesIn.Load(),
+		BytesOut:  c.bytesOut.Load(),
+		FramesIn:  c.framesIn.Load(),
+		FramesOut: c.framesOut.Load(),
+	}
+}
+
+// countInbound 记录一帧入站数据
+func (c *Connection) countInbound(n int) {
+	c.bytesIn.Add(int64(n))
+	c.framesIn.Add(1)
+}
+
+// countOutbound 记录一帧出站数据
+func (c *Connection) countOutbound(n int) {
+	c.bytesOut.Add(int64(n))
+	c.framesOut.Add(1)
+}
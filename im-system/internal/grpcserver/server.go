@@ -0,0 +1,312 @@
+// Package grpcserver 服务间内部 gRPC 接口：供订单、CRM 等后端服务向 IM 用户发送消息、查询在线状态
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/d60-lab/im-system/internal/gateway"
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
+	"github.com/d60-lab/im-system/pkg/errcode"
+	"github.com/d60-lab/im-system/pkg/internalapi"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// MaxStatusQueryUsers 单次查询在线状态的最大用户数
+const MaxStatusQueryUsers = 500
+
+// SendGuard 发送前检查（维护模式、内容结构、加密会话等），返回错误时拒绝发送
+type SendGuard func(ctx context.Context, msg *model.Message) error
+
+// Config gRPC 内部接口配置
+type Config struct {
+	Addr      string // 监听地址，如 :9100
+	AuthToken string // 调用方须在 metadata authorization 中携带 Bearer <token>，为空时不鉴权（仅限内网）
+}
+
+// Server 服务间内部 gRPC 服务
+type Server struct {
+	internalapi.UnimplementedInternalServiceServer
+
+	config         *Config
+	dispatcher     gateway.MessageDispatcher
+	messageService service.MessageService
+	groupService   service.GroupService
+	sendGuard      SendGuard
+
+	grpcServer *grpc.Server
+}
+
+// NewServer 创建内部 gRPC 服务，消息保存复用消息服务，投递复用网关分发器
+func NewServer(config *Config, dispatcher gateway.MessageDispatcher, messageService service.MessageService, groupService service.GroupService) *Server {
+	s := &Server{
+		config:         config,
+		dispatcher:     dispatcher,
+		messageService: messageService,
+		groupService:   groupService,
+	}
+	s.grpcServer = grpc.NewServer(grpc.UnaryInterceptor(s.authInterceptor))
+	internalapi.RegisterInternalServiceServer(s.grpcServer, s)
+	return s
+}
+
+// SetSendGuard 设置发送前检查
+func (s *Server) SetSendGuard(guard SendGuard) {
+	s.sendGuard = guard
+}
+
+// Start 监听并提供服务，直到 ctx 取消后优雅停止
+func (s *Server) Start(ctx context.Context) {
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		log.Printf("gRPC server listen on %s error: %v", s.config.Addr, err)
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		stopped := make(chan struct{})
+		go func() {
+			s.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			s.grpcServer.Stop()
+		}
+	}()
+
+	log.Printf("gRPC internal API listening on %s", s.config.Addr)
+	if err := s.grpcServer.Serve(listener); err != nil {
+		log.Printf("gRPC server error: %v", err)
+	}
+}
+
+// authInterceptor 校验调用方令牌
+func (s *Server) authInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if s.config.AuthToken != "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var token string
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = strings.TrimPrefix(values[0], "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AuthToken)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+	}
+	return handler(ctx, req)
+}
+
+// SendMessage 发送单聊消息
+func (s *Server) SendMessage(ctx context.Context, req *internalapi.SendMessageRequest) (*internalapi.SendMessageResponse, error) {
+	if req.GetFrom() == "" || req.GetTo() == "" {
+		return nil, status.Error(codes.InvalidArgument, "from and to are required")
+	}
+	msgType, err := chatType(req.GetType(), model.MsgSingleChat)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &model.Message{
+		MessageID: req.GetMessageId(),
+		Type:      msgType,
+		From:      req.GetFrom(),
+		To:        req.GetTo(),
+		Content:   req.GetContent().AsMap(),
+	}
+	msg.ConversationID = model.GetSingleChatConversationID(msg.From, msg.To)
+	if err := s.save(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	// 接收者离线时由分发器转离线消息和推送
+	if err := s.dispatcher.DispatchToUsers(ctx, []string{msg.To}, msg); err != nil {
+		log.Printf("dispatch internal message %s error: %v", msg.MessageID, err)
+	}
+	return sendResponse(msg), nil
+}
+
+// SendToGroup 发送群消息
+func (s *Server) SendToGroup(ctx context.Context, req *internalapi.SendToGroupRequest) (*internalapi.SendMessageResponse, error) {
+	if req.GetFrom() == "" || req.GetGroupId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "from and group_id are required")
+	}
+	msgType, err := chatType(req.GetType(), model.MsgGroupChat)
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.groupService.IsMember(ctx, req.GetGroupId(), req.GetFrom())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if !isMember {
+		return nil, toStatus(service.ErrNotGroupMember)
+	}
+
+	msg := &model.Message{
+		MessageID: req.GetMessageId(),
+		Type:      msgType,
+		From:      req.GetFrom(),
+		To:        req.GetGroupId(),
+		Content:   req.GetContent().AsMap(),
+	}
+	// 非文本类型的群消息按自定义消息约定携带 group_id
+	if msgType != model.MsgGroupChat {
+		msg.GroupID = req.GetGroupId()
+	}
+	msg.ConversationID = model.GetGroupChatConversationID(msg.To)
+	if err := s.save(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	if err := s.dispatcher.DispatchToConversation(ctx, msg.ConversationID, msg, msg.From); err != nil {
+		log.Printf("dispatch internal group message %s error: %v", msg.MessageID, err)
+	}
+	return sendResponse(msg), nil
+}
+
+// Broadcast 向全部在线用户发送服务器通知
+func (s *Server) Broadcast(ctx context.Context, req *internalapi.BroadcastRequest) (*internalapi.BroadcastResponse, error) {
+	if req.GetContent() == "" {
+		return nil, status.Error(codes.InvalidArgument, "content is required")
+	}
+
+	msg := &model.Message{
+		MessageID: util.GenerateMessageID(),
+		Type:      model.MsgServerNotice,
+		Content: &model.ServerNoticeContent{
+			Title:   req.GetTitle(),
+			Content: req.GetContent(),
+			Action:  req.GetAction(),
+			Data:    req.GetData(),
+		},
+		Timestamp: time.Now().UnixMilli(),
+	}
+	var err error
+	if len(req.GetPlatforms()) > 0 {
+		err = s.dispatcher.BroadcastToPlatforms(ctx, msg, req.GetPlatforms())
+	} else {
+		err = s.dispatcher.BroadcastToAllNodes(ctx, msg)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "broadcast error: %v", err)
+	}
+	return &internalapi.BroadcastResponse{MessageId: msg.MessageID}, nil
+}
+
+// QueryOnlineStatus 批量查询用户在线状态
+func (s *Server) QueryOnlineStatus(ctx context.Context, req *internalapi.QueryOnlineStatusRequest) (*internalapi.QueryOnlineStatusResponse, error) {
+	if len(req.GetUserIds()) > MaxStatusQueryUsers {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d user ids", MaxStatusQueryUsers)
+	}
+
+	resp := &internalapi.QueryOnlineStatusResponse{Statuses: make([]*internalapi.UserOnlineStatus, 0, len(req.GetUserIds()))}
+	for _, userID := range req.GetUserIds() {
+		nodeID, err := s.dispatcher.GetUserNode(ctx, userID)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "query online status error: %v", err)
+		}
+		userStatus := &internalapi.UserOnlineStatus{UserId: userID, Online: nodeID != "", NodeId: nodeID}
+		if nodeID == "" {
+			lastSeen, err := s.dispatcher.LastSeen(ctx, userID)
+			if err != nil {
+				return nil, status.Errorf(codes.Internal, "query last seen error: %v", err)
+			}
+			if !lastSeen.IsZero() {
+				userStatus.LastSeen = lastSeen.Unix()
+			}
+		}
+		resp.Statuses = append(resp.Statuses, userStatus)
+	}
+	return resp, nil
+}
+
+// save 执行发送前检查并保存消息
+func (s *Server) save(ctx context.Context, msg *model.Message) error {
+	if msg.MessageID == "" {
+		msg.MessageID = util.GenerateMessageID()
+	}
+	msg.Timestamp = time.Now().UnixMilli()
+
+	if s.sendGuard != nil {
+		if err := s.sendGuard(ctx, msg); err != nil {
+			return toStatus(err)
+		}
+	}
+	if err := s.messageService.SaveMessage(ctx, msg); err != nil {
+		return toStatus(err)
+	}
+	return nil
+}
+
+// chatType 校验消息类型，0 为该会话类别的文本消息
+func chatType(t int32, text model.MessageType) (model.MessageType, error) {
+	msgType := model.MessageType(t)
+	switch {
+	case msgType == model.MsgText:
+		return text, nil
+	case msgType == model.MsgSingleChat || msgType == model.MsgGroupChat:
+		if msgType != text {
+			return 0, status.Errorf(codes.InvalidArgument, "message type %d not allowed here", t)
+		}
+		return msgType, nil
+	case msgType.IsChat():
+		return msgType, nil
+	default:
+		return 0, status.Errorf(codes.InvalidArgument, "message type %d is not a chat message type", t)
+	}
+}
+
+// sendResponse 发送结果
+func sendResponse(msg *model.Message) *internalapi.SendMessageResponse {
+	return &internalapi.SendMessageResponse{
+		MessageId:      msg.MessageID,
+		ConversationId: msg.ConversationID,
+		Timestamp:      msg.Timestamp,
+	}
+}
+
+// toStatus 将业务错误转换为 gRPC 状态（按错误码的 HTTP 状态映射，消息中带业务错误码）
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code, ok := errcode.Lookup(err)
+	if !ok {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	grpcCode := codes.Unknown
+	switch code.HTTPStatus {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusRequestEntityTooLarge:
+		grpcCode = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		grpcCode = codes.Unauthenticated
+	case http.StatusForbidden:
+		grpcCode = codes.PermissionDenied
+	case http.StatusNotFound:
+		grpcCode = codes.NotFound
+	case http.StatusConflict:
+		grpcCode = codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		grpcCode = codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		grpcCode = codes.Unavailable
+	case http.StatusInternalServerError:
+		grpcCode = codes.Internal
+	}
+	return status.Error(grpcCode, fmt.Sprintf("%d: %v", code.Code, err))
+}
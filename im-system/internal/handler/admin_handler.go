@@ -0,0 +1,925 @@
+package handler
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
+	"github.com/d60-lab/im-system/pkg/errcode"
+)
+
+// adminUserIDs 管理员用户ID集合
+var adminUserIDs = map[string]bool{}
+
+// SetAdminUserIDs 设置管理员用户ID列表
+func SetAdminUserIDs(userIDs []string) {
+	admins := make(map[string]bool, len(userIDs))
+	for _, userID := range userIDs {
+		admins[userID] = true
+	}
+	adminUserIDs = admins
+}
+
+// IsAdmin 检查用户是否为管理员（ADMIN_USER_IDS 中的用户，拥有全部权限）
+func IsAdmin(userID string) bool {
+	return adminUserIDs[userID]
+}
+
+// NewAdminMiddleware 管理员权限中间件（需在认证中间件之后使用）
+// 按路由所需的权限校验：ADMIN_USER_IDS 中的用户直接放行，其余用户按 rbacService 分配的角色校验；
+// 修改类请求及被拒绝的请求写入审计日志
+func NewAdminMiddleware(rbacService service.RBACService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("user_id")
+		permission := routePermission(c.Request.Method, c.FullPath())
+
+		allowed := IsAdmin(userID)
+		if !allowed && rbacService != nil {
+			ok, err := rbacService.HasPermission(c.Request.Context(), userID, permission)
+			if err != nil {
+				log.Printf("Check admin permission of %s error: %v", userID, err)
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "permission service unavailable"})
+				c.Abort()
+				return
+			}
+			allowed = ok
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "admin permission required", "permission": permission})
+			c.Abort()
+			auditAdminCall(c, rbacService, userID, permission, false)
+			return
+		}
+
+		c.Next()
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			auditAdminCall(c, rbacService, userID, permission, true)
+		}
+	}
+}
+
+// MaintenanceMiddleware 维护模式中间件，用于发送类接口
+func MaintenanceMiddleware(maintenance service.MaintenanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maintenance != nil {
+			if err := maintenance.CheckSend(c.Request.Context()); err != nil {
+				respondError(c, err)
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// AdminHandler 管理接口处理器
+type AdminHandler struct {
+	maintenance service.MaintenanceService
+	nodes       service.NodeService
+	userImport  service.UserImportService
+	accounts    service.AccountService
+	succession  service.GroupSuccessionService
+	regions     service.DataRegionService
+	quarantine  service.MessageQuarantineService
+	messages    service.MessageService
+	sessions    service.SessionService
+	groupAdmin  service.GroupAdminService
+}
+
+// NewAdminHandler 创建管理接口处理器
+func NewAdminHandler(maintenance service.MaintenanceService) *AdminHandler {
+	return &AdminHandler{
+		maintenance: maintenance,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *AdminHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	// 客户端查询维护状态（用于展示维护提示）
+	r.GET("/api/maintenance", h.GetMaintenance)
+
+	admin := r.Group("/api/admin")
+	admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+	{
+		admin.GET("/maintenance", h.GetMaintenance)
+		admin.PUT("/maintenance", h.SetMaintenance)
+
+		if h.nodes != nil {
+			admin.POST("/broadcast", h.Broadcast)
+			admin.GET("/nodes", h.ListNodes)
+			admin.GET("/nodes/:node_id/connections", h.ListNodeConnections)
+			admin.POST("/nodes/:node_id/broadcast", h.BroadcastToNode)
+			admin.POST("/nodes/:node_id/drain", h.DrainNode)
+			admin.GET("/clients/stats", h.GetClientStats)
+			admin.GET("/latency", h.GetLatency)
+		}
+
+		if h.userImport != nil {
+			admin.POST("/users/import", h.ImportUsers)
+		}
+
+		if h.accounts != nil {
+			admin.GET("/users", h.ListUsers)
+			admin.PUT("/users/:user_id/status", h.SetUserStatus)
+			admin.DELETE("/users/:user_id", h.DeleteUser)
+		}
+
+		if h.sessions != nil {
+			admin.POST("/users/:user_id/logout", h.ForceLogout)
+		}
+
+		if h.groupAdmin != nil {
+			admin.GET("/groups", h.ListGroups)
+			admin.POST("/groups/:group_id/dismiss", h.DismissGroup)
+		}
+
+		if h.succession != nil {
+			admin.GET("/groups/:group_id/successions", h.ListGroupSuccessions)
+		}
+
+		if h.regions != nil {
+			admin.GET("/regions", h.GetRegions)
+			admin.PUT("/users/:user_id/region", h.SetUserRegion)
+		}
+
+		if h.quarantine != nil {
+			admin.GET("/messages/quarantine", h.ListQuarantinedMessages)
+			admin.POST("/messages/quarantine/:message_id/release", h.ReleaseQuarantinedMessage)
+			admin.DELETE("/messages/quarantine/:message_id", h.DiscardQuarantinedMessage)
+		}
+
+		if h.messages != nil {
+			admin.POST("/conversations/:conversation_id/purge", h.PurgeConversation)
+		}
+	}
+}
+
+// SetNodeService 设置节点管理服务（为空时不注册节点管理接口）
+func (h *AdminHandler) SetNodeService(nodes service.NodeService) {
+	h.nodes = nodes
+}
+
+// SetUserImportService 设置用户批量导入服务（为空时不注册导入接口）
+func (h *AdminHandler) SetUserImportService(userImport service.UserImportService) {
+	h.userImport = userImport
+}
+
+// SetAccountService 设置账号状态管理服务（为空时不注册账号管理接口）
+func (h *AdminHandler) SetAccountService(accounts service.AccountService) {
+	h.accounts = accounts
+}
+
+// SetGroupSuccessionService 设置群主继任服务（为空时不注册继任记录接口）
+func (h *AdminHandler) SetGroupSuccessionService(succession service.GroupSuccessionService) {
+	h.succession = succession
+}
+
+// SetDataRegionService 设置数据驻留服务（未启用数据驻留时不注册区域接口）
+func (h *AdminHandler) SetDataRegionService(regions service.DataRegionService) {
+	h.regions = regions
+}
+
+// SetMessageQuarantineService 设置隔离消息审核服务（为空时不注册隔离消息接口）
+func (h *AdminHandler) SetMessageQuarantineService(quarantine service.MessageQuarantineService) {
+	h.quarantine = quarantine
+}
+
+// SetMessageService 设置消息服务（为空时不注册会话消息清除接口）
+func (h *AdminHandler) SetMessageService(messages service.MessageService) {
+	h.messages = messages
+}
+
+// SetSessionService 设置登录会话服务（为空时不注册强制下线接口）
+func (h *AdminHandler) SetSessionService(sessions service.SessionService) {
+	h.sessions = sessions
+}
+
+// SetGroupAdminService 设置平台群组管理服务（为空时不注册群组列表、解散接口）
+func (h *AdminHandler) SetGroupAdminService(groupAdmin service.GroupAdminService) {
+	h.groupAdmin = groupAdmin
+}
+
+// SetMaintenanceRequest 设置维护模式请求
+type SetMaintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason" binding:"max=256"`
+}
+
+// GetMaintenance 获取维护模式状态
+// @Summary		获取维护模式状态
+// @Description	获取集群维护（只读）模式状态
+// @Tags			管理
+// @Produce		json
+// @Success		200	{object}	map[string]interface{}	"维护状态"
+// @Router			/maintenance [get]
+func (h *AdminHandler) GetMaintenance(c *gin.Context) {
+	status, err := h.maintenance.Status(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    status,
+	})
+}
+
+// SetMaintenance 开启或关闭维护模式
+// @Summary		设置维护模式
+// @Description	开启后拒绝新消息发送，历史消息读取和文件下载不受影响
+// @Tags			管理
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		SetMaintenanceRequest	true	"维护模式设置"
+// @Success		200		{object}	map[string]interface{}	"设置成功"
+// @Failure		400		{object}	map[string]interface{}	"参数错误"
+// @Failure		403		{object}	map[string]interface{}	"需要管理员权限"
+// @Router			/admin/maintenance [put]
+func (h *AdminHandler) SetMaintenance(c *gin.Context) {
+	var req SetMaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var err error
+	if req.Enabled {
+		err = h.maintenance.Enable(ctx, req.Reason, c.GetString("user_id"))
+	} else {
+		err = h.maintenance.Disable(ctx)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.GetMaintenance(c)
+}
+
+// NodeBroadcastRequest 服务器通知广播请求（全局或节点）
+type NodeBroadcastRequest struct {
+	Title     string   `json:"title" binding:"max=128"`
+	Content   string   `json:"content" binding:"required,max=1024"`
+	Platforms []string `json:"platforms"`
+}
+
+// Broadcast 向集群全部在线用户发送服务器通知
+// @Summary		全局广播
+// @Description	向集群所有节点上的在线用户发送服务器通知（type 101，不保存历史、不转离线），可按平台过滤
+// @Tags			管理
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		NodeBroadcastRequest	true	"通知内容"
+// @Success		200		{object}	map[string]interface{}	"发送成功，返回通知的消息ID"
+// @Failure		400		{object}	map[string]interface{}	"参数错误"
+// @Router			/admin/broadcast [post]
+func (h *AdminHandler) Broadcast(c *gin.Context) {
+	var req NodeBroadcastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	notice := &model.ServerNoticeContent{Title: req.Title, Content: req.Content}
+	messageID, err := h.nodes.BroadcastAll(c.Request.Context(), notice, req.Platforms)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setAuditDetail(c, gin.H{"message_id": messageID, "title": req.Title, "platforms": req.Platforms})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    gin.H{"message_id": messageID},
+	})
+}
+
+// ListNodes 获取集群各节点连接数
+// @Summary		获取节点连接统计
+// @Description	获取集群各节点的连接数及总连接数
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"节点连接统计"
+// @Failure		403	{object}	map[string]interface{}	"需要管理员权限"
+// @Router			/admin/nodes [get]
+func (h *AdminHandler) ListNodes(c *gin.Context) {
+	result, err := h.nodes.ClusterConnections(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// ListNodeConnections 分页列出节点上的连接
+// @Summary		获取节点连接列表
+// @Description	按游标分页列出指定节点上已连接的用户和设备，next_cursor 为0表示结束
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			node_id	path		string					true	"节点ID"
+// @Param			cursor	query		int						false	"游标"
+// @Param			limit	query		int						false	"每页数量（默认100，最大500）"
+// @Success		200		{object}	map[string]interface{}	"连接列表"
+// @Failure		404		{object}	map[string]interface{}	"节点不存在"
+// @Router			/admin/nodes/{node_id}/connections [get]
+func (h *AdminHandler) ListNodeConnections(c *gin.Context) {
+	cursor, _ := strconv.ParseUint(c.Query("cursor"), 10, 64)
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "100"), 10, 64)
+
+	conns, next, err := h.nodes.ListConnections(c.Request.Context(), c.Param("node_id"), cursor, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"connections": conns,
+			"next_cursor": next,
+		},
+	})
+}
+
+// BroadcastToNode 向节点上的用户发送服务器通知
+// @Summary		节点定向广播
+// @Description	向指定节点上的在线用户发送服务器通知，可按平台过滤
+// @Tags			管理
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			node_id	path		string					true	"节点ID"
+// @Param			request	body		NodeBroadcastRequest	true	"通知内容"
+// @Success		200		{object}	map[string]interface{}	"发送成功"
+// @Failure		404		{object}	map[string]interface{}	"节点不存在"
+// @Router			/admin/nodes/{node_id}/broadcast [post]
+func (h *AdminHandler) BroadcastToNode(c *gin.Context) {
+	var req NodeBroadcastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	notice := &model.ServerNoticeContent{Title: req.Title, Content: req.Content}
+	if err := h.nodes.Broadcast(c.Request.Context(), c.Param("node_id"), notice, req.Platforms); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
+
+// GetClientStats 获取客户端分布
+// @Summary		获取客户端分布
+// @Description	统计集群在线连接的客户端版本（按平台）、操作系统及网络类型分布，未上报的归为 unknown
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"客户端分布"
+// @Router			/admin/clients/stats [get]
+func (h *AdminHandler) GetClientStats(c *gin.Context) {
+	stats, err := h.nodes.ClientStats(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    stats,
+	})
+}
+
+// GetLatency 获取消息处理耗时
+// @Summary		获取消息处理耗时
+// @Description	按节点列出消息各处理阶段（received/validated/persisted/dispatched/delivered）与上一阶段间隔的 p50/p95/p99（毫秒），基于各节点最近的样本
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"各节点阶段耗时"
+// @Router			/admin/latency [get]
+func (h *AdminHandler) GetLatency(c *gin.Context) {
+	nodes, err := h.nodes.Latency(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    nodes,
+	})
+}
+
+// DrainNode 排空节点
+// @Summary		排空节点
+// @Description	断开节点上的全部连接并拒绝新连接（健康检查返回503），客户端重连到其他节点，重启节点后恢复
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			node_id	path		string					true	"节点ID"
+// @Success		200		{object}	map[string]interface{}	"已发送排空指令"
+// @Failure		404		{object}	map[string]interface{}	"节点不存在"
+// @Router			/admin/nodes/{node_id}/drain [post]
+func (h *AdminHandler) DrainNode(c *gin.Context) {
+	if err := h.nodes.Drain(c.Request.Context(), c.Param("node_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
+
+// ImportUsers 批量导入用户
+// @Summary		批量导入用户
+// @Description	支持JSON请求体、text/csv请求体或multipart文件（字段file）。CSV表头：username,nickname,password,groups（多个群ID以;分隔），
+// @Description	CSV方式的导入选项通过查询参数传递。逐行校验，失败行在errors中返回且不影响其他行；random策略生成的初始密码仅在本次响应中返回
+// @Tags			管理
+// @Accept			json,text/csv,multipart/form-data
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request			body		service.UserImportRequest	false	"导入数据（JSON方式）"
+// @Param			file			formData	file						false	"CSV文件（multipart方式）"
+// @Param			password_policy	query		string						false	"初始密码策略：random（默认，首次登录须修改）/ provided"
+// @Param			force_reset		query		bool						false	"provided策略下是否要求首次登录修改密码"
+// @Param			group_ids		query		string						false	"全部用户自动加入的群组ID，逗号分隔"
+// @Param			tenant_id		query		string						false	"租户ID"
+// @Param			dry_run			query		bool						false	"仅校验不创建"
+// @Success		200				{object}	map[string]interface{}		"导入结果"
+// @Failure		400				{object}	map[string]interface{}		"参数错误或超过单次导入上限"
+// @Router			/admin/users/import [post]
+func (h *AdminHandler) ImportUsers(c *gin.Context) {
+	req, err := bindUserImportRequest(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	req.OperatorID = c.GetString("user_id")
+
+	result, err := h.userImport.Import(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 行错误按请求语言本地化
+	locale := requestLocale(c)
+	for _, rowErr := range result.Errors {
+		if code, ok := errcode.Lookup(rowErr.Err); ok {
+			rowErr.Code = code.Code
+			rowErr.Message = code.Message(locale)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// ListUsers 分页查询用户
+// @Summary		查询用户列表
+// @Description	按用户ID（精确）、用户名或昵称（前缀）、账号状态、租户、是否访客筛选，按注册时间倒序
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			keyword		query		string					false	"关键字"
+// @Param			status		query		int						false	"账号状态：1-正常 0-禁用 2-已注销"
+// @Param			tenant_id	query		string					false	"租户ID"
+// @Param			guest		query		bool					false	"是否访客"
+// @Param			page		query		int						false	"页码"
+// @Param			page_size	query		int						false	"每页数量（默认20，最大100）"
+// @Success		200			{object}	map[string]interface{}	"用户列表"
+// @Failure		400			{object}	map[string]interface{}	"参数错误"
+// @Router			/admin/users [get]
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	var query service.UserListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, total, err := h.accounts.ListUsers(c.Request.Context(), &query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total": total,
+			"users": users,
+		},
+	})
+}
+
+// ForceLogout 强制用户下线
+// @Summary		强制下线
+// @Description	吊销用户此前签发的全部 Token（含 Refresh Token）并断开其在各节点上的连接（客户端收到 type 100 踢下线通知，reason_code 为 kickout.force_logout），用户需重新登录
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string					true	"用户ID"
+// @Success		200		{object}	map[string]interface{}	"已强制下线"
+// @Failure		404		{object}	map[string]interface{}	"用户不存在"
+// @Router			/admin/users/{user_id}/logout [post]
+func (h *AdminHandler) ForceLogout(c *gin.Context) {
+	if err := h.sessions.ForceLogout(c.Request.Context(), c.Param("user_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
+
+// SetUserStatusRequest 设置账号状态请求
+type SetUserStatusRequest struct {
+	Status *model.UserStatus `json:"status" binding:"required"` // 1-正常 0-禁用
+}
+
+// SetUserStatus 禁用/恢复账号
+// @Summary		禁用/恢复账号
+// @Description	禁用账号时，其名下的群自动移交给最早加入的管理员或成员；无可继任成员的群在宽限期后解散，宽限期内恢复账号可取消解散
+// @Tags			管理
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string					true	"用户ID"
+// @Param			request	body		SetUserStatusRequest	true	"账号状态"
+// @Success		200		{object}	map[string]interface{}	"设置成功"
+// @Failure		400		{object}	map[string]interface{}	"参数错误"
+// @Failure		404		{object}	map[string]interface{}	"用户不存在"
+// @Failure		409		{object}	map[string]interface{}	"账号已注销"
+// @Router			/admin/users/{user_id}/status [put]
+func (h *AdminHandler) SetUserStatus(c *gin.Context) {
+	var req SetUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if *req.Status == model.UserStatusDeleted {
+		respondError(c, service.ErrInvalidUserStatus)
+		return
+	}
+
+	if err := h.accounts.SetStatus(c.Request.Context(), c.Param("user_id"), *req.Status); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// DeleteUser 注销账号
+// @Summary		注销账号
+// @Description	注销后不可恢复，其名下的群自动移交并将其移出，无可继任成员的群在宽限期后解散
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string					true	"用户ID"
+// @Success		200		{object}	map[string]interface{}	"注销成功"
+// @Failure		404		{object}	map[string]interface{}	"用户不存在"
+// @Failure		409		{object}	map[string]interface{}	"账号已注销"
+// @Router			/admin/users/{user_id} [delete]
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	if err := h.accounts.SetStatus(c.Request.Context(), c.Param("user_id"), model.UserStatusDeleted); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// ListGroups 分页查询群组
+// @Summary		查询群组列表
+// @Description	按群组ID（精确）或群名称（包含）、群主、群状态筛选，按创建时间倒序
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			keyword		query		string					false	"关键字"
+// @Param			owner_id	query		string					false	"群主用户ID"
+// @Param			status		query		int						false	"群状态：1-正常 0-已解散"
+// @Param			page		query		int						false	"页码"
+// @Param			page_size	query		int						false	"每页数量（默认20，最大100）"
+// @Success		200			{object}	map[string]interface{}	"群组列表"
+// @Failure		400			{object}	map[string]interface{}	"参数错误"
+// @Router			/admin/groups [get]
+func (h *AdminHandler) ListGroups(c *gin.Context) {
+	var query service.GroupListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groups, total, err := h.groupAdmin.ListGroups(c.Request.Context(), &query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":  total,
+			"groups": groups,
+		},
+	})
+}
+
+// DismissGroupRequest 强制解散群组请求
+type DismissGroupRequest struct {
+	Reason string `json:"reason" binding:"max=256"` // 解散原因，随解散通知发给群成员
+}
+
+// DismissGroup 强制解散群组
+// @Summary		强制解散群组
+// @Description	解散违规群组（不要求群主身份），群成员收到 payload_type 为 admin_action 的解散通知（type 24，携带原因）
+// @Tags			管理
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string					true	"群组ID"
+// @Param			request		body		DismissGroupRequest		false	"解散原因"
+// @Success		200			{object}	map[string]interface{}	"解散成功"
+// @Failure		400			{object}	map[string]interface{}	"群组已解散"
+// @Failure		404			{object}	map[string]interface{}	"群组不存在"
+// @Router			/admin/groups/{group_id}/dismiss [post]
+func (h *AdminHandler) DismissGroup(c *gin.Context) {
+	var req DismissGroupRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.groupAdmin.DismissGroup(c.Request.Context(), c.Param("group_id"), c.GetString("user_id"), req.Reason); err != nil {
+		respondError(c, err)
+		return
+	}
+	setAuditDetail(c, gin.H{"reason": req.Reason})
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
+
+// ListGroupSuccessions 查询群主继任记录
+// @Summary		查询群主继任记录
+// @Description	查询群主账号禁用/注销后的自动继任、解散审计记录（按时间倒序）
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string					true	"群组ID"
+// @Param			limit		query		int						false	"数量，默认100"
+// @Success		200			{object}	map[string]interface{}	"继任记录"
+// @Router			/admin/groups/{group_id}/successions [get]
+func (h *AdminHandler) ListGroupSuccessions(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	records, err := h.succession.GetSuccessions(c.Request.Context(), c.Param("group_id"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    records,
+	})
+}
+
+// GetRegions 获取数据驻留配置
+// @Summary		获取数据驻留配置
+// @Description	获取已部署存储的区域、默认区域、租户区域及跨区域会话规则
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"数据驻留配置"
+// @Router			/admin/regions [get]
+func (h *AdminHandler) GetRegions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.regions.Info(),
+	})
+}
+
+// SetUserRegion 设置用户所属区域
+// @Summary		设置用户所属区域
+// @Description	只影响之后新建的会话和上传的文件，已有会话消息和文件不迁移；区域为空时恢复为租户区域或默认区域
+// @Tags			管理
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string							true	"用户ID"
+// @Param			request	body		service.SetUserRegionRequest	true	"区域"
+// @Success		200		{object}	map[string]interface{}			"设置成功"
+// @Failure		400		{object}	map[string]interface{}			"未部署存储的区域"
+// @Failure		404		{object}	map[string]interface{}			"用户不存在"
+// @Router			/admin/users/{user_id}/region [put]
+func (h *AdminHandler) SetUserRegion(c *gin.Context) {
+	var req service.SetUserRegionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.regions.SetUserRegion(c.Request.Context(), c.Param("user_id"), req.Region); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// ListQuarantinedMessages 获取隔离消息
+// @Summary		获取隔离消息
+// @Description	分页获取因时间超前或落后服务器时间超出范围而被隔离的消息（按隔离时间倒序）
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			page		query		int						false	"页码"		default(1)
+// @Param			page_size	query		int						false	"每页数量"	default(20)
+// @Success		200			{object}	map[string]interface{}	"隔离消息列表"
+// @Router			/admin/messages/quarantine [get]
+func (h *AdminHandler) ListQuarantinedMessages(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	messages, total, err := h.quarantine.List(c.Request.Context(), page, pageSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":    total,
+			"messages": messages,
+		},
+	})
+}
+
+// ReleaseQuarantinedMessage 放行隔离消息
+// @Summary		放行隔离消息
+// @Description	消息时间改为隔离时间后写入消息历史，不重新投递
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			message_id	path		string					true	"消息ID"
+// @Success		200			{object}	map[string]interface{}	"已放行"
+// @Failure		404			{object}	map[string]interface{}	"隔离消息不存在"
+// @Router			/admin/messages/quarantine/{message_id}/release [post]
+func (h *AdminHandler) ReleaseQuarantinedMessage(c *gin.Context) {
+	msg, err := h.quarantine.Release(c.Request.Context(), c.Param("message_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    msg.ToMessage(),
+	})
+}
+
+// DiscardQuarantinedMessage 丢弃隔离消息
+// @Summary		丢弃隔离消息
+// @Description	从隔离集合删除消息，不写入消息历史
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			message_id	path		string					true	"消息ID"
+// @Success		200			{object}	map[string]interface{}	"已丢弃"
+// @Failure		404			{object}	map[string]interface{}	"隔离消息不存在"
+// @Router			/admin/messages/quarantine/{message_id} [delete]
+func (h *AdminHandler) DiscardQuarantinedMessage(c *gin.Context) {
+	if err := h.quarantine.Discard(c.Request.Context(), c.Param("message_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// PurgeConversation 批量清除会话消息
+// @Summary		批量清除会话消息
+// @Description	按发送者、时间范围、关键字或消息ID（条件同时满足）清除会话内的消息：清空内容并标记为已清除，移除尚未拉取的离线副本和推送，向在线成员推送 patch 帧；单次最多清除1000条，has_more 为 true 时可重复调用；dry_run 只返回匹配的消息；操作条件和数量记入审计日志
+// @Tags			管理
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string							true	"会话ID（single:a:b 或 group:群组ID）"
+// @Param			request			body		service.PurgeMessagesRequest	true	"清除条件"
+// @Success		200				{object}	map[string]interface{}			"清除结果"
+// @Failure		400				{object}	map[string]interface{}			"清除条件无效"
+// @Router			/admin/conversations/{conversation_id}/purge [post]
+func (h *AdminHandler) PurgeConversation(c *gin.Context) {
+	var req service.PurgeMessagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.messages.PurgeConversation(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	setAuditDetail(c, gin.H{
+		"filter":          req,
+		"purged":          result.Purged,
+		"offline_removed": result.OfflineRemoved,
+		"has_more":        result.HasMore,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// bindUserImportRequest 解析导入请求：JSON请求体，或CSV（请求体/multipart文件）加查询参数
+func bindUserImportRequest(c *gin.Context) (*service.UserImportRequest, error) {
+	contentType := c.ContentType()
+	if contentType == "application/json" {
+		var req service.UserImportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return nil, service.ErrInvalidRequest
+		}
+		return &req, nil
+	}
+
+	var body io.Reader = c.Request.Body
+	if contentType == "multipart/form-data" {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, service.ErrInvalidRequest
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		body = file
+	}
+
+	rows, err := service.ParseUserImportCSV(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &service.UserImportRequest{
+		Users:          rows,
+		TenantID:       c.Query("tenant_id"),
+		PasswordPolicy: c.Query("password_policy"),
+		ForceReset:     c.Query("force_reset") == "true",
+		DryRun:         c.Query("dry_run") == "true",
+	}
+	if groupIDs := c.Query("group_ids"); groupIDs != "" {
+		req.GroupIDs = strings.Split(groupIDs, ",")
+	}
+	return req, nil
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// AnalyticsHandler 管理后台分析处理器（只读取会话汇总表，不查询线上消息与会话表）
+type AnalyticsHandler struct {
+	analytics service.ConversationAnalyticsService
+}
+
+// NewAnalyticsHandler 创建分析处理器
+func NewAnalyticsHandler(analytics service.ConversationAnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{analytics: analytics}
+}
+
+// RegisterRoutes 注册路由
+func (h *AnalyticsHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	admin := r.Group("/api/admin/analytics")
+	admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+	{
+		admin.GET("/overview", h.GetOverview)
+		admin.GET("/conversations", h.ListConversations)
+		admin.GET("/conversations/:conversation_id", h.GetConversation)
+	}
+}
+
+// GetOverview 获取会话分析概览
+// @Summary		获取会话分析概览
+// @Description	会话总数、消息总数及最近24小时/7天活跃会话数（数据来自会话汇总表，有数秒延迟）
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"会话分析概览"
+// @Router			/admin/analytics/overview [get]
+func (h *AnalyticsHandler) GetOverview(c *gin.Context) {
+	overview, err := h.analytics.Overview(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    overview,
+	})
+}
+
+// ListConversations 分页查询会话统计
+// @Summary		分页查询会话统计
+// @Description	按消息数、参与人数或最后活跃时间排序查询会话统计
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			type			query		string					false	"会话类型（single/group）"
+// @Param			sort			query		string					false	"排序（messages/participants/last_activity，默认last_activity）"
+// @Param			active_hours	query		int						false	"只查询最近N小时内活跃的会话"
+// @Param			page			query		int						false	"页码"
+// @Param			page_size		query		int						false	"每页数量"
+// @Success		200				{object}	map[string]interface{}	"会话统计列表"
+// @Failure		400				{object}	map[string]interface{}	"参数错误"
+// @Router			/admin/analytics/conversations [get]
+func (h *AnalyticsHandler) ListConversations(c *gin.Context) {
+	var query service.ConversationStatsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, total, err := h.analytics.ListConversations(c.Request.Context(), &query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":         total,
+			"conversations": stats,
+		},
+	})
+}
+
+// GetConversation 查询会话统计
+// @Summary		查询会话统计
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Success		200				{object}	map[string]interface{}	"会话统计"
+// @Failure		404				{object}	map[string]interface{}	"会话不存在"
+// @Router			/admin/analytics/conversations/{conversation_id} [get]
+func (h *AnalyticsHandler) GetConversation(c *gin.Context) {
+	stats, err := h.analytics.GetConversation(c.Request.Context(), c.Param("conversation_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    stats,
+	})
+}
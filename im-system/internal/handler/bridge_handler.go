@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
+	"github.com/d60-lab/im-system/pkg/bridge"
+)
+
+// bridgeMaxBody 外部平台推送请求体大小上限
+const bridgeMaxBody = 1 << 20
+
+// BridgeHandler 外部平台桥接处理器：Slack/Matrix 入站推送及映射管理
+type BridgeHandler struct {
+	bridgeService service.BridgeService
+	slack         *bridge.SlackConnector
+	matrix        *bridge.MatrixConnector
+}
+
+// NewBridgeHandler 创建桥接处理器
+func NewBridgeHandler(bridgeService service.BridgeService) *BridgeHandler {
+	return &BridgeHandler{bridgeService: bridgeService}
+}
+
+// SetSlack 设置 Slack 连接器，设置后注册 Events API 接收地址
+func (h *BridgeHandler) SetSlack(slack *bridge.SlackConnector) {
+	h.slack = slack
+}
+
+// SetMatrix 设置 Matrix 连接器，设置后注册应用服务事务接收地址
+func (h *BridgeHandler) SetMatrix(matrix *bridge.MatrixConnector) {
+	h.matrix = matrix
+}
+
+// RegisterRoutes 注册路由
+func (h *BridgeHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	if h.slack != nil {
+		r.POST("/api/bridge/slack/events", h.SlackEvents)
+	}
+	if h.matrix != nil {
+		// 应用服务注册文件中的 url 配置为 {host}/api/bridge/matrix
+		r.PUT("/api/bridge/matrix/_matrix/app/v1/transactions/:txn_id", h.MatrixTransaction)
+	}
+
+	admin := r.Group("/api/admin/bridges")
+	admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+	{
+		admin.GET("/rooms", h.ListRooms)
+		admin.POST("/rooms", h.BindRoom)
+		admin.DELETE("/rooms/:id", h.UnbindRoom)
+		admin.GET("/users", h.ListUsers)
+		admin.POST("/users", h.MapUser)
+		admin.DELETE("/users/:platform/:remote_user_id", h.UnmapUser)
+	}
+}
+
+// SlackEvents 接收 Slack Events API 推送
+// @Summary		接收Slack事件
+// @Description	Slack Events API 请求地址：校验签名后将绑定频道的用户消息同步到群组，机器人消息不处理以防回环
+// @Tags			集成应用
+// @Accept			json
+// @Produce		json
+// @Success		200	{object}	map[string]interface{}	"处理成功（URL校验时返回challenge）"
+// @Failure		401	{object}	map[string]interface{}	"签名错误或已过期"
+// @Router			/bridge/slack/events [post]
+func (h *BridgeHandler) SlackEvents(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, bridgeMaxBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.slack.VerifyRequest(c.Request.Header, body); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	challenge, event, err := h.slack.ParseEvent(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if challenge != "" {
+		c.JSON(http.StatusOK, gin.H{"challenge": challenge})
+		return
+	}
+	if event != nil {
+		if err := h.bridgeService.HandleInbound(c.Request.Context(), event); err != nil {
+			log.Printf("handle slack event %s error: %v", event.EventID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
+
+// MatrixTransaction 接收 Matrix homeserver 推送的应用服务事务
+// @Summary		接收Matrix事务
+// @Description	Matrix 应用服务事务接口：校验 hs_token 后将绑定房间的文本消息同步到群组，桥接机器人自己的消息不处理以防回环
+// @Tags			集成应用
+// @Accept			json
+// @Produce		json
+// @Param			txn_id	path		string					true	"事务ID"
+// @Success		200		{object}	map[string]interface{}	"处理成功"
+// @Failure		401		{object}	map[string]interface{}	"hs_token 错误"
+// @Router			/bridge/matrix/_matrix/app/v1/transactions/{txn_id} [put]
+func (h *BridgeHandler) MatrixTransaction(c *gin.Context) {
+	if err := h.matrix.VerifyRequest(c.Request); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"errcode": "M_FORBIDDEN", "error": err.Error()})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, bridgeMaxBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := h.matrix.ParseTransaction(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errcode": "M_BAD_JSON", "error": err.Error()})
+		return
+	}
+	// 处理失败时返回错误让 homeserver 重试整个事务，已处理的事件按事件ID去重
+	for _, event := range events {
+		if err := h.bridgeService.HandleInbound(c.Request.Context(), event); err != nil {
+			log.Printf("handle matrix event %s error: %v", event.EventID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"errcode": "M_UNKNOWN", "error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// ListRooms 获取频道映射列表（管理员）
+// @Summary		获取桥接频道映射列表
+// @Tags			集成应用
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"频道映射列表"
+// @Router			/admin/bridges/rooms [get]
+func (h *BridgeHandler) ListRooms(c *gin.Context) {
+	rooms, err := h.bridgeService.ListRooms(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    rooms,
+	})
+}
+
+// BindRoom 绑定群组与外部频道（管理员）
+// @Summary		绑定群组与外部频道
+// @Description	将群组与 Slack 频道或 Matrix 房间绑定，双向同步文本消息；同一群组在同一平台重复绑定时覆盖
+// @Tags			集成应用
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		model.BindBridgeRoomRequest	true	"频道映射"
+// @Success		200		{object}	map[string]interface{}		"频道映射"
+// @Failure		400		{object}	map[string]interface{}		"平台未启用"
+// @Failure		404		{object}	map[string]interface{}		"群组不存在"
+// @Router			/admin/bridges/rooms [post]
+func (h *BridgeHandler) BindRoom(c *gin.Context) {
+	var req model.BindBridgeRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	room, err := h.bridgeService.BindRoom(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    room,
+	})
+}
+
+// UnbindRoom 解除频道映射（管理员）
+// @Summary		解除频道映射
+// @Tags			集成应用
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int						true	"映射ID"
+// @Success		200	{object}	map[string]interface{}	"解除成功"
+// @Failure		404	{object}	map[string]interface{}	"映射不存在"
+// @Router			/admin/bridges/rooms/{id} [delete]
+func (h *BridgeHandler) UnbindRoom(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, service.ErrInvalidRequest)
+		return
+	}
+
+	if err := h.bridgeService.UnbindRoom(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// ListUsers 获取外部用户映射列表（管理员）
+// @Summary		获取外部用户映射列表
+// @Tags			集成应用
+// @Produce		json
+// @Security		BearerAuth
+// @Param			platform	query		string					false	"平台：slack / matrix"
+// @Success		200			{object}	map[string]interface{}	"用户映射列表"
+// @Router			/admin/bridges/users [get]
+func (h *BridgeHandler) ListUsers(c *gin.Context) {
+	users, err := h.bridgeService.ListUsers(c.Request.Context(), c.Query("platform"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    users,
+	})
+}
+
+// MapUser 映射外部用户（管理员）
+// @Summary		映射外部用户
+// @Description	映射后该外部用户在绑定频道的发言以IM用户本人身份同步到群组（须为群成员），未映射的用户由桥接机器人代发
+// @Tags			集成应用
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		model.MapBridgeUserRequest	true	"用户映射"
+// @Success		200		{object}	map[string]interface{}		"用户映射"
+// @Failure		404		{object}	map[string]interface{}		"用户不存在"
+// @Router			/admin/bridges/users [post]
+func (h *BridgeHandler) MapUser(c *gin.Context) {
+	var req model.MapBridgeUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.bridgeService.MapUser(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    user,
+	})
+}
+
+// UnmapUser 删除外部用户映射（管理员）
+// @Summary		删除外部用户映射
+// @Tags			集成应用
+// @Produce		json
+// @Security		BearerAuth
+// @Param			platform		path		string					true	"平台：slack / matrix"
+// @Param			remote_user_id	path		string					true	"外部用户ID"
+// @Success		200				{object}	map[string]interface{}	"删除成功"
+// @Failure		404				{object}	map[string]interface{}	"映射不存在"
+// @Router			/admin/bridges/users/{platform}/{remote_user_id} [delete]
+func (h *BridgeHandler) UnmapUser(c *gin.Context) {
+	if err := h.bridgeService.UnmapUser(c.Request.Context(), c.Param("platform"), c.Param("remote_user_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
@@ -0,0 +1,632 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// ConversationHandler 会话处理器
+type ConversationHandler struct {
+	conversationService service.ConversationService
+	exportService       service.ConversationExportService
+	encryptionService   service.ConversationEncryptionService
+	summaryService      service.ConversationSummaryService
+	pinService          service.MessagePinService
+}
+
+// NewConversationHandler 创建会话处理器
+func NewConversationHandler(conversationService service.ConversationService) *ConversationHandler {
+	return &ConversationHandler{
+		conversationService: conversationService,
+	}
+}
+
+// SetExportService 设置会话导出服务，为空时不注册导出接口
+func (h *ConversationHandler) SetExportService(exportService service.ConversationExportService) {
+	h.exportService = exportService
+}
+
+// SetEncryptionService 设置会话加密服务，为空时不注册加密接口
+func (h *ConversationHandler) SetEncryptionService(encryptionService service.ConversationEncryptionService) {
+	h.encryptionService = encryptionService
+}
+
+// SetSummaryService 设置会话摘要服务，为空时不注册摘要接口
+func (h *ConversationHandler) SetSummaryService(summaryService service.ConversationSummaryService) {
+	h.summaryService = summaryService
+}
+
+// SetPinService 设置置顶消息服务，为空时不注册置顶接口
+func (h *ConversationHandler) SetPinService(pinService service.MessagePinService) {
+	h.pinService = pinService
+}
+
+// RegisterRoutes 注册路由
+func (h *ConversationHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	conv := r.Group("/api/conversations")
+	conv.Use(NewAuthMiddleware(ra.Authenticator))
+	{
+		conv.GET("", h.ListConversations)
+		conv.GET("/:conversation_id", h.GetConversation)
+		conv.DELETE("/:conversation_id", h.DeleteConversation)
+		conv.PUT("/:conversation_id/pin", h.PinConversation)
+		conv.DELETE("/:conversation_id/pin", h.UnpinConversation)
+		conv.PUT("/:conversation_id/mute", h.MuteConversation)
+		conv.DELETE("/:conversation_id/mute", h.UnmuteConversation)
+		conv.GET("/:conversation_id/search", h.SearchMessages)
+		conv.GET("/:conversation_id/messages/:message_id/context", h.GetMessageContext)
+		if h.exportService != nil {
+			conv.POST("/:conversation_id/export", h.CreateExport)
+			conv.GET("/:conversation_id/export/:job_id", h.GetExport)
+		}
+		if h.encryptionService != nil {
+			conv.GET("/:conversation_id/encryption", h.GetEncryption)
+			conv.PUT("/:conversation_id/encryption", h.SetEncryption)
+			conv.POST("/:conversation_id/encryption/rotate", h.RotateKey)
+		}
+		if h.pinService != nil {
+			conv.GET("/:conversation_id/pins", h.ListPins)
+			conv.PUT("/:conversation_id/pins/:message_id", h.PinMessage)
+			conv.DELETE("/:conversation_id/pins/:message_id", h.UnpinMessage)
+		}
+		if h.summaryService != nil {
+			conv.POST("/:conversation_id/summarize", h.Summarize)
+			conv.GET("/:conversation_id/summaries", h.ListSummaries)
+		}
+	}
+
+	if h.encryptionService != nil {
+		admin := r.Group("/api/admin/conversations")
+		admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+		admin.GET("/encrypted", h.ListEncrypted)
+	}
+}
+
+// ListConversations 获取会话列表
+// @Summary		获取会话列表
+// @Description	分页获取当前用户的会话列表，置顶会话在前，其余按最后一条消息时间倒序，不含已删除的会话
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			page		query		int						false	"页码"
+// @Param			page_size	query		int						false	"每页数量（最大100）"	default(20)
+// @Success		200			{object}	map[string]interface{}	"会话列表"
+// @Router			/conversations [get]
+func (h *ConversationHandler) ListConversations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	conversations, total, err := h.conversationService.ListConversations(c.Request.Context(), c.GetString("user_id"), page, pageSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":         total,
+			"conversations": conversations,
+		},
+	})
+}
+
+// DeleteConversation 删除会话
+// @Summary		删除会话
+// @Description	从当前用户的会话列表中删除会话并清零未读数，不删除消息；会话有新消息时重新显示
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Success		200				{object}	map[string]interface{}	"成功"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Failure		404				{object}	map[string]interface{}	"会话不存在"
+// @Router			/conversations/{conversation_id} [delete]
+func (h *ConversationHandler) DeleteConversation(c *gin.Context) {
+	if err := h.conversationService.DeleteConversation(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// PinConversation 置顶会话
+// @Summary		置顶会话
+// @Description	在当前用户的会话列表中置顶会话，仅会话参与者可操作
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Success		200				{object}	map[string]interface{}	"成功"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Router			/conversations/{conversation_id}/pin [put]
+func (h *ConversationHandler) PinConversation(c *gin.Context) {
+	h.setPinned(c, true)
+}
+
+// UnpinConversation 取消置顶会话
+// @Summary		取消置顶会话
+// @Description	取消当前用户会话列表中的会话置顶
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Success		200				{object}	map[string]interface{}	"成功"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Router			/conversations/{conversation_id}/pin [delete]
+func (h *ConversationHandler) UnpinConversation(c *gin.Context) {
+	h.setPinned(c, false)
+}
+
+// setPinned 设置会话置顶
+func (h *ConversationHandler) setPinned(c *gin.Context, pinned bool) {
+	if err := h.conversationService.SetPinned(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), pinned); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// MuteConversation 会话免打扰
+// @Summary		开启会话免打扰
+// @Description	开启后该会话的新消息不再推送离线通知（仍计未读），仅会话参与者可操作
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Success		200				{object}	map[string]interface{}	"成功"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Router			/conversations/{conversation_id}/mute [put]
+func (h *ConversationHandler) MuteConversation(c *gin.Context) {
+	h.setMuted(c, true)
+}
+
+// UnmuteConversation 关闭会话免打扰
+// @Summary		关闭会话免打扰
+// @Description	关闭会话免打扰，恢复离线推送
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Success		200				{object}	map[string]interface{}	"成功"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Router			/conversations/{conversation_id}/mute [delete]
+func (h *ConversationHandler) UnmuteConversation(c *gin.Context) {
+	h.setMuted(c, false)
+}
+
+// setMuted 设置会话免打扰
+func (h *ConversationHandler) setMuted(c *gin.Context, muted bool) {
+	if err := h.conversationService.SetMuted(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), muted); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// GetConversation 获取会话详情
+// @Summary		获取会话详情
+// @Description	获取会话类型、参与者（单聊）或群组引用（群聊）、当前用户的会话设置及最后一条消息，仅会话参与者可查看
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Success		200				{object}	map[string]interface{}	"会话详情"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Failure		404				{object}	map[string]interface{}	"会话不存在"
+// @Router			/conversations/{conversation_id} [get]
+func (h *ConversationHandler) GetConversation(c *gin.Context) {
+	detail, err := h.conversationService.GetConversation(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    detail,
+	})
+}
+
+// SearchMessages 会话内搜索
+// @Summary		会话内搜索消息
+// @Description	在会话内按关键字搜索文本消息（不区分大小写，按时间倒序），返回关键字高亮位置及跳转锚点，仅会话参与者可搜索
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Param			keyword			query		string					true	"关键字"
+// @Param			before			query		string					false	"分页游标（上一页返回的 next_cursor）"
+// @Param			limit			query		int						false	"返回条数（最大50）"	default(20)
+// @Success		200				{object}	map[string]interface{}	"搜索结果"
+// @Failure		400				{object}	map[string]interface{}	"关键字为空或过长"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Router			/conversations/{conversation_id}/search [get]
+func (h *ConversationHandler) SearchMessages(c *gin.Context) {
+	var req service.ConversationSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.conversationService.SearchMessages(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// GetMessageContext 获取消息上下文
+// @Summary		获取消息上下文
+// @Description	获取会话内某条消息前后的消息（均按时间升序），用于跳转到搜索结果；继续加载时以两端消息为锚点再次调用
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Param			message_id		path		string					true	"锚点消息ID"
+// @Param			before			query		int						false	"之前的消息数（最大50）"	default(10)
+// @Param			after			query		int						false	"之后的消息数（最大50）"	default(10)
+// @Success		200				{object}	map[string]interface{}	"消息上下文"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Failure		404				{object}	map[string]interface{}	"消息不存在"
+// @Router			/conversations/{conversation_id}/messages/{message_id}/context [get]
+func (h *ConversationHandler) GetMessageContext(c *gin.Context) {
+	before, _ := strconv.Atoi(c.DefaultQuery("before", "10"))
+	after, _ := strconv.Atoi(c.DefaultQuery("after", "10"))
+
+	result, err := h.conversationService.GetMessageContext(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), c.Param("message_id"), before, after)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// CreateExport 导出会话
+// @Summary		导出会话记录
+// @Description	异步生成指定时间范围内的会话记录（HTML，配置了PDF转换时可选PDF），图片以内嵌缩略图展示；完成后通过查询接口获取签名下载链接。仅会话参与者可导出，每个用户同时只能有一个导出任务
+// @Tags			会话
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Param			request			body		service.ExportRequest	true	"时间范围（RFC3339）及格式"
+// @Success		202				{object}	map[string]interface{}	"导出任务"
+// @Failure		400				{object}	map[string]interface{}	"时间范围或格式无效"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Failure		429				{object}	map[string]interface{}	"已有进行中的导出任务"
+// @Router			/conversations/{conversation_id}/export [post]
+func (h *ConversationHandler) CreateExport(c *gin.Context) {
+	var req service.ExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Locale = requestLocale(c)
+
+	job, err := h.exportService.CreateExport(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    job,
+	})
+}
+
+// GetExport 查询会话导出任务
+// @Summary		查询会话导出任务
+// @Description	查询导出任务状态，完成后返回签名下载链接
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Param			job_id			path		string					true	"导出任务ID"
+// @Success		200				{object}	map[string]interface{}	"导出任务"
+// @Failure		404				{object}	map[string]interface{}	"任务不存在或已过期"
+// @Router			/conversations/{conversation_id}/export/{job_id} [get]
+func (h *ConversationHandler) GetExport(c *gin.Context) {
+	job, err := h.exportService.GetExport(c.Request.Context(), c.GetString("user_id"), c.Param("job_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    job,
+	})
+}
+
+// GetEncryption 获取会话加密状态
+// @Summary		获取会话加密状态
+// @Description	获取会话是否开启客户端加密及当前密钥版本，仅会话参与者可查看
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Success		200				{object}	map[string]interface{}	"加密状态"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Router			/conversations/{conversation_id}/encryption [get]
+func (h *ConversationHandler) GetEncryption(c *gin.Context) {
+	encryption, err := h.encryptionService.GetEncryption(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    encryption,
+	})
+}
+
+// SetEncryption 开启或关闭会话加密
+// @Summary		开启或关闭会话加密
+// @Description	单聊双方、群主或群管理员可开启或关闭会话加密；开启时密钥版本递增，状态变化时会话成员收到 type=106 的密钥轮换事件。开启后会话只接受携带 ciphertext 的文本类消息
+// @Tags			会话
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string							true	"会话ID"
+// @Param			request			body		service.SetEncryptionRequest	true	"是否开启加密"
+// @Success		200				{object}	map[string]interface{}			"加密状态"
+// @Failure		403				{object}	map[string]interface{}			"无权限"
+// @Router			/conversations/{conversation_id}/encryption [put]
+func (h *ConversationHandler) SetEncryption(c *gin.Context) {
+	var req service.SetEncryptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	encryption, err := h.encryptionService.SetEncrypted(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), req.Encrypted)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    encryption,
+	})
+}
+
+// RotateKey 轮换会话密钥
+// @Summary		轮换会话密钥
+// @Description	单聊双方、群主或群管理员轮换加密会话的密钥（如成员退群后），密钥版本递增，会话成员收到 type=106 的密钥轮换事件后生成并分发新密钥，之后使用旧版本密钥加密的消息被拒绝
+// @Tags			会话
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string						true	"会话ID"
+// @Param			request			body		service.RotateKeyRequest	false	"轮换原因"
+// @Success		200				{object}	map[string]interface{}		"加密状态"
+// @Failure		400				{object}	map[string]interface{}		"会话未开启加密"
+// @Failure		403				{object}	map[string]interface{}		"无权限"
+// @Router			/conversations/{conversation_id}/encryption/rotate [post]
+func (h *ConversationHandler) RotateKey(c *gin.Context) {
+	var req service.RotateKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	encryption, err := h.encryptionService.RotateKey(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), req.Reason)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    encryption,
+	})
+}
+
+// ListEncrypted 分页查询加密会话
+// @Summary		分页查询加密会话
+// @Description	查询开启客户端加密的会话及密钥版本（按最近轮换时间倒序）
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			page		query		int						false	"页码"
+// @Param			page_size	query		int						false	"每页数量"
+// @Success		200			{object}	map[string]interface{}	"加密会话列表"
+// @Router			/admin/conversations/encrypted [get]
+func (h *ConversationHandler) ListEncrypted(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	conversations, total, err := h.encryptionService.ListEncrypted(c.Request.Context(), page, pageSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":         total,
+			"conversations": conversations,
+		},
+	})
+}
+
+// Summarize 生成会话摘要
+// @Summary		生成会话摘要
+// @Description	将会话最近的消息（脱敏后，跳过密文）交给外部摘要服务生成摘要，摘要保存后以 type 107 消息只下发给请求者；加密会话不支持，按用户每小时限频
+// @Tags			会话
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string						true	"会话ID"
+// @Param			request			body		service.SummarizeRequest	false	"最近的消息数"
+// @Success		200				{object}	map[string]interface{}		"会话摘要"
+// @Failure		400				{object}	map[string]interface{}		"加密会话或没有可摘要的消息"
+// @Failure		403				{object}	map[string]interface{}		"不是会话参与者"
+// @Failure		429				{object}	map[string]interface{}		"摘要次数已达上限"
+// @Failure		502				{object}	map[string]interface{}		"摘要服务不可用"
+// @Router			/conversations/{conversation_id}/summarize [post]
+func (h *ConversationHandler) Summarize(c *gin.Context) {
+	var req service.SummarizeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	req.Locale = requestLocale(c)
+
+	summary, err := h.summaryService.Summarize(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    summary,
+	})
+}
+
+// ListSummaries 查询我生成的会话摘要
+// @Summary		查询我生成的会话摘要
+// @Description	按生成时间倒序返回当前用户在会话中生成过的摘要
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Param			limit			query		int						false	"数量，默认20，最多50"
+// @Success		200				{object}	map[string]interface{}	"摘要列表"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Router			/conversations/{conversation_id}/summaries [get]
+func (h *ConversationHandler) ListSummaries(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	summaries, err := h.summaryService.ListSummaries(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    summaries,
+	})
+}
+
+// ListPins 查询会话置顶消息
+// @Summary		查询会话置顶消息
+// @Description	按置顶时间倒序返回会话置顶消息及消息内容，仅会话参与者可查看
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Success		200				{object}	map[string]interface{}	"置顶消息列表"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Router			/conversations/{conversation_id}/pins [get]
+func (h *ConversationHandler) ListPins(c *gin.Context) {
+	pins, err := h.pinService.ListPins(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    pins,
+	})
+}
+
+// PinMessage 置顶消息
+// @Summary		置顶消息
+// @Description	置顶会话中的消息（单聊双方，群聊为群主或管理员），每个会话最多50条，重复置顶不报错
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Param			message_id		path		string					true	"消息ID"
+// @Success		200				{object}	map[string]interface{}	"置顶记录"
+// @Failure		400				{object}	map[string]interface{}	"置顶消息数已达上限"
+// @Failure		403				{object}	map[string]interface{}	"无权限"
+// @Failure		404				{object}	map[string]interface{}	"消息不存在"
+// @Router			/conversations/{conversation_id}/pins/{message_id} [put]
+func (h *ConversationHandler) PinMessage(c *gin.Context) {
+	pin, err := h.pinService.Pin(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), c.Param("message_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    pin,
+	})
+}
+
+// UnpinMessage 取消置顶消息
+// @Summary		取消置顶消息
+// @Description	取消置顶会话中的消息（单聊双方，群聊为群主或管理员）
+// @Tags			会话
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Param			message_id		path		string					true	"消息ID"
+// @Success		200				{object}	map[string]interface{}	"成功"
+// @Failure		403				{object}	map[string]interface{}	"无权限"
+// @Router			/conversations/{conversation_id}/pins/{message_id} [delete]
+func (h *ConversationHandler) UnpinMessage(c *gin.Context) {
+	if err := h.pinService.Unpin(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), c.Param("message_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
@@ -0,0 +1,592 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// CSHandler 客服处理器
+type CSHandler struct {
+	csService service.CustomerService
+}
+
+// NewCSHandler 创建客服处理器
+func NewCSHandler(csService service.CustomerService) *CSHandler {
+	return &CSHandler{
+		csService: csService,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *CSHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	cs := r.Group("/api/cs")
+	cs.Use(NewAuthMiddleware(ra.Authenticator))
+	{
+		// 访客
+		cs.POST("/sessions", h.CreateSession)
+		cs.GET("/sessions/current", h.CurrentSession)
+		cs.GET("/sessions/:session_id", h.GetSession)
+		cs.POST("/sessions/:session_id/close", h.CloseSession)
+		cs.POST("/sessions/:session_id/rating", h.RateSession)
+
+		// 坐席
+		cs.POST("/sessions/:session_id/transfer", h.TransferSession)
+		cs.PUT("/agent/status", h.SetAgentStatus)
+		cs.GET("/agent/sessions", h.ListAgentSessions)
+		cs.GET("/queue", h.ListQueue)
+		cs.GET("/canned-replies", h.ListCannedReplies)
+		cs.POST("/canned-replies", h.CreateCannedReply)
+		cs.PUT("/canned-replies/:id", h.UpdateCannedReply)
+		cs.DELETE("/canned-replies/:id", h.DeleteCannedReply)
+	}
+
+	admin := r.Group("/api/admin/cs")
+	admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+	{
+		admin.GET("/agents", h.ListAgents)
+		admin.PUT("/agents/:user_id", h.SaveAgent)
+		admin.DELETE("/agents/:user_id", h.RemoveAgent)
+		admin.GET("/canned-replies", h.ListSharedCannedReplies)
+		admin.POST("/canned-replies", h.CreateSharedCannedReply)
+		admin.PUT("/canned-replies/:id", h.UpdateSharedCannedReply)
+		admin.DELETE("/canned-replies/:id", h.DeleteSharedCannedReply)
+	}
+}
+
+// SetCSAgentStatusRequest 坐席上线/下线请求
+type SetCSAgentStatusRequest struct {
+	Online bool `json:"online"`
+}
+
+// CreateSession 发起客服会话
+// @Summary		发起客服会话
+// @Description	访客发起客服会话，有空闲坐席时立即分配，否则排队；已有未结束的会话时直接返回。分配、转接、结束时访客和坐席收到 type 108 通知，消息通过与坐席的单聊发送
+// @Tags			客服
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		service.CreateCSSessionRequest	true	"咨询信息"
+// @Success		200		{object}	map[string]interface{}			"会话（排队中时含排队位置）"
+// @Failure		429		{object}	map[string]interface{}			"排队已满"
+// @Router			/cs/sessions [post]
+func (h *CSHandler) CreateSession(c *gin.Context) {
+	var req service.CreateCSSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.csService.CreateSession(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    session,
+	})
+}
+
+// CurrentSession 获取当前客服会话
+// @Summary		获取当前客服会话
+// @Description	获取访客排队中或接待中的会话，没有时 data 为空
+// @Tags			客服
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"会话"
+// @Router			/cs/sessions/current [get]
+func (h *CSHandler) CurrentSession(c *gin.Context) {
+	session, err := h.csService.CurrentSession(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    session,
+	})
+}
+
+// GetSession 获取客服会话
+// @Summary		获取客服会话
+// @Description	访客或当前坐席获取会话详情
+// @Tags			客服
+// @Produce		json
+// @Security		BearerAuth
+// @Param			session_id	path		string					true	"会话ID"
+// @Success		200			{object}	map[string]interface{}	"会话"
+// @Failure		404			{object}	map[string]interface{}	"会话不存在"
+// @Router			/cs/sessions/{session_id} [get]
+func (h *CSHandler) GetSession(c *gin.Context) {
+	session, err := h.csService.GetSession(c.Request.Context(), c.GetString("user_id"), c.Param("session_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    session,
+	})
+}
+
+// CloseSession 结束客服会话
+// @Summary		结束客服会话
+// @Description	访客或坐席结束会话，坐席释放接待名额后自动接待排队会话
+// @Tags			客服
+// @Produce		json
+// @Security		BearerAuth
+// @Param			session_id	path		string					true	"会话ID"
+// @Success		200			{object}	map[string]interface{}	"已结束的会话"
+// @Failure		404			{object}	map[string]interface{}	"会话不存在"
+// @Failure		409			{object}	map[string]interface{}	"会话已结束"
+// @Router			/cs/sessions/{session_id}/close [post]
+func (h *CSHandler) CloseSession(c *gin.Context) {
+	session, err := h.csService.Close(c.Request.Context(), c.GetString("user_id"), c.Param("session_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    session,
+	})
+}
+
+// RateSession 评价客服会话
+// @Summary		评价客服会话
+// @Description	访客对已结束的会话评价满意度（1-5），每个会话只能评价一次
+// @Tags			客服
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			session_id	path		string						true	"会话ID"
+// @Param			request		body		service.RateCSSessionRequest	true	"评价"
+// @Success		200			{object}	map[string]interface{}		"评价成功"
+// @Failure		409			{object}	map[string]interface{}		"会话未结束或已评价"
+// @Router			/cs/sessions/{session_id}/rating [post]
+func (h *CSHandler) RateSession(c *gin.Context) {
+	var req service.RateCSSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.csService.Rate(c.Request.Context(), c.GetString("user_id"), c.Param("session_id"), &req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// TransferSession 转接客服会话
+// @Summary		转接客服会话
+// @Description	当前坐席把接待中的会话转给指定坐席（须在线且未满），未指定时自动分配接待数最少的坐席
+// @Tags			客服
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			session_id	path		string							true	"会话ID"
+// @Param			request		body		service.TransferCSSessionRequest	true	"目标坐席"
+// @Success		200			{object}	map[string]interface{}			"转接后的会话"
+// @Failure		403			{object}	map[string]interface{}			"不是当前坐席"
+// @Failure		503			{object}	map[string]interface{}			"没有空闲坐席"
+// @Router			/cs/sessions/{session_id}/transfer [post]
+func (h *CSHandler) TransferSession(c *gin.Context) {
+	var req service.TransferCSSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.csService.Transfer(c.Request.Context(), c.GetString("user_id"), c.Param("session_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    session,
+	})
+}
+
+// SetAgentStatus 坐席上线/下线
+// @Summary		坐席上线/下线
+// @Description	上线后按接待上限自动接待排队会话；下线后不再分配新会话，接待中的会话不受影响
+// @Tags			客服
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		SetCSAgentStatusRequest	true	"状态"
+// @Success		200		{object}	map[string]interface{}	"坐席"
+// @Failure		403		{object}	map[string]interface{}	"不是坐席"
+// @Router			/cs/agent/status [put]
+func (h *CSHandler) SetAgentStatus(c *gin.Context) {
+	var req SetCSAgentStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	agent, err := h.csService.SetAgentStatus(c.Request.Context(), c.GetString("user_id"), req.Online)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    agent,
+	})
+}
+
+// ListAgentSessions 获取坐席的会话
+// @Summary		获取坐席的会话
+// @Description	坐席分页获取自己接待中或已结束的会话（按更新时间倒序）
+// @Tags			客服
+// @Produce		json
+// @Security		BearerAuth
+// @Param			status		query		string					false	"active（接待中）或 closed（已结束）"	default(active)
+// @Param			page		query		int						false	"页码"								default(1)
+// @Param			page_size	query		int						false	"每页数量"							default(20)
+// @Success		200			{object}	map[string]interface{}	"会话列表"
+// @Failure		403			{object}	map[string]interface{}	"不是坐席"
+// @Router			/cs/agent/sessions [get]
+func (h *CSHandler) ListAgentSessions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	closed := c.Query("status") == "closed"
+
+	sessions, total, err := h.csService.ListAgentSessions(c.Request.Context(), c.GetString("user_id"), closed, page, pageSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":    total,
+			"sessions": sessions,
+		},
+	})
+}
+
+// ListQueue 获取排队中的会话
+// @Summary		获取排队中的会话
+// @Description	坐席查看排队中的会话（按排队时间升序）及排队总数
+// @Tags			客服
+// @Produce		json
+// @Security		BearerAuth
+// @Param			limit	query		int						false	"数量"	default(20)
+// @Success		200		{object}	map[string]interface{}	"排队列表"
+// @Failure		403		{object}	map[string]interface{}	"不是坐席"
+// @Router			/cs/queue [get]
+func (h *CSHandler) ListQueue(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	sessions, total, err := h.csService.ListQueue(c.Request.Context(), c.GetString("user_id"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":    total,
+			"sessions": sessions,
+		},
+	})
+}
+
+// ListCannedReplies 获取快捷回复
+// @Summary		获取快捷回复
+// @Description	坐席获取公共快捷回复及自己的快捷回复
+// @Tags			客服
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"快捷回复列表"
+// @Failure		403	{object}	map[string]interface{}	"不是坐席"
+// @Router			/cs/canned-replies [get]
+func (h *CSHandler) ListCannedReplies(c *gin.Context) {
+	h.listCannedReplies(c, c.GetString("user_id"))
+}
+
+// CreateCannedReply 创建快捷回复
+// @Summary		创建快捷回复
+// @Description	坐席创建自己的快捷回复
+// @Tags			客服
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		service.CannedReplyRequest	true	"快捷回复"
+// @Success		200		{object}	map[string]interface{}		"快捷回复"
+// @Failure		403		{object}	map[string]interface{}		"不是坐席"
+// @Router			/cs/canned-replies [post]
+func (h *CSHandler) CreateCannedReply(c *gin.Context) {
+	h.createCannedReply(c, c.GetString("user_id"))
+}
+
+// UpdateCannedReply 修改快捷回复
+// @Summary		修改快捷回复
+// @Description	坐席修改自己的快捷回复
+// @Tags			客服
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id		path		int							true	"快捷回复ID"
+// @Param			request	body		service.CannedReplyRequest	true	"快捷回复"
+// @Success		200		{object}	map[string]interface{}		"修改成功"
+// @Failure		404		{object}	map[string]interface{}		"快捷回复不存在"
+// @Router			/cs/canned-replies/{id} [put]
+func (h *CSHandler) UpdateCannedReply(c *gin.Context) {
+	h.updateCannedReply(c, c.GetString("user_id"))
+}
+
+// DeleteCannedReply 删除快捷回复
+// @Summary		删除快捷回复
+// @Description	坐席删除自己的快捷回复
+// @Tags			客服
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int						true	"快捷回复ID"
+// @Success		200	{object}	map[string]interface{}	"删除成功"
+// @Failure		404	{object}	map[string]interface{}	"快捷回复不存在"
+// @Router			/cs/canned-replies/{id} [delete]
+func (h *CSHandler) DeleteCannedReply(c *gin.Context) {
+	h.deleteCannedReply(c, c.GetString("user_id"))
+}
+
+// ListAgents 获取坐席池
+// @Summary		获取坐席池
+// @Description	获取全部坐席及其状态、接待数
+// @Tags			客服
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"坐席列表"
+// @Failure		403	{object}	map[string]interface{}	"需要管理员权限"
+// @Router			/admin/cs/agents [get]
+func (h *CSHandler) ListAgents(c *gin.Context) {
+	agents, err := h.csService.ListAgents(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    agents,
+	})
+}
+
+// SaveAgent 添加坐席
+// @Summary		添加坐席
+// @Description	把用户加入坐席池（初始为离线）或修改接待上限，max_concurrent 为 0 时使用默认上限
+// @Tags			客服
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string						true	"用户ID"
+// @Param			request	body		service.SaveCSAgentRequest	true	"接待上限"
+// @Success		200		{object}	map[string]interface{}		"坐席"
+// @Failure		404		{object}	map[string]interface{}		"用户不存在"
+// @Router			/admin/cs/agents/{user_id} [put]
+func (h *CSHandler) SaveAgent(c *gin.Context) {
+	var req service.SaveCSAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	agent, err := h.csService.SaveAgent(c.Request.Context(), c.Param("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    agent,
+	})
+}
+
+// RemoveAgent 移出坐席
+// @Summary		移出坐席
+// @Description	把用户移出坐席池，有接待中的会话时须先结束或转接
+// @Tags			客服
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string					true	"用户ID"
+// @Success		200		{object}	map[string]interface{}	"移出成功"
+// @Failure		404		{object}	map[string]interface{}	"坐席不存在"
+// @Failure		409		{object}	map[string]interface{}	"有接待中的会话"
+// @Router			/admin/cs/agents/{user_id} [delete]
+func (h *CSHandler) RemoveAgent(c *gin.Context) {
+	if err := h.csService.RemoveAgent(c.Request.Context(), c.Param("user_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// ListSharedCannedReplies 获取公共快捷回复
+// @Summary		获取公共快捷回复
+// @Description	获取所有坐席可见的公共快捷回复
+// @Tags			客服
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"快捷回复列表"
+// @Router			/admin/cs/canned-replies [get]
+func (h *CSHandler) ListSharedCannedReplies(c *gin.Context) {
+	h.listCannedReplies(c, "")
+}
+
+// CreateSharedCannedReply 创建公共快捷回复
+// @Summary		创建公共快捷回复
+// @Description	创建所有坐席可见的公共快捷回复
+// @Tags			客服
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		service.CannedReplyRequest	true	"快捷回复"
+// @Success		200		{object}	map[string]interface{}		"快捷回复"
+// @Router			/admin/cs/canned-replies [post]
+func (h *CSHandler) CreateSharedCannedReply(c *gin.Context) {
+	h.createCannedReply(c, "")
+}
+
+// UpdateSharedCannedReply 修改公共快捷回复
+// @Summary		修改公共快捷回复
+// @Description	修改公共快捷回复
+// @Tags			客服
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id		path		int							true	"快捷回复ID"
+// @Param			request	body		service.CannedReplyRequest	true	"快捷回复"
+// @Success		200		{object}	map[string]interface{}		"修改成功"
+// @Failure		404		{object}	map[string]interface{}		"快捷回复不存在"
+// @Router			/admin/cs/canned-replies/{id} [put]
+func (h *CSHandler) UpdateSharedCannedReply(c *gin.Context) {
+	h.updateCannedReply(c, "")
+}
+
+// DeleteSharedCannedReply 删除公共快捷回复
+// @Summary		删除公共快捷回复
+// @Description	删除公共快捷回复
+// @Tags			客服
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int						true	"快捷回复ID"
+// @Success		200	{object}	map[string]interface{}	"删除成功"
+// @Failure		404	{object}	map[string]interface{}	"快捷回复不存在"
+// @Router			/admin/cs/canned-replies/{id} [delete]
+func (h *CSHandler) DeleteSharedCannedReply(c *gin.Context) {
+	h.deleteCannedReply(c, "")
+}
+
+// listCannedReplies 获取坐席（agentID 为空时为公共）的快捷回复
+func (h *CSHandler) listCannedReplies(c *gin.Context, agentID string) {
+	replies, err := h.csService.ListCannedReplies(c.Request.Context(), agentID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    replies,
+	})
+}
+
+// createCannedReply 创建坐席（agentID 为空时为公共）的快捷回复
+func (h *CSHandler) createCannedReply(c *gin.Context, agentID string) {
+	var req service.CannedReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reply, err := h.csService.CreateCannedReply(c.Request.Context(), agentID, &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    reply,
+	})
+}
+
+// updateCannedReply 修改坐席（agentID 为空时为公共）的快捷回复
+func (h *CSHandler) updateCannedReply(c *gin.Context, agentID string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, service.ErrInvalidRequest)
+		return
+	}
+	var req service.CannedReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.csService.UpdateCannedReply(c.Request.Context(), agentID, uint(id), &req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// deleteCannedReply 删除坐席（agentID 为空时为公共）的快捷回复
+func (h *CSHandler) deleteCannedReply(c *gin.Context, agentID string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, service.ErrInvalidRequest)
+		return
+	}
+
+	if err := h.csService.DeleteCannedReply(c.Request.Context(), agentID, uint(id)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
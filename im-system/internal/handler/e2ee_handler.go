@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// E2EEHandler 端到端加密密钥处理器
+type E2EEHandler struct {
+	e2eeService service.E2EEService
+}
+
+// NewE2EEHandler 创建端到端加密密钥处理器
+func NewE2EEHandler(e2eeService service.E2EEService) *E2EEHandler {
+	return &E2EEHandler{e2eeService: e2eeService}
+}
+
+// RegisterRoutes 注册路由
+func (h *E2EEHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	e2ee := r.Group("/api/e2ee")
+	e2ee.Use(NewAuthMiddleware(ra.Authenticator))
+	{
+		e2ee.GET("/devices", h.ListMyDevices)
+		e2ee.PUT("/devices/:device_id", h.RegisterDevice)
+		e2ee.POST("/devices/:device_id/prekeys", h.UploadPreKeys)
+		e2ee.DELETE("/devices/:device_id", h.RemoveDevice)
+		e2ee.GET("/users/:user_id/devices", h.ListDevices)
+		e2ee.POST("/users/:user_id/bundles", h.FetchBundles)
+	}
+}
+
+// ListMyDevices 获取自己已注册加密密钥的设备
+// @Summary		获取我的加密设备
+// @Description	获取当前用户已注册端到端加密密钥的设备及剩余一次性预密钥数（不足时客户端应补充）
+// @Tags			端到端加密
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"设备列表"
+// @Router			/e2ee/devices [get]
+func (h *E2EEHandler) ListMyDevices(c *gin.Context) {
+	devices, err := h.e2eeService.ListMyDevices(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    devices,
+	})
+}
+
+// RegisterDevice 注册设备密钥
+// @Summary		注册设备加密密钥
+// @Description	上传设备的身份公钥、签名预密钥及一次性预密钥（Base64），已注册时更新；身份公钥变化时清空该设备未使用的一次性预密钥
+// @Tags			端到端加密
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			device_id	path		string							true	"设备ID"
+// @Param			request		body		model.RegisterE2EEKeysRequest	true	"设备密钥"
+// @Success		200			{object}	map[string]interface{}			"设备信息"
+// @Failure		400			{object}	map[string]interface{}			"密钥格式错误或超出数量限制"
+// @Router			/e2ee/devices/{device_id} [put]
+func (h *E2EEHandler) RegisterDevice(c *gin.Context) {
+	var req model.RegisterE2EEKeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, err := h.e2eeService.RegisterDevice(c.Request.Context(), c.GetString("user_id"), c.Param("device_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    device,
+	})
+}
+
+// UploadPreKeys 补充一次性预密钥
+// @Summary		补充一次性预密钥
+// @Description	补充设备的一次性预密钥，可同时轮换签名预密钥（已存在的 key_id 忽略）
+// @Tags			端到端加密
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			device_id	path		string							true	"设备ID"
+// @Param			request		body		model.UploadE2EEPreKeysRequest	true	"预密钥"
+// @Success		200			{object}	map[string]interface{}			"设备信息"
+// @Failure		400			{object}	map[string]interface{}			"密钥格式错误或超出数量限制"
+// @Failure		404			{object}	map[string]interface{}			"设备未注册"
+// @Router			/e2ee/devices/{device_id}/prekeys [post]
+func (h *E2EEHandler) UploadPreKeys(c *gin.Context) {
+	var req model.UploadE2EEPreKeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, err := h.e2eeService.UploadPreKeys(c.Request.Context(), c.GetString("user_id"), c.Param("device_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    device,
+	})
+}
+
+// RemoveDevice 删除设备密钥
+// @Summary		删除设备加密密钥
+// @Description	设备登出或重置加密时删除其公钥及未使用的一次性预密钥
+// @Tags			端到端加密
+// @Produce		json
+// @Security		BearerAuth
+// @Param			device_id	path		string					true	"设备ID"
+// @Success		200			{object}	map[string]interface{}	"删除成功"
+// @Failure		404			{object}	map[string]interface{}	"设备未注册"
+// @Router			/e2ee/devices/{device_id} [delete]
+func (h *E2EEHandler) RemoveDevice(c *gin.Context) {
+	if err := h.e2eeService.RemoveDevice(c.Request.Context(), c.GetString("user_id"), c.Param("device_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// ListDevices 获取用户的设备身份公钥
+// @Summary		获取用户的加密设备
+// @Description	获取用户各设备的身份公钥（用于核对安全码、发现新设备），不消耗一次性预密钥
+// @Tags			端到端加密
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string					true	"用户ID"
+// @Success		200		{object}	map[string]interface{}	"设备列表"
+// @Failure		403		{object}	map[string]interface{}	"被对方屏蔽"
+// @Failure		404		{object}	map[string]interface{}	"用户不存在"
+// @Router			/e2ee/users/{user_id}/devices [get]
+func (h *E2EEHandler) ListDevices(c *gin.Context) {
+	devices, err := h.e2eeService.ListDevices(c.Request.Context(), c.GetString("user_id"), c.Param("user_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    devices,
+	})
+}
+
+// FetchBundles 获取用户设备的预密钥包
+// @Summary		获取预密钥包
+// @Description	获取用户设备的身份公钥、签名预密钥及一个一次性预密钥（取出后即删除，耗尽时不返回），用于建立加密会话
+// @Tags			端到端加密
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id		path		string					true	"用户ID"
+// @Param			device_id	query		string					false	"设备ID，为空时返回全部设备"
+// @Success		200			{object}	map[string]interface{}	"预密钥包列表"
+// @Failure		403			{object}	map[string]interface{}	"被对方屏蔽"
+// @Failure		404			{object}	map[string]interface{}	"用户不存在或没有注册加密设备"
+// @Router			/e2ee/users/{user_id}/bundles [post]
+func (h *E2EEHandler) FetchBundles(c *gin.Context) {
+	bundles, err := h.e2eeService.FetchBundles(c.Request.Context(), c.GetString("user_id"), c.Param("user_id"), c.Query("device_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    bundles,
+	})
+}
@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/service"
+	"github.com/d60-lab/im-system/pkg/errcode"
+	"github.com/d60-lab/im-system/pkg/i18n"
+	"github.com/d60-lab/im-system/pkg/ratelimit"
+)
+
+// 业务错误码注册（2xxxx 群组, 3xxxx 用户, 4xxxx 文件, 5xxxx 节点, 6xxxx 会话, 7xxxx 组织架构, 8xxxx 消息, 9xxxx 系统配置）
+func init() {
+	errcode.Register(service.ErrInvalidRequest, errcode.CodeInvalidRequest, http.StatusBadRequest, "error.invalid_request")
+	errcode.Register(service.ErrPermissionDeny, errcode.CodePermissionDenied, http.StatusForbidden, "error.permission_denied")
+	errcode.Register(service.ErrMaintenanceMode, errcode.CodeMaintenance, http.StatusServiceUnavailable, "error.maintenance")
+
+	errcode.Register(service.ErrGroupNotFound, 20001, http.StatusNotFound, "error.group_not_found")
+	errcode.Register(service.ErrNotGroupMember, 20002, http.StatusForbidden, "error.not_group_member")
+	errcode.Register(service.ErrNotGroupOwner, 20003, http.StatusForbidden, "error.not_group_owner")
+	errcode.Register(service.ErrNotGroupAdmin, 20004, http.StatusForbidden, "error.not_group_admin")
+	errcode.Register(service.ErrGroupFull, 20005, http.StatusBadRequest, "error.group_full")
+	errcode.Register(service.ErrAlreadyInGroup, 20006, http.StatusBadRequest, "error.already_in_group")
+	errcode.Register(service.ErrCannotKickOwner, 20007, http.StatusBadRequest, "error.cannot_kick_owner")
+	errcode.Register(service.ErrGroupDismissed, 20008, http.StatusBadRequest, "error.group_dismissed")
+	errcode.Register(service.ErrOwnerCannotLeave, 20009, http.StatusBadRequest, "error.owner_cannot_leave")
+	errcode.Register(service.ErrGroupConflict, 20010, http.StatusConflict, "error.group_conflict")
+	errcode.Register(service.ErrJoinRequestNotFound, 20011, http.StatusNotFound, "error.join_request_not_found")
+	errcode.Register(service.ErrJoinRequestHandled, 20012, http.StatusConflict, "error.join_request_handled")
+	errcode.Register(service.ErrGroupExpiryInvalid, 20013, http.StatusBadRequest, "error.group_expiry_invalid")
+	errcode.Register(service.ErrGroupMuteAll, 20014, http.StatusForbidden, "error.group_mute_all")
+	errcode.Register(service.ErrMemberMuted, 20015, http.StatusForbidden, "error.member_muted")
+	errcode.Register(service.ErrMentionGroupNotFound, 20016, http.StatusNotFound, "error.mention_group_not_found")
+	errcode.Register(service.ErrMentionGroupExists, 20017, http.StatusConflict, "error.mention_group_exists")
+	errcode.Register(service.ErrMentionGroupInvalid, 20018, http.StatusBadRequest, "error.mention_group_invalid")
+	errcode.Register(service.ErrGroupDigestNotFound, 20019, http.StatusNotFound, "error.group_digest_not_found")
+	errcode.Register(service.ErrGroupDigestInvalid, 20020, http.StatusBadRequest, "error.group_digest_invalid")
+
+	errcode.Register(service.ErrNameReserved, 30001, http.StatusBadRequest, "error.name_reserved")
+	errcode.Register(service.ErrUsernameTaken, 30002, http.StatusBadRequest, "error.username_taken")
+	errcode.Register(service.ErrNicknameTaken, 30003, http.StatusBadRequest, "error.nickname_taken")
+	errcode.Register(service.ErrRenameTooFrequent, 30004, http.StatusTooManyRequests, "error.rename_too_frequent")
+	errcode.Register(service.ErrUserNotFound, 30005, http.StatusNotFound, "error.user_not_found")
+	errcode.Register(service.ErrImportEmpty, 30006, http.StatusBadRequest, "error.import_empty")
+	errcode.Register(service.ErrImportTooManyRows, 30007, http.StatusBadRequest, "error.import_too_many_rows")
+	errcode.Register(service.ErrAutoReplyTextRequired, 30008, http.StatusBadRequest, "error.auto_reply_text_required")
+	errcode.Register(service.ErrAutoReplyTextTooLong, 30009, http.StatusBadRequest, "error.auto_reply_text_too_long")
+	errcode.Register(service.ErrAutoReplyPeriod, 30010, http.StatusBadRequest, "error.auto_reply_period")
+	errcode.Register(service.ErrAccountDeleted, 30011, http.StatusConflict, "error.account_deleted")
+	errcode.Register(service.ErrInvalidUserStatus, 30012, http.StatusBadRequest, "error.invalid_user_status")
+	errcode.Register(service.ErrFriendSelf, 30013, http.StatusBadRequest, "error.friend_self")
+	errcode.Register(service.ErrAlreadyFriends, 30014, http.StatusConflict, "error.already_friends")
+	errcode.Register(service.ErrNotFriends, 30015, http.StatusNotFound, "error.not_friends")
+	errcode.Register(service.ErrFriendRequestNotFound, 30016, http.StatusNotFound, "error.friend_request_not_found")
+	errcode.Register(service.ErrFriendRequestHandled, 30017, http.StatusConflict, "error.friend_request_handled")
+	errcode.Register(service.ErrBlockedByUser, 30018, http.StatusForbidden, "error.blocked_by_user")
+	errcode.Register(service.ErrGuestDisabled, 30019, http.StatusForbidden, "error.guest_disabled")
+	errcode.Register(service.ErrGuestRateLimited, 30020, http.StatusTooManyRequests, "error.guest_rate_limited")
+	errcode.Register(service.ErrGuestTargetForbidden, 30021, http.StatusForbidden, "error.guest_target_forbidden")
+	errcode.Register(service.ErrGuestExpired, 30022, http.StatusForbidden, "error.guest_expired")
+	errcode.Register(service.ErrNotGuest, 30023, http.StatusBadRequest, "error.not_guest")
+	errcode.Register(service.ErrUsageMonthInvalid, 30024, http.StatusBadRequest, "error.usage_month_invalid")
+	errcode.Register(service.ErrPresenceTooManyUsers, 30025, http.StatusBadRequest, "error.presence_too_many_users")
+	errcode.Register(service.ErrDoNotDisturbInvalid, 30026, http.StatusBadRequest, "error.dnd_invalid")
+	errcode.Register(service.ErrInvalidWebPushSubscription, 30027, http.StatusBadRequest, "error.webpush_subscription_invalid")
+
+	errcode.Register(service.ErrFileNotFound, 40001, http.StatusNotFound, "error.file_not_found")
+	errcode.Register(service.ErrFileTooLarge, 40002, http.StatusBadRequest, "error.file_too_large")
+	errcode.Register(service.ErrInvalidFileType, 40003, http.StatusUnsupportedMediaType, "error.invalid_file_type")
+	errcode.Register(service.ErrChecksumMismatch, 40004, http.StatusBadRequest, "error.checksum_mismatch")
+	errcode.Register(service.ErrFileContentMismatch, 40005, http.StatusUnsupportedMediaType, "error.file_content_mismatch")
+	errcode.Register(service.ErrFilePolicyInvalid, 40006, http.StatusBadRequest, "error.file_policy_invalid")
+	errcode.Register(service.ErrSuspiciousArchive, 40007, http.StatusUnprocessableEntity, "error.suspicious_archive")
+	errcode.Register(service.ErrFileExpired, 40008, http.StatusGone, "error.file_expired")
+	errcode.Register(service.ErrStorageUnavailable, 40009, http.StatusServiceUnavailable, "error.storage_unavailable")
+
+	errcode.Register(service.ErrNodeNotFound, 50001, http.StatusNotFound, "error.node_not_found")
+
+	errcode.Register(service.ErrConversationNotFound, 60001, http.StatusNotFound, "error.conversation_not_found")
+	errcode.Register(service.ErrExportRangeInvalid, 60002, http.StatusBadRequest, "error.export_range_invalid")
+	errcode.Register(service.ErrExportRangeTooLong, 60003, http.StatusBadRequest, "error.export_range_too_long")
+	errcode.Register(service.ErrExportFormatInvalid, 60004, http.StatusBadRequest, "error.export_format_invalid")
+	errcode.Register(service.ErrExportInProgress, 60005, http.StatusTooManyRequests, "error.export_in_progress")
+	errcode.Register(service.ErrExportJobNotFound, 60006, http.StatusNotFound, "error.export_job_not_found")
+	errcode.Register(service.ErrSearchKeywordInvalid, 60007, http.StatusBadRequest, "error.search_keyword_invalid")
+	errcode.Register(service.ErrConversationNotEncrypted, 60008, http.StatusBadRequest, "error.conversation_not_encrypted")
+	errcode.Register(service.ErrSummaryRateLimited, 60009, http.StatusTooManyRequests, "error.summary_rate_limited")
+	errcode.Register(service.ErrSummaryEncrypted, 60010, http.StatusBadRequest, "error.summary_encrypted")
+	errcode.Register(service.ErrSummaryEmpty, 60011, http.StatusBadRequest, "error.summary_empty")
+	errcode.Register(service.ErrSummaryUnavailable, 60012, http.StatusBadGateway, "error.summary_unavailable")
+	errcode.Register(service.ErrPinLimitExceeded, 60013, http.StatusBadRequest, "error.pin_limit_exceeded")
+	errcode.Register(service.ErrCrossRegionDenied, 60014, http.StatusForbidden, "error.cross_region_denied")
+	errcode.Register(service.ErrCSAgentNotFound, 60015, http.StatusNotFound, "error.cs_agent_not_found")
+	errcode.Register(service.ErrCSNotAgent, 60016, http.StatusForbidden, "error.cs_not_agent")
+	errcode.Register(service.ErrCSAgentBusy, 60017, http.StatusConflict, "error.cs_agent_busy")
+	errcode.Register(service.ErrCSSessionNotFound, 60018, http.StatusNotFound, "error.cs_session_not_found")
+	errcode.Register(service.ErrCSSessionClosed, 60019, http.StatusConflict, "error.cs_session_closed")
+	errcode.Register(service.ErrCSSessionNotClosed, 60020, http.StatusConflict, "error.cs_session_not_closed")
+	errcode.Register(service.ErrCSSessionRated, 60021, http.StatusConflict, "error.cs_session_rated")
+	errcode.Register(service.ErrCSNoAgentAvailable, 60022, http.StatusServiceUnavailable, "error.cs_no_agent_available")
+	errcode.Register(service.ErrCSQueueFull, 60023, http.StatusTooManyRequests, "error.cs_queue_full")
+	errcode.Register(service.ErrCannedReplyNotFound, 60024, http.StatusNotFound, "error.canned_reply_not_found")
+	errcode.Register(service.ErrCSSessionNotAssigned, 60025, http.StatusForbidden, "error.cs_session_not_assigned")
+	errcode.Register(service.ErrE2EEDeviceNotFound, 60026, http.StatusNotFound, "error.e2ee_device_not_found")
+	errcode.Register(service.ErrE2EEKeyInvalid, 60027, http.StatusBadRequest, "error.e2ee_key_invalid")
+	errcode.Register(service.ErrE2EETooManyPreKeys, 60028, http.StatusBadRequest, "error.e2ee_too_many_prekeys")
+	errcode.Register(service.ErrE2EEDeviceLimit, 60029, http.StatusBadRequest, "error.e2ee_device_limit")
+
+	errcode.Register(service.ErrDepartmentNotFound, 70001, http.StatusNotFound, "error.department_not_found")
+	errcode.Register(service.ErrDepartmentNotEmpty, 70002, http.StatusBadRequest, "error.department_not_empty")
+	errcode.Register(service.ErrDepartmentCycle, 70003, http.StatusBadRequest, "error.department_cycle")
+
+	errcode.Register(service.ErrMessageNotFound, 80001, http.StatusNotFound, "error.message_not_found")
+	errcode.Register(service.ErrReminderNotFound, 80002, http.StatusNotFound, "error.reminder_not_found")
+	errcode.Register(service.ErrReminderLimit, 80003, http.StatusTooManyRequests, "error.reminder_limit")
+	errcode.Register(service.ErrInvalidRemindTime, 80004, http.StatusBadRequest, "error.invalid_remind_time")
+	errcode.Register(service.ErrRemindTimeTooFar, 80005, http.StatusBadRequest, "error.remind_time_too_far")
+	errcode.Register(service.ErrReminderNotPending, 80006, http.StatusConflict, "error.reminder_not_pending")
+	errcode.Register(service.ErrCustomSignatureInvalid, 80007, http.StatusForbidden, "error.custom_signature_invalid")
+	errcode.Register(service.ErrCustomSignatureExpired, 80008, http.StatusBadRequest, "error.custom_signature_expired")
+	errcode.Register(service.ErrCustomMessageUnverified, 80009, http.StatusForbidden, "error.custom_message_unverified")
+	errcode.Register(service.ErrAppNotFound, 80010, http.StatusNotFound, "error.app_not_found")
+	errcode.Register(service.ErrRevokeNotSender, 80011, http.StatusForbidden, "error.revoke_not_sender")
+	errcode.Register(service.ErrRevokeTimeExceeded, 80012, http.StatusBadRequest, "error.revoke_time_exceeded")
+	errcode.Register(service.ErrMessageContentInvalid, 80013, http.StatusBadRequest, "error.message_content_invalid")
+	errcode.Register(service.ErrCancelNotSender, 80014, http.StatusForbidden, "error.cancel_not_sender")
+	errcode.Register(service.ErrMessageNotPending, 80015, http.StatusConflict, "error.message_not_pending")
+	errcode.Register(service.ErrPlaintextInEncrypted, 80016, http.StatusBadRequest, "error.plaintext_in_encrypted")
+	errcode.Register(service.ErrStaleKeyVersion, 80017, http.StatusConflict, "error.stale_key_version")
+	errcode.Register(service.ErrMessageQuarantined, 80018, http.StatusUnprocessableEntity, "error.message_quarantined")
+	errcode.Register(service.ErrMessageClockOutOfRange, 80019, http.StatusBadRequest, "error.message_clock_out_of_range")
+	errcode.Register(service.ErrQuarantineNotFound, 80020, http.StatusNotFound, "error.quarantine_not_found")
+	errcode.Register(service.ErrPollNotFound, 80021, http.StatusNotFound, "error.poll_not_found")
+	errcode.Register(service.ErrPollClosed, 80022, http.StatusConflict, "error.poll_closed")
+	errcode.Register(service.ErrPollAlreadyVoted, 80023, http.StatusConflict, "error.poll_already_voted")
+	errcode.Register(service.ErrPollInvalid, 80024, http.StatusBadRequest, "error.poll_invalid")
+	errcode.Register(service.ErrPollInvalidChoice, 80025, http.StatusBadRequest, "error.poll_invalid_choice")
+	errcode.Register(service.ErrPollDeadlineInvalid, 80026, http.StatusBadRequest, "error.poll_deadline_invalid")
+	errcode.Register(service.ErrMessageTypeNotAllowed, 80027, http.StatusForbidden, "error.message_type_not_allowed")
+	errcode.Register(service.ErrMessageTypePolicyInvalid, 80028, http.StatusBadRequest, "error.message_type_policy_invalid")
+	errcode.Register(service.ErrPurgeFilterInvalid, 80029, http.StatusBadRequest, "error.purge_filter_invalid")
+	errcode.Register(service.ErrSearchUnavailable, 80030, http.StatusServiceUnavailable, "error.search_unavailable")
+	errcode.Register(service.ErrContentRejected, 80031, http.StatusUnprocessableEntity, "error.content_rejected")
+	errcode.Register(service.ErrModerationUnavailable, 80032, http.StatusServiceUnavailable, "error.moderation_unavailable")
+
+	errcode.Register(service.ErrFlagNotFound, 90001, http.StatusNotFound, "error.flag_not_found")
+	errcode.Register(service.ErrFlagInvalidKey, 90002, http.StatusBadRequest, "error.flag_invalid_key")
+	errcode.Register(service.ErrPushExperimentNotFound, 90003, http.StatusNotFound, "error.push_experiment_not_found")
+	errcode.Register(service.ErrPushVariantInvalid, 90004, http.StatusBadRequest, "error.push_variant_invalid")
+	errcode.Register(service.ErrBridgePlatformUnsupported, 90005, http.StatusBadRequest, "error.bridge_platform_unsupported")
+	errcode.Register(service.ErrBridgeRoomNotFound, 90006, http.StatusNotFound, "error.bridge_room_not_found")
+	errcode.Register(service.ErrBridgeUserNotFound, 90007, http.StatusNotFound, "error.bridge_user_not_found")
+	errcode.Register(service.ErrRoleNotFound, 90008, http.StatusNotFound, "error.role_not_found")
+	errcode.Register(service.ErrRoleBuiltin, 90009, http.StatusBadRequest, "error.role_builtin")
+	errcode.Register(service.ErrRoleNameInvalid, 90010, http.StatusBadRequest, "error.role_name_invalid")
+	errcode.Register(service.ErrPermissionUnknown, 90011, http.StatusBadRequest, "error.permission_unknown")
+	errcode.Register(service.ErrRoleSelfRevoke, 90012, http.StatusBadRequest, "error.role_self_revoke")
+	errcode.Register(service.ErrUnknownRegion, 90013, http.StatusBadRequest, "error.unknown_region")
+	errcode.Register(ratelimit.ErrRateLimited, 90014, http.StatusTooManyRequests, "error.rate_limited")
+}
+
+// requestLocale 获取请求语言：X-Device-Locale > locale参数 > Accept-Language
+func requestLocale(c *gin.Context) string {
+	return i18n.Resolve(c.GetHeader("Accept-Language"), c.GetHeader("X-Device-Locale"), c.Query("locale"))
+}
+
+// respondError 按错误码注册表返回本地化错误，未注册的错误按500返回原始信息
+func respondError(c *gin.Context, err error) {
+	code, ok := errcode.Lookup(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": errcode.CodeInternal, "error": err.Error()})
+		return
+	}
+
+	// 并发修改冲突：客户端重新获取最新状态后可重试
+	if errors.Is(err, service.ErrGroupConflict) {
+		c.Header("Retry-After", "1")
+	}
+
+	c.JSON(code.HTTPStatus, gin.H{
+		"code":  code.Code,
+		"error": code.Message(requestLocale(c)),
+	})
+}
+
+// I18nHandler 多语言文案处理器
+type I18nHandler struct{}
+
+// NewI18nHandler 创建多语言文案处理器
+func NewI18nHandler() *I18nHandler {
+	return &I18nHandler{}
+}
+
+// RegisterRoutes 注册路由
+func (h *I18nHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/api/i18n", h.GetMessages)
+	r.GET("/api/i18n/error-codes", h.GetErrorCodes)
+}
+
+// GetMessages 获取文案目录
+// @Summary		获取文案目录
+// @Description	获取指定语言的服务端文案（含群事件渲染模板），未指定时按Accept-Language协商
+// @Tags			多语言
+// @Produce		json
+// @Param			locale	query		string					false	"语言，如 zh-CN、en-US"
+// @Success		200		{object}	map[string]interface{}	"文案目录"
+// @Router			/i18n [get]
+func (h *I18nHandler) GetMessages(c *gin.Context) {
+	locale := requestLocale(c)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"locale":    locale,
+			"supported": i18n.Supported(),
+			"messages":  i18n.Messages(locale),
+		},
+	})
+}
+
+// GetErrorCodes 获取错误码列表
+// @Summary		获取错误码列表
+// @Description	获取全部已注册错误码及其本地化文案
+// @Tags			多语言
+// @Produce		json
+// @Param			locale	query		string					false	"语言，如 zh-CN、en-US"
+// @Success		200		{object}	map[string]interface{}	"错误码列表"
+// @Router			/i18n/error-codes [get]
+func (h *I18nHandler) GetErrorCodes(c *gin.Context) {
+	locale := requestLocale(c)
+	codes := errcode.All()
+	result := make([]gin.H, 0, len(codes))
+	for _, code := range codes {
+		result = append(result, gin.H{
+			"code":        code.Code,
+			"http_status": code.HTTPStatus,
+			"message":     code.Message(locale),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
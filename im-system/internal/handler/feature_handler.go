@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// FeatureHandler 功能开关/灰度发布处理器
+type FeatureHandler struct {
+	flags service.FeatureFlagService
+}
+
+// NewFeatureHandler 创建功能开关处理器
+func NewFeatureHandler(flags service.FeatureFlagService) *FeatureHandler {
+	return &FeatureHandler{flags: flags}
+}
+
+// RegisterRoutes 注册路由
+func (h *FeatureHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	r.GET("/api/features", NewAuthMiddleware(ra.Authenticator), h.GetMyFeatures)
+
+	admin := r.Group("/api/admin/flags")
+	admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+	{
+		admin.GET("", h.ListFlags)
+		admin.PUT("/:key", h.SetFlag)
+		admin.DELETE("/:key", h.DeleteFlag)
+		admin.GET("/:key/metrics", h.CompareCohorts)
+		admin.DELETE("/:key/metrics", h.ResetMetrics)
+	}
+}
+
+// GetMyFeatures 获取当前用户的灰度分组
+// @Summary		获取我的灰度分组
+// @Description	获取当前用户在各已开启功能开关中的分组（control/treatment），客户端据此切换新协议行为
+// @Tags			功能开关
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"开关Key -> 分组"
+// @Router			/features [get]
+func (h *FeatureHandler) GetMyFeatures(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.flags.Cohorts(c.Request.Context(), c.GetString("user_id")),
+	})
+}
+
+// ListFlags 获取功能开关列表
+// @Summary		获取功能开关列表
+// @Tags			功能开关
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"功能开关列表"
+// @Router			/admin/flags [get]
+func (h *FeatureHandler) ListFlags(c *gin.Context) {
+	flags, err := h.flags.ListFlags(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    flags,
+	})
+}
+
+// SetFlag 创建或更新功能开关
+// @Summary		创建或更新功能开关
+// @Description	按 user_id 稳定哈希把 percentage% 的用户分到实验组，allow_users/deny_users 强制指定分组；新建开关时重置分组指标
+// @Tags			功能开关
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			key		path		string						true	"开关Key"
+// @Param			request	body		model.SetFeatureFlagRequest	true	"开关配置"
+// @Success		200		{object}	map[string]interface{}		"功能开关"
+// @Failure		400		{object}	map[string]interface{}		"参数错误"
+// @Router			/admin/flags/{key} [put]
+func (h *FeatureHandler) SetFlag(c *gin.Context) {
+	var req model.SetFeatureFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flag, err := h.flags.SetFlag(c.Request.Context(), c.Param("key"), c.GetString("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    flag,
+	})
+}
+
+// DeleteFlag 删除功能开关
+// @Summary		删除功能开关
+// @Tags			功能开关
+// @Produce		json
+// @Security		BearerAuth
+// @Param			key	path		string					true	"开关Key"
+// @Success		200	{object}	map[string]interface{}	"删除成功"
+// @Failure		404	{object}	map[string]interface{}	"开关不存在"
+// @Router			/admin/flags/{key} [delete]
+func (h *FeatureHandler) DeleteFlag(c *gin.Context) {
+	if err := h.flags.DeleteFlag(c.Request.Context(), c.Param("key")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
+
+// CompareCohorts 对比灰度分组指标
+// @Summary		对比灰度分组指标
+// @Description	对比实验组与对照组的连接数、平均连接时长、上行消息数、处理失败率及平均处理耗时
+// @Tags			功能开关
+// @Produce		json
+// @Security		BearerAuth
+// @Param			key	path		string					true	"开关Key"
+// @Success		200	{object}	map[string]interface{}	"分组指标"
+// @Failure		404	{object}	map[string]interface{}	"开关不存在"
+// @Router			/admin/flags/{key}/metrics [get]
+func (h *FeatureHandler) CompareCohorts(c *gin.Context) {
+	result, err := h.flags.CompareCohorts(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// ResetMetrics 重置灰度分组指标
+// @Summary		重置灰度分组指标
+// @Description	调整实验组比例后重置指标，从当前时间重新对比
+// @Tags			功能开关
+// @Produce		json
+// @Security		BearerAuth
+// @Param			key	path		string					true	"开关Key"
+// @Success		200	{object}	map[string]interface{}	"重置成功"
+// @Router			/admin/flags/{key}/metrics [delete]
+func (h *FeatureHandler) ResetMetrics(c *gin.Context) {
+	if err := h.flags.ResetMetrics(c.Request.Context(), c.Param("key")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
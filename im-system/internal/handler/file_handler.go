@@ -2,6 +2,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,7 +14,9 @@ import (
 
 // FileHandler 文件处理器
 type FileHandler struct {
-	fileService service.FileStorageService
+	fileService      service.FileStorageService
+	retentionService service.FileRetentionService
+	uploadTracker    service.UploadTracker
 }
 
 // NewFileHandler 创建文件处理器
@@ -23,22 +26,58 @@ func NewFileHandler(fileService service.FileStorageService) *FileHandler {
 	}
 }
 
+// SetRetentionService 设置文件保存期限服务（启用文件长期保存接口）
+func (h *FileHandler) SetRetentionService(retentionService service.FileRetentionService) {
+	h.retentionService = retentionService
+}
+
+// SetUploadTracker 设置待关联文件记录：声明用于消息的上传超时未被消息引用时由清理任务回收
+func (h *FileHandler) SetUploadTracker(tracker service.UploadTracker) {
+	h.uploadTracker = tracker
+}
+
+// uploadPurposeMessage 先上传、后随消息发送的文件（?purpose=message），超时未被消息引用时作为孤儿文件回收；
+// 未声明用途的上传（头像、按URL引用的文件等）不回收
+const uploadPurposeMessage = "message"
+
+// trackUpload 声明用于消息的上传记录为待关联文件
+func (h *FileHandler) trackUpload(c *gin.Context, fileID string) {
+	if h.uploadTracker == nil || c.Query("purpose") != uploadPurposeMessage {
+		return
+	}
+	h.uploadTracker.TrackUpload(c.Request.Context(), fileID)
+}
+
 // RegisterRoutes 注册路由
-func (h *FileHandler) RegisterRoutes(r *gin.Engine) {
+func (h *FileHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	// 代理下载通过URL签名鉴权，无需登录
+	r.GET("/api/file/proxy/:file_id", h.ProxyDownload)
+
 	file := r.Group("/api/file")
-	file.Use(AuthMiddleware())
+	file.Use(NewAuthMiddleware(ra.Authenticator))
 	{
-		file.POST("/upload", h.Upload)
+		file.POST("/upload", RateLimit(RateLimitUpload), h.Upload)
 		file.GET("/info/:file_id", h.GetFileInfo)
 		file.GET("/url/:file_id", h.GetFileURL)
 		file.GET("/download/:file_id", h.Download)
 		file.DELETE("/:file_id", h.Delete)
+		if h.retentionService != nil {
+			file.POST("/:file_id/retain", h.Retain)
+			file.DELETE("/:file_id/retain", h.Unretain)
+		}
 
 		// 分片上传
-		file.POST("/multipart/init", h.InitMultipartUpload)
+		file.POST("/multipart/init", RateLimit(RateLimitUpload), h.InitMultipartUpload)
 		file.POST("/multipart/upload", h.UploadPart)
 		file.POST("/multipart/complete", h.CompleteMultipartUpload)
 		file.POST("/multipart/abort", h.AbortMultipartUpload)
+
+		// 断点续传
+		file.POST("/resumable", RateLimit(RateLimitUpload), h.CreateUploadSession)
+		file.PATCH("/resumable/:upload_id", h.WriteUploadSession)
+		file.GET("/resumable/:upload_id", h.GetUploadSession)
+		file.POST("/resumable/:upload_id/complete", h.FinalizeUploadSession)
+		file.DELETE("/resumable/:upload_id", h.AbortUploadSession)
 	}
 }
 
@@ -50,9 +89,15 @@ func (h *FileHandler) RegisterRoutes(r *gin.Engine) {
 // @Produce		json
 // @Security		BearerAuth
 // @Param			file	formData	file					true	"文件"
+// @Param			sha256	formData	string					false	"期望的SHA-256（十六进制），不匹配时拒绝"
+// @Param			md5		formData	string					false	"期望的MD5（十六进制），不匹配时拒绝"
+// @Param			group_id	formData	string					false	"上传到群聊时的群组ID，应用群组文件类型策略"
+// @Param			purpose	query		string					false	"message：用于随后发送的消息，1小时内未被消息引用时作为孤儿文件删除；不传时不回收"
 // @Success		200		{object}	map[string]interface{}	"上传成功"
 // @Failure		400		{object}	map[string]interface{}	"参数错误"
 // @Failure		401		{object}	map[string]interface{}	"未授权"
+// @Failure		415		{object}	map[string]interface{}	"文件类型不允许或内容与扩展名不符"
+// @Failure		422		{object}	map[string]interface{}	"压缩包未通过安全检查"
 // @Failure		500		{object}	map[string]interface{}	"上传失败"
 // @Router			/file/upload [post]
 func (h *FileHandler) Upload(c *gin.Context) {
@@ -81,9 +126,28 @@ func (h *FileHandler) Upload(c *gin.Context) {
 		Header:      header,
 		UserID:      userID,
 		ContentType: contentType,
+		GroupID:     c.PostForm("group_id"),
+
+		ExpectedSHA256: c.PostForm("sha256"),
+		ExpectedMD5:    c.PostForm("md5"),
 	}
 
 	fileInfo, err := h.fileService.Upload(c.Request.Context(), req)
+	if isModerationError(err) {
+		respondError(c, err)
+		return
+	}
+	if isFileTypeError(err) {
+		h.fileTypeError(c, err)
+		return
+	}
+	if errors.Is(err, service.ErrChecksumMismatch) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "文件校验失败: " + err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -92,6 +156,8 @@ func (h *FileHandler) Upload(c *gin.Context) {
 		return
 	}
 
+	h.trackUpload(c, fileInfo.FileID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
@@ -132,29 +198,37 @@ func (h *FileHandler) GetFileInfo(c *gin.Context) {
 
 // GetFileURL 获取文件URL
 // @Summary		获取文件访问URL
-// @Description	获取文件的临时访问URL
+// @Description	获取文件的临时访问URL，过期时间受文件类型策略限制；启用代理下载时返回经网关下载的签名URL
 // @Tags			文件
 // @Accept			json
 // @Produce		json
 // @Security		BearerAuth
 // @Param			file_id	path		string					true	"文件ID"
-// @Param			expiry	query		int						false	"过期时间(秒)"	default(3600)
+// @Param			expiry	query		int						false	"过期时间(秒)，不超过策略上限"
 // @Success		200		{object}	map[string]interface{}	"文件URL"
 // @Failure		401		{object}	map[string]interface{}	"未授权"
 // @Failure		404		{object}	map[string]interface{}	"文件不存在"
+// @Failure		410		{object}	map[string]interface{}	"文件已过期"
 // @Router			/file/url/{file_id} [get]
 func (h *FileHandler) GetFileURL(c *gin.Context) {
 	fileID := c.Param("file_id")
 
-	// 过期时间，默认1小时
-	expiry := time.Hour
+	// 过期时间，默认使用策略配置
+	var expiry time.Duration
 	if expiryStr := c.Query("expiry"); expiryStr != "" {
 		if seconds, err := strconv.Atoi(expiryStr); err == nil {
 			expiry = time.Duration(seconds) * time.Second
 		}
 	}
 
-	url, err := h.fileService.GetFileURL(c.Request.Context(), fileID, expiry)
+	signedURL, err := h.fileService.GetFileURL(c.Request.Context(), fileID, &service.FileURLOptions{
+		Expiry:   expiry,
+		ClientIP: c.ClientIP(),
+	})
+	if errors.Is(err, service.ErrFileExpired) {
+		h.fileExpired(c)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code":    404,
@@ -166,13 +240,46 @@ func (h *FileHandler) GetFileURL(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
-		"data": gin.H{
-			"url":       url,
-			"expire_at": time.Now().Add(expiry).Unix(),
-		},
+		"data":    signedURL,
 	})
 }
 
+// ProxyDownload 通过签名URL代理下载文件
+// @Summary		代理下载文件
+// @Description	校验签名、过期时间、客户端IP和Referer后经网关下载文件
+// @Tags			文件
+// @Produce		octet-stream
+// @Param			file_id	path	string	true	"文件ID"
+// @Param			expires	query	int		true	"过期时间戳"
+// @Param			nonce	query	string	true	"随机串"
+// @Param			sig		query	string	true	"签名"
+// @Success		200		"文件内容"
+// @Failure		403		{object}	map[string]interface{}	"访问被拒绝"
+// @Failure		404		{object}	map[string]interface{}	"文件不存在"
+// @Failure		410		{object}	map[string]interface{}	"文件已过期"
+// @Router			/file/proxy/{file_id} [get]
+func (h *FileHandler) ProxyDownload(c *gin.Context) {
+	fileID := c.Param("file_id")
+	expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+
+	err := h.fileService.VerifyFileURL(c.Request.Context(), fileID, &service.FileURLVerifyRequest{
+		Expires:   expires,
+		Nonce:     c.Query("nonce"),
+		Signature: c.Query("sig"),
+		ClientIP:  c.ClientIP(),
+		Referer:   c.GetHeader("Referer"),
+	})
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "访问被拒绝: " + err.Error(),
+		})
+		return
+	}
+
+	h.Download(c)
+}
+
 // Download 下载文件
 // @Summary		下载文件
 // @Description	下载指定文件
@@ -184,11 +291,16 @@ func (h *FileHandler) GetFileURL(c *gin.Context) {
 // @Success		200		"文件内容"
 // @Failure		401		{object}	map[string]interface{}	"未授权"
 // @Failure		404		{object}	map[string]interface{}	"文件不存在"
+// @Failure		410		{object}	map[string]interface{}	"文件已过期"
 // @Router			/file/download/{file_id} [get]
 func (h *FileHandler) Download(c *gin.Context) {
 	fileID := c.Param("file_id")
 
 	reader, fileInfo, err := h.fileService.Download(c.Request.Context(), fileID)
+	if errors.Is(err, service.ErrFileExpired) {
+		h.fileExpired(c)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code":    404,
@@ -236,6 +348,72 @@ func (h *FileHandler) Delete(c *gin.Context) {
 	})
 }
 
+// Retain 设置文件长期保存
+// @Summary		文件长期保存
+// @Description	将文件设置为长期保存，不受聊天文件保存期限影响（收藏、群文件等保存操作使用），只有上传者可以设置
+// @Tags			文件
+// @Produce		json
+// @Security		BearerAuth
+// @Param			file_id	path		string					true	"文件ID"
+// @Success		200		{object}	map[string]interface{}	"设置成功"
+// @Failure		401		{object}	map[string]interface{}	"未授权"
+// @Failure		403		{object}	map[string]interface{}	"无权限"
+// @Failure		404		{object}	map[string]interface{}	"文件不存在"
+// @Failure		410		{object}	map[string]interface{}	"文件已过期"
+// @Router			/file/{file_id}/retain [post]
+func (h *FileHandler) Retain(c *gin.Context) {
+	h.setRetained(c, true)
+}
+
+// Unretain 取消文件长期保存
+// @Summary		取消文件长期保存
+// @Description	取消长期保存，文件重新按聊天文件保存期限过期
+// @Tags			文件
+// @Produce		json
+// @Security		BearerAuth
+// @Param			file_id	path		string					true	"文件ID"
+// @Success		200		{object}	map[string]interface{}	"设置成功"
+// @Failure		401		{object}	map[string]interface{}	"未授权"
+// @Failure		403		{object}	map[string]interface{}	"无权限"
+// @Failure		404		{object}	map[string]interface{}	"文件不存在"
+// @Failure		410		{object}	map[string]interface{}	"文件已过期"
+// @Router			/file/{file_id}/retain [delete]
+func (h *FileHandler) Unretain(c *gin.Context) {
+	h.setRetained(c, false)
+}
+
+// setRetained 设置文件是否长期保存
+func (h *FileHandler) setRetained(c *gin.Context, retained bool) {
+	userID := c.GetString("user_id")
+	fileID := c.Param("file_id")
+
+	err := h.retentionService.SetRetained(c.Request.Context(), fileID, userID, retained)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "success",
+		})
+	case errors.Is(err, service.ErrFileExpired):
+		h.fileExpired(c)
+	case errors.Is(err, service.ErrFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    404,
+			"message": "文件不存在",
+		})
+	case errors.Is(err, service.ErrPermissionDeny):
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": "只有上传者可以设置",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": "设置失败: " + err.Error(),
+		})
+	}
+}
+
 // InitMultipartUpload 初始化分片上传
 // @Summary		初始化分片上传
 // @Description	初始化大文件分片上传
@@ -247,6 +425,7 @@ func (h *FileHandler) Delete(c *gin.Context) {
 // @Success		200		{object}	map[string]interface{}				"初始化成功"
 // @Failure		400		{object}	map[string]interface{}				"参数错误"
 // @Failure		401		{object}	map[string]interface{}				"未授权"
+// @Failure		415		{object}	map[string]interface{}				"文件类型不允许"
 // @Failure		500		{object}	map[string]interface{}				"初始化失败"
 // @Router			/file/multipart/init [post]
 func (h *FileHandler) InitMultipartUpload(c *gin.Context) {
@@ -262,6 +441,14 @@ func (h *FileHandler) InitMultipartUpload(c *gin.Context) {
 	}
 
 	resp, err := h.fileService.InitMultipartUpload(c.Request.Context(), &req, userID)
+	if isModerationError(err) {
+		respondError(c, err)
+		return
+	}
+	if isFileTypeError(err) {
+		h.fileTypeError(c, err)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -339,6 +526,12 @@ func (h *FileHandler) UploadPart(c *gin.Context) {
 	})
 }
 
+// completeMultipartRequest 完成分片上传请求
+type completeMultipartRequest struct {
+	UploadID string            `json:"upload_id" binding:"required"`
+	Parts    []*model.PartInfo `json:"parts" binding:"required"`
+}
+
 // CompleteMultipartUpload 完成分片上传
 // @Summary		完成分片上传
 // @Description	完成分片上传，合并文件
@@ -347,16 +540,16 @@ func (h *FileHandler) UploadPart(c *gin.Context) {
 // @Produce		json
 // @Security		BearerAuth
 // @Param			request	body		object{upload_id=string,parts=[]object}	true	"分片信息"
+// @Param			purpose	query		string									false	"message：用于随后发送的消息，1小时内未被消息引用时作为孤儿文件删除；不传时不回收"
 // @Success		200		{object}	map[string]interface{}					"完成成功"
 // @Failure		400		{object}	map[string]interface{}					"参数错误"
 // @Failure		401		{object}	map[string]interface{}					"未授权"
+// @Failure		415		{object}	map[string]interface{}					"文件类型不允许或内容与扩展名不符"
+// @Failure		422		{object}	map[string]interface{}					"压缩包未通过安全检查"
 // @Failure		500		{object}	map[string]interface{}					"完成失败"
 // @Router			/file/multipart/complete [post]
 func (h *FileHandler) CompleteMultipartUpload(c *gin.Context) {
-	var req struct {
-		UploadID string            `json:"upload_id" binding:"required"`
-		Parts    []*model.PartInfo `json:"parts" binding:"required"`
-	}
+	var req completeMultipartRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -367,6 +560,17 @@ func (h *FileHandler) CompleteMultipartUpload(c *gin.Context) {
 	}
 
 	fileInfo, err := h.fileService.CompleteMultipartUpload(c.Request.Context(), req.UploadID, req.Parts)
+	if isFileTypeError(err) {
+		h.fileTypeError(c, err)
+		return
+	}
+	if errors.Is(err, service.ErrChecksumMismatch) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "文件校验失败: " + err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -375,6 +579,8 @@ func (h *FileHandler) CompleteMultipartUpload(c *gin.Context) {
 		return
 	}
 
+	h.trackUpload(c, fileInfo.FileID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
@@ -382,6 +588,11 @@ func (h *FileHandler) CompleteMultipartUpload(c *gin.Context) {
 	})
 }
 
+// abortMultipartRequest 取消分片上传请求
+type abortMultipartRequest struct {
+	UploadID string `json:"upload_id" binding:"required"`
+}
+
 // AbortMultipartUpload 取消分片上传
 // @Summary		取消分片上传
 // @Description	取消分片上传，清理已上传分片
@@ -396,9 +607,7 @@ func (h *FileHandler) CompleteMultipartUpload(c *gin.Context) {
 // @Failure		500		{object}	map[string]interface{}		"取消失败"
 // @Router			/file/multipart/abort [post]
 func (h *FileHandler) AbortMultipartUpload(c *gin.Context) {
-	var req struct {
-		UploadID string `json:"upload_id" binding:"required"`
-	}
+	var req abortMultipartRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -421,3 +630,239 @@ func (h *FileHandler) AbortMultipartUpload(c *gin.Context) {
 		"message": "success",
 	})
 }
+
+// CreateUploadSession 创建断点续传会话
+// @Summary		创建断点续传会话
+// @Description	创建可恢复的上传会话，之后通过PATCH按偏移量追加数据
+// @Tags			文件
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		model.CreateUploadSessionRequest	true	"上传信息"
+// @Success		200		{object}	map[string]interface{}				"会话信息"
+// @Failure		400		{object}	map[string]interface{}				"参数错误"
+// @Failure		401		{object}	map[string]interface{}				"未授权"
+// @Failure		415		{object}	map[string]interface{}				"文件类型不允许"
+// @Failure		500		{object}	map[string]interface{}				"创建失败"
+// @Router			/file/resumable [post]
+func (h *FileHandler) CreateUploadSession(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req model.CreateUploadSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	session, err := h.fileService.CreateUploadSession(c.Request.Context(), &req, userID)
+	if isModerationError(err) {
+		respondError(c, err)
+		return
+	}
+	if isFileTypeError(err) {
+		h.fileTypeError(c, err)
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrFileTooLarge) || errors.Is(err, service.ErrUploadChunkTooLarge) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"code":    status,
+			"message": "创建上传会话失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    session,
+	})
+}
+
+// WriteUploadSession 追加上传数据
+// @Summary		追加上传数据
+// @Description	在Upload-Offset指定的偏移量写入请求体数据，偏移量必须等于已接收字节数
+// @Tags			文件
+// @Accept			application/offset+octet-stream
+// @Produce		json
+// @Security		BearerAuth
+// @Param			upload_id		path		string					true	"上传ID"
+// @Param			Upload-Offset	header		int						true	"写入偏移量"
+// @Success		200				{object}	map[string]interface{}	"上传进度"
+// @Failure		400				{object}	map[string]interface{}	"参数错误"
+// @Failure		404				{object}	map[string]interface{}	"会话不存在"
+// @Failure		409				{object}	map[string]interface{}	"偏移量不匹配或会话正在写入"
+// @Router			/file/resumable/{upload_id} [patch]
+func (h *FileHandler) WriteUploadSession(c *gin.Context) {
+	userID := c.GetString("user_id")
+	uploadID := c.Param("upload_id")
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "参数错误: invalid Upload-Offset",
+		})
+		return
+	}
+
+	session, err := h.fileService.WriteUploadSession(c.Request.Context(), uploadID, userID, offset, c.Request.Body)
+	if err != nil {
+		h.uploadSessionError(c, err)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    session,
+	})
+}
+
+// GetUploadSession 查询上传进度
+// @Summary		查询上传进度
+// @Description	查询断点续传会话的已接收字节数，用于中断后恢复
+// @Tags			文件
+// @Produce		json
+// @Security		BearerAuth
+// @Param			upload_id	path		string					true	"上传ID"
+// @Success		200			{object}	map[string]interface{}	"上传进度"
+// @Failure		404			{object}	map[string]interface{}	"会话不存在"
+// @Router			/file/resumable/{upload_id} [get]
+func (h *FileHandler) GetUploadSession(c *gin.Context) {
+	userID := c.GetString("user_id")
+	uploadID := c.Param("upload_id")
+
+	session, err := h.fileService.GetUploadSession(c.Request.Context(), uploadID, userID)
+	if err != nil {
+		h.uploadSessionError(c, err)
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    session,
+	})
+}
+
+// FinalizeUploadSession 完成断点续传
+// @Summary		完成断点续传
+// @Description	全部数据接收后合并分片、校验摘要并创建文件记录
+// @Tags			文件
+// @Produce		json
+// @Security		BearerAuth
+// @Param			upload_id	path		string					true	"上传ID"
+// @Param			purpose		query		string					false	"message：用于随后发送的消息，1小时内未被消息引用时作为孤儿文件删除；不传时不回收"
+// @Success		200			{object}	map[string]interface{}	"文件信息"
+// @Failure		400			{object}	map[string]interface{}	"数据不完整或校验失败"
+// @Failure		404			{object}	map[string]interface{}	"会话不存在"
+// @Failure		415			{object}	map[string]interface{}	"文件类型不允许或内容与扩展名不符"
+// @Failure		422			{object}	map[string]interface{}	"压缩包未通过安全检查"
+// @Router			/file/resumable/{upload_id}/complete [post]
+func (h *FileHandler) FinalizeUploadSession(c *gin.Context) {
+	userID := c.GetString("user_id")
+	uploadID := c.Param("upload_id")
+
+	fileInfo, err := h.fileService.FinalizeUploadSession(c.Request.Context(), uploadID, userID)
+	if isFileTypeError(err) {
+		h.fileTypeError(c, err)
+		return
+	}
+	if err != nil {
+		h.uploadSessionError(c, err)
+		return
+	}
+
+	h.trackUpload(c, fileInfo.FileID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    fileInfo,
+	})
+}
+
+// AbortUploadSession 取消断点续传
+// @Summary		取消断点续传
+// @Description	取消上传会话并清理已上传数据
+// @Tags			文件
+// @Produce		json
+// @Security		BearerAuth
+// @Param			upload_id	path		string					true	"上传ID"
+// @Success		200			{object}	map[string]interface{}	"取消成功"
+// @Failure		404			{object}	map[string]interface{}	"会话不存在"
+// @Router			/file/resumable/{upload_id} [delete]
+func (h *FileHandler) AbortUploadSession(c *gin.Context) {
+	userID := c.GetString("user_id")
+	uploadID := c.Param("upload_id")
+
+	if err := h.fileService.AbortUploadSession(c.Request.Context(), uploadID, userID); err != nil {
+		h.uploadSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// uploadSessionError 返回断点续传错误响应
+func (h *FileHandler) uploadSessionError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrUploadSessionNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrUploadOffsetMismatch), errors.Is(err, service.ErrUploadSessionBusy):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrUploadSizeExceeded), errors.Is(err, service.ErrMultipartIncomplete),
+		errors.Is(err, service.ErrChecksumMismatch):
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": "断点续传失败: " + err.Error(),
+	})
+}
+
+// isFileTypeError 是否为文件类型策略或压缩包安全检查错误
+func isFileTypeError(err error) bool {
+	return errors.Is(err, service.ErrInvalidFileType) || errors.Is(err, service.ErrFileContentMismatch) ||
+		errors.Is(err, service.ErrSuspiciousArchive)
+}
+
+// isModerationError 是否为内容审核拒绝或审核服务不可用
+func isModerationError(err error) bool {
+	return errors.Is(err, service.ErrContentRejected) || errors.Is(err, service.ErrModerationUnavailable)
+}
+
+// fileTypeError 返回文件类型策略错误响应（可疑压缩包返回422）
+func (h *FileHandler) fileTypeError(c *gin.Context, err error) {
+	status := http.StatusUnsupportedMediaType
+	if errors.Is(err, service.ErrSuspiciousArchive) {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, gin.H{
+		"code":    status,
+		"message": "文件类型校验失败: " + err.Error(),
+	})
+}
+
+// fileExpired 返回文件已过期响应
+func (h *FileHandler) fileExpired(c *gin.Context) {
+	c.JSON(http.StatusGone, gin.H{
+		"code":    410,
+		"message": "文件已过期",
+		"expired": true,
+	})
+}
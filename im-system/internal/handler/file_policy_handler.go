@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// FilePolicyHandler 文件类型策略处理器
+type FilePolicyHandler struct {
+	policy service.FileTypePolicyService
+}
+
+// NewFilePolicyHandler 创建文件类型策略处理器
+func NewFilePolicyHandler(policy service.FileTypePolicyService) *FilePolicyHandler {
+	return &FilePolicyHandler{policy: policy}
+}
+
+// RegisterRoutes 注册路由
+func (h *FilePolicyHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	group := r.Group("/api/groups/:group_id/file-policy")
+	group.Use(NewAuthMiddleware(ra.Authenticator))
+	{
+		group.GET("", h.GetGroupPolicy)
+		group.PUT("", h.SetGroupPolicy)
+		group.DELETE("", h.DeleteGroupPolicy)
+	}
+
+	admin := r.Group("/api/admin/files/policy")
+	admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+	{
+		admin.GET("", h.GetPolicy)
+		admin.PUT("", h.SetPolicy)
+	}
+}
+
+// GetPolicy 获取全局文件类型策略
+// @Summary		获取全局文件类型策略
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"全局策略"
+// @Router			/admin/files/policy [get]
+func (h *FilePolicyHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.policy.GetPolicy(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    policy,
+	})
+}
+
+// SetPolicy 设置全局文件类型策略
+// @Summary		设置全局文件类型策略
+// @Description	运行时修改允许上传的扩展名和文件大类，立即对所有节点生效（本地缓存数秒）；extensions为空表示不限制扩展名
+// @Tags			管理
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		model.SetFileTypePolicyRequest	true	"策略"
+// @Success		200		{object}	map[string]interface{}			"更新后的策略"
+// @Failure		400		{object}	map[string]interface{}			"参数错误"
+// @Router			/admin/files/policy [put]
+func (h *FilePolicyHandler) SetPolicy(c *gin.Context) {
+	var req model.SetFileTypePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.policy.SetPolicy(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    policy,
+	})
+}
+
+// GetGroupPolicy 获取群组文件类型策略
+// @Summary		获取群组文件类型策略
+// @Description	返回全局策略和群组策略（未设置时为null），上传到该群的文件需同时满足两者
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string					true	"群组ID"
+// @Success		200			{object}	map[string]interface{}	"策略"
+// @Failure		403			{object}	map[string]interface{}	"不是群成员"
+// @Router			/groups/{group_id}/file-policy [get]
+func (h *FilePolicyHandler) GetGroupPolicy(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	groupPolicy, err := h.policy.GetGroupPolicy(ctx, c.Param("group_id"), c.GetString("user_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	global, err := h.policy.GetPolicy(ctx)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"global": global,
+			"group":  groupPolicy,
+		},
+	})
+}
+
+// SetGroupPolicy 设置群组文件类型策略
+// @Summary		设置群组文件类型策略
+// @Description	群主或管理员设置更严格的群组策略（如仅允许图片、禁止可执行文件），只能在全局策略基础上收紧
+// @Tags			群组
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string							true	"群组ID"
+// @Param			request		body		model.SetFileTypePolicyRequest	true	"策略"
+// @Success		200			{object}	map[string]interface{}			"更新后的策略"
+// @Failure		400			{object}	map[string]interface{}			"参数错误"
+// @Failure		403			{object}	map[string]interface{}			"不是群管理员"
+// @Router			/groups/{group_id}/file-policy [put]
+func (h *FilePolicyHandler) SetGroupPolicy(c *gin.Context) {
+	var req model.SetFileTypePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.policy.SetGroupPolicy(c.Request.Context(), c.Param("group_id"), c.GetString("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    policy,
+	})
+}
+
+// DeleteGroupPolicy 删除群组文件类型策略
+// @Summary		删除群组文件类型策略
+// @Description	删除后该群上传仅受全局策略约束
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string					true	"群组ID"
+// @Success		200			{object}	map[string]interface{}	"删除成功"
+// @Failure		403			{object}	map[string]interface{}	"不是群管理员"
+// @Router			/groups/{group_id}/file-policy [delete]
+func (h *FilePolicyHandler) DeleteGroupPolicy(c *gin.Context) {
+	if err := h.policy.DeleteGroupPolicy(c.Request.Context(), c.Param("group_id"), c.GetString("user_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// FriendHandler 好友处理器
+type FriendHandler struct {
+	friendService service.FriendService
+}
+
+// NewFriendHandler 创建好友处理器
+func NewFriendHandler(friendService service.FriendService) *FriendHandler {
+	return &FriendHandler{
+		friendService: friendService,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *FriendHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	friends := r.Group("/api/friends")
+	friends.Use(NewAuthMiddleware(ra.Authenticator))
+	{
+		friends.GET("", h.ListFriends)
+		friends.DELETE("/:user_id", h.RemoveFriend)
+
+		friends.POST("/requests", h.SendRequest)
+		friends.GET("/requests", h.ListRequests)
+		friends.POST("/requests/:request_id/accept", h.AcceptRequest)
+		friends.POST("/requests/:request_id/reject", h.RejectRequest)
+
+		friends.GET("/blocked", h.ListBlocked)
+		friends.PUT("/blocked/:user_id", h.Block)
+		friends.DELETE("/blocked/:user_id", h.Unblock)
+	}
+}
+
+// ListFriends 获取好友列表
+// @Summary		获取好友列表
+// @Description	获取当前用户的好友（按添加时间倒序）
+// @Tags			好友
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"好友列表"
+// @Failure		401	{object}	map[string]interface{}	"未授权"
+// @Router			/friends [get]
+func (h *FriendHandler) ListFriends(c *gin.Context) {
+	friends, err := h.friendService.ListFriends(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    friends,
+	})
+}
+
+// RemoveFriend 删除好友
+// @Summary		删除好友
+// @Description	双向解除好友关系，不通知对方
+// @Tags			好友
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string					true	"好友用户ID"
+// @Success		200		{object}	map[string]interface{}	"删除成功"
+// @Failure		404		{object}	map[string]interface{}	"不是好友"
+// @Router			/friends/{user_id} [delete]
+func (h *FriendHandler) RemoveFriend(c *gin.Context) {
+	if err := h.friendService.RemoveFriend(c.Request.Context(), c.GetString("user_id"), c.Param("user_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// SendRequest 发送好友申请
+// @Summary		发送好友申请
+// @Description	向用户发送好友申请，对方收到 type 102 通知；对方已向自己发出待处理的申请时直接成为好友
+// @Tags			好友
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		service.SendFriendRequestRequest	true	"申请"
+// @Success		200		{object}	map[string]interface{}				"申请"
+// @Failure		403		{object}	map[string]interface{}				"被对方屏蔽"
+// @Failure		409		{object}	map[string]interface{}				"已是好友"
+// @Router			/friends/requests [post]
+func (h *FriendHandler) SendRequest(c *gin.Context) {
+	var req service.SendFriendRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := h.friendService.SendRequest(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    request,
+	})
+}
+
+// ListRequests 获取好友申请
+// @Summary		获取好友申请
+// @Description	分页获取收到或发出的好友申请（按更新时间倒序）
+// @Tags			好友
+// @Produce		json
+// @Security		BearerAuth
+// @Param			direction	query		string					false	"incoming（收到）或 outgoing（发出）"	default(incoming)
+// @Param			page		query		int						false	"页码"								default(1)
+// @Param			page_size	query		int						false	"每页数量"							default(20)
+// @Success		200			{object}	map[string]interface{}	"申请列表"
+// @Router			/friends/requests [get]
+func (h *FriendHandler) ListRequests(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	outgoing := c.Query("direction") == "outgoing"
+
+	requests, total, err := h.friendService.ListRequests(c.Request.Context(), c.GetString("user_id"), outgoing, page, pageSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":    total,
+			"requests": requests,
+		},
+	})
+}
+
+// AcceptRequest 通过好友申请
+// @Summary		通过好友申请
+// @Description	通过发给自己的好友申请，申请人收到 type 103 通知
+// @Tags			好友
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request_id	path		string					true	"申请ID"
+// @Success		200			{object}	map[string]interface{}	"已通过"
+// @Failure		404			{object}	map[string]interface{}	"申请不存在"
+// @Failure		409			{object}	map[string]interface{}	"申请已处理"
+// @Router			/friends/requests/{request_id}/accept [post]
+func (h *FriendHandler) AcceptRequest(c *gin.Context) {
+	request, err := h.friendService.AcceptRequest(c.Request.Context(), c.GetString("user_id"), c.Param("request_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    request,
+	})
+}
+
+// RejectRequest 拒绝好友申请
+// @Summary		拒绝好友申请
+// @Description	拒绝发给自己的好友申请，不通知申请人
+// @Tags			好友
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request_id	path		string					true	"申请ID"
+// @Success		200			{object}	map[string]interface{}	"已拒绝"
+// @Failure		404			{object}	map[string]interface{}	"申请不存在"
+// @Failure		409			{object}	map[string]interface{}	"申请已处理"
+// @Router			/friends/requests/{request_id}/reject [post]
+func (h *FriendHandler) RejectRequest(c *gin.Context) {
+	if err := h.friendService.RejectRequest(c.Request.Context(), c.GetString("user_id"), c.Param("request_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// ListBlocked 获取屏蔽列表
+// @Summary		获取屏蔽列表
+// @Description	获取当前用户屏蔽的用户（按屏蔽时间倒序）
+// @Tags			好友
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"屏蔽列表"
+// @Router			/friends/blocked [get]
+func (h *FriendHandler) ListBlocked(c *gin.Context) {
+	blocked, err := h.friendService.ListBlocked(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    blocked,
+	})
+}
+
+// Block 屏蔽用户
+// @Summary		屏蔽用户
+// @Description	对方不能再发送好友申请和单聊消息，对方待处理的申请被拒绝；不解除好友关系
+// @Tags			好友
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string					true	"用户ID"
+// @Success		200		{object}	map[string]interface{}	"屏蔽成功"
+// @Failure		404		{object}	map[string]interface{}	"用户不存在"
+// @Router			/friends/blocked/{user_id} [put]
+func (h *FriendHandler) Block(c *gin.Context) {
+	if err := h.friendService.Block(c.Request.Context(), c.GetString("user_id"), c.Param("user_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// Unblock 取消屏蔽
+// @Summary		取消屏蔽
+// @Description	取消对用户的屏蔽
+// @Tags			好友
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string					true	"用户ID"
+// @Success		200		{object}	map[string]interface{}	"已取消"
+// @Router			/friends/blocked/{user_id} [delete]
+func (h *FriendHandler) Unblock(c *gin.Context) {
+	if err := h.friendService.Unblock(c.Request.Context(), c.GetString("user_id"), c.Param("user_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
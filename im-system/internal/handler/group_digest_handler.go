@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// GroupDigestHandler 群活动摘要处理器
+type GroupDigestHandler struct {
+	digestService service.GroupDigestService
+}
+
+// NewGroupDigestHandler 创建群活动摘要处理器
+func NewGroupDigestHandler(digestService service.GroupDigestService) *GroupDigestHandler {
+	return &GroupDigestHandler{digestService: digestService}
+}
+
+// RegisterRoutes 注册路由
+func (h *GroupDigestHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	r.GET("/api/groups/digests", NewAuthMiddleware(ra.Authenticator), h.ListDigests)
+
+	digest := r.Group("/api/groups/:group_id/digest")
+	digest.Use(NewAuthMiddleware(ra.Authenticator))
+	{
+		digest.GET("", h.GetDigest)
+		digest.PUT("", h.Subscribe)
+		digest.DELETE("", h.Unsubscribe)
+	}
+}
+
+// ListDigests 获取群活动摘要订阅列表
+// @Summary		获取群活动摘要订阅列表
+// @Description	获取当前用户订阅了每日活动摘要的全部群
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"订阅列表"
+// @Router			/groups/digests [get]
+func (h *GroupDigestHandler) ListDigests(c *gin.Context) {
+	subs, err := h.digestService.ListSubscriptions(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    subs,
+	})
+}
+
+// GetDigest 获取群活动摘要订阅
+// @Summary		获取群活动摘要订阅
+// @Description	获取当前用户在群内的每日活动摘要订阅
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string					true	"群组ID"
+// @Success		200			{object}	map[string]interface{}	"订阅信息"
+// @Failure		404			{object}	map[string]interface{}	"未订阅"
+// @Router			/groups/{group_id}/digest [get]
+func (h *GroupDigestHandler) GetDigest(c *gin.Context) {
+	sub, err := h.digestService.GetSubscription(c.Request.Context(), c.GetString("user_id"), c.Param("group_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    sub,
+	})
+}
+
+// Subscribe 订阅群活动每日摘要
+// @Summary		订阅群活动每日摘要
+// @Description	每天在指定时间（所选时区）收到一条系统消息（type=111），汇总过去一天的消息数、@我的消息和新置顶的消息，可选同时推送；已订阅时修改设置
+// @Tags			群组
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string						true	"群组ID"
+// @Param			request		body		service.GroupDigestRequest	true	"发送时间（HH:MM）、时区及是否推送"
+// @Success		200			{object}	map[string]interface{}		"订阅信息"
+// @Failure		400			{object}	map[string]interface{}		"发送时间或时区无效"
+// @Failure		403			{object}	map[string]interface{}		"不是群成员"
+// @Router			/groups/{group_id}/digest [put]
+func (h *GroupDigestHandler) Subscribe(c *gin.Context) {
+	var req service.GroupDigestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, err := h.digestService.Subscribe(c.Request.Context(), c.GetString("user_id"), c.Param("group_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    sub,
+	})
+}
+
+// Unsubscribe 取消群活动摘要订阅
+// @Summary		取消群活动摘要订阅
+// @Description	取消当前用户在群内的每日活动摘要
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string					true	"群组ID"
+// @Success		200			{object}	map[string]interface{}	"取消成功"
+// @Failure		404			{object}	map[string]interface{}	"未订阅"
+// @Router			/groups/{group_id}/digest [delete]
+func (h *GroupDigestHandler) Unsubscribe(c *gin.Context) {
+	if err := h.digestService.Unsubscribe(c.Request.Context(), c.GetString("user_id"), c.Param("group_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
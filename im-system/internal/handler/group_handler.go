@@ -25,9 +25,9 @@ func NewGroupHandler(groupService service.GroupService) *GroupHandler {
 }
 
 // RegisterRoutes 注册路由
-func (h *GroupHandler) RegisterRoutes(r *gin.Engine) {
+func (h *GroupHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
 	group := r.Group("/api/groups")
-	group.Use(AuthMiddleware())
+	group.Use(NewAuthMiddleware(ra.Authenticator))
 	{
 		group.POST("", h.CreateGroup)
 		group.GET("/:group_id", h.GetGroupInfo)
@@ -39,6 +39,10 @@ func (h *GroupHandler) RegisterRoutes(r *gin.Engine) {
 		group.POST("/:group_id/kick", h.KickMember)
 		group.GET("/:group_id/members", h.GetGroupMembers)
 
+		group.GET("/:group_id/join-requests", h.ListJoinRequests)
+		group.POST("/:group_id/join-requests/:request_id/approve", h.ApproveJoinRequest)
+		group.POST("/:group_id/join-requests/:request_id/reject", h.RejectJoinRequest)
+
 		group.POST("/:group_id/admin", h.SetAdmin)
 		group.POST("/:group_id/transfer", h.TransferOwner)
 		group.POST("/:group_id/mute", h.MuteMember)
@@ -46,7 +50,18 @@ func (h *GroupHandler) RegisterRoutes(r *gin.Engine) {
 	}
 
 	// 用户相关群组接口
-	r.GET("/api/groups/my", AuthMiddleware(), h.GetUserGroups) //
+	r.GET("/api/groups/my", NewAuthMiddleware(ra.Authenticator), h.GetUserGroups) //
+}
+
+// createGroupRequest 创建群组请求
+type createGroupRequest struct {
+	Name        string   `json:"name" binding:"required,max=128"`
+	Avatar      string   `json:"avatar"`
+	Description string   `json:"description" binding:"max=512"`
+	MemberIDs   []string `json:"member_ids"`
+
+	ExpiresAt      *time.Time `json:"expires_at"`       // 临时群到期时间（RFC3339），到期自动解散
+	ExportOnExpiry bool       `json:"export_on_expiry"` // 到期解散前为群主导出聊天记录
 }
 
 // CreateGroup 创建群组
@@ -65,12 +80,7 @@ func (h *GroupHandler) RegisterRoutes(r *gin.Engine) {
 func (h *GroupHandler) CreateGroup(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	var req struct {
-		Name        string   `json:"name" binding:"required,max=128"`
-		Avatar      string   `json:"avatar"`
-		Description string   `json:"description" binding:"max=512"`
-		MemberIDs   []string `json:"member_ids"`
-	}
+	var req createGroupRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -83,11 +93,14 @@ func (h *GroupHandler) CreateGroup(c *gin.Context) {
 		Avatar:      req.Avatar,
 		Description: req.Description,
 		MemberIDs:   req.MemberIDs,
+
+		ExpiresAt:      req.ExpiresAt,
+		ExportOnExpiry: req.ExportOnExpiry,
 	}
 
 	group, err := h.groupService.CreateGroup(c.Request.Context(), createReq)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -115,11 +128,7 @@ func (h *GroupHandler) GetGroupInfo(c *gin.Context) {
 
 	group, err := h.groupService.GetGroupInfo(c.Request.Context(), groupID)
 	if err != nil {
-		if err == service.ErrGroupNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "group not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -130,9 +139,22 @@ func (h *GroupHandler) GetGroupInfo(c *gin.Context) {
 	})
 }
 
+// updateGroupRequest 更新群组信息请求（字段为空表示不修改）
+type updateGroupRequest struct {
+	Name         *string `json:"name"`
+	Avatar       *string `json:"avatar"`
+	Announcement *string `json:"announcement"`
+	Description  *string `json:"description"`
+	JoinMode     *int    `json:"join_mode"`
+
+	ExpiresAt      *time.Time `json:"expires_at"`       // 设置或修改临时群到期时间
+	ClearExpiry    bool       `json:"clear_expiry"`     // 取消到期（仅群主）
+	ExportOnExpiry *bool      `json:"export_on_expiry"` // 到期解散前为群主导出聊天记录（仅群主）
+}
+
 // UpdateGroupInfo 更新群信息
 // @Summary		更新群组信息
-// @Description	更新群组的名称、头像、公告等信息
+// @Description	更新群组的名称、头像、公告等信息；群主或管理员可设置临时群到期时间，取消到期和导出设置仅群主可修改
 // @Tags			群组
 // @Accept			json
 // @Produce		json
@@ -148,13 +170,7 @@ func (h *GroupHandler) UpdateGroupInfo(c *gin.Context) {
 	userID := c.GetString("user_id")
 	groupID := c.Param("group_id")
 
-	var req struct {
-		Name         *string `json:"name"`
-		Avatar       *string `json:"avatar"`
-		Announcement *string `json:"announcement"`
-		Description  *string `json:"description"`
-		JoinMode     *int    `json:"join_mode"`
-	}
+	var req updateGroupRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -169,10 +185,14 @@ func (h *GroupHandler) UpdateGroupInfo(c *gin.Context) {
 		Announcement: req.Announcement,
 		Description:  req.Description,
 		JoinMode:     req.JoinMode,
+
+		ExpiresAt:      req.ExpiresAt,
+		ClearExpiry:    req.ClearExpiry,
+		ExportOnExpiry: req.ExportOnExpiry,
 	}
 
 	if err := h.groupService.UpdateGroupInfo(c.Request.Context(), updateReq); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -200,7 +220,7 @@ func (h *GroupHandler) DismissGroup(c *gin.Context) {
 	groupID := c.Param("group_id")
 
 	if err := h.groupService.DismissGroup(c.Request.Context(), groupID, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -228,21 +248,21 @@ func (h *GroupHandler) JoinGroup(c *gin.Context) {
 	groupID := c.Param("group_id")
 
 	if err := h.groupService.JoinGroup(c.Request.Context(), groupID, userID, ""); err != nil {
-		if err == service.ErrAlreadyInGroup {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "already in group"})
-			return
-		}
-		if err == service.ErrGroupFull {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "group is full"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
+		return
+	}
+
+	// 需审批的群只创建了入群申请，pending 为 true
+	isMember, err := h.groupService.IsMember(c.Request.Context(), groupID, userID)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
+		"data":    gin.H{"pending": !isMember},
 	})
 }
 
@@ -264,7 +284,7 @@ func (h *GroupHandler) LeaveGroup(c *gin.Context) {
 	groupID := c.Param("group_id")
 
 	if err := h.groupService.LeaveGroup(c.Request.Context(), groupID, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -274,6 +294,11 @@ func (h *GroupHandler) LeaveGroup(c *gin.Context) {
 	})
 }
 
+// kickMemberRequest 踢出群成员请求
+type kickMemberRequest struct {
+	TargetIDs []string `json:"target_ids" binding:"required"`
+}
+
 // KickMember 踢出成员
 // @Summary		踢出群成员
 // @Description	将指定成员踢出群组
@@ -287,14 +312,13 @@ func (h *GroupHandler) LeaveGroup(c *gin.Context) {
 // @Failure		401			{object}	map[string]interface{}	"未授权"
 // @Failure		403			{object}	map[string]interface{}	"无权限"
 // @Failure		404			{object}	map[string]interface{}	"用户不在群中"
+// @Failure		409			{object}	map[string]interface{}	"群组已被并发修改，请刷新后重试"
 // @Router			/groups/{group_id}/members/{user_id} [delete]
 func (h *GroupHandler) KickMember(c *gin.Context) {
 	userID := c.GetString("user_id")
 	groupID := c.Param("group_id")
 
-	var req struct {
-		TargetIDs []string `json:"target_ids" binding:"required"`
-	}
+	var req kickMemberRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -302,7 +326,7 @@ func (h *GroupHandler) KickMember(c *gin.Context) {
 	}
 
 	if err := h.groupService.KickMember(c.Request.Context(), groupID, userID, req.TargetIDs); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -338,7 +362,7 @@ func (h *GroupHandler) GetGroupMembers(c *gin.Context) {
 
 	members, total, err := h.groupService.GetGroupMembers(c.Request.Context(), groupID, page, pageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -352,15 +376,121 @@ func (h *GroupHandler) GetGroupMembers(c *gin.Context) {
 	})
 }
 
+// ListJoinRequests 获取入群申请列表
+// @Summary		获取入群申请列表
+// @Description	群主或管理员查询入群申请，按申请时间倒序；status 为 0 待处理、1 已同意、2 已拒绝，不传时查询全部
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string					true	"群组ID"
+// @Param			status		query		int						false	"申请状态"
+// @Param			page		query		int						false	"页码"
+// @Param			page_size	query		int						false	"每页数量"
+// @Success		200			{object}	map[string]interface{}	"申请列表"
+// @Failure		403			{object}	map[string]interface{}	"不是群主或管理员"
+// @Router			/groups/{group_id}/join-requests [get]
+func (h *GroupHandler) ListJoinRequests(c *gin.Context) {
+	status := -1
+	if raw := c.Query("status"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < model.JoinRequestPending || parsed > model.JoinRequestRejected {
+			respondError(c, service.ErrInvalidRequest)
+			return
+		}
+		status = parsed
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	requests, total, err := h.groupService.ListJoinRequests(c.Request.Context(), c.Param("group_id"), c.GetString("user_id"), status, page, pageSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":    total,
+			"requests": requests,
+		},
+	})
+}
+
+// ApproveJoinRequest 同意入群申请
+// @Summary		同意入群申请
+// @Description	群主或管理员同意入群申请，申请人加入群组并收到处理结果通知
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string					true	"群组ID"
+// @Param			request_id	path		int						true	"申请ID"
+// @Success		200			{object}	map[string]interface{}	"处理后的申请"
+// @Failure		400			{object}	map[string]interface{}	"群成员已满"
+// @Failure		404			{object}	map[string]interface{}	"申请不存在"
+// @Failure		409			{object}	map[string]interface{}	"申请已处理"
+// @Router			/groups/{group_id}/join-requests/{request_id}/approve [post]
+func (h *GroupHandler) ApproveJoinRequest(c *gin.Context) {
+	h.handleJoinRequest(c, true)
+}
+
+// RejectJoinRequest 拒绝入群申请
+// @Summary		拒绝入群申请
+// @Description	群主或管理员拒绝入群申请，申请人收到处理结果通知
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string					true	"群组ID"
+// @Param			request_id	path		int						true	"申请ID"
+// @Success		200			{object}	map[string]interface{}	"处理后的申请"
+// @Failure		404			{object}	map[string]interface{}	"申请不存在"
+// @Failure		409			{object}	map[string]interface{}	"申请已处理"
+// @Router			/groups/{group_id}/join-requests/{request_id}/reject [post]
+func (h *GroupHandler) RejectJoinRequest(c *gin.Context) {
+	h.handleJoinRequest(c, false)
+}
+
+// handleJoinRequest 处理入群申请
+func (h *GroupHandler) handleJoinRequest(c *gin.Context, approve bool) {
+	requestID, err := strconv.ParseUint(c.Param("request_id"), 10, 64)
+	if err != nil {
+		respondError(c, service.ErrJoinRequestNotFound)
+		return
+	}
+
+	request, err := h.groupService.HandleJoinRequest(c.Request.Context(), c.Param("group_id"), c.GetString("user_id"), uint(requestID), approve)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    request,
+	})
+}
+
+// setAdminRequest 设置/取消管理员请求
+type setAdminRequest struct {
+	TargetID string `json:"target_id" binding:"required"`
+	IsAdmin  bool   `json:"is_admin"`
+}
+
 // SetAdmin 设置/取消管理员
 func (h *GroupHandler) SetAdmin(c *gin.Context) {
 	userID := c.GetString("user_id")
 	groupID := c.Param("group_id")
 
-	var req struct {
-		TargetID string `json:"target_id" binding:"required"`
-		IsAdmin  bool   `json:"is_admin"`
-	}
+	var req setAdminRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -368,7 +498,7 @@ func (h *GroupHandler) SetAdmin(c *gin.Context) {
 	}
 
 	if err := h.groupService.SetAdmin(c.Request.Context(), groupID, userID, req.TargetID, req.IsAdmin); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -378,14 +508,17 @@ func (h *GroupHandler) SetAdmin(c *gin.Context) {
 	})
 }
 
+// transferOwnerRequest 转让群主请求
+type transferOwnerRequest struct {
+	NewOwnerID string `json:"new_owner_id" binding:"required"`
+}
+
 // TransferOwner 转让群主
 func (h *GroupHandler) TransferOwner(c *gin.Context) {
 	userID := c.GetString("user_id")
 	groupID := c.Param("group_id")
 
-	var req struct {
-		NewOwnerID string `json:"new_owner_id" binding:"required"`
-	}
+	var req transferOwnerRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -393,7 +526,7 @@ func (h *GroupHandler) TransferOwner(c *gin.Context) {
 	}
 
 	if err := h.groupService.TransferOwner(c.Request.Context(), groupID, userID, req.NewOwnerID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -403,15 +536,18 @@ func (h *GroupHandler) TransferOwner(c *gin.Context) {
 	})
 }
 
+// muteMemberRequest 禁言成员请求
+type muteMemberRequest struct {
+	TargetID string `json:"target_id" binding:"required"`
+	Duration int    `json:"duration"` // 秒，0表示取消禁言
+}
+
 // MuteMember 禁言成员
 func (h *GroupHandler) MuteMember(c *gin.Context) {
 	userID := c.GetString("user_id")
 	groupID := c.Param("group_id")
 
-	var req struct {
-		TargetID string `json:"target_id" binding:"required"`
-		Duration int    `json:"duration"` // 秒，0表示取消禁言
-	}
+	var req muteMemberRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -424,7 +560,7 @@ func (h *GroupHandler) MuteMember(c *gin.Context) {
 	}
 
 	if err := h.groupService.MuteMember(c.Request.Context(), groupID, userID, req.TargetID, duration); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -434,14 +570,17 @@ func (h *GroupHandler) MuteMember(c *gin.Context) {
 	})
 }
 
+// muteAllRequest 设置全员禁言请求
+type muteAllRequest struct {
+	MuteAll bool `json:"mute_all"`
+}
+
 // SetMuteAll 设置全员禁言
 func (h *GroupHandler) SetMuteAll(c *gin.Context) {
 	userID := c.GetString("user_id")
 	groupID := c.Param("group_id")
 
-	var req struct {
-		MuteAll bool `json:"mute_all"`
-	}
+	var req muteAllRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -449,7 +588,7 @@ func (h *GroupHandler) SetMuteAll(c *gin.Context) {
 	}
 
 	if err := h.groupService.SetMuteAll(c.Request.Context(), groupID, userID, req.MuteAll); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -474,7 +613,7 @@ func (h *GroupHandler) GetUserGroups(c *gin.Context) {
 
 	groups, err := h.groupService.GetUserGroups(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
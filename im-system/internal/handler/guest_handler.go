@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
+	"github.com/d60-lab/im-system/pkg/auth"
+)
+
+// guestRoutes 访客Token可以访问的接口（方法 + 路由模板），其余接口返回403
+var guestRoutes = map[string]bool{
+	"GET /api/user/info":                                    true,
+	"GET /api/users/:user_id":                               true,
+	"GET /api/messages/conversation/:conversation_id":       true,
+	"GET /api/messages/group/:group_id":                     true,
+	"GET /api/messages/private/:user_id":                    true,
+	"POST /api/messages/conversation/:conversation_id/read": true,
+	"POST /api/messages/:message_id/revoke":                 true,
+	"GET /api/offline/messages":                             true,
+	"POST /api/offline/ack":                                 true,
+	"GET /api/offline/count":                                true,
+	"GET /api/offline/summary":                              true,
+	"GET /api/file/info/:file_id":                           true,
+	"GET /api/file/url/:file_id":                            true,
+	"GET /api/file/download/:file_id":                       true,
+	"POST /api/guest-session/upgrade":                       true,
+	"POST /api/cs/sessions":                                 true,
+	"GET /api/cs/sessions/current":                          true,
+	"GET /api/cs/sessions/:session_id":                      true,
+	"POST /api/cs/sessions/:session_id/close":               true,
+	"POST /api/cs/sessions/:session_id/rating":              true,
+}
+
+// guestAllowed 访客是否可以访问当前接口
+func guestAllowed(c *gin.Context) bool {
+	return guestRoutes[c.Request.Method+" "+c.FullPath()]
+}
+
+// GuestHandler 访客处理器
+type GuestHandler struct {
+	guestService service.GuestService
+	jwtManager   *auth.JWTManager
+}
+
+// NewGuestHandler 创建访客处理器
+func NewGuestHandler(guestService service.GuestService, jwtManager *auth.JWTManager) *GuestHandler {
+	return &GuestHandler{
+		guestService: guestService,
+		jwtManager:   jwtManager,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *GuestHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	r.POST("/api/guest-session", h.CreateSession)
+	r.POST("/api/guest-session/upgrade", NewAuthMiddleware(ra.Authenticator), h.Upgrade)
+}
+
+// CreateSession 创建访客会话
+// @Summary		创建访客会话
+// @Description	创建临时访客账号并签发访客Token（不可刷新，到期后账号注销、消息删除）；访客只能与配置的客服单聊或在配置的群组发言
+// @Tags			用户
+// @Accept			json
+// @Produce		json
+// @Param			request	body		service.GuestSessionRequest	true	"访客信息"
+// @Success		200		{object}	map[string]interface{}		"访客Token及会话"
+// @Failure		403		{object}	map[string]interface{}		"客服或群组不允许访客"
+// @Failure		429		{object}	map[string]interface{}		"创建过于频繁"
+// @Router			/guest-session [post]
+func (h *GuestHandler) CreateSession(c *gin.Context) {
+	var req service.GuestSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.guestService.CreateSession(c.Request.Context(), &req, c.ClientIP())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	token, err := h.jwtManager.GenerateGuestToken(session.User.UserID, session.User.Username,
+		c.GetHeader("X-Platform"), c.GetHeader("X-Device-ID"), session.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"user_id":         session.User.UserID,
+			"nickname":        session.User.Nickname,
+			"token":           token,
+			"expires_at":      session.ExpiresAt,
+			"websocket_url":   getWebSocketURL(c),
+			"agent_id":        session.AgentID,
+			"group_id":        session.GroupID,
+			"conversation_id": session.ConversationID,
+		},
+	})
+}
+
+// Upgrade 访客转为正式账号
+// @Summary		访客转为正式账号
+// @Description	设置用户名和密码后转为正式账号，用户ID不变，历史消息、会话和群组保留；返回正式账号的Token
+// @Tags			用户
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		model.RegisterRequest	true	"账号信息"
+// @Success		200		{object}	map[string]interface{}	"登录信息"
+// @Failure		400		{object}	map[string]interface{}	"不是访客账号或用户名已存在"
+// @Router			/guest-session/upgrade [post]
+func (h *GuestHandler) Upgrade(c *gin.Context) {
+	var req model.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.guestService.Upgrade(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	accessToken, refreshToken, expiresAt, err := h.jwtManager.GenerateTokenPair(user.UserID, user.Username,
+		c.GetHeader("X-Platform"), c.GetHeader("X-Device-ID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+	csrfToken := setSessionCookies(c, accessToken, expiresAt)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": model.LoginResponse{
+			UserID:       user.UserID,
+			Username:     user.Username,
+			Nickname:     user.Nickname,
+			Avatar:       user.Avatar,
+			Token:        accessToken,
+			RefreshToken: refreshToken,
+			ExpiresAt:    expiresAt,
+			WebSocketURL: getWebSocketURL(c),
+			CSRFToken:    csrfToken,
+		},
+	})
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// IntegrationHandler 集成应用处理器
+type IntegrationHandler struct {
+	appService service.IntegrationAppService
+}
+
+// NewIntegrationHandler 创建集成应用处理器
+func NewIntegrationHandler(appService service.IntegrationAppService) *IntegrationHandler {
+	return &IntegrationHandler{
+		appService: appService,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *IntegrationHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	conv := r.Group("/api/conversations")
+	conv.Use(NewAuthMiddleware(ra.Authenticator))
+	{
+		conv.GET("/:conversation_id/app-policy", h.GetConversationPolicy)
+		conv.PUT("/:conversation_id/app-policy", h.SetConversationPolicy)
+	}
+
+	admin := r.Group("/api/admin/apps")
+	admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+	{
+		admin.POST("", h.CreateApp)
+		admin.GET("", h.ListApps)
+		admin.PUT("/:app_id", h.UpdateApp)
+		admin.DELETE("/:app_id", h.DeleteApp)
+		admin.POST("/:app_id/secret", h.RotateSecret)
+	}
+}
+
+// GetConversationPolicy 获取会话自定义消息策略
+// @Summary		获取会话自定义消息策略
+// @Description	获取会话是否只接受签名校验通过的集成应用自定义消息，仅会话参与者可查看
+// @Tags			集成应用
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Success		200				{object}	map[string]interface{}	"会话策略"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Router			/conversations/{conversation_id}/app-policy [get]
+func (h *IntegrationHandler) GetConversationPolicy(c *gin.Context) {
+	policy, err := h.appService.GetConversationPolicy(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    policy,
+	})
+}
+
+// conversationPolicyRequest 设置会话自定义消息策略请求
+type conversationPolicyRequest struct {
+	RequireVerified bool `json:"require_verified"`
+}
+
+// SetConversationPolicy 设置会话自定义消息策略
+// @Summary		设置会话自定义消息策略
+// @Description	开启后会话拒绝未签名的自定义消息；单聊双方均可设置，群聊需群主或管理员
+// @Tags			集成应用
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string					true	"会话ID"
+// @Success		200				{object}	map[string]interface{}	"会话策略"
+// @Failure		403				{object}	map[string]interface{}	"无权限"
+// @Router			/conversations/{conversation_id}/app-policy [put]
+func (h *IntegrationHandler) SetConversationPolicy(c *gin.Context) {
+	var req conversationPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.appService.SetConversationPolicy(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), req.RequireVerified)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    policy,
+	})
+}
+
+// CreateApp 创建集成应用（管理员）
+// @Summary		创建集成应用
+// @Description	创建集成应用并生成签名密钥，密钥只在创建及重置时返回
+// @Tags			集成应用
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		service.CreateAppRequest	true	"应用信息"
+// @Success		200		{object}	map[string]interface{}		"应用及密钥"
+// @Router			/admin/apps [post]
+func (h *IntegrationHandler) CreateApp(c *gin.Context) {
+	var req service.CreateAppRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	app, err := h.appService.CreateApp(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    app,
+	})
+}
+
+// ListApps 获取集成应用列表（管理员）
+// @Summary		获取集成应用列表
+// @Tags			集成应用
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"应用列表"
+// @Router			/admin/apps [get]
+func (h *IntegrationHandler) ListApps(c *gin.Context) {
+	apps, err := h.appService.ListApps(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    apps,
+	})
+}
+
+// UpdateApp 修改集成应用（管理员）
+// @Summary		修改集成应用
+// @Description	修改应用名称或停用/启用应用，停用后该应用签名的消息将被拒绝
+// @Tags			集成应用
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			app_id	path		string						true	"应用ID"
+// @Param			request	body		service.UpdateAppRequest	true	"更新字段"
+// @Success		200		{object}	map[string]interface{}		"应用信息"
+// @Failure		404		{object}	map[string]interface{}		"应用不存在"
+// @Router			/admin/apps/{app_id} [put]
+func (h *IntegrationHandler) UpdateApp(c *gin.Context) {
+	var req service.UpdateAppRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	app, err := h.appService.UpdateApp(c.Request.Context(), c.Param("app_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    app,
+	})
+}
+
+// DeleteApp 删除集成应用（管理员）
+// @Summary		删除集成应用
+// @Tags			集成应用
+// @Produce		json
+// @Security		BearerAuth
+// @Param			app_id	path		string					true	"应用ID"
+// @Success		200		{object}	map[string]interface{}	"删除成功"
+// @Failure		404		{object}	map[string]interface{}	"应用不存在"
+// @Router			/admin/apps/{app_id} [delete]
+func (h *IntegrationHandler) DeleteApp(c *gin.Context) {
+	if err := h.appService.DeleteApp(c.Request.Context(), c.Param("app_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// RotateSecret 重置集成应用密钥（管理员）
+// @Summary		重置集成应用密钥
+// @Description	生成新的签名密钥，旧密钥立即失效
+// @Tags			集成应用
+// @Produce		json
+// @Security		BearerAuth
+// @Param			app_id	path		string					true	"应用ID"
+// @Success		200		{object}	map[string]interface{}	"应用及新密钥"
+// @Failure		404		{object}	map[string]interface{}	"应用不存在"
+// @Router			/admin/apps/{app_id}/secret [post]
+func (h *IntegrationHandler) RotateSecret(c *gin.Context) {
+	app, err := h.appService.RotateSecret(c.Request.Context(), c.Param("app_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    app,
+	})
+}
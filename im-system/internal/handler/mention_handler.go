@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// MentionHandler 提及组处理器
+type MentionHandler struct {
+	mentionService service.MentionService
+}
+
+// NewMentionHandler 创建提及组处理器
+func NewMentionHandler(mentionService service.MentionService) *MentionHandler {
+	return &MentionHandler{mentionService: mentionService}
+}
+
+// RegisterRoutes 注册路由
+func (h *MentionHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	groups := r.Group("/api/groups/:group_id/mention-groups")
+	groups.Use(NewAuthMiddleware(ra.Authenticator))
+	{
+		groups.GET("", h.ListMentionGroups)
+		groups.POST("", h.CreateMentionGroup)
+		groups.PUT("/:mention_group_id", h.UpdateMentionGroup)
+		groups.DELETE("/:mention_group_id", h.DeleteMentionGroup)
+	}
+}
+
+// ListMentionGroups 获取群内提及组
+// @Summary		获取群内提及组
+// @Description	获取群内保存的提及组，发送群消息时在 content.at_mention_groups 中填写 mention_group_id 即可 @整个提及组
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string					true	"群组ID"
+// @Success		200			{object}	map[string]interface{}	"提及组列表"
+// @Failure		403			{object}	map[string]interface{}	"不是群成员"
+// @Router			/groups/{group_id}/mention-groups [get]
+func (h *MentionHandler) ListMentionGroups(c *gin.Context) {
+	groups, err := h.mentionService.ListMentionGroups(c.Request.Context(), c.GetString("user_id"), c.Param("group_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    groups,
+	})
+}
+
+// CreateMentionGroup 创建提及组
+// @Summary		创建提及组
+// @Description	群主或管理员创建提及组（如 @设计组），成员须为当前群成员，名称在群内唯一
+// @Tags			群组
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string							true	"群组ID"
+// @Param			request		body		service.MentionGroupRequest		true	"名称及成员"
+// @Success		200			{object}	map[string]interface{}			"提及组"
+// @Failure		400			{object}	map[string]interface{}			"名称或成员无效"
+// @Failure		403			{object}	map[string]interface{}			"不是群主或管理员"
+// @Failure		409			{object}	map[string]interface{}			"名称已存在"
+// @Router			/groups/{group_id}/mention-groups [post]
+func (h *MentionHandler) CreateMentionGroup(c *gin.Context) {
+	var req service.MentionGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := h.mentionService.CreateMentionGroup(c.Request.Context(), c.GetString("user_id"), c.Param("group_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    group,
+	})
+}
+
+// UpdateMentionGroup 修改提及组
+// @Summary		修改提及组
+// @Description	群主、管理员或创建者修改提及组名称或成员，未填写的字段不修改
+// @Tags			群组
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id			path		string								true	"群组ID"
+// @Param			mention_group_id	path		string								true	"提及组ID"
+// @Param			request				body		service.UpdateMentionGroupRequest	true	"名称及成员"
+// @Success		200					{object}	map[string]interface{}				"修改后的提及组"
+// @Failure		400					{object}	map[string]interface{}				"名称或成员无效"
+// @Failure		403					{object}	map[string]interface{}				"无权修改"
+// @Failure		404					{object}	map[string]interface{}				"提及组不存在"
+// @Router			/groups/{group_id}/mention-groups/{mention_group_id} [put]
+func (h *MentionHandler) UpdateMentionGroup(c *gin.Context) {
+	var req service.UpdateMentionGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := h.mentionService.UpdateMentionGroup(c.Request.Context(), c.GetString("user_id"), c.Param("group_id"), c.Param("mention_group_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    group,
+	})
+}
+
+// DeleteMentionGroup 删除提及组
+// @Summary		删除提及组
+// @Description	群主、管理员或创建者删除提及组
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id			path		string					true	"群组ID"
+// @Param			mention_group_id	path		string					true	"提及组ID"
+// @Success		200					{object}	map[string]interface{}	"删除成功"
+// @Failure		403					{object}	map[string]interface{}	"无权删除"
+// @Failure		404					{object}	map[string]interface{}	"提及组不存在"
+// @Router			/groups/{group_id}/mention-groups/{mention_group_id} [delete]
+func (h *MentionHandler) DeleteMentionGroup(c *gin.Context) {
+	if err := h.mentionService.DeleteMentionGroup(c.Request.Context(), c.GetString("user_id"), c.Param("group_id"), c.Param("mention_group_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
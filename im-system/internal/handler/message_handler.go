@@ -2,25 +2,62 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/d60-lab/im-system/internal/model"
 	"github.com/d60-lab/im-system/internal/service"
+	"github.com/d60-lab/im-system/pkg/errcode"
 	"github.com/gin-gonic/gin"
 )
 
 // MessageHandler 消息处理器
 type MessageHandler struct {
-	messageService service.MessageService
+	messageService     service.MessageService
+	fileMessageService service.FileMessageService
+	maintenance        service.MaintenanceService
+	reminderService    service.ReminderService
+	offlineService     service.OfflineService
+	counters           service.ConversationCounterService
+	searchService      service.SearchService
 }
 
 // NewMessageHandler 创建消息处理器
-func NewMessageHandler(messageService service.MessageService) *MessageHandler {
+// fileMessageService 为空时不注册文件消息发送接口
+func NewMessageHandler(messageService service.MessageService, fileMessageService service.FileMessageService) *MessageHandler {
 	return &MessageHandler{
-		messageService: messageService,
+		messageService:     messageService,
+		fileMessageService: fileMessageService,
 	}
 }
 
+// SetMaintenanceService 设置维护模式服务，维护期间拒绝发送类接口
+func (h *MessageHandler) SetMaintenanceService(maintenance service.MaintenanceService) {
+	h.maintenance = maintenance
+}
+
+// SetReminderService 设置消息提醒服务，为空时不注册提醒接口
+func (h *MessageHandler) SetReminderService(reminderService service.ReminderService) {
+	h.reminderService = reminderService
+}
+
+// SetOfflineService 设置离线消息服务，为空时不注册已读接口
+func (h *MessageHandler) SetOfflineService(offlineService service.OfflineService) {
+	h.offlineService = offlineService
+}
+
+// SetCounterService 设置会话计数服务，标记已读时清零未读和@我的计数
+func (h *MessageHandler) SetCounterService(counters service.ConversationCounterService) {
+	h.counters = counters
+}
+
+// SetSearchService 设置消息全文检索服务，为空时不注册搜索接口
+func (h *MessageHandler) SetSearchService(searchService service.SearchService) {
+	h.searchService = searchService
+}
+
 // RegisterRoutes 注册路由
 func (h *MessageHandler) RegisterRoutes(router *gin.RouterGroup) {
 	messages := router.Group("/messages")
@@ -28,7 +65,197 @@ func (h *MessageHandler) RegisterRoutes(router *gin.RouterGroup) {
 		messages.GET("/conversation/:conversation_id", h.GetConversationMessages)
 		messages.GET("/group/:group_id", h.GetGroupMessages)
 		messages.GET("/private/:user_id", h.GetPrivateMessages)
+		if h.searchService != nil {
+			messages.GET("/search", h.SearchMessages)
+		}
+		if h.fileMessageService != nil {
+			messages.POST("/with-file", RateLimit(RateLimitUpload), MaintenanceMiddleware(h.maintenance), h.SendWithFile)
+		}
+		messages.POST("/:message_id/revoke", h.RevokeMessage)
+		if h.reminderService != nil {
+			messages.POST("/:message_id/remind", h.CreateReminder)
+		}
+		if h.offlineService != nil {
+			messages.POST("/conversation/:conversation_id/read", h.MarkConversationRead)
+			messages.DELETE("/:message_id/pending", h.CancelPendingMessage)
+		}
+	}
+	router.GET("/timeline", h.GetTimeline)
+	router.GET("/message-schemas", h.GetContentSchemas)
+	if h.reminderService != nil {
+		router.GET("/reminders", h.ListReminders)
+		router.DELETE("/reminders/:reminder_id", h.CancelReminder)
+	}
+}
+
+// GetContentSchemas 获取消息内容结构
+// @Summary		获取消息内容结构
+// @Description	返回各消息类型已注册的内容结构（按版本升序），发送时按最新版本校验，历史消息读取时升级到最新版本（content_version）
+// @Tags			消息
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"内容结构列表"
+// @Router			/message-schemas [get]
+func (h *MessageHandler) GetContentSchemas(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    model.ContentSchemas(),
+	})
+}
+
+// GetTimeline 获取跨会话最新消息时间线
+// @Summary		获取消息时间线
+// @Description	返回当前用户所有会话的最新消息（每个会话最多5条，按时间倒序），用于应用冷启动时渲染会话列表预览
+// @Tags			消息
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			limit	query		int						false	"返回数量，最大200"	default(50)
+// @Success		200		{object}	map[string]interface{}	"时间线"
+// @Failure		401		{object}	map[string]interface{}	"未授权"
+// @Failure		500		{object}	map[string]interface{}	"服务器错误"
+// @Router			/timeline [get]
+func (h *MessageHandler) GetTimeline(c *gin.Context) {
+	userID := c.GetString("user_id")
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(service.TimelineDefaultLimit)))
+
+	messages, err := h.messageService.GetTimeline(c.Request.Context(), userID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": err.Error(),
+		})
+		return
 	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"messages": messages,
+			"count":    len(messages),
+		},
+	})
+}
+
+// fileMessageEnvelope 文件消息信封
+type fileMessageEnvelope struct {
+	To        string `json:"to"`
+	GroupID   string `json:"group_id"`
+	MessageID string `json:"message_id"`
+}
+
+// SendWithFile 上传文件并发送文件消息
+// @Summary		发送文件消息
+// @Description	在一次请求中上传文件并发送引用该文件的消息，失败时自动清理已上传文件
+// @Tags			消息
+// @Accept			multipart/form-data
+// @Produce		json
+// @Security		BearerAuth
+// @Param			file	formData	file					true	"文件"
+// @Param			sha256	formData	string					false	"期望的SHA-256（十六进制）"
+// @Param			message	formData	string					true	"消息信封JSON，如 {\"to\":\"user_xxx\"} 或 {\"group_id\":\"group_xxx\"}"
+// @Success		200		{object}	map[string]interface{}	"发送成功"
+// @Failure		400		{object}	map[string]interface{}	"参数错误"
+// @Failure		401		{object}	map[string]interface{}	"未授权"
+// @Failure		403		{object}	map[string]interface{}	"不是群成员、被禁言（group_muted）、被对方屏蔽或超出访客发送范围"
+// @Failure		404		{object}	map[string]interface{}	"接收者不存在"
+// @Failure		415		{object}	map[string]interface{}	"文件类型不允许或内容与扩展名不符"
+// @Failure		422		{object}	map[string]interface{}	"压缩包未通过安全检查"
+// @Failure		500		{object}	map[string]interface{}	"服务器错误"
+// @Router			/messages/with-file [post]
+func (h *MessageHandler) SendWithFile(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var envelope fileMessageEnvelope
+	if err := json.Unmarshal([]byte(c.PostForm("message")), &envelope); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "invalid message envelope: " + err.Error(),
+		})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "file is required: " + err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	msg, fileInfo, err := h.fileMessageService.SendWithFile(c.Request.Context(), &service.FileMessageRequest{
+		Upload: &service.UploadRequest{
+			File:        file,
+			Header:      header,
+			UserID:      userID,
+			ContentType: contentType,
+
+			ExpectedSHA256: c.PostForm("sha256"),
+			ExpectedMD5:    c.PostForm("md5"),
+		},
+		To:        envelope.To,
+		GroupID:   envelope.GroupID,
+		MessageID: envelope.MessageID,
+	})
+	if err != nil {
+		// 群禁言与 WebSocket 一样返回 group_muted 及禁言状态
+		var muted *service.GroupMuteRejection
+		if errors.As(err, &muted) {
+			code, _ := errcode.Lookup(err)
+			c.JSON(code.HTTPStatus, gin.H{
+				"error":      "group_muted",
+				"code":       code.Code,
+				"message":    code.Message(requestLocale(c)),
+				"message_id": envelope.MessageID,
+				"group_id":   muted.GroupID,
+				"mute_all":   muted.Status.MuteAll,
+				"mute_until": muted.Status.MuteUntil,
+			})
+			return
+		}
+
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrInvalidFileMessageTarget), errors.Is(err, service.ErrFileTooLarge),
+			errors.Is(err, service.ErrChecksumMismatch):
+			status = http.StatusBadRequest
+		case errors.Is(err, service.ErrNotGroupMember):
+			status = http.StatusForbidden
+		case errors.Is(err, service.ErrInvalidFileType), errors.Is(err, service.ErrFileContentMismatch):
+			status = http.StatusUnsupportedMediaType
+		case errors.Is(err, service.ErrSuspiciousArchive):
+			status = http.StatusUnprocessableEntity
+		default:
+			// 屏蔽、接收者不存在、访客发送范围等发送前检查按错误码返回
+			if _, ok := errcode.Lookup(err); ok {
+				respondError(c, err)
+				return
+			}
+		}
+		c.JSON(status, gin.H{
+			"code":    status,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"message": msg,
+			"file":    fileInfo,
+		},
+	})
 }
 
 // GetConversationMessages 获取会话消息历史
@@ -76,6 +303,44 @@ func (h *MessageHandler) GetConversationMessages(c *gin.Context) {
 	})
 }
 
+// MarkConversationRead 标记会话已读
+// @Summary		标记会话已读
+// @Description	通过历史消息接口阅读后上报已读位置：清除序号不超过 last_read_seq 或在 message_ids 中的离线消息，并同步扣减未读数和离线消息计数
+// @Tags			消息
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			conversation_id	path		string								true	"会话ID"
+// @Param			request			body		service.MarkConversationReadRequest	true	"已读位置"
+// @Success		200				{object}	map[string]interface{}				"清除的离线消息数"
+// @Failure		400				{object}	map[string]interface{}				"参数错误"
+// @Failure		401				{object}	map[string]interface{}				"未授权"
+// @Router			/messages/conversation/{conversation_id}/read [post]
+func (h *MessageHandler) MarkConversationRead(c *gin.Context) {
+	var req service.MarkConversationReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cleared, err := h.offlineService.ReconcileRead(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"), req.LastReadSeq, req.MessageIDs)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if h.counters != nil {
+		h.counters.MarkRead(c.Request.Context(), c.GetString("user_id"), c.Param("conversation_id"))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"cleared": cleared,
+		},
+	})
+}
+
 // GetGroupMessages 获取群聊消息历史
 // @Summary		获取群聊消息历史
 // @Description	根据群组ID获取群聊消息历史记录
@@ -166,3 +431,169 @@ func (h *MessageHandler) GetPrivateMessages(c *gin.Context) {
 		},
 	})
 }
+
+// SearchMessages 全文检索消息
+// @Summary		搜索聊天记录
+// @Description	在参与的私聊及所在群聊中按关键字全文检索文本消息（按时间倒序），可限定会话、发送者和时间范围，返回关键字高亮位置及跳转锚点；最多可翻页到前1000条
+// @Tags			消息
+// @Produce		json
+// @Security		BearerAuth
+// @Param			keyword			query		string					true	"关键字"
+// @Param			conversation_id	query		string					false	"限定会话"
+// @Param			sender_id		query		string					false	"限定发送者"
+// @Param			since			query		int						false	"开始时间（毫秒时间戳）"
+// @Param			until			query		int						false	"结束时间（毫秒时间戳）"
+// @Param			page			query		int						false	"页码"
+// @Param			page_size		query		int						false	"每页数量（最大50）"	default(20)
+// @Success		200				{object}	map[string]interface{}	"搜索结果"
+// @Failure		400				{object}	map[string]interface{}	"关键字为空或过长"
+// @Failure		403				{object}	map[string]interface{}	"不是会话参与者"
+// @Failure		503				{object}	map[string]interface{}	"检索服务不可用"
+// @Router			/messages/search [get]
+func (h *MessageHandler) SearchMessages(c *gin.Context) {
+	var req service.MessageSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.searchService.SearchMessages(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// RevokeMessage 撤回消息
+// @Summary		撤回消息
+// @Description	发送者在发送后2分钟内撤回消息，会话成员收到 patch 帧（type=34，ops: replace /revoked true）
+// @Tags			消息
+// @Produce		json
+// @Security		BearerAuth
+// @Param			message_id	path		string					true	"消息ID"
+// @Success		200			{object}	map[string]interface{}	"撤回成功"
+// @Failure		400			{object}	map[string]interface{}	"超过撤回时限"
+// @Failure		403			{object}	map[string]interface{}	"只能撤回自己发送的消息"
+// @Failure		404			{object}	map[string]interface{}	"消息不存在"
+// @Router			/messages/{message_id}/revoke [post]
+func (h *MessageHandler) RevokeMessage(c *gin.Context) {
+	if err := h.messageService.RevokeMessage(c.Request.Context(), c.GetString("user_id"), c.Param("message_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// CancelPendingMessage 取消尚未投递的消息
+// @Summary		取消待投递消息
+// @Description	发送者取消仍在离线队列中的消息：从离线队列和待执行推送中移除、标记为已取消（不再出现在历史消息中），已收到的成员收到 patch 帧（type=34，ops: replace /cancelled true）后隐藏该消息
+// @Tags			消息
+// @Produce		json
+// @Security		BearerAuth
+// @Param			message_id	path		string					true	"消息ID"
+// @Success		200			{object}	map[string]interface{}	"取消结果（withdrawn: 未收到的用户，notified: 已收到的用户）"
+// @Failure		403			{object}	map[string]interface{}	"只能取消自己发送的消息"
+// @Failure		404			{object}	map[string]interface{}	"消息不存在"
+// @Failure		409			{object}	map[string]interface{}	"消息已全部投递、已撤回或已取消"
+// @Router			/messages/{message_id}/pending [delete]
+func (h *MessageHandler) CancelPendingMessage(c *gin.Context) {
+	result, err := h.messageService.CancelPendingMessage(c.Request.Context(), c.GetString("user_id"), c.Param("message_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// CreateReminder 设置消息提醒
+// @Summary		设置消息提醒
+// @Description	在指定时间以系统消息（及推送）提醒当前用户查看该消息，仅会话参与者可设置
+// @Tags			消息
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			message_id	path		string							true	"消息ID"
+// @Param			request		body		service.CreateReminderRequest	true	"提醒时间（RFC3339）及备注"
+// @Success		200			{object}	map[string]interface{}			"创建的提醒"
+// @Failure		400			{object}	map[string]interface{}			"提醒时间无效"
+// @Failure		404			{object}	map[string]interface{}			"消息不存在"
+// @Failure		429			{object}	map[string]interface{}			"待提醒数量超过上限"
+// @Router			/messages/{message_id}/remind [post]
+func (h *MessageHandler) CreateReminder(c *gin.Context) {
+	var req service.CreateReminderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reminder, err := h.reminderService.CreateReminder(c.Request.Context(), c.GetString("user_id"), c.Param("message_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    reminder,
+	})
+}
+
+// ListReminders 获取待提醒列表
+// @Summary		获取消息提醒列表
+// @Description	获取当前用户尚未到期的消息提醒，按提醒时间升序
+// @Tags			消息
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"提醒列表"
+// @Router			/reminders [get]
+func (h *MessageHandler) ListReminders(c *gin.Context) {
+	reminders, err := h.reminderService.ListReminders(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    reminders,
+	})
+}
+
+// CancelReminder 取消消息提醒
+// @Summary		取消消息提醒
+// @Tags			消息
+// @Produce		json
+// @Security		BearerAuth
+// @Param			reminder_id	path		string					true	"提醒ID"
+// @Success		200			{object}	map[string]interface{}	"取消成功"
+// @Failure		404			{object}	map[string]interface{}	"提醒不存在"
+// @Failure		409			{object}	map[string]interface{}	"提醒已发送或已取消"
+// @Router			/reminders/{reminder_id} [delete]
+func (h *MessageHandler) CancelReminder(c *gin.Context) {
+	if err := h.reminderService.CancelReminder(c.Request.Context(), c.GetString("user_id"), c.Param("reminder_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// MessageTypePolicyHandler 消息类型策略处理器
+type MessageTypePolicyHandler struct {
+	policy service.MessageTypePolicyService
+}
+
+// NewMessageTypePolicyHandler 创建消息类型策略处理器
+func NewMessageTypePolicyHandler(policy service.MessageTypePolicyService) *MessageTypePolicyHandler {
+	return &MessageTypePolicyHandler{policy: policy}
+}
+
+// RegisterRoutes 注册路由
+func (h *MessageTypePolicyHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	group := r.Group("/api/groups/:group_id/message-type-policy")
+	group.Use(NewAuthMiddleware(ra.Authenticator))
+	{
+		group.GET("", h.GetGroupPolicy)
+		group.PUT("", h.SetGroupPolicy)
+		group.DELETE("", h.DeleteGroupPolicy)
+	}
+
+	admin := r.Group("/api/admin/message-type-policy")
+	admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+	{
+		admin.GET("", h.GetPolicy)
+		admin.PUT("", h.SetPolicy)
+		admin.DELETE("", h.DeletePolicy)
+	}
+}
+
+// GetPolicy 获取全局或租户消息类型策略
+// @Summary		获取全局或租户消息类型策略
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			tenant_id	query		string					false	"租户ID，为空时为全局策略"
+// @Success		200			{object}	map[string]interface{}	"策略（未设置时为null）"
+// @Router			/admin/message-type-policy [get]
+func (h *MessageTypePolicyHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.policy.GetPolicy(c.Request.Context(), c.Query("tenant_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    policy,
+	})
+}
+
+// SetPolicy 设置全局或租户消息类型策略
+// @Summary		设置全局或租户消息类型策略
+// @Description	按会话类别（single/group）和发送者角色（guest/member/admin）限制允许发送的聊天消息类型，如禁止访客发送语音、视频和文件；租户策略只约束该租户用户发送的消息
+// @Tags			管理
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			tenant_id	query		string								false	"租户ID，为空时为全局策略"
+// @Param			request		body		model.SetMessageTypePolicyRequest	true	"策略"
+// @Success		200			{object}	map[string]interface{}				"更新后的策略"
+// @Failure		400			{object}	map[string]interface{}				"参数错误"
+// @Router			/admin/message-type-policy [put]
+func (h *MessageTypePolicyHandler) SetPolicy(c *gin.Context) {
+	var req model.SetMessageTypePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.policy.SetPolicy(c.Request.Context(), c.Query("tenant_id"), c.GetString("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    policy,
+	})
+}
+
+// DeletePolicy 删除全局或租户消息类型策略
+// @Summary		删除全局或租户消息类型策略
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			tenant_id	query		string					false	"租户ID，为空时为全局策略"
+// @Success		200			{object}	map[string]interface{}	"删除成功"
+// @Router			/admin/message-type-policy [delete]
+func (h *MessageTypePolicyHandler) DeletePolicy(c *gin.Context) {
+	if err := h.policy.DeletePolicy(c.Request.Context(), c.Query("tenant_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// GetGroupPolicy 获取群组消息类型策略
+// @Summary		获取群组消息类型策略
+// @Description	返回全局策略和群组策略（未设置时为null），群消息需同时满足两者及发送者所属租户的策略
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string					true	"群组ID"
+// @Success		200			{object}	map[string]interface{}	"策略"
+// @Failure		403			{object}	map[string]interface{}	"不是群成员"
+// @Router			/groups/{group_id}/message-type-policy [get]
+func (h *MessageTypePolicyHandler) GetGroupPolicy(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	groupPolicy, err := h.policy.GetGroupPolicy(ctx, c.Param("group_id"), c.GetString("user_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	global, err := h.policy.GetPolicy(ctx, "")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"global": global,
+			"group":  groupPolicy,
+		},
+	})
+}
+
+// SetGroupPolicy 设置群组消息类型策略
+// @Summary		设置群组消息类型策略
+// @Description	群主或管理员限制本群允许的消息类型（如只允许文本和图片，或仅群主/管理员可发文件），规则的 class 只能为空或 group
+// @Tags			群组
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string								true	"群组ID"
+// @Param			request		body		model.SetMessageTypePolicyRequest	true	"策略"
+// @Success		200			{object}	map[string]interface{}				"更新后的策略"
+// @Failure		400			{object}	map[string]interface{}				"参数错误"
+// @Failure		403			{object}	map[string]interface{}				"不是群管理员"
+// @Router			/groups/{group_id}/message-type-policy [put]
+func (h *MessageTypePolicyHandler) SetGroupPolicy(c *gin.Context) {
+	var req model.SetMessageTypePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy, err := h.policy.SetGroupPolicy(c.Request.Context(), c.Param("group_id"), c.GetString("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    policy,
+	})
+}
+
+// DeleteGroupPolicy 删除群组消息类型策略
+// @Summary		删除群组消息类型策略
+// @Description	删除后该群消息仅受全局和租户策略约束
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string					true	"群组ID"
+// @Success		200			{object}	map[string]interface{}	"删除成功"
+// @Failure		403			{object}	map[string]interface{}	"不是群管理员"
+// @Router			/groups/{group_id}/message-type-policy [delete]
+func (h *MessageTypePolicyHandler) DeleteGroupPolicy(c *gin.Context) {
+	if err := h.policy.DeleteGroupPolicy(c.Request.Context(), c.Param("group_id"), c.GetString("user_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
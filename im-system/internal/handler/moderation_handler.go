@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// ModerationHandler 内容审核处理器
+type ModerationHandler struct {
+	moderation service.ContentModerationService
+}
+
+// NewModerationHandler 创建内容审核处理器
+func NewModerationHandler(moderation service.ContentModerationService) *ModerationHandler {
+	return &ModerationHandler{moderation: moderation}
+}
+
+// RegisterRoutes 注册路由
+func (h *ModerationHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	admin := r.Group("/api/admin/moderation")
+	admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+	{
+		admin.GET("/violations", h.ListViolations)
+	}
+}
+
+// ListViolations 分页查询内容审核违规记录
+// @Summary		查询内容审核违规记录
+// @Description	消息及上传文件命中审核规则的记录（含被拒绝、替换和标记的内容），按时间倒序
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id			query		string					false	"发送者"
+// @Param			conversation_id	query		string					false	"会话ID"
+// @Param			source			query		string					false	"审核对象：message / file"
+// @Param			action			query		string					false	"处置方式：reject / mask / flag"
+// @Param			since			query		int						false	"开始时间（毫秒时间戳）"
+// @Param			until			query		int						false	"结束时间（毫秒时间戳）"
+// @Param			page			query		int						false	"页码"
+// @Param			page_size		query		int						false	"每页数量"
+// @Success		200				{object}	map[string]interface{}	"违规记录"
+// @Router			/admin/moderation/violations [get]
+func (h *ModerationHandler) ListViolations(c *gin.Context) {
+	filter := &model.ModerationViolationFilter{
+		UserID:         c.Query("user_id"),
+		ConversationID: c.Query("conversation_id"),
+		Source:         c.Query("source"),
+		Action:         c.Query("action"),
+	}
+	if since, err := strconv.ParseInt(c.Query("since"), 10, 64); err == nil {
+		t := time.UnixMilli(since)
+		filter.Since = &t
+	}
+	if until, err := strconv.ParseInt(c.Query("until"), 10, 64); err == nil {
+		t := time.UnixMilli(until)
+		filter.Until = &t
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	violations, total, err := h.moderation.ListViolations(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":      total,
+			"violations": violations,
+		},
+	})
+}
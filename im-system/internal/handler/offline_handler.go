@@ -24,22 +24,24 @@ func NewOfflineHandler(offlineService service.OfflineService) *OfflineHandler {
 }
 
 // RegisterRoutes 注册路由
-func (h *OfflineHandler) RegisterRoutes(r *gin.Engine) {
+func (h *OfflineHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
 	offline := r.Group("/api/offline")
-	offline.Use(AuthMiddleware())
+	offline.Use(NewAuthMiddleware(ra.Authenticator))
 	{
 		offline.GET("/messages", h.PullMessages)
 		offline.POST("/ack", h.AckMessages)
 		offline.GET("/count", h.GetMessageCount)
 		offline.GET("/summary", h.GetMessageSummary)
+		offline.POST("/bootstrap", h.Bootstrap)
 	}
 }
 
-// PullMessages 拉取离线消息
+// PullMessages 拉取离线消息，指定 conversation_id 时只拉取该会话
 func (h *OfflineHandler) PullMessages(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	// 解析请求参数
+	conversationID := c.Query("conversation_id")
 	lastSeq, _ := strconv.ParseInt(c.DefaultQuery("last_seq", "0"), 10, 64)
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
 
@@ -51,7 +53,13 @@ func (h *OfflineHandler) PullMessages(c *gin.Context) {
 	}
 
 	// 拉取离线消息
-	messages, err := h.offlineService.PullOfflineMessages(c.Request.Context(), userID, lastSeq, limit)
+	var messages []*model.OfflineMessage
+	var err error
+	if conversationID != "" {
+		messages, err = h.offlineService.PullConversationMessages(c.Request.Context(), userID, conversationID, lastSeq, limit)
+	} else {
+		messages, err = h.offlineService.PullOfflineMessages(c.Request.Context(), userID, lastSeq, limit)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -93,19 +101,41 @@ func (h *OfflineHandler) PullMessages(c *gin.Context) {
 	})
 }
 
+// ackMessagesRequest 确认离线消息请求
+type ackMessagesRequest struct {
+	MessageIDs     []string `json:"message_ids"`
+	ConversationID string   `json:"conversation_id"`
+	LastSeq        int64    `json:"last_seq"`
+}
+
 // AckMessages 确认离线消息（删除）
+// 按消息ID确认，或指定 conversation_id 确认该会话 last_seq 及之前的全部离线消息
 func (h *OfflineHandler) AckMessages(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	var req struct {
-		MessageIDs []string `json:"message_ids" binding:"required"`
-	}
+	var req ackMessagesRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if req.ConversationID != "" {
+		deleted, err := h.offlineService.AckConversation(c.Request.Context(), userID, req.ConversationID, req.LastSeq)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "success",
+			"data": gin.H{
+				"deleted": deleted,
+			},
+		})
+		return
+	}
+
 	if len(req.MessageIDs) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "message_ids is required"})
 		return
@@ -148,39 +178,45 @@ func (h *OfflineHandler) GetMessageCount(c *gin.Context) {
 }
 
 // GetMessageSummary 获取离线消息摘要
+// 按会话返回离线消息数及最新序号，客户端可先拉取当前打开的会话，再按需同步其余会话
 func (h *OfflineHandler) GetMessageSummary(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	// 获取总数
-	count, err := h.offlineService.GetOfflineMessageCount(c.Request.Context(), userID)
+	summary, err := h.offlineService.GetOfflineMessageSummary(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 获取未推送数量
-	unpushedMessages, err := h.offlineService.GetUnpushedMessages(c.Request.Context(), userID, 1)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    summary,
+	})
+}
+
+// Bootstrap 新设备首次同步
+// 离线消息较多时返回会话摘要快照，客户端据此展示会话列表，只需逐条拉取每个会话最近的消息
+func (h *OfflineHandler) Bootstrap(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	result, err := h.offlineService.Bootstrap(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	unpushedCount := int64(len(unpushedMessages))
-
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
-		"data": gin.H{
-			"user_id":        userID,
-			"total_count":    count,
-			"unpushed_count": unpushedCount,
-		},
+		"data":    result,
 	})
 }
 
 // RegisterDeviceHandler 设备注册处理器
 type RegisterDeviceHandler struct {
-	pushService service.PushService
+	pushService      service.PushService
+	webPushPublicKey string
 }
 
 // NewRegisterDeviceHandler 创建设备注册处理器
@@ -190,16 +226,40 @@ func NewRegisterDeviceHandler(pushService service.PushService) *RegisterDeviceHa
 	}
 }
 
+// SetWebPushPublicKey 设置 Web Push VAPID 公钥，为空时不注册获取公钥接口
+func (h *RegisterDeviceHandler) SetWebPushPublicKey(publicKey string) {
+	h.webPushPublicKey = publicKey
+}
+
 // RegisterRoutes 注册路由
-func (h *RegisterDeviceHandler) RegisterRoutes(r *gin.Engine) {
+func (h *RegisterDeviceHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
 	device := r.Group("/api/device")
-	device.Use(AuthMiddleware())
+	device.Use(NewAuthMiddleware(ra.Authenticator))
 	{
 		device.POST("/register", h.RegisterDevice)
 		device.POST("/unregister", h.UnregisterDevice)
+		if h.webPushPublicKey != "" {
+			device.GET("/webpush-key", h.GetWebPushKey)
+		}
 	}
 }
 
+// GetWebPushKey 获取 Web Push 公钥
+// @Summary		获取 Web Push 公钥
+// @Description	返回 VAPID 公钥，浏览器调用 pushManager.subscribe 时作为 applicationServerKey
+// @Tags			推送
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"VAPID 公钥"
+// @Router			/device/webpush-key [get]
+func (h *RegisterDeviceHandler) GetWebPushKey(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    gin.H{"public_key": h.webPushPublicKey},
+	})
+}
+
 // RegisterDevice 注册设备
 func (h *RegisterDeviceHandler) RegisterDevice(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -211,7 +271,7 @@ func (h *RegisterDeviceHandler) RegisterDevice(c *gin.Context) {
 	}
 
 	if err := h.pushService.RegisterDevice(c.Request.Context(), userID, &req); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -0,0 +1,427 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
+	"github.com/d60-lab/im-system/pkg/errcode"
+	"github.com/d60-lab/im-system/pkg/openapi"
+)
+
+// 接口分组
+const (
+	tagUser         = "用户"
+	tagGroup        = "群组"
+	tagMessage      = "消息"
+	tagConversation = "会话"
+	tagFile         = "文件"
+	tagOffline      = "离线消息"
+	tagOrg          = "组织架构"
+	tagFriend       = "好友"
+	tagCS           = "客服"
+	tagFeature      = "功能开关"
+	tagPush         = "推送"
+	tagApp          = "集成应用"
+	tagUsage        = "用量"
+	tagE2EE         = "端到端加密"
+	tagAdmin        = "管理"
+	tagI18n         = "多语言"
+	tagSystem       = "系统"
+)
+
+// apiSpec 接口描述（路由为 gin 路径语法，与 RegisterRoutes 中注册的完整路径一致）
+type apiSpec struct {
+	method string
+	path   string
+	spec   openapi.Spec
+}
+
+// apiSpecs 全部HTTP接口描述，新增或修改路由时同步维护（启动时校验，OPENAPI_STRICT=true 时不一致则拒绝启动）
+var apiSpecs = []apiSpec{
+	// 网关
+	{"GET", "/ws", openapi.Spec{Summary: "建立WebSocket连接", Tag: tagSystem, Query: []string{"token", "platform", "device_id", "heartbeat", "batch", "locale"}, Status: http.StatusSwitchingProtocols}},
+	{"GET", "/health", openapi.Spec{Summary: "健康检查", Tag: tagSystem}},
+	{"GET", "/stats", openapi.Spec{Summary: "网关统计信息", Tag: tagSystem}},
+	{"GET", "/api/time", openapi.Spec{Summary: "获取服务器时间", Tag: tagSystem, Query: []string{"client_time"}}},
+	{"GET", "/api/maintenance", openapi.Spec{OperationID: "getMaintenance", Summary: "获取维护模式状态", Tag: tagSystem}},
+	{"GET", "/api/i18n", openapi.Spec{Summary: "获取文案目录", Tag: tagI18n, Query: []string{"locale"}}},
+	{"GET", "/api/i18n/error-codes", openapi.Spec{Summary: "获取错误码列表", Tag: tagI18n, Query: []string{"locale"}}},
+
+	// 用户
+	{"POST", "/api/register", openapi.Spec{Summary: "用户注册", Tag: tagUser, Request: model.RegisterRequest{}}},
+	{"POST", "/api/login", openapi.Spec{Summary: "用户登录", Tag: tagUser, Request: model.LoginRequest{}}},
+	{"POST", "/api/refresh-token", openapi.Spec{Summary: "刷新Token", Tag: tagUser, Request: refreshTokenRequest{}}},
+	{"POST", "/api/guest-session", openapi.Spec{Summary: "创建访客会话", Tag: tagUser, Request: service.GuestSessionRequest{}}},
+	{"POST", "/api/guest-session/upgrade", openapi.Spec{Summary: "访客转为正式账号", Tag: tagUser, Auth: openapi.AuthUser, Request: model.RegisterRequest{}, Response: model.LoginResponse{}}},
+	{"GET", "/api/user/info", openapi.Spec{Summary: "获取当前用户信息", Tag: tagUser, Auth: openapi.AuthUser}},
+	{"PUT", "/api/user/info", openapi.Spec{Summary: "更新用户信息", Tag: tagUser, Auth: openapi.AuthUser, Request: model.UpdateUserRequest{}}},
+	{"POST", "/api/user/change-password", openapi.Spec{Summary: "修改密码", Tag: tagUser, Auth: openapi.AuthUser, Request: model.ChangePasswordRequest{}}},
+	{"POST", "/api/user/logout", openapi.Spec{Summary: "登出", Tag: tagUser, Auth: openapi.AuthUser}},
+	{"GET", "/api/user/auto-reply", openapi.Spec{Summary: "获取自动回复设置", Tag: tagUser, Auth: openapi.AuthUser, Optional: true}},
+	{"PUT", "/api/user/auto-reply", openapi.Spec{Summary: "更新自动回复设置", Tag: tagUser, Auth: openapi.AuthUser, Request: service.UpdateAutoReplyRequest{}, Optional: true}},
+	{"GET", "/api/user/notification-settings", openapi.Spec{Summary: "获取通知设置", Tag: tagUser, Auth: openapi.AuthUser, Optional: true}},
+	{"PUT", "/api/user/notification-settings", openapi.Spec{Summary: "更新通知设置", Tag: tagUser, Auth: openapi.AuthUser, Request: service.UpdateNotificationSettingsRequest{}, Optional: true}},
+	{"GET", "/api/users", openapi.Spec{Summary: "搜索用户", Tag: tagUser, Auth: openapi.AuthUser, Query: []string{"keyword", "limit"}}},
+	{"GET", "/api/users/:user_id", openapi.Spec{Summary: "根据ID获取用户", Tag: tagUser, Auth: openapi.AuthUser}},
+	{"GET", "/api/users/:user_id/names", openapi.Spec{Summary: "获取用户改名历史", Tag: tagUser, Auth: openapi.AuthUser, Query: []string{"limit"}}},
+	{"GET", "/api/presence", openapi.Spec{Summary: "查询用户在线状态", Tag: tagUser, Auth: openapi.AuthUser, Query: []string{"user_ids"}, Response: []*model.PresenceInfo{}}},
+	{"GET", "/api/features", openapi.Spec{Summary: "获取我的灰度分组", Tag: tagFeature, Auth: openapi.AuthUser}},
+
+	// 好友
+	{"GET", "/api/friends", openapi.Spec{Summary: "获取好友列表", Tag: tagFriend, Auth: openapi.AuthUser, Response: []*service.FriendView{}}},
+	{"DELETE", "/api/friends/:user_id", openapi.Spec{Summary: "删除好友", Tag: tagFriend, Auth: openapi.AuthUser}},
+	{"POST", "/api/friends/requests", openapi.Spec{Summary: "发送好友申请", Tag: tagFriend, Auth: openapi.AuthUser, Request: service.SendFriendRequestRequest{}, Response: model.FriendRequest{}}},
+	{"GET", "/api/friends/requests", openapi.Spec{Summary: "获取好友申请", Tag: tagFriend, Auth: openapi.AuthUser, Query: []string{"direction", "page", "page_size"}}},
+	{"POST", "/api/friends/requests/:request_id/accept", openapi.Spec{Summary: "通过好友申请", Tag: tagFriend, Auth: openapi.AuthUser, Response: model.FriendRequest{}}},
+	{"POST", "/api/friends/requests/:request_id/reject", openapi.Spec{Summary: "拒绝好友申请", Tag: tagFriend, Auth: openapi.AuthUser}},
+	{"GET", "/api/friends/blocked", openapi.Spec{Summary: "获取屏蔽列表", Tag: tagFriend, Auth: openapi.AuthUser, Response: []*service.BlockedUserView{}}},
+	{"PUT", "/api/friends/blocked/:user_id", openapi.Spec{Summary: "屏蔽用户", Tag: tagFriend, Auth: openapi.AuthUser}},
+	{"DELETE", "/api/friends/blocked/:user_id", openapi.Spec{Summary: "取消屏蔽", Tag: tagFriend, Auth: openapi.AuthUser}},
+
+	{"POST", "/api/cs/sessions", openapi.Spec{Summary: "发起客服会话", Tag: tagCS, Auth: openapi.AuthUser, Request: service.CreateCSSessionRequest{}, Response: service.CSSessionView{}}},
+	{"GET", "/api/cs/sessions/current", openapi.Spec{Summary: "获取当前客服会话", Tag: tagCS, Auth: openapi.AuthUser, Response: service.CSSessionView{}}},
+	{"GET", "/api/cs/sessions/:session_id", openapi.Spec{Summary: "获取客服会话", Tag: tagCS, Auth: openapi.AuthUser, Response: service.CSSessionView{}}},
+	{"POST", "/api/cs/sessions/:session_id/close", openapi.Spec{Summary: "结束客服会话", Tag: tagCS, Auth: openapi.AuthUser, Response: model.CSSession{}}},
+	{"POST", "/api/cs/sessions/:session_id/rating", openapi.Spec{Summary: "评价客服会话", Tag: tagCS, Auth: openapi.AuthUser, Request: service.RateCSSessionRequest{}}},
+	{"POST", "/api/cs/sessions/:session_id/transfer", openapi.Spec{Summary: "转接客服会话", Tag: tagCS, Auth: openapi.AuthUser, Request: service.TransferCSSessionRequest{}, Response: model.CSSession{}}},
+	{"PUT", "/api/cs/agent/status", openapi.Spec{Summary: "坐席上线/下线", Tag: tagCS, Auth: openapi.AuthUser, Request: SetCSAgentStatusRequest{}, Response: model.CSAgent{}}},
+	{"GET", "/api/cs/agent/sessions", openapi.Spec{Summary: "获取坐席的会话", Tag: tagCS, Auth: openapi.AuthUser, Query: []string{"status", "page", "page_size"}}},
+	{"GET", "/api/cs/queue", openapi.Spec{Summary: "获取排队中的会话", Tag: tagCS, Auth: openapi.AuthUser, Query: []string{"limit"}}},
+	{"GET", "/api/cs/canned-replies", openapi.Spec{Summary: "获取快捷回复", Tag: tagCS, Auth: openapi.AuthUser, Response: []*model.CSCannedReply{}}},
+	{"POST", "/api/cs/canned-replies", openapi.Spec{Summary: "创建快捷回复", Tag: tagCS, Auth: openapi.AuthUser, Request: service.CannedReplyRequest{}, Response: model.CSCannedReply{}}},
+	{"PUT", "/api/cs/canned-replies/:id", openapi.Spec{Summary: "修改快捷回复", Tag: tagCS, Auth: openapi.AuthUser, Request: service.CannedReplyRequest{}}},
+	{"DELETE", "/api/cs/canned-replies/:id", openapi.Spec{Summary: "删除快捷回复", Tag: tagCS, Auth: openapi.AuthUser}},
+
+	// 群组
+	{"GET", "/api/groups/my", openapi.Spec{Summary: "获取我的群组列表", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"POST", "/api/groups", openapi.Spec{Summary: "创建群组", Tag: tagGroup, Auth: openapi.AuthUser, Request: createGroupRequest{}}},
+	{"GET", "/api/groups/:group_id", openapi.Spec{Summary: "获取群组信息", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"PUT", "/api/groups/:group_id", openapi.Spec{Summary: "更新群组信息", Tag: tagGroup, Auth: openapi.AuthUser, Request: updateGroupRequest{}}},
+	{"DELETE", "/api/groups/:group_id", openapi.Spec{Summary: "解散群组", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"POST", "/api/groups/:group_id/join", openapi.Spec{Summary: "加入群组", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"POST", "/api/groups/:group_id/leave", openapi.Spec{Summary: "退出群组", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"GET", "/api/groups/:group_id/members", openapi.Spec{Summary: "获取群成员列表", Tag: tagGroup, Auth: openapi.AuthUser, Query: []string{"page", "page_size"}}},
+	{"GET", "/api/groups/:group_id/join-requests", openapi.Spec{Summary: "获取入群申请列表", Tag: tagGroup, Auth: openapi.AuthUser, Query: []string{"status", "page", "page_size"}}},
+	{"POST", "/api/groups/:group_id/join-requests/:request_id/approve", openapi.Spec{Summary: "同意入群申请", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"POST", "/api/groups/:group_id/join-requests/:request_id/reject", openapi.Spec{Summary: "拒绝入群申请", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"POST", "/api/groups/:group_id/kick", openapi.Spec{Summary: "踢出群成员", Tag: tagGroup, Auth: openapi.AuthUser, Request: kickMemberRequest{}}},
+	{"POST", "/api/groups/:group_id/admin", openapi.Spec{Summary: "设置/取消管理员", Tag: tagGroup, Auth: openapi.AuthUser, Request: setAdminRequest{}}},
+	{"POST", "/api/groups/:group_id/transfer", openapi.Spec{Summary: "转让群主", Tag: tagGroup, Auth: openapi.AuthUser, Request: transferOwnerRequest{}}},
+	{"POST", "/api/groups/:group_id/mute", openapi.Spec{Summary: "禁言成员", Tag: tagGroup, Auth: openapi.AuthUser, Request: muteMemberRequest{}}},
+	{"POST", "/api/groups/:group_id/mute-all", openapi.Spec{Summary: "设置全员禁言", Tag: tagGroup, Auth: openapi.AuthUser, Request: muteAllRequest{}}},
+	{"GET", "/api/groups/:group_id/file-policy", openapi.Spec{Summary: "获取群组文件类型策略", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"PUT", "/api/groups/:group_id/file-policy", openapi.Spec{Summary: "设置群组文件类型策略", Tag: tagGroup, Auth: openapi.AuthUser, Request: model.SetFileTypePolicyRequest{}}},
+	{"DELETE", "/api/groups/:group_id/file-policy", openapi.Spec{Summary: "删除群组文件类型策略", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"POST", "/api/groups/:group_id/polls", openapi.Spec{Summary: "发起群投票", Tag: tagGroup, Auth: openapi.AuthUser, Request: service.CreatePollRequest{}}},
+	{"GET", "/api/polls/:poll_id", openapi.Spec{Summary: "获取投票详情", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"POST", "/api/polls/:poll_id/votes", openapi.Spec{Summary: "投票", Tag: tagGroup, Auth: openapi.AuthUser, Request: service.VotePollRequest{}}},
+	{"POST", "/api/polls/:poll_id/close", openapi.Spec{Summary: "结束投票", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"GET", "/api/groups/:group_id/mention-groups", openapi.Spec{Summary: "获取群内提及组", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"POST", "/api/groups/:group_id/mention-groups", openapi.Spec{Summary: "创建提及组", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"PUT", "/api/groups/:group_id/mention-groups/:mention_group_id", openapi.Spec{Summary: "修改提及组", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"DELETE", "/api/groups/:group_id/mention-groups/:mention_group_id", openapi.Spec{Summary: "删除提及组", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"GET", "/api/groups/digests", openapi.Spec{Summary: "获取群活动摘要订阅列表", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"GET", "/api/groups/:group_id/digest", openapi.Spec{Summary: "获取群活动摘要订阅", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"PUT", "/api/groups/:group_id/digest", openapi.Spec{Summary: "订阅群活动每日摘要", Tag: tagGroup, Auth: openapi.AuthUser, Request: service.GroupDigestRequest{}}},
+	{"DELETE", "/api/groups/:group_id/digest", openapi.Spec{Summary: "取消群活动摘要订阅", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"GET", "/api/groups/:group_id/message-type-policy", openapi.Spec{Summary: "获取群组消息类型策略", Tag: tagGroup, Auth: openapi.AuthUser}},
+	{"PUT", "/api/groups/:group_id/message-type-policy", openapi.Spec{Summary: "设置群组消息类型策略", Tag: tagGroup, Auth: openapi.AuthUser, Request: model.SetMessageTypePolicyRequest{}}},
+	{"DELETE", "/api/groups/:group_id/message-type-policy", openapi.Spec{Summary: "删除群组消息类型策略", Tag: tagGroup, Auth: openapi.AuthUser}},
+
+	// 消息
+	{"GET", "/api/messages/conversation/:conversation_id", openapi.Spec{Summary: "获取会话消息历史", Tag: tagMessage, Auth: openapi.AuthUser, Query: []string{"last_seq", "limit"}}},
+	{"GET", "/api/messages/group/:group_id", openapi.Spec{Summary: "获取群聊消息历史", Tag: tagMessage, Auth: openapi.AuthUser, Query: []string{"last_seq", "limit"}}},
+	{"GET", "/api/messages/private/:user_id", openapi.Spec{Summary: "获取私聊消息历史", Tag: tagMessage, Auth: openapi.AuthUser, Query: []string{"last_seq", "limit"}}},
+	{"GET", "/api/messages/search", openapi.Spec{Summary: "搜索聊天记录", Tag: tagMessage, Auth: openapi.AuthUser, Query: []string{"keyword", "conversation_id", "sender_id", "since", "until", "page", "page_size"}, Optional: true}},
+	{"POST", "/api/messages/with-file", openapi.Spec{Summary: "发送文件消息", Tag: tagMessage, Auth: openapi.AuthUser, Form: []string{"message", "file", "sha256", "md5"}, Optional: true}},
+	{"POST", "/api/messages/:message_id/revoke", openapi.Spec{Summary: "撤回消息", Tag: tagMessage, Auth: openapi.AuthUser}},
+	{"POST", "/api/messages/:message_id/remind", openapi.Spec{Summary: "设置消息提醒", Tag: tagMessage, Auth: openapi.AuthUser, Request: service.CreateReminderRequest{}, Optional: true}},
+	{"POST", "/api/messages/conversation/:conversation_id/read", openapi.Spec{Summary: "标记会话已读", Tag: tagMessage, Auth: openapi.AuthUser, Request: service.MarkConversationReadRequest{}, Optional: true}},
+	{"DELETE", "/api/messages/:message_id/pending", openapi.Spec{Summary: "取消待投递消息", Tag: tagMessage, Auth: openapi.AuthUser, Response: service.PendingCancelResult{}, Optional: true}},
+	{"GET", "/api/timeline", openapi.Spec{Summary: "获取消息时间线", Tag: tagMessage, Auth: openapi.AuthUser, Query: []string{"limit"}}},
+	{"GET", "/api/message-schemas", openapi.Spec{Summary: "获取消息内容结构", Tag: tagMessage, Auth: openapi.AuthUser, Response: []*model.ContentSchema{}}},
+	{"GET", "/api/reminders", openapi.Spec{Summary: "获取消息提醒列表", Tag: tagMessage, Auth: openapi.AuthUser, Optional: true}},
+	{"DELETE", "/api/reminders/:reminder_id", openapi.Spec{Summary: "取消消息提醒", Tag: tagMessage, Auth: openapi.AuthUser, Optional: true}},
+
+	// 会话
+	{"GET", "/api/conversations", openapi.Spec{Summary: "获取会话列表", Tag: tagConversation, Auth: openapi.AuthUser, Query: []string{"page", "page_size"}}},
+	{"GET", "/api/conversations/:conversation_id", openapi.Spec{Summary: "获取会话详情", Tag: tagConversation, Auth: openapi.AuthUser}},
+	{"DELETE", "/api/conversations/:conversation_id", openapi.Spec{Summary: "删除会话", Tag: tagConversation, Auth: openapi.AuthUser}},
+	{"PUT", "/api/conversations/:conversation_id/pin", openapi.Spec{Summary: "置顶会话", Tag: tagConversation, Auth: openapi.AuthUser}},
+	{"DELETE", "/api/conversations/:conversation_id/pin", openapi.Spec{Summary: "取消置顶会话", Tag: tagConversation, Auth: openapi.AuthUser}},
+	{"PUT", "/api/conversations/:conversation_id/mute", openapi.Spec{Summary: "开启会话免打扰", Tag: tagConversation, Auth: openapi.AuthUser}},
+	{"DELETE", "/api/conversations/:conversation_id/mute", openapi.Spec{Summary: "关闭会话免打扰", Tag: tagConversation, Auth: openapi.AuthUser}},
+	{"GET", "/api/conversations/:conversation_id/search", openapi.Spec{Summary: "会话内搜索消息", Tag: tagConversation, Auth: openapi.AuthUser, Query: []string{"keyword", "before", "limit"}}},
+	{"GET", "/api/conversations/:conversation_id/messages/:message_id/context", openapi.Spec{Summary: "获取消息上下文", Tag: tagConversation, Auth: openapi.AuthUser, Query: []string{"before", "after"}}},
+	{"POST", "/api/conversations/:conversation_id/export", openapi.Spec{Summary: "导出会话记录", Tag: tagConversation, Auth: openapi.AuthUser, Request: service.ExportRequest{}, Optional: true}},
+	{"GET", "/api/conversations/:conversation_id/export/:job_id", openapi.Spec{Summary: "查询会话导出任务", Tag: tagConversation, Auth: openapi.AuthUser, Optional: true}},
+	{"GET", "/api/conversations/:conversation_id/encryption", openapi.Spec{Summary: "获取会话加密状态", Tag: tagConversation, Auth: openapi.AuthUser, Response: service.ConversationEncryption{}, Optional: true}},
+	{"PUT", "/api/conversations/:conversation_id/encryption", openapi.Spec{Summary: "开启或关闭会话加密", Tag: tagConversation, Auth: openapi.AuthUser, Request: service.SetEncryptionRequest{}, Response: service.ConversationEncryption{}, Optional: true}},
+	{"POST", "/api/conversations/:conversation_id/encryption/rotate", openapi.Spec{Summary: "轮换会话密钥", Tag: tagConversation, Auth: openapi.AuthUser, Request: service.RotateKeyRequest{}, Response: service.ConversationEncryption{}, Optional: true}},
+	{"GET", "/api/conversations/:conversation_id/pins", openapi.Spec{Summary: "查询会话置顶消息", Tag: tagConversation, Auth: openapi.AuthUser, Response: []service.PinnedMessageView{}, Optional: true}},
+	{"PUT", "/api/conversations/:conversation_id/pins/:message_id", openapi.Spec{Summary: "置顶消息", Tag: tagConversation, Auth: openapi.AuthUser, Response: model.PinnedMessage{}, Optional: true}},
+	{"DELETE", "/api/conversations/:conversation_id/pins/:message_id", openapi.Spec{Summary: "取消置顶消息", Tag: tagConversation, Auth: openapi.AuthUser, Optional: true}},
+	{"POST", "/api/conversations/:conversation_id/summarize", openapi.Spec{Summary: "生成会话摘要", Tag: tagConversation, Auth: openapi.AuthUser, Request: service.SummarizeRequest{}, Response: model.ConversationSummary{}, Optional: true}},
+	{"GET", "/api/conversations/:conversation_id/summaries", openapi.Spec{Summary: "查询我生成的会话摘要", Tag: tagConversation, Auth: openapi.AuthUser, Query: []string{"limit"}, Response: []model.ConversationSummary{}, Optional: true}},
+	{"GET", "/api/conversations/:conversation_id/app-policy", openapi.Spec{Summary: "获取会话自定义消息策略", Tag: tagApp, Auth: openapi.AuthUser}},
+	{"PUT", "/api/conversations/:conversation_id/app-policy", openapi.Spec{Summary: "设置会话自定义消息策略", Tag: tagApp, Auth: openapi.AuthUser, Request: conversationPolicyRequest{}}},
+
+	// 离线消息
+	{"GET", "/api/offline/messages", openapi.Spec{Summary: "拉取离线消息", Tag: tagOffline, Auth: openapi.AuthUser, Query: []string{"conversation_id", "last_seq", "limit"}}},
+	{"GET", "/api/offline/count", openapi.Spec{Summary: "获取离线消息数量", Tag: tagOffline, Auth: openapi.AuthUser}},
+	{"GET", "/api/offline/summary", openapi.Spec{Summary: "获取离线消息摘要", Tag: tagOffline, Auth: openapi.AuthUser}},
+	{"POST", "/api/offline/bootstrap", openapi.Spec{Summary: "新设备首次同步", Tag: tagOffline, Auth: openapi.AuthUser}},
+	{"POST", "/api/offline/ack", openapi.Spec{Summary: "确认离线消息", Tag: tagOffline, Auth: openapi.AuthUser, Request: ackMessagesRequest{}}},
+
+	// 文件
+	{"POST", "/api/file/upload", openapi.Spec{Summary: "上传文件", Tag: tagFile, Auth: openapi.AuthUser, Form: []string{"file", "group_id", "sha256", "md5"}, Query: []string{"purpose"}}},
+	{"GET", "/api/file/info/:file_id", openapi.Spec{Summary: "获取文件信息", Tag: tagFile, Auth: openapi.AuthUser}},
+	{"GET", "/api/file/url/:file_id", openapi.Spec{Summary: "获取文件访问URL", Tag: tagFile, Auth: openapi.AuthUser, Query: []string{"expiry"}}},
+	{"GET", "/api/file/download/:file_id", openapi.Spec{Summary: "下载文件", Tag: tagFile, Auth: openapi.AuthUser, Produces: "application/octet-stream"}},
+	{"GET", "/api/file/proxy/:file_id", openapi.Spec{Summary: "代理下载文件（URL签名鉴权）", Tag: tagFile, Query: []string{"expires", "nonce", "sig"}, Produces: "application/octet-stream"}},
+	{"DELETE", "/api/file/:file_id", openapi.Spec{Summary: "删除文件", Tag: tagFile, Auth: openapi.AuthUser}},
+	{"POST", "/api/file/:file_id/retain", openapi.Spec{Summary: "文件长期保存", Tag: tagFile, Auth: openapi.AuthUser, Optional: true}},
+	{"DELETE", "/api/file/:file_id/retain", openapi.Spec{Summary: "取消文件长期保存", Tag: tagFile, Auth: openapi.AuthUser, Optional: true}},
+	{"POST", "/api/file/multipart/init", openapi.Spec{Summary: "初始化分片上传", Tag: tagFile, Auth: openapi.AuthUser, Request: model.InitMultipartUploadRequest{}}},
+	{"POST", "/api/file/multipart/upload", openapi.Spec{Summary: "上传分片", Tag: tagFile, Auth: openapi.AuthUser, Form: []string{"upload_id", "part_number", "file"}}},
+	{"POST", "/api/file/multipart/complete", openapi.Spec{Summary: "完成分片上传", Tag: tagFile, Auth: openapi.AuthUser, Query: []string{"purpose"}, Request: completeMultipartRequest{}}},
+	{"POST", "/api/file/multipart/abort", openapi.Spec{Summary: "取消分片上传", Tag: tagFile, Auth: openapi.AuthUser, Request: abortMultipartRequest{}}},
+	{"POST", "/api/file/resumable", openapi.Spec{Summary: "创建断点续传会话", Tag: tagFile, Auth: openapi.AuthUser, Request: model.CreateUploadSessionRequest{}}},
+	{"PATCH", "/api/file/resumable/:upload_id", openapi.Spec{Summary: "追加上传数据", Tag: tagFile, Auth: openapi.AuthUser, Headers: []string{"Upload-Offset"}, Request: &openapi.Schema{Type: "string", Format: "binary"}, Consumes: "application/offset+octet-stream"}},
+	{"GET", "/api/file/resumable/:upload_id", openapi.Spec{Summary: "查询上传进度", Tag: tagFile, Auth: openapi.AuthUser}},
+	{"POST", "/api/file/resumable/:upload_id/complete", openapi.Spec{Summary: "完成断点续传", Tag: tagFile, Auth: openapi.AuthUser, Query: []string{"purpose"}}},
+	{"DELETE", "/api/file/resumable/:upload_id", openapi.Spec{Summary: "取消断点续传", Tag: tagFile, Auth: openapi.AuthUser}},
+
+	// 组织架构
+	{"GET", "/api/org/tree", openapi.Spec{Summary: "获取部门树", Tag: tagOrg, Auth: openapi.AuthUser}},
+	{"GET", "/api/org/search", openapi.Spec{Summary: "搜索通讯录", Tag: tagOrg, Auth: openapi.AuthUser, Query: []string{"keyword", "limit"}}},
+	{"GET", "/api/org/departments/:department_id/members", openapi.Spec{Summary: "获取部门成员", Tag: tagOrg, Auth: openapi.AuthUser, Query: []string{"page", "page_size"}}},
+	{"GET", "/api/org/users/:user_id/departments", openapi.Spec{Summary: "获取用户所属部门", Tag: tagOrg, Auth: openapi.AuthUser}},
+
+	// 管理
+	{"GET", "/api/admin/maintenance", openapi.Spec{OperationID: "adminGetMaintenance", Summary: "获取维护模式状态", Tag: tagAdmin, Auth: openapi.AuthAdmin}},
+	{"PUT", "/api/admin/maintenance", openapi.Spec{Summary: "设置维护模式", Tag: tagAdmin, Auth: openapi.AuthAdmin, Request: SetMaintenanceRequest{}}},
+	{"POST", "/api/admin/broadcast", openapi.Spec{Summary: "全局广播", Tag: tagAdmin, Auth: openapi.AuthAdmin, Request: NodeBroadcastRequest{}, Optional: true}},
+	{"GET", "/api/admin/nodes", openapi.Spec{Summary: "获取节点连接统计", Tag: tagAdmin, Auth: openapi.AuthAdmin, Optional: true}},
+	{"GET", "/api/admin/nodes/:node_id/connections", openapi.Spec{Summary: "获取节点连接列表", Tag: tagAdmin, Auth: openapi.AuthAdmin, Query: []string{"cursor", "limit"}, Optional: true}},
+	{"POST", "/api/admin/nodes/:node_id/broadcast", openapi.Spec{Summary: "节点定向广播", Tag: tagAdmin, Auth: openapi.AuthAdmin, Request: NodeBroadcastRequest{}, Optional: true}},
+	{"POST", "/api/admin/nodes/:node_id/drain", openapi.Spec{Summary: "排空节点", Tag: tagAdmin, Auth: openapi.AuthAdmin, Optional: true}},
+	{"GET", "/api/admin/clients/stats", openapi.Spec{Summary: "获取客户端分布", Tag: tagAdmin, Auth: openapi.AuthAdmin, Optional: true}},
+	{"GET", "/api/admin/latency", openapi.Spec{Summary: "获取消息处理耗时", Tag: tagAdmin, Auth: openapi.AuthAdmin, Response: []*service.NodeLatency{}, Optional: true}},
+	{"POST", "/api/admin/users/import", openapi.Spec{Summary: "批量导入用户", Tag: tagAdmin, Auth: openapi.AuthAdmin, Query: []string{"password_policy", "force_reset", "group_ids", "tenant_id", "dry_run"}, Request: service.UserImportRequest{}, Optional: true}},
+	{"PUT", "/api/admin/users/:user_id/status", openapi.Spec{Summary: "禁用/恢复账号", Tag: tagAdmin, Auth: openapi.AuthAdmin, Request: SetUserStatusRequest{}, Optional: true}},
+	{"DELETE", "/api/admin/users/:user_id", openapi.Spec{Summary: "注销账号", Tag: tagAdmin, Auth: openapi.AuthAdmin, Optional: true}},
+	{"GET", "/api/admin/users", openapi.Spec{Summary: "查询用户列表", Tag: tagAdmin, Auth: openapi.AuthAdmin, Query: []string{"keyword", "status", "tenant_id", "guest", "page", "page_size"}, Optional: true}},
+	{"POST", "/api/admin/users/:user_id/logout", openapi.Spec{Summary: "强制下线", Tag: tagAdmin, Auth: openapi.AuthAdmin, Optional: true}},
+	{"GET", "/api/admin/groups", openapi.Spec{Summary: "查询群组列表", Tag: tagAdmin, Auth: openapi.AuthAdmin, Query: []string{"keyword", "owner_id", "status", "page", "page_size"}, Optional: true}},
+	{"POST", "/api/admin/groups/:group_id/dismiss", openapi.Spec{Summary: "强制解散群组", Tag: tagAdmin, Auth: openapi.AuthAdmin, Request: DismissGroupRequest{}, Optional: true}},
+	{"GET", "/api/admin/groups/:group_id/successions", openapi.Spec{Summary: "查询群主继任记录", Tag: tagAdmin, Auth: openapi.AuthAdmin, Query: []string{"limit"}, Optional: true}},
+	{"GET", "/api/admin/regions", openapi.Spec{Summary: "获取数据驻留配置", Tag: tagAdmin, Auth: openapi.AuthAdmin, Response: service.RegionInfo{}, Optional: true}},
+	{"PUT", "/api/admin/users/:user_id/region", openapi.Spec{Summary: "设置用户所属区域", Tag: tagAdmin, Auth: openapi.AuthAdmin, Request: service.SetUserRegionRequest{}, Optional: true}},
+	{"GET", "/api/admin/messages/quarantine", openapi.Spec{Summary: "获取隔离消息", Tag: tagAdmin, Auth: openapi.AuthAdmin, Query: []string{"page", "page_size"}, Optional: true}},
+	{"POST", "/api/admin/messages/quarantine/:message_id/release", openapi.Spec{Summary: "放行隔离消息", Tag: tagAdmin, Auth: openapi.AuthAdmin, Optional: true}},
+	{"DELETE", "/api/admin/messages/quarantine/:message_id", openapi.Spec{Summary: "丢弃隔离消息", Tag: tagAdmin, Auth: openapi.AuthAdmin, Optional: true}},
+	{"POST", "/api/admin/conversations/:conversation_id/purge", openapi.Spec{Summary: "批量清除会话消息", Tag: tagAdmin, Auth: openapi.AuthAdmin, Request: service.PurgeMessagesRequest{}, Response: service.PurgeMessagesResult{}, Optional: true}},
+	{"GET", "/api/admin/analytics/overview", openapi.Spec{Summary: "获取会话分析概览", Tag: tagAdmin, Auth: openapi.AuthAdmin}},
+	{"GET", "/api/admin/analytics/conversations", openapi.Spec{Summary: "分页查询会话统计", Tag: tagAdmin, Auth: openapi.AuthAdmin, Query: []string{"type", "sort", "active_hours", "page", "page_size"}}},
+	{"GET", "/api/admin/analytics/conversations/:conversation_id", openapi.Spec{Summary: "查询会话统计", Tag: tagAdmin, Auth: openapi.AuthAdmin}},
+	{"GET", "/api/admin/conversations/encrypted", openapi.Spec{Summary: "分页查询加密会话", Tag: tagAdmin, Auth: openapi.AuthAdmin, Query: []string{"page", "page_size"}, Optional: true}},
+	{"GET", "/api/admin/rbac/permissions", openapi.Spec{Summary: "获取管理权限列表", Tag: tagAdmin, Auth: openapi.AuthAdmin, Response: []string{}}},
+	{"GET", "/api/admin/rbac/roles", openapi.Spec{Summary: "获取管理角色列表", Tag: tagAdmin, Auth: openapi.AuthAdmin, Response: []*model.AdminRole{}}},
+	{"PUT", "/api/admin/rbac/roles/:role", openapi.Spec{Summary: "创建或更新自定义角色", Tag: tagAdmin, Auth: openapi.AuthAdmin, Request: model.SaveAdminRoleRequest{}, Response: model.AdminRole{}}},
+	{"DELETE", "/api/admin/rbac/roles/:role", openapi.Spec{Summary: "删除自定义角色", Tag: tagAdmin, Auth: openapi.AuthAdmin}},
+	{"GET", "/api/admin/rbac/assignments", openapi.Spec{Summary: "获取角色分配列表", Tag: tagAdmin, Auth: openapi.AuthAdmin, Response: []*model.AdminRoleAssignment{}}},
+	{"GET", "/api/admin/rbac/users/:user_id/roles", openapi.Spec{Summary: "获取用户的管理角色", Tag: tagAdmin, Auth: openapi.AuthAdmin}},
+	{"PUT", "/api/admin/rbac/users/:user_id/roles", openapi.Spec{Summary: "设置用户的管理角色", Tag: tagAdmin, Auth: openapi.AuthAdmin, Request: model.SetUserRolesRequest{}}},
+	{"GET", "/api/admin/audit-logs", openapi.Spec{Summary: "查询审计日志", Tag: tagAdmin, Auth: openapi.AuthAdmin, Query: []string{"user_id", "permission", "outcome", "since", "until", "page", "page_size"}}},
+	{"GET", "/api/admin/moderation/violations", openapi.Spec{Summary: "查询内容审核违规记录", Tag: tagAdmin, Auth: openapi.AuthAdmin, Query: []string{"user_id", "conversation_id", "source", "action", "since", "until", "page", "page_size"}, Optional: true}},
+	{"GET", "/api/admin/files/policy", openapi.Spec{Summary: "获取全局文件类型策略", Tag: tagAdmin, Auth: openapi.AuthAdmin}},
+	{"PUT", "/api/admin/files/policy", openapi.Spec{Summary: "设置全局文件类型策略", Tag: tagAdmin, Auth: openapi.AuthAdmin, Request: model.SetFileTypePolicyRequest{}}},
+	{"GET", "/api/admin/message-type-policy", openapi.Spec{Summary: "获取全局或租户消息类型策略", Tag: tagAdmin, Auth: openapi.AuthAdmin, Query: []string{"tenant_id"}}},
+	{"PUT", "/api/admin/message-type-policy", openapi.Spec{Summary: "设置全局或租户消息类型策略", Tag: tagAdmin, Auth: openapi.AuthAdmin, Query: []string{"tenant_id"}, Request: model.SetMessageTypePolicyRequest{}}},
+	{"DELETE", "/api/admin/message-type-policy", openapi.Spec{Summary: "删除全局或租户消息类型策略", Tag: tagAdmin, Auth: openapi.AuthAdmin, Query: []string{"tenant_id"}}},
+	{"GET", "/api/admin/flags", openapi.Spec{Summary: "获取功能开关列表", Tag: tagFeature, Auth: openapi.AuthAdmin}},
+	{"PUT", "/api/admin/flags/:key", openapi.Spec{Summary: "创建或更新功能开关", Tag: tagFeature, Auth: openapi.AuthAdmin, Request: model.SetFeatureFlagRequest{}}},
+	{"DELETE", "/api/admin/flags/:key", openapi.Spec{Summary: "删除功能开关", Tag: tagFeature, Auth: openapi.AuthAdmin}},
+	{"GET", "/api/admin/flags/:key/metrics", openapi.Spec{Summary: "对比灰度分组指标", Tag: tagFeature, Auth: openapi.AuthAdmin}},
+	{"DELETE", "/api/admin/flags/:key/metrics", openapi.Spec{Summary: "重置灰度分组指标", Tag: tagFeature, Auth: openapi.AuthAdmin}},
+
+	// 端到端加密
+	{"GET", "/api/e2ee/devices", openapi.Spec{Summary: "获取我的加密设备", Tag: tagE2EE, Auth: openapi.AuthUser, Response: []*model.E2EEDevice{}}},
+	{"PUT", "/api/e2ee/devices/:device_id", openapi.Spec{Summary: "注册设备加密密钥", Tag: tagE2EE, Auth: openapi.AuthUser, Request: model.RegisterE2EEKeysRequest{}, Response: model.E2EEDevice{}}},
+	{"POST", "/api/e2ee/devices/:device_id/prekeys", openapi.Spec{Summary: "补充一次性预密钥", Tag: tagE2EE, Auth: openapi.AuthUser, Request: model.UploadE2EEPreKeysRequest{}, Response: model.E2EEDevice{}}},
+	{"DELETE", "/api/e2ee/devices/:device_id", openapi.Spec{Summary: "删除设备加密密钥", Tag: tagE2EE, Auth: openapi.AuthUser}},
+	{"GET", "/api/e2ee/users/:user_id/devices", openapi.Spec{Summary: "获取用户的加密设备", Tag: tagE2EE, Auth: openapi.AuthUser, Response: []*model.E2EEDevice{}}},
+	{"POST", "/api/e2ee/users/:user_id/bundles", openapi.Spec{Summary: "获取预密钥包", Tag: tagE2EE, Auth: openapi.AuthUser, Query: []string{"device_id"}, Response: []*model.E2EEPreKeyBundle{}}},
+
+	// 用量
+	{"GET", "/api/usage", openapi.Spec{Summary: "获取我的月度用量", Tag: tagUsage, Auth: openapi.AuthUser, Query: []string{"month"}, Response: model.MonthlyUsage{}, Optional: true}},
+	{"GET", "/api/usage/sessions", openapi.Spec{Summary: "获取我的连接会话记录", Tag: tagUsage, Auth: openapi.AuthUser, Query: []string{"since", "until", "page", "page_size"}, Optional: true}},
+	{"GET", "/api/admin/usage/users", openapi.Spec{Summary: "查询用户月度用量", Tag: tagUsage, Auth: openapi.AuthAdmin, Query: []string{"month", "tenant_id", "sort", "page", "page_size"}, Optional: true}},
+	{"GET", "/api/admin/usage/tenants", openapi.Spec{Summary: "查询租户月度用量", Tag: tagUsage, Auth: openapi.AuthAdmin, Query: []string{"month", "tenant_id"}, Response: []*model.TenantUsage{}, Optional: true}},
+	{"GET", "/api/admin/users/:user_id/usage", openapi.Spec{Summary: "查询指定用户的月度用量", Tag: tagUsage, Auth: openapi.AuthAdmin, Query: []string{"month"}, Response: model.MonthlyUsage{}, Optional: true}},
+	{"GET", "/api/admin/users/:user_id/sessions", openapi.Spec{Summary: "查询指定用户的连接会话记录", Tag: tagUsage, Auth: openapi.AuthAdmin, Query: []string{"since", "until", "page", "page_size"}, Optional: true}},
+
+	// 推送
+	{"POST", "/api/push/opened", openapi.Spec{Summary: "推送打开/确认回调", Tag: tagPush, Auth: openapi.AuthUser, Request: model.PushOpenedRequest{}}},
+	{"POST", "/api/device/register", openapi.Spec{Summary: "注册推送设备", Tag: tagPush, Auth: openapi.AuthUser, Request: model.RegisterDeviceRequest{}, Optional: true}},
+	{"POST", "/api/device/unregister", openapi.Spec{Summary: "注销推送设备", Tag: tagPush, Auth: openapi.AuthUser, Request: model.UnregisterDeviceRequest{}, Optional: true}},
+	{"GET", "/api/device/webpush-key", openapi.Spec{Summary: "获取 Web Push 公钥", Tag: tagPush, Auth: openapi.AuthUser, Optional: true}},
+	{"GET", "/api/admin/push/analytics", openapi.Spec{Summary: "推送分析", Tag: tagPush, Auth: openapi.AuthAdmin}},
+	{"GET", "/api/admin/push/experiments", openapi.Spec{Summary: "获取推送文案实验列表", Tag: tagPush, Auth: openapi.AuthAdmin, Response: []*model.PushExperiment{}}},
+	{"PUT", "/api/admin/push/experiments/:key", openapi.Spec{Summary: "创建或更新推送文案实验", Tag: tagPush, Auth: openapi.AuthAdmin, Request: model.SetPushExperimentRequest{}, Response: model.PushExperiment{}}},
+	{"DELETE", "/api/admin/push/experiments/:key", openapi.Spec{Summary: "删除推送文案实验", Tag: tagPush, Auth: openapi.AuthAdmin}},
+	{"GET", "/api/admin/push/experiments/:key/stats", openapi.Spec{Summary: "获取推送文案实验指标", Tag: tagPush, Auth: openapi.AuthAdmin, Response: service.PushExperimentStats{}}},
+	{"GET", "/api/admin/apps", openapi.Spec{Summary: "获取集成应用列表", Tag: tagApp, Auth: openapi.AuthAdmin}},
+	{"POST", "/api/admin/apps", openapi.Spec{Summary: "创建集成应用", Tag: tagApp, Auth: openapi.AuthAdmin, Request: service.CreateAppRequest{}}},
+	{"PUT", "/api/admin/apps/:app_id", openapi.Spec{Summary: "修改集成应用", Tag: tagApp, Auth: openapi.AuthAdmin, Request: service.UpdateAppRequest{}}},
+	{"DELETE", "/api/admin/apps/:app_id", openapi.Spec{Summary: "删除集成应用", Tag: tagApp, Auth: openapi.AuthAdmin}},
+	{"POST", "/api/admin/apps/:app_id/secret", openapi.Spec{Summary: "重置集成应用密钥", Tag: tagApp, Auth: openapi.AuthAdmin}},
+	{"POST", "/api/bridge/slack/events", openapi.Spec{Summary: "接收Slack事件", Tag: tagApp, Headers: []string{"X-Slack-Signature", "X-Slack-Request-Timestamp"}, Optional: true}},
+	{"PUT", "/api/bridge/matrix/_matrix/app/v1/transactions/:txn_id", openapi.Spec{Summary: "接收Matrix事务", Tag: tagApp, Query: []string{"access_token"}, Optional: true}},
+	{"GET", "/api/admin/bridges/rooms", openapi.Spec{Summary: "获取桥接频道映射列表", Tag: tagApp, Auth: openapi.AuthAdmin, Response: []*model.BridgeRoom{}, Optional: true}},
+	{"POST", "/api/admin/bridges/rooms", openapi.Spec{Summary: "绑定群组与外部频道", Tag: tagApp, Auth: openapi.AuthAdmin, Request: model.BindBridgeRoomRequest{}, Response: model.BridgeRoom{}, Optional: true}},
+	{"DELETE", "/api/admin/bridges/rooms/:id", openapi.Spec{Summary: "解除频道映射", Tag: tagApp, Auth: openapi.AuthAdmin, Optional: true}},
+	{"GET", "/api/admin/bridges/users", openapi.Spec{Summary: "获取外部用户映射列表", Tag: tagApp, Auth: openapi.AuthAdmin, Query: []string{"platform"}, Response: []*model.BridgeUser{}, Optional: true}},
+	{"POST", "/api/admin/bridges/users", openapi.Spec{Summary: "映射外部用户", Tag: tagApp, Auth: openapi.AuthAdmin, Request: model.MapBridgeUserRequest{}, Response: model.BridgeUser{}, Optional: true}},
+	{"DELETE", "/api/admin/bridges/users/:platform/:remote_user_id", openapi.Spec{Summary: "删除外部用户映射", Tag: tagApp, Auth: openapi.AuthAdmin, Optional: true}},
+	{"POST", "/api/admin/org/departments", openapi.Spec{Summary: "创建部门", Tag: tagOrg, Auth: openapi.AuthAdmin, Request: service.CreateDepartmentRequest{}}},
+	{"PUT", "/api/admin/org/departments/:department_id", openapi.Spec{Summary: "更新部门", Tag: tagOrg, Auth: openapi.AuthAdmin, Request: service.UpdateDepartmentRequest{}}},
+	{"DELETE", "/api/admin/org/departments/:department_id", openapi.Spec{Summary: "删除部门", Tag: tagOrg, Auth: openapi.AuthAdmin}},
+	{"POST", "/api/admin/org/departments/:department_id/members", openapi.Spec{Summary: "添加部门成员", Tag: tagOrg, Auth: openapi.AuthAdmin, Request: addMembersRequest{}}},
+	{"DELETE", "/api/admin/org/departments/:department_id/members/:user_id", openapi.Spec{Summary: "移除部门成员", Tag: tagOrg, Auth: openapi.AuthAdmin}},
+	{"GET", "/api/admin/cs/agents", openapi.Spec{Summary: "获取坐席池", Tag: tagCS, Auth: openapi.AuthAdmin, Response: []*model.CSAgent{}}},
+	{"PUT", "/api/admin/cs/agents/:user_id", openapi.Spec{Summary: "添加坐席", Tag: tagCS, Auth: openapi.AuthAdmin, Request: service.SaveCSAgentRequest{}, Response: model.CSAgent{}}},
+	{"DELETE", "/api/admin/cs/agents/:user_id", openapi.Spec{Summary: "移出坐席", Tag: tagCS, Auth: openapi.AuthAdmin}},
+	{"GET", "/api/admin/cs/canned-replies", openapi.Spec{Summary: "获取公共快捷回复", Tag: tagCS, Auth: openapi.AuthAdmin, Response: []*model.CSCannedReply{}}},
+	{"POST", "/api/admin/cs/canned-replies", openapi.Spec{Summary: "创建公共快捷回复", Tag: tagCS, Auth: openapi.AuthAdmin, Request: service.CannedReplyRequest{}, Response: model.CSCannedReply{}}},
+	{"PUT", "/api/admin/cs/canned-replies/:id", openapi.Spec{Summary: "修改公共快捷回复", Tag: tagCS, Auth: openapi.AuthAdmin, Request: service.CannedReplyRequest{}}},
+	{"DELETE", "/api/admin/cs/canned-replies/:id", openapi.Spec{Summary: "删除公共快捷回复", Tag: tagCS, Auth: openapi.AuthAdmin}},
+}
+
+// newOpenAPIGenerator 创建文档生成器，注册统一响应结构、错误码、分页参数和认证方式
+func newOpenAPIGenerator() *openapi.Generator {
+	g := openapi.NewGenerator(openapi.Info{
+		Title:       "IM System API",
+		Description: "即时通讯系统API文档（由路由注册生成）",
+		Version:     "1.0",
+	})
+	for _, tag := range []string{tagUser, tagGroup, tagMessage, tagConversation, tagFile, tagOffline, tagOrg, tagFriend, tagCS, tagFeature, tagPush, tagApp, tagUsage, tagE2EE, tagAdmin, tagI18n, tagSystem} {
+		g.AddTag(tag, "")
+	}
+
+	g.SetSecurity("BearerAuth", &openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+		Description:  "Authorization: Bearer {token}，浏览器也可使用 Cookie 会话",
+	})
+
+	// 错误码：全部已注册错误码及文案见 GET /api/i18n/error-codes
+	enum := []interface{}{errcode.CodeUnauthorized, errcode.CodeInternal}
+	for _, code := range errcode.All() {
+		if code.Code != errcode.CodeUnauthorized && code.Code != errcode.CodeInternal {
+			enum = append(enum, code.Code)
+		}
+	}
+	g.AddSchema("ErrorCode", &openapi.Schema{Type: "integer", Enum: enum, Description: "业务错误码，文案见 GET /api/i18n/error-codes"})
+	g.AddSchema("ErrorResponse", &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"code":    openapi.RefSchema("ErrorCode"),
+			"error":   {Type: "string", Description: "本地化错误信息"},
+			"message": {Type: "string", Description: "文件接口的错误信息"},
+		},
+	})
+	g.SetErrorResponse("Error", &openapi.Response{
+		Description: "错误",
+		Content:     map[string]*openapi.MediaType{"application/json": {Schema: openapi.RefSchema("ErrorResponse")}},
+	})
+	g.SetEnvelope(func(data *openapi.Schema) *openapi.Schema {
+		return &openapi.Schema{
+			Type:     "object",
+			Required: []string{"code"},
+			Properties: map[string]*openapi.Schema{
+				"code":    {Type: "integer", Enum: []interface{}{errcode.CodeOK}},
+				"message": {Type: "string"},
+				"data":    data,
+			},
+		}
+	})
+
+	// 公共查询参数：分页（page/page_size）、游标（cursor/limit、last_seq/limit）及语言
+	g.AddParameter(&openapi.Parameter{Name: "page", In: "query", Description: "页码，从1开始", Schema: &openapi.Schema{Type: "integer", Default: 1}})
+	g.AddParameter(&openapi.Parameter{Name: "page_size", In: "query", Description: "每页条数", Schema: &openapi.Schema{Type: "integer", Default: 20}})
+	g.AddParameter(&openapi.Parameter{Name: "limit", In: "query", Description: "返回条数上限", Schema: &openapi.Schema{Type: "integer"}})
+	g.AddParameter(&openapi.Parameter{Name: "cursor", In: "query", Description: "分页游标，取上一页返回的 next_cursor", Schema: &openapi.Schema{Type: "string"}})
+	g.AddParameter(&openapi.Parameter{Name: "last_seq", In: "query", Description: "从该序号之后拉取", Schema: &openapi.Schema{Type: "integer", Format: "int64", Default: 0}})
+	g.AddParameter(&openapi.Parameter{Name: "locale", In: "query", Description: "语言，如 zh-CN、en-US", Schema: &openapi.Schema{Type: "string"}})
+
+	// /api 下的路由必须有接口描述
+	g.RequirePrefix("/api/")
+	for _, api := range apiSpecs {
+		g.Describe(api.method, api.path, api.spec)
+	}
+	return g
+}
+
+// OpenAPIHandler OpenAPI文档处理器
+type OpenAPIHandler struct {
+	engine    *gin.Engine
+	generator *openapi.Generator
+
+	once sync.Once
+	doc  []byte
+	err  error
+}
+
+// NewOpenAPIHandler 创建OpenAPI文档处理器（文档在首次请求时按已注册的路由生成）
+func NewOpenAPIHandler(engine *gin.Engine) *OpenAPIHandler {
+	return &OpenAPIHandler{
+		engine:    engine,
+		generator: newOpenAPIGenerator(),
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *OpenAPIHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/openapi.json", h.GetSpec)
+}
+
+// routes 已注册的路由
+func (h *OpenAPIHandler) routes() []openapi.Route {
+	infos := h.engine.Routes()
+	routes := make([]openapi.Route, 0, len(infos))
+	for _, info := range infos {
+		routes = append(routes, openapi.Route{Method: info.Method, Path: info.Path, Handler: info.Handler})
+	}
+	return routes
+}
+
+// Check 校验已注册的路由与接口描述是否一致，返回不一致项
+func (h *OpenAPIHandler) Check() []string {
+	return h.generator.Check(h.routes())
+}
+
+// GetSpec 获取OpenAPI文档
+// @Summary		获取OpenAPI文档
+// @Description	按已注册的路由生成的 OpenAPI 3 文档，可用于生成前端SDK
+// @Tags			系统
+// @Produce		json
+// @Success		200	{object}	map[string]interface{}	"OpenAPI文档"
+// @Router			/openapi.json [get]
+func (h *OpenAPIHandler) GetSpec(c *gin.Context) {
+	h.once.Do(func() {
+		h.doc, h.err = json.Marshal(h.generator.Build(h.routes()))
+	})
+	if h.err != nil {
+		respondError(c, h.err)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.doc)
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/gateway"
+	"github.com/d60-lab/im-system/pkg/auth"
+)
+
+// registerAPIRoutes 按 app.Server 的方式注册全部 API 路由，可选服务均不配置，处理器不会被调用
+func registerAPIRoutes(r *gin.Engine) {
+	jwtManager := auth.NewJWTManager(nil)
+	ra := &RouteAuth{}
+
+	(&gateway.WebSocketHandler{}).RegisterRoutes(r)
+	NewGroupHandler(nil).RegisterRoutes(r, ra)
+	NewConversationHandler(nil).RegisterRoutes(r, ra)
+	NewOfflineHandler(nil).RegisterRoutes(r, ra)
+	NewUserHandler(nil, jwtManager).RegisterRoutes(r, ra)
+	NewGuestHandler(nil, jwtManager).RegisterRoutes(r, ra)
+	NewFriendHandler(nil).RegisterRoutes(r, ra)
+	NewE2EEHandler(nil).RegisterRoutes(r, ra)
+	NewPresenceHandler(nil).RegisterRoutes(r, ra)
+	NewPollHandler(nil).RegisterRoutes(r, ra)
+	NewMentionHandler(nil).RegisterRoutes(r, ra)
+	NewGroupDigestHandler(nil).RegisterRoutes(r, ra)
+	NewUsageHandler(nil).RegisterRoutes(r, ra)
+	NewCSHandler(nil).RegisterRoutes(r, ra)
+	NewRBACHandler(nil).RegisterRoutes(r, ra)
+	NewAdminHandler(nil).RegisterRoutes(r, ra)
+	NewAnalyticsHandler(nil).RegisterRoutes(r, ra)
+	NewFeatureHandler(nil).RegisterRoutes(r, ra)
+	NewRegisterDeviceHandler(nil).RegisterRoutes(r, ra)
+	NewPushHandler(nil).RegisterRoutes(r, ra)
+	NewIntegrationHandler(nil).RegisterRoutes(r, ra)
+	NewBridgeHandler(nil).RegisterRoutes(r, ra)
+	NewOrgHandler(nil, nil).RegisterRoutes(r, ra)
+	NewI18nHandler().RegisterRoutes(r)
+	NewTimeHandler(time.Minute).RegisterRoutes(r)
+	NewMessageHandler(nil, nil).RegisterRoutes(r.Group("/api", NewAuthMiddleware(nil)))
+	NewFileHandler(nil).RegisterRoutes(r, ra)
+	NewFilePolicyHandler(nil).RegisterRoutes(r, ra)
+	NewModerationHandler(nil).RegisterRoutes(r, ra)
+	NewMessageTypePolicyHandler(nil).RegisterRoutes(r, ra)
+}
+
+func TestOpenAPIRoutesDescribed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	registerAPIRoutes(engine)
+
+	openAPIHandler := NewOpenAPIHandler(engine)
+	openAPIHandler.RegisterRoutes(engine)
+
+	if problems := openAPIHandler.Check(); len(problems) > 0 {
+		t.Fatalf("routes do not match OpenAPI descriptions:\n%s", strings.Join(problems, "\n"))
+	}
+}
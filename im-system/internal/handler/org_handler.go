@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// OrgHandler 组织架构/通讯录处理器
+type OrgHandler struct {
+	orgService  service.OrgService
+	rbacService service.RBACService
+}
+
+// NewOrgHandler 创建组织架构处理器，rbacService 用于判断查看者是否有组织架构维护权限（可为空）
+func NewOrgHandler(orgService service.OrgService, rbacService service.RBACService) *OrgHandler {
+	return &OrgHandler{
+		orgService:  orgService,
+		rbacService: rbacService,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *OrgHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	org := r.Group("/api/org")
+	org.Use(NewAuthMiddleware(ra.Authenticator))
+	{
+		org.GET("/tree", h.GetTree)
+		org.GET("/search", h.Search)
+		org.GET("/departments/:department_id/members", h.GetDepartmentMembers)
+		org.GET("/users/:user_id/departments", h.GetUserDepartments)
+	}
+
+	admin := r.Group("/api/admin/org")
+	admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+	{
+		admin.POST("/departments", h.CreateDepartment)
+		admin.PUT("/departments/:department_id", h.UpdateDepartment)
+		admin.DELETE("/departments/:department_id", h.DeleteDepartment)
+		admin.POST("/departments/:department_id/members", h.AddMembers)
+		admin.DELETE("/departments/:department_id/members/:user_id", h.RemoveMember)
+	}
+}
+
+// viewer 当前请求的通讯录查看者（有组织架构维护权限的管理员可见全部部门）
+func (h *OrgHandler) viewer(c *gin.Context) *service.OrgViewer {
+	return &service.OrgViewer{UserID: c.GetString("user_id"), Admin: hasAdminPermission(c, h.rbacService, model.PermOrgWrite)}
+}
+
+// GetTree 获取部门树
+// @Summary		获取部门树
+// @Description	获取当前用户所在租户的部门树，仅返回当前用户可见的部门
+// @Tags			组织架构
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"部门树"
+// @Failure		401	{object}	map[string]interface{}	"未授权"
+// @Router			/org/tree [get]
+func (h *OrgHandler) GetTree(c *gin.Context) {
+	tree, err := h.orgService.GetTree(c.Request.Context(), h.viewer(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    tree,
+	})
+}
+
+// GetDepartmentMembers 获取部门成员
+// @Summary		获取部门成员
+// @Description	分页获取部门直属成员
+// @Tags			组织架构
+// @Produce		json
+// @Security		BearerAuth
+// @Param			department_id	path		string					true	"部门ID"
+// @Param			page			query		int						false	"页码"	default(1)
+// @Param			page_size		query		int						false	"每页数量"	default(20)
+// @Success		200				{object}	map[string]interface{}	"成员列表"
+// @Failure		404				{object}	map[string]interface{}	"部门不存在或不可见"
+// @Router			/org/departments/{department_id}/members [get]
+func (h *OrgHandler) GetDepartmentMembers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	members, total, err := h.orgService.GetDepartmentMembers(c.Request.Context(), h.viewer(c), c.Param("department_id"), page, pageSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":   total,
+			"members": members,
+		},
+	})
+}
+
+// Search 搜索通讯录
+// @Summary		搜索通讯录
+// @Description	在当前用户可见的部门内按用户名或昵称搜索同事
+// @Tags			组织架构
+// @Produce		json
+// @Security		BearerAuth
+// @Param			keyword	query		string					true	"关键字"
+// @Param			limit	query		int						false	"返回条数（最大50）"	default(20)
+// @Success		200		{object}	map[string]interface{}	"联系人列表"
+// @Failure		400		{object}	map[string]interface{}	"关键字为空"
+// @Router			/org/search [get]
+func (h *OrgHandler) Search(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	contacts, err := h.orgService.Search(c.Request.Context(), h.viewer(c), c.Query("keyword"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    contacts,
+	})
+}
+
+// GetUserDepartments 获取用户所属部门
+// @Summary		获取用户所属部门
+// @Description	获取用户在当前用户可见部门中的任职信息
+// @Tags			组织架构
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string					true	"用户ID"
+// @Success		200		{object}	map[string]interface{}	"任职列表"
+// @Router			/org/users/{user_id}/departments [get]
+func (h *OrgHandler) GetUserDepartments(c *gin.Context) {
+	departments, err := h.orgService.GetUserDepartments(c.Request.Context(), h.viewer(c), c.Param("user_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    departments,
+	})
+}
+
+// CreateDepartment 创建部门（管理员）
+// @Summary		创建部门
+// @Description	创建部门，parent_id 为空时创建根部门
+// @Tags			组织架构
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		service.CreateDepartmentRequest	true	"部门信息"
+// @Success		200		{object}	map[string]interface{}			"创建的部门"
+// @Failure		404		{object}	map[string]interface{}			"上级部门不存在"
+// @Router			/admin/org/departments [post]
+func (h *OrgHandler) CreateDepartment(c *gin.Context) {
+	var req service.CreateDepartmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dept, err := h.orgService.CreateDepartment(c.Request.Context(), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    dept,
+	})
+}
+
+// UpdateDepartment 更新部门（管理员）
+// @Summary		更新部门
+// @Description	修改部门名称、排序、可见范围或上级部门，修改上级部门时整棵子树随之移动
+// @Tags			组织架构
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			department_id	path		string							true	"部门ID"
+// @Param			request			body		service.UpdateDepartmentRequest	true	"更新字段"
+// @Success		200				{object}	map[string]interface{}			"更新成功"
+// @Failure		400				{object}	map[string]interface{}			"不能移动到自身或下级部门"
+// @Failure		404				{object}	map[string]interface{}			"部门不存在"
+// @Router			/admin/org/departments/{department_id} [put]
+func (h *OrgHandler) UpdateDepartment(c *gin.Context) {
+	var req service.UpdateDepartmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.DepartmentID = c.Param("department_id")
+
+	if err := h.orgService.UpdateDepartment(c.Request.Context(), &req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// DeleteDepartment 删除部门（管理员）
+// @Summary		删除部门
+// @Description	删除部门，部门下仍有下级部门或成员时拒绝删除
+// @Tags			组织架构
+// @Produce		json
+// @Security		BearerAuth
+// @Param			department_id	path		string					true	"部门ID"
+// @Success		200				{object}	map[string]interface{}	"删除成功"
+// @Failure		400				{object}	map[string]interface{}	"部门非空"
+// @Router			/admin/org/departments/{department_id} [delete]
+func (h *OrgHandler) DeleteDepartment(c *gin.Context) {
+	if err := h.orgService.DeleteDepartment(c.Request.Context(), c.Param("department_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// addMembersRequest 添加部门成员请求
+type addMembersRequest struct {
+	Members []*service.DepartmentMemberInput `json:"members" binding:"required,min=1,dive"`
+}
+
+// AddMembers 添加部门成员（管理员）
+// @Summary		添加部门成员
+// @Description	批量添加部门成员，已在部门中的成员更新职位、主部门及排序
+// @Tags			组织架构
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			department_id	path		string					true	"部门ID"
+// @Success		200				{object}	map[string]interface{}	"添加成功"
+// @Failure		404				{object}	map[string]interface{}	"部门或用户不存在"
+// @Router			/admin/org/departments/{department_id}/members [post]
+func (h *OrgHandler) AddMembers(c *gin.Context) {
+	var req addMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.orgService.AddMembers(c.Request.Context(), c.Param("department_id"), req.Members); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// RemoveMember 移除部门成员（管理员）
+// @Summary		移除部门成员
+// @Tags			组织架构
+// @Produce		json
+// @Security		BearerAuth
+// @Param			department_id	path		string					true	"部门ID"
+// @Param			user_id			path		string					true	"用户ID"
+// @Success		200				{object}	map[string]interface{}	"移除成功"
+// @Router			/admin/org/departments/{department_id}/members/{user_id} [delete]
+func (h *OrgHandler) RemoveMember(c *gin.Context) {
+	if err := h.orgService.RemoveMember(c.Request.Context(), c.Param("department_id"), c.Param("user_id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// PollHandler 群投票处理器
+type PollHandler struct {
+	pollService service.PollService
+}
+
+// NewPollHandler 创建群投票处理器
+func NewPollHandler(pollService service.PollService) *PollHandler {
+	return &PollHandler{pollService: pollService}
+}
+
+// RegisterRoutes 注册路由
+func (h *PollHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	r.POST("/api/groups/:group_id/polls", NewAuthMiddleware(ra.Authenticator), h.CreatePoll)
+
+	polls := r.Group("/api/polls")
+	polls.Use(NewAuthMiddleware(ra.Authenticator))
+	{
+		polls.GET("/:poll_id", h.GetPoll)
+		polls.POST("/:poll_id/votes", h.Vote)
+		polls.POST("/:poll_id/close", h.ClosePoll)
+	}
+}
+
+// CreatePoll 发起群投票
+// @Summary		发起群投票
+// @Description	在群聊中发起投票，投票以投票消息（type 11）发送给群成员；设置截止时间时到期自动结束并发送结果消息
+// @Tags			群组
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			group_id	path		string						true	"群组ID"
+// @Param			request		body		service.CreatePollRequest	true	"问题、选项、是否多选、是否匿名及截止时间（RFC3339）"
+// @Success		200			{object}	map[string]interface{}		"投票及投票消息"
+// @Failure		400			{object}	map[string]interface{}		"问题、选项或截止时间无效"
+// @Failure		403			{object}	map[string]interface{}		"不是群成员"
+// @Router			/groups/{group_id}/polls [post]
+func (h *PollHandler) CreatePoll(c *gin.Context) {
+	var req service.CreatePollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	poll, msg, err := h.pollService.CreatePoll(c.Request.Context(), c.GetString("user_id"), c.Param("group_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"poll":    poll,
+			"message": msg,
+		},
+	})
+}
+
+// GetPoll 获取投票详情
+// @Summary		获取投票详情
+// @Description	获取投票及当前结果，非匿名投票包含每个选项的投票人，my_choices 为当前用户的选择
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Param			poll_id	path		string					true	"投票ID"
+// @Success		200		{object}	map[string]interface{}	"投票详情"
+// @Failure		404		{object}	map[string]interface{}	"投票不存在"
+// @Router			/polls/{poll_id} [get]
+func (h *PollHandler) GetPoll(c *gin.Context) {
+	view, err := h.pollService.GetPoll(c.Request.Context(), c.GetString("user_id"), c.Param("poll_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    view,
+	})
+}
+
+// Vote 投票
+// @Summary		投票
+// @Description	提交选项下标，每个用户每个投票只能投一次；投票后通过 patch 帧向群成员推送最新统计
+// @Tags			群组
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			poll_id	path		string						true	"投票ID"
+// @Param			request	body		service.VotePollRequest		true	"选项下标"
+// @Success		200		{object}	map[string]interface{}		"投票后的结果"
+// @Failure		400		{object}	map[string]interface{}		"选项无效"
+// @Failure		404		{object}	map[string]interface{}		"投票不存在"
+// @Failure		409		{object}	map[string]interface{}		"投票已结束或已投过票"
+// @Router			/polls/{poll_id}/votes [post]
+func (h *PollHandler) Vote(c *gin.Context) {
+	var req service.VotePollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	view, err := h.pollService.Vote(c.Request.Context(), c.GetString("user_id"), c.Param("poll_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    view,
+	})
+}
+
+// ClosePoll 结束投票
+// @Summary		结束投票
+// @Description	发起人或群主、管理员手动结束投票，并向群聊发送结果消息
+// @Tags			群组
+// @Produce		json
+// @Security		BearerAuth
+// @Param			poll_id	path		string					true	"投票ID"
+// @Success		200		{object}	map[string]interface{}	"最终结果"
+// @Failure		403		{object}	map[string]interface{}	"不是发起人或管理员"
+// @Failure		404		{object}	map[string]interface{}	"投票不存在"
+// @Failure		409		{object}	map[string]interface{}	"投票已结束"
+// @Router			/polls/{poll_id}/close [post]
+func (h *PollHandler) ClosePoll(c *gin.Context) {
+	view, err := h.pollService.ClosePoll(c.Request.Context(), c.GetString("user_id"), c.Param("poll_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    view,
+	})
+}
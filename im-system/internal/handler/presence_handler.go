@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// PresenceHandler 在线状态处理器
+type PresenceHandler struct {
+	presenceService service.PresenceService
+}
+
+// NewPresenceHandler 创建在线状态处理器
+func NewPresenceHandler(presenceService service.PresenceService) *PresenceHandler {
+	return &PresenceHandler{presenceService: presenceService}
+}
+
+// RegisterRoutes 注册路由
+func (h *PresenceHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	r.GET("/api/presence", NewAuthMiddleware(ra.Authenticator), h.GetPresence)
+}
+
+// GetPresence 批量查询用户在线状态
+// @Summary		查询用户在线状态
+// @Description	批量查询用户是否在线及最近在线时间；对方设置为仅好友可见或不公开、或屏蔽了当前用户时返回 visible=false
+// @Tags			用户
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_ids	query		string					true	"用户ID，逗号分隔（最多100个）"
+// @Success		200			{object}	map[string]interface{}	"在线状态列表"
+// @Failure		400			{object}	map[string]interface{}	"用户数超出限制"
+// @Router			/presence [get]
+func (h *PresenceHandler) GetPresence(c *gin.Context) {
+	var userIDs []string
+	for _, id := range strings.Split(c.Query("user_ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			userIDs = append(userIDs, id)
+		}
+	}
+
+	presence, err := h.presenceService.GetPresence(c.Request.Context(), c.GetString("user_id"), userIDs)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    presence,
+	})
+}
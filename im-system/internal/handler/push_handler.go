@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// PushHandler 推送回调与推送分析处理器
+type PushHandler struct {
+	experiments service.PushExperimentService
+	pushService service.PushService
+}
+
+// NewPushHandler 创建推送处理器
+func NewPushHandler(experiments service.PushExperimentService) *PushHandler {
+	return &PushHandler{experiments: experiments}
+}
+
+// SetPushService 设置推送服务，推送分析接口同时返回推送总体统计
+func (h *PushHandler) SetPushService(pushService service.PushService) {
+	h.pushService = pushService
+}
+
+// RegisterRoutes 注册路由
+func (h *PushHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	r.POST("/api/push/opened", NewAuthMiddleware(ra.Authenticator), h.PushOpened)
+
+	admin := r.Group("/api/admin/push")
+	admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+	{
+		admin.GET("/analytics", h.GetAnalytics)
+		admin.GET("/experiments", h.ListExperiments)
+		admin.PUT("/experiments/:key", h.SetExperiment)
+		admin.DELETE("/experiments/:key", h.DeleteExperiment)
+		admin.GET("/experiments/:key/stats", h.GetExperimentStats)
+	}
+}
+
+// PushOpened 推送打开/确认回调
+// @Summary		推送打开/确认回调
+// @Description	客户端在用户点击通知（event=opened）或收到通知（event=acked）时回传通知 data 中的 push_id，同一推送的同一事件只计一次
+// @Tags			推送
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		model.PushOpenedRequest	true	"回调参数"
+// @Success		200		{object}	map[string]interface{}	"记录成功"
+// @Failure		403		{object}	map[string]interface{}	"不是该推送的接收者"
+// @Router			/push/opened [post]
+func (h *PushHandler) PushOpened(c *gin.Context) {
+	var req model.PushOpenedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.experiments.RecordCallback(c.Request.Context(), c.GetString("user_id"), &req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
+
+// GetAnalytics 推送分析
+// @Summary		推送分析
+// @Description	返回推送总体统计（推送服务启用时）及各文案实验分组的分配、送达、打开、确认数和打开率
+// @Tags			推送
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"推送分析"
+// @Router			/admin/push/analytics [get]
+func (h *PushHandler) GetAnalytics(c *gin.Context) {
+	ctx := c.Request.Context()
+	experiments, err := h.experiments.ListStats(ctx)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	data := gin.H{"experiments": experiments}
+	if h.pushService != nil {
+		stats, err := h.pushService.GetPushStats(ctx)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		data["push"] = stats
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    data,
+	})
+}
+
+// ListExperiments 获取推送文案实验列表
+// @Summary		获取推送文案实验列表
+// @Tags			推送
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"实验列表"
+// @Router			/admin/push/experiments [get]
+func (h *PushHandler) ListExperiments(c *gin.Context) {
+	experiments, err := h.experiments.ListExperiments(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    experiments,
+	})
+}
+
+// SetExperiment 创建或更新推送文案实验
+// @Summary		创建或更新推送文案实验
+// @Description	实验Key与功能开关Key相同，开关开启后用户按分组（control/treatment）使用对应的标题/正文模板，模板支持 {title}、{body}、{count} 占位符；新建实验时重置指标
+// @Tags			推送
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			key		path		string							true	"实验Key"
+// @Param			request	body		model.SetPushExperimentRequest	true	"实验配置"
+// @Success		200		{object}	map[string]interface{}			"推送文案实验"
+// @Failure		400		{object}	map[string]interface{}			"参数错误"
+// @Router			/admin/push/experiments/{key} [put]
+func (h *PushHandler) SetExperiment(c *gin.Context) {
+	var req model.SetPushExperimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exp, err := h.experiments.SetExperiment(c.Request.Context(), c.Param("key"), c.GetString("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    exp,
+	})
+}
+
+// DeleteExperiment 删除推送文案实验
+// @Summary		删除推送文案实验
+// @Tags			推送
+// @Produce		json
+// @Security		BearerAuth
+// @Param			key	path		string					true	"实验Key"
+// @Success		200	{object}	map[string]interface{}	"删除成功"
+// @Failure		404	{object}	map[string]interface{}	"实验不存在"
+// @Router			/admin/push/experiments/{key} [delete]
+func (h *PushHandler) DeleteExperiment(c *gin.Context) {
+	if err := h.experiments.DeleteExperiment(c.Request.Context(), c.Param("key")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
+
+// GetExperimentStats 获取推送文案实验指标
+// @Summary		获取推送文案实验指标
+// @Tags			推送
+// @Produce		json
+// @Security		BearerAuth
+// @Param			key	path		string					true	"实验Key"
+// @Success		200	{object}	map[string]interface{}	"各分组指标"
+// @Failure		404	{object}	map[string]interface{}	"实验不存在"
+// @Router			/admin/push/experiments/{key}/stats [get]
+func (h *PushHandler) GetExperimentStats(c *gin.Context) {
+	stats, err := h.experiments.GetStats(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    stats,
+	})
+}
@@ -0,0 +1,417 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// routePermissions 管理接口所需的权限（METHOD + 路由模板），未列出的管理接口只有超级管理员可以访问
+var routePermissions = map[string]string{
+	"GET /api/admin/maintenance":                              model.PermSystemRead,
+	"PUT /api/admin/maintenance":                              model.PermSystemWrite,
+	"GET /api/admin/nodes":                                    model.PermSystemRead,
+	"GET /api/admin/nodes/:node_id/connections":               model.PermSystemRead,
+	"POST /api/admin/nodes/:node_id/broadcast":                model.PermSystemWrite,
+	"POST /api/admin/nodes/:node_id/drain":                    model.PermSystemWrite,
+	"GET /api/admin/clients/stats":                            model.PermSystemRead,
+	"POST /api/admin/users/import":                            model.PermUserWrite,
+	"PUT /api/admin/users/:user_id/status":                    model.PermUserWrite,
+	"DELETE /api/admin/users/:user_id":                        model.PermUserWrite,
+	"GET /api/admin/users":                                    model.PermUserRead,
+	"POST /api/admin/users/:user_id/logout":                   model.PermUserWrite,
+	"POST /api/admin/broadcast":                               model.PermSystemWrite,
+	"GET /api/admin/groups":                                   model.PermGroupRead,
+	"POST /api/admin/groups/:group_id/dismiss":                model.PermGroupWrite,
+	"GET /api/admin/groups/:group_id/successions":             model.PermGroupRead,
+	"GET /api/admin/regions":                                  model.PermSystemRead,
+	"PUT /api/admin/users/:user_id/region":                    model.PermUserWrite,
+	"GET /api/admin/messages/quarantine":                      model.PermConversationRead,
+	"POST /api/admin/messages/quarantine/:message_id/release": model.PermUserWrite,
+	"POST /api/admin/conversations/:conversation_id/purge":    model.PermConversationWrite,
+	"DELETE /api/admin/messages/quarantine/:message_id":       model.PermUserWrite,
+	"GET /api/admin/conversations/encrypted":                  model.PermConversationRead,
+
+	"GET /api/admin/analytics/overview":                       model.PermAnalytics,
+	"GET /api/admin/analytics/conversations":                  model.PermAnalytics,
+	"GET /api/admin/analytics/conversations/:conversation_id": model.PermAnalytics,
+	"GET /api/admin/push/analytics":                           model.PermAnalytics,
+	"GET /api/admin/push/experiments/:key/stats":              model.PermAnalytics,
+	"GET /api/admin/flags/:key/metrics":                       model.PermAnalytics,
+	"GET /api/admin/usage/users":                              model.PermAnalytics,
+	"GET /api/admin/usage/tenants":                            model.PermAnalytics,
+	"GET /api/admin/users/:user_id/usage":                     model.PermAnalytics,
+	"GET /api/admin/users/:user_id/sessions":                  model.PermAnalytics,
+	"DELETE /api/admin/flags/:key/metrics":                    model.PermFeatureWrite,
+
+	"GET /api/admin/files/policy": model.PermFileRead,
+	"PUT /api/admin/files/policy": model.PermFileWrite,
+
+	"GET /api/admin/moderation/violations": model.PermModerationRead,
+
+	"GET /api/admin/flags":                                      model.PermFeatureRead,
+	"PUT /api/admin/flags/:key":                                 model.PermFeatureWrite,
+	"DELETE /api/admin/flags/:key":                              model.PermFeatureWrite,
+	"GET /api/admin/push/experiments":                           model.PermFeatureRead,
+	"PUT /api/admin/push/experiments/:key":                      model.PermFeatureWrite,
+	"DELETE /api/admin/push/experiments/:key":                   model.PermFeatureWrite,
+	"GET /api/admin/apps":                                       model.PermAppRead,
+	"POST /api/admin/apps":                                      model.PermAppWrite,
+	"PUT /api/admin/apps/:app_id":                               model.PermAppWrite,
+	"DELETE /api/admin/apps/:app_id":                            model.PermAppWrite,
+	"POST /api/admin/apps/:app_id/secret":                       model.PermAppWrite,
+	"GET /api/admin/bridges/rooms":                              model.PermAppRead,
+	"POST /api/admin/bridges/rooms":                             model.PermAppWrite,
+	"DELETE /api/admin/bridges/rooms/:id":                       model.PermAppWrite,
+	"GET /api/admin/bridges/users":                              model.PermAppRead,
+	"POST /api/admin/bridges/users":                             model.PermAppWrite,
+	"DELETE /api/admin/bridges/users/:platform/:remote_user_id": model.PermAppWrite,
+
+	"POST /api/admin/org/departments":                                   model.PermOrgWrite,
+	"PUT /api/admin/org/departments/:department_id":                     model.PermOrgWrite,
+	"DELETE /api/admin/org/departments/:department_id":                  model.PermOrgWrite,
+	"POST /api/admin/org/departments/:department_id/members":            model.PermOrgWrite,
+	"DELETE /api/admin/org/departments/:department_id/members/:user_id": model.PermOrgWrite,
+
+	"GET /api/admin/cs/agents":                model.PermCSRead,
+	"PUT /api/admin/cs/agents/:user_id":       model.PermCSWrite,
+	"DELETE /api/admin/cs/agents/:user_id":    model.PermCSWrite,
+	"GET /api/admin/cs/canned-replies":        model.PermCSRead,
+	"POST /api/admin/cs/canned-replies":       model.PermCSWrite,
+	"PUT /api/admin/cs/canned-replies/:id":    model.PermCSWrite,
+	"DELETE /api/admin/cs/canned-replies/:id": model.PermCSWrite,
+
+	"GET /api/admin/rbac/permissions":          model.PermRBACRead,
+	"GET /api/admin/rbac/roles":                model.PermRBACRead,
+	"PUT /api/admin/rbac/roles/:role":          model.PermRBACWrite,
+	"DELETE /api/admin/rbac/roles/:role":       model.PermRBACWrite,
+	"GET /api/admin/rbac/assignments":          model.PermRBACRead,
+	"GET /api/admin/rbac/users/:user_id/roles": model.PermRBACRead,
+	"PUT /api/admin/rbac/users/:user_id/roles": model.PermRBACWrite,
+	"GET /api/admin/audit-logs":                model.PermRBACRead,
+}
+
+// routePermission 路由所需的权限
+func routePermission(method, route string) string {
+	if permission, ok := routePermissions[method+" "+route]; ok {
+		return permission
+	}
+	return model.PermAll
+}
+
+// hasAdminPermission 当前用户是否拥有管理权限，rbacService 为空时只有 ADMIN_USER_IDS 中的用户拥有
+func hasAdminPermission(c *gin.Context, rbacService service.RBACService, permission string) bool {
+	userID := c.GetString("user_id")
+	if IsAdmin(userID) {
+		return true
+	}
+	if rbacService == nil {
+		return false
+	}
+	ok, err := rbacService.HasPermission(c.Request.Context(), userID, permission)
+	return err == nil && ok
+}
+
+// auditDetailKey 管理接口在 gin 上下文中提供的审计详情
+const auditDetailKey = "audit_detail"
+
+// setAuditDetail 设置本次管理操作的审计详情（序列化为 JSON 记入审计日志）
+func setAuditDetail(c *gin.Context, detail interface{}) {
+	data, err := json.Marshal(detail)
+	if err != nil {
+		log.Printf("Marshal admin audit detail error: %v", err)
+		return
+	}
+	c.Set(auditDetailKey, string(data))
+}
+
+// auditAdminCall 异步写入管理接口审计日志
+func auditAdminCall(c *gin.Context, rbacService service.RBACService, userID, permission string, allowed bool) {
+	if rbacService == nil {
+		return
+	}
+	entry := &model.AdminAuditLog{
+		UserID:     userID,
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		Route:      c.FullPath(),
+		Permission: permission,
+		Allowed:    allowed,
+		Status:     c.Writer.Status(),
+		Outcome:    model.AuditOutcome(allowed, c.Writer.Status()),
+		ClientIP:   c.ClientIP(),
+		Detail:     c.GetString(auditDetailKey),
+		CreatedAt:  time.Now(),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := rbacService.RecordAudit(ctx, entry); err != nil {
+			log.Printf("Record admin audit log error: %v", err)
+		}
+	}()
+}
+
+// RBACHandler 管理角色与审计日志处理器
+type RBACHandler struct {
+	rbacService service.RBACService
+}
+
+// NewRBACHandler 创建管理角色处理器
+func NewRBACHandler(rbacService service.RBACService) *RBACHandler {
+	return &RBACHandler{rbacService: rbacService}
+}
+
+// RegisterRoutes 注册路由
+func (h *RBACHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	admin := r.Group("/api/admin")
+	admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+	{
+		admin.GET("/rbac/permissions", h.ListPermissions)
+		admin.GET("/rbac/roles", h.ListRoles)
+		admin.PUT("/rbac/roles/:role", h.SaveRole)
+		admin.DELETE("/rbac/roles/:role", h.DeleteRole)
+		admin.GET("/rbac/assignments", h.ListAssignments)
+		admin.GET("/rbac/users/:user_id/roles", h.GetUserRoles)
+		admin.PUT("/rbac/users/:user_id/roles", h.SetUserRoles)
+		admin.GET("/audit-logs", h.ListAuditLogs)
+	}
+}
+
+// ListPermissions 获取全部管理权限
+// @Summary		获取管理权限列表
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"权限列表"
+// @Router			/admin/rbac/permissions [get]
+func (h *RBACHandler) ListPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    model.AllPermissions,
+	})
+}
+
+// ListRoles 获取管理角色列表
+// @Summary		获取管理角色列表
+// @Description	返回内置角色（support、moderator、ops、super_admin）及自定义角色的权限
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"角色列表"
+// @Router			/admin/rbac/roles [get]
+func (h *RBACHandler) ListRoles(c *gin.Context) {
+	roles, err := h.rbacService.ListRoles(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    roles,
+	})
+}
+
+// SaveRole 创建或更新自定义角色
+// @Summary		创建或更新自定义角色
+// @Description	内置角色不可修改；修改后拥有该角色的用户权限立即生效
+// @Tags			管理
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			role	path		string						true	"角色名（小写字母、数字、_、-）"
+// @Param			request	body		model.SaveAdminRoleRequest	true	"角色权限"
+// @Success		200		{object}	map[string]interface{}		"角色"
+// @Failure		400		{object}	map[string]interface{}		"角色名或权限无效"
+// @Router			/admin/rbac/roles/{role} [put]
+func (h *RBACHandler) SaveRole(c *gin.Context) {
+	var req model.SaveAdminRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.rbacService.SaveRole(c.Request.Context(), c.GetString("user_id"), c.Param("role"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    role,
+	})
+}
+
+// DeleteRole 删除自定义角色
+// @Summary		删除自定义角色
+// @Description	同时撤销该角色的全部分配，内置角色不可删除
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			role	path		string					true	"角色名"
+// @Success		200		{object}	map[string]interface{}	"删除成功"
+// @Failure		404		{object}	map[string]interface{}	"角色不存在"
+// @Router			/admin/rbac/roles/{role} [delete]
+func (h *RBACHandler) DeleteRole(c *gin.Context) {
+	if err := h.rbacService.DeleteRole(c.Request.Context(), c.Param("role")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// ListAssignments 获取全部角色分配
+// @Summary		获取角色分配列表
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"角色分配列表"
+// @Router			/admin/rbac/assignments [get]
+func (h *RBACHandler) ListAssignments(c *gin.Context) {
+	assignments, err := h.rbacService.ListAssignments(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    assignments,
+	})
+}
+
+// GetUserRoles 获取用户的角色及权限
+// @Summary		获取用户的管理角色
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string					true	"用户ID"
+// @Success		200		{object}	map[string]interface{}	"角色及权限"
+// @Router			/admin/rbac/users/{user_id}/roles [get]
+func (h *RBACHandler) GetUserRoles(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.Param("user_id")
+	roles, err := h.rbacService.GetUserRoles(ctx, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	permissions, err := h.rbacService.Permissions(ctx, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"user_id":     userID,
+			"roles":       roles,
+			"permissions": permissions,
+			"super_admin": IsAdmin(userID),
+		},
+	})
+}
+
+// SetUserRoles 设置用户的角色
+// @Summary		设置用户的管理角色
+// @Description	覆盖用户原有的角色，roles 为空表示撤销全部角色；不能撤销自己的角色管理权限
+// @Tags			管理
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string						true	"用户ID"
+// @Param			request	body		model.SetUserRolesRequest	true	"角色列表"
+// @Success		200		{object}	map[string]interface{}		"设置后的角色"
+// @Failure		404		{object}	map[string]interface{}		"角色不存在"
+// @Router			/admin/rbac/users/{user_id}/roles [put]
+func (h *RBACHandler) SetUserRoles(c *gin.Context) {
+	var req model.SetUserRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.Param("user_id")
+	roles, err := h.rbacService.SetUserRoles(c.Request.Context(), c.GetString("user_id"), userID, req.Roles)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"user_id": userID,
+			"roles":   roles,
+		},
+	})
+}
+
+// ListAuditLogs 分页查询管理接口审计日志
+// @Summary		查询审计日志
+// @Description	管理接口的修改类调用及被拒绝的调用，按时间倒序
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id		query		string					false	"操作者"
+// @Param			permission	query		string					false	"权限"
+// @Param			outcome		query		string					false	"调用结果：success、failed、denied"
+// @Param			since		query		int						false	"开始时间（毫秒时间戳）"
+// @Param			until		query		int						false	"结束时间（毫秒时间戳）"
+// @Param			page		query		int						false	"页码"
+// @Param			page_size	query		int						false	"每页数量"
+// @Success		200			{object}	map[string]interface{}	"审计日志"
+// @Router			/admin/audit-logs [get]
+func (h *RBACHandler) ListAuditLogs(c *gin.Context) {
+	filter := &model.AuditLogFilter{
+		UserID:     c.Query("user_id"),
+		Permission: c.Query("permission"),
+		Outcome:    c.Query("outcome"),
+	}
+	if since, err := strconv.ParseInt(c.Query("since"), 10, 64); err == nil {
+		t := time.UnixMilli(since)
+		filter.Since = &t
+	}
+	if until, err := strconv.ParseInt(c.Query("until"), 10, 64); err == nil {
+		t := time.UnixMilli(until)
+		filter.Until = &t
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	logs, total, err := h.rbacService.ListAuditLogs(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total": total,
+			"logs":  logs,
+		},
+	})
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/service"
+	"github.com/d60-lab/im-system/pkg/auth"
+)
+
+// 会话Cookie相关名称
+const (
+	SessionCookieName = "im_session"   // 会话Cookie（HttpOnly，保存Access Token）
+	CSRFCookieName    = "im_csrf"      // CSRF Cookie（前端可读）
+	CSRFHeaderName    = "X-CSRF-Token" // 前端回传CSRF Token的请求头
+)
+
+// SecurityConfig Web安全配置
+type SecurityConfig struct {
+	AllowOrigins  []string // 允许的跨域来源，为空时仅允许同源
+	CookieSession bool     // 是否启用Cookie会话（Web端）
+	CookieSecure  bool     // Cookie是否仅通过HTTPS发送
+	CookieDomain  string   // Cookie域
+	CSRFSecret    string   // CSRF Token签名密钥
+}
+
+// securityConfig 当前生效的安全配置
+var securityConfig = &SecurityConfig{AllowOrigins: []string{"*"}}
+
+// SetSecurityConfig 设置Web安全配置
+func SetSecurityConfig(config *SecurityConfig) {
+	if config != nil {
+		securityConfig = config
+	}
+}
+
+// RouteAuth 注册路由时注入的认证提供者和管理接口权限服务
+type RouteAuth struct {
+	Authenticator auth.Authenticator  // 与签发 Token 的 JWT 管理器同一配置，为空时拒绝所有认证请求
+	RBAC          service.RBACService // 为空时只有 ADMIN_USER_IDS 中的用户可以访问管理接口
+}
+
+// OriginMiddleware 跨域来源检查中间件
+// 允许的来源返回CORS响应头，不允许的跨域请求直接拒绝
+func OriginMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		if !auth.OriginAllowed(origin, c.Request.Host, securityConfig.AllowOrigins) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "origin not allowed"})
+			c.Abort()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Credentials", "true")
+		h.Add("Vary", "Origin")
+
+		if c.Request.Method == http.MethodOptions {
+			h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			h.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Platform, X-Device-ID, "+CSRFHeaderName)
+			h.Set("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// setSessionCookies 登录后写入会话Cookie和CSRF Cookie，返回CSRF Token
+func setSessionCookies(c *gin.Context, token string, expiresAt time.Time) string {
+	if !securityConfig.CookieSession {
+		return ""
+	}
+
+	maxAge := int(time.Until(expiresAt).Seconds())
+	csrfToken := auth.GenerateCSRFToken(securityConfig.CSRFSecret, token)
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(SessionCookieName, token, maxAge, "/", securityConfig.CookieDomain, securityConfig.CookieSecure, true)
+	c.SetCookie(CSRFCookieName, csrfToken, maxAge, "/", securityConfig.CookieDomain, securityConfig.CookieSecure, false)
+	return csrfToken
+}
+
+// clearSessionCookies 清除会话Cookie
+func clearSessionCookies(c *gin.Context) {
+	if !securityConfig.CookieSession {
+		return
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(SessionCookieName, "", -1, "/", securityConfig.CookieDomain, securityConfig.CookieSecure, true)
+	c.SetCookie(CSRFCookieName, "", -1, "/", securityConfig.CookieDomain, securityConfig.CookieSecure, false)
+}
+
+// sessionToken 从Cookie中获取会话Token
+func sessionToken(c *gin.Context) string {
+	if !securityConfig.CookieSession {
+		return ""
+	}
+	token, err := c.Cookie(SessionCookieName)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// checkCSRF 检查Cookie会话的非安全请求是否携带有效CSRF Token
+func checkCSRF(c *gin.Context, token string) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return auth.VerifyCSRFToken(securityConfig.CSRFSecret, token, strings.TrimSpace(c.GetHeader(CSRFHeaderName)))
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimeHandler 服务器时间同步处理器
+type TimeHandler struct {
+	maxClientSkew time.Duration
+}
+
+// NewTimeHandler 创建服务器时间处理器
+// maxClientSkew 为发送消息时允许的客户端时钟偏差，0表示不校验
+func NewTimeHandler(maxClientSkew time.Duration) *TimeHandler {
+	return &TimeHandler{
+		maxClientSkew: maxClientSkew,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *TimeHandler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/api/time", h.GetTime)
+}
+
+// GetTime 获取服务器时间
+// @Summary		获取服务器时间
+// @Description	返回服务器时间（毫秒）及允许的客户端时钟偏差；传入 client_time 时原样返回，客户端可结合往返时间估算时钟偏移：offset = server_time - (client_time + rtt/2)
+// @Tags			系统
+// @Produce		json
+// @Param			client_time	query		int						false	"客户端发送请求时的时间（毫秒）"
+// @Success		200			{object}	map[string]interface{}	"服务器时间"
+// @Router			/time [get]
+func (h *TimeHandler) GetTime(c *gin.Context) {
+	now := time.Now()
+	data := gin.H{
+		"server_time":     now.UnixMilli(),
+		"timezone":        now.Format("-07:00"),
+		"max_client_skew": h.maxClientSkew.Milliseconds(),
+	}
+	if clientTime, err := strconv.ParseInt(c.Query("client_time"), 10, 64); err == nil {
+		data["client_time"] = clientTime
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    data,
+	})
+}
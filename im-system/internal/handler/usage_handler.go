@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/d60-lab/im-system/internal/service"
+)
+
+// UsageHandler 连接会话计量处理器
+type UsageHandler struct {
+	usageService service.UsageService
+}
+
+// NewUsageHandler 创建连接会话计量处理器
+func NewUsageHandler(usageService service.UsageService) *UsageHandler {
+	return &UsageHandler{usageService: usageService}
+}
+
+// RegisterRoutes 注册路由
+func (h *UsageHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
+	usage := r.Group("/api/usage")
+	usage.Use(NewAuthMiddleware(ra.Authenticator))
+	{
+		usage.GET("", h.GetMyUsage)
+		usage.GET("/sessions", h.ListMySessions)
+	}
+
+	admin := r.Group("/api/admin")
+	admin.Use(NewAuthMiddleware(ra.Authenticator), NewAdminMiddleware(ra.RBAC))
+	{
+		admin.GET("/usage/users", h.ListUserUsage)
+		admin.GET("/usage/tenants", h.ListTenantUsage)
+		admin.GET("/users/:user_id/usage", h.GetUserUsage)
+		admin.GET("/users/:user_id/sessions", h.ListUserSessions)
+	}
+}
+
+// GetMyUsage 获取当前用户的月度用量
+// @Summary		获取我的月度用量
+// @Description	获取当前用户某月的连接会话数、在线时长及近似流量（会话按断开时间计入月份，月份按UTC划分）
+// @Tags			用量
+// @Produce		json
+// @Security		BearerAuth
+// @Param			month	query		string					false	"月份（YYYY-MM，默认当月）"
+// @Success		200		{object}	map[string]interface{}	"月度用量"
+// @Failure		400		{object}	map[string]interface{}	"月份格式错误"
+// @Router			/usage [get]
+func (h *UsageHandler) GetMyUsage(c *gin.Context) {
+	usage, err := h.usageService.GetUserUsage(c.Request.Context(), c.GetString("user_id"), c.Query("month"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    usage,
+	})
+}
+
+// ListMySessions 获取当前用户的连接会话记录
+// @Summary		获取我的连接会话记录
+// @Tags			用量
+// @Produce		json
+// @Security		BearerAuth
+// @Param			since		query		int						false	"断开时间下限（毫秒时间戳）"
+// @Param			until		query		int						false	"断开时间上限（毫秒时间戳，不含）"
+// @Param			page		query		int						false	"页码"
+// @Param			page_size	query		int						false	"每页数量"
+// @Success		200			{object}	map[string]interface{}	"会话记录"
+// @Router			/usage/sessions [get]
+func (h *UsageHandler) ListMySessions(c *gin.Context) {
+	h.listSessions(c, c.GetString("user_id"))
+}
+
+// ListUserUsage 分页查询各用户月度用量
+// @Summary		查询用户月度用量
+// @Description	按在线时长、流量或会话数排序查询某月各用户用量，可按租户筛选
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			month		query		string					false	"月份（YYYY-MM，默认当月）"
+// @Param			tenant_id	query		string					false	"租户ID"
+// @Param			sort		query		string					false	"排序（duration/bytes/sessions，默认duration）"
+// @Param			page		query		int						false	"页码"
+// @Param			page_size	query		int						false	"每页数量"
+// @Success		200			{object}	map[string]interface{}	"用户用量列表"
+// @Failure		400			{object}	map[string]interface{}	"参数错误"
+// @Router			/admin/usage/users [get]
+func (h *UsageHandler) ListUserUsage(c *gin.Context) {
+	var query service.UsageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	usages, total, err := h.usageService.ListUserUsage(c.Request.Context(), &query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total": total,
+			"usage": usages,
+		},
+	})
+}
+
+// ListTenantUsage 按租户汇总月度用量
+// @Summary		查询租户月度用量
+// @Description	按租户汇总某月用量（未分配租户的用户汇总为空租户）
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			month		query		string					false	"月份（YYYY-MM，默认当月）"
+// @Param			tenant_id	query		string					false	"租户ID"
+// @Success		200			{object}	map[string]interface{}	"租户用量列表"
+// @Failure		400			{object}	map[string]interface{}	"月份格式错误"
+// @Router			/admin/usage/tenants [get]
+func (h *UsageHandler) ListTenantUsage(c *gin.Context) {
+	usages, err := h.usageService.ListTenantUsage(c.Request.Context(), c.Query("month"), c.Query("tenant_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    usages,
+	})
+}
+
+// GetUserUsage 查询指定用户的月度用量
+// @Summary		查询指定用户的月度用量
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string					true	"用户ID"
+// @Param			month	query		string					false	"月份（YYYY-MM，默认当月）"
+// @Success		200		{object}	map[string]interface{}	"月度用量"
+// @Failure		400		{object}	map[string]interface{}	"月份格式错误"
+// @Router			/admin/users/{user_id}/usage [get]
+func (h *UsageHandler) GetUserUsage(c *gin.Context) {
+	usage, err := h.usageService.GetUserUsage(c.Request.Context(), c.Param("user_id"), c.Query("month"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    usage,
+	})
+}
+
+// ListUserSessions 查询指定用户的连接会话记录
+// @Summary		查询指定用户的连接会话记录
+// @Description	会话明细按配置的保留时长清理，月度用量不受影响
+// @Tags			管理
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id		path		string					true	"用户ID"
+// @Param			since		query		int						false	"断开时间下限（毫秒时间戳）"
+// @Param			until		query		int						false	"断开时间上限（毫秒时间戳，不含）"
+// @Param			page		query		int						false	"页码"
+// @Param			page_size	query		int						false	"每页数量"
+// @Success		200			{object}	map[string]interface{}	"会话记录"
+// @Router			/admin/users/{user_id}/sessions [get]
+func (h *UsageHandler) ListUserSessions(c *gin.Context) {
+	h.listSessions(c, c.Param("user_id"))
+}
+
+// listSessions 分页查询用户的会话记录
+func (h *UsageHandler) listSessions(c *gin.Context, userID string) {
+	var query service.SessionListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sessions, total, err := h.usageService.ListSessions(c.Request.Context(), userID, &query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"total":    total,
+			"sessions": sessions,
+		},
+	})
+}
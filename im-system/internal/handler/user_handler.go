@@ -3,53 +3,102 @@ package handler
 
 import (
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
 	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/service"
 	"github.com/d60-lab/im-system/pkg/auth"
 	"github.com/d60-lab/im-system/pkg/util"
 )
 
 // UserHandler 用户处理器
 type UserHandler struct {
-	db         *gorm.DB
-	jwtManager *auth.JWTManager
+	db           *gorm.DB
+	jwtManager   *auth.JWTManager
+	searchConfig *model.UserSearchConfig
+	redis        *redis.Client
+	naming       service.NamingService
+	autoReply    service.AutoReplyService
+	sessions     service.SessionService
+	notify       service.NotificationSettingsService
 }
 
 // NewUserHandler 创建用户处理器
 func NewUserHandler(db *gorm.DB, jwtManager *auth.JWTManager) *UserHandler {
 	return &UserHandler{
-		db:         db,
-		jwtManager: jwtManager,
+		db:           db,
+		jwtManager:   jwtManager,
+		searchConfig: model.DefaultUserSearchConfig(),
 	}
 }
 
+// SetNamingService 设置命名服务（保留名、昵称唯一性、改名历史）
+func (h *UserHandler) SetNamingService(naming service.NamingService) {
+	h.naming = naming
+}
+
+// SetAutoReplyService 设置自动回复服务，为空时不注册自动回复接口
+func (h *UserHandler) SetAutoReplyService(autoReply service.AutoReplyService) {
+	h.autoReply = autoReply
+}
+
+// SetSessionService 设置登录会话服务，刷新Token时拒绝已强制下线前签发的 Refresh Token
+func (h *UserHandler) SetSessionService(sessions service.SessionService) {
+	h.sessions = sessions
+}
+
+// SetNotificationSettingsService 设置通知设置服务，为空时不注册通知设置接口
+func (h *UserHandler) SetNotificationSettingsService(notify service.NotificationSettingsService) {
+	h.notify = notify
+}
+
+// SetSearchConfig 设置用户搜索配置，redisClient用于按请求者限流（为nil时不限流）
+func (h *UserHandler) SetSearchConfig(config *model.UserSearchConfig, redisClient *redis.Client) {
+	if config != nil {
+		h.searchConfig = config
+	}
+	h.redis = redisClient
+}
+
 // RegisterRoutes 注册路由
-func (h *UserHandler) RegisterRoutes(r *gin.Engine) {
+func (h *UserHandler) RegisterRoutes(r *gin.Engine, ra *RouteAuth) {
 	// 公开接口
-	r.POST("/api/register", h.Register)
-	r.POST("/api/login", h.Login)
+	r.POST("/api/register", RateLimit(RateLimitRegister), h.Register)
+	r.POST("/api/login", RateLimit(RateLimitLogin), h.Login)
 	r.POST("/api/refresh-token", h.RefreshToken)
 
 	// 需要认证的接口
 	auth := r.Group("/api/user")
-	auth.Use(AuthMiddleware())
+	auth.Use(NewAuthMiddleware(ra.Authenticator))
 	{
 		auth.GET("/info", h.GetUserInfo)
 		auth.PUT("/info", h.UpdateUserInfo)
 		auth.POST("/change-password", h.ChangePassword)
 		auth.POST("/logout", h.Logout)
+		if h.autoReply != nil {
+			auth.GET("/auto-reply", h.GetAutoReply)
+			auth.PUT("/auto-reply", h.UpdateAutoReply)
+		}
+		if h.notify != nil {
+			auth.GET("/notification-settings", h.GetNotificationSettings)
+			auth.PUT("/notification-settings", h.UpdateNotificationSettings)
+		}
 	}
 
 	// 用户查询接口
-	r.GET("/api/users/:user_id", AuthMiddleware(), h.GetUserByID)
-	r.GET("/api/users", AuthMiddleware(), h.SearchUsers)
+	r.GET("/api/users/:user_id", NewAuthMiddleware(ra.Authenticator), h.GetUserByID)
+	r.GET("/api/users/:user_id/names", NewAuthMiddleware(ra.Authenticator), h.GetRenameHistory)
+	r.GET("/api/users", NewAuthMiddleware(ra.Authenticator), h.SearchUsers)
 }
 
 // Register 用户注册
@@ -70,11 +119,26 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
+	if req.Nickname == "" {
+		req.Nickname = req.Username
+	}
+
 	// 检查用户名是否已存在
-	var existingUser model.User
-	if err := h.db.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "username already exists"})
-		return
+	if h.naming != nil {
+		if err := h.naming.CheckUsername(c.Request.Context(), req.Username); err != nil {
+			respondError(c, err)
+			return
+		}
+		if err := h.naming.CheckNickname(c.Request.Context(), "", "", req.Nickname); err != nil {
+			respondError(c, err)
+			return
+		}
+	} else {
+		var existingUser model.User
+		if err := h.db.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "username already exists"})
+			return
+		}
 	}
 
 	// 哈希密码
@@ -95,10 +159,6 @@ func (h *UserHandler) Register(c *gin.Context) {
 		UpdatedAt:    time.Now(),
 	}
 
-	if user.Nickname == "" {
-		user.Nickname = req.Username
-	}
-
 	if err := h.db.Create(user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
 		return
@@ -160,8 +220,12 @@ func (h *UserHandler) Login(c *gin.Context) {
 	platform := c.GetHeader("X-Platform")
 	deviceID := c.GetHeader("X-Device-ID")
 
-	// 生成Token
-	accessToken, refreshToken, expiresAt, err := h.jwtManager.GenerateTokenPair(user.UserID, user.Username, platform, deviceID)
+	// 生成Token，须修改初始密码时Token只能用于修改密码和登出
+	generateTokenPair := h.jwtManager.GenerateTokenPair
+	if user.MustResetPassword {
+		generateTokenPair = h.jwtManager.GeneratePasswordResetTokenPair
+	}
+	accessToken, refreshToken, expiresAt, err := generateTokenPair(user.UserID, user.Username, platform, deviceID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
@@ -170,6 +234,9 @@ func (h *UserHandler) Login(c *gin.Context) {
 	// 获取WebSocket URL
 	wsURL := getWebSocketURL(c)
 
+	// Cookie会话模式下写入会话Cookie
+	csrfToken := setSessionCookies(c, accessToken, expiresAt)
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
@@ -182,21 +249,40 @@ func (h *UserHandler) Login(c *gin.Context) {
 			RefreshToken: refreshToken,
 			ExpiresAt:    expiresAt,
 			WebSocketURL: wsURL,
+			CSRFToken:    csrfToken,
+
+			MustResetPassword: user.MustResetPassword,
 		},
 	})
 }
 
+// refreshTokenRequest 刷新Token请求
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 // RefreshToken 刷新Token
 func (h *UserHandler) RefreshToken(c *gin.Context) {
-	var req struct {
-		RefreshToken string `json:"refresh_token" binding:"required"`
-	}
+	var req refreshTokenRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// 强制下线前签发的 Refresh Token 不可再刷新
+	if h.sessions != nil {
+		if claims, err := h.jwtManager.ParseToken(req.RefreshToken); err == nil && claims.IssuedAt != nil {
+			revoked, err := h.sessions.IsRevoked(c.Request.Context(), claims.UserID, claims.IssuedAt.Time)
+			if err != nil {
+				log.Printf("Check refresh token revocation of %s error: %v", claims.UserID, err)
+			} else if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+				return
+			}
+		}
+	}
+
 	// 验证并刷新Token
 	newAccessToken, err := h.jwtManager.RefreshToken(req.RefreshToken)
 	if err != nil {
@@ -204,12 +290,16 @@ func (h *UserHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	expiresAt := time.Now().Add(7 * 24 * time.Hour)
+	csrfToken := setSessionCookies(c, newAccessToken, expiresAt)
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
 		"data": gin.H{
 			"token":      newAccessToken,
-			"expires_at": time.Now().Add(7 * 24 * time.Hour),
+			"expires_at": expiresAt,
+			"csrf_token": csrfToken,
 		},
 	})
 }
@@ -264,13 +354,43 @@ func (h *UserHandler) UpdateUserInfo(c *gin.Context) {
 
 	updates := make(map[string]interface{})
 	if req.Nickname != nil {
-		updates["nickname"] = *req.Nickname
+		if h.naming != nil {
+			// 昵称修改经命名服务校验并记录改名历史
+			if err := h.naming.Rename(c.Request.Context(), userID, *req.Nickname); err != nil {
+				respondError(c, err)
+				return
+			}
+		} else {
+			updates["nickname"] = *req.Nickname
+		}
 	}
 	if req.Avatar != nil {
 		updates["avatar"] = *req.Avatar
 	}
+	if req.Phone != nil {
+		updates["phone"] = *req.Phone
+	}
+	if req.Searchable != nil {
+		updates["searchable"] = *req.Searchable
+	}
+	if req.Email != nil {
+		updates["email"] = *req.Email
+	}
+	if req.EmailDigest != nil {
+		updates["email_digest"] = *req.EmailDigest
+	}
+	if req.PresenceVisibility != nil {
+		updates["presence_visibility"] = *req.PresenceVisibility
+	}
 
 	if len(updates) == 0 {
+		if req.Nickname != nil && h.naming != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"code":    0,
+				"message": "success",
+			})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
 		return
 	}
@@ -290,7 +410,7 @@ func (h *UserHandler) UpdateUserInfo(c *gin.Context) {
 
 // ChangePassword 修改密码
 // @Summary		修改密码
-// @Description	修改当前用户的登录密码
+// @Description	修改当前用户的登录密码；使用须修改初始密码的Token时返回新的Token对
 // @Tags			用户
 // @Accept			json
 // @Produce		json
@@ -331,13 +451,37 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 
 	// 更新密码
 	if err := h.db.Model(&user).Updates(map[string]interface{}{
-		"password_hash": string(hashedPassword),
-		"updated_at":    time.Now(),
+		"password_hash":       string(hashedPassword),
+		"must_reset_password": false,
+		"updated_at":          time.Now(),
 	}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update password"})
 		return
 	}
 
+	// 使用须修改初始密码的Token时换发不受限的Token
+	if c.GetBool("password_reset") {
+		accessToken, refreshToken, expiresAt, err := h.jwtManager.GenerateTokenPair(user.UserID, user.Username,
+			c.GetHeader("X-Platform"), c.GetHeader("X-Device-ID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+			return
+		}
+		csrfToken := setSessionCookies(c, accessToken, expiresAt)
+
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "success",
+			"data": gin.H{
+				"token":         accessToken,
+				"refresh_token": refreshToken,
+				"expires_at":    expiresAt,
+				"csrf_token":    csrfToken,
+			},
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
@@ -348,6 +492,7 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 func (h *UserHandler) Logout(c *gin.Context) {
 	// 这里可以实现Token黑名单等逻辑
 	// 简化实现：客户端直接删除Token即可
+	clearSessionCookies(c)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -389,7 +534,7 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 
 // SearchUsers 搜索用户
 // @Summary		搜索用户
-// @Description	根据关键词搜索用户
+// @Description	根据关键词搜索用户。精确模式仅匹配用户名或手机号，模糊模式匹配用户名和昵称；不返回已禁用及关闭搜索的用户
 // @Tags			用户
 // @Accept			json
 // @Produce		json
@@ -399,18 +544,35 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 // @Success		200		{object}	map[string]interface{}	"用户列表"
 // @Failure		400		{object}	map[string]interface{}	"参数错误"
 // @Failure		401		{object}	map[string]interface{}	"未授权"
+// @Failure		429		{object}	map[string]interface{}	"搜索过于频繁"
 // @Router			/users [get]
 func (h *UserHandler) SearchUsers(c *gin.Context) {
-	keyword := c.Query("keyword")
+	keyword := strings.TrimSpace(c.Query("keyword"))
 	if keyword == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "keyword is required"})
 		return
 	}
 
+	// 按请求者限流，防止遍历用户库
+	if !h.allowSearch(c, c.GetString("user_id")) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many search requests"})
+		return
+	}
+
+	limit := h.searchConfig.MaxResults
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 && n < limit {
+		limit = n
+	}
+
+	query := h.db.Where("status = ? AND searchable = ?", model.UserStatusNormal, true)
+	if h.searchConfig.Mode == model.UserSearchFuzzy {
+		query = query.Where("username LIKE ? OR nickname LIKE ?", "%"+keyword+"%", "%"+keyword+"%")
+	} else {
+		query = query.Where("username = ? OR phone = ?", keyword, keyword)
+	}
+
 	var users []model.User
-	if err := h.db.Where("username LIKE ? OR nickname LIKE ?", "%"+keyword+"%", "%"+keyword+"%").
-		Limit(20).
-		Find(&users).Error; err != nil {
+	if err := query.Limit(limit).Find(&users).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search users"})
 		return
 	}
@@ -430,15 +592,203 @@ func (h *UserHandler) SearchUsers(c *gin.Context) {
 	})
 }
 
-// AuthMiddleware 认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+// allowSearch 检查请求者是否超出搜索频率限制
+func (h *UserHandler) allowSearch(c *gin.Context, userID string) bool {
+	if h.redis == nil || h.searchConfig.RateLimit <= 0 {
+		return true
+	}
+
+	ctx := c.Request.Context()
+	key := fmt.Sprintf("user:search:rate:%s", userID)
+	count, err := h.redis.Incr(ctx, key).Result()
+	if err != nil {
+		// Redis异常时不阻断搜索
+		return true
+	}
+	if count == 1 {
+		h.redis.Expire(ctx, key, h.searchConfig.RateWindow)
+	}
+	return count <= int64(h.searchConfig.RateLimit)
+}
+
+// GetRenameHistory 获取用户改名历史
+// @Summary		获取用户改名历史
+// @Description	获取用户的昵称修改记录，客户端可据此解析缓存消息中的旧显示名
+// @Tags			用户
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		string					true	"用户ID"
+// @Param			limit	query		int						false	"返回数量限制"	default(20)
+// @Success		200		{object}	map[string]interface{}	"改名历史"
+// @Failure		401		{object}	map[string]interface{}	"未授权"
+// @Router			/users/{user_id}/names [get]
+func (h *UserHandler) GetRenameHistory(c *gin.Context) {
+	if h.naming == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "success",
+			"data":    []*model.UserRenameHistory{},
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	history, err := h.naming.GetRenameHistory(c.Request.Context(), c.Param("user_id"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get rename history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    history,
+	})
+}
+
+// GetAutoReply 获取自动回复设置
+// @Summary		获取自动回复设置
+// @Description	获取当前用户的自动回复（休假模式）设置
+// @Tags			用户
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"自动回复设置"
+// @Failure		401	{object}	map[string]interface{}	"未授权"
+// @Router			/user/auto-reply [get]
+func (h *UserHandler) GetAutoReply(c *gin.Context) {
+	setting, err := h.autoReply.GetSetting(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    setting,
+	})
+}
+
+// UpdateAutoReply 更新自动回复设置
+// @Summary		更新自动回复设置
+// @Description	开启后，每个发送者在回复窗口内的第一条单聊消息会收到自动回复；可设置生效时间段
+// @Tags			用户
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		service.UpdateAutoReplyRequest	true	"自动回复设置"
+// @Success		200		{object}	map[string]interface{}			"更新后的设置"
+// @Failure		400		{object}	map[string]interface{}			"参数错误"
+// @Router			/user/auto-reply [put]
+func (h *UserHandler) UpdateAutoReply(c *gin.Context) {
+	var req service.UpdateAutoReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	setting, err := h.autoReply.UpdateSetting(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    setting,
+	})
+}
+
+// GetNotificationSettings 获取通知设置
+// @Summary		获取通知设置
+// @Description	获取当前用户的推送开关、推送预览、免打扰时段及免打扰会话
+// @Tags			用户
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	map[string]interface{}	"通知设置"
+// @Failure		401	{object}	map[string]interface{}	"未授权"
+// @Router			/user/notification-settings [get]
+func (h *UserHandler) GetNotificationSettings(c *gin.Context) {
+	settings, err := h.notify.GetSettings(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    settings,
+	})
+}
+
+// UpdateNotificationSettings 更新通知设置
+// @Summary		更新通知设置
+// @Description	修改推送开关、推送预览和免打扰时段，未传的字段不修改；会话免打扰通过会话接口设置
+// @Tags			用户
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		service.UpdateNotificationSettingsRequest	true	"通知设置"
+// @Success		200		{object}	map[string]interface{}						"更新后的设置"
+// @Failure		400		{object}	map[string]interface{}						"参数错误"
+// @Router			/user/notification-settings [put]
+func (h *UserHandler) UpdateNotificationSettings(c *gin.Context) {
+	var req service.UpdateNotificationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.notify.UpdateSettings(c.Request.Context(), c.GetString("user_id"), &req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    settings,
+	})
+}
+
+// passwordResetRoutes 须修改初始密码的Token可以访问的接口（方法 + 路由模板）
+var passwordResetRoutes = map[string]bool{
+	"POST /api/user/change-password": true,
+	"POST /api/user/logout":          true,
+}
+
+// NewAuthMiddleware 使用指定认证提供者的认证中间件，认证提供者为空时拒绝所有请求
+func NewAuthMiddleware(authenticator auth.Authenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if authenticator == nil {
+			log.Printf("Authenticate error: authenticator not configured")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "authentication service unavailable"})
+			c.Abort()
+			return
+		}
+
 		// 获取Token
 		token := c.GetHeader("Authorization")
 		if token == "" {
 			token = c.Query("token")
 		}
 
+		// Cookie会话：非安全请求需校验CSRF Token
+		fromCookie := false
+		if token == "" {
+			token = sessionToken(c)
+			fromCookie = token != ""
+		}
+		if fromCookie && !checkCSRF(c, token) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid csrf token"})
+			c.Abort()
+			return
+		}
+
 		if token == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing token"})
 			c.Abort()
@@ -451,16 +801,37 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		// 验证Token
-		claims, err := auth.ParseAccessToken(token)
+		identity, err := authenticator.Authenticate(c.Request.Context(), token)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			if errors.Is(err, auth.ErrAuthUnavailable) {
+				log.Printf("Authenticate error: %v", err)
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "authentication service unavailable"})
+			} else {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			}
+			c.Abort()
+			return
+		}
+
+		// 访客只能访问限定的接口
+		if identity.Guest && !guestAllowed(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "guest access not allowed"})
+			c.Abort()
+			return
+		}
+
+		// 须修改初始密码时只能修改密码或登出
+		if identity.PasswordReset && !passwordResetRoutes[c.Request.Method+" "+c.FullPath()] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "password reset required", "must_reset_password": true})
 			c.Abort()
 			return
 		}
 
 		// 将用户信息存入上下文
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
+		c.Set("user_id", identity.UserID)
+		c.Set("username", identity.Username)
+		c.Set("guest", identity.Guest)
+		c.Set("password_reset", identity.PasswordReset)
 
 		c.Next()
 	}
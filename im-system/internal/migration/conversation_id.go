@@ -0,0 +1,154 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/pressly/goose/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// conversationIDTables 存储会话ID的MySQL表
+var conversationIDTables = []string{"offline_messages", "conversations", "user_conversations"}
+
+// canonicalConversationIDPattern 规范格式会话ID（single:a:b / group:x）
+const canonicalConversationIDPattern = "^(single|group):"
+
+// mysqlConversationIDMigration 将MySQL中的旧格式会话ID统一为规范格式
+// 数据迁移无法还原原始格式，回滚为空操作（读取端兼容两种格式）
+func mysqlConversationIDMigration() *goose.Migration {
+	m := goose.NewGoMigration(2,
+		&goose.GoFunc{RunTx: upCanonicalConversationIDs},
+		nil,
+	)
+	m.Source = "00002_canonical_conversation_ids.go"
+	return m
+}
+
+// mergeLegacyConversationRows 同一会话已存在规范格式记录时（唯一键冲突），将旧记录的状态合并到规范格式记录的语句，
+// 参数依次为规范格式、旧格式会话ID；同一条 UPDATE 中每列只引用自身和旧记录的值，不依赖赋值顺序
+var mergeLegacyConversationRows = map[string][]string{
+	// 未读数相加，已读位置、更新时间取较大值，置顶、免打扰任一记录设置即保留，两条记录都已删除时才保持删除
+	"user_conversations": {
+		"UPDATE `user_conversations` AS c JOIN `user_conversations` AS l ON l.user_id = c.user_id AND l.conversation_id = ? " +
+			"SET c.unread_count = COALESCE(c.unread_count, 0) + COALESCE(l.unread_count, 0), " +
+			"c.last_read_seq = GREATEST(COALESCE(c.last_read_seq, 0), COALESCE(l.last_read_seq, 0)), " +
+			"c.muted = COALESCE(c.muted, 0) OR COALESCE(l.muted, 0), " +
+			"c.pinned = COALESCE(c.pinned, 0) OR COALESCE(l.pinned, 0), " +
+			"c.deleted = COALESCE(c.deleted, 0) AND COALESCE(l.deleted, 0), " +
+			"c.created_at = LEAST(COALESCE(c.created_at, l.created_at), COALESCE(l.created_at, c.created_at)), " +
+			"c.updated_at = GREATEST(COALESCE(c.updated_at, l.updated_at), COALESCE(l.updated_at, c.updated_at)) " +
+			"WHERE c.conversation_id = ?",
+	},
+	// 最后一条消息取较新的记录（先改消息ID，再改时间），创建时间取较早值
+	"conversations": {
+		"UPDATE `conversations` AS c JOIN `conversations` AS l ON l.conversation_id = ? " +
+			"SET c.last_message_id = l.last_message_id " +
+			"WHERE c.conversation_id = ? AND l.last_message_at IS NOT NULL AND (c.last_message_at IS NULL OR l.last_message_at > c.last_message_at)",
+		"UPDATE `conversations` AS c JOIN `conversations` AS l ON l.conversation_id = ? " +
+			"SET c.last_message_at = GREATEST(COALESCE(c.last_message_at, l.last_message_at), COALESCE(l.last_message_at, c.last_message_at)), " +
+			"c.created_at = LEAST(COALESCE(c.created_at, l.created_at), COALESCE(l.created_at, c.created_at)), " +
+			"c.updated_at = GREATEST(COALESCE(c.updated_at, l.updated_at), COALESCE(l.updated_at, c.updated_at)) " +
+			"WHERE c.conversation_id = ?",
+	},
+}
+
+// upCanonicalConversationIDs 逐表改写旧格式会话ID
+// 同一会话已存在规范格式记录时（唯一键冲突），旧记录的未读数、置顶/免打扰/删除状态和最后一条消息合并到规范格式记录后再删除
+func upCanonicalConversationIDs(ctx context.Context, tx *sql.Tx) error {
+	for _, table := range conversationIDTables {
+		legacyIDs, err := selectLegacyConversationIDs(ctx, tx, table)
+		if err != nil {
+			return fmt.Errorf("select legacy conversation ids from %s error: %w", table, err)
+		}
+
+		migrated, merged := 0, int64(0)
+		for _, legacyID := range legacyIDs {
+			canonical := model.CanonicalConversationID(legacyID)
+			if canonical == legacyID {
+				continue // 无法解析的会话ID保持原样
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE IGNORE `"+table+"` SET conversation_id = ? WHERE conversation_id = ?", canonical, legacyID); err != nil {
+				return fmt.Errorf("migrate conversation id %s in %s error: %w", legacyID, table, err)
+			}
+			// 剩余的旧记录与规范格式记录冲突，合并后删除
+			for _, stmt := range mergeLegacyConversationRows[table] {
+				if _, err := tx.ExecContext(ctx, stmt, legacyID, canonical); err != nil {
+					return fmt.Errorf("merge conversation id %s in %s error: %w", legacyID, table, err)
+				}
+			}
+			result, err := tx.ExecContext(ctx, "DELETE FROM `"+table+"` WHERE conversation_id = ?", legacyID)
+			if err != nil {
+				return fmt.Errorf("delete duplicated conversation id %s in %s error: %w", legacyID, table, err)
+			}
+			if n, err := result.RowsAffected(); err == nil {
+				merged += n
+			}
+			migrated++
+		}
+		log.Printf("mysql migration: canonicalized %d conversation ids in %s, merged %d duplicated rows", migrated, table, merged)
+	}
+	return nil
+}
+
+// selectLegacyConversationIDs 查询表中非规范格式的会话ID
+func selectLegacyConversationIDs(ctx context.Context, tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT DISTINCT conversation_id FROM `"+table+"` WHERE conversation_id NOT REGEXP ?", canonicalConversationIDPattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// upMongoCanonicalConversationIDs 将消息集合中的旧格式会话ID统一为规范格式
+func upMongoCanonicalConversationIDs(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection(repository.CollectionMessages)
+	legacyIDs, err := collection.Distinct(ctx, "conversation_id", bson.M{
+		"conversation_id": bson.M{
+			"$type": "string",
+			"$not":  primitive.Regex{Pattern: canonicalConversationIDPattern},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var migrated int64
+	for _, v := range legacyIDs {
+		legacyID, ok := v.(string)
+		if !ok {
+			continue
+		}
+		canonical := model.CanonicalConversationID(legacyID)
+		if canonical == legacyID {
+			continue
+		}
+		result, err := collection.UpdateMany(ctx,
+			bson.M{"conversation_id": legacyID},
+			bson.M{"$set": bson.M{"conversation_id": canonical}},
+		)
+		if err != nil {
+			return fmt.Errorf("migrate conversation id %s error: %w", legacyID, err)
+		}
+		migrated += result.ModifiedCount
+	}
+	log.Printf("mongodb migration: canonicalized conversation id of %d messages", migrated)
+	return nil
+}
@@ -0,0 +1,136 @@
+// Package migration 数据库结构版本管理（MySQL使用goose版本化SQL迁移，MongoDB使用脚本化索引迁移）
+package migration
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+
+	"github.com/pressly/goose/v3"
+	"gorm.io/gorm"
+
+	"github.com/d60-lab/im-system/pkg/database"
+)
+
+//go:embed mysql/*.sql
+var mysqlMigrations embed.FS
+
+// 迁移错误定义
+var (
+	ErrSchemaOutdated = errors.New("database schema is out of date, run `gateway migrate up` first")
+	ErrNoMigration    = errors.New("no migration to roll back")
+)
+
+// Status 迁移状态
+type Status struct {
+	Store       string `json:"store"` // mysql / mongodb
+	Version     int64  `json:"version"`
+	Description string `json:"description"`
+	Applied     bool   `json:"applied"`
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	mysql *goose.Provider
+	mongo *database.MongoClient
+}
+
+// NewMigrator 创建迁移执行器
+func NewMigrator(db *gorm.DB, mongoClient *database.MongoClient) (*Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sql.DB: %w", err)
+	}
+
+	fsys, err := fs.Sub(mysqlMigrations, "mysql")
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := goose.NewProvider(goose.DialectMySQL, sqlDB, fsys,
+		goose.WithGoMigrations(mysqlConversationIDMigration()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mysql migration provider: %w", err)
+	}
+
+	return &Migrator{mysql: provider, mongo: mongoClient}, nil
+}
+
+// Up 执行全部未应用的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	results, err := m.mysql.Up(ctx)
+	for _, r := range results {
+		log.Printf("mysql migration: %s", r)
+	}
+	if err != nil {
+		return fmt.Errorf("mysql migration failed: %w", err)
+	}
+
+	if err := m.upMongo(ctx); err != nil {
+		return fmt.Errorf("mongodb migration failed: %w", err)
+	}
+	return nil
+}
+
+// Down 回滚最近一次MySQL迁移（MongoDB索引迁移只向前执行）
+func (m *Migrator) Down(ctx context.Context) error {
+	result, err := m.mysql.Down(ctx)
+	if errors.Is(err, goose.ErrNoNextVersion) {
+		return ErrNoMigration
+	}
+	if err != nil {
+		return fmt.Errorf("mysql rollback failed: %w", err)
+	}
+	log.Printf("mysql migration: %s", result)
+	return nil
+}
+
+// Status 获取全部迁移的状态
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	mysqlStatus, err := m.mysql.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get mysql migration status error: %w", err)
+	}
+
+	var result []*Status
+	for _, s := range mysqlStatus {
+		result = append(result, &Status{
+			Store:       "mysql",
+			Version:     s.Source.Version,
+			Description: s.Source.Path,
+			Applied:     s.State == goose.StateApplied,
+		})
+	}
+
+	applied, err := m.appliedMongoVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get mongodb migration status error: %w", err)
+	}
+	for _, mm := range mongoMigrations {
+		result = append(result, &Status{
+			Store:       "mongodb",
+			Version:     mm.Version,
+			Description: mm.Description,
+			Applied:     applied[mm.Version],
+		})
+	}
+	return result, nil
+}
+
+// Check 检查数据库结构是否为最新版本，存在未应用的迁移时返回ErrSchemaOutdated
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		if !s.Applied {
+			return fmt.Errorf("%w: %s migration %d (%s) not applied", ErrSchemaOutdated, s.Store, s.Version, s.Description)
+		}
+	}
+	return nil
+}
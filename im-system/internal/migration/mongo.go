@@ -0,0 +1,198 @@
+package migration
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// mongoMigrationCollection MongoDB迁移记录集合
+const mongoMigrationCollection = "schema_migrations"
+
+// MongoMigration MongoDB迁移脚本（版本号只增不改，已发布的迁移不可修改）
+type MongoMigration struct {
+	Version     int64
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// mongoMigrationRecord 迁移记录
+type mongoMigrationRecord struct {
+	Version     int64     `bson:"version"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// mongoMigrations 全部MongoDB迁移（按版本号递增）
+var mongoMigrations = []*MongoMigration{
+	{
+		Version:     1,
+		Description: "create messages indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(repository.CollectionMessages).Indexes().CreateMany(ctx, []mongo.IndexModel{
+				// 消息ID唯一索引
+				{
+					Keys:    bson.D{{Key: "message_id", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+				// 会话ID + 序号复合索引（用于分页查询）
+				{
+					Keys: bson.D{
+						{Key: "conversation_id", Value: 1},
+						{Key: "seq", Value: -1},
+					},
+				},
+				// 群组ID + 创建时间索引
+				{
+					Keys: bson.D{
+						{Key: "group_id", Value: 1},
+						{Key: "created_at", Value: -1},
+					},
+				},
+				// 发送者索引
+				{
+					Keys: bson.D{{Key: "from", Value: 1}},
+				},
+				// 接收者索引
+				{
+					Keys: bson.D{{Key: "to", Value: 1}},
+				},
+				// TTL索引（自动清理过期消息）
+				{
+					Keys:    bson.D{{Key: "expire_at", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(0),
+				},
+				// 创建时间索引
+				{
+					Keys: bson.D{{Key: "created_at", Value: -1}},
+				},
+			})
+			return err
+		},
+	},
+	{
+		Version:     2,
+		Description: "canonicalize messages conversation_id",
+		Up:          upMongoCanonicalConversationIDs,
+	},
+	{
+		Version:     3,
+		Description: "create message_quarantine indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(repository.CollectionMessageQuarantine).Indexes().CreateMany(ctx, []mongo.IndexModel{
+				// 消息ID唯一索引
+				{
+					Keys:    bson.D{{Key: "message_id", Value: 1}},
+					Options: options.Index().SetUnique(true),
+				},
+				// 隔离时间索引（审核列表）
+				{
+					Keys: bson.D{{Key: "quarantined_at", Value: -1}},
+				},
+			})
+			return err
+		},
+	},
+	{
+		Version:     4,
+		Description: "create message_latency_samples indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(repository.CollectionMessageLatency).Indexes().CreateMany(ctx, []mongo.IndexModel{
+				// TTL索引（自动清理过期抽样）
+				{
+					Keys:    bson.D{{Key: "received_at", Value: 1}},
+					Options: options.Index().SetExpireAfterSeconds(int32(repository.MessageLatencyTTL / time.Second)),
+				},
+				// 节点 + 总耗时索引（定位慢消息）
+				{
+					Keys: bson.D{
+						{Key: "node_id", Value: 1},
+						{Key: "total_ms", Value: -1},
+					},
+				},
+			})
+			return err
+		},
+	},
+	{
+		Version:     5,
+		Description: "create messages mention_targets index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(repository.CollectionMessages).Indexes().CreateOne(ctx, mongo.IndexModel{
+				// 提及对象 + 创建时间索引（查询/审计提及某用户的消息，多键索引）
+				Keys: bson.D{
+					{Key: "mention_targets", Value: 1},
+					{Key: "created_at", Value: -1},
+				},
+				Options: options.Index().SetSparse(true),
+			})
+			return err
+		},
+	},
+	{
+		Version:     6,
+		Description: "create messages text index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(repository.CollectionMessages).Indexes().CreateOne(ctx, mongo.IndexModel{
+				// 文本消息全文索引（不做词干化，中日韩文由检索时回退为正则匹配）
+				Keys:    bson.D{{Key: "content.text", Value: "text"}},
+				Options: options.Index().SetDefaultLanguage("none"),
+			})
+			return err
+		},
+	},
+}
+
+// appliedMongoVersions 获取已应用的MongoDB迁移版本
+func (m *Migrator) appliedMongoVersions(ctx context.Context) (map[int64]bool, error) {
+	cursor, err := m.mongo.Collection(mongoMigrationCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []mongoMigrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]bool, len(records))
+	for _, r := range records {
+		applied[r.Version] = true
+	}
+	return applied, nil
+}
+
+// upMongo 按版本顺序执行未应用的MongoDB迁移
+func (m *Migrator) upMongo(ctx context.Context) error {
+	applied, err := m.appliedMongoVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	records := m.mongo.Collection(mongoMigrationCollection)
+	for _, mm := range mongoMigrations {
+		if applied[mm.Version] {
+			continue
+		}
+		start := time.Now()
+		if err := mm.Up(ctx, m.mongo.Database()); err != nil {
+			return err
+		}
+		if _, err := records.InsertOne(ctx, &mongoMigrationRecord{
+			Version:     mm.Version,
+			Description: mm.Description,
+			AppliedAt:   time.Now(),
+		}); err != nil {
+			return err
+		}
+		log.Printf("mongodb migration: OK up %d %s (%s)", mm.Version, mm.Description, time.Since(start))
+	}
+	return nil
+}
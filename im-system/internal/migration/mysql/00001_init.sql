@@ -0,0 +1,157 @@
+-- 初始表结构（与原AutoMigrate生成的结构一致，已存在的表会被跳过）
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `users` (
+  `user_id` varchar(64) NOT NULL,
+  `tenant_id` varchar(64) DEFAULT NULL,
+  `username` varchar(64) NOT NULL,
+  `nickname` varchar(64) DEFAULT NULL,
+  `avatar` varchar(512) DEFAULT NULL,
+  `phone` varchar(32) DEFAULT NULL,
+  `searchable` tinyint(1) DEFAULT 1,
+  `password_hash` varchar(256) NOT NULL,
+  `status` bigint DEFAULT 1,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`user_id`),
+  UNIQUE KEY `idx_users_username` (`username`),
+  KEY `idx_users_tenant_id` (`tenant_id`),
+  KEY `idx_users_phone` (`phone`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_rename_history` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` varchar(64) DEFAULT NULL,
+  `field` varchar(16) DEFAULT NULL,
+  `old_name` varchar(64) DEFAULT NULL,
+  `new_name` varchar(64) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_field_time` (`user_id`, `field`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `groups` (
+  `group_id` varchar(64) NOT NULL,
+  `name` varchar(128) NOT NULL,
+  `avatar` varchar(512) DEFAULT NULL,
+  `announcement` text,
+  `description` varchar(512) DEFAULT NULL,
+  `owner_id` varchar(64) NOT NULL,
+  `max_members` bigint DEFAULT 500,
+  `member_count` bigint DEFAULT 0,
+  `mute_all` tinyint(1) DEFAULT 0,
+  `join_mode` bigint DEFAULT 0,
+  `status` bigint DEFAULT 1,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`group_id`),
+  KEY `idx_groups_owner_id` (`owner_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `group_members` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `group_id` varchar(64) NOT NULL,
+  `user_id` varchar(64) NOT NULL,
+  `role` bigint DEFAULT 0,
+  `nickname` varchar(64) DEFAULT NULL,
+  `mute_until` bigint DEFAULT 0,
+  `joined_at` datetime(3) DEFAULT NULL,
+  `inviter_id` varchar(64) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_group_user` (`group_id`, `user_id`),
+  KEY `idx_group_members_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `offline_messages` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` varchar(64) DEFAULT NULL,
+  `message_id` varchar(64) DEFAULT NULL,
+  `conversation_id` varchar(128) DEFAULT NULL,
+  `content` text,
+  `pushed` tinyint(1) DEFAULT 0,
+  `pushed_at` datetime(3) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  `expire_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_offline_messages_message_id` (`message_id`),
+  KEY `idx_user_created` (`user_id`, `created_at`),
+  KEY `idx_offline_messages_pushed` (`pushed`),
+  KEY `idx_offline_messages_expire_at` (`expire_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `conversations` (
+  `conversation_id` varchar(128) NOT NULL,
+  `type` tinyint NOT NULL,
+  `last_message_id` varchar(64) DEFAULT NULL,
+  `last_message_at` datetime(3) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`conversation_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_conversations` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` varchar(64) DEFAULT NULL,
+  `conversation_id` varchar(128) DEFAULT NULL,
+  `unread_count` bigint DEFAULT 0,
+  `last_read_seq` bigint DEFAULT 0,
+  `muted` tinyint(1) DEFAULT 0,
+  `pinned` tinyint(1) DEFAULT 0,
+  `deleted` tinyint(1) DEFAULT 0,
+  `updated_at` datetime(3) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_user_conv` (`user_id`, `conversation_id`),
+  KEY `idx_user_updated` (`updated_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `devices` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` varchar(64) NOT NULL,
+  `device_token` varchar(256) NOT NULL,
+  `platform` varchar(16) NOT NULL,
+  `app_version` varchar(32) DEFAULT NULL,
+  `device_info` varchar(256) DEFAULT NULL,
+  `push_enabled` tinyint(1) DEFAULT 1,
+  `updated_at` datetime(3) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_devices_device_token` (`device_token`),
+  KEY `idx_devices_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `files` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `file_id` varchar(64) NOT NULL,
+  `user_id` varchar(64) NOT NULL,
+  `file_name` varchar(256) NOT NULL,
+  `file_size` bigint NOT NULL,
+  `file_ext` varchar(32) DEFAULT NULL,
+  `mime_type` varchar(128) DEFAULT NULL,
+  `file_type` tinyint DEFAULT NULL,
+  `storage_path` varchar(512) NOT NULL,
+  `thumbnail_path` varchar(512) DEFAULT NULL,
+  `md5` varchar(64) DEFAULT NULL,
+  `sha256` varchar(64) DEFAULT NULL,
+  `width` bigint DEFAULT 0,
+  `height` bigint DEFAULT 0,
+  `duration` bigint DEFAULT 0,
+  `status` bigint DEFAULT 1,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_files_file_id` (`file_id`),
+  KEY `idx_files_user_id` (`user_id`),
+  KEY `idx_files_sha256` (`sha256`),
+  KEY `idx_files_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `files`;
+DROP TABLE IF EXISTS `devices`;
+DROP TABLE IF EXISTS `user_conversations`;
+DROP TABLE IF EXISTS `conversations`;
+DROP TABLE IF EXISTS `offline_messages`;
+DROP TABLE IF EXISTS `group_members`;
+DROP TABLE IF EXISTS `groups`;
+DROP TABLE IF EXISTS `user_rename_history`;
+DROP TABLE IF EXISTS `users`;
@@ -0,0 +1,7 @@
+-- 批量导入用户的初始密码须在首次登录后修改
+
+-- +goose Up
+ALTER TABLE `users` ADD COLUMN `must_reset_password` tinyint(1) DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE `users` DROP COLUMN `must_reset_password`;
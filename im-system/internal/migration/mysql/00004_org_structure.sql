@@ -0,0 +1,34 @@
+-- 组织架构：部门树及部门成员
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `departments` (
+  `department_id` varchar(64) NOT NULL,
+  `tenant_id` varchar(64) DEFAULT NULL,
+  `parent_id` varchar(64) DEFAULT NULL,
+  `name` varchar(128) NOT NULL,
+  `path` varchar(1024) DEFAULT NULL,
+  `sort_order` bigint DEFAULT 0,
+  `visibility` bigint DEFAULT 0,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`department_id`),
+  KEY `idx_dept_tenant_parent` (`tenant_id`, `parent_id`),
+  KEY `idx_departments_path` (`path`(255))
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `department_members` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `department_id` varchar(64) DEFAULT NULL,
+  `user_id` varchar(64) DEFAULT NULL,
+  `title` varchar(64) DEFAULT NULL,
+  `is_primary` tinyint(1) DEFAULT 0,
+  `sort_order` bigint DEFAULT 0,
+  `joined_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_dept_user` (`department_id`, `user_id`),
+  KEY `idx_department_members_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `department_members`;
+DROP TABLE IF EXISTS `departments`;
@@ -0,0 +1,22 @@
+-- 消息提醒
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `message_reminders` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `reminder_id` varchar(64) DEFAULT NULL,
+  `user_id` varchar(64) DEFAULT NULL,
+  `message_id` varchar(64) DEFAULT NULL,
+  `conversation_id` varchar(128) DEFAULT NULL,
+  `note` varchar(256) DEFAULT NULL,
+  `remind_at` datetime(3) DEFAULT NULL,
+  `status` bigint DEFAULT 0,
+  `fired_at` datetime(3) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_message_reminders_reminder_id` (`reminder_id`),
+  KEY `idx_reminder_user_status` (`user_id`, `status`),
+  KEY `idx_reminder_status_at` (`status`, `remind_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `message_reminders`;
@@ -0,0 +1,15 @@
+-- 用户自动回复设置
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `user_auto_replies` (
+  `user_id` varchar(64) NOT NULL,
+  `enabled` tinyint(1) DEFAULT 0,
+  `text` varchar(512) DEFAULT NULL,
+  `start_at` datetime(3) DEFAULT NULL,
+  `end_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `user_auto_replies`;
@@ -0,0 +1,24 @@
+-- 集成应用及会话的自定义消息签名策略
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `integration_apps` (
+  `app_id` varchar(64) NOT NULL,
+  `name` varchar(128) NOT NULL,
+  `secret` varchar(128) NOT NULL,
+  `disabled` tinyint(1) DEFAULT 0,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`app_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `conversation_app_policies` (
+  `conversation_id` varchar(128) NOT NULL,
+  `require_verified` tinyint(1) DEFAULT 0,
+  `updated_by` varchar(64) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`conversation_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `conversation_app_policies`;
+DROP TABLE IF EXISTS `integration_apps`;
@@ -0,0 +1,7 @@
+-- 按会话拉取及确认离线消息
+
+-- +goose Up
+CREATE INDEX `idx_user_conversation` ON `offline_messages` (`user_id`, `conversation_id`);
+
+-- +goose Down
+DROP INDEX `idx_user_conversation` ON `offline_messages`;
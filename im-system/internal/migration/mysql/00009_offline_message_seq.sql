@@ -0,0 +1,7 @@
+-- 离线消息记录消息序号，已读时按序号对账清理
+
+-- +goose Up
+ALTER TABLE `offline_messages` ADD COLUMN `seq` bigint DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE `offline_messages` DROP COLUMN `seq`;
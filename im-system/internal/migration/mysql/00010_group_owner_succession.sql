@@ -0,0 +1,21 @@
+-- 群主账号禁用/注销后的群主继任审计记录
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `group_owner_successions` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `group_id` varchar(64) NOT NULL,
+  `previous_owner_id` varchar(64) NOT NULL,
+  `new_owner_id` varchar(64) DEFAULT NULL,
+  `reason` varchar(32) NOT NULL,
+  `action` varchar(32) NOT NULL,
+  `dismiss_at` datetime(3) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_group_owner_successions_group_id` (`group_id`),
+  KEY `idx_group_owner_successions_previous_owner_id` (`previous_owner_id`),
+  KEY `idx_action_dismiss_at` (`action`, `dismiss_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `group_owner_successions`;
@@ -0,0 +1,9 @@
+-- 群组及群成员乐观锁版本号，防止转让群主、设置管理员等并发操作互相覆盖
+
+-- +goose Up
+ALTER TABLE `groups` ADD COLUMN `version` bigint NOT NULL DEFAULT 0;
+ALTER TABLE `group_members` ADD COLUMN `version` bigint NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE `group_members` DROP COLUMN `version`;
+ALTER TABLE `groups` DROP COLUMN `version`;
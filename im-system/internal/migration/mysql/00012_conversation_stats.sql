@@ -0,0 +1,27 @@
+-- 会话聚合统计，供管理后台分析查询使用，避免分析查询扫描线上消息与会话表
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `conversation_stats` (
+  `conversation_id` varchar(128) NOT NULL,
+  `type` tinyint NOT NULL,
+  `message_count` bigint DEFAULT 0,
+  `participant_count` bigint DEFAULT 0,
+  `last_activity_at` datetime(3) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`conversation_id`),
+  KEY `idx_conversation_stats_message_count` (`message_count`),
+  KEY `idx_type_last_activity` (`type`, `last_activity_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `conversation_participant_stats` (
+  `conversation_id` varchar(128) NOT NULL,
+  `user_id` varchar(64) NOT NULL,
+  `message_count` bigint DEFAULT 0,
+  `last_active_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`conversation_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `conversation_participant_stats`;
+DROP TABLE IF EXISTS `conversation_stats`;
@@ -0,0 +1,7 @@
+-- 压缩包上传时的安全检查结果及文件列表（供客户端预览）
+
+-- +goose Up
+ALTER TABLE `files` ADD COLUMN `archive_info` json NULL;
+
+-- +goose Down
+ALTER TABLE `files` DROP COLUMN `archive_info`;
@@ -0,0 +1,15 @@
+-- 聊天文件保存期限：过期时间、过期清理时间及长期保存标记
+
+-- +goose Up
+ALTER TABLE `files`
+    ADD COLUMN `expires_at` datetime(3) NULL,
+    ADD COLUMN `expired_at` datetime(3) NULL,
+    ADD COLUMN `retained` tinyint(1) NOT NULL DEFAULT 0,
+    ADD INDEX `idx_files_expires_at` (`expires_at`);
+
+-- +goose Down
+ALTER TABLE `files`
+    DROP INDEX `idx_files_expires_at`,
+    DROP COLUMN `retained`,
+    DROP COLUMN `expired_at`,
+    DROP COLUMN `expires_at`;
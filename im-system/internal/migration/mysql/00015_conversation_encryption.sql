@@ -0,0 +1,15 @@
+-- 会话客户端加密：加密标记、会话密钥版本及最近轮换时间
+
+-- +goose Up
+ALTER TABLE `conversations`
+    ADD COLUMN `encrypted` tinyint(1) NOT NULL DEFAULT 0,
+    ADD COLUMN `key_version` int NOT NULL DEFAULT 0,
+    ADD COLUMN `key_rotated_at` datetime(3) NULL,
+    ADD INDEX `idx_conversations_encrypted` (`encrypted`);
+
+-- +goose Down
+ALTER TABLE `conversations`
+    DROP INDEX `idx_conversations_encrypted`,
+    DROP COLUMN `key_rotated_at`,
+    DROP COLUMN `key_version`,
+    DROP COLUMN `encrypted`;
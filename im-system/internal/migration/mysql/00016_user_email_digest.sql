@@ -0,0 +1,11 @@
+-- 长期离线用户的未读消息邮件摘要：用户邮箱及退订标记
+
+-- +goose Up
+ALTER TABLE `users`
+    ADD COLUMN `email` varchar(255) NULL,
+    ADD COLUMN `email_digest` tinyint(1) NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE `users`
+    DROP COLUMN `email_digest`,
+    DROP COLUMN `email`;
@@ -0,0 +1,30 @@
+-- Slack/Matrix 桥接：群组与外部频道映射、外部用户映射
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `bridge_rooms` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `group_id` varchar(64) NOT NULL,
+  `platform` varchar(16) NOT NULL,
+  `remote_room_id` varchar(255) NOT NULL,
+  `disabled` tinyint(1) DEFAULT 0,
+  `created_by` varchar(64) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_group_platform` (`group_id`, `platform`),
+  UNIQUE KEY `uk_platform_room` (`platform`, `remote_room_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `bridge_users` (
+  `platform` varchar(16) NOT NULL,
+  `remote_user_id` varchar(255) NOT NULL,
+  `user_id` varchar(64) NOT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`platform`, `remote_user_id`),
+  KEY `idx_bridge_users_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `bridge_users`;
+DROP TABLE IF EXISTS `bridge_rooms`;
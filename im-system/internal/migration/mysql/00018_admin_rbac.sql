@@ -0,0 +1,43 @@
+-- 管理接口权限：自定义角色、用户角色分配及审计日志（内置角色定义在代码中）
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `admin_roles` (
+  `name` varchar(64) NOT NULL,
+  `description` varchar(256) DEFAULT NULL,
+  `permissions` json DEFAULT NULL,
+  `updated_by` varchar(64) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `admin_role_assignments` (
+  `user_id` varchar(64) NOT NULL,
+  `role` varchar(64) NOT NULL,
+  `granted_by` varchar(64) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`user_id`, `role`),
+  KEY `idx_admin_role_assignments_role` (`role`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `admin_audit_logs` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `user_id` varchar(64) DEFAULT NULL,
+  `method` varchar(8) DEFAULT NULL,
+  `path` varchar(255) DEFAULT NULL,
+  `route` varchar(255) DEFAULT NULL,
+  `permission` varchar(64) DEFAULT NULL,
+  `allowed` tinyint(1) DEFAULT NULL,
+  `status` int DEFAULT NULL,
+  `client_ip` varchar(64) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_audit_user_time` (`user_id`, `created_at`),
+  KEY `idx_admin_audit_logs_permission` (`permission`),
+  KEY `idx_admin_audit_logs_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `admin_audit_logs`;
+DROP TABLE IF EXISTS `admin_role_assignments`;
+DROP TABLE IF EXISTS `admin_roles`;
@@ -0,0 +1,20 @@
+-- 会话摘要：外部摘要服务生成的摘要，仅请求者可见
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `conversation_summaries` (
+  `summary_id` varchar(64) NOT NULL,
+  `conversation_id` varchar(128) DEFAULT NULL,
+  `user_id` varchar(64) DEFAULT NULL,
+  `summary` text,
+  `message_count` bigint DEFAULT NULL,
+  `from_message_id` varchar(64) DEFAULT NULL,
+  `to_message_id` varchar(64) DEFAULT NULL,
+  `from_timestamp` bigint DEFAULT NULL,
+  `to_timestamp` bigint DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`summary_id`),
+  KEY `idx_summary_conv_user` (`conversation_id`, `user_id`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `conversation_summaries`;
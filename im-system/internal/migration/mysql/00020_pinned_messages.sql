@@ -0,0 +1,13 @@
+-- 会话置顶消息
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `pinned_messages` (
+  `conversation_id` varchar(128) NOT NULL,
+  `message_id` varchar(64) NOT NULL,
+  `pinned_by` varchar(64) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`conversation_id`, `message_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `pinned_messages`;
@@ -0,0 +1,21 @@
+-- 数据驻留：用户所属区域、会话消息存储区域及文件存储区域
+
+-- +goose Up
+ALTER TABLE `users`
+    ADD COLUMN `region` varchar(32) NULL;
+
+ALTER TABLE `conversations`
+    ADD COLUMN `region` varchar(32) NULL;
+
+ALTER TABLE `files`
+    ADD COLUMN `region` varchar(32) NULL;
+
+-- +goose Down
+ALTER TABLE `files`
+    DROP COLUMN `region`;
+
+ALTER TABLE `conversations`
+    DROP COLUMN `region`;
+
+ALTER TABLE `users`
+    DROP COLUMN `region`;
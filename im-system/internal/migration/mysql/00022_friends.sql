@@ -0,0 +1,36 @@
+-- 好友与屏蔽
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `friend_requests` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `request_id` varchar(64) DEFAULT NULL,
+  `from_user_id` varchar(64) DEFAULT NULL,
+  `to_user_id` varchar(64) DEFAULT NULL,
+  `message` varchar(256) DEFAULT NULL,
+  `status` bigint DEFAULT 0,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_friend_requests_request_id` (`request_id`),
+  UNIQUE KEY `idx_friend_request_pair` (`from_user_id`, `to_user_id`),
+  KEY `idx_friend_request_to_status` (`to_user_id`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `friends` (
+  `user_id` varchar(64) NOT NULL,
+  `friend_id` varchar(64) NOT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`user_id`, `friend_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_blocks` (
+  `user_id` varchar(64) NOT NULL,
+  `blocked_id` varchar(64) NOT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`user_id`, `blocked_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `user_blocks`;
+DROP TABLE IF EXISTS `friends`;
+DROP TABLE IF EXISTS `friend_requests`;
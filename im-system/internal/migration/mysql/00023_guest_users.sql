@@ -0,0 +1,13 @@
+-- 访客账号
+
+-- +goose Up
+ALTER TABLE `users`
+    ADD COLUMN `guest` boolean DEFAULT false,
+    ADD COLUMN `guest_expires_at` datetime(3) NULL,
+    ADD KEY `idx_users_guest_expires_at` (`guest_expires_at`);
+
+-- +goose Down
+ALTER TABLE `users`
+    DROP KEY `idx_users_guest_expires_at`,
+    DROP COLUMN `guest_expires_at`,
+    DROP COLUMN `guest`;
@@ -0,0 +1,53 @@
+-- 客服坐席、会话与快捷回复
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `cs_agents` (
+  `user_id` varchar(64) NOT NULL,
+  `max_concurrent` bigint DEFAULT 5,
+  `status` bigint DEFAULT 0,
+  `active_count` bigint DEFAULT 0,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`user_id`),
+  KEY `idx_cs_agents_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `cs_sessions` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `session_id` varchar(64) DEFAULT NULL,
+  `visitor_id` varchar(64) DEFAULT NULL,
+  `agent_id` varchar(64) DEFAULT NULL,
+  `status` bigint DEFAULT 0,
+  `conversation_id` varchar(128) DEFAULT NULL,
+  `subject` varchar(256) DEFAULT NULL,
+  `transfer_count` bigint DEFAULT 0,
+  `closed_by` varchar(64) DEFAULT NULL,
+  `rating` bigint DEFAULT 0,
+  `rating_comment` varchar(512) DEFAULT NULL,
+  `queued_at` datetime(3) DEFAULT NULL,
+  `assigned_at` datetime(3) DEFAULT NULL,
+  `closed_at` datetime(3) DEFAULT NULL,
+  `rated_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_cs_sessions_session_id` (`session_id`),
+  KEY `idx_cs_session_visitor_status` (`visitor_id`, `status`),
+  KEY `idx_cs_session_agent_status` (`agent_id`, `status`),
+  KEY `idx_cs_session_queue` (`status`, `queued_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `cs_canned_replies` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `agent_id` varchar(64) DEFAULT NULL,
+  `title` varchar(64) DEFAULT NULL,
+  `content` text,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_cs_canned_replies_agent_id` (`agent_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `cs_canned_replies`;
+DROP TABLE IF EXISTS `cs_sessions`;
+DROP TABLE IF EXISTS `cs_agents`;
@@ -0,0 +1,7 @@
+-- 新设备首次同步时被会话摘要快照取代的离线消息
+
+-- +goose Up
+ALTER TABLE `offline_messages` ADD COLUMN `superseded` tinyint(1) DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE `offline_messages` DROP COLUMN `superseded`;
@@ -0,0 +1,35 @@
+-- 群投票及投票记录
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `polls` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `poll_id` varchar(64) DEFAULT NULL,
+  `group_id` varchar(64) DEFAULT NULL,
+  `message_id` varchar(64) DEFAULT NULL,
+  `creator_id` varchar(64) DEFAULT NULL,
+  `question` varchar(256) DEFAULT NULL,
+  `options` json DEFAULT NULL,
+  `multi_choice` tinyint(1) DEFAULT 0,
+  `anonymous` tinyint(1) DEFAULT 0,
+  `deadline` datetime(3) DEFAULT NULL,
+  `status` bigint DEFAULT 0,
+  `result_message_id` varchar(64) DEFAULT NULL,
+  `closed_at` datetime(3) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_polls_poll_id` (`poll_id`),
+  KEY `idx_polls_group_id` (`group_id`),
+  KEY `idx_poll_status_deadline` (`status`, `deadline`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `poll_votes` (
+  `poll_id` varchar(64) NOT NULL,
+  `user_id` varchar(64) NOT NULL,
+  `options` json DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`poll_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `poll_votes`;
+DROP TABLE IF EXISTS `polls`;
@@ -0,0 +1,19 @@
+-- 入群申请（加入需审批的群时创建）
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `group_join_requests` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `group_id` varchar(64) NOT NULL,
+  `user_id` varchar(64) NOT NULL,
+  `message` varchar(256) DEFAULT NULL,
+  `status` bigint DEFAULT 0,
+  `handler_id` varchar(64) DEFAULT NULL,
+  `handled_at` datetime(3) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_group_status` (`group_id`, `status`),
+  KEY `idx_group_join_requests_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `group_join_requests`;
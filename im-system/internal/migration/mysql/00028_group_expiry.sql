@@ -0,0 +1,15 @@
+-- 临时群：到期自动解散
+
+-- +goose Up
+ALTER TABLE `groups`
+    ADD COLUMN `expires_at` datetime(3) NULL,
+    ADD COLUMN `expiry_warned` boolean DEFAULT false,
+    ADD COLUMN `export_on_expiry` boolean DEFAULT false,
+    ADD KEY `idx_groups_expires_at` (`expires_at`);
+
+-- +goose Down
+ALTER TABLE `groups`
+    DROP KEY `idx_groups_expires_at`,
+    DROP COLUMN `export_on_expiry`,
+    DROP COLUMN `expiry_warned`,
+    DROP COLUMN `expires_at`;
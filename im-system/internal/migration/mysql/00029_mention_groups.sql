@@ -0,0 +1,19 @@
+-- 群内保存的提及组
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `mention_groups` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `mention_group_id` varchar(64) DEFAULT NULL,
+  `group_id` varchar(64) DEFAULT NULL,
+  `name` varchar(32) DEFAULT NULL,
+  `member_ids` json DEFAULT NULL,
+  `creator_id` varchar(64) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_mention_groups_mention_group_id` (`mention_group_id`),
+  UNIQUE KEY `idx_group_mention_name` (`group_id`, `name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `mention_groups`;
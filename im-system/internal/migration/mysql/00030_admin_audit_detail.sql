@@ -0,0 +1,9 @@
+-- 管理审计日志：记录操作详情（如消息清除条件和数量）
+
+-- +goose Up
+ALTER TABLE `admin_audit_logs`
+    ADD COLUMN `detail` text NULL AFTER `client_ip`;
+
+-- +goose Down
+ALTER TABLE `admin_audit_logs`
+    DROP COLUMN `detail`;
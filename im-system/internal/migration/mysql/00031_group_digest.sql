@@ -0,0 +1,19 @@
+-- 群活动每日摘要订阅
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `group_digest_subscriptions` (
+  `user_id` varchar(64) NOT NULL,
+  `group_id` varchar(64) NOT NULL,
+  `send_at` varchar(5) DEFAULT NULL,
+  `timezone` varchar(64) DEFAULT NULL,
+  `push` tinyint(1) DEFAULT 0,
+  `next_run_at` datetime(3) DEFAULT NULL,
+  `last_sent_at` datetime(3) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`user_id`, `group_id`),
+  KEY `idx_group_digest_subscriptions_next_run_at` (`next_run_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `group_digest_subscriptions`;
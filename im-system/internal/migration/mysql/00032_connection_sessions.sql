@@ -0,0 +1,43 @@
+-- 连接会话计量：会话明细及用户月度用量汇总
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `connection_sessions` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `connection_id` varchar(64) DEFAULT NULL,
+  `user_id` varchar(64) DEFAULT NULL,
+  `tenant_id` varchar(64) DEFAULT NULL,
+  `node_id` varchar(64) DEFAULT NULL,
+  `platform` varchar(16) DEFAULT NULL,
+  `device_id` varchar(128) DEFAULT NULL,
+  `client_ip` varchar(64) DEFAULT NULL,
+  `app_version` varchar(32) DEFAULT NULL,
+  `connected_at` datetime(3) DEFAULT NULL,
+  `disconnected_at` datetime(3) DEFAULT NULL,
+  `duration_seconds` bigint DEFAULT 0,
+  `bytes_in` bigint DEFAULT 0,
+  `bytes_out` bigint DEFAULT 0,
+  `frames_in` bigint DEFAULT 0,
+  `frames_out` bigint DEFAULT 0,
+  PRIMARY KEY (`id`),
+  KEY `idx_connection_sessions_user` (`user_id`, `disconnected_at`),
+  KEY `idx_connection_sessions_disconnected_at` (`disconnected_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `monthly_usage` (
+  `month` varchar(7) NOT NULL,
+  `user_id` varchar(64) NOT NULL,
+  `tenant_id` varchar(64) DEFAULT NULL,
+  `sessions` bigint DEFAULT 0,
+  `duration_seconds` bigint DEFAULT 0,
+  `bytes_in` bigint DEFAULT 0,
+  `bytes_out` bigint DEFAULT 0,
+  `frames_in` bigint DEFAULT 0,
+  `frames_out` bigint DEFAULT 0,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`month`, `user_id`),
+  KEY `idx_monthly_usage_tenant` (`tenant_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `monthly_usage`;
+DROP TABLE IF EXISTS `connection_sessions`;
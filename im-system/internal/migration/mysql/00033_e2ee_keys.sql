@@ -0,0 +1,29 @@
+-- 端到端加密：设备公钥及一次性预密钥
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `e2ee_device_keys` (
+  `user_id` varchar(64) NOT NULL,
+  `device_id` varchar(128) NOT NULL,
+  `identity_key` varchar(512) DEFAULT NULL,
+  `signed_pre_key_id` bigint DEFAULT NULL,
+  `signed_pre_key` varchar(512) DEFAULT NULL,
+  `signed_pre_key_signature` varchar(512) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`user_id`, `device_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `e2ee_one_time_prekeys` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` varchar(64) DEFAULT NULL,
+  `device_id` varchar(128) DEFAULT NULL,
+  `key_id` bigint DEFAULT NULL,
+  `public_key` varchar(512) DEFAULT NULL,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_e2ee_prekeys_device_key` (`user_id`, `device_id`, `key_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `e2ee_one_time_prekeys`;
+DROP TABLE IF EXISTS `e2ee_device_keys`;
@@ -0,0 +1,9 @@
+-- 在线状态隐私设置：在线状态及最近在线时间的可见范围（everyone/friends/nobody）
+
+-- +goose Up
+ALTER TABLE `users`
+    ADD COLUMN `presence_visibility` varchar(16) NOT NULL DEFAULT 'everyone';
+
+-- +goose Down
+ALTER TABLE `users`
+    DROP COLUMN `presence_visibility`;
@@ -0,0 +1,13 @@
+-- 免打扰时段：时段内离线消息不推送到设备（HH:MM，用户时区）
+
+-- +goose Up
+ALTER TABLE `users`
+    ADD COLUMN `dnd_start` varchar(5) NULL,
+    ADD COLUMN `dnd_end` varchar(5) NULL,
+    ADD COLUMN `dnd_timezone` varchar(64) NULL;
+
+-- +goose Down
+ALTER TABLE `users`
+    DROP COLUMN `dnd_start`,
+    DROP COLUMN `dnd_end`,
+    DROP COLUMN `dnd_timezone`;
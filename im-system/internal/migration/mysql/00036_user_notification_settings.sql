@@ -0,0 +1,11 @@
+-- 通知设置：推送开关、推送预览（关闭后隐藏通知中的消息内容）
+
+-- +goose Up
+ALTER TABLE `users`
+    ADD COLUMN `push_enabled` tinyint(1) NOT NULL DEFAULT 1,
+    ADD COLUMN `push_preview` tinyint(1) NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE `users`
+    DROP COLUMN `push_enabled`,
+    DROP COLUMN `push_preview`;
@@ -0,0 +1,7 @@
+-- 浏览器推送：web 平台设备保存 Web Push 订阅（PushSubscription JSON）
+
+-- +goose Up
+ALTER TABLE `devices` ADD COLUMN `subscription` text NULL;
+
+-- +goose Down
+ALTER TABLE `devices` DROP COLUMN `subscription`;
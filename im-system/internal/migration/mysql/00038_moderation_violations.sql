@@ -0,0 +1,25 @@
+-- 内容审核违规记录
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS `moderation_violations` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `user_id` varchar(64) DEFAULT NULL,
+  `source` varchar(16) DEFAULT NULL,
+  `conversation_id` varchar(128) DEFAULT NULL,
+  `message_id` varchar(64) DEFAULT NULL,
+  `file_name` varchar(255) DEFAULT NULL,
+  `moderator` varchar(32) DEFAULT NULL,
+  `action` varchar(16) DEFAULT NULL,
+  `labels` json DEFAULT NULL,
+  `reason` varchar(512) DEFAULT NULL,
+  `content` text,
+  `created_at` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_moderation_user_time` (`user_id`, `created_at`),
+  KEY `idx_moderation_violations_conversation_id` (`conversation_id`),
+  KEY `idx_moderation_violations_action` (`action`),
+  KEY `idx_moderation_violations_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- +goose Down
+DROP TABLE IF EXISTS `moderation_violations`;
@@ -0,0 +1,18 @@
+-- 管理审计日志：记录调用结果（success 成功、failed 已放行但接口返回错误、denied 权限不足被拒绝），已有记录按 allowed 和 status 回填
+
+-- +goose Up
+ALTER TABLE `admin_audit_logs`
+    ADD COLUMN `outcome` varchar(16) DEFAULT NULL AFTER `status`,
+    ADD KEY `idx_admin_audit_logs_outcome` (`outcome`);
+
+UPDATE `admin_audit_logs`
+SET `outcome` = CASE
+    WHEN `allowed` = 0 THEN 'denied'
+    WHEN `status` >= 400 THEN 'failed'
+    ELSE 'success'
+END;
+
+-- +goose Down
+ALTER TABLE `admin_audit_logs`
+    DROP KEY `idx_admin_audit_logs_outcome`,
+    DROP COLUMN `outcome`;
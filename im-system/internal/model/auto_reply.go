@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// AutoReplySetting 用户自动回复设置（休假/离开模式）
+type AutoReplySetting struct {
+	UserID    string     `json:"-" gorm:"primaryKey;type:varchar(64)"`
+	Enabled   bool       `json:"enabled" gorm:"default:false"`
+	Text      string     `json:"text" gorm:"type:varchar(512)"`
+	StartAt   *time.Time `json:"start_at,omitempty"` // 生效开始时间，为空表示立即生效
+	EndAt     *time.Time `json:"end_at,omitempty"`   // 生效结束时间，为空表示一直生效
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (AutoReplySetting) TableName() string {
+	return "user_auto_replies"
+}
+
+// Active 自动回复在指定时间是否生效
+func (s *AutoReplySetting) Active(now time.Time) bool {
+	if s == nil || !s.Enabled || s.Text == "" {
+		return false
+	}
+	if s.StartAt != nil && now.Before(*s.StartAt) {
+		return false
+	}
+	if s.EndAt != nil && !now.Before(*s.EndAt) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package model
+
+import "time"
+
+// 桥接平台
+const (
+	BridgeSlack  = "slack"
+	BridgeMatrix = "matrix"
+)
+
+// BridgeRoom 群组与外部平台频道/房间的映射
+type BridgeRoom struct {
+	ID           int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	GroupID      string    `json:"group_id" gorm:"type:varchar(64);not null;uniqueIndex:uk_group_platform"`
+	Platform     string    `json:"platform" gorm:"type:varchar(16);not null;uniqueIndex:uk_group_platform;uniqueIndex:uk_platform_room"`
+	RemoteRoomID string    `json:"remote_room_id" gorm:"type:varchar(255);not null;uniqueIndex:uk_platform_room"` // Slack 频道ID / Matrix 房间ID
+	Disabled     bool      `json:"disabled" gorm:"default:false"`
+	CreatedBy    string    `json:"created_by" gorm:"type:varchar(64)"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (BridgeRoom) TableName() string {
+	return "bridge_rooms"
+}
+
+// BridgeUser 外部平台用户与IM用户的映射（已迁移的用户以本人身份发言，未映射的用户由桥接机器人代发）
+type BridgeUser struct {
+	Platform     string    `json:"platform" gorm:"primaryKey;type:varchar(16)"`
+	RemoteUserID string    `json:"remote_user_id" gorm:"primaryKey;type:varchar(255)"`
+	UserID       string    `json:"user_id" gorm:"type:varchar(64);not null;index"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (BridgeUser) TableName() string {
+	return "bridge_users"
+}
+
+// BindBridgeRoomRequest 绑定群组与外部频道请求
+type BindBridgeRoomRequest struct {
+	GroupID      string `json:"group_id" binding:"required"`
+	Platform     string `json:"platform" binding:"required"`
+	RemoteRoomID string `json:"remote_room_id" binding:"required"`
+	Disabled     bool   `json:"disabled"`
+}
+
+// MapBridgeUserRequest 映射外部用户请求
+type MapBridgeUserRequest struct {
+	Platform     string `json:"platform" binding:"required"`
+	RemoteUserID string `json:"remote_user_id" binding:"required"`
+	UserID       string `json:"user_id" binding:"required"`
+}
@@ -0,0 +1,304 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ContentFieldType 内容字段类型（按JSON解码后的类型校验）
+type ContentFieldType string
+
+const (
+	FieldString ContentFieldType = "string"
+	FieldNumber ContentFieldType = "number"
+	FieldBool   ContentFieldType = "bool"
+	FieldArray  ContentFieldType = "array"
+	FieldObject ContentFieldType = "object"
+)
+
+// 内容结构错误定义
+var (
+	ErrContentInvalid       = errors.New("message content does not match schema")
+	ErrContentSchemaVersion = errors.New("content schema version must follow the latest version")
+)
+
+// ContentField 内容字段定义
+type ContentField struct {
+	Name     string           `json:"name"`
+	Type     ContentFieldType `json:"type"`
+	Required bool             `json:"required,omitempty"`
+}
+
+// ContentUpgrader 将上一版本的内容升级为当前版本（可原地修改并返回）
+type ContentUpgrader func(content map[string]interface{}) map[string]interface{}
+
+// ContentSchema 某消息类型某一版本的内容结构
+// 未定义的字段不校验（兼容新版客户端附加字段），已定义字段必须类型正确
+type ContentSchema struct {
+	Type    MessageType     `json:"type"`
+	Version int             `json:"version"`
+	Fields  []ContentField  `json:"fields"`
+	AnyOf   []string        `json:"any_of,omitempty"` // 至少包含其中一个字段
+	Upgrade ContentUpgrader `json:"-"`                // 从 Version-1 升级到 Version，版本1的升级函数处理未记录版本的历史文档
+}
+
+// Validate 校验内容
+func (s *ContentSchema) Validate(content map[string]interface{}) error {
+	for _, field := range s.Fields {
+		value, ok := content[field.Name]
+		if !ok || value == nil {
+			if field.Required {
+				return fmt.Errorf("%w: %s is required", ErrContentInvalid, field.Name)
+			}
+			continue
+		}
+		if !matchFieldType(field.Type, value) {
+			return fmt.Errorf("%w: %s must be %s", ErrContentInvalid, field.Name, field.Type)
+		}
+	}
+
+	if len(s.AnyOf) > 0 {
+		for _, name := range s.AnyOf {
+			if value, ok := content[name]; ok && value != nil && value != "" {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: one of %v is required", ErrContentInvalid, s.AnyOf)
+	}
+	return nil
+}
+
+// matchFieldType 值是否符合字段类型
+func matchFieldType(fieldType ContentFieldType, value interface{}) bool {
+	switch fieldType {
+	case FieldString:
+		_, ok := value.(string)
+		return ok
+	case FieldNumber:
+		switch value.(type) {
+		case float64, float32, int, int32, int64, json.Number:
+			return true
+		}
+		return false
+	case FieldBool:
+		_, ok := value.(bool)
+		return ok
+	case FieldArray:
+		switch value.(type) {
+		case []interface{}, []string:
+			return true
+		}
+		return false
+	case FieldObject:
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return true
+}
+
+// contentSchemas 内容结构注册表（消息类型 -> 按版本升序的结构定义）
+var contentSchemas = struct {
+	mu      sync.RWMutex
+	schemas map[MessageType][]*ContentSchema
+}{schemas: make(map[MessageType][]*ContentSchema)}
+
+// RegisterContentSchema 注册内容结构，版本必须紧接当前最新版本（从1开始）
+func RegisterContentSchema(schema *ContentSchema) error {
+	contentSchemas.mu.Lock()
+	defer contentSchemas.mu.Unlock()
+
+	versions := contentSchemas.schemas[schema.Type]
+	if schema.Version != len(versions)+1 {
+		return fmt.Errorf("%w: %s v%d", ErrContentSchemaVersion, schema.Type, schema.Version)
+	}
+	contentSchemas.schemas[schema.Type] = append(versions, schema)
+	return nil
+}
+
+// mustRegisterContentSchemas 注册内置内容结构
+func mustRegisterContentSchemas(schemas ...*ContentSchema) {
+	for _, schema := range schemas {
+		if err := RegisterContentSchema(schema); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// ContentSchemaVersion 消息类型的最新内容版本（未注册结构时为0）
+func ContentSchemaVersion(t MessageType) int {
+	contentSchemas.mu.RLock()
+	defer contentSchemas.mu.RUnlock()
+	return len(contentSchemas.schemas[t])
+}
+
+// ContentSchemas 所有已注册的内容结构（按消息类型、版本排序）
+func ContentSchemas() []*ContentSchema {
+	contentSchemas.mu.RLock()
+	defer contentSchemas.mu.RUnlock()
+
+	result := make([]*ContentSchema, 0, len(contentSchemas.schemas))
+	for _, versions := range contentSchemas.schemas {
+		result = append(result, versions...)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		return result[i].Version < result[j].Version
+	})
+	return result
+}
+
+// ValidateContent 按最新版本校验内容，返回内容版本（未注册结构的类型不校验，版本为0）
+func ValidateContent(t MessageType, content map[string]interface{}) (int, error) {
+	contentSchemas.mu.RLock()
+	versions := contentSchemas.schemas[t]
+	contentSchemas.mu.RUnlock()
+
+	if len(versions) == 0 {
+		return 0, nil
+	}
+	latest := versions[len(versions)-1]
+	if err := latest.Validate(content); err != nil {
+		return 0, err
+	}
+	return latest.Version, nil
+}
+
+// UpgradeContent 将指定版本的内容依次升级到最新版本，返回升级后的内容和版本
+func UpgradeContent(t MessageType, version int, content map[string]interface{}) (map[string]interface{}, int) {
+	contentSchemas.mu.RLock()
+	versions := contentSchemas.schemas[t]
+	contentSchemas.mu.RUnlock()
+
+	for _, schema := range versions {
+		if schema.Version <= version {
+			continue
+		}
+		if schema.Upgrade != nil {
+			if content == nil {
+				content = make(map[string]interface{})
+			}
+			content = schema.Upgrade(content)
+		}
+		version = schema.Version
+	}
+	return content, version
+}
+
+// 内置消息类型的内容结构
+func init() {
+	textFields := []ContentField{
+		{Name: "text", Type: FieldString},
+		{Name: "at_user_ids", Type: FieldArray},
+		{Name: "at_all", Type: FieldBool},
+		{Name: "at_roles", Type: FieldArray},
+		{Name: "at_mention_groups", Type: FieldArray},
+		{Name: "auto_reply", Type: FieldBool},
+		{Name: "reply_to_message_id", Type: FieldString},
+		{Name: "reply_to_user_id", Type: FieldString},
+		{Name: "bridge_source", Type: FieldString},
+		{Name: "ciphertext", Type: FieldString},
+		{Name: "key_version", Type: FieldNumber},
+		{Name: "algorithm", Type: FieldString},
+	}
+	mediaFields := []ContentField{
+		{Name: "file_id", Type: FieldString},
+		{Name: "url", Type: FieldString},
+		{Name: "thumbnail_url", Type: FieldString},
+		{Name: "width", Type: FieldNumber},
+		{Name: "height", Type: FieldNumber},
+		{Name: "duration", Type: FieldNumber},
+		{Name: "file_size", Type: FieldNumber},
+		{Name: "format", Type: FieldString},
+	}
+	fileFields := []ContentField{
+		{Name: "file_id", Type: FieldString},
+		{Name: "file_name", Type: FieldString},
+		{Name: "file_size", Type: FieldNumber},
+		{Name: "file_ext", Type: FieldString},
+		{Name: "mime_type", Type: FieldString},
+		{Name: "url", Type: FieldString},
+	}
+
+	mustRegisterContentSchemas(
+		// 单聊/群聊消息也用于承载客户端自定义结构（如带 file_id 的文件），只校验已知字段的类型
+		&ContentSchema{Type: MsgText, Version: 1, Fields: textFields, Upgrade: upgradeLegacyText},
+		&ContentSchema{Type: MsgSingleChat, Version: 1, Fields: textFields, Upgrade: upgradeLegacyText},
+		&ContentSchema{Type: MsgGroupChat, Version: 1, Fields: textFields, Upgrade: upgradeLegacyText},
+		&ContentSchema{Type: MsgImage, Version: 1, Fields: mediaFields, AnyOf: []string{"file_id", "url"}, Upgrade: upgradeLegacyWrapped},
+		&ContentSchema{Type: MsgVoice, Version: 1, Fields: mediaFields, AnyOf: []string{"file_id", "url"}, Upgrade: upgradeLegacyWrapped},
+		&ContentSchema{Type: MsgVideo, Version: 1, Fields: mediaFields, AnyOf: []string{"file_id", "url"}, Upgrade: upgradeLegacyWrapped},
+		&ContentSchema{Type: MsgFile, Version: 1, Fields: fileFields, AnyOf: []string{"file_id", "url"}, Upgrade: upgradeLegacyWrapped},
+		&ContentSchema{Type: MsgLocation, Version: 1, Fields: []ContentField{
+			{Name: "latitude", Type: FieldNumber, Required: true},
+			{Name: "longitude", Type: FieldNumber, Required: true},
+			{Name: "name", Type: FieldString},
+			{Name: "address", Type: FieldString},
+			{Name: "zoom", Type: FieldNumber},
+		}, Upgrade: upgradeLegacyWrapped},
+		&ContentSchema{Type: MsgCard, Version: 1, Fields: []ContentField{
+			{Name: "user_id", Type: FieldString, Required: true},
+			{Name: "nickname", Type: FieldString},
+			{Name: "avatar", Type: FieldString},
+		}, Upgrade: upgradeLegacyWrapped},
+		&ContentSchema{Type: MsgCustom, Version: 1, Fields: []ContentField{
+			{Name: "custom_type", Type: FieldString, Required: true},
+			{Name: "data", Type: FieldObject},
+			{Name: "app_id", Type: FieldString},
+			{Name: "signed_at", Type: FieldNumber},
+			{Name: "signature", Type: FieldString},
+			{Name: "verified", Type: FieldBool},
+		}},
+		&ContentSchema{Type: MsgEncrypted, Version: 1, Fields: []ContentField{
+			{Name: "sender_device_id", Type: FieldString, Required: true},
+			{Name: "algorithm", Type: FieldString},
+			{Name: "ciphertext", Type: FieldString},
+			{Name: "envelopes", Type: FieldArray},
+			{Name: "file_ids", Type: FieldArray},
+		}, AnyOf: []string{"ciphertext", "envelopes"}},
+		&ContentSchema{Type: MsgPoll, Version: 1, Fields: []ContentField{
+			{Name: "poll_id", Type: FieldString, Required: true},
+			{Name: "question", Type: FieldString, Required: true},
+			{Name: "options", Type: FieldArray, Required: true},
+			{Name: "multi_choice", Type: FieldBool},
+			{Name: "anonymous", Type: FieldBool},
+			{Name: "deadline", Type: FieldNumber},
+			{Name: "counts", Type: FieldArray},
+			{Name: "total_voters", Type: FieldNumber},
+			{Name: "closed", Type: FieldBool},
+		}},
+	)
+}
+
+// upgradeLegacyWrapped 历史文档中非对象内容被包装为 {"data": ...}，对象内容展开到顶层
+func upgradeLegacyWrapped(content map[string]interface{}) map[string]interface{} {
+	if len(content) != 1 {
+		return content
+	}
+	if data, ok := content["data"].(map[string]interface{}); ok {
+		return data
+	}
+	return content
+}
+
+// upgradeLegacyText 历史文本消息的内容可能是字符串，被保存为 {"data": "..."} 或 {"raw": "\"...\""}，统一为 {"text": "..."}
+func upgradeLegacyText(content map[string]interface{}) map[string]interface{} {
+	if _, ok := content["text"]; ok || len(content) != 1 {
+		return content
+	}
+	if text, ok := content["data"].(string); ok {
+		return map[string]interface{}{"text": text}
+	}
+	if raw, ok := content["raw"].(string); ok {
+		var text string
+		if err := json.Unmarshal([]byte(raw), &text); err != nil {
+			text = raw
+		}
+		return map[string]interface{}{"text": text}
+	}
+	return upgradeLegacyWrapped(content)
+}
@@ -0,0 +1,183 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// 会话ID错误定义
+var (
+	ErrInvalidConversationID = errors.New("invalid conversation id")
+)
+
+// 规范会话ID前缀
+const (
+	conversationPrefixSingle = "single:"
+	conversationPrefixGroup  = "group:"
+)
+
+// 旧版会话ID前缀（util.GenerateConversationID 生成，仅用于兼容已存储数据）
+const (
+	legacyPrefixSingle = "single_"
+	legacyPrefixGroup  = "group_"
+)
+
+// ConversationID 会话ID
+// 规范格式（写入存储、缓存及下发客户端时统一使用）：
+//
+//	single:<较小user_id>:<较大user_id>    单聊
+//	group:<group_id>                      群聊
+//
+// 解析时兼容旧格式：
+//
+//	single_<user1>_<user2>               单聊（util.GenerateConversationID）
+//	group_<group_id>                     群聊（util.GenerateConversationID）
+//	group_<uuid>                         群聊，会话ID即群ID
+type ConversationID struct {
+	Type    int       // ConversationTypeSingle / ConversationTypeGroup
+	GroupID string    // 群聊时的群ID
+	UserIDs [2]string // 单聊时的双方用户ID（按字典序排列）
+}
+
+// NewSingleConversationID 创建单聊会话ID（与参数顺序无关）
+func NewSingleConversationID(userID1, userID2 string) ConversationID {
+	if userID2 < userID1 {
+		userID1, userID2 = userID2, userID1
+	}
+	return ConversationID{Type: ConversationTypeSingle, UserIDs: [2]string{userID1, userID2}}
+}
+
+// NewGroupConversationID 创建群聊会话ID
+func NewGroupConversationID(groupID string) ConversationID {
+	return ConversationID{Type: ConversationTypeGroup, GroupID: groupID}
+}
+
+// ParseConversationID 解析会话ID（兼容旧格式），返回规范化后的会话ID
+func ParseConversationID(s string) (ConversationID, error) {
+	if groupID, ok := strings.CutPrefix(s, conversationPrefixGroup); ok {
+		if groupID == "" {
+			return ConversationID{}, ErrInvalidConversationID
+		}
+		return NewGroupConversationID(groupID), nil
+	}
+
+	if users, ok := strings.CutPrefix(s, conversationPrefixSingle); ok {
+		user1, user2, found := strings.Cut(users, ":")
+		if !found || user1 == "" || user2 == "" {
+			return ConversationID{}, ErrInvalidConversationID
+		}
+		return NewSingleConversationID(user1, user2), nil
+	}
+
+	if rest, ok := strings.CutPrefix(s, legacyPrefixGroup); ok && rest != "" {
+		// group_<group_id>：群ID本身以 group_ 开头；否则整个会话ID即群ID
+		if strings.HasPrefix(rest, legacyPrefixGroup) {
+			return NewGroupConversationID(rest), nil
+		}
+		return NewGroupConversationID(s), nil
+	}
+
+	if users, ok := strings.CutPrefix(s, legacyPrefixSingle); ok {
+		user1, user2, found := splitUnderscorePair(users)
+		if !found {
+			return ConversationID{}, ErrInvalidConversationID
+		}
+		return NewSingleConversationID(user1, user2), nil
+	}
+
+	return ConversationID{}, ErrInvalidConversationID
+}
+
+// CanonicalConversationID 将会话ID转换为规范格式，无法解析时原样返回
+func CanonicalConversationID(s string) string {
+	id, err := ParseConversationID(s)
+	if err != nil {
+		return s
+	}
+	return id.String()
+}
+
+// String 规范格式的会话ID
+func (id ConversationID) String() string {
+	switch id.Type {
+	case ConversationTypeGroup:
+		return conversationPrefixGroup + id.GroupID
+	case ConversationTypeSingle:
+		return conversationPrefixSingle + id.UserIDs[0] + ":" + id.UserIDs[1]
+	}
+	return ""
+}
+
+// Aliases 会话ID的全部存储形式（规范格式在前），用于迁移完成前兼容读取旧数据
+func (id ConversationID) Aliases() []string {
+	switch id.Type {
+	case ConversationTypeGroup:
+		aliases := []string{id.String(), legacyPrefixGroup + id.GroupID}
+		if strings.HasPrefix(id.GroupID, legacyPrefixGroup) {
+			aliases = append(aliases, id.GroupID)
+		}
+		return aliases
+	case ConversationTypeSingle:
+		return []string{id.String(), legacyPrefixSingle + id.UserIDs[0] + "_" + id.UserIDs[1]}
+	}
+	return nil
+}
+
+// IsZero 是否为空会话ID
+func (id ConversationID) IsZero() bool {
+	return id.Type == 0
+}
+
+// IsGroup 是否为群聊会话
+func (id ConversationID) IsGroup() bool {
+	return id.Type == ConversationTypeGroup
+}
+
+// Participants 单聊会话的双方用户ID
+func (id ConversationID) Participants() []string {
+	if id.Type != ConversationTypeSingle {
+		return nil
+	}
+	return []string{id.UserIDs[0], id.UserIDs[1]}
+}
+
+// HasParticipant 单聊会话是否包含该用户（群聊需查询群成员）
+func (id ConversationID) HasParticipant(userID string) bool {
+	return id.Type == ConversationTypeSingle && (id.UserIDs[0] == userID || id.UserIDs[1] == userID)
+}
+
+// Peer 单聊会话中对方的用户ID
+func (id ConversationID) Peer(userID string) string {
+	if id.UserIDs[0] == userID {
+		return id.UserIDs[1]
+	}
+	return id.UserIDs[0]
+}
+
+// MarshalText 以规范格式序列化
+func (id ConversationID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText 解析会话ID（兼容旧格式）
+func (id *ConversationID) UnmarshalText(data []byte) error {
+	parsed, err := ParseConversationID(string(data))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// splitUnderscorePair 拆分以下划线连接的两个用户ID
+// 用户ID本身可能包含下划线（如 user_xxx），优先在第二个ID的 "user_" 前缀处拆分，否则按最后一个下划线拆分
+func splitUnderscorePair(s string) (string, string, bool) {
+	if i := strings.Index(s, "_user_"); i > 0 {
+		return s[:i], s[i+1:], true
+	}
+	i := strings.LastIndex(s, "_")
+	if i <= 0 || i == len(s)-1 {
+		return "", "", false
+	}
+	return s[:i], s[i+1:], true
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// ConversationStats 会话聚合统计（管理后台分析查询专用，由消息分发事件增量汇总，不参与线上读写）
+type ConversationStats struct {
+	ConversationID   string    `json:"conversation_id" gorm:"primaryKey;type:varchar(128)"`
+	Type             int       `json:"type" gorm:"type:tinyint;not null;index:idx_type_last_activity"` // 1-单聊 2-群聊
+	MessageCount     int64     `json:"message_count" gorm:"default:0;index"`
+	ParticipantCount int       `json:"participant_count" gorm:"default:0"` // 发过消息的用户数
+	LastActivityAt   time.Time `json:"last_activity_at" gorm:"index:idx_type_last_activity"`
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (ConversationStats) TableName() string {
+	return "conversation_stats"
+}
+
+// ConversationParticipantStats 会话参与者统计（用于增量计算参与人数）
+type ConversationParticipantStats struct {
+	ConversationID string    `json:"conversation_id" gorm:"primaryKey;type:varchar(128)"`
+	UserID         string    `json:"user_id" gorm:"primaryKey;type:varchar(64)"`
+	MessageCount   int64     `json:"message_count" gorm:"default:0"`
+	LastActiveAt   time.Time `json:"last_active_at"`
+}
+
+// TableName 指定表名
+func (ConversationParticipantStats) TableName() string {
+	return "conversation_participant_stats"
+}
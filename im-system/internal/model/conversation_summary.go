@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// ConversationSummary 会话摘要（由外部摘要服务生成，仅请求者可见）
+type ConversationSummary struct {
+	SummaryID      string    `json:"summary_id" gorm:"primaryKey;type:varchar(64)"`
+	ConversationID string    `json:"conversation_id" gorm:"type:varchar(128);index:idx_summary_conv_user"`
+	UserID         string    `json:"user_id" gorm:"type:varchar(64);index:idx_summary_conv_user"`
+	Summary        string    `json:"summary" gorm:"type:text"`
+	MessageCount   int       `json:"message_count"`                           // 参与摘要的消息数
+	FromMessageID  string    `json:"from_message_id" gorm:"type:varchar(64)"` // 摘要窗口第一条消息
+	ToMessageID    string    `json:"to_message_id" gorm:"type:varchar(64)"`   // 摘要窗口最后一条消息
+	FromTimestamp  int64     `json:"from_timestamp"`                          // 第一条消息时间（毫秒）
+	ToTimestamp    int64     `json:"to_timestamp"`                            // 最后一条消息时间（毫秒）
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime;index:idx_summary_conv_user"`
+}
+
+// TableName 指定表名
+func (ConversationSummary) TableName() string {
+	return "conversation_summaries"
+}
+
+// SummaryContent 会话摘要消息内容（type 107，只下发给请求者，不写入会话历史）
+type SummaryContent struct {
+	SummaryID      string `json:"summary_id"`
+	ConversationID string `json:"conversation_id"`
+	Summary        string `json:"summary"`
+	MessageCount   int    `json:"message_count"`
+	FromMessageID  string `json:"from_message_id"`
+	ToMessageID    string `json:"to_message_id"`
+	FromTimestamp  int64  `json:"from_timestamp"`
+	ToTimestamp    int64  `json:"to_timestamp"`
+}
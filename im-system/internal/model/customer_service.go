@@ -0,0 +1,75 @@
+package model
+
+import "time"
+
+// CSAgentStatus 客服坐席状态
+type CSAgentStatus int
+
+const (
+	CSAgentOffline CSAgentStatus = 0 // 离线（不分配新会话）
+	CSAgentOnline  CSAgentStatus = 1 // 在线
+)
+
+// CSAgent 客服坐席
+type CSAgent struct {
+	UserID        string        `json:"user_id" gorm:"primaryKey;type:varchar(64)"`
+	MaxConcurrent int           `json:"max_concurrent" gorm:"default:5"` // 同时接待的会话上限
+	Status        CSAgentStatus `json:"status" gorm:"default:0;index"`   // 0=离线 1=在线
+	ActiveCount   int           `json:"active_count" gorm:"default:0"`   // 正在接待的会话数
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (CSAgent) TableName() string {
+	return "cs_agents"
+}
+
+// CSSessionStatus 客服会话状态
+type CSSessionStatus int
+
+const (
+	CSSessionQueued CSSessionStatus = 0 // 排队中
+	CSSessionActive CSSessionStatus = 1 // 接待中
+	CSSessionClosed CSSessionStatus = 2 // 已结束
+)
+
+// CSSession 客服会话（访客与坐席的单聊会话，转接后更换坐席和会话ID）
+type CSSession struct {
+	ID             uint            `json:"-" gorm:"primaryKey;autoIncrement"`
+	SessionID      string          `json:"session_id" gorm:"type:varchar(64);uniqueIndex"`
+	VisitorID      string          `json:"visitor_id" gorm:"type:varchar(64);index:idx_cs_session_visitor_status"`
+	AgentID        string          `json:"agent_id,omitempty" gorm:"type:varchar(64);index:idx_cs_session_agent_status"`
+	Status         CSSessionStatus `json:"status" gorm:"default:0;index:idx_cs_session_visitor_status;index:idx_cs_session_agent_status;index:idx_cs_session_queue"`
+	ConversationID string          `json:"conversation_id,omitempty" gorm:"type:varchar(128)"`
+	Subject        string          `json:"subject,omitempty" gorm:"type:varchar(256)"` // 咨询主题
+	TransferCount  int             `json:"transfer_count" gorm:"default:0"`
+	ClosedBy       string          `json:"closed_by,omitempty" gorm:"type:varchar(64)"`
+	Rating         int             `json:"rating,omitempty" gorm:"default:0"` // 满意度 1-5，0 表示未评价
+	RatingComment  string          `json:"rating_comment,omitempty" gorm:"type:varchar(512)"`
+	QueuedAt       time.Time       `json:"queued_at" gorm:"index:idx_cs_session_queue"`
+	AssignedAt     *time.Time      `json:"assigned_at,omitempty"`
+	ClosedAt       *time.Time      `json:"closed_at,omitempty"`
+	RatedAt        *time.Time      `json:"rated_at,omitempty"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (CSSession) TableName() string {
+	return "cs_sessions"
+}
+
+// CSCannedReply 客服快捷回复
+type CSCannedReply struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	AgentID   string    `json:"agent_id,omitempty" gorm:"type:varchar(64);index"` // 为空表示公共快捷回复
+	Title     string    `json:"title" gorm:"type:varchar(64)"`
+	Content   string    `json:"content" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (CSCannedReply) TableName() string {
+	return "cs_canned_replies"
+}
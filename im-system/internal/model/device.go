@@ -2,6 +2,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -25,6 +26,8 @@ type Device struct {
 	PushEnabled bool      `json:"push_enabled" gorm:"default:true"`     // 是否开启推送
 	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	Subscription string `json:"-" gorm:"type:text"` // Web Push 订阅（浏览器 PushSubscription JSON），仅 web 平台
 }
 
 // TableName 指定表名
@@ -42,12 +45,19 @@ func (d *Device) IsAndroid() bool {
 	return d.Platform == PlatformAndroid
 }
 
+// IsWeb 判断是否为浏览器设备
+func (d *Device) IsWeb() bool {
+	return d.Platform == PlatformWeb
+}
+
 // RegisterDeviceRequest 注册设备请求
 type RegisterDeviceRequest struct {
-	DeviceToken string   `json:"device_token" binding:"required"`
+	DeviceToken string   `json:"device_token" binding:"required_unless=Platform web"` // web 平台为空时按订阅地址生成
 	Platform    Platform `json:"platform" binding:"required,oneof=ios android web"`
 	AppVersion  string   `json:"app_version"`
 	DeviceInfo  string   `json:"device_info"`
+
+	Subscription json.RawMessage `json:"subscription,omitempty"` // web 平台必填：浏览器 PushSubscription.toJSON() 的结果
 }
 
 // UnregisterDeviceRequest 注销设备请求
@@ -0,0 +1,80 @@
+package model
+
+import "time"
+
+// E2EEDeviceKey 设备的端到端加密公钥（身份公钥及当前签名预密钥），服务端不保存任何私钥
+type E2EEDeviceKey struct {
+	UserID                string    `json:"user_id" gorm:"primaryKey;type:varchar(64)"`
+	DeviceID              string    `json:"device_id" gorm:"primaryKey;type:varchar(128)"`
+	IdentityKey           string    `json:"identity_key" gorm:"type:varchar(512)"` // Base64 身份公钥
+	SignedPreKeyID        int64     `json:"signed_prekey_id"`
+	SignedPreKey          string    `json:"signed_prekey" gorm:"type:varchar(512)"`           // Base64 签名预密钥公钥
+	SignedPreKeySignature string    `json:"signed_prekey_signature" gorm:"type:varchar(512)"` // 身份密钥对签名预密钥的签名
+	CreatedAt             time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt             time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (E2EEDeviceKey) TableName() string {
+	return "e2ee_device_keys"
+}
+
+// E2EEOneTimePreKey 设备上传的一次性预密钥，被其他用户获取后即删除
+type E2EEOneTimePreKey struct {
+	ID        uint64    `json:"-" gorm:"primaryKey;autoIncrement"`
+	UserID    string    `json:"user_id" gorm:"type:varchar(64);uniqueIndex:idx_e2ee_prekeys_device_key"`
+	DeviceID  string    `json:"device_id" gorm:"type:varchar(128);uniqueIndex:idx_e2ee_prekeys_device_key"`
+	KeyID     int64     `json:"key_id" gorm:"uniqueIndex:idx_e2ee_prekeys_device_key"`
+	PublicKey string    `json:"public_key" gorm:"type:varchar(512)"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (E2EEOneTimePreKey) TableName() string {
+	return "e2ee_one_time_prekeys"
+}
+
+// E2EEPreKey 预密钥公钥
+type E2EEPreKey struct {
+	KeyID     int64  `json:"key_id" binding:"min=0"`
+	PublicKey string `json:"public_key" binding:"required"`
+}
+
+// E2EESignedPreKey 签名预密钥公钥
+type E2EESignedPreKey struct {
+	KeyID     int64  `json:"key_id" binding:"min=0"`
+	PublicKey string `json:"public_key" binding:"required"`
+	Signature string `json:"signature" binding:"required"`
+}
+
+// RegisterE2EEKeysRequest 注册设备密钥请求（身份密钥变化时清空该设备未使用的一次性预密钥）
+type RegisterE2EEKeysRequest struct {
+	IdentityKey    string            `json:"identity_key" binding:"required"`
+	SignedPreKey   *E2EESignedPreKey `json:"signed_prekey" binding:"required"`
+	OneTimePreKeys []*E2EEPreKey     `json:"one_time_prekeys" binding:"omitempty,dive"`
+}
+
+// UploadE2EEPreKeysRequest 补充一次性预密钥请求（可同时轮换签名预密钥）
+type UploadE2EEPreKeysRequest struct {
+	SignedPreKey   *E2EESignedPreKey `json:"signed_prekey"`
+	OneTimePreKeys []*E2EEPreKey     `json:"one_time_prekeys" binding:"omitempty,dive"`
+}
+
+// E2EEDevice 设备公钥信息（不消耗一次性预密钥）
+type E2EEDevice struct {
+	UserID         string    `json:"user_id"`
+	DeviceID       string    `json:"device_id"`
+	IdentityKey    string    `json:"identity_key"`
+	PreKeyCount    int64     `json:"one_time_prekey_count,omitempty"` // 剩余一次性预密钥数（只返回给设备所属用户）
+	SignedPreKeyID int64     `json:"signed_prekey_id"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// E2EEPreKeyBundle 建立加密会话所需的设备预密钥包
+type E2EEPreKeyBundle struct {
+	UserID        string            `json:"user_id"`
+	DeviceID      string            `json:"device_id"`
+	IdentityKey   string            `json:"identity_key"`
+	SignedPreKey  *E2EESignedPreKey `json:"signed_prekey"`
+	OneTimePreKey *E2EEPreKey       `json:"one_time_prekey,omitempty"` // 一次性预密钥已耗尽时为空，只使用签名预密钥
+}
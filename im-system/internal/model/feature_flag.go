@@ -0,0 +1,78 @@
+package model
+
+import (
+	"hash/fnv"
+	"time"
+)
+
+// 已知的灰度开关
+const (
+	FlagProtobufFraming = "protocol.protobuf_framing" // WebSocket 使用 protobuf 帧
+	FlagReadDiffusion   = "group.read_diffusion"      // 群消息读扩散
+	FlagSmartReply      = "assist.smart_reply"        // 单聊回复建议（关闭即停止请求外部建议服务）
+)
+
+// 灰度分组
+const (
+	CohortControl   = "control"   // 对照组（旧行为）
+	CohortTreatment = "treatment" // 实验组（新行为）
+)
+
+// 灰度分组指标事件
+const (
+	CohortEventConnect        = "connect"         // 建立连接
+	CohortEventDisconnect     = "disconnect"      // 断开连接
+	CohortEventSessionSeconds = "session_seconds" // 连接时长（秒）
+	CohortEventMessage        = "message"         // 上行消息
+	CohortEventError          = "error"           // 消息处理失败
+	CohortEventHandleMillis   = "handle_ms"       // 消息处理耗时（毫秒）
+)
+
+// rolloutBuckets 按 0.01% 粒度分桶
+const rolloutBuckets = 10000
+
+// FeatureFlag 功能开关
+// 开启后按 user_id 的稳定哈希把 Percentage% 的用户分到实验组，AllowUsers/DenyUsers 可强制指定分组
+type FeatureFlag struct {
+	Key         string    `json:"key"`
+	Description string    `json:"description,omitempty"`
+	Enabled     bool      `json:"enabled"`
+	Percentage  float64   `json:"percentage"`            // 实验组比例（0-100）
+	AllowUsers  []string  `json:"allow_users,omitempty"` // 始终进入实验组的用户
+	DenyUsers   []string  `json:"deny_users,omitempty"`  // 始终留在对照组的用户
+	UpdatedBy   string    `json:"updated_by,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SetFeatureFlagRequest 设置功能开关请求
+type SetFeatureFlagRequest struct {
+	Description string   `json:"description" binding:"max=256"`
+	Enabled     bool     `json:"enabled"`
+	Percentage  float64  `json:"percentage" binding:"min=0,max=100"`
+	AllowUsers  []string `json:"allow_users" binding:"max=1000"`
+	DenyUsers   []string `json:"deny_users" binding:"max=1000"`
+}
+
+// Cohort 计算用户分组：强制名单优先，其余按 开关Key + 用户ID 的稳定哈希分桶
+// 哈希包含开关Key，不同开关的实验组用户相互独立；同一开关调大比例时已在实验组的用户保持不变
+func (f *FeatureFlag) Cohort(userID string) string {
+	for _, id := range f.DenyUsers {
+		if id == userID {
+			return CohortControl
+		}
+	}
+	for _, id := range f.AllowUsers {
+		if id == userID {
+			return CohortTreatment
+		}
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(f.Key))
+	h.Write([]byte{'/'})
+	h.Write([]byte(userID))
+	if float64(h.Sum32()%rolloutBuckets) < f.Percentage*rolloutBuckets/100 {
+		return CohortTreatment
+	}
+	return CohortControl
+}
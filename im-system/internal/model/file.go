@@ -41,6 +41,7 @@ type FileStatus int
 const (
 	FileStatusNormal  FileStatus = 1 // 正常
 	FileStatusDeleted FileStatus = 0 // 已删除
+	FileStatusExpired FileStatus = 2 // 已过期（按保存期限清理）
 )
 
 // File 文件记录
@@ -56,11 +57,48 @@ type File struct {
 	StoragePath   string     `json:"storage_path" gorm:"type:varchar(512);not null"`
 	ThumbnailPath string     `json:"thumbnail_path" gorm:"type:varchar(512)"`
 	MD5           string     `json:"md5" gorm:"type:varchar(64)"`
+	SHA256        string     `json:"sha256" gorm:"type:varchar(64);index"`
 	Width         int        `json:"width" gorm:"default:0"`    // 图片/视频宽度
 	Height        int        `json:"height" gorm:"default:0"`   // 图片/视频高度
 	Duration      int        `json:"duration" gorm:"default:0"` // 音视频时长(秒)
 	Status        FileStatus `json:"status" gorm:"default:1"`   // 状态
 	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime;index"`
+	Region        string     `json:"region,omitempty" gorm:"type:varchar(32)"` // 存储区域，为空表示默认区域
+
+	ArchiveInfo *ArchiveInfo `json:"archive_info,omitempty" gorm:"serializer:json;type:json"` // 压缩包检查结果及文件列表
+
+	// 保存期限：作为消息附件发送后按会话类型设置过期时间，Retained（收藏、群文件等）的文件不过期
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	ExpiredAt *time.Time `json:"expired_at,omitempty"`
+	Retained  bool       `json:"retained" gorm:"default:false"`
+}
+
+// Expiry 返回生效的过期时间（长期保存的文件返回nil）
+func (f *File) Expiry() *time.Time {
+	if f.Retained {
+		return nil
+	}
+	return f.ExpiresAt
+}
+
+// ArchiveInfo 压缩包检查结果（上传时只读取目录/文件头得出，不完整解压）
+type ArchiveInfo struct {
+	Format      string         `json:"format"`
+	Entries     int            `json:"entries"`
+	TotalSize   int64          `json:"total_size"` // 解压后总大小
+	Ratio       float64        `json:"ratio"`      // 压缩比
+	NestedDepth int            `json:"nested_depth"`
+	Encrypted   bool           `json:"encrypted,omitempty"`
+	Inspected   bool           `json:"inspected"` // false表示格式无法检查（如7z）
+	Files       []ArchiveEntry `json:"files,omitempty"`
+	Truncated   bool           `json:"truncated,omitempty"` // 文件列表是否被截断
+}
+
+// ArchiveEntry 压缩包内的文件
+type ArchiveEntry struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	Dir  bool   `json:"dir,omitempty"`
 }
 
 // TableName 指定表名
@@ -82,6 +120,7 @@ type FileMessage struct {
 	Height       int      `json:"height,omitempty"`
 	Duration     int      `json:"duration,omitempty"`
 	MD5          string   `json:"md5,omitempty"`
+	SHA256       string   `json:"sha256,omitempty"`
 	UploadStatus string   `json:"upload_status,omitempty"` // uploading, completed, failed
 }
 
@@ -160,6 +199,9 @@ type InitMultipartUploadRequest struct {
 	FileSize    int64  `json:"file_size" binding:"required,min=1"`
 	ContentType string `json:"content_type"`
 	ChunkSize   int64  `json:"chunk_size,omitempty"` // 分片大小，默认5MB
+	SHA256      string `json:"sha256,omitempty"`     // 可选，客户端期望的SHA-256，合并后校验
+	MD5         string `json:"md5,omitempty"`        // 可选，客户端期望的MD5，合并后校验
+	GroupID     string `json:"group_id,omitempty"`   // 可选，上传到群聊时填写，应用群组文件类型策略
 }
 
 // InitMultipartUploadResponse 初始化分片上传响应
@@ -204,6 +246,28 @@ type CompleteMultipartUploadResponse struct {
 	FileSize     int64  `json:"file_size"`
 }
 
+// CreateUploadSessionRequest 创建断点续传会话请求
+type CreateUploadSessionRequest struct {
+	FileName    string `json:"file_name" binding:"required"`
+	FileSize    int64  `json:"file_size" binding:"required,min=1"`
+	ContentType string `json:"content_type"`
+	ChunkSize   int64  `json:"chunk_size,omitempty"` // 底层分片大小，5MB~64MB
+	SHA256      string `json:"sha256,omitempty"`     // 可选，完成时校验
+	MD5         string `json:"md5,omitempty"`        // 可选，完成时校验
+	GroupID     string `json:"group_id,omitempty"`   // 可选，上传到群聊时填写，应用群组文件类型策略
+}
+
+// UploadSessionInfo 断点续传会话信息
+type UploadSessionInfo struct {
+	UploadID  string    `json:"upload_id"`
+	FileID    string    `json:"file_id"`
+	FileName  string    `json:"file_name"`
+	FileSize  int64     `json:"file_size"`
+	Offset    int64     `json:"offset"`   // 已接收字节数
+	Progress  float64   `json:"progress"` // 上传进度 0-1
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 // FileInfo 文件信息
 type FileInfo struct {
 	FileID       string    `json:"file_id"`
@@ -218,8 +282,15 @@ type FileInfo struct {
 	Height       int       `json:"height,omitempty"`
 	Duration     int       `json:"duration,omitempty"`
 	MD5          string    `json:"md5,omitempty"`
+	SHA256       string    `json:"sha256,omitempty"`
 	UploaderID   string    `json:"uploader_id,omitempty"`
 	UploadedAt   time.Time `json:"uploaded_at"`
+
+	Archive *ArchiveInfo `json:"archive,omitempty"` // 压缩包内容（用于客户端预览）
+
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 过期时间，为空表示长期保存
+	Expired   bool       `json:"expired,omitempty"`    // 已过期，文件内容已清理，仅保留元信息
+	ExpiredAt *time.Time `json:"expired_at,omitempty"`
 }
 
 // StorageConfig 存储配置
@@ -239,9 +310,21 @@ type FileAccessControl struct {
 	// 签名URL过期时间（秒）
 	SignedURLExpiry int `json:"signed_url_expiry"`
 
+	// 按文件类型的签名URL过期时间（秒），未配置的类型使用 SignedURLExpiry
+	TypeURLExpiry map[FileType]int `json:"type_url_expiry,omitempty"`
+
+	// 是否通过网关代理下载（支持IP绑定、Referer校验和撤销）
+	ProxyDownload bool `json:"proxy_download"`
+
+	// 代理下载URL是否绑定签发时的客户端IP
+	BindClientIP bool `json:"bind_client_ip"`
+
 	// Referer白名单
 	RefererWhitelist []string `json:"referer_whitelist,omitempty"`
 
+	// 启用Referer白名单时是否允许空Referer（如移动端请求）
+	AllowEmptyReferer bool `json:"allow_empty_referer"`
+
 	// 是否启用病毒扫描
 	EnableVirusScan bool `json:"enable_virus_scan"`
 
@@ -249,6 +332,47 @@ type FileAccessControl struct {
 	EnableContentScan bool `json:"enable_content_scan"`
 }
 
+// DefaultFileAccessControl 默认文件访问控制
+func DefaultFileAccessControl() *FileAccessControl {
+	return &FileAccessControl{
+		SignedURLExpiry: 2 * 3600,
+		TypeURLExpiry: map[FileType]int{
+			FileTypeImage:    2 * 3600,
+			FileTypeVideo:    30 * 60,
+			FileTypeAudio:    30 * 60,
+			FileTypeDocument: 15 * 60,
+			FileTypeArchive:  15 * 60,
+		},
+		AllowEmptyReferer: true,
+	}
+}
+
+// URLExpiry 获取指定文件类型的签名URL过期时间（秒）
+func (a *FileAccessControl) URLExpiry(fileType FileType) int {
+	if expiry, ok := a.TypeURLExpiry[fileType]; ok && expiry > 0 {
+		return expiry
+	}
+	return a.SignedURLExpiry
+}
+
+// MaxURLExpiry 获取所有文件类型中最长的签名URL过期时间（秒）
+func (a *FileAccessControl) MaxURLExpiry() int {
+	maxExpiry := a.SignedURLExpiry
+	for _, expiry := range a.TypeURLExpiry {
+		if expiry > maxExpiry {
+			maxExpiry = expiry
+		}
+	}
+	return maxExpiry
+}
+
+// SignedFileURL 签发的文件访问URL
+type SignedFileURL struct {
+	URL      string `json:"url"`
+	ExpireAt int64  `json:"expire_at"`
+	Proxy    bool   `json:"proxy"` // 是否为网关代理下载URL
+}
+
 // GetFileTypeByMimeType 根据MIME类型判断文件类型
 func GetFileTypeByMimeType(mimeType string) FileType {
 	switch {
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// FileTypePolicy 文件类型策略（全局策略或群组策略，群组策略只能在全局策略基础上进一步收紧）
+type FileTypePolicy struct {
+	Extensions      []string   `json:"extensions"`       // 允许的扩展名（小写、不含点），为空表示不限制扩展名
+	FileTypes       []FileType `json:"file_types"`       // 允许的文件大类，为空表示不限制
+	DenyExecutables bool       `json:"deny_executables"` // 拒绝可执行文件（按扩展名和文件头识别）
+	UpdatedBy       string     `json:"updated_by,omitempty"`
+	UpdatedAt       time.Time  `json:"updated_at,omitempty"`
+}
+
+// AllowsExtension 判断扩展名是否允许
+func (p *FileTypePolicy) AllowsExtension(ext string) bool {
+	if len(p.Extensions) == 0 {
+		return true
+	}
+	for _, allowed := range p.Extensions {
+		if allowed == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsType 判断文件大类是否允许
+func (p *FileTypePolicy) AllowsType(fileType FileType) bool {
+	if len(p.FileTypes) == 0 {
+		return true
+	}
+	for _, allowed := range p.FileTypes {
+		if allowed == fileType {
+			return true
+		}
+	}
+	return false
+}
+
+// SetFileTypePolicyRequest 设置文件类型策略请求
+type SetFileTypePolicyRequest struct {
+	Extensions      []string   `json:"extensions"`
+	FileTypes       []FileType `json:"file_types"`
+	DenyExecutables bool       `json:"deny_executables"`
+}
@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// FriendRequestStatus 好友申请状态
+type FriendRequestStatus int
+
+const (
+	FriendRequestPending  FriendRequestStatus = 0 // 待处理
+	FriendRequestAccepted FriendRequestStatus = 1 // 已通过
+	FriendRequestRejected FriendRequestStatus = 2 // 已拒绝
+)
+
+// FriendRequest 好友申请（同一对用户只保留一条，重新申请时覆盖）
+type FriendRequest struct {
+	ID         uint                `json:"-" gorm:"primaryKey;autoIncrement"`
+	RequestID  string              `json:"request_id" gorm:"type:varchar(64);uniqueIndex"`
+	FromUserID string              `json:"from_user_id" gorm:"type:varchar(64);uniqueIndex:idx_friend_request_pair"`
+	ToUserID   string              `json:"to_user_id" gorm:"type:varchar(64);uniqueIndex:idx_friend_request_pair;index:idx_friend_request_to_status"`
+	Message    string              `json:"message,omitempty" gorm:"type:varchar(256)"` // 申请留言
+	Status     FriendRequestStatus `json:"status" gorm:"default:0;index:idx_friend_request_to_status"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (FriendRequest) TableName() string {
+	return "friend_requests"
+}
+
+// Friend 好友关系（双向各一条）
+type Friend struct {
+	UserID    string    `json:"-" gorm:"primaryKey;type:varchar(64)"`
+	FriendID  string    `json:"friend_id" gorm:"primaryKey;type:varchar(64)"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (Friend) TableName() string {
+	return "friends"
+}
+
+// UserBlock 屏蔽关系：BlockedID 不能向 UserID 发送好友申请和单聊消息
+type UserBlock struct {
+	UserID    string    `json:"-" gorm:"primaryKey;type:varchar(64)"`
+	BlockedID string    `json:"blocked_id" gorm:"primaryKey;type:varchar(64)"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (UserBlock) TableName() string {
+	return "user_blocks"
+}
@@ -39,11 +39,17 @@ type Group struct {
 	OwnerID      string        `json:"owner_id" gorm:"type:varchar(64);index;not null"`
 	MaxMembers   int           `json:"max_members" gorm:"default:500"`
 	MemberCount  int           `json:"member_count" gorm:"default:0"`
-	MuteAll      bool          `json:"mute_all" gorm:"default:false"` // 全员禁言
-	JoinMode     GroupJoinMode `json:"join_mode" gorm:"default:0"`    // 加入模式
-	Status       GroupStatus   `json:"status" gorm:"default:1"`       // 状态
+	MuteAll      bool          `json:"mute_all" gorm:"default:false"`     // 全员禁言
+	JoinMode     GroupJoinMode `json:"join_mode" gorm:"default:0"`        // 加入模式
+	Status       GroupStatus   `json:"status" gorm:"default:1"`           // 状态
+	Version      int64         `json:"version" gorm:"not null;default:0"` // 乐观锁版本号
 	CreatedAt    time.Time     `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt    time.Time     `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// 临时群（活动群等）：到期自动解散，解散前向群成员发送提醒
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" gorm:"index"`     // 到期时间，为空表示长期群
+	ExpiryWarned   bool       `json:"-" gorm:"default:false"`                // 是否已发送到期提醒（修改到期时间后重置）
+	ExportOnExpiry bool       `json:"export_on_expiry" gorm:"default:false"` // 到期解散前为群主导出聊天记录
 }
 
 // TableName 指定表名
@@ -66,6 +72,11 @@ func (g *Group) CanJoinFreely() bool {
 	return g.JoinMode == JoinModeFree
 }
 
+// IsTemporary 判断是否为设置了到期时间的临时群
+func (g *Group) IsTemporary() bool {
+	return g.ExpiresAt != nil
+}
+
 // NeedApproval 判断是否需要审批
 func (g *Group) NeedApproval() bool {
 	return g.JoinMode == JoinModeApproval
@@ -81,6 +92,7 @@ type GroupMember struct {
 	MuteUntil int64     `json:"mute_until" gorm:"default:0"`      // 禁言截止时间戳
 	JoinedAt  time.Time `json:"joined_at" gorm:"autoCreateTime"`
 	InviterID string    `json:"inviter_id" gorm:"type:varchar(64)"` // 邀请人
+	Version   int64     `json:"version" gorm:"not null;default:0"`  // 乐观锁版本号
 }
 
 // TableName 指定表名
@@ -103,6 +115,12 @@ func (m *GroupMember) IsMuted() bool {
 	return m.MuteUntil > time.Now().Unix()
 }
 
+// GroupMuteStatus 发送者在群内的禁言状态（群消息被拒绝时返回给发送者）
+type GroupMuteStatus struct {
+	MuteAll   bool  `json:"mute_all"`   // 全员禁言中（群主、管理员不受限制）
+	MuteUntil int64 `json:"mute_until"` // 个人禁言截止时间戳，0 表示未被单独禁言
+}
+
 // GroupWithMembers 群组及成员信息（用于查询返回）
 type GroupWithMembers struct {
 	Group   *Group         `json:"group"`
@@ -122,8 +140,8 @@ type GroupJoinRequest struct {
 	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
 	GroupID   string     `json:"group_id" gorm:"type:varchar(64);index:idx_group_status;not null"`
 	UserID    string     `json:"user_id" gorm:"type:varchar(64);index;not null"`
-	Message   string     `json:"message" gorm:"type:varchar(256)"` // 申请留言
-	Status    int        `json:"status" gorm:"default:0"`          // 0-待处理 1-已同意 2-已拒绝
+	Message   string     `json:"message" gorm:"type:varchar(256)"`               // 申请留言
+	Status    int        `json:"status" gorm:"default:0;index:idx_group_status"` // 0-待处理 1-已同意 2-已拒绝
 	HandlerID string     `json:"handler_id" gorm:"type:varchar(64)"`
 	HandledAt *time.Time `json:"handled_at"`
 	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
@@ -141,6 +159,42 @@ const (
 	JoinRequestRejected = 2 // 已拒绝
 )
 
+// SuccessionReason 群主继任原因
+type SuccessionReason string
+
+const (
+	SuccessionOwnerDisabled SuccessionReason = "owner_disabled" // 群主账号被禁用
+	SuccessionOwnerDeleted  SuccessionReason = "owner_deleted"  // 群主账号已注销
+)
+
+// SuccessionAction 群主继任处理结果
+type SuccessionAction string
+
+const (
+	SuccessionTransferred    SuccessionAction = "transferred"     // 已移交给新群主
+	SuccessionDismissPending SuccessionAction = "dismiss_pending" // 无可继任成员，宽限期后解散
+	SuccessionDismissed      SuccessionAction = "dismissed"       // 宽限期结束已解散
+	SuccessionCancelled      SuccessionAction = "cancelled"       // 宽限期内原群主账号恢复，取消解散
+)
+
+// GroupOwnerSuccession 群主继任审计记录
+type GroupOwnerSuccession struct {
+	ID              uint             `json:"id" gorm:"primaryKey;autoIncrement"`
+	GroupID         string           `json:"group_id" gorm:"type:varchar(64);index;not null"`
+	PreviousOwnerID string           `json:"previous_owner_id" gorm:"type:varchar(64);index;not null"`
+	NewOwnerID      string           `json:"new_owner_id,omitempty" gorm:"type:varchar(64)"`
+	Reason          SuccessionReason `json:"reason" gorm:"type:varchar(32);not null"`
+	Action          SuccessionAction `json:"action" gorm:"type:varchar(32);index:idx_action_dismiss_at;not null"`
+	DismissAt       *time.Time       `json:"dismiss_at,omitempty" gorm:"index:idx_action_dismiss_at"` // 计划解散时间
+	CreatedAt       time.Time        `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (GroupOwnerSuccession) TableName() string {
+	return "group_owner_successions"
+}
+
 // CreateGroupRequest 创建群组请求
 type CreateGroupRequest struct {
 	OwnerID     string   `json:"owner_id" binding:"required"`
@@ -148,6 +202,9 @@ type CreateGroupRequest struct {
 	Avatar      string   `json:"avatar"`
 	Description string   `json:"description" binding:"max=512"`
 	MemberIDs   []string `json:"member_ids"` // 初始成员
+
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"` // 临时群到期时间
+	ExportOnExpiry bool       `json:"export_on_expiry"`     // 到期解散前为群主导出聊天记录
 }
 
 // UpdateGroupRequest 更新群组请求
@@ -159,6 +216,11 @@ type UpdateGroupRequest struct {
 	Announcement *string `json:"announcement,omitempty"`
 	Description  *string `json:"description,omitempty"`
 	JoinMode     *int    `json:"join_mode,omitempty"`
+
+	// 临时群设置：群主或管理员可设置、修改到期时间，取消到期（ClearExpiry）和导出设置仅群主可修改
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	ClearExpiry    bool       `json:"clear_expiry,omitempty"`
+	ExportOnExpiry *bool      `json:"export_on_expiry,omitempty"`
 }
 
 // GroupMemberListResponse 群成员列表响应
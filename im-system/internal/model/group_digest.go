@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// GroupDigestSubscription 群活动每日摘要订阅（按用户、按群）
+type GroupDigestSubscription struct {
+	UserID     string     `json:"user_id" gorm:"primaryKey;type:varchar(64)"`
+	GroupID    string     `json:"group_id" gorm:"primaryKey;type:varchar(64)"`
+	SendAt     string     `json:"send_at" gorm:"type:varchar(5)"`   // 每日发送时间（HH:MM，用户时区）
+	Timezone   string     `json:"timezone" gorm:"type:varchar(64)"` // IANA 时区，如 Asia/Shanghai
+	Push       bool       `json:"push" gorm:"default:false"`        // 是否同时推送到设备
+	NextRunAt  time.Time  `json:"next_run_at" gorm:"index"`         // 下次发送时间（UTC）
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`           // 最近一次发送时间
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (GroupDigestSubscription) TableName() string {
+	return "group_digest_subscriptions"
+}
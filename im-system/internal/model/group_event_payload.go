@@ -0,0 +1,163 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// 群事件类型化负载类型（GroupEventContent.PayloadType）
+const (
+	GroupPayloadInfoUpdate  = "info_update"  // 群资料变更（type 25）
+	GroupPayloadAdminChange = "admin_change" // 管理员变更（type 26）
+	GroupPayloadMemberMute  = "member_mute"  // 成员禁言（type 27，target_ids 为被禁言成员）
+	GroupPayloadMuteAll     = "mute_all"     // 全员禁言（type 27）
+	GroupPayloadMemberBatch = "member_batch" // 成员变动汇总（type 21-23）
+	GroupPayloadSuccession  = "succession"   // 群主自动继任/解散（type 24、28）
+	GroupPayloadExpiry      = "expiry"       // 临时群到期提醒/解散（type 29、24）
+	GroupPayloadAdminAction = "admin_action" // 平台管理员操作（type 24）
+)
+
+// GroupEventPayload 群事件类型化负载
+// 弃用过渡期内同时下发 LegacyExtra 生成的旧版 extra 字段，之后只下发 payload
+type GroupEventPayload interface {
+	// PayloadType 负载类型
+	PayloadType() string
+	// LegacyExtra 对应的旧版 extra 字段
+	LegacyExtra() map[string]string
+}
+
+// GroupInfoUpdatePayload 群资料变更负载
+type GroupInfoUpdatePayload struct {
+	Field    string `json:"field"` // name, avatar, announcement, description, join_mode, expires_at
+	OldValue string `json:"old_value"`
+	NewValue string `json:"new_value"`
+}
+
+// PayloadType 负载类型
+func (p *GroupInfoUpdatePayload) PayloadType() string { return GroupPayloadInfoUpdate }
+
+// LegacyExtra 旧版 extra: field, new_value
+func (p *GroupInfoUpdatePayload) LegacyExtra() map[string]string {
+	return map[string]string{
+		"field":     p.Field,
+		"new_value": p.NewValue,
+	}
+}
+
+// GroupAdminChangePayload 管理员变更负载
+type GroupAdminChangePayload struct {
+	IsAdmin bool `json:"is_admin"` // true 设为管理员，false 取消管理员
+}
+
+// PayloadType 负载类型
+func (p *GroupAdminChangePayload) PayloadType() string { return GroupPayloadAdminChange }
+
+// LegacyExtra 旧版 extra: action=set_admin/remove_admin
+func (p *GroupAdminChangePayload) LegacyExtra() map[string]string {
+	action := "set_admin"
+	if !p.IsAdmin {
+		action = "remove_admin"
+	}
+	return map[string]string{"action": action}
+}
+
+// GroupMemberMutePayload 成员禁言负载
+type GroupMemberMutePayload struct {
+	DurationSeconds int64 `json:"duration_seconds"` // 禁言时长，0 表示取消禁言
+	MuteUntil       int64 `json:"mute_until"`       // 禁言截止时间（Unix秒），0 表示取消禁言
+}
+
+// PayloadType 负载类型
+func (p *GroupMemberMutePayload) PayloadType() string { return GroupPayloadMemberMute }
+
+// LegacyExtra 旧版 extra: duration（秒）
+func (p *GroupMemberMutePayload) LegacyExtra() map[string]string {
+	return map[string]string{"duration": strconv.FormatInt(p.DurationSeconds, 10)}
+}
+
+// GroupMuteAllPayload 全员禁言负载
+type GroupMuteAllPayload struct {
+	MuteAll bool `json:"mute_all"`
+}
+
+// PayloadType 负载类型
+func (p *GroupMuteAllPayload) PayloadType() string { return GroupPayloadMuteAll }
+
+// LegacyExtra 旧版 extra: mute_all=true/false
+func (p *GroupMuteAllPayload) LegacyExtra() map[string]string {
+	return map[string]string{"mute_all": fmt.Sprintf("%t", p.MuteAll)}
+}
+
+// GroupMemberBatchPayload 成员变动汇总负载（大群合并通知）
+type GroupMemberBatchPayload struct {
+	Count int `json:"count"` // 合并的成员变动总数，可能多于 target_ids
+}
+
+// PayloadType 负载类型
+func (p *GroupMemberBatchPayload) PayloadType() string { return GroupPayloadMemberBatch }
+
+// LegacyExtra 旧版 extra: batched=true, count
+func (p *GroupMemberBatchPayload) LegacyExtra() map[string]string {
+	return map[string]string{
+		"batched": "true",
+		"count":   strconv.Itoa(p.Count),
+	}
+}
+
+// GroupSuccessionPayload 群主自动继任/解散负载
+type GroupSuccessionPayload struct {
+	Reason SuccessionReason `json:"reason"`
+}
+
+// PayloadType 负载类型
+func (p *GroupSuccessionPayload) PayloadType() string { return GroupPayloadSuccession }
+
+// LegacyExtra 旧版 extra: auto=true, reason
+func (p *GroupSuccessionPayload) LegacyExtra() map[string]string {
+	return map[string]string{
+		"auto":   "true",
+		"reason": string(p.Reason),
+	}
+}
+
+// GroupExpiryPayload 临时群到期负载
+type GroupExpiryPayload struct {
+	ExpiresAt   int64  `json:"expires_at"`              // 到期时间（毫秒）
+	ExportJobID string `json:"export_job_id,omitempty"` // 为群主创建的聊天记录导出任务（仅发给群主的解散通知携带）
+}
+
+// PayloadType 负载类型
+func (p *GroupExpiryPayload) PayloadType() string { return GroupPayloadExpiry }
+
+// LegacyExtra 旧版 extra: auto=true, expires_at
+func (p *GroupExpiryPayload) LegacyExtra() map[string]string {
+	return map[string]string{
+		"auto":       "true",
+		"expires_at": strconv.FormatInt(p.ExpiresAt, 10),
+	}
+}
+
+// GroupAdminActionPayload 平台管理员操作负载（如违规群被解散）
+type GroupAdminActionPayload struct {
+	Reason string `json:"reason,omitempty"` // 操作原因
+}
+
+// PayloadType 负载类型
+func (p *GroupAdminActionPayload) PayloadType() string { return GroupPayloadAdminAction }
+
+// LegacyExtra 旧版 extra: admin=true, reason
+func (p *GroupAdminActionPayload) LegacyExtra() map[string]string {
+	return map[string]string{
+		"admin":  "true",
+		"reason": p.Reason,
+	}
+}
+
+// SetPayload 设置类型化负载，legacyExtra 为 true 时同时填充旧版 extra 字段
+func (c *GroupEventContent) SetPayload(payload GroupEventPayload, legacyExtra bool) {
+	c.PayloadType = payload.PayloadType()
+	c.Payload = payload
+	if legacyExtra {
+		c.Extra = payload.LegacyExtra()
+	}
+}
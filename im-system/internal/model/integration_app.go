@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// IntegrationApp 第三方集成应用（通过自定义消息接入的机器人、Webhook等）
+type IntegrationApp struct {
+	AppID     string    `json:"app_id" gorm:"primaryKey;type:varchar(64)"`
+	Name      string    `json:"name" gorm:"type:varchar(128);not null"`
+	Secret    string    `json:"-" gorm:"type:varchar(128);not null"` // 签名密钥，仅创建及重置时返回
+	Disabled  bool      `json:"disabled" gorm:"default:false"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (IntegrationApp) TableName() string {
+	return "integration_apps"
+}
+
+// ConversationAppPolicy 会话的集成应用消息策略
+type ConversationAppPolicy struct {
+	ConversationID  string    `json:"conversation_id" gorm:"primaryKey;type:varchar(128)"`
+	RequireVerified bool      `json:"require_verified" gorm:"default:false"` // 只接受签名校验通过的自定义消息
+	UpdatedBy       string    `json:"updated_by" gorm:"type:varchar(64)"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (ConversationAppPolicy) TableName() string {
+	return "conversation_app_policies"
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// 角色提及（消息内容 at_roles），发送时解析为当前担任该角色的群成员
+const (
+	MentionRoleOwner  = "owner"  // @群主
+	MentionRoleAdmins = "admins" // @管理员（含群主）
+)
+
+// MentionGroup 群内保存的提及组（如 @设计组），发送时按当前群成员解析为提及对象
+type MentionGroup struct {
+	ID             uint      `json:"-" gorm:"primaryKey;autoIncrement"`
+	MentionGroupID string    `json:"mention_group_id" gorm:"type:varchar(64);uniqueIndex"`
+	GroupID        string    `json:"group_id" gorm:"type:varchar(64);uniqueIndex:idx_group_mention_name"`
+	Name           string    `json:"name" gorm:"type:varchar(32);uniqueIndex:idx_group_mention_name"`
+	MemberIDs      []string  `json:"member_ids" gorm:"serializer:json;type:json"`
+	CreatorID      string    `json:"creator_id" gorm:"type:varchar(64)"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (MentionGroup) TableName() string {
+	return "mention_groups"
+}
@@ -2,8 +2,12 @@
 package model
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"time"
+
+	"github.com/d60-lab/im-system/pkg/i18n"
 )
 
 // MessageType 消息类型
@@ -25,6 +29,13 @@ const (
 	MsgCard     MessageType = 9  // 名片消息
 	MsgCustom   MessageType = 10 // 自定义消息
 
+	// 群投票消息
+	MsgPoll       MessageType = 11 // 投票
+	MsgPollResult MessageType = 12 // 投票结果（投票结束时由系统发送）
+
+	// 端到端加密消息
+	MsgEncrypted MessageType = 13 // 端到端加密消息（服务端只存储、转发密文）
+
 	// 群组事件消息
 	MsgGroupCreated      MessageType = 20 // 群组创建
 	MsgGroupMemberJoin   MessageType = 21 // 成员加入
@@ -35,12 +46,17 @@ const (
 	MsgGroupAdminChange  MessageType = 26 // 管理员变更
 	MsgGroupMute         MessageType = 27 // 群禁言
 	MsgGroupTransfer     MessageType = 28 // 群主转让
+	MsgGroupExpiring     MessageType = 29 // 临时群即将到期解散
 
 	// 消息状态类型
 	MsgAck         MessageType = 30 // 消息确认
 	MsgReadReceipt MessageType = 31 // 已读回执
 	MsgRevoke      MessageType = 32 // 消息撤回
 	MsgTyping      MessageType = 33 // 正在输入
+	MsgPatch       MessageType = 34 // 消息局部更新
+	MsgEphemeral   MessageType = 35 // 临时消息（不持久化）
+	MsgSendFailed  MessageType = 36 // 发送失败（回ACK后被拒绝）
+	MsgOfflineAck  MessageType = 37 // 离线消息批次确认（客户端确认连接建立后补发的离线消息）
 
 	// 系统消息类型
 	MsgHeartbeat     MessageType = 99  // 心跳消息
@@ -48,8 +64,31 @@ const (
 	MsgServerNotice  MessageType = 101 // 服务器通知
 	MsgFriendRequest MessageType = 102 // 好友请求
 	MsgFriendAccept  MessageType = 103 // 好友接受
+	MsgConvUpdated   MessageType = 104 // 会话更新（轻量同步通知）
+	MsgReminder      MessageType = 105 // 消息提醒
+	MsgKeyRotation   MessageType = 106 // 会话加密状态变更/密钥轮换
+	MsgSummary       MessageType = 107 // 会话摘要（仅发给请求者）
+	MsgCSEvent       MessageType = 108 // 客服会话事件（排队、分配、转接、结束）
+	MsgJoinRequest   MessageType = 109 // 入群申请（发给群主和管理员）
+	MsgJoinResult    MessageType = 110 // 入群申请处理结果（发给申请人）
+	MsgGroupDigest   MessageType = 111 // 群活动每日摘要（发给订阅者）
+	MsgOfflineBatch  MessageType = 112 // 离线消息批次（连接建立后自动补发，按序号升序）
 )
 
+// IsChat 是否为用户发送的聊天消息（文本及媒体、自定义消息、投票、端到端加密消息）
+func (t MessageType) IsChat() bool {
+	switch t {
+	case MsgSingleChat, MsgGroupChat, MsgImage, MsgVoice, MsgVideo, MsgFile, MsgLocation, MsgCard, MsgCustom, MsgPoll, MsgEncrypted:
+		return true
+	}
+	return false
+}
+
+// IsEphemeral 是否为临时消息（只投递给在线成员，不持久化、不保存离线消息、不回ACK）
+func (t MessageType) IsEphemeral() bool {
+	return t == MsgTyping || t == MsgEphemeral
+}
+
 // String 返回消息类型的字符串表示
 func (t MessageType) String() string {
 	switch t {
@@ -75,6 +114,12 @@ func (t MessageType) String() string {
 		return "card"
 	case MsgCustom:
 		return "custom"
+	case MsgPoll:
+		return "poll"
+	case MsgPollResult:
+		return "poll_result"
+	case MsgEncrypted:
+		return "encrypted"
 	case MsgGroupCreated:
 		return "group_created"
 	case MsgGroupMemberJoin:
@@ -93,6 +138,8 @@ func (t MessageType) String() string {
 		return "group_mute"
 	case MsgGroupTransfer:
 		return "group_transfer"
+	case MsgGroupExpiring:
+		return "group_expiring"
 	case MsgAck:
 		return "ack"
 	case MsgReadReceipt:
@@ -101,6 +148,14 @@ func (t MessageType) String() string {
 		return "revoke"
 	case MsgTyping:
 		return "typing"
+	case MsgPatch:
+		return "patch"
+	case MsgEphemeral:
+		return "ephemeral"
+	case MsgSendFailed:
+		return "send_failed"
+	case MsgOfflineAck:
+		return "offline_ack"
 	case MsgHeartbeat:
 		return "heartbeat"
 	case MsgKickout:
@@ -111,6 +166,24 @@ func (t MessageType) String() string {
 		return "friend_request"
 	case MsgFriendAccept:
 		return "friend_accept"
+	case MsgConvUpdated:
+		return "conversation_updated"
+	case MsgReminder:
+		return "reminder"
+	case MsgKeyRotation:
+		return "key_rotation"
+	case MsgSummary:
+		return "summary"
+	case MsgCSEvent:
+		return "cs_event"
+	case MsgJoinRequest:
+		return "join_request"
+	case MsgJoinResult:
+		return "join_result"
+	case MsgGroupDigest:
+		return "group_digest"
+	case MsgOfflineBatch:
+		return "offline_batch"
 	default:
 		return "unknown"
 	}
@@ -141,6 +214,9 @@ type Message struct {
 	Seq             int64       `json:"seq,omitempty"`
 	Revoked         bool        `json:"revoked,omitempty"`
 	CreatedAt       time.Time   `json:"created_at,omitempty"`
+
+	// MentionTargets 服务端解析的提及对象（at_user_ids、at_roles、at_mention_groups 展开后的用户，不含发送者），客户端传入的值会被覆盖
+	MentionTargets []string `json:"mention_targets,omitempty"`
 }
 
 // MarshalBinary 序列化为二进制（用于Redis）
@@ -158,6 +234,15 @@ type TextContent struct {
 	Text      string   `json:"text"`
 	AtUserIDs []string `json:"at_user_ids,omitempty"` // @的用户ID列表
 	AtAll     bool     `json:"at_all,omitempty"`      // 是否@所有人
+	AutoReply bool     `json:"auto_reply,omitempty"`  // 是否为自动回复（收到自动回复时不再触发自动回复）
+
+	AtRoles         []string `json:"at_roles,omitempty"`          // @的群角色（owner / admins）
+	AtMentionGroups []string `json:"at_mention_groups,omitempty"` // @的提及组ID
+
+	ReplyToMessageID string `json:"reply_to_message_id,omitempty"` // 回复的消息ID
+	ReplyToUserID    string `json:"reply_to_user_id,omitempty"`    // 被回复消息的发送者
+
+	BridgeSource string `json:"bridge_source,omitempty"` // 从外部平台（slack / matrix）桥接进来的消息，不再转发回外部平台
 }
 
 // ImageContent 图片消息内容
@@ -220,10 +305,27 @@ type CardContent struct {
 
 // GroupEventContent 群组事件内容
 type GroupEventContent struct {
-	GroupID    string            `json:"group_id"`
-	OperatorID string            `json:"operator_id"`
-	TargetIDs  []string          `json:"target_ids,omitempty"`
-	Extra      map[string]string `json:"extra,omitempty"`
+	GroupID     string            `json:"group_id"`
+	OperatorID  string            `json:"operator_id"`
+	TargetIDs   []string          `json:"target_ids,omitempty"`
+	Extra       map[string]string `json:"extra,omitempty"`        // 已弃用，由 payload 取代，过渡期内与 payload 同时下发
+	TemplateKey string            `json:"template_key,omitempty"` // 渲染模板Key，客户端按本地语言从文案目录中取模板
+	PayloadType string            `json:"payload_type,omitempty"` // 类型化负载类型，见 GroupPayload* 常量
+	Payload     GroupEventPayload `json:"payload,omitempty"`      // 类型化负载
+}
+
+// groupEventTemplateKeys 群事件类型对应的渲染模板Key
+var groupEventTemplateKeys = map[MessageType]string{
+	MsgGroupCreated:      i18n.KeyGroupCreated,
+	MsgGroupMemberJoin:   i18n.KeyGroupMemberJoin,
+	MsgGroupMemberLeave:  i18n.KeyGroupMemberLeave,
+	MsgGroupMemberKicked: i18n.KeyGroupMemberKicked,
+	MsgGroupDismissed:    i18n.KeyGroupDismissed,
+	MsgGroupInfoUpdate:   i18n.KeyGroupInfoUpdate,
+	MsgGroupAdminChange:  i18n.KeyGroupAdminChange,
+	MsgGroupMute:         i18n.KeyGroupMute,
+	MsgGroupTransfer:     i18n.KeyGroupTransfer,
+	MsgGroupExpiring:     i18n.KeyGroupExpiring,
 }
 
 // GroupInfoUpdateContent 群资料变更内容
@@ -248,6 +350,71 @@ type ReadReceiptContent struct {
 	LastReadSeq    int64    `json:"last_read_seq"`         // 最后已读序列号
 }
 
+// KeyRotationContent 会话加密状态变更/密钥轮换事件内容
+// 客户端收到后按 key_version 生成并分发新的会话密钥（密钥本身不经过服务端），之后的消息使用新版本加密
+type KeyRotationContent struct {
+	ConversationID string `json:"conversation_id"`
+	Encrypted      bool   `json:"encrypted"`
+	KeyVersion     int    `json:"key_version"`
+	OperatorID     string `json:"operator_id"`
+	Reason         string `json:"reason,omitempty"` // enabled, disabled, rotated 或调用方指定的原因
+}
+
+// EncryptedContent 密文消息内容（开启加密的会话中聊天消息须使用此结构）
+type EncryptedContent struct {
+	Ciphertext string `json:"ciphertext"`          // Base64 密文
+	KeyVersion int    `json:"key_version"`         // 加密使用的会话密钥版本
+	Algorithm  string `json:"algorithm,omitempty"` // 加密算法，由客户端约定
+}
+
+// E2EEContent 端到端加密消息内容（type 13），服务端不解析密文，原样保存和转发
+// 使用会话密钥/群发送者密钥时携带 ciphertext，按设备分别加密时携带 envelopes（每个接收设备一份）
+type E2EEContent struct {
+	SenderDeviceID string          `json:"sender_device_id"`     // 发送设备ID
+	Algorithm      string          `json:"algorithm,omitempty"`  // 加密协议，由客户端约定
+	Ciphertext     string          `json:"ciphertext,omitempty"` // Base64 密文
+	Envelopes      []*E2EEEnvelope `json:"envelopes,omitempty"`
+
+	FileIDs []string `json:"file_ids,omitempty"` // 密文中引用的已上传文件（明文列出，避免被孤儿文件清理回收）
+}
+
+// E2EEEnvelope 发给单个设备的密文
+type E2EEEnvelope struct {
+	UserID     string `json:"user_id"`
+	DeviceID   string `json:"device_id"`
+	PreKey     bool   `json:"prekey,omitempty"` // 是否为使用预密钥建立会话的首条消息
+	Ciphertext string `json:"ciphertext"`       // Base64 密文
+}
+
+// SendFailedContent 发送失败通知内容：消息已回ACK，但随后被拒绝（审核、禁言、成员资格等），不会投递给接收者
+type SendFailedContent struct {
+	MessageID      string `json:"message_id"` // 原消息ID
+	ConversationID string `json:"conversation_id"`
+	Code           int    `json:"code"`   // 错误码
+	Reason         string `json:"reason"` // 错误说明（按连接语言）
+}
+
+// OfflineBatchContent 离线消息批次内容，客户端处理后回复 type 37 确认，服务端删除该批消息并发送下一批
+type OfflineBatchContent struct {
+	Messages []*OfflineBatchItem `json:"messages"`
+	LastSeq  int64               `json:"last_seq"` // 本批最后一条离线消息的序号，确认时原样回传
+	HasMore  bool                `json:"has_more"` // 确认后是否还有下一批
+}
+
+// OfflineBatchItem 离线消息批次中的消息（与 GET /api/offline/messages 的条目一致）
+type OfflineBatchItem struct {
+	ID             uint      `json:"id"`
+	MessageID      string    `json:"message_id"`
+	ConversationID string    `json:"conversation_id"`
+	Message        *Message  `json:"message"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// OfflineAckContent 离线消息批次确认内容
+type OfflineAckContent struct {
+	LastSeq int64 `json:"last_seq"` // 已处理的离线消息批次的 last_seq
+}
+
 // RevokeContent 撤回消息内容
 type RevokeContent struct {
 	MessageID string `json:"message_id"` // 被撤回的消息ID
@@ -259,15 +426,26 @@ type TypingContent struct {
 	ConversationID string `json:"conversation_id"`
 }
 
+// EphemeralContent 临时消息内容
+type EphemeralContent struct {
+	Kind string      `json:"kind"`           // 类型，如 typing、cursor、annotation、presence
+	Data interface{} `json:"data,omitempty"` // 业务数据，由客户端定义
+}
+
 // HeartbeatContent 心跳消息内容
 type HeartbeatContent struct {
 	Timestamp int64 `json:"timestamp"`
+	Interval  int   `json:"interval,omitempty"` // 服务端下发：当前心跳间隔（秒）
+	Timeout   int   `json:"timeout,omitempty"`  // 服务端下发：超过该时间（秒）未收到数据即断开
+
+	MaxClientSkew int64 `json:"max_client_skew,omitempty"` // 服务端下发：允许的 client_timestamp 偏差（毫秒）
 }
 
 // KickoutContent 踢出下线内容
 type KickoutContent struct {
-	Reason   string `json:"reason"`              // 踢出原因
-	DeviceID string `json:"device_id,omitempty"` // 新登录的设备ID
+	Reason     string `json:"reason"`                // 踢出原因（已按连接语言本地化）
+	ReasonCode string `json:"reason_code,omitempty"` // 踢出原因Key
+	DeviceID   string `json:"device_id,omitempty"`   // 新登录的设备ID
 }
 
 // ServerNoticeContent 服务器通知内容
@@ -278,18 +456,130 @@ type ServerNoticeContent struct {
 	Data    string `json:"data,omitempty"`   // 附加数据
 }
 
+// ConversationUpdatedContent 会话更新通知内容（客户端据此增量同步会话）
+type ConversationUpdatedContent struct {
+	ConversationID string `json:"conversation_id"`
+	Op             string `json:"op"` // insert/update/delete
+	MessageID      string `json:"message_id,omitempty"`
+	Seq            int64  `json:"seq,omitempty"`
+	Revoked        bool   `json:"revoked,omitempty"`
+}
+
+// ReminderContent 消息提醒内容（引用原消息）
+type ReminderContent struct {
+	ReminderID     string `json:"reminder_id"`
+	MessageID      string `json:"message_id"`
+	ConversationID string `json:"conversation_id"`
+	Sender         string `json:"sender,omitempty"`  // 原消息发送者
+	Preview        string `json:"preview,omitempty"` // 原消息摘要
+	Note           string `json:"note,omitempty"`
+	RemindAt       int64  `json:"remind_at"`
+	TemplateKey    string `json:"template_key,omitempty"`
+}
+
 // FriendRequestContent 好友请求内容
 type FriendRequestContent struct {
+	RequestID  string `json:"request_id"`
 	FromUserID string `json:"from_user_id"`
 	Nickname   string `json:"nickname"`
 	Avatar     string `json:"avatar,omitempty"`
 	Message    string `json:"message,omitempty"` // 申请留言
 }
 
+// FriendAcceptContent 好友申请通过通知内容（发给申请人）
+type FriendAcceptContent struct {
+	RequestID string `json:"request_id"`
+	UserID    string `json:"user_id"` // 通过申请的用户
+	Nickname  string `json:"nickname"`
+	Avatar    string `json:"avatar,omitempty"`
+}
+
+// JoinRequestContent 入群申请通知内容（发给群主和管理员）
+type JoinRequestContent struct {
+	RequestID uint   `json:"request_id"`
+	GroupID   string `json:"group_id"`
+	UserID    string `json:"user_id"` // 申请人
+	Message   string `json:"message,omitempty"`
+}
+
+// JoinResultContent 入群申请处理结果通知内容（发给申请人）
+type JoinResultContent struct {
+	RequestID uint   `json:"request_id"`
+	GroupID   string `json:"group_id"`
+	GroupName string `json:"group_name"`
+	Approved  bool   `json:"approved"`
+	HandlerID string `json:"handler_id"`
+}
+
+// GroupDigestContent 群活动每日摘要内容（发给订阅者）
+type GroupDigestContent struct {
+	GroupID        string                `json:"group_id"`
+	GroupName      string                `json:"group_name"`
+	ConversationID string                `json:"conversation_id"`
+	From           int64                 `json:"from"`                    // 统计区间开始（毫秒时间戳）
+	To             int64                 `json:"to"`                      // 统计区间结束（毫秒时间戳）
+	MessageCount   int                   `json:"message_count"`           // 区间内消息数
+	MoreMessages   bool                  `json:"more_messages,omitempty"` // 消息数超出统计上限，message_count 为下限
+	MentionCount   int                   `json:"mention_count"`           // @订阅者（含@所有人）的消息数
+	Mentions       []*GroupDigestMention `json:"mentions,omitempty"`      // 最近的@消息
+	NewPins        []string              `json:"new_pins,omitempty"`      // 区间内新置顶的消息ID
+	PinnedCount    int                   `json:"pinned_count"`            // 当前置顶消息数
+	TemplateKey    string                `json:"template_key,omitempty"`
+}
+
+// GroupDigestMention 摘要中的@消息
+type GroupDigestMention struct {
+	MessageID string `json:"message_id"`
+	Sender    string `json:"sender"`
+	Preview   string `json:"preview,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// 客服会话事件
+const (
+	CSEventQueued      = "queued"      // 进入排队（或排队位置变化）
+	CSEventAssigned    = "assigned"    // 分配坐席
+	CSEventTransferred = "transferred" // 转接到其他坐席
+	CSEventClosed      = "closed"      // 会话结束
+)
+
+// CSEventContent 客服会话事件通知内容（发给访客和相关坐席）
+type CSEventContent struct {
+	SessionID      string `json:"session_id"`
+	Event          string `json:"event"`
+	VisitorID      string `json:"visitor_id"`
+	AgentID        string `json:"agent_id,omitempty"`
+	FromAgentID    string `json:"from_agent_id,omitempty"`   // 转接前的坐席
+	ConversationID string `json:"conversation_id,omitempty"` // 访客与坐席的单聊会话
+	Position       int64  `json:"position,omitempty"`        // 排队位置（从1开始）
+	ClosedBy       string `json:"closed_by,omitempty"`
+}
+
 // CustomContent 自定义消息内容
+// 集成应用发送时可携带签名：signature = hex(HMAC-SHA256(secret, SigningPayload()))，
+// 服务端校验通过后设置 verified 并去掉签名再投递
 type CustomContent struct {
-	CustomType string                 `json:"custom_type"` // 自定义类型
-	Data       map[string]interface{} `json:"data"`        // 自定义数据
+	CustomType string                 `json:"custom_type"`         // 自定义类型
+	Data       map[string]interface{} `json:"data"`                // 自定义数据
+	AppID      string                 `json:"app_id,omitempty"`    // 来源集成应用
+	SignedAt   int64                  `json:"signed_at,omitempty"` // 签名时间（毫秒）
+	Signature  string                 `json:"signature,omitempty"` // 签名
+	Verified   bool                   `json:"verified"`            // 服务端设置：签名校验通过
+}
+
+// SigningPayload 参与签名的内容：app_id、custom_type、signed_at 与 data 以换行连接，
+// data 为键按字典序排列、不转义HTML字符的紧凑JSON（与对键排序后的 JSON.stringify 结果一致）
+func (c *CustomContent) SigningPayload() ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n%s\n%d\n", c.AppID, c.CustomType, c.SignedAt)
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(c.Data); err != nil {
+		return nil, err
+	}
+	// Encode 会追加换行
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
 }
 
 // MessageRecord 已废弃，请使用 repository.MessageDocument
@@ -298,14 +588,16 @@ type CustomContent struct {
 // OfflineMessage 离线消息
 type OfflineMessage struct {
 	ID             uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	UserID         string    `json:"user_id" gorm:"type:varchar(64);index:idx_user_created"`
+	UserID         string    `json:"user_id" gorm:"type:varchar(64);index:idx_user_created;index:idx_user_conversation"`
 	MessageID      string    `json:"message_id" gorm:"type:varchar(64);uniqueIndex"`
-	ConversationID string    `json:"conversation_id" gorm:"type:varchar(128)"`
+	ConversationID string    `json:"conversation_id" gorm:"type:varchar(128);index:idx_user_conversation"`
+	Seq            int64     `json:"seq" gorm:"default:0"` // 消息在会话中的序号，用于已读对账
 	Content        string    `json:"content" gorm:"type:text"`
 	Pushed         bool      `json:"pushed" gorm:"default:false;index"`
 	PushedAt       time.Time `json:"pushed_at,omitempty"`
 	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime;index:idx_user_created"`
 	ExpireAt       time.Time `json:"expire_at" gorm:"index"`
+	Superseded     bool      `json:"superseded" gorm:"default:false"` // 新设备首次同步时已被会话摘要快照取代，不再逐条下发，过期后清理
 }
 
 // TableName 指定离线消息表名
@@ -319,8 +611,17 @@ type Conversation struct {
 	Type           int       `json:"type" gorm:"type:tinyint;not null"` // 1-单聊 2-群聊
 	LastMessageID  string    `json:"last_message_id,omitempty" gorm:"type:varchar(64)"`
 	LastMessageAt  time.Time `json:"last_message_at,omitempty"`
-	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// 客户端加密：开启后只接受密文消息，KeyVersion 随开启和每次密钥轮换递增
+	Encrypted    bool       `json:"encrypted" gorm:"default:false;index"`
+	KeyVersion   int        `json:"key_version" gorm:"default:0"`
+	KeyRotatedAt *time.Time `json:"key_rotated_at,omitempty"`
+
+	// 消息存储区域：首条消息发送时按参与者区域确定，之后不再变化（为空表示默认区域）
+	Region string `json:"region,omitempty" gorm:"type:varchar(32)"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 // TableName 指定会话表名
@@ -400,9 +701,10 @@ func NewGroupEventMessage(eventType MessageType, groupID, operatorID string, tar
 		From: operatorID,
 		To:   groupID,
 		Content: &GroupEventContent{
-			GroupID:    groupID,
-			OperatorID: operatorID,
-			TargetIDs:  targetIDs,
+			GroupID:     groupID,
+			OperatorID:  operatorID,
+			TargetIDs:   targetIDs,
+			TemplateKey: groupEventTemplateKeys[eventType],
 		},
 		Timestamp: time.Now().UnixMilli(),
 		QoS:       QoSAtLeastOnce,
@@ -432,15 +734,29 @@ func NewAckMessage(messageID string, status int) *Message {
 	}
 }
 
-// GetSingleChatConversationID 获取单聊会话ID
-func GetSingleChatConversationID(userID1, userID2 string) string {
-	if userID1 < userID2 {
-		return "single:" + userID1 + ":" + userID2
+// NewSendFailedMessage 创建发送失败通知
+func NewSendFailedMessage(msg *Message, code int, reason string) *Message {
+	return &Message{
+		Type:           MsgSendFailed,
+		From:           "system",
+		To:             msg.From,
+		ConversationID: msg.ConversationID,
+		Content: &SendFailedContent{
+			MessageID:      msg.MessageID,
+			ConversationID: msg.ConversationID,
+			Code:           code,
+			Reason:         reason,
+		},
+		Timestamp: time.Now().UnixMilli(),
 	}
-	return "single:" + userID2 + ":" + userID1
 }
 
-// GetGroupChatConversationID 获取群聊会话ID
+// GetSingleChatConversationID 获取单聊会话ID（规范格式）
+func GetSingleChatConversationID(userID1, userID2 string) string {
+	return NewSingleConversationID(userID1, userID2).String()
+}
+
+// GetGroupChatConversationID 获取群聊会话ID（规范格式）
 func GetGroupChatConversationID(groupID string) string {
-	return "group:" + groupID
+	return NewGroupConversationID(groupID).String()
 }
@@ -0,0 +1,171 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PatchOp 局部更新操作类型（JSON Patch 子集）
+type PatchOp string
+
+const (
+	PatchOpAdd     PatchOp = "add"     // 添加字段或向数组追加元素（路径末段为"-"）
+	PatchOpReplace PatchOp = "replace" // 替换字段，字段不存在时等同于添加
+	PatchOpRemove  PatchOp = "remove"  // 删除字段或数组元素，字段不存在时忽略
+)
+
+// 局部更新错误定义
+var (
+	ErrPatchInvalidOp   = errors.New("invalid patch op")
+	ErrPatchInvalidPath = errors.New("invalid patch path")
+)
+
+// PatchOperation 局部更新操作
+// Path 为相对消息对象（与 Message 的 JSON 结构一致）的 JSON Pointer，如 /revoked、/content/text、/reactions/👍/-
+type PatchOperation struct {
+	Op    PatchOp     `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// PatchContent 消息局部更新内容
+// 客户端按 message_id 找到本地消息并依次应用 ops；本地不存在该消息时忽略（下次拉取历史即为最新状态）。
+// version 为服务端更新时间（毫秒），客户端应忽略不大于已应用版本的补丁，保证乱序到达时结果一致。
+type PatchContent struct {
+	MessageID      string           `json:"message_id"`
+	ConversationID string           `json:"conversation_id,omitempty"`
+	Seq            int64            `json:"seq,omitempty"`
+	Version        int64            `json:"version"`
+	Ops            []PatchOperation `json:"ops"`
+}
+
+// ApplyPatch 将局部更新应用到消息对象（消息的 JSON map 表示），供服务端测试和客户端实现参考
+func ApplyPatch(target map[string]interface{}, ops []PatchOperation) error {
+	for _, op := range ops {
+		switch op.Op {
+		case PatchOpAdd, PatchOpReplace, PatchOpRemove:
+		default:
+			return fmt.Errorf("%s %s: %w", op.Op, op.Path, ErrPatchInvalidOp)
+		}
+		tokens, err := parsePatchPath(op.Path)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", op.Op, op.Path, err)
+		}
+		if _, err := patchNode(target, tokens, op); err != nil {
+			return fmt.Errorf("%s %s: %w", op.Op, op.Path, err)
+		}
+	}
+	return nil
+}
+
+// patchNode 在节点上应用操作，返回更新后的节点（数组追加、删除会生成新切片）
+func patchNode(node interface{}, tokens []string, op PatchOperation) (interface{}, error) {
+	token := tokens[0]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if len(tokens) == 1 {
+			if op.Op == PatchOpRemove {
+				delete(n, token)
+			} else {
+				n[token] = op.Value
+			}
+			return n, nil
+		}
+		child, ok := n[token]
+		if !ok || child == nil {
+			// 中间缺失的对象自动创建，删除时直接忽略
+			if op.Op == PatchOpRemove {
+				return n, nil
+			}
+			child = newPatchContainer(tokens[1:])
+		}
+		updated, err := patchNode(child, tokens[1:], op)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = updated
+		return n, nil
+
+	case []interface{}:
+		if len(tokens) == 1 {
+			switch op.Op {
+			case PatchOpAdd:
+				if token == "-" {
+					return append(n, op.Value), nil
+				}
+				index, ok := arrayIndex(token, len(n)+1)
+				if !ok {
+					return nil, ErrPatchInvalidPath
+				}
+				n = append(n, nil)
+				copy(n[index+1:], n[index:])
+				n[index] = op.Value
+				return n, nil
+			case PatchOpReplace:
+				index, ok := arrayIndex(token, len(n))
+				if !ok {
+					return nil, ErrPatchInvalidPath
+				}
+				n[index] = op.Value
+				return n, nil
+			default:
+				index, ok := arrayIndex(token, len(n))
+				if !ok {
+					return n, nil
+				}
+				return append(n[:index:index], n[index+1:]...), nil
+			}
+		}
+		index, ok := arrayIndex(token, len(n))
+		if !ok {
+			return nil, ErrPatchInvalidPath
+		}
+		updated, err := patchNode(n[index], tokens[1:], op)
+		if err != nil {
+			return nil, err
+		}
+		n[index] = updated
+		return n, nil
+
+	case nil:
+		// 路径中间缺失的节点：按对象创建
+		if op.Op == PatchOpRemove {
+			return nil, nil
+		}
+		return patchNode(newPatchContainer(tokens), tokens, op)
+
+	default:
+		return nil, ErrPatchInvalidPath
+	}
+}
+
+// newPatchContainer 创建缺失的中间节点：以"-"追加时为数组，否则为对象
+func newPatchContainer(tokens []string) interface{} {
+	if len(tokens) == 1 && tokens[0] == "-" {
+		return []interface{}{}
+	}
+	return make(map[string]interface{})
+}
+
+// parsePatchPath 解析 JSON Pointer 路径（~1 表示 "/"，~0 表示 "~"）
+func parsePatchPath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "/") || len(path) < 2 {
+		return nil, ErrPatchInvalidPath
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex 解析数组下标，要求 0 <= index < length
+func arrayIndex(token string, length int) (int, bool) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index >= length {
+		return 0, false
+	}
+	return index, true
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// PinnedMessage 会话置顶消息
+type PinnedMessage struct {
+	ConversationID string    `json:"conversation_id" gorm:"primaryKey;type:varchar(128)"`
+	MessageID      string    `json:"message_id" gorm:"primaryKey;type:varchar(64)"`
+	PinnedBy       string    `json:"pinned_by" gorm:"type:varchar(64)"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (PinnedMessage) TableName() string {
+	return "pinned_messages"
+}
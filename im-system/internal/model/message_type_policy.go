@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+// 消息类型策略的会话类别
+const (
+	ConversationClassSingle = "single" // 单聊
+	ConversationClassGroup  = "group"  // 群聊
+)
+
+// 消息类型策略的发送者角色
+const (
+	SenderRoleGuest  = "guest"  // 访客账号
+	SenderRoleMember = "member" // 普通用户（群聊中为普通成员）
+	SenderRoleAdmin  = "admin"  // 群主或管理员（仅群聊）
+)
+
+// MessageTypeRule 消息类型规则：会话类别和发送者角色匹配时，聊天消息只能使用 Allowed 中的类型
+type MessageTypeRule struct {
+	Class   string        `json:"class,omitempty"` // single / group，为空匹配全部会话
+	Role    string        `json:"role,omitempty"`  // guest / member / admin，为空匹配全部发送者
+	Allowed []MessageType `json:"allowed"`         // 允许的聊天消息类型（文本填 0），为空表示禁止发送聊天消息
+}
+
+// Matches 判断规则是否适用于该会话类别和发送者角色
+func (r *MessageTypeRule) Matches(class, role string) bool {
+	return (r.Class == "" || r.Class == class) && (r.Role == "" || r.Role == role)
+}
+
+// Allows 判断规则是否允许该消息类型
+func (r *MessageTypeRule) Allows(t MessageType) bool {
+	t = PolicyMessageType(t)
+	for _, allowed := range r.Allowed {
+		if PolicyMessageType(allowed) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// MessageTypePolicy 允许发送的消息类型策略（全局、租户或群组策略）
+// 聊天消息须满足各级策略中全部匹配的规则，没有匹配规则时不限制
+type MessageTypePolicy struct {
+	Rules     []MessageTypeRule `json:"rules"`
+	UpdatedBy string            `json:"updated_by,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at,omitempty"`
+}
+
+// Violation 返回第一条不允许该消息类型的匹配规则，全部允许时返回 nil
+func (p *MessageTypePolicy) Violation(class, role string, t MessageType) *MessageTypeRule {
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Matches(class, role) && !rule.Allows(t) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// SetMessageTypePolicyRequest 设置消息类型策略请求
+type SetMessageTypePolicyRequest struct {
+	Rules []MessageTypeRule `json:"rules"`
+}
+
+// PolicyMessageType 策略中的消息类型：单聊、群聊文本消息统一按文本（MsgText）匹配
+func PolicyMessageType(t MessageType) MessageType {
+	if t == MsgSingleChat || t == MsgGroupChat {
+		return MsgText
+	}
+	return t
+}
@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// 内容审核处置方式（严重程度依次降低）
+const (
+	ModerationActionReject = "reject" // 拒绝发送或上传
+	ModerationActionMask   = "mask"   // 命中内容替换为 * 后放行
+	ModerationActionFlag   = "flag"   // 放行，记录违规供人工复核
+)
+
+// 内容审核对象
+const (
+	ModerationSourceMessage = "message" // 聊天消息文本
+	ModerationSourceFile    = "file"    // 上传文件（文件名及元数据）
+)
+
+// ModerationViolation 内容审核违规记录
+type ModerationViolation struct {
+	ID             int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID         string    `json:"user_id" gorm:"type:varchar(64);index:idx_moderation_user_time"`
+	Source         string    `json:"source" gorm:"type:varchar(16)"`
+	ConversationID string    `json:"conversation_id,omitempty" gorm:"type:varchar(128);index"`
+	MessageID      string    `json:"message_id,omitempty" gorm:"type:varchar(64)"`
+	FileName       string    `json:"file_name,omitempty" gorm:"type:varchar(255)"`
+	Moderator      string    `json:"moderator" gorm:"type:varchar(32)"` // 命中的审核器：keyword / http
+	Action         string    `json:"action" gorm:"type:varchar(16);index"`
+	Labels         []string  `json:"labels,omitempty" gorm:"serializer:json;type:json"`
+	Reason         string    `json:"reason,omitempty" gorm:"type:varchar(512)"`
+	Content        string    `json:"content,omitempty" gorm:"type:text"` // 审核前的原始内容（超长截断）
+	CreatedAt      time.Time `json:"created_at" gorm:"index:idx_moderation_user_time;index"`
+}
+
+// TableName 指定表名
+func (ModerationViolation) TableName() string {
+	return "moderation_violations"
+}
+
+// ModerationViolationFilter 违规记录查询条件
+type ModerationViolationFilter struct {
+	UserID         string
+	ConversationID string
+	Source         string
+	Action         string
+	Since          *time.Time
+	Until          *time.Time
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// DepartmentVisibility 部门可见范围
+type DepartmentVisibility int
+
+const (
+	DeptVisibleAll     DepartmentVisibility = 0 // 租户内全员可见
+	DeptVisibleMembers DepartmentVisibility = 1 // 仅本部门及下级部门成员可见
+	DeptVisibleHidden  DepartmentVisibility = 2 // 仅管理员可见
+)
+
+// Department 部门
+type Department struct {
+	DepartmentID string               `json:"department_id" gorm:"primaryKey;type:varchar(64)"`
+	TenantID     string               `json:"tenant_id,omitempty" gorm:"type:varchar(64);index:idx_dept_tenant_parent"`
+	ParentID     string               `json:"parent_id,omitempty" gorm:"type:varchar(64);index:idx_dept_tenant_parent"` // 为空表示根部门
+	Name         string               `json:"name" gorm:"type:varchar(128);not null"`
+	Path         string               `json:"-" gorm:"type:varchar(1024);index"` // 物化路径 /<根部门ID>/.../<本部门ID>/，用于子树查询
+	SortOrder    int                  `json:"sort_order" gorm:"default:0"`
+	Visibility   DepartmentVisibility `json:"visibility" gorm:"default:0"`
+	CreatedAt    time.Time            `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time            `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (Department) TableName() string {
+	return "departments"
+}
+
+// DepartmentPath 生成部门物化路径
+func DepartmentPath(parentPath, departmentID string) string {
+	if parentPath == "" {
+		parentPath = "/"
+	}
+	return parentPath + departmentID + "/"
+}
+
+// IsAncestorOf 是否为指定部门的祖先部门（含自身）
+func (d *Department) IsAncestorOf(other *Department) bool {
+	return strings.HasPrefix(other.Path, d.Path)
+}
+
+// AncestorIDs 祖先部门ID（由根到父，不含自身）
+func (d *Department) AncestorIDs() []string {
+	ids := strings.Split(strings.Trim(d.Path, "/"), "/")
+	if len(ids) == 0 {
+		return nil
+	}
+	return ids[:len(ids)-1]
+}
+
+// DepartmentMember 部门成员
+type DepartmentMember struct {
+	ID           uint      `json:"-" gorm:"primaryKey;autoIncrement"`
+	DepartmentID string    `json:"department_id" gorm:"type:varchar(64);uniqueIndex:idx_dept_user"`
+	UserID       string    `json:"user_id" gorm:"type:varchar(64);uniqueIndex:idx_dept_user;index"`
+	Title        string    `json:"title,omitempty" gorm:"type:varchar(64)"` // 职位
+	IsPrimary    bool      `json:"is_primary" gorm:"default:false"`         // 主部门
+	SortOrder    int       `json:"sort_order" gorm:"default:0"`
+	JoinedAt     time.Time `json:"joined_at"`
+}
+
+// TableName 指定表名
+func (DepartmentMember) TableName() string {
+	return "department_members"
+}
@@ -0,0 +1,79 @@
+package model
+
+import "time"
+
+// PollStatus 投票状态
+type PollStatus int
+
+const (
+	PollOpen   PollStatus = 0 // 进行中
+	PollClosed PollStatus = 1 // 已结束
+)
+
+// Poll 群投票（投票本身以 type 11 消息发送到群聊，选项和状态保存在这里）
+type Poll struct {
+	ID              uint       `json:"-" gorm:"primaryKey;autoIncrement"`
+	PollID          string     `json:"poll_id" gorm:"type:varchar(64);uniqueIndex"`
+	GroupID         string     `json:"group_id" gorm:"type:varchar(64);index"`
+	MessageID       string     `json:"message_id" gorm:"type:varchar(64)"` // 投票消息
+	CreatorID       string     `json:"creator_id" gorm:"type:varchar(64)"`
+	Question        string     `json:"question" gorm:"type:varchar(256)"`
+	Options         []string   `json:"options" gorm:"serializer:json;type:json"`
+	MultiChoice     bool       `json:"multi_choice"`
+	Anonymous       bool       `json:"anonymous"` // 匿名投票不公开每个选项的投票人
+	Deadline        *time.Time `json:"deadline,omitempty" gorm:"index:idx_poll_status_deadline"`
+	Status          PollStatus `json:"status" gorm:"default:0;index:idx_poll_status_deadline"`
+	ResultMessageID string     `json:"result_message_id,omitempty" gorm:"type:varchar(64)"` // 结束时发送的结果消息
+	ClosedAt        *time.Time `json:"closed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (Poll) TableName() string {
+	return "polls"
+}
+
+// PollVote 用户的投票（每个用户每个投票只能投一次，多选投票一次提交全部选项）
+type PollVote struct {
+	PollID    string    `json:"poll_id" gorm:"primaryKey;type:varchar(64)"`
+	UserID    string    `json:"user_id" gorm:"primaryKey;type:varchar(64)"`
+	Options   []int     `json:"options" gorm:"serializer:json;type:json"` // 选项下标
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (PollVote) TableName() string {
+	return "poll_votes"
+}
+
+// PollContent 投票消息内容
+// counts、total_voters、closed 随投票通过 patch 帧更新（/content/counts、/content/total_voters、/content/closed）
+type PollContent struct {
+	PollID      string   `json:"poll_id"`
+	Question    string   `json:"question"`
+	Options     []string `json:"options"`
+	MultiChoice bool     `json:"multi_choice"`
+	Anonymous   bool     `json:"anonymous"`
+	Deadline    int64    `json:"deadline,omitempty"` // 截止时间（毫秒），为空时由发起人或管理员手动结束
+	Counts      []int64  `json:"counts"`
+	TotalVoters int64    `json:"total_voters"`
+	Closed      bool     `json:"closed"`
+}
+
+// PollOptionResult 选项的投票结果
+type PollOptionResult struct {
+	Option string   `json:"option"`
+	Count  int64    `json:"count"`
+	Voters []string `json:"voters,omitempty"` // 非匿名投票的投票人
+}
+
+// PollResultContent 投票结果消息内容（投票结束时发送到群聊）
+type PollResultContent struct {
+	PollID        string              `json:"poll_id"`
+	PollMessageID string              `json:"poll_message_id"`
+	Question      string              `json:"question"`
+	Results       []*PollOptionResult `json:"results"`
+	TotalVoters   int64               `json:"total_voters"`
+	ClosedBy      string              `json:"closed_by,omitempty"` // 手动结束的用户，到期自动结束时为空
+	TemplateKey   string              `json:"template_key,omitempty"`
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 推送通知中携带的实验信息（PushNotification.Data）
+const (
+	PushDataID         = "push_id"         // 推送ID，客户端打开/确认通知时回传
+	PushDataExperiment = "push_experiment" // 推送文案实验Key
+	PushDataVariant    = "push_variant"    // 推送文案分组
+)
+
+// 推送回调事件
+const (
+	PushEventOpened = "opened" // 用户点击打开通知
+	PushEventAcked  = "acked"  // 客户端确认收到通知
+)
+
+// PushTemplate 推送文案模板
+// 占位符: {title} 原标题, {body} 原正文, {count} 消息数；为空时保留原文案
+type PushTemplate struct {
+	Title string `json:"title,omitempty" binding:"max=128"`
+	Body  string `json:"body,omitempty" binding:"max=512"`
+}
+
+// Render 渲染推送文案
+func (t *PushTemplate) Render(notification *PushNotification) (string, string) {
+	replacer := strings.NewReplacer(
+		"{title}", notification.Title,
+		"{body}", notification.Body,
+		"{count}", strconv.Itoa(notification.Badge),
+	)
+
+	title, body := notification.Title, notification.Body
+	if t.Title != "" {
+		title = replacer.Replace(t.Title)
+	}
+	if t.Body != "" {
+		body = replacer.Replace(t.Body)
+	}
+	return title, body
+}
+
+// PushExperiment 推送文案实验
+// Key 与功能开关Key相同，用户按开关分组（control/treatment）使用对应的文案模板，开关未开启时不生效
+type PushExperiment struct {
+	Key         string                   `json:"key"`
+	Description string                   `json:"description,omitempty"`
+	Variants    map[string]*PushTemplate `json:"variants"` // 分组 -> 文案模板，未配置的分组保留原文案
+	UpdatedBy   string                   `json:"updated_by,omitempty"`
+	UpdatedAt   time.Time                `json:"updated_at"`
+}
+
+// SetPushExperimentRequest 设置推送文案实验请求
+type SetPushExperimentRequest struct {
+	Description string                   `json:"description" binding:"max=256"`
+	Variants    map[string]*PushTemplate `json:"variants" binding:"required,min=1,max=2,dive"`
+}
+
+// PushOpenedRequest 推送打开/确认回调请求
+type PushOpenedRequest struct {
+	PushID string `json:"push_id" binding:"required,max=64"`            // 通知 data 中的 push_id
+	Event  string `json:"event" binding:"omitempty,oneof=opened acked"` // 默认 opened
+}
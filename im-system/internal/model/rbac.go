@@ -0,0 +1,182 @@
+package model
+
+import "time"
+
+// 管理权限
+const (
+	PermAll = "*" // 全部权限（超级管理员）
+
+	PermSystemRead        = "system:read"        // 查看维护状态、节点、客户端统计
+	PermSystemWrite       = "system:write"       // 维护模式、全局/节点广播与摘除
+	PermUserRead          = "user:read"          // 查看用户列表
+	PermUserWrite         = "user:write"         // 导入用户、禁用/注销账号、强制下线
+	PermGroupRead         = "group:read"         // 查看群组列表、群主继任记录
+	PermGroupWrite        = "group:write"        // 解散违规群组
+	PermConversationRead  = "conversation:read"  // 查看加密会话列表
+	PermConversationWrite = "conversation:write" // 批量清除会话消息
+	PermAnalytics         = "analytics:read"     // 会话分析、推送分析、灰度指标、用量计量
+	PermFileRead          = "file:read"          // 查看全局文件类型策略
+	PermFileWrite         = "file:write"         // 修改全局文件类型策略
+	PermFeatureRead       = "feature:read"       // 查看功能开关、推送文案实验
+	PermFeatureWrite      = "feature:write"      // 修改功能开关、推送文案实验
+	PermAppRead           = "app:read"           // 查看集成应用、外部平台桥接
+	PermAppWrite          = "app:write"          // 管理集成应用、外部平台桥接
+	PermOrgWrite          = "org:write"          // 维护组织架构
+	PermCSRead            = "cs:read"            // 查看客服坐席池、公共快捷回复
+	PermCSWrite           = "cs:write"           // 管理客服坐席池、公共快捷回复
+	PermRBACRead          = "rbac:read"          // 查看角色、角色分配及审计日志
+	PermRBACWrite         = "rbac:write"         // 管理角色及角色分配
+
+	PermModerationRead = "moderation:read" // 查看内容审核违规记录
+)
+
+// AllPermissions 全部管理权限
+var AllPermissions = []string{
+	PermSystemRead, PermSystemWrite,
+	PermUserRead, PermUserWrite,
+	PermGroupRead, PermGroupWrite,
+	PermConversationRead, PermConversationWrite,
+	PermAnalytics,
+	PermFileRead, PermFileWrite,
+	PermModerationRead,
+	PermFeatureRead, PermFeatureWrite,
+	PermAppRead, PermAppWrite,
+	PermOrgWrite,
+	PermCSRead, PermCSWrite,
+	PermRBACRead, PermRBACWrite,
+}
+
+// 内置角色
+const (
+	RoleSupport    = "support"     // 客服：查看账号相关信息、维护通讯录
+	RoleModerator  = "moderator"   // 内容审核：处置违规账号、文件策略、违规记录
+	RoleOps        = "ops"         // 运维：节点、维护模式、开关、集成
+	RoleSuperAdmin = "super_admin" // 超级管理员：全部权限，包括角色分配
+)
+
+// BuiltinRoles 内置角色及其权限（不可修改、删除）
+var BuiltinRoles = []*AdminRole{
+	{
+		Name:        RoleSupport,
+		Description: "客服",
+		Permissions: []string{PermSystemRead, PermUserRead, PermGroupRead, PermConversationRead, PermOrgWrite, PermCSRead, PermCSWrite},
+		Builtin:     true,
+	},
+	{
+		Name:        RoleModerator,
+		Description: "内容审核",
+		Permissions: []string{PermUserRead, PermUserWrite, PermGroupRead, PermGroupWrite, PermConversationRead, PermConversationWrite, PermFileRead, PermFileWrite, PermModerationRead},
+		Builtin:     true,
+	},
+	{
+		Name:        RoleOps,
+		Description: "运维",
+		Permissions: []string{PermSystemRead, PermSystemWrite, PermAnalytics, PermFileRead, PermFeatureRead, PermFeatureWrite, PermAppRead, PermAppWrite},
+		Builtin:     true,
+	},
+	{
+		Name:        RoleSuperAdmin,
+		Description: "超级管理员",
+		Permissions: []string{PermAll},
+		Builtin:     true,
+	},
+}
+
+// AdminRole 管理角色
+type AdminRole struct {
+	Name        string    `json:"name" gorm:"primaryKey;type:varchar(64)"`
+	Description string    `json:"description" gorm:"type:varchar(256)"`
+	Permissions []string  `json:"permissions" gorm:"serializer:json;type:json"`
+	Builtin     bool      `json:"builtin" gorm:"-"`
+	UpdatedBy   string    `json:"updated_by,omitempty" gorm:"type:varchar(64)"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (AdminRole) TableName() string {
+	return "admin_roles"
+}
+
+// Grants 角色是否拥有权限
+func (r *AdminRole) Grants(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == PermAll || p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminRoleAssignment 用户的管理角色
+type AdminRoleAssignment struct {
+	UserID    string    `json:"user_id" gorm:"primaryKey;type:varchar(64)"`
+	Role      string    `json:"role" gorm:"primaryKey;type:varchar(64);index"`
+	GrantedBy string    `json:"granted_by" gorm:"type:varchar(64)"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (AdminRoleAssignment) TableName() string {
+	return "admin_role_assignments"
+}
+
+// AdminAuditLog 管理接口调用审计日志
+type AdminAuditLog struct {
+	ID         int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     string    `json:"user_id" gorm:"type:varchar(64);index:idx_audit_user_time"`
+	Method     string    `json:"method" gorm:"type:varchar(8)"`
+	Path       string    `json:"path" gorm:"type:varchar(255)"`  // 实际请求路径
+	Route      string    `json:"route" gorm:"type:varchar(255)"` // 路由模板，如 /api/admin/users/:user_id
+	Permission string    `json:"permission" gorm:"type:varchar(64);index"`
+	Allowed    bool      `json:"allowed"`
+	Status     int       `json:"status"`
+	Outcome    string    `json:"outcome" gorm:"type:varchar(16);index"` // 调用结果，见 AuditOutcome* 常量
+	ClientIP   string    `json:"client_ip" gorm:"type:varchar(64)"`
+	Detail     string    `json:"detail,omitempty" gorm:"type:text"` // 操作详情（JSON），由具体接口提供
+	CreatedAt  time.Time `json:"created_at" gorm:"index:idx_audit_user_time;index"`
+}
+
+// TableName 指定表名
+func (AdminAuditLog) TableName() string {
+	return "admin_audit_logs"
+}
+
+// 管理接口调用结果
+const (
+	AuditOutcomeSuccess = "success" // 已放行且接口成功
+	AuditOutcomeFailed  = "failed"  // 已放行但接口返回错误（状态码 >= 400）
+	AuditOutcomeDenied  = "denied"  // 权限不足被拒绝
+)
+
+// AuditOutcome 根据是否放行和响应状态码判断调用结果
+func AuditOutcome(allowed bool, status int) string {
+	switch {
+	case !allowed:
+		return AuditOutcomeDenied
+	case status >= 400:
+		return AuditOutcomeFailed
+	default:
+		return AuditOutcomeSuccess
+	}
+}
+
+// SaveAdminRoleRequest 创建或更新自定义角色请求
+type SaveAdminRoleRequest struct {
+	Description string   `json:"description" binding:"max=256"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// SetUserRolesRequest 设置用户角色请求（覆盖原有角色，为空表示撤销全部角色）
+type SetUserRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// AuditLogFilter 审计日志查询条件
+type AuditLogFilter struct {
+	UserID     string
+	Permission string
+	Outcome    string // 调用结果，为空时不过滤
+	Since      *time.Time
+	Until      *time.Time
+}
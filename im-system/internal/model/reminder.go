@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// ReminderStatus 消息提醒状态
+type ReminderStatus int
+
+const (
+	ReminderPending   ReminderStatus = 0 // 待提醒
+	ReminderFired     ReminderStatus = 1 // 已提醒
+	ReminderCancelled ReminderStatus = 2 // 已取消
+)
+
+// MessageReminder 消息提醒（"稍后提醒我"）
+type MessageReminder struct {
+	ID             uint           `json:"-" gorm:"primaryKey;autoIncrement"`
+	ReminderID     string         `json:"reminder_id" gorm:"type:varchar(64);uniqueIndex"`
+	UserID         string         `json:"user_id" gorm:"type:varchar(64);index:idx_reminder_user_status"`
+	MessageID      string         `json:"message_id" gorm:"type:varchar(64)"`
+	ConversationID string         `json:"conversation_id" gorm:"type:varchar(128)"`
+	Note           string         `json:"note,omitempty" gorm:"type:varchar(256)"` // 提醒备注
+	RemindAt       time.Time      `json:"remind_at" gorm:"index:idx_reminder_status_at"`
+	Status         ReminderStatus `json:"status" gorm:"default:0;index:idx_reminder_user_status;index:idx_reminder_status_at"`
+	FiredAt        *time.Time     `json:"fired_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName 指定表名
+func (MessageReminder) TableName() string {
+	return "message_reminders"
+}
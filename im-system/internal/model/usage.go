@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+// ConnectionSession 长连接会话记录（连接断开时写入，用于计费/用量计量）
+type ConnectionSession struct {
+	ID              uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ConnectionID    string    `json:"connection_id" gorm:"type:varchar(64)"`
+	UserID          string    `json:"user_id" gorm:"type:varchar(64);index:idx_connection_sessions_user"`
+	TenantID        string    `json:"tenant_id,omitempty" gorm:"type:varchar(64)"`
+	NodeID          string    `json:"node_id" gorm:"type:varchar(64)"`
+	Platform        string    `json:"platform,omitempty" gorm:"type:varchar(16)"`
+	DeviceID        string    `json:"device_id,omitempty" gorm:"type:varchar(128)"`
+	ClientIP        string    `json:"client_ip,omitempty" gorm:"type:varchar(64)"`
+	AppVersion      string    `json:"app_version,omitempty" gorm:"type:varchar(32)"`
+	ConnectedAt     time.Time `json:"connected_at"`
+	DisconnectedAt  time.Time `json:"disconnected_at" gorm:"index;index:idx_connection_sessions_user"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	BytesIn         int64     `json:"bytes_in"`   // 收到的字节数（消息帧负载，近似值）
+	BytesOut        int64     `json:"bytes_out"`  // 发出的字节数（消息帧负载，近似值）
+	FramesIn        int64     `json:"frames_in"`  // 收到的消息帧数
+	FramesOut       int64     `json:"frames_out"` // 发出的消息帧数
+}
+
+// TableName 指定表名
+func (ConnectionSession) TableName() string {
+	return "connection_sessions"
+}
+
+// MonthlyUsage 用户月度用量汇总（会话按断开时间计入所在月份，月份按 UTC 划分）
+type MonthlyUsage struct {
+	Month           string    `json:"month" gorm:"primaryKey;type:varchar(7)"` // YYYY-MM
+	UserID          string    `json:"user_id" gorm:"primaryKey;type:varchar(64)"`
+	TenantID        string    `json:"tenant_id,omitempty" gorm:"type:varchar(64);index:idx_monthly_usage_tenant"`
+	Sessions        int64     `json:"sessions"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	BytesIn         int64     `json:"bytes_in"`
+	BytesOut        int64     `json:"bytes_out"`
+	FramesIn        int64     `json:"frames_in"`
+	FramesOut       int64     `json:"frames_out"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TableName 指定表名
+func (MonthlyUsage) TableName() string {
+	return "monthly_usage"
+}
+
+// TenantUsage 租户月度用量（按租户汇总用户用量，未分配租户的用户汇总为空租户）
+type TenantUsage struct {
+	Month           string `json:"month"`
+	TenantID        string `json:"tenant_id"`
+	Users           int64  `json:"users"`
+	Sessions        int64  `json:"sessions"`
+	DurationSeconds int64  `json:"duration_seconds"`
+	BytesIn         int64  `json:"bytes_in"`
+	BytesOut        int64  `json:"bytes_out"`
+	FramesIn        int64  `json:"frames_in"`
+	FramesOut       int64  `json:"frames_out"`
+}
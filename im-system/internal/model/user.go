@@ -11,20 +11,51 @@ type UserStatus int
 const (
 	UserStatusNormal   UserStatus = 1 // 正常
 	UserStatusDisabled UserStatus = 0 // 禁用
+	UserStatusDeleted  UserStatus = 2 // 已注销
 )
 
 // User 用户模型
 type User struct {
 	UserID       string     `json:"user_id" gorm:"primaryKey;type:varchar(64)"`
+	TenantID     string     `json:"tenant_id,omitempty" gorm:"type:varchar(64);index"` // 租户ID（昵称唯一性范围）
+	Region       string     `json:"region,omitempty" gorm:"type:varchar(32)"`          // 数据驻留区域，为空时按租户区域或默认区域
 	Username     string     `json:"username" gorm:"type:varchar(64);uniqueIndex;not null"`
 	Nickname     string     `json:"nickname" gorm:"type:varchar(64)"`
 	Avatar       string     `json:"avatar" gorm:"type:varchar(512)"`
+	Phone        string     `json:"-" gorm:"type:varchar(32);index"`     // 手机号，仅用于精确搜索
+	Searchable   bool       `json:"searchable" gorm:"default:true"`      // 是否允许被搜索到
+	Email        string     `json:"-" gorm:"type:varchar(255)"`          // 邮箱，仅用于长期离线时的未读消息邮件摘要
+	EmailDigest  bool       `json:"email_digest" gorm:"default:true"`    // 是否接收未读消息邮件摘要
 	PasswordHash string     `json:"-" gorm:"type:varchar(256);not null"` // 密码哈希，JSON序列化时忽略
 	Status       UserStatus `json:"status" gorm:"default:1"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
+
+	MustResetPassword bool `json:"must_reset_password" gorm:"default:false"` // 首次登录须修改密码（批量导入的初始密码）
+
+	Guest          bool       `json:"guest,omitempty" gorm:"default:false"`    // 访客账号（售前咨询等匿名会话）
+	GuestExpiresAt *time.Time `json:"guest_expires_at,omitempty" gorm:"index"` // 访客账号过期时间，过期后账号及消息被清理
+
+	PresenceVisibility PresenceVisibility `json:"presence_visibility" gorm:"type:varchar(16);default:everyone"` // 在线状态及最近在线时间对谁可见
+
+	// 免打扰时段：时段内离线消息不推送到设备（HH:MM，用户时区，结束早于开始表示跨午夜），为空表示未开启
+	DNDStart    string `json:"dnd_start,omitempty" gorm:"type:varchar(5)"`
+	DNDEnd      string `json:"dnd_end,omitempty" gorm:"type:varchar(5)"`
+	DNDTimezone string `json:"dnd_timezone,omitempty" gorm:"type:varchar(64)"` // IANA 时区，为空时为 UTC
+
+	PushEnabled bool `json:"push_enabled" gorm:"default:true"` // 是否接收离线推送
+	PushPreview bool `json:"push_preview" gorm:"default:true"` // 推送是否显示消息内容，关闭后只提示"收到一条新消息"
 }
 
+// PresenceVisibility 在线状态可见范围
+type PresenceVisibility string
+
+const (
+	PresenceEveryone PresenceVisibility = "everyone" // 所有人可见
+	PresenceFriends  PresenceVisibility = "friends"  // 仅好友可见
+	PresenceNobody   PresenceVisibility = "nobody"   // 不公开
+)
+
 // TableName 指定表名
 func (User) TableName() string {
 	return "users"
@@ -73,12 +104,93 @@ type LoginResponse struct {
 	RefreshToken string    `json:"refresh_token"`
 	ExpiresAt    time.Time `json:"expires_at"`
 	WebSocketURL string    `json:"websocket_url"`
+	CSRFToken    string    `json:"csrf_token,omitempty"` // Cookie会话模式下的CSRF Token
+
+	MustResetPassword bool `json:"must_reset_password,omitempty"` // 客户端须引导用户修改初始密码
 }
 
 // UpdateUserRequest 更新用户信息请求
 type UpdateUserRequest struct {
 	Nickname *string `json:"nickname" binding:"omitempty,max=32"`
 	Avatar   *string `json:"avatar" binding:"omitempty,max=512"`
+
+	Phone      *string `json:"phone" binding:"omitempty,max=32"`
+	Searchable *bool   `json:"searchable"`
+
+	Email       *string `json:"email" binding:"omitempty,email,max=255"`
+	EmailDigest *bool   `json:"email_digest"` // 关闭后不再发送未读消息邮件摘要
+
+	PresenceVisibility *string `json:"presence_visibility" binding:"omitempty,oneof=everyone friends nobody"`
+}
+
+// NotificationSettings 用户通知设置
+type NotificationSettings struct {
+	PushEnabled        bool                `json:"push_enabled"`        // 是否接收离线推送
+	ShowPreview        bool                `json:"show_preview"`        // 推送是否显示消息内容
+	DoNotDisturb       DoNotDisturbSetting `json:"do_not_disturb"`      // 免打扰时段，开始、结束均为空表示未开启
+	MutedConversations []string            `json:"muted_conversations"` // 已设置免打扰的会话（通过会话接口设置）
+}
+
+// DoNotDisturbSetting 免打扰时段设置
+type DoNotDisturbSetting struct {
+	Start    string `json:"start"`    // 开始时间 HH:MM
+	End      string `json:"end"`      // 结束时间 HH:MM，早于开始时间表示跨午夜
+	Timezone string `json:"timezone"` // IANA 时区，如 Asia/Shanghai
+}
+
+// PresenceInfo 用户在线状态（对方设置不可见时只返回 visible=false）
+type PresenceInfo struct {
+	UserID   string `json:"user_id"`
+	Visible  bool   `json:"visible"`
+	Online   bool   `json:"online"`
+	LastSeen int64  `json:"last_seen,omitempty"` // 最近一次下线时间（毫秒时间戳），在线或没有记录时为空
+}
+
+// RenameField 改名字段
+type RenameField string
+
+const (
+	RenameFieldUsername RenameField = "username"
+	RenameFieldNickname RenameField = "nickname"
+)
+
+// UserRenameHistory 用户改名历史（客户端据此解析缓存消息中的旧显示名）
+type UserRenameHistory struct {
+	ID        uint64      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    string      `json:"user_id" gorm:"type:varchar(64);index:idx_user_field_time"`
+	Field     RenameField `json:"field" gorm:"type:varchar(16);index:idx_user_field_time"`
+	OldName   string      `json:"old_name" gorm:"type:varchar(64)"`
+	NewName   string      `json:"new_name" gorm:"type:varchar(64)"`
+	CreatedAt time.Time   `json:"created_at" gorm:"index:idx_user_field_time"`
+}
+
+// TableName 指定表名
+func (UserRenameHistory) TableName() string {
+	return "user_rename_history"
+}
+
+// 用户搜索模式
+const (
+	UserSearchExact = "exact" // 仅按用户名/手机号精确匹配
+	UserSearchFuzzy = "fuzzy" // 按用户名/昵称模糊匹配
+)
+
+// UserSearchConfig 用户搜索配置
+type UserSearchConfig struct {
+	Mode       string        // 搜索模式
+	MaxResults int           // 单次返回的最大结果数
+	RateLimit  int           // 每个请求者在窗口内的最大搜索次数，0表示不限制
+	RateWindow time.Duration // 限流窗口
+}
+
+// DefaultUserSearchConfig 默认用户搜索配置
+func DefaultUserSearchConfig() *UserSearchConfig {
+	return &UserSearchConfig{
+		Mode:       UserSearchExact,
+		MaxResults: 20,
+		RateLimit:  30,
+		RateWindow: time.Minute,
+	}
 }
 
 // ChangePasswordRequest 修改密码请求
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// AutoReplyRepository 自动回复设置仓库接口
+type AutoReplyRepository interface {
+	// Find 查询用户的自动回复设置，未设置时返回 nil
+	Find(ctx context.Context, userID string) (*model.AutoReplySetting, error)
+
+	// Save 保存自动回复设置
+	Save(ctx context.Context, setting *model.AutoReplySetting) error
+}
+
+// autoReplyRepository 自动回复设置仓库实现
+type autoReplyRepository struct {
+	db *gorm.DB
+}
+
+// NewAutoReplyRepository 创建自动回复设置仓库
+func NewAutoReplyRepository(db *gorm.DB) AutoReplyRepository {
+	return &autoReplyRepository{db: db}
+}
+
+// Find 查询用户的自动回复设置
+func (r *autoReplyRepository) Find(ctx context.Context, userID string) (*model.AutoReplySetting, error) {
+	var setting model.AutoReplySetting
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&setting).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// Save 保存自动回复设置
+func (r *autoReplyRepository) Save(ctx context.Context, setting *model.AutoReplySetting) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "text", "start_at", "end_at", "updated_at"}),
+	}).Create(setting).Error
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// BridgeRepository 桥接映射仓库接口
+type BridgeRepository interface {
+	// SaveRoom 保存群组与外部频道的映射（同一群组在同一平台只有一个映射）
+	SaveRoom(ctx context.Context, room *model.BridgeRoom) error
+
+	// FindRoomByID 查询频道映射，不存在时返回 nil
+	FindRoomByID(ctx context.Context, id int64) (*model.BridgeRoom, error)
+
+	// FindRoomsByGroup 查询群组的全部频道映射
+	FindRoomsByGroup(ctx context.Context, groupID string) ([]*model.BridgeRoom, error)
+
+	// FindRoomByRemote 按外部频道查询映射，不存在时返回 nil
+	FindRoomByRemote(ctx context.Context, platform, remoteRoomID string) (*model.BridgeRoom, error)
+
+	// ListRooms 查询全部频道映射
+	ListRooms(ctx context.Context) ([]*model.BridgeRoom, error)
+
+	// DeleteRoom 删除频道映射
+	DeleteRoom(ctx context.Context, id int64) error
+
+	// SaveUser 保存外部用户映射
+	SaveUser(ctx context.Context, user *model.BridgeUser) error
+
+	// FindUser 查询外部用户映射，不存在时返回 nil
+	FindUser(ctx context.Context, platform, remoteUserID string) (*model.BridgeUser, error)
+
+	// ListUsers 查询平台的外部用户映射，platform 为空时查询全部
+	ListUsers(ctx context.Context, platform string) ([]*model.BridgeUser, error)
+
+	// DeleteUser 删除外部用户映射，返回是否存在
+	DeleteUser(ctx context.Context, platform, remoteUserID string) (bool, error)
+}
+
+// bridgeRepository 桥接映射仓库实现
+type bridgeRepository struct {
+	db *gorm.DB
+}
+
+// NewBridgeRepository 创建桥接映射仓库
+func NewBridgeRepository(db *gorm.DB) BridgeRepository {
+	return &bridgeRepository{db: db}
+}
+
+// SaveRoom 保存频道映射
+func (r *bridgeRepository) SaveRoom(ctx context.Context, room *model.BridgeRoom) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_id"}, {Name: "platform"}},
+		DoUpdates: clause.AssignmentColumns([]string{"remote_room_id", "disabled", "created_by", "updated_at"}),
+	}).Create(room).Error
+}
+
+// FindRoomByID 查询频道映射
+func (r *bridgeRepository) FindRoomByID(ctx context.Context, id int64) (*model.BridgeRoom, error) {
+	var room model.BridgeRoom
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&room).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &room, nil
+}
+
+// FindRoomsByGroup 查询群组的全部频道映射
+func (r *bridgeRepository) FindRoomsByGroup(ctx context.Context, groupID string) ([]*model.BridgeRoom, error) {
+	var rooms []*model.BridgeRoom
+	err := r.db.WithContext(ctx).Where("group_id = ?", groupID).Find(&rooms).Error
+	return rooms, err
+}
+
+// FindRoomByRemote 按外部频道查询映射
+func (r *bridgeRepository) FindRoomByRemote(ctx context.Context, platform, remoteRoomID string) (*model.BridgeRoom, error) {
+	var room model.BridgeRoom
+	if err := r.db.WithContext(ctx).Where("platform = ? AND remote_room_id = ?", platform, remoteRoomID).First(&room).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &room, nil
+}
+
+// ListRooms 查询全部频道映射
+func (r *bridgeRepository) ListRooms(ctx context.Context) ([]*model.BridgeRoom, error) {
+	var rooms []*model.BridgeRoom
+	err := r.db.WithContext(ctx).Order("id").Find(&rooms).Error
+	return rooms, err
+}
+
+// DeleteRoom 删除频道映射
+func (r *bridgeRepository) DeleteRoom(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.BridgeRoom{}).Error
+}
+
+// SaveUser 保存外部用户映射
+func (r *bridgeRepository) SaveUser(ctx context.Context, user *model.BridgeUser) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "platform"}, {Name: "remote_user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "updated_at"}),
+	}).Create(user).Error
+}
+
+// FindUser 查询外部用户映射
+func (r *bridgeRepository) FindUser(ctx context.Context, platform, remoteUserID string) (*model.BridgeUser, error) {
+	var user model.BridgeUser
+	if err := r.db.WithContext(ctx).Where("platform = ? AND remote_user_id = ?", platform, remoteUserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListUsers 查询外部用户映射
+func (r *bridgeRepository) ListUsers(ctx context.Context, platform string) ([]*model.BridgeUser, error) {
+	var users []*model.BridgeUser
+	query := r.db.WithContext(ctx).Order("platform, remote_user_id")
+	if platform != "" {
+		query = query.Where("platform = ?", platform)
+	}
+	err := query.Find(&users).Error
+	return users, err
+}
+
+// DeleteUser 删除外部用户映射
+func (r *bridgeRepository) DeleteUser(ctx context.Context, platform, remoteUserID string) (bool, error) {
+	result := r.db.WithContext(ctx).Where("platform = ? AND remote_user_id = ?", platform, remoteUserID).Delete(&model.BridgeUser{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,329 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// UserConversationEntry 用户会话及会话最后一条消息（会话列表）
+type UserConversationEntry struct {
+	model.UserConversation
+	Type          int
+	LastMessageID string
+	LastMessageAt *time.Time
+}
+
+// UserConversationUpdate 用户会话设置变更，为空的字段不修改
+type UserConversationUpdate struct {
+	Pinned  *bool
+	Muted   *bool
+	Deleted *bool
+}
+
+// ConversationRepository 会话仓库接口
+type ConversationRepository interface {
+	// FindByID 查询会话，不存在时返回 nil
+	FindByID(ctx context.Context, conversationID string) (*model.Conversation, error)
+
+	// UpsertLastMessage 创建会话或更新最后一条消息（仅当消息时间不早于当前记录时更新）
+	UpsertLastMessage(ctx context.Context, conversationID string, convType int, messageID string, messageAt time.Time) error
+
+	// RecordMessage 在同一事务中更新会话最后一条消息并维护参与者的用户会话：缺少的记录自动创建，
+	// 已删除的会话重新显示，接收者未读数加一（发送者不计未读）
+	RecordMessage(ctx context.Context, conversationID string, convType int, messageID string, messageAt time.Time, senderID string, recipientIDs []string) error
+
+	// FindUserConversation 查询用户的会话设置，不存在时返回 nil
+	FindUserConversation(ctx context.Context, userID, conversationID string) (*model.UserConversation, error)
+
+	// FindUserConversations 分页查询用户未删除的会话（置顶优先，再按最后一条消息时间倒序）
+	FindUserConversations(ctx context.Context, userID string, offset, limit int) ([]*UserConversationEntry, int64, error)
+
+	// FindMutedConversationIDs 查询用户设置了免打扰的会话ID
+	FindMutedConversationIDs(ctx context.Context, userID string) ([]string, error)
+
+	// FindMutedUserIDs 查询对会话设置了免打扰的用户ID
+	FindMutedUserIDs(ctx context.Context, conversationID string) ([]string, error)
+
+	// UpdateUserConversation 修改用户的会话设置（记录不存在时创建），删除会话时未读数清零
+	UpdateUserConversation(ctx context.Context, userID, conversationID string, update *UserConversationUpdate) error
+
+	// UpdateEncryption 设置会话加密状态（会话不存在时创建），rotate 为 true 时递增密钥版本，返回更新后的会话
+	UpdateEncryption(ctx context.Context, conversationID string, convType int, encrypted, rotate bool) (*model.Conversation, error)
+
+	// FindEncrypted 分页查询开启加密的会话（按密钥轮换时间倒序）
+	FindEncrypted(ctx context.Context, offset, limit int) ([]*model.Conversation, int64, error)
+
+	// AssignRegion 设置会话的存储区域（会话不存在时创建，已确定区域时不修改），返回生效的区域
+	AssignRegion(ctx context.Context, conversationID string, convType int, region string) (string, error)
+}
+
+// conversationRepository 会话仓库实现
+type conversationRepository struct {
+	db *gorm.DB
+}
+
+// NewConversationRepository 创建会话仓库
+func NewConversationRepository(db *gorm.DB) ConversationRepository {
+	return &conversationRepository{db: db}
+}
+
+// FindByID 查询会话（兼容读取旧格式会话ID，优先返回规范格式的记录）
+func (r *conversationRepository) FindByID(ctx context.Context, conversationID string) (*model.Conversation, error) {
+	var convs []*model.Conversation
+	if err := r.db.WithContext(ctx).Where("conversation_id IN ?", conversationIDAliases(conversationID)).Find(&convs).Error; err != nil {
+		return nil, err
+	}
+	if len(convs) == 0 {
+		return nil, nil
+	}
+	canonical := model.CanonicalConversationID(conversationID)
+	for _, conv := range convs {
+		if conv.ConversationID == canonical {
+			return conv, nil
+		}
+	}
+	return convs[0], nil
+}
+
+// UpsertLastMessage 创建会话或更新最后一条消息
+func (r *conversationRepository) UpsertLastMessage(ctx context.Context, conversationID string, convType int, messageID string, messageAt time.Time) error {
+	return upsertLastMessage(r.db.WithContext(ctx), conversationID, convType, messageID, messageAt)
+}
+
+// upsertLastMessage 创建会话或更新最后一条消息（仅当消息时间不早于当前记录时更新）
+func upsertLastMessage(db *gorm.DB, conversationID string, convType int, messageID string, messageAt time.Time) error {
+	conv := &model.Conversation{
+		ConversationID: model.CanonicalConversationID(conversationID),
+		Type:           convType,
+		LastMessageID:  messageID,
+		LastMessageAt:  messageAt,
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "conversation_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"last_message_id": gorm.Expr("IF(last_message_at IS NULL OR VALUES(last_message_at) >= last_message_at, VALUES(last_message_id), last_message_id)"),
+			"last_message_at": gorm.Expr("GREATEST(COALESCE(last_message_at, VALUES(last_message_at)), VALUES(last_message_at))"),
+			"updated_at":      time.Now(),
+		}),
+	}).Create(conv).Error
+}
+
+// RecordMessage 更新会话最后一条消息并维护参与者的用户会话（大群按批写入）
+func (r *conversationRepository) RecordMessage(ctx context.Context, conversationID string, convType int, messageID string, messageAt time.Time, senderID string, recipientIDs []string) error {
+	conversationID = model.CanonicalConversationID(conversationID)
+	now := time.Now()
+	rows := make([]*model.UserConversation, 0, len(recipientIDs)+1)
+	if senderID != "" {
+		rows = append(rows, &model.UserConversation{UserID: senderID, ConversationID: conversationID, CreatedAt: now, UpdatedAt: now})
+	}
+	for _, userID := range recipientIDs {
+		if userID == "" || userID == senderID {
+			continue
+		}
+		rows = append(rows, &model.UserConversation{UserID: userID, ConversationID: conversationID, UnreadCount: 1, CreatedAt: now, UpdatedAt: now})
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := upsertLastMessage(tx, conversationID, convType, messageID, messageAt); err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}, {Name: "conversation_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"unread_count": gorm.Expr("unread_count + VALUES(unread_count)"),
+				"deleted":      false,
+				"updated_at":   now,
+			}),
+		}).CreateInBatches(rows, 500).Error
+	})
+}
+
+// FindUserConversation 查询用户的会话设置（兼容读取旧格式会话ID，优先返回规范格式的记录）
+func (r *conversationRepository) FindUserConversation(ctx context.Context, userID, conversationID string) (*model.UserConversation, error) {
+	var ucs []*model.UserConversation
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND conversation_id IN ?", userID, conversationIDAliases(conversationID)).
+		Find(&ucs).Error; err != nil {
+		return nil, err
+	}
+	if len(ucs) == 0 {
+		return nil, nil
+	}
+	canonical := model.CanonicalConversationID(conversationID)
+	for _, uc := range ucs {
+		if uc.ConversationID == canonical {
+			return uc, nil
+		}
+	}
+	return ucs[0], nil
+}
+
+// FindUserConversations 分页查询用户未删除的会话
+func (r *conversationRepository) FindUserConversations(ctx context.Context, userID string, offset, limit int) ([]*UserConversationEntry, int64, error) {
+	query := r.db.WithContext(ctx).Table("user_conversations AS uc").
+		Where("uc.user_id = ? AND uc.deleted = ?", userID, false)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []*UserConversationEntry
+	if err := query.
+		Select("uc.*, c.type, c.last_message_id, c.last_message_at").
+		Joins("LEFT JOIN conversations AS c ON c.conversation_id = uc.conversation_id").
+		Order("uc.pinned DESC, c.last_message_at DESC, uc.updated_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// FindMutedConversationIDs 查询用户设置了免打扰的会话ID（旧格式会话ID转换为规范格式）
+func (r *conversationRepository) FindMutedConversationIDs(ctx context.Context, userID string) ([]string, error) {
+	var ids []string
+	if err := r.db.WithContext(ctx).Model(&model.UserConversation{}).
+		Where("user_id = ? AND muted = ?", userID, true).
+		Pluck("conversation_id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		canonical := model.CanonicalConversationID(id)
+		if !seen[canonical] {
+			seen[canonical] = true
+			result = append(result, canonical)
+		}
+	}
+	return result, nil
+}
+
+// FindMutedUserIDs 查询对会话设置了免打扰的用户ID（兼容旧格式会话ID）
+func (r *conversationRepository) FindMutedUserIDs(ctx context.Context, conversationID string) ([]string, error) {
+	var userIDs []string
+	err := r.db.WithContext(ctx).Model(&model.UserConversation{}).
+		Where("conversation_id IN ? AND muted = ?", conversationIDAliases(conversationID), true).
+		Distinct().Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// UpdateUserConversation 修改用户的会话设置
+func (r *conversationRepository) UpdateUserConversation(ctx context.Context, userID, conversationID string, update *UserConversationUpdate) error {
+	now := time.Now()
+	uc := &model.UserConversation{
+		UserID:         userID,
+		ConversationID: model.CanonicalConversationID(conversationID),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	assignments := map[string]interface{}{"updated_at": now}
+	if update.Pinned != nil {
+		uc.Pinned = *update.Pinned
+		assignments["pinned"] = *update.Pinned
+	}
+	if update.Muted != nil {
+		uc.Muted = *update.Muted
+		assignments["muted"] = *update.Muted
+	}
+	if update.Deleted != nil {
+		uc.Deleted = *update.Deleted
+		assignments["deleted"] = *update.Deleted
+		if *update.Deleted {
+			assignments["unread_count"] = 0
+		}
+	}
+
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "conversation_id"}},
+		DoUpdates: clause.Assignments(assignments),
+	}).Create(uc).Error
+}
+
+// UpdateEncryption 设置会话加密状态（会话可能在开启加密时还没有消息，此时不写入最后一条消息）
+func (r *conversationRepository) UpdateEncryption(ctx context.Context, conversationID string, convType int, encrypted, rotate bool) (*model.Conversation, error) {
+	now := time.Now()
+	conv := &model.Conversation{
+		ConversationID: model.CanonicalConversationID(conversationID),
+		Type:           convType,
+		Encrypted:      encrypted,
+	}
+	assignments := map[string]interface{}{
+		"encrypted":  encrypted,
+		"updated_at": now,
+	}
+	if rotate {
+		conv.KeyVersion = 1
+		conv.KeyRotatedAt = &now
+		assignments["key_version"] = gorm.Expr("key_version + 1")
+		assignments["key_rotated_at"] = now
+	}
+
+	if err := r.db.WithContext(ctx).Omit("last_message_id", "last_message_at").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "conversation_id"}},
+		DoUpdates: clause.Assignments(assignments),
+	}).Create(conv).Error; err != nil {
+		return nil, err
+	}
+	return r.FindByID(ctx, conversationID)
+}
+
+// FindEncrypted 分页查询开启加密的会话
+func (r *conversationRepository) FindEncrypted(ctx context.Context, offset, limit int) ([]*model.Conversation, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Conversation{}).Where("encrypted = ?", true)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var convs []*model.Conversation
+	if err := query.Order("key_rotated_at DESC").Offset(offset).Limit(limit).Find(&convs).Error; err != nil {
+		return nil, 0, err
+	}
+	return convs, total, nil
+}
+
+// AssignRegion 设置会话的存储区域
+func (r *conversationRepository) AssignRegion(ctx context.Context, conversationID string, convType int, region string) (string, error) {
+	conv := &model.Conversation{
+		ConversationID: model.CanonicalConversationID(conversationID),
+		Type:           convType,
+		Region:         region,
+	}
+	if err := r.db.WithContext(ctx).Omit("last_message_id", "last_message_at").Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "conversation_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"region": gorm.Expr("IF(region IS NULL OR region = '', VALUES(region), region)"),
+		}),
+	}).Create(conv).Error; err != nil {
+		return "", err
+	}
+
+	stored, err := r.FindByID(ctx, conversationID)
+	if err != nil {
+		return "", err
+	}
+	if stored == nil {
+		return region, nil
+	}
+	return stored.Region, nil
+}
+
+// conversationIDAliases 会话ID的全部存储形式，数据迁移完成前兼容读取旧格式会话ID
+func conversationIDAliases(conversationID string) []string {
+	if convID, err := model.ParseConversationID(conversationID); err == nil {
+		return convID.Aliases()
+	}
+	return []string{conversationID}
+}
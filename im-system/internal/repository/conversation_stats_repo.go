@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// ConversationStatsDelta 会话统计增量
+type ConversationStatsDelta struct {
+	ConversationID string
+	Type           int
+	Messages       int64
+	Senders        map[string]int64 // 发送者 -> 消息数
+	LastActivityAt time.Time
+}
+
+// ConversationStatsFilter 会话统计查询条件
+type ConversationStatsFilter struct {
+	Type        int       // 会话类型，0 表示全部
+	ActiveSince time.Time // 最后活跃时间下限，零值表示不限
+	OrderBy     string    // message_count / participant_count / last_activity_at
+	Offset      int
+	Limit       int
+}
+
+// ConversationStatsTotal 按会话类型汇总的统计
+type ConversationStatsTotal struct {
+	Type          int   `json:"type"`
+	Conversations int64 `json:"conversations"`
+	Messages      int64 `json:"messages"`
+}
+
+// ConversationStatsRepository 会话聚合统计仓库接口
+type ConversationStatsRepository interface {
+	// ApplyDelta 累加会话统计增量（消息数、新增参与者、最后活跃时间）
+	ApplyDelta(ctx context.Context, delta *ConversationStatsDelta) error
+
+	// FindByID 查询会话统计，不存在时返回 nil
+	FindByID(ctx context.Context, conversationID string) (*model.ConversationStats, error)
+
+	// List 分页查询会话统计
+	List(ctx context.Context, filter *ConversationStatsFilter) ([]*model.ConversationStats, int64, error)
+
+	// Totals 按会话类型汇总，activeSince 非零时只统计该时间之后活跃的会话
+	Totals(ctx context.Context, activeSince time.Time) ([]*ConversationStatsTotal, error)
+}
+
+// conversationStatsRepository 会话聚合统计仓库实现
+type conversationStatsRepository struct {
+	db *gorm.DB
+}
+
+// NewConversationStatsRepository 创建会话聚合统计仓库
+func NewConversationStatsRepository(db *gorm.DB) ConversationStatsRepository {
+	return &conversationStatsRepository{db: db}
+}
+
+// ApplyDelta 累加会话统计增量
+func (r *conversationStatsRepository) ApplyDelta(ctx context.Context, delta *ConversationStatsDelta) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 参与者首次出现时计入参与人数
+		newParticipants := 0
+		for userID, count := range delta.Senders {
+			participant := &model.ConversationParticipantStats{
+				ConversationID: delta.ConversationID,
+				UserID:         userID,
+				MessageCount:   count,
+				LastActiveAt:   delta.LastActivityAt,
+			}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(participant)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				newParticipants++
+				continue
+			}
+
+			if err := tx.Model(&model.ConversationParticipantStats{}).
+				Where("conversation_id = ? AND user_id = ?", delta.ConversationID, userID).
+				Updates(map[string]interface{}{
+					"message_count":  gorm.Expr("message_count + ?", count),
+					"last_active_at": gorm.Expr("GREATEST(last_active_at, ?)", delta.LastActivityAt),
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		stats := &model.ConversationStats{
+			ConversationID:   delta.ConversationID,
+			Type:             delta.Type,
+			MessageCount:     delta.Messages,
+			ParticipantCount: newParticipants,
+			LastActivityAt:   delta.LastActivityAt,
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "conversation_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"message_count":     gorm.Expr("message_count + VALUES(message_count)"),
+				"participant_count": gorm.Expr("participant_count + VALUES(participant_count)"),
+				"last_activity_at":  gorm.Expr("GREATEST(last_activity_at, VALUES(last_activity_at))"),
+				"updated_at":        time.Now(),
+			}),
+		}).Create(stats).Error
+	})
+}
+
+// FindByID 查询会话统计
+func (r *conversationStatsRepository) FindByID(ctx context.Context, conversationID string) (*model.ConversationStats, error) {
+	var stats model.ConversationStats
+	if err := r.db.WithContext(ctx).Where("conversation_id = ?", conversationID).First(&stats).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// List 分页查询会话统计
+func (r *conversationStatsRepository) List(ctx context.Context, filter *ConversationStatsFilter) ([]*model.ConversationStats, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.ConversationStats{})
+	if filter.Type > 0 {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if !filter.ActiveSince.IsZero() {
+		query = query.Where("last_activity_at >= ?", filter.ActiveSince)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	orderBy := "last_activity_at"
+	switch filter.OrderBy {
+	case "message_count", "participant_count":
+		orderBy = filter.OrderBy
+	}
+
+	var stats []*model.ConversationStats
+	if err := query.
+		Order(orderBy + " DESC").
+		Offset(filter.Offset).
+		Limit(filter.Limit).
+		Find(&stats).Error; err != nil {
+		return nil, 0, err
+	}
+	return stats, total, nil
+}
+
+// Totals 按会话类型汇总
+func (r *conversationStatsRepository) Totals(ctx context.Context, activeSince time.Time) ([]*ConversationStatsTotal, error) {
+	query := r.db.WithContext(ctx).Model(&model.ConversationStats{}).
+		Select("type, COUNT(*) AS conversations, COALESCE(SUM(message_count), 0) AS messages")
+	if !activeSince.IsZero() {
+		query = query.Where("last_activity_at >= ?", activeSince)
+	}
+
+	var totals []*ConversationStatsTotal
+	if err := query.Group("type").Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// ConversationSummaryRepository 会话摘要仓库接口
+type ConversationSummaryRepository interface {
+	// Create 保存摘要
+	Create(ctx context.Context, summary *model.ConversationSummary) error
+
+	// FindByUser 查询用户在会话中生成的摘要（按生成时间倒序）
+	FindByUser(ctx context.Context, conversationID, userID string, limit int) ([]*model.ConversationSummary, error)
+}
+
+// conversationSummaryRepository 会话摘要仓库实现
+type conversationSummaryRepository struct {
+	db *gorm.DB
+}
+
+// NewConversationSummaryRepository 创建会话摘要仓库
+func NewConversationSummaryRepository(db *gorm.DB) ConversationSummaryRepository {
+	return &conversationSummaryRepository{db: db}
+}
+
+// Create 保存摘要
+func (r *conversationSummaryRepository) Create(ctx context.Context, summary *model.ConversationSummary) error {
+	return r.db.WithContext(ctx).Create(summary).Error
+}
+
+// FindByUser 查询用户在会话中生成的摘要
+func (r *conversationSummaryRepository) FindByUser(ctx context.Context, conversationID, userID string, limit int) ([]*model.ConversationSummary, error) {
+	var summaries []*model.ConversationSummary
+	err := r.db.WithContext(ctx).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&summaries).Error
+	return summaries, err
+}
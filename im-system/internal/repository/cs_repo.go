@@ -0,0 +1,319 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// CustomerServiceRepository 客服仓库接口
+type CustomerServiceRepository interface {
+	// SaveAgent 添加坐席，已存在时只更新接待上限
+	SaveAgent(ctx context.Context, agent *model.CSAgent) error
+
+	// FindAgent 查询坐席，不存在时返回 nil
+	FindAgent(ctx context.Context, userID string) (*model.CSAgent, error)
+
+	// ListAgents 查询全部坐席
+	ListAgents(ctx context.Context) ([]*model.CSAgent, error)
+
+	// DeleteAgent 删除坐席，不存在时返回 false
+	DeleteAgent(ctx context.Context, userID string) (bool, error)
+
+	// SetAgentStatus 设置坐席状态，不存在时返回 false
+	SetAgentStatus(ctx context.Context, userID string, status model.CSAgentStatus) (bool, error)
+
+	// FindAvailableAgents 查询在线且未满的坐席（按接待数升序）
+	FindAvailableAgents(ctx context.Context) ([]*model.CSAgent, error)
+
+	// AcquireAgent 坐席在线且未满时占用一个接待名额，返回是否成功
+	AcquireAgent(ctx context.Context, userID string) (bool, error)
+
+	// ReleaseAgent 释放坐席的一个接待名额
+	ReleaseAgent(ctx context.Context, userID string) error
+
+	// CreateSession 创建客服会话
+	CreateSession(ctx context.Context, session *model.CSSession) error
+
+	// FindSession 查询客服会话，不存在时返回 nil
+	FindSession(ctx context.Context, sessionID string) (*model.CSSession, error)
+
+	// FindOpenSession 查询访客排队中或接待中的会话，不存在时返回 nil
+	FindOpenSession(ctx context.Context, visitorID string) (*model.CSSession, error)
+
+	// ExistsActiveSession 访客与坐席之间是否有接待中的会话
+	ExistsActiveSession(ctx context.Context, visitorID, agentID string) (bool, error)
+
+	// ListQueued 查询排队中的会话（按排队时间升序）
+	ListQueued(ctx context.Context, limit int) ([]*model.CSSession, error)
+
+	// CountQueued 统计排队中的会话数，before 非零时只统计之前排队的会话
+	CountQueued(ctx context.Context, before time.Time) (int64, error)
+
+	// ListAgentSessions 分页查询坐席的会话（按更新时间倒序）
+	ListAgentSessions(ctx context.Context, agentID string, status model.CSSessionStatus, offset, limit int) ([]*model.CSSession, int64, error)
+
+	// AssignSession 仅当会话状态为 from 且坐席为 fromAgentID 时分配给 toAgentID，转接时累加转接次数，返回是否更新成功
+	AssignSession(ctx context.Context, sessionID string, from model.CSSessionStatus, fromAgentID, toAgentID, conversationID string) (bool, error)
+
+	// CloseSession 结束排队中或接待中的会话，返回是否更新成功
+	CloseSession(ctx context.Context, sessionID, closedBy string) (bool, error)
+
+	// RateSession 评价已结束且未评价的会话，返回是否更新成功
+	RateSession(ctx context.Context, sessionID string, rating int, comment string) (bool, error)
+
+	// CreateCannedReply 创建快捷回复
+	CreateCannedReply(ctx context.Context, reply *model.CSCannedReply) error
+
+	// UpdateCannedReply 更新坐席（agentID 为空时为公共）的快捷回复，不存在时返回 false
+	UpdateCannedReply(ctx context.Context, id uint, agentID, title, content string) (bool, error)
+
+	// DeleteCannedReply 删除坐席（agentID 为空时为公共）的快捷回复，不存在时返回 false
+	DeleteCannedReply(ctx context.Context, id uint, agentID string) (bool, error)
+
+	// ListCannedReplies 查询公共快捷回复及坐席自己的快捷回复（agentID 为空时只查询公共）
+	ListCannedReplies(ctx context.Context, agentID string) ([]*model.CSCannedReply, error)
+}
+
+// customerServiceRepository 客服仓库实现
+type customerServiceRepository struct {
+	db *gorm.DB
+}
+
+// NewCustomerServiceRepository 创建客服仓库
+func NewCustomerServiceRepository(db *gorm.DB) CustomerServiceRepository {
+	return &customerServiceRepository{db: db}
+}
+
+// SaveAgent 添加或更新坐席
+func (r *customerServiceRepository) SaveAgent(ctx context.Context, agent *model.CSAgent) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_concurrent", "updated_at"}),
+	}).Create(agent).Error
+}
+
+// FindAgent 查询坐席
+func (r *customerServiceRepository) FindAgent(ctx context.Context, userID string) (*model.CSAgent, error) {
+	var agent model.CSAgent
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&agent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// ListAgents 查询全部坐席
+func (r *customerServiceRepository) ListAgents(ctx context.Context) ([]*model.CSAgent, error) {
+	var agents []*model.CSAgent
+	err := r.db.WithContext(ctx).Order("created_at ASC").Find(&agents).Error
+	return agents, err
+}
+
+// DeleteAgent 删除坐席
+func (r *customerServiceRepository) DeleteAgent(ctx context.Context, userID string) (bool, error) {
+	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.CSAgent{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// SetAgentStatus 设置坐席状态
+func (r *customerServiceRepository) SetAgentStatus(ctx context.Context, userID string, status model.CSAgentStatus) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.CSAgent{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count == 0 {
+		return false, nil
+	}
+	err := r.db.WithContext(ctx).Model(&model.CSAgent{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"status": status, "updated_at": time.Now()}).Error
+	return err == nil, err
+}
+
+// FindAvailableAgents 查询在线且未满的坐席
+func (r *customerServiceRepository) FindAvailableAgents(ctx context.Context) ([]*model.CSAgent, error) {
+	var agents []*model.CSAgent
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND active_count < max_concurrent", model.CSAgentOnline).
+		Order("active_count ASC, updated_at ASC").
+		Find(&agents).Error
+	return agents, err
+}
+
+// AcquireAgent 条件占用接待名额
+func (r *customerServiceRepository) AcquireAgent(ctx context.Context, userID string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.CSAgent{}).
+		Where("user_id = ? AND status = ? AND active_count < max_concurrent", userID, model.CSAgentOnline).
+		Update("active_count", gorm.Expr("active_count + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReleaseAgent 释放接待名额
+func (r *customerServiceRepository) ReleaseAgent(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).Model(&model.CSAgent{}).
+		Where("user_id = ? AND active_count > 0", userID).
+		Update("active_count", gorm.Expr("active_count - 1")).Error
+}
+
+// CreateSession 创建客服会话
+func (r *customerServiceRepository) CreateSession(ctx context.Context, session *model.CSSession) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+// FindSession 查询客服会话
+func (r *customerServiceRepository) FindSession(ctx context.Context, sessionID string) (*model.CSSession, error) {
+	return r.findSession(ctx, "session_id = ?", sessionID)
+}
+
+// FindOpenSession 查询访客未结束的会话
+func (r *customerServiceRepository) FindOpenSession(ctx context.Context, visitorID string) (*model.CSSession, error) {
+	return r.findSession(ctx, "visitor_id = ? AND status IN ?", visitorID, []model.CSSessionStatus{model.CSSessionQueued, model.CSSessionActive})
+}
+
+// findSession 按条件查询一条会话
+func (r *customerServiceRepository) findSession(ctx context.Context, query string, args ...interface{}) (*model.CSSession, error) {
+	var session model.CSSession
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ExistsActiveSession 访客与坐席之间是否有接待中的会话
+func (r *customerServiceRepository) ExistsActiveSession(ctx context.Context, visitorID, agentID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.CSSession{}).
+		Where("visitor_id = ? AND agent_id = ? AND status = ?", visitorID, agentID, model.CSSessionActive).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListQueued 查询排队中的会话
+func (r *customerServiceRepository) ListQueued(ctx context.Context, limit int) ([]*model.CSSession, error) {
+	var sessions []*model.CSSession
+	err := r.db.WithContext(ctx).
+		Where("status = ?", model.CSSessionQueued).
+		Order("queued_at ASC").
+		Limit(limit).
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// CountQueued 统计排队中的会话数
+func (r *customerServiceRepository) CountQueued(ctx context.Context, before time.Time) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.CSSession{}).Where("status = ?", model.CSSessionQueued)
+	if !before.IsZero() {
+		query = query.Where("queued_at < ?", before)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// ListAgentSessions 分页查询坐席的会话
+func (r *customerServiceRepository) ListAgentSessions(ctx context.Context, agentID string, status model.CSSessionStatus, offset, limit int) ([]*model.CSSession, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&model.CSSession{}).
+		Where("agent_id = ? AND status = ?", agentID, status).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var sessions []*model.CSSession
+	if err := r.db.WithContext(ctx).
+		Where("agent_id = ? AND status = ?", agentID, status).
+		Order("updated_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&sessions).Error; err != nil {
+		return nil, 0, err
+	}
+	return sessions, total, nil
+}
+
+// AssignSession 条件分配会话
+func (r *customerServiceRepository) AssignSession(ctx context.Context, sessionID string, from model.CSSessionStatus, fromAgentID, toAgentID, conversationID string) (bool, error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":          model.CSSessionActive,
+		"agent_id":        toAgentID,
+		"conversation_id": conversationID,
+		"assigned_at":     now,
+		"updated_at":      now,
+	}
+	if from == model.CSSessionActive {
+		updates["transfer_count"] = gorm.Expr("transfer_count + 1")
+	}
+	result := r.db.WithContext(ctx).Model(&model.CSSession{}).
+		Where("session_id = ? AND status = ? AND agent_id = ?", sessionID, from, fromAgentID).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CloseSession 条件结束会话
+func (r *customerServiceRepository) CloseSession(ctx context.Context, sessionID, closedBy string) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&model.CSSession{}).
+		Where("session_id = ? AND status IN ?", sessionID, []model.CSSessionStatus{model.CSSessionQueued, model.CSSessionActive}).
+		Updates(map[string]interface{}{
+			"status":     model.CSSessionClosed,
+			"closed_by":  closedBy,
+			"closed_at":  now,
+			"updated_at": now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RateSession 条件评价会话
+func (r *customerServiceRepository) RateSession(ctx context.Context, sessionID string, rating int, comment string) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&model.CSSession{}).
+		Where("session_id = ? AND status = ? AND rating = 0", sessionID, model.CSSessionClosed).
+		Updates(map[string]interface{}{
+			"rating":         rating,
+			"rating_comment": comment,
+			"rated_at":       now,
+			"updated_at":     now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CreateCannedReply 创建快捷回复
+func (r *customerServiceRepository) CreateCannedReply(ctx context.Context, reply *model.CSCannedReply) error {
+	return r.db.WithContext(ctx).Create(reply).Error
+}
+
+// UpdateCannedReply 更新快捷回复
+func (r *customerServiceRepository) UpdateCannedReply(ctx context.Context, id uint, agentID, title, content string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.CSCannedReply{}).
+		Where("id = ? AND agent_id = ?", id, agentID).
+		Updates(map[string]interface{}{"title": title, "content": content, "updated_at": time.Now()})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteCannedReply 删除快捷回复
+func (r *customerServiceRepository) DeleteCannedReply(ctx context.Context, id uint, agentID string) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND agent_id = ?", id, agentID).Delete(&model.CSCannedReply{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ListCannedReplies 查询快捷回复
+func (r *customerServiceRepository) ListCannedReplies(ctx context.Context, agentID string) ([]*model.CSCannedReply, error) {
+	var replies []*model.CSCannedReply
+	err := r.db.WithContext(ctx).
+		Where("agent_id IN ?", []string{"", agentID}).
+		Order("agent_id ASC, id ASC").
+		Find(&replies).Error
+	return replies, err
+}
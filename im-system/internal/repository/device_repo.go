@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// DeviceRepository 推送设备仓库接口
+type DeviceRepository interface {
+	// Upsert 按设备Token创建或更新设备（Token换绑到新用户时覆盖归属）
+	Upsert(ctx context.Context, device *model.Device) error
+
+	// FindPushEnabled 查询用户开启推送的设备
+	FindPushEnabled(ctx context.Context, userID string) ([]*model.Device, error)
+
+	// UpdateToken 更新设备Token，返回受影响的行数
+	UpdateToken(ctx context.Context, oldToken, newToken string) (int64, error)
+
+	// Delete 删除用户的设备
+	Delete(ctx context.Context, userID, deviceToken string) error
+
+	// DeleteByToken 按Token删除设备（无效Token清理）
+	DeleteByToken(ctx context.Context, deviceToken string) error
+
+	// CountByPlatform 按平台统计设备数
+	CountByPlatform(ctx context.Context, platform model.Platform) (int64, error)
+}
+
+// deviceRepository 推送设备仓库实现
+type deviceRepository struct {
+	db *gorm.DB
+}
+
+// NewDeviceRepository 创建推送设备仓库
+func NewDeviceRepository(db *gorm.DB) DeviceRepository {
+	return &deviceRepository{db: db}
+}
+
+// Upsert 创建或更新设备
+func (r *deviceRepository) Upsert(ctx context.Context, device *model.Device) error {
+	return r.db.WithContext(ctx).Where("device_token = ?", device.DeviceToken).
+		Assign(model.Device{
+			UserID:     device.UserID,
+			Platform:   device.Platform,
+			AppVersion: device.AppVersion,
+			DeviceInfo: device.DeviceInfo,
+			UpdatedAt:  time.Now(),
+
+			Subscription: device.Subscription,
+		}).
+		FirstOrCreate(device).Error
+}
+
+// FindPushEnabled 查询用户开启推送的设备
+func (r *deviceRepository) FindPushEnabled(ctx context.Context, userID string) ([]*model.Device, error) {
+	var devices []*model.Device
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND push_enabled = ?", userID, true).
+		Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// UpdateToken 更新设备Token
+func (r *deviceRepository) UpdateToken(ctx context.Context, oldToken, newToken string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&model.Device{}).
+		Where("device_token = ?", oldToken).
+		Update("device_token", newToken)
+	return result.RowsAffected, result.Error
+}
+
+// Delete 删除用户的设备
+func (r *deviceRepository) Delete(ctx context.Context, userID, deviceToken string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND device_token = ?", userID, deviceToken).
+		Delete(&model.Device{}).Error
+}
+
+// DeleteByToken 按Token删除设备
+func (r *deviceRepository) DeleteByToken(ctx context.Context, deviceToken string) error {
+	return r.db.WithContext(ctx).Where("device_token = ?", deviceToken).Delete(&model.Device{}).Error
+}
+
+// CountByPlatform 按平台统计设备数
+func (r *deviceRepository) CountByPlatform(ctx context.Context, platform model.Platform) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Device{}).Where("platform = ?", platform).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// E2EEPreKeyCount 设备剩余一次性预密钥数
+type E2EEPreKeyCount struct {
+	DeviceID string
+	Count    int64
+}
+
+// E2EEKeyRepository 端到端加密公钥仓库接口
+type E2EEKeyRepository interface {
+	// SaveDevice 创建或更新设备公钥，resetPreKeys 为 true 时同时删除该设备未使用的一次性预密钥
+	SaveDevice(ctx context.Context, device *model.E2EEDeviceKey, resetPreKeys bool) error
+
+	// FindDevice 查询设备公钥，不存在时返回 nil
+	FindDevice(ctx context.Context, userID, deviceID string) (*model.E2EEDeviceKey, error)
+
+	// FindDevices 查询用户全部设备公钥
+	FindDevices(ctx context.Context, userID string) ([]*model.E2EEDeviceKey, error)
+
+	// DeleteDevice 删除设备公钥及其一次性预密钥，返回设备是否存在
+	DeleteDevice(ctx context.Context, userID, deviceID string) (bool, error)
+
+	// AddPreKeys 添加一次性预密钥（同一设备已存在的 key_id 忽略）
+	AddPreKeys(ctx context.Context, preKeys []*model.E2EEOneTimePreKey) error
+
+	// ClaimPreKey 取出并删除设备的一个一次性预密钥，已耗尽时返回 nil
+	ClaimPreKey(ctx context.Context, userID, deviceID string) (*model.E2EEOneTimePreKey, error)
+
+	// CountPreKeys 统计用户各设备剩余的一次性预密钥数
+	CountPreKeys(ctx context.Context, userID string) ([]*E2EEPreKeyCount, error)
+}
+
+// e2eeKeyRepository 端到端加密公钥仓库实现
+type e2eeKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewE2EEKeyRepository 创建端到端加密公钥仓库
+func NewE2EEKeyRepository(db *gorm.DB) E2EEKeyRepository {
+	return &e2eeKeyRepository{db: db}
+}
+
+// SaveDevice 创建或更新设备公钥
+func (r *e2eeKeyRepository) SaveDevice(ctx context.Context, device *model.E2EEDeviceKey, resetPreKeys bool) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if resetPreKeys {
+			if err := tx.Where("user_id = ? AND device_id = ?", device.UserID, device.DeviceID).
+				Delete(&model.E2EEOneTimePreKey{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "device_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"identity_key", "signed_pre_key_id", "signed_pre_key", "signed_pre_key_signature", "updated_at"}),
+		}).Create(device).Error
+	})
+}
+
+// FindDevice 查询设备公钥
+func (r *e2eeKeyRepository) FindDevice(ctx context.Context, userID, deviceID string) (*model.E2EEDeviceKey, error) {
+	var device model.E2EEDeviceKey
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND device_id = ?", userID, deviceID).First(&device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+// FindDevices 查询用户全部设备公钥
+func (r *e2eeKeyRepository) FindDevices(ctx context.Context, userID string) ([]*model.E2EEDeviceKey, error) {
+	var devices []*model.E2EEDeviceKey
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// DeleteDevice 删除设备公钥及其一次性预密钥
+func (r *e2eeKeyRepository) DeleteDevice(ctx context.Context, userID, deviceID string) (bool, error) {
+	var deleted bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND device_id = ?", userID, deviceID).
+			Delete(&model.E2EEOneTimePreKey{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("user_id = ? AND device_id = ?", userID, deviceID).Delete(&model.E2EEDeviceKey{})
+		deleted = result.RowsAffected > 0
+		return result.Error
+	})
+	return deleted, err
+}
+
+// AddPreKeys 添加一次性预密钥
+func (r *e2eeKeyRepository) AddPreKeys(ctx context.Context, preKeys []*model.E2EEOneTimePreKey) error {
+	if len(preKeys) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(preKeys, 100).Error
+}
+
+// ClaimPreKey 取出并删除设备的一个一次性预密钥
+// 先查询最早的预密钥再按主键删除，删除失败说明已被并发请求取走，重试下一个
+func (r *e2eeKeyRepository) ClaimPreKey(ctx context.Context, userID, deviceID string) (*model.E2EEOneTimePreKey, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		var preKey model.E2EEOneTimePreKey
+		if err := r.db.WithContext(ctx).
+			Where("user_id = ? AND device_id = ?", userID, deviceID).
+			Order("id").
+			First(&preKey).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		result := r.db.WithContext(ctx).Where("id = ?", preKey.ID).Delete(&model.E2EEOneTimePreKey{})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			return &preKey, nil
+		}
+	}
+	return nil, nil
+}
+
+// CountPreKeys 统计用户各设备剩余的一次性预密钥数
+func (r *e2eeKeyRepository) CountPreKeys(ctx context.Context, userID string) ([]*E2EEPreKeyCount, error) {
+	var counts []*E2EEPreKeyCount
+	if err := r.db.WithContext(ctx).Model(&model.E2EEOneTimePreKey{}).
+		Select("device_id, COUNT(*) AS count").
+		Where("user_id = ?", userID).
+		Group("device_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
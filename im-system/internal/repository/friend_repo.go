@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// FriendRepository 好友仓库接口
+type FriendRepository interface {
+	// SaveRequest 保存好友申请，同一对用户已有申请时覆盖为新的待处理申请
+	SaveRequest(ctx context.Context, req *model.FriendRequest) error
+
+	// FindRequest 查询好友申请，不存在时返回 nil
+	FindRequest(ctx context.Context, requestID string) (*model.FriendRequest, error)
+
+	// FindRequestBetween 查询 fromUserID 发给 toUserID 的申请，不存在时返回 nil
+	FindRequestBetween(ctx context.Context, fromUserID, toUserID string) (*model.FriendRequest, error)
+
+	// ListRequests 分页查询用户收到（outgoing 为 true 时为发出）的申请，按更新时间倒序
+	ListRequests(ctx context.Context, userID string, outgoing bool, offset, limit int) ([]*model.FriendRequest, int64, error)
+
+	// TransitionRequest 仅当申请状态为 from 时更新为 to，返回是否更新成功
+	TransitionRequest(ctx context.Context, requestID string, from, to model.FriendRequestStatus) (bool, error)
+
+	// AddFriend 建立双向好友关系（已是好友时忽略）
+	AddFriend(ctx context.Context, userID, friendID string) error
+
+	// RemoveFriend 解除双向好友关系，不是好友时返回 false
+	RemoveFriend(ctx context.Context, userID, friendID string) (bool, error)
+
+	// ListFriends 查询用户的好友（按添加时间倒序）
+	ListFriends(ctx context.Context, userID string) ([]*model.Friend, error)
+
+	// FindFriendIDs 查询用户的好友ID
+	FindFriendIDs(ctx context.Context, userID string) ([]string, error)
+
+	// Block 屏蔽用户，已屏蔽时返回 false
+	Block(ctx context.Context, userID, blockedID string) (bool, error)
+
+	// Unblock 取消屏蔽，未屏蔽时返回 false
+	Unblock(ctx context.Context, userID, blockedID string) (bool, error)
+
+	// ListBlocked 查询用户屏蔽的用户（按屏蔽时间倒序）
+	ListBlocked(ctx context.Context, userID string) ([]*model.UserBlock, error)
+
+	// FindBlockedIDs 查询用户屏蔽的用户ID
+	FindBlockedIDs(ctx context.Context, userID string) ([]string, error)
+}
+
+// friendRepository 好友仓库实现
+type friendRepository struct {
+	db *gorm.DB
+}
+
+// NewFriendRepository 创建好友仓库
+func NewFriendRepository(db *gorm.DB) FriendRepository {
+	return &friendRepository{db: db}
+}
+
+// SaveRequest 保存好友申请
+func (r *friendRepository) SaveRequest(ctx context.Context, req *model.FriendRequest) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "from_user_id"}, {Name: "to_user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"request_id", "message", "status", "created_at", "updated_at"}),
+	}).Create(req).Error
+}
+
+// FindRequest 查询好友申请
+func (r *friendRepository) FindRequest(ctx context.Context, requestID string) (*model.FriendRequest, error) {
+	return r.findRequest(ctx, "request_id = ?", requestID)
+}
+
+// FindRequestBetween 查询两个用户之间的申请
+func (r *friendRepository) FindRequestBetween(ctx context.Context, fromUserID, toUserID string) (*model.FriendRequest, error) {
+	return r.findRequest(ctx, "from_user_id = ? AND to_user_id = ?", fromUserID, toUserID)
+}
+
+// findRequest 按条件查询一条申请
+func (r *friendRepository) findRequest(ctx context.Context, query string, args ...interface{}) (*model.FriendRequest, error) {
+	var req model.FriendRequest
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&req).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+// ListRequests 分页查询好友申请
+func (r *friendRepository) ListRequests(ctx context.Context, userID string, outgoing bool, offset, limit int) ([]*model.FriendRequest, int64, error) {
+	column := "to_user_id = ?"
+	if outgoing {
+		column = "from_user_id = ?"
+	}
+
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&model.FriendRequest{}).
+		Where(column, userID).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var requests []*model.FriendRequest
+	if err := r.db.WithContext(ctx).
+		Where(column, userID).
+		Order("updated_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&requests).Error; err != nil {
+		return nil, 0, err
+	}
+	return requests, total, nil
+}
+
+// TransitionRequest 条件更新申请状态
+func (r *friendRepository) TransitionRequest(ctx context.Context, requestID string, from, to model.FriendRequestStatus) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.FriendRequest{}).
+		Where("request_id = ? AND status = ?", requestID, from).
+		Updates(map[string]interface{}{"status": to, "updated_at": time.Now()})
+	return result.RowsAffected > 0, result.Error
+}
+
+// AddFriend 建立双向好友关系
+func (r *friendRepository) AddFriend(ctx context.Context, userID, friendID string) error {
+	now := time.Now()
+	friends := []*model.Friend{
+		{UserID: userID, FriendID: friendID, CreatedAt: now},
+		{UserID: friendID, FriendID: userID, CreatedAt: now},
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&friends).Error
+}
+
+// RemoveFriend 解除双向好友关系
+func (r *friendRepository) RemoveFriend(ctx context.Context, userID, friendID string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("(user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)", userID, friendID, friendID, userID).
+		Delete(&model.Friend{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ListFriends 查询用户的好友
+func (r *friendRepository) ListFriends(ctx context.Context, userID string) ([]*model.Friend, error) {
+	var friends []*model.Friend
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&friends).Error
+	return friends, err
+}
+
+// FindFriendIDs 查询用户的好友ID
+func (r *friendRepository) FindFriendIDs(ctx context.Context, userID string) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Model(&model.Friend{}).
+		Where("user_id = ?", userID).
+		Pluck("friend_id", &ids).Error
+	return ids, err
+}
+
+// Block 屏蔽用户
+func (r *friendRepository) Block(ctx context.Context, userID, blockedID string) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model.UserBlock{
+		UserID:    userID,
+		BlockedID: blockedID,
+		CreatedAt: time.Now(),
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Unblock 取消屏蔽
+func (r *friendRepository) Unblock(ctx context.Context, userID, blockedID string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND blocked_id = ?", userID, blockedID).
+		Delete(&model.UserBlock{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ListBlocked 查询用户屏蔽的用户
+func (r *friendRepository) ListBlocked(ctx context.Context, userID string) ([]*model.UserBlock, error) {
+	var blocks []*model.UserBlock
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&blocks).Error
+	return blocks, err
+}
+
+// FindBlockedIDs 查询用户屏蔽的用户ID
+func (r *friendRepository) FindBlockedIDs(ctx context.Context, userID string) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Model(&model.UserBlock{}).
+		Where("user_id = ?", userID).
+		Pluck("blocked_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// GroupDigestRepository 群活动摘要订阅仓库接口
+type GroupDigestRepository interface {
+	// Save 创建或更新订阅
+	Save(ctx context.Context, sub *model.GroupDigestSubscription) error
+
+	// Delete 取消订阅，未订阅时返回 false
+	Delete(ctx context.Context, userID, groupID string) (bool, error)
+
+	// Find 查询订阅，不存在时返回 nil
+	Find(ctx context.Context, userID, groupID string) (*model.GroupDigestSubscription, error)
+
+	// FindByUser 查询用户的全部订阅
+	FindByUser(ctx context.Context, userID string) ([]*model.GroupDigestSubscription, error)
+
+	// FindDue 查询到达发送时间的订阅（按发送时间升序）
+	FindDue(ctx context.Context, now time.Time, limit int) ([]*model.GroupDigestSubscription, error)
+
+	// Advance 仅当下次发送时间仍为 prev 时更新为 next 并记录发送时间，返回是否更新成功（多节点下用于抢占）
+	Advance(ctx context.Context, userID, groupID string, prev, next time.Time) (bool, error)
+}
+
+// groupDigestRepository 群活动摘要订阅仓库实现
+type groupDigestRepository struct {
+	db *gorm.DB
+}
+
+// NewGroupDigestRepository 创建群活动摘要订阅仓库
+func NewGroupDigestRepository(db *gorm.DB) GroupDigestRepository {
+	return &groupDigestRepository{db: db}
+}
+
+// Save 创建或更新订阅
+func (r *groupDigestRepository) Save(ctx context.Context, sub *model.GroupDigestSubscription) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "group_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"send_at", "timezone", "push", "next_run_at", "updated_at"}),
+	}).Create(sub).Error
+}
+
+// Delete 取消订阅
+func (r *groupDigestRepository) Delete(ctx context.Context, userID, groupID string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND group_id = ?", userID, groupID).
+		Delete(&model.GroupDigestSubscription{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Find 查询订阅
+func (r *groupDigestRepository) Find(ctx context.Context, userID, groupID string) (*model.GroupDigestSubscription, error) {
+	var sub model.GroupDigestSubscription
+	err := r.db.WithContext(ctx).Where("user_id = ? AND group_id = ?", userID, groupID).First(&sub).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// FindByUser 查询用户的全部订阅
+func (r *groupDigestRepository) FindByUser(ctx context.Context, userID string) ([]*model.GroupDigestSubscription, error) {
+	var subs []*model.GroupDigestSubscription
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&subs).Error
+	return subs, err
+}
+
+// FindDue 查询到达发送时间的订阅
+func (r *groupDigestRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]*model.GroupDigestSubscription, error) {
+	var subs []*model.GroupDigestSubscription
+	err := r.db.WithContext(ctx).
+		Where("next_run_at <= ?", now).
+		Order("next_run_at ASC").
+		Limit(limit).
+		Find(&subs).Error
+	return subs, err
+}
+
+// Advance 条件更新下次发送时间
+func (r *groupDigestRepository) Advance(ctx context.Context, userID, groupID string, prev, next time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.GroupDigestSubscription{}).
+		Where("user_id = ? AND group_id = ? AND next_run_at = ?", userID, groupID, prev).
+		Updates(map[string]interface{}{"next_run_at": next, "last_sent_at": time.Now()})
+	return result.RowsAffected > 0, result.Error
+}
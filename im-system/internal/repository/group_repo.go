@@ -0,0 +1,485 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// ErrVersionConflict 乐观锁版本冲突（记录已被并发修改或已删除）
+var ErrVersionConflict = errors.New("version conflict")
+
+// GroupRepository 群组仓库接口
+type GroupRepository interface {
+	// Transaction 在事务中执行，fn 内必须使用传入的仓库
+	Transaction(ctx context.Context, fn func(tx GroupRepository) error) error
+
+	// Create 创建群组
+	Create(ctx context.Context, group *model.Group) error
+
+	// FindByID 查询群组，不存在时返回 nil
+	FindByID(ctx context.Context, groupID string) (*model.Group, error)
+
+	// FindByIDForUpdate 加行锁查询群组（须在事务中调用），不存在时返回 nil
+	FindByIDForUpdate(ctx context.Context, groupID string) (*model.Group, error)
+
+	// Update 更新群组字段（版本号自增）
+	Update(ctx context.Context, groupID string, updates map[string]interface{}) error
+
+	// UpdateWithVersion 按版本号更新群组字段，版本不一致时返回 ErrVersionConflict
+	UpdateWithVersion(ctx context.Context, groupID string, version int64, updates map[string]interface{}) error
+
+	// IncrMemberCount 增减成员数
+	IncrMemberCount(ctx context.Context, groupID string, delta int) error
+
+	// FindUserGroups 查询用户所在的正常状态群组
+	FindUserGroups(ctx context.Context, userID string) ([]*model.Group, error)
+
+	// AddMembers 批量添加成员
+	AddMembers(ctx context.Context, members []*model.GroupMember) error
+
+	// RemoveMembers 删除成员，userIDs 为空时删除全部成员
+	RemoveMembers(ctx context.Context, groupID string, userIDs []string) error
+
+	// FindMember 查询成员，不存在时返回 nil
+	FindMember(ctx context.Context, groupID, userID string) (*model.GroupMember, error)
+
+	// FindMemberForUpdate 加行锁查询成员（须在事务中调用），不存在时返回 nil
+	FindMemberForUpdate(ctx context.Context, groupID, userID string) (*model.GroupMember, error)
+
+	// UpdateMember 更新成员字段（版本号自增）
+	UpdateMember(ctx context.Context, groupID, userID string, updates map[string]interface{}) error
+
+	// UpdateMemberWithVersion 按版本号更新成员字段，版本不一致时返回 ErrVersionConflict
+	UpdateMemberWithVersion(ctx context.Context, groupID, userID string, version int64, updates map[string]interface{}) error
+
+	// FindMembers 分页查询成员（群主、管理员在前）
+	FindMembers(ctx context.Context, groupID string, offset, limit int) ([]*model.GroupMember, int64, error)
+
+	// FindMemberIDs 查询成员ID，minRole 用于只查询管理员等高角色成员
+	FindMemberIDs(ctx context.Context, groupID string, minRole model.GroupRole) ([]string, error)
+
+	// CreateJoinRequest 创建入群申请
+	CreateJoinRequest(ctx context.Context, req *model.GroupJoinRequest) error
+
+	// FindJoinRequest 查询入群申请，不存在时返回 nil
+	FindJoinRequest(ctx context.Context, id uint) (*model.GroupJoinRequest, error)
+
+	// FindPendingJoinRequest 查询用户对群的待处理申请，不存在时返回 nil
+	FindPendingJoinRequest(ctx context.Context, groupID, userID string) (*model.GroupJoinRequest, error)
+
+	// FindJoinRequests 分页查询群的入群申请（按申请时间倒序），status 小于0时查询全部状态
+	FindJoinRequests(ctx context.Context, groupID string, status, offset, limit int) ([]*model.GroupJoinRequest, int64, error)
+
+	// HandleJoinRequest 仅当申请待处理时更新为 status，返回是否更新成功（并发处理时只有一个成功）
+	HandleJoinRequest(ctx context.Context, id uint, status int, handlerID string, handledAt time.Time) (bool, error)
+
+	// FindExpiryWarnDue 查询到期时间早于 before 且尚未发送到期提醒的正常状态临时群
+	FindExpiryWarnDue(ctx context.Context, before time.Time, limit int) ([]*model.Group, error)
+
+	// FindExpired 查询已到期的正常状态临时群
+	FindExpired(ctx context.Context, now time.Time, limit int) ([]*model.Group, error)
+
+	// FindOwnedGroups 查询用户作为群主的正常状态群组
+	FindOwnedGroups(ctx context.Context, ownerID string) ([]*model.Group, error)
+
+	// CreateSuccession 记录群主继任
+	CreateSuccession(ctx context.Context, record *model.GroupOwnerSuccession) error
+
+	// UpdateSuccession 更新群主继任记录
+	UpdateSuccession(ctx context.Context, id uint, updates map[string]interface{}) error
+
+	// FindSuccessions 查询群的继任记录（按时间倒序）
+	FindSuccessions(ctx context.Context, groupID string, limit int) ([]*model.GroupOwnerSuccession, error)
+
+	// FindPendingDismissals 查询到期待解散的继任记录
+	FindPendingDismissals(ctx context.Context, before time.Time, limit int) ([]*model.GroupOwnerSuccession, error)
+
+	// FindPendingDismissalsByOwner 查询原群主名下待解散的继任记录
+	FindPendingDismissalsByOwner(ctx context.Context, ownerID string) ([]*model.GroupOwnerSuccession, error)
+
+	// FindGroups 按条件分页查询群组（按创建时间倒序），返回总数
+	FindGroups(ctx context.Context, filter *GroupFilter, offset, limit int) ([]*model.Group, int64, error)
+}
+
+// GroupFilter 管理后台群组查询条件，空字段不限
+type GroupFilter struct {
+	Keyword string             // 群组ID（精确）或群名称（包含）
+	OwnerID string             // 群主
+	Status  *model.GroupStatus // 群状态
+}
+
+// groupRepository 群组仓库实现
+type groupRepository struct {
+	db *gorm.DB
+}
+
+// NewGroupRepository 创建群组仓库
+func NewGroupRepository(db *gorm.DB) GroupRepository {
+	return &groupRepository{db: db}
+}
+
+// Transaction 在事务中执行
+func (r *groupRepository) Transaction(ctx context.Context, fn func(tx GroupRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&groupRepository{db: tx})
+	})
+}
+
+// Create 创建群组
+func (r *groupRepository) Create(ctx context.Context, group *model.Group) error {
+	return r.db.WithContext(ctx).Create(group).Error
+}
+
+// FindByID 查询群组
+func (r *groupRepository) FindByID(ctx context.Context, groupID string) (*model.Group, error) {
+	var group model.Group
+	if err := r.db.WithContext(ctx).Where("group_id = ?", groupID).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// FindByIDForUpdate 加行锁查询群组
+func (r *groupRepository) FindByIDForUpdate(ctx context.Context, groupID string) (*model.Group, error) {
+	var group model.Group
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("group_id = ?", groupID).
+		First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// Update 更新群组字段
+func (r *groupRepository) Update(ctx context.Context, groupID string, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.Group{}).Where("group_id = ?", groupID).Updates(bumpVersion(updates)).Error
+}
+
+// UpdateWithVersion 按版本号更新群组字段
+func (r *groupRepository) UpdateWithVersion(ctx context.Context, groupID string, version int64, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&model.Group{}).
+		Where("group_id = ? AND version = ?", groupID, version).
+		Updates(bumpVersion(updates))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// IncrMemberCount 增减成员数
+func (r *groupRepository) IncrMemberCount(ctx context.Context, groupID string, delta int) error {
+	return r.db.WithContext(ctx).Model(&model.Group{}).Where("group_id = ?", groupID).
+		UpdateColumn("member_count", gorm.Expr("member_count + ?", delta)).Error
+}
+
+// FindUserGroups 查询用户所在的群组
+func (r *groupRepository) FindUserGroups(ctx context.Context, userID string) ([]*model.Group, error) {
+	var groups []*model.Group
+
+	// 子查询获取用户所在的群组ID
+	subQuery := r.db.WithContext(ctx).Model(&model.GroupMember{}).
+		Select("group_id").
+		Where("user_id = ?", userID)
+
+	if err := r.db.WithContext(ctx).
+		Where("group_id IN (?) AND status = ?", subQuery, model.GroupStatusNormal).
+		Order("updated_at DESC").
+		Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// AddMembers 批量添加成员
+func (r *groupRepository) AddMembers(ctx context.Context, members []*model.GroupMember) error {
+	if len(members) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&members).Error
+}
+
+// RemoveMembers 删除成员
+func (r *groupRepository) RemoveMembers(ctx context.Context, groupID string, userIDs []string) error {
+	query := r.db.WithContext(ctx).Where("group_id = ?", groupID)
+	if len(userIDs) > 0 {
+		query = query.Where("user_id IN ?", userIDs)
+	}
+	return query.Delete(&model.GroupMember{}).Error
+}
+
+// FindMember 查询成员
+func (r *groupRepository) FindMember(ctx context.Context, groupID, userID string) (*model.GroupMember, error) {
+	var member model.GroupMember
+	if err := r.db.WithContext(ctx).
+		Where("group_id = ? AND user_id = ?", groupID, userID).
+		First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// FindMemberForUpdate 加行锁查询成员
+func (r *groupRepository) FindMemberForUpdate(ctx context.Context, groupID, userID string) (*model.GroupMember, error) {
+	var member model.GroupMember
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("group_id = ? AND user_id = ?", groupID, userID).
+		First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// UpdateMember 更新成员字段
+func (r *groupRepository) UpdateMember(ctx context.Context, groupID, userID string, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.GroupMember{}).
+		Where("group_id = ? AND user_id = ?", groupID, userID).
+		Updates(bumpVersion(updates)).Error
+}
+
+// UpdateMemberWithVersion 按版本号更新成员字段
+func (r *groupRepository) UpdateMemberWithVersion(ctx context.Context, groupID, userID string, version int64, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&model.GroupMember{}).
+		Where("group_id = ? AND user_id = ? AND version = ?", groupID, userID, version).
+		Updates(bumpVersion(updates))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// bumpVersion 复制更新字段并追加版本号自增
+func bumpVersion(updates map[string]interface{}) map[string]interface{} {
+	cp := make(map[string]interface{}, len(updates)+1)
+	for k, v := range updates {
+		cp[k] = v
+	}
+	cp["version"] = gorm.Expr("version + 1")
+	return cp
+}
+
+// FindMembers 分页查询成员
+func (r *groupRepository) FindMembers(ctx context.Context, groupID string, offset, limit int) ([]*model.GroupMember, int64, error) {
+	var members []*model.GroupMember
+	var total int64
+
+	if err := r.db.WithContext(ctx).Model(&model.GroupMember{}).
+		Where("group_id = ?", groupID).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 按角色降序，群主在前
+	if err := r.db.WithContext(ctx).
+		Where("group_id = ?", groupID).
+		Order("role DESC, joined_at ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&members).Error; err != nil {
+		return nil, 0, err
+	}
+	return members, total, nil
+}
+
+// FindMemberIDs 查询成员ID
+func (r *groupRepository) FindMemberIDs(ctx context.Context, groupID string, minRole model.GroupRole) ([]string, error) {
+	query := r.db.WithContext(ctx).Model(&model.GroupMember{}).Where("group_id = ?", groupID)
+	if minRole > model.RoleMember {
+		query = query.Where("role >= ?", minRole)
+	}
+
+	var memberIDs []string
+	if err := query.Pluck("user_id", &memberIDs).Error; err != nil {
+		return nil, err
+	}
+	return memberIDs, nil
+}
+
+// CreateJoinRequest 创建入群申请
+func (r *groupRepository) CreateJoinRequest(ctx context.Context, req *model.GroupJoinRequest) error {
+	if req.CreatedAt.IsZero() {
+		req.CreatedAt = time.Now()
+	}
+	return r.db.WithContext(ctx).Create(req).Error
+}
+
+// FindJoinRequest 查询入群申请
+func (r *groupRepository) FindJoinRequest(ctx context.Context, id uint) (*model.GroupJoinRequest, error) {
+	var req model.GroupJoinRequest
+	if err := r.db.WithContext(ctx).First(&req, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+// FindPendingJoinRequest 查询用户对群的待处理申请
+func (r *groupRepository) FindPendingJoinRequest(ctx context.Context, groupID, userID string) (*model.GroupJoinRequest, error) {
+	var req model.GroupJoinRequest
+	if err := r.db.WithContext(ctx).
+		Where("group_id = ? AND user_id = ? AND status = ?", groupID, userID, model.JoinRequestPending).
+		First(&req).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+// FindJoinRequests 分页查询群的入群申请
+func (r *groupRepository) FindJoinRequests(ctx context.Context, groupID string, status, offset, limit int) ([]*model.GroupJoinRequest, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.GroupJoinRequest{}).Where("group_id = ?", groupID)
+	if status >= 0 {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var requests []*model.GroupJoinRequest
+	if err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&requests).Error; err != nil {
+		return nil, 0, err
+	}
+	return requests, total, nil
+}
+
+// HandleJoinRequest 条件更新入群申请状态
+func (r *groupRepository) HandleJoinRequest(ctx context.Context, id uint, status int, handlerID string, handledAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.GroupJoinRequest{}).
+		Where("id = ? AND status = ?", id, model.JoinRequestPending).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"handler_id": handlerID,
+			"handled_at": handledAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindExpiryWarnDue 查询待发送到期提醒的临时群
+func (r *groupRepository) FindExpiryWarnDue(ctx context.Context, before time.Time, limit int) ([]*model.Group, error) {
+	var groups []*model.Group
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND expiry_warned = ? AND expires_at IS NOT NULL AND expires_at <= ?", model.GroupStatusNormal, false, before).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&groups).Error
+	return groups, err
+}
+
+// FindExpired 查询已到期的临时群
+func (r *groupRepository) FindExpired(ctx context.Context, now time.Time, limit int) ([]*model.Group, error) {
+	var groups []*model.Group
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND expires_at IS NOT NULL AND expires_at <= ?", model.GroupStatusNormal, now).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&groups).Error
+	return groups, err
+}
+
+// FindOwnedGroups 查询用户作为群主的群组
+func (r *groupRepository) FindOwnedGroups(ctx context.Context, ownerID string) ([]*model.Group, error) {
+	var groups []*model.Group
+	if err := r.db.WithContext(ctx).
+		Where("owner_id = ? AND status = ?", ownerID, model.GroupStatusNormal).
+		Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// CreateSuccession 记录群主继任
+func (r *groupRepository) CreateSuccession(ctx context.Context, record *model.GroupOwnerSuccession) error {
+	return r.db.WithContext(ctx).Create(record).Error
+}
+
+// UpdateSuccession 更新群主继任记录
+func (r *groupRepository) UpdateSuccession(ctx context.Context, id uint, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.GroupOwnerSuccession{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// FindSuccessions 查询群的继任记录
+func (r *groupRepository) FindSuccessions(ctx context.Context, groupID string, limit int) ([]*model.GroupOwnerSuccession, error) {
+	var records []*model.GroupOwnerSuccession
+	err := r.db.WithContext(ctx).
+		Where("group_id = ?", groupID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&records).Error
+	return records, err
+}
+
+// FindPendingDismissals 查询到期待解散的继任记录
+func (r *groupRepository) FindPendingDismissals(ctx context.Context, before time.Time, limit int) ([]*model.GroupOwnerSuccession, error) {
+	var records []*model.GroupOwnerSuccession
+	err := r.db.WithContext(ctx).
+		Where("action = ? AND dismiss_at <= ?", model.SuccessionDismissPending, before).
+		Order("dismiss_at ASC").
+		Limit(limit).
+		Find(&records).Error
+	return records, err
+}
+
+// FindPendingDismissalsByOwner 查询原群主名下待解散的继任记录
+func (r *groupRepository) FindPendingDismissalsByOwner(ctx context.Context, ownerID string) ([]*model.GroupOwnerSuccession, error) {
+	var records []*model.GroupOwnerSuccession
+	err := r.db.WithContext(ctx).
+		Where("previous_owner_id = ? AND action = ?", ownerID, model.SuccessionDismissPending).
+		Find(&records).Error
+	return records, err
+}
+
+// FindGroups 按条件分页查询群组
+func (r *groupRepository) FindGroups(ctx context.Context, filter *GroupFilter, offset, limit int) ([]*model.Group, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Group{})
+	if filter.Keyword != "" {
+		query = query.Where("(group_id = ? OR name LIKE ?)", filter.Keyword, "%"+escapeLike(filter.Keyword)+"%")
+	}
+	if filter.OwnerID != "" {
+		query = query.Where("owner_id = ?", filter.OwnerID)
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var groups []*model.Group
+	if err := query.Order("created_at DESC, group_id DESC").Offset(offset).Limit(limit).Find(&groups).Error; err != nil {
+		return nil, 0, err
+	}
+	return groups, total, nil
+}
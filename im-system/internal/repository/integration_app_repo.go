@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// IntegrationAppRepository 集成应用仓库接口
+type IntegrationAppRepository interface {
+	// Create 创建应用
+	Create(ctx context.Context, app *model.IntegrationApp) error
+
+	// FindByID 查询应用，不存在时返回 nil
+	FindByID(ctx context.Context, appID string) (*model.IntegrationApp, error)
+
+	// List 查询全部应用
+	List(ctx context.Context) ([]*model.IntegrationApp, error)
+
+	// Update 更新应用名称、密钥及状态
+	Update(ctx context.Context, app *model.IntegrationApp) error
+
+	// Delete 删除应用
+	Delete(ctx context.Context, appID string) error
+
+	// FindPolicy 查询会话策略，未设置时返回 nil
+	FindPolicy(ctx context.Context, conversationID string) (*model.ConversationAppPolicy, error)
+
+	// SavePolicy 保存会话策略
+	SavePolicy(ctx context.Context, policy *model.ConversationAppPolicy) error
+}
+
+// integrationAppRepository 集成应用仓库实现
+type integrationAppRepository struct {
+	db *gorm.DB
+}
+
+// NewIntegrationAppRepository 创建集成应用仓库
+func NewIntegrationAppRepository(db *gorm.DB) IntegrationAppRepository {
+	return &integrationAppRepository{db: db}
+}
+
+// Create 创建应用
+func (r *integrationAppRepository) Create(ctx context.Context, app *model.IntegrationApp) error {
+	return r.db.WithContext(ctx).Create(app).Error
+}
+
+// FindByID 查询应用
+func (r *integrationAppRepository) FindByID(ctx context.Context, appID string) (*model.IntegrationApp, error) {
+	var app model.IntegrationApp
+	if err := r.db.WithContext(ctx).Where("app_id = ?", appID).First(&app).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &app, nil
+}
+
+// List 查询全部应用
+func (r *integrationAppRepository) List(ctx context.Context) ([]*model.IntegrationApp, error) {
+	var apps []*model.IntegrationApp
+	err := r.db.WithContext(ctx).Order("created_at").Find(&apps).Error
+	return apps, err
+}
+
+// Update 更新应用
+func (r *integrationAppRepository) Update(ctx context.Context, app *model.IntegrationApp) error {
+	return r.db.WithContext(ctx).Model(&model.IntegrationApp{}).
+		Where("app_id = ?", app.AppID).
+		Updates(map[string]interface{}{
+			"name":     app.Name,
+			"secret":   app.Secret,
+			"disabled": app.Disabled,
+		}).Error
+}
+
+// Delete 删除应用
+func (r *integrationAppRepository) Delete(ctx context.Context, appID string) error {
+	return r.db.WithContext(ctx).Where("app_id = ?", appID).Delete(&model.IntegrationApp{}).Error
+}
+
+// FindPolicy 查询会话策略
+func (r *integrationAppRepository) FindPolicy(ctx context.Context, conversationID string) (*model.ConversationAppPolicy, error) {
+	var policy model.ConversationAppPolicy
+	if err := r.db.WithContext(ctx).Where("conversation_id = ?", conversationID).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SavePolicy 保存会话策略
+func (r *integrationAppRepository) SavePolicy(ctx context.Context, policy *model.ConversationAppPolicy) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "conversation_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"require_verified", "updated_by", "updated_at"}),
+	}).Create(policy).Error
+}
@@ -0,0 +1,310 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// ConversationRepository 会话仓库内存实现
+type ConversationRepository struct {
+	mu            sync.RWMutex
+	conversations map[string]*model.Conversation
+	userConvs     map[string]*model.UserConversation // userID:conversationID -> 设置
+}
+
+// NewConversationRepository 创建会话仓库内存实现
+func NewConversationRepository() *ConversationRepository {
+	return &ConversationRepository{
+		conversations: make(map[string]*model.Conversation),
+		userConvs:     make(map[string]*model.UserConversation),
+	}
+}
+
+var _ repository.ConversationRepository = (*ConversationRepository)(nil)
+
+// FindByID 查询会话
+func (r *ConversationRepository) FindByID(ctx context.Context, conversationID string) (*model.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conv, ok := r.conversations[model.CanonicalConversationID(conversationID)]
+	if !ok {
+		return nil, nil
+	}
+	copied := *conv
+	return &copied, nil
+}
+
+// UpsertLastMessage 创建会话或更新最后一条消息
+func (r *ConversationRepository) UpsertLastMessage(ctx context.Context, conversationID string, convType int, messageID string, messageAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversationID = model.CanonicalConversationID(conversationID)
+	now := time.Now()
+	conv, ok := r.conversations[conversationID]
+	if !ok {
+		r.conversations[conversationID] = &model.Conversation{
+			ConversationID: conversationID,
+			Type:           convType,
+			LastMessageID:  messageID,
+			LastMessageAt:  messageAt,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		return nil
+	}
+	if !messageAt.Before(conv.LastMessageAt) {
+		conv.LastMessageID = messageID
+		conv.LastMessageAt = messageAt
+	}
+	conv.UpdatedAt = now
+	return nil
+}
+
+// RecordMessage 更新会话最后一条消息并维护参与者的用户会话
+func (r *ConversationRepository) RecordMessage(ctx context.Context, conversationID string, convType int, messageID string, messageAt time.Time, senderID string, recipientIDs []string) error {
+	if err := r.UpsertLastMessage(ctx, conversationID, convType, messageID, messageAt); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversationID = model.CanonicalConversationID(conversationID)
+	now := time.Now()
+	touch := func(userID string, unread int) {
+		key := userID + ":" + conversationID
+		uc, ok := r.userConvs[key]
+		if !ok {
+			uc = &model.UserConversation{UserID: userID, ConversationID: conversationID, CreatedAt: now}
+			r.userConvs[key] = uc
+		}
+		uc.UnreadCount += unread
+		uc.Deleted = false
+		uc.UpdatedAt = now
+	}
+	if senderID != "" {
+		touch(senderID, 0)
+	}
+	for _, userID := range recipientIDs {
+		if userID != "" && userID != senderID {
+			touch(userID, 1)
+		}
+	}
+	return nil
+}
+
+// FindUserConversation 查询用户的会话设置
+func (r *ConversationRepository) FindUserConversation(ctx context.Context, userID, conversationID string) (*model.UserConversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	uc, ok := r.userConvs[userID+":"+model.CanonicalConversationID(conversationID)]
+	if !ok {
+		return nil, nil
+	}
+	copied := *uc
+	return &copied, nil
+}
+
+// FindUserConversations 分页查询用户未删除的会话（置顶优先，再按最后一条消息时间倒序）
+func (r *ConversationRepository) FindUserConversations(ctx context.Context, userID string, offset, limit int) ([]*repository.UserConversationEntry, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*repository.UserConversationEntry
+	for _, uc := range r.userConvs {
+		if uc.UserID != userID || uc.Deleted {
+			continue
+		}
+		entry := &repository.UserConversationEntry{UserConversation: *uc}
+		if conv, ok := r.conversations[uc.ConversationID]; ok {
+			entry.Type = conv.Type
+			entry.LastMessageID = conv.LastMessageID
+			lastMessageAt := conv.LastMessageAt
+			entry.LastMessageAt = &lastMessageAt
+		}
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Pinned != b.Pinned {
+			return a.Pinned
+		}
+		if at, bt := lastMessageAt(a), lastMessageAt(b); !at.Equal(bt) {
+			return at.After(bt)
+		}
+		return a.UpdatedAt.After(b.UpdatedAt)
+	})
+
+	total := int64(len(result))
+	if offset >= len(result) {
+		return []*repository.UserConversationEntry{}, total, nil
+	}
+	result = result[offset:]
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, total, nil
+}
+
+// FindMutedConversationIDs 查询用户设置了免打扰的会话ID
+func (r *ConversationRepository) FindMutedConversationIDs(ctx context.Context, userID string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0)
+	for _, uc := range r.userConvs {
+		if uc.UserID == userID && uc.Muted {
+			ids = append(ids, uc.ConversationID)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// FindMutedUserIDs 查询对会话设置了免打扰的用户ID
+func (r *ConversationRepository) FindMutedUserIDs(ctx context.Context, conversationID string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conversationID = model.CanonicalConversationID(conversationID)
+	userIDs := make([]string, 0)
+	for _, uc := range r.userConvs {
+		if uc.ConversationID == conversationID && uc.Muted {
+			userIDs = append(userIDs, uc.UserID)
+		}
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+// UpdateUserConversation 修改用户的会话设置（记录不存在时创建）
+func (r *ConversationRepository) UpdateUserConversation(ctx context.Context, userID, conversationID string, update *repository.UserConversationUpdate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversationID = model.CanonicalConversationID(conversationID)
+	now := time.Now()
+	key := userID + ":" + conversationID
+	uc, ok := r.userConvs[key]
+	if !ok {
+		uc = &model.UserConversation{UserID: userID, ConversationID: conversationID, CreatedAt: now}
+		r.userConvs[key] = uc
+	}
+	if update.Pinned != nil {
+		uc.Pinned = *update.Pinned
+	}
+	if update.Muted != nil {
+		uc.Muted = *update.Muted
+	}
+	if update.Deleted != nil {
+		uc.Deleted = *update.Deleted
+		if uc.Deleted {
+			uc.UnreadCount = 0
+		}
+	}
+	uc.UpdatedAt = now
+	return nil
+}
+
+// UpdateEncryption 设置会话加密状态
+func (r *ConversationRepository) UpdateEncryption(ctx context.Context, conversationID string, convType int, encrypted, rotate bool) (*model.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversationID = model.CanonicalConversationID(conversationID)
+	now := time.Now()
+	conv, ok := r.conversations[conversationID]
+	if !ok {
+		conv = &model.Conversation{ConversationID: conversationID, Type: convType, CreatedAt: now}
+		r.conversations[conversationID] = conv
+	}
+	conv.Encrypted = encrypted
+	if rotate {
+		conv.KeyVersion++
+		rotatedAt := now
+		conv.KeyRotatedAt = &rotatedAt
+	}
+	conv.UpdatedAt = now
+
+	copied := *conv
+	return &copied, nil
+}
+
+// FindEncrypted 分页查询开启加密的会话
+func (r *ConversationRepository) FindEncrypted(ctx context.Context, offset, limit int) ([]*model.Conversation, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*model.Conversation
+	for _, conv := range r.conversations {
+		if conv.Encrypted {
+			copied := *conv
+			result = append(result, &copied)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return rotatedAt(result[i]).After(rotatedAt(result[j]))
+	})
+
+	total := int64(len(result))
+	if offset >= len(result) {
+		return []*model.Conversation{}, total, nil
+	}
+	result = result[offset:]
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, total, nil
+}
+
+// AssignRegion 设置会话的存储区域（已确定区域时不修改）
+func (r *ConversationRepository) AssignRegion(ctx context.Context, conversationID string, convType int, region string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversationID = model.CanonicalConversationID(conversationID)
+	now := time.Now()
+	conv, ok := r.conversations[conversationID]
+	if !ok {
+		conv = &model.Conversation{ConversationID: conversationID, Type: convType, CreatedAt: now, UpdatedAt: now}
+		r.conversations[conversationID] = conv
+	}
+	if conv.Region == "" {
+		conv.Region = region
+	}
+	return conv.Region, nil
+}
+
+// rotatedAt 会话最近一次密钥轮换时间
+func rotatedAt(conv *model.Conversation) time.Time {
+	if conv.KeyRotatedAt == nil {
+		return time.Time{}
+	}
+	return *conv.KeyRotatedAt
+}
+
+// lastMessageAt 会话最后一条消息时间
+func lastMessageAt(entry *repository.UserConversationEntry) time.Time {
+	if entry.LastMessageAt == nil {
+		return time.Time{}
+	}
+	return *entry.LastMessageAt
+}
+
+// PutUserConversation 写入用户会话设置（用于准备测试数据）
+func (r *ConversationRepository) PutUserConversation(uc *model.UserConversation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *uc
+	copied.ConversationID = model.CanonicalConversationID(uc.ConversationID)
+	r.userConvs[uc.UserID+":"+copied.ConversationID] = &copied
+}
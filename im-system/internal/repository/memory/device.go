@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// DeviceRepository 推送设备仓库内存实现
+type DeviceRepository struct {
+	mu      sync.RWMutex
+	devices map[string]*model.Device // deviceToken -> device
+	nextID  uint
+}
+
+// NewDeviceRepository 创建推送设备仓库内存实现
+func NewDeviceRepository() *DeviceRepository {
+	return &DeviceRepository{devices: make(map[string]*model.Device)}
+}
+
+var _ repository.DeviceRepository = (*DeviceRepository)(nil)
+
+// Upsert 创建或更新设备
+func (r *DeviceRepository) Upsert(ctx context.Context, device *model.Device) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.devices[device.DeviceToken]; ok {
+		existing.UserID = device.UserID
+		existing.Platform = device.Platform
+		existing.AppVersion = device.AppVersion
+		existing.DeviceInfo = device.DeviceInfo
+		existing.Subscription = device.Subscription
+		existing.UpdatedAt = time.Now()
+		*device = *existing
+		return nil
+	}
+
+	r.nextID++
+	device.ID = r.nextID
+	cp := *device
+	r.devices[device.DeviceToken] = &cp
+	return nil
+}
+
+// FindPushEnabled 查询用户开启推送的设备
+func (r *DeviceRepository) FindPushEnabled(ctx context.Context, userID string) ([]*model.Device, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var devices []*model.Device
+	for _, device := range r.devices {
+		if device.UserID == userID && device.PushEnabled {
+			cp := *device
+			devices = append(devices, &cp)
+		}
+	}
+	return devices, nil
+}
+
+// UpdateToken 更新设备Token
+func (r *DeviceRepository) UpdateToken(ctx context.Context, oldToken, newToken string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	device, ok := r.devices[oldToken]
+	if !ok {
+		return 0, nil
+	}
+	delete(r.devices, oldToken)
+	device.DeviceToken = newToken
+	r.devices[newToken] = device
+	return 1, nil
+}
+
+// Delete 删除用户的设备
+func (r *DeviceRepository) Delete(ctx context.Context, userID, deviceToken string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if device, ok := r.devices[deviceToken]; ok && device.UserID == userID {
+		delete(r.devices, deviceToken)
+	}
+	return nil
+}
+
+// DeleteByToken 按Token删除设备
+func (r *DeviceRepository) DeleteByToken(ctx context.Context, deviceToken string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.devices, deviceToken)
+	return nil
+}
+
+// CountByPlatform 按平台统计设备数
+func (r *DeviceRepository) CountByPlatform(ctx context.Context, platform model.Platform) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, device := range r.devices {
+		if device.Platform == platform {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,511 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// GroupRepository 群组仓库内存实现
+type GroupRepository struct {
+	mu           sync.RWMutex
+	groups       map[string]*model.Group
+	members      map[string]map[string]*model.GroupMember // groupID -> userID -> member
+	joinRequests []*model.GroupJoinRequest
+	successions  []*model.GroupOwnerSuccession
+	nextID       uint
+}
+
+// NewGroupRepository 创建群组仓库内存实现
+func NewGroupRepository() *GroupRepository {
+	return &GroupRepository{
+		groups:  make(map[string]*model.Group),
+		members: make(map[string]map[string]*model.GroupMember),
+	}
+}
+
+var _ repository.GroupRepository = (*GroupRepository)(nil)
+
+// Transaction 直接执行 fn（内存实现不支持回滚）
+func (r *GroupRepository) Transaction(ctx context.Context, fn func(tx repository.GroupRepository) error) error {
+	return fn(r)
+}
+
+// Create 创建群组
+func (r *GroupRepository) Create(ctx context.Context, group *model.Group) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cp := *group
+	r.groups[group.GroupID] = &cp
+	return nil
+}
+
+// FindByID 查询群组
+func (r *GroupRepository) FindByID(ctx context.Context, groupID string) (*model.Group, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	group, ok := r.groups[groupID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *group
+	return &cp, nil
+}
+
+// FindByIDForUpdate 查询群组（内存实现不加锁）
+func (r *GroupRepository) FindByIDForUpdate(ctx context.Context, groupID string) (*model.Group, error) {
+	return r.FindByID(ctx, groupID)
+}
+
+// Update 更新群组字段
+func (r *GroupRepository) Update(ctx context.Context, groupID string, updates map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	group, ok := r.groups[groupID]
+	if !ok {
+		return nil
+	}
+	if err := applyUpdates(group, updates); err != nil {
+		return err
+	}
+	group.Version++
+	return nil
+}
+
+// UpdateWithVersion 按版本号更新群组字段
+func (r *GroupRepository) UpdateWithVersion(ctx context.Context, groupID string, version int64, updates map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	group, ok := r.groups[groupID]
+	if !ok || group.Version != version {
+		return repository.ErrVersionConflict
+	}
+	if err := applyUpdates(group, updates); err != nil {
+		return err
+	}
+	group.Version++
+	return nil
+}
+
+// IncrMemberCount 增减成员数
+func (r *GroupRepository) IncrMemberCount(ctx context.Context, groupID string, delta int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if group, ok := r.groups[groupID]; ok {
+		group.MemberCount += delta
+	}
+	return nil
+}
+
+// FindUserGroups 查询用户所在的群组
+func (r *GroupRepository) FindUserGroups(ctx context.Context, userID string) ([]*model.Group, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var groups []*model.Group
+	for groupID, members := range r.members {
+		group, ok := r.groups[groupID]
+		if _, isMember := members[userID]; !isMember || !ok || group.Status != model.GroupStatusNormal {
+			continue
+		}
+		cp := *group
+		groups = append(groups, &cp)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].UpdatedAt.After(groups[j].UpdatedAt) })
+	return groups, nil
+}
+
+// AddMembers 批量添加成员
+func (r *GroupRepository) AddMembers(ctx context.Context, members []*model.GroupMember) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, member := range members {
+		if r.members[member.GroupID] == nil {
+			r.members[member.GroupID] = make(map[string]*model.GroupMember)
+		}
+		r.nextID++
+		member.ID = r.nextID
+		cp := *member
+		r.members[member.GroupID][member.UserID] = &cp
+	}
+	return nil
+}
+
+// RemoveMembers 删除成员
+func (r *GroupRepository) RemoveMembers(ctx context.Context, groupID string, userIDs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(userIDs) == 0 {
+		delete(r.members, groupID)
+		return nil
+	}
+	for _, userID := range userIDs {
+		delete(r.members[groupID], userID)
+	}
+	return nil
+}
+
+// FindMember 查询成员
+func (r *GroupRepository) FindMember(ctx context.Context, groupID, userID string) (*model.GroupMember, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	member, ok := r.members[groupID][userID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *member
+	return &cp, nil
+}
+
+// FindMemberForUpdate 查询成员（内存实现不加锁）
+func (r *GroupRepository) FindMemberForUpdate(ctx context.Context, groupID, userID string) (*model.GroupMember, error) {
+	return r.FindMember(ctx, groupID, userID)
+}
+
+// UpdateMember 更新成员字段
+func (r *GroupRepository) UpdateMember(ctx context.Context, groupID, userID string, updates map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	member, ok := r.members[groupID][userID]
+	if !ok {
+		return nil
+	}
+	if err := applyUpdates(member, updates); err != nil {
+		return err
+	}
+	member.Version++
+	return nil
+}
+
+// UpdateMemberWithVersion 按版本号更新成员字段
+func (r *GroupRepository) UpdateMemberWithVersion(ctx context.Context, groupID, userID string, version int64, updates map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	member, ok := r.members[groupID][userID]
+	if !ok || member.Version != version {
+		return repository.ErrVersionConflict
+	}
+	if err := applyUpdates(member, updates); err != nil {
+		return err
+	}
+	member.Version++
+	return nil
+}
+
+// FindMembers 分页查询成员
+func (r *GroupRepository) FindMembers(ctx context.Context, groupID string, offset, limit int) ([]*model.GroupMember, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	members := make([]*model.GroupMember, 0, len(r.members[groupID]))
+	for _, member := range r.members[groupID] {
+		cp := *member
+		members = append(members, &cp)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Role != members[j].Role {
+			return members[i].Role > members[j].Role
+		}
+		return members[i].JoinedAt.Before(members[j].JoinedAt)
+	})
+
+	total := int64(len(members))
+	if offset >= len(members) {
+		return []*model.GroupMember{}, total, nil
+	}
+	members = members[offset:]
+	if limit > 0 && len(members) > limit {
+		members = members[:limit]
+	}
+	return members, total, nil
+}
+
+// FindMemberIDs 查询成员ID
+func (r *GroupRepository) FindMemberIDs(ctx context.Context, groupID string, minRole model.GroupRole) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var memberIDs []string
+	for userID, member := range r.members[groupID] {
+		if member.Role >= minRole {
+			memberIDs = append(memberIDs, userID)
+		}
+	}
+	sort.Strings(memberIDs)
+	return memberIDs, nil
+}
+
+// CreateJoinRequest 创建入群申请
+func (r *GroupRepository) CreateJoinRequest(ctx context.Context, req *model.GroupJoinRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	req.ID = r.nextID
+	cp := *req
+	r.joinRequests = append(r.joinRequests, &cp)
+	return nil
+}
+
+// FindJoinRequest 查询入群申请
+func (r *GroupRepository) FindJoinRequest(ctx context.Context, id uint) (*model.GroupJoinRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, req := range r.joinRequests {
+		if req.ID == id {
+			cp := *req
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindPendingJoinRequest 查询用户对群的待处理申请
+func (r *GroupRepository) FindPendingJoinRequest(ctx context.Context, groupID, userID string) (*model.GroupJoinRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, req := range r.joinRequests {
+		if req.GroupID == groupID && req.UserID == userID && req.Status == model.JoinRequestPending {
+			cp := *req
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindJoinRequests 分页查询群的入群申请（按申请时间倒序）
+func (r *GroupRepository) FindJoinRequests(ctx context.Context, groupID string, status, offset, limit int) ([]*model.GroupJoinRequest, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []*model.GroupJoinRequest
+	for i := len(r.joinRequests) - 1; i >= 0; i-- {
+		req := r.joinRequests[i]
+		if req.GroupID == groupID && (status < 0 || req.Status == status) {
+			cp := *req
+			matched = append(matched, &cp)
+		}
+	}
+
+	total := int64(len(matched))
+	if offset >= len(matched) {
+		return nil, total, nil
+	}
+	end := offset + limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return matched[offset:end], total, nil
+}
+
+// HandleJoinRequest 条件更新入群申请状态
+func (r *GroupRepository) HandleJoinRequest(ctx context.Context, id uint, status int, handlerID string, handledAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, req := range r.joinRequests {
+		if req.ID == id {
+			if req.Status != model.JoinRequestPending {
+				return false, nil
+			}
+			req.Status = status
+			req.HandlerID = handlerID
+			req.HandledAt = &handledAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FindExpiryWarnDue 查询待发送到期提醒的临时群
+func (r *GroupRepository) FindExpiryWarnDue(ctx context.Context, before time.Time, limit int) ([]*model.Group, error) {
+	return r.findExpiring(before, true, limit), nil
+}
+
+// FindExpired 查询已到期的临时群
+func (r *GroupRepository) FindExpired(ctx context.Context, now time.Time, limit int) ([]*model.Group, error) {
+	return r.findExpiring(now, false, limit), nil
+}
+
+// findExpiring 按到期时间升序查询到期时间早于 before 的正常状态群
+func (r *GroupRepository) findExpiring(before time.Time, unwarnedOnly bool, limit int) []*model.Group {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var groups []*model.Group
+	for _, group := range r.groups {
+		if !group.IsActive() || group.ExpiresAt == nil || group.ExpiresAt.After(before) {
+			continue
+		}
+		if unwarnedOnly && group.ExpiryWarned {
+			continue
+		}
+		cp := *group
+		groups = append(groups, &cp)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ExpiresAt.Before(*groups[j].ExpiresAt) })
+	if len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups
+}
+
+// FindOwnedGroups 查询用户作为群主的群组
+func (r *GroupRepository) FindOwnedGroups(ctx context.Context, ownerID string) ([]*model.Group, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var groups []*model.Group
+	for _, group := range r.groups {
+		if group.OwnerID != ownerID || group.Status != model.GroupStatusNormal {
+			continue
+		}
+		cp := *group
+		groups = append(groups, &cp)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].GroupID < groups[j].GroupID })
+	return groups, nil
+}
+
+// CreateSuccession 记录群主继任
+func (r *GroupRepository) CreateSuccession(ctx context.Context, record *model.GroupOwnerSuccession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	record.ID = r.nextID
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	record.UpdatedAt = record.CreatedAt
+	cp := *record
+	r.successions = append(r.successions, &cp)
+	return nil
+}
+
+// UpdateSuccession 更新群主继任记录
+func (r *GroupRepository) UpdateSuccession(ctx context.Context, id uint, updates map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, record := range r.successions {
+		if record.ID == id {
+			record.UpdatedAt = time.Now()
+			return applyUpdates(record, updates)
+		}
+	}
+	return nil
+}
+
+// FindSuccessions 查询群的继任记录
+func (r *GroupRepository) FindSuccessions(ctx context.Context, groupID string, limit int) ([]*model.GroupOwnerSuccession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var records []*model.GroupOwnerSuccession
+	for i := len(r.successions) - 1; i >= 0 && (limit <= 0 || len(records) < limit); i-- {
+		if r.successions[i].GroupID == groupID {
+			cp := *r.successions[i]
+			records = append(records, &cp)
+		}
+	}
+	return records, nil
+}
+
+// FindPendingDismissals 查询到期待解散的继任记录
+func (r *GroupRepository) FindPendingDismissals(ctx context.Context, before time.Time, limit int) ([]*model.GroupOwnerSuccession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var records []*model.GroupOwnerSuccession
+	for _, record := range r.successions {
+		if record.Action != model.SuccessionDismissPending || record.DismissAt == nil || record.DismissAt.After(before) {
+			continue
+		}
+		cp := *record
+		records = append(records, &cp)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].DismissAt.Before(*records[j].DismissAt) })
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// FindPendingDismissalsByOwner 查询原群主名下待解散的继任记录
+func (r *GroupRepository) FindPendingDismissalsByOwner(ctx context.Context, ownerID string) ([]*model.GroupOwnerSuccession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var records []*model.GroupOwnerSuccession
+	for _, record := range r.successions {
+		if record.PreviousOwnerID == ownerID && record.Action == model.SuccessionDismissPending {
+			cp := *record
+			records = append(records, &cp)
+		}
+	}
+	return records, nil
+}
+
+// JoinRequests 获取全部入群申请（测试断言用）
+func (r *GroupRepository) JoinRequests() []*model.GroupJoinRequest {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*model.GroupJoinRequest(nil), r.joinRequests...)
+}
+
+// FindGroups 按条件分页查询群组（按创建时间倒序）
+func (r *GroupRepository) FindGroups(ctx context.Context, filter *repository.GroupFilter, offset, limit int) ([]*model.Group, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keyword := strings.ToLower(filter.Keyword)
+	var matched []*model.Group
+	for _, group := range r.groups {
+		if keyword != "" && group.GroupID != filter.Keyword && !strings.Contains(strings.ToLower(group.Name), keyword) {
+			continue
+		}
+		if filter.OwnerID != "" && group.OwnerID != filter.OwnerID {
+			continue
+		}
+		if filter.Status != nil && group.Status != *filter.Status {
+			continue
+		}
+		cp := *group
+		matched = append(matched, &cp)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+		return matched[i].GroupID > matched[j].GroupID
+	})
+
+	total := int64(len(matched))
+	if offset >= len(matched) {
+		return nil, total, nil
+	}
+	end := offset + limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return matched[offset:end], total, nil
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+func TestGroupRepositoryUpdateWithVersion(t *testing.T) {
+	ctx := context.Background()
+	repo := NewGroupRepository()
+	if err := repo.Create(ctx, &model.Group{GroupID: "g1", Name: "old"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	group, _ := repo.FindByID(ctx, "g1")
+	stale := group.Version
+
+	if err := repo.UpdateWithVersion(ctx, "g1", stale, map[string]interface{}{"name": "first"}); err != nil {
+		t.Fatalf("UpdateWithVersion: %v", err)
+	}
+	if err := repo.UpdateWithVersion(ctx, "g1", stale, map[string]interface{}{"name": "second"}); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("UpdateWithVersion with stale version = %v, want ErrVersionConflict", err)
+	}
+
+	// 无版本更新同样推进版本号
+	if err := repo.Update(ctx, "g1", map[string]interface{}{"announcement": "hi"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repo.UpdateWithVersion(ctx, "g1", stale+1, map[string]interface{}{"name": "third"}); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("UpdateWithVersion after Update = %v, want ErrVersionConflict", err)
+	}
+
+	group, _ = repo.FindByID(ctx, "g1")
+	if group.Name != "first" || group.Version != stale+2 {
+		t.Fatalf("group = %q version %d, want %q version %d", group.Name, group.Version, "first", stale+2)
+	}
+
+	if err := repo.UpdateWithVersion(ctx, "missing", 0, map[string]interface{}{"name": "x"}); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("UpdateWithVersion on missing group = %v, want ErrVersionConflict", err)
+	}
+}
+
+func TestGroupRepositoryUpdateMemberWithVersion(t *testing.T) {
+	ctx := context.Background()
+	repo := NewGroupRepository()
+	if err := repo.AddMembers(ctx, []*model.GroupMember{{GroupID: "g1", UserID: "u1"}}); err != nil {
+		t.Fatalf("AddMembers: %v", err)
+	}
+
+	member, _ := repo.FindMemberForUpdate(ctx, "g1", "u1")
+	if err := repo.UpdateMemberWithVersion(ctx, "g1", "u1", member.Version, map[string]interface{}{"role": model.RoleAdmin}); err != nil {
+		t.Fatalf("UpdateMemberWithVersion: %v", err)
+	}
+	if err := repo.UpdateMemberWithVersion(ctx, "g1", "u1", member.Version, map[string]interface{}{"role": model.RoleMember}); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("UpdateMemberWithVersion with stale version = %v, want ErrVersionConflict", err)
+	}
+
+	// 已删除的成员按版本冲突处理
+	if err := repo.RemoveMembers(ctx, "g1", []string{"u1"}); err != nil {
+		t.Fatalf("RemoveMembers: %v", err)
+	}
+	if err := repo.UpdateMemberWithVersion(ctx, "g1", "u1", member.Version+1, map[string]interface{}{"role": model.RoleMember}); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("UpdateMemberWithVersion on removed member = %v, want ErrVersionConflict", err)
+	}
+}
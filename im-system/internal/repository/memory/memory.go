@@ -0,0 +1,59 @@
+// Package memory 提供仓库接口的内存实现，用于单元测试和本地调试（不支持事务回滚）
+package memory
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// applyUpdates 按gorm列名将更新字段写入结构体，与 gorm Updates(map) 的语义一致
+func applyUpdates(dst interface{}, updates map[string]interface{}) error {
+	v := reflect.ValueOf(dst).Elem()
+	t := v.Type()
+
+	for column, value := range updates {
+		idx := -1
+		for i := 0; i < t.NumField(); i++ {
+			if columnName(t.Field(i)) == column {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return fmt.Errorf("memory: unknown column %q on %s", column, t.Name())
+		}
+
+		field := v.Field(idx)
+		val := reflect.ValueOf(value)
+		if !val.Type().ConvertibleTo(field.Type()) {
+			return fmt.Errorf("memory: cannot assign %T to column %q", value, column)
+		}
+		field.Set(val.Convert(field.Type()))
+	}
+	return nil
+}
+
+// columnName 获取字段对应的列名（gorm column 标签优先，否则转为蛇形命名）
+func columnName(field reflect.StructField) string {
+	for _, part := range strings.Split(field.Tag.Get("gorm"), ";") {
+		if name, ok := strings.CutPrefix(part, "column:"); ok {
+			return name
+		}
+	}
+
+	var b strings.Builder
+	runes := []rune(field.Name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// 连续大写（如 ID、URL）视为一个单词
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
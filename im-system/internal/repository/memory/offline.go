@@ -0,0 +1,343 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// OfflineMessageRepository 离线消息仓库内存实现
+type OfflineMessageRepository struct {
+	mu       sync.RWMutex
+	messages []*model.OfflineMessage // 按ID递增
+	nextID   uint
+}
+
+// NewOfflineMessageRepository 创建离线消息仓库内存实现
+func NewOfflineMessageRepository() *OfflineMessageRepository {
+	return &OfflineMessageRepository{}
+}
+
+var _ repository.OfflineMessageRepository = (*OfflineMessageRepository)(nil)
+
+// Create 保存离线消息
+func (r *OfflineMessageRepository) Create(ctx context.Context, msg *model.OfflineMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.insert(msg)
+	return nil
+}
+
+// CreateBatch 批量保存离线消息
+func (r *OfflineMessageRepository) CreateBatch(ctx context.Context, msgs []*model.OfflineMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, msg := range msgs {
+		r.insert(msg)
+	}
+	return nil
+}
+
+// insert 分配自增ID并保存副本
+func (r *OfflineMessageRepository) insert(msg *model.OfflineMessage) {
+	r.nextID++
+	msg.ID = r.nextID
+	cp := *msg
+	r.messages = append(r.messages, &cp)
+}
+
+// filter 按条件筛选未过期且未被取代的消息
+func (r *OfflineMessageRepository) filter(match func(msg *model.OfflineMessage) bool) []*model.OfflineMessage {
+	now := time.Now()
+	var result []*model.OfflineMessage
+	for _, msg := range r.messages {
+		if msg.ExpireAt.After(now) && !msg.Superseded && match(msg) {
+			cp := *msg
+			result = append(result, &cp)
+		}
+	}
+	return result
+}
+
+// FindByUser 拉取离线消息
+func (r *OfflineMessageRepository) FindByUser(ctx context.Context, userID string, afterID int64, limit int) ([]*model.OfflineMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := r.filter(func(msg *model.OfflineMessage) bool {
+		return msg.UserID == userID && int64(msg.ID) > afterID
+	})
+	return truncate(result, limit), nil
+}
+
+// FindByConversation 拉取指定会话的离线消息
+func (r *OfflineMessageRepository) FindByConversation(ctx context.Context, userID string, conversationIDs []string, afterID int64, limit int) ([]*model.OfflineMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	convs := toSet(conversationIDs)
+	result := r.filter(func(msg *model.OfflineMessage) bool {
+		return msg.UserID == userID && convs[msg.ConversationID] && int64(msg.ID) > afterID
+	})
+	return truncate(result, limit), nil
+}
+
+// FindIDsByConversation 查询指定会话的离线消息ID
+func (r *OfflineMessageRepository) FindIDsByConversation(ctx context.Context, userID string, conversationIDs []string, upToID int64, limit int) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	convs := toSet(conversationIDs)
+	var ids []string
+	for _, msg := range r.messages {
+		if limit > 0 && len(ids) >= limit {
+			break
+		}
+		if msg.UserID == userID && convs[msg.ConversationID] && !msg.Superseded && (upToID <= 0 || int64(msg.ID) <= upToID) {
+			ids = append(ids, msg.MessageID)
+		}
+	}
+	return ids, nil
+}
+
+// DeleteRead 删除已读的离线消息（内存实现不维护用户会话状态）
+func (r *OfflineMessageRepository) DeleteRead(ctx context.Context, userID string, conversationIDs []string, lastReadSeq int64, messageIDs []string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	convs := toSet(conversationIDs)
+	ids := toSet(messageIDs)
+	var deleted []string
+	r.remove(func(msg *model.OfflineMessage) bool {
+		if msg.UserID != userID || !convs[msg.ConversationID] {
+			return false
+		}
+		if (lastReadSeq > 0 && msg.Seq > 0 && msg.Seq <= lastReadSeq) || ids[msg.MessageID] {
+			if !msg.Superseded {
+				deleted = append(deleted, msg.MessageID)
+			}
+			return true
+		}
+		return false
+	})
+	return deleted, nil
+}
+
+// FindUnpushed 查询未推送消息
+func (r *OfflineMessageRepository) FindUnpushed(ctx context.Context, userID string, limit int) ([]*model.OfflineMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := r.filter(func(msg *model.OfflineMessage) bool {
+		return msg.UserID == userID && !msg.Pushed
+	})
+	sortByCreatedAt(result)
+	return truncate(result, limit), nil
+}
+
+// FindOldestIDs 查询最旧的消息ID
+func (r *OfflineMessageRepository) FindOldestIDs(ctx context.Context, userID string, limit int) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*model.OfflineMessage
+	for _, msg := range r.messages {
+		if msg.UserID == userID && !msg.Superseded {
+			result = append(result, msg)
+		}
+	}
+	sortByCreatedAt(result)
+	result = truncate(result, limit)
+
+	ids := make([]string, len(result))
+	for i, msg := range result {
+		ids[i] = msg.MessageID
+	}
+	return ids, nil
+}
+
+// FindUsersByMessageID 查询仍有该消息离线副本的用户
+func (r *OfflineMessageRepository) FindUsersByMessageID(ctx context.Context, messageID string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var userIDs []string
+	for _, msg := range r.messages {
+		if msg.MessageID == messageID && !msg.Superseded && !seen[msg.UserID] {
+			seen[msg.UserID] = true
+			userIDs = append(userIDs, msg.UserID)
+		}
+	}
+	return userIDs, nil
+}
+
+// FindUsersWithMessagesBefore 查询有早于 before 的离线消息的用户
+func (r *OfflineMessageRepository) FindUsersWithMessagesBefore(ctx context.Context, before time.Time, afterUserID string, limit int) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var userIDs []string
+	for _, msg := range r.filter(func(msg *model.OfflineMessage) bool {
+		return msg.CreatedAt.Before(before) && msg.UserID > afterUserID
+	}) {
+		if !seen[msg.UserID] {
+			seen[msg.UserID] = true
+			userIDs = append(userIDs, msg.UserID)
+		}
+	}
+	sort.Strings(userIDs)
+	if len(userIDs) > limit {
+		userIDs = userIDs[:limit]
+	}
+	return userIDs, nil
+}
+
+// MarkPushed 标记消息已推送
+func (r *OfflineMessageRepository) MarkPushed(ctx context.Context, messageIDs []string, pushedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := toSet(messageIDs)
+	for _, msg := range r.messages {
+		if ids[msg.MessageID] {
+			msg.Pushed = true
+			msg.PushedAt = pushedAt
+		}
+	}
+	return nil
+}
+
+// Delete 删除用户的离线消息
+func (r *OfflineMessageRepository) Delete(ctx context.Context, userID string, messageIDs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := toSet(messageIDs)
+	r.remove(func(msg *model.OfflineMessage) bool {
+		return msg.UserID == userID && ids[msg.MessageID]
+	})
+	return nil
+}
+
+// DeleteExpired 删除过期消息
+func (r *OfflineMessageRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.remove(func(msg *model.OfflineMessage) bool {
+		return msg.ExpireAt.Before(now)
+	}), nil
+}
+
+// remove 删除满足条件的消息，返回删除数量
+func (r *OfflineMessageRepository) remove(match func(msg *model.OfflineMessage) bool) int64 {
+	kept := r.messages[:0]
+	var removed int64
+	for _, msg := range r.messages {
+		if match(msg) {
+			removed++
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	r.messages = kept
+	return removed
+}
+
+// Count 统计离线消息数
+func (r *OfflineMessageRepository) Count(ctx context.Context, userID string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.filter(func(msg *model.OfflineMessage) bool {
+		return msg.UserID == userID
+	}))), nil
+}
+
+// CountUnpushed 统计未推送消息数
+func (r *OfflineMessageRepository) CountUnpushed(ctx context.Context, userID string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.filter(func(msg *model.OfflineMessage) bool {
+		return msg.UserID == userID && !msg.Pushed
+	}))), nil
+}
+
+// ConversationStats 按会话统计离线消息
+func (r *OfflineMessageRepository) ConversationStats(ctx context.Context, userID string) ([]*repository.OfflineConversationStat, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	byConversation := make(map[string]*repository.OfflineConversationStat)
+	var stats []*repository.OfflineConversationStat
+	for _, msg := range r.filter(func(msg *model.OfflineMessage) bool { return msg.UserID == userID }) {
+		stat, ok := byConversation[msg.ConversationID]
+		if !ok {
+			stat = &repository.OfflineConversationStat{ConversationID: msg.ConversationID}
+			byConversation[msg.ConversationID] = stat
+			stats = append(stats, stat)
+		}
+		stat.Count++
+		if int64(msg.ID) > stat.LastID {
+			stat.LastID = int64(msg.ID)
+		}
+		if msg.CreatedAt.After(stat.LastCreatedAt) {
+			stat.LastCreatedAt = msg.CreatedAt
+		}
+	}
+	return stats, nil
+}
+
+// Supersede 将每个会话中除最近 keepRecent 条以外的离线消息标记为已取代
+func (r *OfflineMessageRepository) Supersede(ctx context.Context, userID string, keepRecent int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// 消息按ID递增保存，倒序遍历时每个会话先遇到最新的消息
+	kept := make(map[string]int)
+	var superseded int64
+	for i := len(r.messages) - 1; i >= 0; i-- {
+		msg := r.messages[i]
+		if msg.UserID != userID || msg.Superseded {
+			continue
+		}
+		if kept[msg.ConversationID] < keepRecent {
+			kept[msg.ConversationID]++
+			continue
+		}
+		msg.Superseded = true
+		superseded++
+	}
+	return superseded, nil
+}
+
+// sortByCreatedAt 按创建时间升序排序
+func sortByCreatedAt(msgs []*model.OfflineMessage) {
+	sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].CreatedAt.Before(msgs[j].CreatedAt) })
+}
+
+// truncate 截取前 limit 条（limit<=0 表示不限制）
+func truncate(msgs []*model.OfflineMessage, limit int) []*model.OfflineMessage {
+	if limit > 0 && len(msgs) > limit {
+		return msgs[:limit]
+	}
+	return msgs
+}
+
+// toSet 字符串切片转集合
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
@@ -0,0 +1,277 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// UserRepository 用户仓库内存实现
+type UserRepository struct {
+	mu      sync.RWMutex
+	users   map[string]*model.User
+	history []*model.UserRenameHistory
+	nextID  uint64
+}
+
+// NewUserRepository 创建用户仓库内存实现，可传入初始用户
+func NewUserRepository(users ...*model.User) *UserRepository {
+	r := &UserRepository{users: make(map[string]*model.User)}
+	for _, user := range users {
+		cp := *user
+		r.users[user.UserID] = &cp
+	}
+	return r
+}
+
+var _ repository.UserRepository = (*UserRepository)(nil)
+
+// Transaction 直接执行 fn（内存实现不支持回滚）
+func (r *UserRepository) Transaction(ctx context.Context, fn func(tx repository.UserRepository) error) error {
+	return fn(r)
+}
+
+// Create 创建用户
+func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cp := *user
+	r.users[user.UserID] = &cp
+	return nil
+}
+
+// FindByID 查询用户
+func (r *UserRepository) FindByID(ctx context.Context, userID string) (*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, nil
+	}
+	cp := *user
+	return &cp, nil
+}
+
+// FindByIDs 批量查询用户
+func (r *UserRepository) FindByIDs(ctx context.Context, userIDs []string) ([]*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]*model.User, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if user, ok := r.users[userID]; ok {
+			cp := *user
+			users = append(users, &cp)
+		}
+	}
+	return users, nil
+}
+
+// ExistsUsername 用户名是否已存在
+func (r *UserRepository) ExistsUsername(ctx context.Context, username string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Username == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ExistsNickname 租户内昵称是否已被使用
+func (r *UserRepository) ExistsNickname(ctx context.Context, tenantID, nickname, excludeUserID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.TenantID == tenantID && user.Nickname == nickname && user.UserID != excludeUserID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UpdateNickname 更新昵称
+func (r *UserRepository) UpdateNickname(ctx context.Context, userID, nickname string, updatedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[userID]; ok {
+		user.Nickname = nickname
+		user.UpdatedAt = updatedAt
+	}
+	return nil
+}
+
+// UpdateStatus 更新账号状态
+func (r *UserRepository) UpdateStatus(ctx context.Context, userID string, status model.UserStatus, updatedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[userID]; ok {
+		user.Status = status
+		user.UpdatedAt = updatedAt
+	}
+	return nil
+}
+
+// UpdateRegion 更新数据驻留区域
+func (r *UserRepository) UpdateRegion(ctx context.Context, userID, region string, updatedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[userID]; ok {
+		user.Region = region
+		user.UpdatedAt = updatedAt
+	}
+	return nil
+}
+
+// UpdateNotificationSettings 更新通知设置
+func (r *UserRepository) UpdateNotificationSettings(ctx context.Context, userID string, settings *model.NotificationSettings, updatedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[userID]; ok {
+		user.PushEnabled = settings.PushEnabled
+		user.PushPreview = settings.ShowPreview
+		user.DNDStart = settings.DoNotDisturb.Start
+		user.DNDEnd = settings.DoNotDisturb.End
+		user.DNDTimezone = settings.DoNotDisturb.Timezone
+		user.UpdatedAt = updatedAt
+	}
+	return nil
+}
+
+// CreateRenameHistory 记录改名历史
+func (r *UserRepository) CreateRenameHistory(ctx context.Context, history *model.UserRenameHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	history.ID = r.nextID
+	cp := *history
+	r.history = append(r.history, &cp)
+	return nil
+}
+
+// CountRenamesSince 统计改名次数
+func (r *UserRepository) CountRenamesSince(ctx context.Context, userID string, field model.RenameField, since time.Time) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, h := range r.history {
+		if h.UserID == userID && h.Field == field && h.CreatedAt.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// FindRenameHistory 查询改名历史
+func (r *UserRepository) FindRenameHistory(ctx context.Context, userID string, limit int) ([]*model.UserRenameHistory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*model.UserRenameHistory
+	for _, h := range r.history {
+		if h.UserID == userID {
+			cp := *h
+			result = append(result, &cp)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// FindExpiredGuests 查询过期的访客账号
+func (r *UserRepository) FindExpiredGuests(ctx context.Context, before time.Time, limit int) ([]*model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*model.User
+	for _, user := range r.users {
+		if user.Guest && user.GuestExpiresAt != nil && user.GuestExpiresAt.Before(before) && user.Status != model.UserStatusDeleted {
+			cp := *user
+			result = append(result, &cp)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].GuestExpiresAt.Before(*result[j].GuestExpiresAt) })
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// UpgradeGuest 将访客账号转为正式账号
+func (r *UserRepository) UpgradeGuest(ctx context.Context, userID, username, nickname, passwordHash string, updatedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok || !user.Guest || user.Status != model.UserStatusNormal {
+		return false, nil
+	}
+	user.Username = username
+	user.Nickname = nickname
+	user.PasswordHash = passwordHash
+	user.Guest = false
+	user.GuestExpiresAt = nil
+	user.UpdatedAt = updatedAt
+	return true, nil
+}
+
+// FindUsers 按条件分页查询用户（按注册时间倒序）
+func (r *UserRepository) FindUsers(ctx context.Context, filter *repository.UserFilter, offset, limit int) ([]*model.User, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keyword := strings.ToLower(filter.Keyword)
+	var matched []*model.User
+	for _, user := range r.users {
+		if keyword != "" && user.UserID != filter.Keyword &&
+			!strings.HasPrefix(strings.ToLower(user.Username), keyword) && !strings.HasPrefix(strings.ToLower(user.Nickname), keyword) {
+			continue
+		}
+		if filter.Status != nil && user.Status != *filter.Status {
+			continue
+		}
+		if filter.TenantID != "" && user.TenantID != filter.TenantID {
+			continue
+		}
+		if filter.Guest != nil && user.Guest != *filter.Guest {
+			continue
+		}
+		cp := *user
+		matched = append(matched, &cp)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+		return matched[i].UserID > matched[j].UserID
+	})
+
+	total := int64(len(matched))
+	if offset >= len(matched) {
+		return nil, total, nil
+	}
+	end := offset + limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return matched[offset:end], total, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// MentionGroupRepository 提及组仓库接口
+type MentionGroupRepository interface {
+	// Create 创建提及组
+	Create(ctx context.Context, group *model.MentionGroup) error
+
+	// Update 更新提及组
+	Update(ctx context.Context, mentionGroupID string, updates map[string]interface{}) error
+
+	// Delete 删除提及组
+	Delete(ctx context.Context, mentionGroupID string) error
+
+	// FindByID 查询提及组，不存在时返回 nil
+	FindByID(ctx context.Context, mentionGroupID string) (*model.MentionGroup, error)
+
+	// FindByName 按名称查询群内的提及组，不存在时返回 nil
+	FindByName(ctx context.Context, groupID, name string) (*model.MentionGroup, error)
+
+	// FindByGroup 查询群内全部提及组（按名称排序）
+	FindByGroup(ctx context.Context, groupID string) ([]*model.MentionGroup, error)
+
+	// FindByIDs 查询群内指定的提及组（不属于该群的ID忽略）
+	FindByIDs(ctx context.Context, groupID string, mentionGroupIDs []string) ([]*model.MentionGroup, error)
+}
+
+// mentionGroupRepository 提及组仓库实现
+type mentionGroupRepository struct {
+	db *gorm.DB
+}
+
+// NewMentionGroupRepository 创建提及组仓库
+func NewMentionGroupRepository(db *gorm.DB) MentionGroupRepository {
+	return &mentionGroupRepository{db: db}
+}
+
+// Create 创建提及组
+func (r *mentionGroupRepository) Create(ctx context.Context, group *model.MentionGroup) error {
+	return r.db.WithContext(ctx).Create(group).Error
+}
+
+// Update 更新提及组
+func (r *mentionGroupRepository) Update(ctx context.Context, mentionGroupID string, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.MentionGroup{}).
+		Where("mention_group_id = ?", mentionGroupID).
+		Updates(updates).Error
+}
+
+// Delete 删除提及组
+func (r *mentionGroupRepository) Delete(ctx context.Context, mentionGroupID string) error {
+	return r.db.WithContext(ctx).Where("mention_group_id = ?", mentionGroupID).Delete(&model.MentionGroup{}).Error
+}
+
+// FindByID 查询提及组
+func (r *mentionGroupRepository) FindByID(ctx context.Context, mentionGroupID string) (*model.MentionGroup, error) {
+	var group model.MentionGroup
+	if err := r.db.WithContext(ctx).Where("mention_group_id = ?", mentionGroupID).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// FindByName 按名称查询提及组
+func (r *mentionGroupRepository) FindByName(ctx context.Context, groupID, name string) (*model.MentionGroup, error) {
+	var group model.MentionGroup
+	if err := r.db.WithContext(ctx).Where("group_id = ? AND name = ?", groupID, name).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// FindByGroup 查询群内全部提及组
+func (r *mentionGroupRepository) FindByGroup(ctx context.Context, groupID string) ([]*model.MentionGroup, error) {
+	var groups []*model.MentionGroup
+	err := r.db.WithContext(ctx).
+		Where("group_id = ?", groupID).
+		Order("name ASC").
+		Find(&groups).Error
+	return groups, err
+}
+
+// FindByIDs 查询群内指定的提及组
+func (r *mentionGroupRepository) FindByIDs(ctx context.Context, groupID string, mentionGroupIDs []string) ([]*model.MentionGroup, error) {
+	if len(mentionGroupIDs) == 0 {
+		return nil, nil
+	}
+	var groups []*model.MentionGroup
+	err := r.db.WithContext(ctx).
+		Where("group_id = ? AND mention_group_id IN ?", groupID, mentionGroupIDs).
+		Find(&groups).Error
+	return groups, err
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/d60-lab/im-system/pkg/database"
+)
+
+// CollectionMessageLatency 消息处理耗时抽样诊断集合（TTL 自动清理）
+const CollectionMessageLatency = "message_latency_samples"
+
+// MessageLatencyTTL 耗时抽样的保留时间
+const MessageLatencyTTL = 7 * 24 * time.Hour
+
+// MessageLatencySample 抽样消息的各阶段耗时
+type MessageLatencySample struct {
+	MessageID      string             `bson:"message_id" json:"message_id"`
+	ConversationID string             `bson:"conversation_id" json:"conversation_id"`
+	Type           int                `bson:"type" json:"type"`
+	NodeID         string             `bson:"node_id" json:"node_id"`
+	StagesMs       map[string]float64 `bson:"stages_ms" json:"stages_ms"` // 阶段 -> 与上一阶段的间隔（毫秒）
+	TotalMs        float64            `bson:"total_ms" json:"total_ms"`   // 收到帧到分发完成（毫秒）
+	ReceivedAt     time.Time          `bson:"received_at" json:"received_at"`
+}
+
+// MessageLatencyRepository 消息耗时抽样仓库接口
+type MessageLatencyRepository interface {
+	// Save 保存耗时抽样
+	Save(ctx context.Context, sample *MessageLatencySample) error
+}
+
+// messageLatencyRepository 消息耗时抽样仓库实现
+type messageLatencyRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMessageLatencyRepository 创建消息耗时抽样仓库
+func NewMessageLatencyRepository(mongoClient *database.MongoClient) MessageLatencyRepository {
+	return &messageLatencyRepository{
+		collection: mongoClient.Collection(CollectionMessageLatency),
+	}
+}
+
+// Save 保存耗时抽样
+func (r *messageLatencyRepository) Save(ctx context.Context, sample *MessageLatencySample) error {
+	if _, err := r.collection.InsertOne(ctx, sample); err != nil {
+		return fmt.Errorf("failed to save latency sample: %w", err)
+	}
+	return nil
+}
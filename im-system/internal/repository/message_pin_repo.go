@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// MessagePinRepository 置顶消息仓库接口
+type MessagePinRepository interface {
+	// Create 置顶消息，已置顶时返回 false
+	Create(ctx context.Context, pin *model.PinnedMessage) (bool, error)
+
+	// Delete 取消置顶，未置顶时返回 false
+	Delete(ctx context.Context, conversationID, messageID string) (bool, error)
+
+	// FindByConversation 查询会话置顶消息（按置顶时间倒序）
+	FindByConversation(ctx context.Context, conversationID string) ([]*model.PinnedMessage, error)
+
+	// CountByConversation 统计会话置顶消息数
+	CountByConversation(ctx context.Context, conversationID string) (int64, error)
+}
+
+// messagePinRepository 置顶消息仓库实现
+type messagePinRepository struct {
+	db *gorm.DB
+}
+
+// NewMessagePinRepository 创建置顶消息仓库
+func NewMessagePinRepository(db *gorm.DB) MessagePinRepository {
+	return &messagePinRepository{db: db}
+}
+
+// Create 置顶消息
+func (r *messagePinRepository) Create(ctx context.Context, pin *model.PinnedMessage) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(pin)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Delete 取消置顶
+func (r *messagePinRepository) Delete(ctx context.Context, conversationID, messageID string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("conversation_id = ? AND message_id = ?", conversationID, messageID).
+		Delete(&model.PinnedMessage{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindByConversation 查询会话置顶消息
+func (r *messagePinRepository) FindByConversation(ctx context.Context, conversationID string) ([]*model.PinnedMessage, error) {
+	var pins []*model.PinnedMessage
+	err := r.db.WithContext(ctx).
+		Where("conversation_id = ?", conversationID).
+		Order("created_at DESC").
+		Find(&pins).Error
+	return pins, err
+}
+
+// CountByConversation 统计会话置顶消息数
+func (r *messagePinRepository) CountByConversation(ctx context.Context, conversationID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.PinnedMessage{}).
+		Where("conversation_id = ?", conversationID).
+		Count(&count).Error
+	return count, err
+}
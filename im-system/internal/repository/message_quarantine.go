@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/d60-lab/im-system/pkg/database"
+)
+
+// CollectionMessageQuarantine 时间超出范围的消息隔离集合（待审核）
+const CollectionMessageQuarantine = "message_quarantine"
+
+// 消息时间偏差方向
+const (
+	ClockSkewFuture = "future" // 超前服务器时间
+	ClockSkewPast   = "past"   // 落后服务器时间
+)
+
+// QuarantinedMessage 被隔离的消息
+type QuarantinedMessage struct {
+	MessageID     string           `bson:"message_id" json:"message_id"`
+	Direction     string           `bson:"direction" json:"direction"` // future/past
+	SkewMs        int64            `bson:"skew_ms" json:"skew_ms"`     // 消息时间与服务器时间的偏差（毫秒，超前为正）
+	Message       *MessageDocument `bson:"message" json:"message"`
+	QuarantinedAt time.Time        `bson:"quarantined_at" json:"quarantined_at"`
+}
+
+// MessageQuarantineRepository 消息隔离仓库接口
+type MessageQuarantineRepository interface {
+	// Save 保存隔离消息（同一消息重复隔离时覆盖）
+	Save(ctx context.Context, msg *QuarantinedMessage) error
+
+	// Find 查询隔离消息，不存在时返回 nil
+	Find(ctx context.Context, messageID string) (*QuarantinedMessage, error)
+
+	// List 分页查询隔离消息（按隔离时间倒序）
+	List(ctx context.Context, offset, limit int) ([]*QuarantinedMessage, int64, error)
+
+	// Delete 删除隔离消息，不存在时返回 false
+	Delete(ctx context.Context, messageID string) (bool, error)
+}
+
+// messageQuarantineRepository 消息隔离仓库实现
+type messageQuarantineRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMessageQuarantineRepository 创建消息隔离仓库
+func NewMessageQuarantineRepository(mongoClient *database.MongoClient) MessageQuarantineRepository {
+	return &messageQuarantineRepository{
+		collection: mongoClient.Collection(CollectionMessageQuarantine),
+	}
+}
+
+// Save 保存隔离消息
+func (r *messageQuarantineRepository) Save(ctx context.Context, msg *QuarantinedMessage) error {
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"message_id": msg.MessageID}, msg, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save quarantined message: %w", err)
+	}
+	return nil
+}
+
+// Find 查询隔离消息
+func (r *messageQuarantineRepository) Find(ctx context.Context, messageID string) (*QuarantinedMessage, error) {
+	var msg QuarantinedMessage
+	err := r.collection.FindOne(ctx, bson.M{"message_id": messageID}).Decode(&msg)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find quarantined message: %w", err)
+	}
+	return &msg, nil
+}
+
+// List 分页查询隔离消息
+func (r *messageQuarantineRepository) List(ctx context.Context, offset, limit int) ([]*QuarantinedMessage, int64, error) {
+	total, err := r.collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count quarantined messages: %w", err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "quarantined_at", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list quarantined messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var msgs []*QuarantinedMessage
+	if err := cursor.All(ctx, &msgs); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode quarantined messages: %w", err)
+	}
+	return msgs, total, nil
+}
+
+// Delete 删除隔离消息
+func (r *messageQuarantineRepository) Delete(ctx context.Context, messageID string) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"message_id": messageID})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete quarantined message: %w", err)
+	}
+	return result.DeletedCount > 0, nil
+}
@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// RegionLocator 返回会话消息所在的存储区域，未知区域或返回空时使用默认区域
+type RegionLocator func(ctx context.Context, conversationID string) string
+
+// regionalMessageRepository 按区域路由的消息仓库：按会话的存储区域读写对应的 MongoDB 集群，
+// 只有消息ID的操作先在默认区域查找，再依次查找其余区域
+type regionalMessageRepository struct {
+	defaultRegion string
+	regions       []string // 默认区域在前
+	repos         map[string]MessageRepository
+	locate        RegionLocator
+}
+
+// NewRegionalMessageRepository 创建按区域路由的消息仓库，repos 须包含默认区域
+func NewRegionalMessageRepository(defaultRegion string, repos map[string]MessageRepository, locate RegionLocator) MessageRepository {
+	regions := []string{defaultRegion}
+	for region := range repos {
+		if region != defaultRegion {
+			regions = append(regions, region)
+		}
+	}
+	return &regionalMessageRepository{
+		defaultRegion: defaultRegion,
+		regions:       regions,
+		repos:         repos,
+		locate:        locate,
+	}
+}
+
+// repoFor 会话所在区域的仓库
+func (r *regionalMessageRepository) repoFor(ctx context.Context, conversationID string) MessageRepository {
+	if repo, ok := r.repos[r.locate(ctx, conversationID)]; ok {
+		return repo
+	}
+	return r.repos[r.defaultRegion]
+}
+
+// Save 保存消息到会话所在区域
+func (r *regionalMessageRepository) Save(ctx context.Context, msg *MessageDocument) error {
+	return r.repoFor(ctx, msg.ConversationID).Save(ctx, msg)
+}
+
+// SaveBatch 按会话所在区域分组批量保存
+func (r *regionalMessageRepository) SaveBatch(ctx context.Context, msgs []*MessageDocument) error {
+	batches := make(map[MessageRepository][]*MessageDocument)
+	for _, msg := range msgs {
+		repo := r.repoFor(ctx, msg.ConversationID)
+		batches[repo] = append(batches[repo], msg)
+	}
+	for repo, batch := range batches {
+		if err := repo.SaveBatch(ctx, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindByConversation 按会话查询消息
+func (r *regionalMessageRepository) FindByConversation(ctx context.Context, conversationID string, lastSeq int64, limit int) ([]*MessageDocument, error) {
+	return r.repoFor(ctx, conversationID).FindByConversation(ctx, conversationID, lastSeq, limit)
+}
+
+// FindByConversationRange 按时间范围查询会话消息
+func (r *regionalMessageRepository) FindByConversationRange(ctx context.Context, conversationID string, from, to time.Time, limit int) ([]*MessageDocument, error) {
+	return r.repoFor(ctx, conversationID).FindByConversationRange(ctx, conversationID, from, to, limit)
+}
+
+// SearchInConversation 在会话内按关键字搜索
+func (r *regionalMessageRepository) SearchInConversation(ctx context.Context, conversationID, keyword string, before *MessageCursor, limit int) ([]*MessageDocument, error) {
+	return r.repoFor(ctx, conversationID).SearchInConversation(ctx, conversationID, keyword, before, limit)
+}
+
+// Search 在各区域检索后按时间倒序合并分页
+func (r *regionalMessageRepository) Search(ctx context.Context, query *MessageSearchQuery) ([]*MessageDocument, int64, error) {
+	regional := *query
+	regional.Offset, regional.Limit = 0, query.Offset+query.Limit
+
+	var merged []*MessageDocument
+	var total int64
+	err := r.each(func(repo MessageRepository) error {
+		docs, n, err := repo.Search(ctx, &regional)
+		merged = append(merged, docs...)
+		total += n
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		if !merged[i].CreatedAt.Equal(merged[j].CreatedAt) {
+			return merged[i].CreatedAt.After(merged[j].CreatedAt)
+		}
+		return merged[i].MessageID > merged[j].MessageID
+	})
+	if query.Offset >= len(merged) {
+		return nil, total, nil
+	}
+	merged = merged[query.Offset:]
+	if len(merged) > query.Limit {
+		merged = merged[:query.Limit]
+	}
+	return merged, total, nil
+}
+
+// FindAround 查询会话内锚点消息前后的消息
+func (r *regionalMessageRepository) FindAround(ctx context.Context, conversationID string, anchor MessageCursor, before, after int) ([]*MessageDocument, []*MessageDocument, error) {
+	return r.repoFor(ctx, conversationID).FindAround(ctx, conversationID, anchor, before, after)
+}
+
+// FindByGroup 按群组查询消息
+func (r *regionalMessageRepository) FindByGroup(ctx context.Context, groupID string, lastSeq int64, limit int) ([]*MessageDocument, error) {
+	return r.repoFor(ctx, model.NewGroupConversationID(groupID).String()).FindByGroup(ctx, groupID, lastSeq, limit)
+}
+
+// FindByPrivateChat 按私聊查询消息
+func (r *regionalMessageRepository) FindByPrivateChat(ctx context.Context, userID1, userID2 string, lastSeq int64, limit int) ([]*MessageDocument, error) {
+	return r.repoFor(ctx, model.NewSingleConversationID(userID1, userID2).String()).FindByPrivateChat(ctx, userID1, userID2, lastSeq, limit)
+}
+
+// FindByMessageID 按消息ID查询（依次查找各区域）
+func (r *regionalMessageRepository) FindByMessageID(ctx context.Context, messageID string) (*MessageDocument, error) {
+	for _, region := range r.regions {
+		doc, err := r.repos[region].FindByMessageID(ctx, messageID)
+		if err != nil || doc != nil {
+			return doc, err
+		}
+	}
+	return nil, nil
+}
+
+// UpdateStatus 更新消息状态（消息只存在于一个区域，其余区域不匹配）
+func (r *regionalMessageRepository) UpdateStatus(ctx context.Context, messageID string, status int) error {
+	return r.each(func(repo MessageRepository) error {
+		return repo.UpdateStatus(ctx, messageID, status)
+	})
+}
+
+// Revoke 撤回消息
+func (r *regionalMessageRepository) Revoke(ctx context.Context, messageID string) error {
+	return r.each(func(repo MessageRepository) error {
+		return repo.Revoke(ctx, messageID)
+	})
+}
+
+// Cancel 标记消息已取消
+func (r *regionalMessageRepository) Cancel(ctx context.Context, messageID string) (bool, error) {
+	cancelled := false
+	err := r.each(func(repo MessageRepository) error {
+		ok, err := repo.Cancel(ctx, messageID)
+		cancelled = cancelled || ok
+		return err
+	})
+	return cancelled, err
+}
+
+// MarkFailed 标记消息发送失败
+func (r *regionalMessageRepository) MarkFailed(ctx context.Context, messageID string, code int, reason string) (bool, error) {
+	marked := false
+	err := r.each(func(repo MessageRepository) error {
+		ok, err := repo.MarkFailed(ctx, messageID, code, reason)
+		marked = marked || ok
+		return err
+	})
+	return marked, err
+}
+
+// MarkDelivered 记录接收者已确认收到消息
+func (r *regionalMessageRepository) MarkDelivered(ctx context.Context, messageID, userID string) (bool, error) {
+	marked := false
+	err := r.each(func(repo MessageRepository) error {
+		ok, err := repo.MarkDelivered(ctx, messageID, userID)
+		marked = marked || ok
+		return err
+	})
+	return marked, err
+}
+
+// FindForPurge 查询会话内待清除的消息
+func (r *regionalMessageRepository) FindForPurge(ctx context.Context, conversationID string, filter *MessagePurgeFilter, limit int) ([]*MessageDocument, error) {
+	return r.repoFor(ctx, conversationID).FindForPurge(ctx, conversationID, filter, limit)
+}
+
+// Purge 清除会话内的消息
+func (r *regionalMessageRepository) Purge(ctx context.Context, conversationID string, messageIDs []string, operatorID string) (int64, error) {
+	return r.repoFor(ctx, conversationID).Purge(ctx, conversationID, messageIDs, operatorID)
+}
+
+// Delete 删除消息
+func (r *regionalMessageRepository) Delete(ctx context.Context, messageID string) error {
+	return r.each(func(repo MessageRepository) error {
+		return repo.Delete(ctx, messageID)
+	})
+}
+
+// DeleteByUser 在各区域删除用户的消息
+func (r *regionalMessageRepository) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	var deleted int64
+	err := r.each(func(repo MessageRepository) error {
+		n, err := repo.DeleteByUser(ctx, userID)
+		deleted += n
+		return err
+	})
+	return deleted, err
+}
+
+// CountByConversation 统计会话消息数
+func (r *regionalMessageRepository) CountByConversation(ctx context.Context, conversationID string) (int64, error) {
+	return r.repoFor(ctx, conversationID).CountByConversation(ctx, conversationID)
+}
+
+// each 在各区域依次执行操作
+func (r *regionalMessageRepository) each(fn func(repo MessageRepository) error) error {
+	for _, region := range r.regions {
+		if err := fn(r.repos[region]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Watch 同时订阅各区域的变更流，事件串行交给 handler 处理；
+// 续订位置为各区域 resumeToken 的 JSON 对象（兼容单区域时的原始 resumeToken，视为默认区域的位置）
+func (r *regionalMessageRepository) Watch(ctx context.Context, resumeToken []byte, handler MessageChangeHandler) error {
+	tokens := make(map[string][]byte)
+	if len(resumeToken) > 0 {
+		if err := json.Unmarshal(resumeToken, &tokens); err != nil {
+			tokens = map[string][]byte{r.defaultRegion: resumeToken}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := make(map[string][]byte, len(tokens))
+	for region, token := range tokens {
+		start[region] = token
+	}
+
+	var mu sync.Mutex
+	errCh := make(chan error, len(r.regions))
+	for _, region := range r.regions {
+		region := region
+		go func() {
+			errCh <- r.repos[region].Watch(ctx, start[region], func(ctx context.Context, event *MessageChangeEvent) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if len(event.ResumeToken) > 0 {
+					tokens[region] = event.ResumeToken
+					combined, err := json.Marshal(tokens)
+					if err != nil {
+						return err
+					}
+					event.ResumeToken = combined
+				}
+				return handler(ctx, event)
+			})
+		}()
+	}
+
+	// 任一区域的订阅结束即取消全部订阅，由调用方重新订阅
+	err := <-errCh
+	cancel()
+	for i := 1; i < len(r.regions); i++ {
+		<-errCh
+	}
+	return err
+}
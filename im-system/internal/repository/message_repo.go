@@ -4,6 +4,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -30,12 +31,38 @@ type MessageDocument struct {
 	To             string                 `bson:"to"`
 	GroupID        string                 `bson:"group_id,omitempty"`
 	Content        map[string]interface{} `bson:"content"`
+	ContentVersion int                    `bson:"content_version,omitempty"` // 内容结构版本，未记录时为0（历史文档）
 	Seq            int64                  `bson:"seq"`
 	Status         int                    `bson:"status"`
 	Revoked        bool                   `bson:"revoked"`
+	Cancelled      bool                   `bson:"cancelled,omitempty"` // 投递前被发送者取消
+	Failed         bool                   `bson:"failed,omitempty"`    // 回ACK后被拒绝（审核、禁言、成员资格等）
+	FailCode       int                    `bson:"fail_code,omitempty"`
+	FailReason     string                 `bson:"fail_reason,omitempty"`
+	DeliveredTo    []string               `bson:"delivered_to,omitempty"` // 已确认收到的接收者（QoS1 投递确认）
 	CreatedAt      time.Time              `bson:"created_at"`
 	UpdatedAt      time.Time              `bson:"updated_at"`
 	ExpireAt       *time.Time             `bson:"expire_at,omitempty"` // TTL索引字段
+
+	MentionTargets []string `bson:"mention_targets,omitempty"` // 发送时解析的提及对象（含角色、提及组展开后的用户）
+
+	// 管理员清除（同时标记为已撤回，内容清空，只保留墓碑）
+	Purged   bool       `bson:"purged,omitempty"`
+	PurgedBy string     `bson:"purged_by,omitempty"`
+	PurgedAt *time.Time `bson:"purged_at,omitempty"`
+}
+
+// 消息状态
+const (
+	MessageStatusSent      = 1 // 已发送
+	MessageStatusDelivered = 2 // 已送达（至少一个接收者确认收到）
+)
+
+// UpgradeContent 将内容升级到最新结构版本（读取时调用，历史文档按最新结构返回）
+func (d *MessageDocument) UpgradeContent() {
+	if d.ContentVersion < model.ContentSchemaVersion(model.MessageType(d.Type)) {
+		d.Content, d.ContentVersion = model.UpgradeContent(model.MessageType(d.Type), d.ContentVersion, d.Content)
+	}
 }
 
 // ToMessage 转换为传输层 Message
@@ -52,6 +79,7 @@ func (d *MessageDocument) ToMessage() *model.Message {
 		Seq:            d.Seq,
 		Revoked:        d.Revoked,
 		CreatedAt:      d.CreatedAt,
+		MentionTargets: d.MentionTargets,
 	}
 }
 
@@ -76,9 +104,11 @@ func NewMessageDocument(msg *model.Message) *MessageDocument {
 		To:             msg.To,
 		GroupID:        msg.GroupID,
 		Content:        content,
+		ContentVersion: model.ContentSchemaVersion(msg.Type),
 		Seq:            msg.Seq,
 		Status:         1, // 默认已发送
 		Revoked:        msg.Revoked,
+		MentionTargets: msg.MentionTargets,
 		CreatedAt:      now,
 		UpdatedAt:      now,
 	}
@@ -104,6 +134,18 @@ type MessageRepository interface {
 	// FindByConversation 按会话查询消息
 	FindByConversation(ctx context.Context, conversationID string, lastSeq int64, limit int) ([]*MessageDocument, error)
 
+	// FindByConversationRange 按时间范围查询会话消息（按时间升序，不含已撤回消息）
+	FindByConversationRange(ctx context.Context, conversationID string, from, to time.Time, limit int) ([]*MessageDocument, error)
+
+	// SearchInConversation 在会话内按关键字搜索文本消息（不区分大小写，按时间倒序，before 非空时只返回该位置之前的消息）
+	SearchInConversation(ctx context.Context, conversationID, keyword string, before *MessageCursor, limit int) ([]*MessageDocument, error)
+
+	// Search 跨会话全文检索文本消息（按时间倒序），返回当前页及命中总数（实现 MessageSearchIndex）
+	Search(ctx context.Context, query *MessageSearchQuery) ([]*MessageDocument, int64, error)
+
+	// FindAround 查询会话内锚点消息前后的消息（均按时间升序，不含已撤回消息）
+	FindAround(ctx context.Context, conversationID string, anchor MessageCursor, before, after int) (older, newer []*MessageDocument, err error)
+
 	// FindByGroup 按群组查询消息
 	FindByGroup(ctx context.Context, groupID string, lastSeq int64, limit int) ([]*MessageDocument, error)
 
@@ -119,14 +161,64 @@ type MessageRepository interface {
 	// Revoke 撤回消息
 	Revoke(ctx context.Context, messageID string) error
 
+	// Cancel 标记消息已取消（投递前被发送者取消），消息已撤回或已取消时返回 false
+	Cancel(ctx context.Context, messageID string) (bool, error)
+
+	// MarkFailed 标记消息发送失败（记录错误码和原因），已标记时返回 false
+	MarkFailed(ctx context.Context, messageID string, code int, reason string) (bool, error)
+
+	// MarkDelivered 记录接收者已确认收到消息并将状态更新为已送达，该接收者已记录时返回 false
+	MarkDelivered(ctx context.Context, messageID, userID string) (bool, error)
+
+	// FindForPurge 按条件查询会话内待清除的消息（不含已清除的消息，按时间升序）
+	FindForPurge(ctx context.Context, conversationID string, filter *MessagePurgeFilter, limit int) ([]*MessageDocument, error)
+
+	// Purge 清除会话内的消息：清空内容并标记为已清除、已撤回，返回清除数量
+	Purge(ctx context.Context, conversationID string, messageIDs []string, operatorID string) (int64, error)
+
 	// Delete 删除消息
 	Delete(ctx context.Context, messageID string) error
 
+	// DeleteByUser 删除用户发送及单聊收到的全部消息，返回删除数量
+	DeleteByUser(ctx context.Context, userID string) (int64, error)
+
 	// CountByConversation 统计会话消息数
 	CountByConversation(ctx context.Context, conversationID string) (int64, error)
 
-	// EnsureIndexes 确保索引存在
-	EnsureIndexes(ctx context.Context) error
+	// Watch 订阅消息集合的变更流（需要副本集），阻塞直到 ctx 取消或出错
+	// resumeToken 为空时从当前位置开始，handler 返回错误时中止订阅
+	Watch(ctx context.Context, resumeToken []byte, handler MessageChangeHandler) error
+}
+
+// MessagePurgeFilter 消息清除条件，各条件同时满足，为空的条件不限制
+type MessagePurgeFilter struct {
+	SenderID   string
+	Since      time.Time
+	Until      time.Time
+	Keyword    string   // 文本消息内容包含关键字（不区分大小写）
+	MessageIDs []string // 指定消息ID
+}
+
+// MessageCursor 会话内消息位置（按创建时间排序，同一时间按消息ID排序）
+type MessageCursor struct {
+	CreatedAt time.Time
+	MessageID string
+}
+
+// beforeFilter 位于游标之前的消息条件
+func (c MessageCursor) beforeFilter() bson.M {
+	return bson.M{"$or": []bson.M{
+		{"created_at": bson.M{"$lt": c.CreatedAt}},
+		{"created_at": c.CreatedAt, "message_id": bson.M{"$lt": c.MessageID}},
+	}}
+}
+
+// afterFilter 位于游标之后的消息条件
+func (c MessageCursor) afterFilter() bson.M {
+	return bson.M{"$or": []bson.M{
+		{"created_at": bson.M{"$gt": c.CreatedAt}},
+		{"created_at": c.CreatedAt, "message_id": bson.M{"$gt": c.MessageID}},
+	}}
 }
 
 // messageRepository 消息仓库实现
@@ -183,8 +275,10 @@ func (r *messageRepository) SaveBatch(ctx context.Context, msgs []*MessageDocume
 // FindByConversation 按会话查询消息
 func (r *messageRepository) FindByConversation(ctx context.Context, conversationID string, lastSeq int64, limit int) ([]*MessageDocument, error) {
 	filter := bson.M{
-		"conversation_id": conversationID,
+		"conversation_id": bson.M{"$in": conversationIDAliases(conversationID)},
 		"revoked":         false,
+		"cancelled":       bson.M{"$ne": true},
+		"failed":          bson.M{"$ne": true},
 	}
 
 	if lastSeq > 0 {
@@ -198,11 +292,89 @@ func (r *messageRepository) FindByConversation(ctx context.Context, conversation
 	return r.findMessages(ctx, filter, opts)
 }
 
+// FindByConversationRange 按时间范围查询会话消息
+func (r *messageRepository) FindByConversationRange(ctx context.Context, conversationID string, from, to time.Time, limit int) ([]*MessageDocument, error) {
+	filter := bson.M{
+		"conversation_id": bson.M{"$in": conversationIDAliases(conversationID)},
+		"revoked":         false,
+		"cancelled":       bson.M{"$ne": true},
+		"failed":          bson.M{"$ne": true},
+		"created_at":      bson.M{"$gte": from, "$lt": to},
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "seq", Value: 1}}).
+		SetLimit(int64(limit))
+
+	return r.findMessages(ctx, filter, opts)
+}
+
+// SearchInConversation 在会话内按关键字搜索文本消息
+func (r *messageRepository) SearchInConversation(ctx context.Context, conversationID, keyword string, before *MessageCursor, limit int) ([]*MessageDocument, error) {
+	filter := bson.M{
+		"conversation_id": bson.M{"$in": conversationIDAliases(conversationID)},
+		"revoked":         false,
+		"cancelled":       bson.M{"$ne": true},
+		"failed":          bson.M{"$ne": true},
+		"content.text":    bson.M{"$regex": regexp.QuoteMeta(keyword), "$options": "i"},
+	}
+	if before != nil {
+		filter["$and"] = []bson.M{before.beforeFilter()}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "message_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	return r.findMessages(ctx, filter, opts)
+}
+
+// FindAround 查询会话内锚点消息前后的消息
+func (r *messageRepository) FindAround(ctx context.Context, conversationID string, anchor MessageCursor, before, after int) ([]*MessageDocument, []*MessageDocument, error) {
+	base := bson.M{
+		"conversation_id": bson.M{"$in": conversationIDAliases(conversationID)},
+		"revoked":         false,
+		"cancelled":       bson.M{"$ne": true},
+		"failed":          bson.M{"$ne": true},
+	}
+
+	var older, newer []*MessageDocument
+	if before > 0 {
+		filter := bson.M{"$and": []bson.M{base, anchor.beforeFilter()}}
+		opts := options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "message_id", Value: -1}}).
+			SetLimit(int64(before))
+		docs, err := r.findMessages(ctx, filter, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		// 倒序查询最近的消息后翻转为升序
+		for i, j := 0, len(docs)-1; i < j; i, j = i+1, j-1 {
+			docs[i], docs[j] = docs[j], docs[i]
+		}
+		older = docs
+	}
+	if after > 0 {
+		filter := bson.M{"$and": []bson.M{base, anchor.afterFilter()}}
+		opts := options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "message_id", Value: 1}}).
+			SetLimit(int64(after))
+		docs, err := r.findMessages(ctx, filter, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		newer = docs
+	}
+	return older, newer, nil
+}
+
 // FindByGroup 按群组查询消息
 func (r *messageRepository) FindByGroup(ctx context.Context, groupID string, lastSeq int64, limit int) ([]*MessageDocument, error) {
 	filter := bson.M{
-		"group_id": groupID,
-		"revoked":  false,
+		"group_id":  groupID,
+		"revoked":   false,
+		"cancelled": bson.M{"$ne": true},
+		"failed":    bson.M{"$ne": true},
 	}
 
 	if lastSeq > 0 {
@@ -223,8 +395,10 @@ func (r *messageRepository) FindByPrivateChat(ctx context.Context, userID1, user
 			{"from": userID1, "to": userID2},
 			{"from": userID2, "to": userID1},
 		},
-		"group_id": bson.M{"$in": []interface{}{"", nil}},
-		"revoked":  false,
+		"group_id":  bson.M{"$in": []interface{}{"", nil}},
+		"revoked":   false,
+		"cancelled": bson.M{"$ne": true},
+		"failed":    bson.M{"$ne": true},
 	}
 
 	if lastSeq > 0 {
@@ -250,6 +424,9 @@ func (r *messageRepository) findMessages(ctx context.Context, filter bson.M, opt
 	if err := cursor.All(ctx, &messages); err != nil {
 		return nil, fmt.Errorf("failed to decode messages: %w", err)
 	}
+	for _, msg := range messages {
+		msg.UpgradeContent()
+	}
 
 	return messages, nil
 }
@@ -264,6 +441,7 @@ func (r *messageRepository) FindByMessageID(ctx context.Context, messageID strin
 		}
 		return nil, fmt.Errorf("failed to find message: %w", err)
 	}
+	msg.UpgradeContent()
 	return &msg, nil
 }
 
@@ -299,6 +477,130 @@ func (r *messageRepository) Revoke(ctx context.Context, messageID string) error
 	return nil
 }
 
+// Cancel 标记消息已取消
+func (r *messageRepository) Cancel(ctx context.Context, messageID string) (bool, error) {
+	filter := bson.M{
+		"message_id": messageID,
+		"revoked":    false,
+		"cancelled":  bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"cancelled":  true,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel message: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// MarkFailed 标记消息发送失败
+func (r *messageRepository) MarkFailed(ctx context.Context, messageID string, code int, reason string) (bool, error) {
+	filter := bson.M{
+		"message_id": messageID,
+		"failed":     bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"failed":      true,
+			"fail_code":   code,
+			"fail_reason": reason,
+			"updated_at":  time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark message failed: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// MarkDelivered 记录接收者已确认收到消息
+func (r *messageRepository) MarkDelivered(ctx context.Context, messageID, userID string) (bool, error) {
+	filter := bson.M{
+		"message_id":   messageID,
+		"delivered_to": bson.M{"$ne": userID},
+	}
+	update := bson.M{
+		"$addToSet": bson.M{"delivered_to": userID},
+		"$max":      bson.M{"status": MessageStatusDelivered},
+		"$set":      bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark message delivered: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// FindForPurge 按条件查询会话内待清除的消息
+func (r *messageRepository) FindForPurge(ctx context.Context, conversationID string, filter *MessagePurgeFilter, limit int) ([]*MessageDocument, error) {
+	query := bson.M{
+		"conversation_id": bson.M{"$in": conversationIDAliases(conversationID)},
+		"purged":          bson.M{"$ne": true},
+	}
+	if filter.SenderID != "" {
+		query["from"] = filter.SenderID
+	}
+	if !filter.Since.IsZero() || !filter.Until.IsZero() {
+		createdAt := bson.M{}
+		if !filter.Since.IsZero() {
+			createdAt["$gte"] = filter.Since
+		}
+		if !filter.Until.IsZero() {
+			createdAt["$lt"] = filter.Until
+		}
+		query["created_at"] = createdAt
+	}
+	if filter.Keyword != "" {
+		query["content.text"] = bson.M{"$regex": regexp.QuoteMeta(filter.Keyword), "$options": "i"}
+	}
+	if len(filter.MessageIDs) > 0 {
+		query["message_id"] = bson.M{"$in": filter.MessageIDs}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "message_id", Value: 1}}).
+		SetLimit(int64(limit))
+
+	return r.findMessages(ctx, query, opts)
+}
+
+// Purge 清除会话内的消息
+func (r *messageRepository) Purge(ctx context.Context, conversationID string, messageIDs []string, operatorID string) (int64, error) {
+	if len(messageIDs) == 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	filter := bson.M{
+		"conversation_id": bson.M{"$in": conversationIDAliases(conversationID)},
+		"message_id":      bson.M{"$in": messageIDs},
+		"purged":          bson.M{"$ne": true},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"content":    bson.M{},
+			"revoked":    true,
+			"purged":     true,
+			"purged_by":  operatorID,
+			"purged_at":  now,
+			"updated_at": now,
+		},
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge messages: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
+
 // Delete 删除消息
 func (r *messageRepository) Delete(ctx context.Context, messageID string) error {
 	_, err := r.collection.DeleteOne(ctx, bson.M{"message_id": messageID})
@@ -308,62 +610,25 @@ func (r *messageRepository) Delete(ctx context.Context, messageID string) error
 	return nil
 }
 
+// DeleteByUser 删除用户发送及单聊收到的全部消息
+func (r *messageRepository) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"$or": []bson.M{{"from": userID}, {"to": userID}}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user messages: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
 // CountByConversation 统计会话消息数
 func (r *messageRepository) CountByConversation(ctx context.Context, conversationID string) (int64, error) {
 	count, err := r.collection.CountDocuments(ctx, bson.M{
-		"conversation_id": conversationID,
+		"conversation_id": bson.M{"$in": conversationIDAliases(conversationID)},
 		"revoked":         false,
+		"cancelled":       bson.M{"$ne": true},
+		"failed":          bson.M{"$ne": true},
 	})
 	if err != nil {
 		return 0, fmt.Errorf("failed to count messages: %w", err)
 	}
 	return count, nil
 }
-
-// EnsureIndexes 确保索引存在
-func (r *messageRepository) EnsureIndexes(ctx context.Context) error {
-	indexes := []mongo.IndexModel{
-		// 消息ID唯一索引
-		{
-			Keys:    bson.D{{Key: "message_id", Value: 1}},
-			Options: options.Index().SetUnique(true),
-		},
-		// 会话ID + 序号复合索引（用于分页查询）
-		{
-			Keys: bson.D{
-				{Key: "conversation_id", Value: 1},
-				{Key: "seq", Value: -1},
-			},
-		},
-		// 群组ID + 创建时间索引
-		{
-			Keys: bson.D{
-				{Key: "group_id", Value: 1},
-				{Key: "created_at", Value: -1},
-			},
-		},
-		// 发送者索引
-		{
-			Keys: bson.D{{Key: "from", Value: 1}},
-		},
-		// 接收者索引
-		{
-			Keys: bson.D{{Key: "to", Value: 1}},
-		},
-		// TTL索引（自动清理过期消息）
-		{
-			Keys:    bson.D{{Key: "expire_at", Value: 1}},
-			Options: options.Index().SetExpireAfterSeconds(0),
-		},
-		// 创建时间索引
-		{
-			Keys: bson.D{{Key: "created_at", Value: -1}},
-		},
-	}
-
-	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
-	if err != nil {
-		return fmt.Errorf("failed to create indexes: %w", err)
-	}
-	return nil
-}
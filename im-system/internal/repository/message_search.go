@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MessageSearchQuery 跨会话全文检索条件（只检索文本消息，不含已撤回、已取消、发送失败的消息）
+type MessageSearchQuery struct {
+	Keyword string
+
+	// 可见范围：指定 ConversationIDs 时只检索这些会话（调用方已校验权限，须包含别名），
+	// 否则检索 UserID 参与的私聊及 GroupIDs 中的群聊
+	ConversationIDs []string
+	UserID          string
+	GroupIDs        []string
+
+	SenderID string
+	Since    time.Time // 为零值时不限
+	Until    time.Time // 为零值时不限（不含）
+
+	Offset int
+	Limit  int
+}
+
+// MessageSearchIndex 消息全文检索后端，结果按时间倒序
+type MessageSearchIndex interface {
+	// Search 检索消息，返回当前页及命中总数
+	Search(ctx context.Context, query *MessageSearchQuery) ([]*MessageDocument, int64, error)
+}
+
+// Search 基于 MongoDB 文本索引检索消息
+// 文本索引按空格和标点分词，不支持中日韩文分词，关键字包含中日韩文字时改用正则匹配
+func (r *messageRepository) Search(ctx context.Context, query *MessageSearchQuery) ([]*MessageDocument, int64, error) {
+	filter := query.mongoFilter()
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+	if total == 0 || int64(query.Offset) >= total {
+		return nil, total, nil
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "message_id", Value: -1}}).
+		SetSkip(int64(query.Offset)).
+		SetLimit(int64(query.Limit))
+	docs, err := r.findMessages(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return docs, total, nil
+}
+
+// mongoFilter 转换为 MongoDB 查询条件
+func (q *MessageSearchQuery) mongoFilter() bson.M {
+	conditions := []bson.M{
+		{"revoked": false},
+		{"cancelled": bson.M{"$ne": true}},
+		{"failed": bson.M{"$ne": true}},
+	}
+
+	if len(q.ConversationIDs) > 0 {
+		conditions = append(conditions, bson.M{"conversation_id": bson.M{"$in": q.ConversationIDs}})
+	} else {
+		// 私聊消息没有 group_id 字段
+		scope := []bson.M{
+			{"group_id": bson.M{"$in": bson.A{nil, ""}}, "from": q.UserID},
+			{"group_id": bson.M{"$in": bson.A{nil, ""}}, "to": q.UserID},
+		}
+		if len(q.GroupIDs) > 0 {
+			scope = append(scope, bson.M{"group_id": bson.M{"$in": q.GroupIDs}})
+		}
+		conditions = append(conditions, bson.M{"$or": scope})
+	}
+
+	if q.SenderID != "" {
+		conditions = append(conditions, bson.M{"from": q.SenderID})
+	}
+	if !q.Since.IsZero() {
+		conditions = append(conditions, bson.M{"created_at": bson.M{"$gte": q.Since}})
+	}
+	if !q.Until.IsZero() {
+		conditions = append(conditions, bson.M{"created_at": bson.M{"$lt": q.Until}})
+	}
+
+	filter := bson.M{}
+	if containsCJK(q.Keyword) {
+		conditions = append(conditions, bson.M{"content.text": bson.M{"$regex": regexp.QuoteMeta(q.Keyword), "$options": "i"}})
+	} else {
+		// 按短语匹配，多个词须连续出现
+		filter["$text"] = bson.M{"$search": `"` + strings.ReplaceAll(q.Keyword, `"`, " ") + `"`}
+	}
+	filter["$and"] = conditions
+	return filter
+}
+
+// containsCJK 是否包含中日韩文字
+func containsCJK(s string) bool {
+	for _, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ElasticsearchConfig Elasticsearch 检索后端配置
+type ElasticsearchConfig struct {
+	URL      string        // 集群地址，如 http://localhost:9200
+	Index    string        // 消息索引名
+	Username string        // Basic 认证用户名，为空时不认证
+	Password string        // Basic 认证密码
+	Analyzer string        // 消息文本分词器，中文建议安装 IK 插件后使用 ik_max_word
+	Timeout  time.Duration // 请求超时
+}
+
+// MessageSearchIndexer 由消息变更流增量写入的检索索引
+type MessageSearchIndexer interface {
+	MessageSearchIndex
+
+	// EnsureIndex 索引不存在时按映射创建
+	EnsureIndex(ctx context.Context) error
+
+	// HandleChange 同步消息变更：可检索的文本消息写入索引，撤回、取消、失败、删除的消息从索引移除
+	HandleChange(ctx context.Context, event *MessageChangeEvent) error
+}
+
+// esMessageDocument 索引中的消息文档（content 只存储不索引，用于返回结果）
+type esMessageDocument struct {
+	MessageID      string                 `json:"message_id"`
+	ConversationID string                 `json:"conversation_id"`
+	GroupID        string                 `json:"group_id,omitempty"`
+	From           string                 `json:"from"`
+	To             string                 `json:"to"`
+	Type           int                    `json:"type"`
+	Text           string                 `json:"text"`
+	Content        map[string]interface{} `json:"content"`
+	ContentVersion int                    `json:"content_version,omitempty"`
+	Seq            int64                  `json:"seq"`
+	Status         int                    `json:"status"`
+	CreatedAt      int64                  `json:"created_at"` // 毫秒时间戳
+}
+
+// esSearchResponse 检索响应
+type esSearchResponse struct {
+	Hits struct {
+		Total struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+		Hits []struct {
+			Source esMessageDocument `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// elasticsearchMessageIndex Elasticsearch 检索后端实现（通过 REST API 访问）
+type elasticsearchMessageIndex struct {
+	config *ElasticsearchConfig
+	client *http.Client
+}
+
+// NewElasticsearchMessageIndex 创建 Elasticsearch 检索后端
+func NewElasticsearchMessageIndex(config *ElasticsearchConfig) (MessageSearchIndexer, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("elasticsearch url is required")
+	}
+	if config.Index == "" {
+		config.Index = "im_messages"
+	}
+	if config.Analyzer == "" {
+		config.Analyzer = "standard"
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &elasticsearchMessageIndex{
+		config: config,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// EnsureIndex 索引不存在时按映射创建
+func (e *elasticsearchMessageIndex) EnsureIndex(ctx context.Context) error {
+	status, _, err := e.do(ctx, http.MethodHead, "", nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		return nil
+	}
+
+	keyword := map[string]string{"type": "keyword"}
+	mapping := map[string]interface{}{
+		"mappings": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"message_id":      keyword,
+				"conversation_id": keyword,
+				"group_id":        keyword,
+				"from":            keyword,
+				"to":              keyword,
+				"type":            map[string]string{"type": "integer"},
+				"text":            map[string]string{"type": "text", "analyzer": e.config.Analyzer},
+				"content":         map[string]interface{}{"type": "object", "enabled": false},
+				"content_version": map[string]interface{}{"type": "integer", "index": false},
+				"seq":             map[string]string{"type": "long"},
+				"status":          map[string]interface{}{"type": "integer", "index": false},
+				"created_at":      map[string]string{"type": "date", "format": "epoch_millis"},
+			},
+		},
+	}
+	status, body, err := e.do(ctx, http.MethodPut, "", mapping)
+	if err != nil {
+		return err
+	}
+	// 多节点同时启动时索引可能已由其他节点创建
+	if status >= 300 && !strings.Contains(string(body), "resource_already_exists_exception") {
+		return fmt.Errorf("create elasticsearch index %s: status %d: %s", e.config.Index, status, body)
+	}
+	return nil
+}
+
+// HandleChange 同步消息变更，索引文档ID为消息的 MongoDB _id（删除事件没有前镜像时也能移除）
+func (e *elasticsearchMessageIndex) HandleChange(ctx context.Context, event *MessageChangeEvent) error {
+	path := "/_doc/" + event.DocumentID.Hex()
+	doc := event.Document
+	if event.Op != MessageChangeDelete && doc == nil {
+		return nil
+	}
+	if event.Op == MessageChangeDelete || !isSearchable(doc) {
+		status, body, err := e.do(ctx, http.MethodDelete, path, nil)
+		if err != nil {
+			return err
+		}
+		if status >= 300 && status != http.StatusNotFound {
+			return fmt.Errorf("delete message %s from index: status %d: %s", event.DocumentID.Hex(), status, body)
+		}
+		return nil
+	}
+
+	text, _ := doc.Content["text"].(string)
+	status, body, err := e.do(ctx, http.MethodPut, path, &esMessageDocument{
+		MessageID:      doc.MessageID,
+		ConversationID: doc.ConversationID,
+		GroupID:        doc.GroupID,
+		From:           doc.From,
+		To:             doc.To,
+		Type:           doc.Type,
+		Text:           text,
+		Content:        doc.Content,
+		ContentVersion: doc.ContentVersion,
+		Seq:            doc.Seq,
+		Status:         doc.Status,
+		CreatedAt:      doc.CreatedAt.UnixMilli(),
+	})
+	if err != nil {
+		return err
+	}
+	if status >= 300 {
+		return fmt.Errorf("index message %s: status %d: %s", doc.MessageID, status, body)
+	}
+	return nil
+}
+
+// Search 检索消息
+func (e *elasticsearchMessageIndex) Search(ctx context.Context, query *MessageSearchQuery) ([]*MessageDocument, int64, error) {
+	filters := []interface{}{}
+	if len(query.ConversationIDs) > 0 {
+		filters = append(filters, esTerms("conversation_id", query.ConversationIDs))
+	} else {
+		scope := []interface{}{
+			map[string]interface{}{"bool": map[string]interface{}{
+				"must_not":             esExists("group_id"),
+				"should":               []interface{}{esTerm("from", query.UserID), esTerm("to", query.UserID)},
+				"minimum_should_match": 1,
+			}},
+		}
+		if len(query.GroupIDs) > 0 {
+			scope = append(scope, esTerms("group_id", query.GroupIDs))
+		}
+		filters = append(filters, map[string]interface{}{"bool": map[string]interface{}{
+			"should":               scope,
+			"minimum_should_match": 1,
+		}})
+	}
+	if query.SenderID != "" {
+		filters = append(filters, esTerm("from", query.SenderID))
+	}
+	if !query.Since.IsZero() || !query.Until.IsZero() {
+		rng := map[string]interface{}{}
+		if !query.Since.IsZero() {
+			rng["gte"] = query.Since.UnixMilli()
+		}
+		if !query.Until.IsZero() {
+			rng["lt"] = query.Until.UnixMilli()
+		}
+		filters = append(filters, map[string]interface{}{"range": map[string]interface{}{"created_at": rng}})
+	}
+
+	request := map[string]interface{}{
+		"from":             query.Offset,
+		"size":             query.Limit,
+		"track_total_hits": true,
+		"sort": []interface{}{
+			map[string]string{"created_at": "desc"},
+			map[string]string{"message_id": "desc"},
+		},
+		"query": map[string]interface{}{"bool": map[string]interface{}{
+			"must":   map[string]interface{}{"match_phrase": map[string]string{"text": query.Keyword}},
+			"filter": filters,
+		}},
+	}
+	status, body, err := e.do(ctx, http.MethodPost, "/_search", request)
+	if err != nil {
+		return nil, 0, err
+	}
+	if status >= 300 {
+		return nil, 0, fmt.Errorf("search elasticsearch index %s: status %d: %s", e.config.Index, status, body)
+	}
+
+	var resp esSearchResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, 0, fmt.Errorf("decode elasticsearch response: %w", err)
+	}
+	docs := make([]*MessageDocument, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		src := hit.Source
+		doc := &MessageDocument{
+			MessageID:      src.MessageID,
+			ConversationID: src.ConversationID,
+			GroupID:        src.GroupID,
+			From:           src.From,
+			To:             src.To,
+			Type:           src.Type,
+			Content:        src.Content,
+			ContentVersion: src.ContentVersion,
+			Seq:            src.Seq,
+			Status:         src.Status,
+			CreatedAt:      time.UnixMilli(src.CreatedAt),
+		}
+		doc.UpgradeContent()
+		docs = append(docs, doc)
+	}
+	return docs, resp.Hits.Total.Value, nil
+}
+
+// do 发送请求，返回状态码和响应体
+func (e *elasticsearchMessageIndex) do(ctx context.Context, method, path string, payload interface{}) (int, []byte, error) {
+	var reader io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return 0, nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	endpoint := strings.TrimRight(e.config.URL, "/") + "/" + url.PathEscape(e.config.Index) + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if e.config.Username != "" {
+		req.SetBasicAuth(e.config.Username, e.config.Password)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("elasticsearch request error: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("read elasticsearch response: %w", err)
+	}
+	return resp.StatusCode, body, nil
+}
+
+// isSearchable 是否为可检索的文本消息
+func isSearchable(doc *MessageDocument) bool {
+	if doc.Revoked || doc.Cancelled || doc.Failed || doc.Purged {
+		return false
+	}
+	text, _ := doc.Content["text"].(string)
+	return text != ""
+}
+
+// esTerm 精确匹配条件
+func esTerm(field, value string) map[string]interface{} {
+	return map[string]interface{}{"term": map[string]string{field: value}}
+}
+
+// esTerms 多值精确匹配条件
+func esTerms(field string, values []string) map[string]interface{} {
+	return map[string]interface{}{"terms": map[string][]string{field: values}}
+}
+
+// esExists 字段存在条件
+func esExists(field string) map[string]interface{} {
+	return map[string]interface{}{"exists": map[string]string{"field": field}}
+}
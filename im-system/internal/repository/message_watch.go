@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrResumeTokenExpired 续订位置已不在 oplog 中，需要丢弃 resumeToken 从当前位置重新订阅
+var ErrResumeTokenExpired = errors.New("change stream resume token expired")
+
+// MessageChangeOp 消息变更类型
+type MessageChangeOp string
+
+const (
+	MessageChangeInsert MessageChangeOp = "insert" // 新消息
+	MessageChangeUpdate MessageChangeOp = "update" // 消息更新（撤回、状态变更等）
+	MessageChangeDelete MessageChangeOp = "delete" // 消息删除（含TTL过期）
+)
+
+// MessageChangeEvent 消息变更事件
+type MessageChangeEvent struct {
+	Op          MessageChangeOp
+	DocumentID  primitive.ObjectID
+	Document    *MessageDocument // 变更后的文档，删除时为变更前镜像（未开启前镜像时为 nil）
+	ResumeToken []byte           // 处理完成后可持久化，用于断点续订
+}
+
+// MessageChangeHandler 消息变更处理函数
+type MessageChangeHandler func(ctx context.Context, event *MessageChangeEvent) error
+
+// messageChangeDocument 变更流原始事件
+type messageChangeDocument struct {
+	OperationType            string           `bson:"operationType"`
+	DocumentKey              bson.M           `bson:"documentKey"`
+	FullDocument             *MessageDocument `bson:"fullDocument"`
+	FullDocumentBeforeChange *MessageDocument `bson:"fullDocumentBeforeChange"`
+}
+
+// Watch 订阅消息集合的变更流
+func (r *messageRepository) Watch(ctx context.Context, resumeToken []byte, handler MessageChangeHandler) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}},
+		}}},
+	}
+
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+	if len(resumeToken) > 0 {
+		opts.SetResumeAfter(bson.Raw(resumeToken))
+	}
+
+	stream, err := r.collection.Watch(ctx, pipeline, opts)
+	if err != nil {
+		if isResumeTokenExpired(err) {
+			return ErrResumeTokenExpired
+		}
+		return fmt.Errorf("failed to watch messages: %w", err)
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var change messageChangeDocument
+		if err := stream.Decode(&change); err != nil {
+			return fmt.Errorf("failed to decode change event: %w", err)
+		}
+
+		event := &MessageChangeEvent{
+			Document:    change.FullDocument,
+			ResumeToken: append([]byte(nil), stream.ResumeToken()...),
+		}
+		if id, ok := change.DocumentKey["_id"].(primitive.ObjectID); ok {
+			event.DocumentID = id
+		}
+
+		switch change.OperationType {
+		case "insert":
+			event.Op = MessageChangeInsert
+		case "delete":
+			event.Op = MessageChangeDelete
+			event.Document = change.FullDocumentBeforeChange
+		default:
+			event.Op = MessageChangeUpdate
+		}
+		if event.Document != nil {
+			event.Document.UpgradeContent()
+		}
+
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		if isResumeTokenExpired(err) {
+			return ErrResumeTokenExpired
+		}
+		return fmt.Errorf("change stream error: %w", err)
+	}
+	return ctx.Err()
+}
+
+// isResumeTokenExpired 是否为续订位置丢失错误（ChangeStreamHistoryLost / ChangeStreamFatalError）
+func isResumeTokenExpired(err error) bool {
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) {
+		return serverErr.HasErrorCode(286) || serverErr.HasErrorCode(280)
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// ModerationRepository 内容审核违规记录仓库接口
+type ModerationRepository interface {
+	// CreateViolation 写入违规记录
+	CreateViolation(ctx context.Context, violation *model.ModerationViolation) error
+
+	// ListViolations 分页查询违规记录（按时间倒序）
+	ListViolations(ctx context.Context, filter *model.ModerationViolationFilter, offset, limit int) ([]*model.ModerationViolation, int64, error)
+}
+
+// moderationRepository 内容审核违规记录仓库实现
+type moderationRepository struct {
+	db *gorm.DB
+}
+
+// NewModerationRepository 创建内容审核违规记录仓库
+func NewModerationRepository(db *gorm.DB) ModerationRepository {
+	return &moderationRepository{db: db}
+}
+
+// CreateViolation 写入违规记录
+func (r *moderationRepository) CreateViolation(ctx context.Context, violation *model.ModerationViolation) error {
+	return r.db.WithContext(ctx).Create(violation).Error
+}
+
+// ListViolations 分页查询违规记录
+func (r *moderationRepository) ListViolations(ctx context.Context, filter *model.ModerationViolationFilter, offset, limit int) ([]*model.ModerationViolation, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.ModerationViolation{})
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.ConversationID != "" {
+		query = query.Where("conversation_id = ?", filter.ConversationID)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var violations []*model.ModerationViolation
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&violations).Error; err != nil {
+		return nil, 0, err
+	}
+	return violations, total, nil
+}
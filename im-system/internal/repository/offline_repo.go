@@ -0,0 +1,360 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// OfflineConversationStat 会话维度的离线消息统计
+type OfflineConversationStat struct {
+	ConversationID string
+	Count          int64
+	LastID         int64 // 最新一条离线消息的自增ID
+	LastCreatedAt  time.Time
+}
+
+// OfflineMessageRepository 离线消息仓库接口（查询均排除已过期及已被快照取代的消息）
+type OfflineMessageRepository interface {
+	// Create 保存离线消息
+	Create(ctx context.Context, msg *model.OfflineMessage) error
+
+	// CreateBatch 批量保存离线消息
+	CreateBatch(ctx context.Context, msgs []*model.OfflineMessage) error
+
+	// FindByUser 按自增ID顺序拉取离线消息
+	FindByUser(ctx context.Context, userID string, afterID int64, limit int) ([]*model.OfflineMessage, error)
+
+	// FindByConversation 按自增ID顺序拉取指定会话的离线消息（conversationIDs 为同一会话的全部存储形式）
+	FindByConversation(ctx context.Context, userID string, conversationIDs []string, afterID int64, limit int) ([]*model.OfflineMessage, error)
+
+	// FindIDsByConversation 查询指定会话中自增ID不超过 upToID 的离线消息ID，upToID 为0时不限制
+	FindIDsByConversation(ctx context.Context, userID string, conversationIDs []string, upToID int64, limit int) ([]string, error)
+
+	// DeleteRead 在同一事务中删除会话内已读的离线消息（序号不超过 lastReadSeq 或在 messageIDs 中，含已被快照取代的消息），
+	// 并推进用户会话的已读序号、扣减未读数，返回被删除的消息ID（不含已被取代的消息，它们已不计入离线计数）
+	DeleteRead(ctx context.Context, userID string, conversationIDs []string, lastReadSeq int64, messageIDs []string) ([]string, error)
+
+	// FindUnpushed 查询未推送消息
+	FindUnpushed(ctx context.Context, userID string, limit int) ([]*model.OfflineMessage, error)
+
+	// FindOldestIDs 查询最旧的消息ID
+	FindOldestIDs(ctx context.Context, userID string, limit int) ([]string, error)
+
+	// FindUsersByMessageID 查询仍有该消息离线副本的用户
+	FindUsersByMessageID(ctx context.Context, messageID string) ([]string, error)
+
+	// FindUsersWithMessagesBefore 按用户ID顺序分页查询有早于 before 的离线消息的用户（user_id 大于 afterUserID）
+	FindUsersWithMessagesBefore(ctx context.Context, before time.Time, afterUserID string, limit int) ([]string, error)
+
+	// MarkPushed 标记消息已推送
+	MarkPushed(ctx context.Context, messageIDs []string, pushedAt time.Time) error
+
+	// Delete 删除用户的离线消息
+	Delete(ctx context.Context, userID string, messageIDs []string) error
+
+	// DeleteExpired 删除过期消息
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+
+	// Count 统计离线消息数
+	Count(ctx context.Context, userID string) (int64, error)
+
+	// CountUnpushed 统计未推送消息数
+	CountUnpushed(ctx context.Context, userID string) (int64, error)
+
+	// ConversationStats 按会话统计离线消息
+	ConversationStats(ctx context.Context, userID string) ([]*OfflineConversationStat, error)
+
+	// Supersede 将用户每个会话中除最近 keepRecent 条以外的离线消息标记为已被快照取代，返回标记数量
+	Supersede(ctx context.Context, userID string, keepRecent int) (int64, error)
+}
+
+// offlineMessageRepository 离线消息仓库实现
+type offlineMessageRepository struct {
+	db *gorm.DB
+}
+
+// NewOfflineMessageRepository 创建离线消息仓库
+func NewOfflineMessageRepository(db *gorm.DB) OfflineMessageRepository {
+	return &offlineMessageRepository{db: db}
+}
+
+// Create 保存离线消息
+func (r *offlineMessageRepository) Create(ctx context.Context, msg *model.OfflineMessage) error {
+	return r.db.WithContext(ctx).Create(msg).Error
+}
+
+// CreateBatch 批量保存离线消息
+func (r *offlineMessageRepository) CreateBatch(ctx context.Context, msgs []*model.OfflineMessage) error {
+	return r.db.WithContext(ctx).CreateInBatches(msgs, 100).Error
+}
+
+// FindByUser 拉取离线消息
+func (r *offlineMessageRepository) FindByUser(ctx context.Context, userID string, afterID int64, limit int) ([]*model.OfflineMessage, error) {
+	query := r.db.WithContext(ctx).
+		Where("user_id = ? AND superseded = ?", userID, false).
+		Where("expire_at > ?", time.Now())
+
+	if afterID > 0 {
+		query = query.Where("id > ?", afterID)
+	}
+
+	var messages []*model.OfflineMessage
+	if err := query.Order("id ASC").Limit(limit).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// FindByConversation 拉取指定会话的离线消息
+func (r *offlineMessageRepository) FindByConversation(ctx context.Context, userID string, conversationIDs []string, afterID int64, limit int) ([]*model.OfflineMessage, error) {
+	query := r.db.WithContext(ctx).
+		Where("user_id = ? AND conversation_id IN ? AND superseded = ?", userID, conversationIDs, false).
+		Where("expire_at > ?", time.Now())
+
+	if afterID > 0 {
+		query = query.Where("id > ?", afterID)
+	}
+
+	var messages []*model.OfflineMessage
+	if err := query.Order("id ASC").Limit(limit).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// FindIDsByConversation 查询指定会话的离线消息ID
+func (r *offlineMessageRepository) FindIDsByConversation(ctx context.Context, userID string, conversationIDs []string, upToID int64, limit int) ([]string, error) {
+	query := r.db.WithContext(ctx).
+		Model(&model.OfflineMessage{}).
+		Where("user_id = ? AND conversation_id IN ? AND superseded = ?", userID, conversationIDs, false)
+
+	if upToID > 0 {
+		query = query.Where("id <= ?", upToID)
+	}
+
+	var messageIDs []string
+	if err := query.Order("id ASC").Limit(limit).Pluck("message_id", &messageIDs).Error; err != nil {
+		return nil, err
+	}
+	return messageIDs, nil
+}
+
+// DeleteRead 删除已读的离线消息并更新用户会话的已读状态
+func (r *offlineMessageRepository) DeleteRead(ctx context.Context, userID string, conversationIDs []string, lastReadSeq int64, messageIDs []string) ([]string, error) {
+	var deleted []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			MessageID  string
+			Superseded bool
+		}
+		query := tx.Model(&model.OfflineMessage{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND conversation_id IN ?", userID, conversationIDs)
+		switch {
+		case lastReadSeq > 0 && len(messageIDs) > 0:
+			query = query.Where("(seq > 0 AND seq <= ?) OR message_id IN ?", lastReadSeq, messageIDs)
+		case lastReadSeq > 0:
+			query = query.Where("seq > 0 AND seq <= ?", lastReadSeq)
+		case len(messageIDs) > 0:
+			query = query.Where("message_id IN ?", messageIDs)
+		default:
+			return nil
+		}
+		if err := query.Select("message_id, superseded").Scan(&rows).Error; err != nil {
+			return err
+		}
+
+		if len(rows) > 0 {
+			ids := make([]string, 0, len(rows))
+			for _, row := range rows {
+				ids = append(ids, row.MessageID)
+				if !row.Superseded {
+					deleted = append(deleted, row.MessageID)
+				}
+			}
+			if err := tx.Where("user_id = ? AND message_id IN ?", userID, ids).
+				Delete(&model.OfflineMessage{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// 已被取代的消息同样是未读消息，按删除的全部记录扣减未读数
+		updates := map[string]interface{}{
+			"last_read_seq": gorm.Expr("GREATEST(last_read_seq, ?)", lastReadSeq),
+		}
+		if len(rows) > 0 {
+			updates["unread_count"] = gorm.Expr("GREATEST(unread_count - ?, 0)", len(rows))
+		}
+		return tx.Model(&model.UserConversation{}).
+			Where("user_id = ? AND conversation_id IN ?", userID, conversationIDs).
+			Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// FindUnpushed 查询未推送消息
+func (r *offlineMessageRepository) FindUnpushed(ctx context.Context, userID string, limit int) ([]*model.OfflineMessage, error) {
+	var messages []*model.OfflineMessage
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND pushed = ? AND superseded = ? AND expire_at > ?", userID, false, false, time.Now()).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// FindOldestIDs 查询最旧的消息ID
+func (r *offlineMessageRepository) FindOldestIDs(ctx context.Context, userID string, limit int) ([]string, error) {
+	var messageIDs []string
+	if err := r.db.WithContext(ctx).
+		Model(&model.OfflineMessage{}).
+		Where("user_id = ? AND superseded = ?", userID, false).
+		Order("created_at ASC").
+		Limit(limit).
+		Pluck("message_id", &messageIDs).Error; err != nil {
+		return nil, err
+	}
+	return messageIDs, nil
+}
+
+// FindUsersByMessageID 查询仍有该消息离线副本的用户
+func (r *offlineMessageRepository) FindUsersByMessageID(ctx context.Context, messageID string) ([]string, error) {
+	var userIDs []string
+	if err := r.db.WithContext(ctx).
+		Model(&model.OfflineMessage{}).
+		Where("message_id = ? AND superseded = ?", messageID, false).
+		Distinct().
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// FindUsersWithMessagesBefore 查询有早于 before 的离线消息的用户
+func (r *offlineMessageRepository) FindUsersWithMessagesBefore(ctx context.Context, before time.Time, afterUserID string, limit int) ([]string, error) {
+	var userIDs []string
+	if err := r.db.WithContext(ctx).
+		Model(&model.OfflineMessage{}).
+		Where("created_at < ? AND expire_at > ? AND user_id > ? AND superseded = ?", before, time.Now(), afterUserID, false).
+		Distinct().
+		Order("user_id ASC").
+		Limit(limit).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// MarkPushed 标记消息已推送
+func (r *offlineMessageRepository) MarkPushed(ctx context.Context, messageIDs []string, pushedAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&model.OfflineMessage{}).
+		Where("message_id IN ?", messageIDs).
+		Updates(map[string]interface{}{
+			"pushed":    true,
+			"pushed_at": pushedAt,
+		}).Error
+}
+
+// Delete 删除用户的离线消息
+func (r *offlineMessageRepository) Delete(ctx context.Context, userID string, messageIDs []string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND message_id IN ?", userID, messageIDs).
+		Delete(&model.OfflineMessage{}).Error
+}
+
+// DeleteExpired 删除过期消息
+func (r *offlineMessageRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expire_at < ?", now).
+		Delete(&model.OfflineMessage{})
+	return result.RowsAffected, result.Error
+}
+
+// Count 统计离线消息数
+func (r *offlineMessageRepository) Count(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.OfflineMessage{}).
+		Where("user_id = ? AND superseded = ? AND expire_at > ?", userID, false, time.Now()).
+		Count(&count).Error
+	return count, err
+}
+
+// CountUnpushed 统计未推送消息数
+func (r *offlineMessageRepository) CountUnpushed(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&model.OfflineMessage{}).
+		Where("user_id = ? AND pushed = ? AND superseded = ? AND expire_at > ?", userID, false, false, time.Now()).
+		Count(&count).Error
+	return count, err
+}
+
+// ConversationStats 按会话统计离线消息
+func (r *offlineMessageRepository) ConversationStats(ctx context.Context, userID string) ([]*OfflineConversationStat, error) {
+	var stats []*OfflineConversationStat
+	if err := r.db.WithContext(ctx).
+		Model(&model.OfflineMessage{}).
+		Select("conversation_id, COUNT(*) as count, MAX(id) as last_id, MAX(created_at) as last_created_at").
+		Where("user_id = ? AND superseded = ? AND expire_at > ?", userID, false, time.Now()).
+		Group("conversation_id").
+		Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Supersede 将每个会话中除最近 keepRecent 条以外的离线消息标记为已取代（逐个会话更新，避免长事务）
+func (r *offlineMessageRepository) Supersede(ctx context.Context, userID string, keepRecent int) (int64, error) {
+	active := func() *gorm.DB {
+		return r.db.WithContext(ctx).
+			Model(&model.OfflineMessage{}).
+			Where("user_id = ? AND superseded = ?", userID, false)
+	}
+
+	if keepRecent <= 0 {
+		result := active().Update("superseded", true)
+		return result.RowsAffected, result.Error
+	}
+
+	var conversationIDs []string
+	if err := active().Distinct().Pluck("conversation_id", &conversationIDs).Error; err != nil {
+		return 0, err
+	}
+
+	var superseded int64
+	for _, conversationID := range conversationIDs {
+		// 第 keepRecent+1 新的消息及更早的消息被取代
+		var cutoff []int64
+		if err := active().
+			Where("conversation_id = ?", conversationID).
+			Order("id DESC").
+			Offset(keepRecent).
+			Limit(1).
+			Pluck("id", &cutoff).Error; err != nil {
+			return superseded, err
+		}
+		if len(cutoff) == 0 {
+			continue
+		}
+		result := active().
+			Where("conversation_id = ? AND id <= ?", conversationID, cutoff[0]).
+			Update("superseded", true)
+		if result.Error != nil {
+			return superseded, result.Error
+		}
+		superseded += result.RowsAffected
+	}
+	return superseded, nil
+}
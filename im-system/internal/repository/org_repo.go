@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// OrgRepository 组织架构仓库接口
+type OrgRepository interface {
+	// Transaction 在事务中执行，fn 内必须使用传入的仓库
+	Transaction(ctx context.Context, fn func(tx OrgRepository) error) error
+
+	// CreateDepartment 创建部门
+	CreateDepartment(ctx context.Context, dept *model.Department) error
+
+	// FindDepartment 查询部门，不存在时返回 nil
+	FindDepartment(ctx context.Context, departmentID string) (*model.Department, error)
+
+	// FindDepartments 查询租户下的全部部门（按排序值、名称）
+	FindDepartments(ctx context.Context, tenantID string) ([]*model.Department, error)
+
+	// UpdateDepartment 更新部门字段
+	UpdateDepartment(ctx context.Context, departmentID string, updates map[string]interface{}) error
+
+	// MoveSubtree 将路径前缀为 oldPath 的部门（含自身）改为 newPath 前缀
+	MoveSubtree(ctx context.Context, oldPath, newPath string) error
+
+	// DeleteDepartment 删除部门
+	DeleteDepartment(ctx context.Context, departmentID string) error
+
+	// CountChildren 统计直属下级部门数
+	CountChildren(ctx context.Context, departmentID string) (int64, error)
+
+	// UpsertMembers 批量添加部门成员，已存在时更新职位等信息
+	UpsertMembers(ctx context.Context, members []*model.DepartmentMember) error
+
+	// RemoveMember 移除部门成员
+	RemoveMember(ctx context.Context, departmentID, userID string) error
+
+	// FindMembers 分页查询部门直属成员
+	FindMembers(ctx context.Context, departmentID string, offset, limit int) ([]*model.DepartmentMember, int64, error)
+
+	// CountMembers 统计各部门直属成员数
+	CountMembers(ctx context.Context, departmentIDs []string) (map[string]int64, error)
+
+	// FindUserMemberships 查询用户所属的部门关系
+	FindUserMemberships(ctx context.Context, userID string) ([]*model.DepartmentMember, error)
+
+	// SearchMembers 在指定部门内按用户名/昵称搜索成员
+	SearchMembers(ctx context.Context, departmentIDs []string, keyword string, limit int) ([]*model.DepartmentMember, error)
+}
+
+// orgRepository 组织架构仓库实现
+type orgRepository struct {
+	db *gorm.DB
+}
+
+// NewOrgRepository 创建组织架构仓库
+func NewOrgRepository(db *gorm.DB) OrgRepository {
+	return &orgRepository{db: db}
+}
+
+// Transaction 在事务中执行
+func (r *orgRepository) Transaction(ctx context.Context, fn func(tx OrgRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&orgRepository{db: tx})
+	})
+}
+
+// CreateDepartment 创建部门
+func (r *orgRepository) CreateDepartment(ctx context.Context, dept *model.Department) error {
+	return r.db.WithContext(ctx).Create(dept).Error
+}
+
+// FindDepartment 查询部门
+func (r *orgRepository) FindDepartment(ctx context.Context, departmentID string) (*model.Department, error) {
+	var dept model.Department
+	if err := r.db.WithContext(ctx).Where("department_id = ?", departmentID).First(&dept).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &dept, nil
+}
+
+// FindDepartments 查询租户下的全部部门
+func (r *orgRepository) FindDepartments(ctx context.Context, tenantID string) ([]*model.Department, error) {
+	var depts []*model.Department
+	err := r.db.WithContext(ctx).
+		Where("tenant_id = ?", tenantID).
+		Order("sort_order ASC, name ASC").
+		Find(&depts).Error
+	return depts, err
+}
+
+// UpdateDepartment 更新部门字段
+func (r *orgRepository) UpdateDepartment(ctx context.Context, departmentID string, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.Department{}).Where("department_id = ?", departmentID).Updates(updates).Error
+}
+
+// MoveSubtree 替换子树路径前缀
+func (r *orgRepository) MoveSubtree(ctx context.Context, oldPath, newPath string) error {
+	return r.db.WithContext(ctx).Model(&model.Department{}).
+		Where("path LIKE ?", escapeLike(oldPath)+"%").
+		UpdateColumn("path", gorm.Expr("CONCAT(?, SUBSTRING(path, ?))", newPath, len(oldPath)+1)).Error
+}
+
+// DeleteDepartment 删除部门
+func (r *orgRepository) DeleteDepartment(ctx context.Context, departmentID string) error {
+	return r.db.WithContext(ctx).Where("department_id = ?", departmentID).Delete(&model.Department{}).Error
+}
+
+// CountChildren 统计直属下级部门数
+func (r *orgRepository) CountChildren(ctx context.Context, departmentID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.Department{}).Where("parent_id = ?", departmentID).Count(&count).Error
+	return count, err
+}
+
+// UpsertMembers 批量添加部门成员
+func (r *orgRepository) UpsertMembers(ctx context.Context, members []*model.DepartmentMember) error {
+	if len(members) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "department_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "is_primary", "sort_order"}),
+	}).Create(&members).Error
+}
+
+// RemoveMember 移除部门成员
+func (r *orgRepository) RemoveMember(ctx context.Context, departmentID, userID string) error {
+	return r.db.WithContext(ctx).
+		Where("department_id = ? AND user_id = ?", departmentID, userID).
+		Delete(&model.DepartmentMember{}).Error
+}
+
+// FindMembers 分页查询部门直属成员
+func (r *orgRepository) FindMembers(ctx context.Context, departmentID string, offset, limit int) ([]*model.DepartmentMember, int64, error) {
+	var members []*model.DepartmentMember
+	var total int64
+
+	if err := r.db.WithContext(ctx).Model(&model.DepartmentMember{}).
+		Where("department_id = ?", departmentID).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := r.db.WithContext(ctx).
+		Where("department_id = ?", departmentID).
+		Order("sort_order ASC, joined_at ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&members).Error; err != nil {
+		return nil, 0, err
+	}
+	return members, total, nil
+}
+
+// CountMembers 统计各部门直属成员数
+func (r *orgRepository) CountMembers(ctx context.Context, departmentIDs []string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(departmentIDs))
+	if len(departmentIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		DepartmentID string
+		Count        int64
+	}
+	if err := r.db.WithContext(ctx).Model(&model.DepartmentMember{}).
+		Select("department_id, COUNT(*) as count").
+		Where("department_id IN ?", departmentIDs).
+		Group("department_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.DepartmentID] = row.Count
+	}
+	return counts, nil
+}
+
+// FindUserMemberships 查询用户所属的部门关系
+func (r *orgRepository) FindUserMemberships(ctx context.Context, userID string) ([]*model.DepartmentMember, error) {
+	var members []*model.DepartmentMember
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("is_primary DESC, joined_at ASC").
+		Find(&members).Error
+	return members, err
+}
+
+// SearchMembers 在指定部门内搜索成员
+func (r *orgRepository) SearchMembers(ctx context.Context, departmentIDs []string, keyword string, limit int) ([]*model.DepartmentMember, error) {
+	var members []*model.DepartmentMember
+	if len(departmentIDs) == 0 {
+		return members, nil
+	}
+
+	pattern := "%" + escapeLike(keyword) + "%"
+	err := r.db.WithContext(ctx).
+		Select("department_members.*").
+		Joins("JOIN users ON users.user_id = department_members.user_id").
+		Where("department_members.department_id IN ?", departmentIDs).
+		Where("users.status = ?", model.UserStatusNormal).
+		Where("(users.username LIKE ? OR users.nickname LIKE ?)", pattern, pattern).
+		Order("department_members.is_primary DESC, users.nickname ASC").
+		Limit(limit).
+		Find(&members).Error
+	return members, err
+}
+
+// likeEscaper 转义 LIKE 通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike 转义 LIKE 查询中的通配符（部门ID、用户ID含下划线）
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
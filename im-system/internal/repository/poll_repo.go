@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// PollRepository 群投票仓库接口
+type PollRepository interface {
+	// Create 创建投票
+	Create(ctx context.Context, poll *model.Poll) error
+
+	// Delete 删除投票及投票记录（投票消息发送失败时回滚）
+	Delete(ctx context.Context, pollID string) error
+
+	// FindByID 查询投票，不存在时返回 nil
+	FindByID(ctx context.Context, pollID string) (*model.Poll, error)
+
+	// FindDue 查询已到截止时间仍未结束的投票
+	FindDue(ctx context.Context, now time.Time, limit int) ([]*model.Poll, error)
+
+	// Vote 在同一事务中锁定投票并保存用户的选择，投票已结束或用户已投过票时返回 false
+	Vote(ctx context.Context, vote *model.PollVote) (bool, error)
+
+	// FindVotes 查询投票的全部投票记录（按投票时间升序）
+	FindVotes(ctx context.Context, pollID string) ([]*model.PollVote, error)
+
+	// FindVote 查询用户的投票记录，未投票时返回 nil
+	FindVote(ctx context.Context, pollID, userID string) (*model.PollVote, error)
+
+	// Close 仅当投票进行中时标记为已结束，返回是否更新成功（多节点下用于抢占）
+	Close(ctx context.Context, pollID string, closedAt time.Time) (bool, error)
+
+	// SetResultMessage 记录投票结束时发送的结果消息
+	SetResultMessage(ctx context.Context, pollID, messageID string) error
+}
+
+// pollRepository 群投票仓库实现
+type pollRepository struct {
+	db *gorm.DB
+}
+
+// NewPollRepository 创建群投票仓库
+func NewPollRepository(db *gorm.DB) PollRepository {
+	return &pollRepository{db: db}
+}
+
+// Create 创建投票
+func (r *pollRepository) Create(ctx context.Context, poll *model.Poll) error {
+	return r.db.WithContext(ctx).Create(poll).Error
+}
+
+// Delete 删除投票及投票记录
+func (r *pollRepository) Delete(ctx context.Context, pollID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("poll_id = ?", pollID).Delete(&model.PollVote{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("poll_id = ?", pollID).Delete(&model.Poll{}).Error
+	})
+}
+
+// FindByID 查询投票
+func (r *pollRepository) FindByID(ctx context.Context, pollID string) (*model.Poll, error) {
+	var poll model.Poll
+	if err := r.db.WithContext(ctx).Where("poll_id = ?", pollID).First(&poll).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// FindDue 查询到期未结束的投票
+func (r *pollRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]*model.Poll, error) {
+	var polls []*model.Poll
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND deadline IS NOT NULL AND deadline <= ?", model.PollOpen, now).
+		Order("deadline ASC").
+		Limit(limit).
+		Find(&polls).Error
+	return polls, err
+}
+
+// Vote 保存投票：锁定投票行，与结束投票互斥，结束后统计的结果包含全部已保存的投票
+func (r *pollRepository) Vote(ctx context.Context, vote *model.PollVote) (bool, error) {
+	created := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var poll model.Poll
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("poll_id = ?", vote.PollID).
+			First(&poll).Error; err != nil {
+			return err
+		}
+		if poll.Status != model.PollOpen {
+			return nil
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(vote)
+		if result.Error != nil {
+			return result.Error
+		}
+		created = result.RowsAffected > 0
+		return nil
+	})
+	return created, err
+}
+
+// FindVotes 查询投票记录
+func (r *pollRepository) FindVotes(ctx context.Context, pollID string) ([]*model.PollVote, error) {
+	var votes []*model.PollVote
+	err := r.db.WithContext(ctx).
+		Where("poll_id = ?", pollID).
+		Order("created_at ASC").
+		Find(&votes).Error
+	return votes, err
+}
+
+// FindVote 查询用户的投票记录
+func (r *pollRepository) FindVote(ctx context.Context, pollID, userID string) (*model.PollVote, error) {
+	var vote model.PollVote
+	if err := r.db.WithContext(ctx).Where("poll_id = ? AND user_id = ?", pollID, userID).First(&vote).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &vote, nil
+}
+
+// Close 条件结束投票
+func (r *pollRepository) Close(ctx context.Context, pollID string, closedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.Poll{}).
+		Where("poll_id = ? AND status = ?", pollID, model.PollOpen).
+		Updates(map[string]interface{}{
+			"status":    model.PollClosed,
+			"closed_at": closedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// SetResultMessage 记录结果消息
+func (r *pollRepository) SetResultMessage(ctx context.Context, pollID, messageID string) error {
+	return r.db.WithContext(ctx).Model(&model.Poll{}).
+		Where("poll_id = ?", pollID).
+		Update("result_message_id", messageID).Error
+}
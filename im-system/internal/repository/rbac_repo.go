@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// RBACRepository 管理角色仓库接口
+type RBACRepository interface {
+	// ListRoles 查询全部自定义角色
+	ListRoles(ctx context.Context) ([]*model.AdminRole, error)
+
+	// FindRoles 批量查询自定义角色（不存在的角色忽略）
+	FindRoles(ctx context.Context, names []string) ([]*model.AdminRole, error)
+
+	// SaveRole 保存自定义角色
+	SaveRole(ctx context.Context, role *model.AdminRole) error
+
+	// DeleteRole 删除自定义角色及其分配，返回是否存在
+	DeleteRole(ctx context.Context, name string) (bool, error)
+
+	// FindUserRoles 查询用户的角色名
+	FindUserRoles(ctx context.Context, userID string) ([]string, error)
+
+	// SetUserRoles 覆盖用户的角色
+	SetUserRoles(ctx context.Context, userID string, roles []string, grantedBy string) error
+
+	// ListAssignments 查询全部角色分配
+	ListAssignments(ctx context.Context) ([]*model.AdminRoleAssignment, error)
+
+	// CreateAuditLog 写入审计日志
+	CreateAuditLog(ctx context.Context, log *model.AdminAuditLog) error
+
+	// ListAuditLogs 分页查询审计日志（按时间倒序）
+	ListAuditLogs(ctx context.Context, filter *model.AuditLogFilter, offset, limit int) ([]*model.AdminAuditLog, int64, error)
+}
+
+// rbacRepository 管理角色仓库实现
+type rbacRepository struct {
+	db *gorm.DB
+}
+
+// NewRBACRepository 创建管理角色仓库
+func NewRBACRepository(db *gorm.DB) RBACRepository {
+	return &rbacRepository{db: db}
+}
+
+// ListRoles 查询全部自定义角色
+func (r *rbacRepository) ListRoles(ctx context.Context) ([]*model.AdminRole, error) {
+	var roles []*model.AdminRole
+	err := r.db.WithContext(ctx).Order("name").Find(&roles).Error
+	return roles, err
+}
+
+// FindRoles 批量查询自定义角色
+func (r *rbacRepository) FindRoles(ctx context.Context, names []string) ([]*model.AdminRole, error) {
+	var roles []*model.AdminRole
+	if len(names) == 0 {
+		return roles, nil
+	}
+	err := r.db.WithContext(ctx).Where("name IN ?", names).Find(&roles).Error
+	return roles, err
+}
+
+// SaveRole 保存自定义角色
+func (r *rbacRepository) SaveRole(ctx context.Context, role *model.AdminRole) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "permissions", "updated_by", "updated_at"}),
+	}).Create(role).Error
+}
+
+// DeleteRole 删除自定义角色及其分配
+func (r *rbacRepository) DeleteRole(ctx context.Context, name string) (bool, error) {
+	deleted := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("name = ?", name).Delete(&model.AdminRole{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected > 0
+		return tx.Where("role = ?", name).Delete(&model.AdminRoleAssignment{}).Error
+	})
+	return deleted, err
+}
+
+// FindUserRoles 查询用户的角色名
+func (r *rbacRepository) FindUserRoles(ctx context.Context, userID string) ([]string, error) {
+	var roles []string
+	err := r.db.WithContext(ctx).Model(&model.AdminRoleAssignment{}).
+		Where("user_id = ?", userID).
+		Order("role").
+		Pluck("role", &roles).Error
+	return roles, err
+}
+
+// SetUserRoles 覆盖用户的角色
+func (r *rbacRepository) SetUserRoles(ctx context.Context, userID string, roles []string, grantedBy string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.AdminRoleAssignment{}).Error; err != nil {
+			return err
+		}
+		if len(roles) == 0 {
+			return nil
+		}
+		assignments := make([]*model.AdminRoleAssignment, 0, len(roles))
+		for _, role := range roles {
+			assignments = append(assignments, &model.AdminRoleAssignment{UserID: userID, Role: role, GrantedBy: grantedBy})
+		}
+		return tx.Create(&assignments).Error
+	})
+}
+
+// ListAssignments 查询全部角色分配
+func (r *rbacRepository) ListAssignments(ctx context.Context) ([]*model.AdminRoleAssignment, error) {
+	var assignments []*model.AdminRoleAssignment
+	err := r.db.WithContext(ctx).Order("user_id, role").Find(&assignments).Error
+	return assignments, err
+}
+
+// CreateAuditLog 写入审计日志
+func (r *rbacRepository) CreateAuditLog(ctx context.Context, log *model.AdminAuditLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
+}
+
+// ListAuditLogs 分页查询审计日志
+func (r *rbacRepository) ListAuditLogs(ctx context.Context, filter *model.AuditLogFilter, offset, limit int) ([]*model.AdminAuditLog, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.AdminAuditLog{})
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Permission != "" {
+		query = query.Where("permission = ?", filter.Permission)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var logs []*model.AdminAuditLog
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
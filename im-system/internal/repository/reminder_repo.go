@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// ReminderRepository 消息提醒仓库接口
+type ReminderRepository interface {
+	// Create 创建提醒
+	Create(ctx context.Context, reminder *model.MessageReminder) error
+
+	// FindByID 查询提醒，不存在时返回 nil
+	FindByID(ctx context.Context, reminderID string) (*model.MessageReminder, error)
+
+	// FindPendingByUser 查询用户待提醒的提醒（按提醒时间升序）
+	FindPendingByUser(ctx context.Context, userID string) ([]*model.MessageReminder, error)
+
+	// CountPending 统计用户待提醒的提醒数
+	CountPending(ctx context.Context, userID string) (int64, error)
+
+	// FindDue 查询到期的待提醒记录
+	FindDue(ctx context.Context, now time.Time, limit int) ([]*model.MessageReminder, error)
+
+	// Transition 仅当状态为 from 时更新为 to，返回是否更新成功（多节点下用于抢占）
+	Transition(ctx context.Context, reminderID string, from, to model.ReminderStatus) (bool, error)
+}
+
+// reminderRepository 消息提醒仓库实现
+type reminderRepository struct {
+	db *gorm.DB
+}
+
+// NewReminderRepository 创建消息提醒仓库
+func NewReminderRepository(db *gorm.DB) ReminderRepository {
+	return &reminderRepository{db: db}
+}
+
+// Create 创建提醒
+func (r *reminderRepository) Create(ctx context.Context, reminder *model.MessageReminder) error {
+	return r.db.WithContext(ctx).Create(reminder).Error
+}
+
+// FindByID 查询提醒
+func (r *reminderRepository) FindByID(ctx context.Context, reminderID string) (*model.MessageReminder, error) {
+	var reminder model.MessageReminder
+	if err := r.db.WithContext(ctx).Where("reminder_id = ?", reminderID).First(&reminder).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &reminder, nil
+}
+
+// FindPendingByUser 查询用户待提醒的提醒
+func (r *reminderRepository) FindPendingByUser(ctx context.Context, userID string) ([]*model.MessageReminder, error) {
+	var reminders []*model.MessageReminder
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, model.ReminderPending).
+		Order("remind_at ASC").
+		Find(&reminders).Error
+	return reminders, err
+}
+
+// CountPending 统计用户待提醒的提醒数
+func (r *reminderRepository) CountPending(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.MessageReminder{}).
+		Where("user_id = ? AND status = ?", userID, model.ReminderPending).
+		Count(&count).Error
+	return count, err
+}
+
+// FindDue 查询到期的待提醒记录
+func (r *reminderRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]*model.MessageReminder, error) {
+	var reminders []*model.MessageReminder
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND remind_at <= ?", model.ReminderPending, now).
+		Order("remind_at ASC").
+		Limit(limit).
+		Find(&reminders).Error
+	return reminders, err
+}
+
+// Transition 条件更新提醒状态
+func (r *reminderRepository) Transition(ctx context.Context, reminderID string, from, to model.ReminderStatus) (bool, error) {
+	updates := map[string]interface{}{"status": to}
+	if to == model.ReminderFired {
+		updates["fired_at"] = time.Now()
+	}
+	result := r.db.WithContext(ctx).Model(&model.MessageReminder{}).
+		Where("reminder_id = ? AND status = ?", reminderID, from).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
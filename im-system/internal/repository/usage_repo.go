@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// UsageMonthLayout 用量月份格式
+const UsageMonthLayout = "2006-01"
+
+// SessionQuery 连接会话查询条件
+type SessionQuery struct {
+	UserID string
+	Since  time.Time // 断开时间下限，零值表示不限
+	Until  time.Time // 断开时间上限（不含），零值表示不限
+	Offset int
+	Limit  int
+}
+
+// MonthlyUsageQuery 用户月度用量查询条件
+type MonthlyUsageQuery struct {
+	Month    string
+	TenantID string // 为空时不限租户
+	OrderBy  string // duration_seconds / bytes / sessions
+	Offset   int
+	Limit    int
+}
+
+// UsageRepository 连接会话计量仓库接口
+type UsageRepository interface {
+	// SaveSessions 批量写入会话记录，并在同一事务中累加到用户月度用量
+	SaveSessions(ctx context.Context, sessions []*model.ConnectionSession) error
+
+	// ListSessions 分页查询会话记录（按断开时间倒序）
+	ListSessions(ctx context.Context, query *SessionQuery) ([]*model.ConnectionSession, int64, error)
+
+	// FindMonthly 查询用户某月用量，不存在时返回 nil
+	FindMonthly(ctx context.Context, month, userID string) (*model.MonthlyUsage, error)
+
+	// ListMonthly 分页查询某月各用户用量
+	ListMonthly(ctx context.Context, query *MonthlyUsageQuery) ([]*model.MonthlyUsage, int64, error)
+
+	// TenantTotals 按租户汇总某月用量，tenantID 非空时只汇总该租户
+	TenantTotals(ctx context.Context, month, tenantID string) ([]*model.TenantUsage, error)
+
+	// DeleteSessionsBefore 删除断开时间早于 before 的会话记录（不影响月度汇总），每次最多删除 limit 条
+	DeleteSessionsBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// usageRepository 连接会话计量仓库实现
+type usageRepository struct {
+	db *gorm.DB
+}
+
+// NewUsageRepository 创建连接会话计量仓库
+func NewUsageRepository(db *gorm.DB) UsageRepository {
+	return &usageRepository{db: db}
+}
+
+// SaveSessions 批量写入会话记录并累加月度用量
+func (r *usageRepository) SaveSessions(ctx context.Context, sessions []*model.ConnectionSession) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	// 按月份、用户合并后再累加，减少汇总表的行锁竞争
+	type usageKey struct{ month, userID string }
+	monthly := make(map[usageKey]*model.MonthlyUsage)
+	for _, session := range sessions {
+		key := usageKey{session.DisconnectedAt.UTC().Format(UsageMonthLayout), session.UserID}
+		usage, ok := monthly[key]
+		if !ok {
+			usage = &model.MonthlyUsage{Month: key.month, UserID: key.userID}
+			monthly[key] = usage
+		}
+		usage.TenantID = session.TenantID
+		usage.Sessions++
+		usage.DurationSeconds += session.DurationSeconds
+		usage.BytesIn += session.BytesIn
+		usage.BytesOut += session.BytesOut
+		usage.FramesIn += session.FramesIn
+		usage.FramesOut += session.FramesOut
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(sessions, 500).Error; err != nil {
+			return err
+		}
+		for _, usage := range monthly {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "month"}, {Name: "user_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"tenant_id":        gorm.Expr("VALUES(tenant_id)"),
+					"sessions":         gorm.Expr("sessions + VALUES(sessions)"),
+					"duration_seconds": gorm.Expr("duration_seconds + VALUES(duration_seconds)"),
+					"bytes_in":         gorm.Expr("bytes_in + VALUES(bytes_in)"),
+					"bytes_out":        gorm.Expr("bytes_out + VALUES(bytes_out)"),
+					"frames_in":        gorm.Expr("frames_in + VALUES(frames_in)"),
+					"frames_out":       gorm.Expr("frames_out + VALUES(frames_out)"),
+					"updated_at":       time.Now(),
+				}),
+			}).Create(usage).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListSessions 分页查询会话记录
+func (r *usageRepository) ListSessions(ctx context.Context, query *SessionQuery) ([]*model.ConnectionSession, int64, error) {
+	db := r.db.WithContext(ctx).Model(&model.ConnectionSession{})
+	if query.UserID != "" {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if !query.Since.IsZero() {
+		db = db.Where("disconnected_at >= ?", query.Since)
+	}
+	if !query.Until.IsZero() {
+		db = db.Where("disconnected_at < ?", query.Until)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var sessions []*model.ConnectionSession
+	if err := db.
+		Order("disconnected_at DESC, id DESC").
+		Offset(query.Offset).
+		Limit(query.Limit).
+		Find(&sessions).Error; err != nil {
+		return nil, 0, err
+	}
+	return sessions, total, nil
+}
+
+// FindMonthly 查询用户某月用量
+func (r *usageRepository) FindMonthly(ctx context.Context, month, userID string) (*model.MonthlyUsage, error) {
+	var usage model.MonthlyUsage
+	if err := r.db.WithContext(ctx).Where("month = ? AND user_id = ?", month, userID).First(&usage).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// ListMonthly 分页查询某月各用户用量
+func (r *usageRepository) ListMonthly(ctx context.Context, query *MonthlyUsageQuery) ([]*model.MonthlyUsage, int64, error) {
+	db := r.db.WithContext(ctx).Model(&model.MonthlyUsage{}).Where("month = ?", query.Month)
+	if query.TenantID != "" {
+		db = db.Where("tenant_id = ?", query.TenantID)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	orderBy := "duration_seconds DESC"
+	switch query.OrderBy {
+	case "bytes":
+		orderBy = "bytes_in + bytes_out DESC"
+	case "sessions":
+		orderBy = "sessions DESC"
+	}
+
+	var usages []*model.MonthlyUsage
+	if err := db.
+		Order(orderBy).
+		Order("user_id").
+		Offset(query.Offset).
+		Limit(query.Limit).
+		Find(&usages).Error; err != nil {
+		return nil, 0, err
+	}
+	return usages, total, nil
+}
+
+// TenantTotals 按租户汇总某月用量
+func (r *usageRepository) TenantTotals(ctx context.Context, month, tenantID string) ([]*model.TenantUsage, error) {
+	db := r.db.WithContext(ctx).Model(&model.MonthlyUsage{}).
+		Select("month, COALESCE(tenant_id, '') AS tenant_id, COUNT(*) AS users, "+
+			"SUM(sessions) AS sessions, SUM(duration_seconds) AS duration_seconds, "+
+			"SUM(bytes_in) AS bytes_in, SUM(bytes_out) AS bytes_out, "+
+			"SUM(frames_in) AS frames_in, SUM(frames_out) AS frames_out").
+		Where("month = ?", month)
+	if tenantID != "" {
+		db = db.Where("tenant_id = ?", tenantID)
+	}
+
+	var totals []*model.TenantUsage
+	if err := db.Group("month, COALESCE(tenant_id, '')").Order("duration_seconds DESC").Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// DeleteSessionsBefore 删除过期会话记录
+func (r *usageRepository) DeleteSessionsBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("disconnected_at < ?", before).
+		Limit(limit).
+		Delete(&model.ConnectionSession{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// UserRepository 用户仓库接口
+type UserRepository interface {
+	// Transaction 在事务中执行，fn 内必须使用传入的仓库
+	Transaction(ctx context.Context, fn func(tx UserRepository) error) error
+
+	// Create 创建用户
+	Create(ctx context.Context, user *model.User) error
+
+	// FindByID 查询用户，不存在时返回 nil
+	FindByID(ctx context.Context, userID string) (*model.User, error)
+
+	// FindByIDs 批量查询用户（不存在的用户忽略）
+	FindByIDs(ctx context.Context, userIDs []string) ([]*model.User, error)
+
+	// ExistsUsername 用户名是否已存在
+	ExistsUsername(ctx context.Context, username string) (bool, error)
+
+	// ExistsNickname 租户内昵称是否已被其他用户使用（excludeUserID 为空表示不排除）
+	ExistsNickname(ctx context.Context, tenantID, nickname, excludeUserID string) (bool, error)
+
+	// UpdateNickname 更新昵称
+	UpdateNickname(ctx context.Context, userID, nickname string, updatedAt time.Time) error
+
+	// UpdateStatus 更新账号状态
+	UpdateStatus(ctx context.Context, userID string, status model.UserStatus, updatedAt time.Time) error
+
+	// UpdateRegion 更新数据驻留区域
+	UpdateRegion(ctx context.Context, userID, region string, updatedAt time.Time) error
+
+	// UpdateNotificationSettings 更新通知设置（推送开关、推送预览、免打扰时段）
+	UpdateNotificationSettings(ctx context.Context, userID string, settings *model.NotificationSettings, updatedAt time.Time) error
+
+	// CreateRenameHistory 记录改名历史
+	CreateRenameHistory(ctx context.Context, history *model.UserRenameHistory) error
+
+	// CountRenamesSince 统计指定时间之后的改名次数
+	CountRenamesSince(ctx context.Context, userID string, field model.RenameField, since time.Time) (int64, error)
+
+	// FindRenameHistory 查询改名历史（按时间倒序）
+	FindRenameHistory(ctx context.Context, userID string, limit int) ([]*model.UserRenameHistory, error)
+
+	// FindExpiredGuests 查询过期且未注销的访客账号
+	FindExpiredGuests(ctx context.Context, before time.Time, limit int) ([]*model.User, error)
+
+	// UpgradeGuest 将访客账号转为正式账号（设置用户名、昵称和密码），不是访客账号时返回 false
+	UpgradeGuest(ctx context.Context, userID, username, nickname, passwordHash string, updatedAt time.Time) (bool, error)
+
+	// FindUsers 按条件分页查询用户（按注册时间倒序），返回总数
+	FindUsers(ctx context.Context, filter *UserFilter, offset, limit int) ([]*model.User, int64, error)
+}
+
+// UserFilter 管理后台用户查询条件，空字段不限
+type UserFilter struct {
+	Keyword  string            // 用户ID（精确）、用户名或昵称（前缀）
+	Status   *model.UserStatus // 账号状态
+	TenantID string            // 租户
+	Guest    *bool             // 是否访客
+}
+
+// userRepository 用户仓库实现
+type userRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository 创建用户仓库
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+// Transaction 在事务中执行
+func (r *userRepository) Transaction(ctx context.Context, fn func(tx UserRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&userRepository{db: tx})
+	})
+}
+
+// Create 创建用户
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+// FindByID 查询用户
+func (r *userRepository) FindByID(ctx context.Context, userID string) (*model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindByIDs 批量查询用户
+func (r *userRepository) FindByIDs(ctx context.Context, userIDs []string) ([]*model.User, error) {
+	var users []*model.User
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&users).Error
+	return users, err
+}
+
+// ExistsUsername 用户名是否已存在
+func (r *userRepository) ExistsUsername(ctx context.Context, username string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ExistsNickname 租户内昵称是否已被使用
+func (r *userRepository) ExistsNickname(ctx context.Context, tenantID, nickname, excludeUserID string) (bool, error) {
+	query := r.db.WithContext(ctx).Model(&model.User{}).Where("tenant_id = ? AND nickname = ?", tenantID, nickname)
+	if excludeUserID != "" {
+		query = query.Where("user_id <> ?", excludeUserID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// UpdateNickname 更新昵称
+func (r *userRepository) UpdateNickname(ctx context.Context, userID, nickname string, updatedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"nickname": nickname, "updated_at": updatedAt}).Error
+}
+
+// UpdateStatus 更新账号状态
+func (r *userRepository) UpdateStatus(ctx context.Context, userID string, status model.UserStatus, updatedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"status": status, "updated_at": updatedAt}).Error
+}
+
+// UpdateRegion 更新数据驻留区域
+func (r *userRepository) UpdateRegion(ctx context.Context, userID, region string, updatedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"region": region, "updated_at": updatedAt}).Error
+}
+
+// UpdateNotificationSettings 更新通知设置
+func (r *userRepository) UpdateNotificationSettings(ctx context.Context, userID string, settings *model.NotificationSettings, updatedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"push_enabled": settings.PushEnabled,
+			"push_preview": settings.ShowPreview,
+			"dnd_start":    settings.DoNotDisturb.Start,
+			"dnd_end":      settings.DoNotDisturb.End,
+			"dnd_timezone": settings.DoNotDisturb.Timezone,
+			"updated_at":   updatedAt,
+		}).Error
+}
+
+// CreateRenameHistory 记录改名历史
+func (r *userRepository) CreateRenameHistory(ctx context.Context, history *model.UserRenameHistory) error {
+	return r.db.WithContext(ctx).Create(history).Error
+}
+
+// CountRenamesSince 统计改名次数
+func (r *userRepository) CountRenamesSince(ctx context.Context, userID string, field model.RenameField, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.UserRenameHistory{}).
+		Where("user_id = ? AND field = ? AND created_at > ?", userID, field, since).
+		Count(&count).Error
+	return count, err
+}
+
+// FindRenameHistory 查询改名历史
+func (r *userRepository) FindRenameHistory(ctx context.Context, userID string, limit int) ([]*model.UserRenameHistory, error) {
+	var history []*model.UserRenameHistory
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&history).Error
+	return history, err
+}
+
+// FindExpiredGuests 查询过期的访客账号
+func (r *userRepository) FindExpiredGuests(ctx context.Context, before time.Time, limit int) ([]*model.User, error) {
+	var users []*model.User
+	err := r.db.WithContext(ctx).
+		Where("guest = ? AND guest_expires_at < ? AND status <> ?", true, before, model.UserStatusDeleted).
+		Order("guest_expires_at").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// UpgradeGuest 将访客账号转为正式账号
+func (r *userRepository) UpgradeGuest(ctx context.Context, userID, username, nickname, passwordHash string, updatedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.User{}).
+		Where("user_id = ? AND guest = ? AND status = ?", userID, true, model.UserStatusNormal).
+		Updates(map[string]interface{}{
+			"username":         username,
+			"nickname":         nickname,
+			"password_hash":    passwordHash,
+			"guest":            false,
+			"guest_expires_at": nil,
+			"updated_at":       updatedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindUsers 按条件分页查询用户
+func (r *userRepository) FindUsers(ctx context.Context, filter *UserFilter, offset, limit int) ([]*model.User, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.User{})
+	if filter.Keyword != "" {
+		pattern := escapeLike(filter.Keyword) + "%"
+		query = query.Where("(user_id = ? OR username LIKE ? OR nickname LIKE ?)", filter.Keyword, pattern, pattern)
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if filter.TenantID != "" {
+		query = query.Where("tenant_id = ?", filter.TenantID)
+	}
+	if filter.Guest != nil {
+		query = query.Where("guest = ?", *filter.Guest)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []*model.User
+	if err := query.Order("created_at DESC, user_id DESC").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// AccountEvent 账号生命周期事件
+type AccountEvent string
+
+const (
+	AccountDisabled AccountEvent = "disabled" // 账号被禁用
+	AccountEnabled  AccountEvent = "enabled"  // 账号恢复正常
+	AccountDeleted  AccountEvent = "deleted"  // 账号已注销
+)
+
+// 账号服务错误定义
+var (
+	ErrAccountDeleted    = errors.New("account has been deleted")
+	ErrInvalidUserStatus = errors.New("invalid user status")
+)
+
+// AccountLifecycleListener 账号生命周期事件监听者
+type AccountLifecycleListener interface {
+	OnAccountEvent(ctx context.Context, userID string, event AccountEvent) error
+}
+
+// AccountService 账号状态管理服务
+type AccountService interface {
+	// SetStatus 变更账号状态并通知监听者，已注销的账号不可恢复
+	SetStatus(ctx context.Context, userID string, status model.UserStatus) error
+
+	// AddListener 注册生命周期事件监听者
+	AddListener(listener AccountLifecycleListener)
+
+	// ListUsers 管理后台按条件分页查询用户
+	ListUsers(ctx context.Context, query *UserListQuery) ([]*model.User, int64, error)
+}
+
+// UserListQuery 管理后台用户查询条件
+type UserListQuery struct {
+	Keyword  string            `form:"keyword"`                                // 用户ID（精确）、用户名或昵称（前缀）
+	Status   *model.UserStatus `form:"status" binding:"omitempty,oneof=0 1 2"` // 账号状态，不传时不限
+	TenantID string            `form:"tenant_id"`                              // 租户
+	Guest    *bool             `form:"guest"`                                  // 是否访客，不传时不限
+	Page     int               `form:"page"`
+	PageSize int               `form:"page_size"`
+}
+
+// accountServiceImpl 账号状态管理服务实现
+type accountServiceImpl struct {
+	userRepo  repository.UserRepository
+	listeners []AccountLifecycleListener
+}
+
+// NewAccountService 创建账号状态管理服务
+func NewAccountService(userRepo repository.UserRepository) AccountService {
+	return &accountServiceImpl{userRepo: userRepo}
+}
+
+// AddListener 注册生命周期事件监听者（需在服务启动前调用）
+func (s *accountServiceImpl) AddListener(listener AccountLifecycleListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// SetStatus 变更账号状态
+func (s *accountServiceImpl) SetStatus(ctx context.Context, userID string, status model.UserStatus) error {
+	var event AccountEvent
+	switch status {
+	case model.UserStatusNormal:
+		event = AccountEnabled
+	case model.UserStatusDisabled:
+		event = AccountDisabled
+	case model.UserStatusDeleted:
+		event = AccountDeleted
+	default:
+		return ErrInvalidUserStatus
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if user.Status == model.UserStatusDeleted {
+		return ErrAccountDeleted
+	}
+	if user.Status == status {
+		return nil
+	}
+
+	if err := s.userRepo.UpdateStatus(ctx, userID, status, time.Now()); err != nil {
+		return err
+	}
+
+	// 状态已生效，监听者失败只记录日志
+	for _, listener := range s.listeners {
+		if err := listener.OnAccountEvent(ctx, userID, event); err != nil {
+			log.Printf("account %s %s listener error: %v", userID, event, err)
+		}
+	}
+	return nil
+}
+
+// ListUsers 按条件分页查询用户
+func (s *accountServiceImpl) ListUsers(ctx context.Context, query *UserListQuery) ([]*model.User, int64, error) {
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := &repository.UserFilter{
+		Keyword:  strings.TrimSpace(query.Keyword),
+		Status:   query.Status,
+		TenantID: query.TenantID,
+		Guest:    query.Guest,
+	}
+	return s.userRepo.FindUsers(ctx, filter, (page-1)*pageSize, pageSize)
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/pkg/archive"
+)
+
+// 压缩包检查错误定义
+var (
+	ErrSuspiciousArchive = errors.New("archive rejected by safety checks")
+)
+
+// checkArchive 检查压缩包（条目数、压缩比、嵌套层数、路径穿越），不是压缩包时返回nil
+func (s *objectStorageService) checkArchive(r io.ReaderAt, size int64, fileName string, head []byte) (*model.ArchiveInfo, error) {
+	format := archive.Detect(head)
+	if format == "" && model.GetFileTypeByExtension(fileExtension(fileName)) != model.FileTypeArchive {
+		return nil, nil
+	}
+
+	report, err := archive.Inspect(r, size, format, s.config.ArchiveLimits)
+	switch {
+	case errors.Is(err, archive.ErrUnsupported):
+		// 扩展名是压缩包但格式无法识别（如bz2、xz）
+		report = &archive.Report{Format: fileExtension(fileName), CompressedSize: size}
+	case err != nil:
+		return nil, fmt.Errorf("%w: %v", ErrSuspiciousArchive, err)
+	}
+
+	if !report.Inspected && s.config.RejectUninspectedArchives {
+		return nil, fmt.Errorf("%w: %s archive cannot be inspected", ErrSuspiciousArchive, report.Format)
+	}
+
+	info := &model.ArchiveInfo{
+		Format:      report.Format,
+		Entries:     report.Entries,
+		TotalSize:   report.TotalSize,
+		Ratio:       report.Ratio,
+		NestedDepth: report.NestedDepth,
+		Encrypted:   report.Encrypted,
+		Inspected:   report.Inspected,
+		Truncated:   report.Truncated,
+	}
+	if !s.config.HideArchiveContents {
+		info.Files = make([]model.ArchiveEntry, 0, len(report.Files))
+		for _, entry := range report.Files {
+			info.Files = append(info.Files, model.ArchiveEntry{Name: entry.Name, Size: entry.Size, Dir: entry.Dir})
+		}
+	} else {
+		info.Truncated = false
+	}
+	return info, nil
+}
+
+// checkObjectArchive 检查已上传对象（分片上传、断点续传合并后）
+func (s *objectStorageService) checkObjectArchive(ctx context.Context, objectPath, fileName string, size int64) (*model.ArchiveInfo, error) {
+	object, err := s.store.Get(ctx, objectPath)
+	if err != nil {
+		return nil, fmt.Errorf("get object error: %w", err)
+	}
+	defer object.Close()
+
+	head := make([]byte, FileSniffLength)
+	n, err := object.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read object error: %w", err)
+	}
+	return s.checkArchive(object, size, fileName, head[:n])
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 自动回复服务错误定义
+var (
+	ErrAutoReplyTextRequired = errors.New("auto-reply text is required when enabled")
+	ErrAutoReplyTextTooLong  = errors.New("auto-reply text is too long")
+	ErrAutoReplyPeriod       = errors.New("auto-reply end time must be after start time")
+)
+
+// 自动回复Redis Key
+const (
+	autoReplySettingKeyPrefix = "autoreply:setting:" // 设置缓存
+	autoReplySentKeyPrefix    = "autoreply:sent:"    // 已回复的发送者（autoreply:sent:<user>:<sender>）
+	autoReplyRateKeyPrefix    = "autoreply:rate:"    // 每小时回复计数
+)
+
+// AutoReplyConfig 自动回复配置
+type AutoReplyConfig struct {
+	Window        time.Duration // 同一发送者在窗口内只自动回复一次
+	MaxPerHour    int           // 每个用户每小时最多自动回复次数
+	MaxTextLength int           // 回复内容最大长度（字符）
+	CacheTTL      time.Duration // 设置缓存时间
+}
+
+// DefaultAutoReplyConfig 默认自动回复配置
+func DefaultAutoReplyConfig() *AutoReplyConfig {
+	return &AutoReplyConfig{
+		Window:        24 * time.Hour,
+		MaxPerHour:    100,
+		MaxTextLength: 500,
+		CacheTTL:      10 * time.Minute,
+	}
+}
+
+// UpdateAutoReplyRequest 更新自动回复设置请求
+type UpdateAutoReplyRequest struct {
+	Enabled bool       `json:"enabled"`
+	Text    string     `json:"text"`
+	StartAt *time.Time `json:"start_at"`
+	EndAt   *time.Time `json:"end_at"`
+}
+
+// AutoReplyService 自动回复服务接口
+type AutoReplyService interface {
+	// GetSetting 获取用户的自动回复设置（未设置时返回关闭状态）
+	GetSetting(ctx context.Context, userID string) (*model.AutoReplySetting, error)
+
+	// UpdateSetting 更新用户的自动回复设置
+	UpdateSetting(ctx context.Context, userID string, req *UpdateAutoReplyRequest) (*model.AutoReplySetting, error)
+
+	// HandleMessage 处理已发送的单聊消息，接收者开启自动回复时向发送者回复
+	HandleMessage(ctx context.Context, msg *model.Message) error
+}
+
+// autoReplyServiceImpl 自动回复服务实现
+type autoReplyServiceImpl struct {
+	repo           repository.AutoReplyRepository
+	messageService MessageService
+	dispatcher     MessageDispatcher
+	redis          *redis.Client
+	config         *AutoReplyConfig
+}
+
+// NewAutoReplyService 创建自动回复服务
+func NewAutoReplyService(
+	repo repository.AutoReplyRepository,
+	messageService MessageService,
+	dispatcher MessageDispatcher,
+	redisClient *redis.Client,
+	config *AutoReplyConfig,
+) AutoReplyService {
+	if config == nil {
+		config = DefaultAutoReplyConfig()
+	}
+	return &autoReplyServiceImpl{
+		repo:           repo,
+		messageService: messageService,
+		dispatcher:     dispatcher,
+		redis:          redisClient,
+		config:         config,
+	}
+}
+
+// GetSetting 获取用户的自动回复设置
+func (s *autoReplyServiceImpl) GetSetting(ctx context.Context, userID string) (*model.AutoReplySetting, error) {
+	setting, err := s.repo.Find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if setting == nil {
+		setting = &model.AutoReplySetting{UserID: userID}
+	}
+	return setting, nil
+}
+
+// UpdateSetting 更新用户的自动回复设置
+func (s *autoReplyServiceImpl) UpdateSetting(ctx context.Context, userID string, req *UpdateAutoReplyRequest) (*model.AutoReplySetting, error) {
+	text := strings.TrimSpace(req.Text)
+	if req.Enabled && text == "" {
+		return nil, ErrAutoReplyTextRequired
+	}
+	if utf8.RuneCountInString(text) > s.config.MaxTextLength {
+		return nil, ErrAutoReplyTextTooLong
+	}
+	if req.StartAt != nil && req.EndAt != nil && !req.EndAt.After(*req.StartAt) {
+		return nil, ErrAutoReplyPeriod
+	}
+
+	setting := &model.AutoReplySetting{
+		UserID:    userID,
+		Enabled:   req.Enabled,
+		Text:      text,
+		StartAt:   req.StartAt,
+		EndAt:     req.EndAt,
+		UpdatedAt: time.Now(),
+	}
+	if err := s.repo.Save(ctx, setting); err != nil {
+		return nil, fmt.Errorf("save auto-reply setting error: %w", err)
+	}
+
+	// 设置变更后重新开始按发送者计数，关闭后再开启的回复不受旧窗口影响
+	if s.redis != nil {
+		s.redis.Del(ctx, autoReplySettingKeyPrefix+userID)
+		s.clearSent(ctx, userID)
+	}
+	return setting, nil
+}
+
+// HandleMessage 处理已发送的单聊消息
+func (s *autoReplyServiceImpl) HandleMessage(ctx context.Context, msg *model.Message) error {
+	if msg.GroupID != "" || msg.To == "" || msg.From == "" || msg.From == msg.To || msg.From == "system" {
+		return nil
+	}
+	// 收到的是自动回复时不再回复，避免双方都开启时互相回复
+	if isAutoReplyContent(msg.Content) {
+		return nil
+	}
+
+	setting, err := s.cachedSetting(ctx, msg.To)
+	if err != nil {
+		return err
+	}
+	if !setting.Active(time.Now()) {
+		return nil
+	}
+
+	if s.redis != nil {
+		// 同一发送者在窗口内只回复一次
+		sentKey := autoReplySentKeyPrefix + msg.To + ":" + msg.From
+		first, err := s.redis.SetNX(ctx, sentKey, 1, s.config.Window).Result()
+		if err != nil {
+			return fmt.Errorf("mark auto-reply sender error: %w", err)
+		}
+		if !first {
+			return nil
+		}
+
+		// 每小时回复次数限制
+		rateKey := autoReplyRateKeyPrefix + msg.To
+		count, err := s.redis.Incr(ctx, rateKey).Result()
+		if err != nil {
+			return fmt.Errorf("count auto-reply error: %w", err)
+		}
+		if count == 1 {
+			s.redis.Expire(ctx, rateKey, time.Hour)
+		}
+		if count > int64(s.config.MaxPerHour) {
+			// 超限未回复的发送者下次发消息时仍可收到回复
+			s.redis.Del(ctx, sentKey)
+			return nil
+		}
+	}
+
+	reply := &model.Message{
+		MessageID:      util.GenerateMessageID(),
+		Type:           model.MsgSingleChat,
+		From:           msg.To,
+		To:             msg.From,
+		ConversationID: model.GetSingleChatConversationID(msg.To, msg.From),
+		Content:        &model.TextContent{Text: setting.Text, AutoReply: true},
+		Timestamp:      time.Now().UnixMilli(),
+		CreatedAt:      time.Now(),
+	}
+	if err := s.messageService.SaveMessage(ctx, reply); err != nil {
+		return err
+	}
+
+	// 同时发给回复者本人的其他设备，便于多端同步
+	if s.dispatcher != nil {
+		if err := s.dispatcher.DispatchToUsers(ctx, []string{msg.From, msg.To}, reply); err != nil {
+			log.Printf("dispatch auto-reply %s error: %v", reply.MessageID, err)
+		}
+	}
+	return nil
+}
+
+// cachedSetting 读取设置（Redis缓存，未设置的用户同样缓存）
+func (s *autoReplyServiceImpl) cachedSetting(ctx context.Context, userID string) (*model.AutoReplySetting, error) {
+	key := autoReplySettingKeyPrefix + userID
+	if s.redis != nil {
+		if data, err := s.redis.Get(ctx, key).Bytes(); err == nil {
+			var setting model.AutoReplySetting
+			if json.Unmarshal(data, &setting) == nil {
+				return &setting, nil
+			}
+		}
+	}
+
+	setting, err := s.GetSetting(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if s.redis != nil {
+		if data, err := json.Marshal(setting); err == nil {
+			s.redis.Set(ctx, key, data, s.config.CacheTTL)
+		}
+	}
+	return setting, nil
+}
+
+// clearSent 清除用户已回复的发送者记录
+func (s *autoReplyServiceImpl) clearSent(ctx context.Context, userID string) {
+	iter := s.redis.Scan(ctx, 0, autoReplySentKeyPrefix+userID+":*", 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if len(keys) > 0 {
+		s.redis.Del(ctx, keys...)
+	}
+}
+
+// isAutoReplyContent 消息内容是否标记为自动回复
+func isAutoReplyContent(content interface{}) bool {
+	switch c := content.(type) {
+	case *model.TextContent:
+		return c.AutoReply
+	case map[string]interface{}:
+		autoReply, _ := c["auto_reply"].(bool)
+		return autoReply
+	}
+	return false
+}
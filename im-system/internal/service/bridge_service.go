@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/bridge"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 桥接服务错误定义
+var (
+	ErrBridgePlatformUnsupported = errors.New("bridge platform is not configured")
+	ErrBridgeRoomNotFound        = errors.New("bridge room not found")
+	ErrBridgeUserNotFound        = errors.New("bridge user mapping not found")
+)
+
+// 桥接Redis Key
+const (
+	bridgeInboundKeyPrefix  = "im:bridge:in:"  // 已处理的外部事件（im:bridge:in:<platform>:<event_id>）
+	bridgeOutboundKeyPrefix = "im:bridge:out:" // 已转发的IM消息
+)
+
+// BridgeConfig 桥接配置
+type BridgeConfig struct {
+	BotUserID    string        // 未映射的外部用户以该IM用户身份发言，消息前加 [外部显示名]
+	DedupTTL     time.Duration // 入站事件、出站消息去重时间
+	RoomCacheTTL time.Duration // 群组频道映射的本地缓存时间
+}
+
+// DefaultBridgeConfig 默认桥接配置
+func DefaultBridgeConfig() *BridgeConfig {
+	return &BridgeConfig{
+		BotUserID:    "bridge",
+		DedupTTL:     24 * time.Hour,
+		RoomCacheTTL: time.Minute,
+	}
+}
+
+// BridgeService 外部平台桥接服务：群组与 Slack 频道 / Matrix 房间双向同步文本消息
+type BridgeService interface {
+	// SetConnector 设置平台连接器，未设置连接器的平台不能绑定
+	SetConnector(connector bridge.Connector)
+
+	// ListRooms 查询全部频道映射
+	ListRooms(ctx context.Context) ([]*model.BridgeRoom, error)
+
+	// BindRoom 绑定群组与外部频道（同一群组在同一平台重复绑定时覆盖）
+	BindRoom(ctx context.Context, operatorID string, req *model.BindBridgeRoomRequest) (*model.BridgeRoom, error)
+
+	// UnbindRoom 解除频道映射
+	UnbindRoom(ctx context.Context, id int64) error
+
+	// ListUsers 查询外部用户映射
+	ListUsers(ctx context.Context, platform string) ([]*model.BridgeUser, error)
+
+	// MapUser 映射外部用户到IM用户，映射后该外部用户以IM用户本人身份发言
+	MapUser(ctx context.Context, req *model.MapBridgeUserRequest) (*model.BridgeUser, error)
+
+	// UnmapUser 删除外部用户映射
+	UnmapUser(ctx context.Context, platform, remoteUserID string) error
+
+	// HandleMessage 将已分发的群聊文本消息转发到绑定的外部频道（不回传给消息来源平台）
+	HandleMessage(ctx context.Context, msg *model.Message) error
+
+	// HandleInbound 将外部频道的消息保存并分发给绑定群组的成员
+	HandleInbound(ctx context.Context, event *bridge.Event) error
+}
+
+// bridgeRoomCache 群组频道映射缓存
+type bridgeRoomCache struct {
+	rooms     []*model.BridgeRoom
+	expiresAt time.Time
+}
+
+// bridgeServiceImpl 桥接服务实现
+type bridgeServiceImpl struct {
+	repo           repository.BridgeRepository
+	userRepo       repository.UserRepository
+	messageService MessageService
+	groupService   GroupService
+	dispatcher     MessageDispatcher
+	redis          *redis.Client
+	config         *BridgeConfig
+
+	mu         sync.RWMutex
+	connectors map[string]bridge.Connector
+	roomCache  map[string]*bridgeRoomCache
+}
+
+// NewBridgeService 创建桥接服务
+func NewBridgeService(
+	repo repository.BridgeRepository,
+	userRepo repository.UserRepository,
+	messageService MessageService,
+	groupService GroupService,
+	dispatcher MessageDispatcher,
+	redisClient *redis.Client,
+	config *BridgeConfig,
+) BridgeService {
+	if config == nil {
+		config = DefaultBridgeConfig()
+	}
+	return &bridgeServiceImpl{
+		repo:           repo,
+		userRepo:       userRepo,
+		messageService: messageService,
+		groupService:   groupService,
+		dispatcher:     dispatcher,
+		redis:          redisClient,
+		config:         config,
+		connectors:     make(map[string]bridge.Connector),
+		roomCache:      make(map[string]*bridgeRoomCache),
+	}
+}
+
+// SetConnector 设置平台连接器
+func (s *bridgeServiceImpl) SetConnector(connector bridge.Connector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connectors[connector.Platform()] = connector
+}
+
+// connector 获取平台连接器
+func (s *bridgeServiceImpl) connector(platform string) bridge.Connector {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.connectors[platform]
+}
+
+// ListRooms 查询全部频道映射
+func (s *bridgeServiceImpl) ListRooms(ctx context.Context) ([]*model.BridgeRoom, error) {
+	return s.repo.ListRooms(ctx)
+}
+
+// BindRoom 绑定群组与外部频道
+func (s *bridgeServiceImpl) BindRoom(ctx context.Context, operatorID string, req *model.BindBridgeRoomRequest) (*model.BridgeRoom, error) {
+	if s.connector(req.Platform) == nil {
+		return nil, ErrBridgePlatformUnsupported
+	}
+	if _, err := s.groupService.GetGroupInfo(ctx, req.GroupID); err != nil {
+		return nil, err
+	}
+
+	room := &model.BridgeRoom{
+		GroupID:      req.GroupID,
+		Platform:     req.Platform,
+		RemoteRoomID: strings.TrimSpace(req.RemoteRoomID),
+		Disabled:     req.Disabled,
+		CreatedBy:    operatorID,
+	}
+	if err := s.repo.SaveRoom(ctx, room); err != nil {
+		return nil, fmt.Errorf("save bridge room error: %w", err)
+	}
+	s.invalidateRooms(req.GroupID)
+
+	// 覆盖已有映射时 Create 不回填ID，重新查询
+	rooms, err := s.repo.FindRoomsByGroup(ctx, req.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("find bridge rooms error: %w", err)
+	}
+	for _, r := range rooms {
+		if r.Platform == req.Platform {
+			return r, nil
+		}
+	}
+	return room, nil
+}
+
+// UnbindRoom 解除频道映射
+func (s *bridgeServiceImpl) UnbindRoom(ctx context.Context, id int64) error {
+	room, err := s.repo.FindRoomByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("find bridge room error: %w", err)
+	}
+	if room == nil {
+		return ErrBridgeRoomNotFound
+	}
+	if err := s.repo.DeleteRoom(ctx, id); err != nil {
+		return fmt.Errorf("delete bridge room error: %w", err)
+	}
+	s.invalidateRooms(room.GroupID)
+	return nil
+}
+
+// ListUsers 查询外部用户映射
+func (s *bridgeServiceImpl) ListUsers(ctx context.Context, platform string) ([]*model.BridgeUser, error) {
+	return s.repo.ListUsers(ctx, platform)
+}
+
+// MapUser 映射外部用户到IM用户
+func (s *bridgeServiceImpl) MapUser(ctx context.Context, req *model.MapBridgeUserRequest) (*model.BridgeUser, error) {
+	if s.connector(req.Platform) == nil {
+		return nil, ErrBridgePlatformUnsupported
+	}
+	user, err := s.userRepo.FindByID(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("find user error: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	mapping := &model.BridgeUser{
+		Platform:     req.Platform,
+		RemoteUserID: strings.TrimSpace(req.RemoteUserID),
+		UserID:       req.UserID,
+	}
+	if err := s.repo.SaveUser(ctx, mapping); err != nil {
+		return nil, fmt.Errorf("save bridge user error: %w", err)
+	}
+	return mapping, nil
+}
+
+// UnmapUser 删除外部用户映射
+func (s *bridgeServiceImpl) UnmapUser(ctx context.Context, platform, remoteUserID string) error {
+	deleted, err := s.repo.DeleteUser(ctx, platform, remoteUserID)
+	if err != nil {
+		return fmt.Errorf("delete bridge user error: %w", err)
+	}
+	if !deleted {
+		return ErrBridgeUserNotFound
+	}
+	return nil
+}
+
+// HandleMessage 转发群聊文本消息到外部频道
+func (s *bridgeServiceImpl) HandleMessage(ctx context.Context, msg *model.Message) error {
+	if msg.Type != model.MsgGroupChat && msg.Type != model.MsgText {
+		return nil
+	}
+	groupID := msg.GroupID
+	if groupID == "" && msg.Type == model.MsgGroupChat {
+		groupID = msg.To
+	}
+	if groupID == "" || msg.From == "" || msg.From == "system" {
+		return nil
+	}
+
+	content := bridgeTextContent(msg.Content)
+	if content == nil || strings.TrimSpace(content.Text) == "" {
+		return nil
+	}
+
+	rooms, err := s.cachedRooms(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	var targets []*model.BridgeRoom
+	for _, room := range rooms {
+		// 防回环：外部平台桥接进来的消息不回传给来源平台
+		if room.Disabled || room.Platform == content.BridgeSource || s.connector(room.Platform) == nil {
+			continue
+		}
+		targets = append(targets, room)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	// 同一条消息多次分发（如重新投递）时只转发一次
+	if s.redis != nil {
+		first, err := s.redis.SetNX(ctx, bridgeOutboundKeyPrefix+msg.MessageID, 1, s.config.DedupTTL).Result()
+		if err != nil {
+			return fmt.Errorf("mark bridged message error: %w", err)
+		}
+		if !first {
+			return nil
+		}
+	}
+
+	// 机器人代发的消息正文已带有外部显示名前缀
+	userName := ""
+	if msg.From != s.config.BotUserID {
+		userName = s.displayName(ctx, msg.From)
+	}
+	for _, room := range targets {
+		out := &bridge.Outgoing{
+			RemoteRoomID: room.RemoteRoomID,
+			TxnID:        msg.MessageID,
+			UserName:     userName,
+			Text:         content.Text,
+		}
+		if err := s.connector(room.Platform).Send(ctx, out); err != nil {
+			log.Printf("bridge message %s to %s room %s error: %v", msg.MessageID, room.Platform, room.RemoteRoomID, err)
+		}
+	}
+	return nil
+}
+
+// HandleInbound 处理外部频道的消息
+func (s *bridgeServiceImpl) HandleInbound(ctx context.Context, event *bridge.Event) error {
+	if strings.TrimSpace(event.Text) == "" {
+		return nil
+	}
+
+	room, err := s.repo.FindRoomByRemote(ctx, event.Platform, event.RemoteRoomID)
+	if err != nil {
+		return fmt.Errorf("find bridge room error: %w", err)
+	}
+	if room == nil || room.Disabled {
+		return nil
+	}
+
+	// 外部平台会重试推送，同一事件只处理一次
+	if s.redis != nil && event.EventID != "" {
+		key := bridgeInboundKeyPrefix + event.Platform + ":" + event.EventID
+		first, err := s.redis.SetNX(ctx, key, 1, s.config.DedupTTL).Result()
+		if err != nil {
+			return fmt.Errorf("mark bridge event error: %w", err)
+		}
+		if !first {
+			return nil
+		}
+	}
+
+	// 已映射且仍在群内的用户以本人身份发言，否则由机器人代发
+	from := s.config.BotUserID
+	text := fmt.Sprintf("[%s] %s", event.UserName, event.Text)
+	mapping, err := s.repo.FindUser(ctx, event.Platform, event.RemoteUserID)
+	if err != nil {
+		return fmt.Errorf("find bridge user error: %w", err)
+	}
+	if mapping != nil {
+		if isMember, err := s.groupService.IsMember(ctx, room.GroupID, mapping.UserID); err == nil && isMember {
+			from = mapping.UserID
+			text = event.Text
+		}
+	}
+
+	timestamp := event.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	msg := &model.Message{
+		MessageID:      util.GenerateMessageID(),
+		Type:           model.MsgGroupChat,
+		From:           from,
+		To:             room.GroupID,
+		GroupID:        room.GroupID,
+		ConversationID: model.GetGroupChatConversationID(room.GroupID),
+		Content:        &model.TextContent{Text: text, BridgeSource: event.Platform},
+		Timestamp:      timestamp.UnixMilli(),
+		CreatedAt:      time.Now(),
+	}
+	if err := s.messageService.SaveMessage(ctx, msg); err != nil {
+		return err
+	}
+
+	memberIDs, err := s.groupService.GetGroupMemberIDs(ctx, room.GroupID)
+	if err != nil {
+		return fmt.Errorf("get group members error: %w", err)
+	}
+	if s.dispatcher != nil {
+		if err := s.dispatcher.DispatchToUsers(ctx, memberIDs, msg); err != nil {
+			log.Printf("dispatch bridged message %s error: %v", msg.MessageID, err)
+		}
+	}
+	return nil
+}
+
+// cachedRooms 获取群组的频道映射（本地缓存，未绑定的群组同样缓存）
+func (s *bridgeServiceImpl) cachedRooms(ctx context.Context, groupID string) ([]*model.BridgeRoom, error) {
+	s.mu.RLock()
+	cached, ok := s.roomCache[groupID]
+	s.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.rooms, nil
+	}
+
+	rooms, err := s.repo.FindRoomsByGroup(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("find bridge rooms error: %w", err)
+	}
+	s.mu.Lock()
+	s.roomCache[groupID] = &bridgeRoomCache{rooms: rooms, expiresAt: time.Now().Add(s.config.RoomCacheTTL)}
+	s.mu.Unlock()
+	return rooms, nil
+}
+
+// invalidateRooms 清除群组的频道映射缓存（其他节点在缓存过期后生效）
+func (s *bridgeServiceImpl) invalidateRooms(groupID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.roomCache, groupID)
+}
+
+// displayName 用户显示名
+func (s *bridgeServiceImpl) displayName(ctx context.Context, userID string) string {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return userID
+	}
+	if user.Nickname != "" {
+		return user.Nickname
+	}
+	return user.Username
+}
+
+// bridgeTextContent 解析文本消息内容（WebSocket 收到的消息内容为 map）
+func bridgeTextContent(content interface{}) *model.TextContent {
+	switch c := content.(type) {
+	case *model.TextContent:
+		return c
+	case map[string]interface{}:
+		data, err := json.Marshal(c)
+		if err != nil {
+			return nil
+		}
+		var text model.TextContent
+		if json.Unmarshal(data, &text) != nil {
+			return nil
+		}
+		return &text
+	}
+	return nil
+}
@@ -0,0 +1,406 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// 内容审核错误定义
+var (
+	ErrContentRejected       = errors.New("content rejected by moderation")
+	ErrModerationUnavailable = errors.New("content moderation unavailable")
+)
+
+const (
+	// moderationAuditContentMax 违规记录保存的原始内容最大字符数
+	moderationAuditContentMax = 2000
+	// moderationRecordTimeout 异步写入违规记录的超时时间
+	moderationRecordTimeout = 5 * time.Second
+)
+
+// ModerationInput 待审核内容，文件审核时 Text 为文件名
+type ModerationInput struct {
+	Source         string `json:"source"`
+	UserID         string `json:"user_id"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	MessageID      string `json:"message_id,omitempty"`
+	Text           string `json:"text"`
+	FileName       string `json:"file_name,omitempty"`
+	ContentType    string `json:"content_type,omitempty"`
+	FileSize       int64  `json:"file_size,omitempty"`
+}
+
+// ModerationResult 审核结果，Action 为空表示未命中
+type ModerationResult struct {
+	Action     string   `json:"action"`
+	Labels     []string `json:"labels,omitempty"`
+	Reason     string   `json:"reason,omitempty"`
+	Terms      []string `json:"terms,omitempty"`       // 命中的原文片段，处置为 mask 时替换为 *
+	MaskedText string   `json:"masked_text,omitempty"` // 审核器给出的替换后文本，优先于 Terms
+}
+
+// ContentModerator 可插拔的内容审核器
+type ContentModerator interface {
+	// Name 审核器名称（记入违规记录）
+	Name() string
+	// Moderate 审核内容，未命中时返回 Action 为空的结果
+	Moderate(ctx context.Context, input *ModerationInput) (*ModerationResult, error)
+}
+
+// ValidModerationAction 是否为有效的处置方式
+func ValidModerationAction(action string) bool {
+	switch action {
+	case model.ModerationActionReject, model.ModerationActionMask, model.ModerationActionFlag:
+		return true
+	}
+	return false
+}
+
+// KeywordModerator 本地关键词及正则审核器
+type KeywordModerator struct {
+	patterns []*regexp.Regexp
+	action   string
+}
+
+// NewKeywordModerator 创建关键词审核器：关键词忽略大小写匹配，正则按原样编译，命中时按 action 处置
+func NewKeywordModerator(keywords, patterns []string, action string) (*KeywordModerator, error) {
+	if !ValidModerationAction(action) {
+		return nil, fmt.Errorf("invalid moderation action %q", action)
+	}
+
+	m := &KeywordModerator{action: action}
+	quoted := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			quoted = append(quoted, regexp.QuoteMeta(keyword))
+		}
+	}
+	if len(quoted) > 0 {
+		m.patterns = append(m.patterns, regexp.MustCompile("(?i)"+strings.Join(quoted, "|")))
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid moderation pattern %q: %w", pattern, err)
+		}
+		m.patterns = append(m.patterns, re)
+	}
+	return m, nil
+}
+
+// Name 审核器名称
+func (m *KeywordModerator) Name() string {
+	return "keyword"
+}
+
+// Moderate 匹配关键词及正则，返回命中的原文片段
+func (m *KeywordModerator) Moderate(ctx context.Context, input *ModerationInput) (*ModerationResult, error) {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, re := range m.patterns {
+		for _, term := range re.FindAllString(input.Text, -1) {
+			if term != "" && !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	if len(terms) == 0 {
+		return &ModerationResult{}, nil
+	}
+	return &ModerationResult{
+		Action: m.action,
+		Labels: []string{"keyword"},
+		Reason: fmt.Sprintf("matched %d keyword(s)", len(terms)),
+		Terms:  terms,
+	}, nil
+}
+
+// HTTPModerator 通过HTTP调用外部审核服务：POST JSON（ModerationInput），
+// 响应 {"flagged": bool, "action": "", "labels": [], "reason": "", "terms": [], "masked_text": ""}
+type HTTPModerator struct {
+	Endpoint string
+	APIKey   string // 不为空时以 Bearer 方式携带
+	Action   string // 响应命中但未给出 action 时的处置方式
+	Client   *http.Client
+}
+
+// Name 审核器名称
+func (p *HTTPModerator) Name() string {
+	return "http"
+}
+
+// Moderate 调用外部审核服务
+func (p *HTTPModerator) Moderate(ctx context.Context, input *ModerationInput) (*ModerationResult, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if p.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("moderation endpoint status %d: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Flagged bool `json:"flagged"`
+		ModerationResult
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("decode moderation response error: %w", err)
+	}
+	if result.Action == "pass" || (!result.Flagged && result.Action == "") {
+		return &ModerationResult{}, nil
+	}
+	if result.Action == "" {
+		result.Action = p.Action
+	}
+	if !ValidModerationAction(result.Action) {
+		return nil, fmt.Errorf("moderation endpoint returned invalid action %q", result.Action)
+	}
+	return &result.ModerationResult, nil
+}
+
+// ContentModerationConfig 内容审核配置
+type ContentModerationConfig struct {
+	Timeout    time.Duration // 单个审核器的超时时间
+	FailClosed bool          // 审核器出错时拒绝内容（默认放行）
+}
+
+// DefaultContentModerationConfig 默认内容审核配置
+func DefaultContentModerationConfig() *ContentModerationConfig {
+	return &ContentModerationConfig{Timeout: 2 * time.Second}
+}
+
+// ContentModerationService 内容审核服务接口
+// 依次调用各审核器：命中 reject 时拒绝，mask 时替换命中内容后交给下一个审核器，flag 时放行；
+// 所有命中均写入违规记录
+type ContentModerationService interface {
+	// CheckMessage 审核聊天消息文本（密文消息跳过），处置为 mask 时改写消息内容，被拒绝时返回 ErrContentRejected
+	CheckMessage(ctx context.Context, msg *model.Message) error
+
+	// CheckFile 审核上传文件的文件名及元数据，返回处置后的文件名，被拒绝时返回 ErrContentRejected
+	CheckFile(ctx context.Context, userID, groupID, fileName, contentType string, size int64) (string, error)
+
+	// ListViolations 分页查询违规记录（管理员）
+	ListViolations(ctx context.Context, filter *model.ModerationViolationFilter, page, pageSize int) ([]*model.ModerationViolation, int64, error)
+}
+
+// contentModerationServiceImpl 内容审核服务实现
+type contentModerationServiceImpl struct {
+	repo       repository.ModerationRepository
+	moderators []ContentModerator
+	config     *ContentModerationConfig
+}
+
+// NewContentModerationService 创建内容审核服务
+func NewContentModerationService(repo repository.ModerationRepository, config *ContentModerationConfig, moderators ...ContentModerator) ContentModerationService {
+	if config == nil {
+		config = DefaultContentModerationConfig()
+	}
+	return &contentModerationServiceImpl{repo: repo, moderators: moderators, config: config}
+}
+
+// CheckMessage 审核聊天消息文本
+func (s *contentModerationServiceImpl) CheckMessage(ctx context.Context, msg *model.Message) error {
+	text, setText := moderationText(msg)
+	if text == "" {
+		return nil
+	}
+
+	moderated, err := s.moderate(ctx, &ModerationInput{
+		Source:         model.ModerationSourceMessage,
+		UserID:         msg.From,
+		ConversationID: msg.ConversationID,
+		MessageID:      msg.MessageID,
+		Text:           text,
+	})
+	if err != nil {
+		return err
+	}
+	if moderated != text {
+		setText(moderated)
+	}
+	return nil
+}
+
+// CheckFile 审核上传文件
+func (s *contentModerationServiceImpl) CheckFile(ctx context.Context, userID, groupID, fileName, contentType string, size int64) (string, error) {
+	input := &ModerationInput{
+		Source:      model.ModerationSourceFile,
+		UserID:      userID,
+		Text:        fileName,
+		FileName:    fileName,
+		ContentType: contentType,
+		FileSize:    size,
+	}
+	if groupID != "" {
+		input.ConversationID = model.GetGroupChatConversationID(groupID)
+	}
+	return s.moderate(ctx, input)
+}
+
+// ListViolations 分页查询违规记录
+func (s *contentModerationServiceImpl) ListViolations(ctx context.Context, filter *model.ModerationViolationFilter, page, pageSize int) ([]*model.ModerationViolation, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	violations, total, err := s.repo.ListViolations(ctx, filter, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list moderation violations error: %w", err)
+	}
+	return violations, total, nil
+}
+
+// moderate 依次调用审核器，返回处置后的文本
+func (s *contentModerationServiceImpl) moderate(ctx context.Context, input *ModerationInput) (string, error) {
+	original := input.Text
+	for _, moderator := range s.moderators {
+		result, err := s.callModerator(ctx, moderator, input)
+		if err != nil {
+			log.Printf("Content moderator %s error: %v", moderator.Name(), err)
+			if s.config.FailClosed {
+				return "", ErrModerationUnavailable
+			}
+			continue
+		}
+		if result == nil || result.Action == "" {
+			continue
+		}
+
+		action := result.Action
+		if action == model.ModerationActionMask {
+			masked := maskModeratedText(input.Text, result)
+			if masked == input.Text {
+				// 没有可替换的内容时按拒绝处理，避免违规内容原样放行
+				action = model.ModerationActionReject
+			} else {
+				input.Text = masked
+			}
+		}
+		s.recordViolation(input, original, moderator.Name(), action, result)
+
+		if action == model.ModerationActionReject {
+			return "", ErrContentRejected
+		}
+	}
+	return input.Text, nil
+}
+
+// callModerator 按超时调用单个审核器
+func (s *contentModerationServiceImpl) callModerator(ctx context.Context, moderator ContentModerator, input *ModerationInput) (*ModerationResult, error) {
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+	return moderator.Moderate(ctx, input)
+}
+
+// recordViolation 异步写入违规记录，失败只记录日志
+func (s *contentModerationServiceImpl) recordViolation(input *ModerationInput, original, moderator, action string, result *ModerationResult) {
+	if s.repo == nil {
+		return
+	}
+	if utf8.RuneCountInString(original) > moderationAuditContentMax {
+		original = string([]rune(original)[:moderationAuditContentMax])
+	}
+	reason := result.Reason
+	if utf8.RuneCountInString(reason) > 512 {
+		reason = string([]rune(reason)[:512])
+	}
+	violation := &model.ModerationViolation{
+		UserID:         input.UserID,
+		Source:         input.Source,
+		ConversationID: input.ConversationID,
+		MessageID:      input.MessageID,
+		FileName:       input.FileName,
+		Moderator:      moderator,
+		Action:         action,
+		Labels:         result.Labels,
+		Reason:         reason,
+		Content:        original,
+		CreatedAt:      time.Now(),
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), moderationRecordTimeout)
+		defer cancel()
+		if err := s.repo.CreateViolation(ctx, violation); err != nil {
+			log.Printf("Record moderation violation of user %s error: %v", violation.UserID, err)
+		}
+	}()
+}
+
+// maskModeratedText 按审核结果替换命中内容：优先使用审核器给出的文本，否则将命中片段替换为等长的 *
+func maskModeratedText(text string, result *ModerationResult) string {
+	if result.MaskedText != "" {
+		return result.MaskedText
+	}
+
+	terms := make([]string, 0, len(result.Terms))
+	for _, term := range result.Terms {
+		if term != "" {
+			terms = append(terms, term)
+		}
+	}
+	// 先替换较长的片段，避免被其中包含的短片段拆开
+	sort.Slice(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	for _, term := range terms {
+		text = strings.ReplaceAll(text, term, strings.Repeat("*", utf8.RuneCountInString(term)))
+	}
+	return text
+}
+
+// moderationText 获取消息的明文文本及改写函数，密文或非文本消息返回空
+func moderationText(msg *model.Message) (string, func(string)) {
+	switch content := msg.Content.(type) {
+	case map[string]interface{}:
+		if ciphertext, _ := content["ciphertext"].(string); ciphertext != "" {
+			return "", nil
+		}
+		text, _ := content["text"].(string)
+		return text, func(masked string) { content["text"] = masked }
+	case *model.TextContent:
+		return content.Text, func(masked string) { content.Text = masked }
+	}
+	return "", nil
+}
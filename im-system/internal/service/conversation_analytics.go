@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// ConversationAnalyticsConfig 会话分析配置
+type ConversationAnalyticsConfig struct {
+	FlushInterval time.Duration // 本地增量写入汇总表的间隔
+	MaxPending    int           // 本地累计的会话数达到该值时提前写入
+}
+
+// DefaultConversationAnalyticsConfig 默认会话分析配置
+func DefaultConversationAnalyticsConfig() *ConversationAnalyticsConfig {
+	return &ConversationAnalyticsConfig{
+		FlushInterval: 10 * time.Second,
+		MaxPending:    5000,
+	}
+}
+
+// ConversationStatsQuery 会话统计查询请求
+type ConversationStatsQuery struct {
+	Type        string `form:"type" binding:"omitempty,oneof=single group"`
+	Sort        string `form:"sort" binding:"omitempty,oneof=messages participants last_activity"`
+	ActiveHours int    `form:"active_hours" binding:"omitempty,min=1"` // 只查询最近N小时内活跃的会话
+	Page        int    `form:"page"`
+	PageSize    int    `form:"page_size"`
+}
+
+// ConversationAnalyticsOverview 会话分析概览
+type ConversationAnalyticsOverview struct {
+	Conversations       int64     `json:"conversations"`
+	SingleConversations int64     `json:"single_conversations"`
+	GroupConversations  int64     `json:"group_conversations"`
+	Messages            int64     `json:"messages"`
+	Active24h           int64     `json:"active_24h"`
+	Active7d            int64     `json:"active_7d"`
+	GeneratedAt         time.Time `json:"generated_at"`
+}
+
+// ConversationAnalyticsService 会话分析服务
+// 根据消息分发事件在本地累计会话增量，定期批量写入会话汇总表；管理后台的分析查询只读取汇总表
+type ConversationAnalyticsService interface {
+	// Record 记录一条已分发的消息（可作为分发事件回调）
+	Record(ctx context.Context, msg *model.Message)
+
+	// ListConversations 分页查询会话统计
+	ListConversations(ctx context.Context, query *ConversationStatsQuery) ([]*model.ConversationStats, int64, error)
+
+	// GetConversation 查询单个会话的统计
+	GetConversation(ctx context.Context, conversationID string) (*model.ConversationStats, error)
+
+	// Overview 获取会话分析概览
+	Overview(ctx context.Context) (*ConversationAnalyticsOverview, error)
+
+	// Start 启动增量写入，阻塞直到 ctx 取消（退出前写入剩余增量）
+	Start(ctx context.Context)
+}
+
+// conversationAnalyticsServiceImpl 会话分析服务实现
+type conversationAnalyticsServiceImpl struct {
+	repo   repository.ConversationStatsRepository
+	config *ConversationAnalyticsConfig
+
+	mu      sync.Mutex
+	pending map[string]*repository.ConversationStatsDelta
+	flushCh chan struct{}
+}
+
+// NewConversationAnalyticsService 创建会话分析服务
+func NewConversationAnalyticsService(repo repository.ConversationStatsRepository, config *ConversationAnalyticsConfig) ConversationAnalyticsService {
+	if config == nil {
+		config = DefaultConversationAnalyticsConfig()
+	}
+	return &conversationAnalyticsServiceImpl{
+		repo:    repo,
+		config:  config,
+		pending: make(map[string]*repository.ConversationStatsDelta),
+		flushCh: make(chan struct{}, 1),
+	}
+}
+
+// Record 记录一条已分发的消息
+func (s *conversationAnalyticsServiceImpl) Record(ctx context.Context, msg *model.Message) {
+	if msg == nil || !msg.Type.IsChat() || msg.ConversationID == "" {
+		return
+	}
+	convID, err := model.ParseConversationID(msg.ConversationID)
+	if err != nil {
+		return
+	}
+	conversationID := convID.String()
+
+	at := time.Now()
+	if msg.Timestamp > 0 {
+		at = time.UnixMilli(msg.Timestamp)
+	}
+
+	s.mu.Lock()
+	delta, ok := s.pending[conversationID]
+	if !ok {
+		delta = &repository.ConversationStatsDelta{
+			ConversationID: conversationID,
+			Type:           convID.Type,
+			Senders:        make(map[string]int64),
+		}
+		s.pending[conversationID] = delta
+	}
+	delta.Messages++
+	if msg.From != "" {
+		delta.Senders[msg.From]++
+	}
+	if at.After(delta.LastActivityAt) {
+		delta.LastActivityAt = at
+	}
+	full := len(s.pending) >= s.config.MaxPending
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start 启动增量写入
+func (s *conversationAnalyticsServiceImpl) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// 使用独立上下文写入剩余增量
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			s.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			s.flush(ctx)
+		case <-s.flushCh:
+			s.flush(ctx)
+		}
+	}
+}
+
+// flush 将本地累计的增量写入汇总表，写入失败的增量合并回本地等待下次写入
+func (s *conversationAnalyticsServiceImpl) flush(ctx context.Context) {
+	s.mu.Lock()
+	if len(s.pending) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	pending := s.pending
+	s.pending = make(map[string]*repository.ConversationStatsDelta, len(pending))
+	s.mu.Unlock()
+
+	failed := 0
+	for _, delta := range pending {
+		if err := s.repo.ApplyDelta(ctx, delta); err != nil {
+			failed++
+			s.restore(delta)
+		}
+	}
+	if failed > 0 {
+		log.Printf("Flush conversation stats: %d of %d conversations failed, will retry", failed, len(pending))
+	}
+}
+
+// restore 将写入失败的增量合并回本地
+func (s *conversationAnalyticsServiceImpl) restore(delta *repository.ConversationStatsDelta) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.pending[delta.ConversationID]
+	if !ok {
+		s.pending[delta.ConversationID] = delta
+		return
+	}
+	current.Messages += delta.Messages
+	for userID, count := range delta.Senders {
+		current.Senders[userID] += count
+	}
+	if delta.LastActivityAt.After(current.LastActivityAt) {
+		current.LastActivityAt = delta.LastActivityAt
+	}
+}
+
+// ListConversations 分页查询会话统计
+func (s *conversationAnalyticsServiceImpl) ListConversations(ctx context.Context, query *ConversationStatsQuery) ([]*model.ConversationStats, int64, error) {
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := &repository.ConversationStatsFilter{
+		Offset: (page - 1) * pageSize,
+		Limit:  pageSize,
+	}
+	switch query.Type {
+	case ConversationTypeNameSingle:
+		filter.Type = model.ConversationTypeSingle
+	case ConversationTypeNameGroup:
+		filter.Type = model.ConversationTypeGroup
+	}
+	switch query.Sort {
+	case "messages":
+		filter.OrderBy = "message_count"
+	case "participants":
+		filter.OrderBy = "participant_count"
+	default:
+		filter.OrderBy = "last_activity_at"
+	}
+	if query.ActiveHours > 0 {
+		filter.ActiveSince = time.Now().Add(-time.Duration(query.ActiveHours) * time.Hour)
+	}
+
+	return s.repo.List(ctx, filter)
+}
+
+// GetConversation 查询单个会话的统计
+func (s *conversationAnalyticsServiceImpl) GetConversation(ctx context.Context, conversationID string) (*model.ConversationStats, error) {
+	convID, err := model.ParseConversationID(conversationID)
+	if err != nil {
+		return nil, ErrConversationNotFound
+	}
+
+	stats, err := s.repo.FindByID(ctx, convID.String())
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return nil, ErrConversationNotFound
+	}
+	return stats, nil
+}
+
+// Overview 获取会话分析概览
+func (s *conversationAnalyticsServiceImpl) Overview(ctx context.Context) (*ConversationAnalyticsOverview, error) {
+	now := time.Now()
+	overview := &ConversationAnalyticsOverview{GeneratedAt: now}
+
+	totals, err := s.repo.Totals(ctx, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range totals {
+		overview.Conversations += total.Conversations
+		overview.Messages += total.Messages
+		switch total.Type {
+		case model.ConversationTypeSingle:
+			overview.SingleConversations = total.Conversations
+		case model.ConversationTypeGroup:
+			overview.GroupConversations = total.Conversations
+		}
+	}
+
+	daily, err := s.repo.Totals(ctx, now.Add(-24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range daily {
+		overview.Active24h += total.Conversations
+	}
+
+	weekly, err := s.repo.Totals(ctx, now.Add(-7*24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range weekly {
+		overview.Active7d += total.Conversations
+	}
+
+	return overview, nil
+}
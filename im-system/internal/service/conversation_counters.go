@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// 会话计数Redis Key：会话级 conv:counters:{会话ID}，用户级 conv:counters:{会话ID}:{用户ID}
+const conversationCountersKeyPrefix = "conv:counters:"
+
+// 会话级计数字段
+const (
+	counterMessages   = "messages"    // 聊天消息数
+	counterFiles      = "files"       // 文件类消息数（图片、语音、视频、文件）
+	counterPinned     = "pinned"      // 置顶消息数
+	counterMentionAll = "mention_all" // @所有人的消息数
+)
+
+// 用户级计数字段
+const (
+	counterReadMark       = "read_mark"        // 最后一次已读时会话的聊天消息数
+	counterMentions       = "mentions"         // 上次已读后@我的消息数
+	counterMentionAllMark = "mention_all_mark" // 最后一次已读时会话的@所有人消息数
+)
+
+// ConversationCounters 会话派生计数
+type ConversationCounters struct {
+	PinnedCount  int64 `json:"pinned_count"`  // 置顶消息数
+	FileCount    int64 `json:"file_count"`    // 文件类消息数
+	MentionCount int64 `json:"mention_count"` // 未读消息中@我（含@所有人）的条数
+	UnreadCount  int64 `json:"unread_count"`  // 未读聊天消息数
+}
+
+// ConversationCounterService 会话计数服务
+// 计数由各子系统在写入路径上增量维护在 Redis 中，会话详情直接读取，不查询消息和文件
+type ConversationCounterService interface {
+	// Record 记录一条已分发的聊天消息：消息数、文件数、@计数，发送者视为已读
+	Record(ctx context.Context, msg *model.Message)
+
+	// RecordRevoke 记录消息撤回（文件类消息的文件数减一）
+	RecordRevoke(ctx context.Context, conversationID string, msgType model.MessageType)
+
+	// MarkRead 用户已读会话：未读数和@我的计数清零
+	MarkRead(ctx context.Context, userID, conversationID string)
+
+	// AdjustPinned 调整会话置顶消息数
+	AdjustPinned(ctx context.Context, conversationID string, delta int64)
+
+	// Get 获取用户在会话中的计数
+	Get(ctx context.Context, userID, conversationID string) (*ConversationCounters, error)
+}
+
+// conversationCounterServiceImpl 会话计数服务实现
+type conversationCounterServiceImpl struct {
+	redis *redis.Client
+}
+
+// NewConversationCounterService 创建会话计数服务
+func NewConversationCounterService(redisClient *redis.Client) ConversationCounterService {
+	return &conversationCounterServiceImpl{redis: redisClient}
+}
+
+// Record 记录一条已分发的聊天消息
+func (s *conversationCounterServiceImpl) Record(ctx context.Context, msg *model.Message) {
+	if !msg.Type.IsChat() {
+		return
+	}
+	conversationID := model.CanonicalConversationID(msg.ConversationID)
+	if conversationID == "" {
+		return
+	}
+
+	atUserIDs, atAll := messageMentions(msg.Content)
+	// 通过群角色、提及组提及的用户同样计入@我
+	atUserIDs = append(atUserIDs, msg.MentionTargets...)
+	convKey := conversationCountersKey(conversationID)
+
+	var messages, mentionAll *redis.IntCmd
+	_, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		messages = pipe.HIncrBy(ctx, convKey, counterMessages, 1)
+		if isFileMessage(msg.Type) {
+			pipe.HIncrBy(ctx, convKey, counterFiles, 1)
+		}
+		var mentionAllDelta int64
+		if atAll {
+			mentionAllDelta = 1
+		}
+		mentionAll = pipe.HIncrBy(ctx, convKey, counterMentionAll, mentionAllDelta)
+		for _, userID := range uniqueStrings(atUserIDs) {
+			if userID != msg.From {
+				pipe.HIncrBy(ctx, userCountersKey(conversationID, userID), counterMentions, 1)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("record counters of conversation %s error: %v", conversationID, err)
+		return
+	}
+
+	// 发送者已看过会话中此前的消息
+	if err := s.redis.HSet(ctx, userCountersKey(conversationID, msg.From),
+		counterReadMark, messages.Val(),
+		counterMentions, 0,
+		counterMentionAllMark, mentionAll.Val(),
+	).Err(); err != nil {
+		log.Printf("mark conversation %s read for sender %s error: %v", conversationID, msg.From, err)
+	}
+}
+
+// RecordRevoke 记录消息撤回
+func (s *conversationCounterServiceImpl) RecordRevoke(ctx context.Context, conversationID string, msgType model.MessageType) {
+	if !isFileMessage(msgType) {
+		return
+	}
+	if err := s.redis.HIncrBy(ctx, conversationCountersKey(model.CanonicalConversationID(conversationID)), counterFiles, -1).Err(); err != nil {
+		log.Printf("record revoke counters of conversation %s error: %v", conversationID, err)
+	}
+}
+
+// MarkRead 用户已读会话
+func (s *conversationCounterServiceImpl) MarkRead(ctx context.Context, userID, conversationID string) {
+	conversationID = model.CanonicalConversationID(conversationID)
+	if conversationID == "" {
+		return
+	}
+
+	values, err := s.redis.HMGet(ctx, conversationCountersKey(conversationID), counterMessages, counterMentionAll).Result()
+	if err != nil {
+		log.Printf("get counters of conversation %s error: %v", conversationID, err)
+		return
+	}
+	if err := s.redis.HSet(ctx, userCountersKey(conversationID, userID),
+		counterReadMark, counterValue(values[0]),
+		counterMentions, 0,
+		counterMentionAllMark, counterValue(values[1]),
+	).Err(); err != nil {
+		log.Printf("mark conversation %s read for user %s error: %v", conversationID, userID, err)
+	}
+}
+
+// AdjustPinned 调整会话置顶消息数
+func (s *conversationCounterServiceImpl) AdjustPinned(ctx context.Context, conversationID string, delta int64) {
+	if err := s.redis.HIncrBy(ctx, conversationCountersKey(model.CanonicalConversationID(conversationID)), counterPinned, delta).Err(); err != nil {
+		log.Printf("adjust pinned count of conversation %s error: %v", conversationID, err)
+	}
+}
+
+// Get 获取用户在会话中的计数
+func (s *conversationCounterServiceImpl) Get(ctx context.Context, userID, conversationID string) (*ConversationCounters, error) {
+	conversationID = model.CanonicalConversationID(conversationID)
+
+	var conv, user *redis.SliceCmd
+	_, err := s.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		conv = pipe.HMGet(ctx, conversationCountersKey(conversationID), counterMessages, counterFiles, counterPinned, counterMentionAll)
+		user = pipe.HMGet(ctx, userCountersKey(conversationID, userID), counterReadMark, counterMentions, counterMentionAllMark)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get conversation counters error: %w", err)
+	}
+
+	convValues, userValues := conv.Val(), user.Val()
+	counters := &ConversationCounters{
+		PinnedCount:  nonNegative(counterValue(convValues[2])),
+		FileCount:    nonNegative(counterValue(convValues[1])),
+		UnreadCount:  nonNegative(counterValue(convValues[0]) - counterValue(userValues[0])),
+		MentionCount: counterValue(userValues[1]) + nonNegative(counterValue(convValues[3])-counterValue(userValues[2])),
+	}
+	// @我的消息一定是未读消息
+	if counters.MentionCount > counters.UnreadCount {
+		counters.MentionCount = counters.UnreadCount
+	}
+	return counters, nil
+}
+
+// conversationCountersKey 会话级计数Key
+func conversationCountersKey(conversationID string) string {
+	return conversationCountersKeyPrefix + conversationID
+}
+
+// userCountersKey 用户级计数Key
+func userCountersKey(conversationID, userID string) string {
+	return conversationCountersKeyPrefix + conversationID + ":" + userID
+}
+
+// counterValue 解析 HMGET 返回的计数，不存在时为0
+func counterValue(v interface{}) int64 {
+	str, ok := v.(string)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(str, 10, 64)
+	return n
+}
+
+// nonNegative 负数按0处理
+func nonNegative(n int64) int64 {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// isFileMessage 是否为文件类消息
+func isFileMessage(t model.MessageType) bool {
+	switch t {
+	case model.MsgImage, model.MsgVoice, model.MsgVideo, model.MsgFile:
+		return true
+	}
+	return false
+}
+
+// messageMentions 解析消息@的用户及是否@所有人
+func messageMentions(content interface{}) ([]string, bool) {
+	switch c := content.(type) {
+	case *model.TextContent:
+		return c.AtUserIDs, c.AtAll
+	case map[string]interface{}:
+		var atUserIDs []string
+		if ids, ok := c["at_user_ids"].([]interface{}); ok {
+			for _, id := range ids {
+				if str, ok := id.(string); ok {
+					atUserIDs = append(atUserIDs, str)
+				}
+			}
+		}
+		atAll, _ := c["at_all"].(bool)
+		return atUserIDs, atAll
+	}
+	return nil, false
+}
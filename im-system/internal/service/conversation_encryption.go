@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 会话加密错误定义
+var (
+	ErrConversationNotEncrypted = errors.New("conversation is not encrypted")
+	ErrPlaintextInEncrypted     = errors.New("plaintext message is not allowed in encrypted conversation")
+	ErrStaleKeyVersion          = errors.New("message is encrypted with an outdated conversation key")
+)
+
+// 密钥轮换原因
+const (
+	KeyRotationEnabled  = "enabled"
+	KeyRotationDisabled = "disabled"
+	KeyRotationRotated  = "rotated"
+)
+
+// ConversationEncryption 会话加密状态
+type ConversationEncryption struct {
+	ConversationID string     `json:"conversation_id"`
+	Type           string     `json:"type"` // single / group
+	Encrypted      bool       `json:"encrypted"`
+	KeyVersion     int        `json:"key_version"`
+	KeyRotatedAt   *time.Time `json:"key_rotated_at,omitempty"`
+}
+
+// SetEncryptionRequest 设置会话加密请求
+type SetEncryptionRequest struct {
+	Encrypted bool `json:"encrypted"`
+}
+
+// RotateKeyRequest 轮换会话密钥请求
+type RotateKeyRequest struct {
+	Reason string `json:"reason" binding:"max=64"`
+}
+
+// ConversationEncryptionService 会话加密服务
+// 只维护加密标记和密钥版本并下发轮换事件（type 106），密钥由客户端生成和分发，服务端不接触明文
+type ConversationEncryptionService interface {
+	// GetEncryption 获取会话加密状态（仅会话参与者可查看）
+	GetEncryption(ctx context.Context, userID, conversationID string) (*ConversationEncryption, error)
+
+	// SetEncrypted 开启或关闭会话加密（单聊双方、群主或群管理员），开启时密钥版本递增，状态变化时通知会话成员
+	SetEncrypted(ctx context.Context, userID, conversationID string, encrypted bool) (*ConversationEncryption, error)
+
+	// RotateKey 轮换会话密钥（单聊双方、群主或群管理员），密钥版本递增并通知会话成员
+	RotateKey(ctx context.Context, userID, conversationID, reason string) (*ConversationEncryption, error)
+
+	// CheckMessage 发送前检查：加密会话中只接受使用当前密钥版本加密的文本类消息
+	CheckMessage(ctx context.Context, msg *model.Message) error
+
+	// ListEncrypted 分页查询开启加密的会话（管理后台）
+	ListEncrypted(ctx context.Context, page, pageSize int) ([]*ConversationEncryption, int64, error)
+}
+
+// conversationEncryptionServiceImpl 会话加密服务实现
+type conversationEncryptionServiceImpl struct {
+	repo         repository.ConversationRepository
+	groupService GroupService
+	dispatcher   MessageDispatcher
+}
+
+// NewConversationEncryptionService 创建会话加密服务
+func NewConversationEncryptionService(repo repository.ConversationRepository, groupService GroupService, dispatcher MessageDispatcher) ConversationEncryptionService {
+	return &conversationEncryptionServiceImpl{
+		repo:         repo,
+		groupService: groupService,
+		dispatcher:   dispatcher,
+	}
+}
+
+// GetEncryption 获取会话加密状态
+func (s *conversationEncryptionServiceImpl) GetEncryption(ctx context.Context, userID, conversationID string) (*ConversationEncryption, error) {
+	convID, err := authorizeConversation(ctx, s.groupService, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := s.repo.FindByID(ctx, convID.String())
+	if err != nil {
+		return nil, fmt.Errorf("find conversation error: %w", err)
+	}
+	if conv == nil {
+		return &ConversationEncryption{ConversationID: convID.String(), Type: conversationTypeName(convID.Type)}, nil
+	}
+	return toConversationEncryption(conv), nil
+}
+
+// SetEncrypted 开启或关闭会话加密
+func (s *conversationEncryptionServiceImpl) SetEncrypted(ctx context.Context, userID, conversationID string, encrypted bool) (*ConversationEncryption, error) {
+	convID, err := s.authorizeManage(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.FindByID(ctx, convID.String())
+	if err != nil {
+		return nil, fmt.Errorf("find conversation error: %w", err)
+	}
+	if current != nil && current.Encrypted == encrypted {
+		return toConversationEncryption(current), nil
+	}
+	if current == nil && !encrypted {
+		return &ConversationEncryption{ConversationID: convID.String(), Type: conversationTypeName(convID.Type)}, nil
+	}
+
+	conv, err := s.repo.UpdateEncryption(ctx, convID.String(), convID.Type, encrypted, encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("update conversation encryption error: %w", err)
+	}
+
+	reason := KeyRotationEnabled
+	if !encrypted {
+		reason = KeyRotationDisabled
+	}
+	s.notify(ctx, convID, conv, userID, reason)
+	return toConversationEncryption(conv), nil
+}
+
+// RotateKey 轮换会话密钥
+func (s *conversationEncryptionServiceImpl) RotateKey(ctx context.Context, userID, conversationID, reason string) (*ConversationEncryption, error) {
+	convID, err := s.authorizeManage(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.FindByID(ctx, convID.String())
+	if err != nil {
+		return nil, fmt.Errorf("find conversation error: %w", err)
+	}
+	if current == nil || !current.Encrypted {
+		return nil, ErrConversationNotEncrypted
+	}
+
+	conv, err := s.repo.UpdateEncryption(ctx, convID.String(), convID.Type, true, true)
+	if err != nil {
+		return nil, fmt.Errorf("rotate conversation key error: %w", err)
+	}
+
+	if reason == "" {
+		reason = KeyRotationRotated
+	}
+	s.notify(ctx, convID, conv, userID, reason)
+	return toConversationEncryption(conv), nil
+}
+
+// authorizeManage 校验用户可以管理会话加密：单聊双方，群聊为群主或管理员
+func (s *conversationEncryptionServiceImpl) authorizeManage(ctx context.Context, userID, conversationID string) (model.ConversationID, error) {
+	convID, err := authorizeConversation(ctx, s.groupService, userID, conversationID)
+	if err != nil || !convID.IsGroup() {
+		return convID, err
+	}
+
+	role, err := s.groupService.GetMemberRole(ctx, convID.GroupID, userID)
+	if err != nil {
+		return convID, err
+	}
+	if role < model.RoleAdmin {
+		return convID, ErrPermissionDeny
+	}
+	return convID, nil
+}
+
+// notify 向会话成员下发加密状态变更/密钥轮换事件
+func (s *conversationEncryptionServiceImpl) notify(ctx context.Context, convID model.ConversationID, conv *model.Conversation, operatorID, reason string) {
+	if s.dispatcher == nil {
+		return
+	}
+
+	recipients := convID.Participants()
+	if convID.IsGroup() {
+		memberIDs, err := s.groupService.GetGroupMemberIDs(ctx, convID.GroupID)
+		if err != nil {
+			log.Printf("get members of conversation %s for key rotation error: %v", convID, err)
+			return
+		}
+		recipients = memberIDs
+	}
+
+	msg := &model.Message{
+		MessageID:      util.GenerateMessageID(),
+		Type:           model.MsgKeyRotation,
+		From:           "system",
+		ConversationID: convID.String(),
+		Content: &model.KeyRotationContent{
+			ConversationID: convID.String(),
+			Encrypted:      conv.Encrypted,
+			KeyVersion:     conv.KeyVersion,
+			OperatorID:     operatorID,
+			Reason:         reason,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if convID.IsGroup() {
+		msg.GroupID = convID.GroupID
+	}
+	if err := s.dispatcher.DispatchToUsers(ctx, recipients, msg); err != nil {
+		log.Printf("dispatch key rotation of conversation %s error: %v", convID, err)
+	}
+}
+
+// CheckMessage 发送前检查消息是否符合会话加密要求
+func (s *conversationEncryptionServiceImpl) CheckMessage(ctx context.Context, msg *model.Message) error {
+	if !msg.Type.IsChat() {
+		return nil
+	}
+	// 端到端加密消息由客户端按设备加密，不使用会话密钥，只需确保不携带明文
+	if msg.Type == model.MsgEncrypted {
+		if content, ok := msg.Content.(map[string]interface{}); ok {
+			if text, _ := content["text"].(string); text != "" {
+				return ErrPlaintextInEncrypted
+			}
+		}
+		return nil
+	}
+
+	convID := model.NewSingleConversationID(msg.From, msg.To)
+	switch {
+	case msg.GroupID != "":
+		convID = model.NewGroupConversationID(msg.GroupID)
+	case msg.Type == model.MsgGroupChat:
+		convID = model.NewGroupConversationID(msg.To)
+	}
+
+	conv, err := s.repo.FindByID(ctx, convID.String())
+	if err != nil {
+		return fmt.Errorf("find conversation error: %w", err)
+	}
+	if conv == nil || !conv.Encrypted {
+		return nil
+	}
+
+	// 图片、文件、位置、名片、自定义等类型的内容本身是明文，加密会话中须封装为密文文本消息发送
+	switch msg.Type {
+	case model.MsgText, model.MsgSingleChat, model.MsgGroupChat:
+	default:
+		return ErrPlaintextInEncrypted
+	}
+
+	content, ok := msg.Content.(map[string]interface{})
+	if !ok {
+		return ErrPlaintextInEncrypted
+	}
+	if ciphertext, _ := content["ciphertext"].(string); ciphertext == "" {
+		return ErrPlaintextInEncrypted
+	}
+	if text, _ := content["text"].(string); text != "" {
+		return ErrPlaintextInEncrypted
+	}
+	if version, _ := content["key_version"].(float64); int(version) < conv.KeyVersion {
+		return ErrStaleKeyVersion
+	}
+	return nil
+}
+
+// ListEncrypted 分页查询开启加密的会话
+func (s *conversationEncryptionServiceImpl) ListEncrypted(ctx context.Context, page, pageSize int) ([]*ConversationEncryption, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	convs, total, err := s.repo.FindEncrypted(ctx, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("find encrypted conversations error: %w", err)
+	}
+
+	result := make([]*ConversationEncryption, 0, len(convs))
+	for _, conv := range convs {
+		result = append(result, toConversationEncryption(conv))
+	}
+	return result, total, nil
+}
+
+// toConversationEncryption 转换为会话加密状态
+func toConversationEncryption(conv *model.Conversation) *ConversationEncryption {
+	return &ConversationEncryption{
+		ConversationID: model.CanonicalConversationID(conv.ConversationID),
+		Type:           conversationTypeName(conv.Type),
+		Encrypted:      conv.Encrypted,
+		KeyVersion:     conv.KeyVersion,
+		KeyRotatedAt:   conv.KeyRotatedAt,
+	}
+}
+
+// conversationTypeName 会话类型名称
+func conversationTypeName(convType int) string {
+	if convType == model.ConversationTypeGroup {
+		return ConversationTypeNameGroup
+	}
+	return ConversationTypeNameSingle
+}
@@ -0,0 +1,578 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"image"
+	_ "image/gif" // 注册GIF解码器
+	"image/jpeg"
+	_ "image/png" // 注册PNG解码器
+	"io"
+	"log"
+	"mime/multipart"
+	"net/textproto"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/i18n"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 会话导出服务错误定义
+var (
+	ErrExportRangeInvalid  = errors.New("export time range is invalid")
+	ErrExportRangeTooLong  = errors.New("export time range is too long")
+	ErrExportFormatInvalid = errors.New("unsupported export format")
+	ErrExportInProgress    = errors.New("another export is in progress")
+	ErrExportJobNotFound   = errors.New("export job not found")
+	ErrExportTooLarge      = errors.New("export exceeds size limit")
+)
+
+// 导出格式
+const (
+	ExportFormatHTML = "html"
+	ExportFormatPDF  = "pdf"
+)
+
+// ExportStatus 导出任务状态
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending"
+	ExportRunning ExportStatus = "running"
+	ExportDone    ExportStatus = "done"
+	ExportFailed  ExportStatus = "failed"
+)
+
+// 导出任务Redis Key
+const (
+	exportJobKeyPrefix  = "export:job:"  // 任务状态
+	exportUserKeyPrefix = "export:user:" // 用户进行中的任务（每个用户同时只能有一个）
+)
+
+// ConversationExportConfig 会话导出配置
+type ConversationExportConfig struct {
+	MaxRange        time.Duration // 最大导出时间范围
+	MaxMessages     int           // 单次导出最多消息数
+	MaxBytes        int64         // 导出文件大小上限
+	MaxInlineImages int           // 最多内嵌的图片缩略图数
+	MaxImageBytes   int64         // 超过该大小的图片不生成缩略图
+	ThumbnailSize   int           // 缩略图最长边（像素）
+	MaxConcurrent   int           // 本节点同时执行的导出任务数
+	JobTimeout      time.Duration // 单个任务超时
+	JobTTL          time.Duration // 任务状态保留时间
+	URLExpiry       time.Duration // 下载链接有效期
+}
+
+// DefaultConversationExportConfig 默认会话导出配置
+func DefaultConversationExportConfig() *ConversationExportConfig {
+	return &ConversationExportConfig{
+		MaxRange:        366 * 24 * time.Hour,
+		MaxMessages:     10000,
+		MaxBytes:        20 * 1024 * 1024,
+		MaxInlineImages: 200,
+		MaxImageBytes:   10 * 1024 * 1024,
+		ThumbnailSize:   320,
+		MaxConcurrent:   2,
+		JobTimeout:      10 * time.Minute,
+		JobTTL:          24 * time.Hour,
+		URLExpiry:       24 * time.Hour,
+	}
+}
+
+// ExportRequest 会话导出请求
+type ExportRequest struct {
+	From   time.Time `json:"from" binding:"required"`
+	To     time.Time `json:"to" binding:"required"`
+	Format string    `json:"format"` // html（默认）或 pdf
+	Locale string    `json:"-"`
+}
+
+// ExportJob 会话导出任务
+type ExportJob struct {
+	JobID          string       `json:"job_id"`
+	UserID         string       `json:"-"`
+	ConversationID string       `json:"conversation_id"`
+	Format         string       `json:"format"`
+	From           time.Time    `json:"from"`
+	To             time.Time    `json:"to"`
+	Locale         string       `json:"-"`
+	Status         ExportStatus `json:"status"`
+	MessageCount   int          `json:"message_count,omitempty"`
+	Size           int64        `json:"size,omitempty"`
+	FileID         string       `json:"file_id,omitempty"`
+	URL            string       `json:"url,omitempty"`
+	URLExpireAt    int64        `json:"url_expire_at,omitempty"`
+	Error          string       `json:"error,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	FinishedAt     *time.Time   `json:"finished_at,omitempty"`
+}
+
+// PDFRenderer 将导出的HTML转换为PDF
+type PDFRenderer interface {
+	Render(ctx context.Context, html []byte) ([]byte, error)
+}
+
+// CommandPDFRenderer 调用外部命令转换PDF（从标准输入读取HTML，向标准输出写PDF，如 wkhtmltopdf --quiet - -）
+type CommandPDFRenderer struct {
+	Command string
+	Args    []string
+}
+
+// Render 执行外部命令生成PDF
+func (r *CommandPDFRenderer) Render(ctx context.Context, html []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.Command, r.Args...)
+	cmd.Stdin = bytes.NewReader(html)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("render pdf error: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// ConversationExportService 会话导出服务接口
+type ConversationExportService interface {
+	// CreateExport 创建导出任务（异步执行，仅会话参与者可导出）
+	CreateExport(ctx context.Context, userID, conversationID string, req *ExportRequest) (*ExportJob, error)
+
+	// GetExport 查询导出任务（仅任务创建者可查询）
+	GetExport(ctx context.Context, userID, jobID string) (*ExportJob, error)
+}
+
+// conversationExportServiceImpl 会话导出服务实现
+type conversationExportServiceImpl struct {
+	conversations ConversationService
+	messageRepo   repository.MessageRepository
+	users         repository.UserRepository
+	fileService   FileStorageService
+	redis         *redis.Client
+	pdf           PDFRenderer
+	config        *ConversationExportConfig
+	slots         chan struct{}
+}
+
+// NewConversationExportService 创建会话导出服务
+// pdf 为空时仅支持HTML格式
+func NewConversationExportService(
+	conversations ConversationService,
+	messageRepo repository.MessageRepository,
+	users repository.UserRepository,
+	fileService FileStorageService,
+	redisClient *redis.Client,
+	pdf PDFRenderer,
+	config *ConversationExportConfig,
+) ConversationExportService {
+	if config == nil {
+		config = DefaultConversationExportConfig()
+	}
+	return &conversationExportServiceImpl{
+		conversations: conversations,
+		messageRepo:   messageRepo,
+		users:         users,
+		fileService:   fileService,
+		redis:         redisClient,
+		pdf:           pdf,
+		config:        config,
+		slots:         make(chan struct{}, config.MaxConcurrent),
+	}
+}
+
+// CreateExport 创建导出任务
+func (s *conversationExportServiceImpl) CreateExport(ctx context.Context, userID, conversationID string, req *ExportRequest) (*ExportJob, error) {
+	format := strings.ToLower(req.Format)
+	switch format {
+	case "", ExportFormatHTML:
+		format = ExportFormatHTML
+	case ExportFormatPDF:
+		if s.pdf == nil {
+			return nil, ErrExportFormatInvalid
+		}
+	default:
+		return nil, ErrExportFormatInvalid
+	}
+	if !req.To.After(req.From) {
+		return nil, ErrExportRangeInvalid
+	}
+	if req.To.Sub(req.From) > s.config.MaxRange {
+		return nil, ErrExportRangeTooLong
+	}
+
+	// 参与者校验
+	detail, err := s.conversations.GetConversation(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &ExportJob{
+		JobID:          util.GenerateShortUUID(),
+		UserID:         userID,
+		ConversationID: detail.ConversationID,
+		Format:         format,
+		From:           req.From,
+		To:             req.To,
+		Locale:         req.Locale,
+		Status:         ExportPending,
+		CreatedAt:      time.Now(),
+	}
+
+	ok, err := s.redis.SetNX(ctx, exportUserKeyPrefix+userID, job.JobID, s.config.JobTimeout).Result()
+	if err != nil {
+		return nil, fmt.Errorf("lock export error: %w", err)
+	}
+	if !ok {
+		return nil, ErrExportInProgress
+	}
+	if err := s.saveJob(ctx, job); err != nil {
+		s.redis.Del(ctx, exportUserKeyPrefix+userID)
+		return nil, err
+	}
+
+	created := *job
+	go s.run(job, detail)
+	return &created, nil
+}
+
+// GetExport 查询导出任务
+func (s *conversationExportServiceImpl) GetExport(ctx context.Context, userID, jobID string) (*ExportJob, error) {
+	data, err := s.redis.Get(ctx, exportJobKeyPrefix+jobID).Bytes()
+	if err == redis.Nil {
+		return nil, ErrExportJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stored := &storedExportJob{ExportJob: &ExportJob{}}
+	if err := json.Unmarshal(data, stored); err != nil {
+		return nil, fmt.Errorf("decode export job error: %w", err)
+	}
+	if stored.UserID != userID {
+		return nil, ErrExportJobNotFound
+	}
+	stored.ExportJob.UserID = stored.UserID
+	return stored.ExportJob, nil
+}
+
+// run 执行导出任务
+func (s *conversationExportServiceImpl) run(job *ExportJob, detail *ConversationDetail) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.JobTimeout)
+	defer cancel()
+	defer s.redis.Del(context.Background(), exportUserKeyPrefix+job.UserID)
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		s.finish(job, ctx.Err())
+		return
+	}
+
+	job.Status = ExportRunning
+	if err := s.saveJob(ctx, job); err != nil {
+		log.Printf("save export job %s error: %v", job.JobID, err)
+	}
+
+	s.finish(job, s.export(ctx, job, detail))
+}
+
+// export 渲染并上传导出文件
+func (s *conversationExportServiceImpl) export(ctx context.Context, job *ExportJob, detail *ConversationDetail) error {
+	docs, err := s.messageRepo.FindByConversationRange(ctx, job.ConversationID, job.From, job.To, s.config.MaxMessages+1)
+	if err != nil {
+		return fmt.Errorf("find messages error: %w", err)
+	}
+	if len(docs) > s.config.MaxMessages {
+		return fmt.Errorf("%w: more than %d messages, narrow the time range", ErrExportTooLarge, s.config.MaxMessages)
+	}
+	job.MessageCount = len(docs)
+
+	content, err := s.renderHTML(ctx, job, detail, docs)
+	if err != nil {
+		return err
+	}
+	ext, contentType := "html", "text/html; charset=utf-8"
+	if job.Format == ExportFormatPDF {
+		if content, err = s.pdf.Render(ctx, content); err != nil {
+			return err
+		}
+		ext, contentType = "pdf", "application/pdf"
+	}
+	if int64(len(content)) > s.config.MaxBytes {
+		return fmt.Errorf("%w: %d bytes", ErrExportTooLarge, len(content))
+	}
+	job.Size = int64(len(content))
+
+	fileName := fmt.Sprintf("conversation-%s-%s.%s", job.From.Format("20060102"), job.To.Format("20060102"), ext)
+	fileInfo, err := s.fileService.Upload(ctx, &UploadRequest{
+		File: &memoryFile{Reader: bytes.NewReader(content)},
+		Header: &multipart.FileHeader{
+			Filename: fileName,
+			Size:     int64(len(content)),
+			Header:   textproto.MIMEHeader{"Content-Type": {contentType}},
+		},
+		UserID:        job.UserID,
+		ContentType:   contentType,
+		SkipTypeCheck: true,
+	})
+	if err != nil {
+		return fmt.Errorf("upload export error: %w", err)
+	}
+	job.FileID = fileInfo.FileID
+
+	signed, err := s.fileService.GetFileURL(ctx, fileInfo.FileID, &FileURLOptions{Expiry: s.config.URLExpiry})
+	if err != nil {
+		return fmt.Errorf("sign export url error: %w", err)
+	}
+	job.URL = signed.URL
+	job.URLExpireAt = signed.ExpireAt
+	return nil
+}
+
+// finish 记录任务结果
+func (s *conversationExportServiceImpl) finish(job *ExportJob, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = ExportDone
+	if err != nil {
+		job.Status = ExportFailed
+		job.Error = err.Error()
+		log.Printf("export conversation %s for %s failed: %v", job.ConversationID, job.UserID, err)
+	}
+	if err := s.saveJob(context.Background(), job); err != nil {
+		log.Printf("save export job %s error: %v", job.JobID, err)
+	}
+}
+
+// storedExportJob Redis中保存的任务（UserID 不对外输出，单独保存以便校验任务归属）
+type storedExportJob struct {
+	*ExportJob
+	UserID string `json:"user_id"`
+}
+
+// saveJob 保存任务状态
+func (s *conversationExportServiceImpl) saveJob(ctx context.Context, job *ExportJob) error {
+	data, err := json.Marshal(&storedExportJob{ExportJob: job, UserID: job.UserID})
+	if err != nil {
+		return err
+	}
+	return s.redis.Set(ctx, exportJobKeyPrefix+job.JobID, data, s.config.JobTTL).Err()
+}
+
+// exportMessage 导出模板中的消息
+type exportMessage struct {
+	Time   string
+	Sender string
+	Text   string
+	Image  template.URL // 内嵌缩略图（data URI）
+	Note   string       // 非文本消息的说明，如 [文件] xxx.pdf
+}
+
+// exportPage 导出模板数据
+type exportPage struct {
+	Lang     string
+	Title    string
+	Range    string
+	Footer   string
+	Messages []*exportMessage
+}
+
+// exportTemplate 导出HTML模板（自包含，不引用外部资源）
+var exportTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body{font-family:-apple-system,"Segoe UI","PingFang SC","Microsoft YaHei",sans-serif;max-width:800px;margin:0 auto;padding:24px;color:#222}
+h1{font-size:20px;margin:0 0 4px}
+.range{color:#888;font-size:13px;margin-bottom:24px}
+.msg{padding:8px 0;border-bottom:1px solid #eee}
+.meta{font-size:12px;color:#888}
+.sender{font-weight:600;color:#333;margin-right:8px}
+.text{white-space:pre-wrap;word-break:break-word;margin-top:4px}
+.note{color:#666;font-style:italic;margin-top:4px}
+img{display:block;max-width:320px;margin-top:4px;border-radius:4px}
+footer{margin-top:24px;color:#aaa;font-size:12px}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="range">{{.Range}}</div>
+{{range .Messages}}<div class="msg">
+<div class="meta"><span class="sender">{{.Sender}}</span>{{.Time}}</div>
+{{if .Text}}<div class="text">{{.Text}}</div>{{end}}{{if .Image}}<img src="{{.Image}}" alt="">{{end}}{{if .Note}}<div class="note">{{.Note}}</div>{{end}}
+</div>
+{{end}}<footer>{{.Footer}}</footer>
+</body>
+</html>
+`))
+
+// renderHTML 渲染导出HTML
+func (s *conversationExportServiceImpl) renderHTML(ctx context.Context, job *ExportJob, detail *ConversationDetail, docs []*repository.MessageDocument) ([]byte, error) {
+	locale := job.Locale
+	if locale == "" {
+		locale = i18n.DefaultLocale
+	}
+
+	names, err := s.senderNames(ctx, docs)
+	if err != nil {
+		return nil, err
+	}
+
+	title := i18n.T(locale, i18n.KeyExportTitleSingle)
+	if detail.Group != nil {
+		title = strings.ReplaceAll(i18n.T(locale, i18n.KeyExportTitleGroup), "{name}", detail.Group.Name)
+	}
+	page := &exportPage{
+		Lang:  locale,
+		Title: title,
+		Range: job.From.Format("2006-01-02 15:04") + " ~ " + job.To.Format("2006-01-02 15:04"),
+		Footer: strings.NewReplacer(
+			"{time}", time.Now().Format("2006-01-02 15:04"),
+			"{count}", fmt.Sprint(len(docs)),
+		).Replace(i18n.T(locale, i18n.KeyExportFooter)),
+		Messages: make([]*exportMessage, 0, len(docs)),
+	}
+
+	inlined := 0
+	for _, doc := range docs {
+		msg := &exportMessage{
+			Time:   doc.CreatedAt.Format("2006-01-02 15:04:05"),
+			Sender: names[doc.From],
+		}
+		if msg.Sender == "" {
+			msg.Sender = doc.From
+		}
+
+		text, _ := doc.Content["text"].(string)
+		fileID, _ := doc.Content["file_id"].(string)
+		fileName, _ := doc.Content["file_name"].(string)
+		switch model.MessageType(doc.Type) {
+		case model.MsgImage:
+			if inlined < s.config.MaxInlineImages && fileID != "" {
+				if data := s.thumbnail(ctx, fileID); data != "" {
+					msg.Image = data
+					inlined++
+					break
+				}
+			}
+			msg.Note = i18n.T(locale, i18n.KeyExportImage)
+		case model.MsgFile, model.MsgVoice, model.MsgVideo:
+			msg.Note = strings.TrimSpace(i18n.T(locale, i18n.KeyExportFile) + " " + fileName)
+		default:
+			if text == "" {
+				msg.Note = "[" + model.MessageType(doc.Type).String() + "]"
+			}
+		}
+		msg.Text = text
+		page.Messages = append(page.Messages, msg)
+	}
+
+	var buf bytes.Buffer
+	if err := exportTemplate.Execute(&buf, page); err != nil {
+		return nil, fmt.Errorf("render export error: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// senderNames 查询发送者显示名
+func (s *conversationExportServiceImpl) senderNames(ctx context.Context, docs []*repository.MessageDocument) (map[string]string, error) {
+	seen := make(map[string]bool)
+	var userIDs []string
+	for _, doc := range docs {
+		if !seen[doc.From] {
+			seen[doc.From] = true
+			userIDs = append(userIDs, doc.From)
+		}
+	}
+
+	names := make(map[string]string, len(userIDs))
+	if len(userIDs) == 0 {
+		return names, nil
+	}
+	users, err := s.users.FindByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("find senders error: %w", err)
+	}
+	for _, user := range users {
+		names[user.UserID] = user.Nickname
+		if user.Nickname == "" {
+			names[user.UserID] = user.Username
+		}
+	}
+	return names, nil
+}
+
+// thumbnail 下载图片并生成内嵌缩略图，失败时返回空
+func (s *conversationExportServiceImpl) thumbnail(ctx context.Context, fileID string) template.URL {
+	reader, info, err := s.fileService.Download(ctx, fileID)
+	if err != nil {
+		return ""
+	}
+	defer reader.Close()
+	if info.FileSize > s.config.MaxImageBytes {
+		return ""
+	}
+
+	src, _, err := image.Decode(io.LimitReader(reader, s.config.MaxImageBytes))
+	if err != nil {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resizeImage(src, s.config.ThumbnailSize), &jpeg.Options{Quality: 75}); err != nil {
+		return ""
+	}
+	return template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+// resizeImage 按最长边等比缩小图片（最近邻采样）
+func resizeImage(src image.Image, maxSide int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSide && h <= maxSide {
+		return src
+	}
+
+	nw, nh := maxSide, h*maxSide/w
+	if h > w {
+		nw, nh = w*maxSide/h, maxSide
+	}
+	if nw < 1 {
+		nw = 1
+	}
+	if nh < 1 {
+		nh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, nw, nh))
+	for y := 0; y < nh; y++ {
+		sy := bounds.Min.Y + y*h/nh
+		for x := 0; x < nw; x++ {
+			dst.Set(x, y, src.At(bounds.Min.X+x*w/nw, sy))
+		}
+	}
+	return dst
+}
+
+// memoryFile 内存中的上传文件
+type memoryFile struct {
+	*bytes.Reader
+}
+
+// Close 实现 multipart.File
+func (f *memoryFile) Close() error {
+	return nil
+}
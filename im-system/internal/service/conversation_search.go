@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// 会话内搜索限制
+const (
+	conversationSearchMaxKeyword   = 100 // 关键字最大长度（字符）
+	conversationSearchDefaultLimit = 20
+	conversationSearchMaxLimit     = 50
+	messageContextDefaultSize      = 10
+	messageContextMaxSize          = 50
+)
+
+// ConversationSearchRequest 会话内搜索请求
+type ConversationSearchRequest struct {
+	Keyword string `form:"keyword"`
+	Before  string `form:"before"` // 分页游标：上一页最后一条命中的消息ID
+	Limit   int    `form:"limit"`
+}
+
+// ConversationSearchResult 会话内搜索结果（按时间倒序）
+type ConversationSearchResult struct {
+	Hits       []*ConversationSearchHit `json:"hits"`
+	HasMore    bool                     `json:"has_more"`
+	NextCursor string                   `json:"next_cursor,omitempty"` // 作为下一页的 before 参数
+}
+
+// ConversationSearchHit 搜索命中
+type ConversationSearchHit struct {
+	Message    *MessageDTO     `json:"message"`
+	Highlights []TextHighlight `json:"highlights"` // 关键字在 content.text 中的位置
+	Anchor     *MessageAnchor  `json:"anchor"`
+}
+
+// TextHighlight 高亮区间（按字符计算）
+type TextHighlight struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+// MessageAnchor 跳转锚点
+// 客户端可用 message_id 调用上下文接口加载前后消息，或用 last_seq 调用历史消息接口从命中消息开始向前加载
+type MessageAnchor struct {
+	MessageID string `json:"message_id"`
+	Seq       int64  `json:"seq"`
+	LastSeq   int64  `json:"last_seq"` // 历史消息接口参数，返回结果包含命中消息
+	Timestamp int64  `json:"timestamp"`
+}
+
+// MessageContext 消息上下文（均按时间升序）
+type MessageContext struct {
+	Before        []*MessageDTO `json:"before"`
+	Message       *MessageDTO   `json:"message"`
+	After         []*MessageDTO `json:"after"`
+	HasMoreBefore bool          `json:"has_more_before"`
+	HasMoreAfter  bool          `json:"has_more_after"`
+}
+
+// SearchMessages 在会话内搜索文本消息
+func (s *conversationServiceImpl) SearchMessages(ctx context.Context, userID, conversationID string, req *ConversationSearchRequest) (*ConversationSearchResult, error) {
+	keyword := strings.TrimSpace(req.Keyword)
+	if keyword == "" || utf8.RuneCountInString(keyword) > conversationSearchMaxKeyword {
+		return nil, ErrSearchKeywordInvalid
+	}
+	limit := req.Limit
+	if limit <= 0 || limit > conversationSearchMaxLimit {
+		limit = conversationSearchDefaultLimit
+	}
+
+	convID, err := s.authorize(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var before *repository.MessageCursor
+	if req.Before != "" {
+		doc, err := s.findInConversation(ctx, convID, req.Before)
+		if err != nil {
+			return nil, err
+		}
+		before = &repository.MessageCursor{CreatedAt: doc.CreatedAt, MessageID: doc.MessageID}
+	}
+
+	// 多查一条用于判断是否还有更多
+	docs, err := s.messageRepo.SearchInConversation(ctx, convID.String(), keyword, before, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("search conversation messages error: %w", err)
+	}
+
+	result := &ConversationSearchResult{Hits: make([]*ConversationSearchHit, 0, len(docs))}
+	if len(docs) > limit {
+		docs = docs[:limit]
+		result.HasMore = true
+		result.NextCursor = docs[limit-1].MessageID
+	}
+	for _, doc := range docs {
+		text, _ := doc.Content["text"].(string)
+		result.Hits = append(result.Hits, &ConversationSearchHit{
+			Message:    documentToDTO(doc),
+			Highlights: highlightRanges(text, keyword),
+			Anchor: &MessageAnchor{
+				MessageID: doc.MessageID,
+				Seq:       doc.Seq,
+				LastSeq:   doc.Seq + 1,
+				Timestamp: doc.CreatedAt.UnixMilli(),
+			},
+		})
+	}
+	return result, nil
+}
+
+// GetMessageContext 获取会话内某条消息前后的消息
+func (s *conversationServiceImpl) GetMessageContext(ctx context.Context, userID, conversationID, messageID string, before, after int) (*MessageContext, error) {
+	if before < 0 || before > messageContextMaxSize {
+		before = messageContextDefaultSize
+	}
+	if after < 0 || after > messageContextMaxSize {
+		after = messageContextDefaultSize
+	}
+
+	convID, err := s.authorize(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.findInConversation(ctx, convID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor := repository.MessageCursor{CreatedAt: doc.CreatedAt, MessageID: doc.MessageID}
+	older, newer, err := s.messageRepo.FindAround(ctx, convID.String(), anchor, before+1, after+1)
+	if err != nil {
+		return nil, fmt.Errorf("find message context error: %w", err)
+	}
+
+	result := &MessageContext{Message: documentToDTO(doc)}
+	if len(older) > before {
+		older = older[len(older)-before:]
+		result.HasMoreBefore = true
+	}
+	if len(newer) > after {
+		newer = newer[:after]
+		result.HasMoreAfter = true
+	}
+	result.Before = documentsToDTO(older)
+	result.After = documentsToDTO(newer)
+	return result, nil
+}
+
+// findInConversation 查询属于该会话的消息，已撤回或不属于该会话时按不存在处理
+func (s *conversationServiceImpl) findInConversation(ctx context.Context, convID model.ConversationID, messageID string) (*repository.MessageDocument, error) {
+	doc, err := s.messageRepo.FindByMessageID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil || doc.Revoked || model.CanonicalConversationID(doc.ConversationID) != convID.String() {
+		return nil, ErrMessageNotFound
+	}
+	return doc, nil
+}
+
+// highlightRanges 计算关键字在文本中出现的位置（不区分大小写，按字符计算，不重叠）
+func highlightRanges(text, keyword string) []TextHighlight {
+	source := foldRunes(text)
+	pattern := foldRunes(keyword)
+	ranges := make([]TextHighlight, 0, 1)
+	if len(pattern) == 0 {
+		return ranges
+	}
+
+	for i := 0; i+len(pattern) <= len(source); {
+		if runesEqual(source[i:i+len(pattern)], pattern) {
+			ranges = append(ranges, TextHighlight{Start: i, Length: len(pattern)})
+			i += len(pattern)
+			continue
+		}
+		i++
+	}
+	return ranges
+}
+
+// foldRunes 转为小写字符序列
+func foldRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// runesEqual 比较字符序列
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
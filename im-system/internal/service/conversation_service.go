@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// 会话服务错误定义
+var (
+	ErrConversationNotFound = errors.New("conversation not found")
+	ErrSearchKeywordInvalid = errors.New("search keyword is empty or too long")
+)
+
+// 会话类型名称
+const (
+	ConversationTypeNameSingle = "single"
+	ConversationTypeNameGroup  = "group"
+)
+
+// ConversationDetail 会话详情
+type ConversationDetail struct {
+	ConversationID string                   `json:"conversation_id"`
+	Type           string                   `json:"type"`                   // single / group
+	Participants   []string                 `json:"participants,omitempty"` // 单聊双方
+	Group          *ConversationGroup       `json:"group,omitempty"`        // 群聊引用（成员通过群成员接口分页获取）
+	Settings       *ConversationSettings    `json:"settings"`               // 当前用户的会话设置
+	LastMessage    *ConversationLastMessage `json:"last_message,omitempty"`
+	Counters       *ConversationCounters    `json:"counters,omitempty"` // 置顶、文件、@我、未读计数（未启用计数时为空）
+}
+
+// ConversationGroup 会话关联的群组
+type ConversationGroup struct {
+	GroupID     string `json:"group_id"`
+	Name        string `json:"name"`
+	Avatar      string `json:"avatar,omitempty"`
+	MemberCount int    `json:"member_count"`
+}
+
+// ConversationSettings 用户的会话设置
+type ConversationSettings struct {
+	Muted       bool  `json:"muted"`
+	Pinned      bool  `json:"pinned"`
+	UnreadCount int   `json:"unread_count"`
+	LastReadSeq int64 `json:"last_read_seq"`
+}
+
+// ConversationLastMessage 最后一条消息摘要
+type ConversationLastMessage struct {
+	MessageID string    `json:"message_id"`
+	Type      int       `json:"type"`
+	From      string    `json:"from"`
+	Seq       int64     `json:"seq"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ConversationItem 会话列表项
+type ConversationItem struct {
+	ConversationID string     `json:"conversation_id"`
+	Type           string     `json:"type"`               // single / group
+	PeerID         string     `json:"peer_id,omitempty"`  // 单聊对方
+	GroupID        string     `json:"group_id,omitempty"` // 群聊群ID
+	LastMessageID  string     `json:"last_message_id,omitempty"`
+	LastMessageAt  *time.Time `json:"last_message_at,omitempty"`
+	UnreadCount    int        `json:"unread_count"`
+	Pinned         bool       `json:"pinned"`
+	Muted          bool       `json:"muted"`
+}
+
+// ConversationService 会话服务接口
+type ConversationService interface {
+	// GetConversation 获取会话详情（仅会话参与者可查看）
+	GetConversation(ctx context.Context, userID, conversationID string) (*ConversationDetail, error)
+
+	// SearchMessages 在会话内搜索文本消息，返回命中位置及跳转锚点（仅会话参与者可搜索）
+	SearchMessages(ctx context.Context, userID, conversationID string, req *ConversationSearchRequest) (*ConversationSearchResult, error)
+
+	// GetMessageContext 获取会话内某条消息前后的消息，用于跳转到搜索结果后加载上下文
+	GetMessageContext(ctx context.Context, userID, conversationID, messageID string, before, after int) (*MessageContext, error)
+
+	// ListConversations 分页获取用户的会话列表（置顶优先，再按最后一条消息时间倒序，不含已删除的会话）
+	ListConversations(ctx context.Context, userID string, page, pageSize int) ([]*ConversationItem, int64, error)
+
+	// SetPinned 置顶或取消置顶会话（仅会话参与者）
+	SetPinned(ctx context.Context, userID, conversationID string, pinned bool) error
+
+	// SetMuted 开启或关闭会话免打扰（仅会话参与者），免打扰的会话不推送离线通知
+	SetMuted(ctx context.Context, userID, conversationID string, muted bool) error
+
+	// DeleteConversation 从会话列表中删除会话（未读数清零，不删除消息），会话有新消息时重新显示
+	DeleteConversation(ctx context.Context, userID, conversationID string) error
+
+	// SetCounters 设置会话计数服务，设置后会话详情包含派生计数
+	SetCounters(counters ConversationCounterService)
+}
+
+// conversationServiceImpl 会话服务实现
+type conversationServiceImpl struct {
+	repo         repository.ConversationRepository
+	messageRepo  repository.MessageRepository
+	groupService GroupService
+	counters     ConversationCounterService
+}
+
+// NewConversationService 创建会话服务
+func NewConversationService(repo repository.ConversationRepository, messageRepo repository.MessageRepository, groupService GroupService) ConversationService {
+	return &conversationServiceImpl{
+		repo:         repo,
+		messageRepo:  messageRepo,
+		groupService: groupService,
+	}
+}
+
+// GetConversation 获取会话详情
+func (s *conversationServiceImpl) GetConversation(ctx context.Context, userID, conversationID string) (*ConversationDetail, error) {
+	convID, err := s.authorize(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &ConversationDetail{ConversationID: convID.String()}
+	var docs []*repository.MessageDocument
+
+	if convID.IsGroup() {
+		group, err := s.groupService.GetGroupInfo(ctx, convID.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		detail.Type = ConversationTypeNameGroup
+		detail.Group = &ConversationGroup{
+			GroupID:     group.GroupID,
+			Name:        group.Name,
+			Avatar:      group.Avatar,
+			MemberCount: group.MemberCount,
+		}
+		docs, err = s.messageRepo.FindByGroup(ctx, convID.GroupID, 0, 1)
+		if err != nil {
+			return nil, fmt.Errorf("find last message error: %w", err)
+		}
+	} else {
+		detail.Type = ConversationTypeNameSingle
+		detail.Participants = convID.Participants()
+		docs, err = s.messageRepo.FindByPrivateChat(ctx, convID.UserIDs[0], convID.UserIDs[1], 0, 1)
+		if err != nil {
+			return nil, fmt.Errorf("find last message error: %w", err)
+		}
+	}
+
+	if len(docs) > 0 {
+		doc := docs[0]
+		detail.LastMessage = &ConversationLastMessage{
+			MessageID: doc.MessageID,
+			Type:      doc.Type,
+			From:      doc.From,
+			Seq:       doc.Seq,
+			CreatedAt: doc.CreatedAt,
+		}
+	}
+
+	settings, err := s.repo.FindUserConversation(ctx, userID, convID.String())
+	if err != nil {
+		return nil, fmt.Errorf("find conversation settings error: %w", err)
+	}
+	detail.Settings = &ConversationSettings{}
+	if settings != nil {
+		detail.Settings = &ConversationSettings{
+			Muted:       settings.Muted,
+			Pinned:      settings.Pinned,
+			UnreadCount: settings.UnreadCount,
+			LastReadSeq: settings.LastReadSeq,
+		}
+	}
+
+	if s.counters != nil {
+		counters, err := s.counters.Get(ctx, userID, convID.String())
+		if err != nil {
+			return nil, err
+		}
+		detail.Counters = counters
+	}
+
+	return detail, nil
+}
+
+// ListConversations 分页获取用户的会话列表
+func (s *conversationServiceImpl) ListConversations(ctx context.Context, userID string, page, pageSize int) ([]*ConversationItem, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	entries, total, err := s.repo.FindUserConversations(ctx, userID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items := make([]*ConversationItem, 0, len(entries))
+	for _, entry := range entries {
+		item := &ConversationItem{
+			ConversationID: entry.ConversationID,
+			LastMessageID:  entry.LastMessageID,
+			LastMessageAt:  entry.LastMessageAt,
+			UnreadCount:    entry.UnreadCount,
+			Pinned:         entry.Pinned,
+			Muted:          entry.Muted,
+		}
+		if convID, err := model.ParseConversationID(entry.ConversationID); err == nil {
+			item.ConversationID = convID.String()
+			if convID.IsGroup() {
+				item.Type = ConversationTypeNameGroup
+				item.GroupID = convID.GroupID
+			} else {
+				item.Type = ConversationTypeNameSingle
+				item.PeerID = convID.Peer(userID)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, total, nil
+}
+
+// SetPinned 置顶或取消置顶会话
+func (s *conversationServiceImpl) SetPinned(ctx context.Context, userID, conversationID string, pinned bool) error {
+	convID, err := s.authorize(ctx, userID, conversationID)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdateUserConversation(ctx, userID, convID.String(), &repository.UserConversationUpdate{Pinned: &pinned})
+}
+
+// SetMuted 开启或关闭会话免打扰
+func (s *conversationServiceImpl) SetMuted(ctx context.Context, userID, conversationID string, muted bool) error {
+	convID, err := s.authorize(ctx, userID, conversationID)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdateUserConversation(ctx, userID, convID.String(), &repository.UserConversationUpdate{Muted: &muted})
+}
+
+// DeleteConversation 从会话列表中删除会话（已退出的群会话也可删除，只修改当前用户的记录）
+func (s *conversationServiceImpl) DeleteConversation(ctx context.Context, userID, conversationID string) error {
+	convID, err := model.ParseConversationID(conversationID)
+	if err != nil {
+		return ErrConversationNotFound
+	}
+	if !convID.IsGroup() && !convID.HasParticipant(userID) {
+		return ErrPermissionDeny
+	}
+
+	deleted := true
+	return s.repo.UpdateUserConversation(ctx, userID, convID.String(), &repository.UserConversationUpdate{Deleted: &deleted})
+}
+
+// SetCounters 设置会话计数服务
+func (s *conversationServiceImpl) SetCounters(counters ConversationCounterService) {
+	s.counters = counters
+}
+
+// authorize 解析会话ID并校验用户是会话参与者
+func (s *conversationServiceImpl) authorize(ctx context.Context, userID, conversationID string) (model.ConversationID, error) {
+	return authorizeConversation(ctx, s.groupService, userID, conversationID)
+}
+
+// authorizeConversation 解析会话ID并校验用户是会话参与者（群聊为群成员，单聊为双方之一）
+func authorizeConversation(ctx context.Context, groupService GroupService, userID, conversationID string) (model.ConversationID, error) {
+	convID, err := model.ParseConversationID(conversationID)
+	if err != nil {
+		return convID, ErrConversationNotFound
+	}
+
+	if convID.IsGroup() {
+		isMember, err := groupService.IsMember(ctx, convID.GroupID, userID)
+		if err != nil {
+			return convID, err
+		}
+		if !isMember {
+			return convID, ErrNotGroupMember
+		}
+		return convID, nil
+	}
+	if !convID.HasParticipant(userID) {
+		return convID, ErrPermissionDeny
+	}
+	return convID, nil
+}
@@ -0,0 +1,392 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 会话摘要错误定义
+var (
+	ErrSummaryRateLimited = errors.New("summary rate limit exceeded")
+	ErrSummaryEncrypted   = errors.New("encrypted conversation cannot be summarized")
+	ErrSummaryEmpty       = errors.New("no messages to summarize")
+	ErrSummaryUnavailable = errors.New("summary service unavailable")
+)
+
+// 会话摘要Redis Key
+const summaryRateKeyPrefix = "summary:rate:" // 用户每小时摘要次数
+
+// SummaryConfig 会话摘要配置
+type SummaryConfig struct {
+	MaxMessages   int            // 单次摘要最多取最近的消息数
+	MaxPerHour    int            // 每个用户每小时最多摘要次数
+	MaxTextLength int            // 单条消息送入摘要的最大字符数
+	RedactPattern *regexp.Regexp // 发送给外部服务前替换为 [redacted] 的内容
+}
+
+// DefaultSummaryConfig 默认会话摘要配置（默认脱敏邮箱、手机号及12-19位数字）
+func DefaultSummaryConfig() *SummaryConfig {
+	return &SummaryConfig{
+		MaxMessages:   100,
+		MaxPerHour:    10,
+		MaxTextLength: 2000,
+		RedactPattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}|\+?\d[\d \-]{10,}\d`),
+	}
+}
+
+// SummaryMessage 送入摘要的消息（已脱敏）
+type SummaryMessage struct {
+	MessageID string `json:"message_id"`
+	From      string `json:"from"`
+	Name      string `json:"name"`
+	Text      string `json:"text"`
+	Timestamp int64  `json:"timestamp"` // 毫秒
+}
+
+// SummaryInput 摘要请求（发送给外部摘要服务）
+type SummaryInput struct {
+	ConversationID string            `json:"conversation_id"`
+	Locale         string            `json:"locale,omitempty"`
+	Messages       []*SummaryMessage `json:"messages"`
+}
+
+// Summarizer 摘要生成器（外部LLM服务的接入点）
+type Summarizer interface {
+	// Summarize 根据消息窗口生成摘要
+	Summarize(ctx context.Context, input *SummaryInput) (string, error)
+}
+
+// HTTPSummarizer 通过HTTP调用外部摘要服务：POST JSON（SummaryInput），响应 {"summary": "..."}
+type HTTPSummarizer struct {
+	Endpoint string
+	APIKey   string // 不为空时以 Bearer 方式携带
+	Client   *http.Client
+}
+
+// Summarize 调用外部摘要服务
+func (h *HTTPSummarizer) Summarize(ctx context.Context, input *SummaryInput) (string, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.Endpoint, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if h.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.APIKey)
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("summary endpoint status %d: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Summary string `json:"summary"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("decode summary response error: %w", err)
+	}
+	return result.Summary, nil
+}
+
+// SummarizeRequest 会话摘要请求
+type SummarizeRequest struct {
+	Limit  int    `json:"limit"` // 最近的消息数，默认且最多为配置上限
+	Locale string `json:"-"`
+}
+
+// ConversationSummaryService 会话摘要服务
+// 取会话最近的消息窗口（脱敏后）交给外部摘要服务生成摘要，摘要保存后以 type 107 消息只下发给请求者
+type ConversationSummaryService interface {
+	// Summarize 生成会话摘要（仅会话参与者，加密会话不支持，按用户限频）
+	Summarize(ctx context.Context, userID, conversationID string, req *SummarizeRequest) (*model.ConversationSummary, error)
+
+	// ListSummaries 查询用户在会话中生成过的摘要
+	ListSummaries(ctx context.Context, userID, conversationID string, limit int) ([]*model.ConversationSummary, error)
+}
+
+// conversationSummaryServiceImpl 会话摘要服务实现
+type conversationSummaryServiceImpl struct {
+	repo          repository.ConversationSummaryRepository
+	conversations repository.ConversationRepository
+	messageRepo   repository.MessageRepository
+	users         repository.UserRepository
+	groupService  GroupService
+	dispatcher    MessageDispatcher
+	summarizer    Summarizer
+	redis         *redis.Client
+	config        *SummaryConfig
+}
+
+// NewConversationSummaryService 创建会话摘要服务
+func NewConversationSummaryService(
+	repo repository.ConversationSummaryRepository,
+	conversations repository.ConversationRepository,
+	messageRepo repository.MessageRepository,
+	users repository.UserRepository,
+	groupService GroupService,
+	dispatcher MessageDispatcher,
+	summarizer Summarizer,
+	redisClient *redis.Client,
+	config *SummaryConfig,
+) ConversationSummaryService {
+	if config == nil {
+		config = DefaultSummaryConfig()
+	}
+	return &conversationSummaryServiceImpl{
+		repo:          repo,
+		conversations: conversations,
+		messageRepo:   messageRepo,
+		users:         users,
+		groupService:  groupService,
+		dispatcher:    dispatcher,
+		summarizer:    summarizer,
+		redis:         redisClient,
+		config:        config,
+	}
+}
+
+// Summarize 生成会话摘要
+func (s *conversationSummaryServiceImpl) Summarize(ctx context.Context, userID, conversationID string, req *SummarizeRequest) (*model.ConversationSummary, error) {
+	convID, err := authorizeConversation(ctx, s.groupService, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := s.conversations.FindByID(ctx, convID.String())
+	if err != nil {
+		return nil, fmt.Errorf("find conversation error: %w", err)
+	}
+	if conv != nil && conv.Encrypted {
+		return nil, ErrSummaryEncrypted
+	}
+
+	limit := req.Limit
+	if limit <= 0 || limit > s.config.MaxMessages {
+		limit = s.config.MaxMessages
+	}
+	docs, err := s.messageRepo.FindByConversation(ctx, convID.String(), 0, limit)
+	if err != nil {
+		return nil, fmt.Errorf("find messages error: %w", err)
+	}
+
+	input, err := s.buildInput(ctx, convID.String(), req.Locale, docs)
+	if err != nil {
+		return nil, err
+	}
+	if len(input.Messages) == 0 {
+		return nil, ErrSummaryEmpty
+	}
+
+	if err := s.checkRate(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	text, err := s.summarizer.Summarize(ctx, input)
+	if err != nil {
+		log.Printf("summarize conversation %s error: %v", convID, err)
+		return nil, fmt.Errorf("%w: %v", ErrSummaryUnavailable, err)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrSummaryUnavailable
+	}
+
+	first, last := input.Messages[0], input.Messages[len(input.Messages)-1]
+	summary := &model.ConversationSummary{
+		SummaryID:      util.GenerateShortUUID(),
+		ConversationID: convID.String(),
+		UserID:         userID,
+		Summary:        text,
+		MessageCount:   len(input.Messages),
+		FromMessageID:  first.MessageID,
+		ToMessageID:    last.MessageID,
+		FromTimestamp:  first.Timestamp,
+		ToTimestamp:    last.Timestamp,
+	}
+	if err := s.repo.Create(ctx, summary); err != nil {
+		return nil, fmt.Errorf("save summary error: %w", err)
+	}
+
+	s.deliver(ctx, convID, summary)
+	return summary, nil
+}
+
+// ListSummaries 查询用户在会话中生成过的摘要
+func (s *conversationSummaryServiceImpl) ListSummaries(ctx context.Context, userID, conversationID string, limit int) ([]*model.ConversationSummary, error) {
+	convID, err := authorizeConversation(ctx, s.groupService, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 50 {
+		limit = 20
+	}
+
+	summaries, err := s.repo.FindByUser(ctx, convID.String(), userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("find summaries error: %w", err)
+	}
+	return summaries, nil
+}
+
+// checkRate 按用户每小时限频
+func (s *conversationSummaryServiceImpl) checkRate(ctx context.Context, userID string) error {
+	if s.config.MaxPerHour <= 0 {
+		return nil
+	}
+
+	key := summaryRateKeyPrefix + userID + ":" + time.Now().Format("2006010215")
+	count, err := s.redis.Incr(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("incr summary rate error: %w", err)
+	}
+	if count == 1 {
+		s.redis.Expire(ctx, key, time.Hour)
+	}
+	if count > int64(s.config.MaxPerHour) {
+		return ErrSummaryRateLimited
+	}
+	return nil
+}
+
+// buildInput 将最近的消息（按序列号倒序）转换为按时间顺序的摘要输入：
+// 只保留文本类消息，跳过密文，其余类型以 [类型] 占位，文本按规则脱敏
+func (s *conversationSummaryServiceImpl) buildInput(ctx context.Context, conversationID, locale string, docs []*repository.MessageDocument) (*SummaryInput, error) {
+	names, err := s.senderNames(ctx, docs)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &SummaryInput{ConversationID: conversationID, Locale: locale}
+	for i := len(docs) - 1; i >= 0; i-- {
+		doc := docs[i]
+		msgType := model.MessageType(doc.Type)
+		if !msgType.IsChat() {
+			continue
+		}
+		if ciphertext, _ := doc.Content["ciphertext"].(string); ciphertext != "" {
+			continue
+		}
+
+		text, _ := doc.Content["text"].(string)
+		switch msgType {
+		case model.MsgText, model.MsgSingleChat, model.MsgGroupChat:
+		default:
+			text = "[" + msgType.String() + "]"
+		}
+		text = s.redact(text)
+		if text == "" {
+			continue
+		}
+
+		input.Messages = append(input.Messages, &SummaryMessage{
+			MessageID: doc.MessageID,
+			From:      doc.From,
+			Name:      names[doc.From],
+			Text:      text,
+			Timestamp: doc.CreatedAt.UnixMilli(),
+		})
+	}
+	return input, nil
+}
+
+// redact 脱敏并截断消息文本
+func (s *conversationSummaryServiceImpl) redact(text string) string {
+	text = strings.TrimSpace(text)
+	if s.config.RedactPattern != nil {
+		text = s.config.RedactPattern.ReplaceAllString(text, "[redacted]")
+	}
+	if runes := []rune(text); s.config.MaxTextLength > 0 && len(runes) > s.config.MaxTextLength {
+		text = string(runes[:s.config.MaxTextLength]) + "…"
+	}
+	return text
+}
+
+// senderNames 查询发送者显示名
+func (s *conversationSummaryServiceImpl) senderNames(ctx context.Context, docs []*repository.MessageDocument) (map[string]string, error) {
+	var userIDs []string
+	for _, doc := range docs {
+		userIDs = append(userIDs, doc.From)
+	}
+	userIDs = uniqueStrings(userIDs)
+
+	names := make(map[string]string, len(userIDs))
+	if len(userIDs) == 0 {
+		return names, nil
+	}
+	users, err := s.users.FindByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("find senders error: %w", err)
+	}
+	for _, user := range users {
+		names[user.UserID] = user.Nickname
+		if user.Nickname == "" {
+			names[user.UserID] = user.Username
+		}
+	}
+	return names, nil
+}
+
+// deliver 向请求者下发摘要消息（不写入会话历史，其他成员不可见）
+func (s *conversationSummaryServiceImpl) deliver(ctx context.Context, convID model.ConversationID, summary *model.ConversationSummary) {
+	if s.dispatcher == nil {
+		return
+	}
+
+	msg := &model.Message{
+		MessageID:      util.GenerateMessageID(),
+		Type:           model.MsgSummary,
+		From:           "system",
+		To:             summary.UserID,
+		ConversationID: convID.String(),
+		Content: &model.SummaryContent{
+			SummaryID:      summary.SummaryID,
+			ConversationID: summary.ConversationID,
+			Summary:        summary.Summary,
+			MessageCount:   summary.MessageCount,
+			FromMessageID:  summary.FromMessageID,
+			ToMessageID:    summary.ToMessageID,
+			FromTimestamp:  summary.FromTimestamp,
+			ToTimestamp:    summary.ToTimestamp,
+		},
+		Timestamp: time.Now().UnixMilli(),
+		QoS:       model.QoSAtLeastOnce,
+	}
+	if convID.IsGroup() {
+		msg.GroupID = convID.GroupID
+	}
+	if err := s.dispatcher.DispatchToUsers(ctx, []string{summary.UserID}, msg); err != nil {
+		log.Printf("dispatch summary %s to user %s error: %v", summary.SummaryID, summary.UserID, err)
+	}
+}
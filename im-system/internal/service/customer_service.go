@@ -0,0 +1,697 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 客服错误定义
+var (
+	ErrCSAgentNotFound      = errors.New("customer service agent not found")
+	ErrCSNotAgent           = errors.New("not a customer service agent")
+	ErrCSAgentBusy          = errors.New("customer service agent has active sessions")
+	ErrCSSessionNotFound    = errors.New("customer service session not found")
+	ErrCSSessionClosed      = errors.New("customer service session already closed")
+	ErrCSSessionNotClosed   = errors.New("customer service session not closed")
+	ErrCSSessionRated       = errors.New("customer service session already rated")
+	ErrCSNoAgentAvailable   = errors.New("no customer service agent available")
+	ErrCSQueueFull          = errors.New("customer service queue is full")
+	ErrCannedReplyNotFound  = errors.New("canned reply not found")
+	ErrCSSessionNotAssigned = errors.New("customer service session not assigned to you")
+)
+
+// CustomerServiceConfig 客服配置
+type CustomerServiceConfig struct {
+	DefaultMaxConcurrent int // 添加坐席时未指定的接待上限
+	MaxQueue             int // 排队会话上限，0表示不限制
+	DrainBatch           int // 每次分配排队会话的批量
+}
+
+// DefaultCustomerServiceConfig 默认客服配置
+func DefaultCustomerServiceConfig() *CustomerServiceConfig {
+	return &CustomerServiceConfig{
+		DefaultMaxConcurrent: 5,
+		MaxQueue:             1000,
+		DrainBatch:           50,
+	}
+}
+
+// CreateCSSessionRequest 发起客服会话请求
+type CreateCSSessionRequest struct {
+	Subject string `json:"subject" binding:"max=256"`
+}
+
+// TransferCSSessionRequest 转接客服会话请求
+type TransferCSSessionRequest struct {
+	AgentID string `json:"agent_id"` // 目标坐席，为空时自动分配
+}
+
+// RateCSSessionRequest 评价客服会话请求
+type RateCSSessionRequest struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=512"`
+}
+
+// SaveCSAgentRequest 添加坐席请求
+type SaveCSAgentRequest struct {
+	MaxConcurrent int `json:"max_concurrent" binding:"min=0,max=100"` // 0 表示使用默认上限
+}
+
+// CannedReplyRequest 快捷回复请求
+type CannedReplyRequest struct {
+	Title   string `json:"title" binding:"required,max=64"`
+	Content string `json:"content" binding:"required,max=4096"`
+}
+
+// CSSessionView 客服会话（排队中时含排队位置）
+type CSSessionView struct {
+	*model.CSSession
+	Position int64 `json:"position,omitempty"`
+}
+
+// CustomerService 客服服务：访客发起的会话排队，按接待上限分配给在线坐席，消息走普通单聊
+type CustomerService interface {
+	// CreateSession 访客发起客服会话（已有未结束的会话时直接返回），有空闲坐席时立即分配
+	CreateSession(ctx context.Context, visitorID string, req *CreateCSSessionRequest) (*CSSessionView, error)
+
+	// CurrentSession 查询访客未结束的会话，没有时返回 nil
+	CurrentSession(ctx context.Context, visitorID string) (*CSSessionView, error)
+
+	// GetSession 查询会话（仅访客及当前坐席）
+	GetSession(ctx context.Context, userID, sessionID string) (*CSSessionView, error)
+
+	// Transfer 坐席转接会话，agentID 为空时自动分配
+	Transfer(ctx context.Context, agentID, sessionID string, req *TransferCSSessionRequest) (*model.CSSession, error)
+
+	// Close 访客或坐席结束会话，释放接待名额并分配排队会话
+	Close(ctx context.Context, userID, sessionID string) (*model.CSSession, error)
+
+	// Rate 访客评价已结束的会话（每个会话只能评价一次）
+	Rate(ctx context.Context, visitorID, sessionID string, req *RateCSSessionRequest) error
+
+	// SetAgentStatus 坐席上线/下线，上线时分配排队会话；下线不影响接待中的会话
+	SetAgentStatus(ctx context.Context, agentID string, online bool) (*model.CSAgent, error)
+
+	// ListQueue 坐席查看排队中的会话
+	ListQueue(ctx context.Context, agentID string, limit int) ([]*model.CSSession, int64, error)
+
+	// ListAgentSessions 坐席分页查看自己接待中（closed 为 true 时为已结束）的会话
+	ListAgentSessions(ctx context.Context, agentID string, closed bool, page, pageSize int) ([]*model.CSSession, int64, error)
+
+	// IsAssigned 访客与坐席之间是否有接待中的会话
+	IsAssigned(ctx context.Context, visitorID, agentID string) (bool, error)
+
+	// ListCannedReplies 查询公共快捷回复及坐席自己的快捷回复，agentID 为空时只查询公共
+	ListCannedReplies(ctx context.Context, agentID string) ([]*model.CSCannedReply, error)
+
+	// CreateCannedReply 创建快捷回复，agentID 为空时为公共快捷回复
+	CreateCannedReply(ctx context.Context, agentID string, req *CannedReplyRequest) (*model.CSCannedReply, error)
+
+	// UpdateCannedReply 更新快捷回复，坐席只能更新自己的
+	UpdateCannedReply(ctx context.Context, agentID string, id uint, req *CannedReplyRequest) error
+
+	// DeleteCannedReply 删除快捷回复，坐席只能删除自己的
+	DeleteCannedReply(ctx context.Context, agentID string, id uint) error
+
+	// ListAgents 查询坐席池
+	ListAgents(ctx context.Context) ([]*model.CSAgent, error)
+
+	// SaveAgent 添加坐席或修改接待上限
+	SaveAgent(ctx context.Context, userID string, req *SaveCSAgentRequest) (*model.CSAgent, error)
+
+	// RemoveAgent 移出坐席池（有接待中的会话时拒绝）
+	RemoveAgent(ctx context.Context, userID string) error
+}
+
+// customerServiceImpl 客服服务实现
+type customerServiceImpl struct {
+	repo       repository.CustomerServiceRepository
+	users      repository.UserRepository
+	dispatcher MessageDispatcher
+	config     *CustomerServiceConfig
+}
+
+// NewCustomerService 创建客服服务，dispatcher 为空时不发送通知
+func NewCustomerService(repo repository.CustomerServiceRepository, users repository.UserRepository, dispatcher MessageDispatcher, config *CustomerServiceConfig) CustomerService {
+	if config == nil {
+		config = DefaultCustomerServiceConfig()
+	}
+	return &customerServiceImpl{
+		repo:       repo,
+		users:      users,
+		dispatcher: dispatcher,
+		config:     config,
+	}
+}
+
+// CreateSession 访客发起客服会话
+func (s *customerServiceImpl) CreateSession(ctx context.Context, visitorID string, req *CreateCSSessionRequest) (*CSSessionView, error) {
+	current, err := s.CurrentSession(ctx, visitorID)
+	if err != nil || current != nil {
+		return current, err
+	}
+
+	if s.config.MaxQueue > 0 {
+		queued, err := s.repo.CountQueued(ctx, time.Time{})
+		if err != nil {
+			return nil, fmt.Errorf("count queued sessions error: %w", err)
+		}
+		if queued >= int64(s.config.MaxQueue) {
+			return nil, ErrCSQueueFull
+		}
+	}
+
+	now := time.Now()
+	session := &model.CSSession{
+		SessionID: util.GenerateCSSessionID(),
+		VisitorID: visitorID,
+		Status:    model.CSSessionQueued,
+		Subject:   req.Subject,
+		QueuedAt:  now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("create cs session error: %w", err)
+	}
+
+	s.drainQueue(ctx)
+	view, err := s.view(ctx, session.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if view.Status == model.CSSessionQueued {
+		s.notify(ctx, visitorID, &model.CSEventContent{
+			SessionID: view.SessionID,
+			Event:     model.CSEventQueued,
+			VisitorID: visitorID,
+			Position:  view.Position,
+		})
+	}
+	return view, nil
+}
+
+// CurrentSession 查询访客未结束的会话
+func (s *customerServiceImpl) CurrentSession(ctx context.Context, visitorID string) (*CSSessionView, error) {
+	session, err := s.repo.FindOpenSession(ctx, visitorID)
+	if err != nil {
+		return nil, fmt.Errorf("find cs session error: %w", err)
+	}
+	if session == nil {
+		return nil, nil
+	}
+	return s.withPosition(ctx, session)
+}
+
+// GetSession 查询会话
+func (s *customerServiceImpl) GetSession(ctx context.Context, userID, sessionID string) (*CSSessionView, error) {
+	view, err := s.view(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if view.VisitorID != userID && view.AgentID != userID {
+		return nil, ErrCSSessionNotFound
+	}
+	return view, nil
+}
+
+// view 查询会话及排队位置
+func (s *customerServiceImpl) view(ctx context.Context, sessionID string) (*CSSessionView, error) {
+	session, err := s.repo.FindSession(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("find cs session error: %w", err)
+	}
+	if session == nil {
+		return nil, ErrCSSessionNotFound
+	}
+	return s.withPosition(ctx, session)
+}
+
+// withPosition 排队中的会话补充排队位置
+func (s *customerServiceImpl) withPosition(ctx context.Context, session *model.CSSession) (*CSSessionView, error) {
+	view := &CSSessionView{CSSession: session}
+	if session.Status != model.CSSessionQueued {
+		return view, nil
+	}
+	ahead, err := s.repo.CountQueued(ctx, session.QueuedAt)
+	if err != nil {
+		return nil, fmt.Errorf("count queued sessions error: %w", err)
+	}
+	view.Position = ahead + 1
+	return view, nil
+}
+
+// Transfer 坐席转接会话
+func (s *customerServiceImpl) Transfer(ctx context.Context, agentID, sessionID string, req *TransferCSSessionRequest) (*model.CSSession, error) {
+	session, err := s.repo.FindSession(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("find cs session error: %w", err)
+	}
+	if session == nil {
+		return nil, ErrCSSessionNotFound
+	}
+	if session.Status == model.CSSessionClosed {
+		return nil, ErrCSSessionClosed
+	}
+	if session.Status != model.CSSessionActive || session.AgentID != agentID {
+		return nil, ErrCSSessionNotAssigned
+	}
+
+	var target string
+	if req.AgentID != "" {
+		if req.AgentID == agentID || req.AgentID == session.VisitorID {
+			return nil, ErrInvalidRequest
+		}
+		agent, err := s.repo.FindAgent(ctx, req.AgentID)
+		if err != nil {
+			return nil, fmt.Errorf("find cs agent error: %w", err)
+		}
+		if agent == nil {
+			return nil, ErrCSAgentNotFound
+		}
+		ok, err := s.repo.AcquireAgent(ctx, req.AgentID)
+		if err != nil {
+			return nil, fmt.Errorf("acquire cs agent error: %w", err)
+		}
+		if !ok {
+			return nil, ErrCSNoAgentAvailable
+		}
+		target = req.AgentID
+	} else {
+		target, err = s.acquireAgent(ctx, agentID, session.VisitorID)
+		if err != nil {
+			return nil, err
+		}
+		if target == "" {
+			return nil, ErrCSNoAgentAvailable
+		}
+	}
+
+	conversationID := model.GetSingleChatConversationID(session.VisitorID, target)
+	ok, err := s.repo.AssignSession(ctx, sessionID, model.CSSessionActive, agentID, target, conversationID)
+	if err != nil || !ok {
+		s.releaseAgent(ctx, target)
+		if err != nil {
+			return nil, fmt.Errorf("assign cs session error: %w", err)
+		}
+		return nil, ErrCSSessionNotAssigned
+	}
+	s.releaseAgent(ctx, agentID)
+
+	content := &model.CSEventContent{
+		SessionID:      sessionID,
+		Event:          model.CSEventTransferred,
+		VisitorID:      session.VisitorID,
+		AgentID:        target,
+		FromAgentID:    agentID,
+		ConversationID: conversationID,
+	}
+	s.notify(ctx, session.VisitorID, content)
+	s.notify(ctx, target, content)
+	s.notify(ctx, agentID, content)
+
+	// 原坐席释放了名额
+	s.drainQueue(ctx)
+	return s.repo.FindSession(ctx, sessionID)
+}
+
+// Close 结束会话
+func (s *customerServiceImpl) Close(ctx context.Context, userID, sessionID string) (*model.CSSession, error) {
+	session, err := s.repo.FindSession(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("find cs session error: %w", err)
+	}
+	if session == nil || (session.VisitorID != userID && session.AgentID != userID) {
+		return nil, ErrCSSessionNotFound
+	}
+	if session.Status == model.CSSessionClosed {
+		return nil, ErrCSSessionClosed
+	}
+
+	ok, err := s.repo.CloseSession(ctx, sessionID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("close cs session error: %w", err)
+	}
+	if !ok {
+		return nil, ErrCSSessionClosed
+	}
+
+	// 关闭前可能刚被分配或转接，以最新的坐席为准
+	closed, err := s.repo.FindSession(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("find cs session error: %w", err)
+	}
+	if closed.AgentID != "" {
+		s.releaseAgent(ctx, closed.AgentID)
+	}
+
+	content := &model.CSEventContent{
+		SessionID:      sessionID,
+		Event:          model.CSEventClosed,
+		VisitorID:      closed.VisitorID,
+		AgentID:        closed.AgentID,
+		ConversationID: closed.ConversationID,
+		ClosedBy:       userID,
+	}
+	s.notify(ctx, closed.VisitorID, content)
+	if closed.AgentID != "" {
+		s.notify(ctx, closed.AgentID, content)
+		s.drainQueue(ctx)
+	}
+	return closed, nil
+}
+
+// Rate 访客评价会话
+func (s *customerServiceImpl) Rate(ctx context.Context, visitorID, sessionID string, req *RateCSSessionRequest) error {
+	session, err := s.repo.FindSession(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("find cs session error: %w", err)
+	}
+	if session == nil || session.VisitorID != visitorID {
+		return ErrCSSessionNotFound
+	}
+	if session.Status != model.CSSessionClosed {
+		return ErrCSSessionNotClosed
+	}
+
+	ok, err := s.repo.RateSession(ctx, sessionID, req.Rating, req.Comment)
+	if err != nil {
+		return fmt.Errorf("rate cs session error: %w", err)
+	}
+	if !ok {
+		return ErrCSSessionRated
+	}
+	return nil
+}
+
+// SetAgentStatus 坐席上线/下线
+func (s *customerServiceImpl) SetAgentStatus(ctx context.Context, agentID string, online bool) (*model.CSAgent, error) {
+	status := model.CSAgentOffline
+	if online {
+		status = model.CSAgentOnline
+	}
+	ok, err := s.repo.SetAgentStatus(ctx, agentID, status)
+	if err != nil {
+		return nil, fmt.Errorf("set cs agent status error: %w", err)
+	}
+	if !ok {
+		return nil, ErrCSNotAgent
+	}
+	if online {
+		s.drainQueue(ctx)
+	}
+	return s.repo.FindAgent(ctx, agentID)
+}
+
+// ListQueue 坐席查看排队中的会话
+func (s *customerServiceImpl) ListQueue(ctx context.Context, agentID string, limit int) ([]*model.CSSession, int64, error) {
+	if err := s.requireAgent(ctx, agentID); err != nil {
+		return nil, 0, err
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	sessions, err := s.repo.ListQueued(ctx, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list queued sessions error: %w", err)
+	}
+	total, err := s.repo.CountQueued(ctx, time.Time{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("count queued sessions error: %w", err)
+	}
+	return sessions, total, nil
+}
+
+// ListAgentSessions 坐席分页查看自己的会话
+func (s *customerServiceImpl) ListAgentSessions(ctx context.Context, agentID string, closed bool, page, pageSize int) ([]*model.CSSession, int64, error) {
+	if err := s.requireAgent(ctx, agentID); err != nil {
+		return nil, 0, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	status := model.CSSessionActive
+	if closed {
+		status = model.CSSessionClosed
+	}
+	sessions, total, err := s.repo.ListAgentSessions(ctx, agentID, status, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list cs sessions error: %w", err)
+	}
+	return sessions, total, nil
+}
+
+// IsAssigned 访客与坐席之间是否有接待中的会话
+func (s *customerServiceImpl) IsAssigned(ctx context.Context, visitorID, agentID string) (bool, error) {
+	return s.repo.ExistsActiveSession(ctx, visitorID, agentID)
+}
+
+// ListCannedReplies 查询快捷回复
+func (s *customerServiceImpl) ListCannedReplies(ctx context.Context, agentID string) ([]*model.CSCannedReply, error) {
+	if agentID != "" {
+		if err := s.requireAgent(ctx, agentID); err != nil {
+			return nil, err
+		}
+	}
+	replies, err := s.repo.ListCannedReplies(ctx, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("list canned replies error: %w", err)
+	}
+	return replies, nil
+}
+
+// CreateCannedReply 创建快捷回复
+func (s *customerServiceImpl) CreateCannedReply(ctx context.Context, agentID string, req *CannedReplyRequest) (*model.CSCannedReply, error) {
+	if agentID != "" {
+		if err := s.requireAgent(ctx, agentID); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	reply := &model.CSCannedReply{
+		AgentID:   agentID,
+		Title:     req.Title,
+		Content:   req.Content,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.CreateCannedReply(ctx, reply); err != nil {
+		return nil, fmt.Errorf("create canned reply error: %w", err)
+	}
+	return reply, nil
+}
+
+// UpdateCannedReply 更新快捷回复
+func (s *customerServiceImpl) UpdateCannedReply(ctx context.Context, agentID string, id uint, req *CannedReplyRequest) error {
+	ok, err := s.repo.UpdateCannedReply(ctx, id, agentID, req.Title, req.Content)
+	if err != nil {
+		return fmt.Errorf("update canned reply error: %w", err)
+	}
+	if !ok {
+		return ErrCannedReplyNotFound
+	}
+	return nil
+}
+
+// DeleteCannedReply 删除快捷回复
+func (s *customerServiceImpl) DeleteCannedReply(ctx context.Context, agentID string, id uint) error {
+	ok, err := s.repo.DeleteCannedReply(ctx, id, agentID)
+	if err != nil {
+		return fmt.Errorf("delete canned reply error: %w", err)
+	}
+	if !ok {
+		return ErrCannedReplyNotFound
+	}
+	return nil
+}
+
+// ListAgents 查询坐席池
+func (s *customerServiceImpl) ListAgents(ctx context.Context) ([]*model.CSAgent, error) {
+	agents, err := s.repo.ListAgents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list cs agents error: %w", err)
+	}
+	return agents, nil
+}
+
+// SaveAgent 添加坐席或修改接待上限
+func (s *customerServiceImpl) SaveAgent(ctx context.Context, userID string, req *SaveCSAgentRequest) (*model.CSAgent, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find user error: %w", err)
+	}
+	if user == nil || user.Status == model.UserStatusDeleted || user.Guest {
+		return nil, ErrUserNotFound
+	}
+
+	maxConcurrent := req.MaxConcurrent
+	if maxConcurrent == 0 {
+		maxConcurrent = s.config.DefaultMaxConcurrent
+	}
+	now := time.Now()
+	if err := s.repo.SaveAgent(ctx, &model.CSAgent{
+		UserID:        userID,
+		MaxConcurrent: maxConcurrent,
+		Status:        model.CSAgentOffline,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}); err != nil {
+		return nil, fmt.Errorf("save cs agent error: %w", err)
+	}
+
+	// 上限调高后可能可以接待排队会话
+	s.drainQueue(ctx)
+	return s.repo.FindAgent(ctx, userID)
+}
+
+// RemoveAgent 移出坐席池
+func (s *customerServiceImpl) RemoveAgent(ctx context.Context, userID string) error {
+	agent, err := s.repo.FindAgent(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("find cs agent error: %w", err)
+	}
+	if agent == nil {
+		return ErrCSAgentNotFound
+	}
+	if agent.ActiveCount > 0 {
+		return ErrCSAgentBusy
+	}
+	if _, err := s.repo.DeleteAgent(ctx, userID); err != nil {
+		return fmt.Errorf("delete cs agent error: %w", err)
+	}
+	return nil
+}
+
+// requireAgent 检查用户是否为坐席
+func (s *customerServiceImpl) requireAgent(ctx context.Context, userID string) error {
+	agent, err := s.repo.FindAgent(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("find cs agent error: %w", err)
+	}
+	if agent == nil {
+		return ErrCSNotAgent
+	}
+	return nil
+}
+
+// acquireAgent 按接待数从少到多占用空闲坐席的名额，跳过 excludes，没有空闲坐席时返回空
+func (s *customerServiceImpl) acquireAgent(ctx context.Context, excludes ...string) (string, error) {
+	agents, err := s.repo.FindAvailableAgents(ctx)
+	if err != nil {
+		return "", fmt.Errorf("find available cs agents error: %w", err)
+	}
+next:
+	for _, agent := range agents {
+		for _, exclude := range excludes {
+			if agent.UserID == exclude {
+				continue next
+			}
+		}
+		ok, err := s.repo.AcquireAgent(ctx, agent.UserID)
+		if err != nil {
+			return "", fmt.Errorf("acquire cs agent error: %w", err)
+		}
+		if ok {
+			return agent.UserID, nil
+		}
+	}
+	return "", nil
+}
+
+// releaseAgent 释放坐席名额
+func (s *customerServiceImpl) releaseAgent(ctx context.Context, agentID string) {
+	if err := s.repo.ReleaseAgent(ctx, agentID); err != nil {
+		log.Printf("release cs agent %s error: %v", agentID, err)
+	}
+}
+
+// drainQueue 按排队顺序把排队会话分配给空闲坐席，直到队列为空或没有空闲坐席
+// 多实例并发分配时由条件更新保证名额和会话不会重复分配
+func (s *customerServiceImpl) drainQueue(ctx context.Context) {
+	sessions, err := s.repo.ListQueued(ctx, s.config.DrainBatch)
+	if err != nil {
+		log.Printf("list queued cs sessions error: %v", err)
+		return
+	}
+	assigned := false
+	for _, session := range sessions {
+		agentID, err := s.acquireAgent(ctx, session.VisitorID)
+		if err != nil {
+			log.Printf("drain cs queue error: %v", err)
+			break
+		}
+		if agentID == "" {
+			break
+		}
+
+		conversationID := model.GetSingleChatConversationID(session.VisitorID, agentID)
+		ok, err := s.repo.AssignSession(ctx, session.SessionID, model.CSSessionQueued, "", agentID, conversationID)
+		if err != nil || !ok {
+			// 已被其他实例分配或访客已结束会话
+			s.releaseAgent(ctx, agentID)
+			if err != nil {
+				log.Printf("assign cs session %s error: %v", session.SessionID, err)
+			}
+			continue
+		}
+		assigned = true
+
+		content := &model.CSEventContent{
+			SessionID:      session.SessionID,
+			Event:          model.CSEventAssigned,
+			VisitorID:      session.VisitorID,
+			AgentID:        agentID,
+			ConversationID: conversationID,
+		}
+		s.notify(ctx, session.VisitorID, content)
+		s.notify(ctx, agentID, content)
+	}
+
+	if assigned {
+		s.notifyPositions(ctx)
+	}
+}
+
+// notifyPositions 队列前移后通知排队中的访客新的排队位置
+func (s *customerServiceImpl) notifyPositions(ctx context.Context) {
+	sessions, err := s.repo.ListQueued(ctx, s.config.DrainBatch)
+	if err != nil {
+		log.Printf("list queued cs sessions error: %v", err)
+		return
+	}
+	for i, session := range sessions {
+		s.notify(ctx, session.VisitorID, &model.CSEventContent{
+			SessionID: session.SessionID,
+			Event:     model.CSEventQueued,
+			VisitorID: session.VisitorID,
+			Position:  int64(i + 1),
+		})
+	}
+}
+
+// notify 通知用户客服会话事件（离线时保存为离线消息）
+func (s *customerServiceImpl) notify(ctx context.Context, userID string, content *model.CSEventContent) {
+	if s.dispatcher == nil {
+		return
+	}
+	msg := &model.Message{
+		MessageID: util.GenerateMessageID(),
+		Type:      model.MsgCSEvent,
+		From:      "system",
+		To:        userID,
+		Content:   content,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if err := s.dispatcher.DispatchToUsers(ctx, []string{userID}, msg); err != nil {
+		log.Printf("dispatch cs event %s to %s error: %v", content.Event, userID, err)
+	}
+}
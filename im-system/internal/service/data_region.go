@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// 数据驻留错误定义
+var (
+	ErrUnknownRegion      = errors.New("unknown region")
+	ErrCrossRegionDenied  = errors.New("cross-region conversation is not allowed")
+	ErrInvalidRegionRules = errors.New("invalid region rules")
+)
+
+// userRegionKeyPrefix 用户所属区域缓存
+const userRegionKeyPrefix = "region:user:"
+
+// RegionConfig 数据驻留配置
+type RegionConfig struct {
+	DefaultRegion string            // 默认区域（未标记区域的用户、租户及存量数据）
+	Regions       []string          // 已部署存储的区域（含默认区域）
+	TenantRegions map[string]string // 租户所属区域，用户未单独标记时使用
+	// CrossRegion 跨区域会话规则：键为 RegionPair 生成的区域组合，值为会话消息的存储区域（须为组合中的一个），
+	// 未配置的区域组合之间不能建立单聊，也不能在对方区域的群里发言
+	CrossRegion map[string]string
+	CacheTTL    time.Duration // 用户区域缓存时间
+}
+
+// DefaultRegionConfig 默认数据驻留配置（单区域）
+func DefaultRegionConfig() *RegionConfig {
+	return &RegionConfig{
+		DefaultRegion: "default",
+		Regions:       []string{"default"},
+		TenantRegions: map[string]string{},
+		CrossRegion:   map[string]string{},
+		CacheTTL:      10 * time.Minute,
+	}
+}
+
+// RegionPair 区域组合（与参数顺序无关）
+func RegionPair(region1, region2 string) string {
+	if region2 < region1 {
+		region1, region2 = region2, region1
+	}
+	return region1 + "+" + region2
+}
+
+// ParseRegionMap 解析 key=value 以 sep 分隔的映射（如租户区域 tenant1=eu,tenant2=us）
+func ParseRegionMap(value, sep string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		key, region, ok := strings.Cut(item, "=")
+		key, region = strings.TrimSpace(key), strings.TrimSpace(region)
+		if !ok || key == "" || region == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRegionRules, item)
+		}
+		result[key] = region
+	}
+	return result, nil
+}
+
+// ParseCrossRegionRules 解析 区域A+区域B=存储区域 逗号分隔的跨区域会话规则（如 eu+us=eu）
+func ParseCrossRegionRules(value string) (map[string]string, error) {
+	pairs, err := ParseRegionMap(value, ",")
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(pairs))
+	for pair, region := range pairs {
+		region1, region2, ok := strings.Cut(pair, "+")
+		region1, region2 = strings.TrimSpace(region1), strings.TrimSpace(region2)
+		if !ok || region1 == "" || region2 == "" || region1 == region2 || (region != region1 && region != region2) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRegionRules, pair+"="+region)
+		}
+		result[RegionPair(region1, region2)] = region
+	}
+	return result, nil
+}
+
+// RegionInfo 数据驻留配置（管理后台）
+type RegionInfo struct {
+	DefaultRegion string            `json:"default_region"`
+	Regions       []string          `json:"regions"`
+	TenantRegions map[string]string `json:"tenant_regions"`
+	CrossRegion   map[string]string `json:"cross_region"`
+}
+
+// SetUserRegionRequest 设置用户区域请求
+type SetUserRegionRequest struct {
+	Region string `json:"region" binding:"max=32"` // 为空表示恢复为租户区域或默认区域
+}
+
+// DataRegionService 数据驻留服务：确定用户和会话所在区域，消息仓库与文件存储据此选择存储集群
+type DataRegionService interface {
+	// Info 获取数据驻留配置
+	Info() *RegionInfo
+
+	// UserRegion 用户所属区域：用户标记的区域，其次为租户区域，最后为默认区域
+	UserRegion(ctx context.Context, userID string) (string, error)
+
+	// SetUserRegion 标记用户所属区域（只影响之后新建的会话和上传的文件，已有数据不迁移）
+	SetUserRegion(ctx context.Context, userID, region string) error
+
+	// ConversationRegion 会话消息所在区域，未确定时返回默认区域（用于消息仓库路由）
+	ConversationRegion(ctx context.Context, conversationID string) string
+
+	// ResolveConversation 确定会话的存储区域：首次调用时按参与者区域及跨区域规则确定并记录，之后保持不变；
+	// senderID 非空时校验发送者所在区域与会话区域之间允许通信
+	ResolveConversation(ctx context.Context, conversationID, senderID string) (string, error)
+
+	// CheckMessage 发送前检查：聊天消息的发送者与会话须满足跨区域规则
+	CheckMessage(ctx context.Context, msg *model.Message) error
+}
+
+// dataRegionServiceImpl 数据驻留服务实现
+type dataRegionServiceImpl struct {
+	config       *RegionConfig
+	users        repository.UserRepository
+	convs        repository.ConversationRepository
+	groupService GroupService
+	redis        *redis.Client
+
+	conversations sync.Map // 会话ID -> 区域（确定后不再变化）
+}
+
+// NewDataRegionService 创建数据驻留服务
+func NewDataRegionService(config *RegionConfig, users repository.UserRepository, convs repository.ConversationRepository, groupService GroupService, redisClient *redis.Client) DataRegionService {
+	if config == nil {
+		config = DefaultRegionConfig()
+	}
+	return &dataRegionServiceImpl{
+		config:       config,
+		users:        users,
+		convs:        convs,
+		groupService: groupService,
+		redis:        redisClient,
+	}
+}
+
+// Info 获取数据驻留配置
+func (s *dataRegionServiceImpl) Info() *RegionInfo {
+	regions := append([]string(nil), s.config.Regions...)
+	sort.Strings(regions)
+	return &RegionInfo{
+		DefaultRegion: s.config.DefaultRegion,
+		Regions:       regions,
+		TenantRegions: s.config.TenantRegions,
+		CrossRegion:   s.config.CrossRegion,
+	}
+}
+
+// knownRegion 区域是否已部署存储
+func (s *dataRegionServiceImpl) knownRegion(region string) bool {
+	for _, r := range s.config.Regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+// UserRegion 用户所属区域
+func (s *dataRegionServiceImpl) UserRegion(ctx context.Context, userID string) (string, error) {
+	key := userRegionKeyPrefix + userID
+	if region, err := s.redis.Get(ctx, key).Result(); err == nil {
+		return region, nil
+	}
+
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("find user error: %w", err)
+	}
+	if user == nil {
+		return "", ErrUserNotFound
+	}
+
+	region := user.Region
+	if region == "" {
+		region = s.config.TenantRegions[user.TenantID]
+	}
+	if region == "" {
+		region = s.config.DefaultRegion
+	}
+	s.redis.Set(ctx, key, region, s.config.CacheTTL)
+	return region, nil
+}
+
+// SetUserRegion 标记用户所属区域
+func (s *dataRegionServiceImpl) SetUserRegion(ctx context.Context, userID, region string) error {
+	if region != "" && !s.knownRegion(region) {
+		return ErrUnknownRegion
+	}
+
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("find user error: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.users.UpdateRegion(ctx, userID, region, time.Now()); err != nil {
+		return fmt.Errorf("update user region error: %w", err)
+	}
+	s.redis.Del(ctx, userRegionKeyPrefix+userID)
+	return nil
+}
+
+// ConversationRegion 会话消息所在区域
+func (s *dataRegionServiceImpl) ConversationRegion(ctx context.Context, conversationID string) string {
+	canonical := model.CanonicalConversationID(conversationID)
+	if region, ok := s.conversations.Load(canonical); ok {
+		return region.(string)
+	}
+
+	conv, err := s.convs.FindByID(ctx, canonical)
+	if err != nil {
+		log.Printf("find region of conversation %s error: %v", canonical, err)
+		return s.config.DefaultRegion
+	}
+	if conv == nil {
+		return s.config.DefaultRegion
+	}
+	region := storedRegion(conv, s.config.DefaultRegion)
+	if region != "" {
+		s.conversations.Store(canonical, region)
+		return region
+	}
+	return s.config.DefaultRegion
+}
+
+// storedRegion 会话已确定的区域：已记录的区域，或启用数据驻留前已有消息的会话（默认区域）
+func storedRegion(conv *model.Conversation, defaultRegion string) string {
+	if conv.Region != "" {
+		return conv.Region
+	}
+	if conv.LastMessageID != "" {
+		return defaultRegion
+	}
+	return ""
+}
+
+// ResolveConversation 确定会话的存储区域
+func (s *dataRegionServiceImpl) ResolveConversation(ctx context.Context, conversationID, senderID string) (string, error) {
+	convID, err := model.ParseConversationID(conversationID)
+	if err != nil {
+		return "", err
+	}
+	canonical := convID.String()
+
+	region := ""
+	if cached, ok := s.conversations.Load(canonical); ok {
+		region = cached.(string)
+	} else {
+		conv, err := s.convs.FindByID(ctx, canonical)
+		if err != nil {
+			return "", fmt.Errorf("find conversation error: %w", err)
+		}
+		if conv != nil {
+			region = storedRegion(conv, s.config.DefaultRegion)
+		}
+		if region == "" {
+			if region, err = s.initialRegion(ctx, convID); err != nil {
+				return "", err
+			}
+		}
+		if region, err = s.convs.AssignRegion(ctx, canonical, convID.Type, region); err != nil {
+			return "", fmt.Errorf("assign conversation region error: %w", err)
+		}
+		s.conversations.Store(canonical, region)
+	}
+
+	if senderID != "" {
+		senderRegion, err := s.UserRegion(ctx, senderID)
+		if err != nil {
+			return "", err
+		}
+		if !s.allowed(senderRegion, region) {
+			return "", ErrCrossRegionDenied
+		}
+	}
+	return region, nil
+}
+
+// initialRegion 新会话的存储区域：单聊双方同区域时为该区域，跨区域时按规则确定；群聊为群主所在区域
+func (s *dataRegionServiceImpl) initialRegion(ctx context.Context, convID model.ConversationID) (string, error) {
+	if convID.IsGroup() {
+		group, err := s.groupService.GetGroupInfo(ctx, convID.GroupID)
+		if err != nil {
+			return "", err
+		}
+		return s.UserRegion(ctx, group.OwnerID)
+	}
+
+	region1, err := s.UserRegion(ctx, convID.UserIDs[0])
+	if err != nil {
+		return "", err
+	}
+	region2, err := s.UserRegion(ctx, convID.UserIDs[1])
+	if err != nil {
+		return "", err
+	}
+	if region1 == region2 {
+		return region1, nil
+	}
+	if region, ok := s.config.CrossRegion[RegionPair(region1, region2)]; ok {
+		return region, nil
+	}
+	return "", ErrCrossRegionDenied
+}
+
+// allowed 发送者所在区域能否向存储在 region 的会话发消息
+func (s *dataRegionServiceImpl) allowed(senderRegion, region string) bool {
+	if senderRegion == region {
+		return true
+	}
+	_, ok := s.config.CrossRegion[RegionPair(senderRegion, region)]
+	return ok
+}
+
+// CheckMessage 发送前检查聊天消息的跨区域规则
+func (s *dataRegionServiceImpl) CheckMessage(ctx context.Context, msg *model.Message) error {
+	if !msg.Type.IsChat() {
+		return nil
+	}
+
+	convID := model.NewSingleConversationID(msg.From, msg.To)
+	switch {
+	case msg.GroupID != "":
+		convID = model.NewGroupConversationID(msg.GroupID)
+	case msg.Type == model.MsgGroupChat:
+		convID = model.NewGroupConversationID(msg.To)
+	}
+
+	_, err := s.ResolveConversation(ctx, convID.String(), msg.From)
+	return err
+}
@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// 端到端加密错误定义
+var (
+	ErrE2EEDeviceNotFound = errors.New("e2ee device not found")
+	ErrE2EEKeyInvalid     = errors.New("invalid e2ee public key")
+	ErrE2EETooManyPreKeys = errors.New("too many e2ee prekeys")
+	ErrE2EEDeviceLimit    = errors.New("e2ee device limit reached")
+)
+
+// E2EEConfig 端到端加密配置
+type E2EEConfig struct {
+	MaxDevices          int // 每个用户最多注册的设备数
+	MaxPreKeysPerUpload int // 单次最多上传的一次性预密钥数
+	MaxPreKeysPerDevice int // 每个设备最多保存的一次性预密钥数
+	MaxKeyBytes         int // 公钥、签名解码后的最大字节数
+}
+
+// DefaultE2EEConfig 默认端到端加密配置
+func DefaultE2EEConfig() *E2EEConfig {
+	return &E2EEConfig{
+		MaxDevices:          10,
+		MaxPreKeysPerUpload: 100,
+		MaxPreKeysPerDevice: 500,
+		MaxKeyBytes:         256,
+	}
+}
+
+// maxE2EEDeviceIDLength 设备ID最大长度
+const maxE2EEDeviceIDLength = 128
+
+// E2EEService 端到端加密密钥服务
+// 只保存各设备的公钥（身份公钥、签名预密钥、一次性预密钥）并分发给通信对方，密钥协商和加解密由客户端完成；
+// 加密消息（type 13）的内容由服务端原样保存和转发
+type E2EEService interface {
+	// RegisterDevice 注册或更新设备密钥，身份公钥变化时清空该设备未使用的一次性预密钥
+	RegisterDevice(ctx context.Context, userID, deviceID string, req *model.RegisterE2EEKeysRequest) (*model.E2EEDevice, error)
+
+	// UploadPreKeys 补充一次性预密钥，可同时轮换签名预密钥
+	UploadPreKeys(ctx context.Context, userID, deviceID string, req *model.UploadE2EEPreKeysRequest) (*model.E2EEDevice, error)
+
+	// ListMyDevices 查询自己已注册的设备（含剩余一次性预密钥数）
+	ListMyDevices(ctx context.Context, userID string) ([]*model.E2EEDevice, error)
+
+	// RemoveDevice 删除设备密钥（设备登出或重置加密时调用）
+	RemoveDevice(ctx context.Context, userID, deviceID string) error
+
+	// ListDevices 查询用户的设备身份公钥（不消耗一次性预密钥）
+	ListDevices(ctx context.Context, requesterID, userID string) ([]*model.E2EEDevice, error)
+
+	// FetchBundles 获取用户设备的预密钥包（每个设备取出一个一次性预密钥），deviceID 为空时返回全部设备
+	FetchBundles(ctx context.Context, requesterID, userID, deviceID string) ([]*model.E2EEPreKeyBundle, error)
+}
+
+// e2eeServiceImpl 端到端加密密钥服务实现
+type e2eeServiceImpl struct {
+	repo          repository.E2EEKeyRepository
+	userRepo      repository.UserRepository
+	friendService FriendService
+	config        *E2EEConfig
+}
+
+// NewE2EEService 创建端到端加密密钥服务
+func NewE2EEService(repo repository.E2EEKeyRepository, userRepo repository.UserRepository, friendService FriendService, config *E2EEConfig) E2EEService {
+	if config == nil {
+		config = DefaultE2EEConfig()
+	}
+	return &e2eeServiceImpl{
+		repo:          repo,
+		userRepo:      userRepo,
+		friendService: friendService,
+		config:        config,
+	}
+}
+
+// RegisterDevice 注册或更新设备密钥
+func (s *e2eeServiceImpl) RegisterDevice(ctx context.Context, userID, deviceID string, req *model.RegisterE2EEKeysRequest) (*model.E2EEDevice, error) {
+	if err := s.validateDeviceID(deviceID); err != nil {
+		return nil, err
+	}
+	if err := s.validateKey(req.IdentityKey); err != nil {
+		return nil, err
+	}
+	if err := s.validateSignedPreKey(req.SignedPreKey); err != nil {
+		return nil, err
+	}
+	if err := s.validatePreKeys(req.OneTimePreKeys); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.FindDevice(ctx, userID, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("find e2ee device error: %w", err)
+	}
+	if existing == nil {
+		devices, err := s.repo.FindDevices(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("find e2ee devices error: %w", err)
+		}
+		if len(devices) >= s.config.MaxDevices {
+			return nil, ErrE2EEDeviceLimit
+		}
+	}
+	if len(req.OneTimePreKeys) > s.config.MaxPreKeysPerDevice {
+		return nil, ErrE2EETooManyPreKeys
+	}
+
+	device := &model.E2EEDeviceKey{
+		UserID:                userID,
+		DeviceID:              deviceID,
+		IdentityKey:           req.IdentityKey,
+		SignedPreKeyID:        req.SignedPreKey.KeyID,
+		SignedPreKey:          req.SignedPreKey.PublicKey,
+		SignedPreKeySignature: req.SignedPreKey.Signature,
+	}
+	// 身份公钥变化说明设备重新生成了密钥，旧的一次性预密钥已无法使用
+	resetPreKeys := existing == nil || existing.IdentityKey != req.IdentityKey
+	if err := s.repo.SaveDevice(ctx, device, resetPreKeys); err != nil {
+		return nil, fmt.Errorf("save e2ee device error: %w", err)
+	}
+	if err := s.repo.AddPreKeys(ctx, toOneTimePreKeys(userID, deviceID, req.OneTimePreKeys)); err != nil {
+		return nil, fmt.Errorf("add e2ee prekeys error: %w", err)
+	}
+	return s.ownDevice(ctx, userID, deviceID)
+}
+
+// UploadPreKeys 补充一次性预密钥
+func (s *e2eeServiceImpl) UploadPreKeys(ctx context.Context, userID, deviceID string, req *model.UploadE2EEPreKeysRequest) (*model.E2EEDevice, error) {
+	if err := s.validatePreKeys(req.OneTimePreKeys); err != nil {
+		return nil, err
+	}
+	if req.SignedPreKey != nil {
+		if err := s.validateSignedPreKey(req.SignedPreKey); err != nil {
+			return nil, err
+		}
+	}
+
+	device, err := s.repo.FindDevice(ctx, userID, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("find e2ee device error: %w", err)
+	}
+	if device == nil {
+		return nil, ErrE2EEDeviceNotFound
+	}
+
+	counts, err := s.repo.CountPreKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("count e2ee prekeys error: %w", err)
+	}
+	for _, count := range counts {
+		if count.DeviceID == deviceID && count.Count+int64(len(req.OneTimePreKeys)) > int64(s.config.MaxPreKeysPerDevice) {
+			return nil, ErrE2EETooManyPreKeys
+		}
+	}
+
+	if req.SignedPreKey != nil {
+		device.SignedPreKeyID = req.SignedPreKey.KeyID
+		device.SignedPreKey = req.SignedPreKey.PublicKey
+		device.SignedPreKeySignature = req.SignedPreKey.Signature
+		if err := s.repo.SaveDevice(ctx, device, false); err != nil {
+			return nil, fmt.Errorf("save e2ee device error: %w", err)
+		}
+	}
+	if err := s.repo.AddPreKeys(ctx, toOneTimePreKeys(userID, deviceID, req.OneTimePreKeys)); err != nil {
+		return nil, fmt.Errorf("add e2ee prekeys error: %w", err)
+	}
+	return s.ownDevice(ctx, userID, deviceID)
+}
+
+// ListMyDevices 查询自己已注册的设备
+func (s *e2eeServiceImpl) ListMyDevices(ctx context.Context, userID string) ([]*model.E2EEDevice, error) {
+	devices, err := s.repo.FindDevices(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find e2ee devices error: %w", err)
+	}
+	counts, err := s.repo.CountPreKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("count e2ee prekeys error: %w", err)
+	}
+	remaining := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		remaining[count.DeviceID] = count.Count
+	}
+
+	result := make([]*model.E2EEDevice, 0, len(devices))
+	for _, device := range devices {
+		view := toE2EEDevice(device)
+		view.PreKeyCount = remaining[device.DeviceID]
+		result = append(result, view)
+	}
+	return result, nil
+}
+
+// RemoveDevice 删除设备密钥
+func (s *e2eeServiceImpl) RemoveDevice(ctx context.Context, userID, deviceID string) error {
+	deleted, err := s.repo.DeleteDevice(ctx, userID, deviceID)
+	if err != nil {
+		return fmt.Errorf("delete e2ee device error: %w", err)
+	}
+	if !deleted {
+		return ErrE2EEDeviceNotFound
+	}
+	return nil
+}
+
+// ListDevices 查询用户的设备身份公钥
+func (s *e2eeServiceImpl) ListDevices(ctx context.Context, requesterID, userID string) ([]*model.E2EEDevice, error) {
+	if err := s.checkTarget(ctx, requesterID, userID); err != nil {
+		return nil, err
+	}
+
+	devices, err := s.repo.FindDevices(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find e2ee devices error: %w", err)
+	}
+	result := make([]*model.E2EEDevice, 0, len(devices))
+	for _, device := range devices {
+		result = append(result, toE2EEDevice(device))
+	}
+	return result, nil
+}
+
+// FetchBundles 获取用户设备的预密钥包
+func (s *e2eeServiceImpl) FetchBundles(ctx context.Context, requesterID, userID, deviceID string) ([]*model.E2EEPreKeyBundle, error) {
+	if err := s.checkTarget(ctx, requesterID, userID); err != nil {
+		return nil, err
+	}
+
+	var devices []*model.E2EEDeviceKey
+	if deviceID != "" {
+		device, err := s.repo.FindDevice(ctx, userID, deviceID)
+		if err != nil {
+			return nil, fmt.Errorf("find e2ee device error: %w", err)
+		}
+		if device != nil {
+			devices = append(devices, device)
+		}
+	} else {
+		var err error
+		if devices, err = s.repo.FindDevices(ctx, userID); err != nil {
+			return nil, fmt.Errorf("find e2ee devices error: %w", err)
+		}
+	}
+	if len(devices) == 0 {
+		return nil, ErrE2EEDeviceNotFound
+	}
+
+	bundles := make([]*model.E2EEPreKeyBundle, 0, len(devices))
+	for _, device := range devices {
+		bundle := &model.E2EEPreKeyBundle{
+			UserID:      device.UserID,
+			DeviceID:    device.DeviceID,
+			IdentityKey: device.IdentityKey,
+			SignedPreKey: &model.E2EESignedPreKey{
+				KeyID:     device.SignedPreKeyID,
+				PublicKey: device.SignedPreKey,
+				Signature: device.SignedPreKeySignature,
+			},
+		}
+		preKey, err := s.repo.ClaimPreKey(ctx, device.UserID, device.DeviceID)
+		if err != nil {
+			return nil, fmt.Errorf("claim e2ee prekey error: %w", err)
+		}
+		if preKey != nil {
+			bundle.OneTimePreKey = &model.E2EEPreKey{KeyID: preKey.KeyID, PublicKey: preKey.PublicKey}
+		}
+		bundles = append(bundles, bundle)
+	}
+	return bundles, nil
+}
+
+// checkTarget 检查目标用户存在且未屏蔽请求者
+func (s *e2eeServiceImpl) checkTarget(ctx context.Context, requesterID, userID string) error {
+	if requesterID == userID {
+		return nil
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("find user error: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if s.friendService != nil {
+		blocked, err := s.friendService.IsBlocked(ctx, userID, requesterID)
+		if err != nil {
+			return fmt.Errorf("check block error: %w", err)
+		}
+		if blocked {
+			return ErrBlockedByUser
+		}
+	}
+	return nil
+}
+
+// ownDevice 查询自己的设备（含剩余一次性预密钥数）
+func (s *e2eeServiceImpl) ownDevice(ctx context.Context, userID, deviceID string) (*model.E2EEDevice, error) {
+	devices, err := s.ListMyDevices(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		if device.DeviceID == deviceID {
+			return device, nil
+		}
+	}
+	return nil, ErrE2EEDeviceNotFound
+}
+
+// validateDeviceID 校验设备ID
+func (s *e2eeServiceImpl) validateDeviceID(deviceID string) error {
+	if deviceID == "" || len(deviceID) > maxE2EEDeviceIDLength {
+		return ErrE2EEKeyInvalid
+	}
+	return nil
+}
+
+// validateKey 校验 Base64 编码的公钥或签名
+func (s *e2eeServiceImpl) validateKey(key string) error {
+	data, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(data) == 0 || len(data) > s.config.MaxKeyBytes {
+		return ErrE2EEKeyInvalid
+	}
+	return nil
+}
+
+// validateSignedPreKey 校验签名预密钥（签名由客户端使用身份公钥验证）
+func (s *e2eeServiceImpl) validateSignedPreKey(preKey *model.E2EESignedPreKey) error {
+	if preKey == nil {
+		return ErrE2EEKeyInvalid
+	}
+	if err := s.validateKey(preKey.PublicKey); err != nil {
+		return err
+	}
+	return s.validateKey(preKey.Signature)
+}
+
+// validatePreKeys 校验一次性预密钥
+func (s *e2eeServiceImpl) validatePreKeys(preKeys []*model.E2EEPreKey) error {
+	if len(preKeys) > s.config.MaxPreKeysPerUpload {
+		return ErrE2EETooManyPreKeys
+	}
+	seen := make(map[int64]bool, len(preKeys))
+	for _, preKey := range preKeys {
+		if preKey == nil || seen[preKey.KeyID] {
+			return ErrE2EEKeyInvalid
+		}
+		seen[preKey.KeyID] = true
+		if err := s.validateKey(preKey.PublicKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toOneTimePreKeys 转换为一次性预密钥记录
+func toOneTimePreKeys(userID, deviceID string, preKeys []*model.E2EEPreKey) []*model.E2EEOneTimePreKey {
+	result := make([]*model.E2EEOneTimePreKey, 0, len(preKeys))
+	for _, preKey := range preKeys {
+		result = append(result, &model.E2EEOneTimePreKey{
+			UserID:    userID,
+			DeviceID:  deviceID,
+			KeyID:     preKey.KeyID,
+			PublicKey: preKey.PublicKey,
+		})
+	}
+	return result
+}
+
+// toE2EEDevice 转换为设备公钥信息
+func toE2EEDevice(device *model.E2EEDeviceKey) *model.E2EEDevice {
+	return &model.E2EEDevice{
+		UserID:         device.UserID,
+		DeviceID:       device.DeviceID,
+		IdentityKey:    device.IdentityKey,
+		SignedPreKeyID: device.SignedPreKeyID,
+		UpdatedAt:      device.UpdatedAt,
+	}
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/mailer"
+)
+
+// emailDigestSentPrefix 用户最近一次发送摘要的标记（带过期时间，同时用于多节点去重）
+const emailDigestSentPrefix = "im:email_digest:sent:"
+
+// UserPresence 用户在线状态查询
+type UserPresence interface {
+	// IsUserOnline 用户是否在线
+	IsUserOnline(ctx context.Context, userID string) (bool, error)
+	// LastSeen 用户最近一次下线的时间，没有记录时返回零值
+	LastSeen(ctx context.Context, userID string) (time.Time, error)
+}
+
+// EmailDigestConfig 未读消息邮件摘要配置
+type EmailDigestConfig struct {
+	OfflineAfter     time.Duration      // 离线超过该时长且有未读消息时发送摘要
+	MinInterval      time.Duration      // 同一用户两次摘要的最小间隔
+	ScanInterval     time.Duration      // 扫描间隔
+	BatchSize        int                // 每页扫描的用户数
+	MaxConversations int                // 摘要中最多列出的会话数
+	LinkBase         string             // 客户端深链前缀，如 https://im.example.com/app 或 imapp://
+	Subject          string             // 邮件标题模板（text/template，数据同 Template）
+	Template         *template.Template // 邮件正文模板（html/template，数据为 EmailDigestData）
+}
+
+// DefaultEmailDigestConfig 默认邮件摘要配置
+func DefaultEmailDigestConfig() *EmailDigestConfig {
+	return &EmailDigestConfig{
+		OfflineAfter:     72 * time.Hour,
+		MinInterval:      24 * time.Hour,
+		ScanInterval:     10 * time.Minute,
+		BatchSize:        200,
+		MaxConversations: 5,
+		LinkBase:         "imapp://",
+		Subject:          "您有 {{.TotalCount}} 条未读消息",
+		Template:         defaultEmailDigestTemplate,
+	}
+}
+
+// EmailDigestData 邮件摘要模板数据
+type EmailDigestData struct {
+	Nickname      string
+	TotalCount    int64
+	Conversations []*EmailDigestConversation
+	More          int    // 未列出的会话数
+	AppLink       string // 打开应用
+	SettingsLink  string // 通知设置（退订入口）
+}
+
+// EmailDigestConversation 邮件摘要中的会话
+type EmailDigestConversation struct {
+	Name  string
+	Count int64
+	Link  string // 打开该会话的深链
+}
+
+// defaultEmailDigestTemplate 默认邮件正文模板
+var defaultEmailDigestTemplate = template.Must(template.New("email_digest").Parse(`<p>{{.Nickname}}，您好：</p>
+<p>您已有一段时间未登录，共有 <b>{{.TotalCount}}</b> 条未读消息。</p>
+<ul>
+{{range .Conversations}}<li><a href="{{.Link}}">{{.Name}}</a>：{{.Count}} 条</li>
+{{end}}</ul>
+{{if .More}}<p>以及其他 {{.More}} 个会话。</p>{{end}}
+<p><a href="{{.AppLink}}">打开应用查看</a></p>
+<p style="color:#999">不想再收到此类邮件？可在 <a href="{{.SettingsLink}}">通知设置</a> 中关闭邮件摘要。</p>
+`))
+
+// EmailDigestService 未读消息邮件摘要服务
+// 定时扫描离线消息，向长期离线且有未读消息的用户发送摘要邮件，每个用户在 MinInterval 内最多发送一次
+type EmailDigestService interface {
+	// RunOnce 扫描并发送一轮摘要，返回发送数量
+	RunOnce(ctx context.Context) (int, error)
+	// Start 启动定时扫描
+	Start(ctx context.Context)
+}
+
+// emailDigestServiceImpl 邮件摘要服务实现
+type emailDigestServiceImpl struct {
+	offlineRepo    repository.OfflineMessageRepository
+	offlineService OfflineService
+	users          repository.UserRepository
+	groupService   GroupService
+	presence       UserPresence
+	redis          *redis.Client
+	sender         mailer.Sender
+	config         *EmailDigestConfig
+	subject        *texttemplate.Template
+}
+
+// NewEmailDigestService 创建邮件摘要服务
+func NewEmailDigestService(
+	offlineRepo repository.OfflineMessageRepository,
+	offlineService OfflineService,
+	users repository.UserRepository,
+	groupService GroupService,
+	presence UserPresence,
+	redisClient *redis.Client,
+	sender mailer.Sender,
+	config *EmailDigestConfig,
+) (EmailDigestService, error) {
+	if config == nil {
+		config = DefaultEmailDigestConfig()
+	}
+	if config.Template == nil {
+		config.Template = defaultEmailDigestTemplate
+	}
+	subject, err := texttemplate.New("email_digest_subject").Parse(config.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("parse email digest subject error: %w", err)
+	}
+
+	return &emailDigestServiceImpl{
+		offlineRepo:    offlineRepo,
+		offlineService: offlineService,
+		users:          users,
+		groupService:   groupService,
+		presence:       presence,
+		redis:          redisClient,
+		sender:         sender,
+		config:         config,
+		subject:        subject,
+	}, nil
+}
+
+// Start 启动定时扫描
+func (s *emailDigestServiceImpl) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.ScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if sent, err := s.RunOnce(ctx); err != nil {
+				log.Printf("email digest error: %v", err)
+			} else if sent > 0 {
+				log.Printf("Sent %d email digests", sent)
+			}
+		}
+	}
+}
+
+// RunOnce 扫描有超过 OfflineAfter 未读离线消息的用户并发送摘要
+func (s *emailDigestServiceImpl) RunOnce(ctx context.Context) (int, error) {
+	before := time.Now().Add(-s.config.OfflineAfter)
+	sent := 0
+	after := ""
+	for {
+		userIDs, err := s.offlineRepo.FindUsersWithMessagesBefore(ctx, before, after, s.config.BatchSize)
+		if err != nil {
+			return sent, fmt.Errorf("find users with offline messages error: %w", err)
+		}
+
+		for _, userID := range userIDs {
+			ok, err := s.sendDigest(ctx, userID, before)
+			if err != nil {
+				log.Printf("send email digest to user %s error: %v", userID, err)
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+
+		if len(userIDs) < s.config.BatchSize {
+			return sent, nil
+		}
+		after = userIDs[len(userIDs)-1]
+	}
+}
+
+// sendDigest 向用户发送摘要，用户在线、近期上线、未设置邮箱、已退订或间隔内已发送时跳过
+func (s *emailDigestServiceImpl) sendDigest(ctx context.Context, userID string, offlineSince time.Time) (bool, error) {
+	if s.presence != nil {
+		online, err := s.presence.IsUserOnline(ctx, userID)
+		if err != nil || online {
+			return false, err
+		}
+		lastSeen, err := s.presence.LastSeen(ctx, userID)
+		if err != nil || lastSeen.After(offlineSince) {
+			return false, err
+		}
+	}
+
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if user == nil || user.Status != model.UserStatusNormal || user.Email == "" || !user.EmailDigest {
+		return false, nil
+	}
+
+	summary, err := s.offlineService.GetOfflineMessageSummary(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if summary.TotalCount == 0 {
+		return false, nil
+	}
+
+	// 限频：间隔内只发送一次，多节点同时扫描时只有一个节点发送
+	sentKey := emailDigestSentPrefix + userID
+	acquired, err := s.redis.SetNX(ctx, sentKey, time.Now().Unix(), s.config.MinInterval).Result()
+	if err != nil || !acquired {
+		return false, err
+	}
+
+	msg, err := s.render(ctx, user, summary)
+	if err == nil {
+		err = s.sender.Send(ctx, msg)
+	}
+	if err != nil {
+		// 发送失败时清除标记，下一轮重试
+		s.redis.Del(ctx, sentKey)
+		return false, err
+	}
+	return true, nil
+}
+
+// render 渲染摘要邮件
+func (s *emailDigestServiceImpl) render(ctx context.Context, user *model.User, summary *OfflineMessageSummary) (*mailer.Message, error) {
+	data := &EmailDigestData{
+		Nickname:     user.Nickname,
+		TotalCount:   summary.TotalCount,
+		AppLink:      s.link(""),
+		SettingsLink: s.link("settings/notifications"),
+	}
+	if data.Nickname == "" {
+		data.Nickname = user.Username
+	}
+
+	conversations := summary.Conversations
+	if limit := s.config.MaxConversations; limit > 0 && len(conversations) > limit {
+		data.More = len(conversations) - limit
+		conversations = conversations[:limit]
+	}
+	for _, conv := range conversations {
+		data.Conversations = append(data.Conversations, &EmailDigestConversation{
+			Name:  s.conversationName(ctx, user.UserID, conv.ConversationID),
+			Count: conv.Count,
+			Link:  s.link("conversations/" + url.PathEscape(conv.ConversationID)),
+		})
+	}
+
+	var subject, body bytes.Buffer
+	if err := s.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("render email digest subject error: %w", err)
+	}
+	if err := s.config.Template.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("render email digest error: %w", err)
+	}
+
+	return &mailer.Message{
+		To:      user.Email,
+		Subject: subject.String(),
+		HTML:    body.String(),
+		Text:    digestText(data),
+	}, nil
+}
+
+// conversationName 会话显示名：单聊为对方昵称，群聊为群名称
+func (s *emailDigestServiceImpl) conversationName(ctx context.Context, userID, conversationID string) string {
+	convID, err := model.ParseConversationID(conversationID)
+	if err != nil {
+		return conversationID
+	}
+
+	if convID.IsGroup() {
+		if s.groupService != nil {
+			if group, err := s.groupService.GetGroupInfo(ctx, convID.GroupID); err == nil && group.Name != "" {
+				return group.Name
+			}
+		}
+		return "群聊"
+	}
+
+	if peer, err := s.users.FindByID(ctx, convID.Peer(userID)); err == nil && peer != nil {
+		if peer.Nickname != "" {
+			return peer.Nickname
+		}
+		return peer.Username
+	}
+	return "单聊"
+}
+
+// link 生成客户端深链
+func (s *emailDigestServiceImpl) link(path string) string {
+	base := s.config.LinkBase
+	if path == "" || strings.HasSuffix(base, "/") {
+		return base + path
+	}
+	return base + "/" + path
+}
+
+// digestText 纯文本正文
+func digestText(data *EmailDigestData) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s，您好：\n\n您已有一段时间未登录，共有 %d 条未读消息。\n\n", data.Nickname, data.TotalCount)
+	for _, conv := range data.Conversations {
+		fmt.Fprintf(&b, "- %s：%d 条 %s\n", conv.Name, conv.Count, conv.Link)
+	}
+	if data.More > 0 {
+		fmt.Fprintf(&b, "以及其他 %d 个会话。\n", data.More)
+	}
+	fmt.Fprintf(&b, "\n打开应用查看: %s\n关闭邮件摘要: %s\n", data.AppLink, data.SettingsLink)
+	return b.String()
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// 功能开关服务错误定义
+var (
+	ErrFlagNotFound   = errors.New("feature flag not found")
+	ErrFlagInvalidKey = errors.New("invalid feature flag key")
+)
+
+const (
+	featureFlagsKey       = "im:feature_flags"    // 功能开关（HASH，field为开关Key）
+	cohortMetricsPrefix   = "im:rollout:metrics:" // 分组指标（HASH，field为 分组:事件）
+	cohortMetricsSince    = "_since"              // 分组指标开始统计时间
+	cohortMetricsTTL      = 30 * 24 * time.Hour
+	featureFlagsCacheTTL  = 5 * time.Second
+	cohortMetricsInterval = 10 * time.Second
+)
+
+// flagKeyPattern 开关Key格式
+var flagKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// CohortStats 单个分组的指标
+type CohortStats struct {
+	Connections        int64   `json:"connections"`
+	Disconnects        int64   `json:"disconnects"`
+	Messages           int64   `json:"messages"`
+	Errors             int64   `json:"errors"`
+	ErrorRate          float64 `json:"error_rate"`           // 消息处理失败率
+	AvgSessionSeconds  float64 `json:"avg_session_seconds"`  // 平均连接时长
+	AvgHandleMillis    float64 `json:"avg_handle_ms"`        // 平均消息处理耗时
+	MessagesPerSession float64 `json:"messages_per_session"` // 每个连接的平均上行消息数
+}
+
+// CohortComparison 灰度开关的分组指标对比
+type CohortComparison struct {
+	Flag    *model.FeatureFlag      `json:"flag"`
+	Since   time.Time               `json:"since"`
+	Cohorts map[string]*CohortStats `json:"cohorts"`
+}
+
+// FeatureFlagService 功能开关与灰度发布服务
+type FeatureFlagService interface {
+	// ListFlags 获取全部开关
+	ListFlags(ctx context.Context) ([]*model.FeatureFlag, error)
+	// SetFlag 创建或更新开关，新建开关时重置分组指标
+	SetFlag(ctx context.Context, key, operatorID string, req *model.SetFeatureFlagRequest) (*model.FeatureFlag, error)
+	// DeleteFlag 删除开关及其分组指标
+	DeleteFlag(ctx context.Context, key string) error
+
+	// Cohorts 获取用户在全部已开启开关中的分组（开关Key -> 分组），Redis异常时返回空（全部走旧行为）
+	Cohorts(ctx context.Context, userID string) map[string]string
+	// IsEnabled 用户是否进入指定开关的实验组
+	IsEnabled(ctx context.Context, key, userID string) bool
+
+	// Record 记录分组指标（本地累加，定期批量写入Redis）
+	Record(cohorts map[string]string, event string, value int64)
+	// CompareCohorts 获取开关各分组的指标对比
+	CompareCohorts(ctx context.Context, key string) (*CohortComparison, error)
+	// ResetMetrics 重置开关的分组指标（调整比例后重新对比）
+	ResetMetrics(ctx context.Context, key string) error
+
+	// Start 启动指标定期写入
+	Start(ctx context.Context)
+}
+
+// featureFlagServiceImpl 功能开关服务实现
+type featureFlagServiceImpl struct {
+	redis *redis.Client
+
+	mu        sync.RWMutex
+	cached    map[string]*model.FeatureFlag
+	expiresAt time.Time
+
+	metricsMu sync.Mutex
+	pending   map[string]map[string]int64 // 开关Key -> 分组:事件 -> 增量
+}
+
+// NewFeatureFlagService 创建功能开关服务
+func NewFeatureFlagService(redisClient *redis.Client) FeatureFlagService {
+	return &featureFlagServiceImpl{
+		redis:   redisClient,
+		pending: make(map[string]map[string]int64),
+	}
+}
+
+// ListFlags 获取全部开关（按Key排序）
+func (s *featureFlagServiceImpl) ListFlags(ctx context.Context) ([]*model.FeatureFlag, error) {
+	flags, err := s.loadFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.FeatureFlag, 0, len(flags))
+	for _, flag := range flags {
+		result = append(result, flag)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result, nil
+}
+
+// SetFlag 创建或更新开关
+func (s *featureFlagServiceImpl) SetFlag(ctx context.Context, key, operatorID string, req *model.SetFeatureFlagRequest) (*model.FeatureFlag, error) {
+	if !flagKeyPattern.MatchString(key) {
+		return nil, ErrFlagInvalidKey
+	}
+	if req.Percentage < 0 || req.Percentage > 100 {
+		return nil, ErrInvalidRequest
+	}
+
+	flag := &model.FeatureFlag{
+		Key:         key,
+		Description: req.Description,
+		Enabled:     req.Enabled,
+		Percentage:  req.Percentage,
+		AllowUsers:  uniqueStrings(req.AllowUsers),
+		DenyUsers:   uniqueStrings(req.DenyUsers),
+		UpdatedBy:   operatorID,
+		UpdatedAt:   time.Now(),
+	}
+	data, err := json.Marshal(flag)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.redis.HSet(ctx, featureFlagsKey, key, data).Result()
+	if err != nil {
+		return nil, fmt.Errorf("set feature flag error: %w", err)
+	}
+	if created > 0 {
+		if err := s.ResetMetrics(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+	s.invalidate()
+	return flag, nil
+}
+
+// DeleteFlag 删除开关
+func (s *featureFlagServiceImpl) DeleteFlag(ctx context.Context, key string) error {
+	deleted, err := s.redis.HDel(ctx, featureFlagsKey, key).Result()
+	if err != nil {
+		return fmt.Errorf("delete feature flag error: %w", err)
+	}
+	if deleted == 0 {
+		return ErrFlagNotFound
+	}
+	s.redis.Del(ctx, cohortMetricsPrefix+key)
+	s.invalidate()
+	return nil
+}
+
+// Cohorts 获取用户在全部已开启开关中的分组
+func (s *featureFlagServiceImpl) Cohorts(ctx context.Context, userID string) map[string]string {
+	flags, err := s.cachedFlags(ctx)
+	if err != nil {
+		log.Printf("load feature flags error: %v", err)
+		return nil
+	}
+
+	cohorts := make(map[string]string)
+	for key, flag := range flags {
+		if flag.Enabled {
+			cohorts[key] = flag.Cohort(userID)
+		}
+	}
+	return cohorts
+}
+
+// IsEnabled 用户是否进入指定开关的实验组
+func (s *featureFlagServiceImpl) IsEnabled(ctx context.Context, key, userID string) bool {
+	flags, err := s.cachedFlags(ctx)
+	if err != nil {
+		return false
+	}
+	flag, ok := flags[key]
+	return ok && flag.Enabled && flag.Cohort(userID) == model.CohortTreatment
+}
+
+// Record 记录分组指标
+func (s *featureFlagServiceImpl) Record(cohorts map[string]string, event string, value int64) {
+	if len(cohorts) == 0 || value == 0 {
+		return
+	}
+
+	s.metricsMu.Lock()
+	defer s.metricsMu.Unlock()
+	for key, cohort := range cohorts {
+		counters := s.pending[key]
+		if counters == nil {
+			counters = make(map[string]int64)
+			s.pending[key] = counters
+		}
+		counters[cohort+":"+event] += value
+	}
+}
+
+// Start 启动指标定期写入
+func (s *featureFlagServiceImpl) Start(ctx context.Context) {
+	ticker := time.NewTicker(cohortMetricsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// 退出前写入剩余指标
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			s.flushMetrics(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			s.flushMetrics(ctx)
+		}
+	}
+}
+
+// flushMetrics 批量写入本地累加的指标
+func (s *featureFlagServiceImpl) flushMetrics(ctx context.Context) {
+	s.metricsMu.Lock()
+	pending := s.pending
+	s.pending = make(map[string]map[string]int64)
+	s.metricsMu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	pipe := s.redis.Pipeline()
+	for key, counters := range pending {
+		metricsKey := cohortMetricsPrefix + key
+		for field, value := range counters {
+			pipe.HIncrBy(ctx, metricsKey, field, value)
+		}
+		pipe.HSetNX(ctx, metricsKey, cohortMetricsSince, time.Now().Unix())
+		pipe.Expire(ctx, metricsKey, cohortMetricsTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("flush cohort metrics error: %v", err)
+	}
+}
+
+// CompareCohorts 获取开关各分组的指标对比
+func (s *featureFlagServiceImpl) CompareCohorts(ctx context.Context, key string) (*CohortComparison, error) {
+	flags, err := s.loadFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	flag, ok := flags[key]
+	if !ok {
+		return nil, ErrFlagNotFound
+	}
+
+	values, err := s.redis.HGetAll(ctx, cohortMetricsPrefix+key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get cohort metrics error: %w", err)
+	}
+
+	result := &CohortComparison{
+		Flag: flag,
+		Cohorts: map[string]*CohortStats{
+			model.CohortControl:   {},
+			model.CohortTreatment: {},
+		},
+	}
+	sums := make(map[string]map[string]int64)
+	for field, raw := range values {
+		value, _ := strconv.ParseInt(raw, 10, 64)
+		if field == cohortMetricsSince {
+			result.Since = time.Unix(value, 0)
+			continue
+		}
+		cohort, event, ok := strings.Cut(field, ":")
+		if !ok || result.Cohorts[cohort] == nil {
+			continue
+		}
+		if sums[cohort] == nil {
+			sums[cohort] = make(map[string]int64)
+		}
+		sums[cohort][event] = value
+	}
+
+	for cohort, stats := range result.Cohorts {
+		sum := sums[cohort]
+		stats.Connections = sum[model.CohortEventConnect]
+		stats.Disconnects = sum[model.CohortEventDisconnect]
+		stats.Messages = sum[model.CohortEventMessage]
+		stats.Errors = sum[model.CohortEventError]
+		if stats.Messages > 0 {
+			stats.ErrorRate = float64(stats.Errors) / float64(stats.Messages)
+			stats.AvgHandleMillis = float64(sum[model.CohortEventHandleMillis]) / float64(stats.Messages)
+		}
+		if stats.Disconnects > 0 {
+			stats.AvgSessionSeconds = float64(sum[model.CohortEventSessionSeconds]) / float64(stats.Disconnects)
+		}
+		if stats.Connections > 0 {
+			stats.MessagesPerSession = float64(stats.Messages) / float64(stats.Connections)
+		}
+	}
+	return result, nil
+}
+
+// ResetMetrics 重置开关的分组指标
+func (s *featureFlagServiceImpl) ResetMetrics(ctx context.Context, key string) error {
+	metricsKey := cohortMetricsPrefix + key
+	pipe := s.redis.TxPipeline()
+	pipe.Del(ctx, metricsKey)
+	pipe.HSet(ctx, metricsKey, cohortMetricsSince, time.Now().Unix())
+	pipe.Expire(ctx, metricsKey, cohortMetricsTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("reset cohort metrics error: %w", err)
+	}
+	return nil
+}
+
+// loadFlags 从Redis读取全部开关
+func (s *featureFlagServiceImpl) loadFlags(ctx context.Context) (map[string]*model.FeatureFlag, error) {
+	values, err := s.redis.HGetAll(ctx, featureFlagsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get feature flags error: %w", err)
+	}
+
+	flags := make(map[string]*model.FeatureFlag, len(values))
+	for key, data := range values {
+		var flag model.FeatureFlag
+		if err := json.Unmarshal([]byte(data), &flag); err != nil {
+			log.Printf("unmarshal feature flag %s error: %v", key, err)
+			continue
+		}
+		flags[key] = &flag
+	}
+	return flags, nil
+}
+
+// cachedFlags 读取本地缓存的开关，过期后从Redis刷新
+func (s *featureFlagServiceImpl) cachedFlags(ctx context.Context) (map[string]*model.FeatureFlag, error) {
+	s.mu.RLock()
+	cached, valid := s.cached, time.Now().Before(s.expiresAt)
+	s.mu.RUnlock()
+	if valid {
+		return cached, nil
+	}
+
+	flags, err := s.loadFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.cached = flags
+	s.expiresAt = time.Now().Add(featureFlagsCacheTTL)
+	s.mu.Unlock()
+	return flags, nil
+}
+
+// invalidate 清除本地缓存（其他节点在缓存过期后生效）
+func (s *featureFlagServiceImpl) invalidate() {
+	s.mu.Lock()
+	s.expiresAt = time.Time{}
+	s.mu.Unlock()
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/pkg/objectstore"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 文件访问错误定义
+var (
+	ErrFileURLExpired     = errors.New("file url expired")
+	ErrFileURLInvalid     = errors.New("invalid file url signature")
+	ErrFileURLRevoked     = errors.New("file url revoked")
+	ErrFileRefererDenied  = errors.New("referer not allowed")
+	ErrFileClientIPDenied = errors.New("client ip not allowed")
+)
+
+// FileURLOptions 文件URL签发选项
+type FileURLOptions struct {
+	Expiry   time.Duration // 期望过期时间，0表示使用策略默认值，超过策略上限时截断
+	ClientIP string        // 客户端IP（绑定IP时使用）
+}
+
+// FileURLVerifyRequest 代理下载URL校验请求
+type FileURLVerifyRequest struct {
+	Expires   int64
+	Nonce     string
+	Signature string
+	ClientIP  string
+	Referer   string
+}
+
+// accessControl 获取文件访问控制策略
+func (s *objectStorageService) accessControl() *model.FileAccessControl {
+	if s.config.AccessControl != nil {
+		return s.config.AccessControl
+	}
+	return model.DefaultFileAccessControl()
+}
+
+// GetFileURL 获取文件访问URL
+// 代理模式下返回经网关下载的签名URL，否则返回对象存储预签名URL（存储后端不支持时同代理模式）
+func (s *objectStorageService) GetFileURL(ctx context.Context, fileID string, opts *FileURLOptions) (*model.SignedFileURL, error) {
+	if opts == nil {
+		opts = &FileURLOptions{}
+	}
+
+	var file model.File
+	if err := s.db.WithContext(ctx).Where("file_id = ? AND status IN ?", fileID,
+		[]model.FileStatus{model.FileStatusNormal, model.FileStatusExpired}).First(&file).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	if file.Status == model.FileStatusExpired {
+		return nil, ErrFileExpired
+	}
+
+	policy := s.accessControl()
+
+	// 按文件类型限制过期时间
+	expiry := time.Duration(policy.URLExpiry(file.FileType)) * time.Second
+	if expiry <= 0 {
+		expiry = s.config.SignedURLExpiry
+	}
+	if opts.Expiry > 0 && (expiry <= 0 || opts.Expiry < expiry) {
+		expiry = opts.Expiry
+	}
+	expireAt := time.Now().Add(expiry)
+
+	if !policy.ProxyDownload && s.cdnDomain != "" {
+		cdnURL, cdnExpireAt := s.buildCDNURL(file.StoragePath, expiry)
+		if cdnURL == "" {
+			return nil, errors.New("sign cdn url failed")
+		}
+		if cdnExpireAt == 0 {
+			cdnExpireAt = expireAt.Unix()
+		}
+		return &model.SignedFileURL{URL: cdnURL, ExpireAt: cdnExpireAt}, nil
+	}
+
+	if !policy.ProxyDownload {
+		presignedURL, err := s.store.PresignGet(ctx, file.StoragePath, expiry)
+		if err == nil {
+			return &model.SignedFileURL{URL: presignedURL, ExpireAt: expireAt.Unix()}, nil
+		}
+		// 存储后端不支持预签名（本地磁盘）时改用代理下载
+		if !errors.Is(err, objectstore.ErrPresignUnsupported) {
+			return nil, fmt.Errorf("generate presigned url error: %w", err)
+		}
+	}
+
+	// 代理模式：记录签发的nonce以支持撤销
+	nonce := util.GenerateToken(8)
+	clientIP := ""
+	if policy.BindClientIP {
+		clientIP = opts.ClientIP
+	}
+
+	tokensKey := fmt.Sprintf("file:url:tokens:%s", fileID)
+	pipe := s.redis.TxPipeline()
+	pipe.SAdd(ctx, tokensKey, nonce)
+	pipe.Expire(ctx, tokensKey, time.Duration(policy.MaxURLExpiry())*time.Second)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("save url token error: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expireAt.Unix(), 10))
+	query.Set("nonce", nonce)
+	query.Set("sig", s.signFileURL(fileID, expireAt.Unix(), nonce, clientIP))
+
+	return &model.SignedFileURL{
+		URL:      fmt.Sprintf("%s/%s?%s", strings.TrimSuffix(s.config.ProxyURLPrefix, "/"), fileID, query.Encode()),
+		ExpireAt: expireAt.Unix(),
+		Proxy:    true,
+	}, nil
+}
+
+// VerifyFileURL 校验代理下载URL
+func (s *objectStorageService) VerifyFileURL(ctx context.Context, fileID string, req *FileURLVerifyRequest) error {
+	policy := s.accessControl()
+
+	if time.Now().Unix() > req.Expires {
+		return ErrFileURLExpired
+	}
+
+	clientIP := ""
+	if policy.BindClientIP {
+		clientIP = req.ClientIP
+	}
+	expected := s.signFileURL(fileID, req.Expires, req.Nonce, clientIP)
+	if !hmac.Equal([]byte(expected), []byte(req.Signature)) {
+		if policy.BindClientIP {
+			return ErrFileClientIPDenied
+		}
+		return ErrFileURLInvalid
+	}
+
+	if !checkReferer(policy, req.Referer) {
+		return ErrFileRefererDenied
+	}
+
+	// 文件删除后签发的URL全部失效
+	tokensKey := fmt.Sprintf("file:url:tokens:%s", fileID)
+	ok, err := s.redis.SIsMember(ctx, tokensKey, req.Nonce).Result()
+	if err != nil {
+		return fmt.Errorf("check url token error: %w", err)
+	}
+	if !ok {
+		return ErrFileURLRevoked
+	}
+
+	return nil
+}
+
+// RevokeFileURLs 撤销文件已签发的所有代理下载URL
+func (s *objectStorageService) RevokeFileURLs(ctx context.Context, fileID string) error {
+	return s.redis.Del(ctx, fmt.Sprintf("file:url:tokens:%s", fileID)).Err()
+}
+
+// signFileURL 计算代理下载URL签名
+func (s *objectStorageService) signFileURL(fileID string, expires int64, nonce, clientIP string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.URLSigningSecret))
+	fmt.Fprintf(mac, "%s|%d|%s|%s", fileID, expires, nonce, clientIP)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkReferer 检查Referer是否在白名单中
+func checkReferer(policy *model.FileAccessControl, referer string) bool {
+	if len(policy.RefererWhitelist) == 0 {
+		return true
+	}
+	if referer == "" {
+		return policy.AllowEmptyReferer
+	}
+
+	u, err := url.Parse(referer)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	for _, allowed := range policy.RefererWhitelist {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
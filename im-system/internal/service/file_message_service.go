@@ -0,0 +1,388 @@
+// Package service 提供业务逻辑服务
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 文件消息服务错误定义
+var (
+	ErrInvalidFileMessageTarget = errors.New("either to or group_id is required")
+)
+
+// pendingFilesKey 已上传但尚未关联消息的文件（ZSET，score为上传时间），
+// 包括带文件发消息及声明用于消息（purpose=message）的普通上传、分片上传和断点续传
+const pendingFilesKey = "file:pending"
+
+// GroupMuteRejection 群文件消息因禁言被拒绝（errors.Is 匹配 ErrMemberMuted 或 ErrGroupMuteAll）
+type GroupMuteRejection struct {
+	GroupID string
+	Status  *model.GroupMuteStatus
+	Err     error
+}
+
+// Error 实现 error 接口
+func (e *GroupMuteRejection) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 支持 errors.Is 匹配禁言错误
+func (e *GroupMuteRejection) Unwrap() error {
+	return e.Err
+}
+
+// FileMessageDispatcher 文件消息分发器：单聊按用户分发，群聊按会话分发（每个节点只发布一次会话路由消息）
+type FileMessageDispatcher interface {
+	MessageDispatcher
+	DispatchToConversation(ctx context.Context, conversationID string, msg *model.Message, excludeUserID string) error
+}
+
+// FileMessageGuard 文件消息发送前检查（屏蔽、访客发送范围等），在上传文件前执行，返回错误时拒绝发送
+type FileMessageGuard func(ctx context.Context, msg *model.Message) error
+
+// UploadTracker 记录已上传但尚未被消息引用的文件，超时仍未引用时由孤儿文件清理任务回收
+type UploadTracker interface {
+	TrackUpload(ctx context.Context, fileID string)
+}
+
+// FileMessageService 文件消息服务接口（上传文件并发送消息）
+type FileMessageService interface {
+	// SendWithFile 上传文件、创建文件记录、保存并分发消息，任一步骤失败时清理已上传文件
+	SendWithFile(ctx context.Context, req *FileMessageRequest) (*model.Message, *model.FileInfo, error)
+
+	// TrackUpload 记录声明用于消息、先上传后发送的文件，保存引用该文件的消息时移除
+	TrackUpload(ctx context.Context, fileID string)
+
+	// ReapOrphanFiles 清理上传后超过指定时间仍未关联消息的孤儿文件
+	ReapOrphanFiles(ctx context.Context, olderThan time.Duration) (int, error)
+
+	// StartOrphanReaper 启动孤儿文件清理任务
+	StartOrphanReaper(ctx context.Context, interval, olderThan time.Duration)
+
+	// SetEncryptionService 设置会话加密服务，加密会话中拒绝发送明文文件消息
+	SetEncryptionService(encryption ConversationEncryptionService)
+
+	// SetMessageTypePolicy 设置消息类型策略，不允许的文件消息类型在保存前拒绝
+	SetMessageTypePolicy(policy MessageTypePolicyService)
+
+	// SetSendGuard 设置发送前检查，与 WebSocket 发送前检查一致（如接收者屏蔽了发送者时拒绝）
+	SetSendGuard(guard FileMessageGuard)
+
+	// SetUserRepository 设置用户仓库，单聊接收者不存在或已注销时拒绝
+	SetUserRepository(users repository.UserRepository)
+}
+
+// FileMessageRequest 文件消息请求
+type FileMessageRequest struct {
+	Upload    *UploadRequest
+	To        string // 单聊接收者
+	GroupID   string // 群聊ID
+	MessageID string // 客户端消息ID（可选）
+}
+
+// fileMessageServiceImpl 文件消息服务实现
+type fileMessageServiceImpl struct {
+	fileService    FileStorageService
+	messageService MessageService
+	groupService   GroupService
+	dispatcher     FileMessageDispatcher
+	redis          *redis.Client
+	encryption     ConversationEncryptionService
+	typePolicy     MessageTypePolicyService
+	sendGuard      FileMessageGuard
+	users          repository.UserRepository
+}
+
+// NewFileMessageService 创建文件消息服务
+func NewFileMessageService(
+	fileService FileStorageService,
+	messageService MessageService,
+	groupService GroupService,
+	dispatcher FileMessageDispatcher,
+	redisClient *redis.Client,
+) FileMessageService {
+	return &fileMessageServiceImpl{
+		fileService:    fileService,
+		messageService: messageService,
+		groupService:   groupService,
+		dispatcher:     dispatcher,
+		redis:          redisClient,
+	}
+}
+
+// SetEncryptionService 设置会话加密服务
+func (s *fileMessageServiceImpl) SetEncryptionService(encryption ConversationEncryptionService) {
+	s.encryption = encryption
+}
+
+// SetMessageTypePolicy 设置消息类型策略
+func (s *fileMessageServiceImpl) SetMessageTypePolicy(policy MessageTypePolicyService) {
+	s.typePolicy = policy
+}
+
+// SetSendGuard 设置发送前检查
+func (s *fileMessageServiceImpl) SetSendGuard(guard FileMessageGuard) {
+	s.sendGuard = guard
+}
+
+// SetUserRepository 设置用户仓库
+func (s *fileMessageServiceImpl) SetUserRepository(users repository.UserRepository) {
+	s.users = users
+}
+
+// SendWithFile 上传文件并发送文件消息
+func (s *fileMessageServiceImpl) SendWithFile(ctx context.Context, req *FileMessageRequest) (*model.Message, *model.FileInfo, error) {
+	if req.To == "" && req.GroupID == "" {
+		return nil, nil, ErrInvalidFileMessageTarget
+	}
+
+	userID := req.Upload.UserID
+
+	// 群聊需要校验成员身份
+	if req.GroupID != "" {
+		isMember, err := s.groupService.IsMember(ctx, req.GroupID, userID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("check membership error: %w", err)
+		}
+		if !isMember {
+			return nil, nil, ErrNotGroupMember
+		}
+		// 被禁言成员（含全员禁言下的普通成员）不能发送，查询出错时放行
+		if status, err := s.groupService.CheckMute(ctx, req.GroupID, userID); err != nil {
+			if status != nil {
+				return nil, nil, &GroupMuteRejection{GroupID: req.GroupID, Status: status, Err: err}
+			}
+			log.Printf("check group mute of %s in %s error: %v", userID, req.GroupID, err)
+		}
+	}
+
+	// 单聊接收者须为未注销的用户
+	if req.GroupID == "" && s.users != nil {
+		user, err := s.users.FindByID(ctx, req.To)
+		if err != nil {
+			return nil, nil, fmt.Errorf("find user error: %w", err)
+		}
+		if user == nil || user.Status == model.UserStatusDeleted {
+			return nil, nil, ErrUserNotFound
+		}
+	}
+
+	// 加密会话中文件须由客户端加密后以密文消息发送
+	if s.encryption != nil {
+		probe := &model.Message{Type: model.MsgFile, From: userID, To: req.To, GroupID: req.GroupID}
+		if err := s.encryption.CheckMessage(ctx, probe); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// 发送前检查（屏蔽、访客发送范围等）在上传前执行，被拒绝时不产生文件
+	if s.sendGuard != nil {
+		target := &model.Message{Type: model.MsgFile, From: userID, To: req.To}
+		if req.GroupID != "" {
+			target.To, target.GroupID = req.GroupID, req.GroupID
+		}
+		if err := s.sendGuard(ctx, target); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// 上传文件并创建文件记录（群聊文件受群组文件类型策略约束）
+	req.Upload.GroupID = req.GroupID
+	fileInfo, err := s.fileService.Upload(ctx, req.Upload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 记录为待关联文件，进程异常退出时由清理任务回收
+	s.TrackUpload(ctx, fileInfo.FileID)
+
+	msg := buildFileMessage(userID, req, fileInfo)
+
+	// 消息类型由上传的文件决定，不允许时删除已上传文件
+	if s.typePolicy != nil {
+		if err := s.typePolicy.CheckMessage(ctx, msg); err != nil {
+			s.cleanupFile(ctx, fileInfo.FileID)
+			return nil, nil, err
+		}
+	}
+
+	// 保存消息（同时移出待关联集合），失败时删除已上传文件
+	if err := s.messageService.SaveMessage(ctx, msg); err != nil {
+		s.cleanupFile(ctx, fileInfo.FileID)
+		return nil, nil, err
+	}
+
+	// 分发消息（消息已持久化，分发失败的用户可通过历史消息拉取，不回滚）
+	if s.dispatcher != nil {
+		var err error
+		if req.GroupID != "" {
+			err = s.dispatcher.DispatchToConversation(ctx, msg.ConversationID, msg, userID)
+		} else {
+			err = s.dispatcher.DispatchToUsers(ctx, []string{req.To}, msg)
+		}
+		if err != nil {
+			log.Printf("dispatch file message %s error: %v", msg.MessageID, err)
+		}
+	}
+
+	return msg, fileInfo, nil
+}
+
+// TrackUpload 记录待关联文件
+func (s *fileMessageServiceImpl) TrackUpload(ctx context.Context, fileID string) {
+	if err := s.redis.ZAdd(ctx, pendingFilesKey, &redis.Z{Score: float64(time.Now().Unix()), Member: fileID}).Err(); err != nil {
+		log.Printf("track pending file %s error: %v", fileID, err)
+	}
+}
+
+// referencedFileIDs 消息内容引用的文件ID：文件类消息的 file_id，端到端加密消息明文列出的 file_ids
+func referencedFileIDs(content map[string]interface{}) []string {
+	var fileIDs []string
+	if fileID, ok := content["file_id"].(string); ok && fileID != "" {
+		fileIDs = append(fileIDs, fileID)
+	}
+	if items, ok := content["file_ids"].([]interface{}); ok {
+		for _, item := range items {
+			if fileID, ok := item.(string); ok && fileID != "" {
+				fileIDs = append(fileIDs, fileID)
+			}
+		}
+	}
+	return fileIDs
+}
+
+// buildFileMessage 根据文件类型构建消息
+func buildFileMessage(userID string, req *FileMessageRequest, fileInfo *model.FileInfo) *model.Message {
+	messageID := req.MessageID
+	if messageID == "" {
+		messageID = util.GenerateMessageID()
+	}
+
+	msg := &model.Message{
+		MessageID: messageID,
+		From:      userID,
+		To:        req.To,
+		Timestamp: time.Now().UnixMilli(),
+		CreatedAt: time.Now(),
+	}
+
+	if req.GroupID != "" {
+		msg.To = req.GroupID
+		msg.GroupID = req.GroupID
+		msg.ConversationID = model.GetGroupChatConversationID(req.GroupID)
+	} else {
+		msg.ConversationID = model.GetSingleChatConversationID(userID, req.To)
+	}
+
+	format := strings.ToLower(fileInfo.FileExt)
+	switch fileInfo.FileType {
+	case model.FileTypeImage:
+		msg.Type = model.MsgImage
+		msg.Content = &model.ImageContent{
+			FileID:       fileInfo.FileID,
+			URL:          fileInfo.URL,
+			ThumbnailURL: fileInfo.ThumbnailURL,
+			Width:        fileInfo.Width,
+			Height:       fileInfo.Height,
+			FileSize:     fileInfo.FileSize,
+			Format:       format,
+		}
+	case model.FileTypeVideo:
+		msg.Type = model.MsgVideo
+		msg.Content = &model.VideoContent{
+			FileID:       fileInfo.FileID,
+			URL:          fileInfo.URL,
+			ThumbnailURL: fileInfo.ThumbnailURL,
+			Duration:     fileInfo.Duration,
+			Width:        fileInfo.Width,
+			Height:       fileInfo.Height,
+			FileSize:     fileInfo.FileSize,
+			Format:       format,
+		}
+	case model.FileTypeAudio:
+		msg.Type = model.MsgVoice
+		msg.Content = &model.VoiceContent{
+			FileID:   fileInfo.FileID,
+			URL:      fileInfo.URL,
+			Duration: fileInfo.Duration,
+			FileSize: fileInfo.FileSize,
+			Format:   format,
+		}
+	default:
+		msg.Type = model.MsgFile
+		msg.Content = &model.FileContent{
+			FileID:   fileInfo.FileID,
+			FileName: fileInfo.FileName,
+			FileSize: fileInfo.FileSize,
+			FileExt:  fileInfo.FileExt,
+			MimeType: fileInfo.MimeType,
+			URL:      fileInfo.URL,
+		}
+	}
+
+	return msg
+}
+
+// cleanupFile 删除已上传文件，失败时保留在待关联集合中由清理任务重试
+func (s *fileMessageServiceImpl) cleanupFile(ctx context.Context, fileID string) {
+	if err := s.fileService.Delete(ctx, fileID); err != nil && !errors.Is(err, ErrFileNotFound) {
+		log.Printf("cleanup file %s error: %v", fileID, err)
+		return
+	}
+	s.redis.ZRem(ctx, pendingFilesKey, fileID)
+}
+
+// ReapOrphanFiles 清理孤儿文件
+func (s *fileMessageServiceImpl) ReapOrphanFiles(ctx context.Context, olderThan time.Duration) (int, error) {
+	maxScore := fmt.Sprintf("%d", time.Now().Add(-olderThan).Unix())
+	fileIDs, err := s.redis.ZRangeByScore(ctx, pendingFilesKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: maxScore,
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("get pending files error: %w", err)
+	}
+
+	reaped := 0
+	for _, fileID := range fileIDs {
+		if err := s.fileService.Delete(ctx, fileID); err != nil && !errors.Is(err, ErrFileNotFound) {
+			log.Printf("reap orphan file %s error: %v", fileID, err)
+			continue
+		}
+		s.redis.ZRem(ctx, pendingFilesKey, fileID)
+		reaped++
+	}
+
+	return reaped, nil
+}
+
+// StartOrphanReaper 启动孤儿文件清理任务
+func (s *fileMessageServiceImpl) StartOrphanReaper(ctx context.Context, interval, olderThan time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := s.ReapOrphanFiles(ctx, olderThan)
+			if err != nil {
+				log.Printf("reap orphan files error: %v", err)
+			} else if count > 0 {
+				log.Printf("reaped %d orphan files", count)
+			}
+		}
+	}
+}
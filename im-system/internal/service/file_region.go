@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// regionalStorageService 按区域路由的文件存储：上传按上传者所在区域选择存储桶，已上传的文件按文件记录的区域访问
+type regionalStorageService struct {
+	defaultRegion string
+	services      map[string]FileStorageService // 区域 -> 该区域的存储服务（默认区域的存储服务不写入区域标记）
+	regions       DataRegionService
+	db            *gorm.DB
+}
+
+// NewRegionalStorageService 创建按区域路由的文件存储服务，services 须包含默认区域
+func NewRegionalStorageService(defaultRegion string, services map[string]FileStorageService, regions DataRegionService, db *gorm.DB) FileStorageService {
+	return &regionalStorageService{
+		defaultRegion: defaultRegion,
+		services:      services,
+		regions:       regions,
+		db:            db,
+	}
+}
+
+// forUser 用户所在区域的存储服务，该区域未部署存储时拒绝（不回落到其他区域）
+func (s *regionalStorageService) forUser(ctx context.Context, userID string) (FileStorageService, error) {
+	region, err := s.regions.UserRegion(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	svc, ok := s.services[region]
+	if !ok {
+		return nil, ErrStorageUnavailable
+	}
+	return svc, nil
+}
+
+// forFile 文件所在区域的存储服务（文件不存在时由默认区域返回 ErrFileNotFound）
+func (s *regionalStorageService) forFile(ctx context.Context, fileID string) (FileStorageService, error) {
+	var regions []string
+	if err := s.db.WithContext(ctx).Model(&model.File{}).Where("file_id = ?", fileID).Pluck("COALESCE(region, '')", &regions).Error; err != nil {
+		return nil, err
+	}
+	if len(regions) == 0 || regions[0] == "" {
+		return s.services[s.defaultRegion], nil
+	}
+	svc, ok := s.services[regions[0]]
+	if !ok {
+		return nil, ErrStorageUnavailable
+	}
+	return svc, nil
+}
+
+// forUpload 依次在各区域执行分片上传操作，直到找到持有该上传的区域
+func (s *regionalStorageService) forUpload(fn func(svc FileStorageService) error) error {
+	for _, svc := range s.services {
+		if err := fn(svc); !errors.Is(err, ErrInvalidUploadID) {
+			return err
+		}
+	}
+	return ErrInvalidUploadID
+}
+
+// Upload 上传到上传者所在区域
+func (s *regionalStorageService) Upload(ctx context.Context, req *UploadRequest) (*model.FileInfo, error) {
+	svc, err := s.forUser(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.Upload(ctx, req)
+}
+
+// Download 下载文件
+func (s *regionalStorageService) Download(ctx context.Context, fileID string) (io.ReadCloser, *model.FileInfo, error) {
+	svc, err := s.forFile(ctx, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return svc.Download(ctx, fileID)
+}
+
+// Delete 删除文件
+func (s *regionalStorageService) Delete(ctx context.Context, fileID string) error {
+	svc, err := s.forFile(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	return svc.Delete(ctx, fileID)
+}
+
+// Expire 按保存期限清理文件
+func (s *regionalStorageService) Expire(ctx context.Context, fileID string) error {
+	svc, err := s.forFile(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	return svc.Expire(ctx, fileID)
+}
+
+// GetFileInfo 获取文件信息
+func (s *regionalStorageService) GetFileInfo(ctx context.Context, fileID string) (*model.FileInfo, error) {
+	svc, err := s.forFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.GetFileInfo(ctx, fileID)
+}
+
+// GetFileURL 获取文件访问URL
+func (s *regionalStorageService) GetFileURL(ctx context.Context, fileID string, opts *FileURLOptions) (*model.SignedFileURL, error) {
+	svc, err := s.forFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.GetFileURL(ctx, fileID, opts)
+}
+
+// VerifyFileURL 校验代理下载URL
+func (s *regionalStorageService) VerifyFileURL(ctx context.Context, fileID string, req *FileURLVerifyRequest) error {
+	svc, err := s.forFile(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	return svc.VerifyFileURL(ctx, fileID, req)
+}
+
+// RevokeFileURLs 吊销文件已签发的URL
+func (s *regionalStorageService) RevokeFileURLs(ctx context.Context, fileID string) error {
+	svc, err := s.forFile(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	return svc.RevokeFileURLs(ctx, fileID)
+}
+
+// InitMultipartUpload 在上传者所在区域初始化分片上传
+func (s *regionalStorageService) InitMultipartUpload(ctx context.Context, req *model.InitMultipartUploadRequest, userID string) (*model.InitMultipartUploadResponse, error) {
+	svc, err := s.forUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.InitMultipartUpload(ctx, req, userID)
+}
+
+// UploadPart 上传分片
+func (s *regionalStorageService) UploadPart(ctx context.Context, uploadID string, partNumber int, reader io.Reader, size int64) (*model.PartInfo, error) {
+	var part *model.PartInfo
+	err := s.forUpload(func(svc FileStorageService) error {
+		var err error
+		part, err = svc.UploadPart(ctx, uploadID, partNumber, reader, size)
+		return err
+	})
+	return part, err
+}
+
+// CompleteMultipartUpload 完成分片上传
+func (s *regionalStorageService) CompleteMultipartUpload(ctx context.Context, uploadID string, parts []*model.PartInfo) (*model.FileInfo, error) {
+	var file *model.FileInfo
+	err := s.forUpload(func(svc FileStorageService) error {
+		var err error
+		file, err = svc.CompleteMultipartUpload(ctx, uploadID, parts)
+		return err
+	})
+	return file, err
+}
+
+// AbortMultipartUpload 取消分片上传
+func (s *regionalStorageService) AbortMultipartUpload(ctx context.Context, uploadID string) error {
+	return s.forUpload(func(svc FileStorageService) error {
+		return svc.AbortMultipartUpload(ctx, uploadID)
+	})
+}
+
+// CreateUploadSession 在上传者所在区域创建断点续传会话
+func (s *regionalStorageService) CreateUploadSession(ctx context.Context, req *model.CreateUploadSessionRequest, userID string) (*model.UploadSessionInfo, error) {
+	svc, err := s.forUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.CreateUploadSession(ctx, req, userID)
+}
+
+// WriteUploadSession 写入断点续传数据
+func (s *regionalStorageService) WriteUploadSession(ctx context.Context, uploadID, userID string, offset int64, reader io.Reader) (*model.UploadSessionInfo, error) {
+	svc, err := s.forUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.WriteUploadSession(ctx, uploadID, userID, offset, reader)
+}
+
+// GetUploadSession 查询断点续传会话
+func (s *regionalStorageService) GetUploadSession(ctx context.Context, uploadID, userID string) (*model.UploadSessionInfo, error) {
+	svc, err := s.forUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.GetUploadSession(ctx, uploadID, userID)
+}
+
+// FinalizeUploadSession 完成断点续传
+func (s *regionalStorageService) FinalizeUploadSession(ctx context.Context, uploadID, userID string) (*model.FileInfo, error) {
+	svc, err := s.forUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.FinalizeUploadSession(ctx, uploadID, userID)
+}
+
+// AbortUploadSession 取消断点续传
+func (s *regionalStorageService) AbortUploadSession(ctx context.Context, uploadID, userID string) error {
+	svc, err := s.forUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return svc.AbortUploadSession(ctx, uploadID, userID)
+}
+
+// StartUploadSessionCleanup 启动各区域的过期上传会话清理任务，阻塞直到 ctx 取消
+func (s *regionalStorageService) StartUploadSessionCleanup(ctx context.Context, interval time.Duration) {
+	for _, svc := range s.services {
+		go svc.StartUploadSessionCleanup(ctx, interval)
+	}
+	<-ctx.Done()
+}
+
+// GenerateThumbnail 生成缩略图
+func (s *regionalStorageService) GenerateThumbnail(ctx context.Context, fileID string, width, height int) (string, error) {
+	svc, err := s.forFile(ctx, fileID)
+	if err != nil {
+		return "", err
+	}
+	return svc.GenerateThumbnail(ctx, fileID, width, height)
+}
+
+// CheckFileExists 秒传检测（无法确定上传者区域，只在默认区域中查找）
+func (s *regionalStorageService) CheckFileExists(ctx context.Context, md5Hash string) (*model.FileInfo, bool, error) {
+	return s.services[s.defaultRegion].CheckFileExists(ctx, md5Hash)
+}
+
+// SetFileTypePolicy 设置各区域的文件类型策略
+func (s *regionalStorageService) SetFileTypePolicy(policy FileTypePolicyService) {
+	for _, svc := range s.services {
+		svc.SetFileTypePolicy(policy)
+	}
+}
+
+// SetContentModeration 设置各区域上传文件的内容审核
+func (s *regionalStorageService) SetContentModeration(moderation ContentModerationService) {
+	for _, svc := range s.services {
+		svc.SetContentModeration(moderation)
+	}
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// FileRetentionConfig 聊天文件保存期限配置
+type FileRetentionConfig struct {
+	SingleChat   time.Duration // 单聊文件保存期限（0表示长期保存）
+	GroupChat    time.Duration // 群聊文件保存期限（0表示长期保存）
+	ReapInterval time.Duration // 过期文件清理间隔
+	BatchSize    int           // 每次清理的最大文件数
+	QueueSize    int           // 待登记附件队列长度，满时丢弃（文件保持长期保存）
+}
+
+// DefaultFileRetentionConfig 默认配置（不启用过期）
+func DefaultFileRetentionConfig() *FileRetentionConfig {
+	return &FileRetentionConfig{
+		ReapInterval: 10 * time.Minute,
+		BatchSize:    500,
+		QueueSize:    10000,
+	}
+}
+
+// FileRetentionService 聊天文件保存期限服务接口
+type FileRetentionService interface {
+	// Record 登记消息中的附件（消息分发回调，非阻塞）
+	Record(ctx context.Context, msg *model.Message)
+	// SetRetained 设置文件是否长期保存（收藏、群文件等保存操作调用），只有上传者可以设置
+	SetRetained(ctx context.Context, fileID, userID string, retained bool) error
+	// ReapExpired 清理已过期的文件，返回清理数量
+	ReapExpired(ctx context.Context) (int, error)
+	// Start 启动附件登记和过期清理任务
+	Start(ctx context.Context)
+}
+
+// fileAttachment 待登记的消息附件
+type fileAttachment struct {
+	fileID    string
+	expiresAt time.Time
+}
+
+// fileRetentionServiceImpl 聊天文件保存期限服务实现
+type fileRetentionServiceImpl struct {
+	db          *gorm.DB
+	fileService FileStorageService
+	config      *FileRetentionConfig
+	pending     chan fileAttachment
+}
+
+// NewFileRetentionService 创建聊天文件保存期限服务
+func NewFileRetentionService(db *gorm.DB, fileService FileStorageService, config *FileRetentionConfig) FileRetentionService {
+	if config == nil {
+		config = DefaultFileRetentionConfig()
+	}
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultFileRetentionConfig().QueueSize
+	}
+	return &fileRetentionServiceImpl{
+		db:          db,
+		fileService: fileService,
+		config:      config,
+		pending:     make(chan fileAttachment, queueSize),
+	}
+}
+
+// Record 登记消息中的附件
+func (s *fileRetentionServiceImpl) Record(ctx context.Context, msg *model.Message) {
+	retention := s.config.SingleChat
+	if msg.GroupID != "" {
+		retention = s.config.GroupChat
+	}
+	if retention <= 0 {
+		return
+	}
+
+	fileID := attachmentFileID(msg)
+	if fileID == "" {
+		return
+	}
+
+	select {
+	case s.pending <- fileAttachment{fileID: fileID, expiresAt: time.Now().Add(retention)}:
+	default:
+		log.Printf("file retention queue full, drop attachment %s", fileID)
+	}
+}
+
+// attachmentFileID 获取附件消息引用的文件ID
+func attachmentFileID(msg *model.Message) string {
+	switch msg.Type {
+	case model.MsgImage, model.MsgVoice, model.MsgVideo, model.MsgFile:
+	default:
+		return ""
+	}
+
+	// 内容可能是具体类型，也可能是客户端上行解析出的map，统一按JSON读取file_id
+	data, err := json.Marshal(msg.Content)
+	if err != nil {
+		return ""
+	}
+	var content struct {
+		FileID string `json:"file_id"`
+	}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return ""
+	}
+	return content.FileID
+}
+
+// attach 设置附件过期时间，同一文件在多个会话中发送时取最晚的过期时间
+func (s *fileRetentionServiceImpl) attach(ctx context.Context, a fileAttachment) error {
+	return s.db.WithContext(ctx).Model(&model.File{}).
+		Where("file_id = ? AND status = ? AND retained = ?", a.fileID, model.FileStatusNormal, false).
+		Where("expires_at IS NULL OR expires_at < ?", a.expiresAt).
+		Update("expires_at", a.expiresAt).Error
+}
+
+// SetRetained 设置文件是否长期保存
+func (s *fileRetentionServiceImpl) SetRetained(ctx context.Context, fileID, userID string, retained bool) error {
+	var file model.File
+	if err := s.db.WithContext(ctx).Where("file_id = ?", fileID).First(&file).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFileNotFound
+		}
+		return err
+	}
+	switch file.Status {
+	case model.FileStatusExpired:
+		return ErrFileExpired
+	case model.FileStatusDeleted:
+		return ErrFileNotFound
+	}
+	if file.UserID != userID {
+		return ErrPermissionDeny
+	}
+
+	if err := s.db.WithContext(ctx).Model(&model.File{}).
+		Where("file_id = ? AND status = ?", fileID, model.FileStatusNormal).
+		Update("retained", retained).Error; err != nil {
+		return fmt.Errorf("update file retention error: %w", err)
+	}
+	return nil
+}
+
+// ReapExpired 清理已过期的文件
+func (s *fileRetentionServiceImpl) ReapExpired(ctx context.Context) (int, error) {
+	batchSize := s.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultFileRetentionConfig().BatchSize
+	}
+
+	var fileIDs []string
+	if err := s.db.WithContext(ctx).Model(&model.File{}).
+		Where("status = ? AND retained = ? AND expires_at <= ?", model.FileStatusNormal, false, time.Now()).
+		Order("expires_at").
+		Limit(batchSize).
+		Pluck("file_id", &fileIDs).Error; err != nil {
+		return 0, fmt.Errorf("find expired files error: %w", err)
+	}
+
+	reaped := 0
+	for _, fileID := range fileIDs {
+		if err := s.fileService.Expire(ctx, fileID); err != nil && !errors.Is(err, ErrFileNotFound) {
+			log.Printf("expire file %s error: %v", fileID, err)
+			continue
+		}
+		reaped++
+	}
+	return reaped, nil
+}
+
+// Start 启动附件登记和过期清理任务
+func (s *fileRetentionServiceImpl) Start(ctx context.Context) {
+	interval := s.config.ReapInterval
+	if interval <= 0 {
+		interval = DefaultFileRetentionConfig().ReapInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case a := <-s.pending:
+			if err := s.attach(ctx, a); err != nil {
+				log.Printf("record file %s expiry error: %v", a.fileID, err)
+			}
+		case <-ticker.C:
+			if s.config.SingleChat <= 0 && s.config.GroupChat <= 0 {
+				continue
+			}
+			if n, err := s.ReapExpired(ctx); err != nil {
+				log.Printf("reap expired files error: %v", err)
+			} else if n > 0 {
+				log.Printf("expired %d chat files", n)
+			}
+		}
+	}
+}
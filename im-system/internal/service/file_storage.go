@@ -2,23 +2,26 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
-	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/pkg/archive"
+	"github.com/d60-lab/im-system/pkg/cdn"
+	"github.com/d60-lab/im-system/pkg/objectstore"
 	"github.com/d60-lab/im-system/pkg/util"
 	"github.com/go-redis/redis/v8"
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 	"gorm.io/gorm"
 )
 
@@ -32,6 +35,8 @@ var (
 	ErrInvalidUploadID     = errors.New("invalid upload id")
 	ErrPartNumberInvalid   = errors.New("invalid part number")
 	ErrMultipartIncomplete = errors.New("multipart upload incomplete")
+	ErrChecksumMismatch    = errors.New("file checksum mismatch")
+	ErrFileExpired         = errors.New("file expired")
 )
 
 // FileStorageService 文件存储服务接口
@@ -40,8 +45,13 @@ type FileStorageService interface {
 	Upload(ctx context.Context, req *UploadRequest) (*model.FileInfo, error)
 	Download(ctx context.Context, fileID string) (io.ReadCloser, *model.FileInfo, error)
 	Delete(ctx context.Context, fileID string) error
+	Expire(ctx context.Context, fileID string) error
 	GetFileInfo(ctx context.Context, fileID string) (*model.FileInfo, error)
-	GetFileURL(ctx context.Context, fileID string, expiry time.Duration) (string, error)
+	GetFileURL(ctx context.Context, fileID string, opts *FileURLOptions) (*model.SignedFileURL, error)
+
+	// 访问控制
+	VerifyFileURL(ctx context.Context, fileID string, req *FileURLVerifyRequest) error
+	RevokeFileURLs(ctx context.Context, fileID string) error
 
 	// 分片上传
 	InitMultipartUpload(ctx context.Context, req *model.InitMultipartUploadRequest, userID string) (*model.InitMultipartUploadResponse, error)
@@ -49,11 +59,25 @@ type FileStorageService interface {
 	CompleteMultipartUpload(ctx context.Context, uploadID string, parts []*model.PartInfo) (*model.FileInfo, error)
 	AbortMultipartUpload(ctx context.Context, uploadID string) error
 
+	// 断点续传
+	CreateUploadSession(ctx context.Context, req *model.CreateUploadSessionRequest, userID string) (*model.UploadSessionInfo, error)
+	WriteUploadSession(ctx context.Context, uploadID, userID string, offset int64, reader io.Reader) (*model.UploadSessionInfo, error)
+	GetUploadSession(ctx context.Context, uploadID, userID string) (*model.UploadSessionInfo, error)
+	FinalizeUploadSession(ctx context.Context, uploadID, userID string) (*model.FileInfo, error)
+	AbortUploadSession(ctx context.Context, uploadID, userID string) error
+	StartUploadSessionCleanup(ctx context.Context, interval time.Duration)
+
 	// 缩略图
 	GenerateThumbnail(ctx context.Context, fileID string, width, height int) (string, error)
 
 	// 秒传检测
 	CheckFileExists(ctx context.Context, md5Hash string) (*model.FileInfo, bool, error)
+
+	// SetFileTypePolicy 设置文件类型策略（未设置时使用默认全局策略）
+	SetFileTypePolicy(policy FileTypePolicyService)
+
+	// SetContentModeration 设置上传文件的内容审核（未设置时不审核）
+	SetContentModeration(moderation ContentModerationService)
 }
 
 // UploadRequest 上传请求
@@ -62,20 +86,32 @@ type UploadRequest struct {
 	Header      *multipart.FileHeader
 	UserID      string
 	ContentType string
+	GroupID     string // 上传到群聊时的群组ID，用于应用群组文件类型策略
+
+	// 客户端期望的校验值（可选，十六进制），不匹配时拒绝上传
+	ExpectedSHA256 string
+	ExpectedMD5    string
+
+	// SkipTypeCheck 跳过文件类型策略（仅用于服务端生成的文件，如会话导出）
+	SkipTypeCheck bool
 }
 
 // StorageConfig 存储配置
 type StorageConfig struct {
-	Provider    string // minio, aliyun, aws, qiniu
+	Provider    string // 存储后端：minio、aws、aliyun、local（见 objectstore.Provider*）
 	Endpoint    string
 	AccessKey   string
 	SecretKey   string
 	Bucket      string
 	Region      string
+	DataRegion  string // 数据驻留区域（写入文件记录和上传会话），为空表示默认区域
 	UseSSL      bool
 	CDNDomain   string
 	MaxFileSize int64 // 最大文件大小（字节）
 
+	// 本地磁盘存储的根目录（Provider 为 local 时使用）
+	LocalDir string
+
 	// 文件大小限制
 	MaxImageSize int64
 	MaxVideoSize int64
@@ -86,6 +122,26 @@ type StorageConfig struct {
 
 	// 签名URL过期时间
 	SignedURLExpiry time.Duration
+
+	// 断点续传会话有效期（每次写入后顺延）
+	UploadSessionTTL time.Duration
+
+	// 访问控制策略（为空使用默认策略）
+	AccessControl *model.FileAccessControl
+
+	// 代理下载URL签名密钥与路径前缀
+	URLSigningSecret string
+	ProxyURLPrefix   string
+
+	// CDN URL签名（配置CDNDomain时生效，为空表示不签名）
+	CDNSign *cdn.Config
+
+	// 压缩包安全检查阈值（为空使用默认阈值）
+	ArchiveLimits *archive.Limits
+	// 拒绝无法检查的压缩包（如7z、头部加密的rar），默认放行
+	RejectUninspectedArchives bool
+	// 不在文件信息中记录压缩包文件列表
+	HideArchiveContents bool
 }
 
 // DefaultStorageConfig 默认存储配置
@@ -104,18 +160,27 @@ func DefaultStorageConfig() *StorageConfig {
 		MaxAudioSize:    20 * 1024 * 1024,  // 20MB
 		ChunkSize:       5 * 1024 * 1024,   // 5MB
 		SignedURLExpiry: 2 * time.Hour,
+		AccessControl:   model.DefaultFileAccessControl(),
+		ProxyURLPrefix:  "/api/file/proxy",
+
+		UploadSessionTTL: 24 * time.Hour,
 	}
 }
 
-// minioStorageService MinIO存储服务实现
-type minioStorageService struct {
+// objectStorageService 对象存储文件服务实现
+type objectStorageService struct {
 	config    *StorageConfig
-	client    *minio.Client
+	store     objectstore.Store
 	db        *gorm.DB
 	redis     *redis.Client
 	cdnDomain string
+	cdnSigner cdn.Signer
+	policy    FileTypePolicyService
+
+	moderation ContentModerationService
 
 	// 分片上传信息缓存
+	multipartMu      sync.Mutex
 	multipartUploads map[string]*MultipartUploadState
 }
 
@@ -127,57 +192,58 @@ type MultipartUploadState struct {
 	FileSize    int64
 	ContentType string
 	UserID      string
+	GroupID     string
 	ObjectPath  string
 	TotalParts  int
 	ChunkSize   int64
 	Parts       map[int]*model.PartInfo
 	CreatedAt   time.Time
+
+	// 客户端期望的校验值
+	ExpectedSHA256 string
+	ExpectedMD5    string
+
+	MultipartID string // 对象存储的分片上传ID
 }
 
-// NewMinioStorageService 创建MinIO存储服务
-func NewMinioStorageService(config *StorageConfig, db *gorm.DB, redisClient *redis.Client) (FileStorageService, error) {
+// NewObjectStorageService 创建对象存储文件服务，按 config.Provider 选择存储后端
+func NewObjectStorageService(config *StorageConfig, db *gorm.DB, redisClient *redis.Client) (FileStorageService, error) {
 	if config == nil {
 		config = DefaultStorageConfig()
 	}
 
-	// 创建MinIO客户端
-	client, err := minio.New(config.Endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
-		Secure: config.UseSSL,
-		Region: config.Region,
-	})
+	cdnSigner, err := cdn.NewSigner(config.CDNSign)
 	if err != nil {
-		return nil, fmt.Errorf("create minio client error: %w", err)
-	}
-
-	// 检查桶是否存在，不存在则创建
-	ctx := context.Background()
-	exists, err := client.BucketExists(ctx, config.Bucket)
+		return nil, fmt.Errorf("create cdn signer error: %w", err)
+	}
+
+	store, err := objectstore.New(context.Background(), &objectstore.Config{
+		Provider:  config.Provider,
+		Endpoint:  config.Endpoint,
+		AccessKey: config.AccessKey,
+		SecretKey: config.SecretKey,
+		Bucket:    config.Bucket,
+		Region:    config.Region,
+		UseSSL:    config.UseSSL,
+		Dir:       config.LocalDir,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("check bucket exists error: %w", err)
+		return nil, fmt.Errorf("create object store error: %w", err)
 	}
 
-	if !exists {
-		err = client.MakeBucket(ctx, config.Bucket, minio.MakeBucketOptions{
-			Region: config.Region,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("create bucket error: %w", err)
-		}
-	}
-
-	return &minioStorageService{
+	return &objectStorageService{
 		config:           config,
-		client:           client,
+		store:            store,
 		db:               db,
 		redis:            redisClient,
-		cdnDomain:        config.CDNDomain,
+		cdnDomain:        strings.TrimSuffix(config.CDNDomain, "/"),
+		cdnSigner:        cdnSigner,
 		multipartUploads: make(map[string]*MultipartUploadState),
 	}, nil
 }
 
 // Upload 上传文件
-func (s *minioStorageService) Upload(ctx context.Context, req *UploadRequest) (*model.FileInfo, error) {
+func (s *objectStorageService) Upload(ctx context.Context, req *UploadRequest) (*model.FileInfo, error) {
 	if req.File == nil || req.Header == nil {
 		return nil, errors.New("file is required")
 	}
@@ -202,24 +268,57 @@ func (s *minioStorageService) Upload(ctx context.Context, req *UploadRequest) (*
 		return nil, err
 	}
 
-	// 计算MD5
-	hash := md5.New()
-	teeReader := io.TeeReader(req.File, hash)
+	// 内容审核（服务端生成的文件不审核）
+	if !req.SkipTypeCheck {
+		name, err := s.moderateFile(ctx, req.UserID, req.GroupID, fileName, contentType, fileSize)
+		if err != nil {
+			return nil, err
+		}
+		fileName = name
+	}
+
+	// 读取文件头校验文件类型，再拼回读取流
+	var reader io.Reader = req.File
+	var archiveInfo *model.ArchiveInfo
+	if !req.SkipTypeCheck {
+		head := make([]byte, FileSniffLength)
+		n, err := io.ReadFull(req.File, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("read file error: %w", err)
+		}
+		head = head[:n]
+		if err := s.checkFileType(ctx, req.GroupID, fileName, head); err != nil {
+			return nil, err
+		}
+		// 压缩包在写入存储前检查（只读取目录，不改变读取位置）
+		archiveInfo, err = s.checkArchive(req.File, fileSize, fileName, head)
+		if err != nil {
+			return nil, err
+		}
+		reader = io.MultiReader(bytes.NewReader(head), req.File)
+	}
+
+	// 同时计算MD5和SHA-256
+	md5Hasher := md5.New()
+	sha256Hasher := sha256.New()
+	teeReader := io.TeeReader(reader, io.MultiWriter(md5Hasher, sha256Hasher))
 
 	// 生成文件ID和存储路径
 	fileID := util.GenerateFileID()
 	objectPath := s.generateObjectPath(fileID, fileExt)
 
-	// 上传到MinIO
-	_, err := s.client.PutObject(ctx, s.config.Bucket, objectPath, teeReader, fileSize, minio.PutObjectOptions{
-		ContentType: contentType,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("upload to minio error: %w", err)
+	// 上传到对象存储
+	if err := s.store.Put(ctx, objectPath, teeReader, fileSize, contentType); err != nil {
+		return nil, fmt.Errorf("upload object error: %w", err)
 	}
 
-	// 计算MD5值
-	md5Hash := hex.EncodeToString(hash.Sum(nil))
+	// 校验客户端提供的摘要，不匹配时删除已上传对象
+	md5Hash := hex.EncodeToString(md5Hasher.Sum(nil))
+	sha256Hash := hex.EncodeToString(sha256Hasher.Sum(nil))
+	if err := verifyChecksum(req.ExpectedSHA256, sha256Hash, req.ExpectedMD5, md5Hash); err != nil {
+		s.store.Remove(ctx, objectPath)
+		return nil, err
+	}
 
 	// 获取文件URL
 	fileURL := s.buildFileURL(objectPath)
@@ -242,13 +341,16 @@ func (s *minioStorageService) Upload(ctx context.Context, req *UploadRequest) (*
 		StoragePath:   objectPath,
 		ThumbnailPath: thumbnailURL,
 		MD5:           md5Hash,
+		SHA256:        sha256Hash,
 		Status:        model.FileStatusNormal,
 		CreatedAt:     time.Now(),
+		ArchiveInfo:   archiveInfo,
+		Region:        s.config.DataRegion,
 	}
 
 	if err := s.db.WithContext(ctx).Create(fileRecord).Error; err != nil {
 		// 上传成功但记录失败，尝试删除文件
-		s.client.RemoveObject(ctx, s.config.Bucket, objectPath, minio.RemoveObjectOptions{})
+		s.store.Remove(ctx, objectPath)
 		return nil, fmt.Errorf("save file record error: %w", err)
 	}
 
@@ -265,17 +367,22 @@ func (s *minioStorageService) Upload(ctx context.Context, req *UploadRequest) (*
 		URL:          fileURL,
 		ThumbnailURL: thumbnailURL,
 		MD5:          md5Hash,
+		SHA256:       sha256Hash,
 		UploadedAt:   time.Now(),
+		Archive:      archiveInfo,
 	}, nil
 }
 
 // Download 下载文件
-func (s *minioStorageService) Download(ctx context.Context, fileID string) (io.ReadCloser, *model.FileInfo, error) {
+func (s *objectStorageService) Download(ctx context.Context, fileID string) (io.ReadCloser, *model.FileInfo, error) {
 	// 获取文件信息
 	fileInfo, err := s.GetFileInfo(ctx, fileID)
 	if err != nil {
 		return nil, nil, err
 	}
+	if fileInfo.Expired {
+		return nil, nil, ErrFileExpired
+	}
 
 	// 从数据库获取存储路径
 	var file model.File
@@ -283,8 +390,8 @@ func (s *minioStorageService) Download(ctx context.Context, fileID string) (io.R
 		return nil, nil, ErrFileNotFound
 	}
 
-	// 从MinIO获取文件
-	object, err := s.client.GetObject(ctx, s.config.Bucket, file.StoragePath, minio.GetObjectOptions{})
+	// 从对象存储获取文件
+	object, err := s.store.Get(ctx, file.StoragePath)
 	if err != nil {
 		return nil, nil, fmt.Errorf("get object error: %w", err)
 	}
@@ -293,7 +400,7 @@ func (s *minioStorageService) Download(ctx context.Context, fileID string) (io.R
 }
 
 // Delete 删除文件
-func (s *minioStorageService) Delete(ctx context.Context, fileID string) error {
+func (s *objectStorageService) Delete(ctx context.Context, fileID string) error {
 	// 获取文件信息
 	var file model.File
 	if err := s.db.WithContext(ctx).Where("file_id = ?", fileID).First(&file).Error; err != nil {
@@ -303,14 +410,14 @@ func (s *minioStorageService) Delete(ctx context.Context, fileID string) error {
 		return err
 	}
 
-	// 从MinIO删除文件
-	if err := s.client.RemoveObject(ctx, s.config.Bucket, file.StoragePath, minio.RemoveObjectOptions{}); err != nil {
+	// 从对象存储删除文件
+	if err := s.store.Remove(ctx, file.StoragePath); err != nil {
 		return fmt.Errorf("remove object error: %w", err)
 	}
 
 	// 删除缩略图（如果有）
 	if file.ThumbnailPath != "" {
-		s.client.RemoveObject(ctx, s.config.Bucket, file.ThumbnailPath, minio.RemoveObjectOptions{})
+		s.store.Remove(ctx, file.ThumbnailPath)
 	}
 
 	// 更新数据库状态
@@ -322,11 +429,47 @@ func (s *minioStorageService) Delete(ctx context.Context, fileID string) error {
 	cacheKey := fmt.Sprintf("file:info:%s", fileID)
 	s.redis.Del(ctx, cacheKey)
 
+	// 撤销已签发的代理下载URL
+	s.RevokeFileURLs(ctx, fileID)
+
+	return nil
+}
+
+// Expire 清理过期文件：删除对象，保留记录并标记为已过期，客户端据此展示"文件已过期"
+func (s *objectStorageService) Expire(ctx context.Context, fileID string) error {
+	var file model.File
+	if err := s.db.WithContext(ctx).Where("file_id = ? AND status = ?", fileID, model.FileStatusNormal).First(&file).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFileNotFound
+		}
+		return err
+	}
+
+	// 先按条件更新状态，避免与设置长期保存并发时误删
+	now := time.Now()
+	result := s.db.WithContext(ctx).Model(&model.File{}).
+		Where("file_id = ? AND status = ? AND retained = ?", fileID, model.FileStatusNormal, false).
+		Updates(map[string]interface{}{"status": model.FileStatusExpired, "expired_at": now})
+	if result.Error != nil {
+		return fmt.Errorf("update file status error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	if err := s.store.Remove(ctx, file.StoragePath); err != nil {
+		return fmt.Errorf("remove object error: %w", err)
+	}
+	if file.ThumbnailPath != "" {
+		s.store.Remove(ctx, file.ThumbnailPath)
+	}
+
+	s.redis.Del(ctx, fmt.Sprintf("file:info:%s", fileID))
 	return nil
 }
 
 // GetFileInfo 获取文件信息
-func (s *minioStorageService) GetFileInfo(ctx context.Context, fileID string) (*model.FileInfo, error) {
+func (s *objectStorageService) GetFileInfo(ctx context.Context, fileID string) (*model.FileInfo, error) {
 	// 先从Redis获取
 	cacheKey := fmt.Sprintf("file:info:%s", fileID)
 	cached, err := s.redis.Get(ctx, cacheKey).Result()
@@ -335,15 +478,31 @@ func (s *minioStorageService) GetFileInfo(ctx context.Context, fileID string) (*
 		// 这里简化处理，实际应该反序列化
 	}
 
-	// 从数据库获取
+	// 从数据库获取（已过期的文件仍返回元信息）
 	var file model.File
-	if err := s.db.WithContext(ctx).Where("file_id = ? AND status = ?", fileID, model.FileStatusNormal).First(&file).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("file_id = ? AND status IN ?", fileID,
+		[]model.FileStatus{model.FileStatusNormal, model.FileStatusExpired}).First(&file).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrFileNotFound
 		}
 		return nil, err
 	}
 
+	if file.Status == model.FileStatusExpired {
+		return &model.FileInfo{
+			FileID:     file.FileID,
+			FileName:   file.FileName,
+			FileSize:   file.FileSize,
+			FileExt:    file.FileExt,
+			MimeType:   file.MimeType,
+			FileType:   file.FileType,
+			UploadedAt: file.CreatedAt,
+			ExpiresAt:  file.ExpiresAt,
+			Expired:    true,
+			ExpiredAt:  file.ExpiredAt,
+		}, nil
+	}
+
 	fileInfo := &model.FileInfo{
 		FileID:       file.FileID,
 		FileName:     file.FileName,
@@ -357,7 +516,10 @@ func (s *minioStorageService) GetFileInfo(ctx context.Context, fileID string) (*
 		Height:       file.Height,
 		Duration:     file.Duration,
 		MD5:          file.MD5,
+		SHA256:       file.SHA256,
 		UploadedAt:   file.CreatedAt,
+		Archive:      file.ArchiveInfo,
+		ExpiresAt:    file.Expiry(),
 	}
 
 	// 缓存到Redis
@@ -366,48 +528,44 @@ func (s *minioStorageService) GetFileInfo(ctx context.Context, fileID string) (*
 	return fileInfo, nil
 }
 
-// GetFileURL 获取文件访问URL（带签名）
-func (s *minioStorageService) GetFileURL(ctx context.Context, fileID string, expiry time.Duration) (string, error) {
-	// 获取文件信息
-	var file model.File
-	if err := s.db.WithContext(ctx).Where("file_id = ?", fileID).First(&file).Error; err != nil {
-		return "", ErrFileNotFound
-	}
-
-	if expiry == 0 {
-		expiry = s.config.SignedURLExpiry
-	}
-
-	// 生成预签名URL
-	presignedURL, err := s.client.PresignedGetObject(ctx, s.config.Bucket, file.StoragePath, expiry, url.Values{})
-	if err != nil {
-		return "", fmt.Errorf("generate presigned url error: %w", err)
-	}
-
-	return presignedURL.String(), nil
-}
-
 // InitMultipartUpload 初始化分片上传
-func (s *minioStorageService) InitMultipartUpload(ctx context.Context, req *model.InitMultipartUploadRequest, userID string) (*model.InitMultipartUploadResponse, error) {
+func (s *objectStorageService) InitMultipartUpload(ctx context.Context, req *model.InitMultipartUploadRequest, userID string) (*model.InitMultipartUploadResponse, error) {
 	// 检查文件大小
 	fileExt := strings.ToLower(strings.TrimPrefix(filepath.Ext(req.FileName), "."))
 	fileType := model.GetFileTypeByExtension(fileExt)
 	if err := s.checkFileSize(fileType, req.FileSize); err != nil {
 		return nil, err
 	}
+	if err := s.checkFileType(ctx, req.GroupID, req.FileName, nil); err != nil {
+		return nil, err
+	}
+	fileName, err := s.moderateFile(ctx, userID, req.GroupID, req.FileName, req.ContentType, req.FileSize)
+	if err != nil {
+		return nil, err
+	}
+	req.FileName = fileName
 
 	// 生成文件ID和上传ID
 	fileID := util.GenerateFileID()
 	uploadID := util.GenerateUploadID()
 	objectPath := s.generateObjectPath(fileID, fileExt)
 
-	// 计算分片数量
+	// 计算分片数量（对象存储要求除最后一片外不小于5MB）
 	chunkSize := req.ChunkSize
 	if chunkSize == 0 {
 		chunkSize = s.config.ChunkSize
 	}
+	if chunkSize < minUploadPartSize {
+		chunkSize = minUploadPartSize
+	}
 	totalParts := int((req.FileSize + chunkSize - 1) / chunkSize)
 
+	// 创建对象存储的原生分片上传，分片直接写入最终对象，完成时由存储合并
+	multipartID, err := s.store.NewMultipartUpload(ctx, objectPath, req.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("create multipart upload error: %w", err)
+	}
+
 	// 保存上传状态
 	state := &MultipartUploadState{
 		UploadID:    uploadID,
@@ -416,13 +574,21 @@ func (s *minioStorageService) InitMultipartUpload(ctx context.Context, req *mode
 		FileSize:    req.FileSize,
 		ContentType: req.ContentType,
 		UserID:      userID,
+		GroupID:     req.GroupID,
 		ObjectPath:  objectPath,
 		TotalParts:  totalParts,
 		ChunkSize:   chunkSize,
 		Parts:       make(map[int]*model.PartInfo),
 		CreatedAt:   time.Now(),
+
+		ExpectedSHA256: strings.ToLower(req.SHA256),
+		ExpectedMD5:    strings.ToLower(req.MD5),
+
+		MultipartID: multipartID,
 	}
+	s.multipartMu.Lock()
 	s.multipartUploads[uploadID] = state
+	s.multipartMu.Unlock()
 
 	// 也缓存到Redis（用于分布式场景）
 	s.cacheMultipartState(ctx, uploadID, state)
@@ -436,9 +602,9 @@ func (s *minioStorageService) InitMultipartUpload(ctx context.Context, req *mode
 }
 
 // UploadPart 上传分片
-func (s *minioStorageService) UploadPart(ctx context.Context, uploadID string, partNumber int, reader io.Reader, size int64) (*model.PartInfo, error) {
+func (s *objectStorageService) UploadPart(ctx context.Context, uploadID string, partNumber int, reader io.Reader, size int64) (*model.PartInfo, error) {
 	// 获取上传状态
-	state, ok := s.multipartUploads[uploadID]
+	state, ok := s.multipartState(uploadID)
 	if !ok {
 		return nil, ErrInvalidUploadID
 	}
@@ -447,57 +613,67 @@ func (s *minioStorageService) UploadPart(ctx context.Context, uploadID string, p
 		return nil, ErrPartNumberInvalid
 	}
 
-	// 计算分片的MD5
-	hash := md5.New()
-	teeReader := io.TeeReader(reader, hash)
-
-	// 上传分片到临时路径
-	partPath := fmt.Sprintf("%s.part%d", state.ObjectPath, partNumber)
-	_, err := s.client.PutObject(ctx, s.config.Bucket, partPath, teeReader, size, minio.PutObjectOptions{})
+	// 上传分片，重复上传同一分片号时以最后一次为准
+	part, err := s.store.PutPart(ctx, state.ObjectPath, state.MultipartID, partNumber, reader, size)
 	if err != nil {
 		return nil, fmt.Errorf("upload part error: %w", err)
 	}
 
-	etag := hex.EncodeToString(hash.Sum(nil))
-
 	partInfo := &model.PartInfo{
 		PartNumber: partNumber,
-		ETag:       etag,
-		Size:       size,
+		ETag:       part.ETag,
+		Size:       part.Size,
 	}
 
 	// 更新状态
+	s.multipartMu.Lock()
 	state.Parts[partNumber] = partInfo
+	s.multipartMu.Unlock()
 
 	return partInfo, nil
 }
 
 // CompleteMultipartUpload 完成分片上传
-func (s *minioStorageService) CompleteMultipartUpload(ctx context.Context, uploadID string, parts []*model.PartInfo) (*model.FileInfo, error) {
+func (s *objectStorageService) CompleteMultipartUpload(ctx context.Context, uploadID string, parts []*model.PartInfo) (*model.FileInfo, error) {
 	// 获取上传状态
-	state, ok := s.multipartUploads[uploadID]
+	state, ok := s.multipartState(uploadID)
 	if !ok {
 		return nil, ErrInvalidUploadID
 	}
 
-	// 检查所有分片是否都已上传
-	if len(parts) != state.TotalParts {
-		return nil, ErrMultipartIncomplete
+	// 按服务端记录的分片合并：所有分片都须已上传，客户端提交的 ETag 须与记录一致
+	completeParts, totalSize, err := s.completeParts(state, parts)
+	if err != nil {
+		return nil, err
 	}
 
-	// 合并分片
-	// 注意：这里简化处理，实际应该使用MinIO的ComposeObject或类似功能
-	// 或者在上传时使用MinIO原生的分片上传API
+	// 由对象存储合并分片为最终对象
+	if err := s.store.CompleteMultipartUpload(ctx, state.ObjectPath, state.MultipartID, completeParts); err != nil {
+		return nil, fmt.Errorf("complete multipart upload error: %w", err)
+	}
+	s.removeMultipartState(ctx, uploadID)
 
-	// 创建最终对象（这里简化为复制第一个分片）
-	// 实际实现应该合并所有分片
-	var totalSize int64
-	for _, part := range parts {
-		totalSize += part.Size
+	// 合并后按文件头再次校验类型，防止声明的文件名与实际内容不符
+	if err := s.checkObjectType(ctx, state.GroupID, state.FileName, state.ObjectPath); err != nil {
+		s.store.Remove(ctx, state.ObjectPath)
+		return nil, err
+	}
+	archiveInfo, err := s.checkObjectArchive(ctx, state.ObjectPath, state.FileName, totalSize)
+	if err != nil {
+		s.store.Remove(ctx, state.ObjectPath)
+		return nil, err
 	}
 
-	// 计算整体MD5
-	md5Hash := "" // 实际应该计算合并后文件的MD5
+	// 读取合并后的对象计算整体摘要（分片上传的 ETag 不是整体MD5）
+	md5Hash, sha256Hash, err := s.computeObjectDigests(ctx, state.ObjectPath)
+	if err != nil {
+		s.store.Remove(ctx, state.ObjectPath)
+		return nil, err
+	}
+	if err := verifyChecksum(state.ExpectedSHA256, sha256Hash, state.ExpectedMD5, md5Hash); err != nil {
+		s.store.Remove(ctx, state.ObjectPath)
+		return nil, err
+	}
 
 	// 构建文件URL
 	fileURL := s.buildFileURL(state.ObjectPath)
@@ -522,23 +698,18 @@ func (s *minioStorageService) CompleteMultipartUpload(ctx context.Context, uploa
 		StoragePath:   state.ObjectPath,
 		ThumbnailPath: thumbnailURL,
 		MD5:           md5Hash,
+		SHA256:        sha256Hash,
 		Status:        model.FileStatusNormal,
 		CreatedAt:     time.Now(),
+		ArchiveInfo:   archiveInfo,
+		Region:        s.config.DataRegion,
 	}
 
 	if err := s.db.WithContext(ctx).Create(fileRecord).Error; err != nil {
+		s.store.Remove(ctx, state.ObjectPath)
 		return nil, fmt.Errorf("save file record error: %w", err)
 	}
-
-	// 清理分片文件
-	for i := 1; i <= state.TotalParts; i++ {
-		partPath := fmt.Sprintf("%s.part%d", state.ObjectPath, i)
-		s.client.RemoveObject(ctx, s.config.Bucket, partPath, minio.RemoveObjectOptions{})
-	}
-
-	// 清理上传状态
-	delete(s.multipartUploads, uploadID)
-	s.redis.Del(ctx, fmt.Sprintf("multipart:%s", uploadID))
+	s.cacheFileInfo(ctx, state.FileID, fileRecord)
 
 	return &model.FileInfo{
 		FileID:       state.FileID,
@@ -550,33 +721,77 @@ func (s *minioStorageService) CompleteMultipartUpload(ctx context.Context, uploa
 		URL:          fileURL,
 		ThumbnailURL: thumbnailURL,
 		MD5:          md5Hash,
-		UploadedAt:   time.Now(),
+		SHA256:       sha256Hash,
+		UploadedAt:   fileRecord.CreatedAt,
+		Archive:      archiveInfo,
 	}, nil
 }
 
 // AbortMultipartUpload 取消分片上传
-func (s *minioStorageService) AbortMultipartUpload(ctx context.Context, uploadID string) error {
+func (s *objectStorageService) AbortMultipartUpload(ctx context.Context, uploadID string) error {
 	// 获取上传状态
-	state, ok := s.multipartUploads[uploadID]
+	state, ok := s.multipartState(uploadID)
 	if !ok {
 		return ErrInvalidUploadID
 	}
 
-	// 删除已上传的分片
-	for i := 1; i <= state.TotalParts; i++ {
-		partPath := fmt.Sprintf("%s.part%d", state.ObjectPath, i)
-		s.client.RemoveObject(ctx, s.config.Bucket, partPath, minio.RemoveObjectOptions{})
+	// 取消对象存储的分片上传，已上传的分片由存储释放
+	if err := s.store.AbortMultipartUpload(ctx, state.ObjectPath, state.MultipartID); err != nil {
+		return fmt.Errorf("abort multipart upload error: %w", err)
 	}
 
 	// 清理上传状态
-	delete(s.multipartUploads, uploadID)
-	s.redis.Del(ctx, fmt.Sprintf("multipart:%s", uploadID))
+	s.removeMultipartState(ctx, uploadID)
 
 	return nil
 }
 
+// completeParts 按服务端记录的分片生成合并列表，校验分片齐全、ETag 一致且总大小与声明相符
+func (s *objectStorageService) completeParts(state *MultipartUploadState, parts []*model.PartInfo) ([]objectstore.Part, int64, error) {
+	s.multipartMu.Lock()
+	defer s.multipartMu.Unlock()
+
+	if len(parts) != state.TotalParts || len(state.Parts) != state.TotalParts {
+		return nil, 0, ErrMultipartIncomplete
+	}
+	for _, part := range parts {
+		uploaded, ok := state.Parts[part.PartNumber]
+		if !ok || (part.ETag != "" && !strings.EqualFold(strings.Trim(part.ETag, "\""), uploaded.ETag)) {
+			return nil, 0, ErrMultipartIncomplete
+		}
+	}
+
+	completeParts := make([]objectstore.Part, 0, state.TotalParts)
+	var totalSize int64
+	for i := 1; i <= state.TotalParts; i++ {
+		part := state.Parts[i]
+		completeParts = append(completeParts, objectstore.Part{PartNumber: part.PartNumber, ETag: part.ETag, Size: part.Size})
+		totalSize += part.Size
+	}
+	if totalSize != state.FileSize {
+		return nil, 0, ErrMultipartIncomplete
+	}
+	return completeParts, totalSize, nil
+}
+
+// multipartState 获取分片上传状态
+func (s *objectStorageService) multipartState(uploadID string) (*MultipartUploadState, bool) {
+	s.multipartMu.Lock()
+	defer s.multipartMu.Unlock()
+	state, ok := s.multipartUploads[uploadID]
+	return state, ok
+}
+
+// removeMultipartState 清理分片上传状态
+func (s *objectStorageService) removeMultipartState(ctx context.Context, uploadID string) {
+	s.multipartMu.Lock()
+	delete(s.multipartUploads, uploadID)
+	s.multipartMu.Unlock()
+	s.redis.Del(ctx, fmt.Sprintf("multipart:%s", uploadID))
+}
+
 // GenerateThumbnail 生成缩略图
-func (s *minioStorageService) GenerateThumbnail(ctx context.Context, fileID string, width, height int) (string, error) {
+func (s *objectStorageService) GenerateThumbnail(ctx context.Context, fileID string, width, height int) (string, error) {
 	// 这里应该实现实际的缩略图生成逻辑
 	// 可以使用图像处理库如 github.com/disintegration/imaging
 	// 或者调用外部服务
@@ -590,9 +805,10 @@ func (s *minioStorageService) GenerateThumbnail(ctx context.Context, fileID stri
 }
 
 // CheckFileExists 检查文件是否存在（用于秒传）
-func (s *minioStorageService) CheckFileExists(ctx context.Context, md5Hash string) (*model.FileInfo, bool, error) {
+func (s *objectStorageService) CheckFileExists(ctx context.Context, md5Hash string) (*model.FileInfo, bool, error) {
+	// 只秒传本区域存储的文件
 	var file model.File
-	if err := s.db.WithContext(ctx).Where("md5 = ? AND status = ?", md5Hash, model.FileStatusNormal).First(&file).Error; err != nil {
+	if err := s.db.WithContext(ctx).Where("md5 = ? AND status = ? AND COALESCE(region, '') = ?", md5Hash, model.FileStatusNormal, s.config.DataRegion).First(&file).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, false, nil
 		}
@@ -609,37 +825,137 @@ func (s *minioStorageService) CheckFileExists(ctx context.Context, md5Hash strin
 		URL:          s.buildFileURL(file.StoragePath),
 		ThumbnailURL: file.ThumbnailPath,
 		MD5:          file.MD5,
+		SHA256:       file.SHA256,
 		UploadedAt:   file.CreatedAt,
+		Archive:      file.ArchiveInfo,
 	}
 
 	return fileInfo, true, nil
 }
 
+// SetFileTypePolicy 设置文件类型策略
+func (s *objectStorageService) SetFileTypePolicy(policy FileTypePolicyService) {
+	s.policy = policy
+}
+
+// SetContentModeration 设置上传文件的内容审核
+func (s *objectStorageService) SetContentModeration(moderation ContentModerationService) {
+	s.moderation = moderation
+}
+
 // 辅助方法
 
+// moderateFile 审核上传文件，返回处置后的文件名
+func (s *objectStorageService) moderateFile(ctx context.Context, userID, groupID, fileName, contentType string, size int64) (string, error) {
+	if s.moderation == nil {
+		return fileName, nil
+	}
+	return s.moderation.CheckFile(ctx, userID, groupID, fileName, contentType, size)
+}
+
+// checkFileType 校验文件类型，head为nil时只按文件名检查
+func (s *objectStorageService) checkFileType(ctx context.Context, groupID, fileName string, head []byte) error {
+	if s.policy != nil {
+		return s.policy.CheckContent(ctx, groupID, fileName, head)
+	}
+
+	ext := fileExtension(fileName)
+	if err := checkFileTypePolicy(DefaultFileTypePolicy(), ext, head); err != nil {
+		return err
+	}
+	if head != nil {
+		return verifyFileContent(ext, head)
+	}
+	return nil
+}
+
+// checkObjectType 读取已上传对象的文件头校验文件类型
+func (s *objectStorageService) checkObjectType(ctx context.Context, groupID, fileName, objectPath string) error {
+	object, err := s.store.GetRange(ctx, objectPath, 0, FileSniffLength)
+	if err != nil {
+		return fmt.Errorf("get object error: %w", err)
+	}
+	defer object.Close()
+
+	head, err := io.ReadAll(io.LimitReader(object, FileSniffLength))
+	if err != nil {
+		return fmt.Errorf("read object error: %w", err)
+	}
+	return s.checkFileType(ctx, groupID, fileName, head)
+}
+
 // generateObjectPath 生成对象存储路径
-func (s *minioStorageService) generateObjectPath(fileID, ext string) string {
+func (s *objectStorageService) generateObjectPath(fileID, ext string) string {
 	now := time.Now()
 	// 按日期分目录存储
 	return fmt.Sprintf("%d/%02d/%02d/%s.%s", now.Year(), now.Month(), now.Day(), fileID, ext)
 }
 
-// buildFileURL 构建文件URL
-func (s *minioStorageService) buildFileURL(objectPath string) string {
+// buildFileURL 构建文件URL（本地磁盘存储没有直接访问地址，返回空，通过 GetFileURL 获取代理下载URL）
+func (s *objectStorageService) buildFileURL(objectPath string) string {
 	if s.cdnDomain != "" {
-		return fmt.Sprintf("%s/%s", s.cdnDomain, objectPath)
+		fileURL, _ := s.buildCDNURL(objectPath, s.cdnExpiry())
+		return fileURL
+	}
+
+	return s.store.URL(objectPath)
+}
+
+// cdnExpiry CDN签名URL有效期
+func (s *objectStorageService) cdnExpiry() time.Duration {
+	if s.config.CDNSign != nil && s.config.CDNSign.Expiry > 0 {
+		return s.config.CDNSign.Expiry
+	}
+	return s.config.SignedURLExpiry
+}
+
+// buildCDNURL 构建CDN文件URL，配置了签名时追加签名参数，返回URL及过期时间（未签名时为0）
+func (s *objectStorageService) buildCDNURL(objectPath string, expiry time.Duration) (string, int64) {
+	fileURL := fmt.Sprintf("%s/%s", s.cdnDomain, objectPath)
+	if s.cdnSigner == nil {
+		return fileURL, 0
+	}
+
+	expireAt := time.Now().Add(expiry)
+	signed, err := s.cdnSigner.Sign(fileURL, expireAt)
+	if err != nil {
+		// 签名失败时不返回未签名的URL，避免私有文件被公开访问
+		fmt.Printf("sign cdn url error: %v\n", err)
+		return "", 0
+	}
+	return signed, expireAt.Unix()
+}
+
+// computeObjectDigests 读取对象计算MD5和SHA-256
+func (s *objectStorageService) computeObjectDigests(ctx context.Context, objectPath string) (string, string, error) {
+	object, err := s.store.Get(ctx, objectPath)
+	if err != nil {
+		return "", "", fmt.Errorf("get object error: %w", err)
 	}
+	defer object.Close()
 
-	protocol := "http"
-	if s.config.UseSSL {
-		protocol = "https"
+	md5Hasher := md5.New()
+	sha256Hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(md5Hasher, sha256Hasher), object); err != nil {
+		return "", "", fmt.Errorf("read object error: %w", err)
 	}
 
-	return fmt.Sprintf("%s://%s/%s/%s", protocol, s.config.Endpoint, s.config.Bucket, objectPath)
+	return hex.EncodeToString(md5Hasher.Sum(nil)), hex.EncodeToString(sha256Hasher.Sum(nil)), nil
+}
+
+// verifyChecksum 校验客户端期望的摘要（为空表示不校验）
+func verifyChecksum(expectedSHA256, actualSHA256, expectedMD5, actualMD5 string) error {
+	if expectedSHA256 != "" && !strings.EqualFold(expectedSHA256, actualSHA256) {
+		return fmt.Errorf("%w: sha256 expected %s, got %s", ErrChecksumMismatch, expectedSHA256, actualSHA256)
+	}
+	if expectedMD5 != "" && !strings.EqualFold(expectedMD5, actualMD5) {
+		return fmt.Errorf("%w: md5 expected %s, got %s", ErrChecksumMismatch, expectedMD5, actualMD5)
+	}
+	return nil
 }
 
 // checkFileSize 检查文件大小
-func (s *minioStorageService) checkFileSize(fileType model.FileType, size int64) error {
+func (s *objectStorageService) checkFileSize(fileType model.FileType, size int64) error {
 	var maxSize int64
 
 	switch fileType {
@@ -661,14 +977,14 @@ func (s *minioStorageService) checkFileSize(fileType model.FileType, size int64)
 }
 
 // cacheFileInfo 缓存文件信息到Redis
-func (s *minioStorageService) cacheFileInfo(ctx context.Context, fileID string, file *model.File) {
+func (s *objectStorageService) cacheFileInfo(ctx context.Context, fileID string, file *model.File) {
 	cacheKey := fmt.Sprintf("file:info:%s", fileID)
 	// 简化处理，实际应该序列化整个对象
 	s.redis.Set(ctx, cacheKey, file.StoragePath, 24*time.Hour)
 }
 
 // cacheMultipartState 缓存分片上传状态
-func (s *minioStorageService) cacheMultipartState(ctx context.Context, uploadID string, state *MultipartUploadState) {
+func (s *objectStorageService) cacheMultipartState(ctx context.Context, uploadID string, state *MultipartUploadState) {
 	cacheKey := fmt.Sprintf("multipart:%s", uploadID)
 	// 简化处理，实际应该序列化整个对象
 	s.redis.Set(ctx, cacheKey, state.FileID, 24*time.Hour)
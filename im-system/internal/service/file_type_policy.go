@@ -0,0 +1,412 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// 文件类型策略错误定义
+var (
+	ErrFileContentMismatch = errors.New("file content does not match its extension")
+	ErrFilePolicyInvalid   = errors.New("invalid file type policy")
+)
+
+const (
+	// fileTypePolicyKey 全局文件类型策略
+	fileTypePolicyKey = "im:file_policy"
+	// groupFileTypePolicyKeyPrefix 群组文件类型策略
+	groupFileTypePolicyKeyPrefix = "im:file_policy:group:"
+	// fileTypePolicyCacheTTL 本地缓存策略的时间，避免每次上传都访问Redis
+	fileTypePolicyCacheTTL = 5 * time.Second
+	// FileSniffLength 内容嗅探读取的文件头长度
+	FileSniffLength = 512
+)
+
+// fileExtPattern 扩展名格式
+var fileExtPattern = regexp.MustCompile(`^[a-z0-9]{1,16}$`)
+
+// executableExtensions 可执行文件及脚本扩展名
+var executableExtensions = map[string]bool{
+	"exe": true, "dll": true, "com": true, "bat": true, "cmd": true, "msi": true, "scr": true,
+	"ps1": true, "vbs": true, "js": true, "jar": true, "apk": true, "app": true, "dmg": true,
+	"deb": true, "rpm": true, "sh": true, "bin": true, "elf": true, "so": true, "dylib": true,
+}
+
+// FileTypePolicyService 文件类型策略服务接口
+type FileTypePolicyService interface {
+	// GetPolicy 获取全局策略
+	GetPolicy(ctx context.Context) (*model.FileTypePolicy, error)
+	// SetPolicy 设置全局策略（管理员）
+	SetPolicy(ctx context.Context, operatorID string, req *model.SetFileTypePolicyRequest) (*model.FileTypePolicy, error)
+	// GetGroupPolicy 获取群组策略（群成员），未设置时返回nil
+	GetGroupPolicy(ctx context.Context, groupID, userID string) (*model.FileTypePolicy, error)
+	// SetGroupPolicy 设置群组策略（群主或管理员）
+	SetGroupPolicy(ctx context.Context, groupID, operatorID string, req *model.SetFileTypePolicyRequest) (*model.FileTypePolicy, error)
+	// DeleteGroupPolicy 删除群组策略，恢复为仅受全局策略约束（群主或管理员）
+	DeleteGroupPolicy(ctx context.Context, groupID, operatorID string) error
+
+	// CheckName 按文件名检查（分片上传、断点续传创建时使用，此时还没有文件内容）
+	CheckName(ctx context.Context, groupID, fileName string) error
+	// CheckContent 按文件名和文件头检查，head为文件前FileSniffLength字节
+	CheckContent(ctx context.Context, groupID, fileName string, head []byte) error
+}
+
+// cachedFileTypePolicy 本地缓存的策略（policy为nil表示未设置）
+type cachedFileTypePolicy struct {
+	policy    *model.FileTypePolicy
+	expiresAt time.Time
+}
+
+// fileTypePolicyServiceImpl 文件类型策略服务实现
+type fileTypePolicyServiceImpl struct {
+	redis        *redis.Client
+	groupService GroupService
+
+	mu    sync.RWMutex
+	cache map[string]*cachedFileTypePolicy // key为群组ID，全局策略为空字符串
+}
+
+// NewFileTypePolicyService 创建文件类型策略服务
+func NewFileTypePolicyService(redisClient *redis.Client, groupService GroupService) FileTypePolicyService {
+	return &fileTypePolicyServiceImpl{
+		redis:        redisClient,
+		groupService: groupService,
+		cache:        make(map[string]*cachedFileTypePolicy),
+	}
+}
+
+// DefaultFileTypePolicy 默认全局策略：AllowedFileTypes中的扩展名，拒绝可执行文件
+func DefaultFileTypePolicy() *model.FileTypePolicy {
+	exts := make([]string, 0, len(AllowedFileTypes))
+	for ext, allowed := range AllowedFileTypes {
+		if allowed {
+			exts = append(exts, ext)
+		}
+	}
+	sort.Strings(exts)
+	return &model.FileTypePolicy{Extensions: exts, DenyExecutables: true}
+}
+
+// GetPolicy 获取全局策略
+func (s *fileTypePolicyServiceImpl) GetPolicy(ctx context.Context) (*model.FileTypePolicy, error) {
+	policy, err := s.load(ctx, fileTypePolicyKey)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return DefaultFileTypePolicy(), nil
+	}
+	return policy, nil
+}
+
+// SetPolicy 设置全局策略
+func (s *fileTypePolicyServiceImpl) SetPolicy(ctx context.Context, operatorID string, req *model.SetFileTypePolicyRequest) (*model.FileTypePolicy, error) {
+	policy, err := buildFileTypePolicy(req, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, fileTypePolicyKey, policy); err != nil {
+		return nil, err
+	}
+	s.setCache("", policy)
+	return policy, nil
+}
+
+// GetGroupPolicy 获取群组策略
+func (s *fileTypePolicyServiceImpl) GetGroupPolicy(ctx context.Context, groupID, userID string) (*model.FileTypePolicy, error) {
+	isMember, err := s.groupService.IsMember(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotGroupMember
+	}
+	return s.load(ctx, groupFileTypePolicyKeyPrefix+groupID)
+}
+
+// SetGroupPolicy 设置群组策略
+func (s *fileTypePolicyServiceImpl) SetGroupPolicy(ctx context.Context, groupID, operatorID string, req *model.SetFileTypePolicyRequest) (*model.FileTypePolicy, error) {
+	if err := s.checkGroupAdmin(ctx, groupID, operatorID); err != nil {
+		return nil, err
+	}
+	policy, err := buildFileTypePolicy(req, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, groupFileTypePolicyKeyPrefix+groupID, policy); err != nil {
+		return nil, err
+	}
+	s.setCache(groupID, policy)
+	return policy, nil
+}
+
+// DeleteGroupPolicy 删除群组策略
+func (s *fileTypePolicyServiceImpl) DeleteGroupPolicy(ctx context.Context, groupID, operatorID string) error {
+	if err := s.checkGroupAdmin(ctx, groupID, operatorID); err != nil {
+		return err
+	}
+	if err := s.redis.Del(ctx, groupFileTypePolicyKeyPrefix+groupID).Err(); err != nil {
+		return fmt.Errorf("delete file policy error: %w", err)
+	}
+	s.setCache(groupID, nil)
+	return nil
+}
+
+// CheckName 按文件名检查
+func (s *fileTypePolicyServiceImpl) CheckName(ctx context.Context, groupID, fileName string) error {
+	return s.CheckContent(ctx, groupID, fileName, nil)
+}
+
+// CheckContent 按文件名和文件头检查：先校验全局策略，再校验群组策略，最后校验内容与扩展名是否一致
+func (s *fileTypePolicyServiceImpl) CheckContent(ctx context.Context, groupID, fileName string, head []byte) error {
+	ext := fileExtension(fileName)
+
+	global := s.cachedPolicy(ctx, "")
+	if global == nil {
+		global = DefaultFileTypePolicy()
+	}
+	if err := checkFileTypePolicy(global, ext, head); err != nil {
+		return err
+	}
+
+	if groupID != "" {
+		if groupPolicy := s.cachedPolicy(ctx, groupID); groupPolicy != nil {
+			if err := checkFileTypePolicy(groupPolicy, ext, head); err != nil {
+				return err
+			}
+		}
+	}
+
+	if head != nil {
+		return verifyFileContent(ext, head)
+	}
+	return nil
+}
+
+// checkGroupAdmin 检查操作者是否为群主或管理员
+func (s *fileTypePolicyServiceImpl) checkGroupAdmin(ctx context.Context, groupID, operatorID string) error {
+	role, err := s.groupService.GetMemberRole(ctx, groupID, operatorID)
+	if err != nil {
+		return err
+	}
+	if role < model.RoleAdmin {
+		return ErrNotGroupAdmin
+	}
+	return nil
+}
+
+// cachedPolicy 读取策略（带本地缓存），Redis异常时沿用过期的缓存，没有缓存时视为未设置
+func (s *fileTypePolicyServiceImpl) cachedPolicy(ctx context.Context, groupID string) *model.FileTypePolicy {
+	s.mu.RLock()
+	cached := s.cache[groupID]
+	s.mu.RUnlock()
+	if cached != nil && time.Now().Before(cached.expiresAt) {
+		return cached.policy
+	}
+
+	key := fileTypePolicyKey
+	if groupID != "" {
+		key = groupFileTypePolicyKeyPrefix + groupID
+	}
+	policy, err := s.load(ctx, key)
+	if err != nil {
+		if cached != nil {
+			return cached.policy
+		}
+		return nil
+	}
+	s.setCache(groupID, policy)
+	return policy
+}
+
+// setCache 更新本地缓存
+func (s *fileTypePolicyServiceImpl) setCache(groupID string, policy *model.FileTypePolicy) {
+	s.mu.Lock()
+	s.cache[groupID] = &cachedFileTypePolicy{policy: policy, expiresAt: time.Now().Add(fileTypePolicyCacheTTL)}
+	s.mu.Unlock()
+}
+
+// load 从Redis读取策略，未设置时返回nil
+func (s *fileTypePolicyServiceImpl) load(ctx context.Context, key string) (*model.FileTypePolicy, error) {
+	data, err := s.redis.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get file policy error: %w", err)
+	}
+
+	var policy model.FileTypePolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// save 保存策略到Redis
+func (s *fileTypePolicyServiceImpl) save(ctx context.Context, key string, policy *model.FileTypePolicy) error {
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	if err := s.redis.Set(ctx, key, data, 0).Err(); err != nil {
+		return fmt.Errorf("set file policy error: %w", err)
+	}
+	return nil
+}
+
+// buildFileTypePolicy 校验并规范化策略请求
+func buildFileTypePolicy(req *model.SetFileTypePolicyRequest, operatorID string) (*model.FileTypePolicy, error) {
+	policy := &model.FileTypePolicy{
+		Extensions:      []string{},
+		FileTypes:       []model.FileType{},
+		DenyExecutables: req.DenyExecutables,
+		UpdatedBy:       operatorID,
+		UpdatedAt:       time.Now(),
+	}
+
+	seenExt := make(map[string]bool)
+	for _, ext := range req.Extensions {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if !fileExtPattern.MatchString(ext) {
+			return nil, fmt.Errorf("%w: extension %q", ErrFilePolicyInvalid, ext)
+		}
+		if !seenExt[ext] {
+			seenExt[ext] = true
+			policy.Extensions = append(policy.Extensions, ext)
+		}
+	}
+	sort.Strings(policy.Extensions)
+
+	seenType := make(map[model.FileType]bool)
+	for _, fileType := range req.FileTypes {
+		if fileType < model.FileTypeImage || fileType > model.FileTypeOther {
+			return nil, fmt.Errorf("%w: file type %d", ErrFilePolicyInvalid, fileType)
+		}
+		if !seenType[fileType] {
+			seenType[fileType] = true
+			policy.FileTypes = append(policy.FileTypes, fileType)
+		}
+	}
+
+	return policy, nil
+}
+
+// fileExtension 获取小写扩展名（不含点）
+func fileExtension(fileName string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+}
+
+// checkFileTypePolicy 按单个策略检查扩展名、文件大类和可执行文件
+func checkFileTypePolicy(policy *model.FileTypePolicy, ext string, head []byte) error {
+	if !policy.AllowsExtension(ext) {
+		return fmt.Errorf("%w: extension %q is not allowed", ErrInvalidFileType, ext)
+	}
+	fileType := model.GetFileTypeByExtension(ext)
+	if !policy.AllowsType(fileType) {
+		return fmt.Errorf("%w: %s files are not allowed", ErrInvalidFileType, fileType)
+	}
+	if policy.DenyExecutables && (executableExtensions[ext] || isExecutableContent(head)) {
+		return fmt.Errorf("%w: executable files are not allowed", ErrInvalidFileType)
+	}
+	return nil
+}
+
+// isExecutableContent 根据文件头识别可执行文件（PE、ELF、Mach-O）
+func isExecutableContent(head []byte) bool {
+	if len(head) < 4 {
+		return false
+	}
+	if bytes.HasPrefix(head, []byte("MZ")) {
+		// DOS头至少64字节；PE签名在读取范围内时校验签名，避免误判以"MZ"开头的文本
+		if len(head) < 0x40 {
+			return false
+		}
+		offset := int(binary.LittleEndian.Uint32(head[0x3c:0x40]))
+		if offset >= 0x40 && offset+4 <= len(head) {
+			return bytes.Equal(head[offset:offset+4], []byte("PE\x00\x00"))
+		}
+		return true
+	}
+	if bytes.HasPrefix(head, []byte("\x7fELF")) {
+		return true
+	}
+	switch binary.BigEndian.Uint32(head[:4]) {
+	case 0xfeedface, 0xfeedfacf, 0xcefaedfe, 0xcffaedfe, 0xcafebabe:
+		return true
+	}
+	return false
+}
+
+// sniffedFileType 将嗅探到的MIME类型归入文件大类，无法识别时返回FileTypeOther
+func sniffedFileType(mimeType string) model.FileType {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return model.FileTypeImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return model.FileTypeVideo
+	case strings.HasPrefix(mimeType, "audio/"), mimeType == "application/ogg":
+		return model.FileTypeAudio
+	case mimeType == "application/pdf", mimeType == "application/postscript":
+		return model.FileTypeDocument
+	case mimeType == "application/zip", mimeType == "application/x-gzip", mimeType == "application/x-rar-compressed":
+		return model.FileTypeArchive
+	default:
+		return model.FileTypeOther
+	}
+}
+
+// verifyFileContent 校验文件头与扩展名是否一致，拒绝伪装成其他类型的文件
+func verifyFileContent(ext string, head []byte) error {
+	mimeType := http.DetectContentType(head)
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	declared := model.GetFileTypeByExtension(ext)
+	sniffed := sniffedFileType(mimeType)
+
+	// HTML只能以html/htm上传，避免伪装成图片或文档后被浏览器渲染
+	if mimeType == "text/html" && ext != "html" && ext != "htm" {
+		return fmt.Errorf("%w: html content uploaded as %q", ErrFileContentMismatch, ext)
+	}
+
+	// 位图格式都能被识别，内容不是图片即为伪装（SVG为文本格式，不做要求）
+	if declared == model.FileTypeImage && ext != "svg" && sniffed != model.FileTypeImage {
+		return fmt.Errorf("%w: %q is not an image (%s)", ErrFileContentMismatch, ext, mimeType)
+	}
+
+	// 无法识别的内容不做判断
+	if sniffed == model.FileTypeOther || declared == model.FileTypeOther || sniffed == declared {
+		return nil
+	}
+
+	switch {
+	case declared == model.FileTypeDocument && sniffed == model.FileTypeArchive:
+		// Office Open XML文档本身是zip
+		if ext == "docx" || ext == "xlsx" || ext == "pptx" {
+			return nil
+		}
+	case declared == model.FileTypeAudio && sniffed == model.FileTypeVideo:
+		// AAC等音频常封装在MP4容器中
+		return nil
+	case declared == model.FileTypeVideo && sniffed == model.FileTypeAudio:
+		// Ogg、WebM容器可能被识别为音频
+		return nil
+	}
+	return fmt.Errorf("%w: %q content looks like %s", ErrFileContentMismatch, ext, mimeType)
+}
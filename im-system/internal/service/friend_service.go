@@ -0,0 +1,504 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 好友错误定义
+var (
+	ErrFriendSelf            = errors.New("cannot add or block yourself")
+	ErrAlreadyFriends        = errors.New("already friends")
+	ErrNotFriends            = errors.New("not friends")
+	ErrFriendRequestNotFound = errors.New("friend request not found")
+	ErrFriendRequestHandled  = errors.New("friend request already handled")
+	ErrBlockedByUser         = errors.New("blocked by user")
+)
+
+// 好友缓存 Key 前缀（集合中的 cacheSetPlaceholder 表示已缓存，用于区分空集合与未缓存）
+const (
+	friendIDsKeyPrefix  = "friend:ids:"
+	blockedIDsKeyPrefix = "friend:blocked:"
+	cacheSetPlaceholder = "-"
+)
+
+// FriendConfig 好友配置
+type FriendConfig struct {
+	CacheTTL time.Duration // 好友及屏蔽列表缓存时间
+}
+
+// DefaultFriendConfig 默认好友配置
+func DefaultFriendConfig() *FriendConfig {
+	return &FriendConfig{
+		CacheTTL: 24 * time.Hour,
+	}
+}
+
+// SendFriendRequestRequest 发送好友申请请求
+type SendFriendRequestRequest struct {
+	UserID  string `json:"user_id" binding:"required"`
+	Message string `json:"message" binding:"max=256"`
+}
+
+// FriendRequestView 好友申请（含对方用户信息）
+type FriendRequestView struct {
+	*model.FriendRequest
+	User *model.UserInfo `json:"user,omitempty"` // 收到的申请为申请人，发出的申请为接收人；账号已注销时为空
+}
+
+// FriendView 好友（含用户信息）
+type FriendView struct {
+	*model.Friend
+	User *model.UserInfo `json:"user,omitempty"`
+}
+
+// BlockedUserView 屏蔽的用户（含用户信息）
+type BlockedUserView struct {
+	*model.UserBlock
+	User *model.UserInfo `json:"user,omitempty"`
+}
+
+// FriendService 好友服务
+type FriendService interface {
+	// SendRequest 发送好友申请；对方已向自己发出待处理的申请时直接成为好友
+	SendRequest(ctx context.Context, userID string, req *SendFriendRequestRequest) (*model.FriendRequest, error)
+
+	// AcceptRequest 通过好友申请（仅申请接收人），通知申请人
+	AcceptRequest(ctx context.Context, userID, requestID string) (*model.FriendRequest, error)
+
+	// RejectRequest 拒绝好友申请（仅申请接收人），不通知申请人
+	RejectRequest(ctx context.Context, userID, requestID string) error
+
+	// ListRequests 分页查询收到（outgoing 为 true 时为发出）的好友申请
+	ListRequests(ctx context.Context, userID string, outgoing bool, page, pageSize int) ([]*FriendRequestView, int64, error)
+
+	// ListFriends 查询好友列表
+	ListFriends(ctx context.Context, userID string) ([]*FriendView, error)
+
+	// RemoveFriend 删除好友（双向解除）
+	RemoveFriend(ctx context.Context, userID, friendID string) error
+
+	// IsFriend 是否为好友
+	IsFriend(ctx context.Context, userID, friendID string) (bool, error)
+
+	// Block 屏蔽用户：对方不能再发送好友申请和单聊消息，对方待处理的申请被拒绝
+	Block(ctx context.Context, userID, targetID string) error
+
+	// Unblock 取消屏蔽
+	Unblock(ctx context.Context, userID, targetID string) error
+
+	// ListBlocked 查询屏蔽列表
+	ListBlocked(ctx context.Context, userID string) ([]*BlockedUserView, error)
+
+	// IsBlocked blockerID 是否屏蔽了 userID
+	IsBlocked(ctx context.Context, blockerID, userID string) (bool, error)
+
+	// CheckMessage 发送前检查：单聊接收者屏蔽了发送者时拒绝
+	CheckMessage(ctx context.Context, msg *model.Message) error
+}
+
+// friendServiceImpl 好友服务实现
+type friendServiceImpl struct {
+	repo       repository.FriendRepository
+	users      repository.UserRepository
+	redis      *redis.Client
+	dispatcher MessageDispatcher
+	config     *FriendConfig
+}
+
+// NewFriendService 创建好友服务，dispatcher 为空时不发送通知
+func NewFriendService(repo repository.FriendRepository, users repository.UserRepository, redisClient *redis.Client, dispatcher MessageDispatcher, config *FriendConfig) FriendService {
+	if config == nil {
+		config = DefaultFriendConfig()
+	}
+	return &friendServiceImpl{
+		repo:       repo,
+		users:      users,
+		redis:      redisClient,
+		dispatcher: dispatcher,
+		config:     config,
+	}
+}
+
+// SendRequest 发送好友申请
+func (s *friendServiceImpl) SendRequest(ctx context.Context, userID string, req *SendFriendRequestRequest) (*model.FriendRequest, error) {
+	if req.UserID == userID {
+		return nil, ErrFriendSelf
+	}
+	target, err := s.activeUser(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := s.IsBlocked(ctx, target.UserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrBlockedByUser
+	}
+	isFriend, err := s.IsFriend(ctx, userID, target.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if isFriend {
+		return nil, ErrAlreadyFriends
+	}
+
+	// 对方已申请添加自己：直接通过对方的申请
+	reverse, err := s.repo.FindRequestBetween(ctx, target.UserID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find friend request error: %w", err)
+	}
+	if reverse != nil && reverse.Status == model.FriendRequestPending {
+		return s.AcceptRequest(ctx, userID, reverse.RequestID)
+	}
+
+	now := time.Now()
+	request := &model.FriendRequest{
+		RequestID:  util.GenerateFriendRequestID(),
+		FromUserID: userID,
+		ToUserID:   target.UserID,
+		Message:    req.Message,
+		Status:     model.FriendRequestPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.repo.SaveRequest(ctx, request); err != nil {
+		return nil, fmt.Errorf("save friend request error: %w", err)
+	}
+
+	sender, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find user error: %w", err)
+	}
+	content := &model.FriendRequestContent{
+		RequestID:  request.RequestID,
+		FromUserID: userID,
+		Message:    request.Message,
+	}
+	if sender != nil {
+		content.Nickname = sender.Nickname
+		content.Avatar = sender.Avatar
+	}
+	s.notify(ctx, target.UserID, model.MsgFriendRequest, content)
+	return request, nil
+}
+
+// AcceptRequest 通过好友申请
+func (s *friendServiceImpl) AcceptRequest(ctx context.Context, userID, requestID string) (*model.FriendRequest, error) {
+	request, err := s.pendingRequest(ctx, userID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.repo.TransitionRequest(ctx, requestID, model.FriendRequestPending, model.FriendRequestAccepted)
+	if err != nil {
+		return nil, fmt.Errorf("update friend request error: %w", err)
+	}
+	if !ok {
+		return nil, ErrFriendRequestHandled
+	}
+	if err := s.repo.AddFriend(ctx, request.FromUserID, request.ToUserID); err != nil {
+		return nil, fmt.Errorf("add friend error: %w", err)
+	}
+	s.invalidate(ctx, friendIDsKeyPrefix, request.FromUserID, request.ToUserID)
+	request.Status = model.FriendRequestAccepted
+
+	accepter, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find user error: %w", err)
+	}
+	content := &model.FriendAcceptContent{
+		RequestID: requestID,
+		UserID:    userID,
+	}
+	if accepter != nil {
+		content.Nickname = accepter.Nickname
+		content.Avatar = accepter.Avatar
+	}
+	s.notify(ctx, request.FromUserID, model.MsgFriendAccept, content)
+	return request, nil
+}
+
+// RejectRequest 拒绝好友申请
+func (s *friendServiceImpl) RejectRequest(ctx context.Context, userID, requestID string) error {
+	if _, err := s.pendingRequest(ctx, userID, requestID); err != nil {
+		return err
+	}
+
+	ok, err := s.repo.TransitionRequest(ctx, requestID, model.FriendRequestPending, model.FriendRequestRejected)
+	if err != nil {
+		return fmt.Errorf("update friend request error: %w", err)
+	}
+	if !ok {
+		return ErrFriendRequestHandled
+	}
+	return nil
+}
+
+// pendingRequest 查询发给用户的待处理申请
+func (s *friendServiceImpl) pendingRequest(ctx context.Context, userID, requestID string) (*model.FriendRequest, error) {
+	request, err := s.repo.FindRequest(ctx, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("find friend request error: %w", err)
+	}
+	if request == nil || request.ToUserID != userID {
+		return nil, ErrFriendRequestNotFound
+	}
+	if request.Status != model.FriendRequestPending {
+		return nil, ErrFriendRequestHandled
+	}
+	return request, nil
+}
+
+// ListRequests 分页查询好友申请
+func (s *friendServiceImpl) ListRequests(ctx context.Context, userID string, outgoing bool, page, pageSize int) ([]*FriendRequestView, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	requests, total, err := s.repo.ListRequests(ctx, userID, outgoing, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list friend requests error: %w", err)
+	}
+
+	otherIDs := make([]string, 0, len(requests))
+	for _, request := range requests {
+		if outgoing {
+			otherIDs = append(otherIDs, request.ToUserID)
+		} else {
+			otherIDs = append(otherIDs, request.FromUserID)
+		}
+	}
+	infos, err := s.userInfos(ctx, otherIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	views := make([]*FriendRequestView, 0, len(requests))
+	for i, request := range requests {
+		views = append(views, &FriendRequestView{FriendRequest: request, User: infos[otherIDs[i]]})
+	}
+	return views, total, nil
+}
+
+// ListFriends 查询好友列表
+func (s *friendServiceImpl) ListFriends(ctx context.Context, userID string) ([]*FriendView, error) {
+	friends, err := s.repo.ListFriends(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list friends error: %w", err)
+	}
+
+	friendIDs := make([]string, 0, len(friends))
+	for _, friend := range friends {
+		friendIDs = append(friendIDs, friend.FriendID)
+	}
+	infos, err := s.userInfos(ctx, friendIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*FriendView, 0, len(friends))
+	for _, friend := range friends {
+		views = append(views, &FriendView{Friend: friend, User: infos[friend.FriendID]})
+	}
+	return views, nil
+}
+
+// RemoveFriend 删除好友
+func (s *friendServiceImpl) RemoveFriend(ctx context.Context, userID, friendID string) error {
+	removed, err := s.repo.RemoveFriend(ctx, userID, friendID)
+	if err != nil {
+		return fmt.Errorf("remove friend error: %w", err)
+	}
+	if !removed {
+		return ErrNotFriends
+	}
+	s.invalidate(ctx, friendIDsKeyPrefix, userID, friendID)
+	return nil
+}
+
+// IsFriend 是否为好友
+func (s *friendServiceImpl) IsFriend(ctx context.Context, userID, friendID string) (bool, error) {
+	return s.cachedContains(ctx, friendIDsKeyPrefix, userID, friendID, s.repo.FindFriendIDs)
+}
+
+// Block 屏蔽用户
+func (s *friendServiceImpl) Block(ctx context.Context, userID, targetID string) error {
+	if userID == targetID {
+		return ErrFriendSelf
+	}
+	if _, err := s.activeUser(ctx, targetID); err != nil {
+		return err
+	}
+
+	if _, err := s.repo.Block(ctx, userID, targetID); err != nil {
+		return fmt.Errorf("block user error: %w", err)
+	}
+	s.invalidate(ctx, blockedIDsKeyPrefix, userID)
+
+	// 拒绝对方待处理的申请
+	request, err := s.repo.FindRequestBetween(ctx, targetID, userID)
+	if err != nil {
+		return fmt.Errorf("find friend request error: %w", err)
+	}
+	if request != nil && request.Status == model.FriendRequestPending {
+		if _, err := s.repo.TransitionRequest(ctx, request.RequestID, model.FriendRequestPending, model.FriendRequestRejected); err != nil {
+			return fmt.Errorf("update friend request error: %w", err)
+		}
+	}
+	return nil
+}
+
+// Unblock 取消屏蔽
+func (s *friendServiceImpl) Unblock(ctx context.Context, userID, targetID string) error {
+	if _, err := s.repo.Unblock(ctx, userID, targetID); err != nil {
+		return fmt.Errorf("unblock user error: %w", err)
+	}
+	s.invalidate(ctx, blockedIDsKeyPrefix, userID)
+	return nil
+}
+
+// ListBlocked 查询屏蔽列表
+func (s *friendServiceImpl) ListBlocked(ctx context.Context, userID string) ([]*BlockedUserView, error) {
+	blocks, err := s.repo.ListBlocked(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list blocked users error: %w", err)
+	}
+
+	blockedIDs := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		blockedIDs = append(blockedIDs, block.BlockedID)
+	}
+	infos, err := s.userInfos(ctx, blockedIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*BlockedUserView, 0, len(blocks))
+	for _, block := range blocks {
+		views = append(views, &BlockedUserView{UserBlock: block, User: infos[block.BlockedID]})
+	}
+	return views, nil
+}
+
+// IsBlocked blockerID 是否屏蔽了 userID
+func (s *friendServiceImpl) IsBlocked(ctx context.Context, blockerID, userID string) (bool, error) {
+	return s.cachedContains(ctx, blockedIDsKeyPrefix, blockerID, userID, s.repo.FindBlockedIDs)
+}
+
+// CheckMessage 单聊接收者屏蔽了发送者时拒绝
+func (s *friendServiceImpl) CheckMessage(ctx context.Context, msg *model.Message) error {
+	if !msg.Type.IsChat() || msg.Type == model.MsgGroupChat || msg.GroupID != "" || msg.To == "" {
+		return nil
+	}
+	blocked, err := s.IsBlocked(ctx, msg.To, msg.From)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlockedByUser
+	}
+	return nil
+}
+
+// activeUser 查询未注销的用户
+func (s *friendServiceImpl) activeUser(ctx context.Context, userID string) (*model.User, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find user error: %w", err)
+	}
+	if user == nil || user.Status == model.UserStatusDeleted {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// userInfos 批量查询用户信息
+func (s *friendServiceImpl) userInfos(ctx context.Context, userIDs []string) (map[string]*model.UserInfo, error) {
+	infos := make(map[string]*model.UserInfo, len(userIDs))
+	if len(userIDs) == 0 {
+		return infos, nil
+	}
+	users, err := s.users.FindByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("find users error: %w", err)
+	}
+	for _, user := range users {
+		if user.Status != model.UserStatusDeleted {
+			infos[user.UserID] = user.ToUserInfo()
+		}
+	}
+	return infos, nil
+}
+
+// cachedContains 检查 ownerID 的好友/屏蔽集合是否包含 memberID，未缓存时从数据库加载
+func (s *friendServiceImpl) cachedContains(ctx context.Context, prefix, ownerID, memberID string, load func(ctx context.Context, userID string) ([]string, error)) (bool, error) {
+	key := prefix + ownerID
+	pipe := s.redis.Pipeline()
+	exists := pipe.Exists(ctx, key)
+	contains := pipe.SIsMember(ctx, key, memberID)
+	if _, err := pipe.Exec(ctx); err == nil && exists.Val() > 0 {
+		return contains.Val(), nil
+	}
+
+	ids, err := load(ctx, ownerID)
+	if err != nil {
+		return false, fmt.Errorf("load %s error: %w", key, err)
+	}
+	members := append([]string{cacheSetPlaceholder}, ids...)
+	pipe = s.redis.Pipeline()
+	pipe.Del(ctx, key)
+	pipe.SAdd(ctx, key, stringsToInterfaces(members)...)
+	pipe.Expire(ctx, key, s.config.CacheTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("cache %s error: %v", key, err)
+	}
+
+	for _, id := range ids {
+		if id == memberID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// invalidate 清除用户的好友/屏蔽缓存
+func (s *friendServiceImpl) invalidate(ctx context.Context, prefix string, userIDs ...string) {
+	keys := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		keys = append(keys, prefix+userID)
+	}
+	s.redis.Del(ctx, keys...)
+}
+
+// notify 通知用户（离线时保存为离线消息）
+func (s *friendServiceImpl) notify(ctx context.Context, userID string, msgType model.MessageType, content interface{}) {
+	if s.dispatcher == nil {
+		return
+	}
+	msg := &model.Message{
+		MessageID: util.GenerateMessageID(),
+		Type:      msgType,
+		From:      "system",
+		To:        userID,
+		Content:   content,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if err := s.dispatcher.DispatchToUsers(ctx, []string{userID}, msg); err != nil {
+		log.Printf("dispatch %s to %s error: %v", msgType, userID, err)
+	}
+}
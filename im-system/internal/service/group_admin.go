@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// GroupListQuery 管理后台群组查询条件
+type GroupListQuery struct {
+	Keyword  string             `form:"keyword"`                              // 群组ID（精确）或群名称（包含）
+	OwnerID  string             `form:"owner_id"`                             // 群主
+	Status   *model.GroupStatus `form:"status" binding:"omitempty,oneof=0 1"` // 群状态，不传时不限
+	Page     int                `form:"page"`
+	PageSize int                `form:"page_size"`
+}
+
+// GroupAdminService 平台管理员群组管理服务（不受群内角色限制，用于处理违规群）
+type GroupAdminService interface {
+	// ListGroups 按条件分页查询群组
+	ListGroups(ctx context.Context, query *GroupListQuery) ([]*model.Group, int64, error)
+
+	// DismissGroup 强制解散群组，通知全体成员（携带解散原因）
+	DismissGroup(ctx context.Context, groupID, operatorID, reason string) error
+}
+
+// groupAdminServiceImpl 平台管理员群组管理服务实现
+type groupAdminServiceImpl struct {
+	repo          repository.GroupRepository
+	redis         *redis.Client
+	msgDispatcher MessageDispatcher
+	legacyExtra   bool
+}
+
+// NewGroupAdminService 创建平台管理员群组管理服务
+// legacyExtra 为 true 时群事件同时下发旧版 extra 字段（弃用过渡期）
+func NewGroupAdminService(repo repository.GroupRepository, redisClient *redis.Client, dispatcher MessageDispatcher, legacyExtra bool) GroupAdminService {
+	return &groupAdminServiceImpl{
+		repo:          repo,
+		redis:         redisClient,
+		msgDispatcher: dispatcher,
+		legacyExtra:   legacyExtra,
+	}
+}
+
+// ListGroups 按条件分页查询群组
+func (s *groupAdminServiceImpl) ListGroups(ctx context.Context, query *GroupListQuery) ([]*model.Group, int64, error) {
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := &repository.GroupFilter{
+		Keyword: strings.TrimSpace(query.Keyword),
+		OwnerID: query.OwnerID,
+		Status:  query.Status,
+	}
+	return s.repo.FindGroups(ctx, filter, (page-1)*pageSize, pageSize)
+}
+
+// DismissGroup 强制解散群组
+func (s *groupAdminServiceImpl) DismissGroup(ctx context.Context, groupID, operatorID, reason string) error {
+	var memberIDs []string
+	err := s.repo.Transaction(ctx, func(tx repository.GroupRepository) error {
+		if _, err := lockActiveGroup(ctx, tx, groupID); err != nil {
+			return err
+		}
+
+		var err error
+		if memberIDs, err = tx.FindMemberIDs(ctx, groupID, model.RoleMember); err != nil {
+			return err
+		}
+		if err := tx.Update(ctx, groupID, map[string]interface{}{"status": model.GroupStatusDismissed}); err != nil {
+			return fmt.Errorf("update group status error: %w", err)
+		}
+		return tx.RemoveMembers(ctx, groupID, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.redis != nil {
+		s.redis.Del(ctx, fmt.Sprintf("group:members:%s", groupID))
+	}
+	s.notify(ctx, groupID, operatorID, reason, memberIDs)
+	log.Printf("group %s dismissed by admin %s: %s", groupID, operatorID, reason)
+	return nil
+}
+
+// notify 向原群成员发送群解散通知
+func (s *groupAdminServiceImpl) notify(ctx context.Context, groupID, operatorID, reason string, recipients []string) {
+	if s.msgDispatcher == nil || len(recipients) == 0 {
+		return
+	}
+
+	msg := model.NewGroupEventMessage(model.MsgGroupDismissed, groupID, operatorID, nil)
+	if content, ok := msg.Content.(*model.GroupEventContent); ok {
+		content.SetPayload(&model.GroupAdminActionPayload{Reason: reason}, s.legacyExtra)
+	}
+	if err := s.msgDispatcher.DispatchToUsers(ctx, recipients, msg); err != nil {
+		log.Printf("dispatch group dismissed event error: %v", err)
+	}
+}
@@ -0,0 +1,402 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/i18n"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 群活动摘要服务错误定义
+var (
+	ErrGroupDigestNotFound = errors.New("group digest subscription not found")
+	ErrGroupDigestInvalid  = errors.New("invalid digest send time or timezone")
+)
+
+// GroupDigestConfig 群活动每日摘要配置
+type GroupDigestConfig struct {
+	PollInterval    time.Duration // 到期扫描间隔
+	BatchSize       int           // 每次扫描处理的订阅数
+	MaxScanMessages int           // 每次摘要最多统计的消息数
+	MaxMentions     int           // 摘要中最多列出的@消息数
+	PreviewLength   int           // @消息摘要长度（字符）
+	DefaultTimezone string        // 订阅未指定时区时使用的时区
+}
+
+// DefaultGroupDigestConfig 默认群活动摘要配置
+func DefaultGroupDigestConfig() *GroupDigestConfig {
+	return &GroupDigestConfig{
+		PollInterval:    time.Minute,
+		BatchSize:       100,
+		MaxScanMessages: 5000,
+		MaxMentions:     5,
+		PreviewLength:   50,
+		DefaultTimezone: "UTC",
+	}
+}
+
+// GroupDigestRequest 订阅群活动摘要请求
+type GroupDigestRequest struct {
+	SendAt   string `json:"send_at" binding:"required"` // 每日发送时间（HH:MM）
+	Timezone string `json:"timezone"`                   // IANA 时区，为空时使用服务端默认时区
+	Push     bool   `json:"push"`                       // 是否同时推送到设备
+}
+
+// GroupDigestService 群活动每日摘要服务
+// 用户为免打扰的低优先级群开启摘要后，每天在设定的时间（用户时区）收到一条系统消息，
+// 汇总过去一天的消息数、@自己的消息和新置顶的消息，可选同时推送。当天没有新消息时不发送。
+type GroupDigestService interface {
+	// Subscribe 订阅或修改群活动摘要（仅群成员）
+	Subscribe(ctx context.Context, userID, groupID string, req *GroupDigestRequest) (*model.GroupDigestSubscription, error)
+
+	// Unsubscribe 取消订阅
+	Unsubscribe(ctx context.Context, userID, groupID string) error
+
+	// GetSubscription 查询用户在群内的订阅
+	GetSubscription(ctx context.Context, userID, groupID string) (*model.GroupDigestSubscription, error)
+
+	// ListSubscriptions 获取用户的全部订阅
+	ListSubscriptions(ctx context.Context, userID string) ([]*model.GroupDigestSubscription, error)
+
+	// RunDue 发送到期的摘要，返回发送数量
+	RunDue(ctx context.Context) (int, error)
+
+	// Start 启动到期摘要扫描
+	Start(ctx context.Context)
+
+	// SetPushService 设置推送服务，订阅开启推送时同时推送到用户设备
+	SetPushService(pushService PushService)
+}
+
+// groupDigestServiceImpl 群活动摘要服务实现
+type groupDigestServiceImpl struct {
+	repo         repository.GroupDigestRepository
+	messageRepo  repository.MessageRepository
+	pinRepo      repository.MessagePinRepository
+	groupService GroupService
+	dispatcher   MessageDispatcher
+	pushService  PushService
+	config       *GroupDigestConfig
+}
+
+// NewGroupDigestService 创建群活动摘要服务
+func NewGroupDigestService(
+	repo repository.GroupDigestRepository,
+	messageRepo repository.MessageRepository,
+	pinRepo repository.MessagePinRepository,
+	groupService GroupService,
+	dispatcher MessageDispatcher,
+	config *GroupDigestConfig,
+) GroupDigestService {
+	if config == nil {
+		config = DefaultGroupDigestConfig()
+	}
+	return &groupDigestServiceImpl{
+		repo:         repo,
+		messageRepo:  messageRepo,
+		pinRepo:      pinRepo,
+		groupService: groupService,
+		dispatcher:   dispatcher,
+		config:       config,
+	}
+}
+
+// SetPushService 设置推送服务
+func (s *groupDigestServiceImpl) SetPushService(pushService PushService) {
+	s.pushService = pushService
+}
+
+// Subscribe 订阅或修改群活动摘要
+func (s *groupDigestServiceImpl) Subscribe(ctx context.Context, userID, groupID string, req *GroupDigestRequest) (*model.GroupDigestSubscription, error) {
+	timezone := strings.TrimSpace(req.Timezone)
+	if timezone == "" {
+		timezone = s.config.DefaultTimezone
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, ErrGroupDigestInvalid
+	}
+	hour, minute, ok := parseDigestTime(req.SendAt)
+	if !ok {
+		return nil, ErrGroupDigestInvalid
+	}
+
+	isMember, err := s.groupService.IsMember(ctx, groupID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("check membership error: %w", err)
+	}
+	if !isMember {
+		return nil, ErrNotGroupMember
+	}
+
+	sub := &model.GroupDigestSubscription{
+		UserID:    userID,
+		GroupID:   groupID,
+		SendAt:    fmt.Sprintf("%02d:%02d", hour, minute),
+		Timezone:  timezone,
+		Push:      req.Push,
+		NextRunAt: nextDigestRun(hour, minute, loc, time.Now()),
+	}
+	if err := s.repo.Save(ctx, sub); err != nil {
+		return nil, fmt.Errorf("save group digest subscription error: %w", err)
+	}
+	return s.repo.Find(ctx, userID, groupID)
+}
+
+// Unsubscribe 取消订阅
+func (s *groupDigestServiceImpl) Unsubscribe(ctx context.Context, userID, groupID string) error {
+	deleted, err := s.repo.Delete(ctx, userID, groupID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrGroupDigestNotFound
+	}
+	return nil
+}
+
+// GetSubscription 查询用户在群内的订阅
+func (s *groupDigestServiceImpl) GetSubscription(ctx context.Context, userID, groupID string) (*model.GroupDigestSubscription, error) {
+	sub, err := s.repo.Find(ctx, userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrGroupDigestNotFound
+	}
+	return sub, nil
+}
+
+// ListSubscriptions 获取用户的全部订阅
+func (s *groupDigestServiceImpl) ListSubscriptions(ctx context.Context, userID string) ([]*model.GroupDigestSubscription, error) {
+	return s.repo.FindByUser(ctx, userID)
+}
+
+// RunDue 发送到期的摘要
+func (s *groupDigestServiceImpl) RunDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	subs, err := s.repo.FindDue(ctx, now, s.config.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("find due group digests error: %w", err)
+	}
+
+	sent := 0
+	for _, sub := range subs {
+		hour, minute, _ := parseDigestTime(sub.SendAt)
+		loc, err := time.LoadLocation(sub.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		// 先抢占再发送，多节点同时扫描时每个订阅每天只发送一次；服务停机错过的摘要不补发
+		ok, err := s.repo.Advance(ctx, sub.UserID, sub.GroupID, sub.NextRunAt, nextDigestRun(hour, minute, loc, now))
+		if err != nil {
+			log.Printf("claim group digest %s/%s error: %v", sub.UserID, sub.GroupID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		delivered, err := s.deliver(ctx, sub, now)
+		if err != nil {
+			log.Printf("deliver group digest %s/%s error: %v", sub.UserID, sub.GroupID, err)
+			continue
+		}
+		if delivered {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// deliver 汇总区间内的群活动并发送给订阅者，没有新消息时不发送
+func (s *groupDigestServiceImpl) deliver(ctx context.Context, sub *model.GroupDigestSubscription, now time.Time) (bool, error) {
+	isMember, err := s.groupService.IsMember(ctx, sub.GroupID, sub.UserID)
+	if err != nil {
+		return false, fmt.Errorf("check membership error: %w", err)
+	}
+	if !isMember {
+		// 已退群或群已解散，订阅随之失效
+		_, err := s.repo.Delete(ctx, sub.UserID, sub.GroupID)
+		return false, err
+	}
+
+	// 统计区间为最近一天，两次发送间隔不足一天（如修改了发送时间）时从上次发送开始
+	from := now.Add(-24 * time.Hour)
+	if sub.LastSentAt != nil && sub.LastSentAt.After(from) {
+		from = *sub.LastSentAt
+	}
+	content, err := s.summarize(ctx, sub, from, now)
+	if err != nil {
+		return false, err
+	}
+	if content.MessageCount == 0 {
+		return false, nil
+	}
+
+	msg := &model.Message{
+		MessageID:      util.GenerateMessageID(),
+		Type:           model.MsgGroupDigest,
+		From:           "system",
+		To:             sub.UserID,
+		GroupID:        sub.GroupID,
+		ConversationID: content.ConversationID,
+		Content:        content,
+		Timestamp:      now.UnixMilli(),
+		QoS:            model.QoSAtLeastOnce,
+	}
+	if s.dispatcher != nil {
+		if err := s.dispatcher.DispatchToUsers(ctx, []string{sub.UserID}, msg); err != nil {
+			log.Printf("dispatch group digest %s/%s error: %v", sub.UserID, sub.GroupID, err)
+		}
+	}
+
+	if sub.Push && s.pushService != nil {
+		body := strings.NewReplacer(
+			"{name}", content.GroupName,
+			"{count}", strconv.Itoa(content.MessageCount),
+			"{mentions}", strconv.Itoa(content.MentionCount),
+		).Replace(i18n.T(i18n.DefaultLocale, i18n.KeyGroupDigest))
+		notification := &model.PushNotification{
+			Body:      body,
+			Sound:     "default",
+			ThreadID:  content.ConversationID,
+			MessageID: msg.MessageID,
+			Priority:  model.PushPriorityNormal,
+			Data: map[string]string{
+				"type":            "group_digest",
+				"group_id":        sub.GroupID,
+				"conversation_id": content.ConversationID,
+			},
+		}
+		if err := s.pushService.PushToUser(ctx, sub.UserID, notification); err != nil {
+			log.Printf("push group digest %s/%s error: %v", sub.UserID, sub.GroupID, err)
+		}
+	}
+	return true, nil
+}
+
+// summarize 统计区间内其他成员发送的消息数、@订阅者的消息及新置顶的消息
+func (s *groupDigestServiceImpl) summarize(ctx context.Context, sub *model.GroupDigestSubscription, from, to time.Time) (*model.GroupDigestContent, error) {
+	convID := model.NewGroupConversationID(sub.GroupID).String()
+	content := &model.GroupDigestContent{
+		GroupID:        sub.GroupID,
+		ConversationID: convID,
+		From:           from.UnixMilli(),
+		To:             to.UnixMilli(),
+		TemplateKey:    i18n.KeyGroupDigest,
+	}
+	if group, err := s.groupService.GetGroupInfo(ctx, sub.GroupID); err == nil && group != nil {
+		content.GroupName = group.Name
+	}
+
+	docs, err := s.messageRepo.FindByConversationRange(ctx, convID, from, to, s.config.MaxScanMessages+1)
+	if err != nil {
+		return nil, fmt.Errorf("find group messages error: %w", err)
+	}
+	if len(docs) > s.config.MaxScanMessages {
+		docs = docs[:s.config.MaxScanMessages]
+		content.MoreMessages = true
+	}
+
+	var mentions []*model.GroupDigestMention
+	for _, doc := range docs {
+		msgType := model.MessageType(doc.Type)
+		if doc.From == sub.UserID || isGroupEventType(msgType) {
+			continue
+		}
+		content.MessageCount++
+
+		atUserIDs, atAll := messageMentions(doc.Content)
+		if !atAll && !slices.Contains(atUserIDs, sub.UserID) && !slices.Contains(doc.MentionTargets, sub.UserID) {
+			continue
+		}
+		content.MentionCount++
+		mentions = append(mentions, &model.GroupDigestMention{
+			MessageID: doc.MessageID,
+			Sender:    doc.From,
+			Preview:   s.preview(msgType, doc.Content),
+			Timestamp: doc.CreatedAt.UnixMilli(),
+		})
+	}
+	// 只保留最近的@消息，按时间倒序
+	if len(mentions) > s.config.MaxMentions {
+		mentions = mentions[len(mentions)-s.config.MaxMentions:]
+	}
+	slices.Reverse(mentions)
+	content.Mentions = mentions
+
+	pins, err := s.pinRepo.FindByConversation(ctx, convID)
+	if err != nil {
+		log.Printf("find pinned messages of %s error: %v", convID, err)
+	}
+	content.PinnedCount = len(pins)
+	for _, pin := range pins {
+		if !pin.CreatedAt.Before(from) && pin.CreatedAt.Before(to) {
+			content.NewPins = append(content.NewPins, pin.MessageID)
+		}
+	}
+	return content, nil
+}
+
+// preview 生成@消息摘要：文本消息截取正文，其他消息显示类型
+func (s *groupDigestServiceImpl) preview(msgType model.MessageType, content map[string]interface{}) string {
+	if text, ok := content["text"].(string); ok && text != "" {
+		runes := []rune(text)
+		if len(runes) > s.config.PreviewLength {
+			return string(runes[:s.config.PreviewLength]) + "…"
+		}
+		return text
+	}
+	return "[" + msgType.String() + "]"
+}
+
+// Start 启动到期摘要扫描
+func (s *groupDigestServiceImpl) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.RunDue(ctx); err != nil {
+				log.Printf("run group digests error: %v", err)
+			}
+		}
+	}
+}
+
+// isGroupEventType 是否为群事件消息（不计入活动统计）
+func isGroupEventType(t model.MessageType) bool {
+	return t >= model.MsgGroupCreated && t <= model.MsgGroupExpiring
+}
+
+// parseDigestTime 解析 HH:MM 格式的发送时间
+func parseDigestTime(value string) (int, int, bool) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, 0, false
+	}
+	return t.Hour(), t.Minute(), true
+}
+
+// nextDigestRun 计算 after 之后下一个发送时间（按用户时区的当地时间，夏令时切换时由 time.Date 归一化）
+func nextDigestRun(hour, minute int, loc *time.Location, after time.Time) time.Time {
+	local := after.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+	if !next.After(after) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, hour, minute, 0, 0, loc)
+	}
+	return next.UTC()
+}
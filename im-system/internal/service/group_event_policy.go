@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// GroupEventPolicy 群事件通知降级策略
+type GroupEventPolicy struct {
+	// BatchThreshold 成员数达到该值时，成员变动事件在窗口内合并为一条汇总通知（0表示不合并）
+	BatchThreshold int
+	// BatchWindow 合并窗口
+	BatchWindow time.Duration
+	// MaxBatchTargets 单条汇总通知最多携带的目标成员ID，超出部分只计数
+	MaxBatchTargets int
+	// LargeGroupThreshold 成员数达到该值时，成员变动事件只通知相关成员和群管理员（0表示不限制）
+	LargeGroupThreshold int
+	// LegacyExtra 弃用过渡期内是否在类型化负载之外同时下发旧版 extra 字段
+	LegacyExtra bool
+}
+
+// DefaultGroupEventPolicy 默认群事件通知策略
+func DefaultGroupEventPolicy() *GroupEventPolicy {
+	return &GroupEventPolicy{
+		BatchThreshold:      100,
+		BatchWindow:         5 * time.Second,
+		MaxBatchTargets:     50,
+		LargeGroupThreshold: 1000,
+		LegacyExtra:         true,
+	}
+}
+
+// isMembershipEvent 是否为成员变动事件（可降级的事件）
+func isMembershipEvent(eventType model.MessageType) bool {
+	switch eventType {
+	case model.MsgGroupMemberJoin, model.MsgGroupMemberLeave, model.MsgGroupMemberKicked:
+		return true
+	}
+	return false
+}
+
+// pendingGroupEvent 待合并的群事件
+type pendingGroupEvent struct {
+	eventType  model.MessageType
+	groupID    string
+	operatorID string
+	targetIDs  []string
+	count      int
+}
+
+// groupEventBatcher 群成员变动事件合并器
+type groupEventBatcher struct {
+	policy  *GroupEventPolicy
+	flushFn func(ev *pendingGroupEvent)
+
+	mu      sync.Mutex
+	pending map[string]*pendingGroupEvent
+}
+
+// newGroupEventBatcher 创建群事件合并器
+func newGroupEventBatcher(policy *GroupEventPolicy, flushFn func(ev *pendingGroupEvent)) *groupEventBatcher {
+	return &groupEventBatcher{
+		policy:  policy,
+		flushFn: flushFn,
+		pending: make(map[string]*pendingGroupEvent),
+	}
+}
+
+// add 加入待合并事件，窗口内首个事件负责启动定时刷新
+func (b *groupEventBatcher) add(eventType model.MessageType, groupID, operatorID string, targetIDs []string) {
+	key := fmt.Sprintf("%s:%d", groupID, eventType)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ev, ok := b.pending[key]
+	if !ok {
+		ev = &pendingGroupEvent{eventType: eventType, groupID: groupID}
+		b.pending[key] = ev
+		time.AfterFunc(b.policy.BatchWindow, func() { b.flush(key) })
+	}
+
+	// 同一窗口内操作者不同时不指定操作者
+	if ev.count == 0 {
+		ev.operatorID = operatorID
+	} else if ev.operatorID != operatorID {
+		ev.operatorID = ""
+	}
+	ev.count += len(targetIDs)
+	for _, id := range targetIDs {
+		if b.policy.MaxBatchTargets > 0 && len(ev.targetIDs) >= b.policy.MaxBatchTargets {
+			break
+		}
+		ev.targetIDs = append(ev.targetIDs, id)
+	}
+}
+
+// flush 发送合并后的事件
+func (b *groupEventBatcher) flush(key string) {
+	b.mu.Lock()
+	ev, ok := b.pending[key]
+	delete(b.pending, key)
+	b.mu.Unlock()
+
+	if ok {
+		b.flushFn(ev)
+	}
+}
+
+// notifyGroupEvent 发送群事件通知，大群的成员变动事件按策略合并或降级
+func (s *groupServiceImpl) notifyGroupEvent(ctx context.Context, eventType model.MessageType, groupID, operatorID string, targetIDs []string, payload model.GroupEventPayload) {
+	if s.msgDispatcher == nil {
+		return
+	}
+
+	// 获取群成员
+	memberIDs, err := s.GetGroupMemberIDs(ctx, groupID)
+	if err != nil {
+		fmt.Printf("get group member IDs error: %v\n", err)
+		return
+	}
+
+	if isMembershipEvent(eventType) {
+		if s.eventPolicy.LargeGroupThreshold > 0 && len(memberIDs) >= s.eventPolicy.LargeGroupThreshold {
+			// 超大群：只通知相关成员和管理员
+			memberIDs = s.largeGroupRecipients(ctx, groupID, operatorID, targetIDs)
+		} else if s.eventPolicy.BatchThreshold > 0 && len(memberIDs) >= s.eventPolicy.BatchThreshold && payload == nil {
+			s.eventBatcher.add(eventType, groupID, operatorID, targetIDs)
+			return
+		}
+	}
+
+	s.dispatchGroupEvent(ctx, eventType, groupID, operatorID, targetIDs, payload, memberIDs)
+}
+
+// dispatchGroupEvent 构建并分发群事件消息
+func (s *groupServiceImpl) dispatchGroupEvent(ctx context.Context, eventType model.MessageType, groupID, operatorID string, targetIDs []string, payload model.GroupEventPayload, recipients []string) {
+	msg := model.NewGroupEventMessage(eventType, groupID, operatorID, targetIDs)
+	if payload != nil {
+		if content, ok := msg.Content.(*model.GroupEventContent); ok {
+			content.SetPayload(payload, s.eventPolicy.LegacyExtra)
+		}
+	}
+
+	// 分发给所有群成员
+	if err := s.msgDispatcher.DispatchToUsers(ctx, recipients, msg); err != nil {
+		fmt.Printf("dispatch group event error: %v\n", err)
+	}
+}
+
+// flushBatchedGroupEvent 发送合并后的成员变动汇总通知
+func (s *groupServiceImpl) flushBatchedGroupEvent(ev *pendingGroupEvent) {
+	ctx := context.Background()
+
+	memberIDs, err := s.GetGroupMemberIDs(ctx, ev.groupID)
+	if err != nil {
+		fmt.Printf("get group member IDs error: %v\n", err)
+		return
+	}
+
+	payload := &model.GroupMemberBatchPayload{Count: ev.count}
+	s.dispatchGroupEvent(ctx, ev.eventType, ev.groupID, ev.operatorID, ev.targetIDs, payload, memberIDs)
+}
+
+// largeGroupRecipients 超大群成员变动事件的接收者：操作者、目标成员及群主/管理员
+func (s *groupServiceImpl) largeGroupRecipients(ctx context.Context, groupID, operatorID string, targetIDs []string) []string {
+	adminIDs, err := s.repo.FindMemberIDs(ctx, groupID, model.RoleAdmin)
+	if err != nil {
+		fmt.Printf("get group admins error: %v\n", err)
+	}
+
+	recipients := append([]string{operatorID}, targetIDs...)
+	recipients = append(recipients, adminIDs...)
+	return util.UniqueStrings(recipients)
+}
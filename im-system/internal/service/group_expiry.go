@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// GroupExpiryConfig 临时群到期配置
+type GroupExpiryConfig struct {
+	WarnBefore   time.Duration // 到期前多久向群成员发送解散提醒
+	PollInterval time.Duration // 到期扫描间隔
+	BatchSize    int           // 每次扫描处理的群数
+	ExportRange  time.Duration // 到期导出的最长时间范围（不超过会话导出的最大范围）
+	LegacyExtra  bool          // 群事件是否同时下发旧版 extra 字段（弃用过渡期）
+}
+
+// DefaultGroupExpiryConfig 默认临时群到期配置
+func DefaultGroupExpiryConfig() *GroupExpiryConfig {
+	return &GroupExpiryConfig{
+		WarnBefore:   time.Hour,
+		PollInterval: 30 * time.Second,
+		BatchSize:    100,
+		ExportRange:  365 * 24 * time.Hour,
+		LegacyExtra:  true,
+	}
+}
+
+// GroupExpiryService 临时群到期服务
+// 设置了到期时间的群（活动群等）在到期前 WarnBefore 向群成员发送一次提醒，到期后自动解散；
+// 开启 export_on_expiry 的群在解散前为群主创建聊天记录导出任务。
+type GroupExpiryService interface {
+	// ProcessDue 发送到期提醒并解散已到期的群，返回提醒和解散的群数
+	ProcessDue(ctx context.Context) (warned, dismissed int, err error)
+
+	// Start 启动到期扫描
+	Start(ctx context.Context)
+
+	// SetExportService 设置会话导出服务，为空时到期解散不导出聊天记录
+	SetExportService(exportService ConversationExportService)
+}
+
+// groupExpiryServiceImpl 临时群到期服务实现
+type groupExpiryServiceImpl struct {
+	repo          repository.GroupRepository
+	redis         *redis.Client
+	msgDispatcher MessageDispatcher
+	exportService ConversationExportService
+	config        *GroupExpiryConfig
+}
+
+// NewGroupExpiryService 创建临时群到期服务
+func NewGroupExpiryService(
+	repo repository.GroupRepository,
+	redisClient *redis.Client,
+	dispatcher MessageDispatcher,
+	config *GroupExpiryConfig,
+) GroupExpiryService {
+	if config == nil {
+		config = DefaultGroupExpiryConfig()
+	}
+	return &groupExpiryServiceImpl{
+		repo:          repo,
+		redis:         redisClient,
+		msgDispatcher: dispatcher,
+		config:        config,
+	}
+}
+
+// SetExportService 设置会话导出服务
+func (s *groupExpiryServiceImpl) SetExportService(exportService ConversationExportService) {
+	s.exportService = exportService
+}
+
+// ProcessDue 先解散已到期的群，再向即将到期的群发送提醒
+func (s *groupExpiryServiceImpl) ProcessDue(ctx context.Context) (int, int, error) {
+	now := time.Now()
+
+	expired, err := s.repo.FindExpired(ctx, now, s.config.BatchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("find expired groups error: %w", err)
+	}
+	dismissed := 0
+	for _, group := range expired {
+		ok, err := s.dismiss(ctx, group.GroupID, now)
+		if err != nil {
+			log.Printf("dismiss expired group %s error: %v", group.GroupID, err)
+			continue
+		}
+		if ok {
+			dismissed++
+		}
+	}
+
+	due, err := s.repo.FindExpiryWarnDue(ctx, now.Add(s.config.WarnBefore), s.config.BatchSize)
+	if err != nil {
+		return 0, dismissed, fmt.Errorf("find expiring groups error: %w", err)
+	}
+	warned := 0
+	for _, group := range due {
+		// 按版本号抢占，多节点同时扫描或到期时间刚被修改时只由一方发送
+		err := s.repo.UpdateWithVersion(ctx, group.GroupID, group.Version, map[string]interface{}{"expiry_warned": true})
+		if errors.Is(err, repository.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			log.Printf("mark group %s expiry warned error: %v", group.GroupID, err)
+			continue
+		}
+		s.notify(ctx, model.MsgGroupExpiring, group, &model.GroupExpiryPayload{ExpiresAt: group.ExpiresAt.UnixMilli()}, nil)
+		warned++
+	}
+	return warned, dismissed, nil
+}
+
+// dismiss 解散到期的群：锁定群后再次确认到期（期间可能被延期或取消），为群主导出聊天记录后解散并通知成员
+func (s *groupExpiryServiceImpl) dismiss(ctx context.Context, groupID string, now time.Time) (bool, error) {
+	var group *model.Group
+	var memberIDs []string
+	exportJobID := ""
+
+	err := s.repo.Transaction(ctx, func(tx repository.GroupRepository) error {
+		var err error
+		if group, err = lockActiveGroup(ctx, tx, groupID); err != nil {
+			return err
+		}
+		if group.ExpiresAt == nil || group.ExpiresAt.After(now) {
+			group = nil
+			return nil
+		}
+
+		if memberIDs, err = tx.FindMemberIDs(ctx, groupID, model.RoleMember); err != nil {
+			return err
+		}
+
+		// 导出须在解散前创建（校验群主的会话参与身份），导出任务异步执行，失败不影响解散
+		if group.ExportOnExpiry {
+			exportJobID = s.export(ctx, group, now)
+		}
+
+		if err := tx.Update(ctx, groupID, map[string]interface{}{"status": model.GroupStatusDismissed}); err != nil {
+			return err
+		}
+		return tx.RemoveMembers(ctx, groupID, nil)
+	})
+	if errors.Is(err, ErrGroupDismissed) || errors.Is(err, ErrGroupNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if group == nil {
+		return false, nil
+	}
+
+	s.clearMemberCache(ctx, groupID)
+
+	// 群主的解散通知携带导出任务ID，可通过会话导出接口查询下载链接
+	expiresAt := group.ExpiresAt.UnixMilli()
+	if others := util.RemoveString(memberIDs, group.OwnerID); len(others) > 0 {
+		s.notify(ctx, model.MsgGroupDismissed, group, &model.GroupExpiryPayload{ExpiresAt: expiresAt}, others)
+	}
+	s.notify(ctx, model.MsgGroupDismissed, group, &model.GroupExpiryPayload{ExpiresAt: expiresAt, ExportJobID: exportJobID}, []string{group.OwnerID})
+	log.Printf("temporary group %s dismissed on expiry", groupID)
+	return true, nil
+}
+
+// export 为群主创建聊天记录导出任务，返回任务ID（失败时为空）
+func (s *groupExpiryServiceImpl) export(ctx context.Context, group *model.Group, now time.Time) string {
+	if s.exportService == nil {
+		return ""
+	}
+	from := group.CreatedAt
+	if earliest := now.Add(-s.config.ExportRange); from.Before(earliest) {
+		from = earliest
+	}
+	job, err := s.exportService.CreateExport(ctx, group.OwnerID, model.GetGroupChatConversationID(group.GroupID), &ExportRequest{
+		From:   from,
+		To:     now,
+		Format: ExportFormatHTML,
+	})
+	if err != nil {
+		log.Printf("export expired group %s for owner error: %v", group.GroupID, err)
+		return ""
+	}
+	return job.JobID
+}
+
+// Start 启动到期扫描
+func (s *groupExpiryServiceImpl) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, _, err := s.ProcessDue(ctx); err != nil {
+				log.Printf("process expiring groups error: %v", err)
+			}
+		}
+	}
+}
+
+// notify 发送到期提醒/解散群事件，recipients 为空时发给当前群成员
+func (s *groupExpiryServiceImpl) notify(ctx context.Context, eventType model.MessageType, group *model.Group, payload *model.GroupExpiryPayload, recipients []string) {
+	if s.msgDispatcher == nil {
+		return
+	}
+	if recipients == nil {
+		memberIDs, err := s.repo.FindMemberIDs(ctx, group.GroupID, model.RoleMember)
+		if err != nil {
+			log.Printf("get group member IDs error: %v", err)
+			return
+		}
+		recipients = memberIDs
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	msg := model.NewGroupEventMessage(eventType, group.GroupID, group.OwnerID, nil)
+	if content, ok := msg.Content.(*model.GroupEventContent); ok {
+		content.SetPayload(payload, s.config.LegacyExtra)
+	}
+	if err := s.msgDispatcher.DispatchToUsers(ctx, recipients, msg); err != nil {
+		log.Printf("dispatch group expiry event error: %v", err)
+	}
+}
+
+// clearMemberCache 清理Redis中的群成员缓存，下次查询时重建
+func (s *groupExpiryServiceImpl) clearMemberCache(ctx context.Context, groupID string) {
+	if s.redis == nil {
+		return
+	}
+	s.redis.Del(ctx, fmt.Sprintf("group:members:%s", groupID))
+}
@@ -8,9 +8,9 @@ import (
 	"time"
 
 	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
 	"github.com/d60-lab/im-system/pkg/util"
 	"github.com/go-redis/redis/v8"
-	"gorm.io/gorm"
 )
 
 // 群组服务错误定义
@@ -25,8 +25,24 @@ var (
 	ErrGroupDismissed  = errors.New("group has been dismissed")
 	ErrPermissionDeny  = errors.New("permission denied")
 	ErrInvalidRequest  = errors.New("invalid request")
+
+	ErrOwnerCannotLeave = errors.New("group owner cannot leave, please transfer ownership first")
+	ErrGroupConflict    = errors.New("group was modified concurrently, please reload and retry")
+
+	ErrJoinRequestNotFound = errors.New("join request not found")
+	ErrJoinRequestHandled  = errors.New("join request already handled")
+	ErrGroupExpiryInvalid  = errors.New("group expiry must be in the future")
+
+	ErrGroupMuteAll = errors.New("all members are muted in this group")
+	ErrMemberMuted  = errors.New("you are muted in this group")
 )
 
+// groupMuteCacheTTL 群禁言状态缓存时间，禁言、全员禁言及角色变更时主动清除
+const groupMuteCacheTTL = 5 * time.Minute
+
+// groupMuteAllField 禁言状态缓存中全员禁言标记的字段名，其余字段为成员ID
+const groupMuteAllField = "*"
+
 // GroupService 群组服务接口
 type GroupService interface {
 	// 群组操作
@@ -37,16 +53,26 @@ type GroupService interface {
 
 	// 成员管理
 	JoinGroup(ctx context.Context, groupID, userID, inviterID string) error
+	AddMembers(ctx context.Context, groupID, inviterID string, userIDs []string) error
 	LeaveGroup(ctx context.Context, groupID, userID string) error
 	KickMember(ctx context.Context, groupID, operatorID string, targetIDs []string) error
 	GetGroupMembers(ctx context.Context, groupID string, page, pageSize int) ([]*model.GroupMember, int64, error)
 
+	// 入群审批（群主或管理员），status 小于0时查询全部状态
+	ListJoinRequests(ctx context.Context, groupID, operatorID string, status, page, pageSize int) ([]*model.GroupJoinRequest, int64, error)
+	HandleJoinRequest(ctx context.Context, groupID, operatorID string, requestID uint, approve bool) (*model.GroupJoinRequest, error)
+
 	// 管理员操作
 	SetAdmin(ctx context.Context, groupID, operatorID, targetID string, isAdmin bool) error
 	TransferOwner(ctx context.Context, groupID, ownerID, newOwnerID string) error
 	MuteMember(ctx context.Context, groupID, operatorID, targetID string, duration time.Duration) error
 	SetMuteAll(ctx context.Context, groupID, operatorID string, muteAll bool) error
 
+	// CheckMute 群消息发送前检查发送者是否被禁言，被禁言时返回禁言状态及 ErrGroupMuteAll / ErrMemberMuted
+	CheckMute(ctx context.Context, groupID, userID string) (*model.GroupMuteStatus, error)
+	// GetSenderRole 获取群消息发送者的角色（与禁言状态共用缓存），非成员按普通成员处理
+	GetSenderRole(ctx context.Context, groupID, userID string) (model.GroupRole, error)
+
 	// 查询
 	GetUserGroups(ctx context.Context, userID string) ([]*model.Group, error)
 	IsMember(ctx context.Context, groupID, userID string) (bool, error)
@@ -61,18 +87,27 @@ type MessageDispatcher interface {
 
 // groupServiceImpl 群组服务实现
 type groupServiceImpl struct {
-	db            *gorm.DB
+	repo          repository.GroupRepository
 	redis         *redis.Client
 	msgDispatcher MessageDispatcher
+	eventPolicy   *GroupEventPolicy
+	eventBatcher  *groupEventBatcher
 }
 
 // NewGroupService 创建群组服务
-func NewGroupService(db *gorm.DB, redisClient *redis.Client, dispatcher MessageDispatcher) GroupService {
-	return &groupServiceImpl{
-		db:            db,
+func NewGroupService(repo repository.GroupRepository, redisClient *redis.Client, dispatcher MessageDispatcher, eventPolicy *GroupEventPolicy) GroupService {
+	if eventPolicy == nil {
+		eventPolicy = DefaultGroupEventPolicy()
+	}
+
+	s := &groupServiceImpl{
+		repo:          repo,
 		redis:         redisClient,
 		msgDispatcher: dispatcher,
+		eventPolicy:   eventPolicy,
 	}
+	s.eventBatcher = newGroupEventBatcher(eventPolicy, s.flushBatchedGroupEvent)
+	return s
 }
 
 // CreateGroup 创建群组
@@ -83,6 +118,9 @@ func (s *groupServiceImpl) CreateGroup(ctx context.Context, req *model.CreateGro
 
 	groupID := util.GenerateGroupID()
 	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, ErrGroupExpiryInvalid
+	}
 
 	group := &model.Group{
 		GroupID:     groupID,
@@ -96,12 +134,15 @@ func (s *groupServiceImpl) CreateGroup(ctx context.Context, req *model.CreateGro
 		Status:      model.GroupStatusNormal,
 		CreatedAt:   now,
 		UpdatedAt:   now,
+
+		ExpiresAt:      req.ExpiresAt,
+		ExportOnExpiry: req.ExportOnExpiry,
 	}
 
 	// 开启事务
-	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err := s.repo.Transaction(ctx, func(tx repository.GroupRepository) error {
 		// 创建群组
-		if err := tx.Create(group).Error; err != nil {
+		if err := tx.Create(ctx, group); err != nil {
 			return fmt.Errorf("create group error: %w", err)
 		}
 
@@ -112,7 +153,7 @@ func (s *groupServiceImpl) CreateGroup(ctx context.Context, req *model.CreateGro
 			Role:     model.RoleOwner,
 			JoinedAt: now,
 		}
-		if err := tx.Create(ownerMember).Error; err != nil {
+		if err := tx.AddMembers(ctx, []*model.GroupMember{ownerMember}); err != nil {
 			return fmt.Errorf("add owner member error: %w", err)
 		}
 
@@ -135,13 +176,13 @@ func (s *groupServiceImpl) CreateGroup(ctx context.Context, req *model.CreateGro
 			}
 
 			if len(members) > 0 {
-				if err := tx.Create(&members).Error; err != nil {
+				if err := tx.AddMembers(ctx, members); err != nil {
 					return fmt.Errorf("add initial members error: %w", err)
 				}
 
 				// 更新成员数
 				group.MemberCount = 1 + len(members)
-				if err := tx.Model(group).Update("member_count", group.MemberCount).Error; err != nil {
+				if err := tx.Update(ctx, groupID, map[string]interface{}{"member_count": group.MemberCount}); err != nil {
 					return fmt.Errorf("update member count error: %w", err)
 				}
 			}
@@ -184,15 +225,14 @@ func (s *groupServiceImpl) DismissGroup(ctx context.Context, groupID, operatorID
 	}
 
 	// 开启事务
-	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err = s.repo.Transaction(ctx, func(tx repository.GroupRepository) error {
 		// 更新群组状态
-		if err := tx.Model(&model.Group{}).Where("group_id = ?", groupID).
-			Update("status", model.GroupStatusDismissed).Error; err != nil {
+		if err := tx.Update(ctx, groupID, map[string]interface{}{"status": model.GroupStatusDismissed}); err != nil {
 			return fmt.Errorf("update group status error: %w", err)
 		}
 
 		// 删除所有群成员
-		if err := tx.Where("group_id = ?", groupID).Delete(&model.GroupMember{}).Error; err != nil {
+		if err := tx.RemoveMembers(ctx, groupID, nil); err != nil {
 			return fmt.Errorf("delete group members error: %w", err)
 		}
 
@@ -215,19 +255,19 @@ func (s *groupServiceImpl) DismissGroup(ctx context.Context, groupID, operatorID
 
 // GetGroupInfo 获取群信息
 func (s *groupServiceImpl) GetGroupInfo(ctx context.Context, groupID string) (*model.Group, error) {
-	var group model.Group
-	if err := s.db.WithContext(ctx).Where("group_id = ?", groupID).First(&group).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrGroupNotFound
-		}
+	group, err := s.repo.FindByID(ctx, groupID)
+	if err != nil {
 		return nil, err
 	}
+	if group == nil {
+		return nil, ErrGroupNotFound
+	}
 
 	if group.Status == model.GroupStatusDismissed {
 		return nil, ErrGroupDismissed
 	}
 
-	return &group, nil
+	return group, nil
 }
 
 // UpdateGroupInfo 更新群信息
@@ -241,46 +281,70 @@ func (s *groupServiceImpl) UpdateGroupInfo(ctx context.Context, req *model.Updat
 		return ErrNotGroupAdmin
 	}
 
+	// 读取旧值，用于群资料变更通知
+	group, err := s.repo.FindByID(ctx, req.GroupID)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return ErrGroupNotFound
+	}
+
 	// 构建更新字段
 	updates := make(map[string]interface{})
-	changes := make([]map[string]string, 0)
+	changes := make([]*model.GroupInfoUpdatePayload, 0)
 
 	if req.Name != nil {
 		updates["name"] = *req.Name
-		changes = append(changes, map[string]string{
-			"field":     "name",
-			"new_value": *req.Name,
-		})
+		changes = append(changes, &model.GroupInfoUpdatePayload{Field: "name", OldValue: group.Name, NewValue: *req.Name})
 	}
 	if req.Avatar != nil {
 		updates["avatar"] = *req.Avatar
-		changes = append(changes, map[string]string{
-			"field":     "avatar",
-			"new_value": *req.Avatar,
-		})
+		changes = append(changes, &model.GroupInfoUpdatePayload{Field: "avatar", OldValue: group.Avatar, NewValue: *req.Avatar})
 	}
 	if req.Announcement != nil {
 		updates["announcement"] = *req.Announcement
-		changes = append(changes, map[string]string{
-			"field":     "announcement",
-			"new_value": *req.Announcement,
-		})
+		changes = append(changes, &model.GroupInfoUpdatePayload{Field: "announcement", OldValue: group.Announcement, NewValue: *req.Announcement})
 	}
 	if req.Description != nil {
 		updates["description"] = *req.Description
-		changes = append(changes, map[string]string{
-			"field":     "description",
-			"new_value": *req.Description,
-		})
+		changes = append(changes, &model.GroupInfoUpdatePayload{Field: "description", OldValue: group.Description, NewValue: *req.Description})
 	}
 	if req.JoinMode != nil {
 		updates["join_mode"] = *req.JoinMode
-		changes = append(changes, map[string]string{
-			"field":     "join_mode",
-			"new_value": fmt.Sprintf("%d", *req.JoinMode),
+		changes = append(changes, &model.GroupInfoUpdatePayload{
+			Field:    "join_mode",
+			OldValue: fmt.Sprintf("%d", group.JoinMode),
+			NewValue: fmt.Sprintf("%d", *req.JoinMode),
 		})
 	}
 
+	// 临时群设置：取消到期和导出设置只有群主可以修改，防止管理员取消群主设定的解散
+	if req.ClearExpiry || req.ExportOnExpiry != nil {
+		if role != model.RoleOwner {
+			return ErrNotGroupOwner
+		}
+	}
+	if req.ClearExpiry && req.ExpiresAt != nil {
+		return ErrInvalidRequest
+	}
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			return ErrGroupExpiryInvalid
+		}
+		updates["expires_at"] = req.ExpiresAt
+		updates["expiry_warned"] = false
+		changes = append(changes, &model.GroupInfoUpdatePayload{Field: "expires_at", OldValue: formatGroupExpiry(group.ExpiresAt), NewValue: formatGroupExpiry(req.ExpiresAt)})
+	}
+	if req.ClearExpiry && group.ExpiresAt != nil {
+		updates["expires_at"] = (*time.Time)(nil)
+		updates["expiry_warned"] = false
+		changes = append(changes, &model.GroupInfoUpdatePayload{Field: "expires_at", OldValue: formatGroupExpiry(group.ExpiresAt)})
+	}
+	if req.ExportOnExpiry != nil && *req.ExportOnExpiry != group.ExportOnExpiry {
+		updates["export_on_expiry"] = *req.ExportOnExpiry
+	}
+
 	if len(updates) == 0 {
 		return nil
 	}
@@ -288,26 +352,26 @@ func (s *groupServiceImpl) UpdateGroupInfo(ctx context.Context, req *model.Updat
 	updates["updated_at"] = time.Now()
 
 	// 执行更新
-	result := s.db.WithContext(ctx).Model(&model.Group{}).
-		Where("group_id = ?", req.GroupID).
-		Updates(updates)
-
-	if result.Error != nil {
-		return fmt.Errorf("update group info error: %w", result.Error)
+	if err := s.repo.Update(ctx, req.GroupID, updates); err != nil {
+		return fmt.Errorf("update group info error: %w", err)
 	}
 
 	// 发送群信息更新通知
 	for _, change := range changes {
-		extra := map[string]string{
-			"field":     change["field"],
-			"new_value": change["new_value"],
-		}
-		s.notifyGroupEvent(ctx, model.MsgGroupInfoUpdate, req.GroupID, req.OperatorID, nil, extra)
+		s.notifyGroupEvent(ctx, model.MsgGroupInfoUpdate, req.GroupID, req.OperatorID, nil, change)
 	}
 
 	return nil
 }
 
+// formatGroupExpiry 群到期时间的变更通知取值（RFC3339，长期群为空）
+func formatGroupExpiry(expiresAt *time.Time) string {
+	if expiresAt == nil {
+		return ""
+	}
+	return expiresAt.Format(time.RFC3339)
+}
+
 // JoinGroup 加入群组
 func (s *groupServiceImpl) JoinGroup(ctx context.Context, groupID, userID, inviterID string) error {
 	// 获取群信息
@@ -330,14 +394,14 @@ func (s *groupServiceImpl) JoinGroup(ctx context.Context, groupID, userID, invit
 		return ErrAlreadyInGroup
 	}
 
-	// TODO: 如果需要审批，创建加入申请而不是直接加入
+	// 需要审批时创建加入申请，由群主或管理员处理
 	if group.NeedApproval() {
 		// 创建加入申请
 		return s.createJoinRequest(ctx, groupID, userID, "")
 	}
 
 	// 开启事务
-	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err = s.repo.Transaction(ctx, func(tx repository.GroupRepository) error {
 		// 添加成员
 		member := &model.GroupMember{
 			GroupID:   groupID,
@@ -346,13 +410,12 @@ func (s *groupServiceImpl) JoinGroup(ctx context.Context, groupID, userID, invit
 			InviterID: inviterID,
 			JoinedAt:  time.Now(),
 		}
-		if err := tx.Create(member).Error; err != nil {
+		if err := tx.AddMembers(ctx, []*model.GroupMember{member}); err != nil {
 			return fmt.Errorf("create member error: %w", err)
 		}
 
 		// 更新成员数
-		if err := tx.Model(&model.Group{}).Where("group_id = ?", groupID).
-			UpdateColumn("member_count", gorm.Expr("member_count + ?", 1)).Error; err != nil {
+		if err := tx.IncrMemberCount(ctx, groupID, 1); err != nil {
 			return fmt.Errorf("update member count error: %w", err)
 		}
 
@@ -373,8 +436,73 @@ func (s *groupServiceImpl) JoinGroup(ctx context.Context, groupID, userID, invit
 	return nil
 }
 
-// createJoinRequest 创建加入申请
+// AddMembers 直接添加成员（管理操作，不经过入群审批），已是成员的用户会被跳过
+func (s *groupServiceImpl) AddMembers(ctx context.Context, groupID, inviterID string, userIDs []string) error {
+	group, err := s.GetGroupInfo(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	var newIDs []string
+	for _, userID := range userIDs {
+		isMember, err := s.IsMember(ctx, groupID, userID)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			newIDs = append(newIDs, userID)
+		}
+	}
+	if len(newIDs) == 0 {
+		return nil
+	}
+	if group.MemberCount+len(newIDs) > group.MaxMembers {
+		return ErrGroupFull
+	}
+
+	now := time.Now()
+	members := make([]*model.GroupMember, 0, len(newIDs))
+	for _, userID := range newIDs {
+		members = append(members, &model.GroupMember{
+			GroupID:   groupID,
+			UserID:    userID,
+			Role:      model.RoleMember,
+			InviterID: inviterID,
+			JoinedAt:  now,
+		})
+	}
+
+	err = s.repo.Transaction(ctx, func(tx repository.GroupRepository) error {
+		if err := tx.AddMembers(ctx, members); err != nil {
+			return fmt.Errorf("create members error: %w", err)
+		}
+		if err := tx.IncrMemberCount(ctx, groupID, len(members)); err != nil {
+			return fmt.Errorf("update member count error: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	groupKey := fmt.Sprintf("group:members:%s", groupID)
+	s.redis.SAdd(ctx, groupKey, stringsToInterfaces(newIDs)...)
+
+	s.notifyGroupEvent(ctx, model.MsgGroupMemberJoin, groupID, inviterID, newIDs, nil)
+
+	return nil
+}
+
+// createJoinRequest 创建加入申请并通知群主和管理员，已有待处理申请时不重复创建
 func (s *groupServiceImpl) createJoinRequest(ctx context.Context, groupID, userID, message string) error {
+	existing, err := s.repo.FindPendingJoinRequest(ctx, groupID, userID)
+	if err != nil {
+		return fmt.Errorf("find join request error: %w", err)
+	}
+	if existing != nil {
+		return nil
+	}
+
 	request := &model.GroupJoinRequest{
 		GroupID:   groupID,
 		UserID:    userID,
@@ -382,7 +510,150 @@ func (s *groupServiceImpl) createJoinRequest(ctx context.Context, groupID, userI
 		Status:    model.JoinRequestPending,
 		CreatedAt: time.Now(),
 	}
-	return s.db.WithContext(ctx).Create(request).Error
+	if err := s.repo.CreateJoinRequest(ctx, request); err != nil {
+		return fmt.Errorf("create join request error: %w", err)
+	}
+
+	adminIDs, err := s.repo.FindMemberIDs(ctx, groupID, model.RoleAdmin)
+	if err != nil {
+		fmt.Printf("get group admins error: %v\n", err)
+		return nil
+	}
+	s.notifyUsers(ctx, adminIDs, model.MsgJoinRequest, &model.JoinRequestContent{
+		RequestID: request.ID,
+		GroupID:   groupID,
+		UserID:    userID,
+		Message:   message,
+	})
+	return nil
+}
+
+// ListJoinRequests 查询入群申请
+func (s *groupServiceImpl) ListJoinRequests(ctx context.Context, groupID, operatorID string, status, page, pageSize int) ([]*model.GroupJoinRequest, int64, error) {
+	role, err := s.GetMemberRole(ctx, groupID, operatorID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if role < model.RoleAdmin {
+		return nil, 0, ErrNotGroupAdmin
+	}
+
+	offset := (page - 1) * pageSize
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.FindJoinRequests(ctx, groupID, status, offset, pageSize)
+}
+
+// HandleJoinRequest 同意或拒绝入群申请，同意时将申请人加入群组（申请人已在群中时只更新申请状态）
+func (s *groupServiceImpl) HandleJoinRequest(ctx context.Context, groupID, operatorID string, requestID uint, approve bool) (*model.GroupJoinRequest, error) {
+	role, err := s.GetMemberRole(ctx, groupID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if role < model.RoleAdmin {
+		return nil, ErrNotGroupAdmin
+	}
+
+	request, err := s.repo.FindJoinRequest(ctx, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("find join request error: %w", err)
+	}
+	if request == nil || request.GroupID != groupID {
+		return nil, ErrJoinRequestNotFound
+	}
+	if request.Status != model.JoinRequestPending {
+		return nil, ErrJoinRequestHandled
+	}
+
+	status := model.JoinRequestRejected
+	if approve {
+		status = model.JoinRequestApproved
+	}
+	now := time.Now()
+
+	var group *model.Group
+	joined := false
+	err = s.repo.Transaction(ctx, func(tx repository.GroupRepository) error {
+		if group, err = lockActiveGroup(ctx, tx, groupID); err != nil {
+			return err
+		}
+
+		ok, err := tx.HandleJoinRequest(ctx, requestID, status, operatorID, now)
+		if err != nil {
+			return fmt.Errorf("update join request error: %w", err)
+		}
+		if !ok {
+			return ErrJoinRequestHandled
+		}
+		if !approve {
+			return nil
+		}
+
+		member, err := tx.FindMember(ctx, groupID, request.UserID)
+		if err != nil {
+			return err
+		}
+		if member != nil {
+			return nil
+		}
+		if group.IsFull() {
+			return ErrGroupFull
+		}
+		if err := tx.AddMembers(ctx, []*model.GroupMember{{
+			GroupID:   groupID,
+			UserID:    request.UserID,
+			Role:      model.RoleMember,
+			InviterID: operatorID,
+			JoinedAt:  now,
+		}}); err != nil {
+			return fmt.Errorf("create member error: %w", err)
+		}
+		if err := tx.IncrMemberCount(ctx, groupID, 1); err != nil {
+			return fmt.Errorf("update member count error: %w", err)
+		}
+		joined = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	request.Status = status
+	request.HandlerID = operatorID
+	request.HandledAt = &now
+
+	if joined {
+		groupKey := fmt.Sprintf("group:members:%s", groupID)
+		s.redis.SAdd(ctx, groupKey, request.UserID)
+		s.notifyGroupEvent(ctx, model.MsgGroupMemberJoin, groupID, operatorID, []string{request.UserID}, nil)
+	}
+
+	s.notifyUsers(ctx, []string{request.UserID}, model.MsgJoinResult, &model.JoinResultContent{
+		RequestID: request.ID,
+		GroupID:   groupID,
+		GroupName: group.Name,
+		Approved:  approve,
+		HandlerID: operatorID,
+	})
+	return request, nil
+}
+
+// notifyUsers 向指定用户发送群相关的系统通知（离线时保存为离线消息）
+func (s *groupServiceImpl) notifyUsers(ctx context.Context, userIDs []string, msgType model.MessageType, content interface{}) {
+	if s.msgDispatcher == nil || len(userIDs) == 0 {
+		return
+	}
+	msg := &model.Message{
+		MessageID: util.GenerateMessageID(),
+		Type:      msgType,
+		From:      "system",
+		Content:   content,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if err := s.msgDispatcher.DispatchToUsers(ctx, userIDs, msg); err != nil {
+		fmt.Printf("dispatch %s error: %v\n", msgType, err)
+	}
 }
 
 // LeaveGroup 离开群组
@@ -395,20 +666,18 @@ func (s *groupServiceImpl) LeaveGroup(ctx context.Context, groupID, userID strin
 
 	// 群主不能直接离开，需要先转让
 	if role == model.RoleOwner {
-		return errors.New("group owner cannot leave, please transfer ownership first")
+		return ErrOwnerCannotLeave
 	}
 
 	// 开启事务
-	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err = s.repo.Transaction(ctx, func(tx repository.GroupRepository) error {
 		// 删除成员
-		if err := tx.Where("group_id = ? AND user_id = ?", groupID, userID).
-			Delete(&model.GroupMember{}).Error; err != nil {
+		if err := tx.RemoveMembers(ctx, groupID, []string{userID}); err != nil {
 			return fmt.Errorf("delete member error: %w", err)
 		}
 
 		// 更新成员数
-		if err := tx.Model(&model.Group{}).Where("group_id = ?", groupID).
-			UpdateColumn("member_count", gorm.Expr("member_count - ?", 1)).Error; err != nil {
+		if err := tx.IncrMemberCount(ctx, groupID, -1); err != nil {
 			return fmt.Errorf("update member count error: %w", err)
 		}
 
@@ -431,44 +700,59 @@ func (s *groupServiceImpl) LeaveGroup(ctx context.Context, groupID, userID strin
 
 // KickMember 踢出成员
 func (s *groupServiceImpl) KickMember(ctx context.Context, groupID, operatorID string, targetIDs []string) error {
-	// 检查操作者权限
-	operatorRole, err := s.GetMemberRole(ctx, groupID, operatorID)
-	if err != nil {
-		return err
-	}
-	if operatorRole < model.RoleAdmin {
-		return ErrNotGroupAdmin
-	}
+	var kicked []string
 
-	// 检查目标用户
-	for _, targetID := range targetIDs {
-		targetRole, err := s.GetMemberRole(ctx, groupID, targetID)
+	// 在事务中锁定群组及相关成员后校验权限，避免校验后操作者被降级或目标被提升
+	err := s.repo.Transaction(ctx, func(tx repository.GroupRepository) error {
+		if _, err := lockActiveGroup(ctx, tx, groupID); err != nil {
+			return err
+		}
+
+		// 检查操作者权限
+		operator, err := tx.FindMemberForUpdate(ctx, groupID, operatorID)
 		if err != nil {
-			continue // 跳过不存在的成员
+			return err
+		}
+		if operator == nil {
+			return ErrNotGroupMember
+		}
+		if operator.Role < model.RoleAdmin {
+			return ErrNotGroupAdmin
 		}
 
-		// 不能踢群主
-		if targetRole == model.RoleOwner {
-			return ErrCannotKickOwner
+		// 检查目标用户
+		for _, targetID := range targetIDs {
+			target, err := tx.FindMemberForUpdate(ctx, groupID, targetID)
+			if err != nil {
+				return err
+			}
+			if target == nil {
+				continue // 跳过不存在的成员
+			}
+
+			// 不能踢群主
+			if target.Role == model.RoleOwner {
+				return ErrCannotKickOwner
+			}
+
+			// 管理员只能踢普通成员，群主可以踢所有人
+			if operator.Role == model.RoleAdmin && target.Role >= model.RoleAdmin {
+				return ErrPermissionDeny
+			}
+			kicked = append(kicked, targetID)
 		}
 
-		// 管理员只能踢普通成员，群主可以踢所有人
-		if operatorRole == model.RoleAdmin && targetRole >= model.RoleAdmin {
-			return ErrPermissionDeny
+		if len(kicked) == 0 {
+			return nil
 		}
-	}
 
-	// 开启事务
-	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// 批量删除成员
-		if err := tx.Where("group_id = ? AND user_id IN ?", groupID, targetIDs).
-			Delete(&model.GroupMember{}).Error; err != nil {
+		if err := tx.RemoveMembers(ctx, groupID, kicked); err != nil {
 			return fmt.Errorf("delete members error: %w", err)
 		}
 
 		// 更新成员数
-		if err := tx.Model(&model.Group{}).Where("group_id = ?", groupID).
-			UpdateColumn("member_count", gorm.Expr("member_count - ?", len(targetIDs))).Error; err != nil {
+		if err := tx.IncrMemberCount(ctx, groupID, -len(kicked)); err != nil {
 			return fmt.Errorf("update member count error: %w", err)
 		}
 
@@ -478,143 +762,138 @@ func (s *groupServiceImpl) KickMember(ctx context.Context, groupID, operatorID s
 	if err != nil {
 		return err
 	}
+	if len(kicked) == 0 {
+		return nil
+	}
 
 	// 更新Redis中的群成员
 	groupKey := fmt.Sprintf("group:members:%s", groupID)
-	for _, targetID := range targetIDs {
+	for _, targetID := range kicked {
 		s.redis.SRem(ctx, groupKey, targetID)
 	}
 
 	// 发送成员被踢通知
-	s.notifyGroupEvent(ctx, model.MsgGroupMemberKicked, groupID, operatorID, targetIDs, nil)
+	s.notifyGroupEvent(ctx, model.MsgGroupMemberKicked, groupID, operatorID, kicked, nil)
 
 	return nil
 }
 
 // GetGroupMembers 获取群成员列表
 func (s *groupServiceImpl) GetGroupMembers(ctx context.Context, groupID string, page, pageSize int) ([]*model.GroupMember, int64, error) {
-	var members []*model.GroupMember
-	var total int64
-
 	// 计算偏移量
 	offset := (page - 1) * pageSize
 	if offset < 0 {
 		offset = 0
 	}
 
-	// 查询总数
-	if err := s.db.WithContext(ctx).Model(&model.GroupMember{}).
-		Where("group_id = ?", groupID).
-		Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	// 查询成员列表（按角色降序，群主在前）
-	if err := s.db.WithContext(ctx).
-		Where("group_id = ?", groupID).
-		Order("role DESC, joined_at ASC").
-		Offset(offset).
-		Limit(pageSize).
-		Find(&members).Error; err != nil {
-		return nil, 0, err
-	}
-
-	return members, total, nil
+	return s.repo.FindMembers(ctx, groupID, offset, pageSize)
 }
 
 // SetAdmin 设置/取消管理员
 func (s *groupServiceImpl) SetAdmin(ctx context.Context, groupID, operatorID, targetID string, isAdmin bool) error {
-	// 只有群主可以设置管理员
-	operatorRole, err := s.GetMemberRole(ctx, groupID, operatorID)
-	if err != nil {
-		return err
-	}
-	if operatorRole != model.RoleOwner {
-		return ErrNotGroupOwner
-	}
-
-	// 检查目标用户是否为成员
-	targetRole, err := s.GetMemberRole(ctx, groupID, targetID)
-	if err != nil {
-		return err
-	}
-	if targetRole == model.RoleOwner {
-		return errors.New("cannot change owner's role")
-	}
-
-	// 更新角色
 	newRole := model.RoleMember
 	if isAdmin {
 		newRole = model.RoleAdmin
 	}
 
-	if err := s.db.WithContext(ctx).Model(&model.GroupMember{}).
-		Where("group_id = ? AND user_id = ?", groupID, targetID).
-		Update("role", newRole).Error; err != nil {
+	changed := false
+	err := s.repo.Transaction(ctx, func(tx repository.GroupRepository) error {
+		if _, err := lockActiveGroup(ctx, tx, groupID); err != nil {
+			return err
+		}
+
+		// 只有群主可以设置管理员
+		operator, err := tx.FindMemberForUpdate(ctx, groupID, operatorID)
+		if err != nil {
+			return err
+		}
+		if operator == nil || operator.Role != model.RoleOwner {
+			return ErrNotGroupOwner
+		}
+
+		// 检查目标用户是否为成员
+		target, err := tx.FindMemberForUpdate(ctx, groupID, targetID)
+		if err != nil {
+			return err
+		}
+		if target == nil {
+			return ErrNotGroupMember
+		}
+		if target.Role == model.RoleOwner {
+			return errors.New("cannot change owner's role")
+		}
+		if target.Role == newRole {
+			return nil
+		}
+
+		// 更新角色
+		if err := tx.UpdateMemberWithVersion(ctx, groupID, targetID, target.Version, map[string]interface{}{"role": newRole}); err != nil {
+			return groupConflict(err)
+		}
+		changed = true
+		return nil
+	})
+
+	if err != nil || !changed {
 		return err
 	}
+	s.redis.Del(ctx, groupMuteCacheKey(groupID))
 
 	// 发送管理员变更通知
-	extra := map[string]string{
-		"action": "set_admin",
-	}
-	if !isAdmin {
-		extra["action"] = "remove_admin"
-	}
-	s.notifyGroupEvent(ctx, model.MsgGroupAdminChange, groupID, operatorID, []string{targetID}, extra)
+	s.notifyGroupEvent(ctx, model.MsgGroupAdminChange, groupID, operatorID, []string{targetID}, &model.GroupAdminChangePayload{IsAdmin: isAdmin})
 
 	return nil
 }
 
 // TransferOwner 转让群主
 func (s *groupServiceImpl) TransferOwner(ctx context.Context, groupID, ownerID, newOwnerID string) error {
-	// 检查是否为群主
-	role, err := s.GetMemberRole(ctx, groupID, ownerID)
-	if err != nil {
-		return err
-	}
-	if role != model.RoleOwner {
-		return ErrNotGroupOwner
-	}
-
-	// 检查新群主是否为成员
-	isMember, err := s.IsMember(ctx, groupID, newOwnerID)
-	if err != nil {
-		return err
-	}
-	if !isMember {
-		return ErrNotGroupMember
+	if newOwnerID == ownerID {
+		return ErrInvalidRequest
 	}
 
-	// 开启事务
-	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// 原群主变为管理员
-		if err := tx.Model(&model.GroupMember{}).
-			Where("group_id = ? AND user_id = ?", groupID, ownerID).
-			Update("role", model.RoleAdmin).Error; err != nil {
+	// 开启事务，按 群组 -> 原群主 -> 新群主 的顺序加锁，并发转让时后到者等待后按版本号失败
+	err := s.repo.Transaction(ctx, func(tx repository.GroupRepository) error {
+		group, err := lockActiveGroup(ctx, tx, groupID)
+		if err != nil {
 			return err
 		}
 
-		// 新群主
-		if err := tx.Model(&model.GroupMember{}).
-			Where("group_id = ? AND user_id = ?", groupID, newOwnerID).
-			Update("role", model.RoleOwner).Error; err != nil {
+		// 检查是否为群主
+		owner, err := tx.FindMemberForUpdate(ctx, groupID, ownerID)
+		if err != nil {
 			return err
 		}
+		if group.OwnerID != ownerID || owner == nil || owner.Role != model.RoleOwner {
+			return ErrNotGroupOwner
+		}
 
-		// 更新群组的owner_id
-		if err := tx.Model(&model.Group{}).
-			Where("group_id = ?", groupID).
-			Update("owner_id", newOwnerID).Error; err != nil {
+		// 检查新群主是否为成员
+		newOwner, err := tx.FindMemberForUpdate(ctx, groupID, newOwnerID)
+		if err != nil {
 			return err
 		}
+		if newOwner == nil {
+			return ErrNotGroupMember
+		}
 
-		return nil
+		// 原群主变为管理员
+		if err := tx.UpdateMemberWithVersion(ctx, groupID, ownerID, owner.Version, map[string]interface{}{"role": model.RoleAdmin}); err != nil {
+			return groupConflict(err)
+		}
+
+		// 新群主
+		if err := tx.UpdateMemberWithVersion(ctx, groupID, newOwnerID, newOwner.Version, map[string]interface{}{"role": model.RoleOwner}); err != nil {
+			return groupConflict(err)
+		}
+
+		// 更新群组的owner_id
+		return groupConflict(tx.UpdateWithVersion(ctx, groupID, group.Version, map[string]interface{}{"owner_id": newOwnerID}))
 	})
 
 	if err != nil {
 		return err
 	}
+	s.redis.Del(ctx, groupMuteCacheKey(groupID))
 
 	// 发送群主转让通知
 	s.notifyGroupEvent(ctx, model.MsgGroupTransfer, groupID, ownerID, []string{newOwnerID}, nil)
@@ -622,6 +901,29 @@ func (s *groupServiceImpl) TransferOwner(ctx context.Context, groupID, ownerID,
 	return nil
 }
 
+// lockActiveGroup 在事务中锁定正常状态的群组，作为同一群角色变更的串行化点
+func lockActiveGroup(ctx context.Context, tx repository.GroupRepository, groupID string) (*model.Group, error) {
+	group, err := tx.FindByIDForUpdate(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, ErrGroupNotFound
+	}
+	if !group.IsActive() {
+		return nil, ErrGroupDismissed
+	}
+	return group, nil
+}
+
+// groupConflict 将乐观锁版本冲突转换为可重试的群组并发修改错误
+func groupConflict(err error) error {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return ErrGroupConflict
+	}
+	return err
+}
+
 // MuteMember 禁言成员
 func (s *groupServiceImpl) MuteMember(ctx context.Context, groupID, operatorID, targetID string, duration time.Duration) error {
 	// 检查操作者权限
@@ -650,17 +952,17 @@ func (s *groupServiceImpl) MuteMember(ctx context.Context, groupID, operatorID,
 		muteUntil = 0 // 取消禁言
 	}
 
-	if err := s.db.WithContext(ctx).Model(&model.GroupMember{}).
-		Where("group_id = ? AND user_id = ?", groupID, targetID).
-		Update("mute_until", muteUntil).Error; err != nil {
+	if err := s.repo.UpdateMember(ctx, groupID, targetID, map[string]interface{}{"mute_until": muteUntil}); err != nil {
 		return err
 	}
+	s.redis.Del(ctx, groupMuteCacheKey(groupID))
 
 	// 发送禁言通知
-	extra := map[string]string{
-		"duration": fmt.Sprintf("%d", int(duration.Seconds())),
+	payload := &model.GroupMemberMutePayload{
+		DurationSeconds: int64(duration.Seconds()),
+		MuteUntil:       muteUntil,
 	}
-	s.notifyGroupEvent(ctx, model.MsgGroupMute, groupID, operatorID, []string{targetID}, extra)
+	s.notifyGroupEvent(ctx, model.MsgGroupMute, groupID, operatorID, []string{targetID}, payload)
 
 	return nil
 }
@@ -676,38 +978,90 @@ func (s *groupServiceImpl) SetMuteAll(ctx context.Context, groupID, operatorID s
 		return ErrNotGroupAdmin
 	}
 
-	if err := s.db.WithContext(ctx).Model(&model.Group{}).
-		Where("group_id = ?", groupID).
-		Update("mute_all", muteAll).Error; err != nil {
+	if err := s.repo.Update(ctx, groupID, map[string]interface{}{"mute_all": muteAll}); err != nil {
 		return err
 	}
+	s.redis.Del(ctx, groupMuteCacheKey(groupID))
 
 	// 发送全员禁言通知
-	extra := map[string]string{
-		"mute_all": fmt.Sprintf("%t", muteAll),
-	}
-	s.notifyGroupEvent(ctx, model.MsgGroupMute, groupID, operatorID, nil, extra)
+	s.notifyGroupEvent(ctx, model.MsgGroupMute, groupID, operatorID, nil, &model.GroupMuteAllPayload{MuteAll: muteAll})
 
 	return nil
 }
 
-// GetUserGroups 获取用户所在的群组列表
-func (s *groupServiceImpl) GetUserGroups(ctx context.Context, userID string) ([]*model.Group, error) {
-	var groups []*model.Group
+// CheckMute 检查发送者是否被禁言：个人禁言未到期，或全员禁言且发送者不是群主、管理员
+// 禁言状态按群缓存在Redis（全员禁言标记及发送者的角色、禁言截止时间），避免每条群消息都查询数据库
+func (s *groupServiceImpl) CheckMute(ctx context.Context, groupID, userID string) (*model.GroupMuteStatus, error) {
+	muteAll, role, muteUntil, err := s.loadMuteState(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
 
-	// 子查询获取用户所在的群组ID
-	subQuery := s.db.WithContext(ctx).Model(&model.GroupMember{}).
-		Select("group_id").
-		Where("user_id = ?", userID)
+	if muteUntil > time.Now().Unix() {
+		return &model.GroupMuteStatus{MuteAll: muteAll, MuteUntil: muteUntil}, ErrMemberMuted
+	}
+	if muteAll && role < model.RoleAdmin {
+		return &model.GroupMuteStatus{MuteAll: true}, ErrGroupMuteAll
+	}
+	return nil, nil
+}
 
-	if err := s.db.WithContext(ctx).
-		Where("group_id IN (?) AND status = ?", subQuery, model.GroupStatusNormal).
-		Order("updated_at DESC").
-		Find(&groups).Error; err != nil {
-		return nil, err
+// GetSenderRole 获取群消息发送者的角色
+func (s *groupServiceImpl) GetSenderRole(ctx context.Context, groupID, userID string) (model.GroupRole, error) {
+	_, role, _, err := s.loadMuteState(ctx, groupID, userID)
+	return role, err
+}
+
+// loadMuteState 读取全员禁言标记及成员的角色、禁言截止时间，缓存未命中时从数据库加载
+// 非成员按普通成员处理（成员资格由分发前检查负责）
+func (s *groupServiceImpl) loadMuteState(ctx context.Context, groupID, userID string) (bool, model.GroupRole, int64, error) {
+	key := groupMuteCacheKey(groupID)
+	values, err := s.redis.HMGet(ctx, key, groupMuteAllField, userID).Result()
+	if err == nil && len(values) == 2 {
+		allValue, allOK := values[0].(string)
+		memberValue, memberOK := values[1].(string)
+		if allOK && memberOK {
+			var role, muteUntil int64
+			if _, err := fmt.Sscanf(memberValue, "%d:%d", &role, &muteUntil); err == nil {
+				return allValue == "1", model.GroupRole(role), muteUntil, nil
+			}
+		}
+	}
+
+	group, err := s.repo.FindByID(ctx, groupID)
+	if err != nil {
+		return false, 0, 0, err
+	}
+	if group == nil {
+		return false, 0, 0, nil
+	}
+	member, err := s.repo.FindMember(ctx, groupID, userID)
+	if err != nil {
+		return false, 0, 0, err
 	}
 
-	return groups, nil
+	role, muteUntil := model.RoleMember, int64(0)
+	if member != nil {
+		role, muteUntil = member.Role, member.MuteUntil
+	}
+	allValue := "0"
+	if group.MuteAll {
+		allValue = "1"
+	}
+	s.redis.HSet(ctx, key, groupMuteAllField, allValue, userID, fmt.Sprintf("%d:%d", role, muteUntil))
+	s.redis.Expire(ctx, key, groupMuteCacheTTL)
+
+	return group.MuteAll, role, muteUntil, nil
+}
+
+// groupMuteCacheKey 群禁言状态缓存Key
+func groupMuteCacheKey(groupID string) string {
+	return fmt.Sprintf("group:mute:%s", groupID)
+}
+
+// GetUserGroups 获取用户所在的群组列表
+func (s *groupServiceImpl) GetUserGroups(ctx context.Context, userID string) ([]*model.Group, error) {
+	return s.repo.FindUserGroups(ctx, userID)
 }
 
 // IsMember 检查用户是否为群成员
@@ -720,27 +1074,23 @@ func (s *groupServiceImpl) IsMember(ctx context.Context, groupID, userID string)
 	}
 
 	// Redis没有或出错，从数据库检查
-	var count int64
-	if err := s.db.WithContext(ctx).Model(&model.GroupMember{}).
-		Where("group_id = ? AND user_id = ?", groupID, userID).
-		Count(&count).Error; err != nil {
+	member, err := s.repo.FindMember(ctx, groupID, userID)
+	if err != nil {
 		return false, err
 	}
 
-	return count > 0, nil
+	return member != nil, nil
 }
 
 // GetMemberRole 获取成员角色
 func (s *groupServiceImpl) GetMemberRole(ctx context.Context, groupID, userID string) (model.GroupRole, error) {
-	var member model.GroupMember
-	if err := s.db.WithContext(ctx).
-		Where("group_id = ? AND user_id = ?", groupID, userID).
-		First(&member).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return 0, ErrNotGroupMember
-		}
+	member, err := s.repo.FindMember(ctx, groupID, userID)
+	if err != nil {
 		return 0, err
 	}
+	if member == nil {
+		return 0, ErrNotGroupMember
+	}
 	return member.Role, nil
 }
 
@@ -754,10 +1104,8 @@ func (s *groupServiceImpl) GetGroupMemberIDs(ctx context.Context, groupID string
 	}
 
 	// 从数据库获取
-	var memberIDs []string
-	if err := s.db.WithContext(ctx).Model(&model.GroupMember{}).
-		Where("group_id = ?", groupID).
-		Pluck("user_id", &memberIDs).Error; err != nil {
+	memberIDs, err := s.repo.FindMemberIDs(ctx, groupID, model.RoleMember)
+	if err != nil {
 		return nil, err
 	}
 
@@ -793,33 +1141,6 @@ func (s *groupServiceImpl) syncGroupMembersToRedis(ctx context.Context, groupID
 	return nil
 }
 
-// notifyGroupEvent 发送群事件通知
-func (s *groupServiceImpl) notifyGroupEvent(ctx context.Context, eventType model.MessageType, groupID, operatorID string, targetIDs []string, extra map[string]string) {
-	if s.msgDispatcher == nil {
-		return
-	}
-
-	// 获取群成员
-	memberIDs, err := s.GetGroupMemberIDs(ctx, groupID)
-	if err != nil {
-		fmt.Printf("get group member IDs error: %v\n", err)
-		return
-	}
-
-	// 构建消息
-	msg := model.NewGroupEventMessage(eventType, groupID, operatorID, targetIDs)
-	if extra != nil {
-		if content, ok := msg.Content.(*model.GroupEventContent); ok {
-			content.Extra = extra
-		}
-	}
-
-	// 分发给所有群成员
-	if err := s.msgDispatcher.DispatchToUsers(ctx, memberIDs, msg); err != nil {
-		fmt.Printf("dispatch group event error: %v\n", err)
-	}
-}
-
 // uniqueStrings 字符串去重
 func uniqueStrings(strs []string) []string {
 	seen := make(map[string]bool)
@@ -0,0 +1,418 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/internal/repository/memory"
+	"github.com/go-redis/redis/v8"
+)
+
+// newTestRedis 启动 miniredis 并返回连接它的客户端，测试结束时关闭
+func newTestRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// recordingDispatcher 记录分发的群通知
+type recordingDispatcher struct {
+	mu   sync.Mutex
+	msgs []*model.Message
+}
+
+func (d *recordingDispatcher) DispatchToUsers(ctx context.Context, userIDs []string, msg *model.Message) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.msgs = append(d.msgs, msg)
+	return nil
+}
+
+// types 已分发通知的消息类型
+func (d *recordingDispatcher) types() []model.MessageType {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	types := make([]model.MessageType, len(d.msgs))
+	for i, msg := range d.msgs {
+		types[i] = msg.Type
+	}
+	return types
+}
+
+// newTestGroupService 基于内存仓库和 miniredis 创建群组服务
+func newTestGroupService(t *testing.T) (GroupService, *memory.GroupRepository, *recordingDispatcher) {
+	t.Helper()
+	repo := memory.NewGroupRepository()
+	dispatcher := &recordingDispatcher{}
+	return NewGroupService(repo, newTestRedis(t), dispatcher, nil), repo, dispatcher
+}
+
+// createTestGroup 创建群主为 owner、初始成员为 members 的群组
+func createTestGroup(t *testing.T, svc GroupService, owner string, members ...string) *model.Group {
+	t.Helper()
+	group, err := svc.CreateGroup(context.Background(), &model.CreateGroupRequest{
+		OwnerID:   owner,
+		Name:      "test",
+		MemberIDs: members,
+	})
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	return group
+}
+
+// assertRole 检查成员角色
+func assertRole(t *testing.T, svc GroupService, groupID, userID string, role model.GroupRole) {
+	t.Helper()
+	got, err := svc.GetMemberRole(context.Background(), groupID, userID)
+	if err != nil || got != role {
+		t.Fatalf("GetMemberRole(%s) = %v, %v; want %v", userID, got, err, role)
+	}
+}
+
+// assertNotMember 检查用户不是群成员
+func assertNotMember(t *testing.T, svc GroupService, groupID, userID string) {
+	t.Helper()
+	if got, err := svc.GetMemberRole(context.Background(), groupID, userID); !errors.Is(err, ErrNotGroupMember) {
+		t.Fatalf("GetMemberRole(%s) = %v, %v; want ErrNotGroupMember", userID, got, err)
+	}
+}
+
+func TestGroupServiceCreateGroup(t *testing.T) {
+	ctx := context.Background()
+	svc, _, dispatcher := newTestGroupService(t)
+
+	group := createTestGroup(t, svc, "owner", "u1", "u2", "u1", "owner")
+	if group.MemberCount != 3 {
+		t.Fatalf("MemberCount = %d, want 3", group.MemberCount)
+	}
+	assertRole(t, svc, group.GroupID, "owner", model.RoleOwner)
+	assertRole(t, svc, group.GroupID, "u1", model.RoleMember)
+
+	stored, err := svc.GetGroupInfo(ctx, group.GroupID)
+	if err != nil || stored.MemberCount != 3 || stored.OwnerID != "owner" {
+		t.Fatalf("GetGroupInfo = %+v, %v", stored, err)
+	}
+
+	memberIDs, err := svc.GetGroupMemberIDs(ctx, group.GroupID)
+	if err != nil {
+		t.Fatalf("GetGroupMemberIDs: %v", err)
+	}
+	sort.Strings(memberIDs)
+	if len(memberIDs) != 3 || memberIDs[0] != "owner" || memberIDs[1] != "u1" || memberIDs[2] != "u2" {
+		t.Fatalf("GetGroupMemberIDs = %v", memberIDs)
+	}
+
+	if types := dispatcher.types(); len(types) != 1 || types[0] != model.MsgGroupCreated {
+		t.Fatalf("notifications = %v, want [MsgGroupCreated]", types)
+	}
+
+	if _, err := svc.CreateGroup(ctx, &model.CreateGroupRequest{OwnerID: "owner"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("CreateGroup without name = %v, want ErrInvalidRequest", err)
+	}
+}
+
+func TestGroupServiceJoinGroup(t *testing.T) {
+	ctx := context.Background()
+	svc, _, dispatcher := newTestGroupService(t)
+	group := createTestGroup(t, svc, "owner")
+
+	if err := svc.JoinGroup(ctx, group.GroupID, "u1", ""); err != nil {
+		t.Fatalf("JoinGroup: %v", err)
+	}
+	assertRole(t, svc, group.GroupID, "u1", model.RoleMember)
+	if ok, err := svc.IsMember(ctx, group.GroupID, "u1"); err != nil || !ok {
+		t.Fatalf("IsMember = %v, %v", ok, err)
+	}
+	if err := svc.JoinGroup(ctx, group.GroupID, "u1", ""); !errors.Is(err, ErrAlreadyInGroup) {
+		t.Fatalf("JoinGroup twice = %v, want ErrAlreadyInGroup", err)
+	}
+
+	stored, _ := svc.GetGroupInfo(ctx, group.GroupID)
+	if stored.MemberCount != 2 {
+		t.Fatalf("MemberCount = %d, want 2", stored.MemberCount)
+	}
+
+	types := dispatcher.types()
+	if len(types) != 2 || types[1] != model.MsgGroupMemberJoin {
+		t.Fatalf("notifications = %v, want MsgGroupMemberJoin last", types)
+	}
+}
+
+func TestGroupServiceJoinGroupNeedApproval(t *testing.T) {
+	ctx := context.Background()
+	svc, repo, _ := newTestGroupService(t)
+	group := createTestGroup(t, svc, "owner")
+	if err := repo.Update(ctx, group.GroupID, map[string]interface{}{"join_mode": model.JoinModeApproval}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	if err := svc.JoinGroup(ctx, group.GroupID, "u1", ""); err != nil {
+		t.Fatalf("JoinGroup: %v", err)
+	}
+	assertNotMember(t, svc, group.GroupID, "u1")
+
+	requests := repo.JoinRequests()
+	if len(requests) != 1 || requests[0].UserID != "u1" || requests[0].Status != model.JoinRequestPending {
+		t.Fatalf("join requests = %+v", requests)
+	}
+
+	if _, err := svc.HandleJoinRequest(ctx, group.GroupID, "owner", requests[0].ID, true); err != nil {
+		t.Fatalf("HandleJoinRequest: %v", err)
+	}
+	assertRole(t, svc, group.GroupID, "u1", model.RoleMember)
+	if _, err := svc.HandleJoinRequest(ctx, group.GroupID, "owner", requests[0].ID, true); !errors.Is(err, ErrJoinRequestHandled) {
+		t.Fatalf("HandleJoinRequest twice = %v, want ErrJoinRequestHandled", err)
+	}
+}
+
+func TestGroupServiceKickMember(t *testing.T) {
+	ctx := context.Background()
+	svc, _, dispatcher := newTestGroupService(t)
+	group := createTestGroup(t, svc, "owner", "admin", "u1", "u2")
+	if err := svc.SetAdmin(ctx, group.GroupID, "owner", "admin", true); err != nil {
+		t.Fatalf("SetAdmin: %v", err)
+	}
+
+	if err := svc.KickMember(ctx, group.GroupID, "u1", []string{"u2"}); !errors.Is(err, ErrNotGroupAdmin) {
+		t.Fatalf("member kick = %v, want ErrNotGroupAdmin", err)
+	}
+	if err := svc.KickMember(ctx, group.GroupID, "admin", []string{"owner"}); !errors.Is(err, ErrCannotKickOwner) {
+		t.Fatalf("kick owner = %v, want ErrCannotKickOwner", err)
+	}
+	if err := svc.KickMember(ctx, group.GroupID, "outsider", []string{"u1"}); !errors.Is(err, ErrNotGroupMember) {
+		t.Fatalf("outsider kick = %v, want ErrNotGroupMember", err)
+	}
+
+	if err := svc.KickMember(ctx, group.GroupID, "admin", []string{"u1", "missing"}); err != nil {
+		t.Fatalf("KickMember: %v", err)
+	}
+	assertNotMember(t, svc, group.GroupID, "u1")
+	if ok, _ := svc.IsMember(ctx, group.GroupID, "u1"); ok {
+		t.Fatal("kicked member is still cached as a member")
+	}
+
+	// 管理员不能踢管理员，群主可以
+	if err := svc.SetAdmin(ctx, group.GroupID, "owner", "u2", true); err != nil {
+		t.Fatalf("SetAdmin: %v", err)
+	}
+	if err := svc.KickMember(ctx, group.GroupID, "admin", []string{"u2"}); !errors.Is(err, ErrPermissionDeny) {
+		t.Fatalf("admin kick admin = %v, want ErrPermissionDeny", err)
+	}
+	if err := svc.KickMember(ctx, group.GroupID, "owner", []string{"u2"}); err != nil {
+		t.Fatalf("owner kick admin: %v", err)
+	}
+
+	stored, _ := svc.GetGroupInfo(ctx, group.GroupID)
+	if stored.MemberCount != 2 {
+		t.Fatalf("MemberCount = %d, want 2", stored.MemberCount)
+	}
+
+	kicks := 0
+	for _, typ := range dispatcher.types() {
+		if typ == model.MsgGroupMemberKicked {
+			kicks++
+		}
+	}
+	if kicks != 2 {
+		t.Fatalf("kick notifications = %d, want 2", kicks)
+	}
+}
+
+func TestGroupServiceTransferOwner(t *testing.T) {
+	ctx := context.Background()
+	svc, _, dispatcher := newTestGroupService(t)
+	group := createTestGroup(t, svc, "owner", "u1")
+
+	if err := svc.TransferOwner(ctx, group.GroupID, "owner", "owner"); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("transfer to self = %v, want ErrInvalidRequest", err)
+	}
+	if err := svc.TransferOwner(ctx, group.GroupID, "u1", "owner"); !errors.Is(err, ErrNotGroupOwner) {
+		t.Fatalf("transfer by member = %v, want ErrNotGroupOwner", err)
+	}
+	if err := svc.TransferOwner(ctx, group.GroupID, "owner", "outsider"); !errors.Is(err, ErrNotGroupMember) {
+		t.Fatalf("transfer to outsider = %v, want ErrNotGroupMember", err)
+	}
+
+	if err := svc.TransferOwner(ctx, group.GroupID, "owner", "u1"); err != nil {
+		t.Fatalf("TransferOwner: %v", err)
+	}
+	assertRole(t, svc, group.GroupID, "u1", model.RoleOwner)
+	assertRole(t, svc, group.GroupID, "owner", model.RoleAdmin)
+
+	stored, _ := svc.GetGroupInfo(ctx, group.GroupID)
+	if stored.OwnerID != "u1" {
+		t.Fatalf("OwnerID = %s, want u1", stored.OwnerID)
+	}
+	if err := svc.LeaveGroup(ctx, group.GroupID, "u1"); !errors.Is(err, ErrOwnerCannotLeave) {
+		t.Fatalf("new owner leave = %v, want ErrOwnerCannotLeave", err)
+	}
+	if err := svc.LeaveGroup(ctx, group.GroupID, "owner"); err != nil {
+		t.Fatalf("former owner leave: %v", err)
+	}
+
+	types := dispatcher.types()
+	found := false
+	for _, typ := range types {
+		found = found || typ == model.MsgGroupTransfer
+	}
+	if !found {
+		t.Fatalf("notifications = %v, want MsgGroupTransfer", types)
+	}
+}
+
+// hookedGroupRepository 按版本更新成员前调用 beforeUpdate，用于构造并发事务的交错顺序
+type hookedGroupRepository struct {
+	*memory.GroupRepository
+	beforeUpdate func()
+}
+
+func (r *hookedGroupRepository) Transaction(ctx context.Context, fn func(tx repository.GroupRepository) error) error {
+	return fn(r)
+}
+
+func (r *hookedGroupRepository) UpdateMemberWithVersion(ctx context.Context, groupID, userID string, version int64, updates map[string]interface{}) error {
+	if r.beforeUpdate != nil {
+		r.beforeUpdate()
+	}
+	return r.GroupRepository.UpdateMemberWithVersion(ctx, groupID, userID, version, updates)
+}
+
+// rendezvous 前 n 次 wait 互相等待，全部到达后一起放行，之后的调用直接返回
+type rendezvous struct {
+	mu      sync.Mutex
+	pending int
+	ready   chan struct{}
+}
+
+func newRendezvous(n int) *rendezvous {
+	return &rendezvous{pending: n, ready: make(chan struct{})}
+}
+
+func (r *rendezvous) wait() {
+	r.mu.Lock()
+	if r.pending > 0 {
+		r.pending--
+		if r.pending == 0 {
+			close(r.ready)
+		}
+	}
+	r.mu.Unlock()
+	<-r.ready
+}
+
+func TestGroupServiceConcurrentTransferOwner(t *testing.T) {
+	ctx := context.Background()
+	repo := &hookedGroupRepository{GroupRepository: memory.NewGroupRepository()}
+	svc := NewGroupService(repo, newTestRedis(t), nil, nil)
+	group := createTestGroup(t, svc, "owner", "u1", "u2")
+
+	// 两次转让都读取到原群主后再写入，后写入者版本号过期
+	repo.beforeUpdate = newRendezvous(2).wait
+
+	targets := []string{"u1", "u2"}
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			errs[i] = svc.TransferOwner(ctx, group.GroupID, "owner", target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	winner := ""
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			if winner != "" {
+				t.Fatalf("both transfers succeeded")
+			}
+			winner = targets[i]
+		case !errors.Is(err, ErrGroupConflict):
+			t.Fatalf("TransferOwner(%s) = %v, want ErrGroupConflict", targets[i], err)
+		}
+	}
+	if winner == "" {
+		t.Fatalf("no transfer succeeded: %v", errs)
+	}
+
+	stored, _ := svc.GetGroupInfo(ctx, group.GroupID)
+	if stored.OwnerID != winner {
+		t.Fatalf("OwnerID = %s, want %s", stored.OwnerID, winner)
+	}
+	assertRole(t, svc, group.GroupID, winner, model.RoleOwner)
+	assertRole(t, svc, group.GroupID, "owner", model.RoleAdmin)
+	for _, target := range targets {
+		if target != winner {
+			assertRole(t, svc, group.GroupID, target, model.RoleMember)
+		}
+	}
+}
+
+func TestGroupServiceSetAdminRacingKickMember(t *testing.T) {
+	ctx := context.Background()
+	repo := &hookedGroupRepository{GroupRepository: memory.NewGroupRepository()}
+	svc := NewGroupService(repo, newTestRedis(t), nil, nil)
+	group := createTestGroup(t, svc, "owner", "admin", "u1")
+	if err := svc.SetAdmin(ctx, group.GroupID, "owner", "admin", true); err != nil {
+		t.Fatalf("SetAdmin: %v", err)
+	}
+
+	// 降级读取到目标成员后暂停，等待踢人完成再写入
+	reached := make(chan struct{})
+	kicked := make(chan struct{})
+	var once sync.Once
+	repo.beforeUpdate = func() {
+		once.Do(func() { close(reached) })
+		<-kicked
+	}
+
+	demoteErr := make(chan error, 1)
+	go func() {
+		demoteErr <- svc.SetAdmin(ctx, group.GroupID, "owner", "admin", false)
+	}()
+
+	<-reached
+	kickErr := svc.KickMember(ctx, group.GroupID, "owner", []string{"admin"})
+	close(kicked)
+
+	if kickErr != nil {
+		t.Fatalf("KickMember: %v", kickErr)
+	}
+	if err := <-demoteErr; !errors.Is(err, ErrGroupConflict) {
+		t.Fatalf("SetAdmin racing kick = %v, want ErrGroupConflict", err)
+	}
+
+	// 降级失败不能让被踢的成员重新出现
+	assertNotMember(t, svc, group.GroupID, "admin")
+	stored, _ := svc.GetGroupInfo(ctx, group.GroupID)
+	if stored.MemberCount != 2 {
+		t.Fatalf("MemberCount = %d, want 2", stored.MemberCount)
+	}
+
+	// 反过来先降级，被降级的管理员不能再踢人
+	repo.beforeUpdate = nil
+	if err := svc.JoinGroup(ctx, group.GroupID, "admin", ""); err != nil {
+		t.Fatalf("JoinGroup: %v", err)
+	}
+	if err := svc.SetAdmin(ctx, group.GroupID, "owner", "admin", true); err != nil {
+		t.Fatalf("SetAdmin: %v", err)
+	}
+	if err := svc.SetAdmin(ctx, group.GroupID, "owner", "admin", false); err != nil {
+		t.Fatalf("SetAdmin demote: %v", err)
+	}
+	if err := svc.KickMember(ctx, group.GroupID, "admin", []string{"u1"}); !errors.Is(err, ErrNotGroupAdmin) {
+		t.Fatalf("demoted admin kick = %v, want ErrNotGroupAdmin", err)
+	}
+	assertRole(t, svc, group.GroupID, "u1", model.RoleMember)
+}
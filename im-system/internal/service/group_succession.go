@@ -0,0 +1,367 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/go-redis/redis/v8"
+)
+
+// GroupSuccessionConfig 群主继任配置
+type GroupSuccessionConfig struct {
+	DismissGracePeriod time.Duration // 无可继任成员时，解散前的宽限期
+	PollInterval       time.Duration // 到期解散扫描间隔
+	CandidatePageSize  int           // 查找继任者时每页成员数
+	LegacyExtra        bool          // 群事件是否同时下发旧版 extra 字段（弃用过渡期）
+}
+
+// DefaultGroupSuccessionConfig 默认群主继任配置
+func DefaultGroupSuccessionConfig() *GroupSuccessionConfig {
+	return &GroupSuccessionConfig{
+		DismissGracePeriod: 7 * 24 * time.Hour,
+		PollInterval:       time.Minute,
+		CandidatePageSize:  100,
+		LegacyExtra:        true,
+	}
+}
+
+// GroupSuccessionService 群主继任服务
+// 群主账号被禁用或注销时，按 最早加入的管理员 > 最早加入的成员 的顺序移交群主；
+// 没有可继任的正常账号时，宽限期后自动解散（宽限期内原群主账号恢复则取消）。
+type GroupSuccessionService interface {
+	AccountLifecycleListener
+
+	// GetSuccessions 查询群的继任审计记录
+	GetSuccessions(ctx context.Context, groupID string, limit int) ([]*model.GroupOwnerSuccession, error)
+
+	// DismissDue 处理宽限期已到的群，返回处理数量
+	DismissDue(ctx context.Context) (int, error)
+
+	// Start 启动到期解散扫描
+	Start(ctx context.Context)
+}
+
+// groupSuccessionServiceImpl 群主继任服务实现
+type groupSuccessionServiceImpl struct {
+	repo          repository.GroupRepository
+	userRepo      repository.UserRepository
+	redis         *redis.Client
+	msgDispatcher MessageDispatcher
+	config        *GroupSuccessionConfig
+}
+
+// NewGroupSuccessionService 创建群主继任服务
+func NewGroupSuccessionService(
+	repo repository.GroupRepository,
+	userRepo repository.UserRepository,
+	redisClient *redis.Client,
+	dispatcher MessageDispatcher,
+	config *GroupSuccessionConfig,
+) GroupSuccessionService {
+	if config == nil {
+		config = DefaultGroupSuccessionConfig()
+	}
+	return &groupSuccessionServiceImpl{
+		repo:          repo,
+		userRepo:      userRepo,
+		redis:         redisClient,
+		msgDispatcher: dispatcher,
+		config:        config,
+	}
+}
+
+// OnAccountEvent 处理账号生命周期事件
+func (s *groupSuccessionServiceImpl) OnAccountEvent(ctx context.Context, userID string, event AccountEvent) error {
+	switch event {
+	case AccountDisabled:
+		return s.succeedOwner(ctx, userID, model.SuccessionOwnerDisabled)
+	case AccountDeleted:
+		return s.succeedOwner(ctx, userID, model.SuccessionOwnerDeleted)
+	case AccountEnabled:
+		return s.cancelDismissals(ctx, userID)
+	}
+	return nil
+}
+
+// succeedOwner 为用户名下的所有群安排继任
+func (s *groupSuccessionServiceImpl) succeedOwner(ctx context.Context, ownerID string, reason model.SuccessionReason) error {
+	groups, err := s.repo.FindOwnedGroups(ctx, ownerID)
+	if err != nil {
+		return fmt.Errorf("find owned groups error: %w", err)
+	}
+
+	pending, err := s.repo.FindPendingDismissalsByOwner(ctx, ownerID)
+	if err != nil {
+		return fmt.Errorf("find pending dismissals error: %w", err)
+	}
+	pendingByGroup := make(map[string]*model.GroupOwnerSuccession, len(pending))
+	for _, record := range pending {
+		pendingByGroup[record.GroupID] = record
+	}
+
+	var errs []error
+	for _, group := range groups {
+		record := pendingByGroup[group.GroupID]
+		if record == nil {
+			record = &model.GroupOwnerSuccession{GroupID: group.GroupID, PreviousOwnerID: ownerID}
+		}
+		record.Reason = reason
+		if err := s.succeedGroup(ctx, group, record); err != nil {
+			errs = append(errs, fmt.Errorf("group %s: %w", group.GroupID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// succeedGroup 移交单个群的群主，无继任者时安排解散
+func (s *groupSuccessionServiceImpl) succeedGroup(ctx context.Context, group *model.Group, record *model.GroupOwnerSuccession) error {
+	successor, err := s.findSuccessor(ctx, group.GroupID, group.OwnerID)
+	if err != nil {
+		return err
+	}
+	if successor != "" {
+		return s.transfer(ctx, group, successor, record)
+	}
+
+	// 已在等待解散的群保持原计划时间
+	if record.ID != 0 {
+		return s.repo.UpdateSuccession(ctx, record.ID, map[string]interface{}{"reason": record.Reason})
+	}
+	dismissAt := time.Now().Add(s.config.DismissGracePeriod)
+	record.Action = model.SuccessionDismissPending
+	record.DismissAt = &dismissAt
+	return s.repo.CreateSuccession(ctx, record)
+}
+
+// findSuccessor 按角色降序、入群时间升序查找第一个账号正常的成员
+func (s *groupSuccessionServiceImpl) findSuccessor(ctx context.Context, groupID, ownerID string) (string, error) {
+	for offset := 0; ; offset += s.config.CandidatePageSize {
+		members, _, err := s.repo.FindMembers(ctx, groupID, offset, s.config.CandidatePageSize)
+		if err != nil {
+			return "", fmt.Errorf("find members error: %w", err)
+		}
+		if len(members) == 0 {
+			return "", nil
+		}
+
+		userIDs := make([]string, 0, len(members))
+		for _, member := range members {
+			if member.UserID != ownerID {
+				userIDs = append(userIDs, member.UserID)
+			}
+		}
+		users, err := s.userRepo.FindByIDs(ctx, userIDs)
+		if err != nil {
+			return "", fmt.Errorf("find users error: %w", err)
+		}
+		active := make(map[string]bool, len(users))
+		for _, user := range users {
+			active[user.UserID] = user.Status == model.UserStatusNormal
+		}
+		for _, userID := range userIDs {
+			if active[userID] {
+				return userID, nil
+			}
+		}
+
+		if len(members) < s.config.CandidatePageSize {
+			return "", nil
+		}
+	}
+}
+
+// transfer 移交群主：原群主降为普通成员（已注销则移出群），记录审计并通知群成员
+func (s *groupSuccessionServiceImpl) transfer(ctx context.Context, group *model.Group, successor string, record *model.GroupOwnerSuccession) error {
+	previousOwner := group.OwnerID
+	removeOwner := record.Reason == model.SuccessionOwnerDeleted
+
+	err := s.repo.Transaction(ctx, func(tx repository.GroupRepository) error {
+		if removeOwner {
+			if err := tx.RemoveMembers(ctx, group.GroupID, []string{previousOwner}); err != nil {
+				return err
+			}
+			if err := tx.IncrMemberCount(ctx, group.GroupID, -1); err != nil {
+				return err
+			}
+		} else if err := tx.UpdateMember(ctx, group.GroupID, previousOwner, map[string]interface{}{"role": model.RoleMember}); err != nil {
+			return err
+		}
+
+		if err := tx.UpdateMember(ctx, group.GroupID, successor, map[string]interface{}{"role": model.RoleOwner}); err != nil {
+			return err
+		}
+		if err := tx.Update(ctx, group.GroupID, map[string]interface{}{"owner_id": successor}); err != nil {
+			return err
+		}
+
+		if record.ID != 0 {
+			return tx.UpdateSuccession(ctx, record.ID, map[string]interface{}{
+				"new_owner_id": successor,
+				"reason":       record.Reason,
+				"action":       model.SuccessionTransferred,
+			})
+		}
+		record.NewOwnerID = successor
+		record.Action = model.SuccessionTransferred
+		return tx.CreateSuccession(ctx, record)
+	})
+	if err != nil {
+		return fmt.Errorf("transfer owner error: %w", err)
+	}
+
+	if removeOwner {
+		s.clearMemberCache(ctx, group.GroupID)
+	}
+	if s.redis != nil {
+		s.redis.Del(ctx, groupMuteCacheKey(group.GroupID))
+	}
+
+	// 通知群成员（含新群主）
+	s.notify(ctx, model.MsgGroupTransfer, group.GroupID, previousOwner, []string{successor}, record.Reason, nil)
+	log.Printf("group %s owner %s succeeded by %s (%s)", group.GroupID, previousOwner, successor, record.Reason)
+	return nil
+}
+
+// cancelDismissals 原群主账号恢复，取消其名下群的待解散计划
+func (s *groupSuccessionServiceImpl) cancelDismissals(ctx context.Context, ownerID string) error {
+	records, err := s.repo.FindPendingDismissalsByOwner(ctx, ownerID)
+	if err != nil {
+		return fmt.Errorf("find pending dismissals error: %w", err)
+	}
+	for _, record := range records {
+		if err := s.repo.UpdateSuccession(ctx, record.ID, map[string]interface{}{"action": model.SuccessionCancelled}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSuccessions 查询群的继任审计记录
+func (s *groupSuccessionServiceImpl) GetSuccessions(ctx context.Context, groupID string, limit int) ([]*model.GroupOwnerSuccession, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	return s.repo.FindSuccessions(ctx, groupID, limit)
+}
+
+// DismissDue 处理宽限期已到的群：期间有了可继任成员则移交，否则解散
+func (s *groupSuccessionServiceImpl) DismissDue(ctx context.Context) (int, error) {
+	records, err := s.repo.FindPendingDismissals(ctx, time.Now(), 100)
+	if err != nil {
+		return 0, err
+	}
+
+	handled := 0
+	for _, record := range records {
+		group, err := s.repo.FindByID(ctx, record.GroupID)
+		if err != nil {
+			return handled, err
+		}
+
+		// 群已解散或已由他人接管，计划作废
+		if group == nil || !group.IsActive() || group.OwnerID != record.PreviousOwnerID {
+			if err := s.repo.UpdateSuccession(ctx, record.ID, map[string]interface{}{"action": model.SuccessionCancelled}); err != nil {
+				return handled, err
+			}
+			handled++
+			continue
+		}
+
+		successor, err := s.findSuccessor(ctx, group.GroupID, group.OwnerID)
+		if err != nil {
+			return handled, err
+		}
+		if successor != "" {
+			err = s.transfer(ctx, group, successor, record)
+		} else {
+			err = s.dismiss(ctx, group, record)
+		}
+		if err != nil {
+			return handled, err
+		}
+		handled++
+	}
+	return handled, nil
+}
+
+// dismiss 解散群并通知剩余成员
+func (s *groupSuccessionServiceImpl) dismiss(ctx context.Context, group *model.Group, record *model.GroupOwnerSuccession) error {
+	memberIDs, err := s.repo.FindMemberIDs(ctx, group.GroupID, model.RoleMember)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.Transaction(ctx, func(tx repository.GroupRepository) error {
+		if err := tx.Update(ctx, group.GroupID, map[string]interface{}{"status": model.GroupStatusDismissed}); err != nil {
+			return err
+		}
+		if err := tx.RemoveMembers(ctx, group.GroupID, nil); err != nil {
+			return err
+		}
+		return tx.UpdateSuccession(ctx, record.ID, map[string]interface{}{"action": model.SuccessionDismissed})
+	})
+	if err != nil {
+		return fmt.Errorf("dismiss group error: %w", err)
+	}
+
+	s.clearMemberCache(ctx, group.GroupID)
+	s.notify(ctx, model.MsgGroupDismissed, group.GroupID, record.PreviousOwnerID, nil, record.Reason, memberIDs)
+	log.Printf("group %s dismissed after owner %s %s", group.GroupID, record.PreviousOwnerID, record.Reason)
+	return nil
+}
+
+// Start 启动到期解散扫描
+func (s *groupSuccessionServiceImpl) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DismissDue(ctx); err != nil {
+				log.Printf("dismiss due groups error: %v", err)
+			}
+		}
+	}
+}
+
+// notify 发送自动继任/解散的群事件，recipients 为空时发给当前群成员
+func (s *groupSuccessionServiceImpl) notify(ctx context.Context, eventType model.MessageType, groupID, operatorID string, targetIDs []string, reason model.SuccessionReason, recipients []string) {
+	if s.msgDispatcher == nil {
+		return
+	}
+	if recipients == nil {
+		memberIDs, err := s.repo.FindMemberIDs(ctx, groupID, model.RoleMember)
+		if err != nil {
+			log.Printf("get group member IDs error: %v", err)
+			return
+		}
+		recipients = memberIDs
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	msg := model.NewGroupEventMessage(eventType, groupID, operatorID, targetIDs)
+	if content, ok := msg.Content.(*model.GroupEventContent); ok {
+		content.SetPayload(&model.GroupSuccessionPayload{Reason: reason}, s.config.LegacyExtra)
+	}
+	if err := s.msgDispatcher.DispatchToUsers(ctx, recipients, msg); err != nil {
+		log.Printf("dispatch succession event error: %v", err)
+	}
+}
+
+// clearMemberCache 清理Redis中的群成员缓存，下次查询时重建
+func (s *groupSuccessionServiceImpl) clearMemberCache(ctx context.Context, groupID string) {
+	if s.redis == nil {
+		return
+	}
+	s.redis.Del(ctx, fmt.Sprintf("group:members:%s", groupID))
+}
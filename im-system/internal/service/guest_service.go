@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 访客错误定义
+var (
+	ErrGuestDisabled        = errors.New("guest sessions are disabled")
+	ErrGuestRateLimited     = errors.New("too many guest sessions")
+	ErrGuestTargetForbidden = errors.New("guests cannot message this conversation")
+	ErrGuestExpired         = errors.New("guest session has expired")
+	ErrNotGuest             = errors.New("not a guest account")
+)
+
+// 访客缓存 Key 前缀
+const (
+	guestUserKeyPrefix = "guest:user:"    // 用户是否为访客（访客为过期时间毫秒数，正式用户为0）
+	guestRateKeyPrefix = "guest:session:" // 按客户端IP统计创建访客会话的次数
+)
+
+// GuestConfig 访客配置
+type GuestConfig struct {
+	TTL             time.Duration // 访客账号有效期，到期后账号注销、消息删除
+	AgentIDs        []string      // 访客可以单聊的用户（如售前客服），创建会话时未指定则按访客ID分配
+	GroupIDs        []string      // 访客可以发言的群组，创建会话时指定的群组自动加入
+	RateLimit       int           // 每个客户端IP在窗口内可创建的访客会话数，0表示不限制
+	RateWindow      time.Duration // 限流窗口
+	CacheTTL        time.Duration // 访客身份缓存时间
+	CleanupInterval time.Duration // 过期访客清理间隔
+	CleanupBatch    int           // 每次清理的访客数
+}
+
+// DefaultGuestConfig 默认访客配置
+func DefaultGuestConfig() *GuestConfig {
+	return &GuestConfig{
+		TTL:             24 * time.Hour,
+		RateLimit:       10,
+		RateWindow:      time.Hour,
+		CacheTTL:        10 * time.Minute,
+		CleanupInterval: 10 * time.Minute,
+		CleanupBatch:    100,
+	}
+}
+
+// GuestSessionRequest 创建访客会话请求
+type GuestSessionRequest struct {
+	Nickname string `json:"nickname" binding:"max=32"`
+	AgentID  string `json:"agent_id"` // 咨询的客服，须为配置的客服之一，为空时自动分配
+	GroupID  string `json:"group_id"` // 加入的群组，须为配置的群组之一
+}
+
+// GuestSession 访客会话
+type GuestSession struct {
+	User           *model.User `json:"user"`
+	ExpiresAt      time.Time   `json:"expires_at"`
+	AgentID        string      `json:"agent_id,omitempty"`
+	GroupID        string      `json:"group_id,omitempty"`
+	ConversationID string      `json:"conversation_id,omitempty"` // 与客服的单聊会话或加入的群聊会话
+}
+
+// GuestService 访客服务：售前咨询等场景的匿名会话，访客只能与配置的客服单聊或在配置的群组发言，
+// 到期后账号注销、消息删除；转为正式账号后保留用户ID，历史消息随之保留
+type GuestService interface {
+	// CreateSession 创建访客账号，clientIP 用于限流
+	CreateSession(ctx context.Context, req *GuestSessionRequest, clientIP string) (*GuestSession, error)
+
+	// Upgrade 将访客账号转为正式账号（用户ID不变）
+	Upgrade(ctx context.Context, userID string, req *model.RegisterRequest) (*model.User, error)
+
+	// IsGuest 是否为访客
+	IsGuest(ctx context.Context, userID string) (bool, error)
+
+	// CheckMessage 发送前检查：访客只能向配置的客服、群组及分配给自己的客服坐席发送聊天消息，过期后不能发送
+	CheckMessage(ctx context.Context, msg *model.Message) error
+
+	// CleanupExpired 注销过期的访客账号并删除其消息，返回清理数量
+	CleanupExpired(ctx context.Context) (int, error)
+
+	// Start 启动过期访客清理任务
+	Start(ctx context.Context)
+
+	// SetNamingService 设置命名服务（转为正式账号时校验用户名和昵称）
+	SetNamingService(naming NamingService)
+
+	// SetCustomerService 设置客服服务（访客可以与分配给自己的坐席单聊）
+	SetCustomerService(customerService CustomerService)
+}
+
+// guestServiceImpl 访客服务实现
+type guestServiceImpl struct {
+	users        repository.UserRepository
+	messageRepo  repository.MessageRepository
+	groupService GroupService
+	accounts     AccountService
+	naming       NamingService
+	cs           CustomerService
+	redis        *redis.Client
+	config       *GuestConfig
+
+	agents map[string]bool
+	groups map[string]bool
+}
+
+// NewGuestService 创建访客服务
+func NewGuestService(users repository.UserRepository, messageRepo repository.MessageRepository, groupService GroupService, accounts AccountService, redisClient *redis.Client, config *GuestConfig) GuestService {
+	if config == nil {
+		config = DefaultGuestConfig()
+	}
+	s := &guestServiceImpl{
+		users:        users,
+		messageRepo:  messageRepo,
+		groupService: groupService,
+		accounts:     accounts,
+		redis:        redisClient,
+		config:       config,
+		agents:       make(map[string]bool, len(config.AgentIDs)),
+		groups:       make(map[string]bool, len(config.GroupIDs)),
+	}
+	for _, id := range config.AgentIDs {
+		s.agents[id] = true
+	}
+	for _, id := range config.GroupIDs {
+		s.groups[id] = true
+	}
+	return s
+}
+
+// SetNamingService 设置命名服务（转为正式账号时校验用户名和昵称）
+func (s *guestServiceImpl) SetNamingService(naming NamingService) {
+	s.naming = naming
+}
+
+// SetCustomerService 设置客服服务
+func (s *guestServiceImpl) SetCustomerService(customerService CustomerService) {
+	s.cs = customerService
+}
+
+// CreateSession 创建访客账号
+func (s *guestServiceImpl) CreateSession(ctx context.Context, req *GuestSessionRequest, clientIP string) (*GuestSession, error) {
+	if len(s.agents) == 0 && len(s.groups) == 0 {
+		return nil, ErrGuestDisabled
+	}
+	if req.AgentID != "" && !s.agents[req.AgentID] {
+		return nil, ErrGuestTargetForbidden
+	}
+	if req.GroupID != "" && !s.groups[req.GroupID] {
+		return nil, ErrGuestTargetForbidden
+	}
+	if !s.allowSession(ctx, clientIP) {
+		return nil, ErrGuestRateLimited
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.config.TTL)
+	userID := util.GenerateGuestID()
+	nickname := req.Nickname
+	if nickname == "" {
+		nickname = "访客" + userID[len(userID)-6:]
+	}
+	user := &model.User{
+		UserID:         userID,
+		Username:       userID,
+		Nickname:       nickname,
+		Searchable:     false,
+		Status:         model.UserStatusNormal,
+		Guest:          true,
+		GuestExpiresAt: &expiresAt,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("create guest error: %w", err)
+	}
+	s.cacheGuest(ctx, userID, expiresAt.UnixMilli())
+
+	session := &GuestSession{User: user, ExpiresAt: expiresAt}
+	if req.GroupID != "" {
+		if err := s.groupService.JoinGroup(ctx, req.GroupID, userID, ""); err != nil {
+			return nil, err
+		}
+		session.GroupID = req.GroupID
+		session.ConversationID = model.GetGroupChatConversationID(req.GroupID)
+	} else if agentID := s.assignAgent(req.AgentID, userID); agentID != "" {
+		session.AgentID = agentID
+		session.ConversationID = model.GetSingleChatConversationID(userID, agentID)
+	}
+	return session, nil
+}
+
+// assignAgent 未指定客服时按访客ID分配
+func (s *guestServiceImpl) assignAgent(agentID, userID string) string {
+	if agentID != "" || len(s.config.AgentIDs) == 0 {
+		return agentID
+	}
+	h := fnv.New32a()
+	h.Write([]byte(userID))
+	return s.config.AgentIDs[h.Sum32()%uint32(len(s.config.AgentIDs))]
+}
+
+// allowSession 按客户端IP限制访客会话创建频率（Redis异常时放行）
+func (s *guestServiceImpl) allowSession(ctx context.Context, clientIP string) bool {
+	if s.redis == nil || s.config.RateLimit <= 0 {
+		return true
+	}
+	key := guestRateKeyPrefix + clientIP
+	count, err := s.redis.Incr(ctx, key).Result()
+	if err != nil {
+		return true
+	}
+	if count == 1 {
+		s.redis.Expire(ctx, key, s.config.RateWindow)
+	}
+	return count <= int64(s.config.RateLimit)
+}
+
+// Upgrade 将访客账号转为正式账号
+func (s *guestServiceImpl) Upgrade(ctx context.Context, userID string, req *model.RegisterRequest) (*model.User, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find user error: %w", err)
+	}
+	if user == nil || user.Status == model.UserStatusDeleted {
+		return nil, ErrUserNotFound
+	}
+	if !user.Guest {
+		return nil, ErrNotGuest
+	}
+	if user.GuestExpiresAt != nil && user.GuestExpiresAt.Before(time.Now()) {
+		return nil, ErrGuestExpired
+	}
+
+	nickname := req.Nickname
+	if nickname == "" {
+		nickname = req.Username
+	}
+	if s.naming != nil {
+		if err := s.naming.CheckUsername(ctx, req.Username); err != nil {
+			return nil, err
+		}
+		if err := s.naming.CheckNickname(ctx, user.TenantID, userID, nickname); err != nil {
+			return nil, err
+		}
+	} else if exists, err := s.users.ExistsUsername(ctx, req.Username); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, ErrUsernameTaken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("hash password error: %w", err)
+	}
+	upgraded, err := s.users.UpgradeGuest(ctx, userID, req.Username, nickname, string(hashedPassword), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !upgraded {
+		return nil, ErrNotGuest
+	}
+	s.cacheGuest(ctx, userID, 0)
+
+	user.Username = req.Username
+	user.Nickname = nickname
+	user.Guest = false
+	user.GuestExpiresAt = nil
+	return user, nil
+}
+
+// IsGuest 是否为访客
+func (s *guestServiceImpl) IsGuest(ctx context.Context, userID string) (bool, error) {
+	expiresAt, err := s.guestExpiry(ctx, userID)
+	return expiresAt > 0, err
+}
+
+// guestExpiry 访客过期时间（毫秒），正式用户返回0
+func (s *guestServiceImpl) guestExpiry(ctx context.Context, userID string) (int64, error) {
+	if s.redis != nil {
+		if value, err := s.redis.Get(ctx, guestUserKeyPrefix+userID).Result(); err == nil {
+			if expiresAt, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return expiresAt, nil
+			}
+		}
+	}
+
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("find user error: %w", err)
+	}
+	var expiresAt int64
+	if user != nil && user.Guest && user.GuestExpiresAt != nil {
+		expiresAt = user.GuestExpiresAt.UnixMilli()
+	}
+	s.cacheGuest(ctx, userID, expiresAt)
+	return expiresAt, nil
+}
+
+// cacheGuest 缓存访客身份
+func (s *guestServiceImpl) cacheGuest(ctx context.Context, userID string, expiresAt int64) {
+	if s.redis != nil {
+		s.redis.Set(ctx, guestUserKeyPrefix+userID, expiresAt, s.config.CacheTTL)
+	}
+}
+
+// CheckMessage 发送前检查访客的发送范围
+func (s *guestServiceImpl) CheckMessage(ctx context.Context, msg *model.Message) error {
+	if !msg.Type.IsChat() {
+		return nil
+	}
+	expiresAt, err := s.guestExpiry(ctx, msg.From)
+	if err != nil || expiresAt == 0 {
+		return err
+	}
+	if time.Now().UnixMilli() > expiresAt {
+		return ErrGuestExpired
+	}
+
+	if msg.Type == model.MsgGroupChat || msg.GroupID != "" {
+		groupID := msg.GroupID
+		if groupID == "" {
+			groupID = msg.To
+		}
+		if !s.groups[groupID] {
+			return ErrGuestTargetForbidden
+		}
+		return nil
+	}
+	if s.agents[msg.To] {
+		return nil
+	}
+	if s.cs != nil {
+		assigned, err := s.cs.IsAssigned(ctx, msg.From, msg.To)
+		if err != nil {
+			return err
+		}
+		if assigned {
+			return nil
+		}
+	}
+	return ErrGuestTargetForbidden
+}
+
+// CleanupExpired 注销过期的访客账号并删除其消息
+func (s *guestServiceImpl) CleanupExpired(ctx context.Context) (int, error) {
+	guests, err := s.users.FindExpiredGuests(ctx, time.Now(), s.config.CleanupBatch)
+	if err != nil {
+		return 0, fmt.Errorf("find expired guests error: %w", err)
+	}
+
+	cleaned := 0
+	for _, guest := range guests {
+		for groupID := range s.groups {
+			if err := s.groupService.LeaveGroup(ctx, groupID, guest.UserID); err != nil && !errors.Is(err, ErrNotGroupMember) && !errors.Is(err, ErrGroupNotFound) {
+				log.Printf("remove expired guest %s from group %s error: %v", guest.UserID, groupID, err)
+			}
+		}
+		if _, err := s.messageRepo.DeleteByUser(ctx, guest.UserID); err != nil {
+			log.Printf("delete messages of expired guest %s error: %v", guest.UserID, err)
+			continue
+		}
+		if err := s.accounts.SetStatus(ctx, guest.UserID, model.UserStatusDeleted); err != nil && !errors.Is(err, ErrAccountDeleted) {
+			log.Printf("delete expired guest %s error: %v", guest.UserID, err)
+			continue
+		}
+		if s.redis != nil {
+			s.redis.Del(ctx, guestUserKeyPrefix+guest.UserID)
+		}
+		cleaned++
+	}
+	return cleaned, nil
+}
+
+// Start 启动过期访客清理任务
+func (s *guestServiceImpl) Start(ctx context.Context) {
+	interval := s.config.CleanupInterval
+	if interval <= 0 {
+		interval = DefaultGuestConfig().CleanupInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := s.CleanupExpired(ctx); err != nil {
+				log.Printf("cleanup expired guests error: %v", err)
+			} else if n > 0 {
+				log.Printf("cleaned up %d expired guests", n)
+			}
+		}
+	}
+}
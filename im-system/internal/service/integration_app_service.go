@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 集成应用服务错误定义
+var (
+	ErrAppNotFound             = errors.New("integration app not found")
+	ErrCustomSignatureInvalid  = errors.New("custom message signature is invalid")
+	ErrCustomSignatureExpired  = errors.New("custom message signature has expired")
+	ErrCustomMessageUnverified = errors.New("conversation only accepts verified app messages")
+)
+
+// IntegrationAppConfig 集成应用配置
+type IntegrationAppConfig struct {
+	SignatureTTL time.Duration // 签名有效期（signed_at 与服务器时间允许的偏差）
+	SecretBytes  int           // 密钥随机字节数
+}
+
+// DefaultIntegrationAppConfig 默认集成应用配置
+func DefaultIntegrationAppConfig() *IntegrationAppConfig {
+	return &IntegrationAppConfig{
+		SignatureTTL: 5 * time.Minute,
+		SecretBytes:  32,
+	}
+}
+
+// CreateAppRequest 创建集成应用请求
+type CreateAppRequest struct {
+	Name string `json:"name" binding:"required,max=128"`
+}
+
+// UpdateAppRequest 更新集成应用请求
+type UpdateAppRequest struct {
+	Name     *string `json:"name" binding:"omitempty,min=1,max=128"`
+	Disabled *bool   `json:"disabled"`
+}
+
+// AppWithSecret 带密钥的集成应用（仅创建及重置密钥时返回）
+type AppWithSecret struct {
+	*model.IntegrationApp
+	Secret string `json:"secret"`
+}
+
+// IntegrationAppService 集成应用服务接口
+type IntegrationAppService interface {
+	// CreateApp 创建应用并生成签名密钥
+	CreateApp(ctx context.Context, req *CreateAppRequest) (*AppWithSecret, error)
+
+	// ListApps 获取全部应用
+	ListApps(ctx context.Context) ([]*model.IntegrationApp, error)
+
+	// UpdateApp 修改应用名称或启用状态
+	UpdateApp(ctx context.Context, appID string, req *UpdateAppRequest) (*model.IntegrationApp, error)
+
+	// RotateSecret 重置签名密钥，旧密钥立即失效
+	RotateSecret(ctx context.Context, appID string) (*AppWithSecret, error)
+
+	// DeleteApp 删除应用
+	DeleteApp(ctx context.Context, appID string) error
+
+	// GetConversationPolicy 获取会话的自定义消息策略（会话参与者可查看）
+	GetConversationPolicy(ctx context.Context, userID, conversationID string) (*model.ConversationAppPolicy, error)
+
+	// SetConversationPolicy 设置会话是否只接受签名校验通过的自定义消息（单聊参与者、群主或群管理员）
+	SetConversationPolicy(ctx context.Context, userID, conversationID string, requireVerified bool) (*model.ConversationAppPolicy, error)
+
+	// VerifyCustomMessage 分发前校验自定义消息：携带签名时校验签名并标记 verified，
+	// 未签名时若会话要求校验则拒绝，否则标记为未校验后投递
+	VerifyCustomMessage(ctx context.Context, msg *model.Message) error
+}
+
+// integrationAppServiceImpl 集成应用服务实现
+type integrationAppServiceImpl struct {
+	repo         repository.IntegrationAppRepository
+	groupService GroupService
+	config       *IntegrationAppConfig
+}
+
+// NewIntegrationAppService 创建集成应用服务
+func NewIntegrationAppService(repo repository.IntegrationAppRepository, groupService GroupService, config *IntegrationAppConfig) IntegrationAppService {
+	if config == nil {
+		config = DefaultIntegrationAppConfig()
+	}
+	return &integrationAppServiceImpl{
+		repo:         repo,
+		groupService: groupService,
+		config:       config,
+	}
+}
+
+// CreateApp 创建应用
+func (s *integrationAppServiceImpl) CreateApp(ctx context.Context, req *CreateAppRequest) (*AppWithSecret, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrInvalidRequest
+	}
+
+	app := &model.IntegrationApp{
+		AppID:  util.GenerateAppID(),
+		Name:   name,
+		Secret: util.GenerateToken(s.config.SecretBytes),
+	}
+	if err := s.repo.Create(ctx, app); err != nil {
+		return nil, fmt.Errorf("create integration app error: %w", err)
+	}
+	return &AppWithSecret{IntegrationApp: app, Secret: app.Secret}, nil
+}
+
+// ListApps 获取全部应用
+func (s *integrationAppServiceImpl) ListApps(ctx context.Context) ([]*model.IntegrationApp, error) {
+	return s.repo.List(ctx)
+}
+
+// UpdateApp 修改应用
+func (s *integrationAppServiceImpl) UpdateApp(ctx context.Context, appID string, req *UpdateAppRequest) (*model.IntegrationApp, error) {
+	app, err := s.findApp(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, ErrInvalidRequest
+		}
+		app.Name = name
+	}
+	if req.Disabled != nil {
+		app.Disabled = *req.Disabled
+	}
+	if err := s.repo.Update(ctx, app); err != nil {
+		return nil, fmt.Errorf("update integration app error: %w", err)
+	}
+	return app, nil
+}
+
+// RotateSecret 重置签名密钥
+func (s *integrationAppServiceImpl) RotateSecret(ctx context.Context, appID string) (*AppWithSecret, error) {
+	app, err := s.findApp(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	app.Secret = util.GenerateToken(s.config.SecretBytes)
+	if err := s.repo.Update(ctx, app); err != nil {
+		return nil, fmt.Errorf("rotate app secret error: %w", err)
+	}
+	return &AppWithSecret{IntegrationApp: app, Secret: app.Secret}, nil
+}
+
+// DeleteApp 删除应用
+func (s *integrationAppServiceImpl) DeleteApp(ctx context.Context, appID string) error {
+	if _, err := s.findApp(ctx, appID); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, appID)
+}
+
+// GetConversationPolicy 获取会话的自定义消息策略
+func (s *integrationAppServiceImpl) GetConversationPolicy(ctx context.Context, userID, conversationID string) (*model.ConversationAppPolicy, error) {
+	convID, err := model.ParseConversationID(conversationID)
+	if err != nil {
+		return nil, ErrConversationNotFound
+	}
+	if convID.IsGroup() {
+		isMember, err := s.groupService.IsMember(ctx, convID.GroupID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return nil, ErrNotGroupMember
+		}
+	} else if !convID.HasParticipant(userID) {
+		return nil, ErrPermissionDeny
+	}
+
+	policy, err := s.repo.FindPolicy(ctx, convID.String())
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		policy = &model.ConversationAppPolicy{ConversationID: convID.String()}
+	}
+	return policy, nil
+}
+
+// SetConversationPolicy 设置会话的自定义消息策略
+func (s *integrationAppServiceImpl) SetConversationPolicy(ctx context.Context, userID, conversationID string, requireVerified bool) (*model.ConversationAppPolicy, error) {
+	convID, err := model.ParseConversationID(conversationID)
+	if err != nil {
+		return nil, ErrConversationNotFound
+	}
+	if convID.IsGroup() {
+		role, err := s.groupService.GetMemberRole(ctx, convID.GroupID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role < model.RoleAdmin {
+			return nil, ErrNotGroupAdmin
+		}
+	} else if !convID.HasParticipant(userID) {
+		return nil, ErrPermissionDeny
+	}
+
+	policy := &model.ConversationAppPolicy{
+		ConversationID:  convID.String(),
+		RequireVerified: requireVerified,
+		UpdatedBy:       userID,
+		UpdatedAt:       time.Now(),
+	}
+	if err := s.repo.SavePolicy(ctx, policy); err != nil {
+		return nil, fmt.Errorf("save conversation app policy error: %w", err)
+	}
+	return policy, nil
+}
+
+// VerifyCustomMessage 校验自定义消息
+func (s *integrationAppServiceImpl) VerifyCustomMessage(ctx context.Context, msg *model.Message) error {
+	content, err := parseCustomContent(msg.Content)
+	if err != nil || content.CustomType == "" {
+		return ErrInvalidRequest
+	}
+	// verified 只能由服务端设置
+	content.Verified = false
+
+	if content.AppID != "" || content.Signature != "" {
+		if err := s.verifySignature(ctx, content); err != nil {
+			return err
+		}
+		content.Verified = true
+		content.Signature = ""
+	} else {
+		convID := model.NewSingleConversationID(msg.From, msg.To)
+		if msg.GroupID != "" {
+			convID = model.NewGroupConversationID(msg.GroupID)
+		}
+		policy, err := s.repo.FindPolicy(ctx, convID.String())
+		if err != nil {
+			return fmt.Errorf("find conversation app policy error: %w", err)
+		}
+		if policy != nil && policy.RequireVerified {
+			return ErrCustomMessageUnverified
+		}
+	}
+
+	msg.Content = content
+	return nil
+}
+
+// verifySignature 校验签名：应用存在且启用、签名时间在有效期内、HMAC一致
+func (s *integrationAppServiceImpl) verifySignature(ctx context.Context, content *model.CustomContent) error {
+	if content.AppID == "" || content.Signature == "" {
+		return ErrCustomSignatureInvalid
+	}
+
+	signedAt := time.UnixMilli(content.SignedAt)
+	if diff := time.Since(signedAt); diff > s.config.SignatureTTL || diff < -s.config.SignatureTTL {
+		return ErrCustomSignatureExpired
+	}
+
+	app, err := s.repo.FindByID(ctx, content.AppID)
+	if err != nil {
+		return err
+	}
+	// 应用不存在或已停用时不区分原因，避免探测应用ID
+	if app == nil || app.Disabled {
+		return ErrCustomSignatureInvalid
+	}
+
+	signature, err := hex.DecodeString(content.Signature)
+	if err != nil {
+		return ErrCustomSignatureInvalid
+	}
+	expected, err := SignCustomContent(content, app.Secret)
+	if err != nil {
+		return ErrCustomSignatureInvalid
+	}
+	if !hmac.Equal(signature, expected) {
+		return ErrCustomSignatureInvalid
+	}
+	return nil
+}
+
+// findApp 查询应用，不存在时返回 ErrAppNotFound
+func (s *integrationAppServiceImpl) findApp(ctx context.Context, appID string) (*model.IntegrationApp, error) {
+	app, err := s.repo.FindByID(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+	if app == nil {
+		return nil, ErrAppNotFound
+	}
+	return app, nil
+}
+
+// SignCustomContent 计算自定义消息签名 HMAC-SHA256(secret, content.SigningPayload())
+func SignCustomContent(content *model.CustomContent, secret string) ([]byte, error) {
+	payload, err := content.SigningPayload()
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
+
+// parseCustomContent 解析自定义消息内容（WebSocket消息内容为通用map）
+func parseCustomContent(content interface{}) (*model.CustomContent, error) {
+	if c, ok := content.(*model.CustomContent); ok {
+		return c, nil
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	var c model.CustomContent
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// 维护模式错误定义
+var (
+	ErrMaintenanceMode = errors.New("service is in maintenance mode, sending is temporarily disabled")
+)
+
+// maintenanceKey 集群维护模式标记
+const maintenanceKey = "im:maintenance"
+
+// maintenanceCacheTTL 本地缓存维护状态的时间，避免每条消息都访问Redis
+const maintenanceCacheTTL = 2 * time.Second
+
+// Broadcaster 全局广播接口（用于发送服务器通知）
+type Broadcaster interface {
+	BroadcastToAllNodes(ctx context.Context, msg *model.Message) error
+}
+
+// MaintenanceStatus 维护模式状态
+type MaintenanceStatus struct {
+	Enabled    bool      `json:"enabled"`
+	Reason     string    `json:"reason,omitempty"`
+	OperatorID string    `json:"operator_id,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+}
+
+// MaintenanceService 维护模式服务接口（只读模式：拒绝发送消息，读取和下载不受影响）
+type MaintenanceService interface {
+	// Enable 开启维护模式
+	Enable(ctx context.Context, reason, operatorID string) error
+	// Disable 关闭维护模式
+	Disable(ctx context.Context) error
+	// Status 获取维护模式状态
+	Status(ctx context.Context) (*MaintenanceStatus, error)
+	// CheckSend 检查当前是否允许发送消息，维护模式下返回ErrMaintenanceMode
+	CheckSend(ctx context.Context) error
+}
+
+// maintenanceServiceImpl 维护模式服务实现
+type maintenanceServiceImpl struct {
+	redis       *redis.Client
+	broadcaster Broadcaster
+
+	mu        sync.RWMutex
+	cached    *MaintenanceStatus
+	expiresAt time.Time
+}
+
+// NewMaintenanceService 创建维护模式服务
+// broadcaster 用于在切换维护模式时通知在线用户，可为空
+func NewMaintenanceService(redisClient *redis.Client, broadcaster Broadcaster) MaintenanceService {
+	return &maintenanceServiceImpl{redis: redisClient, broadcaster: broadcaster}
+}
+
+// Enable 开启维护模式
+func (s *maintenanceServiceImpl) Enable(ctx context.Context, reason, operatorID string) error {
+	status := &MaintenanceStatus{
+		Enabled:    true,
+		Reason:     reason,
+		OperatorID: operatorID,
+		StartedAt:  time.Now(),
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	if err := s.redis.Set(ctx, maintenanceKey, data, 0).Err(); err != nil {
+		return fmt.Errorf("set maintenance flag error: %w", err)
+	}
+	s.setCache(status)
+	s.notify(ctx, "maintenance_on", reason)
+	return nil
+}
+
+// Disable 关闭维护模式
+func (s *maintenanceServiceImpl) Disable(ctx context.Context) error {
+	if err := s.redis.Del(ctx, maintenanceKey).Err(); err != nil {
+		return fmt.Errorf("clear maintenance flag error: %w", err)
+	}
+	s.setCache(&MaintenanceStatus{})
+	s.notify(ctx, "maintenance_off", "")
+	return nil
+}
+
+// notify 广播维护模式变更通知，客户端据此展示或隐藏维护提示
+func (s *maintenanceServiceImpl) notify(ctx context.Context, action, reason string) {
+	if s.broadcaster == nil {
+		return
+	}
+	msg := &model.Message{
+		Type: model.MsgServerNotice,
+		Content: &model.ServerNoticeContent{
+			Content: reason,
+			Action:  action,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if err := s.broadcaster.BroadcastToAllNodes(ctx, msg); err != nil {
+		fmt.Printf("broadcast maintenance notice error: %v\n", err)
+	}
+}
+
+// Status 获取维护模式状态
+func (s *maintenanceServiceImpl) Status(ctx context.Context) (*MaintenanceStatus, error) {
+	data, err := s.redis.Get(ctx, maintenanceKey).Bytes()
+	if err == redis.Nil {
+		return &MaintenanceStatus{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get maintenance flag error: %w", err)
+	}
+
+	var status MaintenanceStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// CheckSend 检查是否允许发送
+func (s *maintenanceServiceImpl) CheckSend(ctx context.Context) error {
+	s.mu.RLock()
+	cached, valid := s.cached, time.Now().Before(s.expiresAt)
+	s.mu.RUnlock()
+
+	if !valid {
+		status, err := s.Status(ctx)
+		if err != nil {
+			// Redis异常时不阻断发送
+			return nil
+		}
+		s.setCache(status)
+		cached = status
+	}
+
+	if cached.Enabled {
+		return ErrMaintenanceMode
+	}
+	return nil
+}
+
+// setCache 更新本地缓存
+func (s *maintenanceServiceImpl) setCache(status *MaintenanceStatus) {
+	s.mu.Lock()
+	s.cached = status
+	s.expiresAt = time.Now().Add(maintenanceCacheTTL)
+	s.mu.Unlock()
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 提及服务错误定义
+var (
+	ErrMentionGroupNotFound = errors.New("mention group not found")
+	ErrMentionGroupExists   = errors.New("mention group name already exists in this group")
+	ErrMentionGroupInvalid  = errors.New("invalid mention group name or members")
+)
+
+// MentionConfig 提及配置
+type MentionConfig struct {
+	MaxNameLength int // 提及组名称最大长度（字符）
+	MaxMembers    int // 提及组最多成员数
+}
+
+// DefaultMentionConfig 默认提及配置
+func DefaultMentionConfig() *MentionConfig {
+	return &MentionConfig{
+		MaxNameLength: 32,
+		MaxMembers:    500,
+	}
+}
+
+// MentionGroupRequest 创建提及组请求
+type MentionGroupRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	MemberIDs []string `json:"member_ids" binding:"required"`
+}
+
+// UpdateMentionGroupRequest 更新提及组请求，字段为空时不修改
+type UpdateMentionGroupRequest struct {
+	Name      *string  `json:"name"`
+	MemberIDs []string `json:"member_ids"`
+}
+
+// MentionResolver 解析消息的提及对象（保存消息时调用）
+type MentionResolver interface {
+	// Resolve 返回消息提及的用户（去重，不含发送者），groupID 为空时只解析 at_user_ids
+	Resolve(ctx context.Context, groupID, senderID string, content map[string]interface{}) ([]string, error)
+}
+
+// MentionService 提及服务接口
+// 群消息除 at_user_ids 外还可以 @群角色（at_roles: owner / admins）和 @群内保存的提及组（at_mention_groups），
+// 发送时按当前群成员展开，解析结果记录在消息的 mention_targets 中。
+type MentionService interface {
+	MentionResolver
+
+	// CreateMentionGroup 创建提及组（群主或管理员），成员须为当前群成员
+	CreateMentionGroup(ctx context.Context, userID, groupID string, req *MentionGroupRequest) (*model.MentionGroup, error)
+
+	// UpdateMentionGroup 修改提及组名称或成员（群主、管理员或创建者）
+	UpdateMentionGroup(ctx context.Context, userID, groupID, mentionGroupID string, req *UpdateMentionGroupRequest) (*model.MentionGroup, error)
+
+	// DeleteMentionGroup 删除提及组（群主、管理员或创建者）
+	DeleteMentionGroup(ctx context.Context, userID, groupID, mentionGroupID string) error
+
+	// ListMentionGroups 获取群内全部提及组（仅群成员）
+	ListMentionGroups(ctx context.Context, userID, groupID string) ([]*model.MentionGroup, error)
+}
+
+// mentionServiceImpl 提及服务实现
+type mentionServiceImpl struct {
+	repo         repository.MentionGroupRepository
+	groupRepo    repository.GroupRepository
+	groupService GroupService
+	config       *MentionConfig
+}
+
+// NewMentionService 创建提及服务
+func NewMentionService(
+	repo repository.MentionGroupRepository,
+	groupRepo repository.GroupRepository,
+	groupService GroupService,
+	config *MentionConfig,
+) MentionService {
+	if config == nil {
+		config = DefaultMentionConfig()
+	}
+	return &mentionServiceImpl{
+		repo:         repo,
+		groupRepo:    groupRepo,
+		groupService: groupService,
+		config:       config,
+	}
+}
+
+// CreateMentionGroup 创建提及组
+func (s *mentionServiceImpl) CreateMentionGroup(ctx context.Context, userID, groupID string, req *MentionGroupRequest) (*model.MentionGroup, error) {
+	role, err := s.groupService.GetMemberRole(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role < model.RoleAdmin {
+		return nil, ErrNotGroupAdmin
+	}
+
+	name, err := s.validateName(ctx, groupID, req.Name, "")
+	if err != nil {
+		return nil, err
+	}
+	memberIDs, err := s.validateMembers(ctx, groupID, req.MemberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	group := &model.MentionGroup{
+		MentionGroupID: util.GenerateMentionGroupID(),
+		GroupID:        groupID,
+		Name:           name,
+		MemberIDs:      memberIDs,
+		CreatorID:      userID,
+	}
+	if err := s.repo.Create(ctx, group); err != nil {
+		return nil, fmt.Errorf("create mention group error: %w", err)
+	}
+	return group, nil
+}
+
+// UpdateMentionGroup 修改提及组
+func (s *mentionServiceImpl) UpdateMentionGroup(ctx context.Context, userID, groupID, mentionGroupID string, req *UpdateMentionGroupRequest) (*model.MentionGroup, error) {
+	group, err := s.authorizeManage(ctx, userID, groupID, mentionGroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		name, err := s.validateName(ctx, groupID, *req.Name, group.MentionGroupID)
+		if err != nil {
+			return nil, err
+		}
+		updates["name"] = name
+		group.Name = name
+	}
+	if req.MemberIDs != nil {
+		memberIDs, err := s.validateMembers(ctx, groupID, req.MemberIDs)
+		if err != nil {
+			return nil, err
+		}
+		updates["member_ids"] = memberIDs
+		group.MemberIDs = memberIDs
+	}
+	if len(updates) == 0 {
+		return group, nil
+	}
+
+	if err := s.repo.Update(ctx, mentionGroupID, updates); err != nil {
+		return nil, fmt.Errorf("update mention group error: %w", err)
+	}
+	return group, nil
+}
+
+// DeleteMentionGroup 删除提及组
+func (s *mentionServiceImpl) DeleteMentionGroup(ctx context.Context, userID, groupID, mentionGroupID string) error {
+	if _, err := s.authorizeManage(ctx, userID, groupID, mentionGroupID); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, mentionGroupID)
+}
+
+// ListMentionGroups 获取群内全部提及组
+func (s *mentionServiceImpl) ListMentionGroups(ctx context.Context, userID, groupID string) ([]*model.MentionGroup, error) {
+	isMember, err := s.groupService.IsMember(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotGroupMember
+	}
+	return s.repo.FindByGroup(ctx, groupID)
+}
+
+// Resolve 解析消息的提及对象
+// at_user_ids 原样保留（与客户端展示一致）；at_roles、at_mention_groups 按发送时的群成员展开，已退群的提及组成员不再提及
+func (s *mentionServiceImpl) Resolve(ctx context.Context, groupID, senderID string, content map[string]interface{}) ([]string, error) {
+	targets := contentStrings(content, "at_user_ids")
+	if groupID == "" {
+		return excludeSender(targets, senderID), nil
+	}
+
+	for _, role := range contentStrings(content, "at_roles") {
+		var minRole model.GroupRole
+		switch role {
+		case model.MentionRoleOwner:
+			minRole = model.RoleOwner
+		case model.MentionRoleAdmins:
+			minRole = model.RoleAdmin
+		default:
+			continue
+		}
+		memberIDs, err := s.groupRepo.FindMemberIDs(ctx, groupID, minRole)
+		if err != nil {
+			return nil, fmt.Errorf("find group %s members error: %w", role, err)
+		}
+		targets = append(targets, memberIDs...)
+	}
+
+	if mentionGroupIDs := contentStrings(content, "at_mention_groups"); len(mentionGroupIDs) > 0 {
+		groups, err := s.repo.FindByIDs(ctx, groupID, mentionGroupIDs)
+		if err != nil {
+			return nil, fmt.Errorf("find mention groups error: %w", err)
+		}
+		if len(groups) > 0 {
+			memberIDs, err := s.groupService.GetGroupMemberIDs(ctx, groupID)
+			if err != nil {
+				return nil, err
+			}
+			members := make(map[string]bool, len(memberIDs))
+			for _, id := range memberIDs {
+				members[id] = true
+			}
+			for _, group := range groups {
+				for _, id := range group.MemberIDs {
+					if members[id] {
+						targets = append(targets, id)
+					}
+				}
+			}
+		}
+	}
+
+	return excludeSender(targets, senderID), nil
+}
+
+// authorizeManage 校验提及组属于该群，且操作者为群主、管理员或提及组创建者
+func (s *mentionServiceImpl) authorizeManage(ctx context.Context, userID, groupID, mentionGroupID string) (*model.MentionGroup, error) {
+	group, err := s.repo.FindByID(ctx, mentionGroupID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil || group.GroupID != groupID {
+		return nil, ErrMentionGroupNotFound
+	}
+
+	role, err := s.groupService.GetMemberRole(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role < model.RoleAdmin && group.CreatorID != userID {
+		return nil, ErrNotGroupAdmin
+	}
+	return group, nil
+}
+
+// validateName 校验提及组名称（不能与群内其他提及组重名，也不能使用角色名）
+func (s *mentionServiceImpl) validateName(ctx context.Context, groupID, name, selfID string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > s.config.MaxNameLength {
+		return "", ErrMentionGroupInvalid
+	}
+	if name == model.MentionRoleOwner || name == model.MentionRoleAdmins {
+		return "", ErrMentionGroupExists
+	}
+
+	existing, err := s.repo.FindByName(ctx, groupID, name)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil && existing.MentionGroupID != selfID {
+		return "", ErrMentionGroupExists
+	}
+	return name, nil
+}
+
+// validateMembers 校验提及组成员（去重后不能为空、不能超过上限，且须为当前群成员）
+func (s *mentionServiceImpl) validateMembers(ctx context.Context, groupID string, memberIDs []string) ([]string, error) {
+	memberIDs = uniqueStrings(memberIDs)
+	if len(memberIDs) == 0 || len(memberIDs) > s.config.MaxMembers {
+		return nil, ErrMentionGroupInvalid
+	}
+
+	groupMembers, err := s.groupService.GetGroupMemberIDs(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string]bool, len(groupMembers))
+	for _, id := range groupMembers {
+		members[id] = true
+	}
+	for _, id := range memberIDs {
+		if !members[id] {
+			return nil, ErrMentionGroupInvalid
+		}
+	}
+	return memberIDs, nil
+}
+
+// contentStrings 读取消息内容中的字符串数组字段
+func contentStrings(content map[string]interface{}, key string) []string {
+	var result []string
+	switch values := content[key].(type) {
+	case []interface{}:
+		for _, v := range values {
+			if str, ok := v.(string); ok && str != "" {
+				result = append(result, str)
+			}
+		}
+	case []string:
+		result = append(result, values...)
+	}
+	return result
+}
+
+// excludeSender 去重并排除发送者
+func excludeSender(userIDs []string, senderID string) []string {
+	return util.RemoveString(uniqueStrings(userIDs), senderID)
+}
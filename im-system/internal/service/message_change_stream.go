@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 变更流Redis Key
+const (
+	changeStreamLockKey  = "im:changestream:messages:lock"  // 消费者锁，集群内只有一个节点消费变更流
+	changeStreamTokenKey = "im:changestream:messages:token" // 断点续订位置
+)
+
+// renewChangeStreamLockScript 仅当锁仍归属当前节点时续期
+var renewChangeStreamLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// MessageChangeListenerConfig 消息变更监听配置
+type MessageChangeListenerConfig struct {
+	NodeID        string        // 当前节点ID（锁持有者标识）
+	LockTTL       time.Duration // 消费者锁过期时间
+	RetryInterval time.Duration // 订阅失败后的初始重试间隔
+	MaxRetry      time.Duration // 最大重试间隔
+}
+
+// DefaultMessageChangeListenerConfig 默认消息变更监听配置
+func DefaultMessageChangeListenerConfig() *MessageChangeListenerConfig {
+	return &MessageChangeListenerConfig{
+		LockTTL:       30 * time.Second,
+		RetryInterval: time.Second,
+		MaxRetry:      30 * time.Second,
+	}
+}
+
+// MessageChangeListener 消息变更监听器
+// 监听 MongoDB messages 集合的变更流，统一驱动热缓存失效、会话状态更新和客户端同步通知
+type MessageChangeListener interface {
+	// Subscribe 注册变更处理器，需在 Start 之前调用
+	Subscribe(handler repository.MessageChangeHandler)
+
+	// Start 启动监听，阻塞直到 ctx 取消
+	Start(ctx context.Context)
+}
+
+// messageChangeListenerImpl 消息变更监听器实现
+type messageChangeListenerImpl struct {
+	config   *MessageChangeListenerConfig
+	repo     repository.MessageRepository
+	redis    *redis.Client
+	mu       sync.RWMutex
+	handlers []repository.MessageChangeHandler
+}
+
+// NewMessageChangeListener 创建消息变更监听器
+func NewMessageChangeListener(config *MessageChangeListenerConfig, repo repository.MessageRepository, redisClient *redis.Client) MessageChangeListener {
+	if config == nil {
+		config = DefaultMessageChangeListenerConfig()
+	}
+	if config.NodeID == "" {
+		config.NodeID = util.GenerateUUID()
+	}
+	return &messageChangeListenerImpl{
+		config: config,
+		repo:   repo,
+		redis:  redisClient,
+	}
+}
+
+// Subscribe 注册变更处理器
+func (l *messageChangeListenerImpl) Subscribe(handler repository.MessageChangeHandler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handlers = append(l.handlers, handler)
+}
+
+// Start 启动监听
+func (l *messageChangeListenerImpl) Start(ctx context.Context) {
+	backoff := l.config.RetryInterval
+	for ctx.Err() == nil {
+		acquired, err := l.redis.SetNX(ctx, changeStreamLockKey, l.config.NodeID, l.config.LockTTL).Result()
+		if err != nil || !acquired {
+			// 其他节点正在消费，等待锁释放后接管
+			if !sleepContext(ctx, l.config.LockTTL/2) {
+				return
+			}
+			continue
+		}
+
+		err = l.consume(ctx)
+		l.releaseLock()
+
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("message change stream error: %v, retry in %v", err, backoff)
+		}
+		if !sleepContext(ctx, backoff) {
+			return
+		}
+		backoff *= 2
+		if backoff > l.config.MaxRetry {
+			backoff = l.config.MaxRetry
+		}
+	}
+}
+
+// consume 持有锁期间消费变更流，锁丢失时返回
+func (l *messageChangeListenerImpl) consume(ctx context.Context) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go l.renewLock(watchCtx, cancel)
+
+	token, err := l.redis.Get(watchCtx, changeStreamTokenKey).Bytes()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("load resume token error: %w", err)
+	}
+
+	err = l.repo.Watch(watchCtx, token, l.dispatch)
+	if errors.Is(err, repository.ErrResumeTokenExpired) {
+		// 续订位置已过期，期间的变更无法追回，从当前位置重新订阅（热缓存依赖TTL兜底）
+		log.Printf("message change stream resume token expired, restart from now")
+		l.redis.Del(ctx, changeStreamTokenKey)
+		return nil
+	}
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// dispatch 分发变更事件并保存续订位置
+func (l *messageChangeListenerImpl) dispatch(ctx context.Context, event *repository.MessageChangeEvent) error {
+	l.mu.RLock()
+	handlers := l.handlers
+	l.mu.RUnlock()
+
+	// 单个处理器失败不影响其他处理器，也不阻塞变更流
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			log.Printf("handle message change %s error: %v", event.DocumentID.Hex(), err)
+		}
+	}
+
+	if len(event.ResumeToken) > 0 {
+		if err := l.redis.Set(ctx, changeStreamTokenKey, event.ResumeToken, 0).Err(); err != nil {
+			log.Printf("save change stream resume token error: %v", err)
+		}
+	}
+	return nil
+}
+
+// renewLock 定期续期消费者锁，续期失败时终止消费
+func (l *messageChangeListenerImpl) renewLock(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(l.config.LockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			renewed, err := renewChangeStreamLockScript.Run(ctx, l.redis,
+				[]string{changeStreamLockKey}, l.config.NodeID, l.config.LockTTL.Milliseconds()).Int()
+			if err != nil || renewed == 0 {
+				log.Printf("message change stream lock lost: %v", err)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// releaseLock 释放消费者锁（仅释放自己持有的锁）
+func (l *messageChangeListenerImpl) releaseLock() {
+	ctx := context.Background()
+	if owner, err := l.redis.Get(ctx, changeStreamLockKey).Result(); err == nil && owner == l.config.NodeID {
+		l.redis.Del(ctx, changeStreamLockKey)
+	}
+}
+
+// sleepContext 等待指定时间，ctx 取消时返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// ConversationUpdateNotifier 会话更新通知器
+// 消息被修改或删除时向会话成员推送轻量的 conversation_updated 事件，新消息本身已实时投递，不重复通知
+type ConversationUpdateNotifier struct {
+	groupService GroupService
+	dispatcher   MessageDispatcher
+}
+
+// NewConversationUpdateNotifier 创建会话更新通知器
+func NewConversationUpdateNotifier(groupService GroupService, dispatcher MessageDispatcher) *ConversationUpdateNotifier {
+	return &ConversationUpdateNotifier{
+		groupService: groupService,
+		dispatcher:   dispatcher,
+	}
+}
+
+// HandleChange 处理消息变更
+func (n *ConversationUpdateNotifier) HandleChange(ctx context.Context, event *repository.MessageChangeEvent) error {
+	if event.Op == repository.MessageChangeInsert || event.Document == nil {
+		return nil
+	}
+	doc := event.Document
+
+	recipients, err := messageRecipients(ctx, n.groupService, doc)
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	msg := &model.Message{
+		MessageID:      util.GenerateMessageID(),
+		Type:           model.MsgConvUpdated,
+		From:           "system",
+		ConversationID: doc.ConversationID,
+		Content: &model.ConversationUpdatedContent{
+			ConversationID: doc.ConversationID,
+			Op:             string(event.Op),
+			MessageID:      doc.MessageID,
+			Seq:            doc.Seq,
+			Revoked:        doc.Revoked,
+		},
+		Timestamp: time.Now().UnixMilli(),
+	}
+	return n.dispatcher.DispatchToUsers(ctx, recipients, msg)
+}
+
+// messageRecipients 计算消息所在会话的成员
+func messageRecipients(ctx context.Context, groupService GroupService, doc *repository.MessageDocument) ([]string, error) {
+	if doc.GroupID != "" {
+		return groupService.GetGroupMemberIDs(ctx, doc.GroupID)
+	}
+	if doc.From == "" || doc.To == "" {
+		return nil, nil
+	}
+	return []string{doc.From, doc.To}, nil
+}
+
+// ConversationStateUpdater 会话状态维护器，根据新消息更新会话的最后一条消息，
+// 聊天消息同时为参与者创建缺少的用户会话并累加接收者未读数
+type ConversationStateUpdater struct {
+	repo         repository.ConversationRepository
+	groupService GroupService
+}
+
+// NewConversationStateUpdater 创建会话状态维护器
+func NewConversationStateUpdater(repo repository.ConversationRepository, groupService GroupService) *ConversationStateUpdater {
+	return &ConversationStateUpdater{repo: repo, groupService: groupService}
+}
+
+// Record 记录一条新保存的消息
+func (u *ConversationStateUpdater) Record(ctx context.Context, doc *repository.MessageDocument) error {
+	if doc.ConversationID == "" {
+		return nil
+	}
+
+	conversationID, convType := doc.ConversationID, model.ConversationTypeSingle
+	if convID, err := model.ParseConversationID(doc.ConversationID); err == nil {
+		conversationID, convType = convID.String(), convID.Type
+	}
+	if doc.GroupID != "" {
+		convType = model.ConversationTypeGroup
+	}
+	if !model.MessageType(doc.Type).IsChat() {
+		return u.repo.UpsertLastMessage(ctx, conversationID, convType, doc.MessageID, doc.CreatedAt)
+	}
+
+	recipients, err := messageRecipients(ctx, u.groupService, doc)
+	if err != nil {
+		return fmt.Errorf("find message recipients error: %w", err)
+	}
+	return u.repo.RecordMessage(ctx, conversationID, convType, doc.MessageID, doc.CreatedAt, doc.From, recipients)
+}
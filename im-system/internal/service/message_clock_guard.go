@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// 消息时间校验错误定义
+var (
+	ErrMessageClockOutOfRange = errors.New("message timestamp out of range")
+	ErrMessageQuarantined     = errors.New("message quarantined for timestamp review")
+	ErrQuarantineNotFound     = errors.New("quarantined message not found")
+)
+
+// messageClockGuardTotal 时间超出范围的消息数
+var messageClockGuardTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "im",
+	Subsystem: "message",
+	Name:      "clock_out_of_range_total",
+	Help:      "时间超出范围的消息数",
+}, []string{"direction", "action"})
+
+// MessageClockGuardConfig 消息时间校验配置
+type MessageClockGuardConfig struct {
+	MaxFuture  time.Duration // 消息时间超前服务器时间的上限，0表示不检查
+	MaxPast    time.Duration // 消息时间落后服务器时间的上限，0表示不检查
+	Quarantine bool          // 超出范围的消息转入隔离集合待审核，否则直接拒绝
+}
+
+// DefaultMessageClockGuardConfig 默认消息时间校验配置
+func DefaultMessageClockGuardConfig() *MessageClockGuardConfig {
+	return &MessageClockGuardConfig{
+		MaxFuture:  5 * time.Minute,
+		MaxPast:    365 * 24 * time.Hour,
+		Quarantine: true,
+	}
+}
+
+// clockGuardMessageRepository 校验消息时间的消息仓库：消息时间决定 TTL 过期时间和按时间的排序、分页，
+// 导入、桥接等路径使用外部时间戳，时间超出范围的消息不写入消息集合
+type clockGuardMessageRepository struct {
+	repository.MessageRepository
+	quarantine repository.MessageQuarantineRepository
+	config     *MessageClockGuardConfig
+}
+
+// NewClockGuardMessageRepository 创建校验消息时间的消息仓库，quarantine 为空时超出范围的消息直接拒绝
+func NewClockGuardMessageRepository(repo repository.MessageRepository, quarantine repository.MessageQuarantineRepository, config *MessageClockGuardConfig) repository.MessageRepository {
+	if config == nil {
+		config = DefaultMessageClockGuardConfig()
+	}
+	return &clockGuardMessageRepository{
+		MessageRepository: repo,
+		quarantine:        quarantine,
+		config:            config,
+	}
+}
+
+// Save 保存消息，时间超出范围时隔离或拒绝
+func (r *clockGuardMessageRepository) Save(ctx context.Context, msg *repository.MessageDocument) error {
+	direction, skew := r.check(msg, time.Now())
+	if direction == "" {
+		return r.MessageRepository.Save(ctx, msg)
+	}
+	return r.reject(ctx, msg, direction, skew)
+}
+
+// SaveBatch 批量保存消息：不隔离时有超出范围的消息则整批拒绝，否则保存范围内的消息并隔离其余消息
+func (r *clockGuardMessageRepository) SaveBatch(ctx context.Context, msgs []*repository.MessageDocument) error {
+	now := time.Now()
+	accepted := make([]*repository.MessageDocument, 0, len(msgs))
+	var rejected []*repository.MessageDocument
+	for _, msg := range msgs {
+		if direction, _ := r.check(msg, now); direction != "" {
+			rejected = append(rejected, msg)
+			continue
+		}
+		accepted = append(accepted, msg)
+	}
+	if len(rejected) == 0 {
+		return r.MessageRepository.SaveBatch(ctx, msgs)
+	}
+	if !r.quarantining() {
+		for _, msg := range rejected {
+			direction, _ := r.check(msg, now)
+			messageClockGuardTotal.WithLabelValues(direction, "rejected").Inc()
+		}
+		return fmt.Errorf("%d of %d messages: %w", len(rejected), len(msgs), ErrMessageClockOutOfRange)
+	}
+
+	if err := r.MessageRepository.SaveBatch(ctx, accepted); err != nil {
+		return err
+	}
+	for _, msg := range rejected {
+		direction, skew := r.check(msg, now)
+		if err := r.reject(ctx, msg, direction, skew); !errors.Is(err, ErrMessageQuarantined) {
+			return err
+		}
+	}
+	return fmt.Errorf("%d of %d messages: %w", len(rejected), len(msgs), ErrMessageQuarantined)
+}
+
+// check 校验消息时间，超出范围时返回偏差方向和偏差（超前为正）；未设置时间的消息由仓库使用当前时间
+func (r *clockGuardMessageRepository) check(msg *repository.MessageDocument, now time.Time) (string, time.Duration) {
+	if msg.CreatedAt.IsZero() {
+		return "", 0
+	}
+	skew := msg.CreatedAt.Sub(now)
+	switch {
+	case r.config.MaxFuture > 0 && skew > r.config.MaxFuture:
+		return repository.ClockSkewFuture, skew
+	case r.config.MaxPast > 0 && -skew > r.config.MaxPast:
+		return repository.ClockSkewPast, skew
+	}
+	return "", skew
+}
+
+// quarantining 是否隔离超出范围的消息
+func (r *clockGuardMessageRepository) quarantining() bool {
+	return r.config.Quarantine && r.quarantine != nil
+}
+
+// reject 隔离或拒绝超出范围的消息
+func (r *clockGuardMessageRepository) reject(ctx context.Context, msg *repository.MessageDocument, direction string, skew time.Duration) error {
+	if !r.quarantining() {
+		messageClockGuardTotal.WithLabelValues(direction, "rejected").Inc()
+		return fmt.Errorf("message %s created at %s: %w", msg.MessageID, msg.CreatedAt.Format(time.RFC3339), ErrMessageClockOutOfRange)
+	}
+
+	if err := r.quarantine.Save(ctx, &repository.QuarantinedMessage{
+		MessageID:     msg.MessageID,
+		Direction:     direction,
+		SkewMs:        skew.Milliseconds(),
+		Message:       msg,
+		QuarantinedAt: time.Now(),
+	}); err != nil {
+		return err
+	}
+	messageClockGuardTotal.WithLabelValues(direction, "quarantined").Inc()
+	log.Printf("Message %s quarantined: created at %s (%s skew %s)", msg.MessageID, msg.CreatedAt.Format(time.RFC3339), direction, skew)
+	return ErrMessageQuarantined
+}
+
+// MessageQuarantineService 隔离消息审核服务
+type MessageQuarantineService interface {
+	// List 分页获取隔离消息（按隔离时间倒序）
+	List(ctx context.Context, page, pageSize int) ([]*repository.QuarantinedMessage, int64, error)
+
+	// Release 放行隔离消息：消息时间改为隔离时间后写入消息集合（只补入历史，不重新投递）
+	Release(ctx context.Context, messageID string) (*repository.MessageDocument, error)
+
+	// Discard 丢弃隔离消息
+	Discard(ctx context.Context, messageID string) error
+}
+
+// messageQuarantineServiceImpl 隔离消息审核服务实现
+type messageQuarantineServiceImpl struct {
+	quarantine  repository.MessageQuarantineRepository
+	messageRepo repository.MessageRepository
+}
+
+// NewMessageQuarantineService 创建隔离消息审核服务，messageRepo 须为未校验时间的消息仓库
+func NewMessageQuarantineService(quarantine repository.MessageQuarantineRepository, messageRepo repository.MessageRepository) MessageQuarantineService {
+	return &messageQuarantineServiceImpl{
+		quarantine:  quarantine,
+		messageRepo: messageRepo,
+	}
+}
+
+// List 分页获取隔离消息
+func (s *messageQuarantineServiceImpl) List(ctx context.Context, page, pageSize int) ([]*repository.QuarantinedMessage, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return s.quarantine.List(ctx, (page-1)*pageSize, pageSize)
+}
+
+// Release 放行隔离消息
+func (s *messageQuarantineServiceImpl) Release(ctx context.Context, messageID string) (*repository.MessageDocument, error) {
+	quarantined, err := s.quarantine.Find(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if quarantined == nil || quarantined.Message == nil {
+		return nil, ErrQuarantineNotFound
+	}
+
+	doc := quarantined.Message
+	doc.ID = primitive.NilObjectID
+	doc.CreatedAt = quarantined.QuarantinedAt
+	if err := s.messageRepo.Save(ctx, doc); err != nil {
+		return nil, err
+	}
+	if _, err := s.quarantine.Delete(ctx, messageID); err != nil {
+		return nil, err
+	}
+	messageClockGuardTotal.WithLabelValues(quarantined.Direction, "released").Inc()
+	return doc, nil
+}
+
+// Discard 丢弃隔离消息
+func (s *messageQuarantineServiceImpl) Discard(ctx context.Context, messageID string) error {
+	quarantined, err := s.quarantine.Find(ctx, messageID)
+	if err != nil {
+		return err
+	}
+	if quarantined == nil {
+		return ErrQuarantineNotFound
+	}
+	if _, err := s.quarantine.Delete(ctx, messageID); err != nil {
+		return err
+	}
+	messageClockGuardTotal.WithLabelValues(quarantined.Direction, "discarded").Inc()
+	return nil
+}
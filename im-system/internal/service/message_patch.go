@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// MessagePatchNotifier 消息局部更新通知器
+// 消息被服务端修改（撤回、编辑、表情回应等）时向会话成员推送 patch 帧，只携带变更字段，不重发整条消息
+type MessagePatchNotifier struct {
+	groupService GroupService
+	dispatcher   MessageDispatcher
+}
+
+// NewMessagePatchNotifier 创建消息局部更新通知器
+func NewMessagePatchNotifier(groupService GroupService, dispatcher MessageDispatcher) *MessagePatchNotifier {
+	return &MessagePatchNotifier{
+		groupService: groupService,
+		dispatcher:   dispatcher,
+	}
+}
+
+// Notify 推送消息局部更新
+func (n *MessagePatchNotifier) Notify(ctx context.Context, doc *repository.MessageDocument, ops ...model.PatchOperation) error {
+	if len(ops) == 0 {
+		return nil
+	}
+
+	recipients, err := messageRecipients(ctx, n.groupService, doc)
+	if err != nil {
+		return err
+	}
+	return n.NotifyUsers(ctx, recipients, doc, ops...)
+}
+
+// NotifyUsers 向指定用户推送消息局部更新
+func (n *MessagePatchNotifier) NotifyUsers(ctx context.Context, userIDs []string, doc *repository.MessageDocument, ops ...model.PatchOperation) error {
+	if len(userIDs) == 0 || len(ops) == 0 {
+		return nil
+	}
+
+	now := time.Now().UnixMilli()
+	msg := &model.Message{
+		MessageID:      util.GenerateMessageID(),
+		Type:           model.MsgPatch,
+		From:           "system",
+		ConversationID: doc.ConversationID,
+		Content: &model.PatchContent{
+			MessageID:      doc.MessageID,
+			ConversationID: doc.ConversationID,
+			Seq:            doc.Seq,
+			Version:        now,
+			Ops:            ops,
+		},
+		Timestamp: now,
+	}
+	return n.dispatcher.DispatchToUsers(ctx, userIDs, msg)
+}
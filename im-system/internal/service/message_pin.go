@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// 置顶消息错误定义
+var (
+	ErrPinLimitExceeded = errors.New("pinned message limit exceeded")
+)
+
+// MaxPinnedMessages 每个会话最多置顶的消息数
+const MaxPinnedMessages = 50
+
+// PinnedMessageView 置顶消息（含消息内容）
+type PinnedMessageView struct {
+	*model.PinnedMessage
+	Message *MessageDTO `json:"message,omitempty"` // 消息已撤回时为空
+}
+
+// MessagePinService 置顶消息服务
+type MessagePinService interface {
+	// ListPins 查询会话置顶消息（仅会话参与者）
+	ListPins(ctx context.Context, userID, conversationID string) ([]*PinnedMessageView, error)
+
+	// Pin 置顶消息（单聊双方，群聊为群主或管理员）
+	Pin(ctx context.Context, userID, conversationID, messageID string) (*model.PinnedMessage, error)
+
+	// Unpin 取消置顶（单聊双方，群聊为群主或管理员）
+	Unpin(ctx context.Context, userID, conversationID, messageID string) error
+}
+
+// messagePinServiceImpl 置顶消息服务实现
+type messagePinServiceImpl struct {
+	repo         repository.MessagePinRepository
+	messageRepo  repository.MessageRepository
+	groupService GroupService
+	counters     ConversationCounterService
+}
+
+// NewMessagePinService 创建置顶消息服务，counters 不为空时同步维护会话置顶消息数
+func NewMessagePinService(repo repository.MessagePinRepository, messageRepo repository.MessageRepository, groupService GroupService, counters ConversationCounterService) MessagePinService {
+	return &messagePinServiceImpl{
+		repo:         repo,
+		messageRepo:  messageRepo,
+		groupService: groupService,
+		counters:     counters,
+	}
+}
+
+// ListPins 查询会话置顶消息
+func (s *messagePinServiceImpl) ListPins(ctx context.Context, userID, conversationID string) ([]*PinnedMessageView, error) {
+	convID, err := authorizeConversation(ctx, s.groupService, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	pins, err := s.repo.FindByConversation(ctx, convID.String())
+	if err != nil {
+		return nil, fmt.Errorf("find pinned messages error: %w", err)
+	}
+
+	views := make([]*PinnedMessageView, 0, len(pins))
+	for _, pin := range pins {
+		view := &PinnedMessageView{PinnedMessage: pin}
+		doc, err := s.messageRepo.FindByMessageID(ctx, pin.MessageID)
+		if err != nil {
+			return nil, fmt.Errorf("find pinned message error: %w", err)
+		}
+		if doc != nil && !doc.Revoked && !doc.Cancelled {
+			view.Message = documentToDTO(doc)
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// Pin 置顶消息
+func (s *messagePinServiceImpl) Pin(ctx context.Context, userID, conversationID, messageID string) (*model.PinnedMessage, error) {
+	convID, err := s.authorizeManage(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.messageRepo.FindByMessageID(ctx, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("find message error: %w", err)
+	}
+	if doc == nil || doc.Revoked || doc.Cancelled || model.CanonicalConversationID(doc.ConversationID) != convID.String() {
+		return nil, ErrMessageNotFound
+	}
+
+	count, err := s.repo.CountByConversation(ctx, convID.String())
+	if err != nil {
+		return nil, fmt.Errorf("count pinned messages error: %w", err)
+	}
+	if count >= MaxPinnedMessages {
+		return nil, ErrPinLimitExceeded
+	}
+
+	pin := &model.PinnedMessage{
+		ConversationID: convID.String(),
+		MessageID:      messageID,
+		PinnedBy:       userID,
+	}
+	created, err := s.repo.Create(ctx, pin)
+	if err != nil {
+		return nil, fmt.Errorf("pin message error: %w", err)
+	}
+	if created && s.counters != nil {
+		s.counters.AdjustPinned(ctx, convID.String(), 1)
+	}
+	return pin, nil
+}
+
+// Unpin 取消置顶
+func (s *messagePinServiceImpl) Unpin(ctx context.Context, userID, conversationID, messageID string) error {
+	convID, err := s.authorizeManage(ctx, userID, conversationID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.Delete(ctx, convID.String(), messageID)
+	if err != nil {
+		return fmt.Errorf("unpin message error: %w", err)
+	}
+	if deleted && s.counters != nil {
+		s.counters.AdjustPinned(ctx, convID.String(), -1)
+	}
+	return nil
+}
+
+// authorizeManage 校验用户可以管理置顶消息：单聊双方，群聊为群主或管理员
+func (s *messagePinServiceImpl) authorizeManage(ctx context.Context, userID, conversationID string) (model.ConversationID, error) {
+	convID, err := authorizeConversation(ctx, s.groupService, userID, conversationID)
+	if err != nil || !convID.IsGroup() {
+		return convID, err
+	}
+
+	role, err := s.groupService.GetMemberRole(ctx, convID.GroupID, userID)
+	if err != nil {
+		return convID, err
+	}
+	if role < model.RoleAdmin {
+		return convID, ErrPermissionDeny
+	}
+	return convID, nil
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// ErrPurgeFilterInvalid 清除条件无效（会话ID无效、未指定任何条件或时间范围无效）
+var ErrPurgeFilterInvalid = errors.New("invalid purge filter")
+
+// MaxPurgeMessages 单次最多清除的消息数，超过时返回 has_more，可重复调用继续清除
+const MaxPurgeMessages = 1000
+
+// PurgeMessagesRequest 清除会话消息请求，各条件同时满足，至少指定一个条件
+type PurgeMessagesRequest struct {
+	SenderID   string   `json:"sender_id"`   // 发送者
+	Since      int64    `json:"since"`       // 开始时间（毫秒时间戳，含）
+	Until      int64    `json:"until"`       // 结束时间（毫秒时间戳，不含）
+	Keyword    string   `json:"keyword"`     // 文本消息内容包含关键字（不区分大小写）
+	MessageIDs []string `json:"message_ids"` // 指定消息ID
+	DryRun     bool     `json:"dry_run"`     // 只返回匹配的消息，不清除
+}
+
+// PurgeMessagesResult 清除会话消息的结果
+type PurgeMessagesResult struct {
+	ConversationID string   `json:"conversation_id"`
+	MessageIDs     []string `json:"message_ids"`     // 匹配（dry_run）或已清除的消息
+	Purged         int64    `json:"purged"`          // 清除数量
+	OfflineRemoved int      `json:"offline_removed"` // 移除的离线消息副本数
+	HasMore        bool     `json:"has_more"`        // 还有更多匹配的消息
+	DryRun         bool     `json:"dry_run,omitempty"`
+}
+
+// PurgeConversation 管理员批量清除会话内的消息
+// 匹配的消息清空内容并标记为已清除（同时视为已撤回，不再出现在历史、搜索和计数中），
+// 移除接收者尚未拉取的离线副本和待执行推送，并向在线成员推送 /purged patch 帧
+func (s *messageServiceImpl) PurgeConversation(ctx context.Context, operatorID, conversationID string, req *PurgeMessagesRequest) (*PurgeMessagesResult, error) {
+	convID, err := model.ParseConversationID(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPurgeFilterInvalid, err)
+	}
+	filter, err := buildPurgeFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := s.messageRepo.FindForPurge(ctx, convID.String(), filter, MaxPurgeMessages+1)
+	if err != nil {
+		return nil, fmt.Errorf("find messages to purge error: %w", err)
+	}
+	result := &PurgeMessagesResult{ConversationID: convID.String(), MessageIDs: []string{}, DryRun: req.DryRun}
+	if len(docs) > MaxPurgeMessages {
+		docs, result.HasMore = docs[:MaxPurgeMessages], true
+	}
+	for _, doc := range docs {
+		result.MessageIDs = append(result.MessageIDs, doc.MessageID)
+	}
+	if req.DryRun || len(docs) == 0 {
+		return result, nil
+	}
+
+	if result.Purged, err = s.messageRepo.Purge(ctx, convID.String(), result.MessageIDs, operatorID); err != nil {
+		return nil, fmt.Errorf("purge messages error: %w", err)
+	}
+
+	// 接收者尚未拉取的离线副本和推送不再投递；已撤回的消息计数已调整过
+	for _, doc := range docs {
+		if s.offlineService != nil {
+			withdrawn, err := s.offlineService.CancelMessage(ctx, doc.MessageID)
+			if err != nil {
+				log.Printf("withdraw offline copies of purged message %s error: %v", doc.MessageID, err)
+			}
+			result.OfflineRemoved += len(withdrawn)
+		}
+		if s.pushService != nil {
+			s.pushService.CancelMessage(doc.MessageID)
+		}
+		if s.counters != nil && !doc.Revoked {
+			s.counters.RecordRevoke(ctx, doc.ConversationID, model.MessageType(doc.Type))
+		}
+	}
+
+	if !s.changeStream {
+		s.invalidateHotCache(ctx, convID.String())
+	}
+
+	s.notifyPurged(ctx, docs, operatorID)
+	return result, nil
+}
+
+// notifyPurged 向会话成员推送消息已清除的 patch 帧（会话成员只查询一次）
+func (s *messageServiceImpl) notifyPurged(ctx context.Context, docs []*repository.MessageDocument, operatorID string) {
+	if s.patchNotifier == nil || len(docs) == 0 {
+		return
+	}
+	recipients, err := messageRecipients(ctx, s.groupService, docs[0])
+	if err != nil {
+		log.Printf("get recipients of purged messages error: %v", err)
+		return
+	}
+	for _, doc := range docs {
+		if err := s.patchNotifier.NotifyUsers(ctx, recipients, doc,
+			model.PatchOperation{Op: model.PatchOpReplace, Path: "/purged", Value: true},
+			model.PatchOperation{Op: model.PatchOpReplace, Path: "/content", Value: map[string]interface{}{}},
+		); err != nil {
+			log.Printf("push purge patch for message %s error: %v", doc.MessageID, err)
+		}
+	}
+	log.Printf("Purged %d messages in conversation %s by %s", len(docs), docs[0].ConversationID, operatorID)
+}
+
+// buildPurgeFilter 校验并转换清除条件
+func buildPurgeFilter(req *PurgeMessagesRequest) (*repository.MessagePurgeFilter, error) {
+	filter := &repository.MessagePurgeFilter{
+		SenderID:   strings.TrimSpace(req.SenderID),
+		Keyword:    strings.TrimSpace(req.Keyword),
+		MessageIDs: uniqueStrings(req.MessageIDs),
+	}
+	if req.Since > 0 {
+		filter.Since = time.UnixMilli(req.Since)
+	}
+	if req.Until > 0 {
+		filter.Until = time.UnixMilli(req.Until)
+	}
+
+	if filter.SenderID == "" && filter.Keyword == "" && len(filter.MessageIDs) == 0 && filter.Since.IsZero() && filter.Until.IsZero() {
+		return nil, fmt.Errorf("%w: at least one filter is required", ErrPurgeFilterInvalid)
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return nil, fmt.Errorf("%w: since must be before until", ErrPurgeFilterInvalid)
+	}
+	if len(filter.MessageIDs) > MaxPurgeMessages {
+		return nil, fmt.Errorf("%w: at most %d message ids", ErrPurgeFilterInvalid, MaxPurgeMessages)
+	}
+	return filter, nil
+}
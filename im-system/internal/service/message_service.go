@@ -4,18 +4,48 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/d60-lab/im-system/internal/model"
 	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/tracing"
+)
+
+// 消息错误定义
+var (
+	ErrMessageContentInvalid = errors.New("invalid message content")
+
+	ErrRevokeNotSender    = errors.New("only sender can revoke message")
+	ErrRevokeTimeExceeded = errors.New("message revoke time exceeded")
+
+	ErrCancelNotSender   = errors.New("only sender can cancel message")
+	ErrMessageNotPending = errors.New("message is not pending delivery")
 )
 
+// MessageRevokeWindow 消息撤回时限
+const MessageRevokeWindow = 2 * time.Minute
+
 // MessageService 消息服务接口
 type MessageService interface {
-	// SaveMessage 保存消息
+	// SaveMessage 保存消息（内容须符合该消息类型的最新结构）
 	SaveMessage(ctx context.Context, msg *model.Message) error
 
+	// SavePendingMessage 保存消息但暂不更新会话记录，用于回ACK后还需分发前检查的消息，检查通过后调用 RecordConversation
+	SavePendingMessage(ctx context.Context, msg *model.Message) error
+
+	// RecordConversation 更新消息所属会话的最后一条消息及参与者的用户会话、未读数
+	RecordConversation(ctx context.Context, msg *model.Message) error
+
+	// ValidateContent 将消息内容转换为对象并按最新结构校验（发送前调用，会改写 msg.Content）
+	ValidateContent(msg *model.Message) error
+
 	// GetConversationMessages 获取会话消息历史
 	GetConversationMessages(ctx context.Context, userID, conversationID string, lastSeq int64, limit int) ([]*MessageDTO, error)
 
@@ -28,8 +58,42 @@ type MessageService interface {
 	// RevokeMessage 撤回消息
 	RevokeMessage(ctx context.Context, userID, messageID string) error
 
+	// CancelPendingMessage 取消尚未投递的消息：从离线队列和推送队列中移除，标记为已取消，
+	// 并通知已收到该消息的成员隐藏
+	CancelPendingMessage(ctx context.Context, userID, messageID string) (*PendingCancelResult, error)
+
+	// FailMessage 标记消息发送失败（回ACK后被拒绝）：撤回已生成的离线消息和推送，消息不再出现在历史中
+	FailMessage(ctx context.Context, messageID string, code int, reason string) error
+
+	// PurgeConversation 管理员批量清除会话内匹配条件的消息，移除离线副本并通知在线成员
+	PurgeConversation(ctx context.Context, operatorID, conversationID string, req *PurgeMessagesRequest) (*PurgeMessagesResult, error)
+
+	// SetPendingQueues 设置待投递队列（离线消息、推送），为空的队列不参与取消
+	SetPendingQueues(offlineService OfflineService, pushService PushService)
+
 	// GetMessageByID 获取单条消息
 	GetMessageByID(ctx context.Context, messageID string) (*MessageDTO, error)
+
+	// GetTimeline 获取用户跨会话的最新消息（从会话热缓存合并）
+	GetTimeline(ctx context.Context, userID string, limit int) ([]*MessageDTO, error)
+
+	// UseChangeStream 改由消息变更流维护热缓存，不再在写入路径上直接更新
+	UseChangeStream(listener MessageChangeListener)
+
+	// SetPatchNotifier 设置消息局部更新通知器，消息被修改时向会话成员推送 patch 帧
+	SetPatchNotifier(notifier *MessagePatchNotifier)
+
+	// SetCounters 设置会话计数服务，撤回消息时同步调整计数
+	SetCounters(counters ConversationCounterService)
+
+	// SetDataRegions 设置数据驻留服务，保存消息前确定会话的存储区域并校验跨区域规则
+	SetDataRegions(regions DataRegionService)
+
+	// SetConversationState 设置会话状态维护器，保存消息后更新会话及参与者的用户会话
+	SetConversationState(updater *ConversationStateUpdater)
+
+	// SetMentionResolver 设置提及解析，保存聊天消息时解析提及对象并记录到消息文档
+	SetMentionResolver(resolver MentionResolver)
 }
 
 // MessageDTO 消息数据传输对象
@@ -41,60 +105,229 @@ type MessageDTO struct {
 	To             string                 `json:"to"`
 	GroupID        string                 `json:"group_id,omitempty"`
 	Content        map[string]interface{} `json:"content"`
+	ContentVersion int                    `json:"content_version,omitempty"`
 	Seq            int64                  `json:"seq"`
 	Status         int                    `json:"status"`
 	Revoked        bool                   `json:"revoked"`
+	Failed         bool                   `json:"failed,omitempty"` // 回ACK后被拒绝，未投递
+	FailCode       int                    `json:"fail_code,omitempty"`
+	FailReason     string                 `json:"fail_reason,omitempty"`
+	MentionTargets []string               `json:"mention_targets,omitempty"` // 发送时解析的提及对象
+	Purged         bool                   `json:"purged,omitempty"`          // 被管理员清除
 	Timestamp      int64                  `json:"timestamp"`
 	CreatedAt      time.Time              `json:"created_at"`
 }
 
+// PendingCancelResult 取消待投递消息的结果
+type PendingCancelResult struct {
+	MessageID string   `json:"message_id"`
+	Withdrawn []string `json:"withdrawn"` // 尚未收到、已从离线队列移除的用户
+	Notified  []string `json:"notified"`  // 已收到、被通知隐藏的用户
+}
+
 // messageServiceImpl 消息服务实现
 type messageServiceImpl struct {
 	messageRepo  repository.MessageRepository
 	groupService GroupService
+	redis        *redis.Client
+
+	changeStream  bool // 热缓存由变更流维护
+	patchNotifier *MessagePatchNotifier
+
+	offlineService OfflineService
+	pushService    PushService
+	counters       ConversationCounterService
+	regions        DataRegionService
+	conversations  *ConversationStateUpdater
+	mentions       MentionResolver
 }
 
 // NewMessageService 创建消息服务
-func NewMessageService(messageRepo repository.MessageRepository, groupService GroupService) MessageService {
+// redisClient 用于会话热缓存，为空时不启用
+func NewMessageService(messageRepo repository.MessageRepository, groupService GroupService, redisClient *redis.Client) MessageService {
 	return &messageServiceImpl{
 		messageRepo:  messageRepo,
 		groupService: groupService,
+		redis:        redisClient,
 	}
 }
 
-// SaveMessage 保存消息
+// SaveMessage 保存消息并更新会话记录
 func (s *messageServiceImpl) SaveMessage(ctx context.Context, msg *model.Message) error {
-	// 转换content为map
-	content := s.convertContent(msg.Content)
+	doc, err := s.saveMessage(ctx, msg)
+	if err != nil {
+		return err
+	}
+	// 消息已写入，会话记录更新失败只影响会话列表，不影响投递
+	if err := s.recordConversation(ctx, doc); err != nil {
+		log.Printf("record conversation %s for message %s error: %v", doc.ConversationID, doc.MessageID, err)
+	}
+	return nil
+}
+
+// SavePendingMessage 保存消息，会话记录由分发前检查通过后的 RecordConversation 更新
+func (s *messageServiceImpl) SavePendingMessage(ctx context.Context, msg *model.Message) error {
+	_, err := s.saveMessage(ctx, msg)
+	return err
+}
+
+// RecordConversation 更新消息所属会话的记录
+func (s *messageServiceImpl) RecordConversation(ctx context.Context, msg *model.Message) error {
+	groupID, conversationID := messageConversation(msg)
+	doc := &repository.MessageDocument{
+		MessageID:      msg.MessageID,
+		ConversationID: conversationID,
+		Type:           int(msg.Type),
+		From:           msg.From,
+		To:             msg.To,
+		GroupID:        groupID,
+		CreatedAt:      time.Now(),
+	}
+	if msg.Timestamp > 0 {
+		doc.CreatedAt = time.UnixMilli(msg.Timestamp)
+	}
+	return s.recordConversation(ctx, doc)
+}
+
+// recordConversation 根据已保存的消息文档更新会话记录
+func (s *messageServiceImpl) recordConversation(ctx context.Context, doc *repository.MessageDocument) error {
+	if s.conversations == nil {
+		return nil
+	}
+	return s.conversations.Record(ctx, doc)
+}
 
-	// 确定group_id
-	groupID := ""
-	if msg.Type == model.MsgGroupChat {
+// messageConversation 确定消息的群ID和规范格式的会话ID
+func messageConversation(msg *model.Message) (groupID, conversationID string) {
+	groupID = msg.GroupID
+	if groupID == "" && msg.Type == model.MsgGroupChat {
 		groupID = msg.To
 	}
+	conversationID = model.CanonicalConversationID(msg.ConversationID)
+	if conversationID == "" {
+		if groupID != "" {
+			conversationID = model.GetGroupChatConversationID(groupID)
+		} else {
+			conversationID = model.GetSingleChatConversationID(msg.From, msg.To)
+		}
+	}
+	return groupID, conversationID
+}
+
+// saveMessage 校验内容并写入消息文档
+func (s *messageServiceImpl) saveMessage(ctx context.Context, msg *model.Message) (doc *repository.MessageDocument, err error) {
+	ctx, span := tracing.Start(ctx, "message.save", trace.WithAttributes(
+		tracing.AttrMessageID.String(msg.MessageID),
+		tracing.AttrMessageType.Int(int(msg.Type)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	// 转换content为map并按最新结构校验
+	content, contentVersion, err := s.prepareContent(msg.Type, msg.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	// 确定group_id，补全会话ID（统一为规范格式）
+	groupID, conversationID := messageConversation(msg)
+
+	// 确定会话存储区域（消息仓库按区域路由），聊天消息的发送者须满足跨区域规则
+	if s.regions != nil {
+		senderID := ""
+		if msg.Type.IsChat() {
+			senderID = msg.From
+		}
+		if _, err := s.regions.ResolveConversation(ctx, conversationID, senderID); err != nil && !errors.Is(err, model.ErrInvalidConversationID) {
+			return nil, err
+		}
+	}
+
+	// 解析提及对象（角色、提及组按当前群成员展开），客户端传入的 mention_targets 一律覆盖
+	msg.MentionTargets = nil
+	if s.mentions != nil && msg.Type.IsChat() {
+		targets, err := s.mentions.Resolve(ctx, groupID, msg.From, content)
+		if err != nil {
+			// 解析失败不影响发送，直接@的用户仍按 at_user_ids 计数和推送
+			log.Printf("resolve mentions of message %s error: %v", msg.MessageID, err)
+		} else if len(targets) > 0 {
+			msg.MentionTargets = targets
+		}
+	}
 
 	// 创建文档
-	doc := &repository.MessageDocument{
+	doc = &repository.MessageDocument{
 		MessageID:      msg.MessageID,
-		ConversationID: msg.ConversationID,
+		ConversationID: conversationID,
 		Type:           int(msg.Type),
 		From:           msg.From,
 		To:             msg.To,
 		GroupID:        groupID,
 		Content:        content,
+		ContentVersion: contentVersion,
 		Seq:            msg.Seq,
 		Status:         1, // 已发送
 		Revoked:        false,
-		CreatedAt:      time.UnixMilli(msg.Timestamp),
+		MentionTargets: msg.MentionTargets,
+	}
+	// 未设置时间的消息由仓库使用当前时间（避免存为1970年）
+	if msg.Timestamp > 0 {
+		doc.CreatedAt = time.UnixMilli(msg.Timestamp)
+	}
+
+	// 写入 MongoDB 单独记录 Span，与区域、提及解析的耗时区分
+	saveCtx, saveSpan := tracing.Start(ctx, "mongo.save_message",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "mongodb"), tracing.AttrConversationID.String(conversationID)),
+	)
+	err = s.messageRepo.Save(saveCtx, doc)
+	tracing.End(saveSpan, err)
+	if err != nil {
+		return nil, fmt.Errorf("save message error: %w", err)
+	}
+
+	// 消息引用的文件不再是孤儿文件
+	if fileIDs := referencedFileIDs(content); len(fileIDs) > 0 && s.redis != nil {
+		members := make([]interface{}, len(fileIDs))
+		for i, fileID := range fileIDs {
+			members[i] = fileID
+		}
+		if err := s.redis.ZRem(ctx, pendingFilesKey, members...).Err(); err != nil {
+			log.Printf("clear pending files of message %s error: %v", doc.MessageID, err)
+		}
 	}
 
-	if err := s.messageRepo.Save(ctx, doc); err != nil {
-		return fmt.Errorf("save message error: %w", err)
+	if !s.changeStream {
+		s.cacheMessage(ctx, doc)
 	}
 
+	return doc, nil
+}
+
+// ValidateContent 将消息内容转换为对象并按最新结构校验
+func (s *messageServiceImpl) ValidateContent(msg *model.Message) error {
+	content, _, err := s.prepareContent(msg.Type, msg.Content)
+	if err != nil {
+		return err
+	}
+	msg.Content = content
 	return nil
 }
 
+// prepareContent 转换消息内容为map并按最新结构校验，返回内容及结构版本
+func (s *messageServiceImpl) prepareContent(msgType model.MessageType, content interface{}) (map[string]interface{}, int, error) {
+	// 字符串内容按文本处理
+	if text, ok := content.(string); ok {
+		content = map[string]interface{}{"text": text}
+	}
+
+	result := s.convertContent(content)
+	version, err := model.ValidateContent(msgType, result)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrMessageContentInvalid, err)
+	}
+	return result, version, nil
+}
+
 // convertContent 转换消息内容为map
 func (s *messageServiceImpl) convertContent(content interface{}) map[string]interface{} {
 	if content == nil {
@@ -127,7 +360,7 @@ func (s *messageServiceImpl) GetConversationMessages(ctx context.Context, userID
 		return nil, fmt.Errorf("get conversation messages error: %w", err)
 	}
 
-	return s.documentsToDTO(docs), nil
+	return documentsToDTO(docs), nil
 }
 
 // GetGroupMessages 获取群聊消息历史
@@ -148,7 +381,7 @@ func (s *messageServiceImpl) GetGroupMessages(ctx context.Context, userID, group
 		return nil, fmt.Errorf("get group messages error: %w", err)
 	}
 
-	return s.documentsToDTO(docs), nil
+	return documentsToDTO(docs), nil
 }
 
 // GetPrivateMessages 获取私聊消息历史
@@ -158,7 +391,7 @@ func (s *messageServiceImpl) GetPrivateMessages(ctx context.Context, userID, oth
 		return nil, fmt.Errorf("get private messages error: %w", err)
 	}
 
-	return s.documentsToDTO(docs), nil
+	return documentsToDTO(docs), nil
 }
 
 // RevokeMessage 撤回消息
@@ -169,17 +402,17 @@ func (s *messageServiceImpl) RevokeMessage(ctx context.Context, userID, messageI
 		return fmt.Errorf("find message error: %w", err)
 	}
 	if doc == nil {
-		return fmt.Errorf("message not found")
+		return ErrMessageNotFound
 	}
 
 	// 验证是否是发送者
 	if doc.From != userID {
-		return fmt.Errorf("only sender can revoke message")
+		return ErrRevokeNotSender
 	}
 
-	// 检查是否超过撤回时限（2分钟）
-	if time.Since(doc.CreatedAt) > 2*time.Minute {
-		return fmt.Errorf("message revoke time exceeded")
+	// 检查是否超过撤回时限
+	if time.Since(doc.CreatedAt) > MessageRevokeWindow {
+		return ErrRevokeTimeExceeded
 	}
 
 	// 执行撤回
@@ -187,9 +420,150 @@ func (s *messageServiceImpl) RevokeMessage(ctx context.Context, userID, messageI
 		return fmt.Errorf("revoke message error: %w", err)
 	}
 
+	if !s.changeStream {
+		s.invalidateHotCache(ctx, doc.ConversationID)
+	}
+	if s.counters != nil {
+		s.counters.RecordRevoke(ctx, doc.ConversationID, model.MessageType(doc.Type))
+	}
+
+	if s.patchNotifier != nil {
+		if err := s.patchNotifier.Notify(ctx, doc, model.PatchOperation{
+			Op: model.PatchOpReplace, Path: "/revoked", Value: true,
+		}); err != nil {
+			log.Printf("push revoke patch for message %s error: %v", messageID, err)
+		}
+	}
+
 	return nil
 }
 
+// CancelPendingMessage 取消尚未投递的消息
+func (s *messageServiceImpl) CancelPendingMessage(ctx context.Context, userID, messageID string) (*PendingCancelResult, error) {
+	doc, err := s.messageRepo.FindByMessageID(ctx, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("find message error: %w", err)
+	}
+	if doc == nil {
+		return nil, ErrMessageNotFound
+	}
+	if doc.From != userID {
+		return nil, ErrCancelNotSender
+	}
+	if doc.Revoked || doc.Cancelled || s.offlineService == nil {
+		return nil, ErrMessageNotPending
+	}
+
+	// 只有仍在离线队列中（至少一个接收者未收到）的消息才能取消
+	withdrawn, err := s.offlineService.CancelMessage(ctx, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("cancel offline message error: %w", err)
+	}
+	if len(withdrawn) == 0 {
+		return nil, ErrMessageNotPending
+	}
+	if s.pushService != nil {
+		s.pushService.CancelMessage(messageID)
+	}
+
+	if _, err := s.messageRepo.Cancel(ctx, messageID); err != nil {
+		return nil, fmt.Errorf("cancel message error: %w", err)
+	}
+
+	if !s.changeStream {
+		s.invalidateHotCache(ctx, doc.ConversationID)
+	}
+
+	result := &PendingCancelResult{MessageID: messageID, Withdrawn: withdrawn, Notified: []string{}}
+
+	// 已收到消息的成员（含发送者的其他设备）收到 patch 帧后隐藏该消息
+	recipients, err := messageRecipients(ctx, s.groupService, doc)
+	if err != nil {
+		log.Printf("get recipients of cancelled message %s error: %v", messageID, err)
+		return result, nil
+	}
+	skip := make(map[string]bool, len(withdrawn))
+	for _, id := range withdrawn {
+		skip[id] = true
+	}
+	for _, id := range recipients {
+		if !skip[id] {
+			result.Notified = append(result.Notified, id)
+		}
+	}
+
+	if s.patchNotifier != nil {
+		if err := s.patchNotifier.NotifyUsers(ctx, result.Notified, doc, model.PatchOperation{
+			Op: model.PatchOpReplace, Path: "/cancelled", Value: true,
+		}); err != nil {
+			log.Printf("push cancel patch for message %s error: %v", messageID, err)
+		}
+	}
+
+	return result, nil
+}
+
+// FailMessage 标记消息发送失败
+func (s *messageServiceImpl) FailMessage(ctx context.Context, messageID string, code int, reason string) error {
+	if s.offlineService != nil {
+		if _, err := s.offlineService.CancelMessage(ctx, messageID); err != nil {
+			log.Printf("withdraw offline copies of failed message %s error: %v", messageID, err)
+		}
+	}
+	if s.pushService != nil {
+		s.pushService.CancelMessage(messageID)
+	}
+
+	doc, err := s.messageRepo.FindByMessageID(ctx, messageID)
+	if err != nil {
+		return fmt.Errorf("find message error: %w", err)
+	}
+	if doc == nil {
+		// 消息未保存（如保存失败），无需标记
+		return nil
+	}
+
+	marked, err := s.messageRepo.MarkFailed(ctx, messageID, code, reason)
+	if err != nil {
+		return fmt.Errorf("mark message failed error: %w", err)
+	}
+	if marked && !s.changeStream {
+		s.invalidateHotCache(ctx, doc.ConversationID)
+	}
+	return nil
+}
+
+// SetPendingQueues 设置待投递队列
+func (s *messageServiceImpl) SetPendingQueues(offlineService OfflineService, pushService PushService) {
+	s.offlineService = offlineService
+	s.pushService = pushService
+}
+
+// SetPatchNotifier 设置消息局部更新通知器
+func (s *messageServiceImpl) SetPatchNotifier(notifier *MessagePatchNotifier) {
+	s.patchNotifier = notifier
+}
+
+// SetCounters 设置会话计数服务
+func (s *messageServiceImpl) SetCounters(counters ConversationCounterService) {
+	s.counters = counters
+}
+
+// SetDataRegions 设置数据驻留服务
+func (s *messageServiceImpl) SetDataRegions(regions DataRegionService) {
+	s.regions = regions
+}
+
+// SetConversationState 设置会话状态维护器
+func (s *messageServiceImpl) SetConversationState(updater *ConversationStateUpdater) {
+	s.conversations = updater
+}
+
+// SetMentionResolver 设置提及解析
+func (s *messageServiceImpl) SetMentionResolver(resolver MentionResolver) {
+	s.mentions = resolver
+}
+
 // GetMessageByID 获取单条消息
 func (s *messageServiceImpl) GetMessageByID(ctx context.Context, messageID string) (*MessageDTO, error) {
 	doc, err := s.messageRepo.FindByMessageID(ctx, messageID)
@@ -200,20 +574,20 @@ func (s *messageServiceImpl) GetMessageByID(ctx context.Context, messageID strin
 		return nil, nil
 	}
 
-	return s.documentToDTO(doc), nil
+	return documentToDTO(doc), nil
 }
 
 // documentsToDTO 将文档列表转换为DTO列表
-func (s *messageServiceImpl) documentsToDTO(docs []*repository.MessageDocument) []*MessageDTO {
+func documentsToDTO(docs []*repository.MessageDocument) []*MessageDTO {
 	result := make([]*MessageDTO, 0, len(docs))
 	for _, doc := range docs {
-		result = append(result, s.documentToDTO(doc))
+		result = append(result, documentToDTO(doc))
 	}
 	return result
 }
 
 // documentToDTO 将文档转换为DTO
-func (s *messageServiceImpl) documentToDTO(doc *repository.MessageDocument) *MessageDTO {
+func documentToDTO(doc *repository.MessageDocument) *MessageDTO {
 	return &MessageDTO{
 		MessageID:      doc.MessageID,
 		ConversationID: doc.ConversationID,
@@ -222,9 +596,15 @@ func (s *messageServiceImpl) documentToDTO(doc *repository.MessageDocument) *Mes
 		To:             doc.To,
 		GroupID:        doc.GroupID,
 		Content:        doc.Content,
+		ContentVersion: doc.ContentVersion,
 		Seq:            doc.Seq,
 		Status:         doc.Status,
 		Revoked:        doc.Revoked,
+		Failed:         doc.Failed,
+		FailCode:       doc.FailCode,
+		FailReason:     doc.FailReason,
+		MentionTargets: doc.MentionTargets,
+		Purged:         doc.Purged,
 		Timestamp:      doc.CreatedAt.UnixMilli(),
 		CreatedAt:      doc.CreatedAt,
 	}
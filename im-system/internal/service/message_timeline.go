@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// 会话热缓存及时间线参数
+const (
+	hotCacheSize           = 20                 // 每个会话缓存的最近消息数
+	hotCacheTTL            = 7 * 24 * time.Hour // 热缓存过期时间
+	userConversationsLimit = 200                // 用户私聊会话索引保留数量
+
+	TimelineDefaultLimit    = 50  // 时间线默认条数
+	TimelineMaxLimit        = 200 // 时间线最大条数
+	TimelinePerConversation = 5   // 时间线中每个会话最多返回的消息数
+	timelineMaxWarmups      = 10  // 单次请求最多从数据库回填的会话数
+)
+
+// hotCacheKey 会话热缓存Key（LIST，最新消息在前）
+func hotCacheKey(conversationID string) string {
+	return fmt.Sprintf("conv:hot:%s", model.CanonicalConversationID(conversationID))
+}
+
+// userConversationsKey 用户私聊会话索引Key（ZSET，score为最后消息时间）
+func userConversationsKey(userID string) string {
+	return fmt.Sprintf("user:convs:%s", userID)
+}
+
+// cacheMessage 写入会话热缓存并更新会话索引
+func (s *messageServiceImpl) cacheMessage(ctx context.Context, doc *repository.MessageDocument) {
+	if s.redis == nil || doc.ConversationID == "" {
+		return
+	}
+
+	data, err := json.Marshal(documentToDTO(doc))
+	if err != nil {
+		return
+	}
+
+	key := hotCacheKey(doc.ConversationID)
+	pipe := s.redis.Pipeline()
+	pipe.LPush(ctx, key, data)
+	pipe.LTrim(ctx, key, 0, hotCacheSize-1)
+	pipe.Expire(ctx, key, hotCacheTTL)
+
+	// 群会话通过用户群列表获取，只需维护私聊会话索引
+	if doc.GroupID == "" {
+		score := float64(doc.CreatedAt.UnixMilli())
+		for _, userID := range []string{doc.From, doc.To} {
+			convKey := userConversationsKey(userID)
+			pipe.ZAdd(ctx, convKey, &redis.Z{Score: score, Member: model.CanonicalConversationID(doc.ConversationID)})
+			pipe.ZRemRangeByRank(ctx, convKey, 0, -userConversationsLimit-1)
+			pipe.Expire(ctx, convKey, hotCacheTTL)
+		}
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		fmt.Printf("cache message %s error: %v\n", doc.MessageID, err)
+	}
+}
+
+// invalidateHotCache 失效会话热缓存（撤回等修改历史的操作后调用）
+func (s *messageServiceImpl) invalidateHotCache(ctx context.Context, conversationID string) {
+	if s.redis == nil || conversationID == "" {
+		return
+	}
+	s.redis.Del(ctx, hotCacheKey(conversationID))
+}
+
+// UseChangeStream 改由消息变更流维护热缓存
+func (s *messageServiceImpl) UseChangeStream(listener MessageChangeListener) {
+	s.changeStream = true
+	listener.Subscribe(s.handleMessageChange)
+}
+
+// handleMessageChange 根据消息变更同步热缓存：新消息写入缓存，修改或删除时失效整个会话缓存
+func (s *messageServiceImpl) handleMessageChange(ctx context.Context, event *repository.MessageChangeEvent) error {
+	if event.Document == nil {
+		return nil
+	}
+	if event.Op == repository.MessageChangeInsert {
+		s.cacheMessage(ctx, event.Document)
+		return nil
+	}
+	s.invalidateHotCache(ctx, event.Document.ConversationID)
+	return nil
+}
+
+// GetTimeline 获取用户跨会话的最新消息时间线
+func (s *messageServiceImpl) GetTimeline(ctx context.Context, userID string, limit int) ([]*MessageDTO, error) {
+	if limit <= 0 {
+		limit = TimelineDefaultLimit
+	}
+	if limit > TimelineMaxLimit {
+		limit = TimelineMaxLimit
+	}
+	if s.redis == nil {
+		return []*MessageDTO{}, nil
+	}
+
+	// 收集会话：私聊会话索引 + 所在群组
+	conversationIDs, err := s.redis.ZRevRange(ctx, userConversationsKey(userID), 0, userConversationsLimit-1).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("get user conversations error: %w", err)
+	}
+	if s.groupService != nil {
+		groups, err := s.groupService.GetUserGroups(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("get user groups error: %w", err)
+		}
+		for _, group := range groups {
+			conversationIDs = append(conversationIDs, model.GetGroupChatConversationID(group.GroupID))
+		}
+	}
+	if len(conversationIDs) == 0 {
+		return []*MessageDTO{}, nil
+	}
+
+	// 批量读取热缓存
+	pipe := s.redis.Pipeline()
+	cmds := make([]*redis.StringSliceCmd, len(conversationIDs))
+	for i, conversationID := range conversationIDs {
+		cmds[i] = pipe.LRange(ctx, hotCacheKey(conversationID), 0, TimelinePerConversation-1)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("read hot cache error: %w", err)
+	}
+
+	var result []*MessageDTO
+	warmups := 0
+	for i, cmd := range cmds {
+		items := cmd.Val()
+		if len(items) == 0 {
+			// 缓存未命中时从数据库回填（限制次数）
+			if warmups >= timelineMaxWarmups {
+				continue
+			}
+			warmups++
+			result = append(result, s.warmHotCache(ctx, conversationIDs[i])...)
+			continue
+		}
+		for _, item := range items {
+			var dto MessageDTO
+			if err := json.Unmarshal([]byte(item), &dto); err == nil {
+				result = append(result, &dto)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Timestamp > result[j].Timestamp })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// warmHotCache 从数据库加载会话最近消息并回填热缓存
+func (s *messageServiceImpl) warmHotCache(ctx context.Context, conversationID string) []*MessageDTO {
+	var (
+		docs []*repository.MessageDocument
+		err  error
+	)
+	if convID, parseErr := model.ParseConversationID(conversationID); parseErr == nil && convID.IsGroup() {
+		docs, err = s.messageRepo.FindByGroup(ctx, convID.GroupID, 0, hotCacheSize)
+	} else {
+		docs, err = s.messageRepo.FindByConversation(ctx, conversationID, 0, hotCacheSize)
+	}
+	if err != nil || len(docs) == 0 {
+		return nil
+	}
+
+	dtos := documentsToDTO(docs)
+	values := make([]interface{}, 0, len(dtos))
+	for _, dto := range dtos {
+		if data, err := json.Marshal(dto); err == nil {
+			values = append(values, data)
+		}
+	}
+
+	key := hotCacheKey(conversationID)
+	pipe := s.redis.Pipeline()
+	pipe.Del(ctx, key)
+	pipe.RPush(ctx, key, values...)
+	pipe.Expire(ctx, key, hotCacheTTL)
+	pipe.Exec(ctx)
+
+	if len(dtos) > TimelinePerConversation {
+		dtos = dtos[:TimelinePerConversation]
+	}
+	return dtos
+}
@@ -0,0 +1,422 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// 消息类型策略错误定义
+var (
+	ErrMessageTypeNotAllowed    = errors.New("message type not allowed")
+	ErrMessageTypePolicyInvalid = errors.New("invalid message type policy")
+)
+
+const (
+	// messageTypePolicyKey 全局消息类型策略
+	messageTypePolicyKey = "im:msg_type_policy"
+	// tenantMessageTypePolicyKeyPrefix 租户消息类型策略
+	tenantMessageTypePolicyKeyPrefix = "im:msg_type_policy:tenant:"
+	// groupMessageTypePolicyKeyPrefix 群组消息类型策略
+	groupMessageTypePolicyKeyPrefix = "im:msg_type_policy:group:"
+	// messageTypePolicyTenantsKey 设置了策略的租户（SET），没有租户策略时发送消息不查询发送者所属租户
+	messageTypePolicyTenantsKey = "im:msg_type_policy:tenants"
+	// messageTypePolicyCacheTTL 本地缓存策略的时间，避免每条消息都访问Redis
+	messageTypePolicyCacheTTL = 5 * time.Second
+	// maxMessageTypeRules 单个策略最多规则数
+	maxMessageTypeRules = 50
+)
+
+// MessageTypeRejection 消息类型被策略拒绝的原因（errors.Is 匹配 ErrMessageTypeNotAllowed）
+type MessageTypeRejection struct {
+	Scope string            // 拒绝的策略级别：global / tenant / group
+	Class string            // 会话类别
+	Role  string            // 发送者角色
+	Type  model.MessageType // 消息类型
+}
+
+// Error 实现 error 接口
+func (e *MessageTypeRejection) Error() string {
+	return fmt.Sprintf("%s messages are not allowed for %s senders in %s conversations (%s policy)", e.Type, e.Role, e.Class, e.Scope)
+}
+
+// Unwrap 支持 errors.Is(err, ErrMessageTypeNotAllowed)
+func (e *MessageTypeRejection) Unwrap() error {
+	return ErrMessageTypeNotAllowed
+}
+
+// TenantResolver 查询用户所属租户，没有租户时返回空字符串
+type TenantResolver func(ctx context.Context, userID string) (string, error)
+
+// MessageTypePolicyService 消息类型策略服务接口
+// 按会话类别（单聊/群聊）和发送者角色（访客/普通成员/群主管理员）限制可发送的聊天消息类型，
+// 全局策略、发送者所属租户的策略和群组策略依次校验，下级策略只能在上级基础上进一步收紧。
+type MessageTypePolicyService interface {
+	// GetPolicy 获取全局策略（tenantID 为空）或租户策略（管理员），未设置时返回 nil
+	GetPolicy(ctx context.Context, tenantID string) (*model.MessageTypePolicy, error)
+	// SetPolicy 设置全局策略或租户策略（管理员）
+	SetPolicy(ctx context.Context, tenantID, operatorID string, req *model.SetMessageTypePolicyRequest) (*model.MessageTypePolicy, error)
+	// DeletePolicy 删除全局策略或租户策略（管理员）
+	DeletePolicy(ctx context.Context, tenantID string) error
+
+	// GetGroupPolicy 获取群组策略（群成员），未设置时返回 nil
+	GetGroupPolicy(ctx context.Context, groupID, userID string) (*model.MessageTypePolicy, error)
+	// SetGroupPolicy 设置群组策略（群主或管理员），规则只能针对群聊
+	SetGroupPolicy(ctx context.Context, groupID, operatorID string, req *model.SetMessageTypePolicyRequest) (*model.MessageTypePolicy, error)
+	// DeleteGroupPolicy 删除群组策略（群主或管理员）
+	DeleteGroupPolicy(ctx context.Context, groupID, operatorID string) error
+
+	// CheckMessage 发送前校验聊天消息类型，被拒绝时返回 *MessageTypeRejection
+	CheckMessage(ctx context.Context, msg *model.Message) error
+
+	// SetGuestService 设置访客服务，用于识别访客发送者
+	SetGuestService(guests GuestService)
+	// SetTenantResolver 设置租户查询，为空时不校验租户策略
+	SetTenantResolver(resolver TenantResolver)
+}
+
+// cachedMessageTypePolicy 本地缓存的策略（policy为nil表示未设置）
+type cachedMessageTypePolicy struct {
+	policy    *model.MessageTypePolicy
+	expiresAt time.Time
+}
+
+// messageTypePolicyServiceImpl 消息类型策略服务实现
+type messageTypePolicyServiceImpl struct {
+	redis        *redis.Client
+	groupService GroupService
+	guests       GuestService
+	tenants      TenantResolver
+
+	mu    sync.RWMutex
+	cache map[string]*cachedMessageTypePolicy // key为策略的Redis Key
+
+	hasTenants       bool
+	tenantsExpiresAt time.Time
+}
+
+// NewMessageTypePolicyService 创建消息类型策略服务
+func NewMessageTypePolicyService(redisClient *redis.Client, groupService GroupService) MessageTypePolicyService {
+	return &messageTypePolicyServiceImpl{
+		redis:        redisClient,
+		groupService: groupService,
+		cache:        make(map[string]*cachedMessageTypePolicy),
+	}
+}
+
+// SetGuestService 设置访客服务
+func (s *messageTypePolicyServiceImpl) SetGuestService(guests GuestService) {
+	s.guests = guests
+}
+
+// SetTenantResolver 设置租户查询
+func (s *messageTypePolicyServiceImpl) SetTenantResolver(resolver TenantResolver) {
+	s.tenants = resolver
+}
+
+// GetPolicy 获取全局策略或租户策略
+func (s *messageTypePolicyServiceImpl) GetPolicy(ctx context.Context, tenantID string) (*model.MessageTypePolicy, error) {
+	return s.load(ctx, policyKeyForTenant(tenantID))
+}
+
+// SetPolicy 设置全局策略或租户策略
+func (s *messageTypePolicyServiceImpl) SetPolicy(ctx context.Context, tenantID, operatorID string, req *model.SetMessageTypePolicyRequest) (*model.MessageTypePolicy, error) {
+	policy, err := buildMessageTypePolicy(req, operatorID, false)
+	if err != nil {
+		return nil, err
+	}
+	key := policyKeyForTenant(tenantID)
+	if err := s.save(ctx, key, policy); err != nil {
+		return nil, err
+	}
+	if tenantID != "" {
+		s.redis.SAdd(ctx, messageTypePolicyTenantsKey, tenantID)
+		s.setTenantsCache(true)
+	}
+	s.setCache(key, policy)
+	return policy, nil
+}
+
+// DeletePolicy 删除全局策略或租户策略
+func (s *messageTypePolicyServiceImpl) DeletePolicy(ctx context.Context, tenantID string) error {
+	key := policyKeyForTenant(tenantID)
+	if err := s.redis.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("delete message type policy error: %w", err)
+	}
+	if tenantID != "" {
+		s.redis.SRem(ctx, messageTypePolicyTenantsKey, tenantID)
+	}
+	s.setCache(key, nil)
+	return nil
+}
+
+// GetGroupPolicy 获取群组策略
+func (s *messageTypePolicyServiceImpl) GetGroupPolicy(ctx context.Context, groupID, userID string) (*model.MessageTypePolicy, error) {
+	isMember, err := s.groupService.IsMember(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotGroupMember
+	}
+	return s.load(ctx, groupMessageTypePolicyKeyPrefix+groupID)
+}
+
+// SetGroupPolicy 设置群组策略
+func (s *messageTypePolicyServiceImpl) SetGroupPolicy(ctx context.Context, groupID, operatorID string, req *model.SetMessageTypePolicyRequest) (*model.MessageTypePolicy, error) {
+	if err := s.checkGroupAdmin(ctx, groupID, operatorID); err != nil {
+		return nil, err
+	}
+	policy, err := buildMessageTypePolicy(req, operatorID, true)
+	if err != nil {
+		return nil, err
+	}
+	key := groupMessageTypePolicyKeyPrefix + groupID
+	if err := s.save(ctx, key, policy); err != nil {
+		return nil, err
+	}
+	s.setCache(key, policy)
+	return policy, nil
+}
+
+// DeleteGroupPolicy 删除群组策略
+func (s *messageTypePolicyServiceImpl) DeleteGroupPolicy(ctx context.Context, groupID, operatorID string) error {
+	if err := s.checkGroupAdmin(ctx, groupID, operatorID); err != nil {
+		return err
+	}
+	key := groupMessageTypePolicyKeyPrefix + groupID
+	if err := s.redis.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("delete message type policy error: %w", err)
+	}
+	s.setCache(key, nil)
+	return nil
+}
+
+// CheckMessage 依次按全局、租户、群组策略校验聊天消息类型
+func (s *messageTypePolicyServiceImpl) CheckMessage(ctx context.Context, msg *model.Message) error {
+	if !msg.Type.IsChat() {
+		return nil
+	}
+
+	class, groupID := model.ConversationClassSingle, ""
+	if msg.Type == model.MsgGroupChat || msg.GroupID != "" {
+		class, groupID = model.ConversationClassGroup, msg.GroupID
+		if groupID == "" {
+			groupID = msg.To
+		}
+	}
+
+	// 策略级别及对应的Redis Key，依次校验
+	scopes := [][2]string{{"global", messageTypePolicyKey}}
+	if s.tenants != nil && s.cachedHasTenants(ctx) {
+		tenantID, err := s.tenants(ctx, msg.From)
+		if err != nil {
+			return fmt.Errorf("resolve sender tenant error: %w", err)
+		}
+		if tenantID != "" {
+			scopes = append(scopes, [2]string{"tenant", tenantMessageTypePolicyKeyPrefix + tenantID})
+		}
+	}
+	if groupID != "" {
+		scopes = append(scopes, [2]string{"group", groupMessageTypePolicyKeyPrefix + groupID})
+	}
+
+	// 发送者角色只在存在策略时查询
+	role := ""
+	for _, scope := range scopes {
+		policy := s.cachedPolicy(ctx, scope[1])
+		if policy == nil || len(policy.Rules) == 0 {
+			continue
+		}
+		if role == "" {
+			var err error
+			if role, err = s.senderRole(ctx, msg.From, groupID); err != nil {
+				return err
+			}
+		}
+		if policy.Violation(class, role, msg.Type) != nil {
+			return &MessageTypeRejection{Scope: scope[0], Class: class, Role: role, Type: model.PolicyMessageType(msg.Type)}
+		}
+	}
+	return nil
+}
+
+// senderRole 确定发送者角色：访客优先，群聊中区分群主/管理员与普通成员
+func (s *messageTypePolicyServiceImpl) senderRole(ctx context.Context, userID, groupID string) (string, error) {
+	if s.guests != nil {
+		isGuest, err := s.guests.IsGuest(ctx, userID)
+		if err != nil {
+			return "", err
+		}
+		if isGuest {
+			return model.SenderRoleGuest, nil
+		}
+	}
+	if groupID == "" {
+		return model.SenderRoleMember, nil
+	}
+	role, err := s.groupService.GetSenderRole(ctx, groupID, userID)
+	if err != nil {
+		return "", err
+	}
+	if role >= model.RoleAdmin {
+		return model.SenderRoleAdmin, nil
+	}
+	return model.SenderRoleMember, nil
+}
+
+// checkGroupAdmin 检查操作者是否为群主或管理员
+func (s *messageTypePolicyServiceImpl) checkGroupAdmin(ctx context.Context, groupID, operatorID string) error {
+	role, err := s.groupService.GetMemberRole(ctx, groupID, operatorID)
+	if err != nil {
+		return err
+	}
+	if role < model.RoleAdmin {
+		return ErrNotGroupAdmin
+	}
+	return nil
+}
+
+// cachedPolicy 读取策略（带本地缓存），Redis异常时沿用过期的缓存，没有缓存时视为未设置
+func (s *messageTypePolicyServiceImpl) cachedPolicy(ctx context.Context, key string) *model.MessageTypePolicy {
+	s.mu.RLock()
+	cached := s.cache[key]
+	s.mu.RUnlock()
+	if cached != nil && time.Now().Before(cached.expiresAt) {
+		return cached.policy
+	}
+
+	policy, err := s.load(ctx, key)
+	if err != nil {
+		if cached != nil {
+			return cached.policy
+		}
+		return nil
+	}
+	s.setCache(key, policy)
+	return policy
+}
+
+// cachedHasTenants 是否有租户设置了策略（带本地缓存）
+func (s *messageTypePolicyServiceImpl) cachedHasTenants(ctx context.Context) bool {
+	s.mu.RLock()
+	hasTenants, valid := s.hasTenants, time.Now().Before(s.tenantsExpiresAt)
+	s.mu.RUnlock()
+	if valid {
+		return hasTenants
+	}
+
+	count, err := s.redis.SCard(ctx, messageTypePolicyTenantsKey).Result()
+	if err != nil {
+		return hasTenants
+	}
+	s.setTenantsCache(count > 0)
+	return count > 0
+}
+
+// setCache 更新本地缓存
+func (s *messageTypePolicyServiceImpl) setCache(key string, policy *model.MessageTypePolicy) {
+	s.mu.Lock()
+	s.cache[key] = &cachedMessageTypePolicy{policy: policy, expiresAt: time.Now().Add(messageTypePolicyCacheTTL)}
+	s.mu.Unlock()
+}
+
+// setTenantsCache 更新租户策略标记的本地缓存
+func (s *messageTypePolicyServiceImpl) setTenantsCache(hasTenants bool) {
+	s.mu.Lock()
+	s.hasTenants = hasTenants
+	s.tenantsExpiresAt = time.Now().Add(messageTypePolicyCacheTTL)
+	s.mu.Unlock()
+}
+
+// load 从Redis读取策略，未设置时返回nil
+func (s *messageTypePolicyServiceImpl) load(ctx context.Context, key string) (*model.MessageTypePolicy, error) {
+	data, err := s.redis.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get message type policy error: %w", err)
+	}
+
+	var policy model.MessageTypePolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// save 保存策略到Redis
+func (s *messageTypePolicyServiceImpl) save(ctx context.Context, key string, policy *model.MessageTypePolicy) error {
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	if err := s.redis.Set(ctx, key, data, 0).Err(); err != nil {
+		return fmt.Errorf("set message type policy error: %w", err)
+	}
+	return nil
+}
+
+// policyKeyForTenant 全局策略或租户策略的Redis Key
+func policyKeyForTenant(tenantID string) string {
+	if tenantID == "" {
+		return messageTypePolicyKey
+	}
+	return tenantMessageTypePolicyKeyPrefix + tenantID
+}
+
+// buildMessageTypePolicy 校验并规范化策略请求，群组策略的规则只能针对群聊
+func buildMessageTypePolicy(req *model.SetMessageTypePolicyRequest, operatorID string, groupScope bool) (*model.MessageTypePolicy, error) {
+	if len(req.Rules) > maxMessageTypeRules {
+		return nil, fmt.Errorf("%w: at most %d rules", ErrMessageTypePolicyInvalid, maxMessageTypeRules)
+	}
+
+	policy := &model.MessageTypePolicy{
+		Rules:     make([]model.MessageTypeRule, 0, len(req.Rules)),
+		UpdatedBy: operatorID,
+		UpdatedAt: time.Now(),
+	}
+	for _, rule := range req.Rules {
+		switch rule.Class {
+		case "", model.ConversationClassSingle, model.ConversationClassGroup:
+		default:
+			return nil, fmt.Errorf("%w: class %q", ErrMessageTypePolicyInvalid, rule.Class)
+		}
+		if groupScope && rule.Class == model.ConversationClassSingle {
+			return nil, fmt.Errorf("%w: group policy cannot restrict single chats", ErrMessageTypePolicyInvalid)
+		}
+		switch rule.Role {
+		case "", model.SenderRoleGuest, model.SenderRoleMember:
+		case model.SenderRoleAdmin:
+			if rule.Class == model.ConversationClassSingle {
+				return nil, fmt.Errorf("%w: role admin only applies to group chats", ErrMessageTypePolicyInvalid)
+			}
+		default:
+			return nil, fmt.Errorf("%w: role %q", ErrMessageTypePolicyInvalid, rule.Role)
+		}
+
+		allowed := make([]model.MessageType, 0, len(rule.Allowed))
+		seen := make(map[model.MessageType]bool)
+		for _, t := range rule.Allowed {
+			if t != model.MsgText && !t.IsChat() {
+				return nil, fmt.Errorf("%w: type %d is not a chat message type", ErrMessageTypePolicyInvalid, t)
+			}
+			t = model.PolicyMessageType(t)
+			if !seen[t] {
+				seen[t] = true
+				allowed = append(allowed, t)
+			}
+		}
+		rule.Allowed = allowed
+		policy.Rules = append(policy.Rules, rule)
+	}
+	return policy, nil
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// 命名服务错误定义
+var (
+	ErrNameReserved      = errors.New("name is reserved")
+	ErrUsernameTaken     = errors.New("username already exists")
+	ErrNicknameTaken     = errors.New("nickname already in use")
+	ErrRenameTooFrequent = errors.New("rename too frequent")
+	ErrUserNotFound      = errors.New("user not found")
+)
+
+// NamingConfig 命名策略配置
+type NamingConfig struct {
+	ReservedNames  []string      // 保留名称（忽略大小写及 . _ - 分隔符），以*结尾表示前缀匹配
+	UniqueNickname bool          // 同一租户内昵称唯一
+	RenameCooldown time.Duration // 两次改名的最小间隔，0表示不限制
+}
+
+// DefaultNamingConfig 默认命名策略配置
+func DefaultNamingConfig() *NamingConfig {
+	return &NamingConfig{
+		ReservedNames: []string{
+			"admin*", "administrator", "root", "system", "sys", "official*",
+			"support", "service", "customerservice", "moderator", "im", "null", "undefined",
+		},
+		RenameCooldown: 24 * time.Hour,
+	}
+}
+
+// NamingService 命名服务接口
+type NamingService interface {
+	// CheckUsername 检查用户名是否可用于注册
+	CheckUsername(ctx context.Context, username string) error
+	// CheckNickname 检查昵称是否可用（userID为空表示新用户）
+	CheckNickname(ctx context.Context, tenantID, userID, nickname string) error
+	// Rename 修改昵称并记录改名历史
+	Rename(ctx context.Context, userID, nickname string) error
+	// GetRenameHistory 获取用户改名历史（按时间倒序）
+	GetRenameHistory(ctx context.Context, userID string, limit int) ([]*model.UserRenameHistory, error)
+}
+
+// namingServiceImpl 命名服务实现
+type namingServiceImpl struct {
+	users    repository.UserRepository
+	config   *NamingConfig
+	exact    map[string]bool
+	prefixes []string
+}
+
+// NewNamingService 创建命名服务
+func NewNamingService(users repository.UserRepository, config *NamingConfig) NamingService {
+	if config == nil {
+		config = DefaultNamingConfig()
+	}
+
+	s := &namingServiceImpl{
+		users:  users,
+		config: config,
+		exact:  make(map[string]bool),
+	}
+	for _, name := range config.ReservedNames {
+		if strings.HasSuffix(name, "*") {
+			s.prefixes = append(s.prefixes, normalizeName(strings.TrimSuffix(name, "*")))
+		} else {
+			s.exact[normalizeName(name)] = true
+		}
+	}
+	return s
+}
+
+// normalizeName 归一化名称：小写并去除空白及常见分隔符，防止 Ad_min 之类的绕过
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '_', '-', ' ', '\t':
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+}
+
+// isReserved 检查名称是否为保留名称
+func (s *namingServiceImpl) isReserved(name string) bool {
+	normalized := normalizeName(name)
+	if s.exact[normalized] {
+		return true
+	}
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(normalized, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckUsername 检查用户名
+func (s *namingServiceImpl) CheckUsername(ctx context.Context, username string) error {
+	if s.isReserved(username) {
+		return ErrNameReserved
+	}
+
+	exists, err := s.users.ExistsUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrUsernameTaken
+	}
+	return nil
+}
+
+// CheckNickname 检查昵称
+func (s *namingServiceImpl) CheckNickname(ctx context.Context, tenantID, userID, nickname string) error {
+	return s.checkNickname(ctx, s.users, tenantID, userID, nickname)
+}
+
+func (s *namingServiceImpl) checkNickname(ctx context.Context, users repository.UserRepository, tenantID, userID, nickname string) error {
+	if s.isReserved(nickname) {
+		return ErrNameReserved
+	}
+	if !s.config.UniqueNickname {
+		return nil
+	}
+
+	exists, err := users.ExistsNickname(ctx, tenantID, nickname, userID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrNicknameTaken
+	}
+	return nil
+}
+
+// Rename 修改昵称
+func (s *namingServiceImpl) Rename(ctx context.Context, userID, nickname string) error {
+	return s.users.Transaction(ctx, func(tx repository.UserRepository) error {
+		user, err := tx.FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return ErrUserNotFound
+		}
+		if user.Nickname == nickname {
+			return nil
+		}
+
+		if err := s.checkNickname(ctx, tx, user.TenantID, userID, nickname); err != nil {
+			return err
+		}
+
+		// 改名冷却
+		if s.config.RenameCooldown > 0 {
+			count, err := tx.CountRenamesSince(ctx, userID, model.RenameFieldNickname, time.Now().Add(-s.config.RenameCooldown))
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrRenameTooFrequent
+			}
+		}
+
+		now := time.Now()
+		if err := tx.UpdateNickname(ctx, userID, nickname, now); err != nil {
+			return err
+		}
+
+		return tx.CreateRenameHistory(ctx, &model.UserRenameHistory{
+			UserID:    userID,
+			Field:     model.RenameFieldNickname,
+			OldName:   user.Nickname,
+			NewName:   nickname,
+			CreatedAt: now,
+		})
+	})
+}
+
+// GetRenameHistory 获取改名历史
+func (s *namingServiceImpl) GetRenameHistory(ctx context.Context, userID string, limit int) ([]*model.UserRenameHistory, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	return s.users.FindRenameHistory(ctx, userID, limit)
+}
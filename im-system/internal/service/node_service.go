@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 节点管理服务错误定义
+var (
+	ErrNodeNotFound = errors.New("node not found")
+)
+
+// NodeConnection 节点上的连接信息
+type NodeConnection struct {
+	UserID      string `json:"user_id"`
+	ConnID      string `json:"conn_id"`
+	NodeID      string `json:"node_id"`
+	DeviceID    string `json:"device_id,omitempty"`
+	Platform    string `json:"platform,omitempty"`
+	ClientIP    string `json:"client_ip,omitempty"`
+	ConnectedAt int64  `json:"connected_at"`
+
+	AppVersion   string   `json:"app_version,omitempty"`
+	OS           string   `json:"os,omitempty"`
+	NetworkType  string   `json:"network_type,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// ClientVersionCount 按平台、版本统计的连接数
+type ClientVersionCount struct {
+	Platform    string `json:"platform"`
+	AppVersion  string `json:"app_version"`
+	Connections int64  `json:"connections"`
+}
+
+// ClientStats 集群客户端分布（版本、系统、网络类型）
+type ClientStats struct {
+	Total    int64                 `json:"total"`
+	Versions []*ClientVersionCount `json:"versions"`
+	OS       map[string]int64      `json:"os"`
+	Networks map[string]int64      `json:"networks"`
+}
+
+// NodeConnectionCount 节点连接数
+type NodeConnectionCount struct {
+	NodeID      string `json:"node_id"`
+	Connections int64  `json:"connections"`
+}
+
+// ClusterConnections 集群连接统计
+type ClusterConnections struct {
+	Total int64                  `json:"total"`
+	Nodes []*NodeConnectionCount `json:"nodes"`
+}
+
+// StageLatency 消息处理阶段耗时分位数（毫秒）
+type StageLatency struct {
+	Stage   string  `json:"stage"`
+	Samples int     `json:"samples"`
+	P50     float64 `json:"p50_ms"`
+	P95     float64 `json:"p95_ms"`
+	P99     float64 `json:"p99_ms"`
+}
+
+// NodeLatency 节点各消息处理阶段耗时（按处理顺序）
+type NodeLatency struct {
+	NodeID    string          `json:"node_id"`
+	UpdatedAt int64           `json:"updated_at"`
+	Stages    []*StageLatency `json:"stages"`
+}
+
+// NodeGateway 节点网关接口（由网关层实现）
+type NodeGateway interface {
+	// CountConnections 统计各节点连接数
+	CountConnections(ctx context.Context) ([]*NodeConnectionCount, error)
+
+	// ListConnections 分页列出节点上的连接，返回下一页游标（为0表示结束）
+	ListConnections(ctx context.Context, nodeID string, cursor uint64, count int64) ([]*NodeConnection, uint64, error)
+
+	// BroadcastToNode 广播消息给节点上的用户
+	BroadcastToNode(ctx context.Context, nodeID string, msg *model.Message, platforms []string) error
+
+	// DrainNode 排空节点连接
+	DrainNode(ctx context.Context, nodeID string) error
+
+	// ClientStats 统计集群客户端分布
+	ClientStats(ctx context.Context) (*ClientStats, error)
+
+	// ClusterLatency 获取集群各节点的消息处理阶段耗时
+	ClusterLatency(ctx context.Context) ([]*NodeLatency, error)
+
+	// BroadcastToAll 广播消息给所有节点上的用户（platforms为空表示不限平台）
+	BroadcastToAll(ctx context.Context, msg *model.Message, platforms []string) error
+
+	// KickUser 通知各节点断开用户的连接
+	KickUser(ctx context.Context, userID string) error
+}
+
+// NodeService 节点管理服务接口
+type NodeService interface {
+	// ClusterConnections 获取集群各节点连接数
+	ClusterConnections(ctx context.Context) (*ClusterConnections, error)
+
+	// ListConnections 分页列出节点上的连接
+	ListConnections(ctx context.Context, nodeID string, cursor uint64, limit int64) ([]*NodeConnection, uint64, error)
+
+	// Broadcast 向节点上的用户发送服务器通知
+	Broadcast(ctx context.Context, nodeID string, notice *model.ServerNoticeContent, platforms []string) error
+
+	// Drain 排空节点：断开现有连接并拒绝新连接，客户端重连到其他节点
+	Drain(ctx context.Context, nodeID string) error
+
+	// ClientStats 获取集群客户端版本、系统及网络类型分布
+	ClientStats(ctx context.Context) (*ClientStats, error)
+
+	// Latency 获取集群各节点消息处理各阶段耗时的 p50/p95/p99
+	Latency(ctx context.Context) ([]*NodeLatency, error)
+
+	// BroadcastAll 向集群全部在线用户发送服务器通知，返回通知的消息ID
+	BroadcastAll(ctx context.Context, notice *model.ServerNoticeContent, platforms []string) (string, error)
+
+	// KickUser 断开用户在集群中的连接（实现 UserKicker）
+	KickUser(ctx context.Context, userID string) error
+}
+
+// nodeServiceImpl 节点管理服务实现
+type nodeServiceImpl struct {
+	gateway NodeGateway
+}
+
+// NewNodeService 创建节点管理服务
+func NewNodeService(gateway NodeGateway) NodeService {
+	return &nodeServiceImpl{gateway: gateway}
+}
+
+// ClusterConnections 获取集群各节点连接数
+func (s *nodeServiceImpl) ClusterConnections(ctx context.Context) (*ClusterConnections, error) {
+	counts, err := s.gateway.CountConnections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ClusterConnections{Nodes: counts}
+	for _, c := range counts {
+		result.Total += c.Connections
+	}
+	return result, nil
+}
+
+// ListConnections 分页列出节点上的连接
+func (s *nodeServiceImpl) ListConnections(ctx context.Context, nodeID string, cursor uint64, limit int64) ([]*NodeConnection, uint64, error) {
+	if err := s.checkNode(ctx, nodeID); err != nil {
+		return nil, 0, err
+	}
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return s.gateway.ListConnections(ctx, nodeID, cursor, limit)
+}
+
+// Broadcast 向节点上的用户发送服务器通知
+func (s *nodeServiceImpl) Broadcast(ctx context.Context, nodeID string, notice *model.ServerNoticeContent, platforms []string) error {
+	if err := s.checkNode(ctx, nodeID); err != nil {
+		return err
+	}
+
+	msg := &model.Message{
+		MessageID: util.GenerateMessageID(),
+		Type:      model.MsgServerNotice,
+		Content:   notice,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	return s.gateway.BroadcastToNode(ctx, nodeID, msg, platforms)
+}
+
+// BroadcastAll 向集群全部在线用户发送服务器通知
+func (s *nodeServiceImpl) BroadcastAll(ctx context.Context, notice *model.ServerNoticeContent, platforms []string) (string, error) {
+	msg := &model.Message{
+		MessageID: util.GenerateMessageID(),
+		Type:      model.MsgServerNotice,
+		Content:   notice,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if err := s.gateway.BroadcastToAll(ctx, msg, platforms); err != nil {
+		return "", err
+	}
+	return msg.MessageID, nil
+}
+
+// KickUser 断开用户在集群中的连接
+func (s *nodeServiceImpl) KickUser(ctx context.Context, userID string) error {
+	return s.gateway.KickUser(ctx, userID)
+}
+
+// ClientStats 获取集群客户端分布
+func (s *nodeServiceImpl) ClientStats(ctx context.Context) (*ClientStats, error) {
+	return s.gateway.ClientStats(ctx)
+}
+
+// Latency 获取集群各节点消息处理阶段耗时
+func (s *nodeServiceImpl) Latency(ctx context.Context) ([]*NodeLatency, error) {
+	return s.gateway.ClusterLatency(ctx)
+}
+
+// Drain 排空节点
+func (s *nodeServiceImpl) Drain(ctx context.Context, nodeID string) error {
+	if err := s.checkNode(ctx, nodeID); err != nil {
+		return err
+	}
+	return s.gateway.DrainNode(ctx, nodeID)
+}
+
+// checkNode 检查节点是否已注册
+func (s *nodeServiceImpl) checkNode(ctx context.Context, nodeID string) error {
+	counts, err := s.gateway.CountConnections(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range counts {
+		if c.NodeID == nodeID {
+			return nil
+		}
+	}
+	return ErrNodeNotFound
+}
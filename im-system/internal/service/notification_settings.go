@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// 免打扰用户缓存
+const (
+	mutedUsersCacheTTL  = 30 * time.Second // 会话免打扰用户缓存时间，修改免打扰后最多延迟该时间影响长连接投递
+	mutedUsersCacheSize = 10000            // 缓存会话数超过该值时清理过期记录
+)
+
+// UpdateNotificationSettingsRequest 更新通知设置请求，为空的字段不修改
+type UpdateNotificationSettingsRequest struct {
+	PushEnabled  *bool                      `json:"push_enabled"`
+	ShowPreview  *bool                      `json:"show_preview"`
+	DoNotDisturb *model.DoNotDisturbSetting `json:"do_not_disturb"` // 开始、结束均为空时关闭免打扰时段
+}
+
+// NotificationSettingsService 用户通知设置服务接口
+type NotificationSettingsService interface {
+	// GetSettings 获取用户的通知设置
+	GetSettings(ctx context.Context, userID string) (*model.NotificationSettings, error)
+
+	// UpdateSettings 更新用户的通知设置，返回更新后的设置
+	UpdateSettings(ctx context.Context, userID string, req *UpdateNotificationSettingsRequest) (*model.NotificationSettings, error)
+
+	// MutedUsers 返回 userIDs 中对会话设置了免打扰的用户（长连接分发时降低投递优先级）
+	MutedUsers(ctx context.Context, conversationID string, userIDs []string) (map[string]bool, error)
+}
+
+// mutedUsersEntry 会话免打扰用户缓存
+type mutedUsersEntry struct {
+	users     map[string]bool
+	expiresAt time.Time
+}
+
+// notificationSettingsServiceImpl 用户通知设置服务实现
+type notificationSettingsServiceImpl struct {
+	users         repository.UserRepository
+	conversations repository.ConversationRepository
+
+	mu    sync.Mutex
+	muted map[string]*mutedUsersEntry
+}
+
+// NewNotificationSettingsService 创建用户通知设置服务
+func NewNotificationSettingsService(users repository.UserRepository, conversations repository.ConversationRepository) NotificationSettingsService {
+	return &notificationSettingsServiceImpl{
+		users:         users,
+		conversations: conversations,
+		muted:         make(map[string]*mutedUsersEntry),
+	}
+}
+
+// GetSettings 获取用户的通知设置
+func (s *notificationSettingsServiceImpl) GetSettings(ctx context.Context, userID string) (*model.NotificationSettings, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	mutedConversations, err := s.conversations.FindMutedConversationIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := notificationSettingsOf(user)
+	settings.MutedConversations = mutedConversations
+	return settings, nil
+}
+
+// UpdateSettings 更新用户的通知设置
+func (s *notificationSettingsServiceImpl) UpdateSettings(ctx context.Context, userID string, req *UpdateNotificationSettingsRequest) (*model.NotificationSettings, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	settings := notificationSettingsOf(user)
+	if req.PushEnabled != nil {
+		settings.PushEnabled = *req.PushEnabled
+	}
+	if req.ShowPreview != nil {
+		settings.ShowPreview = *req.ShowPreview
+	}
+	if req.DoNotDisturb != nil {
+		if err := ValidateDoNotDisturb(req.DoNotDisturb); err != nil {
+			return nil, err
+		}
+		settings.DoNotDisturb = model.DoNotDisturbSetting{
+			Start:    strings.TrimSpace(req.DoNotDisturb.Start),
+			End:      strings.TrimSpace(req.DoNotDisturb.End),
+			Timezone: req.DoNotDisturb.Timezone,
+		}
+	}
+
+	if err := s.users.UpdateNotificationSettings(ctx, userID, settings, time.Now()); err != nil {
+		return nil, err
+	}
+
+	mutedConversations, err := s.conversations.FindMutedConversationIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	settings.MutedConversations = mutedConversations
+	return settings, nil
+}
+
+// MutedUsers 返回对会话设置了免打扰的用户，按会话缓存一段时间
+func (s *notificationSettingsServiceImpl) MutedUsers(ctx context.Context, conversationID string, userIDs []string) (map[string]bool, error) {
+	conversationID = model.CanonicalConversationID(conversationID)
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.muted[conversationID]
+	s.mu.Unlock()
+
+	if !ok || now.After(entry.expiresAt) {
+		mutedIDs, err := s.conversations.FindMutedUserIDs(ctx, conversationID)
+		if err != nil {
+			return nil, err
+		}
+		entry = &mutedUsersEntry{users: make(map[string]bool, len(mutedIDs)), expiresAt: now.Add(mutedUsersCacheTTL)}
+		for _, id := range mutedIDs {
+			entry.users[id] = true
+		}
+
+		s.mu.Lock()
+		if len(s.muted) >= mutedUsersCacheSize {
+			for id, cached := range s.muted {
+				if now.After(cached.expiresAt) {
+					delete(s.muted, id)
+				}
+			}
+		}
+		s.muted[conversationID] = entry
+		s.mu.Unlock()
+	}
+
+	result := make(map[string]bool)
+	for _, userID := range userIDs {
+		if entry.users[userID] {
+			result[userID] = true
+		}
+	}
+	return result, nil
+}
+
+// notificationSettingsOf 读取用户的通知设置（不含免打扰会话）
+func notificationSettingsOf(user *model.User) *model.NotificationSettings {
+	return &model.NotificationSettings{
+		PushEnabled: user.PushEnabled,
+		ShowPreview: user.PushPreview,
+		DoNotDisturb: model.DoNotDisturbSetting{
+			Start:    user.DNDStart,
+			End:      user.DNDEnd,
+			Timezone: user.DNDTimezone,
+		},
+		MutedConversations: []string{},
+	}
+}
@@ -6,12 +6,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/tracing"
 	"github.com/d60-lab/im-system/pkg/util"
 	"github.com/go-redis/redis/v8"
-	"gorm.io/gorm"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // 离线消息配置
@@ -26,22 +29,52 @@ var (
 	ErrTooManyOfflineMessages = errors.New("too many offline messages")
 )
 
+// reconcileReadScript 原子地移除已读离线消息的索引并扣减离线计数（计数不低于0，保留过期时间）
+// KEYS[1] 离线消息索引 KEYS[2] 离线计数 ARGV[1] 扣减数量 ARGV[2..] 消息ID
+var reconcileReadScript = redis.NewScript(`
+for i = 2, #ARGV do
+	redis.call("ZREM", KEYS[1], ARGV[i])
+end
+if redis.call("EXISTS", KEYS[2]) == 0 then
+	return 0
+end
+local count = redis.call("DECRBY", KEYS[2], ARGV[1])
+if count < 0 then
+	redis.call("INCRBY", KEYS[2], -count)
+	count = 0
+end
+return count
+`)
+
 // OfflineServiceConfig 离线消息服务配置
 type OfflineServiceConfig struct {
 	MaxMessages   int           // 每用户最大离线消息数
 	ExpireDays    int           // 过期天数
 	CleanInterval time.Duration // 清理任务间隔
+
+	// 新设备首次同步：离线消息数达到 BootstrapThreshold 时改为下发会话摘要快照（0表示始终逐条同步），
+	// 每个会话只保留最近 BootstrapKeepRecent 条供逐条拉取，更早的消息标记为已取代
+	BootstrapThreshold  int
+	BootstrapKeepRecent int
 }
 
 // DefaultOfflineServiceConfig 默认配置
 func DefaultOfflineServiceConfig() *OfflineServiceConfig {
 	return &OfflineServiceConfig{
-		MaxMessages:   1000,
-		ExpireDays:    7,
-		CleanInterval: time.Hour,
+		MaxMessages:         1000,
+		ExpireDays:          7,
+		CleanInterval:       time.Hour,
+		BootstrapThreshold:  500,
+		BootstrapKeepRecent: 20,
 	}
 }
 
+// 新设备首次同步方式
+const (
+	OfflineBootstrapReplay   = "replay"   // 离线消息不多，逐条拉取
+	OfflineBootstrapSnapshot = "snapshot" // 下发会话摘要快照，只逐条拉取每个会话最近的消息
+)
+
 // OfflineService 离线消息服务接口
 type OfflineService interface {
 	// SaveOfflineMessage 保存离线消息
@@ -50,12 +83,32 @@ type OfflineService interface {
 	// PullOfflineMessages 拉取离线消息
 	PullOfflineMessages(ctx context.Context, userID string, lastSeq int64, limit int) ([]*model.OfflineMessage, error)
 
+	// PullConversationMessages 按会话拉取离线消息，客户端打开会话时可优先同步该会话
+	PullConversationMessages(ctx context.Context, userID, conversationID string, lastSeq int64, limit int) ([]*model.OfflineMessage, error)
+
+	// AckConversation 确认会话中 lastSeq 及之前的离线消息（lastSeq 为0时确认全部），返回删除数量
+	AckConversation(ctx context.Context, userID, conversationID string, lastSeq int64) (int64, error)
+
+	// ReconcileRead 已读对账：清除会话中序号不超过 lastReadSeq 或在 messageIDs 中的离线消息，
+	// 同步推进已读序号、扣减未读数（MySQL事务）并原子扣减Redis离线计数，返回清除数量
+	ReconcileRead(ctx context.Context, userID, conversationID string, lastReadSeq int64, messageIDs []string) (int64, error)
+
+	// GetOfflineMessageSummary 获取按会话统计的离线消息摘要，用于客户端选择性同步
+	GetOfflineMessageSummary(ctx context.Context, userID string) (*OfflineMessageSummary, error)
+
+	// Bootstrap 新设备首次同步：离线消息数未达阈值时返回 replay，客户端照常逐条拉取；
+	// 达到阈值时返回会话摘要快照，并将每个会话最近若干条以外的离线消息标记为已取代，之后不再逐条下发
+	Bootstrap(ctx context.Context, userID string) (*OfflineBootstrapResult, error)
+
 	// MarkAsPushed 标记消息已推送
 	MarkAsPushed(ctx context.Context, messageIDs []string) error
 
 	// DeleteOfflineMessages 删除离线消息
 	DeleteOfflineMessages(ctx context.Context, userID string, messageIDs []string) error
 
+	// CancelMessage 从所有用户的离线队列中移除该消息，返回被移除副本的用户
+	CancelMessage(ctx context.Context, messageID string) ([]string, error)
+
 	// GetUnpushedMessages 获取未推送的消息
 	GetUnpushedMessages(ctx context.Context, userID string, limit int) ([]*model.OfflineMessage, error)
 
@@ -67,29 +120,51 @@ type OfflineService interface {
 
 	// StartCleanupTask 启动清理任务
 	StartCleanupTask(ctx context.Context)
+
+	// SetPushNotifier 设置离线推送，保存离线消息后触发推送
+	SetPushNotifier(notifier OfflinePushNotifier)
+}
+
+// OfflinePushNotifier 离线消息推送（由推送服务实现）
+type OfflinePushNotifier interface {
+	// NotifyOffline 离线消息已保存，推送给用户的设备（异步处理，不返回错误）
+	NotifyOffline(ctx context.Context, userID string, message *model.OfflineMessage)
 }
 
 // offlineServiceImpl 离线消息服务实现
 type offlineServiceImpl struct {
-	db     *gorm.DB
+	repo   repository.OfflineMessageRepository
 	redis  *redis.Client
 	config *OfflineServiceConfig
+
+	pushNotifier OfflinePushNotifier
 }
 
 // NewOfflineService 创建离线消息服务
-func NewOfflineService(db *gorm.DB, redisClient *redis.Client, config *OfflineServiceConfig) OfflineService {
+func NewOfflineService(repo repository.OfflineMessageRepository, redisClient *redis.Client, config *OfflineServiceConfig) OfflineService {
 	if config == nil {
 		config = DefaultOfflineServiceConfig()
 	}
 	return &offlineServiceImpl{
-		db:     db,
+		repo:   repo,
 		redis:  redisClient,
 		config: config,
 	}
 }
 
+// SetPushNotifier 设置离线推送
+func (s *offlineServiceImpl) SetPushNotifier(notifier OfflinePushNotifier) {
+	s.pushNotifier = notifier
+}
+
 // SaveOfflineMessage 保存离线消息
-func (s *offlineServiceImpl) SaveOfflineMessage(ctx context.Context, userID string, msg *model.Message) error {
+func (s *offlineServiceImpl) SaveOfflineMessage(ctx context.Context, userID string, msg *model.Message) (err error) {
+	ctx, span := tracing.Start(ctx, "offline.save", trace.WithAttributes(
+		tracing.AttrMessageID.String(msg.MessageID),
+		tracing.AttrUserID.String(userID),
+	))
+	defer func() { tracing.End(span, err) }()
+
 	// 检查离线消息数量是否超限
 	count, err := s.GetOfflineMessageCount(ctx, userID)
 	if err != nil {
@@ -117,6 +192,7 @@ func (s *offlineServiceImpl) SaveOfflineMessage(ctx context.Context, userID stri
 		UserID:         userID,
 		MessageID:      msg.MessageID,
 		ConversationID: msg.ConversationID,
+		Seq:            msg.Seq,
 		Content:        string(contentBytes),
 		Pushed:         false,
 		CreatedAt:      time.Now(),
@@ -124,7 +200,7 @@ func (s *offlineServiceImpl) SaveOfflineMessage(ctx context.Context, userID stri
 	}
 
 	// 保存到数据库
-	if err := s.db.WithContext(ctx).Create(offlineMsg).Error; err != nil {
+	if err := s.repo.Create(ctx, offlineMsg); err != nil {
 		return fmt.Errorf("save offline message error: %w", err)
 	}
 
@@ -147,53 +223,66 @@ func (s *offlineServiceImpl) SaveOfflineMessage(ctx context.Context, userID stri
 	s.redis.Incr(ctx, countKey)
 	s.redis.Expire(ctx, countKey, time.Duration(s.config.ExpireDays)*24*time.Hour)
 
+	// 推送给离线用户的设备
+	if s.pushNotifier != nil {
+		s.pushNotifier.NotifyOffline(ctx, userID, offlineMsg)
+	}
+
 	return nil
 }
 
 // PullOfflineMessages 拉取离线消息
 func (s *offlineServiceImpl) PullOfflineMessages(ctx context.Context, userID string, lastSeq int64, limit int) ([]*model.OfflineMessage, error) {
-	if limit <= 0 {
-		limit = 100
-	}
-	if limit > 500 {
-		limit = 500
-	}
-
-	var messages []*model.OfflineMessage
-
 	// 从数据库查询
-	query := s.db.WithContext(ctx).
-		Where("user_id = ?", userID).
-		Where("expire_at > ?", time.Now())
-
-	if lastSeq > 0 {
-		query = query.Where("id > ?", lastSeq)
+	messages, err := s.repo.FindByUser(ctx, userID, lastSeq, offlinePullLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("query offline messages error: %w", err)
 	}
 
-	if err := query.
-		Order("id ASC").
-		Limit(limit).
-		Find(&messages).Error; err != nil {
-		return nil, fmt.Errorf("query offline messages error: %w", err)
+	return messages, nil
+}
+
+// PullConversationMessages 按会话拉取离线消息
+func (s *offlineServiceImpl) PullConversationMessages(ctx context.Context, userID, conversationID string, lastSeq int64, limit int) ([]*model.OfflineMessage, error) {
+	messages, err := s.repo.FindByConversation(ctx, userID, offlineConversationAliases(conversationID), lastSeq, offlinePullLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("query conversation offline messages error: %w", err)
 	}
 
 	return messages, nil
 }
 
+// AckConversation 确认会话的离线消息
+func (s *offlineServiceImpl) AckConversation(ctx context.Context, userID, conversationID string, lastSeq int64) (int64, error) {
+	aliases := offlineConversationAliases(conversationID)
+
+	// 分批删除，复用 DeleteOfflineMessages 同步清理Redis索引和计数
+	var deleted int64
+	for {
+		messageIDs, err := s.repo.FindIDsByConversation(ctx, userID, aliases, lastSeq, offlineAckBatchSize)
+		if err != nil {
+			return deleted, fmt.Errorf("query conversation offline messages error: %w", err)
+		}
+		if len(messageIDs) == 0 {
+			return deleted, nil
+		}
+		if err := s.DeleteOfflineMessages(ctx, userID, messageIDs); err != nil {
+			return deleted, err
+		}
+		deleted += int64(len(messageIDs))
+		if len(messageIDs) < offlineAckBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
 // MarkAsPushed 标记消息已推送
 func (s *offlineServiceImpl) MarkAsPushed(ctx context.Context, messageIDs []string) error {
 	if len(messageIDs) == 0 {
 		return nil
 	}
 
-	now := time.Now()
-	if err := s.db.WithContext(ctx).
-		Model(&model.OfflineMessage{}).
-		Where("message_id IN ?", messageIDs).
-		Updates(map[string]interface{}{
-			"pushed":    true,
-			"pushed_at": now,
-		}).Error; err != nil {
+	if err := s.repo.MarkPushed(ctx, messageIDs, time.Now()); err != nil {
 		return fmt.Errorf("mark messages as pushed error: %w", err)
 	}
 
@@ -207,9 +296,7 @@ func (s *offlineServiceImpl) DeleteOfflineMessages(ctx context.Context, userID s
 	}
 
 	// 从数据库删除
-	if err := s.db.WithContext(ctx).
-		Where("user_id = ? AND message_id IN ?", userID, messageIDs).
-		Delete(&model.OfflineMessage{}).Error; err != nil {
+	if err := s.repo.Delete(ctx, userID, messageIDs); err != nil {
 		return fmt.Errorf("delete offline messages error: %w", err)
 	}
 
@@ -226,19 +313,31 @@ func (s *offlineServiceImpl) DeleteOfflineMessages(ctx context.Context, userID s
 	return nil
 }
 
+// CancelMessage 从所有用户的离线队列中移除该消息
+func (s *offlineServiceImpl) CancelMessage(ctx context.Context, messageID string) ([]string, error) {
+	userIDs, err := s.repo.FindUsersByMessageID(ctx, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("find offline message users error: %w", err)
+	}
+
+	removed := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if err := s.DeleteOfflineMessages(ctx, userID, []string{messageID}); err != nil {
+			return removed, err
+		}
+		removed = append(removed, userID)
+	}
+	return removed, nil
+}
+
 // GetUnpushedMessages 获取未推送的消息
 func (s *offlineServiceImpl) GetUnpushedMessages(ctx context.Context, userID string, limit int) ([]*model.OfflineMessage, error) {
 	if limit <= 0 {
 		limit = 100
 	}
 
-	var messages []*model.OfflineMessage
-
-	if err := s.db.WithContext(ctx).
-		Where("user_id = ? AND pushed = ? AND expire_at > ?", userID, false, time.Now()).
-		Order("created_at ASC").
-		Limit(limit).
-		Find(&messages).Error; err != nil {
+	messages, err := s.repo.FindUnpushed(ctx, userID, limit)
+	if err != nil {
 		return nil, fmt.Errorf("query unpushed messages error: %w", err)
 	}
 
@@ -247,15 +346,12 @@ func (s *offlineServiceImpl) GetUnpushedMessages(ctx context.Context, userID str
 
 // CleanExpiredMessages 清理过期消息
 func (s *offlineServiceImpl) CleanExpiredMessages(ctx context.Context) (int64, error) {
-	result := s.db.WithContext(ctx).
-		Where("expire_at < ?", time.Now()).
-		Delete(&model.OfflineMessage{})
-
-	if result.Error != nil {
-		return 0, fmt.Errorf("clean expired messages error: %w", result.Error)
+	count, err := s.repo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("clean expired messages error: %w", err)
 	}
 
-	return result.RowsAffected, nil
+	return count, nil
 }
 
 // GetOfflineMessageCount 获取离线消息数量
@@ -268,11 +364,8 @@ func (s *offlineServiceImpl) GetOfflineMessageCount(ctx context.Context, userID
 	}
 
 	// Redis没有，从数据库查询
-	var dbCount int64
-	if err := s.db.WithContext(ctx).
-		Model(&model.OfflineMessage{}).
-		Where("user_id = ? AND expire_at > ?", userID, time.Now()).
-		Count(&dbCount).Error; err != nil {
+	dbCount, err := s.repo.Count(ctx, userID)
+	if err != nil {
 		return 0, err
 	}
 
@@ -302,16 +395,70 @@ func (s *offlineServiceImpl) StartCleanupTask(ctx context.Context) {
 	}
 }
 
+// ReconcileRead 已读对账
+func (s *offlineServiceImpl) ReconcileRead(ctx context.Context, userID, conversationID string, lastReadSeq int64, messageIDs []string) (int64, error) {
+	if conversationID == "" || (lastReadSeq <= 0 && len(messageIDs) == 0) {
+		return 0, nil
+	}
+
+	// 群消息批量保存时离线消息ID为 messageID_userID
+	ids := make([]string, 0, len(messageIDs)*2)
+	for _, id := range messageIDs {
+		ids = append(ids, id, fmt.Sprintf("%s_%s", id, userID))
+	}
+
+	deleted, err := s.repo.DeleteRead(ctx, userID, offlineConversationAliases(conversationID), lastReadSeq, ids)
+	if err != nil {
+		return 0, fmt.Errorf("reconcile read offline messages error: %w", err)
+	}
+	if len(deleted) == 0 {
+		return 0, nil
+	}
+
+	// 只有删除了数据库记录的请求才扣减计数，重复的已读回执不会重复扣减
+	args := make([]interface{}, 0, len(deleted)+1)
+	args = append(args, len(deleted))
+	for _, id := range deleted {
+		args = append(args, id)
+	}
+	keys := []string{fmt.Sprintf("offline:msgs:%s", userID), fmt.Sprintf("offline:count:%s", userID)}
+	if err := reconcileReadScript.Run(ctx, s.redis, keys, args...).Err(); err != nil {
+		// 计数缓存不一致时删除，下次查询从数据库重建
+		fmt.Printf("reconcile offline count in redis error: %v\n", err)
+		s.redis.Del(ctx, keys[1])
+	}
+
+	return int64(len(deleted)), nil
+}
+
+// offlineAckBatchSize 按会话确认时每批删除的消息数
+const offlineAckBatchSize = 500
+
+// offlinePullLimit 规范化拉取数量
+func offlinePullLimit(limit int) int {
+	if limit <= 0 {
+		return 100
+	}
+	if limit > 500 {
+		return 500
+	}
+	return limit
+}
+
+// offlineConversationAliases 会话ID的全部存储形式，无法解析时按原样匹配
+func offlineConversationAliases(conversationID string) []string {
+	convID, err := model.ParseConversationID(conversationID)
+	if err != nil {
+		return []string{conversationID}
+	}
+	return convID.Aliases()
+}
+
 // deleteOldestMessages 删除最旧的消息
 func (s *offlineServiceImpl) deleteOldestMessages(ctx context.Context, userID string, count int) error {
 	// 查询最旧的消息ID
-	var messageIDs []string
-	if err := s.db.WithContext(ctx).
-		Model(&model.OfflineMessage{}).
-		Where("user_id = ?", userID).
-		Order("created_at ASC").
-		Limit(count).
-		Pluck("message_id", &messageIDs).Error; err != nil {
+	messageIDs, err := s.repo.FindOldestIDs(ctx, userID, count)
+	if err != nil {
 		return err
 	}
 
@@ -336,13 +483,14 @@ type OfflineMessageSummary struct {
 	UserID        string                        `json:"user_id"`
 	TotalCount    int64                         `json:"total_count"`
 	UnpushedCount int64                         `json:"unpushed_count"`
-	Conversations []*ConversationOfflineSummary `json:"conversations"`
+	Conversations []*ConversationOfflineSummary `json:"conversations"` // 最近有消息的会话在前
 }
 
 // ConversationOfflineSummary 会话离线消息摘要
 type ConversationOfflineSummary struct {
 	ConversationID string `json:"conversation_id"`
 	Count          int64  `json:"count"`
+	LastSeq        int64  `json:"last_seq"` // 会话最新一条离线消息的序号，可用于按会话确认
 	LastMessageAt  int64  `json:"last_message_at"`
 }
 
@@ -360,44 +508,89 @@ func (s *offlineServiceImpl) GetOfflineMessageSummary(ctx context.Context, userI
 	summary.TotalCount = total
 
 	// 获取未推送数
-	var unpushedCount int64
-	if err := s.db.WithContext(ctx).
-		Model(&model.OfflineMessage{}).
-		Where("user_id = ? AND pushed = ? AND expire_at > ?", userID, false, time.Now()).
-		Count(&unpushedCount).Error; err != nil {
+	unpushedCount, err := s.repo.CountUnpushed(ctx, userID)
+	if err != nil {
 		return nil, err
 	}
 	summary.UnpushedCount = unpushedCount
 
 	// 按会话分组统计
-	type conversationStat struct {
-		ConversationID string
-		Count          int64
-		LastCreatedAt  time.Time
-	}
-
-	var stats []conversationStat
-	if err := s.db.WithContext(ctx).
-		Model(&model.OfflineMessage{}).
-		Select("conversation_id, COUNT(*) as count, MAX(created_at) as last_created_at").
-		Where("user_id = ? AND expire_at > ?", userID, time.Now()).
-		Group("conversation_id").
-		Scan(&stats).Error; err != nil {
+	stats, err := s.repo.ConversationStats(ctx, userID)
+	if err != nil {
 		return nil, err
 	}
 
+	// 同一会话的新旧ID格式合并为规范格式
+	byConversation := make(map[string]*ConversationOfflineSummary, len(stats))
 	summary.Conversations = make([]*ConversationOfflineSummary, 0, len(stats))
 	for _, stat := range stats {
-		summary.Conversations = append(summary.Conversations, &ConversationOfflineSummary{
-			ConversationID: stat.ConversationID,
-			Count:          stat.Count,
-			LastMessageAt:  stat.LastCreatedAt.UnixMilli(),
-		})
+		conversationID := model.CanonicalConversationID(stat.ConversationID)
+		conv, ok := byConversation[conversationID]
+		if !ok {
+			conv = &ConversationOfflineSummary{ConversationID: conversationID}
+			byConversation[conversationID] = conv
+			summary.Conversations = append(summary.Conversations, conv)
+		}
+		conv.Count += stat.Count
+		if stat.LastID > conv.LastSeq {
+			conv.LastSeq = stat.LastID
+		}
+		if lastAt := stat.LastCreatedAt.UnixMilli(); lastAt > conv.LastMessageAt {
+			conv.LastMessageAt = lastAt
+		}
 	}
+	sort.Slice(summary.Conversations, func(i, j int) bool {
+		return summary.Conversations[i].LastMessageAt > summary.Conversations[j].LastMessageAt
+	})
 
 	return summary, nil
 }
 
+// OfflineBootstrapResult 新设备首次同步结果
+type OfflineBootstrapResult struct {
+	Mode       string                 `json:"mode"`              // replay / snapshot
+	Summary    *OfflineMessageSummary `json:"summary,omitempty"` // 快照模式下标记前的会话摘要
+	KeepRecent int                    `json:"keep_recent"`       // 快照模式下每个会话仍可逐条拉取的消息数
+	Superseded int64                  `json:"superseded"`        // 被快照取代的离线消息数
+}
+
+// Bootstrap 新设备首次同步
+func (s *offlineServiceImpl) Bootstrap(ctx context.Context, userID string) (*OfflineBootstrapResult, error) {
+	result := &OfflineBootstrapResult{Mode: OfflineBootstrapReplay}
+	if s.config.BootstrapThreshold <= 0 {
+		return result, nil
+	}
+
+	// 以数据库为准判断是否达到阈值，Redis计数可能有偏差
+	total, err := s.repo.Count(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("count offline messages error: %w", err)
+	}
+	if total < int64(s.config.BootstrapThreshold) {
+		return result, nil
+	}
+
+	summary, err := s.GetOfflineMessageSummary(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	summary.TotalCount = total
+
+	superseded, err := s.repo.Supersede(ctx, userID, s.config.BootstrapKeepRecent)
+	if err != nil {
+		return nil, fmt.Errorf("supersede offline messages error: %w", err)
+	}
+
+	// 计数缓存删除后从数据库重建（已取代的消息不计入），索引中的成员随消息删除或过期清理
+	s.redis.Del(ctx, fmt.Sprintf("offline:count:%s", userID))
+
+	result.Mode = OfflineBootstrapSnapshot
+	result.Summary = summary
+	result.KeepRecent = s.config.BootstrapKeepRecent
+	result.Superseded = superseded
+	return result, nil
+}
+
 // BatchSaveOfflineMessages 批量保存离线消息
 func (s *offlineServiceImpl) BatchSaveOfflineMessages(ctx context.Context, userIDs []string, msg *model.Message) error {
 	if len(userIDs) == 0 {
@@ -422,6 +615,7 @@ func (s *offlineServiceImpl) BatchSaveOfflineMessages(ctx context.Context, userI
 			UserID:         userID,
 			MessageID:      fmt.Sprintf("%s_%s", msg.MessageID, userID), // 为每个用户生成唯一ID
 			ConversationID: msg.ConversationID,
+			Seq:            msg.Seq,
 			Content:        content,
 			Pushed:         false,
 			CreatedAt:      now,
@@ -430,7 +624,7 @@ func (s *offlineServiceImpl) BatchSaveOfflineMessages(ctx context.Context, userI
 	}
 
 	// 批量插入
-	if err := s.db.WithContext(ctx).CreateInBatches(offlineMessages, 100).Error; err != nil {
+	if err := s.repo.CreateBatch(ctx, offlineMessages); err != nil {
 		return fmt.Errorf("batch save offline messages error: %w", err)
 	}
 
@@ -476,6 +670,12 @@ type PullOfflineMessagesResponse struct {
 	LastSeq  int64                   `json:"last_seq"`
 }
 
+// MarkConversationReadRequest 会话已读请求
+type MarkConversationReadRequest struct {
+	LastReadSeq int64    `json:"last_read_seq"`
+	MessageIDs  []string `json:"message_ids" binding:"max=100"`
+}
+
 // AckOfflineMessagesRequest 确认离线消息请求
 type AckOfflineMessagesRequest struct {
 	MessageIDs []string `json:"message_ids" binding:"required"`
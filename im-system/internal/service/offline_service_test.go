@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository/memory"
+	"github.com/go-redis/redis/v8"
+)
+
+// newTestOfflineService 基于内存仓库和 miniredis 创建离线消息服务
+func newTestOfflineService(t *testing.T, maxMessages int) (OfflineService, *redis.Client) {
+	t.Helper()
+	config := DefaultOfflineServiceConfig()
+	config.MaxMessages = maxMessages
+	client := newTestRedis(t)
+	return NewOfflineService(memory.NewOfflineMessageRepository(), client, config), client
+}
+
+// saveTestMessages 向用户保存 n 条属于 conversationID 的离线消息，返回消息ID
+func saveTestMessages(t *testing.T, svc OfflineService, userID, conversationID string, n int) []string {
+	t.Helper()
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%s-%d", conversationID, i+1)
+		msg := &model.Message{
+			MessageID:      ids[i],
+			Type:           model.MsgText,
+			From:           "sender",
+			To:             userID,
+			Content:        "hello",
+			ConversationID: conversationID,
+			Seq:            int64(i + 1),
+			Timestamp:      time.Now().UnixMilli() + int64(i),
+		}
+		if err := svc.SaveOfflineMessage(context.Background(), userID, msg); err != nil {
+			t.Fatalf("SaveOfflineMessage: %v", err)
+		}
+	}
+	return ids
+}
+
+// messageIDs 离线消息的消息ID
+func messageIDs(msgs []*model.OfflineMessage) []string {
+	ids := make([]string, len(msgs))
+	for i, msg := range msgs {
+		ids[i] = msg.MessageID
+	}
+	return ids
+}
+
+// assertOfflineCount 检查离线计数及Redis索引大小
+func assertOfflineCount(t *testing.T, svc OfflineService, client *redis.Client, userID string, want int64) {
+	t.Helper()
+	ctx := context.Background()
+	count, err := svc.GetOfflineMessageCount(ctx, userID)
+	if err != nil || count != want {
+		t.Fatalf("GetOfflineMessageCount = %d, %v; want %d", count, err, want)
+	}
+	if size := client.ZCard(ctx, "offline:msgs:"+userID).Val(); size != want {
+		t.Fatalf("offline index size = %d, want %d", size, want)
+	}
+}
+
+func TestOfflineServiceSaveAndPull(t *testing.T) {
+	ctx := context.Background()
+	svc, client := newTestOfflineService(t, 100)
+
+	conv := model.GetSingleChatConversationID("sender", "u1")
+	ids := saveTestMessages(t, svc, "u1", conv, 3)
+	assertOfflineCount(t, svc, client, "u1", 3)
+
+	msgs, err := svc.PullOfflineMessages(ctx, "u1", 0, 10)
+	if err != nil {
+		t.Fatalf("PullOfflineMessages: %v", err)
+	}
+	if got := messageIDs(msgs); fmt.Sprint(got) != fmt.Sprint(ids) {
+		t.Fatalf("pulled %v, want %v", got, ids)
+	}
+
+	parsed, err := ParseOfflineMessage(msgs[0])
+	if err != nil || parsed.MessageID != ids[0] || parsed.ConversationID != conv {
+		t.Fatalf("ParseOfflineMessage = %+v, %v", parsed, err)
+	}
+
+	// 按上次拉取的最后ID续拉
+	next, err := svc.PullOfflineMessages(ctx, "u1", int64(msgs[0].ID), 1)
+	if err != nil || len(next) != 1 || next[0].MessageID != ids[1] {
+		t.Fatalf("PullOfflineMessages after first = %v, %v", messageIDs(next), err)
+	}
+
+	if other, _ := svc.PullOfflineMessages(ctx, "u2", 0, 10); len(other) != 0 {
+		t.Fatalf("other user pulled %v", messageIDs(other))
+	}
+}
+
+func TestOfflineServicePullConversation(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestOfflineService(t, 100)
+
+	single := model.GetSingleChatConversationID("sender", "u1")
+	group := model.GetGroupChatConversationID("g1")
+	saveTestMessages(t, svc, "u1", single, 2)
+	groupIDs := saveTestMessages(t, svc, "u1", group, 2)
+
+	msgs, err := svc.PullConversationMessages(ctx, "u1", group, 0, 10)
+	if err != nil {
+		t.Fatalf("PullConversationMessages: %v", err)
+	}
+	if got := messageIDs(msgs); fmt.Sprint(got) != fmt.Sprint(groupIDs) {
+		t.Fatalf("pulled %v, want %v", got, groupIDs)
+	}
+}
+
+func TestOfflineServiceAckConversation(t *testing.T) {
+	ctx := context.Background()
+	svc, client := newTestOfflineService(t, 100)
+
+	single := model.GetSingleChatConversationID("sender", "u1")
+	group := model.GetGroupChatConversationID("g1")
+	saveTestMessages(t, svc, "u1", single, 3)
+	groupIDs := saveTestMessages(t, svc, "u1", group, 2)
+
+	all, _ := svc.PullOfflineMessages(ctx, "u1", 0, 10)
+
+	// 确认到第二条为止
+	deleted, err := svc.AckConversation(ctx, "u1", single, int64(all[1].ID))
+	if err != nil || deleted != 2 {
+		t.Fatalf("AckConversation = %d, %v; want 2", deleted, err)
+	}
+	assertOfflineCount(t, svc, client, "u1", 3)
+
+	// lastSeq 为0时确认全部
+	deleted, err = svc.AckConversation(ctx, "u1", single, 0)
+	if err != nil || deleted != 1 {
+		t.Fatalf("AckConversation all = %d, %v; want 1", deleted, err)
+	}
+	assertOfflineCount(t, svc, client, "u1", 2)
+
+	rest, _ := svc.PullOfflineMessages(ctx, "u1", 0, 10)
+	if got := messageIDs(rest); fmt.Sprint(got) != fmt.Sprint(groupIDs) {
+		t.Fatalf("remaining %v, want %v", got, groupIDs)
+	}
+}
+
+func TestOfflineServiceLimitEvictsOldest(t *testing.T) {
+	ctx := context.Background()
+	svc, client := newTestOfflineService(t, 3)
+
+	conv := model.GetSingleChatConversationID("sender", "u1")
+	ids := saveTestMessages(t, svc, "u1", conv, 5)
+	assertOfflineCount(t, svc, client, "u1", 3)
+
+	msgs, err := svc.PullOfflineMessages(ctx, "u1", 0, 10)
+	if err != nil {
+		t.Fatalf("PullOfflineMessages: %v", err)
+	}
+	if got := messageIDs(msgs); fmt.Sprint(got) != fmt.Sprint(ids[2:]) {
+		t.Fatalf("kept %v, want %v", got, ids[2:])
+	}
+}
@@ -0,0 +1,521 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 组织架构服务错误定义
+var (
+	ErrDepartmentNotFound = errors.New("department not found")
+	ErrDepartmentNotEmpty = errors.New("department still has sub-departments or members")
+	ErrDepartmentCycle    = errors.New("department cannot be moved under itself or its descendants")
+)
+
+// 通讯录搜索条数限制
+const (
+	orgSearchDefaultLimit = 20
+	orgSearchMaxLimit     = 50
+)
+
+// OrgViewer 通讯录查看者
+type OrgViewer struct {
+	UserID string
+	Admin  bool // 管理员可查看全部部门（含隐藏部门）
+}
+
+// DepartmentNode 部门树节点
+type DepartmentNode struct {
+	DepartmentID string                     `json:"department_id"`
+	ParentID     string                     `json:"parent_id,omitempty"`
+	Name         string                     `json:"name"`
+	SortOrder    int                        `json:"sort_order"`
+	Visibility   model.DepartmentVisibility `json:"visibility"`
+	MemberCount  int64                      `json:"member_count"` // 直属成员数
+	Children     []*DepartmentNode          `json:"children,omitempty"`
+}
+
+// OrgContact 通讯录联系人
+type OrgContact struct {
+	UserID         string `json:"user_id"`
+	Username       string `json:"username"`
+	Nickname       string `json:"nickname"`
+	Avatar         string `json:"avatar,omitempty"`
+	DepartmentID   string `json:"department_id"`
+	DepartmentName string `json:"department_name"`
+	Title          string `json:"title,omitempty"`
+	IsPrimary      bool   `json:"is_primary"`
+}
+
+// CreateDepartmentRequest 创建部门请求
+type CreateDepartmentRequest struct {
+	TenantID   string                     `json:"tenant_id"`
+	ParentID   string                     `json:"parent_id"` // 为空表示根部门
+	Name       string                     `json:"name" binding:"required,max=128"`
+	SortOrder  int                        `json:"sort_order"`
+	Visibility model.DepartmentVisibility `json:"visibility" binding:"min=0,max=2"`
+}
+
+// UpdateDepartmentRequest 更新部门请求（字段为空表示不修改）
+type UpdateDepartmentRequest struct {
+	DepartmentID string                      `json:"-"`
+	Name         *string                     `json:"name" binding:"omitempty,max=128"`
+	ParentID     *string                     `json:"parent_id"` // 空字符串表示移动为根部门
+	SortOrder    *int                        `json:"sort_order"`
+	Visibility   *model.DepartmentVisibility `json:"visibility" binding:"omitempty,min=0,max=2"`
+}
+
+// DepartmentMemberInput 部门成员设置
+type DepartmentMemberInput struct {
+	UserID    string `json:"user_id" binding:"required"`
+	Title     string `json:"title" binding:"max=64"`
+	IsPrimary bool   `json:"is_primary"`
+	SortOrder int    `json:"sort_order"`
+}
+
+// OrgService 组织架构服务接口
+type OrgService interface {
+	// 部门管理（管理员）
+	CreateDepartment(ctx context.Context, req *CreateDepartmentRequest) (*model.Department, error)
+	UpdateDepartment(ctx context.Context, req *UpdateDepartmentRequest) error
+	DeleteDepartment(ctx context.Context, departmentID string) error
+	AddMembers(ctx context.Context, departmentID string, members []*DepartmentMemberInput) error
+	RemoveMember(ctx context.Context, departmentID, userID string) error
+
+	// 通讯录（按可见范围过滤）
+	GetTree(ctx context.Context, viewer *OrgViewer) ([]*DepartmentNode, error)
+	GetDepartmentMembers(ctx context.Context, viewer *OrgViewer, departmentID string, page, pageSize int) ([]*OrgContact, int64, error)
+	Search(ctx context.Context, viewer *OrgViewer, keyword string, limit int) ([]*OrgContact, error)
+	GetUserDepartments(ctx context.Context, viewer *OrgViewer, userID string) ([]*OrgContact, error)
+}
+
+// orgServiceImpl 组织架构服务实现
+type orgServiceImpl struct {
+	repo  repository.OrgRepository
+	users repository.UserRepository
+}
+
+// NewOrgService 创建组织架构服务
+func NewOrgService(repo repository.OrgRepository, users repository.UserRepository) OrgService {
+	return &orgServiceImpl{
+		repo:  repo,
+		users: users,
+	}
+}
+
+// CreateDepartment 创建部门
+func (s *orgServiceImpl) CreateDepartment(ctx context.Context, req *CreateDepartmentRequest) (*model.Department, error) {
+	dept := &model.Department{
+		DepartmentID: util.GenerateDepartmentID(),
+		TenantID:     req.TenantID,
+		ParentID:     req.ParentID,
+		Name:         strings.TrimSpace(req.Name),
+		SortOrder:    req.SortOrder,
+		Visibility:   req.Visibility,
+	}
+	if dept.Name == "" {
+		return nil, ErrInvalidRequest
+	}
+
+	parentPath := ""
+	if req.ParentID != "" {
+		parent, err := s.findDepartment(ctx, req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.TenantID != req.TenantID {
+			return nil, ErrInvalidRequest
+		}
+		parentPath = parent.Path
+	}
+	dept.Path = model.DepartmentPath(parentPath, dept.DepartmentID)
+
+	if err := s.repo.CreateDepartment(ctx, dept); err != nil {
+		return nil, fmt.Errorf("create department error: %w", err)
+	}
+	return dept, nil
+}
+
+// UpdateDepartment 更新部门，修改上级部门时整棵子树随之移动
+func (s *orgServiceImpl) UpdateDepartment(ctx context.Context, req *UpdateDepartmentRequest) error {
+	dept, err := s.findDepartment(ctx, req.DepartmentID)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return ErrInvalidRequest
+		}
+		updates["name"] = name
+	}
+	if req.SortOrder != nil {
+		updates["sort_order"] = *req.SortOrder
+	}
+	if req.Visibility != nil {
+		updates["visibility"] = *req.Visibility
+	}
+
+	newPath := ""
+	if req.ParentID != nil && *req.ParentID != dept.ParentID {
+		parentPath := ""
+		if *req.ParentID != "" {
+			parent, err := s.findDepartment(ctx, *req.ParentID)
+			if err != nil {
+				return err
+			}
+			if parent.TenantID != dept.TenantID {
+				return ErrInvalidRequest
+			}
+			if dept.IsAncestorOf(parent) {
+				return ErrDepartmentCycle
+			}
+			parentPath = parent.Path
+		}
+		updates["parent_id"] = *req.ParentID
+		newPath = model.DepartmentPath(parentPath, dept.DepartmentID)
+	}
+
+	return s.repo.Transaction(ctx, func(tx repository.OrgRepository) error {
+		if err := tx.UpdateDepartment(ctx, dept.DepartmentID, updates); err != nil {
+			return fmt.Errorf("update department error: %w", err)
+		}
+		if newPath != "" {
+			if err := tx.MoveSubtree(ctx, dept.Path, newPath); err != nil {
+				return fmt.Errorf("move department error: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteDepartment 删除部门（须先移除下级部门和成员）
+func (s *orgServiceImpl) DeleteDepartment(ctx context.Context, departmentID string) error {
+	if _, err := s.findDepartment(ctx, departmentID); err != nil {
+		return err
+	}
+
+	children, err := s.repo.CountChildren(ctx, departmentID)
+	if err != nil {
+		return err
+	}
+	counts, err := s.repo.CountMembers(ctx, []string{departmentID})
+	if err != nil {
+		return err
+	}
+	if children > 0 || counts[departmentID] > 0 {
+		return ErrDepartmentNotEmpty
+	}
+
+	return s.repo.DeleteDepartment(ctx, departmentID)
+}
+
+// AddMembers 添加部门成员，已在部门中的成员更新职位等信息
+func (s *orgServiceImpl) AddMembers(ctx context.Context, departmentID string, inputs []*DepartmentMemberInput) error {
+	if len(inputs) == 0 {
+		return ErrInvalidRequest
+	}
+	dept, err := s.findDepartment(ctx, departmentID)
+	if err != nil {
+		return err
+	}
+
+	userIDs := make([]string, len(inputs))
+	for i, input := range inputs {
+		userIDs[i] = input.UserID
+	}
+	users, err := s.users.FindByIDs(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+	tenants := make(map[string]string, len(users))
+	for _, user := range users {
+		tenants[user.UserID] = user.TenantID
+	}
+
+	now := time.Now()
+	members := make([]*model.DepartmentMember, 0, len(inputs))
+	for _, input := range inputs {
+		tenantID, ok := tenants[input.UserID]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUserNotFound, input.UserID)
+		}
+		if tenantID != dept.TenantID {
+			return ErrInvalidRequest
+		}
+		members = append(members, &model.DepartmentMember{
+			DepartmentID: departmentID,
+			UserID:       input.UserID,
+			Title:        input.Title,
+			IsPrimary:    input.IsPrimary,
+			SortOrder:    input.SortOrder,
+			JoinedAt:     now,
+		})
+	}
+	return s.repo.UpsertMembers(ctx, members)
+}
+
+// RemoveMember 移除部门成员
+func (s *orgServiceImpl) RemoveMember(ctx context.Context, departmentID, userID string) error {
+	if _, err := s.findDepartment(ctx, departmentID); err != nil {
+		return err
+	}
+	return s.repo.RemoveMember(ctx, departmentID, userID)
+}
+
+// GetTree 获取查看者可见的部门树
+func (s *orgServiceImpl) GetTree(ctx context.Context, viewer *OrgViewer) ([]*DepartmentNode, error) {
+	org, err := s.loadOrg(ctx, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	visibleIDs := org.visibleIDs()
+	counts, err := s.repo.CountMembers(ctx, visibleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*DepartmentNode, len(visibleIDs))
+	for _, id := range visibleIDs {
+		dept := org.depts[id]
+		nodes[id] = &DepartmentNode{
+			DepartmentID: dept.DepartmentID,
+			ParentID:     dept.ParentID,
+			Name:         dept.Name,
+			SortOrder:    dept.SortOrder,
+			Visibility:   dept.Visibility,
+			MemberCount:  counts[id],
+		}
+	}
+
+	// 部门已按排序值有序，按原顺序挂接子节点
+	roots := []*DepartmentNode{}
+	for _, id := range visibleIDs {
+		node := nodes[id]
+		if parent, ok := nodes[node.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots, nil
+}
+
+// GetDepartmentMembers 分页获取部门直属成员
+func (s *orgServiceImpl) GetDepartmentMembers(ctx context.Context, viewer *OrgViewer, departmentID string, page, pageSize int) ([]*OrgContact, int64, error) {
+	org, err := s.loadOrg(ctx, viewer)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !org.visible(departmentID) {
+		return nil, 0, ErrDepartmentNotFound
+	}
+
+	members, total, err := s.repo.FindMembers(ctx, departmentID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	contacts, err := s.toContacts(ctx, org, members)
+	if err != nil {
+		return nil, 0, err
+	}
+	return contacts, total, nil
+}
+
+// Search 在可见部门内按用户名/昵称搜索同事
+func (s *orgServiceImpl) Search(ctx context.Context, viewer *OrgViewer, keyword string, limit int) ([]*OrgContact, error) {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return nil, ErrInvalidRequest
+	}
+	if limit <= 0 {
+		limit = orgSearchDefaultLimit
+	}
+	if limit > orgSearchMaxLimit {
+		limit = orgSearchMaxLimit
+	}
+
+	org, err := s.loadOrg(ctx, viewer)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.repo.SearchMembers(ctx, org.visibleIDs(), keyword, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.toContacts(ctx, org, members)
+}
+
+// GetUserDepartments 获取用户在可见部门中的任职
+func (s *orgServiceImpl) GetUserDepartments(ctx context.Context, viewer *OrgViewer, userID string) ([]*OrgContact, error) {
+	org, err := s.loadOrg(ctx, viewer)
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := s.repo.FindUserMemberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := memberships[:0]
+	for _, m := range memberships {
+		if org.visible(m.DepartmentID) {
+			visible = append(visible, m)
+		}
+	}
+	return s.toContacts(ctx, org, visible)
+}
+
+// findDepartment 查询部门，不存在时返回 ErrDepartmentNotFound
+func (s *orgServiceImpl) findDepartment(ctx context.Context, departmentID string) (*model.Department, error) {
+	dept, err := s.repo.FindDepartment(ctx, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	if dept == nil {
+		return nil, ErrDepartmentNotFound
+	}
+	return dept, nil
+}
+
+// toContacts 部门成员转换为通讯录联系人（忽略已禁用的用户）
+func (s *orgServiceImpl) toContacts(ctx context.Context, org *orgSnapshot, members []*model.DepartmentMember) ([]*OrgContact, error) {
+	contacts := make([]*OrgContact, 0, len(members))
+	if len(members) == 0 {
+		return contacts, nil
+	}
+
+	userIDs := make([]string, len(members))
+	for i, m := range members {
+		userIDs[i] = m.UserID
+	}
+	users, err := s.users.FindByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	userMap := make(map[string]*model.User, len(users))
+	for _, user := range users {
+		userMap[user.UserID] = user
+	}
+
+	for _, m := range members {
+		user, ok := userMap[m.UserID]
+		if !ok || user.Status != model.UserStatusNormal {
+			continue
+		}
+		contact := &OrgContact{
+			UserID:       user.UserID,
+			Username:     user.Username,
+			Nickname:     user.Nickname,
+			Avatar:       user.Avatar,
+			DepartmentID: m.DepartmentID,
+			Title:        m.Title,
+			IsPrimary:    m.IsPrimary,
+		}
+		if dept, ok := org.depts[m.DepartmentID]; ok {
+			contact.DepartmentName = dept.Name
+		}
+		contacts = append(contacts, contact)
+	}
+	return contacts, nil
+}
+
+// orgSnapshot 查看者所在租户的部门快照
+type orgSnapshot struct {
+	admin       bool
+	depts       map[string]*model.Department
+	ordered     []*model.Department
+	viewerPaths []string // 查看者所属部门的路径
+}
+
+// loadOrg 加载查看者所在租户的部门及其所属部门
+func (s *orgServiceImpl) loadOrg(ctx context.Context, viewer *OrgViewer) (*orgSnapshot, error) {
+	user, err := s.users.FindByID(ctx, viewer.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	depts, err := s.repo.FindDepartments(ctx, user.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("find departments error: %w", err)
+	}
+	org := &orgSnapshot{
+		admin:   viewer.Admin,
+		depts:   make(map[string]*model.Department, len(depts)),
+		ordered: depts,
+	}
+	for _, dept := range depts {
+		org.depts[dept.DepartmentID] = dept
+	}
+
+	memberships, err := s.repo.FindUserMemberships(ctx, viewer.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("find user departments error: %w", err)
+	}
+	for _, m := range memberships {
+		if dept, ok := org.depts[m.DepartmentID]; ok {
+			org.viewerPaths = append(org.viewerPaths, dept.Path)
+		}
+	}
+	return org, nil
+}
+
+// visible 部门对查看者是否可见：部门自身及全部上级部门均须可见
+func (o *orgSnapshot) visible(departmentID string) bool {
+	dept, ok := o.depts[departmentID]
+	if !ok {
+		return false
+	}
+	if o.admin {
+		return true
+	}
+	for _, id := range append(dept.AncestorIDs(), dept.DepartmentID) {
+		ancestor, ok := o.depts[id]
+		if !ok {
+			continue
+		}
+		switch ancestor.Visibility {
+		case model.DeptVisibleHidden:
+			return false
+		case model.DeptVisibleMembers:
+			if !o.inSubtree(ancestor.Path) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// inSubtree 查看者是否属于该路径对应部门或其下级部门
+func (o *orgSnapshot) inSubtree(path string) bool {
+	for _, p := range o.viewerPaths {
+		if strings.HasPrefix(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleIDs 可见部门ID（保持排序）
+func (o *orgSnapshot) visibleIDs() []string {
+	ids := make([]string, 0, len(o.ordered))
+	for _, dept := range o.ordered {
+		if o.visible(dept.DepartmentID) {
+			ids = append(ids, dept.DepartmentID)
+		}
+	}
+	return ids
+}
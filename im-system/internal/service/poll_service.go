@@ -0,0 +1,503 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/i18n"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 群投票服务错误定义
+var (
+	ErrPollNotFound        = errors.New("poll not found")
+	ErrPollClosed          = errors.New("poll already closed")
+	ErrPollAlreadyVoted    = errors.New("already voted in this poll")
+	ErrPollInvalid         = errors.New("invalid poll question or options")
+	ErrPollInvalidChoice   = errors.New("invalid poll choice")
+	ErrPollDeadlineInvalid = errors.New("invalid poll deadline")
+)
+
+// PollConfig 群投票配置
+type PollConfig struct {
+	MinOptions      int           // 最少选项数
+	MaxOptions      int           // 最多选项数
+	MaxOptionLength int           // 单个选项最大长度（字符）
+	MaxDuration     time.Duration // 截止时间最远距离
+	PollInterval    time.Duration // 到期扫描间隔
+	BatchSize       int           // 每次扫描结束的投票数
+}
+
+// DefaultPollConfig 默认群投票配置
+func DefaultPollConfig() *PollConfig {
+	return &PollConfig{
+		MinOptions:      2,
+		MaxOptions:      20,
+		MaxOptionLength: 100,
+		MaxDuration:     30 * 24 * time.Hour,
+		PollInterval:    10 * time.Second,
+		BatchSize:       100,
+	}
+}
+
+// CreatePollRequest 创建投票请求
+type CreatePollRequest struct {
+	Question    string     `json:"question" binding:"required,max=256"`
+	Options     []string   `json:"options" binding:"required"`
+	MultiChoice bool       `json:"multi_choice"`
+	Anonymous   bool       `json:"anonymous"`
+	Deadline    *time.Time `json:"deadline"` // 为空时由发起人或管理员手动结束
+}
+
+// VotePollRequest 投票请求（选项下标，单选投票只能包含一个）
+type VotePollRequest struct {
+	Options []int `json:"options" binding:"required"`
+}
+
+// PollView 投票详情及当前结果
+type PollView struct {
+	*model.Poll
+	Results     []*model.PollOptionResult `json:"results"`
+	TotalVoters int64                     `json:"total_voters"`
+	MyChoices   []int                     `json:"my_choices,omitempty"` // 当前用户的选择，未投票时为空
+}
+
+// PollService 群投票服务接口
+type PollService interface {
+	// CreatePoll 在群聊中发起投票（以投票消息发送到群聊）
+	CreatePoll(ctx context.Context, userID, groupID string, req *CreatePollRequest) (*model.Poll, *model.Message, error)
+
+	// GetPoll 获取投票详情及当前结果（仅群成员可见）
+	GetPoll(ctx context.Context, userID, pollID string) (*PollView, error)
+
+	// Vote 投票，每个用户每个投票只能投一次
+	Vote(ctx context.Context, userID, pollID string, req *VotePollRequest) (*PollView, error)
+
+	// ClosePoll 手动结束投票（发起人或群主、管理员）
+	ClosePoll(ctx context.Context, userID, pollID string) (*PollView, error)
+
+	// CloseDue 结束已到截止时间的投票，返回结束数量
+	CloseDue(ctx context.Context) (int, error)
+
+	// Start 启动到期投票扫描
+	Start(ctx context.Context)
+}
+
+// pollServiceImpl 群投票服务实现
+type pollServiceImpl struct {
+	repo           repository.PollRepository
+	messageService MessageService
+	groupService   GroupService
+	dispatcher     MessageDispatcher
+	patchNotifier  *MessagePatchNotifier
+	config         *PollConfig
+}
+
+// NewPollService 创建群投票服务
+func NewPollService(
+	repo repository.PollRepository,
+	messageService MessageService,
+	groupService GroupService,
+	dispatcher MessageDispatcher,
+	patchNotifier *MessagePatchNotifier,
+	config *PollConfig,
+) PollService {
+	if config == nil {
+		config = DefaultPollConfig()
+	}
+	return &pollServiceImpl{
+		repo:           repo,
+		messageService: messageService,
+		groupService:   groupService,
+		dispatcher:     dispatcher,
+		patchNotifier:  patchNotifier,
+		config:         config,
+	}
+}
+
+// CreatePoll 发起投票
+func (s *pollServiceImpl) CreatePoll(ctx context.Context, userID, groupID string, req *CreatePollRequest) (*model.Poll, *model.Message, error) {
+	question, options, err := s.validate(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	isMember, err := s.groupService.IsMember(ctx, groupID, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("check membership error: %w", err)
+	}
+	if !isMember {
+		return nil, nil, ErrNotGroupMember
+	}
+
+	now := time.Now()
+	poll := &model.Poll{
+		PollID:      util.GeneratePollID(),
+		GroupID:     groupID,
+		MessageID:   util.GenerateMessageID(),
+		CreatorID:   userID,
+		Question:    question,
+		Options:     options,
+		MultiChoice: req.MultiChoice,
+		Anonymous:   req.Anonymous,
+		Deadline:    req.Deadline,
+		Status:      model.PollOpen,
+	}
+	if err := s.repo.Create(ctx, poll); err != nil {
+		return nil, nil, fmt.Errorf("create poll error: %w", err)
+	}
+
+	content := &model.PollContent{
+		PollID:      poll.PollID,
+		Question:    poll.Question,
+		Options:     poll.Options,
+		MultiChoice: poll.MultiChoice,
+		Anonymous:   poll.Anonymous,
+		Counts:      make([]int64, len(poll.Options)),
+	}
+	if poll.Deadline != nil {
+		content.Deadline = poll.Deadline.UnixMilli()
+	}
+	msg := &model.Message{
+		MessageID:      poll.MessageID,
+		Type:           model.MsgPoll,
+		From:           userID,
+		To:             groupID,
+		GroupID:        groupID,
+		ConversationID: model.GetGroupChatConversationID(groupID),
+		Content:        content,
+		Timestamp:      now.UnixMilli(),
+		CreatedAt:      now,
+	}
+
+	// 保存消息，失败时删除投票
+	if err := s.messageService.SaveMessage(ctx, msg); err != nil {
+		if delErr := s.repo.Delete(ctx, poll.PollID); delErr != nil {
+			log.Printf("delete poll %s error: %v", poll.PollID, delErr)
+		}
+		return nil, nil, err
+	}
+
+	// 分发消息（消息已持久化，分发失败的用户可通过历史消息拉取，不回滚）
+	if s.dispatcher != nil {
+		memberIDs, err := s.groupService.GetGroupMemberIDs(ctx, groupID)
+		if err != nil {
+			log.Printf("get group members for poll %s error: %v", poll.PollID, err)
+		} else if err := s.dispatcher.DispatchToUsers(ctx, util.RemoveString(memberIDs, userID), msg); err != nil {
+			log.Printf("dispatch poll message %s error: %v", msg.MessageID, err)
+		}
+	}
+
+	return poll, msg, nil
+}
+
+// GetPoll 获取投票详情
+func (s *pollServiceImpl) GetPoll(ctx context.Context, userID, pollID string) (*PollView, error) {
+	poll, err := s.findVisiblePoll(ctx, userID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	return s.view(ctx, poll, userID)
+}
+
+// Vote 投票
+func (s *pollServiceImpl) Vote(ctx context.Context, userID, pollID string, req *VotePollRequest) (*PollView, error) {
+	poll, err := s.findVisiblePoll(ctx, userID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.Status != model.PollOpen {
+		return nil, ErrPollClosed
+	}
+
+	choices, err := s.normalizeChoices(poll, req.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.repo.Vote(ctx, &model.PollVote{PollID: poll.PollID, UserID: userID, Options: choices})
+	if err != nil {
+		return nil, fmt.Errorf("save poll vote error: %w", err)
+	}
+	if !ok {
+		// 投票已结束或用户已投过票，重新查询区分
+		latest, err := s.repo.FindByID(ctx, pollID)
+		if err != nil {
+			return nil, err
+		}
+		if latest == nil || latest.Status != model.PollOpen {
+			return nil, ErrPollClosed
+		}
+		return nil, ErrPollAlreadyVoted
+	}
+
+	view, err := s.view(ctx, poll, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyCounts(ctx, poll, view)
+	return view, nil
+}
+
+// ClosePoll 手动结束投票
+func (s *pollServiceImpl) ClosePoll(ctx context.Context, userID, pollID string) (*PollView, error) {
+	poll, err := s.findVisiblePoll(ctx, userID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.CreatorID != userID {
+		role, err := s.groupService.GetMemberRole(ctx, poll.GroupID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role != model.RoleOwner && role != model.RoleAdmin {
+			return nil, ErrNotGroupAdmin
+		}
+	}
+
+	ok, err := s.repo.Close(ctx, poll.PollID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrPollClosed
+	}
+	poll.Status = model.PollClosed
+
+	view, err := s.view(ctx, poll, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.announce(ctx, poll, view, userID)
+	return view, nil
+}
+
+// CloseDue 结束到期投票
+func (s *pollServiceImpl) CloseDue(ctx context.Context) (int, error) {
+	polls, err := s.repo.FindDue(ctx, time.Now(), s.config.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("find due polls error: %w", err)
+	}
+
+	closed := 0
+	for _, poll := range polls {
+		// 先抢占再发送结果，多节点同时扫描时每个投票只结束一次
+		ok, err := s.repo.Close(ctx, poll.PollID, time.Now())
+		if err != nil {
+			log.Printf("claim poll %s error: %v", poll.PollID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		poll.Status = model.PollClosed
+
+		view, err := s.view(ctx, poll, "")
+		if err != nil {
+			log.Printf("tally poll %s error: %v", poll.PollID, err)
+			continue
+		}
+		s.announce(ctx, poll, view, "")
+		closed++
+	}
+	return closed, nil
+}
+
+// Start 启动到期投票扫描
+func (s *pollServiceImpl) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.CloseDue(ctx); err != nil {
+				log.Printf("close due polls error: %v", err)
+			}
+		}
+	}
+}
+
+// announce 投票结束后更新投票消息并向群聊发送结果消息
+func (s *pollServiceImpl) announce(ctx context.Context, poll *model.Poll, view *PollView, closedBy string) {
+	s.notifyCounts(ctx, poll, view, model.PatchOperation{Op: model.PatchOpReplace, Path: "/content/closed", Value: true})
+
+	// 结果消息公开统计，匿名投票不包含投票人
+	results := view.Results
+	if poll.Anonymous {
+		results = make([]*model.PollOptionResult, len(view.Results))
+		for i, r := range view.Results {
+			results[i] = &model.PollOptionResult{Option: r.Option, Count: r.Count}
+		}
+	}
+
+	now := time.Now()
+	msg := &model.Message{
+		MessageID:      util.GenerateMessageID(),
+		Type:           model.MsgPollResult,
+		From:           "system",
+		To:             poll.GroupID,
+		GroupID:        poll.GroupID,
+		ConversationID: model.GetGroupChatConversationID(poll.GroupID),
+		Content: &model.PollResultContent{
+			PollID:        poll.PollID,
+			PollMessageID: poll.MessageID,
+			Question:      poll.Question,
+			Results:       results,
+			TotalVoters:   view.TotalVoters,
+			ClosedBy:      closedBy,
+			TemplateKey:   i18n.KeyPollResult,
+		},
+		Timestamp: now.UnixMilli(),
+		CreatedAt: now,
+	}
+	if err := s.messageService.SaveMessage(ctx, msg); err != nil {
+		log.Printf("save poll result %s error: %v", poll.PollID, err)
+		return
+	}
+	if err := s.repo.SetResultMessage(ctx, poll.PollID, msg.MessageID); err != nil {
+		log.Printf("record poll result message %s error: %v", poll.PollID, err)
+	}
+
+	if s.dispatcher == nil {
+		return
+	}
+	memberIDs, err := s.groupService.GetGroupMemberIDs(ctx, poll.GroupID)
+	if err != nil {
+		log.Printf("get group members for poll %s error: %v", poll.PollID, err)
+		return
+	}
+	if err := s.dispatcher.DispatchToUsers(ctx, memberIDs, msg); err != nil {
+		log.Printf("dispatch poll result %s error: %v", poll.PollID, err)
+	}
+}
+
+// notifyCounts 向群成员推送投票消息的最新统计
+func (s *pollServiceImpl) notifyCounts(ctx context.Context, poll *model.Poll, view *PollView, extra ...model.PatchOperation) {
+	if s.patchNotifier == nil {
+		return
+	}
+	counts := make([]int64, len(view.Results))
+	for i, r := range view.Results {
+		counts[i] = r.Count
+	}
+	ops := append([]model.PatchOperation{
+		{Op: model.PatchOpReplace, Path: "/content/counts", Value: counts},
+		{Op: model.PatchOpReplace, Path: "/content/total_voters", Value: view.TotalVoters},
+	}, extra...)
+
+	doc := &repository.MessageDocument{
+		MessageID:      poll.MessageID,
+		ConversationID: model.GetGroupChatConversationID(poll.GroupID),
+		GroupID:        poll.GroupID,
+	}
+	if err := s.patchNotifier.Notify(ctx, doc, ops...); err != nil {
+		log.Printf("notify poll %s counts error: %v", poll.PollID, err)
+	}
+}
+
+// view 统计投票结果，非匿名投票附带每个选项的投票人
+func (s *pollServiceImpl) view(ctx context.Context, poll *model.Poll, userID string) (*PollView, error) {
+	votes, err := s.repo.FindVotes(ctx, poll.PollID)
+	if err != nil {
+		return nil, fmt.Errorf("find poll votes error: %w", err)
+	}
+
+	results := make([]*model.PollOptionResult, len(poll.Options))
+	for i, option := range poll.Options {
+		results[i] = &model.PollOptionResult{Option: option}
+	}
+	view := &PollView{Poll: poll, Results: results, TotalVoters: int64(len(votes))}
+	for _, vote := range votes {
+		for _, idx := range vote.Options {
+			if idx < 0 || idx >= len(results) {
+				continue
+			}
+			results[idx].Count++
+			if !poll.Anonymous {
+				results[idx].Voters = append(results[idx].Voters, vote.UserID)
+			}
+		}
+		if vote.UserID == userID {
+			view.MyChoices = vote.Options
+		}
+	}
+	return view, nil
+}
+
+// findVisiblePoll 查询用户可见的投票（群成员），不可见时按不存在处理
+func (s *pollServiceImpl) findVisiblePoll(ctx context.Context, userID, pollID string) (*model.Poll, error) {
+	poll, err := s.repo.FindByID(ctx, pollID)
+	if err != nil {
+		return nil, err
+	}
+	if poll == nil {
+		return nil, ErrPollNotFound
+	}
+	isMember, err := s.groupService.IsMember(ctx, poll.GroupID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("check membership error: %w", err)
+	}
+	if !isMember {
+		return nil, ErrPollNotFound
+	}
+	return poll, nil
+}
+
+// validate 校验并规范化问题和选项
+func (s *pollServiceImpl) validate(req *CreatePollRequest) (string, []string, error) {
+	question := strings.TrimSpace(req.Question)
+	if question == "" {
+		return "", nil, ErrPollInvalid
+	}
+	if len(req.Options) < s.config.MinOptions || len(req.Options) > s.config.MaxOptions {
+		return "", nil, ErrPollInvalid
+	}
+
+	options := make([]string, 0, len(req.Options))
+	seen := make(map[string]bool, len(req.Options))
+	for _, option := range req.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || len([]rune(option)) > s.config.MaxOptionLength || seen[option] {
+			return "", nil, ErrPollInvalid
+		}
+		seen[option] = true
+		options = append(options, option)
+	}
+
+	if req.Deadline != nil {
+		now := time.Now()
+		if !req.Deadline.After(now) || req.Deadline.Sub(now) > s.config.MaxDuration {
+			return "", nil, ErrPollDeadlineInvalid
+		}
+	}
+	return question, options, nil
+}
+
+// normalizeChoices 校验选项下标并去重
+func (s *pollServiceImpl) normalizeChoices(poll *model.Poll, choices []int) ([]int, error) {
+	if len(choices) == 0 || (!poll.MultiChoice && len(choices) > 1) {
+		return nil, ErrPollInvalidChoice
+	}
+	seen := make(map[int]bool, len(choices))
+	result := make([]int, 0, len(choices))
+	for _, idx := range choices {
+		if idx < 0 || idx >= len(poll.Options) {
+			return nil, ErrPollInvalidChoice
+		}
+		if seen[idx] {
+			continue
+		}
+		seen[idx] = true
+		result = append(result, idx)
+	}
+	return result, nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// ErrPresenceTooManyUsers 单次查询在线状态的用户过多
+var ErrPresenceTooManyUsers = errors.New("too many users in presence query")
+
+// MaxPresenceQueryUsers 单次最多查询在线状态的用户数
+const MaxPresenceQueryUsers = 100
+
+// PresenceService 在线状态服务
+// 按用户的隐私设置（所有人/仅好友/不公开）返回在线状态及最近在线时间；被对方屏蔽时视为不可见
+type PresenceService interface {
+	// GetPresence 批量查询用户在线状态（重复及不存在的用户忽略，结果按请求顺序）
+	GetPresence(ctx context.Context, requesterID string, userIDs []string) ([]*model.PresenceInfo, error)
+}
+
+// presenceServiceImpl 在线状态服务实现
+type presenceServiceImpl struct {
+	presence      UserPresence
+	userRepo      repository.UserRepository
+	friendService FriendService
+}
+
+// NewPresenceService 创建在线状态服务
+func NewPresenceService(presence UserPresence, userRepo repository.UserRepository, friendService FriendService) PresenceService {
+	return &presenceServiceImpl{
+		presence:      presence,
+		userRepo:      userRepo,
+		friendService: friendService,
+	}
+}
+
+// GetPresence 批量查询用户在线状态
+func (s *presenceServiceImpl) GetPresence(ctx context.Context, requesterID string, userIDs []string) ([]*model.PresenceInfo, error) {
+	seen := make(map[string]bool, len(userIDs))
+	ids := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > MaxPresenceQueryUsers {
+		return nil, ErrPresenceTooManyUsers
+	}
+	if len(ids) == 0 {
+		return []*model.PresenceInfo{}, nil
+	}
+
+	users, err := s.userRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("find users error: %w", err)
+	}
+	byID := make(map[string]*model.User, len(users))
+	for _, user := range users {
+		byID[user.UserID] = user
+	}
+
+	result := make([]*model.PresenceInfo, 0, len(users))
+	for _, id := range ids {
+		user, ok := byID[id]
+		if !ok || user.Status == model.UserStatusDeleted {
+			continue
+		}
+		info := &model.PresenceInfo{UserID: id}
+		visible, err := s.visible(ctx, requesterID, user)
+		if err != nil {
+			return nil, err
+		}
+		if visible {
+			if err := s.fill(ctx, info); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// visible 请求者是否可以查看该用户的在线状态
+func (s *presenceServiceImpl) visible(ctx context.Context, requesterID string, user *model.User) (bool, error) {
+	if requesterID == user.UserID {
+		return true, nil
+	}
+	if user.PresenceVisibility == model.PresenceNobody {
+		return false, nil
+	}
+	if s.friendService == nil {
+		return user.PresenceVisibility != model.PresenceFriends, nil
+	}
+
+	blocked, err := s.friendService.IsBlocked(ctx, user.UserID, requesterID)
+	if err != nil {
+		return false, fmt.Errorf("check block error: %w", err)
+	}
+	if blocked {
+		return false, nil
+	}
+	if user.PresenceVisibility == model.PresenceFriends {
+		isFriend, err := s.friendService.IsFriend(ctx, user.UserID, requesterID)
+		if err != nil {
+			return false, fmt.Errorf("check friend error: %w", err)
+		}
+		return isFriend, nil
+	}
+	return true, nil
+}
+
+// fill 填充在线状态及最近在线时间
+func (s *presenceServiceImpl) fill(ctx context.Context, info *model.PresenceInfo) error {
+	online, err := s.presence.IsUserOnline(ctx, info.UserID)
+	if err != nil {
+		return fmt.Errorf("check online error: %w", err)
+	}
+	info.Visible = true
+	info.Online = online
+	if online {
+		return nil
+	}
+
+	lastSeen, err := s.presence.LastSeen(ctx, info.UserID)
+	if err != nil {
+		return fmt.Errorf("get last seen error: %w", err)
+	}
+	if !lastSeen.IsZero() {
+		info.LastSeen = lastSeen.UnixMilli()
+	}
+	return nil
+}
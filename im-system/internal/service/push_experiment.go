@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 推送实验错误定义
+var (
+	ErrPushExperimentNotFound = errors.New("push experiment not found")
+	ErrPushVariantInvalid     = errors.New("push experiment variant must be control or treatment")
+)
+
+const (
+	pushExperimentsKey       = "im:push:experiments"       // 推送文案实验（HASH，field为实验Key）
+	pushExperimentStatsKey   = "im:push:experiment:stats:" // 实验指标（HASH，field为 分组:事件）
+	pushSentPrefix           = "im:push:sent:"             // 实验推送记录（HASH: user_id/experiment/variant，回调事件去重）
+	pushSentTTL              = 7 * 24 * time.Hour
+	pushExperimentsCacheTTL  = 5 * time.Second
+	pushExperimentStatsSince = "_since"
+)
+
+// 推送实验指标事件
+const (
+	pushEventAssigned  = "assigned"  // 分配文案
+	pushEventDelivered = "delivered" // 至少一台设备推送成功
+	pushEventFailed    = "failed"    // 全部设备推送失败（每次重试分别计数）
+)
+
+// PushVariantStats 推送实验单个分组的指标
+type PushVariantStats struct {
+	Assigned  int64   `json:"assigned"`
+	Delivered int64   `json:"delivered"`
+	Failed    int64   `json:"failed"`
+	Opened    int64   `json:"opened"`
+	Acked     int64   `json:"acked"`
+	OpenRate  float64 `json:"open_rate"` // 打开数 / 送达数
+	AckRate   float64 `json:"ack_rate"`  // 确认数 / 送达数
+}
+
+// PushExperimentStats 推送实验各分组指标
+type PushExperimentStats struct {
+	Experiment *model.PushExperiment        `json:"experiment"`
+	Since      time.Time                    `json:"since"`
+	Variants   map[string]*PushVariantStats `json:"variants"`
+}
+
+// PushExperimentService 推送文案A/B实验服务
+// 用户按同Key功能开关的分组使用对应文案，推送记录送达结果，客户端回调打开/确认事件
+type PushExperimentService interface {
+	// ListExperiments 获取全部实验
+	ListExperiments(ctx context.Context) ([]*model.PushExperiment, error)
+	// SetExperiment 创建或更新实验，新建实验时重置指标
+	SetExperiment(ctx context.Context, key, operatorID string, req *model.SetPushExperimentRequest) (*model.PushExperiment, error)
+	// DeleteExperiment 删除实验及其指标
+	DeleteExperiment(ctx context.Context, key string) error
+
+	// Apply 为用户应用实验文案（按Key顺序取第一个对用户生效的实验），返回带实验标记的通知副本，未命中时返回原通知
+	Apply(ctx context.Context, userID string, notification *model.PushNotification) *model.PushNotification
+	// RecordDelivery 记录实验推送的送达结果
+	RecordDelivery(ctx context.Context, notification *model.PushNotification, delivered bool)
+	// RecordCallback 记录客户端打开/确认通知，同一推送的同一事件只计一次，非实验推送忽略
+	RecordCallback(ctx context.Context, userID string, req *model.PushOpenedRequest) error
+
+	// GetStats 获取实验各分组指标
+	GetStats(ctx context.Context, key string) (*PushExperimentStats, error)
+	// ListStats 获取全部实验的指标
+	ListStats(ctx context.Context) ([]*PushExperimentStats, error)
+}
+
+// pushExperimentServiceImpl 推送实验服务实现
+type pushExperimentServiceImpl struct {
+	redis *redis.Client
+	flags FeatureFlagService
+
+	mu        sync.RWMutex
+	cached    []*model.PushExperiment
+	expiresAt time.Time
+}
+
+// NewPushExperimentService 创建推送实验服务
+func NewPushExperimentService(redisClient *redis.Client, flags FeatureFlagService) PushExperimentService {
+	return &pushExperimentServiceImpl{
+		redis: redisClient,
+		flags: flags,
+	}
+}
+
+// ListExperiments 获取全部实验（按Key排序）
+func (s *pushExperimentServiceImpl) ListExperiments(ctx context.Context) ([]*model.PushExperiment, error) {
+	values, err := s.redis.HGetAll(ctx, pushExperimentsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get push experiments error: %w", err)
+	}
+
+	result := make([]*model.PushExperiment, 0, len(values))
+	for key, data := range values {
+		var exp model.PushExperiment
+		if err := json.Unmarshal([]byte(data), &exp); err != nil {
+			log.Printf("unmarshal push experiment %s error: %v", key, err)
+			continue
+		}
+		result = append(result, &exp)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result, nil
+}
+
+// SetExperiment 创建或更新实验
+func (s *pushExperimentServiceImpl) SetExperiment(ctx context.Context, key, operatorID string, req *model.SetPushExperimentRequest) (*model.PushExperiment, error) {
+	if !flagKeyPattern.MatchString(key) {
+		return nil, ErrFlagInvalidKey
+	}
+	for variant := range req.Variants {
+		if variant != model.CohortControl && variant != model.CohortTreatment {
+			return nil, ErrPushVariantInvalid
+		}
+	}
+
+	exp := &model.PushExperiment{
+		Key:         key,
+		Description: req.Description,
+		Variants:    req.Variants,
+		UpdatedBy:   operatorID,
+		UpdatedAt:   time.Now(),
+	}
+	data, err := json.Marshal(exp)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.redis.HSet(ctx, pushExperimentsKey, key, data).Result()
+	if err != nil {
+		return nil, fmt.Errorf("set push experiment error: %w", err)
+	}
+	if created > 0 {
+		pipe := s.redis.TxPipeline()
+		pipe.Del(ctx, pushExperimentStatsKey+key)
+		pipe.HSet(ctx, pushExperimentStatsKey+key, pushExperimentStatsSince, time.Now().Unix())
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("reset push experiment stats error: %w", err)
+		}
+	}
+	s.invalidate()
+	return exp, nil
+}
+
+// DeleteExperiment 删除实验
+func (s *pushExperimentServiceImpl) DeleteExperiment(ctx context.Context, key string) error {
+	deleted, err := s.redis.HDel(ctx, pushExperimentsKey, key).Result()
+	if err != nil {
+		return fmt.Errorf("delete push experiment error: %w", err)
+	}
+	if deleted == 0 {
+		return ErrPushExperimentNotFound
+	}
+	s.redis.Del(ctx, pushExperimentStatsKey+key)
+	s.invalidate()
+	return nil
+}
+
+// Apply 为用户应用实验文案
+func (s *pushExperimentServiceImpl) Apply(ctx context.Context, userID string, notification *model.PushNotification) *model.PushNotification {
+	if notification == nil {
+		return notification
+	}
+	experiments := s.cachedExperiments(ctx)
+	if len(experiments) == 0 {
+		return notification
+	}
+
+	cohorts := s.flags.Cohorts(ctx, userID)
+	for _, exp := range experiments {
+		variant, ok := cohorts[exp.Key]
+		if !ok {
+			continue
+		}
+
+		// PushToUsers 多个用户共用同一通知，按用户复制后修改
+		assigned := *notification
+		assigned.Data = make(map[string]string, len(notification.Data)+3)
+		for k, v := range notification.Data {
+			assigned.Data[k] = v
+		}
+		if tmpl := exp.Variants[variant]; tmpl != nil {
+			assigned.Title, assigned.Body = tmpl.Render(notification)
+		}
+
+		pushID := util.GenerateUUID()
+		assigned.Data[model.PushDataID] = pushID
+		assigned.Data[model.PushDataExperiment] = exp.Key
+		assigned.Data[model.PushDataVariant] = variant
+
+		pipe := s.redis.Pipeline()
+		pipe.HSet(ctx, pushSentPrefix+pushID, "user_id", userID, "experiment", exp.Key, "variant", variant)
+		pipe.Expire(ctx, pushSentPrefix+pushID, pushSentTTL)
+		pipe.HIncrBy(ctx, pushExperimentStatsKey+exp.Key, variant+":"+pushEventAssigned, 1)
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Printf("record push experiment %s assignment error: %v", exp.Key, err)
+		}
+		return &assigned
+	}
+	return notification
+}
+
+// RecordDelivery 记录实验推送的送达结果
+func (s *pushExperimentServiceImpl) RecordDelivery(ctx context.Context, notification *model.PushNotification, delivered bool) {
+	if notification == nil || notification.Data[model.PushDataExperiment] == "" {
+		return
+	}
+
+	event := pushEventFailed
+	if delivered {
+		event = pushEventDelivered
+	}
+	field := notification.Data[model.PushDataVariant] + ":" + event
+	if err := s.redis.HIncrBy(ctx, pushExperimentStatsKey+notification.Data[model.PushDataExperiment], field, 1).Err(); err != nil {
+		log.Printf("record push experiment delivery error: %v", err)
+	}
+}
+
+// RecordCallback 记录客户端打开/确认通知
+func (s *pushExperimentServiceImpl) RecordCallback(ctx context.Context, userID string, req *model.PushOpenedRequest) error {
+	event := req.Event
+	if event == "" {
+		event = model.PushEventOpened
+	}
+
+	sentKey := pushSentPrefix + req.PushID
+	sent, err := s.redis.HGetAll(ctx, sentKey).Result()
+	if err != nil {
+		return fmt.Errorf("get push record error: %w", err)
+	}
+	if len(sent) == 0 {
+		return nil // 非实验推送或记录已过期
+	}
+	if sent["user_id"] != userID {
+		return ErrPermissionDeny
+	}
+
+	first, err := s.redis.HSetNX(ctx, sentKey, event, time.Now().Unix()).Result()
+	if err != nil {
+		return fmt.Errorf("record push callback error: %w", err)
+	}
+	if !first {
+		return nil
+	}
+	return s.redis.HIncrBy(ctx, pushExperimentStatsKey+sent["experiment"], sent["variant"]+":"+event, 1).Err()
+}
+
+// GetStats 获取实验各分组指标
+func (s *pushExperimentServiceImpl) GetStats(ctx context.Context, key string) (*PushExperimentStats, error) {
+	data, err := s.redis.HGet(ctx, pushExperimentsKey, key).Result()
+	if err == redis.Nil {
+		return nil, ErrPushExperimentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get push experiment error: %w", err)
+	}
+
+	var exp model.PushExperiment
+	if err := json.Unmarshal([]byte(data), &exp); err != nil {
+		return nil, err
+	}
+	return s.stats(ctx, &exp)
+}
+
+// ListStats 获取全部实验的指标
+func (s *pushExperimentServiceImpl) ListStats(ctx context.Context) ([]*PushExperimentStats, error) {
+	experiments, err := s.ListExperiments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*PushExperimentStats, 0, len(experiments))
+	for _, exp := range experiments {
+		stats, err := s.stats(ctx, exp)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, stats)
+	}
+	return result, nil
+}
+
+// stats 汇总实验指标
+func (s *pushExperimentServiceImpl) stats(ctx context.Context, exp *model.PushExperiment) (*PushExperimentStats, error) {
+	values, err := s.redis.HGetAll(ctx, pushExperimentStatsKey+exp.Key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get push experiment stats error: %w", err)
+	}
+
+	result := &PushExperimentStats{
+		Experiment: exp,
+		Variants: map[string]*PushVariantStats{
+			model.CohortControl:   {},
+			model.CohortTreatment: {},
+		},
+	}
+	for field, raw := range values {
+		value, _ := strconv.ParseInt(raw, 10, 64)
+		if field == pushExperimentStatsSince {
+			result.Since = time.Unix(value, 0)
+			continue
+		}
+		variant, event, ok := strings.Cut(field, ":")
+		stats := result.Variants[variant]
+		if !ok || stats == nil {
+			continue
+		}
+		switch event {
+		case pushEventAssigned:
+			stats.Assigned = value
+		case pushEventDelivered:
+			stats.Delivered = value
+		case pushEventFailed:
+			stats.Failed = value
+		case model.PushEventOpened:
+			stats.Opened = value
+		case model.PushEventAcked:
+			stats.Acked = value
+		}
+	}
+
+	for _, stats := range result.Variants {
+		if stats.Delivered > 0 {
+			stats.OpenRate = float64(stats.Opened) / float64(stats.Delivered)
+			stats.AckRate = float64(stats.Acked) / float64(stats.Delivered)
+		}
+	}
+	return result, nil
+}
+
+// cachedExperiments 读取本地缓存的实验，过期后从Redis刷新（Redis异常时不应用实验）
+func (s *pushExperimentServiceImpl) cachedExperiments(ctx context.Context) []*model.PushExperiment {
+	s.mu.RLock()
+	cached, valid := s.cached, time.Now().Before(s.expiresAt)
+	s.mu.RUnlock()
+	if valid {
+		return cached
+	}
+
+	experiments, err := s.ListExperiments(ctx)
+	if err != nil {
+		log.Printf("load push experiments error: %v", err)
+		return nil
+	}
+	s.mu.Lock()
+	s.cached = experiments
+	s.expiresAt = time.Now().Add(pushExperimentsCacheTTL)
+	s.mu.Unlock()
+	return experiments
+}
+
+// invalidate 清除本地缓存（其他节点在缓存过期后生效）
+func (s *pushExperimentServiceImpl) invalidate() {
+	s.mu.Lock()
+	s.expiresAt = time.Time{}
+	s.mu.Unlock()
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+)
+
+// pushMergeCollapseKey 合并通知的折叠键，设备上新的合并通知替换旧的
+const pushMergeCollapseKey = "new_messages"
+
+// pushMergeBuffer 用户合并窗口内缓冲的推送
+type pushMergeBuffer struct {
+	notifications []*model.PushNotification
+	timer         *time.Timer
+}
+
+// mergeable 推送是否参与合并：只合并新消息通知，提及通知及提醒、摘要等其他通知立即推送
+func (s *pushServiceImpl) mergeable(notification *model.PushNotification) bool {
+	if !s.config.MergeEnabled || s.config.MergeWindow <= 0 || notification == nil {
+		return false
+	}
+	return notification.Data["type"] == "new_message" && notification.Category != "MENTION"
+}
+
+// bufferPush 将推送加入用户的合并缓冲，窗口从第一条推送开始计时，到期后合并为一条通知推送
+func (s *pushServiceImpl) bufferPush(userID string, notification *model.PushNotification) {
+	s.mergeMu.Lock()
+	defer s.mergeMu.Unlock()
+
+	buffer, ok := s.merging[userID]
+	if !ok {
+		created := &pushMergeBuffer{}
+		created.timer = time.AfterFunc(s.config.MergeWindow, func() {
+			s.flushMerged(context.Background(), userID, created)
+		})
+		buffer = created
+		s.merging[userID] = buffer
+	}
+	buffer.notifications = append(buffer.notifications, notification)
+}
+
+// takeMerged 取出并清空用户的合并缓冲；expected 不为空时只取出该缓冲（到期的定时器不会取走之后新建的缓冲）
+func (s *pushServiceImpl) takeMerged(userID string, expected *pushMergeBuffer) []*model.PushNotification {
+	s.mergeMu.Lock()
+	defer s.mergeMu.Unlock()
+
+	buffer, ok := s.merging[userID]
+	if !ok || (expected != nil && buffer != expected) {
+		return nil
+	}
+	buffer.timer.Stop()
+	delete(s.merging, userID)
+	return buffer.notifications
+}
+
+// flushMerged 合并窗口到期：推送合并后的通知（已撤回消息的推送不计入）
+func (s *pushServiceImpl) flushMerged(ctx context.Context, userID string, expected *pushMergeBuffer) {
+	notifications := s.takeMerged(userID, expected)
+	pending := make([]*model.PushNotification, 0, len(notifications))
+	for _, notification := range notifications {
+		if !s.isCancelled(&PushTask{Notification: notification}) {
+			pending = append(pending, notification)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	if err := s.dispatchPush(ctx, userID, mergeNotifications(pending)); err != nil {
+		log.Printf("Push merged notification to user %s error: %v", userID, err)
+	}
+}
+
+// UserOnline 用户上线：丢弃合并窗口中尚未推送的通知（消息已通过长连接送达）
+func (s *pushServiceImpl) UserOnline(userID string) {
+	s.takeMerged(userID, nil)
+}
+
+// flushAllMerged 立即推送所有用户的合并缓冲（停止推送服务时调用）
+func (s *pushServiceImpl) flushAllMerged(ctx context.Context) {
+	s.mergeMu.Lock()
+	userIDs := make([]string, 0, len(s.merging))
+	for userID := range s.merging {
+		userIDs = append(userIDs, userID)
+	}
+	s.mergeMu.Unlock()
+
+	for _, userID := range userIDs {
+		s.flushMerged(ctx, userID, nil)
+	}
+}
+
+// mergeNotifications 将窗口内的多条推送合并为一条"N 条新消息"通知，只有一条时原样返回
+func mergeNotifications(notifications []*model.PushNotification) *model.PushNotification {
+	if len(notifications) == 1 {
+		return notifications[0]
+	}
+
+	count := 0
+	conversationID := notifications[0].Data["conversation_id"]
+	for _, notification := range notifications {
+		if notification.Badge > 0 {
+			count += notification.Badge
+		} else {
+			count++
+		}
+		if notification.Data["conversation_id"] != conversationID {
+			conversationID = ""
+		}
+	}
+
+	merged := &model.PushNotification{
+		Title:       "您有新消息",
+		Body:        fmt.Sprintf("您有 %d 条未读消息", count),
+		Badge:       count,
+		Sound:       "default",
+		CollapseKey: pushMergeCollapseKey,
+		ThreadID:    conversationID,
+		Priority:    model.PushPriorityHigh,
+		Data: map[string]string{
+			"type":  "new_message",
+			"count": fmt.Sprintf("%d", count),
+		},
+	}
+	// 都来自同一会话时保留会话ID，客户端点击后直接打开该会话
+	if conversationID != "" {
+		merged.Data["conversation_id"] = conversationID
+	}
+	return merged
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/tracing"
+)
+
+// ErrDoNotDisturbInvalid 免打扰时段设置错误
+var ErrDoNotDisturbInvalid = errors.New("invalid do-not-disturb hours")
+
+// offlinePush 待推送的离线消息
+type offlinePush struct {
+	userID  string
+	message *model.OfflineMessage
+	span    trace.SpanContext // 保存离线消息时的链路，推送Worker延续该链路
+}
+
+// SetUserRepository 设置用户仓库
+func (s *pushServiceImpl) SetUserRepository(users repository.UserRepository) {
+	s.users = users
+}
+
+// NotifyOffline 离线消息保存后加入推送处理队列，由推送Worker异步处理，不阻塞消息投递；队列已满时放弃推送
+func (s *pushServiceImpl) NotifyOffline(ctx context.Context, userID string, message *model.OfflineMessage) {
+	select {
+	case s.offlineQueue <- &offlinePush{userID: userID, message: message, span: trace.SpanContextFromContext(ctx)}:
+	default:
+		log.Printf("Push offline queue full, skip push of message %s to user %s", message.MessageID, userID)
+	}
+}
+
+// pushOffline 推送一条离线消息：免打扰会话按提及规则过滤，处理后标记为已推送（推送开关、免打扰时段由 dispatchPush 检查）
+func (s *pushServiceImpl) pushOffline(ctx context.Context, userID string, message *model.OfflineMessage) {
+	var err error
+	ctx, span := tracing.Start(ctx, "push.offline", trace.WithAttributes(
+		tracing.AttrMessageID.String(message.MessageID),
+		tracing.AttrUserID.String(userID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	pushable, mention := s.filterMutedMessages(ctx, userID, []*model.OfflineMessage{message})
+	if len(pushable) > 0 {
+		notification := s.buildNotification(pushable)
+		if mention != nil {
+			tagMention(notification, mention)
+		}
+		if err = s.PushToUser(ctx, userID, notification); err != nil {
+			log.Printf("Push offline message %s to user %s error: %v", message.MessageID, userID, err)
+			return
+		}
+	}
+
+	if s.offlineService != nil {
+		if err := s.offlineService.MarkAsPushed(ctx, []string{message.MessageID}); err != nil {
+			log.Printf("Mark as pushed error: %v", err)
+		}
+	}
+}
+
+// pushTarget 查询推送目标用户的通知设置，未设置用户仓库或查询失败时返回 nil（按默认设置推送）
+func (s *pushServiceImpl) pushTarget(ctx context.Context, userID string) *model.User {
+	if s.users == nil {
+		return nil
+	}
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		log.Printf("Find user for push error: %v", err)
+		return nil
+	}
+	return user
+}
+
+// hideNotificationPreview 隐藏通知内容（用户关闭推送预览），合并通知只包含未读数，原样保留
+func hideNotificationPreview(notification *model.PushNotification) *model.PushNotification {
+	if notification.CollapseKey == pushMergeCollapseKey {
+		return notification
+	}
+	hidden := *notification
+	hidden.Title = "新消息"
+	hidden.Body = "您收到一条新消息"
+	return &hidden
+}
+
+// ValidateDoNotDisturb 校验免打扰时段：开始、结束时间为 HH:MM 且同时设置或同时为空（为空表示关闭），时区为 IANA 时区
+func ValidateDoNotDisturb(setting *model.DoNotDisturbSetting) error {
+	start := strings.TrimSpace(setting.Start)
+	end := strings.TrimSpace(setting.End)
+	if start == "" && end == "" {
+		return nil
+	}
+	if _, _, ok := parseDigestTime(start); !ok {
+		return ErrDoNotDisturbInvalid
+	}
+	if _, _, ok := parseDigestTime(end); !ok {
+		return ErrDoNotDisturbInvalid
+	}
+	if _, err := time.LoadLocation(setting.Timezone); err != nil {
+		return ErrDoNotDisturbInvalid
+	}
+	return nil
+}
+
+// InDoNotDisturb 判断时间是否在用户的免打扰时段内（按用户时区的当地时间，结束早于开始表示跨午夜，两者相同表示全天）
+func InDoNotDisturb(user *model.User, now time.Time) bool {
+	startHour, startMinute, ok := parseDigestTime(user.DNDStart)
+	if !ok {
+		return false
+	}
+	endHour, endMinute, ok := parseDigestTime(user.DNDEnd)
+	if !ok {
+		return false
+	}
+	loc, err := time.LoadLocation(user.DNDTimezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	local := now.In(loc)
+	current := local.Hour()*60 + local.Minute()
+	start := startHour*60 + startMinute
+	end := endHour*60 + endMinute
+	switch {
+	case start == end:
+		return true
+	case start < end:
+		return current >= start && current < end
+	default:
+		return current >= start || current < end
+	}
+}
@@ -10,9 +10,13 @@ import (
 	"time"
 
 	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/tracing"
 	"github.com/d60-lab/im-system/pkg/util"
+	"github.com/d60-lab/im-system/pkg/webpush"
 	"github.com/go-redis/redis/v8"
-	"gorm.io/gorm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // 推送服务错误定义
@@ -44,8 +48,32 @@ type PushService interface {
 
 	// 统计
 	GetPushStats(ctx context.Context) (*PushStats, error)
+
+	// SetConversationRepository 设置会话仓库，用于离线消息推送时判断会话免打扰
+	SetConversationRepository(conversations repository.ConversationRepository)
+
+	// CancelMessage 取消消息的待执行推送（队列中尚未执行或等待重试的单条消息推送任务）
+	CancelMessage(messageID string)
+
+	// SetExperiments 设置推送文案实验服务，按用户分组替换标题/正文并统计送达结果
+	SetExperiments(experiments PushExperimentService)
+
+	// UserOnline 用户上线时调用，丢弃合并窗口中尚未推送的新消息通知
+	UserOnline(userID string)
+
+	// SetUserRepository 设置用户仓库，用于判断用户的免打扰时段
+	SetUserRepository(users repository.UserRepository)
+
+	// NotifyOffline 离线消息保存后触发推送（实现 OfflinePushNotifier）
+	NotifyOffline(ctx context.Context, userID string, message *model.OfflineMessage)
+
+	// SetWebPushClient 设置 Web Push 客户端，用于推送 web 平台（浏览器）设备
+	SetWebPushClient(client WebPushClient)
 }
 
+// pushCancelTTL 已取消消息的保留时间，需覆盖推送任务的最长排队和重试时间
+const pushCancelTTL = 10 * time.Minute
+
 // APNsClient APNs客户端接口
 type APNsClient interface {
 	Push(ctx context.Context, deviceToken string, notification *model.PushNotification) error
@@ -63,10 +91,15 @@ type PushConfig struct {
 	BatchSize       int           // 批量推送大小
 	MaxRetries      int           // 最大重试次数
 	RetryDelay      time.Duration // 重试延迟
-	MergeEnabled    bool          // 是否启用推送合并
-	MergeWindow     time.Duration // 合并窗口
+	MergeEnabled    bool          // 是否启用推送合并（新消息通知按用户在窗口内合并为一条）
+	MergeWindow     time.Duration // 合并窗口，从窗口内第一条推送开始计时
 	QueueSize       int           // 队列大小
 	RateLimitPerSec int           // 每秒限制推送数
+
+	// MentionBypassMute 会话免打扰时，@自己或回复自己的离线消息仍然推送
+	MentionBypassMute bool
+	// DynamicMentionBypassMute 会话免打扰时，通过 @群角色或 @提及组提及自己的离线消息仍然推送
+	DynamicMentionBypassMute bool
 }
 
 // DefaultPushConfig 默认推送配置
@@ -80,6 +113,9 @@ func DefaultPushConfig() *PushConfig {
 		MergeWindow:     5 * time.Second,
 		QueueSize:       10000,
 		RateLimitPerSec: 1000,
+
+		MentionBypassMute:        true,
+		DynamicMentionBypassMute: true,
 	}
 }
 
@@ -91,6 +127,7 @@ type PushStats struct {
 	PendingCount  int64     `json:"pending_count"`
 	IOSCount      int64     `json:"ios_count"`
 	AndroidCount  int64     `json:"android_count"`
+	WebCount      int64     `json:"web_count"`
 	LastPushTime  time.Time `json:"last_push_time"`
 	AvgLatencyMs  float64   `json:"avg_latency_ms"`
 	InvalidTokens int64     `json:"invalid_tokens"`
@@ -99,17 +136,32 @@ type PushStats struct {
 // pushServiceImpl 推送服务实现
 type pushServiceImpl struct {
 	config         *PushConfig
-	db             *gorm.DB
+	devices        repository.DeviceRepository
 	redis          *redis.Client
 	apnsClient     APNsClient
 	fcmClient      FCMClient
+	webPushClient  WebPushClient
 	offlineService PushOfflineService
+	conversations  repository.ConversationRepository
+	experiments    PushExperimentService
+	users          repository.UserRepository
 
 	// 推送队列
 	pushQueue chan *PushTask
 	stopChan  chan struct{}
 	wg        sync.WaitGroup
 
+	// 待推送的离线消息队列
+	offlineQueue chan *offlinePush
+
+	// 已取消的消息（消息ID -> 取消时间），执行推送任务前检查
+	cancelled   map[string]time.Time
+	cancelledMu sync.Mutex
+
+	// 合并窗口内缓冲的推送（用户ID -> 缓冲）
+	merging map[string]*pushMergeBuffer
+	mergeMu sync.Mutex
+
 	// 统计
 	stats   *PushStats
 	statsMu sync.RWMutex
@@ -128,12 +180,14 @@ type PushTask struct {
 	Retries      int
 	CreatedAt    time.Time
 	ScheduledAt  time.Time
+
+	SpanContext trace.SpanContext // 创建任务时的链路，队列中执行时延续该链路
 }
 
 // NewPushService 创建推送服务
 func NewPushService(
 	config *PushConfig,
-	db *gorm.DB,
+	devices repository.DeviceRepository,
 	redisClient *redis.Client,
 	apnsClient APNsClient,
 	fcmClient FCMClient,
@@ -145,57 +199,69 @@ func NewPushService(
 
 	return &pushServiceImpl{
 		config:         config,
-		db:             db,
+		devices:        devices,
 		redis:          redisClient,
 		apnsClient:     apnsClient,
 		fcmClient:      fcmClient,
 		offlineService: offlineService,
 		pushQueue:      make(chan *PushTask, config.QueueSize),
+		offlineQueue:   make(chan *offlinePush, config.QueueSize),
 		stopChan:       make(chan struct{}),
+		cancelled:      make(map[string]time.Time),
+		merging:        make(map[string]*pushMergeBuffer),
 		stats:          &PushStats{},
 	}
 }
 
+// SetConversationRepository 设置会话仓库
+func (s *pushServiceImpl) SetConversationRepository(conversations repository.ConversationRepository) {
+	s.conversations = conversations
+}
+
+// SetExperiments 设置推送文案实验服务
+func (s *pushServiceImpl) SetExperiments(experiments PushExperimentService) {
+	s.experiments = experiments
+}
+
 // RegisterDevice 注册设备
 func (s *pushServiceImpl) RegisterDevice(ctx context.Context, userID string, req *model.RegisterDeviceRequest) error {
-	if req.DeviceToken == "" {
-		return ErrInvalidToken
-	}
-
 	// 验证平台
 	if req.Platform != model.PlatformIOS && req.Platform != model.PlatformAndroid && req.Platform != model.PlatformWeb {
 		return ErrInvalidPlatform
 	}
 
+	deviceToken := req.DeviceToken
+	subscription := ""
+	if req.Platform == model.PlatformWeb {
+		var err error
+		if subscription, deviceToken, err = webPushDevice(req); err != nil {
+			return err
+		}
+	}
+	if deviceToken == "" {
+		return ErrInvalidToken
+	}
+
 	device := &model.Device{
-		UserID:      userID,
-		DeviceToken: req.DeviceToken,
-		Platform:    req.Platform,
-		AppVersion:  req.AppVersion,
-		DeviceInfo:  req.DeviceInfo,
-		PushEnabled: true,
-		UpdatedAt:   time.Now(),
-		CreatedAt:   time.Now(),
+		UserID:       userID,
+		DeviceToken:  deviceToken,
+		Platform:     req.Platform,
+		AppVersion:   req.AppVersion,
+		DeviceInfo:   req.DeviceInfo,
+		PushEnabled:  true,
+		UpdatedAt:    time.Now(),
+		CreatedAt:    time.Now(),
+		Subscription: subscription,
 	}
 
 	// 使用 upsert 操作
-	result := s.db.WithContext(ctx).Where("device_token = ?", req.DeviceToken).
-		Assign(model.Device{
-			UserID:     userID,
-			Platform:   req.Platform,
-			AppVersion: req.AppVersion,
-			DeviceInfo: req.DeviceInfo,
-			UpdatedAt:  time.Now(),
-		}).
-		FirstOrCreate(device)
-
-	if result.Error != nil {
-		return fmt.Errorf("register device error: %w", result.Error)
+	if err := s.devices.Upsert(ctx, device); err != nil {
+		return fmt.Errorf("register device error: %w", err)
 	}
 
 	// 缓存到Redis
 	deviceKey := fmt.Sprintf("device:%s", userID)
-	s.redis.SAdd(ctx, deviceKey, req.DeviceToken)
+	s.redis.SAdd(ctx, deviceKey, deviceToken)
 	s.redis.Expire(ctx, deviceKey, 30*24*time.Hour)
 
 	return nil
@@ -203,12 +269,8 @@ func (s *pushServiceImpl) RegisterDevice(ctx context.Context, userID string, req
 
 // UnregisterDevice 注销设备
 func (s *pushServiceImpl) UnregisterDevice(ctx context.Context, userID, deviceToken string) error {
-	result := s.db.WithContext(ctx).
-		Where("user_id = ? AND device_token = ?", userID, deviceToken).
-		Delete(&model.Device{})
-
-	if result.Error != nil {
-		return fmt.Errorf("unregister device error: %w", result.Error)
+	if err := s.devices.Delete(ctx, userID, deviceToken); err != nil {
+		return fmt.Errorf("unregister device error: %w", err)
 	}
 
 	// 从Redis删除
@@ -220,11 +282,8 @@ func (s *pushServiceImpl) UnregisterDevice(ctx context.Context, userID, deviceTo
 
 // GetUserDevices 获取用户设备列表
 func (s *pushServiceImpl) GetUserDevices(ctx context.Context, userID string) ([]*model.Device, error) {
-	var devices []*model.Device
-
-	if err := s.db.WithContext(ctx).
-		Where("user_id = ? AND push_enabled = ?", userID, true).
-		Find(&devices).Error; err != nil {
+	devices, err := s.devices.FindPushEnabled(ctx, userID)
+	if err != nil {
 		return nil, fmt.Errorf("get user devices error: %w", err)
 	}
 
@@ -233,23 +292,43 @@ func (s *pushServiceImpl) GetUserDevices(ctx context.Context, userID string) ([]
 
 // UpdateDeviceToken 更新设备Token
 func (s *pushServiceImpl) UpdateDeviceToken(ctx context.Context, oldToken, newToken string) error {
-	result := s.db.WithContext(ctx).Model(&model.Device{}).
-		Where("device_token = ?", oldToken).
-		Update("device_token", newToken)
-
-	if result.Error != nil {
-		return fmt.Errorf("update device token error: %w", result.Error)
+	affected, err := s.devices.UpdateToken(ctx, oldToken, newToken)
+	if err != nil {
+		return fmt.Errorf("update device token error: %w", err)
 	}
 
-	if result.RowsAffected == 0 {
+	if affected == 0 {
 		return ErrDeviceNotFound
 	}
 
 	return nil
 }
 
-// PushToUser 推送给单个用户
+// PushToUser 推送给单个用户，启用合并时新消息通知先进入合并窗口
 func (s *pushServiceImpl) PushToUser(ctx context.Context, userID string, notification *model.PushNotification) error {
+	if s.mergeable(notification) {
+		s.bufferPush(userID, notification)
+		return nil
+	}
+	return s.dispatchPush(ctx, userID, notification)
+}
+
+// dispatchPush 按用户通知设置查询设备并创建推送任务：关闭推送或处于免打扰时段时不推送，关闭预览时隐藏通知内容
+func (s *pushServiceImpl) dispatchPush(ctx context.Context, userID string, notification *model.PushNotification) (err error) {
+	ctx, span := tracing.Start(ctx, "push.dispatch", trace.WithAttributes(
+		tracing.AttrMessageID.String(notification.MessageID),
+		tracing.AttrUserID.String(userID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	hidePreview := false
+	if user := s.pushTarget(ctx, userID); user != nil {
+		if !user.PushEnabled || InDoNotDisturb(user, time.Now()) {
+			return nil
+		}
+		hidePreview = !user.PushPreview
+	}
+
 	devices, err := s.GetUserDevices(ctx, userID)
 	if err != nil {
 		return err
@@ -259,6 +338,14 @@ func (s *pushServiceImpl) PushToUser(ctx context.Context, userID string, notific
 		return nil // 用户没有注册设备，不需要推送
 	}
 
+	// 应用推送文案实验
+	if s.experiments != nil {
+		notification = s.experiments.Apply(ctx, userID, notification)
+	}
+	if hidePreview {
+		notification = hideNotificationPreview(notification)
+	}
+
 	// 创建推送任务
 	task := &PushTask{
 		ID:           util.GenerateUUID(),
@@ -268,6 +355,7 @@ func (s *pushServiceImpl) PushToUser(ctx context.Context, userID string, notific
 		Retries:      0,
 		CreatedAt:    time.Now(),
 		ScheduledAt:  time.Now(),
+		SpanContext:  trace.SpanContextFromContext(ctx),
 	}
 
 	// 加入推送队列
@@ -313,6 +401,10 @@ func (s *pushServiceImpl) PushToDevice(ctx context.Context, device *model.Device
 	}
 
 	var err error
+	ctx, span := tracing.Start(ctx, "push.send", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		tracing.AttrPlatform.String(string(device.Platform)),
+	))
+	defer func() { tracing.End(span, err) }()
 
 	switch device.Platform {
 	case model.PlatformIOS:
@@ -327,6 +419,12 @@ func (s *pushServiceImpl) PushToDevice(ctx context.Context, device *model.Device
 		} else {
 			err = errors.New("FCM client not configured")
 		}
+	case model.PlatformWeb:
+		if s.webPushClient != nil {
+			err = s.webPushClient.Push(ctx, device.Subscription, notification)
+		} else {
+			err = errors.New("Web Push client not configured")
+		}
 	default:
 		err = ErrInvalidPlatform
 	}
@@ -339,7 +437,7 @@ func (s *pushServiceImpl) PushToDevice(ctx context.Context, device *model.Device
 		if isInvalidTokenError(err) {
 			result.InvalidToken = true
 			// 删除无效设备
-			s.db.WithContext(ctx).Where("device_token = ?", device.DeviceToken).Delete(&model.Device{})
+			s.devices.DeleteByToken(ctx, device.DeviceToken)
 			s.updateStats(func(stats *PushStats) {
 				stats.InvalidTokens++
 			})
@@ -386,6 +484,9 @@ func (s *pushServiceImpl) StopPushWorker() error {
 	s.running = false
 	s.runMu.Unlock()
 
+	// 合并窗口中的推送立即发出
+	s.flushAllMerged(context.Background())
+
 	close(s.stopChan)
 	s.wg.Wait()
 
@@ -406,6 +507,8 @@ func (s *pushServiceImpl) pushWorker(ctx context.Context, workerID int) {
 			return
 		case <-ctx.Done():
 			return
+		case item := <-s.offlineQueue:
+			s.pushOffline(tracing.WithParent(ctx, item.span), item.userID, item.message)
 		case task, ok := <-s.pushQueue:
 			if !ok {
 				return
@@ -436,8 +539,45 @@ func (s *pushServiceImpl) pushWorker(ctx context.Context, workerID int) {
 	}
 }
 
+// CancelMessage 取消消息的待执行推送
+func (s *pushServiceImpl) CancelMessage(messageID string) {
+	now := time.Now()
+	s.cancelledMu.Lock()
+	defer s.cancelledMu.Unlock()
+
+	for id, at := range s.cancelled {
+		if now.Sub(at) > pushCancelTTL {
+			delete(s.cancelled, id)
+		}
+	}
+	s.cancelled[messageID] = now
+}
+
+// isCancelled 推送任务关联的消息是否已取消
+func (s *pushServiceImpl) isCancelled(task *PushTask) bool {
+	if task.Notification == nil || task.Notification.MessageID == "" {
+		return false
+	}
+	s.cancelledMu.Lock()
+	defer s.cancelledMu.Unlock()
+	_, ok := s.cancelled[task.Notification.MessageID]
+	return ok
+}
+
 // executePushTask 执行推送任务
-func (s *pushServiceImpl) executePushTask(ctx context.Context, task *PushTask) error {
+func (s *pushServiceImpl) executePushTask(ctx context.Context, task *PushTask) (err error) {
+	ctx, span := tracing.Start(tracing.WithParent(ctx, task.SpanContext), "push.execute", trace.WithAttributes(
+		tracing.AttrMessageID.String(task.Notification.MessageID),
+		tracing.AttrRecipients.Int(len(task.Devices)),
+		attribute.Int("im.push.retries", task.Retries),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	if s.isCancelled(task) {
+		log.Printf("Skip push task %s of cancelled message %s", task.ID, task.Notification.MessageID)
+		return nil
+	}
+
 	start := time.Now()
 
 	var lastErr error
@@ -455,6 +595,10 @@ func (s *pushServiceImpl) executePushTask(ctx context.Context, task *PushTask) e
 		}
 	}
 
+	if s.experiments != nil {
+		s.experiments.RecordDelivery(ctx, task.Notification, successCount > 0)
+	}
+
 	// 更新统计
 	latency := time.Since(start).Milliseconds()
 	s.updateStats(func(stats *PushStats) {
@@ -521,22 +665,26 @@ func (s *pushServiceImpl) processUnpushedMessagesForUser(ctx context.Context, us
 		log.Printf("Get unpushed messages error: %v", err)
 		return
 	}
-
-	// 按用户分组
-	userMessages := make(map[string][]*model.OfflineMessage)
-	for _, msg := range messages {
-		userMessages[msg.UserID] = append(userMessages[msg.UserID], msg)
+	if len(messages) == 0 {
+		return
 	}
 
-	// 为每个用户发送推送
-	notification := s.buildNotification(messages)
+	// 过滤免打扰会话的消息（@自己或回复自己的消息按配置仍然推送）
+	pushable, mention := s.filterMutedMessages(ctx, userID, messages)
 
-	if err := s.PushToUser(ctx, userID, notification); err != nil {
-		log.Printf("Push to user %s error: %v", userID, err)
-		return
+	if len(pushable) > 0 {
+		notification := s.buildNotification(pushable)
+		if mention != nil {
+			tagMention(notification, mention)
+		}
+
+		if err := s.PushToUser(ctx, userID, notification); err != nil {
+			log.Printf("Push to user %s error: %v", userID, err)
+			return
+		}
 	}
 
-	// 标记为已推送
+	// 标记为已推送（被免打扰过滤的消息同样标记，避免重复判断）
 	messageIDs := make([]string, len(messages))
 	for i, msg := range messages {
 		messageIDs[i] = msg.MessageID
@@ -547,6 +695,128 @@ func (s *pushServiceImpl) processUnpushedMessagesForUser(ctx context.Context, us
 	}
 }
 
+// 提及类型
+const (
+	pushMentionAt      = "mention"         // 被@
+	pushMentionReply   = "reply"           // 被回复
+	pushMentionDynamic = "dynamic_mention" // 通过 @群角色或 @提及组被提及
+)
+
+// pushMention 离线消息中提及用户的消息
+type pushMention struct {
+	Kind    string
+	Message *model.OfflineMessage
+}
+
+// filterMutedMessages 过滤免打扰会话的离线消息，返回可推送的消息及最近一条提及用户的消息
+func (s *pushServiceImpl) filterMutedMessages(ctx context.Context, userID string, messages []*model.OfflineMessage) ([]*model.OfflineMessage, *pushMention) {
+	muted := make(map[string]bool)
+	pushable := make([]*model.OfflineMessage, 0, len(messages))
+	var mention *pushMention
+
+	for _, msg := range messages {
+		kind := ""
+		if parsed, err := ParseOfflineMessage(msg); err == nil {
+			kind = mentionKind(parsed, userID)
+		}
+
+		isMuted, ok := muted[msg.ConversationID]
+		if !ok {
+			isMuted = s.isConversationMuted(ctx, userID, msg.ConversationID)
+			muted[msg.ConversationID] = isMuted
+		}
+		if isMuted && !s.mentionBypassMute(kind) {
+			continue
+		}
+
+		pushable = append(pushable, msg)
+		if kind != "" {
+			mention = &pushMention{Kind: kind, Message: msg}
+		}
+	}
+
+	return pushable, mention
+}
+
+// isConversationMuted 用户是否对会话开启了免打扰（查询失败时按未免打扰处理）
+func (s *pushServiceImpl) isConversationMuted(ctx context.Context, userID, conversationID string) bool {
+	if s.conversations == nil || conversationID == "" {
+		return false
+	}
+
+	settings, err := s.conversations.FindUserConversation(ctx, userID, conversationID)
+	if err != nil {
+		log.Printf("Find conversation settings for push error: %v", err)
+		return false
+	}
+	return settings != nil && settings.Muted
+}
+
+// mentionBypassMute 提及类型是否按推送规则越过会话免打扰
+func (s *pushServiceImpl) mentionBypassMute(kind string) bool {
+	switch kind {
+	case "":
+		return false
+	case pushMentionDynamic:
+		return s.config.DynamicMentionBypassMute
+	default:
+		return s.config.MentionBypassMute
+	}
+}
+
+// mentionKind 判断消息是否@了用户或回复了用户的消息（@所有人不视为提及）
+// 不在 at_user_ids 中、但在服务端解析的 mention_targets 中的用户视为通过群角色或提及组被提及
+func mentionKind(msg *model.Message, userID string) string {
+	var atUserIDs []string
+	var replyToUserID string
+
+	switch c := msg.Content.(type) {
+	case *model.TextContent:
+		atUserIDs = c.AtUserIDs
+		replyToUserID = c.ReplyToUserID
+	case map[string]interface{}:
+		if ids, ok := c["at_user_ids"].([]interface{}); ok {
+			for _, id := range ids {
+				if str, ok := id.(string); ok {
+					atUserIDs = append(atUserIDs, str)
+				}
+			}
+		}
+		replyToUserID, _ = c["reply_to_user_id"].(string)
+	}
+
+	for _, id := range atUserIDs {
+		if id == userID {
+			return pushMentionAt
+		}
+	}
+	if replyToUserID != "" && replyToUserID == userID {
+		return pushMentionReply
+	}
+	for _, id := range msg.MentionTargets {
+		if id == userID {
+			return pushMentionDynamic
+		}
+	}
+	return ""
+}
+
+// tagMention 标记推送为提及通知，携带定位到提及消息所需的信息
+func tagMention(notification *model.PushNotification, mention *pushMention) {
+	notification.Category = "MENTION"
+	notification.ThreadID = mention.Message.ConversationID
+	if mention.Kind == pushMentionReply {
+		notification.Title = "有人回复了你"
+	} else {
+		notification.Title = "有人@了你"
+	}
+
+	notification.Data["mention"] = mention.Kind
+	notification.Data["mention_conversation_id"] = mention.Message.ConversationID
+	notification.Data["mention_message_id"] = mention.Message.MessageID
+	notification.Data["mention_seq"] = fmt.Sprintf("%d", mention.Message.Seq)
+}
+
 // buildNotification 根据离线消息构建推送通知
 func (s *pushServiceImpl) buildNotification(messages []*model.OfflineMessage) *model.PushNotification {
 	if len(messages) == 0 {
@@ -585,7 +855,7 @@ func (s *pushServiceImpl) buildNotification(messages []*model.OfflineMessage) *m
 // getNotificationTitle 获取通知标题
 func (s *pushServiceImpl) getNotificationTitle(msg *model.OfflineMessage) string {
 	// 根据会话类型返回不同标题
-	if len(msg.ConversationID) > 6 && msg.ConversationID[:6] == "group_" {
+	if convID, err := model.ParseConversationID(msg.ConversationID); err == nil && convID.IsGroup() {
 		return "群消息"
 	}
 	return "新消息"
@@ -624,11 +894,12 @@ func (s *pushServiceImpl) GetPushStats(ctx context.Context) (*PushStats, error)
 	stats.PendingCount = int64(len(s.pushQueue))
 
 	// 获取设备统计
-	var iosCount, androidCount int64
-	s.db.WithContext(ctx).Model(&model.Device{}).Where("platform = ?", model.PlatformIOS).Count(&iosCount)
-	s.db.WithContext(ctx).Model(&model.Device{}).Where("platform = ?", model.PlatformAndroid).Count(&androidCount)
+	iosCount, _ := s.devices.CountByPlatform(ctx, model.PlatformIOS)
+	androidCount, _ := s.devices.CountByPlatform(ctx, model.PlatformAndroid)
+	webCount, _ := s.devices.CountByPlatform(ctx, model.PlatformWeb)
 	stats.IOSCount = iosCount
 	stats.AndroidCount = androidCount
+	stats.WebCount = webCount
 
 	return stats, nil
 }
@@ -638,6 +909,10 @@ func isInvalidTokenError(err error) bool {
 	if err == nil {
 		return false
 	}
+	// Web Push 订阅已取消或过期
+	if errors.Is(err, webpush.ErrGone) || errors.Is(err, webpush.ErrInvalidSubscription) {
+		return true
+	}
 
 	errStr := err.Error()
 	invalidTokenErrors := []string{
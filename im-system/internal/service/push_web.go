@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/pkg/webpush"
+)
+
+// ErrInvalidWebPushSubscription Web Push 订阅缺失或格式错误
+var ErrInvalidWebPushSubscription = errors.New("invalid web push subscription")
+
+// webPushDefaultTTL 通知未设置有效期时推送服务的保留时间
+const webPushDefaultTTL = 24 * time.Hour
+
+// WebPushClient Web Push 客户端接口
+type WebPushClient interface {
+	Push(ctx context.Context, subscription string, notification *model.PushNotification) error
+}
+
+// webPushPayload 发送给浏览器 Service Worker 的通知内容
+type webPushPayload struct {
+	Title     string            `json:"title,omitempty"`
+	Body      string            `json:"body"`
+	Badge     int               `json:"badge,omitempty"`
+	Tag       string            `json:"tag,omitempty"` // 同一 tag 的通知在浏览器中替换显示
+	MessageID string            `json:"message_id,omitempty"`
+	Data      map[string]string `json:"data,omitempty"`
+}
+
+// webPushClient 基于 VAPID 的 Web Push 客户端
+type webPushClient struct {
+	client *webpush.Client
+}
+
+// NewWebPushClient 创建 Web Push 客户端
+func NewWebPushClient(client *webpush.Client) WebPushClient {
+	return &webPushClient{client: client}
+}
+
+// Push 将通知加密后发送到浏览器订阅的推送服务，订阅失效时返回 webpush.ErrGone
+func (c *webPushClient) Push(ctx context.Context, subscription string, notification *model.PushNotification) error {
+	sub, err := webpush.ParseSubscription([]byte(subscription))
+	if err != nil {
+		return err
+	}
+
+	tag := notification.CollapseKey
+	if tag == "" {
+		tag = notification.ThreadID
+	}
+	payload, err := json.Marshal(&webPushPayload{
+		Title:     notification.Title,
+		Body:      notification.Body,
+		Badge:     notification.Badge,
+		Tag:       tag,
+		MessageID: notification.MessageID,
+		Data:      notification.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	opts := webpush.Options{TTL: webPushDefaultTTL, Urgency: webpush.UrgencyNormal}
+	if notification.TTL > 0 {
+		opts.TTL = time.Duration(notification.TTL) * time.Second
+	}
+	if notification.Priority == model.PushPriorityHigh {
+		opts.Urgency = webpush.UrgencyHigh
+	}
+	return c.client.Send(ctx, sub, payload, opts)
+}
+
+// SetWebPushClient 设置 Web Push 客户端，为空时 web 平台设备推送失败
+func (s *pushServiceImpl) SetWebPushClient(client WebPushClient) {
+	s.webPushClient = client
+}
+
+// webPushDevice 校验 web 平台的订阅，返回规范化的订阅 JSON 及设备Token（未指定时按订阅地址生成）
+func webPushDevice(req *model.RegisterDeviceRequest) (subscription, deviceToken string, err error) {
+	if len(req.Subscription) == 0 {
+		return "", "", ErrInvalidWebPushSubscription
+	}
+	sub, err := webpush.ParseSubscription(req.Subscription)
+	if err != nil {
+		return "", "", ErrInvalidWebPushSubscription
+	}
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return "", "", err
+	}
+
+	deviceToken = req.DeviceToken
+	if deviceToken == "" {
+		sum := sha256.Sum256([]byte(sub.Endpoint))
+		deviceToken = "web:" + hex.EncodeToString(sum[:])
+	}
+	return string(data), deviceToken, nil
+}
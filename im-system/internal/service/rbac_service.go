@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// 管理权限错误定义
+var (
+	ErrRoleNotFound      = errors.New("admin role not found")
+	ErrRoleBuiltin       = errors.New("builtin admin role cannot be modified")
+	ErrRoleNameInvalid   = errors.New("admin role name is invalid")
+	ErrPermissionUnknown = errors.New("unknown admin permission")
+	ErrRoleSelfRevoke    = errors.New("cannot revoke your own role management permission")
+)
+
+// rbacPermsKeyPrefix 用户权限缓存（im:rbac:perms:<user_id>）
+const rbacPermsKeyPrefix = "im:rbac:perms:"
+
+// roleNamePattern 自定义角色名
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,63}$`)
+
+// RBACConfig 管理权限配置
+type RBACConfig struct {
+	CacheTTL time.Duration // 用户权限缓存时间
+}
+
+// DefaultRBACConfig 默认管理权限配置
+func DefaultRBACConfig() *RBACConfig {
+	return &RBACConfig{
+		CacheTTL: 5 * time.Minute,
+	}
+}
+
+// RBACService 管理接口权限服务：内置及自定义角色、用户角色分配、权限校验（Redis缓存）与审计日志
+type RBACService interface {
+	// ListRoles 查询全部角色（内置角色在前）
+	ListRoles(ctx context.Context) ([]*model.AdminRole, error)
+
+	// SaveRole 创建或更新自定义角色，内置角色不可修改
+	SaveRole(ctx context.Context, operatorID, name string, req *model.SaveAdminRoleRequest) (*model.AdminRole, error)
+
+	// DeleteRole 删除自定义角色，同时撤销该角色的分配
+	DeleteRole(ctx context.Context, name string) error
+
+	// GetUserRoles 查询用户的角色
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+
+	// SetUserRoles 覆盖用户的角色，操作者不能撤销自己的角色管理权限
+	SetUserRoles(ctx context.Context, operatorID, userID string, roles []string) ([]string, error)
+
+	// ListAssignments 查询全部角色分配
+	ListAssignments(ctx context.Context) ([]*model.AdminRoleAssignment, error)
+
+	// Permissions 查询用户拥有的权限
+	Permissions(ctx context.Context, userID string) ([]string, error)
+
+	// HasPermission 检查用户是否拥有权限
+	HasPermission(ctx context.Context, userID, permission string) (bool, error)
+
+	// RecordAudit 写入审计日志
+	RecordAudit(ctx context.Context, log *model.AdminAuditLog) error
+
+	// ListAuditLogs 分页查询审计日志
+	ListAuditLogs(ctx context.Context, filter *model.AuditLogFilter, page, pageSize int) ([]*model.AdminAuditLog, int64, error)
+}
+
+// rbacServiceImpl 管理接口权限服务实现
+type rbacServiceImpl struct {
+	repo   repository.RBACRepository
+	redis  *redis.Client
+	config *RBACConfig
+}
+
+// NewRBACService 创建管理接口权限服务
+func NewRBACService(repo repository.RBACRepository, redisClient *redis.Client, config *RBACConfig) RBACService {
+	if config == nil {
+		config = DefaultRBACConfig()
+	}
+	return &rbacServiceImpl{
+		repo:   repo,
+		redis:  redisClient,
+		config: config,
+	}
+}
+
+// builtinRole 查找内置角色
+func builtinRole(name string) *model.AdminRole {
+	for _, role := range model.BuiltinRoles {
+		if role.Name == name {
+			return role
+		}
+	}
+	return nil
+}
+
+// ListRoles 查询全部角色
+func (s *rbacServiceImpl) ListRoles(ctx context.Context) ([]*model.AdminRole, error) {
+	custom, err := s.repo.ListRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list admin roles error: %w", err)
+	}
+	roles := make([]*model.AdminRole, 0, len(model.BuiltinRoles)+len(custom))
+	roles = append(roles, model.BuiltinRoles...)
+	return append(roles, custom...), nil
+}
+
+// SaveRole 创建或更新自定义角色
+func (s *rbacServiceImpl) SaveRole(ctx context.Context, operatorID, name string, req *model.SaveAdminRoleRequest) (*model.AdminRole, error) {
+	if builtinRole(name) != nil {
+		return nil, ErrRoleBuiltin
+	}
+	if !roleNamePattern.MatchString(name) {
+		return nil, ErrRoleNameInvalid
+	}
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &model.AdminRole{
+		Name:        name,
+		Description: req.Description,
+		Permissions: permissions,
+		UpdatedBy:   operatorID,
+	}
+	if err := s.repo.SaveRole(ctx, role); err != nil {
+		return nil, fmt.Errorf("save admin role error: %w", err)
+	}
+	s.invalidateAll(ctx)
+	return role, nil
+}
+
+// DeleteRole 删除自定义角色
+func (s *rbacServiceImpl) DeleteRole(ctx context.Context, name string) error {
+	if builtinRole(name) != nil {
+		return ErrRoleBuiltin
+	}
+	deleted, err := s.repo.DeleteRole(ctx, name)
+	if err != nil {
+		return fmt.Errorf("delete admin role error: %w", err)
+	}
+	if !deleted {
+		return ErrRoleNotFound
+	}
+	s.invalidateAll(ctx)
+	return nil
+}
+
+// GetUserRoles 查询用户的角色
+func (s *rbacServiceImpl) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	roles, err := s.repo.FindUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find user roles error: %w", err)
+	}
+	return roles, nil
+}
+
+// SetUserRoles 覆盖用户的角色
+func (s *rbacServiceImpl) SetUserRoles(ctx context.Context, operatorID, userID string, roles []string) ([]string, error) {
+	roles = uniqueStrings(roles)
+	resolved, err := s.resolveRoles(ctx, roles)
+	if err != nil {
+		return nil, err
+	}
+	if len(resolved) != len(roles) {
+		return nil, ErrRoleNotFound
+	}
+	// 防止管理员把自己锁在外面
+	if operatorID == userID && !grantsAny(resolved, model.PermRBACWrite) {
+		return nil, ErrRoleSelfRevoke
+	}
+
+	if err := s.repo.SetUserRoles(ctx, userID, roles, operatorID); err != nil {
+		return nil, fmt.Errorf("set user roles error: %w", err)
+	}
+	if s.redis != nil {
+		s.redis.Del(ctx, rbacPermsKeyPrefix+userID)
+	}
+	sort.Strings(roles)
+	return roles, nil
+}
+
+// ListAssignments 查询全部角色分配
+func (s *rbacServiceImpl) ListAssignments(ctx context.Context) ([]*model.AdminRoleAssignment, error) {
+	return s.repo.ListAssignments(ctx)
+}
+
+// Permissions 查询用户拥有的权限（Redis缓存，无角色的用户同样缓存）
+func (s *rbacServiceImpl) Permissions(ctx context.Context, userID string) ([]string, error) {
+	key := rbacPermsKeyPrefix + userID
+	if s.redis != nil {
+		if data, err := s.redis.Get(ctx, key).Bytes(); err == nil {
+			var permissions []string
+			if json.Unmarshal(data, &permissions) == nil {
+				return permissions, nil
+			}
+		}
+	}
+
+	names, err := s.repo.FindUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find user roles error: %w", err)
+	}
+	roles, err := s.resolveRoles(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	var permissions []string
+	for _, role := range roles {
+		permissions = append(permissions, role.Permissions...)
+	}
+	permissions = uniqueStrings(permissions)
+	sort.Strings(permissions)
+
+	if s.redis != nil {
+		if data, err := json.Marshal(permissions); err == nil {
+			s.redis.Set(ctx, key, data, s.config.CacheTTL)
+		}
+	}
+	return permissions, nil
+}
+
+// HasPermission 检查用户是否拥有权限
+func (s *rbacServiceImpl) HasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	permissions, err := s.Permissions(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	role := &model.AdminRole{Permissions: permissions}
+	return role.Grants(permission), nil
+}
+
+// RecordAudit 写入审计日志
+func (s *rbacServiceImpl) RecordAudit(ctx context.Context, log *model.AdminAuditLog) error {
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	return s.repo.CreateAuditLog(ctx, log)
+}
+
+// ListAuditLogs 分页查询审计日志
+func (s *rbacServiceImpl) ListAuditLogs(ctx context.Context, filter *model.AuditLogFilter, page, pageSize int) ([]*model.AdminAuditLog, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	logs, total, err := s.repo.ListAuditLogs(ctx, filter, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list audit logs error: %w", err)
+	}
+	return logs, total, nil
+}
+
+// resolveRoles 查询角色定义（内置角色取代码中的定义），不存在的角色忽略
+func (s *rbacServiceImpl) resolveRoles(ctx context.Context, names []string) ([]*model.AdminRole, error) {
+	roles := make([]*model.AdminRole, 0, len(names))
+	var custom []string
+	for _, name := range names {
+		if role := builtinRole(name); role != nil {
+			roles = append(roles, role)
+			continue
+		}
+		custom = append(custom, name)
+	}
+	if len(custom) > 0 {
+		found, err := s.repo.FindRoles(ctx, custom)
+		if err != nil {
+			return nil, fmt.Errorf("find admin roles error: %w", err)
+		}
+		roles = append(roles, found...)
+	}
+	return roles, nil
+}
+
+// invalidateAll 角色权限变更后清除全部用户的权限缓存
+func (s *rbacServiceImpl) invalidateAll(ctx context.Context) {
+	if s.redis == nil {
+		return
+	}
+	iter := s.redis.Scan(ctx, 0, rbacPermsKeyPrefix+"*", 100).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if len(keys) > 0 {
+		s.redis.Del(ctx, keys...)
+	}
+}
+
+// normalizePermissions 校验并去重权限，自定义角色不能授予全部权限
+func normalizePermissions(permissions []string) ([]string, error) {
+	known := make(map[string]bool, len(model.AllPermissions))
+	for _, p := range model.AllPermissions {
+		known[p] = true
+	}
+	for _, p := range permissions {
+		if !known[p] {
+			return nil, fmt.Errorf("%w: %s", ErrPermissionUnknown, p)
+		}
+	}
+	permissions = uniqueStrings(permissions)
+	sort.Strings(permissions)
+	return permissions, nil
+}
+
+// grantsAny 任一角色是否拥有权限
+func grantsAny(roles []*model.AdminRole, permission string) bool {
+	for _, role := range roles {
+		if role.Grants(permission) {
+			return true
+		}
+	}
+	return false
+}
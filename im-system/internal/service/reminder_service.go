@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/i18n"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 消息提醒服务错误定义
+var (
+	ErrMessageNotFound    = errors.New("message not found")
+	ErrReminderNotFound   = errors.New("reminder not found")
+	ErrReminderLimit      = errors.New("too many pending reminders")
+	ErrInvalidRemindTime  = errors.New("remind time must be in the future")
+	ErrRemindTimeTooFar   = errors.New("remind time is too far in the future")
+	ErrReminderNotPending = errors.New("reminder already fired or cancelled")
+)
+
+// ReminderConfig 消息提醒配置
+type ReminderConfig struct {
+	MaxPendingPerUser int           // 每个用户最多待提醒数
+	MaxAhead          time.Duration // 最远可设置的提醒时间
+	PollInterval      time.Duration // 到期扫描间隔
+	BatchSize         int           // 每次扫描处理的提醒数
+	PreviewLength     int           // 原消息摘要长度（字符）
+}
+
+// DefaultReminderConfig 默认消息提醒配置
+func DefaultReminderConfig() *ReminderConfig {
+	return &ReminderConfig{
+		MaxPendingPerUser: 100,
+		MaxAhead:          365 * 24 * time.Hour,
+		PollInterval:      10 * time.Second,
+		BatchSize:         100,
+		PreviewLength:     50,
+	}
+}
+
+// CreateReminderRequest 创建提醒请求
+type CreateReminderRequest struct {
+	RemindAt time.Time `json:"remind_at" binding:"required"`
+	Note     string    `json:"note" binding:"max=256"`
+}
+
+// ReminderService 消息提醒服务接口
+type ReminderService interface {
+	// CreateReminder 为可见的消息创建提醒
+	CreateReminder(ctx context.Context, userID, messageID string, req *CreateReminderRequest) (*model.MessageReminder, error)
+
+	// ListReminders 获取用户待提醒的提醒
+	ListReminders(ctx context.Context, userID string) ([]*model.MessageReminder, error)
+
+	// CancelReminder 取消提醒
+	CancelReminder(ctx context.Context, userID, reminderID string) error
+
+	// FireDue 发送到期提醒，返回发送数量
+	FireDue(ctx context.Context) (int, error)
+
+	// Start 启动到期提醒扫描
+	Start(ctx context.Context)
+
+	// SetPushService 设置推送服务，提醒到期时同时推送到用户设备
+	SetPushService(pushService PushService)
+}
+
+// reminderServiceImpl 消息提醒服务实现
+type reminderServiceImpl struct {
+	repo           repository.ReminderRepository
+	messageService MessageService
+	groupService   GroupService
+	dispatcher     MessageDispatcher
+	pushService    PushService
+	config         *ReminderConfig
+}
+
+// NewReminderService 创建消息提醒服务
+func NewReminderService(
+	repo repository.ReminderRepository,
+	messageService MessageService,
+	groupService GroupService,
+	dispatcher MessageDispatcher,
+	config *ReminderConfig,
+) ReminderService {
+	if config == nil {
+		config = DefaultReminderConfig()
+	}
+	return &reminderServiceImpl{
+		repo:           repo,
+		messageService: messageService,
+		groupService:   groupService,
+		dispatcher:     dispatcher,
+		config:         config,
+	}
+}
+
+// SetPushService 设置推送服务，提醒到期时同时推送到用户设备
+func (s *reminderServiceImpl) SetPushService(pushService PushService) {
+	s.pushService = pushService
+}
+
+// CreateReminder 创建提醒
+func (s *reminderServiceImpl) CreateReminder(ctx context.Context, userID, messageID string, req *CreateReminderRequest) (*model.MessageReminder, error) {
+	now := time.Now()
+	if !req.RemindAt.After(now) {
+		return nil, ErrInvalidRemindTime
+	}
+	if req.RemindAt.Sub(now) > s.config.MaxAhead {
+		return nil, ErrRemindTimeTooFar
+	}
+
+	msg, err := s.findVisibleMessage(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.repo.CountPending(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= int64(s.config.MaxPendingPerUser) {
+		return nil, ErrReminderLimit
+	}
+
+	reminder := &model.MessageReminder{
+		ReminderID:     util.GenerateReminderID(),
+		UserID:         userID,
+		MessageID:      msg.MessageID,
+		ConversationID: msg.ConversationID,
+		Note:           strings.TrimSpace(req.Note),
+		RemindAt:       req.RemindAt,
+		Status:         model.ReminderPending,
+	}
+	if err := s.repo.Create(ctx, reminder); err != nil {
+		return nil, fmt.Errorf("create reminder error: %w", err)
+	}
+	return reminder, nil
+}
+
+// ListReminders 获取用户待提醒的提醒
+func (s *reminderServiceImpl) ListReminders(ctx context.Context, userID string) ([]*model.MessageReminder, error) {
+	return s.repo.FindPendingByUser(ctx, userID)
+}
+
+// CancelReminder 取消提醒
+func (s *reminderServiceImpl) CancelReminder(ctx context.Context, userID, reminderID string) error {
+	reminder, err := s.repo.FindByID(ctx, reminderID)
+	if err != nil {
+		return err
+	}
+	if reminder == nil || reminder.UserID != userID {
+		return ErrReminderNotFound
+	}
+
+	ok, err := s.repo.Transition(ctx, reminderID, model.ReminderPending, model.ReminderCancelled)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrReminderNotPending
+	}
+	return nil
+}
+
+// FireDue 发送到期提醒
+func (s *reminderServiceImpl) FireDue(ctx context.Context) (int, error) {
+	reminders, err := s.repo.FindDue(ctx, time.Now(), s.config.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("find due reminders error: %w", err)
+	}
+
+	fired := 0
+	for _, reminder := range reminders {
+		// 先抢占再发送，多节点同时扫描时每条提醒只发送一次
+		ok, err := s.repo.Transition(ctx, reminder.ReminderID, model.ReminderPending, model.ReminderFired)
+		if err != nil {
+			log.Printf("claim reminder %s error: %v", reminder.ReminderID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		s.fire(ctx, reminder)
+		fired++
+	}
+	return fired, nil
+}
+
+// fire 向用户发送提醒系统消息及推送
+func (s *reminderServiceImpl) fire(ctx context.Context, reminder *model.MessageReminder) {
+	content := &model.ReminderContent{
+		ReminderID:     reminder.ReminderID,
+		MessageID:      reminder.MessageID,
+		ConversationID: reminder.ConversationID,
+		Note:           reminder.Note,
+		RemindAt:       reminder.RemindAt.UnixMilli(),
+		TemplateKey:    i18n.KeyMessageReminder,
+	}
+	// 原消息可能已被撤回或删除，仍然发送提醒
+	if msg, err := s.messageService.GetMessageByID(ctx, reminder.MessageID); err == nil && msg != nil {
+		content.Sender = msg.From
+		content.Preview = s.preview(msg)
+	}
+
+	msg := &model.Message{
+		MessageID:      util.GenerateMessageID(),
+		Type:           model.MsgReminder,
+		From:           "system",
+		To:             reminder.UserID,
+		ConversationID: reminder.ConversationID,
+		Content:        content,
+		Timestamp:      time.Now().UnixMilli(),
+		QoS:            model.QoSAtLeastOnce,
+	}
+	if s.dispatcher != nil {
+		if err := s.dispatcher.DispatchToUsers(ctx, []string{reminder.UserID}, msg); err != nil {
+			log.Printf("dispatch reminder %s error: %v", reminder.ReminderID, err)
+		}
+	}
+
+	if s.pushService != nil {
+		body := content.Note
+		if body == "" {
+			body = strings.ReplaceAll(i18n.T(i18n.DefaultLocale, i18n.KeyMessageReminder), "{preview}", content.Preview)
+		}
+		notification := &model.PushNotification{
+			Body:      body,
+			Sound:     "default",
+			ThreadID:  reminder.ConversationID,
+			MessageID: reminder.MessageID,
+			Priority:  model.PushPriorityHigh,
+			Data: map[string]string{
+				"type":            "reminder",
+				"reminder_id":     reminder.ReminderID,
+				"conversation_id": reminder.ConversationID,
+			},
+		}
+		if err := s.pushService.PushToUser(ctx, reminder.UserID, notification); err != nil {
+			log.Printf("push reminder %s error: %v", reminder.ReminderID, err)
+		}
+	}
+}
+
+// Start 启动到期提醒扫描
+func (s *reminderServiceImpl) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.FireDue(ctx); err != nil {
+				log.Printf("fire reminders error: %v", err)
+			}
+		}
+	}
+}
+
+// findVisibleMessage 查询用户可见的消息（会话参与者），不可见时按不存在处理
+func (s *reminderServiceImpl) findVisibleMessage(ctx context.Context, userID, messageID string) (*MessageDTO, error) {
+	msg, err := s.messageService.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, ErrMessageNotFound
+	}
+
+	if msg.GroupID != "" {
+		isMember, err := s.groupService.IsMember(ctx, msg.GroupID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("check membership error: %w", err)
+		}
+		if !isMember {
+			return nil, ErrMessageNotFound
+		}
+		return msg, nil
+	}
+	if msg.From != userID && msg.To != userID {
+		return nil, ErrMessageNotFound
+	}
+	return msg, nil
+}
+
+// preview 生成原消息摘要：文本消息截取正文，其他消息显示类型
+func (s *reminderServiceImpl) preview(msg *MessageDTO) string {
+	if msg.Revoked {
+		return ""
+	}
+	if text, ok := msg.Content["text"].(string); ok && text != "" {
+		runes := []rune(text)
+		if len(runes) > s.config.PreviewLength {
+			return string(runes[:s.config.PreviewLength]) + "…"
+		}
+		return text
+	}
+	return "[" + model.MessageType(msg.Type).String() + "]"
+}
@@ -0,0 +1,546 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/pkg/objectstore"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 断点续传错误定义
+var (
+	ErrUploadSessionNotFound = errors.New("upload session not found")
+	ErrUploadSessionBusy     = errors.New("upload session is busy")
+	ErrUploadOffsetMismatch  = errors.New("upload offset mismatch")
+	ErrUploadSizeExceeded    = errors.New("upload exceeds declared file size")
+	ErrUploadChunkTooLarge   = errors.New("upload chunk size exceeds limit")
+)
+
+const (
+	// minUploadPartSize 对象存储分片最小大小（最后一片除外）
+	minUploadPartSize = 5 * 1024 * 1024
+	// maxUploadChunkSize 断点续传分片最大大小（写入时按分片大小分配缓冲区）
+	maxUploadChunkSize = 64 * 1024 * 1024
+	// defaultUploadSessionTTL 会话默认有效期（每次写入后顺延）
+	defaultUploadSessionTTL = 24 * time.Hour
+	// uploadSessionsKey 会话过期时间索引（ZSET，score为过期时间）
+	uploadSessionsKey = "upload:sessions"
+)
+
+// uploadSession 断点续传会话状态
+type uploadSession struct {
+	UploadID    string           `json:"upload_id"`
+	FileID      string           `json:"file_id"`
+	FileName    string           `json:"file_name"`
+	FileExt     string           `json:"file_ext"`
+	FileSize    int64            `json:"file_size"`
+	ContentType string           `json:"content_type"`
+	UserID      string           `json:"user_id"`
+	GroupID     string           `json:"group_id,omitempty"`
+	ObjectPath  string           `json:"object_path"`
+	MultipartID string           `json:"multipart_id"` // 对象存储分片上传ID
+	ChunkSize   int64            `json:"chunk_size"`
+	Offset      int64            `json:"offset"`
+	Parts       []model.PartInfo `json:"parts"`
+	TailSize    int64            `json:"tail_size"`        // 不足一个分片、暂存于尾部对象的字节数
+	Region      string           `json:"region,omitempty"` // 数据驻留区域，为空表示默认区域
+
+	// 增量摘要状态
+	MD5State    []byte `json:"md5_state"`
+	SHA256State []byte `json:"sha256_state"`
+
+	ExpectedSHA256 string    `json:"expected_sha256,omitempty"`
+	ExpectedMD5    string    `json:"expected_md5,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// info 转换为会话信息
+func (u *uploadSession) info() *model.UploadSessionInfo {
+	return &model.UploadSessionInfo{
+		UploadID:  u.UploadID,
+		FileID:    u.FileID,
+		FileName:  u.FileName,
+		FileSize:  u.FileSize,
+		Offset:    u.Offset,
+		Progress:  float64(u.Offset) / float64(u.FileSize),
+		ExpiresAt: u.ExpiresAt,
+	}
+}
+
+// tailPath 尾部暂存对象路径
+func (u *uploadSession) tailPath() string {
+	return u.ObjectPath + ".tail"
+}
+
+// CreateUploadSession 创建断点续传会话
+func (s *objectStorageService) CreateUploadSession(ctx context.Context, req *model.CreateUploadSessionRequest, userID string) (*model.UploadSessionInfo, error) {
+	if req.ChunkSize > maxUploadChunkSize {
+		return nil, ErrUploadChunkTooLarge
+	}
+	fileExt := strings.ToLower(strings.TrimPrefix(filepath.Ext(req.FileName), "."))
+	fileType := model.GetFileTypeByExtension(fileExt)
+	if err := s.checkFileSize(fileType, req.FileSize); err != nil {
+		return nil, err
+	}
+	if err := s.checkFileType(ctx, req.GroupID, req.FileName, nil); err != nil {
+		return nil, err
+	}
+	fileName, err := s.moderateFile(ctx, userID, req.GroupID, req.FileName, req.ContentType, req.FileSize)
+	if err != nil {
+		return nil, err
+	}
+	req.FileName = fileName
+
+	// 分片不超过文件大小，但不小于对象存储最小分片
+	chunkSize := req.ChunkSize
+	if chunkSize > req.FileSize {
+		chunkSize = req.FileSize
+	}
+	if chunkSize < minUploadPartSize {
+		chunkSize = minUploadPartSize
+	}
+
+	fileID := util.GenerateFileID()
+	objectPath := s.generateObjectPath(fileID, fileExt)
+
+	multipartID, err := s.store.NewMultipartUpload(ctx, objectPath, req.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("create multipart upload error: %w", err)
+	}
+
+	now := time.Now()
+	session := &uploadSession{
+		UploadID:       util.GenerateUploadID(),
+		Region:         s.config.DataRegion,
+		FileID:         fileID,
+		FileName:       req.FileName,
+		FileExt:        fileExt,
+		FileSize:       req.FileSize,
+		ContentType:    req.ContentType,
+		UserID:         userID,
+		GroupID:        req.GroupID,
+		ObjectPath:     objectPath,
+		MultipartID:    multipartID,
+		ChunkSize:      chunkSize,
+		ExpectedSHA256: strings.ToLower(req.SHA256),
+		ExpectedMD5:    strings.ToLower(req.MD5),
+		CreatedAt:      now,
+	}
+
+	if err := saveHashStates(session, md5.New(), sha256.New()); err != nil {
+		return nil, err
+	}
+	if err := s.saveUploadSession(ctx, session); err != nil {
+		s.store.AbortMultipartUpload(ctx, objectPath, multipartID)
+		return nil, err
+	}
+
+	return session.info(), nil
+}
+
+// WriteUploadSession 在指定偏移量写入数据，偏移量必须等于已接收字节数
+func (s *objectStorageService) WriteUploadSession(ctx context.Context, uploadID, userID string, offset int64, reader io.Reader) (*model.UploadSessionInfo, error) {
+	unlock, err := s.lockUploadSession(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	session, err := s.loadUploadSession(ctx, uploadID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if offset != session.Offset {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrUploadOffsetMismatch, session.Offset, offset)
+	}
+
+	md5Hasher, sha256Hasher, err := restoreHashStates(session)
+	if err != nil {
+		return nil, err
+	}
+
+	// 读取不超过剩余大小的数据，同时计算摘要
+	remaining := session.FileSize - session.Offset
+	counter := &countingWriter{}
+	body := io.TeeReader(io.LimitReader(reader, remaining), io.MultiWriter(md5Hasher, sha256Hasher, counter))
+
+	// 拼接上次暂存的尾部数据
+	src := body
+	if session.TailSize > 0 {
+		tail, err := s.store.Get(ctx, session.tailPath())
+		if err != nil {
+			return nil, fmt.Errorf("get upload tail error: %w", err)
+		}
+		defer tail.Close()
+		src = io.MultiReader(tail, body)
+	}
+
+	// 按分片大小上传完整分片
+	// 失败时不保存会话状态，重试时相同分片号会被覆盖
+	parts := session.Parts
+	buf := make([]byte, session.ChunkSize)
+	var leftover []byte
+	for {
+		n, err := io.ReadFull(src, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("read upload data error: %w", err)
+		}
+		if int64(n) < session.ChunkSize {
+			leftover = buf[:n]
+			break
+		}
+
+		part, err := s.putUploadPart(ctx, session, len(parts)+1, buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, *part)
+	}
+
+	// 超出声明大小的数据直接拒绝
+	if counter.n == remaining {
+		if extra, _ := reader.Read(make([]byte, 1)); extra > 0 {
+			return nil, ErrUploadSizeExceeded
+		}
+	}
+
+	newOffset := session.Offset + counter.n
+	var tailSize int64
+	if newOffset == session.FileSize {
+		// 最后一片可以小于分片大小
+		if len(leftover) > 0 {
+			part, err := s.putUploadPart(ctx, session, len(parts)+1, leftover)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, *part)
+		}
+		s.store.Remove(ctx, session.tailPath())
+	} else if len(leftover) > 0 {
+		// 不足一个分片的数据暂存到尾部对象，等待后续写入
+		if err := s.store.Put(ctx, session.tailPath(), bytes.NewReader(leftover), int64(len(leftover)), ""); err != nil {
+			return nil, fmt.Errorf("save upload tail error: %w", err)
+		}
+		tailSize = int64(len(leftover))
+	}
+
+	session.Parts = parts
+	session.Offset = newOffset
+	session.TailSize = tailSize
+	if err := saveHashStates(session, md5Hasher, sha256Hasher); err != nil {
+		return nil, err
+	}
+	if err := s.saveUploadSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return session.info(), nil
+}
+
+// GetUploadSession 查询上传进度
+func (s *objectStorageService) GetUploadSession(ctx context.Context, uploadID, userID string) (*model.UploadSessionInfo, error) {
+	session, err := s.loadUploadSession(ctx, uploadID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return session.info(), nil
+}
+
+// FinalizeUploadSession 完成上传，合并分片并创建文件记录
+func (s *objectStorageService) FinalizeUploadSession(ctx context.Context, uploadID, userID string) (*model.FileInfo, error) {
+	unlock, err := s.lockUploadSession(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	session, err := s.loadUploadSession(ctx, uploadID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Offset != session.FileSize {
+		return nil, ErrMultipartIncomplete
+	}
+
+	md5Hasher, sha256Hasher, err := restoreHashStates(session)
+	if err != nil {
+		return nil, err
+	}
+	md5Hash := hex.EncodeToString(md5Hasher.Sum(nil))
+	sha256Hash := hex.EncodeToString(sha256Hasher.Sum(nil))
+	if err := verifyChecksum(session.ExpectedSHA256, sha256Hash, session.ExpectedMD5, md5Hash); err != nil {
+		s.discardUploadSession(ctx, session)
+		return nil, err
+	}
+
+	completeParts := make([]objectstore.Part, 0, len(session.Parts))
+	for _, part := range session.Parts {
+		completeParts = append(completeParts, objectstore.Part{PartNumber: part.PartNumber, ETag: part.ETag, Size: part.Size})
+	}
+	if err := s.store.CompleteMultipartUpload(ctx, session.ObjectPath, session.MultipartID, completeParts); err != nil {
+		return nil, fmt.Errorf("complete multipart upload error: %w", err)
+	}
+
+	// 合并后按文件头再次校验类型
+	if err := s.checkObjectType(ctx, session.GroupID, session.FileName, session.ObjectPath); err != nil {
+		s.store.Remove(ctx, session.ObjectPath)
+		s.deleteUploadSession(ctx, session.UploadID)
+		return nil, err
+	}
+	archiveInfo, err := s.checkObjectArchive(ctx, session.ObjectPath, session.FileName, session.FileSize)
+	if err != nil {
+		s.store.Remove(ctx, session.ObjectPath)
+		s.deleteUploadSession(ctx, session.UploadID)
+		return nil, err
+	}
+
+	fileType := model.GetFileTypeByExtension(session.FileExt)
+	if fileType == model.FileTypeOther && session.ContentType != "" {
+		fileType = model.GetFileTypeByMimeType(session.ContentType)
+	}
+
+	var thumbnailURL string
+	if fileType == model.FileTypeImage {
+		thumbnailURL, _ = s.GenerateThumbnail(ctx, session.FileID, 200, 200)
+	}
+
+	fileRecord := &model.File{
+		FileID:        session.FileID,
+		UserID:        session.UserID,
+		FileName:      session.FileName,
+		FileSize:      session.FileSize,
+		FileExt:       session.FileExt,
+		MimeType:      session.ContentType,
+		FileType:      fileType,
+		StoragePath:   session.ObjectPath,
+		ThumbnailPath: thumbnailURL,
+		MD5:           md5Hash,
+		SHA256:        sha256Hash,
+		Status:        model.FileStatusNormal,
+		CreatedAt:     time.Now(),
+		ArchiveInfo:   archiveInfo,
+		Region:        s.config.DataRegion,
+	}
+	if err := s.db.WithContext(ctx).Create(fileRecord).Error; err != nil {
+		s.store.Remove(ctx, session.ObjectPath)
+		return nil, fmt.Errorf("save file record error: %w", err)
+	}
+
+	s.cacheFileInfo(ctx, session.FileID, fileRecord)
+	s.deleteUploadSession(ctx, session.UploadID)
+
+	return &model.FileInfo{
+		FileID:       session.FileID,
+		FileName:     session.FileName,
+		FileSize:     session.FileSize,
+		FileExt:      session.FileExt,
+		MimeType:     session.ContentType,
+		FileType:     fileType,
+		URL:          s.buildFileURL(session.ObjectPath),
+		ThumbnailURL: thumbnailURL,
+		MD5:          md5Hash,
+		SHA256:       sha256Hash,
+		UploadedAt:   fileRecord.CreatedAt,
+		Archive:      archiveInfo,
+	}, nil
+}
+
+// AbortUploadSession 取消上传
+func (s *objectStorageService) AbortUploadSession(ctx context.Context, uploadID, userID string) error {
+	session, err := s.loadUploadSession(ctx, uploadID, userID)
+	if err != nil {
+		return err
+	}
+	s.discardUploadSession(ctx, session)
+	return nil
+}
+
+// CleanExpiredUploadSessions 清理过期的上传会话
+func (s *objectStorageService) CleanExpiredUploadSessions(ctx context.Context) (int, error) {
+	uploadIDs, err := s.redis.ZRangeByScore(ctx, uploadSessionsKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: fmt.Sprintf("%d", time.Now().Unix()),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("get expired upload sessions error: %w", err)
+	}
+
+	cleaned := 0
+	for _, uploadID := range uploadIDs {
+		session, err := s.loadUploadSession(ctx, uploadID, "")
+		if err != nil {
+			s.redis.ZRem(ctx, uploadSessionsKey, uploadID)
+			continue
+		}
+		// 其他区域的上传会话由该区域的存储服务清理
+		if session.Region != s.config.DataRegion {
+			continue
+		}
+		s.discardUploadSession(ctx, session)
+		cleaned++
+	}
+
+	return cleaned, nil
+}
+
+// StartUploadSessionCleanup 启动过期上传会话清理任务
+func (s *objectStorageService) StartUploadSessionCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := s.CleanExpiredUploadSessions(ctx)
+			if err != nil {
+				log.Printf("clean expired upload sessions error: %v", err)
+			} else if count > 0 {
+				log.Printf("cleaned %d expired upload sessions", count)
+			}
+		}
+	}
+}
+
+// putUploadPart 上传一个分片
+func (s *objectStorageService) putUploadPart(ctx context.Context, session *uploadSession, partNumber int, data []byte) (*model.PartInfo, error) {
+	part, err := s.store.PutPart(ctx, session.ObjectPath, session.MultipartID, partNumber, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("upload part error: %w", err)
+	}
+	return &model.PartInfo{PartNumber: partNumber, ETag: part.ETag, Size: int64(len(data))}, nil
+}
+
+// discardUploadSession 取消分片上传并删除会话
+func (s *objectStorageService) discardUploadSession(ctx context.Context, session *uploadSession) {
+	if err := s.store.AbortMultipartUpload(ctx, session.ObjectPath, session.MultipartID); err != nil {
+		log.Printf("abort multipart upload %s error: %v", session.MultipartID, err)
+	}
+	s.store.Remove(ctx, session.tailPath())
+	s.deleteUploadSession(ctx, session.UploadID)
+}
+
+// uploadSessionTTL 获取会话有效期
+func (s *objectStorageService) uploadSessionTTL() time.Duration {
+	if s.config.UploadSessionTTL > 0 {
+		return s.config.UploadSessionTTL
+	}
+	return defaultUploadSessionTTL
+}
+
+// saveUploadSession 保存会话并顺延过期时间
+func (s *objectStorageService) saveUploadSession(ctx context.Context, session *uploadSession) error {
+	ttl := s.uploadSessionTTL()
+	session.ExpiresAt = time.Now().Add(ttl)
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	// 会话数据比过期时间多保留一段时间，供清理任务读取
+	pipe := s.redis.TxPipeline()
+	pipe.Set(ctx, fmt.Sprintf("upload:session:%s", session.UploadID), data, ttl+time.Hour)
+	pipe.ZAdd(ctx, uploadSessionsKey, &redis.Z{Score: float64(session.ExpiresAt.Unix()), Member: session.UploadID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("save upload session error: %w", err)
+	}
+	return nil
+}
+
+// loadUploadSession 加载会话，userID非空时校验归属
+func (s *objectStorageService) loadUploadSession(ctx context.Context, uploadID, userID string) (*uploadSession, error) {
+	data, err := s.redis.Get(ctx, fmt.Sprintf("upload:session:%s", uploadID)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrUploadSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get upload session error: %w", err)
+	}
+
+	var session uploadSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("unmarshal upload session error: %w", err)
+	}
+	if userID != "" && (session.UserID != userID || time.Now().After(session.ExpiresAt)) {
+		return nil, ErrUploadSessionNotFound
+	}
+
+	return &session, nil
+}
+
+// deleteUploadSession 删除会话
+func (s *objectStorageService) deleteUploadSession(ctx context.Context, uploadID string) {
+	s.redis.Del(ctx, fmt.Sprintf("upload:session:%s", uploadID))
+	s.redis.ZRem(ctx, uploadSessionsKey, uploadID)
+}
+
+// lockUploadSession 加锁防止同一会话并发写入
+func (s *objectStorageService) lockUploadSession(ctx context.Context, uploadID string) (func(), error) {
+	lockKey := fmt.Sprintf("upload:session:lock:%s", uploadID)
+	ok, err := s.redis.SetNX(ctx, lockKey, 1, 5*time.Minute).Result()
+	if err != nil {
+		return nil, fmt.Errorf("lock upload session error: %w", err)
+	}
+	if !ok {
+		return nil, ErrUploadSessionBusy
+	}
+	return func() { s.redis.Del(context.Background(), lockKey) }, nil
+}
+
+// saveHashStates 保存增量摘要状态
+func saveHashStates(session *uploadSession, md5Hasher, sha256Hasher hash.Hash) error {
+	md5State, err := md5Hasher.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("marshal md5 state error: %w", err)
+	}
+	sha256State, err := sha256Hasher.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("marshal sha256 state error: %w", err)
+	}
+	session.MD5State = md5State
+	session.SHA256State = sha256State
+	return nil
+}
+
+// restoreHashStates 恢复增量摘要状态
+func restoreHashStates(session *uploadSession) (hash.Hash, hash.Hash, error) {
+	md5Hasher := md5.New()
+	if err := md5Hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(session.MD5State); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal md5 state error: %w", err)
+	}
+	sha256Hasher := sha256.New()
+	if err := sha256Hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(session.SHA256State); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal sha256 state error: %w", err)
+	}
+	return md5Hasher, sha256Hasher, nil
+}
+
+// countingWriter 统计写入字节数
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// ErrSearchUnavailable 检索后端不可用
+var ErrSearchUnavailable = errors.New("message search is unavailable")
+
+// 全文检索限制
+const (
+	messageSearchDefaultPageSize = 20
+	messageSearchMaxPageSize     = 50
+	messageSearchMaxResults      = 1000 // 最多可翻页到的命中数
+)
+
+// MessageSearchRequest 消息全文检索请求
+type MessageSearchRequest struct {
+	Keyword        string `form:"keyword"`
+	ConversationID string `form:"conversation_id"` // 限定会话，为空时检索全部可见会话
+	SenderID       string `form:"sender_id"`       // 限定发送者
+	Since          int64  `form:"since"`           // 开始时间（毫秒时间戳）
+	Until          int64  `form:"until"`           // 结束时间（毫秒时间戳，不含）
+	Page           int    `form:"page"`
+	PageSize       int    `form:"page_size"`
+}
+
+// MessageSearchResult 消息全文检索结果（按时间倒序）
+type MessageSearchResult struct {
+	Total int64                    `json:"total"` // 命中总数（可翻页范围不超过前 1000 条）
+	Page  int                      `json:"page"`
+	Hits  []*ConversationSearchHit `json:"hits"`
+}
+
+// SearchService 消息全文检索服务
+// 检索用户参与的私聊及所在群聊中的文本消息，检索后端可替换（MongoDB 文本索引或 Elasticsearch）
+type SearchService interface {
+	// SearchMessages 按关键字检索消息，可限定会话、发送者和时间范围
+	SearchMessages(ctx context.Context, userID string, req *MessageSearchRequest) (*MessageSearchResult, error)
+}
+
+// searchServiceImpl 消息全文检索服务实现
+type searchServiceImpl struct {
+	index        repository.MessageSearchIndex
+	groupService GroupService
+}
+
+// NewSearchService 创建消息全文检索服务
+func NewSearchService(index repository.MessageSearchIndex, groupService GroupService) SearchService {
+	return &searchServiceImpl{index: index, groupService: groupService}
+}
+
+// SearchMessages 按关键字检索消息
+func (s *searchServiceImpl) SearchMessages(ctx context.Context, userID string, req *MessageSearchRequest) (*MessageSearchResult, error) {
+	keyword := strings.TrimSpace(req.Keyword)
+	if keyword == "" || utf8.RuneCountInString(keyword) > conversationSearchMaxKeyword {
+		return nil, ErrSearchKeywordInvalid
+	}
+	page, pageSize := req.Page, req.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > messageSearchMaxPageSize {
+		pageSize = messageSearchDefaultPageSize
+	}
+
+	query := &repository.MessageSearchQuery{
+		Keyword:  keyword,
+		SenderID: req.SenderID,
+		Offset:   (page - 1) * pageSize,
+		Limit:    pageSize,
+	}
+	if req.Since > 0 {
+		query.Since = time.UnixMilli(req.Since)
+	}
+	if req.Until > 0 {
+		query.Until = time.UnixMilli(req.Until)
+	}
+
+	if req.ConversationID != "" {
+		convID, err := authorizeConversation(ctx, s.groupService, userID, req.ConversationID)
+		if err != nil {
+			return nil, err
+		}
+		query.ConversationIDs = convID.Aliases()
+	} else {
+		groups, err := s.groupService.GetUserGroups(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("get user groups error: %w", err)
+		}
+		query.UserID = userID
+		for _, group := range groups {
+			query.GroupIDs = append(query.GroupIDs, group.GroupID)
+		}
+	}
+
+	result := &MessageSearchResult{Page: page, Hits: []*ConversationSearchHit{}}
+	if query.Offset >= messageSearchMaxResults {
+		return result, nil
+	}
+	if query.Offset+query.Limit > messageSearchMaxResults {
+		query.Limit = messageSearchMaxResults - query.Offset
+	}
+
+	docs, total, err := s.index.Search(ctx, query)
+	if err != nil {
+		log.Printf("search messages for %s error: %v", userID, err)
+		return nil, ErrSearchUnavailable
+	}
+	result.Total = total
+	for _, doc := range docs {
+		text, _ := doc.Content["text"].(string)
+		result.Hits = append(result.Hits, &ConversationSearchHit{
+			Message:    documentToDTO(doc),
+			Highlights: highlightRanges(text, keyword),
+			Anchor: &MessageAnchor{
+				MessageID: doc.MessageID,
+				Seq:       doc.Seq,
+				LastSeq:   doc.Seq + 1,
+				Timestamp: doc.CreatedAt.UnixMilli(),
+			},
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// sessionRevokedKeyPrefix 用户Token吊销时间（秒级时间戳），此前签发的Token全部失效
+const sessionRevokedKeyPrefix = "im:session:revoked_before:"
+
+// UserKicker 断开用户在集群中的全部连接（由网关层实现）
+type UserKicker interface {
+	KickUser(ctx context.Context, userID string) error
+}
+
+// SessionService 登录会话管理服务
+// 强制下线时记录吊销时间：此前签发的 Access Token 和 Refresh Token 均失效（REST、WebSocket 握手及刷新Token时校验），
+// 并断开用户在各节点上的连接。账号禁用、注销时自动强制下线。
+type SessionService interface {
+	// ForceLogout 强制用户下线
+	ForceLogout(ctx context.Context, userID string) error
+
+	// IsRevoked 签发时间为 issuedAt 的Token是否已被吊销
+	IsRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error)
+
+	// OnAccountEvent 账号禁用、注销时强制下线（实现 AccountLifecycleListener）
+	OnAccountEvent(ctx context.Context, userID string, event AccountEvent) error
+
+	// SetKicker 设置断开连接的网关实现，为空时只吊销Token
+	SetKicker(kicker UserKicker)
+}
+
+// sessionServiceImpl 登录会话管理服务实现
+type sessionServiceImpl struct {
+	userRepo repository.UserRepository
+	redis    *redis.Client
+	kicker   UserKicker
+	ttl      time.Duration
+}
+
+// NewSessionService 创建登录会话管理服务
+// ttl 为吊销记录的保留时间，不小于 Refresh Token 有效期（之后此前签发的Token已自然过期）
+func NewSessionService(userRepo repository.UserRepository, redisClient *redis.Client, ttl time.Duration) SessionService {
+	return &sessionServiceImpl{userRepo: userRepo, redis: redisClient, ttl: ttl}
+}
+
+// SetKicker 设置断开连接的网关实现
+func (s *sessionServiceImpl) SetKicker(kicker UserKicker) {
+	s.kicker = kicker
+}
+
+// ForceLogout 强制用户下线
+func (s *sessionServiceImpl) ForceLogout(ctx context.Context, userID string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	return s.revoke(ctx, userID)
+}
+
+// revoke 吊销此前签发的Token并断开连接
+func (s *sessionServiceImpl) revoke(ctx context.Context, userID string) error {
+	// Token签发时间为秒级，同一秒内签发的Token一并吊销
+	if err := s.redis.Set(ctx, sessionRevokedKeyPrefix+userID, time.Now().Unix(), s.ttl).Err(); err != nil {
+		return fmt.Errorf("revoke sessions of %s error: %w", userID, err)
+	}
+
+	if s.kicker != nil {
+		if err := s.kicker.KickUser(ctx, userID); err != nil {
+			// Token已吊销，断线的连接重连时会被拒绝
+			log.Printf("kick connections of %s error: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+// IsRevoked 签发时间不晚于吊销时间的Token视为已吊销，签发时间未知时不校验
+func (s *sessionServiceImpl) IsRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error) {
+	if issuedAt.IsZero() {
+		return false, nil
+	}
+	val, err := s.redis.Get(ctx, sessionRevokedKeyPrefix+userID).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	revokedBefore, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	return issuedAt.Unix() <= revokedBefore, nil
+}
+
+// OnAccountEvent 账号禁用、注销时强制下线
+func (s *sessionServiceImpl) OnAccountEvent(ctx context.Context, userID string, event AccountEvent) error {
+	if event != AccountDisabled && event != AccountDeleted {
+		return nil
+	}
+	return s.revoke(ctx, userID)
+}
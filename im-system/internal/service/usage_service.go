@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+)
+
+// ErrUsageMonthInvalid 用量月份格式错误
+var ErrUsageMonthInvalid = errors.New("invalid usage month")
+
+// UsageConfig 连接会话计量配置
+type UsageConfig struct {
+	FlushInterval time.Duration // 本地缓冲的会话记录写入间隔
+	MaxPending    int           // 缓冲的会话数达到该值时提前写入
+	MaxBuffered   int           // 写入失败时最多保留的会话数，超出的丢弃
+	Retention     time.Duration // 会话明细保留时长（月度汇总不清理），0 表示不清理
+}
+
+// DefaultUsageConfig 默认连接会话计量配置
+func DefaultUsageConfig() *UsageConfig {
+	return &UsageConfig{
+		FlushInterval: 10 * time.Second,
+		MaxPending:    1000,
+		MaxBuffered:   100000,
+		Retention:     90 * 24 * time.Hour,
+	}
+}
+
+// 会话明细清理
+const (
+	usageCleanupInterval = time.Hour
+	usageCleanupBatch    = 5000
+)
+
+// UsageQuery 月度用量查询请求
+type UsageQuery struct {
+	Month    string `form:"month"` // YYYY-MM（UTC），默认当月
+	TenantID string `form:"tenant_id"`
+	Sort     string `form:"sort" binding:"omitempty,oneof=duration bytes sessions"`
+	Page     int    `form:"page"`
+	PageSize int    `form:"page_size"`
+}
+
+// SessionListQuery 会话记录查询请求
+type SessionListQuery struct {
+	Since    int64 `form:"since"` // 断开时间下限（毫秒时间戳）
+	Until    int64 `form:"until"` // 断开时间上限（毫秒时间戳，不含）
+	Page     int   `form:"page"`
+	PageSize int   `form:"page_size"`
+}
+
+// UsageService 连接会话计量服务
+// 连接断开时记录会话（时长、近似流量），本地缓冲后批量写入会话明细并累加到用户月度用量，用于计费或内部分摊
+type UsageService interface {
+	// RecordSession 记录一次已结束的连接会话（只写入本地缓冲，不阻塞调用方）
+	RecordSession(session *model.ConnectionSession)
+
+	// GetUserUsage 查询用户某月用量，没有用量时返回零值
+	GetUserUsage(ctx context.Context, userID, month string) (*model.MonthlyUsage, error)
+
+	// ListUserUsage 分页查询某月各用户用量
+	ListUserUsage(ctx context.Context, query *UsageQuery) ([]*model.MonthlyUsage, int64, error)
+
+	// ListTenantUsage 按租户汇总某月用量
+	ListTenantUsage(ctx context.Context, month, tenantID string) ([]*model.TenantUsage, error)
+
+	// ListSessions 分页查询用户的会话记录
+	ListSessions(ctx context.Context, userID string, query *SessionListQuery) ([]*model.ConnectionSession, int64, error)
+
+	// Start 启动批量写入及过期明细清理，阻塞直到 ctx 取消（退出前写入剩余会话）
+	Start(ctx context.Context)
+}
+
+// usageServiceImpl 连接会话计量服务实现
+type usageServiceImpl struct {
+	repo     repository.UsageRepository
+	userRepo repository.UserRepository
+	config   *UsageConfig
+
+	mu      sync.Mutex
+	pending []*model.ConnectionSession
+	flushCh chan struct{}
+}
+
+// NewUsageService 创建连接会话计量服务
+func NewUsageService(repo repository.UsageRepository, userRepo repository.UserRepository, config *UsageConfig) UsageService {
+	if config == nil {
+		config = DefaultUsageConfig()
+	}
+	return &usageServiceImpl{
+		repo:     repo,
+		userRepo: userRepo,
+		config:   config,
+		flushCh:  make(chan struct{}, 1),
+	}
+}
+
+// RecordSession 记录一次已结束的连接会话
+func (s *usageServiceImpl) RecordSession(session *model.ConnectionSession) {
+	if session == nil || session.UserID == "" {
+		return
+	}
+	if session.DurationSeconds == 0 && session.DisconnectedAt.After(session.ConnectedAt) {
+		session.DurationSeconds = int64(session.DisconnectedAt.Sub(session.ConnectedAt) / time.Second)
+	}
+
+	s.mu.Lock()
+	s.pending = append(s.pending, session)
+	full := len(s.pending) >= s.config.MaxPending
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start 启动批量写入及过期明细清理
+func (s *usageServiceImpl) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(usageCleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// 使用独立上下文写入剩余会话（关闭时断开的连接也会计入）
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			s.flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			s.flush(ctx)
+		case <-s.flushCh:
+			s.flush(ctx)
+		case <-cleanup.C:
+			s.cleanup(ctx)
+		}
+	}
+}
+
+// flush 将缓冲的会话写入数据库，写入失败的会话放回缓冲等待下次写入
+func (s *usageServiceImpl) flush(ctx context.Context) {
+	s.mu.Lock()
+	if len(s.pending) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	sessions := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	s.fillTenants(ctx, sessions)
+	if err := s.repo.SaveSessions(ctx, sessions); err != nil {
+		log.Printf("Flush connection sessions: %d sessions failed, will retry: %v", len(sessions), err)
+		s.restore(sessions)
+	}
+}
+
+// fillTenants 补充会话所属租户（按写入时的用户租户计）
+func (s *usageServiceImpl) fillTenants(ctx context.Context, sessions []*model.ConnectionSession) {
+	seen := make(map[string]bool)
+	var userIDs []string
+	for _, session := range sessions {
+		if session.TenantID == "" && !seen[session.UserID] {
+			seen[session.UserID] = true
+			userIDs = append(userIDs, session.UserID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	users, err := s.userRepo.FindByIDs(ctx, userIDs)
+	if err != nil {
+		log.Printf("Find users for connection sessions error: %v", err)
+		return
+	}
+	tenants := make(map[string]string, len(users))
+	for _, user := range users {
+		tenants[user.UserID] = user.TenantID
+	}
+	for _, session := range sessions {
+		if session.TenantID == "" {
+			session.TenantID = tenants[session.UserID]
+		}
+	}
+}
+
+// restore 将写入失败的会话放回缓冲，超出上限时丢弃最早的会话
+func (s *usageServiceImpl) restore(sessions []*model.ConnectionSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = append(sessions, s.pending...)
+	if dropped := len(s.pending) - s.config.MaxBuffered; dropped > 0 {
+		log.Printf("Connection session buffer full, dropped %d sessions", dropped)
+		s.pending = s.pending[dropped:]
+	}
+}
+
+// cleanup 分批删除过期的会话明细
+func (s *usageServiceImpl) cleanup(ctx context.Context) {
+	if s.config.Retention <= 0 {
+		return
+	}
+	before := time.Now().Add(-s.config.Retention)
+	var total int64
+	for ctx.Err() == nil {
+		deleted, err := s.repo.DeleteSessionsBefore(ctx, before, usageCleanupBatch)
+		if err != nil {
+			log.Printf("Delete expired connection sessions error: %v", err)
+			break
+		}
+		total += deleted
+		if deleted < usageCleanupBatch {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("Deleted %d expired connection sessions", total)
+	}
+}
+
+// GetUserUsage 查询用户某月用量
+func (s *usageServiceImpl) GetUserUsage(ctx context.Context, userID, month string) (*model.MonthlyUsage, error) {
+	month, err := parseUsageMonth(month)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := s.repo.FindMonthly(ctx, month, userID)
+	if err != nil {
+		return nil, err
+	}
+	if usage == nil {
+		usage = &model.MonthlyUsage{Month: month, UserID: userID}
+	}
+	return usage, nil
+}
+
+// ListUserUsage 分页查询某月各用户用量
+func (s *usageServiceImpl) ListUserUsage(ctx context.Context, query *UsageQuery) ([]*model.MonthlyUsage, int64, error) {
+	month, err := parseUsageMonth(query.Month)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := &repository.MonthlyUsageQuery{
+		Month:    month,
+		TenantID: query.TenantID,
+		Offset:   (page - 1) * pageSize,
+		Limit:    pageSize,
+	}
+	switch query.Sort {
+	case "bytes", "sessions":
+		filter.OrderBy = query.Sort
+	default:
+		filter.OrderBy = "duration_seconds"
+	}
+	return s.repo.ListMonthly(ctx, filter)
+}
+
+// ListTenantUsage 按租户汇总某月用量
+func (s *usageServiceImpl) ListTenantUsage(ctx context.Context, month, tenantID string) ([]*model.TenantUsage, error) {
+	month, err := parseUsageMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.TenantTotals(ctx, month, tenantID)
+}
+
+// ListSessions 分页查询用户的会话记录
+func (s *usageServiceImpl) ListSessions(ctx context.Context, userID string, query *SessionListQuery) ([]*model.ConnectionSession, int64, error) {
+	page, pageSize := query.Page, query.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := &repository.SessionQuery{
+		UserID: userID,
+		Offset: (page - 1) * pageSize,
+		Limit:  pageSize,
+	}
+	if query.Since > 0 {
+		filter.Since = time.UnixMilli(query.Since)
+	}
+	if query.Until > 0 {
+		filter.Until = time.UnixMilli(query.Until)
+	}
+	return s.repo.ListSessions(ctx, filter)
+}
+
+// parseUsageMonth 校验用量月份，为空时取当前月份（UTC）
+func parseUsageMonth(month string) (string, error) {
+	if month == "" {
+		return time.Now().UTC().Format(repository.UsageMonthLayout), nil
+	}
+	if _, err := time.Parse(repository.UsageMonthLayout, month); err != nil {
+		return "", ErrUsageMonthInvalid
+	}
+	return month, nil
+}
@@ -0,0 +1,409 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/d60-lab/im-system/internal/model"
+	"github.com/d60-lab/im-system/internal/repository"
+	"github.com/d60-lab/im-system/pkg/util"
+)
+
+// 用户导入错误定义
+var (
+	ErrImportEmpty       = errors.New("no users to import")
+	ErrImportTooManyRows = errors.New("too many users in one import")
+)
+
+// 行校验错误（随行错误返回，不单独注册错误码）
+var (
+	errImportUsernameLength  = errors.New("username must be 3-32 characters")
+	errImportNicknameLength  = errors.New("nickname must be at most 32 characters")
+	errImportPasswordLength  = errors.New("password must be 6-32 characters")
+	errImportDuplicateInFile = errors.New("duplicate username in import")
+)
+
+// 初始密码策略
+const (
+	PasswordPolicyRandom   = "random"   // 随机生成初始密码，首次登录须修改
+	PasswordPolicyProvided = "provided" // 使用导入数据中的密码
+)
+
+// 导入行字段名（用于行错误定位）
+const (
+	importFieldUsername = "username"
+	importFieldNickname = "nickname"
+	importFieldPassword = "password"
+	importFieldGroups   = "groups"
+)
+
+// UserImportConfig 用户导入配置
+type UserImportConfig struct {
+	MaxRows        int // 单次导入最大行数
+	PasswordLength int // 随机初始密码长度
+}
+
+// DefaultUserImportConfig 默认用户导入配置
+func DefaultUserImportConfig() *UserImportConfig {
+	return &UserImportConfig{
+		MaxRows:        1000,
+		PasswordLength: 12,
+	}
+}
+
+// UserImportRow 导入行
+type UserImportRow struct {
+	Username string   `json:"username"`
+	Nickname string   `json:"nickname,omitempty"`
+	Password string   `json:"password,omitempty"` // 仅 provided 策略使用
+	GroupIDs []string `json:"group_ids,omitempty"`
+}
+
+// UserImportRequest 用户导入请求
+type UserImportRequest struct {
+	Users          []*UserImportRow `json:"users"`
+	TenantID       string           `json:"tenant_id,omitempty"`
+	PasswordPolicy string           `json:"password_policy,omitempty"` // random（默认）/ provided
+	ForceReset     bool             `json:"force_reset,omitempty"`     // provided 策略下是否要求首次登录修改密码（random 策略始终要求）
+	GroupIDs       []string         `json:"group_ids,omitempty"`       // 全部导入用户自动加入的群组
+	DryRun         bool             `json:"dry_run,omitempty"`         // 仅校验不创建
+	OperatorID     string           `json:"-"`
+}
+
+// ImportedUser 导入成功的用户
+type ImportedUser struct {
+	Row               int      `json:"row"`
+	UserID            string   `json:"user_id,omitempty"`
+	Username          string   `json:"username"`
+	Nickname          string   `json:"nickname"`
+	InitialPassword   string   `json:"initial_password,omitempty"` // 随机生成的初始密码（仅本次返回）
+	MustResetPassword bool     `json:"must_reset_password"`
+	Groups            []string `json:"groups,omitempty"`
+}
+
+// UserImportRowError 行错误
+type UserImportRowError struct {
+	Row      int    `json:"row"` // 从1开始的数据行号（不含表头）
+	Username string `json:"username,omitempty"`
+	Field    string `json:"field"`
+	GroupID  string `json:"group_id,omitempty"` // field 为 groups 时出错的群组
+	Code     int    `json:"code,omitempty"`
+	Message  string `json:"message"`
+	Err      error  `json:"-"`
+}
+
+// UserImportResult 导入结果
+type UserImportResult struct {
+	Total   int                   `json:"total"`
+	Created int                   `json:"created"`
+	Failed  int                   `json:"failed"`
+	DryRun  bool                  `json:"dry_run"`
+	Users   []*ImportedUser       `json:"users"`
+	Errors  []*UserImportRowError `json:"errors"`
+}
+
+// UserImportService 用户批量导入服务接口
+type UserImportService interface {
+	// Import 批量导入用户：逐行校验并创建，校验失败的行记录在结果中，不影响其他行
+	Import(ctx context.Context, req *UserImportRequest) (*UserImportResult, error)
+}
+
+// userImportServiceImpl 用户批量导入服务实现
+type userImportServiceImpl struct {
+	users        repository.UserRepository
+	naming       NamingService
+	groupService GroupService
+	config       *UserImportConfig
+}
+
+// NewUserImportService 创建用户批量导入服务
+func NewUserImportService(users repository.UserRepository, naming NamingService, groupService GroupService, config *UserImportConfig) UserImportService {
+	if config == nil {
+		config = DefaultUserImportConfig()
+	}
+	return &userImportServiceImpl{
+		users:        users,
+		naming:       naming,
+		groupService: groupService,
+		config:       config,
+	}
+}
+
+// Import 批量导入用户
+func (s *userImportServiceImpl) Import(ctx context.Context, req *UserImportRequest) (*UserImportResult, error) {
+	if len(req.Users) == 0 {
+		return nil, ErrImportEmpty
+	}
+	if len(req.Users) > s.config.MaxRows {
+		return nil, ErrImportTooManyRows
+	}
+	switch req.PasswordPolicy {
+	case "":
+		req.PasswordPolicy = PasswordPolicyRandom
+	case PasswordPolicyRandom, PasswordPolicyProvided:
+	default:
+		return nil, ErrInvalidRequest
+	}
+
+	result := &UserImportResult{
+		Total:  len(req.Users),
+		DryRun: req.DryRun,
+		Users:  []*ImportedUser{},
+		Errors: []*UserImportRowError{},
+	}
+
+	groupErrs := s.checkGroups(ctx, req)
+	seen := make(map[string]bool, len(req.Users))
+	groupMembers := make(map[string][]*ImportedUser)
+	var groupOrder []string
+
+	for i, row := range req.Users {
+		rowNum := i + 1
+		row.Username = strings.TrimSpace(row.Username)
+		row.Nickname = strings.TrimSpace(row.Nickname)
+		if row.Nickname == "" {
+			row.Nickname = row.Username
+		}
+
+		if field, err := s.validateRow(ctx, req, row, seen); err != nil {
+			result.Errors = append(result.Errors, &UserImportRowError{Row: rowNum, Username: row.Username, Field: field, Message: err.Error(), Err: err})
+			continue
+		}
+		groupIDs := mergeGroupIDs(req.GroupIDs, row.GroupIDs)
+		if groupID, err := firstGroupError(groupIDs, groupErrs); err != nil {
+			result.Errors = append(result.Errors, &UserImportRowError{Row: rowNum, Username: row.Username, Field: importFieldGroups, GroupID: groupID, Message: err.Error(), Err: err})
+			continue
+		}
+		seen[strings.ToLower(row.Username)] = true
+
+		imported := &ImportedUser{
+			Row:               rowNum,
+			Username:          row.Username,
+			Nickname:          row.Nickname,
+			MustResetPassword: req.PasswordPolicy == PasswordPolicyRandom || req.ForceReset,
+		}
+		if req.DryRun {
+			imported.Groups = groupIDs
+			result.Users = append(result.Users, imported)
+			continue
+		}
+
+		password := row.Password
+		if req.PasswordPolicy == PasswordPolicyRandom {
+			password = util.GeneratePassword(s.config.PasswordLength)
+			imported.InitialPassword = password
+		}
+		if err := s.createUser(ctx, req.TenantID, row, password, imported); err != nil {
+			result.Errors = append(result.Errors, &UserImportRowError{Row: rowNum, Username: row.Username, Field: importFieldUsername, Message: err.Error(), Err: err})
+			continue
+		}
+		result.Users = append(result.Users, imported)
+
+		for _, groupID := range groupIDs {
+			if _, ok := groupMembers[groupID]; !ok {
+				groupOrder = append(groupOrder, groupID)
+			}
+			groupMembers[groupID] = append(groupMembers[groupID], imported)
+		}
+	}
+
+	// 按群批量加入，入群失败不回滚已创建的用户
+	for _, groupID := range groupOrder {
+		members := groupMembers[groupID]
+		userIDs := make([]string, len(members))
+		for i, m := range members {
+			userIDs[i] = m.UserID
+		}
+		if err := s.groupService.AddMembers(ctx, groupID, req.OperatorID, userIDs); err != nil {
+			for _, m := range members {
+				result.Errors = append(result.Errors, &UserImportRowError{
+					Row:      m.Row,
+					Username: m.Username,
+					Field:    importFieldGroups,
+					GroupID:  groupID,
+					Message:  err.Error(),
+					Err:      err,
+				})
+			}
+			continue
+		}
+		for _, m := range members {
+			m.Groups = append(m.Groups, groupID)
+		}
+	}
+
+	result.Created = len(result.Users)
+	if req.DryRun {
+		result.Created = 0
+	}
+	result.Failed = result.Total - len(result.Users)
+	return result, nil
+}
+
+// checkGroups 校验请求涉及的全部群组，返回不可用群组的错误
+func (s *userImportServiceImpl) checkGroups(ctx context.Context, req *UserImportRequest) map[string]error {
+	errs := make(map[string]error)
+	checked := make(map[string]bool)
+	check := func(groupIDs []string) {
+		for _, groupID := range groupIDs {
+			if checked[groupID] {
+				continue
+			}
+			checked[groupID] = true
+			group, err := s.groupService.GetGroupInfo(ctx, groupID)
+			if err == nil && !group.IsActive() {
+				err = ErrGroupDismissed
+			}
+			if err != nil {
+				errs[groupID] = err
+			}
+		}
+	}
+
+	check(req.GroupIDs)
+	for _, row := range req.Users {
+		check(row.GroupIDs)
+	}
+	return errs
+}
+
+// validateRow 校验导入行，返回出错字段及错误
+func (s *userImportServiceImpl) validateRow(ctx context.Context, req *UserImportRequest, row *UserImportRow, seen map[string]bool) (string, error) {
+	if n := utf8.RuneCountInString(row.Username); n < 3 || n > 32 {
+		return importFieldUsername, errImportUsernameLength
+	}
+	if seen[strings.ToLower(row.Username)] {
+		return importFieldUsername, errImportDuplicateInFile
+	}
+	if utf8.RuneCountInString(row.Nickname) > 32 {
+		return importFieldNickname, errImportNicknameLength
+	}
+	if req.PasswordPolicy == PasswordPolicyProvided {
+		if n := len(row.Password); n < 6 || n > 32 {
+			return importFieldPassword, errImportPasswordLength
+		}
+	}
+	if err := s.naming.CheckUsername(ctx, row.Username); err != nil {
+		return importFieldUsername, err
+	}
+	if err := s.naming.CheckNickname(ctx, req.TenantID, "", row.Nickname); err != nil {
+		return importFieldNickname, err
+	}
+	return "", nil
+}
+
+// firstGroupError 返回行内第一个不可用的群组及其错误
+func firstGroupError(groupIDs []string, groupErrs map[string]error) (string, error) {
+	for _, groupID := range groupIDs {
+		if err := groupErrs[groupID]; err != nil {
+			return groupID, err
+		}
+	}
+	return "", nil
+}
+
+// createUser 创建用户
+func (s *userImportServiceImpl) createUser(ctx context.Context, tenantID string, row *UserImportRow, password string, imported *ImportedUser) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("hash password error: %w", err)
+	}
+
+	now := time.Now()
+	user := &model.User{
+		UserID:            util.GenerateUserID(),
+		TenantID:          tenantID,
+		Username:          row.Username,
+		Nickname:          row.Nickname,
+		PasswordHash:      string(hashedPassword),
+		Status:            model.UserStatusNormal,
+		MustResetPassword: imported.MustResetPassword,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		return fmt.Errorf("create user error: %w", err)
+	}
+	imported.UserID = user.UserID
+	return nil
+}
+
+// mergeGroupIDs 合并公共群组与行群组（去重、保持顺序）
+func mergeGroupIDs(common, own []string) []string {
+	var merged []string
+	seen := make(map[string]bool, len(common)+len(own))
+	for _, ids := range [][]string{common, own} {
+		for _, id := range ids {
+			if id = strings.TrimSpace(id); id != "" && !seen[id] {
+				seen[id] = true
+				merged = append(merged, id)
+			}
+		}
+	}
+	return merged
+}
+
+// ParseUserImportCSV 解析导入CSV
+// 首行为表头（不区分大小写）：username（必填）、nickname、password、groups（多个群ID以 ; 分隔），未知列忽略
+func ParseUserImportCSV(r io.Reader) ([]*UserImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrImportEmpty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: read csv header: %v", ErrInvalidRequest, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+	if _, ok := columns[importFieldUsername]; !ok {
+		return nil, fmt.Errorf("%w: csv header missing username column", ErrInvalidRequest)
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []*UserImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: read csv: %v", ErrInvalidRequest, err)
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue // 空行
+		}
+
+		row := &UserImportRow{
+			Username: field(record, importFieldUsername),
+			Nickname: field(record, importFieldNickname),
+			Password: field(record, importFieldPassword),
+		}
+		if groups := field(record, importFieldGroups); groups != "" {
+			row.GroupIDs = strings.Split(groups, ";")
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,542 @@
+// Package archive 提供压缩包安全检查（条目数、压缩比、嵌套层数），只读取目录/文件头，不完整解压
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// 压缩包格式
+const (
+	FormatZip      = "zip"
+	FormatTar      = "tar"
+	FormatGzip     = "gzip"
+	FormatRar      = "rar"
+	FormatSevenZip = "7z"
+)
+
+// 检查错误定义
+var (
+	ErrSuspicious  = errors.New("archive: suspicious archive")
+	ErrUnsupported = errors.New("archive: unsupported format")
+	ErrMalformed   = errors.New("archive: malformed archive")
+)
+
+// Limits 检查阈值（为0表示不限制）
+type Limits struct {
+	MaxEntries     int     // 最大条目数（含嵌套压缩包内的条目）
+	MaxTotalSize   int64   // 解压后总大小
+	MaxRatio       float64 // 最大压缩比（整体及单个大条目）
+	MaxNestedDepth int     // 最大嵌套层数（压缩包内的压缩包）
+	MaxNestedSize  int64   // 检查嵌套zip时最多读入内存的大小，超过时只计层数不展开
+	MaxListEntries int     // 报告中列出的条目数上限
+}
+
+// DefaultLimits 默认检查阈值
+func DefaultLimits() *Limits {
+	return &Limits{
+		MaxEntries:     10000,
+		MaxTotalSize:   1 << 30, // 1GB
+		MaxRatio:       100,
+		MaxNestedDepth: 2,
+		MaxNestedSize:  20 << 20, // 20MB
+		MaxListEntries: 200,
+	}
+}
+
+// ratioCheckMinSize 单个条目解压后超过该大小才检查压缩比，避免误判高度重复的小文件
+const ratioCheckMinSize = 1 << 20
+
+// Entry 压缩包条目
+type Entry struct {
+	Name           string `json:"name"`
+	Size           int64  `json:"size"`
+	CompressedSize int64  `json:"compressed_size,omitempty"`
+	Dir            bool   `json:"dir,omitempty"`
+}
+
+// Report 检查结果
+type Report struct {
+	Format         string  `json:"format"`
+	Entries        int     `json:"entries"`
+	TotalSize      int64   `json:"total_size"`      // 解压后总大小（按头部声明）
+	CompressedSize int64   `json:"compressed_size"` // 压缩包大小
+	Ratio          float64 `json:"ratio"`
+	NestedDepth    int     `json:"nested_depth"`
+	Encrypted      bool    `json:"encrypted,omitempty"`
+	Inspected      bool    `json:"inspected"` // false表示格式无法在不解压的情况下检查（如7z、加密头的rar）
+	Files          []Entry `json:"files,omitempty"`
+	Truncated      bool    `json:"truncated,omitempty"` // Files是否因MaxListEntries被截断
+}
+
+// Detect 根据文件头识别压缩包格式，不是压缩包时返回空字符串
+func Detect(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return FormatZip
+	case bytes.HasPrefix(head, []byte("\x1f\x8b")):
+		return FormatGzip
+	case bytes.HasPrefix(head, []byte("Rar!\x1a\x07")):
+		return FormatRar
+	case bytes.HasPrefix(head, []byte("7z\xbc\xaf\x27\x1c")):
+		return FormatSevenZip
+	case len(head) >= 262 && bytes.Equal(head[257:262], []byte("ustar")):
+		return FormatTar
+	}
+	return ""
+}
+
+// IsArchiveName 根据文件名判断是否为压缩包
+func IsArchiveName(name string) bool {
+	switch strings.ToLower(strings.TrimPrefix(path.Ext(name), ".")) {
+	case "zip", "rar", "7z", "tar", "gz", "tgz", "bz2", "xz", "jar":
+		return true
+	}
+	return false
+}
+
+// Inspect 检查压缩包，format为空时根据文件头识别；超出阈值时返回ErrSuspicious
+func Inspect(r io.ReaderAt, size int64, format string, limits *Limits) (*Report, error) {
+	if limits == nil {
+		limits = DefaultLimits()
+	}
+	if format == "" {
+		head := make([]byte, 512)
+		n, _ := r.ReadAt(head, 0)
+		format = Detect(head[:n])
+	}
+
+	in := &inspector{limits: limits, report: &Report{Format: format, CompressedSize: size, Inspected: true}}
+	var err error
+	switch format {
+	case FormatZip:
+		err = in.zip(r, size, 0)
+	case FormatTar:
+		err = in.tar(io.NewSectionReader(r, 0, size))
+	case FormatGzip:
+		err = in.gzip(r, size)
+	case FormatRar:
+		err = in.rar(io.NewSectionReader(r, 0, size))
+	case FormatSevenZip:
+		in.report.Inspected = false
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if size > 0 {
+		in.report.Ratio = float64(in.report.TotalSize) / float64(size)
+	}
+	if limits.MaxRatio > 0 && in.report.TotalSize > ratioCheckMinSize && in.report.Ratio > limits.MaxRatio {
+		return nil, fmt.Errorf("%w: compression ratio %.0f exceeds %.0f", ErrSuspicious, in.report.Ratio, limits.MaxRatio)
+	}
+	return in.report, nil
+}
+
+// inspector 检查状态（嵌套压缩包的统计累加到同一份报告）
+type inspector struct {
+	limits *Limits
+	report *Report
+}
+
+// add 登记一个条目并检查条目数、总大小、单条目压缩比
+func (in *inspector) add(name string, size, compressed int64, dir bool, depth int) error {
+	in.report.Entries++
+	in.report.TotalSize += size
+
+	// 路径穿越（zip slip）
+	clean := strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(clean, "/") || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, "/../") {
+		return fmt.Errorf("%w: entry %q escapes the extraction directory", ErrSuspicious, name)
+	}
+
+	if in.limits.MaxEntries > 0 && in.report.Entries > in.limits.MaxEntries {
+		return fmt.Errorf("%w: more than %d entries", ErrSuspicious, in.limits.MaxEntries)
+	}
+	if in.limits.MaxTotalSize > 0 && in.report.TotalSize > in.limits.MaxTotalSize {
+		return fmt.Errorf("%w: uncompressed size exceeds %d bytes", ErrSuspicious, in.limits.MaxTotalSize)
+	}
+	if in.limits.MaxRatio > 0 && compressed > 0 && size > ratioCheckMinSize && float64(size)/float64(compressed) > in.limits.MaxRatio {
+		return fmt.Errorf("%w: entry %q compression ratio exceeds %.0f", ErrSuspicious, name, in.limits.MaxRatio)
+	}
+
+	// 只列出顶层条目
+	if depth == 0 {
+		if in.limits.MaxListEntries > 0 && len(in.report.Files) >= in.limits.MaxListEntries {
+			in.report.Truncated = true
+		} else {
+			in.report.Files = append(in.report.Files, Entry{Name: name, Size: size, CompressedSize: compressed, Dir: dir})
+		}
+	}
+	return nil
+}
+
+// nested 登记一层嵌套压缩包并检查嵌套层数
+func (in *inspector) nested(name string, depth int) error {
+	if depth+1 > in.report.NestedDepth {
+		in.report.NestedDepth = depth + 1
+	}
+	if in.limits.MaxNestedDepth > 0 && in.report.NestedDepth > in.limits.MaxNestedDepth {
+		return fmt.Errorf("%w: %q nested deeper than %d levels", ErrSuspicious, name, in.limits.MaxNestedDepth)
+	}
+	return nil
+}
+
+// zip 读取中央目录检查zip，嵌套的zip在大小限制内读入内存递归检查
+func (in *inspector) zip(r io.ReaderAt, size int64, depth int) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	for _, f := range zr.File {
+		if f.Flags&0x1 != 0 {
+			in.report.Encrypted = true
+		}
+		if err := in.add(f.Name, int64(f.UncompressedSize64), int64(f.CompressedSize64), f.FileInfo().IsDir(), depth); err != nil {
+			return err
+		}
+		if !IsArchiveName(f.Name) {
+			continue
+		}
+		if err := in.nested(f.Name, depth); err != nil {
+			return err
+		}
+
+		// 嵌套zip：在大小限制内读入内存继续检查（读取时按限制截断，防止头部声明的大小不实）
+		if strings.ToLower(path.Ext(f.Name)) != ".zip" || f.Flags&0x1 != 0 ||
+			in.limits.MaxNestedSize <= 0 || int64(f.UncompressedSize64) > in.limits.MaxNestedSize {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, in.limits.MaxNestedSize+1))
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		if int64(len(data)) > in.limits.MaxNestedSize {
+			return fmt.Errorf("%w: entry %q is larger than declared", ErrSuspicious, f.Name)
+		}
+		if err := in.zip(bytes.NewReader(data), int64(len(data)), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tar 逐个读取tar头部（跳过文件数据）
+func (in *inspector) tar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if errors.Is(err, ErrSuspicious) {
+				return err
+			}
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		if err := in.add(hdr.Name, hdr.Size, 0, hdr.Typeflag == tar.TypeDir, 0); err != nil {
+			return err
+		}
+		if IsArchiveName(hdr.Name) {
+			if err := in.nested(hdr.Name, 0); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// gzip 流式解压计数（不落盘），内容为tar时检查其中的条目；超出总大小限制立即停止
+func (in *inspector) gzip(r io.ReaderAt, size int64) error {
+	gr, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	defer gr.Close()
+
+	counter := &limitedCounter{r: gr, limit: in.limits.MaxTotalSize}
+	if size > 0 && in.limits.MaxRatio > 0 {
+		// 压缩比超限时同样提前停止
+		if maxByRatio := int64(float64(size) * in.limits.MaxRatio); maxByRatio > ratioCheckMinSize &&
+			(counter.limit <= 0 || maxByRatio < counter.limit) {
+			counter.limit = maxByRatio
+		}
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(counter, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return wrapRead(err)
+	}
+	body := io.MultiReader(bytes.NewReader(head[:n]), counter)
+
+	if Detect(head[:n]) == FormatTar {
+		in.report.Format = FormatTar + "+" + FormatGzip
+		if err := in.tar(body); err != nil {
+			return err
+		}
+		// 读完tar结束块之后的剩余数据
+		if _, err := io.Copy(io.Discard, counter); err != nil {
+			return wrapRead(err)
+		}
+		// 以实际解压大小为准
+		in.report.TotalSize = counter.n
+		return nil
+	}
+
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return wrapRead(err)
+	}
+	return in.add(strings.TrimSuffix(gr.Name, "/"), counter.n, size, false, 0)
+}
+
+// limitedCounter 统计读取字节数，超过limit时返回ErrSuspicious
+type limitedCounter struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func (c *limitedCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if c.limit > 0 && c.n > c.limit {
+		return n, fmt.Errorf("%w: decompressed size exceeds %d bytes", ErrSuspicious, c.limit)
+	}
+	return n, err
+}
+
+// wrapRead 包装读取错误，保留ErrSuspicious
+func wrapRead(err error) error {
+	if errors.Is(err, ErrSuspicious) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrMalformed, err)
+}
+
+// rar 读取RAR4/RAR5文件头（头部未加密时），根据声明的大小检查
+func (in *inspector) rar(r *io.SectionReader) error {
+	sig := make([]byte, 8)
+	if _, err := r.ReadAt(sig, 0); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if bytes.Equal(sig, []byte("Rar!\x1a\x07\x01\x00")) {
+		return in.rar5(r, 8)
+	}
+	return in.rar4(r, 7)
+}
+
+// rar4 遍历RAR 1.5-4.x 块头
+func (in *inspector) rar4(r *io.SectionReader, offset int64) error {
+	const (
+		headMain      = 0x73
+		headFile      = 0x74
+		headEnd       = 0x7b
+		flagLongBlock = 0x8000
+		flagLargeFile = 0x0100
+		flagDirMask   = 0x00e0
+		mainEncrypted = 0x0080
+	)
+
+	buf := make([]byte, 32)
+	for offset < r.Size() {
+		if _, err := r.ReadAt(buf[:7], offset); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		headType := buf[2]
+		flags := binary.LittleEndian.Uint16(buf[3:5])
+		headSize := int64(binary.LittleEndian.Uint16(buf[5:7]))
+		if headSize < 7 {
+			return ErrMalformed
+		}
+
+		var addSize int64
+		if flags&flagLongBlock != 0 {
+			if _, err := r.ReadAt(buf[:4], offset+7); err != nil {
+				return fmt.Errorf("%w: %v", ErrMalformed, err)
+			}
+			addSize = int64(binary.LittleEndian.Uint32(buf[:4]))
+		}
+
+		switch headType {
+		case headMain:
+			if flags&mainEncrypted != 0 {
+				// 头部加密，无法读取文件列表
+				in.report.Encrypted = true
+				in.report.Inspected = false
+				return nil
+			}
+		case headFile:
+			hdr := make([]byte, headSize)
+			if _, err := r.ReadAt(hdr, offset); err != nil || headSize < 32 {
+				return ErrMalformed
+			}
+			packSize := int64(binary.LittleEndian.Uint32(hdr[7:11]))
+			unpSize := int64(binary.LittleEndian.Uint32(hdr[11:15]))
+			nameSize := int64(binary.LittleEndian.Uint16(hdr[26:28]))
+			nameOffset := int64(32)
+			if flags&flagLargeFile != 0 {
+				if headSize < 40 {
+					return ErrMalformed
+				}
+				packSize |= int64(binary.LittleEndian.Uint32(hdr[32:36])) << 32
+				unpSize |= int64(binary.LittleEndian.Uint32(hdr[36:40])) << 32
+				nameOffset = 40
+			}
+			if nameOffset+nameSize > headSize {
+				return ErrMalformed
+			}
+			name := string(hdr[nameOffset : nameOffset+nameSize])
+			if i := strings.IndexByte(name, 0); i >= 0 {
+				name = name[:i] // Unicode文件名附加在ASCII名之后
+			}
+			if flags&0x0004 != 0 {
+				in.report.Encrypted = true
+			}
+			if err := in.add(name, unpSize, packSize, flags&flagDirMask == flagDirMask, 0); err != nil {
+				return err
+			}
+			addSize = packSize // 文件块的ADD_SIZE即压缩数据大小
+			if IsArchiveName(name) {
+				if err := in.nested(name, 0); err != nil {
+					return err
+				}
+			}
+		case headEnd:
+			return nil
+		}
+
+		offset += headSize + addSize
+	}
+	return nil
+}
+
+// rar5 遍历RAR 5.x 块头
+func (in *inspector) rar5(r *io.SectionReader, offset int64) error {
+	const (
+		headFile       = 2
+		headEncryption = 4
+		headEnd        = 5
+		flagExtra      = 0x1
+		flagData       = 0x2
+	)
+
+	buf := make([]byte, 4+3*binary.MaxVarintLen64)
+	for offset < r.Size() {
+		n, err := r.ReadAt(buf, offset)
+		if err != nil && err != io.EOF || n <= 4 {
+			return ErrMalformed
+		}
+		headSize, l := binary.Uvarint(buf[4:n])
+		if l <= 0 || headSize == 0 || headSize > 2<<20 {
+			return ErrMalformed
+		}
+		headStart := offset + 4 + int64(l)
+
+		hdr := make([]byte, headSize)
+		if _, err := r.ReadAt(hdr, headStart); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		vr := &varintReader{b: hdr}
+		headType := vr.next()
+		flags := vr.next()
+		if flags&flagExtra != 0 {
+			vr.next()
+		}
+		var dataSize uint64
+		if flags&flagData != 0 {
+			dataSize = vr.next()
+		}
+
+		switch headType {
+		case headEncryption:
+			in.report.Encrypted = true
+			in.report.Inspected = false
+			return nil
+		case headFile:
+			fileFlags := vr.next()
+			unpSize := vr.next()
+			vr.next() // attributes
+			if fileFlags&0x2 != 0 {
+				vr.skip(4) // mtime
+			}
+			if fileFlags&0x4 != 0 {
+				vr.skip(4) // crc32
+			}
+			vr.next() // compression info
+			vr.next() // host os
+			nameLen := vr.next()
+			name := vr.bytes(int(nameLen))
+			if vr.err {
+				return ErrMalformed
+			}
+			if err := in.add(string(name), int64(unpSize), int64(dataSize), fileFlags&0x1 != 0, 0); err != nil {
+				return err
+			}
+			if IsArchiveName(string(name)) {
+				if err := in.nested(string(name), 0); err != nil {
+					return err
+				}
+			}
+		case headEnd:
+			return nil
+		}
+		if vr.err {
+			return ErrMalformed
+		}
+
+		offset = headStart + int64(headSize) + int64(dataSize)
+	}
+	return nil
+}
+
+// varintReader 读取RAR5头部中的变长整数
+type varintReader struct {
+	b   []byte
+	pos int
+	err bool
+}
+
+func (v *varintReader) next() uint64 {
+	if v.pos >= len(v.b) {
+		v.err = true
+		return 0
+	}
+	x, n := binary.Uvarint(v.b[v.pos:])
+	if n <= 0 {
+		v.err = true
+		return 0
+	}
+	v.pos += n
+	return x
+}
+
+func (v *varintReader) skip(n int) {
+	v.bytes(n)
+}
+
+func (v *varintReader) bytes(n int) []byte {
+	if n < 0 || v.pos+n > len(v.b) {
+		v.err = true
+		return nil
+	}
+	b := v.b[v.pos : v.pos+n]
+	v.pos += n
+	return b
+}
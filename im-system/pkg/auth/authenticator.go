@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 认证方式
+const (
+	ProviderJWT           = "jwt"           // 本地签发的JWT（默认）
+	ProviderIntrospection = "introspection" // OAuth2 Token Introspection（RFC 7662）远程校验
+	ProviderAPIKey        = "apikey"        // 静态API Key
+)
+
+// ErrAuthUnavailable 认证服务不可用（远程校验失败等），与凭证无效区分
+var ErrAuthUnavailable = errors.New("authentication service unavailable")
+
+// Identity 认证通过的身份
+type Identity struct {
+	UserID   string
+	Username string
+	Platform string
+	DeviceID string
+	Guest    bool // 访客
+
+	PasswordReset bool // 须修改初始密码，只能修改密码和登出
+
+	IssuedAt time.Time // 凭证签发时间，未知时为零值（不参与吊销校验）
+}
+
+// Authenticator 认证提供者，REST鉴权中间件和WebSocket握手共用
+type Authenticator interface {
+	// Authenticate 校验访问凭证（已去掉 Bearer 前缀），返回身份
+	Authenticate(ctx context.Context, token string) (*Identity, error)
+}
+
+// AuthenticatorConfig 认证提供者配置
+type AuthenticatorConfig struct {
+	Provider string // 认证方式，为空时使用JWT
+
+	// OAuth2 Token Introspection
+	IntrospectionURL          string        // 校验端点
+	IntrospectionClientID     string        // 调用端点的客户端凭证（HTTP Basic）
+	IntrospectionClientSecret string        //
+	IntrospectionUserClaim    string        // 作为用户ID的字段，默认 sub
+	IntrospectionTimeout      time.Duration // 请求超时
+	IntrospectionCacheTTL     time.Duration // 校验结果缓存时间（不超过Token过期时间），0表示不缓存
+
+	// 静态API Key，key -> 用户ID
+	APIKeys map[string]string
+}
+
+// NewAuthenticator 根据配置创建认证提供者，JWT方式使用传入的 jwtManager
+func NewAuthenticator(config *AuthenticatorConfig, jwtManager *JWTManager) (Authenticator, error) {
+	if config == nil || config.Provider == "" {
+		return jwtManager, nil
+	}
+
+	switch strings.ToLower(config.Provider) {
+	case ProviderJWT:
+		return jwtManager, nil
+	case ProviderIntrospection:
+		if config.IntrospectionURL == "" {
+			return nil, errors.New("auth: introspection url is required")
+		}
+		return newIntrospectionAuthenticator(config), nil
+	case ProviderAPIKey:
+		if len(config.APIKeys) == 0 {
+			return nil, errors.New("auth: at least one api key is required")
+		}
+		return newAPIKeyAuthenticator(config.APIKeys), nil
+	default:
+		return nil, fmt.Errorf("auth: unknown authentication provider %q", config.Provider)
+	}
+}
+
+// ParseAPIKeys 解析 key:userID 逗号分隔的API Key列表
+func ParseAPIKeys(value string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, userID, ok := strings.Cut(item, ":")
+		if !ok || key == "" || userID == "" {
+			return nil, fmt.Errorf("auth: invalid api key entry %q, expected key:user_id", item)
+		}
+		keys[key] = userID
+	}
+	return keys, nil
+}
+
+// Authenticate 校验JWT（实现 Authenticator 接口）
+func (m *JWTManager) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	claims, err := m.ParseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{
+		UserID:   claims.UserID,
+		Username: claims.Username,
+		Platform: claims.Platform,
+		DeviceID: claims.DeviceID,
+		Guest:    claims.Guest,
+		IssuedAt: issuedAt(claims),
+
+		PasswordReset: claims.PasswordReset,
+	}, nil
+}
+
+// issuedAt 凭证签发时间
+func issuedAt(claims *Claims) time.Time {
+	if claims.IssuedAt == nil {
+		return time.Time{}
+	}
+	return claims.IssuedAt.Time
+}
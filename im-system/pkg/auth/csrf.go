@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// OriginAllowed 检查请求来源是否允许
+// 支持 "*"（全部允许）、完整来源（https://chat.example.com）和子域通配（https://*.example.com）；
+// 与请求Host同源的来源始终允许，allowed为空时仅允许同源
+func OriginAllowed(origin, host string, allowed []string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, host) {
+		return true
+	}
+
+	origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSuffix(pattern, "/"))
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		// 子域通配: scheme://*.domain
+		if idx := strings.Index(pattern, "://*."); idx > 0 {
+			scheme, domain := pattern[:idx], pattern[idx+len("://*"):]
+			if strings.EqualFold(u.Scheme, scheme) && strings.HasSuffix(strings.ToLower(u.Host), domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GenerateCSRFToken 生成与会话Token绑定的CSRF Token
+func GenerateCSRFToken(secret, sessionToken string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("csrf|" + sessionToken))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyCSRFToken 校验CSRF Token
+func VerifyCSRFToken(secret, sessionToken, csrfToken string) bool {
+	if sessionToken == "" || csrfToken == "" {
+		return false
+	}
+	expected := GenerateCSRFToken(secret, sessionToken)
+	return hmac.Equal([]byte(expected), []byte(csrfToken))
+}
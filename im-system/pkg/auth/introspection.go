@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// introspectionCacheMax 校验结果缓存的最大条目数，超出时清理过期条目
+const introspectionCacheMax = 10000
+
+// introspectionResponse Token Introspection 响应（RFC 7662）
+type introspectionResponse struct {
+	Active   bool   `json:"active"`
+	Username string `json:"username"`
+	Exp      int64  `json:"exp"`
+	Iat      int64  `json:"iat"`
+}
+
+// introspectionEntry 缓存的校验结果
+type introspectionEntry struct {
+	identity *Identity
+	expireAt time.Time
+}
+
+// introspectionAuthenticator 通过 OAuth2 Token Introspection 端点远程校验Token
+type introspectionAuthenticator struct {
+	endpoint     string
+	clientID     string
+	clientSecret string
+	userClaim    string
+	cacheTTL     time.Duration
+	client       *http.Client
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]*introspectionEntry
+}
+
+// newIntrospectionAuthenticator 创建远程校验认证提供者
+func newIntrospectionAuthenticator(config *AuthenticatorConfig) *introspectionAuthenticator {
+	timeout := config.IntrospectionTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	userClaim := config.IntrospectionUserClaim
+	if userClaim == "" {
+		userClaim = "sub"
+	}
+	return &introspectionAuthenticator{
+		endpoint:     config.IntrospectionURL,
+		clientID:     config.IntrospectionClientID,
+		clientSecret: config.IntrospectionClientSecret,
+		userClaim:    userClaim,
+		cacheTTL:     config.IntrospectionCacheTTL,
+		client:       &http.Client{Timeout: timeout},
+		cache:        make(map[[sha256.Size]byte]*introspectionEntry),
+	}
+}
+
+// Authenticate 校验Token，有效结果在缓存时间内复用
+func (a *introspectionAuthenticator) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	key := sha256.Sum256([]byte(token))
+	if identity := a.cached(key); identity != nil {
+		return identity, nil
+	}
+
+	identity, expireAt, err := a.introspect(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	a.store(key, identity, expireAt)
+	return identity, nil
+}
+
+// introspect 调用校验端点
+func (a *introspectionAuthenticator) introspect(ctx context.Context, token string) (*Identity, time.Time, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if a.clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(a.clientID), url.QueryEscape(a.clientSecret))
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: %v", ErrAuthUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, fmt.Errorf("%w: introspection returned status %d", ErrAuthUnavailable, resp.StatusCode)
+	}
+
+	// 先按通用map解析，以便读取配置的用户ID字段
+	var claims map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: decode introspection response: %v", ErrAuthUnavailable, err)
+	}
+	var result introspectionResponse
+	raw, _ := json.Marshal(claims)
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: decode introspection response: %v", ErrAuthUnavailable, err)
+	}
+
+	if !result.Active {
+		return nil, time.Time{}, ErrInvalidToken
+	}
+	var expireAt time.Time
+	if result.Exp > 0 {
+		expireAt = time.Unix(result.Exp, 0)
+		if !expireAt.After(time.Now()) {
+			return nil, time.Time{}, ErrExpiredToken
+		}
+	}
+
+	userID, _ := claims[a.userClaim].(string)
+	if userID == "" {
+		return nil, time.Time{}, ErrMissingUserID
+	}
+	identity := &Identity{UserID: userID, Username: result.Username}
+	if result.Iat > 0 {
+		identity.IssuedAt = time.Unix(result.Iat, 0)
+	}
+	return identity, expireAt, nil
+}
+
+// cached 查询未过期的缓存结果
+func (a *introspectionAuthenticator) cached(key [sha256.Size]byte) *Identity {
+	if a.cacheTTL <= 0 {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.cache[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.expireAt) {
+		delete(a.cache, key)
+		return nil
+	}
+	return entry.identity
+}
+
+// store 缓存校验结果，缓存时间不超过Token过期时间
+func (a *introspectionAuthenticator) store(key [sha256.Size]byte, identity *Identity, tokenExpireAt time.Time) {
+	if a.cacheTTL <= 0 {
+		return
+	}
+	expireAt := time.Now().Add(a.cacheTTL)
+	if !tokenExpireAt.IsZero() && tokenExpireAt.Before(expireAt) {
+		expireAt = tokenExpireAt
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.cache) >= introspectionCacheMax {
+		now := time.Now()
+		for k, entry := range a.cache {
+			if now.After(entry.expireAt) {
+				delete(a.cache, k)
+			}
+		}
+		if len(a.cache) >= introspectionCacheMax {
+			return
+		}
+	}
+	a.cache[key] = &introspectionEntry{identity: identity, expireAt: expireAt}
+}
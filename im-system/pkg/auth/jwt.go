@@ -2,6 +2,9 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -23,6 +26,10 @@ type JWTConfig struct {
 	Issuer        string        `json:"issuer"`
 	Expire        time.Duration `json:"expire"`         // Access Token过期时间
 	RefreshExpire time.Duration `json:"refresh_expire"` // Refresh Token过期时间
+
+	// 密钥轮换：更换 Secret 后，旧密钥签发的 Token 在宽限期内仍可验证（新 Token 只用 Secret 签发）
+	PreviousSecrets      []string  `json:"previous_secrets"`
+	PreviousSecretsUntil time.Time `json:"previous_secrets_until"` // 旧密钥宽限期截止时间，为零值时一直有效
 }
 
 // DefaultJWTConfig 默认JWT配置
@@ -41,12 +48,29 @@ type Claims struct {
 	Username string `json:"username"`
 	Platform string `json:"platform,omitempty"` // web, ios, android
 	DeviceID string `json:"device_id,omitempty"`
+	Guest    bool   `json:"guest,omitempty"` // 访客Token（不可刷新）
+
+	PasswordReset bool `json:"pwd_reset,omitempty"` // 须修改初始密码，只能用于修改密码和登出（刷新后保持）
 	jwt.RegisteredClaims
 }
 
+// signingKey 签名密钥及其标识（Token 头部的 kid）
+type signingKey struct {
+	id     string
+	secret []byte
+}
+
+// newSigningKey 创建签名密钥，标识取密钥摘要的前8字节（不泄露密钥本身）
+func newSigningKey(secret string) signingKey {
+	sum := sha256.Sum256([]byte(secret))
+	return signingKey{id: hex.EncodeToString(sum[:8]), secret: []byte(secret)}
+}
+
 // JWTManager JWT管理器
 type JWTManager struct {
-	config *JWTConfig
+	config   *JWTConfig
+	current  signingKey
+	previous []signingKey
 }
 
 // NewJWTManager 创建JWT管理器
@@ -54,7 +78,54 @@ func NewJWTManager(config *JWTConfig) *JWTManager {
 	if config == nil {
 		config = DefaultJWTConfig()
 	}
-	return &JWTManager{config: config}
+	m := &JWTManager{config: config, current: newSigningKey(config.Secret)}
+	for _, secret := range config.PreviousSecrets {
+		if secret == "" || secret == config.Secret {
+			continue
+		}
+		m.previous = append(m.previous, newSigningKey(secret))
+	}
+	return m
+}
+
+// sign 使用当前密钥签名，头部携带密钥标识
+func (m *JWTManager) sign(claims *Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = m.current.id
+	return token.SignedString(m.current.secret)
+}
+
+// verificationKeys 当前可用于验证的密钥：当前密钥，宽限期内加上旧密钥
+func (m *JWTManager) verificationKeys(now time.Time) []signingKey {
+	keys := []signingKey{m.current}
+	if len(m.previous) > 0 && (m.config.PreviousSecretsUntil.IsZero() || now.Before(m.config.PreviousSecretsUntil)) {
+		keys = append(keys, m.previous...)
+	}
+	return keys
+}
+
+// keyFunc 按 Token 头部的 kid 选择验证密钥；轮换前签发的 Token 没有 kid，依次尝试所有可用密钥
+func (m *JWTManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	// 验证签名方法
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, ErrSigningMethod
+	}
+
+	keys := m.verificationKeys(time.Now())
+	if kid, _ := token.Header["kid"].(string); kid != "" {
+		for _, key := range keys {
+			if hmac.Equal([]byte(kid), []byte(key.id)) {
+				return key.secret, nil
+			}
+		}
+		return nil, ErrInvalidToken
+	}
+
+	set := jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, 0, len(keys))}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, key.secret)
+	}
+	return set, nil
 }
 
 // GenerateToken 生成Access Token
@@ -64,30 +135,47 @@ func (m *JWTManager) GenerateToken(userID, username string) (string, error) {
 
 // GenerateTokenWithOptions 生成带选项的Token
 func (m *JWTManager) GenerateTokenWithOptions(userID, username, platform, deviceID string) (string, error) {
+	return m.generateAccessToken(userID, username, platform, deviceID, false, false, time.Now().Add(m.config.Expire))
+}
+
+// GenerateGuestToken 生成访客Token，有效期到访客账号过期时间，不签发Refresh Token
+func (m *JWTManager) GenerateGuestToken(userID, username, platform, deviceID string, expiresAt time.Time) (string, error) {
+	return m.generateAccessToken(userID, username, platform, deviceID, true, false, expiresAt)
+}
+
+// generateAccessToken 生成Access Token
+func (m *JWTManager) generateAccessToken(userID, username, platform, deviceID string, guest, passwordReset bool, expiresAt time.Time) (string, error) {
 	now := time.Now()
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		Platform: platform,
-		DeviceID: deviceID,
+		UserID:        userID,
+		Username:      username,
+		Platform:      platform,
+		DeviceID:      deviceID,
+		Guest:         guest,
+		PasswordReset: passwordReset,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    m.config.Issuer,
 			Subject:   userID,
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(m.config.Expire)),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(m.config.Secret))
+	return m.sign(claims)
 }
 
 // GenerateRefreshToken 生成Refresh Token
 func (m *JWTManager) GenerateRefreshToken(userID string) (string, error) {
+	return m.generateRefreshToken(userID, false)
+}
+
+// generateRefreshToken 生成Refresh Token
+func (m *JWTManager) generateRefreshToken(userID string, passwordReset bool) (string, error) {
 	now := time.Now()
 	claims := &Claims{
-		UserID: userID,
+		UserID:        userID,
+		PasswordReset: passwordReset,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    m.config.Issuer,
 			Subject:   userID,
@@ -97,18 +185,27 @@ func (m *JWTManager) GenerateRefreshToken(userID string) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(m.config.Secret))
+	return m.sign(claims)
 }
 
 // GenerateTokenPair 生成Token对（Access Token + Refresh Token）
 func (m *JWTManager) GenerateTokenPair(userID, username, platform, deviceID string) (accessToken, refreshToken string, expiresAt time.Time, err error) {
-	accessToken, err = m.GenerateTokenWithOptions(userID, username, platform, deviceID)
+	return m.generateTokenPair(userID, username, platform, deviceID, false)
+}
+
+// GeneratePasswordResetTokenPair 生成须修改初始密码的Token对，Access Token 只能用于修改密码和登出
+func (m *JWTManager) GeneratePasswordResetTokenPair(userID, username, platform, deviceID string) (accessToken, refreshToken string, expiresAt time.Time, err error) {
+	return m.generateTokenPair(userID, username, platform, deviceID, true)
+}
+
+// generateTokenPair 生成Token对
+func (m *JWTManager) generateTokenPair(userID, username, platform, deviceID string, passwordReset bool) (accessToken, refreshToken string, expiresAt time.Time, err error) {
+	accessToken, err = m.generateAccessToken(userID, username, platform, deviceID, false, passwordReset, time.Now().Add(m.config.Expire))
 	if err != nil {
 		return "", "", time.Time{}, err
 	}
 
-	refreshToken, err = m.GenerateRefreshToken(userID)
+	refreshToken, err = m.generateRefreshToken(userID, passwordReset)
 	if err != nil {
 		return "", "", time.Time{}, err
 	}
@@ -119,13 +216,7 @@ func (m *JWTManager) GenerateTokenPair(userID, username, platform, deviceID stri
 
 // ParseToken 解析并验证Token
 func (m *JWTManager) ParseToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// 验证签名方法
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrSigningMethod
-		}
-		return []byte(m.config.Secret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keyFunc)
 
 	if err != nil {
 		// 检查具体错误类型
@@ -165,9 +256,13 @@ func (m *JWTManager) RefreshToken(refreshToken string) (newAccessToken string, e
 	if err != nil {
 		return "", err
 	}
+	// 访客Token不可刷新（访客账号到期即失效）
+	if claims.Guest {
+		return "", ErrInvalidToken
+	}
 
-	// 生成新的Access Token
-	return m.GenerateTokenWithOptions(claims.UserID, claims.Username, claims.Platform, claims.DeviceID)
+	// 生成新的Access Token，须修改初始密码的限制随 Refresh Token 保留
+	return m.generateAccessToken(claims.UserID, claims.Username, claims.Platform, claims.DeviceID, false, claims.PasswordReset, time.Now().Add(m.config.Expire))
 }
 
 // GetExpiresAt 获取Token过期时间
@@ -215,36 +310,3 @@ func ExtractUserID(tokenString, secret string) (string, error) {
 
 	return claims.UserID, nil
 }
-
-// 全局JWT管理器实例（可选）
-var defaultManager *JWTManager
-
-// InitDefaultManager 初始化默认管理器
-func InitDefaultManager(config *JWTConfig) {
-	defaultManager = NewJWTManager(config)
-}
-
-// GetDefaultManager 获取默认管理器
-func GetDefaultManager() *JWTManager {
-	if defaultManager == nil {
-		defaultManager = NewJWTManager(nil)
-	}
-	return defaultManager
-}
-
-// 便捷函数（使用默认管理器）
-
-// GenerateAccessToken 生成Access Token
-func GenerateAccessToken(userID, username string) (string, error) {
-	return GetDefaultManager().GenerateToken(userID, username)
-}
-
-// ValidateAccessToken 验证Access Token
-func ValidateAccessToken(tokenString string) (string, error) {
-	return GetDefaultManager().ValidateToken(tokenString)
-}
-
-// ParseAccessToken 解析Access Token
-func ParseAccessToken(tokenString string) (*Claims, error) {
-	return GetDefaultManager().ParseToken(tokenString)
-}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// ErrTokenRevoked 凭证已被吊销（强制下线、账号禁用等）
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// RevocationChecker 凭证吊销检查
+type RevocationChecker interface {
+	// IsRevoked 用户签发时间为 issuedAt 的凭证是否已被吊销
+	IsRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error)
+}
+
+// revocationAuthenticator 在认证提供者之后校验凭证是否已被吊销
+type revocationAuthenticator struct {
+	next    Authenticator
+	checker RevocationChecker
+}
+
+// WithRevocation 包装认证提供者，已吊销的凭证返回 ErrTokenRevoked
+// 吊销检查失败时放行（只记录日志），避免吊销存储故障导致全部请求认证失败
+func WithRevocation(next Authenticator, checker RevocationChecker) Authenticator {
+	return &revocationAuthenticator{next: next, checker: checker}
+}
+
+// Authenticate 校验凭证并检查是否已被吊销
+func (a *revocationAuthenticator) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	identity, err := a.next.Authenticate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := a.checker.IsRevoked(ctx, identity.UserID, identity.IssuedAt)
+	if err != nil {
+		log.Printf("check token revocation of %s error: %v", identity.UserID, err)
+		return identity, nil
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return identity, nil
+}
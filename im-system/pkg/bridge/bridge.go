@@ -0,0 +1,81 @@
+// Package bridge 提供与外部聊天平台（Slack、Matrix）互通的连接器
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// 连接器错误定义
+var (
+	ErrSignatureInvalid = errors.New("bridge request signature is invalid")
+	ErrRequestExpired   = errors.New("bridge request timestamp is expired")
+)
+
+// Event 外部平台发来的消息
+type Event struct {
+	Platform     string
+	EventID      string // 外部平台的事件ID，用于去重
+	RemoteRoomID string
+	RemoteUserID string
+	UserName     string // 外部用户显示名，未映射到IM用户时用作消息前缀
+	Text         string
+	Timestamp    time.Time
+}
+
+// Outgoing 发往外部平台的消息
+type Outgoing struct {
+	RemoteRoomID string
+	TxnID        string // 幂等ID（IM消息ID）
+	UserName     string // IM发送者显示名
+	Text         string
+}
+
+// Connector 外部平台连接器
+type Connector interface {
+	// Platform 平台名称
+	Platform() string
+
+	// Send 以桥接机器人身份发送消息到外部频道/房间
+	Send(ctx context.Context, msg *Outgoing) error
+}
+
+// postJSON 发送JSON请求并解析JSON响应
+func postJSON(ctx context.Context, client *http.Client, method, url string, header http.Header, body, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s %s: status %d: %s", method, url, resp.StatusCode, respBody)
+	}
+	if result != nil {
+		return json.Unmarshal(respBody, result)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package bridge
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MatrixConfig Matrix 应用服务（Application Service）配置
+type MatrixConfig struct {
+	Homeserver string // 如 https://matrix.example.com
+	ASToken    string // 应用服务调用 homeserver 使用的 as_token
+	HSToken    string // homeserver 推送事务时携带的 hs_token
+	BotUserID  string // 桥接机器人的 Matrix 用户ID，如 @im-bridge:example.com
+	Timeout    time.Duration
+}
+
+// MatrixConnector Matrix 连接器：出站通过 Client-Server API 发送，入站通过应用服务事务推送
+type MatrixConnector struct {
+	config *MatrixConfig
+	client *http.Client
+}
+
+// NewMatrixConnector 创建 Matrix 连接器
+func NewMatrixConnector(config *MatrixConfig) *MatrixConnector {
+	config.Homeserver = strings.TrimRight(config.Homeserver, "/")
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &MatrixConnector{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Platform 平台名称
+func (c *MatrixConnector) Platform() string {
+	return "matrix"
+}
+
+// Send 以桥接机器人身份发送 m.text 消息到 Matrix 房间，txnId 取IM消息ID保证重试幂等
+func (c *MatrixConnector) Send(ctx context.Context, msg *Outgoing) error {
+	text := msg.Text
+	if msg.UserName != "" {
+		text = msg.UserName + ": " + text
+	}
+	body := map[string]interface{}{
+		"msgtype": "m.text",
+		"body":    text,
+	}
+
+	endpoint := c.config.Homeserver + "/_matrix/client/v3/rooms/" + url.PathEscape(msg.RemoteRoomID) +
+		"/send/m.room.message/" + url.PathEscape(msg.TxnID)
+	if c.config.BotUserID != "" {
+		endpoint += "?user_id=" + url.QueryEscape(c.config.BotUserID)
+	}
+	header := http.Header{"Authorization": {"Bearer " + c.config.ASToken}}
+	return postJSON(ctx, c.client, http.MethodPut, endpoint, header, body, nil)
+}
+
+// VerifyRequest 校验 homeserver 推送事务携带的 hs_token（Authorization 头或 access_token 参数）
+func (c *MatrixConnector) VerifyRequest(r *http.Request) error {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(c.config.HSToken)) != 1 {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// matrixTransaction 应用服务事务
+type matrixTransaction struct {
+	Events []struct {
+		Type           string `json:"type"`
+		EventID        string `json:"event_id"`
+		RoomID         string `json:"room_id"`
+		Sender         string `json:"sender"`
+		OriginServerTS int64  `json:"origin_server_ts"`
+		Content        struct {
+			MsgType string `json:"msgtype"`
+			Body    string `json:"body"`
+		} `json:"content"`
+	} `json:"events"`
+}
+
+// ParseTransaction 解析事务中的房间文本消息，忽略桥接机器人自己发出的消息
+func (c *MatrixConnector) ParseTransaction(body []byte) ([]*Event, error) {
+	var txn matrixTransaction
+	if err := json.Unmarshal(body, &txn); err != nil {
+		return nil, err
+	}
+
+	events := make([]*Event, 0, len(txn.Events))
+	for _, e := range txn.Events {
+		if e.Type != "m.room.message" || e.Sender == c.config.BotUserID || e.Content.Body == "" {
+			continue
+		}
+		switch e.Content.MsgType {
+		case "m.text", "m.notice", "m.emote":
+		default:
+			continue
+		}
+		events = append(events, &Event{
+			Platform:     c.Platform(),
+			EventID:      e.EventID,
+			RemoteRoomID: e.RoomID,
+			RemoteUserID: e.Sender,
+			UserName:     matrixLocalpart(e.Sender),
+			Text:         e.Content.Body,
+			Timestamp:    time.UnixMilli(e.OriginServerTS),
+		})
+	}
+	return events, nil
+}
+
+// matrixLocalpart 取 Matrix 用户ID的本地部分（@alice:example.com -> alice）
+func matrixLocalpart(userID string) string {
+	name := strings.TrimPrefix(userID, "@")
+	if i := strings.Index(name, ":"); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
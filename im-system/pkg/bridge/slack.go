@@ -0,0 +1,163 @@
+package bridge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Slack 请求签名头
+const (
+	SlackSignatureHeader = "X-Slack-Signature"
+	SlackTimestampHeader = "X-Slack-Request-Timestamp"
+)
+
+// slackMaxSkew 签名时间戳允许的最大偏差（防重放）
+const slackMaxSkew = 5 * time.Minute
+
+// SlackConfig Slack 连接器配置
+type SlackConfig struct {
+	BotToken      string // 机器人 Token（xoxb-），需要 chat:write 和 chat:write.customize 权限
+	SigningSecret string // Events API 请求签名密钥
+	APIBase       string // 默认 https://slack.com/api
+	Timeout       time.Duration
+}
+
+// SlackConnector Slack 连接器：出站通过 chat.postMessage，入站通过 Events API
+type SlackConnector struct {
+	config *SlackConfig
+	client *http.Client
+}
+
+// NewSlackConnector 创建 Slack 连接器
+func NewSlackConnector(config *SlackConfig) *SlackConnector {
+	if config.APIBase == "" {
+		config.APIBase = "https://slack.com/api"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &SlackConnector{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Platform 平台名称
+func (c *SlackConnector) Platform() string {
+	return "slack"
+}
+
+// Send 发送消息到 Slack 频道，以IM发送者的显示名作为用户名
+func (c *SlackConnector) Send(ctx context.Context, msg *Outgoing) error {
+	body := map[string]interface{}{
+		"channel": msg.RemoteRoomID,
+		"text":    msg.Text,
+	}
+	if msg.UserName != "" {
+		body["username"] = msg.UserName
+	}
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	header := http.Header{"Authorization": {"Bearer " + c.config.BotToken}}
+	if err := postJSON(ctx, c.client, http.MethodPost, c.config.APIBase+"/chat.postMessage", header, body, &result); err != nil {
+		return err
+	}
+	if !result.OK {
+		return fmt.Errorf("slack chat.postMessage error: %s", result.Error)
+	}
+	return nil
+}
+
+// VerifyRequest 校验 Events API 请求签名：v0=hex(hmac_sha256(secret, "v0:{timestamp}:{body}"))
+func (c *SlackConnector) VerifyRequest(header http.Header, body []byte) error {
+	ts, err := strconv.ParseInt(header.Get(SlackTimestampHeader), 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > slackMaxSkew || skew < -slackMaxSkew {
+		return ErrRequestExpired
+	}
+
+	mac := hmac.New(sha256.New, []byte(c.config.SigningSecret))
+	fmt.Fprintf(mac, "v0:%d:", ts)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get(SlackSignatureHeader))) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// slackEnvelope Events API 请求体
+type slackEnvelope struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	EventID   string `json:"event_id"`
+	Event     struct {
+		Type     string `json:"type"`
+		Subtype  string `json:"subtype"`
+		Channel  string `json:"channel"`
+		User     string `json:"user"`
+		BotID    string `json:"bot_id"`
+		Text     string `json:"text"`
+		Ts       string `json:"ts"`
+		Username string `json:"username"`
+	} `json:"event"`
+}
+
+// ParseEvent 解析 Events API 请求：返回URL校验的 challenge，或频道消息事件；
+// 机器人消息（包括桥接自己发出的消息）、编辑、删除等子类型事件返回 nil
+func (c *SlackConnector) ParseEvent(body []byte) (string, *Event, error) {
+	var envelope slackEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return "", nil, err
+	}
+	if envelope.Type == "url_verification" {
+		return envelope.Challenge, nil, nil
+	}
+
+	e := envelope.Event
+	if envelope.Type != "event_callback" || e.Type != "message" || e.Subtype != "" || e.BotID != "" || e.User == "" {
+		return "", nil, nil
+	}
+
+	timestamp := time.Now()
+	if sec, err := strconv.ParseFloat(e.Ts, 64); err == nil {
+		timestamp = time.UnixMilli(int64(sec * 1000))
+	}
+	return "", &Event{
+		Platform:     c.Platform(),
+		EventID:      envelope.EventID,
+		RemoteRoomID: e.Channel,
+		RemoteUserID: e.User,
+		UserName:     firstNonEmpty(e.Username, e.User),
+		Text:         slackToPlain(e.Text),
+		Timestamp:    timestamp,
+	}, nil
+}
+
+// slackToPlain 还原 Slack mrkdwn 中转义的字符
+func slackToPlain(text string) string {
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&").Replace(text)
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
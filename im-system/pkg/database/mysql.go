@@ -71,11 +71,3 @@ func NewMySQL(config *MySQLConfig) (*gorm.DB, error) {
 
 	return db, nil
 }
-
-// AutoMigrate 自动迁移表结构
-func AutoMigrate(db *gorm.DB, models ...interface{}) error {
-	if err := db.AutoMigrate(models...); err != nil {
-		return fmt.Errorf("failed to auto migrate: %w", err)
-	}
-	return nil
-}
@@ -0,0 +1,90 @@
+// Package errcode 提供错误码注册表，将业务错误映射为稳定的错误码、HTTP状态和多语言文案
+package errcode
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/d60-lab/im-system/pkg/i18n"
+)
+
+// 通用错误码
+const (
+	CodeOK               = 0
+	CodeInvalidRequest   = 10001
+	CodeUnauthorized     = 10002
+	CodePermissionDenied = 10003
+	CodeNotFound         = 10004
+	CodeTooManyRequests  = 10005
+	CodeMaintenance      = 10006
+	CodeInternal         = 10500
+)
+
+// Code 错误码定义
+type Code struct {
+	Code       int    `json:"code"`
+	HTTPStatus int    `json:"-"`
+	MessageKey string `json:"message_key"` // i18n文案Key
+}
+
+// Message 获取指定语言的错误文案
+func (c *Code) Message(locale string) string {
+	return i18n.T(locale, c.MessageKey)
+}
+
+// Internal 未注册错误使用的内部错误码
+var Internal = &Code{Code: CodeInternal, HTTPStatus: http.StatusInternalServerError, MessageKey: "error.internal"}
+
+type entry struct {
+	err  error
+	code *Code
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []entry
+	byCode     = map[int]*Code{}
+)
+
+// Register 注册业务错误对应的错误码（错误码重复时panic，便于启动时发现冲突）
+func Register(err error, code int, httpStatus int, messageKey string) *Code {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if existing, ok := byCode[code]; ok && existing.MessageKey != messageKey {
+		panic("errcode: duplicate code registration")
+	}
+
+	c := &Code{Code: code, HTTPStatus: httpStatus, MessageKey: messageKey}
+	byCode[code] = c
+	registry = append(registry, entry{err: err, code: c})
+	return c
+}
+
+// Lookup 查找错误对应的错误码（支持errors.Is包装链）
+func Lookup(err error) (*Code, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	for _, e := range registry {
+		if errors.Is(err, e.err) {
+			return e.code, true
+		}
+	}
+	return nil, false
+}
+
+// All 获取全部已注册错误码
+func All() []*Code {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	codes := make([]*Code, 0, len(byCode))
+	for _, c := range byCode {
+		codes = append(codes, c)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	return codes
+}
@@ -0,0 +1,161 @@
+// Package i18n 提供服务端文案的多语言支持
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 支持的语言
+const (
+	LocaleZhCN = "zh-CN"
+	LocaleEnUS = "en-US"
+
+	DefaultLocale = LocaleZhCN
+)
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = map[string]map[string]string{}
+)
+
+// Register 注册（合并）某个语言的文案
+func Register(locale string, messages map[string]string) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	m, ok := catalog[locale]
+	if !ok {
+		m = make(map[string]string, len(messages))
+		catalog[locale] = m
+	}
+	for key, text := range messages {
+		m[key] = text
+	}
+}
+
+// T 获取指定语言的文案，不存在时回退到默认语言，仍不存在时返回key
+func T(locale, key string, args ...interface{}) string {
+	catalogMu.RLock()
+	text, ok := catalog[locale][key]
+	if !ok {
+		text, ok = catalog[DefaultLocale][key]
+	}
+	catalogMu.RUnlock()
+
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// Messages 获取某个语言的全部文案（供客户端渲染系统消息模板）
+func Messages(locale string) map[string]string {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	result := make(map[string]string, len(catalog[DefaultLocale]))
+	for key, text := range catalog[DefaultLocale] {
+		result[key] = text
+	}
+	for key, text := range catalog[locale] {
+		result[key] = text
+	}
+	return result
+}
+
+// Supported 获取已注册的语言列表
+func Supported() []string {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	locales := make([]string, 0, len(catalog))
+	for locale := range catalog {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Match 将客户端语言标识匹配为已支持的语言（如 en、en_GB → en-US），无法匹配时返回空
+func Match(tag string) string {
+	tag = strings.TrimSpace(strings.ReplaceAll(tag, "_", "-"))
+	if tag == "" {
+		return ""
+	}
+
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	// 精确匹配（忽略大小写）
+	for locale := range catalog {
+		if strings.EqualFold(locale, tag) {
+			return locale
+		}
+	}
+
+	// 按主语言匹配
+	base := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+	candidates := make([]string, 0, 1)
+	for locale := range catalog {
+		if strings.ToLower(strings.SplitN(locale, "-", 2)[0]) == base {
+			candidates = append(candidates, locale)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.Strings(candidates)
+	return candidates[0]
+}
+
+// Negotiate 根据Accept-Language协商语言，无法匹配时返回默认语言
+func Negotiate(acceptLanguage string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if fields[0] == "" || fields[0] == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag: fields[0], q: q})
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	for _, t := range tags {
+		if locale := Match(t.tag); locale != "" {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// Resolve 依次尝试显式指定的语言（如设备语言），最后按Accept-Language协商
+func Resolve(acceptLanguage string, preferred ...string) string {
+	for _, tag := range preferred {
+		if locale := Match(tag); locale != "" {
+			return locale
+		}
+	}
+	return Negotiate(acceptLanguage)
+}
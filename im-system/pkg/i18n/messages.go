@@ -0,0 +1,440 @@
+package i18n
+
+// 文案Key
+const (
+	KeyKickoutOtherDevice = "kickout.other_device"
+	KeyKickoutNodeDrain   = "kickout.node_drain"
+	KeyKickoutForceLogout = "kickout.force_logout"
+
+	// 群事件模板（占位符: {operator} 操作者, {targets} 目标成员, {field} 变更字段, {value} 新值, {time} 到期时间）
+	KeyGroupCreated      = "group.event.created"
+	KeyGroupMemberJoin   = "group.event.member_join"
+	KeyGroupMemberLeave  = "group.event.member_leave"
+	KeyGroupMemberKicked = "group.event.member_kicked"
+	KeyGroupDismissed    = "group.event.dismissed"
+	KeyGroupInfoUpdate   = "group.event.info_update"
+	KeyGroupAdminChange  = "group.event.admin_change"
+	KeyGroupMute         = "group.event.mute"
+	KeyGroupTransfer     = "group.event.transfer"
+	KeyGroupExpiring     = "group.event.expiring"
+
+	// 消息提醒（占位符: {preview} 原消息摘要）
+	KeyMessageReminder = "reminder.message"
+
+	// 群活动每日摘要（占位符: {name} 群名称, {count} 消息数, {mentions} @我的消息数）
+	KeyGroupDigest = "group.digest"
+
+	// 群投票（占位符: {question} 投票问题）
+	KeyPollResult = "poll.result"
+
+	// 会话导出（占位符: {name} 群名称, {time} 导出时间, {count} 消息数）
+	KeyExportTitleSingle = "export.title_single"
+	KeyExportTitleGroup  = "export.title_group"
+	KeyExportImage       = "export.image"
+	KeyExportFile        = "export.file"
+	KeyExportFooter      = "export.footer"
+)
+
+func init() {
+	Register(LocaleZhCN, map[string]string{
+		KeyKickoutOtherDevice: "您的账号在其他设备登录",
+		KeyKickoutNodeDrain:   "服务器维护中，正在为您重新连接",
+		KeyKickoutForceLogout: "您的登录已失效，请重新登录",
+
+		KeyGroupCreated:      "{operator} 创建了群聊",
+		KeyGroupMemberJoin:   "{targets} 加入了群聊",
+		KeyGroupMemberLeave:  "{targets} 退出了群聊",
+		KeyGroupMemberKicked: "{targets} 被 {operator} 移出了群聊",
+		KeyGroupDismissed:    "群聊已被 {operator} 解散",
+		KeyGroupInfoUpdate:   "{operator} 修改了群{field}",
+		KeyGroupAdminChange:  "{operator} 变更了 {targets} 的管理员身份",
+		KeyGroupMute:         "{operator} 修改了禁言设置",
+		KeyGroupTransfer:     "{operator} 将群主转让给了 {targets}",
+		KeyGroupExpiring:     "本群为临时群，将于 {time} 自动解散",
+
+		KeyMessageReminder: "提醒：{preview}",
+
+		KeyGroupDigest: "群聊“{name}”今日有 {count} 条新消息，{mentions} 条@了你",
+
+		KeyPollResult: "投票“{question}”已结束",
+
+		KeyExportTitleSingle: "聊天记录",
+		KeyExportTitleGroup:  "群聊“{name}”的聊天记录",
+		KeyExportImage:       "[图片]",
+		KeyExportFile:        "[文件]",
+		KeyExportFooter:      "导出于 {time}，共 {count} 条消息",
+
+		"error.invalid_request":     "请求参数错误",
+		"error.unauthorized":        "未登录或登录已过期",
+		"error.permission_denied":   "没有权限",
+		"error.internal":            "服务器内部错误",
+		"error.maintenance":         "系统维护中，暂时无法发送消息",
+		"error.group_not_found":     "群组不存在",
+		"error.not_group_member":    "您不是该群成员",
+		"error.not_group_owner":     "只有群主可以执行此操作",
+		"error.not_group_admin":     "只有群主或管理员可以执行此操作",
+		"error.group_full":          "群成员已满",
+		"error.already_in_group":    "已在群中",
+		"error.cannot_kick_owner":   "不能移除群主",
+		"error.group_dismissed":     "群组已解散",
+		"error.owner_cannot_leave":  "群主不能直接退出，请先转让群主",
+		"error.group_conflict":      "群组信息已被其他操作修改，请刷新后重试",
+		"error.name_reserved":       "该名称为系统保留名称",
+		"error.username_taken":      "用户名已存在",
+		"error.nickname_taken":      "昵称已被使用",
+		"error.rename_too_frequent": "改名过于频繁，请稍后再试",
+		"error.user_not_found":      "用户不存在",
+		"error.file_not_found":      "文件不存在",
+		"error.file_too_large":      "文件过大",
+		"error.invalid_file_type":   "不支持的文件类型",
+		"error.checksum_mismatch":   "文件校验失败",
+		"error.node_not_found":      "节点不存在",
+
+		"error.join_request_not_found": "入群申请不存在",
+		"error.join_request_handled":   "入群申请已处理",
+		"error.group_expiry_invalid":   "群到期时间必须晚于当前时间",
+
+		"error.group_mute_all": "本群已开启全员禁言",
+		"error.member_muted":   "你已被禁言",
+
+		"error.mention_group_not_found": "提及组不存在",
+		"error.mention_group_exists":    "提及组名称已存在",
+		"error.mention_group_invalid":   "提及组名称或成员无效",
+		"error.group_digest_not_found":  "未订阅该群的活动摘要",
+		"error.group_digest_invalid":    "摘要发送时间或时区无效",
+
+		"error.conversation_not_found": "会话不存在",
+
+		"error.import_empty":         "没有可导入的用户",
+		"error.import_too_many_rows": "单次导入的用户数量超过上限",
+
+		"error.department_not_found": "部门不存在",
+		"error.department_not_empty": "部门下仍有下级部门或成员",
+		"error.department_cycle":     "不能将部门移动到自身或其下级部门",
+
+		"error.message_not_found":    "消息不存在",
+		"error.reminder_not_found":   "提醒不存在",
+		"error.reminder_limit":       "待提醒数量已达上限",
+		"error.invalid_remind_time":  "提醒时间必须晚于当前时间",
+		"error.remind_time_too_far":  "提醒时间过远",
+		"error.reminder_not_pending": "提醒已发送或已取消",
+
+		"error.auto_reply_text_required": "开启自动回复时必须填写回复内容",
+		"error.auto_reply_text_too_long": "自动回复内容过长",
+		"error.auto_reply_period":        "自动回复结束时间必须晚于开始时间",
+
+		"error.account_deleted":     "账号已注销",
+		"error.invalid_user_status": "无效的账号状态",
+
+		"error.export_range_invalid":  "导出时间范围无效",
+		"error.export_range_too_long": "导出时间范围过长",
+		"error.export_format_invalid": "不支持的导出格式",
+		"error.export_in_progress":    "已有进行中的导出任务，请稍后再试",
+		"error.export_job_not_found":  "导出任务不存在或已过期",
+
+		"error.search_keyword_invalid": "搜索关键字不能为空且不超过100个字符",
+
+		"error.clock_skew": "设备时间与服务器相差过大，请校准时间后重试",
+
+		"error.custom_signature_invalid":  "自定义消息签名无效",
+		"error.custom_signature_expired":  "自定义消息签名已过期",
+		"error.custom_message_unverified": "该会话只接受已验证应用的消息",
+		"error.app_not_found":             "集成应用不存在",
+
+		"error.flag_not_found":   "功能开关不存在",
+		"error.flag_invalid_key": "功能开关Key只能包含小写字母、数字、下划线、点和横线，且不超过64个字符",
+
+		"error.file_content_mismatch": "文件内容与扩展名不符",
+		"error.file_policy_invalid":   "文件类型策略无效",
+		"error.suspicious_archive":    "压缩包未通过安全检查（条目过多、压缩比异常或嵌套过深）",
+		"error.file_expired":          "文件已过期",
+		"error.revoke_not_sender":     "只能撤回自己发送的消息",
+		"error.revoke_time_exceeded":  "消息发送已超过2分钟，无法撤回",
+
+		"error.message_content_invalid": "消息内容格式不正确",
+
+		"error.cancel_not_sender":   "只能取消自己发送的消息",
+		"error.message_not_pending": "消息已全部送达、已撤回或已取消，无法取消",
+
+		"error.conversation_not_encrypted": "会话未开启加密",
+		"error.plaintext_in_encrypted":     "该会话已开启加密，只能发送密文消息",
+		"error.stale_key_version":          "会话密钥已轮换，请使用新密钥重新加密",
+		"error.message_quarantined":        "消息时间异常，已转入审核",
+		"error.message_clock_out_of_range": "消息时间超出允许范围",
+		"error.quarantine_not_found":       "隔离消息不存在",
+
+		"error.poll_not_found":        "投票不存在",
+		"error.poll_closed":           "投票已结束",
+		"error.poll_already_voted":    "您已投过票",
+		"error.poll_invalid":          "投票问题不能为空，选项须为2-20个且不能重复",
+		"error.poll_invalid_choice":   "投票选项无效",
+		"error.poll_deadline_invalid": "投票截止时间必须晚于当前时间且不超过30天",
+
+		"error.message_type_not_allowed":    "当前会话不允许发送该类型的消息",
+		"error.message_type_policy_invalid": "消息类型策略无效",
+
+		"error.purge_filter_invalid": "消息清除条件无效",
+		"error.search_unavailable":   "消息搜索暂不可用，请稍后重试",
+
+		"error.content_rejected":       "内容包含违规信息，无法发送",
+		"error.moderation_unavailable": "内容审核暂不可用，请稍后重试",
+
+		"error.push_experiment_not_found": "推送文案实验不存在",
+		"error.push_variant_invalid":      "推送文案实验分组只能是 control 或 treatment",
+
+		"error.bridge_platform_unsupported": "桥接平台未启用",
+		"error.bridge_room_not_found":       "桥接频道映射不存在",
+		"error.bridge_user_not_found":       "桥接用户映射不存在",
+
+		"error.role_not_found":     "管理角色不存在",
+		"error.role_builtin":       "内置管理角色不能修改或删除",
+		"error.role_name_invalid":  "角色名只能包含小写字母、数字、下划线和短横线，且以字母开头",
+		"error.permission_unknown": "未知的管理权限",
+		"error.role_self_revoke":   "不能撤销自己的角色管理权限",
+
+		"error.summary_rate_limited": "摘要次数已达上限，请稍后再试",
+		"error.summary_encrypted":    "加密会话不支持生成摘要",
+		"error.summary_empty":        "没有可以摘要的消息",
+		"error.summary_unavailable":  "摘要服务暂时不可用",
+		"error.pin_limit_exceeded":   "置顶消息数已达上限",
+
+		"error.cross_region_denied":     "数据驻留策略不允许与该区域的用户通信",
+		"error.cs_agent_not_found":      "客服坐席不存在",
+		"error.cs_not_agent":            "不是客服坐席",
+		"error.cs_agent_busy":           "坐席有接待中的会话，请先结束或转接",
+		"error.cs_session_not_found":    "客服会话不存在",
+		"error.cs_session_closed":       "客服会话已结束",
+		"error.cs_session_not_closed":   "客服会话未结束，暂不能评价",
+		"error.cs_session_rated":        "客服会话已评价",
+		"error.cs_no_agent_available":   "暂无空闲客服",
+		"error.cs_queue_full":           "排队人数已满，请稍后再试",
+		"error.canned_reply_not_found":  "快捷回复不存在",
+		"error.cs_session_not_assigned": "该客服会话不由你接待",
+		"error.e2ee_device_not_found":   "设备未注册加密密钥",
+		"error.e2ee_key_invalid":        "加密密钥格式错误",
+		"error.e2ee_too_many_prekeys":   "一次性预密钥数量超出限制",
+		"error.e2ee_device_limit":       "注册加密密钥的设备数已达上限",
+		"error.unknown_region":          "未部署存储的区域",
+		"error.storage_unavailable":     "文件存储服务暂时不可用",
+
+		"error.friend_self":              "不能添加或屏蔽自己",
+		"error.already_friends":          "你们已经是好友",
+		"error.not_friends":              "对方不是你的好友",
+		"error.friend_request_not_found": "好友申请不存在",
+		"error.friend_request_handled":   "好友申请已处理",
+		"error.blocked_by_user":          "对方拒收了你的消息",
+		"error.guest_disabled":           "未开放访客咨询",
+		"error.guest_rate_limited":       "访客会话创建过于频繁，请稍后再试",
+		"error.guest_target_forbidden":   "访客不能向该会话发送消息",
+		"error.guest_expired":            "访客会话已过期",
+		"error.not_guest":                "不是访客账号",
+		"error.usage_month_invalid":      "月份格式错误，应为 YYYY-MM",
+		"error.presence_too_many_users":  "单次最多查询100个用户的在线状态",
+		"error.dnd_invalid":              "免打扰时段格式错误，时间应为 HH:MM，时区应为 IANA 时区",
+
+		"error.webpush_subscription_invalid": "浏览器推送订阅缺失或格式错误",
+
+		"error.rate_limited": "操作过于频繁，请稍后再试",
+	})
+
+	Register(LocaleEnUS, map[string]string{
+		KeyKickoutOtherDevice: "Your account has signed in on another device",
+		KeyKickoutNodeDrain:   "Server maintenance in progress, reconnecting",
+		KeyKickoutForceLogout: "Your session has been signed out, please sign in again",
+
+		KeyGroupCreated:      "{operator} created the group",
+		KeyGroupMemberJoin:   "{targets} joined the group",
+		KeyGroupMemberLeave:  "{targets} left the group",
+		KeyGroupMemberKicked: "{targets} was removed by {operator}",
+		KeyGroupDismissed:    "The group was dismissed by {operator}",
+		KeyGroupInfoUpdate:   "{operator} changed the group {field}",
+		KeyGroupAdminChange:  "{operator} changed admin role of {targets}",
+		KeyGroupMute:         "{operator} changed mute settings",
+		KeyGroupTransfer:     "{operator} transferred ownership to {targets}",
+		KeyGroupExpiring:     "This is a temporary group and will be dismissed at {time}",
+
+		KeyMessageReminder: "Reminder: {preview}",
+
+		KeyGroupDigest: "{count} new messages in \"{name}\" today, {mentions} mentioning you",
+
+		KeyPollResult: "Poll \"{question}\" has ended",
+
+		KeyExportTitleSingle: "Chat history",
+		KeyExportTitleGroup:  "Chat history of \"{name}\"",
+		KeyExportImage:       "[Image]",
+		KeyExportFile:        "[File]",
+		KeyExportFooter:      "Exported at {time}, {count} messages",
+
+		"error.invalid_request":     "Invalid request",
+		"error.unauthorized":        "Not signed in or session expired",
+		"error.permission_denied":   "Permission denied",
+		"error.internal":            "Internal server error",
+		"error.maintenance":         "The service is under maintenance, sending is temporarily disabled",
+		"error.group_not_found":     "Group not found",
+		"error.not_group_member":    "You are not a member of this group",
+		"error.not_group_owner":     "Only the group owner can do this",
+		"error.not_group_admin":     "Only the group owner or admins can do this",
+		"error.group_full":          "The group is full",
+		"error.already_in_group":    "Already in the group",
+		"error.cannot_kick_owner":   "The group owner cannot be removed",
+		"error.group_dismissed":     "The group has been dismissed",
+		"error.owner_cannot_leave":  "The group owner cannot leave, please transfer ownership first",
+		"error.group_conflict":      "The group was modified by another operation, please refresh and retry",
+		"error.name_reserved":       "This name is reserved",
+		"error.username_taken":      "Username already exists",
+		"error.nickname_taken":      "Nickname already in use",
+		"error.rename_too_frequent": "Renamed too recently, please try again later",
+		"error.user_not_found":      "User not found",
+		"error.file_not_found":      "File not found",
+		"error.file_too_large":      "File is too large",
+		"error.invalid_file_type":   "File type not allowed",
+		"error.checksum_mismatch":   "File checksum mismatch",
+		"error.node_not_found":      "Node not found",
+
+		"error.join_request_not_found": "Join request not found",
+		"error.join_request_handled":   "Join request has already been handled",
+		"error.group_expiry_invalid":   "Group expiry time must be in the future",
+
+		"error.group_mute_all": "All members are muted in this group",
+		"error.member_muted":   "You are muted in this group",
+
+		"error.mention_group_not_found": "Mention group not found",
+		"error.mention_group_exists":    "Mention group name already exists",
+		"error.mention_group_invalid":   "Invalid mention group name or members",
+		"error.group_digest_not_found":  "Group digest subscription not found",
+		"error.group_digest_invalid":    "Invalid digest send time or timezone",
+
+		"error.conversation_not_found": "Conversation not found",
+
+		"error.import_empty":         "No users to import",
+		"error.import_too_many_rows": "Too many users in one import",
+
+		"error.department_not_found": "Department not found",
+		"error.department_not_empty": "Department still has sub-departments or members",
+		"error.department_cycle":     "A department cannot be moved under itself or its sub-departments",
+
+		"error.message_not_found":    "Message not found",
+		"error.reminder_not_found":   "Reminder not found",
+		"error.reminder_limit":       "Too many pending reminders",
+		"error.invalid_remind_time":  "Reminder time must be in the future",
+		"error.remind_time_too_far":  "Reminder time is too far in the future",
+		"error.reminder_not_pending": "Reminder has already fired or been cancelled",
+
+		"error.auto_reply_text_required": "Auto-reply text is required when enabled",
+		"error.auto_reply_text_too_long": "Auto-reply text is too long",
+		"error.auto_reply_period":        "Auto-reply end time must be after the start time",
+
+		"error.account_deleted":     "The account has been deleted",
+		"error.invalid_user_status": "Invalid account status",
+
+		"error.export_range_invalid":  "Invalid export time range",
+		"error.export_range_too_long": "Export time range is too long",
+		"error.export_format_invalid": "Unsupported export format",
+		"error.export_in_progress":    "Another export is in progress, please try again later",
+		"error.export_job_not_found":  "Export job not found or expired",
+
+		"error.search_keyword_invalid": "Search keyword must be 1-100 characters",
+
+		"error.clock_skew": "Device clock differs too much from the server, please sync your time and retry",
+
+		"error.custom_signature_invalid":  "Invalid custom message signature",
+		"error.custom_signature_expired":  "Custom message signature has expired",
+		"error.custom_message_unverified": "This conversation only accepts messages from verified apps",
+		"error.app_not_found":             "Integration app not found",
+
+		"error.flag_not_found":   "Feature flag not found",
+		"error.flag_invalid_key": "Feature flag key may only contain lowercase letters, digits, underscores, dots and hyphens (max 64 characters)",
+
+		"error.file_content_mismatch": "File content does not match its extension",
+		"error.file_policy_invalid":   "Invalid file type policy",
+		"error.suspicious_archive":    "Archive rejected by safety checks (too many entries, abnormal compression ratio or nested too deep)",
+		"error.file_expired":          "File has expired",
+		"error.revoke_not_sender":     "Only the sender can revoke this message",
+		"error.revoke_time_exceeded":  "Messages can only be revoked within 2 minutes of sending",
+
+		"error.message_content_invalid": "Message content does not match the schema for its type",
+
+		"error.cancel_not_sender":   "Only the sender can cancel this message",
+		"error.message_not_pending": "Message has already been delivered, revoked or cancelled",
+
+		"error.conversation_not_encrypted": "Conversation is not encrypted",
+		"error.plaintext_in_encrypted":     "This conversation is encrypted; only encrypted messages can be sent",
+		"error.stale_key_version":          "Conversation key has been rotated; re-encrypt the message with the new key",
+		"error.message_quarantined":        "Message timestamp is out of range; the message is held for review",
+		"error.message_clock_out_of_range": "Message timestamp is out of the allowed range",
+		"error.quarantine_not_found":       "Quarantined message not found",
+
+		"error.poll_not_found":        "Poll not found",
+		"error.poll_closed":           "Poll has already ended",
+		"error.poll_already_voted":    "You have already voted in this poll",
+		"error.poll_invalid":          "Poll question is required and it must have 2-20 distinct options",
+		"error.poll_invalid_choice":   "Invalid poll choice",
+		"error.poll_deadline_invalid": "Poll deadline must be in the future and within 30 days",
+
+		"error.message_type_not_allowed":    "This message type is not allowed in this conversation",
+		"error.message_type_policy_invalid": "Invalid message type policy",
+
+		"error.purge_filter_invalid": "Invalid message purge filter",
+		"error.search_unavailable":   "Message search is temporarily unavailable",
+
+		"error.content_rejected":       "Content violates the content policy and cannot be sent",
+		"error.moderation_unavailable": "Content moderation is temporarily unavailable",
+
+		"error.push_experiment_not_found": "Push experiment not found",
+		"error.push_variant_invalid":      "Push experiment variant must be control or treatment",
+
+		"error.bridge_platform_unsupported": "Bridge platform is not enabled",
+		"error.bridge_room_not_found":       "Bridge room mapping not found",
+		"error.bridge_user_not_found":       "Bridge user mapping not found",
+
+		"error.role_not_found":     "Admin role not found",
+		"error.role_builtin":       "Builtin admin roles cannot be modified or deleted",
+		"error.role_name_invalid":  "Role name must start with a letter and contain only lowercase letters, digits, underscores and hyphens",
+		"error.permission_unknown": "Unknown admin permission",
+		"error.role_self_revoke":   "You cannot revoke your own role management permission",
+
+		"error.summary_rate_limited": "Summary limit reached, please try again later",
+		"error.summary_encrypted":    "Encrypted conversations cannot be summarized",
+		"error.summary_empty":        "There are no messages to summarize",
+		"error.summary_unavailable":  "Summary service is temporarily unavailable",
+		"error.pin_limit_exceeded":   "Too many pinned messages in this conversation",
+
+		"error.cross_region_denied":     "Data residency policy does not allow messaging users in this region",
+		"error.cs_agent_not_found":      "Customer service agent not found",
+		"error.cs_not_agent":            "Not a customer service agent",
+		"error.cs_agent_busy":           "Agent has active sessions; close or transfer them first",
+		"error.cs_session_not_found":    "Customer service session not found",
+		"error.cs_session_closed":       "Customer service session already closed",
+		"error.cs_session_not_closed":   "Customer service session is not closed yet",
+		"error.cs_session_rated":        "Customer service session already rated",
+		"error.cs_no_agent_available":   "No customer service agent available",
+		"error.cs_queue_full":           "The queue is full, please try again later",
+		"error.canned_reply_not_found":  "Canned reply not found",
+		"error.cs_session_not_assigned": "This session is not assigned to you",
+		"error.e2ee_device_not_found":   "No encryption keys registered for the device",
+		"error.e2ee_key_invalid":        "Invalid encryption key",
+		"error.e2ee_too_many_prekeys":   "Too many one-time prekeys",
+		"error.e2ee_device_limit":       "Encryption device limit reached",
+		"error.unknown_region":          "No storage is deployed in this region",
+		"error.storage_unavailable":     "File storage is temporarily unavailable",
+
+		"error.friend_self":              "You cannot add or block yourself",
+		"error.already_friends":          "You are already friends",
+		"error.not_friends":              "This user is not your friend",
+		"error.friend_request_not_found": "Friend request not found",
+		"error.friend_request_handled":   "Friend request has already been handled",
+		"error.blocked_by_user":          "This user is not accepting your messages",
+		"error.guest_disabled":           "Guest sessions are not available",
+		"error.guest_rate_limited":       "Too many guest sessions, please try again later",
+		"error.guest_target_forbidden":   "Guests cannot send messages to this conversation",
+		"error.guest_expired":            "Guest session has expired",
+		"error.not_guest":                "Not a guest account",
+		"error.usage_month_invalid":      "Invalid month, expected YYYY-MM",
+		"error.presence_too_many_users":  "At most 100 users per presence query",
+		"error.dnd_invalid":              "Invalid do-not-disturb hours: times must be HH:MM and timezone an IANA zone",
+
+		"error.webpush_subscription_invalid": "Web push subscription is missing or invalid",
+
+		"error.rate_limited": "Too many requests, please try again later",
+	})
+}
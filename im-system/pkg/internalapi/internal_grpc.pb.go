@@ -0,0 +1,231 @@
+// 服务间内部 API：供订单、CRM 等后端服务向 IM 用户发送消息、查询在线状态
+// 生成代码: make proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.1
+// source: internal/v1/internal.proto
+
+package internalapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	InternalService_SendMessage_FullMethodName       = "/im.internal.v1.InternalService/SendMessage"
+	InternalService_SendToGroup_FullMethodName       = "/im.internal.v1.InternalService/SendToGroup"
+	InternalService_Broadcast_FullMethodName         = "/im.internal.v1.InternalService/Broadcast"
+	InternalService_QueryOnlineStatus_FullMethodName = "/im.internal.v1.InternalService/QueryOnlineStatus"
+)
+
+// InternalServiceClient is the client API for InternalService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type InternalServiceClient interface {
+	// SendMessage 以指定发送者向用户发送单聊消息（保存历史、在线投递，离线时转离线消息和推送）
+	SendMessage(ctx context.Context, in *SendMessageRequest, opts ...grpc.CallOption) (*SendMessageResponse, error)
+	// SendToGroup 以指定发送者（须为群成员）向群组发送消息
+	SendToGroup(ctx context.Context, in *SendToGroupRequest, opts ...grpc.CallOption) (*SendMessageResponse, error)
+	// Broadcast 向全部在线用户发送服务器通知（不保存历史），可按平台过滤
+	Broadcast(ctx context.Context, in *BroadcastRequest, opts ...grpc.CallOption) (*BroadcastResponse, error)
+	// QueryOnlineStatus 批量查询用户在线状态
+	QueryOnlineStatus(ctx context.Context, in *QueryOnlineStatusRequest, opts ...grpc.CallOption) (*QueryOnlineStatusResponse, error)
+}
+
+type internalServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewInternalServiceClient(cc grpc.ClientConnInterface) InternalServiceClient {
+	return &internalServiceClient{cc}
+}
+
+func (c *internalServiceClient) SendMessage(ctx context.Context, in *SendMessageRequest, opts ...grpc.CallOption) (*SendMessageResponse, error) {
+	out := new(SendMessageResponse)
+	err := c.cc.Invoke(ctx, InternalService_SendMessage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *internalServiceClient) SendToGroup(ctx context.Context, in *SendToGroupRequest, opts ...grpc.CallOption) (*SendMessageResponse, error) {
+	out := new(SendMessageResponse)
+	err := c.cc.Invoke(ctx, InternalService_SendToGroup_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *internalServiceClient) Broadcast(ctx context.Context, in *BroadcastRequest, opts ...grpc.CallOption) (*BroadcastResponse, error) {
+	out := new(BroadcastResponse)
+	err := c.cc.Invoke(ctx, InternalService_Broadcast_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *internalServiceClient) QueryOnlineStatus(ctx context.Context, in *QueryOnlineStatusRequest, opts ...grpc.CallOption) (*QueryOnlineStatusResponse, error) {
+	out := new(QueryOnlineStatusResponse)
+	err := c.cc.Invoke(ctx, InternalService_QueryOnlineStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// InternalServiceServer is the server API for InternalService service.
+// All implementations must embed UnimplementedInternalServiceServer
+// for forward compatibility
+type InternalServiceServer interface {
+	// SendMessage 以指定发送者向用户发送单聊消息（保存历史、在线投递，离线时转离线消息和推送）
+	SendMessage(context.Context, *SendMessageRequest) (*SendMessageResponse, error)
+	// SendToGroup 以指定发送者（须为群成员）向群组发送消息
+	SendToGroup(context.Context, *SendToGroupRequest) (*SendMessageResponse, error)
+	// Broadcast 向全部在线用户发送服务器通知（不保存历史），可按平台过滤
+	Broadcast(context.Context, *BroadcastRequest) (*BroadcastResponse, error)
+	// QueryOnlineStatus 批量查询用户在线状态
+	QueryOnlineStatus(context.Context, *QueryOnlineStatusRequest) (*QueryOnlineStatusResponse, error)
+	mustEmbedUnimplementedInternalServiceServer()
+}
+
+// UnimplementedInternalServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedInternalServiceServer struct {
+}
+
+func (UnimplementedInternalServiceServer) SendMessage(context.Context, *SendMessageRequest) (*SendMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendMessage not implemented")
+}
+func (UnimplementedInternalServiceServer) SendToGroup(context.Context, *SendToGroupRequest) (*SendMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendToGroup not implemented")
+}
+func (UnimplementedInternalServiceServer) Broadcast(context.Context, *BroadcastRequest) (*BroadcastResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Broadcast not implemented")
+}
+func (UnimplementedInternalServiceServer) QueryOnlineStatus(context.Context, *QueryOnlineStatusRequest) (*QueryOnlineStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryOnlineStatus not implemented")
+}
+func (UnimplementedInternalServiceServer) mustEmbedUnimplementedInternalServiceServer() {}
+
+// UnsafeInternalServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to InternalServiceServer will
+// result in compilation errors.
+type UnsafeInternalServiceServer interface {
+	mustEmbedUnimplementedInternalServiceServer()
+}
+
+func RegisterInternalServiceServer(s grpc.ServiceRegistrar, srv InternalServiceServer) {
+	s.RegisterService(&InternalService_ServiceDesc, srv)
+}
+
+func _InternalService_SendMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InternalServiceServer).SendMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InternalService_SendMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InternalServiceServer).SendMessage(ctx, req.(*SendMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InternalService_SendToGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendToGroupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InternalServiceServer).SendToGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InternalService_SendToGroup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InternalServiceServer).SendToGroup(ctx, req.(*SendToGroupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InternalService_Broadcast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BroadcastRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InternalServiceServer).Broadcast(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InternalService_Broadcast_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InternalServiceServer).Broadcast(ctx, req.(*BroadcastRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _InternalService_QueryOnlineStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryOnlineStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InternalServiceServer).QueryOnlineStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: InternalService_QueryOnlineStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(InternalServiceServer).QueryOnlineStatus(ctx, req.(*QueryOnlineStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// InternalService_ServiceDesc is the grpc.ServiceDesc for InternalService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var InternalService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "im.internal.v1.InternalService",
+	HandlerType: (*InternalServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SendMessage",
+			Handler:    _InternalService_SendMessage_Handler,
+		},
+		{
+			MethodName: "SendToGroup",
+			Handler:    _InternalService_SendToGroup_Handler,
+		},
+		{
+			MethodName: "Broadcast",
+			Handler:    _InternalService_Broadcast_Handler,
+		},
+		{
+			MethodName: "QueryOnlineStatus",
+			Handler:    _InternalService_QueryOnlineStatus_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "internal/v1/internal.proto",
+}
@@ -0,0 +1,214 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localMultipartDir 分片上传暂存目录（位于根目录下，不会与对象路径冲突）
+const localMultipartDir = ".multipart"
+
+// localStore 本地磁盘对象存储：对象按路径保存在根目录下，分片上传暂存在 .multipart 目录，合并时按序拼接
+// 不支持签名URL，文件下载走代理；多节点部署时根目录须为共享存储
+type localStore struct {
+	root string
+}
+
+// newLocalStore 创建本地磁盘对象存储
+func newLocalStore(config *Config) (*localStore, error) {
+	if config.Dir == "" {
+		return nil, errors.New("objectstore: dir is required for provider local")
+	}
+	root, err := filepath.Abs(config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve storage dir error: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, localMultipartDir), 0o755); err != nil {
+		return nil, fmt.Errorf("create storage dir error: %w", err)
+	}
+	return &localStore{root: root}, nil
+}
+
+// path 对象在磁盘上的路径（按根目录清理，不会越出根目录），拒绝指向暂存目录的对象路径
+func (s *localStore) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" || strings.SplitN(cleaned[1:], "/", 2)[0] == localMultipartDir {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+// uploadDir 分片上传的暂存目录
+func (s *localStore) uploadDir(uploadID string) (string, error) {
+	if _, err := hex.DecodeString(uploadID); err != nil || uploadID == "" {
+		return "", ErrInvalidUpload
+	}
+	return filepath.Join(s.root, localMultipartDir, uploadID), nil
+}
+
+// Put 上传对象（先写临时文件再重命名，读取方不会看到写了一半的对象）
+func (s *localStore) Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	_, err = writeFileAtomic(target, reader)
+	return err
+}
+
+// Get 读取对象
+func (s *localStore) Get(ctx context.Context, key string) (Object, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// GetRange 读取对象的指定范围
+func (s *localStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	object, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(object, offset, length), object}, nil
+}
+
+// Remove 删除对象
+func (s *localStore) Remove(ctx context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// PresignGet 本地存储不支持签名URL
+func (s *localStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "", ErrPresignUnsupported
+}
+
+// URL 本地存储没有直接访问地址
+func (s *localStore) URL(key string) string {
+	return ""
+}
+
+// NewMultipartUpload 创建分片上传
+func (s *localStore) NewMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Join(s.root, localMultipartDir, uploadID), 0o755); err != nil {
+		return "", err
+	}
+	return uploadID, nil
+}
+
+// PutPart 上传分片，ETag 为分片内容的MD5
+func (s *localStore) PutPart(ctx context.Context, key, uploadID string, partNumber int, reader io.Reader, size int64) (*Part, error) {
+	dir, err := s.uploadDir(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, ErrInvalidUpload
+	}
+
+	hash := md5.New()
+	written, err := writeFileAtomic(filepath.Join(dir, strconv.Itoa(partNumber)), io.TeeReader(reader, hash))
+	if err != nil {
+		return nil, err
+	}
+	return &Part{PartNumber: partNumber, ETag: hex.EncodeToString(hash.Sum(nil)), Size: written}, nil
+}
+
+// CompleteMultipartUpload 按顺序拼接分片为最终对象并删除暂存目录
+func (s *localStore) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []Part) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	dir, err := s.uploadDir(uploadID)
+	if err != nil {
+		return err
+	}
+
+	files := make([]*os.File, 0, len(parts))
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	readers := make([]io.Reader, 0, len(parts))
+	for _, part := range parts {
+		file, err := os.Open(filepath.Join(dir, strconv.Itoa(part.PartNumber)))
+		if err != nil {
+			return fmt.Errorf("%w: part %d not found", ErrInvalidUpload, part.PartNumber)
+		}
+		files = append(files, file)
+		readers = append(readers, file)
+	}
+
+	if _, err := writeFileAtomic(target, io.MultiReader(readers...)); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// AbortMultipartUpload 删除分片暂存目录
+func (s *localStore) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	dir, err := s.uploadDir(uploadID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// writeFileAtomic 写入临时文件后重命名为目标文件，返回写入的字节数
+func writeFileAtomic(target string, reader io.Reader) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(tmp, reader)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	return written, nil
+}
@@ -0,0 +1,157 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// s3Store 基于 S3 协议的对象存储（MinIO、AWS S3、阿里云 OSS 的 S3 兼容接口）
+type s3Store struct {
+	client *minio.Client
+	core   minio.Core // 用于原生分片上传
+	bucket string
+	dnsURL bool // 虚拟主机风格地址（bucket.endpoint/key）
+}
+
+// newS3Store 创建 S3 协议的对象存储
+// MinIO 的存储桶不存在时自动创建；AWS S3、阿里云 OSS 的存储桶须预先在控制台创建
+func newS3Store(ctx context.Context, config *Config) (*s3Store, error) {
+	provider := strings.ToLower(config.Provider)
+	endpoint := config.Endpoint
+	secure := config.UseSSL
+	lookup := minio.BucketLookupAuto
+
+	switch provider {
+	case ProviderAWS:
+		if endpoint == "" {
+			endpoint = "s3.amazonaws.com"
+		}
+		secure = true
+	case ProviderAliyun:
+		// OSS 只支持虚拟主机风格访问
+		if endpoint == "" {
+			if config.Region == "" {
+				return nil, fmt.Errorf("objectstore: region or endpoint is required for provider %q", provider)
+			}
+			endpoint = "oss-" + strings.TrimPrefix(config.Region, "oss-") + ".aliyuncs.com"
+		}
+		secure = true
+		lookup = minio.BucketLookupDNS
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("objectstore: endpoint is required for provider %q", config.Provider)
+	}
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("objectstore: bucket is required")
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure:       secure,
+		Region:       config.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create s3 client error: %w", err)
+	}
+
+	// 检查桶是否存在，MinIO 不存在时创建
+	exists, err := client.BucketExists(ctx, config.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("check bucket exists error: %w", err)
+	}
+	if !exists {
+		if provider != "" && provider != ProviderMinio {
+			return nil, fmt.Errorf("objectstore: bucket %s does not exist", config.Bucket)
+		}
+		if err := client.MakeBucket(ctx, config.Bucket, minio.MakeBucketOptions{Region: config.Region}); err != nil {
+			return nil, fmt.Errorf("create bucket error: %w", err)
+		}
+	}
+
+	return &s3Store{
+		client: client,
+		core:   minio.Core{Client: client},
+		bucket: config.Bucket,
+		dnsURL: lookup == minio.BucketLookupDNS,
+	}, nil
+}
+
+// Put 上传对象
+func (s *s3Store) Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, reader, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+// Get 读取对象（对象不存在时在读取时返回错误）
+func (s *s3Store) Get(ctx context.Context, key string) (Object, error) {
+	return s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+}
+
+// GetRange 读取对象的指定范围
+func (s *s3Store) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(offset, offset+length-1); err != nil {
+		return nil, err
+	}
+	return s.client.GetObject(ctx, s.bucket, key, opts)
+}
+
+// Remove 删除对象
+func (s *s3Store) Remove(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+// PresignGet 生成带签名的下载URL
+func (s *s3Store) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	presignedURL, err := s.client.PresignedGetObject(ctx, s.bucket, key, expiry, url.Values{})
+	if err != nil {
+		return "", err
+	}
+	return presignedURL.String(), nil
+}
+
+// URL 对象的直接访问地址
+func (s *s3Store) URL(key string) string {
+	endpoint := s.client.EndpointURL()
+	if s.dnsURL {
+		return fmt.Sprintf("%s://%s.%s/%s", endpoint.Scheme, s.bucket, endpoint.Host, key)
+	}
+	return fmt.Sprintf("%s://%s/%s/%s", endpoint.Scheme, endpoint.Host, s.bucket, key)
+}
+
+// NewMultipartUpload 创建分片上传
+func (s *s3Store) NewMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	return s.core.NewMultipartUpload(ctx, s.bucket, key, minio.PutObjectOptions{ContentType: contentType})
+}
+
+// PutPart 上传分片
+func (s *s3Store) PutPart(ctx context.Context, key, uploadID string, partNumber int, reader io.Reader, size int64) (*Part, error) {
+	part, err := s.core.PutObjectPart(ctx, s.bucket, key, uploadID, partNumber, reader, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &Part{PartNumber: partNumber, ETag: part.ETag, Size: part.Size}, nil
+}
+
+// CompleteMultipartUpload 合并分片
+func (s *s3Store) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []Part) error {
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+	_, err := s.core.CompleteMultipartUpload(ctx, s.bucket, key, uploadID, completeParts, minio.PutObjectOptions{})
+	return err
+}
+
+// AbortMultipartUpload 取消分片上传
+func (s *s3Store) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	return s.core.AbortMultipartUpload(ctx, s.bucket, key, uploadID)
+}
@@ -0,0 +1,93 @@
+// Package objectstore 提供对象存储抽象（MinIO、AWS S3、阿里云 OSS、本地磁盘）
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// 存储后端
+const (
+	ProviderMinio  = "minio"  // MinIO 或其他兼容 S3 协议的自建存储
+	ProviderAWS    = "aws"    // AWS S3
+	ProviderAliyun = "aliyun" // 阿里云 OSS（S3 兼容接口）
+	ProviderLocal  = "local"  // 本地磁盘（单节点或共享存储挂载）
+)
+
+// 错误定义
+var (
+	ErrNotFound           = errors.New("objectstore: object not found")
+	ErrPresignUnsupported = errors.New("objectstore: presigned url not supported")
+	ErrInvalidKey         = errors.New("objectstore: invalid object key")
+	ErrInvalidUpload      = errors.New("objectstore: invalid multipart upload")
+)
+
+// Config 对象存储配置
+type Config struct {
+	Provider  string // 存储后端，为空时为 minio
+	Endpoint  string // 服务地址，aws / aliyun 为空时按 Region 使用公网地址
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	UseSSL    bool   // 是否使用 HTTPS（aws / aliyun 始终使用 HTTPS）
+	Dir       string // 本地磁盘存储的根目录（local）
+}
+
+// Object 读取中的对象，支持随机读取（压缩包检查读取中央目录）
+type Object interface {
+	io.ReadCloser
+	io.ReaderAt
+}
+
+// Part 已上传的分片
+type Part struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
+
+// Store 对象存储
+type Store interface {
+	// Put 上传对象，size 为 -1 时表示未知大小
+	Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error
+	// Get 读取对象；本地存储不存在时返回 ErrNotFound，S3 协议后端在首次读取时返回错误
+	Get(ctx context.Context, key string) (Object, error)
+	// GetRange 读取对象从 offset 开始的 length 字节
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Remove 删除对象，不存在时不返回错误
+	Remove(ctx context.Context, key string) error
+	// PresignGet 生成带签名的下载URL，不支持时返回 ErrPresignUnsupported（改用代理下载）
+	PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// URL 对象的直接访问地址（未签名，私有存储桶不可直接访问），没有时返回空
+	URL(key string) string
+
+	// NewMultipartUpload 创建分片上传，返回存储端的上传ID
+	NewMultipartUpload(ctx context.Context, key, contentType string) (string, error)
+	// PutPart 上传分片，同一分片号重复上传时以最后一次为准
+	PutPart(ctx context.Context, key, uploadID string, partNumber int, reader io.Reader, size int64) (*Part, error)
+	// CompleteMultipartUpload 按分片号顺序合并分片为最终对象
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []Part) error
+	// AbortMultipartUpload 取消分片上传并释放已上传的分片
+	AbortMultipartUpload(ctx context.Context, key, uploadID string) error
+}
+
+// New 根据配置创建对象存储，并确认存储桶（或目录）可用
+func New(ctx context.Context, config *Config) (Store, error) {
+	if config == nil {
+		return nil, errors.New("objectstore: config is required")
+	}
+
+	switch strings.ToLower(config.Provider) {
+	case "", ProviderMinio, ProviderAWS, ProviderAliyun:
+		return newS3Store(ctx, config)
+	case ProviderLocal:
+		return newLocalStore(config)
+	default:
+		return nil, fmt.Errorf("objectstore: unknown provider %q", config.Provider)
+	}
+}
@@ -0,0 +1,376 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Auth 接口认证要求
+type Auth int
+
+const (
+	AuthNone  Auth = iota // 无需登录
+	AuthUser              // 需要登录
+	AuthAdmin             // 需要管理员权限
+)
+
+const (
+	mimeJSON      = "application/json"
+	mimeMultipart = "multipart/form-data"
+)
+
+// Route 已注册的路由（gin 路径语法，如 /api/groups/:group_id）
+type Route struct {
+	Method  string
+	Path    string
+	Handler string // 处理函数名，用于生成 operationId
+}
+
+// Spec 接口描述
+type Spec struct {
+	OperationID string // 为空时按处理函数名生成
+	Summary     string
+	Description string
+	Tag         string
+	Auth        Auth
+	Query       []string    // 查询参数名，与公共参数同名时引用公共参数（如分页参数）
+	Headers     []string    // 请求头参数名
+	Form        []string    // multipart/form-data 表单字段，名为 file 的字段为文件
+	Request     interface{} // 请求体类型的零值（也可以是 *Schema）
+	Consumes    string      // 请求体内容类型，默认 application/json
+	Response    interface{} // 成功响应 data 字段类型的零值（也可以是 *Schema），为空时为任意值
+	Produces    string      // 成功响应的内容类型，默认 application/json（按统一响应结构包装）
+	Status      int         // 成功响应状态码，默认200
+	Optional    bool        // 依赖可选服务，未注册时不视为不一致
+}
+
+// Generator 文档生成器：路由来自路由注册，接口描述来自 Describe
+type Generator struct {
+	info       Info
+	servers    []Server
+	tags       []Tag
+	components *Components
+	schemas    *schemaRegistry
+	specs      map[string]*Spec
+	required   []string
+
+	envelope      func(data *Schema) *Schema
+	errorResponse string
+	security      string
+}
+
+// NewGenerator 创建文档生成器
+func NewGenerator(info Info) *Generator {
+	components := &Components{
+		Schemas:         make(map[string]*Schema),
+		Parameters:      make(map[string]*Parameter),
+		Responses:       make(map[string]*Response),
+		SecuritySchemes: make(map[string]*SecurityScheme),
+	}
+	return &Generator{
+		info:       info,
+		components: components,
+		schemas:    newSchemaRegistry(components.Schemas),
+		specs:      make(map[string]*Spec),
+	}
+}
+
+// AddServer 添加服务地址
+func (g *Generator) AddServer(url, description string) {
+	g.servers = append(g.servers, Server{URL: url, Description: description})
+}
+
+// AddTag 添加接口分组
+func (g *Generator) AddTag(name, description string) {
+	g.tags = append(g.tags, Tag{Name: name, Description: description})
+}
+
+// AddSchema 添加公共结构
+func (g *Generator) AddSchema(name string, s *Schema) {
+	g.components.Schemas[name] = s
+}
+
+// SchemaOf 生成 Go 类型的数据结构（命名结构体注册为公共结构）
+func (g *Generator) SchemaOf(v interface{}) *Schema {
+	return g.schemas.schemaOf(v)
+}
+
+// AddParameter 添加公共参数，Spec.Query 中同名参数引用该参数
+func (g *Generator) AddParameter(p *Parameter) {
+	g.components.Parameters[p.Name] = p
+}
+
+// SetSecurity 设置认证方式，需要登录的接口引用该认证方式
+func (g *Generator) SetSecurity(name string, scheme *SecurityScheme) {
+	g.security = name
+	g.components.SecuritySchemes[name] = scheme
+}
+
+// SetErrorResponse 设置错误响应，所有接口的 default（及401/403）响应引用该响应
+func (g *Generator) SetErrorResponse(name string, resp *Response) {
+	g.errorResponse = name
+	g.components.Responses[name] = resp
+}
+
+// SetEnvelope 设置 JSON 成功响应的统一包装结构
+func (g *Generator) SetEnvelope(fn func(data *Schema) *Schema) {
+	g.envelope = fn
+}
+
+// RequirePrefix 设置必须有接口描述的路由前缀（其余路由只在有描述时写入文档）
+func (g *Generator) RequirePrefix(prefixes ...string) {
+	g.required = append(g.required, prefixes...)
+}
+
+// Describe 添加接口描述
+func (g *Generator) Describe(method, path string, spec Spec) {
+	g.specs[routeKey(method, path)] = &spec
+}
+
+// routeKey 路由标识
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+// isRequired 路由是否必须有接口描述
+func (g *Generator) isRequired(path string) bool {
+	for _, prefix := range g.required {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Build 根据已注册的路由生成文档：有描述的路由生成完整接口，缺少描述的必需路由标记为 x-undocumented
+func (g *Generator) Build(routes []Route) *Document {
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       g.info,
+		Servers:    g.servers,
+		Tags:       g.tags,
+		Paths:      make(map[string]*PathItem),
+		Components: g.components,
+	}
+
+	ids := g.operationIDs(routes)
+	for _, route := range routes {
+		spec, described := g.specs[routeKey(route.Method, route.Path)]
+		if !described {
+			if !g.isRequired(route.Path) {
+				continue
+			}
+			spec = &Spec{}
+		}
+
+		path := openAPIPath(route.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		if slot := item.operation(route.Method); slot != nil {
+			op := g.operation(route, spec, ids[routeKey(route.Method, route.Path)])
+			op.Undocumented = !described
+			*slot = op
+		}
+	}
+	return doc
+}
+
+// operation 生成接口
+func (g *Generator) operation(route Route, spec *Spec, operationID string) *Operation {
+	op := &Operation{
+		OperationID: operationID,
+		Summary:     spec.Summary,
+		Description: spec.Description,
+		Responses:   make(map[string]*Response),
+		Admin:       spec.Auth == AuthAdmin,
+	}
+	if spec.Tag != "" {
+		op.Tags = []string{spec.Tag}
+	}
+
+	for _, name := range pathParams(route.Path) {
+		op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, name := range spec.Query {
+		if _, ok := g.components.Parameters[name]; ok {
+			op.Parameters = append(op.Parameters, RefParameter(name))
+			continue
+		}
+		op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "query", Schema: &Schema{Type: "string"}})
+	}
+	for _, name := range spec.Headers {
+		op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "header", Required: true, Schema: &Schema{Type: "string"}})
+	}
+
+	switch {
+	case spec.Request != nil:
+		consumes := spec.Consumes
+		if consumes == "" {
+			consumes = mimeJSON
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{consumes: {Schema: g.schemas.schemaOf(spec.Request)}},
+		}
+	case len(spec.Form) > 0:
+		form := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		for _, name := range spec.Form {
+			if name == "file" {
+				form.Properties[name] = &Schema{Type: "string", Format: "binary"}
+				form.Required = append(form.Required, name)
+				continue
+			}
+			form.Properties[name] = &Schema{Type: "string"}
+		}
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{mimeMultipart: {Schema: form}}}
+	}
+
+	status := spec.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	switch {
+	case spec.Produces != "" && spec.Produces != mimeJSON:
+		success.Content = map[string]*MediaType{spec.Produces: {Schema: &Schema{Type: "string", Format: "binary"}}}
+	case status >= http.StatusOK && status < http.StatusMultipleChoices:
+		data := g.schemas.schemaOf(spec.Response)
+		if g.envelope != nil {
+			data = g.envelope(data)
+		}
+		success.Content = map[string]*MediaType{mimeJSON: {Schema: data}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	if spec.Auth != AuthNone && g.security != "" {
+		op.Security = []map[string][]string{{g.security: {}}}
+	}
+	if g.errorResponse != "" {
+		if spec.Auth != AuthNone {
+			op.Responses["401"] = RefResponse(g.errorResponse)
+		}
+		if spec.Auth == AuthAdmin {
+			op.Responses["403"] = RefResponse(g.errorResponse)
+		}
+		op.Responses["default"] = RefResponse(g.errorResponse)
+	}
+	return op
+}
+
+// operationIDs 生成 operationId：优先使用描述中指定的，否则取处理函数名，重名时加处理器名前缀
+func (g *Generator) operationIDs(routes []Route) map[string]string {
+	ids := make(map[string]string, len(routes))
+	counts := make(map[string]int)
+	for _, route := range routes {
+		key := routeKey(route.Method, route.Path)
+		id := ""
+		if spec, ok := g.specs[key]; ok && spec.OperationID != "" {
+			id = spec.OperationID
+		} else {
+			_, id = handlerName(route.Handler)
+		}
+		ids[key] = id
+		counts[id]++
+	}
+	for _, route := range routes {
+		key := routeKey(route.Method, route.Path)
+		if spec, ok := g.specs[key]; ok && spec.OperationID != "" {
+			continue
+		}
+		if counts[ids[key]] > 1 {
+			receiver, method := handlerName(route.Handler)
+			ids[key] = lowerFirst(strings.TrimSuffix(receiver, "Handler")) + exportedName(method)
+		}
+	}
+	return ids
+}
+
+// handlerName 从处理函数全名（如 pkg.(*GroupHandler).CreateGroup-fm）解析处理器名和方法名（首字母小写）
+func handlerName(name string) (string, string) {
+	name = strings.TrimSuffix(name, "-fm")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	parts := strings.Split(name, ".")
+	method := parts[len(parts)-1]
+	receiver := ""
+	if len(parts) > 2 {
+		receiver = strings.Trim(parts[len(parts)-2], "(*)")
+	}
+	return receiver, lowerFirst(method)
+}
+
+// lowerFirst 首字母小写
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// openAPIPath 将 gin 路径参数（:id、*path）转换为 OpenAPI 语法（{id}）
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// pathParams 路径参数名
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			names = append(names, segment[1:])
+		}
+	}
+	return names
+}
+
+// Check 校验已注册的路由与接口描述是否一致，返回不一致项（为空表示一致）：
+// 必需前缀下缺少描述的路由、描述了但未注册的接口（Optional 除外）、重复的 operationId
+func (g *Generator) Check(routes []Route) []string {
+	var problems []string
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		key := routeKey(route.Method, route.Path)
+		registered[key] = true
+		if _, ok := g.specs[key]; !ok && g.isRequired(route.Path) {
+			problems = append(problems, fmt.Sprintf("%s: route is not described", key))
+		}
+	}
+	for key, spec := range g.specs {
+		if !registered[key] && !spec.Optional {
+			problems = append(problems, fmt.Sprintf("%s: described but not registered", key))
+		}
+	}
+
+	seen := make(map[string]string)
+	ids := g.operationIDs(routes)
+	for _, route := range routes {
+		key := routeKey(route.Method, route.Path)
+		if _, ok := g.specs[key]; !ok {
+			continue
+		}
+		if other, ok := seen[ids[key]]; ok {
+			problems = append(problems, fmt.Sprintf("%s: operationId %q duplicates %s", key, ids[key], other))
+			continue
+		}
+		seen[ids[key]] = key
+	}
+
+	sort.Strings(problems)
+	return problems
+}
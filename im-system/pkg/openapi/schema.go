@@ -0,0 +1,187 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// schemaRegistry 按 Go 类型生成数据结构，命名结构体注册到 components.schemas
+type schemaRegistry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+// newSchemaRegistry 创建数据结构注册表
+func newSchemaRegistry(schemas map[string]*Schema) *schemaRegistry {
+	return &schemaRegistry{schemas: schemas, names: make(map[reflect.Type]string)}
+}
+
+// schemaOf 生成值的数据结构，nil 返回空结构（任意值）
+func (r *schemaRegistry) schemaOf(v interface{}) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+	if s, ok := v.(*Schema); ok {
+		return s
+	}
+	return r.schemaOfType(reflect.TypeOf(v))
+}
+
+// schemaOfType 生成类型的数据结构
+func (r *schemaRegistry) schemaOfType(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var s *Schema
+	switch {
+	case t == timeType:
+		s = &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		s = &Schema{Type: "integer", Format: "int64", Description: "纳秒"}
+	case t == rawMessageType:
+		s = &Schema{}
+	default:
+		s = r.schemaOfKind(t)
+	}
+	if nullable && s.Ref == "" {
+		s.Nullable = true
+	}
+	return s
+}
+
+// schemaOfKind 按类型种类生成数据结构
+func (r *schemaRegistry) schemaOfKind(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.schemaOfType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaOfType(t.Elem())}
+	case reflect.Struct:
+		return r.structSchema(t)
+	}
+	// interface{} 等任意值
+	return &Schema{}
+}
+
+// structSchema 生成结构体的数据结构：命名结构体注册为公共结构并返回引用，匿名结构体内联
+func (r *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return r.objectSchema(t)
+	}
+	if name, ok := r.names[t]; ok {
+		return RefSchema(name)
+	}
+
+	name := r.schemaName(t)
+	r.names[t] = name
+	// 先占位，支持自引用结构
+	r.schemas[name] = &Schema{Type: "object"}
+	r.schemas[name] = r.objectSchema(t)
+	return RefSchema(name)
+}
+
+// schemaName 公共结构名称：类型名首字母大写，重名时加包名前缀
+func (r *schemaRegistry) schemaName(t reflect.Type) string {
+	name := exportedName(t.Name())
+	if _, exists := r.schemas[name]; !exists {
+		return name
+	}
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	prefixed := exportedName(pkg) + name
+	for i := 2; ; i++ {
+		if _, exists := r.schemas[prefixed]; !exists {
+			return prefixed
+		}
+		prefixed = exportedName(pkg) + name + strings.Repeat("_", i-1)
+	}
+}
+
+// objectSchema 按 json 标签生成对象结构，binding:"required" 的字段为必填
+func (r *schemaRegistry) objectSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	r.addFields(s, t)
+	return s
+}
+
+// addFields 添加结构体字段（匿名嵌入的结构体字段展开到当前对象）
+func (r *schemaRegistry) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				r.addFields(s, ft)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := r.schemaOfType(field.Type)
+		if strings.Contains(opts, "string") && prop.Ref == "" {
+			prop = &Schema{Type: "string"}
+		}
+		s.Properties[name] = prop
+
+		for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+			if rule == "required" {
+				s.Required = append(s.Required, name)
+				break
+			}
+		}
+	}
+}
+
+// exportedName 首字母大写
+func exportedName(name string) string {
+	if name == "" {
+		return name
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,101 @@
+// Package ratelimit 提供基于 Redis 的令牌桶限流（多节点共享配额）
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrRateLimited 请求过于频繁
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// keyPrefix 令牌桶 Redis Key 前缀（ratelimit:<name>:<key>）
+const keyPrefix = "ratelimit:"
+
+// tokenBucketScript 令牌桶：按经过的时间补充令牌（不超过桶容量），有令牌时取走一个；
+// 返回 {是否允许, 剩余令牌数, 需等待的毫秒数}
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = burst
+	ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], ttl)
+return {allowed, math.floor(tokens), wait}
+`)
+
+// Rule 限流规则：每个 Period 最多 Limit 次，允许一次性用完（桶容量为 Limit）
+type Rule struct {
+	Limit  int
+	Period time.Duration
+}
+
+// Enabled 规则是否生效（Limit 或 Period 为 0 表示不限制）
+func (r Rule) Enabled() bool {
+	return r.Limit > 0 && r.Period > 0
+}
+
+// Result 限流结果
+type Result struct {
+	Allowed    bool
+	Remaining  int           // 剩余可用次数
+	RetryAfter time.Duration // 被限流时距下一次可用的时间
+}
+
+// Limiter 基于 Redis 的令牌桶限流器
+type Limiter struct {
+	redis *redis.Client
+}
+
+// NewLimiter 创建限流器
+func NewLimiter(redisClient *redis.Client) *Limiter {
+	return &Limiter{redis: redisClient}
+}
+
+// Allow 按规则对 name（规则名）下的 key（用户、IP等）取一个令牌，规则未生效时总是允许
+func (l *Limiter) Allow(ctx context.Context, name, key string, rule Rule) (*Result, error) {
+	if !rule.Enabled() {
+		return &Result{Allowed: true, Remaining: -1}, nil
+	}
+
+	rate := float64(rule.Limit) / rule.Period.Seconds()
+	// 空闲到桶补满后状态不再需要保留
+	ttl := rule.Period + time.Second
+	values, err := tokenBucketScript.Run(ctx, l.redis, []string{keyPrefix + name + ":" + key},
+		rate, rule.Limit, time.Now().UnixMilli(), ttl.Milliseconds()).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("rate limit %s error: %w", name, err)
+	}
+	if len(values) != 3 {
+		return nil, fmt.Errorf("rate limit %s: unexpected script result", name)
+	}
+
+	return &Result{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,177 @@
+// Package tracing 提供基于 OpenTelemetry 的分布式链路追踪（OTLP 导出）
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName Tracer 名称
+const instrumentationName = "github.com/d60-lab/im-system"
+
+// OTLP 导出协议
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http/protobuf"
+)
+
+// 消息链路的 Span 属性
+const (
+	AttrMessageID      = attribute.Key("im.message_id")
+	AttrMessageType    = attribute.Key("im.message_type")
+	AttrConversationID = attribute.Key("im.conversation_id")
+	AttrUserID         = attribute.Key("im.user_id")
+	AttrRecipients     = attribute.Key("im.recipients") // 接收者数量
+	AttrNodeID         = attribute.Key("im.node_id")    // 目标节点
+	AttrPlatform       = attribute.Key("im.platform")
+)
+
+// Config 链路追踪配置
+type Config struct {
+	Endpoint    string  // OTLP 接收地址（host:port 或 http(s):// URL），为空时不启用
+	Protocol    string  // grpc（默认）/ http/protobuf
+	Insecure    bool    // 不使用 TLS（URL 为 http:// 时自动启用）
+	ServiceName string  // 服务名
+	NodeID      string  // 节点ID，作为服务实例ID
+	SampleRatio float64 // 根 Span 采样比例（0~1），上游已决定采样的链路沿用上游结果
+}
+
+// Enabled 是否启用链路追踪
+func (c Config) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// Init 初始化全局 TracerProvider 与 W3C Trace Context 传播器，返回关闭函数（写出缓冲的 Span）；
+// 未配置导出地址时不做任何设置，各处创建的 Span 均为空操作
+func Init(ctx context.Context, config Config) (func(context.Context) error, error) {
+	if !config.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(
+			semconv.ServiceName(config.ServiceName),
+			semconv.ServiceInstanceID(config.NodeID),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: create resource error: %w", err)
+	}
+
+	ratio := config.SampleRatio
+	if ratio < 0 || ratio > 1 {
+		ratio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// newExporter 按协议创建 OTLP 导出器
+func newExporter(ctx context.Context, config Config) (*otlptrace.Exporter, error) {
+	endpoint, insecure, err := parseEndpoint(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	insecure = insecure || config.Insecure
+
+	switch config.Protocol {
+	case "", ProtocolGRPC:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+		if insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case ProtocolHTTP, "http":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+		if insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("tracing: unsupported otlp protocol %q", config.Protocol)
+	}
+}
+
+// parseEndpoint 解析导出地址，返回 host:port 及是否为 http:// 地址
+func parseEndpoint(endpoint string) (string, bool, error) {
+	if !strings.Contains(endpoint, "://") {
+		return endpoint, false, nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", false, fmt.Errorf("tracing: invalid otlp endpoint %q", endpoint)
+	}
+	return u.Host, u.Scheme == "http", nil
+}
+
+// Start 创建 Span，ctx 中已有 Span 时作为其子 Span
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End 结束 Span，err 不为空时记录错误并标记失败
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject 将 ctx 中的链路上下文写入跨节点消息的载体，ctx 没有被采样的 Span 时返回 nil
+func Inject(ctx context.Context) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsSampled() {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract 从跨节点消息的载体中恢复链路上下文
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// WithParent 以 parent 作为之后创建的 Span 的父 Span，用于异步队列中的任务延续发起方的链路
+func WithParent(ctx context.Context, parent trace.SpanContext) context.Context {
+	if !parent.IsValid() {
+		return ctx
+	}
+	return trace.ContextWithSpanContext(ctx, parent)
+}
@@ -155,15 +155,15 @@ func GenerateShortUUID() string {
 }
 
 // GenerateMessageID 生成消息ID
-// 格式: msg_<timestamp>_<random>
+// 格式由全局ID生成器决定，默认: msg_<timestamp>_<random>
 func GenerateMessageID() string {
-	return fmt.Sprintf("msg_%d_%s", time.Now().UnixNano(), randomHex(8))
+	return CurrentIDGenerator().NextMessageID()
 }
 
 // GenerateFileID 生成文件ID
-// 格式: file_<timestamp>_<random>
+// 格式由全局ID生成器决定，默认: file_<timestamp>_<random>
 func GenerateFileID() string {
-	return fmt.Sprintf("file_%d_%s", time.Now().UnixNano(), randomHex(8))
+	return CurrentIDGenerator().NextFileID()
 }
 
 // GenerateGroupID 生成群组ID
@@ -178,9 +178,42 @@ func GenerateUserID() string {
 	return "user_" + GenerateShortUUID()
 }
 
-// GenerateConversationID 生成会话ID
+// GenerateDepartmentID 生成部门ID
+// 格式: dept_<uuid>
+func GenerateDepartmentID() string {
+	return "dept_" + GenerateShortUUID()
+}
+
+// GenerateGuestID 生成访客用户ID
+// 格式: guest_<uuid>
+func GenerateGuestID() string {
+	return "guest_" + GenerateShortUUID()
+}
+
+// GenerateReminderID 生成消息提醒ID
+// 格式: rmd_<uuid>
+func GenerateReminderID() string {
+	return "rmd_" + GenerateShortUUID()
+}
+
+// GenerateFriendRequestID 生成好友申请ID
+// 格式: frq_<uuid>
+func GenerateFriendRequestID() string {
+	return "frq_" + GenerateShortUUID()
+}
+
+// GenerateAppID 生成集成应用ID
+// 格式: app_<uuid>
+func GenerateAppID() string {
+	return "app_" + GenerateShortUUID()
+}
+
+// GenerateConversationID 生成旧格式会话ID
 // 单聊: single_<小user_id>_<大user_id>
 // 群聊: group_<group_id>
+//
+// Deprecated: 会话ID统一使用 model.ConversationID 的规范格式（single:a:b / group:x），
+// 本函数仅保留用于兼容旧数据。
 func GenerateConversationID(convType int, id1, id2 string) string {
 	if convType == 1 { // 单聊
 		if id1 < id2 {
@@ -197,6 +230,21 @@ func GenerateToken(length int) string {
 	return randomHex(length)
 }
 
+// passwordAlphabet 随机密码字符集（去除易混淆的 0/O/1/l/I）
+const passwordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GeneratePassword 生成指定长度的随机密码
+func GeneratePassword(length int) string {
+	bytes := make([]byte, length)
+	if _, err := rand.Read(bytes); err != nil {
+		return randomHex((length + 1) / 2)[:length]
+	}
+	for i, b := range bytes {
+		bytes[i] = passwordAlphabet[int(b)%len(passwordAlphabet)]
+	}
+	return string(bytes)
+}
+
 // GenerateDeviceID 生成设备ID
 func GenerateDeviceID() string {
 	return "device_" + randomHex(16)
@@ -394,3 +442,21 @@ func RetryWithBackoff(attempts int, initialDelay time.Duration, maxDelay time.Du
 	}
 	return lastErr
 }
+
+// GenerateCSSessionID 生成客服会话ID
+// 格式: css_<uuid>
+func GenerateCSSessionID() string {
+	return "css_" + GenerateShortUUID()
+}
+
+// GeneratePollID 生成投票ID
+// 格式: pol_<uuid>
+func GeneratePollID() string {
+	return "pol_" + GenerateShortUUID()
+}
+
+// GenerateMentionGroupID 生成提及组ID
+// 格式: mtg_<uuid>
+func GenerateMentionGroupID() string {
+	return "mtg_" + GenerateShortUUID()
+}
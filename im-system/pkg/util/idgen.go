@@ -0,0 +1,221 @@
+package util
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+	"github.com/segmentio/ksuid"
+)
+
+// ID生成策略
+const (
+	IDStrategyLegacy    = "legacy"    // 旧格式: msg_<纳秒时间戳>_<随机数>
+	IDStrategySnowflake = "snowflake" // 雪花算法
+	IDStrategyULID      = "ulid"      // ULID（字典序可排序）
+	IDStrategyKSUID     = "ksuid"     // KSUID（字典序可排序）
+	IDStrategyUUID      = "uuid"      // UUID（仅用于解析）
+)
+
+// ID前缀
+const (
+	MessageIDPrefix = "msg_"
+	FileIDPrefix    = "file_"
+)
+
+// ErrInvalidID ID格式无法识别
+var ErrInvalidID = errors.New("invalid id format")
+
+// IDGeneratorFactory ID生成器工厂函数
+type IDGeneratorFactory func() (IDGenerator, error)
+
+var (
+	idGeneratorsMu sync.RWMutex
+	idGenerators   = map[string]IDGeneratorFactory{
+		IDStrategyLegacy: func() (IDGenerator, error) { return &legacyIDGenerator{}, nil },
+		IDStrategySnowflake: func() (IDGenerator, error) {
+			return NewSnowflakeIDGenerator(DefaultSnowflakeConfig)
+		},
+		IDStrategyULID:  func() (IDGenerator, error) { return NewULIDGenerator(), nil },
+		IDStrategyKSUID: func() (IDGenerator, error) { return &ksuidGenerator{}, nil },
+	}
+
+	currentIDGenerator IDGenerator = &legacyIDGenerator{}
+)
+
+// RegisterIDGenerator 注册ID生成器（同名覆盖）
+func RegisterIDGenerator(name string, factory IDGeneratorFactory) {
+	idGeneratorsMu.Lock()
+	defer idGeneratorsMu.Unlock()
+	idGenerators[name] = factory
+}
+
+// NewIDGenerator 根据策略名称创建ID生成器
+func NewIDGenerator(name string) (IDGenerator, error) {
+	idGeneratorsMu.RLock()
+	factory, ok := idGenerators[name]
+	idGeneratorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown id strategy: %s", name)
+	}
+	return factory()
+}
+
+// InitIDGenerator 根据策略名称设置全局ID生成器
+func InitIDGenerator(name string) error {
+	gen, err := NewIDGenerator(name)
+	if err != nil {
+		return err
+	}
+	SetIDGenerator(gen)
+	return nil
+}
+
+// SetIDGenerator 设置全局ID生成器
+func SetIDGenerator(gen IDGenerator) {
+	idGeneratorsMu.Lock()
+	defer idGeneratorsMu.Unlock()
+	currentIDGenerator = gen
+}
+
+// CurrentIDGenerator 获取全局ID生成器
+func CurrentIDGenerator() IDGenerator {
+	idGeneratorsMu.RLock()
+	defer idGeneratorsMu.RUnlock()
+	return currentIDGenerator
+}
+
+// legacyIDGenerator 旧格式ID生成器
+type legacyIDGenerator struct{}
+
+func (g *legacyIDGenerator) NextID() string { return GenerateID() }
+
+func (g *legacyIDGenerator) NextMessageID() string {
+	return fmt.Sprintf("%s%d_%s", MessageIDPrefix, time.Now().UnixNano(), randomHex(8))
+}
+
+func (g *legacyIDGenerator) NextFileID() string {
+	return fmt.Sprintf("%s%d_%s", FileIDPrefix, time.Now().UnixNano(), randomHex(8))
+}
+
+// snowflakeIDGenerator 雪花算法ID生成器
+type snowflakeIDGenerator struct {
+	sf *Snowflake
+}
+
+// NewSnowflakeIDGenerator 创建雪花算法ID生成器
+func NewSnowflakeIDGenerator(config SnowflakeConfig) (IDGenerator, error) {
+	sf, err := NewSnowflake(config)
+	if err != nil {
+		return nil, err
+	}
+	return &snowflakeIDGenerator{sf: sf}, nil
+}
+
+func (g *snowflakeIDGenerator) NextID() string        { return g.sf.NextIDString() }
+func (g *snowflakeIDGenerator) NextMessageID() string { return MessageIDPrefix + g.sf.NextIDString() }
+func (g *snowflakeIDGenerator) NextFileID() string    { return FileIDPrefix + g.sf.NextIDString() }
+
+// ulidGenerator ULID生成器（同一毫秒内单调递增）
+type ulidGenerator struct {
+	mu      sync.Mutex
+	entropy *ulid.MonotonicEntropy
+}
+
+// NewULIDGenerator 创建ULID生成器
+func NewULIDGenerator() IDGenerator {
+	return &ulidGenerator{entropy: ulid.Monotonic(rand.Reader, 0)}
+}
+
+func (g *ulidGenerator) NextID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return ulid.MustNew(ulid.Timestamp(time.Now()), g.entropy).String()
+}
+
+func (g *ulidGenerator) NextMessageID() string { return MessageIDPrefix + g.NextID() }
+func (g *ulidGenerator) NextFileID() string    { return FileIDPrefix + g.NextID() }
+
+// ksuidGenerator KSUID生成器
+type ksuidGenerator struct{}
+
+func (g *ksuidGenerator) NextID() string        { return ksuid.New().String() }
+func (g *ksuidGenerator) NextMessageID() string { return MessageIDPrefix + g.NextID() }
+func (g *ksuidGenerator) NextFileID() string    { return FileIDPrefix + g.NextID() }
+
+// ParsedID 解析后的ID
+type ParsedID struct {
+	Raw      string    // 原始ID
+	Prefix   string    // 前缀，如 msg_、file_
+	Strategy string    // 生成策略
+	Time     time.Time // ID中包含的时间（UUID等不含时间的格式为零值）
+}
+
+// knownIDPrefixes 已知的ID前缀
+var knownIDPrefixes = []string{MessageIDPrefix, FileIDPrefix, "upload_", "group_", "user_", "device_"}
+
+// ParseID 解析ID，兼容旧格式和各种生成策略
+func ParseID(id string) (*ParsedID, error) {
+	parsed := &ParsedID{Raw: id}
+	body := id
+	for _, prefix := range knownIDPrefixes {
+		if strings.HasPrefix(body, prefix) {
+			parsed.Prefix = prefix
+			body = body[len(prefix):]
+			break
+		}
+	}
+
+	// 旧格式: <纳秒时间戳>_<随机数>
+	if idx := strings.IndexByte(body, '_'); idx > 0 {
+		nanos, err := strconv.ParseInt(body[:idx], 10, 64)
+		if err != nil {
+			return nil, ErrInvalidID
+		}
+		parsed.Strategy = IDStrategyLegacy
+		parsed.Time = time.Unix(0, nanos)
+		return parsed, nil
+	}
+
+	switch len(body) {
+	case ulid.EncodedSize:
+		if u, err := ulid.ParseStrict(body); err == nil {
+			parsed.Strategy = IDStrategyULID
+			parsed.Time = ulid.Time(u.Time())
+			return parsed, nil
+		}
+	case 27: // KSUID编码长度
+		if k, err := ksuid.Parse(body); err == nil {
+			parsed.Strategy = IDStrategyKSUID
+			parsed.Time = k.Time()
+			return parsed, nil
+		}
+	}
+
+	if n, err := strconv.ParseInt(body, 10, 64); err == nil && n > 0 {
+		cfg := DefaultSnowflakeConfig
+		parsed.Strategy = IDStrategySnowflake
+		parsed.Time = time.UnixMilli((n >> (cfg.NodeBits + cfg.SequenceBits)) + cfg.Epoch)
+		return parsed, nil
+	}
+
+	if _, err := uuid.Parse(body); err == nil {
+		parsed.Strategy = IDStrategyUUID
+		return parsed, nil
+	}
+
+	return nil, ErrInvalidID
+}
+
+// MessageIDLowerBound 返回指定时间对应的最小ULID消息ID，可用于按ID范围查询
+func MessageIDLowerBound(t time.Time) string {
+	var u ulid.ULID
+	_ = u.SetTime(ulid.Timestamp(t))
+	return MessageIDPrefix + u.String()
+}
@@ -0,0 +1,307 @@
+// Package webpush 提供浏览器 Web Push 推送（VAPID 认证，RFC 8291 aes128gcm 内容加密）
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/hkdf"
+)
+
+// 错误定义
+var (
+	ErrInvalidSubscription = errors.New("webpush: invalid subscription")
+	ErrInvalidKeys         = errors.New("webpush: invalid vapid keys")
+	ErrPayloadTooLarge     = errors.New("webpush: payload too large")
+	ErrGone                = errors.New("webpush: subscription expired or unsubscribed")
+)
+
+// 加密参数
+const (
+	recordSize = 4096
+	// MaxPayloadSize 推送内容最大字节数（推送服务限制请求体 4096 字节，扣除加密头、认证标签和填充分隔符）
+	MaxPayloadSize = recordSize - 86 - 16 - 1
+
+	vapidTokenTTL = 12 * time.Hour // VAPID JWT 有效期（规范要求不超过24小时）
+)
+
+// 推送紧急程度（Urgency 头）
+const (
+	UrgencyNormal = "normal"
+	UrgencyHigh   = "high"
+)
+
+// Subscription 浏览器推送订阅（PushSubscription.toJSON() 的结果）
+type Subscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// ParseSubscription 解析并校验订阅：endpoint 须为 HTTPS 地址，p256dh 为 P-256 公钥，auth 为16字节
+func ParseSubscription(data []byte) (*Subscription, error) {
+	var sub Subscription
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return nil, ErrInvalidSubscription
+	}
+	endpoint, err := url.Parse(sub.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return nil, ErrInvalidSubscription
+	}
+	if _, _, err := sub.keys(); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// keys 解码订阅的浏览器公钥和认证密钥
+func (s *Subscription) keys() (*ecdh.PublicKey, []byte, error) {
+	rawKey, err := decodeBase64(s.Keys.P256dh)
+	if err != nil {
+		return nil, nil, ErrInvalidSubscription
+	}
+	publicKey, err := ecdh.P256().NewPublicKey(rawKey)
+	if err != nil {
+		return nil, nil, ErrInvalidSubscription
+	}
+	auth, err := decodeBase64(s.Keys.Auth)
+	if err != nil || len(auth) != 16 {
+		return nil, nil, ErrInvalidSubscription
+	}
+	return publicKey, auth, nil
+}
+
+// Config VAPID 配置
+type Config struct {
+	PublicKey  string // VAPID 公钥（base64url 编码的未压缩 P-256 公钥），即浏览器订阅时的 applicationServerKey
+	PrivateKey string // VAPID 私钥（base64url 编码的32字节私钥）
+	Subject    string // 联系方式（mailto: 或 https: 地址），推送服务出现问题时联系
+}
+
+// Client Web Push 客户端
+type Client struct {
+	publicKey  string
+	privateKey *ecdsa.PrivateKey
+	subject    string
+	httpClient *http.Client
+}
+
+// NewClient 创建 Web Push 客户端，校验公钥与私钥匹配
+func NewClient(config Config, httpClient *http.Client) (*Client, error) {
+	rawPrivate, err := decodeBase64(config.PrivateKey)
+	if err != nil {
+		return nil, ErrInvalidKeys
+	}
+	private, err := ecdh.P256().NewPrivateKey(rawPrivate)
+	if err != nil {
+		return nil, ErrInvalidKeys
+	}
+	public := private.PublicKey().Bytes()
+	if rawPublic, err := decodeBase64(config.PublicKey); err != nil || !bytes.Equal(rawPublic, public) {
+		return nil, ErrInvalidKeys
+	}
+	if config.Subject == "" {
+		return nil, errors.New("webpush: vapid subject is required")
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Client{
+		publicKey: base64.RawURLEncoding.EncodeToString(public),
+		privateKey: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(public[1:33]),
+				Y:     new(big.Int).SetBytes(public[33:65]),
+			},
+			D: new(big.Int).SetBytes(rawPrivate),
+		},
+		subject:    config.Subject,
+		httpClient: httpClient,
+	}, nil
+}
+
+// PublicKey VAPID 公钥（浏览器订阅时的 applicationServerKey）
+func (c *Client) PublicKey() string {
+	return c.publicKey
+}
+
+// Options 推送选项
+type Options struct {
+	TTL     time.Duration // 推送服务保留时间，用户离线超过该时间后丢弃
+	Urgency string        // normal / high，为空时不设置
+	Topic   string        // 同一主题未送达的推送被替换（最多32个 URL 安全字符）
+}
+
+// Send 加密并发送推送；订阅已失效（404/410）时返回 ErrGone，调用方应删除订阅
+func (c *Client) Send(ctx context.Context, sub *Subscription, payload []byte, opts Options) error {
+	if len(payload) > MaxPayloadSize {
+		return ErrPayloadTooLarge
+	}
+	body, err := encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+	authorization, err := c.vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(opts.TTL/time.Second)))
+	req.Header.Set("Authorization", authorization)
+	if opts.Urgency != "" {
+		req.Header.Set("Urgency", opts.Urgency)
+	}
+	if opts.Topic != "" {
+		req.Header.Set("Topic", opts.Topic)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webpush: send error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrGone
+	case resp.StatusCode == http.StatusRequestEntityTooLarge:
+		return ErrPayloadTooLarge
+	default:
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webpush: push service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+}
+
+// vapidAuthorization 生成 VAPID 认证头（RFC 8292）
+func (c *Client) vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", ErrInvalidSubscription
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(vapidTokenTTL).Unix(),
+		"sub": c.subject,
+	})
+	signed, err := token.SignedString(c.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("webpush: sign vapid token error: %w", err)
+	}
+	return "vapid t=" + signed + ", k=" + c.publicKey, nil
+}
+
+// encrypt 按 RFC 8291 加密推送内容（单条记录）
+func encrypt(sub *Subscription, payload []byte) ([]byte, error) {
+	uaPublic, authSecret, err := sub.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, ErrInvalidSubscription
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic.Bytes()...), asPublic...)
+	ikm, err := expand(hkdf.Extract(sha256.New, sharedSecret, authSecret), keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	prk := hkdf.Extract(sha256.New, ikm, salt)
+	cek, err := expand(prk, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := expand(prk, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 头部：salt(16) | 记录大小(4) | 密钥ID长度(1) | 服务端临时公钥
+	header := make([]byte, 0, 21+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	// 最后一条记录以 0x02 分隔符结尾
+	plaintext := append(append(make([]byte, 0, len(payload)+1), payload...), 0x02)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// expand HKDF-Expand 读取指定长度
+func expand(prk, info []byte, length int) ([]byte, error) {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, info), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GenerateVAPIDKeys 生成 VAPID 密钥对（base64url 编码的公钥、私钥）
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	private, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(private.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(private.Bytes()), nil
+}
+
+// decodeBase64 解码 base64url（兼容带填充和标准 base64 字符）
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(s)
+	return base64.RawURLEncoding.DecodeString(s)
+}